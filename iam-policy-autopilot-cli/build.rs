@@ -0,0 +1,8 @@
+fn main() -> Result<(), Box<dyn std::error::Error>> {
+    // protoc is vendored, so building doesn't require it to be installed
+    std::env::set_var("PROTOC", protoc_bin_vendored::protoc_bin_path()?);
+    tonic_build::configure()
+        .build_client(false)
+        .compile_protos(&["proto/policy_analysis.proto"], &["proto"])?;
+    Ok(())
+}
@@ -0,0 +1,325 @@
+//! Policies of a fleet of projects, as generated by the aggregate command.
+//!
+//! Each path, a directory or git URL, is a project analyzed on its own, in the language
+//! of its files. Its policies are output under its namespace, the name of its directory
+//! or repository made unique among the paths, so one invocation covers the repositories
+//! of many services. Merged, the projects are analyzed together and the merged policy
+//! comes with the namespaces of the projects requiring each action. The sources of a run
+//! are in one language, so the projects of each language have their own merged policies.
+
+use std::collections::{BTreeMap, BTreeSet, HashSet};
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::api::generate_policies;
+use iam_policy_autopilot_policy_generation::api::model::GeneratePolicyConfig;
+use iam_policy_autopilot_policy_generation::{ActionProvenance, PolicyWithMetadata};
+use serde::Serialize;
+
+use crate::remote_sources::{self, SourceFiles};
+
+/// Namespace of the projects whose name can't be told from their path
+const DEFAULT_NAMESPACE: &str = "project";
+
+/// A path of the fleet, with its source files
+#[derive(Debug)]
+pub(crate) struct Project {
+    /// Directory, file or git URL, as given
+    path: PathBuf,
+    /// Name of the project, unique among the paths
+    namespace: String,
+    /// Language of the source files
+    language: String,
+    sources: SourceFiles,
+}
+
+impl Project {
+    /// Whether the project's sources include `file`, e.g. a file of its dependencies
+    fn contains(&self, file: &Path) -> bool {
+        file.starts_with(&self.path) || self.sources.files.iter().any(|source| source == file)
+    }
+}
+
+/// The policies of a project
+#[derive(Debug, Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct PathPolicies {
+    pub(crate) path: String,
+    pub(crate) namespace: String,
+    pub(crate) policies: Vec<PolicyWithMetadata>,
+}
+
+/// An action of the merged policies, with the projects requiring it
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct PathProvenance {
+    pub(crate) action: String,
+    pub(crate) resources: Vec<String>,
+    /// Namespaces of the projects whose calls require the action
+    pub(crate) namespaces: Vec<String>,
+}
+
+/// The merged policies of the fleet
+#[derive(Debug, Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct MergedPolicies {
+    pub(crate) policies: Vec<PolicyWithMetadata>,
+    pub(crate) provenance: Vec<PathProvenance>,
+}
+
+/// List the source files of `paths`, cloning those that are git URLs
+///
+/// # Errors
+/// Returns an error if a path doesn't exist, can't be cloned or has no source files
+pub(crate) fn load_projects(paths: &[PathBuf], language: Option<&str>) -> Result<Vec<Project>> {
+    paths
+        .iter()
+        .zip(namespaces(paths))
+        .map(|(path, namespace)| {
+            let sources =
+                remote_sources::project_source_files(path, language).with_context(|| {
+                    format!("Failed to list the source files of {}", path.display())
+                })?;
+            let Some(language) = remote_sources::group_by_language(sources.files.iter().cloned())
+                .into_keys()
+                .next()
+            else {
+                anyhow::bail!("{} has no source files to analyze", path.display());
+            };
+            log::info!(
+                "Analyzing {} {language} source files of {} as {namespace}",
+                sources.files.len(),
+                path.display()
+            );
+            Ok(Project {
+                path: path.clone(),
+                namespace,
+                language,
+                sources,
+            })
+        })
+        .collect()
+}
+
+/// Generate the policies of each project with the options of `template`
+pub(crate) async fn path_policies(
+    projects: &[Project],
+    template: &GeneratePolicyConfig,
+) -> Result<Vec<PathPolicies>> {
+    let mut path_policies = Vec::with_capacity(projects.len());
+    for project in projects {
+        let mut config = template.clone();
+        config.extract_sdk_calls_config.source_files = project.sources.files.clone();
+        let result = generate_policies(&config).await.with_context(|| {
+            format!(
+                "Failed to generate the policies of {}",
+                project.path.display()
+            )
+        })?;
+        path_policies.push(PathPolicies {
+            path: project.path.display().to_string(),
+            namespace: project.namespace.clone(),
+            policies: result.policies,
+        });
+    }
+    Ok(path_policies)
+}
+
+/// Generate the merged policies of the projects of each language, with the options of
+/// `template`
+pub(crate) async fn merged_policies(
+    projects: &[Project],
+    template: &GeneratePolicyConfig,
+) -> Result<MergedPolicies> {
+    let mut by_language: BTreeMap<&str, Vec<&Project>> = BTreeMap::new();
+    for project in projects {
+        by_language
+            .entry(project.language.as_str())
+            .or_default()
+            .push(project);
+    }
+
+    let mut merged = MergedPolicies {
+        policies: Vec::new(),
+        provenance: Vec::new(),
+    };
+    for (language, projects) in by_language {
+        let mut config = template.clone();
+        config.action_provenance = true;
+        config.extract_sdk_calls_config.language = Some(language.to_string());
+        config.extract_sdk_calls_config.source_files = projects
+            .iter()
+            .flat_map(|project| project.sources.files.iter().cloned())
+            .collect();
+        let result = generate_policies(&config)
+            .await
+            .with_context(|| format!("Failed to generate the policies of the {language} paths"))?;
+        merged.policies.extend(result.policies);
+        merged.provenance.extend(path_provenance(
+            result.action_provenance.unwrap_or_default(),
+            &projects,
+        ));
+    }
+    merged.provenance.sort_by(|a, b| a.action.cmp(&b.action));
+    Ok(merged)
+}
+
+/// `provenance` with the namespaces of the projects making the calls of each action
+fn path_provenance(
+    provenance: Vec<ActionProvenance>,
+    projects: &[&Project],
+) -> Vec<PathProvenance> {
+    provenance
+        .into_iter()
+        .map(|action| {
+            let namespaces: BTreeSet<&str> = action
+                .calls
+                .iter()
+                .filter_map(|call| {
+                    projects
+                        .iter()
+                        .find(|project| project.contains(&call.location.file_path))
+                        .map(|project| project.namespace.as_str())
+                })
+                .collect();
+            PathProvenance {
+                action: action.action,
+                resources: action.resources,
+                namespaces: namespaces.into_iter().map(str::to_string).collect(),
+            }
+        })
+        .collect()
+}
+
+/// Names of `paths` unique among them: the name of their directory or repository, with a
+/// number appended to the repeated ones
+fn namespaces(paths: &[PathBuf]) -> Vec<String> {
+    let mut taken = HashSet::new();
+    paths
+        .iter()
+        .map(|path| {
+            let name = project_name(path);
+            let mut namespace = name.clone();
+            let mut count = 1;
+            while !taken.insert(namespace.clone()) {
+                count += 1;
+                namespace = format!("{name}-{count}");
+            }
+            namespace
+        })
+        .collect()
+}
+
+/// Name of the directory or repository of `path`, e.g. `orders` for
+/// `https://github.com/org/orders.git#v1.2.0` or `services/orders/`
+fn project_name(path: &Path) -> String {
+    let path_name = path.to_string_lossy();
+    let location = path_name.split('#').next().unwrap_or_default();
+    let name = location
+        .trim_end_matches(['/', '\\'])
+        .rsplit(['/', '\\', ':'])
+        .next()
+        .unwrap_or_default();
+    let name = name.strip_suffix(".git").unwrap_or(name);
+    if !name.is_empty() && name != "." && name != ".." {
+        return name.to_string();
+    }
+    std::fs::canonicalize(path)
+        .ok()
+        .and_then(|path| {
+            path.file_name()
+                .map(|name| name.to_string_lossy().into_owned())
+        })
+        .unwrap_or_else(|| DEFAULT_NAMESPACE.to_string())
+}
+
+/// Write the policies of each project to `<namespace>.json` in `directory`, in the format
+/// of generate-policies
+pub(crate) fn write_path_policies(path_policies: &[PathPolicies], directory: &Path) -> Result<()> {
+    std::fs::create_dir_all(directory).with_context(|| {
+        format!(
+            "Failed to create the output directory {}",
+            directory.display()
+        )
+    })?;
+    for policies in path_policies {
+        let path = directory.join(format!("{}.json", policies.namespace));
+        let json_output = serde_json::to_string_pretty(&serde_json::json!({
+            "Policies": policies.policies,
+        }))
+        .context("Failed to serialize the policies")?;
+        std::fs::write(&path, format!("{json_output}\n"))
+            .with_context(|| format!("Failed to write the policies to {}", path.display()))?;
+    }
+    crate::output::note(&format!(
+        "Wrote the policies of {} paths to {}",
+        path_policies.len(),
+        directory.display()
+    ));
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use iam_policy_autopilot_policy_generation::{CallSite, Location};
+
+    use super::*;
+
+    fn project(path: &str, namespace: &str, files: &[&str]) -> Project {
+        Project {
+            path: PathBuf::from(path),
+            namespace: namespace.to_string(),
+            language: "python".to_string(),
+            sources: SourceFiles::local(files.iter().map(PathBuf::from).collect()),
+        }
+    }
+
+    fn call(file: &str) -> CallSite {
+        CallSite {
+            location: Location::new(PathBuf::from(file), (1, 1), (1, 10)),
+            expression: "s3.get_object()".to_string(),
+        }
+    }
+
+    #[test]
+    fn test_namespaces() {
+        let paths = [
+            "https://github.com/org/orders.git#v1.2.0",
+            "git@github.com:org/billing.git",
+            "services/orders/",
+            "services/payments",
+            "other/orders",
+        ]
+        .map(PathBuf::from);
+
+        assert_eq!(
+            namespaces(&paths),
+            vec!["orders", "billing", "orders-2", "payments", "orders-3"]
+        );
+    }
+
+    #[test]
+    fn test_path_provenance() {
+        let orders = project("services/orders", "orders", &["services/orders/app.py"]);
+        let billing = project("/tmp/clone", "billing", &["/tmp/clone/main.py"]);
+        let provenance = vec![ActionProvenance {
+            action: "s3:GetObject".to_string(),
+            resources: vec!["*".to_string()],
+            calls: vec![
+                call("/tmp/clone/main.py"),
+                call("services/orders/app.py"),
+                call("services/orders/.venv/lib/client.py"),
+                call("elsewhere.py"),
+            ],
+        }];
+
+        assert_eq!(
+            path_provenance(provenance, &[&orders, &billing]),
+            vec![PathProvenance {
+                action: "s3:GetObject".to_string(),
+                resources: vec!["*".to_string()],
+                namespaces: vec!["billing".to_string(), "orders".to_string()],
+            }]
+        );
+    }
+}
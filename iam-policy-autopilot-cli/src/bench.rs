@@ -0,0 +1,229 @@
+//! Benchmarks of the analysis of a source tree, as run by the bench command.
+//!
+//! Each iteration extracts the SDK calls of the sources, then generates their policies,
+//! timing both phases. The report gives the fastest, median and slowest duration of each
+//! phase along with the peak memory of the process, and is keyed by the tool version, so
+//! the reports of two releases on the same sources compare. The first iteration also loads
+//! the service definitions and fetches the service references, which is why the medians
+//! are compared with a baseline rather than the averages.
+
+use std::path::Path;
+use std::time::{Duration, Instant};
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::api::model::GeneratePolicyConfig;
+use iam_policy_autopilot_policy_generation::api::{extract_sdk_calls, generate_policies};
+use serde::{Deserialize, Serialize};
+
+/// Durations of a phase over the iterations of a benchmark
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct PhaseTimings {
+    pub(crate) min_seconds: f64,
+    pub(crate) median_seconds: f64,
+    pub(crate) max_seconds: f64,
+}
+
+impl PhaseTimings {
+    /// Timings of the non-empty `durations`
+    fn new(mut durations: Vec<Duration>) -> Self {
+        durations.sort();
+        let seconds = |duration: &Duration| duration.as_secs_f64();
+        let middle = durations.len() / 2;
+        let median_seconds = if durations.len() % 2 == 0 {
+            (seconds(&durations[middle - 1]) + seconds(&durations[middle])) / 2.0
+        } else {
+            seconds(&durations[middle])
+        };
+        Self {
+            min_seconds: durations.first().map(seconds).unwrap_or_default(),
+            median_seconds,
+            max_seconds: durations.last().map(seconds).unwrap_or_default(),
+        }
+    }
+}
+
+/// What a benchmark measured, as the bench command outputs it
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct BenchReport {
+    /// Version of the tool that ran the benchmark
+    pub(crate) version: String,
+    /// Number of times the sources were analyzed
+    pub(crate) iterations: usize,
+    /// Source files analyzed, after the default exclusions
+    pub(crate) source_files: usize,
+    /// SDK calls extracted from them
+    pub(crate) sdk_calls: usize,
+    /// Policies generated from the calls
+    pub(crate) policies: usize,
+    /// Extracting the SDK calls of the sources
+    pub(crate) extraction: PhaseTimings,
+    /// Generating the policies, extraction included
+    pub(crate) policy_generation: PhaseTimings,
+    /// Peak resident memory of the process, where the platform reports it
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub(crate) peak_memory_bytes: Option<u64>,
+}
+
+/// How the median of a phase compares with the baseline
+#[derive(Debug, Clone, PartialEq)]
+pub(crate) struct PhaseComparison {
+    pub(crate) phase: &'static str,
+    pub(crate) baseline_seconds: f64,
+    pub(crate) current_seconds: f64,
+}
+
+impl PhaseComparison {
+    /// Change of the median, in percent of the baseline, positive if it got slower
+    pub(crate) fn change_percent(&self) -> f64 {
+        if self.baseline_seconds <= 0.0 {
+            return 0.0;
+        }
+        (self.current_seconds - self.baseline_seconds) / self.baseline_seconds * 100.0
+    }
+}
+
+/// Analyze the sources of `config` `iterations` times, timing each phase
+pub(crate) async fn run(config: &GeneratePolicyConfig, iterations: usize) -> Result<BenchReport> {
+    let mut extraction = Vec::with_capacity(iterations);
+    let mut policy_generation = Vec::with_capacity(iterations);
+    let mut source_files = 0;
+    let mut sdk_calls = 0;
+    let mut policies = 0;
+    for iteration in 1..=iterations {
+        let start = Instant::now();
+        let extracted = extract_sdk_calls(&config.extract_sdk_calls_config)
+            .await
+            .context("Failed to extract the SDK calls")?;
+        extraction.push(start.elapsed());
+
+        let start = Instant::now();
+        let result = generate_policies(config)
+            .await
+            .context("Failed to generate the policies")?;
+        policy_generation.push(start.elapsed());
+
+        log::info!(
+            "Iteration {iteration}/{iterations}: extraction {:.3}s, policy generation {:.3}s",
+            extraction[iteration - 1].as_secs_f64(),
+            policy_generation[iteration - 1].as_secs_f64()
+        );
+        source_files = extracted.metadata.source_files.len();
+        sdk_calls = extracted.methods.len();
+        policies = result.policies.len();
+    }
+    Ok(BenchReport {
+        version: env!("CARGO_PKG_VERSION").to_string(),
+        iterations,
+        source_files,
+        sdk_calls,
+        policies,
+        extraction: PhaseTimings::new(extraction),
+        policy_generation: PhaseTimings::new(policy_generation),
+        peak_memory_bytes: peak_memory_bytes(),
+    })
+}
+
+/// Read a report of a previous bench run
+pub(crate) fn load_report(path: &Path) -> Result<BenchReport> {
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read the bench report {}", path.display()))?;
+    serde_json::from_str(&content)
+        .with_context(|| format!("Invalid bench report {}", path.display()))
+}
+
+/// How the phases of `report` compare with those of `baseline`
+pub(crate) fn compare(report: &BenchReport, baseline: &BenchReport) -> Vec<PhaseComparison> {
+    [
+        ("extraction", &baseline.extraction, &report.extraction),
+        (
+            "policy generation",
+            &baseline.policy_generation,
+            &report.policy_generation,
+        ),
+    ]
+    .into_iter()
+    .map(|(phase, baseline, current)| PhaseComparison {
+        phase,
+        baseline_seconds: baseline.median_seconds,
+        current_seconds: current.median_seconds,
+    })
+    .collect()
+}
+
+/// Peak resident memory of the process, from `/proc/self/status` on Linux
+fn peak_memory_bytes() -> Option<u64> {
+    let status = std::fs::read_to_string("/proc/self/status").ok()?;
+    let kilobytes = status
+        .lines()
+        .find_map(|line| line.strip_prefix("VmHWM:"))?
+        .trim()
+        .strip_suffix("kB")?
+        .trim()
+        .parse::<u64>()
+        .ok()?;
+    Some(kilobytes * 1024)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn timings(median_seconds: f64) -> PhaseTimings {
+        PhaseTimings {
+            min_seconds: median_seconds,
+            median_seconds,
+            max_seconds: median_seconds,
+        }
+    }
+
+    fn report(extraction: f64, policy_generation: f64) -> BenchReport {
+        BenchReport {
+            version: "0.2.3".to_string(),
+            iterations: 5,
+            source_files: 10,
+            sdk_calls: 20,
+            policies: 1,
+            extraction: timings(extraction),
+            policy_generation: timings(policy_generation),
+            peak_memory_bytes: None,
+        }
+    }
+
+    #[test]
+    fn test_phase_timings() {
+        let durations = [3, 1, 2, 10].map(Duration::from_secs).to_vec();
+
+        assert_eq!(
+            PhaseTimings::new(durations),
+            PhaseTimings {
+                min_seconds: 1.0,
+                median_seconds: 2.5,
+                max_seconds: 10.0,
+            }
+        );
+        let single = PhaseTimings::new(vec![Duration::from_millis(500)]);
+        assert!((single.median_seconds - 0.5).abs() < 1e-9);
+    }
+
+    #[test]
+    fn test_compare_with_baseline() {
+        let comparisons = compare(&report(1.5, 4.0), &report(1.0, 4.0));
+
+        assert_eq!(comparisons.len(), 2);
+        assert_eq!(comparisons[0].phase, "extraction");
+        assert!((comparisons[0].change_percent() - 50.0).abs() < 1e-9);
+        assert!(comparisons[1].change_percent().abs() < 1e-9);
+    }
+
+    #[test]
+    fn test_report_round_trips() {
+        let report = report(1.0, 2.0);
+        let json = serde_json::to_string(&report).unwrap();
+
+        assert!(json.contains("\"MedianSeconds\":1.0"));
+        let read: BenchReport = serde_json::from_str(&json).unwrap();
+        assert_eq!(read.extraction, report.extraction);
+    }
+}
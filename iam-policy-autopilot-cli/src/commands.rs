@@ -7,7 +7,7 @@ use clap::crate_version;
 use iam_policy_autopilot_access_denied::{ApplyError, ApplyOptions, DenialType};
 use std::io::IsTerminal;
 
-fn is_tty() -> bool {
+pub(crate) fn is_tty() -> bool {
     std::io::stdin().is_terminal() && std::io::stderr().is_terminal()
 }
 
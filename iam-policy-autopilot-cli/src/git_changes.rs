@@ -0,0 +1,366 @@
+//! Source files changed since a git ref, or staged for commit.
+//!
+//! With `diff --diff <REF>`, the changes `git diff --name-status <REF>` lists in the
+//! repository of the source files decide what is analyzed: the source files modified or
+//! added since the ref, and the version at the ref of the modified files and of the files
+//! deleted since, whose policy generated is compared with the policy generated for the
+//! current files. The versions at the ref are read with one `git cat-file --batch` and
+//! checked out into a temporary directory, keeping their relative paths so imports
+//! between them still resolve. The `hook` subcommand skips the analysis when a commit
+//! stages none of the source files, and `changelog` analyzes the versions of all the
+//! source files at each of its refs.
+
+use std::collections::HashSet;
+use std::ffi::OsStr;
+use std::io::Write;
+use std::path::{Path, PathBuf};
+use std::process::{Command, Stdio};
+
+use anyhow::{Context, Result};
+use tempfile::TempDir;
+
+/// The changed source files and their versions at the ref
+#[derive(Debug)]
+pub(crate) struct ChangedFiles {
+    /// Source files whose content changed since the ref, including new files
+    pub(crate) current: Vec<PathBuf>,
+    /// Versions at the ref of the changed files that existed then, including the
+    /// files deleted since
+    pub(crate) previous: Vec<PathBuf>,
+    /// Directory holding the versions at the ref, removed when dropped
+    _checkout: TempDir,
+}
+
+/// The source files as of a ref
+#[derive(Debug)]
+pub(crate) struct FilesAtRef {
+    /// Versions at the ref of the source files that existed then
+    pub(crate) files: Vec<PathBuf>,
+    /// Directory holding the versions at the ref, removed when dropped
+    _checkout: TempDir,
+}
+
+/// The files of `source_files` whose content differs from their version at `git_ref`,
+/// and the versions at `git_ref` of those and of the source files deleted since
+///
+/// Deleted files count as source files when they're in the directory holding all of
+/// `source_files` and have the extension of one of them.
+pub(crate) fn changed_files(source_files: &[PathBuf], git_ref: &str) -> Result<ChangedFiles> {
+    let root = repository_root(source_files)?;
+    verify_commit(&root, git_ref)?;
+    let sources: Vec<(PathBuf, PathBuf)> = source_files
+        .iter()
+        .filter_map(|file| Some((repository_path(&root, file)?, file.clone())))
+        .collect();
+    let directory = common_directory(sources.iter().map(|(path, _)| path.as_path()));
+    let extensions: HashSet<&OsStr> = sources
+        .iter()
+        .filter_map(|(path, _)| path.extension())
+        .collect();
+
+    let changes = git(
+        &root,
+        &["diff", "--name-status", "--no-renames", "-z", git_ref, "--"],
+    )?;
+    let mut fields = changes.split('\0').filter(|field| !field.is_empty());
+    let mut current = Vec::new();
+    let mut at_ref = Vec::new();
+    while let (Some(status), Some(path)) = (fields.next(), fields.next()) {
+        let path = PathBuf::from(path);
+        let source = sources.iter().find(|(source, _)| *source == path);
+        match (status.chars().next(), source) {
+            (Some('A'), Some((_, file))) => current.push(file.clone()),
+            (Some('M' | 'T'), Some((_, file))) => {
+                current.push(file.clone());
+                at_ref.push(path);
+            }
+            (Some('D'), None)
+                if path.starts_with(&directory)
+                    && path.extension().is_some_and(|ext| extensions.contains(ext)) =>
+            {
+                at_ref.push(path);
+            }
+            _ => {}
+        }
+    }
+    // Files git doesn't track yet are new since the ref too
+    let untracked = git(&root, &["ls-files", "--others", "--exclude-standard", "-z"])?;
+    let untracked: HashSet<PathBuf> = untracked
+        .split('\0')
+        .filter(|name| !name.is_empty())
+        .map(PathBuf::from)
+        .collect();
+    current.extend(
+        sources
+            .iter()
+            .filter(|(path, _)| untracked.contains(path))
+            .map(|(_, file)| file.clone()),
+    );
+
+    let checkout = TempDir::new().context("Failed to create a directory for the ref's files")?;
+    let mut previous = Vec::new();
+    for (path, content) in at_ref.iter().zip(contents_at(&root, git_ref, &at_ref)?) {
+        let Some(content) = content else {
+            continue;
+        };
+        let previous_file = checkout.path().join(path);
+        write_version(&previous_file, &content, path, git_ref)?;
+        previous.push(previous_file);
+    }
+
+    Ok(ChangedFiles {
+        current,
+        previous,
+        _checkout: checkout,
+    })
+}
+
+/// The versions of `source_files` at `git_ref`, leaving out the files it didn't track
+pub(crate) fn files_at(source_files: &[PathBuf], git_ref: &str) -> Result<FilesAtRef> {
+    let root = repository_root(source_files)?;
+    verify_commit(&root, git_ref)?;
+    let paths: Vec<PathBuf> = source_files
+        .iter()
+        .filter_map(|file| repository_path(&root, file))
+        .collect();
+    let checkout = TempDir::new().context("Failed to create a directory for the ref's files")?;
+    let mut files = Vec::new();
+    for (path, content) in paths.iter().zip(contents_at(&root, git_ref, &paths)?) {
+        let Some(content) = content else {
+            continue;
+        };
+        let file_at_ref = checkout.path().join(path);
+        write_version(&file_at_ref, &content, path, git_ref)?;
+        files.push(file_at_ref);
+    }
+
+    Ok(FilesAtRef {
+        files,
+        _checkout: checkout,
+    })
+}
+
+/// Whether the index stages changes to any of `files`
+pub(crate) fn stages_any(files: &[&Path]) -> Result<bool> {
+    let toplevel = Command::new("git")
+        .args(["rev-parse", "--show-toplevel"])
+        .output()
+        .context("Failed to run git")?;
+    if !toplevel.status.success() {
+        anyhow::bail!("The current directory is not in a git repository");
+    }
+    let root = PathBuf::from(String::from_utf8_lossy(&toplevel.stdout).trim());
+    let root = root.canonicalize().unwrap_or(root);
+
+    let staged = Command::new("git")
+        .args(["diff", "--cached", "--name-only", "-z"])
+        .output()
+        .context("Failed to run git")?;
+    if !staged.status.success() {
+        anyhow::bail!(
+            "Failed to list the staged files: {}",
+            String::from_utf8_lossy(&staged.stderr).trim()
+        );
+    }
+    let staged: Vec<PathBuf> = String::from_utf8_lossy(&staged.stdout)
+        .split('\0')
+        .filter(|name| !name.is_empty())
+        .map(|name| root.join(name))
+        .collect();
+    Ok(files.iter().any(|file| {
+        file.canonicalize()
+            .is_ok_and(|file| staged.iter().any(|staged| *staged == file))
+    }))
+}
+
+/// Root of the git repository holding the first of `source_files`
+fn repository_root(source_files: &[PathBuf]) -> Result<PathBuf> {
+    let directory = source_files
+        .first()
+        .and_then(|file| file.parent())
+        .filter(|directory| !directory.as_os_str().is_empty())
+        .unwrap_or(Path::new("."));
+    let root = git(directory, &["rev-parse", "--show-toplevel"])
+        .with_context(|| format!("{} is not in a git repository", directory.display()))?;
+    let root = PathBuf::from(root.trim());
+    Ok(root.canonicalize().unwrap_or(root))
+}
+
+/// Path of `file` relative to the repository `root`, if it's in it
+fn repository_path(root: &Path, file: &Path) -> Option<PathBuf> {
+    let file = file.canonicalize().ok()?;
+    file.strip_prefix(root).ok().map(Path::to_path_buf)
+}
+
+/// The deepest directory holding all of `paths`
+fn common_directory<'a>(paths: impl IntoIterator<Item = &'a Path>) -> PathBuf {
+    let mut paths = paths.into_iter();
+    let Some(first) = paths.next() else {
+        return PathBuf::new();
+    };
+    let mut directory = first.parent().unwrap_or(Path::new("")).to_path_buf();
+    for path in paths {
+        while !path.starts_with(&directory) {
+            if !directory.pop() {
+                break;
+            }
+        }
+    }
+    directory
+}
+
+/// Fail unless `git_ref` names a commit of the repository `root`
+fn verify_commit(root: &Path, git_ref: &str) -> Result<()> {
+    // Refs starting with `-` would be read as options of the git commands taking them
+    if git_ref.starts_with('-')
+        || git(
+            root,
+            &[
+                "rev-parse",
+                "--verify",
+                "--quiet",
+                &format!("{git_ref}^{{commit}}"),
+            ],
+        )
+        .is_err()
+    {
+        anyhow::bail!(
+            "{git_ref} is not a commit of the git repository {}",
+            root.display()
+        );
+    }
+    Ok(())
+}
+
+/// Contents of `paths`, relative to the repository `root`, at `git_ref`, with `None`
+/// for those it didn't track
+fn contents_at(root: &Path, git_ref: &str, paths: &[PathBuf]) -> Result<Vec<Option<Vec<u8>>>> {
+    if paths.is_empty() {
+        return Ok(Vec::new());
+    }
+    let mut child = Command::new("git")
+        .arg("-C")
+        .arg(root)
+        .args(["cat-file", "--batch"])
+        .stdin(Stdio::piped())
+        .stdout(Stdio::piped())
+        .stderr(Stdio::piped())
+        .spawn()
+        .context("Failed to run git")?;
+    let mut requests = String::new();
+    for path in paths {
+        requests.push_str(&format!(
+            "{git_ref}:{}\n",
+            path.to_string_lossy().replace('\\', "/")
+        ));
+    }
+    let mut stdin = child.stdin.take().context("Failed to write to git")?;
+    // Write from another thread, so git never blocks on a full stdout pipe
+    let writer = std::thread::spawn(move || stdin.write_all(requests.as_bytes()));
+    let output = child.wait_with_output().context("Failed to run git")?;
+    writer
+        .join()
+        .map_err(|_| anyhow::anyhow!("Failed to write to git"))?
+        .context("Failed to write to git")?;
+    if !output.status.success() {
+        anyhow::bail!(
+            "git cat-file failed: {}",
+            String::from_utf8_lossy(&output.stderr).trim()
+        );
+    }
+    parse_batch(&output.stdout, paths.len())
+}
+
+/// The objects of the output of `git cat-file --batch` for `count` requests: for each,
+/// a `<oid> <type> <size>` header line followed by the content and a newline, or a
+/// `<request> missing` line
+fn parse_batch(mut output: &[u8], count: usize) -> Result<Vec<Option<Vec<u8>>>> {
+    let mut contents = Vec::with_capacity(count);
+    for _ in 0..count {
+        let end = output
+            .iter()
+            .position(|byte| *byte == b'\n')
+            .context("Truncated git cat-file output")?;
+        let header = String::from_utf8_lossy(&output[..end]).into_owned();
+        output = &output[end + 1..];
+        // `<request> missing` and `<request> ambiguous` have no content
+        let mut fields = header.rsplitn(3, ' ');
+        let (Some(size), Some(kind), Some(_)) = (fields.next(), fields.next(), fields.next())
+        else {
+            contents.push(None);
+            continue;
+        };
+        let Ok(size) = size.parse::<usize>() else {
+            contents.push(None);
+            continue;
+        };
+        let content = output
+            .get(..size)
+            .context("Truncated git cat-file output")?
+            .to_vec();
+        output = output.get(size + 1..).unwrap_or_default();
+        // Objects other than files, e.g. a directory named like a deleted file
+        contents.push((kind == "blob").then_some(content));
+    }
+    Ok(contents)
+}
+
+/// Write `content`, the version of `path` at `git_ref`, to `file`
+fn write_version(file: &Path, content: &[u8], path: &Path, git_ref: &str) -> Result<()> {
+    if let Some(parent) = file.parent() {
+        std::fs::create_dir_all(parent)?;
+    }
+    std::fs::write(file, content).with_context(|| {
+        format!(
+            "Failed to write the version of {} at {git_ref}",
+            path.display()
+        )
+    })
+}
+
+/// Run git in `directory`, returning its standard output
+fn git(directory: &Path, args: &[&str]) -> Result<String> {
+    let output = Command::new("git")
+        .arg("-C")
+        .arg(directory)
+        .args(args)
+        .output()
+        .context("Failed to run git")?;
+    if !output.status.success() {
+        anyhow::bail!(
+            "git {} failed: {}",
+            args.first().copied().unwrap_or_default(),
+            String::from_utf8_lossy(&output.stderr).trim()
+        );
+    }
+    Ok(String::from_utf8_lossy(&output.stdout).into_owned())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_common_directory() {
+        assert_eq!(
+            common_directory([Path::new("src/api/app.py"), Path::new("src/lib/util.py")]),
+            PathBuf::from("src")
+        );
+        assert_eq!(
+            common_directory([Path::new("src/app.py"), Path::new("tests/test_app.py")]),
+            PathBuf::new()
+        );
+        assert_eq!(common_directory([]), PathBuf::new());
+    }
+
+    #[test]
+    fn test_parse_batch() {
+        let output = b"0123 blob 5\nhello\nmain:gone.py missing\n4567 tree 3\nabc\n89ab blob 0\n\n";
+
+        assert_eq!(
+            parse_batch(output, 4).unwrap(),
+            vec![Some(b"hello".to_vec()), None, None, Some(Vec::new())]
+        );
+        assert!(parse_batch(b"0123 blob 5\nhel", 1).is_err());
+    }
+}
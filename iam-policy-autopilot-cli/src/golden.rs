@@ -0,0 +1,341 @@
+//! Golden files of the generated policies, as kept by `test --golden`.
+//!
+//! The golden directory holds a JSON policy document per generated policy, named after it:
+//! `policy.json` for the principal running the code, the entry point with per-entry-point
+//! policies, e.g. `cmd-server.json`, and `assumed-<role>.json` for roles the code assumes.
+//! Policies are compared with their golden file semantically, as by the diff command, so
+//! the order of statements, actions and resources and the formatting of the files don't
+//! matter.
+
+use std::collections::{BTreeMap, BTreeSet};
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::PolicyWithMetadata;
+use iam_policy_autopilot_tools::{diff_policies, PolicyDiff};
+use serde::Serialize;
+use serde_json::Value;
+
+use crate::{output, policy_documents};
+
+/// Extension of golden files; other files of the golden directory are left alone
+const GOLDEN_EXTENSION: &str = "json";
+
+/// How a generated policy compares with its golden file
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq)]
+pub(crate) enum GoldenStatus {
+    /// Both grant the same permissions
+    Matches,
+    /// The permissions differ
+    Differs,
+    /// The policy has no golden file yet
+    New,
+    /// The golden file has no generated policy anymore
+    Removed,
+}
+
+/// A generated policy compared with its golden file
+#[derive(Debug, Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct GoldenComparison {
+    /// Name of the policy
+    pub(crate) name: String,
+    /// Golden file of the policy
+    pub(crate) file: PathBuf,
+    pub(crate) status: GoldenStatus,
+    /// How the generated policy differs from the golden file, if it does
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub(crate) diff: Option<PolicyDiff>,
+}
+
+impl GoldenComparison {
+    /// Whether the generated policy doesn't match the golden file
+    pub(crate) fn is_failure(&self) -> bool {
+        self.status != GoldenStatus::Matches
+    }
+}
+
+/// The JSON documents of `policies` by the name of their golden file
+pub(crate) fn named_policies(policies: &[PolicyWithMetadata]) -> Result<BTreeMap<String, Value>> {
+    let mut named = BTreeMap::new();
+    for policy in policies {
+        let document =
+            serde_json::to_value(&policy.policy).context("Failed to serialize the policy")?;
+        let base = policy_name(policy);
+        let name = (1..)
+            .map(|index| {
+                if index == 1 {
+                    base.clone()
+                } else {
+                    format!("{base}-{index}")
+                }
+            })
+            .find(|name| !named.contains_key(name))
+            .unwrap_or(base);
+        named.insert(name, document);
+    }
+    Ok(named)
+}
+
+/// Name of the golden file of `policy`, without extension
+fn policy_name(policy: &PolicyWithMetadata) -> String {
+    let mut parts = Vec::new();
+    if let Some(principal) = &policy.named_principal {
+        parts.push(file_name_part(principal).unwrap_or_else(|| "principal".to_string()));
+    }
+    if let Some(entry_point) = &policy.entry_point {
+        parts.push(file_name_part(entry_point).unwrap_or_else(|| "root".to_string()));
+    }
+    if let Some(role) = &policy.assumed_role {
+        // The name of the role rather than its ARN, which may be a template
+        let role = role.rsplit(['/', ':']).next().unwrap_or(role);
+        parts.push(format!(
+            "assumed-{}",
+            file_name_part(role).unwrap_or_else(|| "role".to_string())
+        ));
+    }
+    if let Some(profile) = &policy.credential_profile {
+        parts.push(format!(
+            "profile-{}",
+            file_name_part(profile).unwrap_or_else(|| "profile".to_string())
+        ));
+    }
+    if parts.is_empty() {
+        "policy".to_string()
+    } else {
+        parts.join("-")
+    }
+}
+
+/// `value` with the characters file names can't portably hold replaced by `-`, if any
+/// character is left
+fn file_name_part(value: &str) -> Option<String> {
+    let part: String = value
+        .chars()
+        .map(|character| {
+            if character.is_ascii_alphanumeric() || matches!(character, '_' | '.' | '-') {
+                character
+            } else {
+                '-'
+            }
+        })
+        .collect();
+    let part = part.trim_matches(['-', '.']);
+    (!part.is_empty()).then(|| part.to_string())
+}
+
+/// The golden files of `directory` by policy name, none if it doesn't exist
+fn golden_files(directory: &Path) -> Result<BTreeMap<String, PathBuf>> {
+    if !directory.exists() {
+        return Ok(BTreeMap::new());
+    }
+    let mut files = BTreeMap::new();
+    for entry in std::fs::read_dir(directory)
+        .with_context(|| format!("Failed to list golden files in {}", directory.display()))?
+    {
+        let path = entry?.path();
+        if path.is_file()
+            && path.extension().and_then(|extension| extension.to_str()) == Some(GOLDEN_EXTENSION)
+        {
+            if let Some(name) = path.file_stem().and_then(|stem| stem.to_str()) {
+                files.insert(name.to_string(), path.clone());
+            }
+        }
+    }
+    Ok(files)
+}
+
+/// Compare the `generated` policies with the golden files of `directory`, by name
+pub(crate) fn compare(
+    directory: &Path,
+    generated: &BTreeMap<String, Value>,
+) -> Result<Vec<GoldenComparison>> {
+    let golden = golden_files(directory)?;
+    let names: BTreeSet<&String> = generated.keys().chain(golden.keys()).collect();
+    let mut comparisons = Vec::new();
+    for name in names {
+        let file = golden
+            .get(name)
+            .cloned()
+            .unwrap_or_else(|| golden_file(directory, name));
+        let (status, diff) = match (generated.get(name), golden.contains_key(name)) {
+            (Some(document), true) => {
+                let content = std::fs::read_to_string(&file)
+                    .with_context(|| format!("Failed to read golden file {}", file.display()))?;
+                let expected = policy_documents(&content)
+                    .with_context(|| format!("Invalid golden file {}", file.display()))?;
+                let diff = diff_policies(std::slice::from_ref(document), &expected);
+                if diff.is_empty() {
+                    (GoldenStatus::Matches, None)
+                } else {
+                    (GoldenStatus::Differs, Some(diff))
+                }
+            }
+            (Some(_), false) => (GoldenStatus::New, None),
+            (None, _) => (GoldenStatus::Removed, None),
+        };
+        comparisons.push(GoldenComparison {
+            name: name.clone(),
+            file,
+            status,
+            diff,
+        });
+    }
+    Ok(comparisons)
+}
+
+/// Write the `generated` policies to the golden files of `directory`, removing the golden
+/// files of policies no longer generated
+pub(crate) fn update(directory: &Path, generated: &BTreeMap<String, Value>) -> Result<()> {
+    std::fs::create_dir_all(directory)
+        .with_context(|| format!("Failed to create {}", directory.display()))?;
+    for (name, file) in golden_files(directory)? {
+        if !generated.contains_key(&name) {
+            std::fs::remove_file(&file)
+                .with_context(|| format!("Failed to remove {}", file.display()))?;
+        }
+    }
+    for (name, document) in generated {
+        let file = golden_file(directory, name);
+        let content =
+            iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(document)
+                .context("Failed to serialize the golden policy to pretty JSON")?;
+        std::fs::write(&file, format!("{content}\n"))
+            .with_context(|| format!("Failed to write golden file {}", file.display()))?;
+    }
+    output::note(&format!(
+        "Updated {} golden files in {}",
+        generated.len(),
+        directory.display()
+    ));
+    Ok(())
+}
+
+fn golden_file(directory: &Path, name: &str) -> PathBuf {
+    directory.join(format!("{name}.{GOLDEN_EXTENSION}"))
+}
+
+#[cfg(test)]
+mod tests {
+    use iam_policy_autopilot_policy_generation::{IamPolicy, PolicyType};
+    use serde_json::json;
+
+    use super::*;
+
+    fn policy(entry_point: Option<&str>, assumed_role: Option<&str>) -> PolicyWithMetadata {
+        PolicyWithMetadata {
+            policy: IamPolicy::new(),
+            policy_type: PolicyType::Identity,
+            assumed_role: assumed_role.map(ToString::to_string),
+            credential_profile: None,
+            entry_point: entry_point.map(ToString::to_string),
+            named_principal: None,
+        }
+    }
+
+    #[test]
+    fn test_named_policies() {
+        let policies = vec![
+            policy(None, None),
+            policy(Some("cmd/server"), None),
+            policy(
+                Some("cmd/server"),
+                Some("arn:aws:iam::123456789012:role/Reader"),
+            ),
+            policy(Some("."), None),
+            policy(None, None),
+        ];
+
+        let named = named_policies(&policies).unwrap();
+
+        assert_eq!(
+            named.keys().collect::<Vec<_>>(),
+            vec![
+                "cmd-server",
+                "cmd-server-assumed-Reader",
+                "policy",
+                "policy-2",
+                "root"
+            ]
+        );
+    }
+
+    #[test]
+    fn test_compare_ignores_order_and_formatting() {
+        let directory = tempfile::tempdir().unwrap();
+        std::fs::write(
+            directory.path().join("policy.json"),
+            r#"{"Version": "2012-10-17", "Statement": [
+                {"Effect": "Allow", "Action": ["s3:PutObject", "s3:GetObject"],
+                 "Resource": "*"}]}"#,
+        )
+        .unwrap();
+        std::fs::write(directory.path().join("worker.json"), "{\"Statement\": []}").unwrap();
+        std::fs::write(directory.path().join("README.md"), "Golden policies\n").unwrap();
+        let generated = BTreeMap::from([
+            (
+                "policy".to_string(),
+                json!({"Version": "2012-10-17", "Statement": [
+                    {"Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"},
+                    {"Effect": "Allow", "Action": "s3:PutObject", "Resource": "*"}]}),
+            ),
+            (
+                "api".to_string(),
+                json!({"Version": "2012-10-17", "Statement": []}),
+            ),
+        ]);
+
+        let comparisons = compare(directory.path(), &generated).unwrap();
+
+        let statuses: Vec<(&str, GoldenStatus)> = comparisons
+            .iter()
+            .map(|comparison| (comparison.name.as_str(), comparison.status))
+            .collect();
+        assert_eq!(
+            statuses,
+            vec![
+                ("api", GoldenStatus::New),
+                ("policy", GoldenStatus::Matches),
+                ("worker", GoldenStatus::Removed)
+            ]
+        );
+    }
+
+    #[test]
+    fn test_compare_reports_differences() {
+        let directory = tempfile::tempdir().unwrap();
+        std::fs::write(
+            directory.path().join("policy.json"),
+            r#"{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}]}"#,
+        )
+        .unwrap();
+        let generated = BTreeMap::from([(
+            "policy".to_string(),
+            json!({"Statement": [{"Effect": "Allow", "Action": "s3:PutObject", "Resource": "*"}]}),
+        )]);
+
+        let comparisons = compare(directory.path(), &generated).unwrap();
+
+        assert_eq!(comparisons[0].status, GoldenStatus::Differs);
+        let diff = comparisons[0].diff.as_ref().unwrap();
+        assert_eq!(diff.missing_actions, vec!["s3:PutObject"]);
+        assert_eq!(diff.extra_actions, vec!["s3:GetObject"]);
+    }
+
+    #[test]
+    fn test_update_writes_and_removes_golden_files() {
+        let directory = tempfile::tempdir().unwrap();
+        std::fs::write(directory.path().join("stale.json"), "{}").unwrap();
+        let generated = BTreeMap::from([(
+            "policy".to_string(),
+            json!({"Version": "2012-10-17", "Statement": []}),
+        )]);
+
+        update(directory.path(), &generated).unwrap();
+
+        assert!(!directory.path().join("stale.json").exists());
+        let comparisons = compare(directory.path(), &generated).unwrap();
+        assert_eq!(comparisons.len(), 1);
+        assert_eq!(comparisons[0].status, GoldenStatus::Matches);
+    }
+}
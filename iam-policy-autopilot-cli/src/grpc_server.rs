@@ -0,0 +1,335 @@
+//! gRPC API streaming the analysis of large repositories.
+//!
+//! The HTTP API of [`http_server`](crate::http_server) is polled for the result of a job,
+//! which says nothing of how far the analysis got. `Analyze` instead streams an event per
+//! analyzed file, and the policies of each language of the sources as soon as they're
+//! generated, so orchestration systems can show live progress. Cancelling the call stops
+//! the analysis. See `proto/policy_analysis.proto` for the messages.
+
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+use std::pin::Pin;
+use std::sync::Arc;
+
+use anyhow::{Context, Result};
+use futures::Stream;
+use iam_policy_autopilot_policy_generation::api::generate_policies;
+use iam_policy_autopilot_policy_generation::api::model::{AwsContext, GeneratePolicyConfig};
+use iam_policy_autopilot_policy_generation::{Language, ProgressObserver};
+use log::{info, warn};
+use tempfile::TempDir;
+use tokio::sync::{mpsc, Semaphore};
+use tokio::task::JoinSet;
+use tonic::{Request, Response, Status};
+
+use crate::http_server::{
+    allowed_path, extract_archive, shutdown_signal, JobOutput, MAX_ARCHIVE_BYTES,
+};
+use crate::{default_generate_config, output, remote_sources, ServeCliConfig, SharedConfig};
+
+// Generated messages and service trait; the server doesn't use every generated item
+#[allow(
+    dead_code,
+    clippy::all,
+    clippy::pedantic,
+    clippy::nursery,
+    clippy::unwrap_used
+)]
+mod proto {
+    tonic::include_proto!("iam_policy_autopilot.v1");
+}
+
+use proto::analyze_event::Event;
+use proto::analyze_request::Sources;
+use proto::policy_analysis_server::{PolicyAnalysis, PolicyAnalysisServer};
+use proto::{AnalyzeEvent, AnalyzeRequest, LanguageResult};
+
+/// Events buffered for a client reading them slower than the analysis produces them
+const EVENT_BUFFER: usize = 64;
+
+type EventSender = mpsc::Sender<Result<AnalyzeEvent, Status>>;
+
+/// Serve the gRPC API on `port` until interrupted, running at most as many analyses at
+/// once as `running` has permits
+pub(crate) async fn serve(
+    config: ServeCliConfig,
+    port: u16,
+    running: Arc<Semaphore>,
+) -> Result<()> {
+    let address = format!("{}:{port}", config.bind_address);
+    let socket_address = tokio::net::lookup_host(&address)
+        .await
+        .ok()
+        .and_then(|mut addresses| addresses.next())
+        .with_context(|| format!("Failed to resolve {address}"))?;
+    let analyzer = Analyzer {
+        config: Arc::new(config),
+        running,
+    };
+
+    output::note(&format!("Listening for gRPC on {address}"));
+    tonic::transport::Server::builder()
+        .add_service(
+            PolicyAnalysisServer::new(analyzer).max_decoding_message_size(MAX_ARCHIVE_BYTES),
+        )
+        .serve_with_shutdown(socket_address, shutdown_signal())
+        .await
+        .with_context(|| format!("The gRPC server on {address} failed"))
+}
+
+struct Analyzer {
+    config: Arc<ServeCliConfig>,
+    running: Arc<Semaphore>,
+}
+
+#[tonic::async_trait]
+impl PolicyAnalysis for Analyzer {
+    type AnalyzeStream = Pin<Box<dyn Stream<Item = Result<AnalyzeEvent, Status>> + Send>>;
+
+    async fn analyze(
+        &self,
+        request: Request<AnalyzeRequest>,
+    ) -> Result<Response<Self::AnalyzeStream>, Status> {
+        let request = request.into_inner();
+        if request.sources.is_none() {
+            return Err(Status::invalid_argument(
+                "Pass either the path of the sources or a source archive",
+            ));
+        }
+
+        let (events, receiver) = mpsc::channel(EVENT_BUFFER);
+        // The stream owns the analysis: dropping it when the client cancels the call
+        // drops the set, which aborts the analysis
+        let mut analysis = JoinSet::new();
+        analysis.spawn(run_analysis(
+            Arc::clone(&self.config),
+            Arc::clone(&self.running),
+            request,
+            events,
+        ));
+        let stream = futures::stream::unfold(
+            (receiver, analysis),
+            |(mut receiver, analysis)| async move {
+                let event = receiver.recv().await?;
+                Some((event, (receiver, analysis)))
+            },
+        );
+        Ok(Response::new(Box::pin(stream)))
+    }
+}
+
+/// Analyze the sources of `request`, ending the stream of `events` with the error the
+/// analysis failed with, if any
+async fn run_analysis(
+    config: Arc<ServeCliConfig>,
+    running: Arc<Semaphore>,
+    request: AnalyzeRequest,
+    events: EventSender,
+) {
+    if let Err(status) = analyze(&config, &running, request, &events).await {
+        warn!("Analysis failed: {}", status.message());
+        // Nothing is left to do if the client went away
+        let _ = events.send(Err(status)).await;
+    }
+}
+
+async fn analyze(
+    config: &ServeCliConfig,
+    running: &Semaphore,
+    request: AnalyzeRequest,
+    events: &EventSender,
+) -> Result<(), Status> {
+    let _permit = match running.try_acquire() {
+        Ok(permit) => permit,
+        Err(_) => {
+            send(events, Event::Queued(proto::Queued {})).await;
+            running
+                .acquire()
+                .await
+                .map_err(|_| Status::unavailable("The server is shutting down"))?
+        }
+    };
+
+    let sources = request
+        .sources
+        .ok_or_else(|| Status::invalid_argument("No sources to analyze"))?;
+    let sources = match sources {
+        Sources::Path(path) => Sources::Path(
+            allowed_path(config.allow_path_root.as_deref(), Path::new(&path))
+                .map_err(|error| Status::permission_denied(format!("{error:#}")))?
+                .display()
+                .to_string(),
+        ),
+        archive @ Sources::Archive(_) => archive,
+    };
+    let language = request.language.clone();
+    // Extracting and listing the sources blocks on the file system
+    let (extracted, by_language) =
+        tokio::task::spawn_blocking(move || analysis_sources(sources, language.as_deref()))
+            .await
+            .map_err(|error| Status::internal(format!("Failed to prepare the sources: {error}")))?
+            .map_err(|error| Status::invalid_argument(format!("{error:#}")))?;
+    let aws_context = AwsContext::with_partition(
+        request
+            .partition
+            .or_else(|| config.partition.partition.clone()),
+        request.region.unwrap_or_else(|| config.region.clone()),
+        request.account.unwrap_or_else(|| config.account.clone()),
+    )
+    .map_err(|error| Status::invalid_argument(format!("{error:#}")))?;
+    let service_hints = Some(request.service_hints)
+        .filter(|hints| !hints.is_empty())
+        .or_else(|| config.shared.service_hints.clone());
+
+    for (language, source_files) in by_language {
+        info!("Analyzing {} {language} source files", source_files.len());
+        let shared = SharedConfig {
+            source_files,
+            language: Some(language.clone()),
+            service_hints: service_hints.clone(),
+            ..config.shared.clone()
+        };
+        let mut generate_config = GeneratePolicyConfig {
+            action_provenance: true,
+            ..default_generate_config(&shared, aws_context.clone())
+        };
+        generate_config.extract_sdk_calls_config.progress = Some(Arc::new(StreamedProgress {
+            language: language.clone(),
+            root: extracted
+                .as_ref()
+                .map(|directory| directory.path().to_path_buf()),
+            events: events.clone(),
+        }));
+
+        let output = generate_policies(&generate_config)
+            .await
+            .and_then(|result| JobOutput::new(&result))
+            .map_err(|error| {
+                Status::internal(format!(
+                    "Failed to analyze the {language} source files: {error:#}"
+                ))
+            })?;
+        send(
+            events,
+            Event::Result(LanguageResult {
+                language,
+                policies: output.policy.to_string(),
+                provenance: output.provenance.to_string(),
+            }),
+        )
+        .await;
+    }
+    Ok(())
+}
+
+/// Source files to analyze by language, under the path of `sources` or in its archive,
+/// with the directory the archive was extracted to
+fn analysis_sources(
+    sources: Sources,
+    language: Option<&str>,
+) -> Result<(Option<TempDir>, BTreeMap<String, Vec<PathBuf>>)> {
+    let (extracted, mut by_language) = match sources {
+        Sources::Path(path) => (
+            None,
+            remote_sources::directory_files_by_language(Path::new(&path))?,
+        ),
+        Sources::Archive(archive) => {
+            let extracted = extract_archive(&archive)?;
+            let by_language = remote_sources::directory_files_by_language(extracted.path())?;
+            (Some(extracted), by_language)
+        }
+    };
+    if let Some(language) = language {
+        let language = Language::try_from_str(language)?.to_string();
+        by_language.retain(|file_language, _| *file_language == language);
+    }
+    anyhow::ensure!(
+        !by_language.is_empty(),
+        "The sources have no file to analyze in a supported language"
+    );
+    Ok((extracted, by_language))
+}
+
+/// Send `event` to the client, unless it went away
+async fn send(events: &EventSender, event: Event) {
+    let _ = events.send(Ok(AnalyzeEvent { event: Some(event) })).await;
+}
+
+/// Streams the files analyzed for a language to the client
+#[derive(Debug)]
+struct StreamedProgress {
+    language: String,
+    /// Directory the archive of the sources was extracted to, if one was uploaded
+    root: Option<PathBuf>,
+    events: EventSender,
+}
+
+impl ProgressObserver for StreamedProgress {
+    fn file_analyzed(&self, path: &Path, done: usize, total: usize) {
+        let file = self
+            .root
+            .as_deref()
+            .and_then(|root| path.strip_prefix(root).ok())
+            .unwrap_or(path);
+        let event = Event::Progress(proto::Progress {
+            language: self.language.clone(),
+            file: file.display().to_string(),
+            analyzed: u64::try_from(done).unwrap_or(u64::MAX),
+            total: u64::try_from(total).unwrap_or(u64::MAX),
+        });
+        // Progress is dropped rather than waited for when the client lags behind, so a
+        // slow client doesn't hold up the analysis
+        let _ = self
+            .events
+            .try_send(Ok(AnalyzeEvent { event: Some(event) }));
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_analysis_sources_by_language() {
+        let root = tempfile::tempdir().unwrap();
+        std::fs::write(root.path().join("handler.py"), "import boto3\n").unwrap();
+        std::fs::write(root.path().join("main.go"), "package main\n").unwrap();
+        let path = root.path().display().to_string();
+
+        let (extracted, by_language) = analysis_sources(Sources::Path(path.clone()), None).unwrap();
+        assert!(extracted.is_none());
+        assert_eq!(by_language.keys().collect::<Vec<_>>(), vec!["go", "python"]);
+
+        let (_, by_language) = analysis_sources(Sources::Path(path.clone()), Some("go")).unwrap();
+        assert_eq!(by_language["go"], vec![root.path().join("main.go")]);
+        assert_eq!(by_language.len(), 1);
+
+        assert!(analysis_sources(Sources::Path(path), Some("java")).is_err());
+    }
+
+    #[test]
+    fn test_streamed_progress_is_relative_to_the_archive() {
+        let (events, mut receiver) = mpsc::channel(1);
+        let progress = StreamedProgress {
+            language: "python".to_string(),
+            root: Some(PathBuf::from("/tmp/sources")),
+            events,
+        };
+
+        progress.file_analyzed(Path::new("/tmp/sources/app/handler.py"), 1, 2);
+        // Dropped as the client hasn't read the previous event
+        progress.file_analyzed(Path::new("/tmp/sources/app/models.py"), 2, 2);
+
+        let event = receiver.try_recv().unwrap().unwrap();
+        assert_eq!(
+            event.event,
+            Some(Event::Progress(proto::Progress {
+                language: "python".to_string(),
+                file: PathBuf::from("app/handler.py").display().to_string(),
+                analyzed: 1,
+                total: 2,
+            }))
+        );
+        assert!(receiver.try_recv().is_err());
+    }
+}
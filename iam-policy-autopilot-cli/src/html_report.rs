@@ -0,0 +1,302 @@
+//! Standalone HTML report of the generated policies, for least-privilege reviews.
+//!
+//! Security reviewers approving a policy seldom run the CLI. The report is a single file
+//! without external resources, to open in a browser or attach to a change request: each
+//! statement of the policies with the calls requiring its actions and the sensitive
+//! actions it grants, followed by how completely the call sites of the code were resolved.
+
+use std::fmt::Write as _;
+use std::path::Path;
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::api::model::GeneratePoliciesResult;
+use iam_policy_autopilot_policy_generation::{
+    ActionProvenance, CoverageCounts, CoverageReport, Location, PolicyWithMetadata, SensitiveAction,
+};
+use serde_json::Value;
+
+/// Style of the report, inlined so the file is self-contained
+const STYLE: &str = "body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.4em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+code, pre { font-family: monospace; font-size: 0.9em; }
+pre { margin: 0; white-space: pre-wrap; }
+ul { margin: 0; padding-left: 1.2em; }
+.Critical { color: #a00; font-weight: bold; }
+.High { color: #c50; font-weight: bold; }
+.Medium { color: #870; }";
+
+/// Escape `text` for HTML element content and attribute values
+fn escape(text: &str) -> String {
+    let mut escaped = String::with_capacity(text.len());
+    for character in text.chars() {
+        match character {
+            '&' => escaped.push_str("&amp;"),
+            '<' => escaped.push_str("&lt;"),
+            '>' => escaped.push_str("&gt;"),
+            '"' => escaped.push_str("&quot;"),
+            '\'' => escaped.push_str("&#39;"),
+            _ => escaped.push(character),
+        }
+    }
+    escaped
+}
+
+/// The strings of a statement element, which is a string or a list of them
+fn strings(value: &Value) -> Vec<&str> {
+    match value {
+        Value::Array(items) => items.iter().filter_map(Value::as_str).collect(),
+        item => item.as_str().into_iter().collect(),
+    }
+}
+
+/// The escaped `items` as a list, each in a `<code>` element
+fn code_list<'a>(items: impl IntoIterator<Item = &'a str>) -> String {
+    let items: String = items
+        .into_iter()
+        .map(|item| format!("<li><code>{}</code></li>", escape(item)))
+        .collect();
+    format!("<ul>{items}</ul>")
+}
+
+fn location(location: &Location) -> String {
+    format!(
+        "{}:{}:{}",
+        location.file_path.display(),
+        location.start_position.0,
+        location.start_position.1
+    )
+}
+
+/// Heading of the policy at `index`, naming the principal it's for
+fn policy_heading(index: usize, policy: &PolicyWithMetadata) -> String {
+    let mut heading = format!("Policy {}", index + 1);
+    for (label, value) in [
+        ("principal", &policy.named_principal),
+        ("entry point", &policy.entry_point),
+        ("assumed role", &policy.assumed_role),
+        ("profile", &policy.credential_profile),
+    ] {
+        if let Some(value) = value {
+            let _ = write!(heading, ", {label} {value}");
+        }
+    }
+    escape(&heading)
+}
+
+fn coverage_row(name: &str, counts: &CoverageCounts) -> String {
+    let resolved = if counts.call_sites == 0 {
+        "-".to_string()
+    } else {
+        format!("{}%", counts.resolved * 100 / counts.call_sites)
+    };
+    format!(
+        "<tr><td>{}</td><td>{}</td><td>{}</td><td>{}</td><td>{}</td><td>{resolved}</td></tr>\n",
+        escape(name),
+        counts.call_sites,
+        counts.resolved,
+        counts.partially_resolved,
+        counts.skipped
+    )
+}
+
+/// Render the review report of `policies`, with the calls requiring their actions, their
+/// sensitive actions and the coverage of the run
+pub(crate) fn render(
+    policies: &[PolicyWithMetadata],
+    provenance: &[ActionProvenance],
+    sensitive_actions: &[SensitiveAction],
+    coverage: Option<&CoverageReport>,
+) -> Result<String> {
+    let mut html = format!(
+        "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n\
+         <title>IAM Policy Autopilot least-privilege review</title>\n\
+         <style>\n{STYLE}\n</style>\n</head>\n<body>\n\
+         <h1>IAM Policy Autopilot least-privilege review</h1>\n\
+         <p>Generated by IAM Policy Autopilot {}: {} policies, {} sensitive actions.</p>\n",
+        env!("CARGO_PKG_VERSION"),
+        policies.len(),
+        sensitive_actions.len()
+    );
+
+    for (policy_index, policy) in policies.iter().enumerate() {
+        let document =
+            serde_json::to_value(&policy.policy).context("Failed to serialize policy document")?;
+        let _ = write!(
+            html,
+            "<h2>{}</h2>\n<table>\n<tr><th>Sid</th><th>Effect</th><th>Actions</th>\
+             <th>Resources</th><th>Conditions</th><th>Required by</th>\
+             <th>Sensitive actions</th></tr>\n",
+            policy_heading(policy_index, policy)
+        );
+        for statement in document["Statement"].as_array().into_iter().flatten() {
+            let actions = strings(&statement["Action"]);
+            let resources = strings(&statement["Resource"]);
+            let conditions = match statement.get("Condition") {
+                Some(condition) => format!(
+                    "<pre>{}</pre>",
+                    escape(
+                        &serde_json::to_string_pretty(condition)
+                            .context("Failed to serialize statement conditions")?
+                    )
+                ),
+                None => String::new(),
+            };
+            let calls: String = provenance
+                .iter()
+                .filter(|provenance| actions.contains(&provenance.action.as_str()))
+                .flat_map(|provenance| &provenance.calls)
+                .map(|call| {
+                    format!(
+                        "<li><code>{}</code><br><code>{}</code></li>",
+                        escape(&location(&call.location)),
+                        escape(&call.expression)
+                    )
+                })
+                .collect();
+            let sensitive: String = sensitive_actions
+                .iter()
+                .filter(|finding| {
+                    finding.policy_index == policy_index
+                        && actions.contains(&finding.action.as_str())
+                        && finding.resources == resources
+                })
+                .map(|finding| {
+                    let severity = format!("{:?}", finding.severity);
+                    format!(
+                        "<li><span class=\"{severity}\">{severity}</span> <code>{}</code>: {}</li>",
+                        escape(&finding.action),
+                        escape(&finding.reason)
+                    )
+                })
+                .collect();
+            let _ = writeln!(
+                html,
+                "<tr><td>{}</td><td>{}</td><td>{}</td><td>{}</td><td>{conditions}</td>\
+                 <td><ul>{calls}</ul></td><td><ul>{sensitive}</ul></td></tr>",
+                escape(statement["Sid"].as_str().unwrap_or_default()),
+                escape(statement["Effect"].as_str().unwrap_or_default()),
+                code_list(actions.iter().copied()),
+                code_list(resources.iter().copied()),
+            );
+        }
+        html.push_str("</table>\n");
+    }
+
+    if let Some(coverage) = coverage {
+        html.push_str(
+            "<h2>Coverage</h2>\n<table>\n<tr><th>Scope</th><th>Call sites</th>\
+             <th>Resolved</th><th>Partially resolved</th><th>Skipped</th>\
+             <th>Resolved share</th></tr>\n",
+        );
+        html.push_str(&coverage_row("Total", &coverage.total));
+        for language in &coverage.languages {
+            html.push_str(&coverage_row(
+                &language.language.to_string(),
+                &language.counts,
+            ));
+        }
+        for file in &coverage.files {
+            html.push_str(&coverage_row(
+                &file.file.display().to_string(),
+                &file.counts,
+            ));
+        }
+        html.push_str("</table>\n");
+    }
+
+    html.push_str("</body>\n</html>\n");
+    Ok(html)
+}
+
+/// Write the review report of the generated policies of `result` to `path`
+pub(crate) fn write_html_report(result: &GeneratePoliciesResult, path: &Path) -> Result<()> {
+    let html = render(
+        &result.policies,
+        result.action_provenance.as_deref().unwrap_or_default(),
+        result.sensitive_actions.as_deref().unwrap_or_default(),
+        result.coverage.as_ref(),
+    )?;
+    std::fs::write(path, html)
+        .with_context(|| format!("Failed to write HTML report {}", path.display()))?;
+    crate::output::note(&format!(
+        "Wrote the review report of {} policies to {}",
+        result.policies.len(),
+        path.display()
+    ));
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use iam_policy_autopilot_policy_generation::{
+        CallSite, IamPolicy, PolicyType, Severity, Statement,
+    };
+
+    use super::*;
+
+    #[test]
+    fn test_report_lists_the_provenance_and_sensitive_actions_of_statements() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["iam:PassRole".to_string()],
+            vec!["*".to_string()],
+        ));
+        let policies = [PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: Some("worker-role".to_string()),
+        }];
+        let location = Location::new(PathBuf::from("app.py"), (12, 5), (12, 60));
+        let provenance = [ActionProvenance {
+            action: "iam:PassRole".to_string(),
+            resources: vec!["*".to_string()],
+            calls: vec![CallSite {
+                location: location.clone(),
+                expression: "ec2.run_instances(IamInstanceProfile={'Arn': arn})".to_string(),
+            }],
+        }];
+        let sensitive_actions = [SensitiveAction {
+            policy_index: 0,
+            sid: None,
+            action: "iam:PassRole".to_string(),
+            resources: vec!["*".to_string()],
+            severity: Severity::Critical,
+            reason: "Can pass any role to a service".to_string(),
+            locations: vec![location],
+        }];
+        let coverage = CoverageReport {
+            total: CoverageCounts {
+                call_sites: 4,
+                resolved: 3,
+                partially_resolved: 0,
+                skipped: 1,
+            },
+            languages: vec![],
+            files: vec![],
+        };
+
+        let html = render(&policies, &provenance, &sensitive_actions, Some(&coverage)).unwrap();
+
+        assert!(html.contains("<h2>Policy 1, principal worker-role</h2>"));
+        assert!(html.contains("<code>app.py:12:5</code>"));
+        assert!(html.contains("ec2.run_instances(IamInstanceProfile={&#39;Arn&#39;: arn})"));
+        assert!(html.contains("<span class=\"Critical\">Critical</span>"));
+        assert!(html.contains("<td>Total</td><td>4</td><td>3</td><td>0</td><td>1</td>"));
+        assert!(html.contains("<td>75%</td>"));
+    }
+
+    #[test]
+    fn test_escape() {
+        assert_eq!(
+            escape(r#"<a href="x">&'"#),
+            "&lt;a href=&quot;x&quot;&gt;&amp;&#39;"
+        );
+    }
+}
@@ -0,0 +1,671 @@
+//! HTTP API generating policies as a service.
+//!
+//! `serve` keeps a process running so callers such as an internal portal don't pay the
+//! CLI's startup cost on each request: the service definitions are loaded once at
+//! startup, and the caches an analysis fills are still warm for the next one. Jobs run in
+//! the background and are polled for:
+//!
+//! - `POST /jobs` submits a job analyzing the tarball of sources in the request body, or
+//!   the directory or file the `path` query parameter names under `--allow-path-root`
+//! - `GET /jobs/{id}` returns the status of a job
+//! - `GET /jobs/{id}/policy` returns the policies a job generated, in the JSON output
+//!   format of generate-policies
+//! - `GET /jobs/{id}/provenance` returns the calls requiring each generated action, in the
+//!   format of generate-policies --provenance
+//!
+//! With `--grpc-port`, the gRPC API of [`grpc_server`](crate::grpc_server) is served
+//! alongside, sharing the limit on the jobs running at once.
+
+use std::collections::{HashMap, VecDeque};
+use std::io::Read;
+use std::path::{Component, Path, PathBuf};
+use std::sync::{Arc, Mutex};
+
+use anyhow::{Context, Result};
+use axum::body::Bytes;
+use axum::extract::{DefaultBodyLimit, Path as UrlPath, Query, State};
+use axum::http::{header, StatusCode};
+use axum::response::{IntoResponse, Response};
+use axum::routing::{get, post};
+use axum::{Json, Router};
+use iam_policy_autopilot_policy_generation::api::model::{
+    AwsContext, GeneratePoliciesResult, GeneratePolicyConfig,
+};
+use iam_policy_autopilot_policy_generation::api::{generate_policies, preload_service_data};
+use iam_policy_autopilot_policy_generation::Language;
+use log::{info, warn};
+use serde::{Deserialize, Serialize};
+use tempfile::TempDir;
+use tokio::sync::Semaphore;
+
+use crate::{
+    default_generate_config, grpc_server, output, remote_sources, ServeCliConfig, SharedConfig,
+};
+
+/// Largest source archive accepted in a request body
+pub(crate) const MAX_ARCHIVE_BYTES: usize = 256 * 1024 * 1024;
+
+/// Largest total size of the entries of a source archive once decompressed
+pub(crate) const MAX_UNPACKED_BYTES: u64 = 1024 * 1024 * 1024;
+
+/// Number of finished jobs kept for polling; older ones are forgotten
+const RETAINED_JOBS: usize = 1000;
+
+/// Languages whose service definitions are loaded at startup
+const PRELOADED_LANGUAGES: [Language; 5] = [
+    Language::Python,
+    Language::Go,
+    Language::JavaScript,
+    Language::TypeScript,
+    Language::Java,
+];
+
+/// Options of a submitted job, as query parameters
+#[derive(Debug, Clone, Default, Deserialize)]
+struct JobRequest {
+    /// Directory or file under `--allow-path-root` to analyze, unless an archive is uploaded
+    path: Option<PathBuf>,
+    /// Language of the source files to analyze, if they're in several
+    language: Option<String>,
+    /// AWS region, the server's by default
+    region: Option<String>,
+    /// AWS account ID, the server's by default
+    account: Option<String>,
+    /// AWS partition, the server's by default
+    partition: Option<String>,
+    /// Comma-separated services to filter the analyzed SDK calls to
+    service_hints: Option<String>,
+}
+
+/// Status of a job
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq)]
+enum JobStatus {
+    /// Waiting for other jobs to finish
+    Queued,
+    /// Being analyzed
+    Running,
+    /// Finished with generated policies
+    Succeeded,
+    /// Finished with an error
+    Failed,
+}
+
+/// What a job generated
+#[derive(Debug)]
+pub(crate) struct JobOutput {
+    /// The JSON output of generate-policies
+    pub(crate) policy: serde_json::Value,
+    /// The calls requiring each generated action, under `Actions`
+    pub(crate) provenance: serde_json::Value,
+}
+
+impl JobOutput {
+    /// The output of policies generated with their action provenance
+    pub(crate) fn new(result: &GeneratePoliciesResult) -> Result<Self> {
+        let provenance = serde_json::json!({
+            "Actions": result.action_provenance.as_deref().unwrap_or_default()
+        });
+        let policy =
+            serde_json::to_value(result).context("Failed to serialize the generated policies")?;
+        Ok(Self { policy, provenance })
+    }
+}
+
+#[derive(Debug)]
+struct Job {
+    status: JobStatus,
+    /// Why the job failed
+    error: Option<String>,
+    /// What the job generated, once it succeeded
+    output: Option<JobOutput>,
+}
+
+/// Status of a job, as returned by the API
+#[derive(Debug, Serialize)]
+#[serde(rename_all = "PascalCase")]
+struct JobResponse<'a> {
+    job_id: &'a str,
+    status: JobStatus,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    error: Option<&'a str>,
+}
+
+/// The submitted jobs, and the order the retained ones finished in
+#[derive(Debug, Default)]
+struct Jobs {
+    by_id: HashMap<String, Job>,
+    finished: VecDeque<String>,
+    /// Number of jobs queued or running
+    unfinished: usize,
+}
+
+impl Jobs {
+    /// Queue a new job, unless `max_unfinished` jobs are already queued or running, as
+    /// each holds its sources in memory until it finishes
+    fn queue(&mut self, max_unfinished: usize) -> Option<String> {
+        if self.unfinished >= max_unfinished {
+            return None;
+        }
+        let id = uuid::Uuid::new_v4().to_string();
+        self.by_id.insert(
+            id.clone(),
+            Job {
+                status: JobStatus::Queued,
+                error: None,
+                output: None,
+            },
+        );
+        self.unfinished += 1;
+        Some(id)
+    }
+
+    fn set_running(&mut self, id: &str) {
+        if let Some(job) = self.by_id.get_mut(id) {
+            job.status = JobStatus::Running;
+        }
+    }
+
+    /// Record the result of the job `id`, forgetting the oldest finished jobs beyond
+    /// [`RETAINED_JOBS`]
+    fn finish(&mut self, id: &str, result: Result<JobOutput>) {
+        if let Some(job) = self.by_id.get_mut(id) {
+            self.unfinished -= 1;
+            match result {
+                Ok(output) => {
+                    job.status = JobStatus::Succeeded;
+                    job.output = Some(output);
+                }
+                Err(error) => {
+                    job.status = JobStatus::Failed;
+                    job.error = Some(format!("{error:#}"));
+                }
+            }
+        }
+        self.finished.push_back(id.to_string());
+        while self.finished.len() > RETAINED_JOBS {
+            if let Some(oldest) = self.finished.pop_front() {
+                self.by_id.remove(&oldest);
+            }
+        }
+    }
+}
+
+struct ServerState {
+    config: ServeCliConfig,
+    jobs: Mutex<Jobs>,
+    /// Limits the jobs running at once; the others are queued
+    running: Arc<Semaphore>,
+}
+
+impl ServerState {
+    fn jobs(&self) -> std::sync::MutexGuard<'_, Jobs> {
+        self.jobs.lock().expect("jobs mutex poisoned")
+    }
+}
+
+/// Serve the HTTP API until interrupted
+pub(crate) async fn serve(config: ServeCliConfig) -> Result<()> {
+    preload_service_data(&PRELOADED_LANGUAGES)
+        .await
+        .context("Failed to preload the service definitions")?;
+
+    let address = format!("{}:{}", config.bind_address, config.port);
+    let running = Arc::new(Semaphore::new(config.max_concurrent_jobs));
+    let grpc = config
+        .grpc_port
+        .map(|port| grpc_server::serve(config.clone(), port, Arc::clone(&running)));
+    let state = Arc::new(ServerState {
+        running,
+        config,
+        jobs: Mutex::default(),
+    });
+    let router = Router::new()
+        .route("/health", get(|| async { "OK" }))
+        .route("/jobs", post(submit_job))
+        .route("/jobs/{id}", get(job_status))
+        .route("/jobs/{id}/policy", get(job_policy))
+        .route("/jobs/{id}/provenance", get(job_provenance))
+        .layer(DefaultBodyLimit::max(MAX_ARCHIVE_BYTES))
+        .with_state(state);
+
+    let listener = tokio::net::TcpListener::bind(&address)
+        .await
+        .with_context(|| format!("Failed to listen on {address}"))?;
+    output::note(&format!("Listening on http://{address}"));
+    let http = async {
+        axum::serve(listener, router)
+            .with_graceful_shutdown(shutdown_signal())
+            .await
+            .context("The HTTP server failed")
+    };
+    match grpc {
+        Some(grpc) => {
+            tokio::try_join!(http, grpc)?;
+        }
+        None => http.await?,
+    }
+    Ok(())
+}
+
+/// Resolves once the server is interrupted
+pub(crate) async fn shutdown_signal() {
+    if let Err(error) = tokio::signal::ctrl_c().await {
+        warn!("Failed to listen for CTRL+C: {error}");
+    }
+    info!("Received shutdown signal");
+}
+
+/// `POST /jobs`: queue a job analyzing `path` or the archive of the request body, unless
+/// the queue is full
+async fn submit_job(
+    State(state): State<Arc<ServerState>>,
+    Query(mut request): Query<JobRequest>,
+    archive: Bytes,
+) -> Response {
+    if archive.is_empty() == request.path.is_none() {
+        return error_response(
+            StatusCode::BAD_REQUEST,
+            "Pass either the path query parameter or a source archive as the request body",
+        );
+    }
+    if let Some(path) = &request.path {
+        match allowed_path(state.config.allow_path_root.as_deref(), path) {
+            Ok(path) => request.path = Some(path),
+            Err(error) => return error_response(StatusCode::FORBIDDEN, &format!("{error:#}")),
+        }
+    }
+
+    let max_unfinished = state.config.max_concurrent_jobs + state.config.max_queued_jobs;
+    let Some(id) = state.jobs().queue(max_unfinished) else {
+        return error_response(
+            StatusCode::SERVICE_UNAVAILABLE,
+            "Too many jobs are queued; retry once some have finished",
+        );
+    };
+    info!("Queued job {id}");
+    tokio::spawn(run_job(Arc::clone(&state), id.clone(), request, archive));
+
+    let response = JobResponse {
+        job_id: &id,
+        status: JobStatus::Queued,
+        error: None,
+    };
+    (
+        StatusCode::ACCEPTED,
+        [(header::LOCATION, format!("/jobs/{id}"))],
+        Json(response),
+    )
+        .into_response()
+}
+
+/// `GET /jobs/{id}`
+async fn job_status(
+    State(state): State<Arc<ServerState>>,
+    UrlPath(id): UrlPath<String>,
+) -> Response {
+    let jobs = state.jobs();
+    let Some(job) = jobs.by_id.get(&id) else {
+        return job_not_found(&id);
+    };
+    Json(JobResponse {
+        job_id: &id,
+        status: job.status,
+        error: job.error.as_deref(),
+    })
+    .into_response()
+}
+
+/// `GET /jobs/{id}/policy`
+async fn job_policy(
+    State(state): State<Arc<ServerState>>,
+    UrlPath(id): UrlPath<String>,
+) -> Response {
+    output_response(&state, &id, |output| &output.policy)
+}
+
+/// `GET /jobs/{id}/provenance`
+async fn job_provenance(
+    State(state): State<Arc<ServerState>>,
+    UrlPath(id): UrlPath<String>,
+) -> Response {
+    output_response(&state, &id, |output| &output.provenance)
+}
+
+/// The part of the output of the job `id` that `select` picks, if it succeeded
+fn output_response(
+    state: &ServerState,
+    id: &str,
+    select: fn(&JobOutput) -> &serde_json::Value,
+) -> Response {
+    let jobs = state.jobs();
+    match jobs.by_id.get(id) {
+        None => job_not_found(id),
+        Some(Job {
+            output: Some(output),
+            ..
+        }) => Json(select(output).clone()).into_response(),
+        Some(_) => error_response(
+            StatusCode::CONFLICT,
+            &format!("Job {id} hasn't succeeded; poll /jobs/{id} for its status"),
+        ),
+    }
+}
+
+fn job_not_found(id: &str) -> Response {
+    error_response(StatusCode::NOT_FOUND, &format!("No job {id}"))
+}
+
+fn error_response(status: StatusCode, message: &str) -> Response {
+    (status, Json(serde_json::json!({ "Error": message }))).into_response()
+}
+
+/// Run the job `id` once fewer than the maximum number of jobs are running
+async fn run_job(state: Arc<ServerState>, id: String, request: JobRequest, archive: Bytes) {
+    // The semaphore is never closed
+    let Ok(_permit) = state.running.acquire().await else {
+        return;
+    };
+    state.jobs().set_running(&id);
+    info!("Running job {id}");
+
+    let result = job_output(&state.config, request, archive).await;
+    if let Err(error) = &result {
+        warn!("Job {id} failed: {error:#}");
+    }
+    state.jobs().finish(&id, result);
+}
+
+/// Generate the policies of a job
+async fn job_output(
+    config: &ServeCliConfig,
+    request: JobRequest,
+    archive: Bytes,
+) -> Result<JobOutput> {
+    let path = request.path.clone();
+    let language = request.language.clone();
+    // Extracting and listing the sources blocks on the file system
+    let (_extracted, source_files) =
+        tokio::task::spawn_blocking(move || job_sources(path, &archive, language.as_deref()))
+            .await
+            .context("Failed to prepare the sources")??;
+
+    let service_hints = request.service_hints.as_ref().map(|hints| {
+        hints
+            .split(',')
+            .map(|hint| hint.trim().to_string())
+            .filter(|hint| !hint.is_empty())
+            .collect()
+    });
+    let shared = SharedConfig {
+        source_files,
+        language: request.language,
+        service_hints: service_hints.or_else(|| config.shared.service_hints.clone()),
+        ..config.shared.clone()
+    };
+    let aws_context = AwsContext::with_partition(
+        request
+            .partition
+            .or_else(|| config.partition.partition.clone()),
+        request.region.unwrap_or_else(|| config.region.clone()),
+        request.account.unwrap_or_else(|| config.account.clone()),
+    )?;
+    let result = generate_policies(&GeneratePolicyConfig {
+        action_provenance: true,
+        ..default_generate_config(&shared, aws_context)
+    })
+    .await?;
+    JobOutput::new(&result)
+}
+
+/// Source files of a job: those under `path`, or of the extracted `archive` if `path` is
+/// `None`, with the directory the archive was extracted to
+fn job_sources(
+    path: Option<PathBuf>,
+    archive: &[u8],
+    language: Option<&str>,
+) -> Result<(Option<TempDir>, Vec<PathBuf>)> {
+    let option = "the language query parameter";
+    match path {
+        Some(path) => Ok((
+            None,
+            remote_sources::directory_source_files(&path, language, option)?,
+        )),
+        None => {
+            let extracted = extract_archive(archive)?;
+            let files = remote_sources::directory_source_files(extracted.path(), language, option)?;
+            Ok((Some(extracted), files))
+        }
+    }
+}
+
+/// The canonical path of the sources a job names by `path`, resolved against `root`
+///
+/// Jobs may only name paths when the server has a root, and only paths under it once
+/// symbolic links and `..` are resolved; paths that don't exist are rejected the same way,
+/// so a job can't probe the file system outside the root.
+pub(crate) fn allowed_path(root: Option<&Path>, path: &Path) -> Result<PathBuf> {
+    let Some(root) = root else {
+        anyhow::bail!(
+            "The server doesn't analyze paths on it, as it wasn't started with \
+--allow-path-root; upload a tarball of the sources instead"
+        );
+    };
+    root.join(path)
+        .canonicalize()
+        .ok()
+        .filter(|resolved| resolved.starts_with(root))
+        .with_context(|| {
+            format!(
+                "{} is not a file or directory under the directory the server analyzes",
+                path.display()
+            )
+        })
+}
+
+/// Extract a tarball, possibly gzip-compressed, into a temporary directory
+pub(crate) fn extract_archive(archive: &[u8]) -> Result<TempDir> {
+    extract_archive_within(archive, MAX_UNPACKED_BYTES)
+}
+
+/// Extract a tarball whose entries take up to `max_unpacked` bytes
+///
+/// The archive is unpacked in-process rather than by `tar`, so only regular files and
+/// directories within the extraction directory are created: absolute paths, `..`
+/// components, symbolic and hard links and special files are rejected.
+fn extract_archive_within(archive: &[u8], max_unpacked: u64) -> Result<TempDir> {
+    let directory = TempDir::new().context("Failed to create a directory for the sources")?;
+    let reader: Box<dyn Read + '_> = if archive.starts_with(&[0x1f, 0x8b]) {
+        Box::new(flate2::read::GzDecoder::new(archive))
+    } else {
+        Box::new(archive)
+    };
+    let mut unpacked: u64 = 0;
+    let mut entries = tar::Archive::new(reader);
+    for entry in entries
+        .entries()
+        .context("Failed to read the source archive")?
+    {
+        let mut entry = entry.context("Failed to read the source archive")?;
+        let path = entry
+            .path()
+            .context("Failed to read the source archive")?
+            .into_owned();
+        unpacked = unpacked.saturating_add(entry.size());
+        anyhow::ensure!(
+            unpacked <= max_unpacked,
+            "The source archive unpacks to more than {max_unpacked} bytes"
+        );
+        anyhow::ensure!(
+            path.components()
+                .all(|component| matches!(component, Component::Normal(_) | Component::CurDir)),
+            "The source archive has an entry outside its directory: {}",
+            path.display()
+        );
+        let target = directory.path().join(&path);
+        match entry.header().entry_type() {
+            tar::EntryType::Directory => std::fs::create_dir_all(&target)
+                .with_context(|| format!("Failed to extract {}", path.display()))?,
+            tar::EntryType::Regular | tar::EntryType::Continuous => {
+                if let Some(parent) = target.parent() {
+                    std::fs::create_dir_all(parent)
+                        .with_context(|| format!("Failed to extract {}", path.display()))?;
+                }
+                let mut file = std::fs::File::create(&target)
+                    .with_context(|| format!("Failed to extract {}", path.display()))?;
+                std::io::copy(&mut entry, &mut file)
+                    .with_context(|| format!("Failed to extract {}", path.display()))?;
+            }
+            // Metadata of the archive, e.g. as written by git archive
+            tar::EntryType::XGlobalHeader => {}
+            entry_type => anyhow::bail!(
+                "The source archive has a {entry_type:?} entry, not a file or directory: {}",
+                path.display()
+            ),
+        }
+    }
+    Ok(directory)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_finished_jobs_are_retained_up_to_the_limit() {
+        let mut jobs = Jobs::default();
+        for index in 0..=RETAINED_JOBS {
+            let id = index.to_string();
+            jobs.by_id.insert(
+                id.clone(),
+                Job {
+                    status: JobStatus::Running,
+                    error: None,
+                    output: None,
+                },
+            );
+            jobs.unfinished += 1;
+            jobs.finish(&id, Err(anyhow::anyhow!("no source files")));
+        }
+
+        assert_eq!(jobs.by_id.len(), RETAINED_JOBS);
+        assert!(!jobs.by_id.contains_key("0"));
+        assert_eq!(jobs.by_id["1"].status, JobStatus::Failed);
+        assert_eq!(jobs.unfinished, 0);
+    }
+
+    #[test]
+    fn test_jobs_beyond_the_queue_are_rejected() {
+        let mut jobs = Jobs::default();
+        let first = jobs.queue(2).unwrap();
+        jobs.queue(2).unwrap();
+
+        assert_eq!(jobs.queue(2), None);
+        assert_eq!(jobs.by_id.len(), 2);
+
+        jobs.finish(&first, Err(anyhow::anyhow!("no source files")));
+        let third = jobs.queue(2).unwrap();
+        assert_eq!(jobs.by_id[&third].status, JobStatus::Queued);
+    }
+
+    /// A tarball of entries of a path, type and content
+    fn archive(entries: &[(&str, tar::EntryType, &[u8])]) -> Vec<u8> {
+        let mut builder = tar::Builder::new(Vec::new());
+        for (path, entry_type, content) in entries {
+            let mut header = tar::Header::new_gnu();
+            // Written as is, as `set_path` refuses the paths extraction must reject
+            header.as_gnu_mut().unwrap().name[..path.len()].copy_from_slice(path.as_bytes());
+            header.set_entry_type(*entry_type);
+            header.set_size(content.len() as u64);
+            header.set_mode(0o644);
+            header.set_cksum();
+            builder.append(&header, *content).unwrap();
+        }
+        builder.into_inner().unwrap()
+    }
+
+    #[test]
+    fn test_archives_are_extracted() {
+        let tarball = archive(&[
+            ("app/", tar::EntryType::Directory, b""),
+            ("app/handler.py", tar::EntryType::Regular, b"import boto3\n"),
+            ("./jobs/run.py", tar::EntryType::Regular, b"import os\n"),
+        ]);
+        let mut gzipped = flate2::write::GzEncoder::new(Vec::new(), flate2::Compression::fast());
+        std::io::Write::write_all(&mut gzipped, &tarball).unwrap();
+        let gzipped = gzipped.finish().unwrap();
+
+        for archive in [tarball, gzipped] {
+            let extracted = extract_archive(&archive).unwrap();
+            assert_eq!(
+                std::fs::read_to_string(extracted.path().join("app/handler.py")).unwrap(),
+                "import boto3\n"
+            );
+            assert!(extracted.path().join("jobs/run.py").is_file());
+        }
+    }
+
+    #[test]
+    fn test_archive_entries_outside_the_directory_are_rejected() {
+        for (path, entry_type) in [
+            ("../handler.py", tar::EntryType::Regular),
+            ("app/../../handler.py", tar::EntryType::Regular),
+            ("/tmp/handler.py", tar::EntryType::Regular),
+            ("handler.py", tar::EntryType::Symlink),
+            ("handler.py", tar::EntryType::Link),
+            ("handler.py", tar::EntryType::Fifo),
+        ] {
+            assert!(
+                extract_archive(&archive(&[(path, entry_type, b"")])).is_err(),
+                "{path} ({entry_type:?}) should be rejected"
+            );
+        }
+    }
+
+    #[test]
+    fn test_archives_unpacking_beyond_the_limit_are_rejected() {
+        let tarball = archive(&[
+            ("a.py", tar::EntryType::Regular, b"12345"),
+            ("b.py", tar::EntryType::Regular, b"123456"),
+        ]);
+
+        assert!(extract_archive_within(&tarball, 11).is_ok());
+        let error = extract_archive_within(&tarball, 10).unwrap_err();
+        assert!(error.to_string().contains("more than 10 bytes"), "{error}");
+    }
+
+    #[test]
+    fn test_paths_are_confined_to_the_root() {
+        let directory = tempfile::tempdir().unwrap();
+        std::fs::create_dir_all(directory.path().join("sources/app")).unwrap();
+        std::fs::write(directory.path().join("secrets.py"), "KEY = ''\n").unwrap();
+        let root = directory.path().join("sources").canonicalize().unwrap();
+
+        assert!(allowed_path(None, &root.join("app")).is_err());
+        assert_eq!(
+            allowed_path(Some(&root), Path::new("app")).unwrap(),
+            root.join("app")
+        );
+        assert_eq!(
+            allowed_path(Some(&root), &root.join("app")).unwrap(),
+            root.join("app")
+        );
+        let outside = directory.path().join("secrets.py");
+        for path in [
+            Path::new("../secrets.py"),
+            Path::new("app/../../secrets.py"),
+            Path::new("missing"),
+            outside.as_path(),
+        ] {
+            assert!(
+                allowed_path(Some(&root), path).is_err(),
+                "{} should be rejected",
+                path.display()
+            );
+        }
+        #[cfg(unix)]
+        {
+            std::os::unix::fs::symlink(directory.path(), root.join("escape")).unwrap();
+            assert!(allowed_path(Some(&root), Path::new("escape/secrets.py")).is_err());
+        }
+    }
+}
@@ -0,0 +1,299 @@
+//! Roles of a Cognito identity pool, as output with the identity-pool output formats.
+//!
+//! Mobile and web clients calling AWS directly, e.g. with the JavaScript SDK in a browser,
+//! get their credentials from a Cognito identity pool: it exchanges the identity of a
+//! signed-in user, or of a guest, for credentials of the pool's authenticated or
+//! unauthenticated role with `sts:AssumeRoleWithWebIdentity`. Both roles trust
+//! `cognito-identity.amazonaws.com` for the identities of the pool alone, through the
+//! `aud` condition, and for identities of their kind, through the `amr` condition.
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::api::model::GeneratePoliciesResult;
+use iam_policy_autopilot_policy_generation::{JsonProvider, PolicyWithMetadata};
+use log::debug;
+use serde_json::Value;
+
+/// Federated principal of Cognito identity pools
+const COGNITO_IDENTITY_PRINCIPAL: &str = "cognito-identity.amazonaws.com";
+
+/// Named principal whose policies are granted to the unauthenticated role
+const UNAUTHENTICATED_PRINCIPAL: &str = "unauthenticated";
+
+/// Identity pool ID of the JSON roles, to replace before creating them
+const IDENTITY_POOL_ID_PLACEHOLDER: &str = "{{IdentityPoolId}}";
+
+/// Form of the identity pool roles the generated policies are emitted in
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub(crate) enum IdentityPoolFormat {
+    /// Properties of the two `AWS::IAM::Role`s as JSON, by role
+    Json,
+    /// CloudFormation template with the roles and their attachment to the identity pool
+    CloudFormation,
+}
+
+/// Kind of the identities of an identity pool a role is for
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum IdentityKind {
+    Authenticated,
+    Unauthenticated,
+}
+
+impl IdentityKind {
+    /// Value of the `amr` claim of identities of the kind
+    const fn amr(self) -> &'static str {
+        match self {
+            Self::Authenticated => "authenticated",
+            Self::Unauthenticated => "unauthenticated",
+        }
+    }
+
+    /// Key of the role in the JSON form, and suffix of its logical ID
+    const fn role_name(self) -> &'static str {
+        match self {
+            Self::Authenticated => "AuthenticatedRole",
+            Self::Unauthenticated => "UnauthenticatedRole",
+        }
+    }
+}
+
+/// Trust policy of the role of `kind` for the identities of `identity_pool_id`
+fn trust_policy(kind: IdentityKind, identity_pool_id: Value) -> Value {
+    serde_json::json!({
+        "Version": "2012-10-17",
+        "Statement": [{
+            "Effect": "Allow",
+            "Principal": { "Federated": COGNITO_IDENTITY_PRINCIPAL },
+            "Action": "sts:AssumeRoleWithWebIdentity",
+            "Condition": {
+                "StringEquals": { "cognito-identity.amazonaws.com:aud": identity_pool_id },
+                "ForAnyValue:StringLike": { "cognito-identity.amazonaws.com:amr": kind.amr() },
+            },
+        }],
+    })
+}
+
+/// The authenticated and unauthenticated roles of an identity pool with `policies`
+///
+/// The policies of the `unauthenticated` principal of `--principals` are granted to both
+/// roles, as signed-in users can do what guests can, the others to the authenticated role
+/// alone. Policies of calls made with credentials of an assumed role or a profile belong
+/// to those, so they are left out.
+pub(crate) fn identity_pool_roles(
+    policies: &[PolicyWithMetadata],
+    format: IdentityPoolFormat,
+) -> Result<Value> {
+    let mut authenticated = Vec::new();
+    let mut unauthenticated = Vec::new();
+    for policy in policies.iter().filter(|p| p.is_workload_policy()) {
+        let document =
+            serde_json::to_value(&policy.policy).context("Failed to serialize policy document")?;
+        if policy.named_principal.as_deref() == Some(UNAUTHENTICATED_PRINCIPAL) {
+            unauthenticated.push(document.clone());
+        }
+        authenticated.push(document);
+    }
+    let skipped = policies.len() - authenticated.len();
+    if skipped > 0 {
+        crate::output::warn(&format!(
+            "left out {skipped} policies of calls made with credentials of assumed roles or \
+             profiles"
+        ));
+    }
+    let roles = [
+        (IdentityKind::Authenticated, authenticated),
+        (IdentityKind::Unauthenticated, unauthenticated),
+    ];
+    let inline_policies = |documents: &[Value]| -> Vec<Value> {
+        documents
+            .iter()
+            .enumerate()
+            .map(|(index, document)| {
+                serde_json::json!({
+                    "PolicyName": format!("IamPolicyAutopilotPolicy{}", index + 1),
+                    "PolicyDocument": document,
+                })
+            })
+            .collect()
+    };
+
+    Ok(match format {
+        IdentityPoolFormat::Json => {
+            let mut output = serde_json::Map::new();
+            for (kind, documents) in &roles {
+                output.insert(
+                    kind.role_name().to_string(),
+                    serde_json::json!({
+                        "AssumeRolePolicyDocument":
+                            trust_policy(*kind, IDENTITY_POOL_ID_PLACEHOLDER.into()),
+                        "Policies": inline_policies(documents),
+                    }),
+                );
+            }
+            Value::Object(output)
+        }
+        IdentityPoolFormat::CloudFormation => {
+            let identity_pool_id = serde_json::json!({ "Ref": "IdentityPoolId" });
+            let mut resources = serde_json::Map::new();
+            let mut attached_roles = serde_json::Map::new();
+            let mut outputs = serde_json::Map::new();
+            for (kind, documents) in &roles {
+                let logical_id = format!("IamPolicyAutopilot{}", kind.role_name());
+                let mut properties = serde_json::json!({
+                    "AssumeRolePolicyDocument": trust_policy(*kind, identity_pool_id.clone()),
+                });
+                if !documents.is_empty() {
+                    properties["Policies"] = inline_policies(documents).into();
+                }
+                resources.insert(
+                    logical_id.clone(),
+                    serde_json::json!({ "Type": "AWS::IAM::Role", "Properties": properties }),
+                );
+                let arn = serde_json::json!({ "Fn::GetAtt": [logical_id, "Arn"] });
+                attached_roles.insert(kind.amr().to_string(), arn.clone());
+                outputs.insert(
+                    format!("{}Arn", kind.role_name()),
+                    serde_json::json!({ "Value": arn }),
+                );
+            }
+            resources.insert(
+                "IamPolicyAutopilotIdentityPoolRoles".to_string(),
+                serde_json::json!({
+                    "Type": "AWS::Cognito::IdentityPoolRoleAttachment",
+                    "Properties": {
+                        "IdentityPoolId": identity_pool_id,
+                        "Roles": attached_roles,
+                    },
+                }),
+            );
+            serde_json::json!({
+                "AWSTemplateFormatVersion": "2010-09-09",
+                "Description": "Cognito identity pool roles generated by IAM Policy Autopilot",
+                "Parameters": {
+                    "IdentityPoolId": {
+                        "Type": "String",
+                        "Description":
+                            "ID of the Cognito identity pool, e.g. us-east-1:1a2b3c4d-5678-90ab",
+                    },
+                },
+                "Resources": resources,
+                "Outputs": outputs,
+            })
+        }
+    })
+}
+
+/// Output the identity pool roles with the generated policies to stdout
+pub(crate) fn output_identity_pool_roles(
+    result: &GeneratePoliciesResult,
+    format: IdentityPoolFormat,
+    pretty: bool,
+) -> Result<()> {
+    debug!("Formatting IAM policies output as identity pool roles ({format:?})");
+    let roles = identity_pool_roles(&result.policies, format)?;
+    let json_output = if pretty {
+        JsonProvider::stringify_pretty(&roles)
+            .context("Failed to serialize identity pool roles to pretty JSON")?
+    } else {
+        JsonProvider::stringify(&roles)
+            .context("Failed to serialize identity pool roles to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+
+    debug!("Identity pool roles written to stdout");
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use iam_policy_autopilot_policy_generation::{IamPolicy, PolicyType, Statement};
+
+    use super::*;
+
+    fn policy(action: &str, named_principal: Option<&str>) -> PolicyWithMetadata {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec![action.to_string()],
+            vec!["*".to_string()],
+        ));
+        PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: named_principal.map(str::to_string),
+        }
+    }
+
+    fn actions(role: &Value) -> Vec<&Value> {
+        role["Policies"]
+            .as_array()
+            .into_iter()
+            .flatten()
+            .map(|policy| &policy["PolicyDocument"]["Statement"][0]["Action"][0])
+            .collect()
+    }
+
+    #[test]
+    fn test_identity_pool_roles() {
+        let policies = [
+            policy("s3:PutObject", None),
+            policy("mobiletargeting:PutEvents", Some(UNAUTHENTICATED_PRINCIPAL)),
+            PolicyWithMetadata {
+                assumed_role: Some("arn:aws:iam::123456789012:role/Admin".to_string()),
+                ..policy("iam:PassRole", None)
+            },
+        ];
+
+        let roles = identity_pool_roles(&policies, IdentityPoolFormat::Json).unwrap();
+
+        assert_eq!(
+            actions(&roles["AuthenticatedRole"]),
+            ["s3:PutObject", "mobiletargeting:PutEvents"]
+        );
+        assert_eq!(
+            actions(&roles["UnauthenticatedRole"]),
+            ["mobiletargeting:PutEvents"]
+        );
+        let trust = &roles["UnauthenticatedRole"]["AssumeRolePolicyDocument"]["Statement"][0];
+        assert_eq!(trust["Action"], "sts:AssumeRoleWithWebIdentity");
+        assert_eq!(trust["Principal"]["Federated"], COGNITO_IDENTITY_PRINCIPAL);
+        assert_eq!(
+            trust["Condition"]["StringEquals"]["cognito-identity.amazonaws.com:aud"],
+            IDENTITY_POOL_ID_PLACEHOLDER
+        );
+        assert_eq!(
+            trust["Condition"]["ForAnyValue:StringLike"]["cognito-identity.amazonaws.com:amr"],
+            "unauthenticated"
+        );
+    }
+
+    #[test]
+    fn test_identity_pool_template_attaches_the_roles() {
+        let policies = [policy("s3:PutObject", None)];
+
+        let template = identity_pool_roles(&policies, IdentityPoolFormat::CloudFormation).unwrap();
+
+        let resources = &template["Resources"];
+        assert!(
+            resources["IamPolicyAutopilotUnauthenticatedRole"]["Properties"]
+                .get("Policies")
+                .is_none()
+        );
+        assert_eq!(
+            resources["IamPolicyAutopilotIdentityPoolRoles"]["Properties"]["Roles"]
+                ["authenticated"],
+            serde_json::json!({ "Fn::GetAtt": ["IamPolicyAutopilotAuthenticatedRole", "Arn"] })
+        );
+        assert_eq!(
+            resources["IamPolicyAutopilotAuthenticatedRole"]["Properties"]
+                ["AssumeRolePolicyDocument"]["Statement"][0]["Condition"]["StringEquals"]
+                ["cognito-identity.amazonaws.com:aud"],
+            serde_json::json!({ "Ref": "IdentityPoolId" })
+        );
+    }
+}
@@ -0,0 +1,414 @@
+//! Language server integrating the analysis into editors.
+//!
+//! `lsp` speaks the Language Server Protocol on stdin and stdout. The source files of the
+//! workspace are analyzed when the editor connects and again whenever a file is saved:
+//! hovering over an SDK call shows the permissions it requires, e.g. "This call requires
+//! `s3:GetObject` on `arn:aws:s3:::my-bucket/*`", and the places where the analysis lost
+//! precision are published as warnings.
+
+use std::collections::{BTreeMap, HashSet};
+use std::ops::ControlFlow;
+use std::path::{Path, PathBuf};
+use std::sync::Arc;
+
+use anyhow::{Context, Result};
+use async_lsp::concurrency::ConcurrencyLayer;
+use async_lsp::panic::CatchUnwindLayer;
+use async_lsp::router::Router;
+use async_lsp::server::LifecycleLayer;
+use async_lsp::{ClientSocket, LanguageClient, MainLoop};
+use iam_policy_autopilot_policy_generation::api::generate_policies;
+use iam_policy_autopilot_policy_generation::api::model::{AwsContext, GeneratePolicyConfig};
+use iam_policy_autopilot_policy_generation::{ActionProvenance, Diagnostic, Language, Location};
+use log::{debug, info, warn};
+use lsp_types::notification::{DidSaveTextDocument, Exit, Initialized};
+use lsp_types::request::{HoverRequest, Initialize, Shutdown};
+use lsp_types::{
+    DiagnosticSeverity, Hover, HoverContents, HoverProviderCapability, InitializeParams,
+    InitializeResult, MarkupContent, MarkupKind, MessageType, NumberOrString, Position,
+    PublishDiagnosticsParams, Range, ServerCapabilities, ServerInfo, ShowMessageParams,
+    TextDocumentSyncCapability, TextDocumentSyncOptions, TextDocumentSyncSaveOptions, Url,
+};
+use tokio_util::compat::{TokioAsyncReadCompatExt, TokioAsyncWriteCompatExt};
+use tower::ServiceBuilder;
+
+use crate::{default_generate_config, remote_sources, SharedConfig};
+
+/// Source of the diagnostics the server publishes
+const DIAGNOSTIC_SOURCE: &str = "iam-policy-autopilot";
+
+/// What the analysis of the workspace found
+#[derive(Debug, Default)]
+struct Analysis {
+    /// Calls requiring each generated action
+    provenance: Vec<ActionProvenance>,
+    /// Places where the analysis lost precision, by source file
+    diagnostics: BTreeMap<PathBuf, Vec<Diagnostic>>,
+}
+
+/// Event of an analysis of the workspace finishing
+struct Analyzed(Result<Analysis>);
+
+struct ServerState {
+    client: ClientSocket,
+    /// Analysis options; the source files are those of the workspace
+    shared: SharedConfig,
+    aws_context: AwsContext,
+    /// Root directory of the workspace, known once the editor initializes the server
+    root: Option<PathBuf>,
+    /// Latest analysis of the workspace
+    analysis: Arc<Analysis>,
+    /// Files diagnostics were published for, cleared once they have none left
+    published: HashSet<PathBuf>,
+    /// Whether an analysis is running
+    analyzing: bool,
+    /// Whether a file was saved while the analysis was running, so it's outdated
+    outdated: bool,
+}
+
+/// Serve the language server on stdin and stdout until the editor exits it
+pub(crate) async fn serve(shared: SharedConfig, aws_context: AwsContext) -> Result<()> {
+    let (server, _) = MainLoop::new_server(|client| {
+        let mut router = Router::new(ServerState {
+            client: client.clone(),
+            shared,
+            aws_context,
+            root: None,
+            analysis: Arc::new(Analysis::default()),
+            published: HashSet::new(),
+            analyzing: false,
+            outdated: false,
+        });
+        router
+            .request::<Initialize, _>(|state, params| {
+                state.root = workspace_root(&params);
+                info!("Initialized language server for workspace {:?}", state.root);
+                std::future::ready(Ok(initialize_result()))
+            })
+            .request::<HoverRequest, _>(|state, params| {
+                let position = params.text_document_position_params;
+                let hover = position
+                    .text_document
+                    .uri
+                    .to_file_path()
+                    .ok()
+                    .and_then(|path| call_hover(&state.analysis, &path, position.position));
+                std::future::ready(Ok(hover))
+            })
+            .request::<Shutdown, _>(|_, ()| std::future::ready(Ok(())))
+            .notification::<Initialized>(|state, _| {
+                start_analysis(state);
+                ControlFlow::Continue(())
+            })
+            .notification::<DidSaveTextDocument>(|state, _| {
+                start_analysis(state);
+                ControlFlow::Continue(())
+            })
+            .notification::<Exit>(|_, ()| ControlFlow::Break(Ok(())))
+            .unhandled_notification(|_, notification| {
+                debug!("Unhandled notification: {}", notification.method);
+                ControlFlow::Continue(())
+            })
+            .event::<Analyzed>(|state, Analyzed(analysis)| {
+                finish_analysis(state, analysis);
+                ControlFlow::Continue(())
+            });
+        ServiceBuilder::new()
+            .layer(LifecycleLayer::default())
+            .layer(CatchUnwindLayer::default())
+            .layer(ConcurrencyLayer::default())
+            .service(router)
+    });
+
+    server
+        .run_buffered(
+            tokio::io::stdin().compat(),
+            tokio::io::stdout().compat_write(),
+        )
+        .await
+        .context("The language server failed")?;
+    Ok(())
+}
+
+/// Root directory of the workspace the editor opened
+#[allow(deprecated)]
+fn workspace_root(params: &InitializeParams) -> Option<PathBuf> {
+    params
+        .workspace_folders
+        .as_ref()
+        .and_then(|folders| folders.first())
+        .map(|folder| &folder.uri)
+        .or(params.root_uri.as_ref())
+        .and_then(|uri| uri.to_file_path().ok())
+}
+
+/// Capabilities of the server: hovers, and analyses of the saved files. Unsaved changes
+/// aren't synchronized, since the analysis reads the files.
+fn initialize_result() -> InitializeResult {
+    InitializeResult {
+        capabilities: ServerCapabilities {
+            hover_provider: Some(HoverProviderCapability::Simple(true)),
+            text_document_sync: Some(TextDocumentSyncCapability::Options(
+                TextDocumentSyncOptions {
+                    save: Some(TextDocumentSyncSaveOptions::Supported(true)),
+                    ..TextDocumentSyncOptions::default()
+                },
+            )),
+            ..ServerCapabilities::default()
+        },
+        server_info: Some(ServerInfo {
+            name: "iam-policy-autopilot".to_string(),
+            version: Some(env!("CARGO_PKG_VERSION").to_string()),
+        }),
+    }
+}
+
+/// Analyze the workspace in the background, or again after the running analysis
+fn start_analysis(state: &mut ServerState) {
+    if state.analyzing {
+        state.outdated = true;
+        return;
+    }
+    let Some(root) = state.root.clone() else {
+        warn!("The editor didn't open a workspace, nothing to analyze");
+        return;
+    };
+    state.analyzing = true;
+    let client = state.client.clone();
+    let shared = state.shared.clone();
+    let aws_context = state.aws_context.clone();
+    tokio::spawn(async move {
+        let analysis = analyze(&root, &shared, aws_context).await;
+        if client.emit(Analyzed(analysis)).is_err() {
+            debug!("The language server exited before the analysis finished");
+        }
+    });
+}
+
+/// Publish the diagnostics of a finished analysis, and keep it for hovers
+fn finish_analysis(state: &mut ServerState, analysis: Result<Analysis>) {
+    state.analyzing = false;
+    match analysis {
+        Ok(analysis) => {
+            publish_diagnostics(state, &analysis);
+            state.analysis = Arc::new(analysis);
+        }
+        Err(error) => {
+            warn!("Failed to analyze the workspace: {error:#}");
+            let params = ShowMessageParams {
+                typ: MessageType::ERROR,
+                message: format!("IAM Policy Autopilot couldn't analyze the workspace: {error:#}"),
+            };
+            if let Err(error) = state.client.show_message(params) {
+                debug!("Failed to show the analysis error: {error}");
+            }
+        }
+    }
+    if std::mem::take(&mut state.outdated) {
+        start_analysis(state);
+    }
+}
+
+/// Analyze the source files of the workspace at `root`, one language at a time
+async fn analyze(root: &Path, shared: &SharedConfig, aws_context: AwsContext) -> Result<Analysis> {
+    let mut by_language = remote_sources::source_files_by_language(root)
+        .context("Failed to list the source files of the workspace")?;
+    if let Some(language) = &shared.language {
+        let language = Language::try_from_str(language)?.to_string();
+        by_language.retain(|file_language, _| *file_language == language);
+    }
+
+    let mut analysis = Analysis::default();
+    for (language, source_files) in by_language {
+        info!("Analyzing {} {language} source files", source_files.len());
+        let shared = SharedConfig {
+            source_files,
+            language: Some(language.clone()),
+            ..shared.clone()
+        };
+        let result = generate_policies(&GeneratePolicyConfig {
+            action_provenance: true,
+            analysis_diagnostics: true,
+            ..default_generate_config(&shared, aws_context.clone())
+        })
+        .await
+        .with_context(|| format!("Failed to analyze the {language} source files"))?;
+        analysis
+            .provenance
+            .extend(result.action_provenance.unwrap_or_default());
+        for diagnostic in result.diagnostics.unwrap_or_default() {
+            analysis
+                .diagnostics
+                .entry(diagnostic.location.file_path.clone())
+                .or_default()
+                .push(diagnostic);
+        }
+    }
+    Ok(analysis)
+}
+
+/// Publish the diagnostics of `analysis`, clearing those of the files that have none left
+fn publish_diagnostics(state: &mut ServerState, analysis: &Analysis) {
+    let files: HashSet<PathBuf> = analysis.diagnostics.keys().cloned().collect();
+    for path in state.published.union(&files) {
+        let Ok(uri) = Url::from_file_path(path) else {
+            continue;
+        };
+        let diagnostics = analysis
+            .diagnostics
+            .get(path)
+            .map(|diagnostics| diagnostics.iter().map(lsp_diagnostic).collect())
+            .unwrap_or_default();
+        let params = PublishDiagnosticsParams {
+            uri,
+            diagnostics,
+            version: None,
+        };
+        if let Err(error) = state.client.publish_diagnostics(params) {
+            debug!(
+                "Failed to publish the diagnostics of {}: {error}",
+                path.display()
+            );
+        }
+    }
+    state.published = files;
+}
+
+/// The LSP diagnostic of an analysis diagnostic
+fn lsp_diagnostic(diagnostic: &Diagnostic) -> lsp_types::Diagnostic {
+    lsp_types::Diagnostic {
+        range: lsp_range(&diagnostic.location),
+        severity: Some(DiagnosticSeverity::WARNING),
+        code: Some(NumberOrString::String(diagnostic.kind.id().to_string())),
+        source: Some(DIAGNOSTIC_SOURCE.to_string()),
+        message: diagnostic.message.clone(),
+        ..lsp_types::Diagnostic::default()
+    }
+}
+
+/// Hover listing the permissions the innermost call at `position` of `path` requires
+fn call_hover(analysis: &Analysis, path: &Path, position: Position) -> Option<Hover> {
+    let position = (position.line as usize + 1, position.character as usize + 1);
+    let call = analysis
+        .provenance
+        .iter()
+        .flat_map(|provenance| &provenance.calls)
+        .map(|call| &call.location)
+        .filter(|location| {
+            location.file_path == path
+                && location.start_position <= position
+                && position < location.end_position
+        })
+        .max_by_key(|location| location.start_position)?;
+
+    let requirements: Vec<String> = analysis
+        .provenance
+        .iter()
+        .filter(|provenance| provenance.calls.iter().any(|site| &site.location == call))
+        .map(|provenance| {
+            let resources: Vec<String> = provenance
+                .resources
+                .iter()
+                .map(|resource| format!("`{resource}`"))
+                .collect();
+            format!(
+                "This call requires `{}` on {}",
+                provenance.action,
+                resources.join(", ")
+            )
+        })
+        .collect();
+    Some(Hover {
+        contents: HoverContents::Markup(MarkupContent {
+            kind: MarkupKind::Markdown,
+            value: requirements.join("\n\n"),
+        }),
+        range: Some(lsp_range(call)),
+    })
+}
+
+/// The 0-based LSP range of a 1-based source location
+fn lsp_range(location: &Location) -> Range {
+    let position = |(line, column): (usize, usize)| {
+        Position::new(
+            u32::try_from(line.saturating_sub(1)).unwrap_or(u32::MAX),
+            u32::try_from(column.saturating_sub(1)).unwrap_or(u32::MAX),
+        )
+    };
+    Range::new(
+        position(location.start_position),
+        position(location.end_position),
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use iam_policy_autopilot_policy_generation::CallSite;
+
+    fn call_site(start: (usize, usize), end: (usize, usize)) -> CallSite {
+        CallSite {
+            location: Location::new(PathBuf::from("/workspace/app.py"), start, end),
+            expression: "s3.get_object(Bucket='my-bucket', Key=key)".to_string(),
+        }
+    }
+
+    fn analysis() -> Analysis {
+        Analysis {
+            provenance: vec![
+                ActionProvenance {
+                    action: "kms:Decrypt".to_string(),
+                    resources: vec!["*".to_string()],
+                    calls: vec![call_site((4, 5), (4, 48))],
+                },
+                ActionProvenance {
+                    action: "s3:GetObject".to_string(),
+                    resources: vec!["arn:aws:s3:::my-bucket/*".to_string()],
+                    calls: vec![call_site((4, 5), (4, 48)), call_site((9, 1), (9, 30))],
+                },
+            ],
+            diagnostics: BTreeMap::new(),
+        }
+    }
+
+    #[test]
+    fn test_call_hover() {
+        let hover = call_hover(
+            &analysis(),
+            Path::new("/workspace/app.py"),
+            Position::new(3, 10),
+        )
+        .expect("the position is in a call");
+
+        let HoverContents::Markup(contents) = hover.contents else {
+            panic!("hover isn't markup");
+        };
+        assert_eq!(
+            contents.value,
+            "This call requires `kms:Decrypt` on `*`\n\n\
+             This call requires `s3:GetObject` on `arn:aws:s3:::my-bucket/*`"
+        );
+        assert_eq!(
+            hover.range,
+            Some(Range::new(Position::new(3, 4), Position::new(3, 47)))
+        );
+    }
+
+    #[test]
+    fn test_no_hover_outside_of_calls() {
+        let analysis = analysis();
+
+        assert!(call_hover(
+            &analysis,
+            Path::new("/workspace/app.py"),
+            Position::new(3, 2)
+        )
+        .is_none());
+        assert!(call_hover(
+            &analysis,
+            Path::new("/workspace/other.py"),
+            Position::new(3, 10)
+        )
+        .is_none());
+    }
+}
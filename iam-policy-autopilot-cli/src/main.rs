@@ -16,34 +16,67 @@
 //!
 //! See `types::ExitCode` for the enum definition.
 
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
 use std::process;
+use std::sync::Arc;
 
 use anyhow::{Context, Result};
-use clap::{Parser, Subcommand};
+use clap::{Args, Parser, Subcommand};
 use iam_policy_autopilot_common::telemetry::{
     self, TelemetryChoice, TelemetryEventDerive, ToTelemetryEvent,
 };
 use iam_policy_autopilot_policy_generation::api::model::{
-    AwsContext, ExtractSdkCallsConfig, GeneratePolicyConfig,
+    ActionFilters, AwsContext, CallConfidence, CustomServices, DefaultExclusion,
+    DependencyAnalysis, ExtractSdkCallsConfig, GeneratePoliciesResult, GeneratePolicyConfig,
+    KmsKeyArn, KmsViaService, MappingOverrides, NetworkOrigins, PrincipalMappings, ResourceAnswers,
+    ResourcePrompt, S3ResourceForm, ServiceChoices, ServicePrompt,
+};
+use iam_policy_autopilot_policy_generation::api::{
+    dump_mappings, extract_sdk_calls, generate_policies, list_calls, update_mappings,
 };
-use iam_policy_autopilot_policy_generation::api::{extract_sdk_calls, generate_policies};
 use iam_policy_autopilot_policy_generation::extraction::SdkMethodCall;
-use iam_policy_autopilot_policy_generation::DEFAULT_RESOURCE_CUTOFF;
-use iam_policy_autopilot_tools::PolicyUploader;
+use iam_policy_autopilot_policy_generation::{
+    ActionProvenance, Diagnostic, DiagnosticKind, Runtime, DEFAULT_RESOURCE_CUTOFF,
+    PROGRESS_LOG_TARGET,
+};
+use iam_policy_autopilot_tools::{
+    audit_unused_permissions, compare_usage, diff_policies, normalize_policy,
+    observed_actions_from_export, policy_changelog, sample_requests, ExistingPolicy, PolicyApplier,
+    PolicySimulator, PolicyUploader, PolicyValidator, Role, RolePolicyReader, UsageCollector,
+};
 use log::{debug, info, trace};
 
+mod aggregate;
+mod bench;
 mod commands;
+mod git_changes;
+mod golden;
+mod grpc_server;
+mod html_report;
+mod http_server;
+mod identity_pool;
+mod lsp_server;
+mod metadata;
 mod output;
+#[cfg(feature = "profiling")]
+mod profiling;
+mod remote_sources;
+mod resource_prompt;
+mod service_split;
+mod terraform_data_source;
 mod types;
 
 use iam_policy_autopilot_mcp_server::{start_mcp_server, McpTransport, DEFAULT_BIND_ADDRESS};
 use types::ExitCode;
 
 use crate::commands::print_version_info;
+use crate::identity_pool::IdentityPoolFormat;
+use crate::metadata::GenerationMetadata;
+use crate::output::{CdkLanguage, CloudFormationPolicyType, RoleFormat, ScpStrategy};
 
 /// Default port for mcp server for Http Transport
 static MCP_HTTP_DEFAULT_PORT: u16 = 8001;
+static SERVE_DEFAULT_PORT: u16 = 8002;
 
 /// Shared CLI configuration for both subcommands
 #[derive(Debug, Clone)]
@@ -58,6 +91,8 @@ struct SharedConfig {
     full_output: bool,
     /// Optional service hints for filtering
     service_hints: Option<Vec<String>>,
+    /// Options of the analysis of the source files
+    analysis: AnalysisArgs,
 }
 
 impl SharedConfig {
@@ -75,6 +110,29 @@ impl SharedConfig {
 
         Ok(())
     }
+
+    /// The default exclusions of the analysis lifted with --include
+    fn included(&self) -> Vec<DefaultExclusion> {
+        DefaultExclusion::ALL
+            .into_iter()
+            .filter(|exclusion| {
+                self.analysis
+                    .include
+                    .iter()
+                    .any(|kind| kind == exclusion.id())
+            })
+            .collect()
+    }
+
+    /// The dependencies of the project analyzed with --dependency-depth
+    fn dependencies(&self) -> Option<DependencyAnalysis> {
+        self.analysis
+            .dependency_depth
+            .map(|depth| DependencyAnalysis {
+                depth: usize::from(depth),
+                allowed: self.analysis.allowed_dependencies.clone(),
+            })
+    }
 }
 
 /// Configuration specific to generate-policies subcommand
@@ -86,6 +144,8 @@ struct GeneratePolicyCliConfig {
     region: String,
     /// AWS account ID
     account: String,
+    /// AWS partition
+    partition: PartitionArgs,
     /// Output individual policies instead of merged policy
     individual_policies: bool,
     /// Upload policies to AWS with optional custom name prefix
@@ -96,6 +156,119 @@ struct GeneratePolicyCliConfig {
     disable_cache: bool,
     /// Resource lists with more than this many entries are collapsed to '*' instead of emitting every resource-specific ARN. Use 0 to collapse every non-empty resource list. Default: 4.
     resource_cutoff: Option<usize>,
+    /// Keep wildcard resources instead of scoping to identifiers known at call sites
+    wildcard_resources: bool,
+    /// Application configuration files the code reads resource names from
+    app_config: Vec<PathBuf>,
+    /// Prompt for resources that can't be resolved statically
+    interactive: bool,
+    /// Optional file of recorded answers for unresolved resources
+    answers_file: Option<PathBuf>,
+    /// Optional file of recorded services for ambiguous calls
+    disambiguation_file: Option<PathBuf>,
+    /// Optional file of operation to action mappings merged over the built-in ones
+    mapping_overrides: Option<PathBuf>,
+    /// Optional lockfile pinning the service references
+    service_reference_lock: Option<PathBuf>,
+    /// Optional directory caching the extracted SDK calls
+    extraction_cache: Option<PathBuf>,
+    /// Optional file of the services other than AWS ones the code calls
+    custom_services: Option<PathBuf>,
+    /// Optional file mapping the source files to named principals
+    principals: Option<PathBuf>,
+    /// Minimum confidence of the services of the calls granted in the policies
+    min_confidence: Option<String>,
+    /// Emit parameterized policies with template variables for unknown resources
+    template: bool,
+    /// S3 resource forms to grant access through, all forms when empty
+    s3_resource_forms: Vec<String>,
+    /// Report actions granted on all resources and why they couldn't be scoped
+    report_unscoped: bool,
+    /// Compact enumerated actions into wildcards that match only granted actions
+    compact_actions: bool,
+    /// Suggest AWS managed policies covering generated statements
+    managed_policies: bool,
+    /// Generate trust policy stubs for the roles the code assumes
+    trust_policies: bool,
+    /// Generate the resource-based policies the code implies
+    resource_policies: bool,
+    /// Report the calls on resources of other accounts
+    cross_account_report: bool,
+    /// Role of the analyzed workload, trusted by the roles it assumes
+    workload_role_arn: Option<String>,
+    /// Suggest condition keys narrowing generated statements
+    suggest_conditions: bool,
+    /// Grant the permissions of the tracing, metrics and logging instrumentation of the code
+    observability_permissions: bool,
+    /// OpenTelemetry Collector configurations whose AWS exporters need permissions
+    collector_config: Vec<PathBuf>,
+    /// Grant the permissions of the event sources of the Lambda handlers of the code
+    event_source_permissions: bool,
+    /// Grant multipart uploads the actions of the whole multipart upload lifecycle
+    s3_multipart_actions: bool,
+    /// Grant no KMS permissions for the services encrypting data on behalf of the calls
+    no_kms_via_service: bool,
+    /// Services to grant the KMS permissions used on their behalf for; all if empty
+    kms_via_services: Vec<String>,
+    /// KMS keys to grant those permissions on, as `ARN` or `SERVICE=ARN`
+    kms_key_arns: Vec<String>,
+    /// Services whose actions to leave out of the policies
+    exclude_services: Vec<String>,
+    /// Actions to strip from the policies
+    deny_actions: Vec<String>,
+    /// Actions the policies may grant, stripping every other
+    allow_actions: Vec<String>,
+    /// Split the policies into read-only and write policies
+    split_read_write: bool,
+    /// Generate a policy per entry point of the code
+    per_entry_point: bool,
+    /// Validate the generated policies with IAM Access Analyzer
+    validate: bool,
+    /// Reference policy the generated policies may not grant more access than
+    check_no_new_access: Option<PathBuf>,
+    /// Actions the generated policies may not grant
+    forbidden_actions: Vec<String>,
+    /// Findings failing the command: unresolved, ambiguous, unsupported, sensitive-action
+    /// or unscoped
+    fail_on: Vec<String>,
+    /// Flag privileged and escalation-prone actions of the generated statements
+    flag_sensitive: bool,
+    /// Summarize the generated actions by service and IAM access level
+    access_summary: bool,
+    /// Report how completely the call sites of the code were resolved
+    coverage: bool,
+    /// Sidecar file to write the calls requiring each generated action to
+    provenance: Option<PathBuf>,
+    /// SARIF log to write the analysis diagnostics to
+    sarif: Option<PathBuf>,
+    /// HTML file to write the least-privilege review report to
+    html_report: Option<PathBuf>,
+    /// CSV file to write the inventory of the generated actions to
+    action_inventory: Option<PathBuf>,
+    /// Where to embed the metadata of the run: id, sid or description
+    metadata: Vec<String>,
+    /// Sidecar manifest to write the metadata of the run to
+    metadata_manifest: Option<PathBuf>,
+    /// Directory to write a policy per AWS service to, instead of outputting the policies
+    split_by_service: Option<PathBuf>,
+    /// Runtime running the code, for role output formats; detected from the code if `None`
+    runtime: Option<String>,
+    /// Regions to restrict the statements to; detected from the code if empty
+    restrict_regions: Option<Vec<String>>,
+    /// VPC endpoints requests to the endpoint services have to come through
+    source_vpce: Vec<String>,
+    /// VPCs requests to the endpoint services have to come from
+    source_vpc: Vec<String>,
+    /// Public IP ranges requests to other services have to come from
+    source_ip: Vec<String>,
+    /// Services reached through VPC endpoints; all services if empty
+    vpc_endpoint_services: Vec<String>,
+    /// Access Analyzer generated policy to merge with the generated policies
+    access_analyzer_policy: Option<PathBuf>,
+    /// Output format: json, cloudformation, cloudformation-inline, terraform, cdk-typescript,
+    /// cdk-python, scp, scp-deny, role-json, role-cloudformation, role-terraform,
+    /// identity-pool-json, identity-pool-cloudformation or opa
+    output_format: String,
     /// Generate explanations for why actions were added (with optional action filters)
     explain: Option<Vec<String>>,
     /// Optional Terraform project directory
@@ -108,15 +281,341 @@ struct GeneratePolicyCliConfig {
     tfvars: Vec<PathBuf>,
     /// Optional ARN patterns to filter resource binding explanations
     explain_resources: Option<Vec<String>>,
+    /// Compiled Go binaries to analyze instead of source files
+    go_binaries: Vec<PathBuf>,
 }
 
 impl GeneratePolicyCliConfig {
+    /// Whether the command fails on `finding`, one of the values of --fail-on
+    fn fails_on(&self, finding: &str) -> bool {
+        self.fail_on.iter().any(|fail_on| fail_on == finding)
+    }
+
     /// Validate the configuration
     fn validate(&self) -> Result<()> {
+        if self.interactive && !commands::is_tty() {
+            anyhow::bail!(
+                "--interactive requires a terminal; pass answers recorded by an interactive run \
+                 with --answers-file and --disambiguation-file instead"
+            );
+        }
+        if self.runtime.is_some() && !self.output_format.starts_with("role-") {
+            anyhow::bail!(
+                "--runtime only applies to the role-json, role-cloudformation and role-terraform \
+                 output formats"
+            );
+        }
+        if !self.vpc_endpoint_services.is_empty()
+            && self.source_vpce.is_empty()
+            && self.source_vpc.is_empty()
+        {
+            anyhow::bail!("--vpc-endpoint-services requires --source-vpce or --source-vpc");
+        }
+        if self.no_kms_via_service
+            && (!self.kms_via_services.is_empty() || !self.kms_key_arns.is_empty())
+        {
+            anyhow::bail!(
+                "--no-kms-via-service can't be combined with --kms-via-services or --kms-key-arns"
+            );
+        }
+        if let Some(range) = self.source_ip.iter().find(|range| !is_ip_range(range)) {
+            anyhow::bail!("--source-ip {range} is not an IP address or CIDR range");
+        }
+        if self.output_format != "json" && self.upload_policies.is_some() {
+            anyhow::bail!(
+                "--output-format {} can't be combined with --upload-policies; deploy the \
+                 generated template or code instead",
+                self.output_format
+            );
+        }
+        if self.split_by_service.is_some() && self.output_format != "json" {
+            anyhow::bail!(
+                "--split-by-service can't be combined with --output-format {}",
+                self.output_format
+            );
+        }
+        if self.upload_policies.is_none()
+            && self.metadata.iter().any(|target| target == "description")
+        {
+            anyhow::bail!("--metadata description requires --upload-policies");
+        }
+        for binary in &self.go_binaries {
+            if !binary.is_file() {
+                anyhow::bail!("Go binary does not exist: {}", binary.display());
+            }
+        }
         self.shared.validate()
     }
 }
 
+/// Whether `range` is an IP address or a CIDR range, e.g. `203.0.113.0/24`
+fn is_ip_range(range: &str) -> bool {
+    let (address, prefix) = range
+        .split_once('/')
+        .map_or((range, None), |(address, prefix)| (address, Some(prefix)));
+    address.parse::<std::net::IpAddr>().is_ok_and(|address| {
+        prefix.is_none_or(|prefix| {
+            let max = if address.is_ipv4() { 32 } else { 128 };
+            prefix.parse::<u8>().is_ok_and(|prefix| prefix <= max)
+        })
+    })
+}
+
+/// Configuration specific to simulate subcommand
+#[derive(Debug, Clone)]
+struct SimulateCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Policy to simulate against instead of the generated one
+    policy_file: Option<PathBuf>,
+}
+
+/// Days of CloudTrail event history checked by default, all the event history keeps
+const DEFAULT_USAGE_DAYS: u32 = 90;
+
+/// Configuration specific to check-usage subcommand
+#[derive(Debug, Clone)]
+struct CheckUsageCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region, whose CloudTrail event history is queried
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Role whose observed actions are compared
+    role_arn: Option<String>,
+    /// Days of event history to query, up to now
+    days: Option<u32>,
+    /// CloudTrail, Athena or CloudTrail Lake export to read instead of the event history
+    cloudtrail_export: Option<PathBuf>,
+}
+
+/// Configuration specific to diff subcommand
+#[derive(Debug, Clone)]
+struct DiffCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Policy file the generated policy is compared with
+    existing: Option<PathBuf>,
+    /// Git ref whose version of the changed source files the generated policy is
+    /// compared with
+    diff_ref: Option<String>,
+}
+
+/// Configuration specific to changelog subcommand
+#[derive(Debug, Clone)]
+struct ChangelogCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Saved report or git ref of the earlier version
+    from: String,
+    /// Saved report or git ref of the later version
+    to: String,
+    /// Output the changelog as Markdown instead of JSON
+    markdown: bool,
+}
+
+/// Configuration specific to check-baseline subcommand
+#[derive(Debug, Clone)]
+struct CheckBaselineCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Committed baseline policy file
+    baseline: PathBuf,
+    /// Overwrite the baseline with the generated policy
+    update_baseline: bool,
+}
+
+/// Configuration specific to test subcommand
+#[derive(Debug, Clone)]
+struct TestCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Directory of the committed golden policy files
+    golden: PathBuf,
+    /// Overwrite the golden files with the generated policies
+    update: bool,
+}
+
+/// Configuration specific to hook subcommand
+#[derive(Debug, Clone)]
+struct HookCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Committed policy file kept up to date
+    policy_file: PathBuf,
+}
+
+/// Configuration specific to lsp subcommand
+#[derive(Debug, Clone)]
+struct LspCliConfig {
+    /// Shared configuration; the source files are those of the editor's workspace
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+}
+
+/// Configuration specific to serve subcommand
+#[derive(Debug, Clone)]
+struct ServeCliConfig {
+    /// Shared configuration; each job names its own source files
+    shared: SharedConfig,
+    /// AWS region of the jobs not passing one
+    region: String,
+    /// AWS account ID of the jobs not passing one
+    account: String,
+    /// AWS partition of the jobs not passing one
+    partition: PartitionArgs,
+    /// Port the HTTP server listens on
+    port: u16,
+    /// Address the HTTP server binds to
+    bind_address: String,
+    /// Port the gRPC API listens on, if served
+    grpc_port: Option<u16>,
+    /// Number of jobs analyzed at once
+    max_concurrent_jobs: usize,
+    /// Number of jobs waiting for others to finish above which submissions are rejected
+    max_queued_jobs: usize,
+    /// Directory the jobs may analyze by path, canonical once the server started
+    allow_path_root: Option<PathBuf>,
+}
+
+/// Configuration specific to audit-unused subcommand
+#[derive(Debug, Clone)]
+struct AuditUnusedCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Policy file to audit
+    policy_file: Option<PathBuf>,
+    /// Role whose policies are audited
+    role_name: Option<String>,
+}
+
+/// Configuration specific to apply subcommand
+#[derive(Debug, Clone)]
+struct ApplyCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// Role the policies are attached to
+    role_arn: String,
+    /// Name of the managed policy, derived from the role name when not provided
+    policy_name: Option<String>,
+    /// Only report the changes, without applying them
+    dry_run: bool,
+}
+
+/// Configuration specific to explain subcommand
+#[derive(Debug, Clone)]
+struct ExplainCliConfig {
+    /// Shared configuration
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Action or resource to explain
+    target: String,
+    /// Provenance file of a previous run, explained instead of analyzing the source files
+    provenance: Option<PathBuf>,
+}
+
+/// Configuration specific to aggregate subcommand
+#[derive(Debug, Clone)]
+struct AggregateCliConfig {
+    /// Shared configuration; the source files are those of each path
+    shared: SharedConfig,
+    /// AWS region
+    region: String,
+    /// AWS account ID
+    account: String,
+    /// AWS partition
+    partition: PartitionArgs,
+    /// Directories or git URLs of the projects
+    paths: Vec<PathBuf>,
+    /// Output the merged policies of all paths
+    merge: bool,
+    /// Directory the policies of each path are written to, instead of stdout
+    output_dir: Option<PathBuf>,
+}
+
+/// Configuration specific to bench subcommand
+#[derive(Debug, Clone)]
+struct BenchCliConfig {
+    /// Shared configuration; the source files are those of the benchmarked path
+    shared: SharedConfig,
+    /// Source file or directory to analyze
+    path: PathBuf,
+    /// Number of times the sources are analyzed
+    iterations: u16,
+    /// Report of a previous run to compare with
+    baseline: Option<PathBuf>,
+    /// Regression of a median, in percent of the baseline, failing the benchmark
+    max_regression: Option<u16>,
+}
+
+const SOURCE_FILES_LONG_HELP: &str = "Source files to analyze for SDK method extraction. A git \
+URL, e.g. https://github.com/org/service.git#v1.2.0 or git@github.com:org/service.git, stands \
+for the files of the repository: it is shallow-cloned at the branch, tag or commit after '#', \
+or its default branch, into a temporary directory, and its tracked files in --language, or in \
+the only language the repository has, are analyzed.";
+
+const PARTITION_LONG_HELP: &str = "AWS partition to use for ARN generation. Examples: aws, \
+aws-cn, aws-us-gov. By default, the partition is derived from --region, or left as '*' when the \
+region is '*'. Use this flag to emit partition-specific ARNs without fixing the region; a region \
+outside the partition is rejected.";
+
 const SERVICE_HINTS_LONG_HELP: &str = "Space-separated list of AWS service names to filter \
 which SDK calls are analyzed. This helps reduce unnecessary permissions by limiting analysis to \
 only the services your application actually uses. For example, if your code only uses S3 and IAM \
@@ -125,6 +624,531 @@ match other services like Chime. Note: The final policy may still include action
 not in your hints if they are required for the operations you perform (e.g., KMS actions for S3 \
 encryption).";
 
+const EXCLUDE_TESTS_LONG_HELP: &str = "Skip test sources so unit tests don't add permissions \
+the production role never needs. Currently recognizes Go test files (*_test.go) and Python files \
+that run against emulated AWS services: moto (@mock_aws and other moto decorators or imports), \
+localstack_client, or clients with an endpoint_url pointing at localhost/LocalStack. Calls recorded on \
+test doubles (e.g., gomock EXPECT() expectations) and generated mock files (MockGen, mockery, \
+counterfeiter) are always ignored, so test files can still be included when generating a policy \
+for an integration-test role.";
+
+const JOBS_LONG_HELP: &str = "Number of source files to analyze concurrently. Default: one per \
+available CPU. At most this many files are parsed at once, which bounds the memory the analysis \
+of a large repository takes; lower it on memory-constrained machines.";
+
+const PLUGIN_LONG_HELP: &str = "Executable reporting the AWS calls of in-house SDK wrappers and \
+private services the analysis doesn't recognize. It's run once with the language and the \
+analyzed source files as JSON on stdin, and writes the calls it finds as JSON on stdout, see \
+the plugin protocol in the README. Calls of services without a service reference list the \
+actions they require. Can be repeated.";
+
+const WRAPPERS_LONG_HELP: &str = "JSON file declaring the helper functions of the code that \
+forward an operation to an AWS SDK client, e.g. awsutil.Do(client, &s3.GetObjectInput{...}), \
+whose calls are analyzed as calls of the operation instead of being dropped. The \
+OperationArgument of a wrapper is the 0-based position of the argument naming the operation: \
+a string such as \"GetObject\" or \"get_object\", or a request or command such as \
+&s3.GetObjectInput{...}, new GetObjectCommand(...) or GetObjectRequest.builder()...build(). \
+Calls of operations several services have are made on each, unless the wrapper names its \
+Service, e.g. {\"Wrappers\": [{\"Function\": \"awsutil.Do\", \"OperationArgument\": 1}]}.";
+
+const GO_BINARY_LONG_HELP: &str = "Experimental: compiled Go binary to generate the policies of \
+instead of source files, e.g. a third-party agent whose sources aren't available. Every AWS SDK \
+for Go v1 or v2 operation linked into the binary is granted, as named in its symbol table, \
+which stripped binaries keep. The policies are coarse: operations are granted on all \
+resources, and binaries calling client methods through reflection link all operations of \
+their clients. Can be repeated.";
+
+const GRPC_PORT_LONG_HELP: &str = "Port to also serve the gRPC API on, on the bind address. Its \
+Analyze call takes the path of the sources on the server or their tarball, streams an event \
+per analyzed file and the policies of each language of the sources as soon as they're \
+generated, and stops the analysis when cancelled. Analyses count towards \
+--max-concurrent-jobs like the jobs of the HTTP API. Not served by default.";
+
+const ALLOW_PATH_ROOT_LONG_HELP: &str = "Directory on the server the jobs may analyze by path, \
+the path query parameter of the HTTP API and the path of the gRPC API. Paths are resolved against \
+it, symbolic links included, and jobs naming a path outside it are rejected. Without this option \
+jobs can only upload their sources as a tarball.";
+
+const DIFF_REF_LONG_HELP: &str = "Git ref, e.g. origin/main or a commit, to compare with \
+instead of an existing policy. Only the source files whose content differs from their version \
+at the ref are analyzed, new files included, so pre-merge checks stay fast and focused on \
+what the change does. The policy generated for them is compared with the policy generated for \
+their version at the ref and the version at the ref of the source files deleted since, so \
+actions only deleted code needed are reported as ExtraActions.";
+
+const CHANGELOG_VERSION_LONG_HELP: &str = "Version of the policies to compare: the path of a \
+saved report, i.e. the JSON output of generate-policies or a single IAM policy document, or \
+else a git ref, e.g. v1.2.0 or a commit, at which the version of the source files is analyzed. \
+Source files the ref didn't track are left out of its version.";
+
+const VERBOSE_LONG_HELP: &str = "Logs to stderr how the analysis progresses. -v reports each \
+source file as it is analyzed, which extractor handled it and how long it took, and how long \
+extraction, enrichment and policy generation took overall, to find slow or skipped files in \
+large repositories. -vv also logs what each extractor matched in every file.";
+
+const PROGRESS_LONG_HELP: &str = "Reports each source file to stderr as it is analyzed, as \
+[<done>/<total>] <file>, without the rest of the logs.";
+
+const WILDCARD_RESOURCES_LONG_HELP: &str = "Keep resource ARNs wildcarded instead of scoping \
+them to the resources named at call sites. By default, bucket names, object keys, table names, \
+queue URLs, function names and parameter names passed as string literals (and S3 URIs) scope \
+statements to those concrete ARNs. Bucket, object, table and function identifiers read from \
+environment variables become templates such as arn:aws:s3:::${BUCKET_NAME}/* naming the variable \
+to substitute. Use this flag when the same code runs against resources it doesn't name \
+literally. Has no effect on resources bound from Terraform inputs, which take precedence over \
+call-site literals.";
+
+const APP_CONFIG_LONG_HELP: &str = "One or more application configuration files (YAML, \
+JSON, TOML or .env) the code reads resource names from. References such as cfg.storage.bucket or \
+settings.TABLE_NAME resolve to the setting whose key path they end with (ignoring case, '_' and \
+'-'), and variables set in .env files replace the ${VARIABLE} templates of environment variable \
+reads. The values scope statements like string literals at call sites do; ambiguous references \
+stay wildcarded. Has no effect with --wildcard-resources or Terraform inputs.";
+
+const INTERACTIVE_LONG_HELP: &str = "Prompt on the terminal for each resource the source \
+code doesn't name statically, e.g. a bucket name computed at runtime. The prompt shows the call, \
+the action and the ARN pattern; enter a name or pattern (such as reports-*), or press Enter to \
+accept the default shown in brackets: the latest answer for the same placeholder, or '*'. \
+Answers are recorded in --answers-file, if provided, for future non-interactive runs. Also \
+prompts for the service of each call whose operation exists in several services when the code \
+doesn't identify its client, e.g. list_tags: the prompt shows the file, line and call; enter \
+the number or name of a service, or press Enter to keep them all. Choices are recorded in \
+--disambiguation-file, if provided.";
+
+const ANSWERS_FILE_LONG_HELP: &str = "JSON file of recorded answers for resources the source \
+code doesn't name statically. Answers identify calls by file and call expression, so they still \
+apply after code around the call changes. With --interactive, new answers are added to the file, \
+which is created if it doesn't exist.";
+
+const DISAMBIGUATION_FILE_LONG_HELP: &str = "JSON file of recorded services for calls whose \
+operation exists in several services, e.g. list_tags on a client whose service can't be \
+resolved. The policies grant such calls' actions in the recorded service only, instead of in \
+every service having the operation. Choices identify calls by file, call expression and \
+operation, so they still apply after code around the call changes; commit the file to reuse \
+them in CI. Its Overrides pin the service of every ambiguous call matching their Files glob, \
+Package or Variable, e.g. {\"Files\": \"internal/ddb/**\", \"Service\": \"dynamodb\"}. With \
+--interactive, new choices are added to the file, which is created if it doesn't exist.";
+
+const MAPPING_OVERRIDES_LONG_HELP: &str = "JSON file of operation to IAM action mappings merged \
+over those of the AWS service references, adding or correcting entries, e.g. for preview \
+services or internal Smithy services. Its Services map the name of each service to its \
+Operations, the actions each operation authorizes, replacing those of the service reference, \
+e.g. {\"Services\": {\"s3\": {\"Operations\": {\"GetObject\": [\"s3:GetObject\", \
+\"kms:Decrypt\"]}}}}, and optionally to its Actions, the resource types each action applies \
+to, and Resources, the ARN format of each resource type, for services without a service \
+reference.";
+
+const EXTRACTION_CACHE_LONG_HELP: &str = "Directory caching the SDK calls extracted from the \
+source files. Runs analyzing the same files with the same content as a previous run, e.g. the \
+unchanged services of a monorepo in CI, read the calls back instead of analyzing the files \
+again. Entries are keyed by the version of the tool, the language, the path and content of \
+every file of the language, since calls resolve against constants and client factories of other \
+files, and the plugins and wrapper definitions of the run: changing any of them analyzes all the \
+files of the language again, except for Java files, which are analyzed on their own.";
+
+const SERVICE_REFERENCE_LOCK_LONG_HELP: &str = "Lockfile pinning the AWS service references \
+operations are mapped to IAM actions with, so the same code yields the same policies on every \
+machine and over time. Services the file pins are mapped with their pinned service reference; \
+the others are pinned to their latest one, saved by update-mappings or fetched, and added to \
+the file, which is created if it doesn't exist. Commit the file to generate policies \
+reproducibly in CI. Services pinned more than 90 days ago are reported as stale on stderr: \
+remove them from the file to pin the latest service references.";
+
+const CUSTOM_SERVICES_LONG_HELP: &str = "JSON file registering services other than AWS ones \
+the code calls, such as Smithy-generated clients of in-house services or AWS SDK clients \
+configured with the endpoint of an AWS-compatible service, whose operations would otherwise \
+be granted as those of AWS services of the same name. Its Services take the calls matching \
+every condition of their Files glob, Package and Variable. Calls of a service with a \
+Namespace are granted the action of their operation in it on *, e.g. widgets:GetWidget; \
+those of a service without one are left out of the policies and listed as suppressed calls, \
+e.g. {\"Services\": [{\"Name\": \"minio\", \"Variable\": \"minio\"}]}.";
+
+const PRINCIPALS_LONG_HELP: &str = "JSON file mapping the source files to the named \
+principals running them, such as the roles of the workers and the API a repository backs, for \
+a policy per principal. The Paths of a principal are directories, files, entry points as \
+reported by --per-entry-point, or globs, e.g. {\"Principals\": [{\"Name\": \"worker-role\", \
+\"Paths\": [\"workers\", \"shared/queue.py\"]}, {\"Name\": \"api-role\", \"Paths\": \
+[\"cmd/api\"]}]}. The calls of files several principals cover are granted to each, those of \
+files none covers stay in the policy of the principal running the code.";
+
+const MIN_CONFIDENCE_LONG_HELP: &str = "Grant only the calls whose service is resolved with \
+at least this confidence: high when it's the service of the client the call is made on, or the \
+only service having the operation whose input shape has the call's argument names or whose SDK \
+the source file imports, medium when only the method name identifies the service, and low when \
+several services have the operation. Calls below it are left out of the policies and listed \
+under LowConfidenceCalls, to review and resolve, e.g. with --disambiguation-file. Without it, \
+every call is granted and the low-confidence calls, granted in every service they may be made \
+on, are listed.";
+
+const TEMPLATE_LONG_HELP: &str = "Emit parameterized policies for deployment pipelines that \
+substitute values per environment. Resources the analysis can't name become template variables \
+instead of wildcards, e.g. arn:aws:s3:::{{BucketName}}/* or {{BUCKET_NAME}} for a bucket read \
+from an environment variable, and the partition, region and account become {{Partition}}, \
+{{Region}} and {{AccountId}} unless provided. The output lists every variable with the resources \
+using it under TemplateVariables. Cannot be combined with --upload-policies.";
+
+const S3_RESOURCE_FORMS_LONG_HELP: &str = "S3 resource forms to grant access through. By \
+default, statements of S3 actions list every resource form the action is authorized on: bucket \
+and object ARNs, access points, Object Lambda Access Points and Multi-Region Access Points. Choose \
+the forms your application uses, e.g. '--s3-resource-forms bucket' for plain bucket/object ARNs \
+only, to keep policies small. Actions authorized only on unselected forms (e.g. \
+s3:GetAccessPoint) keep their resources.";
+
+const REPORT_UNSCOPED_LONG_HELP: &str = "Report the actions granted on Resource \"*\" under \
+UnscopedActions in the output, with the reason each couldn't be scoped: \
+ResourceLevelPermissionsNotSupported for actions that can only be granted on all resources (e.g. \
+s3:ListAllMyBuckets), ResourceCutoff for resource lists collapsed by --resource-cutoff, and \
+UnknownArnFormat for resource types without a known ARN format. Actions without resource-level \
+permissions are moved into statements of their own (Sid NoResourceLevelPermissions), so \
+deliberate wildcards can be told apart from limitations of the analysis.";
+
+const COMPACT_ACTIONS_LONG_HELP: &str = "Compact the actions of each statement into wildcards \
+where every action the wildcard matches is already granted, e.g. s3:GetObject* when the \
+statement lists all s3:GetObject... actions. Wildcards end at a word of the action name and \
+never match an action the statement didn't grant, so policies get smaller without getting \
+broader. Actions AWS adds to a service later would match the wildcards too. Has no effect with \
+--individual-policies.";
+
+const MANAGED_POLICIES_LONG_HELP: &str = "Compare the generated statements with AWS managed \
+policies and suggest attaching a managed policy where it covers a statement, e.g. \
+AmazonDynamoDBReadOnlyAccess for a statement of DynamoDB reads. The suggestions are listed under \
+ManagedPolicySuggestions in the output, and the policies keep only the statements no managed \
+policy covers. Only read-only and narrowly scoped managed policies are suggested, for statements without \
+conditions. Managed policies grant on all resources, so attaching one widens statements scoped to \
+specific resources. Has no effect with --individual-policies.";
+
+const TRUST_POLICIES_LONG_HELP: &str =
+    "Generate a trust policy stub for each role the code assumes by a \
+literal ARN, e.g. sts.assume_role(RoleArn=\"arn:aws:iam::123456789012:role/Reader\"), so both \
+halves of the cross-role relationship are generated. The stubs are listed under TrustPolicies in \
+the output. A role is trusted by the principal that assumes it: the role of another generated \
+policy for chained roles, or the workload role given with --workload-role-arn ({{WorkloadRoleArn}} \
+if not given). Roles assumed with sts:AssumeRoleWithWebIdentity trust a {{OidcProviderArn}} \
+identity provider to fill in.";
+
+const RESOURCE_POLICIES_LONG_HELP: &str = "Generate the resource-based policies the code implies, \
+listed under ResourcePolicies in the output: queue, topic and Lambda permission policies \
+allowing the deliveries the code configures to literal ARNs, e.g. SNS subscriptions of queues, \
+bucket notifications and EventBridge targets, restricted to their source with aws:SourceArn \
+({{SourceArn}} if the code doesn't name it), and key policy statements allowing the workload the \
+KMS actions it calls on keys of other accounts. Key policies allow the role given with \
+--workload-role-arn ({{WorkloadRoleArn}} if not given).";
+
+const CROSS_ACCOUNT_REPORT_LONG_HELP: &str = "Report the calls naming the literal ARN of a \
+resource in another account than --account-id, listed under CrossAccountAccess in the output \
+with what the target account has to allow: a resource-based policy allowing the workload, e.g. \
+a queue or key policy, or the trust policy of an assumed role. Services without resource-based \
+policies can only be called with the credentials of a role in the target account, so the \
+actions of their calls are left out of the policies.";
+
+const WORKLOAD_ROLE_ARN_LONG_HELP: &str = "ARN of the role the analyzed workload runs as, \
+trusted by the trust policy stubs of --trust-policies for the roles the workload assumes, and \
+allowed by the key policies of --resource-policies.";
+
+const SUGGEST_CONDITIONS_LONG_HELP: &str = "Suggest condition keys that could narrow the \
+generated statements, with the values known from the code: s3:prefix for listing buckets with \
+literal prefixes, dynamodb:LeadingKeys for item access to tables, and aws:ResourceAccount for \
+resources in any account. The suggestions are listed under ConditionKeySuggestions in the \
+output, and the terraform and cdk output formats add them as comments above their statements. \
+The policies themselves are left unchanged.";
+
+const OBSERVABILITY_PERMISSIONS_LONG_HELP: &str = "Grant the permissions of the tracing, \
+metrics and logging instrumentation the code uses, which sends telemetry to AWS without SDK \
+calls of its own: xray:PutTraceSegments, xray:PutTelemetryRecords and the sampling rule actions \
+for the X-Ray SDK, Powertools Tracer and OpenTelemetry with the AWS X-Ray extensions (ADOT), \
+aps:RemoteWrite for Prometheus remote write to Amazon Managed Service for Prometheus, \
+cloudwatch:PutMetricData for the CloudWatch embedded metric format libraries, restricted with \
+cloudwatch:namespace to the metric namespaces the code sets, and logs:CreateLogStream and \
+logs:PutLogEvents for the CloudWatch Logs handlers of watchtower, winston-cloudwatch and the \
+logrus hooks, scoped to the log groups they name with literals. The instrumentation is \
+recognized by the libraries the code imports.";
+
+const COLLECTOR_CONFIG_LONG_HELP: &str = "OpenTelemetry Collector configuration, e.g. of the \
+ADOT collector sidecar the code exports OTLP to. The collector makes the AWS calls with the \
+same task or pod role as the code, so with --observability-permissions, its awsxray exporter \
+is granted the X-Ray actions and its prometheusremotewrite exporter aps:RemoteWrite on the \
+workspace of its endpoint. Repeat for several files.";
+
+const EVENT_SOURCE_PERMISSIONS_LONG_HELP: &str = "Grant the permissions the execution \
+role of a Lambda function needs to read the events of its handlers, inferred from the event \
+types of the handler signatures: sqs:ReceiveMessage, sqs:DeleteMessage and \
+sqs:GetQueueAttributes for SQS events, kinesis:GetRecords, kinesis:GetShardIterator and the \
+stream description actions for Kinesis events, and dynamodb:GetRecords, \
+dynamodb:GetShardIterator and the stream description actions for DynamoDB stream events. The \
+event types are recognized in Go (events.SQSEvent), Java and TypeScript (SQSEvent, \
+KinesisStreamEvent, DynamoDBStreamEvent) and the Powertools data classes in Python. S3 events \
+need no permission, as S3 invokes the function through its resource policy.";
+
+const S3_MULTIPART_ACTIONS_LONG_HELP: &str = "Grant the S3 calls of multipart \
+uploads, including those of upload managers such as upload_file, @aws-sdk/lib-storage's \
+Upload, Go's manager.Uploader and Java's S3TransferManager, s3:AbortMultipartUpload and s3:ListMultipartUploadParts on the objects they're granted \
+s3:PutObject on. The upload managers switch to multipart uploads past their part size \
+threshold and abort failed uploads or list the parts of resumed ones, which fails without \
+these actions.";
+
+const NO_KMS_VIA_SERVICE_LONG_HELP: &str = "Grant no KMS permissions for \
+the services that encrypt and decrypt data with KMS keys on behalf of the calls, such as \
+kms:Decrypt and kms:GenerateDataKey conditioned on kms:ViaService for S3 or DynamoDB calls. \
+By default, they're granted for every service on every key of the account.";
+
+const KMS_VIA_SERVICES_LONG_HELP: &str = "Grant the KMS permissions used on behalf of \
+the calls, conditioned on kms:ViaService, only for the calls of these services, e.g. s3 \
+dynamodb. By default, they're granted for the calls of every service.";
+
+const KMS_KEY_ARNS_LONG_HELP: &str = "Grant the KMS permissions used on behalf of the \
+calls, conditioned on kms:ViaService, on these keys or aliases instead of every key of the \
+account (key/*). Prefix an ARN with a service and = to only use it for the calls of that \
+service, e.g. s3=arn:aws:kms:us-east-1:123456789012:alias/reports; unprefixed ARNs are used \
+for the calls of the services without keys of their own.";
+
+const EXCLUDE_SERVICES_LONG_HELP: &str = "Leave the actions of these services out of the \
+generated policies, e.g. sts,logs when a base policy of the platform grants them to every \
+workload, so the policies only grant what the team owns. Actions other services take on \
+behalf of the calls are left out as well. The calls losing actions are still listed, with the \
+actions left out, under ExcludedServiceCalls and on stderr.";
+
+const DENY_ACTIONS_LONG_HELP: &str = "Strip these actions from the generated policies, \
+whatever the code calls, e.g. iam:CreateUser,iam:Create* for actions the organization \
+forbids. Wildcard actions of the policies granting one, e.g. iam:* with --compact-actions, are \
+stripped whole. Unlike --forbidden-actions, no AWS credentials are needed: the policies are \
+output without the actions, which are listed with the denied action matching them under \
+FilteredActions and on stderr. Add --fail-on filtered-action to fail instead.";
+
+const ALLOW_ACTIONS_LONG_HELP: &str = "Only let the generated policies grant these actions, \
+e.g. s3:Get*,s3:PutObject,sqs:SendMessage for an approved list, stripping every other action. \
+The stripped actions are listed under FilteredActions and on stderr; add --fail-on \
+filtered-action to fail instead. Applied after --deny-actions.";
+
+const SPLIT_READ_WRITE_LONG_HELP: &str = "Split each generated policy into a \
+read-only policy of the List and Read actions, with the Id IamPolicyAutopilotRead, and a write \
+policy of the Write, Permissions management and Tagging actions, with the Id \
+IamPolicyAutopilotWrite, classified by the IAM access levels of the Service Reference. The read \
+policy can be attached broadly, and the write policy gated behind stricter controls. Actions of \
+unknown access level go to the write policy. Has no effect with --individual-policies.";
+
+const PER_ENTRY_POINT_LONG_HELP: &str = "Generate separate policies for each entry point \
+of the code instead of one policy for all of it, so the functions and commands of a monorepo \
+don't share a union policy. Entry points are Go main packages, Lambda handler files (Python \
+lambda_handler or handler functions, JavaScript and TypeScript handler exports, Java \
+RequestHandler implementations) and CLI subcommand directories under cmd/ or commands/. Each \
+policy names its entry point under EntryPoint. Calls in files outside of every entry point \
+are granted to the entry points of the nearest directory containing any, or to all of them.";
+
+const VALIDATE_LONG_HELP: &str = "Validate the generated policies with IAM Access \
+Analyzer ValidatePolicy, which checks them against the IAM policy grammar and AWS best \
+practices. Findings are printed to stderr with the policy element they're about. Errors and \
+security warnings fail the command before the policies are output or uploaded, so invalid or \
+overly permissive policies don't reach a deployment; warnings and suggestions are only \
+reported. Requires AWS credentials allowing access-analyzer:ValidatePolicy.";
+
+const CHECK_NO_NEW_ACCESS_LONG_HELP: &str = "Check with IAM Access Analyzer \
+CheckNoNewAccess that the generated policies grant no access the reference policy doesn't, \
+e.g. the previous version of the policy. The reference is an IAM policy document or the JSON \
+output of generate-policies, whose policies are checked against as one. Policies that fail \
+the check fail the command before they're output or uploaded, as a guardrail of pipelines. \
+Requires AWS credentials allowing access-analyzer:CheckNoNewAccess.";
+
+const FORBIDDEN_ACTIONS_LONG_HELP: &str = "Check with IAM Access Analyzer \
+CheckAccessNotGranted that the generated policies grant none of the given actions, e.g. \
+iam:PassRole,s3:DeleteBucket. Policies that grant one fail the command before they're output \
+or uploaded, as a guardrail of pipelines. Requires AWS credentials allowing \
+access-analyzer:CheckAccessNotGranted.";
+
+const FLAG_SENSITIVE_LONG_HELP: &str = "Flag the privileged and escalation-prone \
+actions of the generated statements, such as iam:PutRolePolicy, kms:ScheduleKeyDeletion, \
+s3:PutBucketPolicy, and iam:PassRole or sts:AssumeRole on all resources. Each is listed under \
+SensitiveActions with a severity (Critical, High or Medium), the reason, and the source \
+locations of the calls requiring it, and reported on stderr, so security reviews can focus on \
+the risky parts of the policy.";
+
+const ACCESS_SUMMARY_LONG_HELP: &str = "Summarize the actions of the generated \
+policies by service and IAM access level (List, Read, Write, Tagging, Permissions management), \
+for a quick overview of their risk. The summary is listed under AccessLevelSummary and printed \
+on stderr. Wildcards count at the access level of every action they grant.";
+
+const COVERAGE_LONG_HELP: &str = "Report coverage statistics of the \
+analysis: the number of AWS SDK call sites found, and how many were resolved (their service and \
+resources are known), partially resolved (granted in every service the call may be made on, or \
+on resources widened to *) or skipped (the policies grant nothing for them: calls excluded by \
+annotations or below --min-confidence, operations the Service Reference doesn't know, client \
+methods named at runtime), in total, by language and by file. The summary is printed on stderr \
+and the full report is output with the policies as Coverage.";
+
+const PROVENANCE_LONG_HELP: &str = "Write a sidecar JSON file mapping each generated \
+action to the resources it's granted on and the source locations and expressions of the \
+calls requiring it, so reviewers can tell why a policy grants an action, e.g. kms:Decrypt, \
+without rerunning the analysis. Actions of other inputs, such as an Access Analyzer policy, \
+have no calls.";
+
+const EXPLAIN_PROVENANCE_LONG_HELP: &str = "Provenance file written by generate-policies \
+--provenance to explain the target from, instead of analyzing source files. The explanation is \
+then of the policies of that run, with its options.";
+
+const INCLUDE_LONG_HELP: &str = "Analyze sources that are skipped by default because they \
+aren't the project's own code: 'ignored' for files git ignores through .gitignore files or \
+.git/info/exclude, 'vendored' for dependencies under vendor/, node_modules/, dist/ or \
+site-packages/ directories, 'generated' for generated code, recognized by names such as \
+*.pb.go, *_pb2.py or *.min.js and by 'Code generated ... DO NOT EDIT.' or '@generated' \
+markers at the top of the file, 'oversized' for files larger than --max-file-size or \
+minified, such as webpack bundles, whose parsing could stall the analysis, and 'mocked' for \
+JavaScript and TypeScript tests mocking the AWS SDK clients with aws-sdk-client-mock, \
+jest.mock('@aws-sdk/...') or sinon stubs, whose commands never reach AWS. Comma-separated, \
+e.g. --include vendored,generated.";
+
+const MAX_FILE_SIZE_LONG_HELP: &str = "Skip source files larger than this many bytes, 2097152 \
+(2 MiB) by default. Minified and bundled files, whose lines are 500 bytes long on average, are \
+skipped whatever their size. Each skipped file is reported with the reason on stderr, and \
+among the warnings of the --full-output metadata of extract-sdk-calls. --include oversized \
+analyzes them anyway.";
+
+const DEPENDENCY_DEPTH_LONG_HELP: &str = "Also analyze the third-party dependencies of the \
+project, since libraries such as ORMs and storage adapters make AWS calls the project's own \
+code never shows: 1 for the dependencies it declares, 2 for their dependencies too, and so on. \
+Dependencies are read from the package.json, requirements.txt, pyproject.toml or go.mod above \
+the source files, and analyzed where they're installed: node_modules, the site-packages of the \
+active virtual environment or of .venv, venv or env, and the Go module cache. The AWS SDKs \
+themselves aren't analyzed, nor are Java dependencies, which are installed compiled. Not \
+analyzed by default.";
+
+const ALLOW_DEPENDENCY_LONG_HELP: &str = "Only analyze the dependencies whose name matches this \
+pattern, e.g. typeorm, '@acme/*', sqlalchemy or 'github.com/acme/*'. The dependencies of the \
+others are still followed, down to --dependency-depth. Can be repeated; all dependencies are \
+analyzed by default.";
+
+const FAIL_ON_LONG_HELP: &str = "Fail, without outputting the policies, if the analysis \
+finds any of these, so pipelines choose whether incomplete extraction or risky permissions \
+fail the build: 'unresolved' for calls on clients whose service couldn't be resolved, \
+'ambiguous' for operations existing in several services, 'unsupported' for client methods \
+named at runtime, 'sensitive-action' for privileged or escalation-prone actions as flagged by \
+--flag-sensitive, 'unscoped' for actions granted on all resources as reported by \
+--report-unscoped, and 'filtered-action' for actions stripped by --deny-actions or \
+--allow-actions. The findings are reported on stderr; a SARIF log (--sarif) is still \
+written. Comma-separated, e.g. --fail-on unresolved,ambiguous,sensitive-action.";
+
+const SARIF_LONG_HELP: &str = "Write the places in the code where the analysis lost \
+precision to a SARIF 2.1.0 log, so code scanning shows them as annotations on the exact \
+lines: calls on clients whose service couldn't be resolved (unresolved-client), operations \
+existing in several services (ambiguous-operation), and client methods named at runtime, \
+whose permissions aren't in the policies (unsupported-pattern).";
+
+const HTML_REPORT_LONG_HELP: &str = "Write a standalone HTML report of the generated policies \
+for security reviewers who don't run the CLI: every statement with the source locations and \
+expressions of the calls requiring its actions and the sensitive actions it grants, as with \
+--provenance and --flag-sensitive, followed by the coverage of the call sites, as with \
+--coverage. The report needs no other files or network access to be viewed.";
+
+const ACTION_INVENTORY_LONG_HELP: &str = "Write the generated actions to a CSV file for \
+spreadsheets and GRC tooling, one row per action, resource it's granted on and call requiring \
+it, with the columns service, action, resource, file, line and confidence (High, Medium or Low, \
+as in list-calls). Actions of other inputs, such as an Access Analyzer policy, have a row \
+without call site.";
+
+const METADATA_LONG_HELP: &str = "Embed the metadata of the run, so a deployed policy traces \
+back to the run that generated it, in the comma-separated targets: 'id' sets the Id of the \
+policies to IamPolicyAutopilot-<version>-<run id>, 'sid' prefixes the Sids of their statements \
+with Ipa<run id>, and 'description' describes the policies uploaded with --upload-policies with \
+the tool version, timestamp, git commit and input hash. The run id is a digest of these.";
+
+const METADATA_MANIFEST_LONG_HELP: &str = "Write the metadata of the run to a JSON sidecar \
+file: the ToolVersion, the GitCommit of the repository of the sources and whether it had \
+uncommitted changes (GitDirty), the Timestamp, the SHA-256 InputHash of the paths and contents \
+of the inputs, and the RunId --metadata embeds.";
+
+const SPLIT_BY_SERVICE_LONG_HELP: &str = "Write the generated policies to DIR as one policy \
+document per AWS service, policy-<service>.json (e.g. policy-s3.json, policy-dynamodb.json), \
+instead of outputting them. Statements granting the actions of several services are split by \
+service, keeping their resources and conditions. DIR/manifest.json lists the Service, File and \
+Actions of each policy. Policies for the credentials of assumed roles are left out.";
+
+const OUTPUT_FORMAT_LONG_HELP: &str = "Format of the generated policies. 'json' (default) \
+outputs the policies with their metadata. 'cloudformation' outputs a CloudFormation template \
+with an AWS::IAM::ManagedPolicy resource per policy, and 'cloudformation-inline' one with \
+AWS::IAM::RolePolicy resources instead. The policies are attached to the role named by the \
+template's RoleName parameter; policies for assumed-role credentials are attached to \
+AssumedRoleName parameters defaulting to the assumed role's name. 'terraform' outputs Terraform \
+configuration with an aws_iam_policy_document data source and an aws_iam_policy resource per \
+policy, escaping ${...} in ARNs so they aren't interpolated. 'cdk-typescript' and 'cdk-python' \
+output CDK code declaring a list of iam.PolicyStatement per policy, to add to a role with \
+addToPolicy (add_to_policy in Python). 'scp' outputs a service control policy allowing the \
+actions of all policies, to replace the FullAWSAccess SCP of the accounts running the workload, \
+and 'scp-deny' one denying every other action next to FullAWSAccess. SCP statements grant on \
+all resources without conditions; services with the most actions are collapsed to service:* \
+when the actions exceed the SCP size limit. 'role-json', 'role-cloudformation' and \
+'role-terraform' output a complete IAM role: a trust policy allowing the service of the runtime \
+(see --runtime) to assume it, the managed policies the runtime needs such as \
+AWSLambdaBasicExecutionRole, and the generated policies inline, as JSON role properties, a \
+CloudFormation template or Terraform configuration; EC2 roles come with an instance profile. \
+'identity-pool-json' and 'identity-pool-cloudformation' output the authenticated and \
+unauthenticated roles of a Cognito identity pool for mobile and web clients, as JSON role \
+properties or a CloudFormation template attaching them to the pool: trust policies allowing \
+cognito-identity.amazonaws.com to assume them with sts:AssumeRoleWithWebIdentity for the \
+identities of the pool ({{IdentityPoolId}}, or the template's IdentityPoolId parameter) of \
+their kind, with the aud and amr conditions. The policies of the unauthenticated principal of \
+--principals go to both roles, the others to the authenticated role alone. \
+'opa' outputs a JSON document for Open Policy Agent, e.g. opa eval --input, with the policies \
+under Policies, the services and actions they grant under Services and Actions, and the calls \
+with the actions they require under Calls. Cannot be combined with --upload-policies.";
+
+const RUNTIME_LONG_HELP: &str = "Compute runtime whose service assumes the role of the \
+role-json, role-cloudformation and role-terraform output formats: lambda, ecs (tasks, \
+including Fargate) or ec2. By default, the runtime is detected from the code: Lambda handlers \
+and the Lambda runtime libraries, the ECS task metadata endpoint, or the EC2 instance metadata \
+service.";
+
+const RESTRICT_REGIONS_LONG_HELP: &str = "Restrict every generated statement to the given \
+regions with an aws:RequestedRegion condition, as security baselines commonly require of \
+workload roles. Without regions, the policies are restricted to the regions the code configures \
+its clients with, e.g. boto3.client(\"s3\", region_name=\"eu-west-1\"), or to --region if the \
+code configures none. Statements of global services such as IAM also allow the region of their \
+global endpoint (us-east-1 in the aws partition). \
+Examples:\n  \
+--restrict-regions                        # Regions of the clients in the code\n  \
+--restrict-regions=eu-west-1,eu-central-1 # Allow-list of regions";
+
+const SOURCE_VPCE_LONG_HELP: &str = "Restrict the statements of the services the workload \
+reaches through VPC endpoints (see --vpc-endpoint-services) to requests through the given VPC \
+endpoints, with an aws:SourceVpce condition, for data perimeters.";
+
+const SOURCE_VPC_LONG_HELP: &str = "Restrict the statements of the services the workload reaches \
+through VPC endpoints (see --vpc-endpoint-services) to requests from the given VPCs, with an \
+aws:SourceVpc condition, for data perimeters.";
+
+const SOURCE_IP_LONG_HELP: &str = "Restrict the statements of the services the workload doesn't \
+reach through VPC endpoints to requests from the given public IP addresses or CIDR ranges, with \
+an aws:SourceIp condition, for data perimeters. Requests through VPC endpoints have no source \
+IP, so statements of the endpoint services are restricted to the endpoints only.";
+
+const VPC_ENDPOINT_SERVICES_LONG_HELP: &str = "Services the workload reaches through the VPC \
+endpoints of --source-vpce and --source-vpc, e.g. s3 dynamodb. Statements granting actions of \
+these and of other services are split in two. By default, all services are reached through the \
+VPC endpoints.";
+
+const ACCESS_ANALYZER_POLICY_LONG_HELP: &str = "Merge the policy IAM Access Analyzer \
+generated from the role's CloudTrail activity with the generated policies, so calls the \
+static analysis can't see (e.g. operations named at runtime) are covered as well. The file is \
+the generated policy document or the GetGeneratedPolicy response. Actions the generated \
+policies don't grant are added as statements of their own, with placeholders such as \
+${BucketName} granted as '*'. StatementOrigins labels each statement StaticAnalysis, \
+AccessAnalyzer or Both.";
+
+const AGGREGATE_MERGE_LONG_HELP: &str = "Analyze the paths together and output their merged \
+policies, in the format of generate-policies, with the Provenance of each action: its \
+resources and the Namespaces of the paths whose calls require it. The sources of a run are in \
+one language, so paths in several languages have merged policies per language.";
+
+const BENCH_BASELINE_LONG_HELP: &str = "Report of a previous bench run, e.g. of the last \
+release, to compare with. The change of the median duration of each phase is reported on \
+stderr.";
+
+const MAX_REGRESSION_LONG_HELP: &str = "Fail with exit code 1 if the median duration of a \
+phase is more than this many percent longer than in the --baseline report, e.g. 10.";
+
+#[cfg(feature = "profiling")]
+const PROFILE_LONG_HELP: &str = "Directory to write profiles of the run to when the command \
+finishes: cpu.pb, the call stacks sampled a thousand times a second, and heap.pb, the memory \
+allocated and not yet freed, both in the pprof format, e.g. for go tool pprof -http=: cpu.pb. \
+Only builds with the profiling feature have this option.";
+
 const LONG_ABOUT: &str = r"Unified tool that combines IAM policy generation from source code analysis with
 automatic AccessDenied error fixing.
 
@@ -141,6 +1165,9 @@ Examples:
   iam-policy-autopilot generate-policies src/**/*.py \
     --service-hints s3 iam --region us-east-1 --account 123456789012 --pretty
 
+  iam-policy-autopilot simulate example.py \
+    --region us-east-1 --account 123456789012 --pretty
+
   iam-policy-autopilot mcp-server
 
   iam-policy-autopilot mcp-server --transport http --port 8001";
@@ -158,6 +1185,11 @@ Examples:
 struct Cli {
     #[command(subcommand)]
     command: Commands,
+
+    /// Directory to write CPU and heap profiles of the run to
+    #[cfg(feature = "profiling")]
+    #[arg(long = "profile", value_name = "DIR", global = true, long_help = PROFILE_LONG_HELP)]
+    profile: Option<PathBuf>,
 }
 
 #[derive(Subcommand, Debug, TelemetryEventDerive)]
@@ -221,6 +1253,19 @@ If both --debug and --verbose are specified, --debug takes precedence."
         )]
         debug: bool,
 
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
         /// Format JSON output with indentation for readability
         #[arg(
             short = 'p',
@@ -258,6 +1303,9 @@ This flag has no effect on the generate-policies subcommand."
             long_help = SERVICE_HINTS_LONG_HELP,
         )]
         service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        analysis: AnalysisArgs,
     },
 
     /// Generates baseline IAM policy documents from source files
@@ -279,7 +1327,11 @@ final policy may still include actions from other services if required."#
     #[telemetry(command = "generate-policies")]
     GeneratePolicies {
         /// Source files to analyze for SDK method extraction
-        #[arg(required = true, num_args = 1..)]
+        #[arg(
+            required_unless_present = "go_binaries",
+            num_args = 1..,
+            long_help = SOURCE_FILES_LONG_HELP
+        )]
         #[telemetry(count)]
         source_files: Vec<PathBuf>,
 
@@ -287,6 +1339,19 @@ final policy may still include actions from other services if required."#
         #[arg(hide = true, short = 'd', long = "debug")]
         debug: bool,
 
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
         /// Format JSON output with indentation for readability
         #[arg(short = 'p', long = "pretty")]
         #[telemetry(value)]
@@ -317,12 +1382,17 @@ Examples: us-east-1, us-west-2, eu-west-1."
         #[arg(
             short = 'a',
             long = "account",
+            visible_alias = "account-id",
             default_value = "*",
             long_help = "AWS account ID to use for ARN generation."
         )]
         #[telemetry(presence, default = "*")]
         account: String,
 
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
         /// Output separate policies for each method call instead of a single merged policy
         #[arg(
             hide = true,
@@ -362,7 +1432,8 @@ service, so a single service's actions may be spread across multiple statements
             long = "disable-cache",
             long_help = "When enabled, disables file system caching for service reference data. \
 By default, service reference data is cached in the system temp directory for 6 hours to improve performance. \
-Use this flag to force fresh data retrieval on every run."
+Use this flag to force fresh data retrieval on every run. Service references saved by \
+update-mappings are still used."
         )]
         #[telemetry(value)]
         disable_cache: bool,
@@ -377,21 +1448,483 @@ Default: 4."
         #[telemetry(value, if_present)]
         resource_cutoff: Option<usize>,
 
-        /// Filter extracted SDK calls to specific AWS services
+        /// Keep wildcard resources instead of scoping them to literal identifiers at call sites
+        #[arg(long = "wildcard-resources", long_help = WILDCARD_RESOURCES_LONG_HELP)]
+        #[telemetry(value)]
+        wildcard_resources: bool,
+
+        /// Application configuration files the code reads resource names from
+        #[arg(long = "app-config", num_args = 1.., long_help = APP_CONFIG_LONG_HELP)]
+        #[telemetry(presence)]
+        app_config: Vec<PathBuf>,
+
+        /// Prompt for resources that can't be resolved statically
+        #[arg(long = "interactive", long_help = INTERACTIVE_LONG_HELP)]
+        #[telemetry(value)]
+        interactive: bool,
+
+        /// File of recorded answers for resources that can't be resolved statically
+        #[arg(long = "answers-file", long_help = ANSWERS_FILE_LONG_HELP)]
+        #[telemetry(presence)]
+        answers_file: Option<PathBuf>,
+
+        /// File of recorded services for calls whose operation exists in several services
         #[arg(
-            long = "service-hints",
-            num_args = 1..,
-            long_help = SERVICE_HINTS_LONG_HELP,
+            long = "disambiguation-file",
+            value_name = "PATH",
+            long_help = DISAMBIGUATION_FILE_LONG_HELP
         )]
-        #[telemetry(list)]
-        service_hints: Option<Vec<String>>,
+        #[telemetry(presence)]
+        disambiguation_file: Option<PathBuf>,
 
-        /// Generate explanations for why actions were added, filtered to specific action patterns
+        /// File of operation to action mappings merged over the built-in ones
         #[arg(
-            long = "explain",
-            num_args = 1..,
-            value_name = "ACTION_PATTERNS",
-            long_help = "Generates detailed explanations for why IAM actions were added to the policy. \
+            long = "mapping-overrides",
+            value_name = "PATH",
+            long_help = MAPPING_OVERRIDES_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        mapping_overrides: Option<PathBuf>,
+
+        /// Lockfile pinning the service references, for reproducible policies
+        #[arg(
+            long = "service-reference-lock",
+            value_name = "PATH",
+            long_help = SERVICE_REFERENCE_LOCK_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        service_reference_lock: Option<PathBuf>,
+
+        /// Directory caching the extracted SDK calls, so unchanged sources aren't analyzed again
+        #[arg(
+            long = "extraction-cache",
+            value_name = "DIR",
+            long_help = EXTRACTION_CACHE_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        extraction_cache: Option<PathBuf>,
+
+        /// File of services other than AWS ones the code calls
+        #[arg(
+            long = "custom-services",
+            value_name = "PATH",
+            long_help = CUSTOM_SERVICES_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        custom_services: Option<PathBuf>,
+
+        /// File mapping the source files to named principals, for a policy per principal
+        #[arg(long = "principals", value_name = "PATH", long_help = PRINCIPALS_LONG_HELP)]
+        #[telemetry(presence)]
+        principals: Option<PathBuf>,
+
+        /// Minimum confidence of the services of the calls granted in the policies
+        #[arg(
+            long = "min-confidence",
+            value_name = "LEVEL",
+            value_parser = ["low", "medium", "high"],
+            long_help = MIN_CONFIDENCE_LONG_HELP
+        )]
+        #[telemetry(value, if_present)]
+        min_confidence: Option<String>,
+
+        /// Emit parameterized policies with template variables for unknown resources
+        #[arg(long = "template", conflicts_with = "upload_policies", long_help = TEMPLATE_LONG_HELP)]
+        #[telemetry(value)]
+        template: bool,
+
+        /// S3 resource forms to grant access through
+        #[arg(
+            long = "s3-resource-forms",
+            num_args = 1..,
+            value_parser = [
+                "bucket",
+                "access-point",
+                "object-lambda",
+                "multi-region-access-point",
+                "mrap",
+            ],
+            long_help = S3_RESOURCE_FORMS_LONG_HELP
+        )]
+        #[telemetry(list)]
+        s3_resource_forms: Vec<String>,
+
+        /// Report actions granted on all resources and why they couldn't be scoped
+        #[arg(long = "report-unscoped", long_help = REPORT_UNSCOPED_LONG_HELP)]
+        #[telemetry(value)]
+        report_unscoped: bool,
+
+        /// Compact enumerated actions into wildcards that match only granted actions
+        #[arg(long = "compact-actions", long_help = COMPACT_ACTIONS_LONG_HELP)]
+        #[telemetry(value)]
+        compact_actions: bool,
+
+        /// Suggest AWS managed policies covering generated statements
+        #[arg(long = "managed-policies", long_help = MANAGED_POLICIES_LONG_HELP)]
+        #[telemetry(value)]
+        managed_policies: bool,
+
+        /// Generate trust policy stubs for the roles the code assumes
+        #[arg(long = "trust-policies", long_help = TRUST_POLICIES_LONG_HELP)]
+        #[telemetry(value)]
+        trust_policies: bool,
+
+        /// Generate the resource-based policies the code implies
+        #[arg(long = "resource-policies", long_help = RESOURCE_POLICIES_LONG_HELP)]
+        #[telemetry(value)]
+        resource_policies: bool,
+
+        /// Report the calls on resources of other accounts
+        #[arg(long = "cross-account-report", long_help = CROSS_ACCOUNT_REPORT_LONG_HELP)]
+        #[telemetry(value)]
+        cross_account_report: bool,
+
+        /// Role of the analyzed workload, trusted by the roles it assumes
+        #[arg(
+            long = "workload-role-arn",
+            long_help = WORKLOAD_ROLE_ARN_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        workload_role_arn: Option<String>,
+
+        /// Suggest condition keys narrowing generated statements
+        #[arg(long = "suggest-conditions", long_help = SUGGEST_CONDITIONS_LONG_HELP)]
+        #[telemetry(value)]
+        suggest_conditions: bool,
+
+        /// Grant the permissions of the tracing, metrics and logging instrumentation of the code
+        #[arg(
+            long = "observability-permissions",
+            long_help = OBSERVABILITY_PERMISSIONS_LONG_HELP
+        )]
+        #[telemetry(value)]
+        observability_permissions: bool,
+
+        /// OpenTelemetry Collector configurations whose AWS exporters need permissions
+        #[arg(
+            long = "collector-config",
+            value_name = "PATH",
+            requires = "observability_permissions",
+            long_help = COLLECTOR_CONFIG_LONG_HELP
+        )]
+        #[telemetry(count)]
+        collector_config: Vec<PathBuf>,
+
+        /// Grant the permissions of the event sources of the Lambda handlers of the code
+        #[arg(long = "event-source-permissions", long_help = EVENT_SOURCE_PERMISSIONS_LONG_HELP)]
+        #[telemetry(value)]
+        event_source_permissions: bool,
+
+        /// Grant multipart uploads the actions of the whole multipart upload lifecycle
+        #[arg(long = "s3-multipart-actions", long_help = S3_MULTIPART_ACTIONS_LONG_HELP)]
+        #[telemetry(value)]
+        s3_multipart_actions: bool,
+
+        /// Grant no KMS permissions for the services encrypting data on behalf of the calls
+        #[arg(long = "no-kms-via-service", long_help = NO_KMS_VIA_SERVICE_LONG_HELP)]
+        #[telemetry(value)]
+        no_kms_via_service: bool,
+
+        /// Services to grant the KMS permissions used on their behalf for
+        #[arg(
+            long = "kms-via-services",
+            num_args = 1..,
+            value_name = "SERVICES",
+            long_help = KMS_VIA_SERVICES_LONG_HELP
+        )]
+        #[telemetry(list)]
+        kms_via_services: Vec<String>,
+
+        /// KMS keys to grant the permissions used on behalf of the calls on
+        #[arg(
+            long = "kms-key-arns",
+            num_args = 1..,
+            value_name = "[SERVICE=]ARNS",
+            long_help = KMS_KEY_ARNS_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        kms_key_arns: Vec<String>,
+
+        /// Leave the actions of these services out of the policies, e.g. those of a base policy
+        #[arg(
+            long = "exclude-services",
+            num_args = 1..,
+            value_delimiter = ',',
+            value_name = "SERVICES",
+            long_help = EXCLUDE_SERVICES_LONG_HELP
+        )]
+        #[telemetry(list)]
+        exclude_services: Vec<String>,
+
+        /// Strip these actions from the policies, e.g. actions the organization forbids
+        #[arg(
+            long = "deny-actions",
+            num_args = 1..,
+            value_delimiter = ',',
+            value_name = "ACTIONS",
+            long_help = DENY_ACTIONS_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        deny_actions: Vec<String>,
+
+        /// Only let the policies grant these actions, stripping every other
+        #[arg(
+            long = "allow-actions",
+            num_args = 1..,
+            value_delimiter = ',',
+            value_name = "ACTIONS",
+            long_help = ALLOW_ACTIONS_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        allow_actions: Vec<String>,
+
+        /// Split the policies into read-only and write policies
+        #[arg(long = "split-read-write", long_help = SPLIT_READ_WRITE_LONG_HELP)]
+        #[telemetry(value)]
+        split_read_write: bool,
+
+        /// Generate a policy per entry point of the code
+        #[arg(long = "per-entry-point", long_help = PER_ENTRY_POINT_LONG_HELP)]
+        #[telemetry(value)]
+        per_entry_point: bool,
+
+        /// Validate the generated policies with IAM Access Analyzer
+        #[arg(long = "validate", long_help = VALIDATE_LONG_HELP)]
+        #[telemetry(value)]
+        validate: bool,
+
+        /// Fail if the generated policies grant access a reference policy doesn't
+        #[arg(
+            long = "check-no-new-access",
+            value_name = "PATH",
+            long_help = CHECK_NO_NEW_ACCESS_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        check_no_new_access: Option<PathBuf>,
+
+        /// Fail if the generated policies grant any of these actions
+        #[arg(
+            long = "forbidden-actions",
+            num_args = 1..,
+            value_delimiter = ',',
+            value_name = "ACTIONS",
+            long_help = FORBIDDEN_ACTIONS_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        forbidden_actions: Vec<String>,
+
+        /// Fail if the analysis finds any of these
+        #[arg(
+            long = "fail-on",
+            num_args = 1..,
+            value_delimiter = ',',
+            value_name = "FINDINGS",
+            value_parser = [
+                "unresolved",
+                "ambiguous",
+                "unsupported",
+                "sensitive-action",
+                "unscoped",
+                "filtered-action",
+            ],
+            long_help = FAIL_ON_LONG_HELP
+        )]
+        #[telemetry(list)]
+        fail_on: Vec<String>,
+
+        /// Flag privileged and escalation-prone actions of the generated statements
+        #[arg(long = "flag-sensitive", long_help = FLAG_SENSITIVE_LONG_HELP)]
+        #[telemetry(value)]
+        flag_sensitive: bool,
+
+        /// Summarize the generated actions by service and IAM access level
+        #[arg(long = "access-summary", long_help = ACCESS_SUMMARY_LONG_HELP)]
+        #[telemetry(value)]
+        access_summary: bool,
+
+        /// Report how completely the call sites of the code were resolved
+        #[arg(long = "coverage", long_help = COVERAGE_LONG_HELP)]
+        #[telemetry(value)]
+        coverage: bool,
+
+        /// Write the calls requiring each generated action to a sidecar file
+        #[arg(long = "provenance", value_name = "PATH", long_help = PROVENANCE_LONG_HELP)]
+        #[telemetry(presence)]
+        provenance: Option<PathBuf>,
+
+        /// Write the places the analysis lost precision to a SARIF log
+        #[arg(long = "sarif", value_name = "PATH", long_help = SARIF_LONG_HELP)]
+        #[telemetry(presence)]
+        sarif: Option<PathBuf>,
+
+        /// Write a standalone HTML report for least-privilege reviews of the policies
+        #[arg(long = "html-report", value_name = "PATH", long_help = HTML_REPORT_LONG_HELP)]
+        #[telemetry(presence)]
+        html_report: Option<PathBuf>,
+
+        /// Write the generated actions by resource and call site to a CSV file
+        #[arg(
+            long = "action-inventory",
+            value_name = "PATH",
+            long_help = ACTION_INVENTORY_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        action_inventory: Option<PathBuf>,
+
+        /// Embed the metadata of the run in the policies: id, sid or description
+        #[arg(
+            long = "metadata",
+            value_delimiter = ',',
+            value_name = "TARGETS",
+            value_parser = ["id", "sid", "description"],
+            long_help = METADATA_LONG_HELP
+        )]
+        #[telemetry(list)]
+        metadata: Vec<String>,
+
+        /// Write the metadata of the run to a sidecar manifest
+        #[arg(
+            long = "metadata-manifest",
+            value_name = "PATH",
+            long_help = METADATA_MANIFEST_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        metadata_manifest: Option<PathBuf>,
+
+        /// Write a policy file per AWS service to a directory, with a manifest
+        #[arg(
+            long = "split-by-service",
+            value_name = "DIR",
+            conflicts_with_all = ["upload_policies", "individual_policies"],
+            long_help = SPLIT_BY_SERVICE_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        split_by_service: Option<PathBuf>,
+
+        /// Runtime whose service assumes the role of role output formats
+        #[arg(
+            long = "runtime",
+            value_parser = ["lambda", "ecs", "ec2"],
+            long_help = RUNTIME_LONG_HELP
+        )]
+        #[telemetry(value, if_present)]
+        runtime: Option<String>,
+
+        /// Restrict the statements to regions with aws:RequestedRegion conditions
+        #[arg(
+            long = "restrict-regions",
+            num_args = 0..=1,
+            require_equals = true,
+            value_delimiter = ',',
+            value_name = "REGIONS",
+            long_help = RESTRICT_REGIONS_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        restrict_regions: Option<Vec<String>>,
+
+        /// Restrict the statements of endpoint services to VPC endpoints
+        #[arg(
+            long = "source-vpce",
+            num_args = 1..,
+            value_name = "VPC_ENDPOINT_IDS",
+            long_help = SOURCE_VPCE_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        source_vpce: Vec<String>,
+
+        /// Restrict the statements of endpoint services to VPCs
+        #[arg(
+            long = "source-vpc",
+            num_args = 1..,
+            value_name = "VPC_IDS",
+            long_help = SOURCE_VPC_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        source_vpc: Vec<String>,
+
+        /// Restrict the statements of other services to public IP ranges
+        #[arg(
+            long = "source-ip",
+            num_args = 1..,
+            value_name = "CIDRS",
+            long_help = SOURCE_IP_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        source_ip: Vec<String>,
+
+        /// Services reached through the VPC endpoints
+        #[arg(
+            long = "vpc-endpoint-services",
+            num_args = 1..,
+            value_name = "SERVICES",
+            long_help = VPC_ENDPOINT_SERVICES_LONG_HELP
+        )]
+        #[telemetry(list)]
+        vpc_endpoint_services: Vec<String>,
+
+        /// Merge a policy generated by IAM Access Analyzer from CloudTrail
+        #[arg(
+            long = "access-analyzer-policy",
+            value_name = "PATH",
+            long_help = ACCESS_ANALYZER_POLICY_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        access_analyzer_policy: Option<PathBuf>,
+
+        /// Output format of the generated policies
+        #[arg(
+            long = "output-format",
+            default_value = "json",
+            value_parser = [
+                "json",
+                "cloudformation",
+                "cloudformation-inline",
+                "terraform",
+                "cdk-typescript",
+                "cdk-python",
+                "scp",
+                "scp-deny",
+                "role-json",
+                "role-cloudformation",
+                "role-terraform",
+                "identity-pool-json",
+                "identity-pool-cloudformation",
+                "opa",
+            ],
+            long_help = OUTPUT_FORMAT_LONG_HELP
+        )]
+        #[telemetry(value)]
+        output_format: String,
+
+        /// Filter extracted SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+
+        /// Compiled Go binaries to analyze instead of source files (experimental)
+        #[arg(
+            long = "go-binary",
+            value_name = "PATH",
+            conflicts_with = "source_files",
+            long_help = GO_BINARY_LONG_HELP
+        )]
+        #[telemetry(count)]
+        go_binaries: Vec<PathBuf>,
+
+        /// Generate explanations for why actions were added, filtered to specific action patterns
+        #[arg(
+            long = "explain",
+            num_args = 1..,
+            value_name = "ACTION_PATTERNS",
+            long_help = "Generates detailed explanations for why IAM actions were added to the policy. \
 Requires one or more action patterns. Use '*' to explain all actions. \
 Patterns support wildcards (*) that match any sequence of characters. \
 Examples:\n  \
@@ -472,357 +2005,4078 @@ Examples:\n  \
         explain_resources: Option<Vec<String>>,
     },
 
-    /// Generates an external library model from source code using call graph analysis
-    #[cfg(feature = "model-generation")]
+    /// Simulates the calls of source files against the generated policy
     #[command(
-        long_about = "Analyzes source code to build a call graph from specified entry points, \
-extracts AWS SDK calls reachable from those entry points, and outputs an external library model \
-JSON that maps each entry point to its required SDK operations.\n\n\
-Entry points are specified as file:line:column (1-based). Point to the start of a function \
-declaration. Example: --entry-point handler.go:14:1"
+        long_about = "Simulates the AWS SDK calls of source files against the policy generated \
+for them with the IAM policy simulator (iam:SimulateCustomPolicy), to catch resource scoping or \
+conditions that deny the code's own operations. Each call is simulated as its action on an \
+example resource, the ARN pattern the call was resolved to with its wildcards replaced by \
+'example'. Requests with an aws:RequestedRegion condition are simulated in --region. \
+Outputs the decision of each request as JSON and fails when any is denied; requests denied \
+only because the simulation lacks values of condition keys, such as aws:SourceIp, are listed \
+with those keys but don't fail the command. Requires AWS credentials allowing \
+iam:SimulateCustomPolicy."
     )]
-    #[telemetry(skip)]
-    GenerateModel {
-        /// Source files to analyze
+    #[telemetry(command = "simulate")]
+    Simulate {
+        /// Source files whose SDK calls are simulated
         #[arg(required = true, num_args = 1..)]
+        #[telemetry(count)]
         source_files: Vec<PathBuf>,
 
-        /// Entry points as file:line:column (1-based, pointing to function declaration).
-        /// If omitted, all exported functions are used as entry points.
-        #[arg(long = "entry-point", num_args = 1..)]
-        entry_points: Vec<String>,
-
-        /// Name for the generated library model
-        #[arg(long = "library-name", required = true)]
-        library_name: String,
-
-        /// Enable debug logging output to stderr
+        /// Enable debug logging output to stderr (most verbose)
         #[arg(hide = true, short = 'd', long = "debug")]
         debug: bool,
 
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
         /// Format JSON output with indentation for readability
         #[arg(short = 'p', long = "pretty")]
+        #[telemetry(value)]
         pretty: bool,
 
         /// Override programming language detection
         #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
         language: Option<String>,
 
-        /// Filter extracted SDK calls to specific AWS services
-        #[arg(long = "service-hints", num_args = 1..)]
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation and as the requested region \
+of the simulated requests."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Simulate against this policy instead of generating one
+        #[arg(
+            long = "policy-file",
+            long_help = "Simulate against the policies of this file instead of the policy \
+generated with default options: the JSON output of generate-policies, e.g. generated with \
+--restrict-regions or edited by hand, or a single IAM policy document."
+        )]
+        #[telemetry(presence)]
+        policy_file: Option<PathBuf>,
+
+        /// Filter the simulated SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
         service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
     },
 
-    /// Start MCP server
+    /// Compares the generated policy with the actions a role used according to CloudTrail
     #[command(
-        long_about = "Starts an MCP server that provides IAM policy generation \
-and AccessDenied error fixing capabilities to IDEs and other tools. The server can run in stdio mode \
-for direct integration or HTTP mode for network-based communication. \
-Supports both transport mechanisms with configurable logging."
+        long_about = "Compares the policy generated for source files with the actions a role \
+was observed using in CloudTrail, and reports the actions observed but not generated (calls \
+the static analysis missed, e.g. with computed operation names) and the actions generated but \
+never observed (code paths that didn't run in the time window, or permissions the code doesn't \
+need). Observed actions are looked up in the CloudTrail event history of --region, which keeps \
+90 days of management events and requires cloudtrail:LookupEvents, or read from an export \
+with --cloudtrail-export. Data events such as s3:GetObject are only observed in exports of \
+trails logging them."
     )]
-    // MCP server notice is sent through `notifications/message` on initialization
-    #[telemetry(command = "mcp-server", skip_notice)]
-    McpServer {
-        /// Transport mechanism for MCP communication
-        #[arg(short = 't', long = "transport", default_value_t = McpTransport::Stdio,
-              long_help = "Transport mechanism for MCP communication. 'stdio' uses standard input/output \
-for direct integration with IDEs and tools. 'http' starts an HTTP server for network-based communication.")]
-        #[telemetry(value)]
-        transport: McpTransport,
+    #[telemetry(command = "check-usage")]
+    CheckUsage {
+        /// Source files to generate the policy for
+        #[arg(required = true, num_args = 1..)]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
 
-        /// Port number for HTTP transport (ignored for stdio transport)
-        #[arg(short = 'p', long = "port", default_value_t = MCP_HTTP_DEFAULT_PORT,
-              long_help = "Port number to bind the HTTP server to when using HTTP transport. \
-Only used when --transport=http. The server will bind to the specified address on the specified port.")]
-        #[telemetry(skip)]
-        port: u16,
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
 
-        /// Bind address for HTTP transport (ignored for stdio transport)
-        #[arg(short = 'b', long = "bind-address", default_value_t = DEFAULT_BIND_ADDRESS.to_string(),
-              long_help = "IP address to bind the HTTP server to when using HTTP transport. \
-Only used when --transport=http. Defaults to 127.0.0.1 (localhost). \
-Use 0.0.0.0 to listen on all interfaces.")]
-        bind_address: String,
-    },
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
 
-    #[command(
-        about = "Print version information.",
-        short_flag = 'V',
-        long_flag = "version"
-    )]
-    #[telemetry(skip)]
-    Version {
-        #[arg(long = "verbose", default_value_t = false, hide = true)]
-        verbose: bool,
-    },
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
 
-    /// Manage anonymous telemetry settings
-    #[command(long_about = "View or change anonymous telemetry settings.\n\n\
-IAM Policy Autopilot collects anonymous usage metrics to improve the tool.\n\
-No file paths, policy content, AWS account IDs, or credentials are ever collected.\n\n\
-Use --enable or --disable to persist your preference to ~/.iam-policy-autopilot/config.json.\n\
-Use --status to view the current telemetry state.\n\n\
-The DISABLE_IAM_POLICY_AUTOPILOT_TELEMETRY=true environment variable disables telemetry, overriding the config file.")]
-    #[telemetry(skip)]
-    Telemetry {
-        /// Enable anonymous telemetry
-        #[arg(long = "enable", conflicts_with = "disable")]
-        enable: bool,
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        #[telemetry(value)]
+        pretty: bool,
 
-        /// Disable anonymous telemetry
-        #[arg(long = "disable", conflicts_with = "enable")]
-        disable: bool,
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
 
-        /// Show current telemetry status
-        #[arg(long = "status")]
-        status: bool,
-    },
-}
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation, whose CloudTrail event history \
+is queried. The configured region is queried when '*'."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
 
-/// Initialize logging based on configuration
-fn init_logging(debug: bool) -> Result<()> {
-    let log_level = if debug {
-        // Debug takes precedence - most verbose logging including TRACE
-        log::LevelFilter::Trace
-    } else {
-        // Default: only ERROR messages
-        log::LevelFilter::Error
-    };
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
 
-    env_logger::Builder::from_default_env()
-        .filter_level(log_level)
-        .format_target(false)
-        .format_timestamp_secs()
-        .init();
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
 
-    Ok(())
-}
+        /// Role whose CloudTrail events are compared
+        #[arg(
+            long = "role-arn",
+            required_unless_present = "cloudtrail_export",
+            long_help = "ARN of the role the code runs as. Events of sessions of this role \
+are compared; with --cloudtrail-export, events of other principals are left out, and all \
+events are compared when not given."
+        )]
+        #[telemetry(presence)]
+        role_arn: Option<String>,
 
-/// Handle the extract-sdk-calls subcommand
-async fn handle_extract_sdk_calls(config: &SharedConfig) -> Result<()> {
-    use iam_policy_autopilot_policy_generation::api::model::ServiceHints;
+        /// Days of CloudTrail event history to query, up to now
+        #[arg(
+            long = "days",
+            conflicts_with = "cloudtrail_export",
+            value_parser = clap::value_parser!(u32).range(1..=90),
+            long_help = "Days of CloudTrail event history to query, up to now. Default: 90, \
+all the event history keeps."
+        )]
+        #[telemetry(value, if_present)]
+        days: Option<u32>,
 
-    info!("Running extract-sdk-calls command");
+        /// CloudTrail export to read instead of the event history
+        #[arg(
+            long = "cloudtrail-export",
+            long_help = "Read the observed actions from this file instead of the CloudTrail \
+event history: a CloudTrail log file ({\"Records\": [...]}), or the JSON results of an Athena \
+or CloudTrail Lake query, as an array or JSON lines of events with at least their eventSource \
+and eventName."
+        )]
+        #[telemetry(presence)]
+        cloudtrail_export: Option<PathBuf>,
 
-    // Validate configuration
-    config
-        .validate()
-        .context("Configuration validation failed")?;
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
 
-    let service_hints = config.service_hints.as_ref().map(|names| ServiceHints {
-        service_names: names.clone(),
-    });
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
 
-    let results = extract_sdk_calls(&ExtractSdkCallsConfig {
-        source_files: config.source_files.clone(),
-        language: config.language.clone(),
-        service_hints,
-    })
-    .await?;
+    /// Compares the generated policy with an existing policy
+    #[command(
+        long_about = "Compares the policy generated for source files with an existing policy, \
+e.g. the policy attached to the role today, and outputs a structured diff as JSON: the \
+generated actions the existing policy doesn't grant (MissingActions), the actions it grants \
+that the code doesn't need (ExtraActions), and for the actions both grant, the resources \
+(ResourceDifferences) and conditions (ConditionDifferences) they're granted on. Actions and \
+resources of the existing policy may have wildcards; only its Allow statements are compared. \
+With --diff <REF>, only the source files changed since the git ref are analyzed, and the \
+policy generated for them is compared with the policy generated for their version at the \
+ref, so the diff is the delta in required permissions: MissingActions are newly required and \
+ExtraActions no longer required."
+    )]
+    #[telemetry(command = "diff")]
+    Diff {
+        /// Source files to generate the policy for
+        #[arg(required = true, num_args = 1..)]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
 
-    let json_output =
-        SdkMethodCall::serialize_list(&results.methods, config.full_output, config.pretty)
-            .context("Failed to output extracted operations")?;
+        /// Existing policy to compare with
+        #[arg(
+            long = "existing",
+            required_unless_present = "diff_ref",
+            conflicts_with = "diff_ref",
+            long_help = "Policy file to compare the generated policy with: a single IAM \
+policy document, e.g. from aws iam get-policy-version, or the JSON output of generate-policies."
+        )]
+        #[telemetry(presence)]
+        existing: Option<PathBuf>,
 
-    // Output to stdout (not using println! to avoid extra newline in compact mode)
-    print!("{json_output}");
-    if config.pretty {
-        println!(); // Add newline for pretty output
-    }
+        /// Compare the source files changed since a git ref with their version at the ref
+        #[arg(long = "diff", value_name = "REF", long_help = DIFF_REF_LONG_HELP)]
+        #[telemetry(presence)]
+        diff_ref: Option<String>,
 
-    trace!("Extracted methods JSON output written to stdout");
-    Ok(())
-}
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
 
-/// Handle the generate-policies subcommand.
-async fn handle_generate_policy(config: &GeneratePolicyCliConfig) -> Result<()> {
-    use iam_policy_autopilot_policy_generation::api::model::ServiceHints;
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
 
-    info!("Running generate-policies command");
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
 
-    // Validate configuration
-    config
-        .validate()
-        .context("Configuration validation failed")?;
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        #[telemetry(value)]
+        pretty: bool,
 
-    let service_hints = config
-        .shared
-        .service_hints
-        .as_ref()
-        .map(|names| ServiceHints {
-            service_names: names.clone(),
-        });
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
 
-    let result = generate_policies(&GeneratePolicyConfig {
-        extract_sdk_calls_config: ExtractSdkCallsConfig {
-            source_files: config.shared.source_files.clone(),
-            language: config.shared.language.clone(),
-            service_hints,
-        },
-        aws_context: AwsContext::new(config.region.clone(), config.account.clone())?,
-        individual_policies: config.individual_policies,
-        minimize_policy_size: config.minimal_policy_size,
-        disable_file_system_cache: config.disable_cache,
-        explain_filters: config.explain.clone(),
-        terraform_dir: config.tf_dir.clone(),
-        terraform_files: config.tf_files.clone(),
-        tfstate_paths: config.tfstate.clone(),
-        tfvars_files: config.tfvars.clone(),
-        explain_resource_filters: config.explain_resources.clone(),
-        resource_cutoff: config.resource_cutoff.unwrap_or(DEFAULT_RESOURCE_CUTOFF),
-    })
-    .await?;
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
 
-    if config.individual_policies {
-        // Output individual policies
-        trace!("Outputting {} individual policies", result.policies.len());
-        output::output_iam_policies(result, None, config.shared.pretty)
-            .context("Failed to output individual IAM policies")?;
-    } else {
-        // Default behavior: output merged policy with optional upload
-        let upload_result = if config.upload_policies.is_some() {
-            trace!("Uploading policies to AWS IAM");
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
 
-            let uploader = PolicyUploader::new()
-                .await
-                .context("Failed to create policy uploader")?;
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
 
-            let custom_name = config.upload_policies.as_deref().filter(|s| !s.is_empty());
-            let batch_response = uploader
-                .upload_policies(&result.policies, custom_name)
-                .await
-                .context("Failed to upload policies to AWS IAM")?;
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
 
-            debug!(
-                "Upload completed: {} successful, {} failed",
-                batch_response.successful.len(),
-                batch_response.failed.len()
-            );
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
 
-            // Log upload results
-            for upload in &batch_response.successful {
-                debug!(
-                    "Successfully uploaded policy: {} (ARN: {})",
-                    upload.policy_name, upload.policy_arn
-                );
-            }
-            for (index, error) in &batch_response.failed {
-                debug!("Failed to upload policy {index}: {error}");
-            }
+    /// Lists the permission changes between two commits or saved reports
+    #[command(
+        long_about = "Lists the permission changes between two versions of the generated \
+policies, for release notes and change tickets: the actions the later version grants and the \
+earlier doesn't (AddedActions), the actions it no longer grants (RemovedActions), and for the \
+actions both grant, the resources they're newly or no longer granted on (AddedResources, \
+RemovedResources) and the conditions that changed (ChangedConditions). Each version is a saved \
+report, i.e. the JSON output of generate-policies or a policy document, or a git ref, at which \
+the source files are analyzed."
+    )]
+    #[telemetry(command = "changelog")]
+    Changelog {
+        /// Source files to generate the policies for at the git refs
+        #[arg(num_args = 0..)]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
 
-            Some(batch_response)
-        } else {
-            None
-        };
+        /// Earlier version: a saved report or a git ref
+        #[arg(
+            long = "from",
+            value_name = "REF|REPORT",
+            long_help = CHANGELOG_VERSION_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        from: String,
+
+        /// Later version: a saved report or a git ref
+        #[arg(
+            long = "to",
+            value_name = "REF|REPORT",
+            long_help = CHANGELOG_VERSION_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        to: String,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        #[telemetry(value)]
+        pretty: bool,
+
+        /// Output format of the changelog
+        #[arg(
+            long = "output-format",
+            default_value = "json",
+            value_parser = ["json", "markdown"],
+            long_help = "Output format of the changelog: json for the structured changelog, \
+or markdown for a section listing the changes, to paste into release notes or a change ticket."
+        )]
+        #[telemetry(value)]
+        output_format: String,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Checks that the generated policy needs no permissions beyond a committed baseline
+    #[command(
+        long_about = "Generates the policy of source files and compares it with a baseline \
+policy committed to the repository, so that IAM changes become an explicit review step in CI. \
+Exits with code 1 and reports the new permissions on stderr when code changes require \
+permissions the baseline doesn't grant: actions, resources, or actions the baseline only \
+grants under conditions. Permissions of the baseline that are no longer needed are reported \
+without failing. The diff with the baseline is output as JSON, as by the diff command. After \
+reviewing the new permissions, accept them with --update-baseline. Exits with code 2 on \
+errors."
+    )]
+    #[telemetry(command = "check-baseline")]
+    CheckBaseline {
+        /// Source files to generate the policy for
+        #[arg(required = true, num_args = 1..)]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
+
+        /// Committed baseline policy
+        #[arg(
+            long = "baseline",
+            long_help = "Baseline policy file: the JSON output of generate-policies, as \
+written by --update-baseline, or a single IAM policy document."
+        )]
+        #[telemetry(presence)]
+        baseline: PathBuf,
+
+        /// Overwrite the baseline with the generated policy
+        #[arg(
+            long = "update-baseline",
+            long_help = "Overwrite the baseline file with the generated policy, in the JSON \
+output format of generate-policies, instead of failing on new permissions."
+        )]
+        #[telemetry(value)]
+        update_baseline: bool,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        #[telemetry(value)]
+        pretty: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Tests that the generated policies match committed golden files
+    #[command(
+        long_about = "Generates the policies of source files and compares them with golden \
+files committed to the repository, so CI catches unintended permission changes. The golden \
+directory holds a JSON policy document per generated policy: policy.json for the principal \
+running the code, <entry point>.json with per-entry-point policies and \
+assumed-<role name>.json for roles the code assumes. Policies are compared semantically, \
+ignoring the order of statements, actions and resources and the formatting of the files. \
+Exits with code 1 and reports the changes on stderr when a policy grants other permissions \
+than its golden file, has no golden file, or a golden file has no policy anymore. The \
+comparisons are output as JSON. After reviewing the changes, accept them with --update. \
+Exits with code 2 on errors."
+    )]
+    #[telemetry(command = "test")]
+    Test {
+        /// Source files to generate the policies for
+        #[arg(required = true, num_args = 1..)]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
+
+        /// Directory of the committed golden policy files
+        #[arg(
+            long = "golden",
+            value_name = "DIRECTORY",
+            long_help = "Directory of the golden policy files, one JSON policy document per \
+generated policy, as written by --update. Other files of the directory are ignored."
+        )]
+        #[telemetry(presence)]
+        golden: PathBuf,
+
+        /// Overwrite the golden files with the generated policies
+        #[arg(
+            long = "update",
+            long_help = "Write the generated policies to the golden directory, removing the \
+golden files of policies no longer generated, instead of comparing them."
+        )]
+        #[telemetry(value)]
+        update: bool,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        #[telemetry(value)]
+        pretty: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Generates policies as a Terraform external data source
+    #[command(
+        long_about = "Generates the policies of a source file or directory as a Terraform \
+external data source, so they can feed aws_iam_role_policy during plan and apply. Reads the \
+query of the data source from stdin, a JSON object of strings with the 'path' to analyze \
+and, optionally, 'language', 'region', 'account', 'partition', 'service_hints' and 'include' \
+(both comma-separated) and 'exclude_tests'. Writes the result to stdout: 'policy', the JSON \
+document of the policy when a single one is generated, 'policies', a JSON array of the \
+documents, and 'policy_count'. Policies for roles the code assumes are left out. Errors are \
+written to stderr, where Terraform reports them."
+    )]
+    #[telemetry(command = "terraform-data-source")]
+    TerraformDataSource {
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+    },
+
+    /// Saves the latest AWS service references, mapping operations to IAM actions
+    #[command(
+        long_about = "Fetches the latest service reference of every AWS service from the \
+service reference endpoint and saves them to ~/.iam-policy-autopilot/service-reference, replacing \
+those saved before. Policy generation then maps operations to IAM actions with the saved service \
+references instead of fetching them, so operations launched since the last release are mapped \
+and policies are generated without network access. Run it again to pick up newer services and \
+operations; delete the directory to fetch the service references on every run again. The SDK \
+models calls are extracted with are embedded in the binary and only updated with a release."
+    )]
+    #[telemetry(command = "update-mappings")]
+    UpdateMappings {
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+    },
+
+    /// Inspects the operation to IAM action mappings policies are generated with
+    #[telemetry(command = "mappings")]
+    Mappings {
+        #[command(subcommand)]
+        command: MappingsCommand,
+    },
+
+    /// Keeps a committed policy file up to date from a pre-commit hook
+    #[command(
+        long_about = "Keeps a policy file committed to the repository up to date with its \
+source files, for pre-commit integration. When the commit stages none of the source files \
+nor the policy file, exits right away without analyzing anything. Otherwise generates the \
+policy of the source files and compares it with the policy file: if they differ, or the file \
+doesn't exist yet, the file is updated in place, in the JSON output format of \
+generate-policies, and the hook exits with code 1 so the commit fails until the updated file \
+is reviewed and staged. Exits with code 2 on errors."
+    )]
+    #[telemetry(command = "hook")]
+    Hook {
+        /// Source files to generate the policy for
+        #[arg(required = true, num_args = 1..)]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
+
+        /// Committed policy file to keep up to date
+        #[arg(
+            long = "policy-file",
+            value_name = "PATH",
+            long_help = "Committed policy file: the JSON output of generate-policies, as \
+written by the hook or by check-baseline --update-baseline, or a single IAM policy document."
+        )]
+        #[telemetry(presence)]
+        policy_file: PathBuf,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Reports the permissions of an existing policy that the code doesn't need
+    #[command(
+        long_about = "Audits an existing policy, or the policies of a role, against the \
+policy generated for its code, and reports the permissions no code path requires, for \
+incremental least-privilege cleanup of legacy roles: the actions of each statement matching \
+no generated action, and the resources none of its used actions is generated on. Outputs \
+them as JSON under UnusedPermissions, with the audited policies trimmed of them under \
+TrimmedPolicies. Review unused permissions before removing them: calls the static analysis \
+can't see, e.g. with computed operation names, also show up as unused."
+    )]
+    #[telemetry(command = "audit-unused")]
+    AuditUnused {
+        /// Source files to generate the policy for
+        #[arg(required = true, num_args = 1..)]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
+
+        /// Policy file to audit
+        #[arg(
+            long = "policy-file",
+            required_unless_present = "role_name",
+            conflicts_with = "role_name",
+            long_help = "Policy file to audit: a single IAM policy document, e.g. from aws \
+iam get-policy-version, or the JSON output of generate-policies."
+        )]
+        #[telemetry(presence)]
+        policy_file: Option<PathBuf>,
+
+        /// Role whose policies are audited
+        #[arg(
+            long = "role-name",
+            long_help = "Audit the managed policies attached to this role, at their default \
+version, and its inline policies. Requires iam:ListAttachedRolePolicies, iam:GetPolicy, \
+iam:GetPolicyVersion, iam:ListRolePolicies and iam:GetRolePolicy."
+        )]
+        #[telemetry(presence)]
+        role_name: Option<String>,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        #[telemetry(value)]
+        pretty: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Applies the generated policy to a role as a managed policy
+    #[command(
+        long_about = "Applies the policy generated for the source files to a role directly, for \
+teams without infrastructure as code. The policy is kept as a customer managed policy of the \
+role's account, named after the role unless --policy-name is passed: it's created if it \
+doesn't exist, and gets a new default version when the generated policy grants other \
+permissions, the oldest versions being deleted to stay within the IAM quota of five. It's \
+then attached to the role if it isn't. When the permissions don't fit in one policy, the \
+further policies have their position appended to the name, and the policies of further \
+positions attached to the role are detached from it. Outputs the change of each policy as \
+JSON, with the permissions the generated policy grants differently from the current version \
+under Diff; --dry-run only outputs them. Policies for roles the code assumes aren't \
+applied. Requires iam:ListAttachedRolePolicies, iam:GetPolicy and iam:GetPolicyVersion, and \
+to apply iam:CreatePolicy, iam:ListPolicyVersions, iam:DeletePolicyVersion, \
+iam:CreatePolicyVersion, iam:AttachRolePolicy and iam:DetachRolePolicy."
+    )]
+    #[telemetry(command = "apply")]
+    Apply {
+        /// Source files to generate the policy for
+        #[arg(required = true, num_args = 1..)]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
+
+        /// Role to attach the policy to
+        #[arg(
+            long = "role-arn",
+            required = true,
+            long_help = "ARN of the role to attach the policy to. The policy is created in the \
+role's account, and its resource ARNs are generated for the role's account and partition."
+        )]
+        #[telemetry(presence)]
+        role_arn: String,
+
+        /// Name of the managed policy, IamPolicyAutopilot-<role name> by default
+        #[arg(long = "policy-name")]
+        #[telemetry(presence)]
+        policy_name: Option<String>,
+
+        /// Output the changes without applying them
+        #[arg(long = "dry-run")]
+        #[telemetry(value)]
+        dry_run: bool,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        #[telemetry(value)]
+        pretty: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// Filter extracted SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Lists every AWS SDK call of source files as JSON
+    #[command(
+        long_about = "Lists every AWS SDK call found in the source files as JSON, independently \
+of policy generation, for tooling built on top of the extraction. Each call has the services \
+it may be made on, its SDK method, file, line, column and expression, the resource identifiers \
+known from the call site by ARN placeholder, the role it runs under if known, and the \
+confidence of its service: High when it's the service of the client the call is made on, or \
+the only service having the operation and corroborated by the call's argument names or the \
+source file's imports, Medium when only the method name identifies it, and Low when several \
+services have the operation."
+    )]
+    #[telemetry(command = "list-calls")]
+    ListCalls {
+        /// Source files to list the SDK calls of
+        #[arg(required = true, num_args = 1..)]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        #[telemetry(value)]
+        pretty: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// Filter the listed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Explains which call sites a generated action or resource comes from
+    #[command(
+        long_about = "Explains why the generated policies grant an action or access to a \
+resource: prints each matching action with the resources it's granted on, followed by the \
+location and expression of every call requiring it. The target is an action, e.g. kms:Decrypt, \
+or a resource ARN, either of them possibly with wildcards, e.g. s3:Put* or \
+arn:aws:s3:::my-bucket/*. Explains the provenance file of a previous generate-policies run \
+with --provenance, or analyzes the source files on demand. Fails if no generated action or \
+resource matches the target."
+    )]
+    #[telemetry(command = "explain")]
+    Explain {
+        /// Action or resource ARN to explain, e.g. kms:Decrypt
+        target: String,
+
+        /// Source files to analyze, unless --provenance is given
+        #[arg(
+            num_args = 1..,
+            required_unless_present = "provenance",
+            conflicts_with = "provenance"
+        )]
+        #[telemetry(count)]
+        source_files: Vec<PathBuf>,
+
+        /// Provenance file of a previous generate-policies run to explain from
+        #[arg(
+            long = "provenance",
+            value_name = "PATH",
+            long_help = EXPLAIN_PROVENANCE_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        provenance: Option<PathBuf>,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Rewrites an existing policy in the canonical form of the generated policies
+    #[command(
+        long_about = "Rewrites an IAM policy, human-written or generated, in the canonical form \
+of the generated policies and outputs it as JSON, so two normalized policies granting the same \
+permissions are equal and a diff between them only shows the permissions they differ by. \
+Actions, resources, principals and condition values become sorted lists without duplicates or \
+the entries a wildcard of the same list covers, with the service prefixes of actions in \
+lowercase; statements differing only by their actions are merged, and statements are sorted, \
+Allow before Deny. What the policy allows or denies doesn't change. The statements of the \
+policies of generate-policies output are normalized into one policy."
+    )]
+    #[telemetry(command = "normalize")]
+    Normalize {
+        /// Policy file: an IAM policy document or the JSON output of generate-policies
+        policy_file: PathBuf,
+
+        /// Keep the Id of the policy and the Sids of its statements
+        #[arg(long = "keep-ids")]
+        #[telemetry(value)]
+        keep_ids: bool,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        pretty: bool,
+    },
+
+    /// Generates the policies of several projects, per path or merged
+    #[command(
+        long_about = "Analyzes each path, a directory or git URL, as a project of its own, in \
+the language of its files, and outputs the policies of each path as JSON under its namespace, \
+the name of its directory or repository made unique among the paths. With --merge, the \
+projects are analyzed together into merged policies, one per language, with the namespaces of \
+the projects requiring each of their actions."
+    )]
+    #[telemetry(command = "aggregate")]
+    Aggregate {
+        /// Directories or git URLs of the projects to analyze
+        #[arg(num_args = 1.., required = true)]
+        #[telemetry(count)]
+        paths: Vec<PathBuf>,
+
+        /// Output the merged policies of all paths, with the paths requiring each action
+        #[arg(long = "merge", long_help = AGGREGATE_MERGE_LONG_HELP)]
+        #[telemetry(value)]
+        merge: bool,
+
+        /// Write the policies of each path to <namespace>.json in this directory
+        #[arg(long = "output-dir", value_name = "DIR", conflicts_with = "merge")]
+        #[telemetry(presence)]
+        output_dir: Option<PathBuf>,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Report each source file to stderr as it is analyzed
+        #[arg(long = "progress", long_help = PROGRESS_LONG_HELP)]
+        progress: bool,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        pretty: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Measures how long the analysis of a source tree takes, to compare releases
+    #[command(
+        long_about = "Analyzes the source files of a path several times and reports, as JSON \
+on stdout, the fastest, median and slowest duration of extracting their SDK calls and of \
+generating their policies, with the number of files, calls and policies and the peak memory of \
+the process. The first iteration also loads the service definitions and fetches the service \
+references. Reports of two releases on the same sources compare with --baseline, and \
+--max-regression fails the benchmark on a slowdown, e.g. in CI. Builds with the profiling \
+feature also write CPU and heap profiles of the benchmark with --profile."
+    )]
+    #[telemetry(command = "bench")]
+    Bench {
+        /// Source file or directory to analyze
+        path: PathBuf,
+
+        /// Number of times the sources are analyzed
+        #[arg(
+            short = 'n',
+            long = "iterations",
+            default_value_t = 5,
+            value_parser = clap::value_parser!(u16).range(1..)
+        )]
+        #[telemetry(value)]
+        iterations: u16,
+
+        /// Report of a previous bench run to compare with
+        #[arg(
+            long = "baseline",
+            value_name = "REPORT",
+            long_help = BENCH_BASELINE_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        baseline: Option<PathBuf>,
+
+        /// Fail if a median is this many percent longer than in the baseline
+        #[arg(
+            long = "max-regression",
+            value_name = "PERCENT",
+            requires = "baseline",
+            long_help = MAX_REGRESSION_LONG_HELP
+        )]
+        #[telemetry(value, if_present)]
+        max_regression: Option<u16>,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        pretty: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Generates an external library model from source code using call graph analysis
+    #[cfg(feature = "model-generation")]
+    #[command(
+        long_about = "Analyzes source code to build a call graph from specified entry points, \
+extracts AWS SDK calls reachable from those entry points, and outputs an external library model \
+JSON that maps each entry point to its required SDK operations.\n\n\
+Entry points are specified as file:line:column (1-based). Point to the start of a function \
+declaration. Example: --entry-point handler.go:14:1"
+    )]
+    #[telemetry(skip)]
+    GenerateModel {
+        /// Source files to analyze
+        #[arg(required = true, num_args = 1..)]
+        source_files: Vec<PathBuf>,
+
+        /// Entry points as file:line:column (1-based, pointing to function declaration).
+        /// If omitted, all exported functions are used as entry points.
+        #[arg(long = "entry-point", num_args = 1..)]
+        entry_points: Vec<String>,
+
+        /// Name for the generated library model
+        #[arg(long = "library-name", required = true)]
+        library_name: String,
+
+        /// Enable debug logging output to stderr
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        pretty: bool,
+
+        /// Override programming language detection
+        #[arg(short = 'l', long = "language")]
+        language: Option<String>,
+
+        /// Filter extracted SDK calls to specific AWS services
+        #[arg(long = "service-hints", num_args = 1..)]
+        service_hints: Option<Vec<String>>,
+    },
+
+    /// Starts a language server showing the permissions of SDK calls in editors
+    #[command(
+        long_about = "Starts a Language Server Protocol server on stdin and stdout, for \
+editor integration in VS Code, JetBrains IDEs and other LSP clients. The source files of the \
+workspace, those git tracks or doesn't ignore, are analyzed when the editor connects and again \
+whenever a file is saved, one language at a time. Hovering over an AWS SDK call shows the \
+permissions it requires, e.g. 'This call requires s3:GetObject on arn:aws:s3:::my-bucket/*', \
+and the places where the analysis lost precision are reported as warnings: calls on clients \
+whose service couldn't be resolved, operations existing in several services, and client \
+methods named at runtime."
+    )]
+    #[telemetry(command = "lsp")]
+    Lsp {
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Only analyze the source files of this language
+        #[arg(short = 'l', long = "language")]
+        #[telemetry(value, if_present)]
+        language: Option<String>,
+
+        /// AWS region
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Filter the analyzed SDK calls to specific AWS services
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Starts an HTTP server generating policies as a service
+    #[command(
+        long_about = "Starts an HTTP server exposing policy generation as a REST API, so \
+services such as an internal portal can generate policies without starting the CLI for each \
+request. The service definitions are loaded once at startup and the caches stay warm between \
+jobs. POST /jobs submits a job analyzing the file or directory the 'path' query parameter names \
+on the server, or the tarball of sources in the request body, and returns its JobId; the \
+'language', 'region', 'account', 'partition' and 'service_hints' query parameters override \
+the options of the server. GET /jobs/{id} returns the status of a job, and once it succeeded \
+GET /jobs/{id}/policy and GET /jobs/{id}/provenance return the generated policies and the \
+calls requiring each action, in the formats of generate-policies and its --provenance file.",
+        mut_arg("jobs", |arg| arg.help("Number of files each job analyzes concurrently"))
+    )]
+    #[telemetry(command = "serve")]
+    Serve {
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+
+        /// Log progress and timings to stderr, -vv for details
+        #[arg(
+            short = 'v',
+            long = "verbose",
+            action = clap::ArgAction::Count,
+            long_help = VERBOSE_LONG_HELP
+        )]
+        verbose: u8,
+
+        /// Port number to bind the HTTP server to
+        #[arg(short = 'p', long = "port", default_value_t = SERVE_DEFAULT_PORT)]
+        #[telemetry(skip)]
+        port: u16,
+
+        /// IP address to bind the HTTP server to
+        #[arg(short = 'b', long = "bind-address", default_value_t = DEFAULT_BIND_ADDRESS.to_string(),
+              long_help = "IP address to bind the HTTP server to. Defaults to 127.0.0.1 \
+(localhost). Use 0.0.0.0 to listen on all interfaces.")]
+        bind_address: String,
+
+        /// Port number to also serve the gRPC API streaming the progress of analyses on
+        #[arg(long = "grpc-port", value_name = "PORT", long_help = GRPC_PORT_LONG_HELP)]
+        #[telemetry(presence)]
+        grpc_port: Option<u16>,
+
+        /// Number of jobs analyzed at once; the others are queued
+        #[arg(
+            long = "max-concurrent-jobs",
+            default_value_t = 2,
+            value_parser = clap::value_parser!(u16).range(1..)
+        )]
+        #[telemetry(value)]
+        max_concurrent_jobs: u16,
+
+        /// Number of jobs waiting for others to finish above which submissions are rejected
+        #[arg(
+            long = "max-queued-jobs",
+            default_value_t = 32,
+            long_help = "Number of jobs waiting for the running ones to finish above which \
+submitting a job is rejected with 503 Service Unavailable, as each holds its uploaded archive."
+        )]
+        #[telemetry(value)]
+        max_queued_jobs: u16,
+
+        /// Directory the jobs may analyze by path; jobs otherwise upload their sources
+        #[arg(
+            long = "allow-path-root",
+            value_name = "DIR",
+            long_help = ALLOW_PATH_ROOT_LONG_HELP
+        )]
+        #[telemetry(presence)]
+        allow_path_root: Option<PathBuf>,
+
+        /// AWS region of the jobs not passing one
+        #[arg(
+            short = 'r',
+            long = "region",
+            default_value = "*",
+            long_help = "AWS region to use for ARN generation, unless a job passes one."
+        )]
+        #[telemetry(presence, default = "*")]
+        region: String,
+
+        /// AWS account ID of the jobs not passing one
+        #[arg(
+            short = 'a',
+            long = "account",
+            visible_alias = "account-id",
+            default_value = "*",
+            long_help = "AWS account ID to use for ARN generation, unless a job passes one."
+        )]
+        #[telemetry(presence, default = "*")]
+        account: String,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        partition: PartitionArgs,
+
+        /// Filter the analyzed SDK calls to specific AWS services, unless a job passes some
+        #[arg(
+            long = "service-hints",
+            num_args = 1..,
+            long_help = SERVICE_HINTS_LONG_HELP,
+        )]
+        #[telemetry(list)]
+        service_hints: Option<Vec<String>>,
+
+        #[command(flatten)]
+        #[telemetry(flatten)]
+        analysis: AnalysisArgs,
+    },
+
+    /// Start MCP server
+    #[command(
+        long_about = "Starts an MCP server that provides IAM policy generation \
+and AccessDenied error fixing capabilities to IDEs and other tools. The server can run in stdio mode \
+for direct integration or HTTP mode for network-based communication. \
+Supports both transport mechanisms with configurable logging."
+    )]
+    // MCP server notice is sent through `notifications/message` on initialization
+    #[telemetry(command = "mcp-server", skip_notice)]
+    McpServer {
+        /// Transport mechanism for MCP communication
+        #[arg(short = 't', long = "transport", default_value_t = McpTransport::Stdio,
+              long_help = "Transport mechanism for MCP communication. 'stdio' uses standard input/output \
+for direct integration with IDEs and tools. 'http' starts an HTTP server for network-based communication.")]
+        #[telemetry(value)]
+        transport: McpTransport,
+
+        /// Port number for HTTP transport (ignored for stdio transport)
+        #[arg(short = 'p', long = "port", default_value_t = MCP_HTTP_DEFAULT_PORT,
+              long_help = "Port number to bind the HTTP server to when using HTTP transport. \
+Only used when --transport=http. The server will bind to the specified address on the specified port.")]
+        #[telemetry(skip)]
+        port: u16,
+
+        /// Bind address for HTTP transport (ignored for stdio transport)
+        #[arg(short = 'b', long = "bind-address", default_value_t = DEFAULT_BIND_ADDRESS.to_string(),
+              long_help = "IP address to bind the HTTP server to when using HTTP transport. \
+Only used when --transport=http. Defaults to 127.0.0.1 (localhost). \
+Use 0.0.0.0 to listen on all interfaces.")]
+        bind_address: String,
+    },
+
+    #[command(
+        about = "Print version information.",
+        short_flag = 'V',
+        long_flag = "version"
+    )]
+    #[telemetry(skip)]
+    Version {
+        #[arg(long = "verbose", default_value_t = false, hide = true)]
+        verbose: bool,
+    },
+
+    /// Manage anonymous telemetry settings
+    #[command(long_about = "View or change anonymous telemetry settings.\n\n\
+IAM Policy Autopilot collects anonymous usage metrics to improve the tool.\n\
+No file paths, policy content, AWS account IDs, or credentials are ever collected.\n\n\
+Use --enable or --disable to persist your preference to ~/.iam-policy-autopilot/config.json.\n\
+Use --status to view the current telemetry state.\n\n\
+The DISABLE_IAM_POLICY_AUTOPILOT_TELEMETRY=true environment variable disables telemetry, overriding the config file.")]
+    #[telemetry(skip)]
+    Telemetry {
+        /// Enable anonymous telemetry
+        #[arg(long = "enable", conflicts_with = "disable")]
+        enable: bool,
+
+        /// Disable anonymous telemetry
+        #[arg(long = "disable", conflicts_with = "enable")]
+        disable: bool,
+
+        /// Show current telemetry status
+        #[arg(long = "status")]
+        status: bool,
+    },
+}
+
+/// The AWS partition of the generated ARNs, shared by the commands generating them
+#[derive(Args, Debug, Clone, Default, TelemetryEventDerive)]
+struct PartitionArgs {
+    /// AWS partition, derived from the region by default
+    #[arg(long = "partition", long_help = PARTITION_LONG_HELP)]
+    #[telemetry(presence)]
+    partition: Option<String>,
+}
+
+impl PartitionArgs {
+    /// The AWS context of the ARNs generated for `region` and `account` in the partition
+    fn aws_context(&self, region: &str, account: &str) -> Result<AwsContext> {
+        AwsContext::with_partition(
+            self.partition.clone(),
+            region.to_string(),
+            account.to_string(),
+        )
+    }
+}
+
+/// Options of the analysis of the source files, shared by the commands analyzing them
+#[derive(Args, Debug, Clone, Default, TelemetryEventDerive)]
+struct AnalysisArgs {
+    /// Skip test files (e.g., Go *_test.go, Python moto/LocalStack tests) during analysis
+    #[arg(long = "exclude-tests", long_help = EXCLUDE_TESTS_LONG_HELP)]
+    #[telemetry(value)]
+    exclude_tests: bool,
+
+    /// Analyze sources skipped by default: files git ignores, vendored, generated or oversized
+    #[arg(
+        long = "include",
+        value_delimiter = ',',
+        value_name = "KINDS",
+        value_parser = ["ignored", "vendored", "generated", "oversized", "mocked"],
+        long_help = INCLUDE_LONG_HELP
+    )]
+    #[telemetry(list)]
+    include: Vec<String>,
+
+    /// Number of files to analyze concurrently
+    #[arg(
+        long = "jobs",
+        short = 'j',
+        value_parser = clap::value_parser!(u16).range(1..),
+        long_help = JOBS_LONG_HELP
+    )]
+    #[telemetry(value, if_present)]
+    jobs: Option<u16>,
+
+    /// Skip source files larger than this many bytes, or minified
+    #[arg(
+        long = "max-file-size",
+        value_name = "BYTES",
+        long_help = MAX_FILE_SIZE_LONG_HELP
+    )]
+    #[telemetry(value, if_present)]
+    max_file_size: Option<u64>,
+
+    /// Also analyze the dependencies of the project, down to this depth
+    #[arg(
+        long = "dependency-depth",
+        value_name = "DEPTH",
+        value_parser = clap::value_parser!(u16).range(1..),
+        long_help = DEPENDENCY_DEPTH_LONG_HELP
+    )]
+    #[telemetry(value, if_present)]
+    dependency_depth: Option<u16>,
+
+    /// Dependencies to analyze, by name pattern
+    #[arg(
+        long = "allow-dependency",
+        value_name = "PATTERN",
+        requires = "dependency_depth",
+        long_help = ALLOW_DEPENDENCY_LONG_HELP
+    )]
+    #[telemetry(count)]
+    allowed_dependencies: Vec<String>,
+
+    /// Executables reporting the calls of in-house SDK wrappers and private services
+    #[arg(long = "plugin", value_name = "PATH", long_help = PLUGIN_LONG_HELP)]
+    #[telemetry(count)]
+    plugins: Vec<PathBuf>,
+
+    /// File declaring the helper functions forwarding an operation to an SDK client
+    #[arg(long = "wrappers", value_name = "PATH", long_help = WRAPPERS_LONG_HELP)]
+    #[telemetry(presence)]
+    wrappers: Option<PathBuf>,
+}
+
+/// Subcommands of the mappings command
+#[derive(Subcommand, Debug)]
+enum MappingsCommand {
+    /// Exports the operation to IAM action and resource format mappings as JSON
+    #[command(
+        long_about = "Writes the mappings policy generation uses to stdout as JSON: for each \
+service, each operation of its SDK model with the IAM actions it requires, including those AWS \
+performs on the caller's behalf, the resource types of each action with their ARN formats, and \
+the condition keys actions are granted with. The mappings are those of the saved service \
+references if update-mappings was run, with the mapping overrides file merged over them. Audit \
+what the generator will grant, or build tooling on the same data."
+    )]
+    Dump {
+        /// Services to export, e.g. s3 (repeatable; all services if omitted)
+        #[arg(long = "service", value_name = "SERVICE")]
+        services: Vec<String>,
+
+        /// File of operation to action mappings merged over the built-in ones
+        #[arg(
+            long = "mapping-overrides",
+            value_name = "PATH",
+            long_help = MAPPING_OVERRIDES_LONG_HELP
+        )]
+        mapping_overrides: Option<PathBuf>,
+
+        /// Format JSON output with indentation for readability
+        #[arg(short = 'p', long = "pretty")]
+        pretty: bool,
+
+        /// Enable debug logging output to stderr (most verbose)
+        #[arg(hide = true, short = 'd', long = "debug")]
+        debug: bool,
+    },
+}
+
+/// Initialize logging based on configuration
+fn init_logging(debug: bool, verbose: u8, progress: bool) -> Result<()> {
+    let log_level = match (debug, verbose) {
+        // Debug takes precedence - most verbose logging including TRACE
+        (true, _) => log::LevelFilter::Trace,
+        // Default: only ERROR messages
+        (false, 0) => log::LevelFilter::Error,
+        // -v: progress, per-file timing and where time is spent
+        (false, 1) => log::LevelFilter::Info,
+        // -vv: details of what each extractor matched
+        (false, _) => log::LevelFilter::Debug,
+    };
+
+    let mut builder = env_logger::Builder::from_default_env();
+    builder.filter_level(log_level);
+    if progress {
+        builder.filter_module(PROGRESS_LOG_TARGET, log::LevelFilter::Info);
+    }
+    builder.format_target(false).format_timestamp_secs().init();
+
+    Ok(())
+}
+
+/// Handle the extract-sdk-calls subcommand
+async fn handle_extract_sdk_calls(config: &SharedConfig) -> Result<()> {
+    use iam_policy_autopilot_policy_generation::api::model::ServiceHints;
+
+    info!("Running extract-sdk-calls command");
+
+    // Validate configuration
+    config
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let service_hints = config.service_hints.as_ref().map(|names| ServiceHints {
+        service_names: names.clone(),
+    });
+
+    let results = extract_sdk_calls(&ExtractSdkCallsConfig {
+        source_files: config.source_files.clone(),
+        language: config.language.clone(),
+        service_hints,
+        exclude_tests: config.analysis.exclude_tests,
+        included: config.included(),
+        jobs: config.analysis.jobs.map(usize::from),
+        max_file_size: config.analysis.max_file_size,
+        dependencies: config.dependencies(),
+        plugins: config.analysis.plugins.clone(),
+        wrappers: config.analysis.wrappers.clone(),
+        go_binaries: Vec::new(),
+        progress: None,
+        cache_dir: None,
+    })
+    .await?;
+
+    let json_output =
+        SdkMethodCall::serialize_list(&results.methods, config.full_output, config.pretty)
+            .context("Failed to output extracted operations")?;
+
+    // Output to stdout (not using println! to avoid extra newline in compact mode)
+    print!("{json_output}");
+    if config.pretty {
+        println!(); // Add newline for pretty output
+    }
+
+    trace!("Extracted methods JSON output written to stdout");
+    Ok(())
+}
+
+/// Handle the generate-policies subcommand.
+async fn handle_generate_policy(config: &GeneratePolicyCliConfig) -> Result<()> {
+    use iam_policy_autopilot_policy_generation::api::model::ServiceHints;
+
+    info!("Running generate-policies command");
+
+    // Clone the repositories of git URLs, keeping them until the policies are generated
+    let sources = remote_sources::resolve(
+        &config.shared.source_files,
+        config.shared.language.as_deref(),
+    )?;
+    let config = &GeneratePolicyCliConfig {
+        shared: SharedConfig {
+            source_files: sources.files,
+            ..config.shared.clone()
+        },
+        ..config.clone()
+    };
+
+    // Validate configuration
+    config
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let service_hints = config
+        .shared
+        .service_hints
+        .as_ref()
+        .map(|names| ServiceHints {
+            service_names: names.clone(),
+        });
+
+    let mut resource_answers = match &config.answers_file {
+        Some(path) => resource_prompt::load_answers(path)?,
+        None => ResourceAnswers::default(),
+    };
+    let mut service_choices = match &config.disambiguation_file {
+        Some(path) => resource_prompt::load_choices(path)?,
+        None => ServiceChoices::default(),
+    };
+    let mapping_overrides = config
+        .mapping_overrides
+        .as_deref()
+        .map(load_mapping_overrides)
+        .transpose()?
+        .unwrap_or_default();
+    let custom_services = config
+        .custom_services
+        .as_deref()
+        .map(load_custom_services)
+        .transpose()?
+        .unwrap_or_default();
+    let principal_mappings = config
+        .principals
+        .as_deref()
+        .map(load_principal_mappings)
+        .transpose()?
+        .unwrap_or_default();
+    let prompt = config
+        .interactive
+        .then(|| Arc::new(resource_prompt::TerminalPrompt::default()));
+
+    let s3_resource_forms = if config.s3_resource_forms.is_empty() {
+        None
+    } else {
+        Some(
+            config
+                .s3_resource_forms
+                .iter()
+                .map(|name| S3ResourceForm::parse(name))
+                .collect::<Result<Vec<_>>>()?,
+        )
+    };
+
+    let kms_via_service = if config.no_kms_via_service {
+        None
+    } else {
+        Some(KmsViaService {
+            services: config.kms_via_services.clone(),
+            key_arns: config
+                .kms_key_arns
+                .iter()
+                .map(|arn| KmsKeyArn::parse(arn))
+                .collect::<Result<Vec<_>>>()?,
+        })
+    };
+
+    let network_origins = (!config.source_vpce.is_empty()
+        || !config.source_vpc.is_empty()
+        || !config.source_ip.is_empty())
+    .then(|| NetworkOrigins {
+        vpc_endpoints: config.source_vpce.clone(),
+        vpcs: config.source_vpc.clone(),
+        source_ips: config.source_ip.clone(),
+        endpoint_services: config.vpc_endpoint_services.clone(),
+    });
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    let partition = aws_context.partition.clone();
+    let flag_sensitive = config.flag_sensitive || config.fails_on("sensitive-action");
+    let mut result = generate_policies(&GeneratePolicyConfig {
+        extract_sdk_calls_config: ExtractSdkCallsConfig {
+            source_files: config.shared.source_files.clone(),
+            language: config.shared.language.clone(),
+            service_hints,
+            exclude_tests: config.shared.analysis.exclude_tests,
+            included: config.shared.included(),
+            jobs: config.shared.analysis.jobs.map(usize::from),
+            max_file_size: config.shared.analysis.max_file_size,
+            dependencies: config.shared.dependencies(),
+            plugins: config.shared.analysis.plugins.clone(),
+            wrappers: config.shared.analysis.wrappers.clone(),
+            go_binaries: config.go_binaries.clone(),
+            progress: None,
+            cache_dir: config.extraction_cache.clone(),
+        },
+        aws_context,
+        individual_policies: config.individual_policies,
+        minimize_policy_size: config.minimal_policy_size,
+        disable_file_system_cache: config.disable_cache,
+        explain_filters: config.explain.clone(),
+        terraform_dir: config.tf_dir.clone(),
+        terraform_files: config.tf_files.clone(),
+        tfstate_paths: config.tfstate.clone(),
+        tfvars_files: config.tfvars.clone(),
+        explain_resource_filters: config.explain_resources.clone(),
+        resource_cutoff: config.resource_cutoff.unwrap_or(DEFAULT_RESOURCE_CUTOFF),
+        wildcard_resources: config.wildcard_resources,
+        app_config_files: config.app_config.clone(),
+        resource_answers: resource_answers.clone(),
+        resource_prompt: prompt
+            .clone()
+            .map(|prompt| prompt as Arc<dyn ResourcePrompt>),
+        service_choices: service_choices.clone(),
+        service_prompt: prompt
+            .clone()
+            .map(|prompt| prompt as Arc<dyn ServicePrompt>),
+        mapping_overrides,
+        service_reference_lock: config.service_reference_lock.clone(),
+        custom_services,
+        principal_mappings,
+        min_confidence: config
+            .min_confidence
+            .as_deref()
+            .map(CallConfidence::parse)
+            .transpose()?,
+        template_variables: config.template,
+        s3_resource_forms,
+        report_unscoped_actions: config.report_unscoped || config.fails_on("unscoped"),
+        compact_actions: config.compact_actions,
+        match_managed_policies: config.managed_policies,
+        trust_policies: config.trust_policies,
+        resource_policies: config.resource_policies,
+        cross_account_report: config.cross_account_report,
+        workload_role_arn: config.workload_role_arn.clone(),
+        suggest_condition_keys: config.suggest_conditions,
+        observability_permissions: config.observability_permissions,
+        collector_config_files: config.collector_config.clone(),
+        event_source_permissions: config.event_source_permissions,
+        s3_multipart_actions: config.s3_multipart_actions,
+        kms_via_service,
+        excluded_services: config.exclude_services.clone(),
+        action_filters: ActionFilters {
+            deny: config.deny_actions.clone(),
+            allow: config.allow_actions.clone(),
+        },
+        split_read_write: config.split_read_write,
+        entry_point_policies: config.per_entry_point,
+        detect_runtime: config.output_format.starts_with("role-") && config.runtime.is_none(),
+        restrict_regions: config.restrict_regions.clone(),
+        network_origins,
+        access_analyzer_policy: config.access_analyzer_policy.clone(),
+        flag_sensitive_actions: flag_sensitive || config.html_report.is_some(),
+        access_level_summary: config.access_summary,
+        action_provenance: config.provenance.is_some()
+            || config.html_report.is_some()
+            || config.output_format == "opa",
+        action_inventory: config.action_inventory.is_some(),
+        analysis_diagnostics: config.sarif.is_some()
+            || ["unresolved", "ambiguous", "unsupported"]
+                .iter()
+                .any(|finding| config.fails_on(finding)),
+        coverage_report: config.coverage || config.html_report.is_some(),
+    })
+    .await?;
+
+    // Record new answers, so later runs apply them without asking
+    if let (Some(prompt), Some(path)) = (&prompt, &config.answers_file) {
+        let new_answers = prompt.take_answers();
+        if !new_answers.is_empty() {
+            let count = new_answers.len();
+            resource_answers.answers.extend(new_answers);
+            resource_prompt::save_answers(path, &resource_answers)?;
+            output::note(&format!(
+                "Recorded {count} resource answers in {}",
+                path.display()
+            ));
+        }
+    }
+    if let (Some(prompt), Some(path)) = (&prompt, &config.disambiguation_file) {
+        let new_choices = prompt.take_choices();
+        if !new_choices.is_empty() {
+            let count = new_choices.len();
+            service_choices.choices.extend(new_choices);
+            resource_prompt::save_choices(path, &service_choices)?;
+            output::note(&format!(
+                "Recorded {count} service choices in {}",
+                path.display()
+            ));
+        }
+    }
+
+    if let Some(suppressed_calls) = &result.suppressed_calls {
+        output::print_suppressed_calls(suppressed_calls);
+    }
+    if let Some(low_confidence_calls) = &result.low_confidence_calls {
+        output::print_low_confidence_calls(low_confidence_calls, config.min_confidence.is_some());
+    }
+    if let Some(deprecated_calls) = &result.deprecated_calls {
+        output::print_deprecated_calls(deprecated_calls);
+    }
+    if let Some(no_permission_calls) = &result.no_permission_calls {
+        output::print_no_permission_calls(no_permission_calls);
+    }
+    if let Some(excluded_service_calls) = &result.excluded_service_calls {
+        output::print_excluded_service_calls(excluded_service_calls);
+    }
+    if let Some(filtered_actions) = &result.filtered_actions {
+        output::print_filtered_actions(filtered_actions);
+    }
+    if let Some(summary) = &result.access_level_summary {
+        output::print_access_level_summary(summary);
+    }
+    if let Some(sensitive_actions) = result.sensitive_actions.as_ref().filter(|_| flag_sensitive) {
+        output::print_sensitive_actions(sensitive_actions);
+    }
+    if let Some(coverage) = result.coverage.as_ref().filter(|_| config.coverage) {
+        output::print_coverage(coverage);
+    }
+
+    let mut generation_metadata = None;
+    if !config.metadata.is_empty() || config.metadata_manifest.is_some() {
+        let mut inputs = config.shared.source_files.clone();
+        inputs.extend(config.go_binaries.iter().cloned());
+        let metadata = GenerationMetadata::collect(&inputs)?;
+        metadata.embed(&mut result.policies, &config.metadata);
+        if let Some(path) = &config.metadata_manifest {
+            metadata.write_manifest(path)?;
+        }
+        generation_metadata = Some(metadata);
+    }
+
+    // Validate before anything is output or uploaded, so rejected policies aren't deployed
+    if config.validate {
+        trace!(
+            "Validating {} policies with IAM Access Analyzer",
+            result.policies.len()
+        );
+        let findings = PolicyValidator::new()
+            .await
+            .validate_policies(&result.policies)
+            .await
+            .context("Failed to validate policies with IAM Access Analyzer")?;
+        output::print_validation_findings(&findings);
+        let blocking = findings
+            .iter()
+            .filter(|finding| finding.finding_type.is_blocking())
+            .count();
+        if blocking > 0 {
+            anyhow::bail!(
+                "IAM Access Analyzer reported {blocking} errors or security warnings for the \
+                 generated policies"
+            );
+        }
+    }
+
+    // Guardrails of pipelines, checked before anything is output or uploaded as well
+    let mut check_results = Vec::new();
+    if let Some(path) = &config.check_no_new_access {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read reference policy {}", path.display()))?;
+        let reference = combined_policy_document(
+            &policy_documents(&content)
+                .with_context(|| format!("Invalid reference policy {}", path.display()))?,
+        );
+        check_results.extend(
+            PolicyValidator::new()
+                .await
+                .check_no_new_access(&result.policies, &reference.to_string())
+                .await
+                .context("Failed to check the policies for new access with IAM Access Analyzer")?,
+        );
+    }
+    if !config.forbidden_actions.is_empty() {
+        check_results.extend(
+            PolicyValidator::new()
+                .await
+                .check_access_not_granted(&result.policies, &config.forbidden_actions)
+                .await
+                .context(
+                    "Failed to check the policies for forbidden actions with IAM Access Analyzer",
+                )?,
+        );
+    }
+    output::print_custom_check_results(&check_results);
+    let failed = check_results.iter().filter(|result| !result.passed).count();
+    if failed > 0 {
+        anyhow::bail!(
+            "{failed} IAM Access Analyzer custom policy checks failed for the generated policies"
+        );
+    }
+
+    if let (Some(path), Some(provenance)) = (&config.provenance, &result.action_provenance) {
+        output::write_provenance(provenance, path)?;
+    }
+
+    if let (Some(path), Some(inventory)) = (&config.action_inventory, &result.action_inventory) {
+        output::write_action_inventory(inventory, path)?;
+    }
+
+    if let (Some(path), Some(diagnostics)) = (&config.sarif, &result.diagnostics) {
+        output::write_sarif(diagnostics, path)?;
+    }
+
+    // The report's sensitive actions and coverage are only output with the policies when
+    // requested themselves
+    if let Some(path) = &config.html_report {
+        html_report::write_html_report(&result, path)?;
+        if !flag_sensitive {
+            result.sensitive_actions = None;
+        }
+        if !config.coverage {
+            result.coverage = None;
+        }
+    }
+
+    // Findings the pipeline fails on, after the SARIF log annotating them is written
+    let findings = failing_findings(config, &result);
+    if !findings.is_empty() {
+        anyhow::bail!("The analysis found {} (--fail-on)", findings.join(", "));
+    }
+
+    if let Some(directory) = &config.split_by_service {
+        trace!(
+            "Writing {} policies by service to {}",
+            result.policies.len(),
+            directory.display()
+        );
+        return service_split::write_service_policies(&result.policies, directory);
+    }
+
+    let cloudformation = match config.output_format.as_str() {
+        "cloudformation" => Some(CloudFormationPolicyType::Managed),
+        "cloudformation-inline" => Some(CloudFormationPolicyType::Inline),
+        _ => None,
+    };
+
+    let cdk = match config.output_format.as_str() {
+        "cdk-typescript" => Some(CdkLanguage::TypeScript),
+        "cdk-python" => Some(CdkLanguage::Python),
+        _ => None,
+    };
+
+    let scp = match config.output_format.as_str() {
+        "scp" => Some(ScpStrategy::AllowList),
+        "scp-deny" => Some(ScpStrategy::DenyByException),
+        _ => None,
+    };
+
+    let role = match config.output_format.as_str() {
+        "role-json" => Some(RoleFormat::Json),
+        "role-cloudformation" => Some(RoleFormat::CloudFormation),
+        "role-terraform" => Some(RoleFormat::Terraform),
+        _ => None,
+    };
+
+    let identity_pool = match config.output_format.as_str() {
+        "identity-pool-json" => Some(IdentityPoolFormat::Json),
+        "identity-pool-cloudformation" => Some(IdentityPoolFormat::CloudFormation),
+        _ => None,
+    };
+
+    if let Some(format) = role {
+        let runtime = match config.runtime.as_deref() {
+            Some("lambda") => Some(Runtime::Lambda),
+            Some("ecs") => Some(Runtime::Ecs),
+            Some("ec2") => Some(Runtime::Ec2),
+            _ => result.runtime,
+        }
+        .context(
+            "Couldn't detect the runtime the code runs on; pass it with --runtime lambda, ecs \
+             or ec2",
+        )?;
+        trace!(
+            "Outputting {} policies as a role for {runtime:?}",
+            result.policies.len()
+        );
+        output::output_role(&result, runtime, format, &partition, config.shared.pretty)
+            .context("Failed to output role")?;
+    } else if let Some(format) = identity_pool {
+        trace!(
+            "Outputting {} policies as identity pool roles",
+            result.policies.len()
+        );
+        identity_pool::output_identity_pool_roles(&result, format, config.shared.pretty)
+            .context("Failed to output identity pool roles")?;
+    } else if let Some(strategy) = scp {
+        trace!(
+            "Outputting {} policies as a service control policy",
+            result.policies.len()
+        );
+        output::output_scp(&result, strategy, config.shared.pretty)
+            .context("Failed to output service control policy")?;
+    } else if let Some(language) = cdk {
+        trace!("Outputting {} policies as CDK code", result.policies.len());
+        output::output_cdk(&result, language).context("Failed to output CDK code")?;
+    } else if config.output_format == "opa" {
+        trace!("Outputting {} policies as OPA input", result.policies.len());
+        output::output_opa(&result, config.shared.pretty).context("Failed to output OPA input")?;
+    } else if config.output_format == "terraform" {
+        trace!("Outputting {} policies as Terraform", result.policies.len());
+        output::output_terraform(&result).context("Failed to output Terraform configuration")?;
+    } else if let Some(policy_type) = cloudformation {
+        trace!(
+            "Outputting {} policies as CloudFormation",
+            result.policies.len()
+        );
+        output::output_cloudformation(&result, policy_type, config.shared.pretty)
+            .context("Failed to output CloudFormation template")?;
+    } else if config.individual_policies {
+        // Output individual policies
+        trace!("Outputting {} individual policies", result.policies.len());
+        output::output_iam_policies(result, None, config.shared.pretty)
+            .context("Failed to output individual IAM policies")?;
+    } else {
+        // Default behavior: output merged policy with optional upload
+        let upload_result = if config.upload_policies.is_some() {
+            trace!("Uploading policies to AWS IAM");
+
+            let mut uploader = PolicyUploader::new()
+                .await
+                .context("Failed to create policy uploader")?;
+            if let Some(metadata) = generation_metadata
+                .as_ref()
+                .filter(|_| config.metadata.iter().any(|target| target == "description"))
+            {
+                uploader = uploader.with_description(metadata.description());
+            }
+
+            let custom_name = config.upload_policies.as_deref().filter(|s| !s.is_empty());
+            let batch_response = uploader
+                .upload_policies(&result.policies, custom_name)
+                .await
+                .context("Failed to upload policies to AWS IAM")?;
+
+            debug!(
+                "Upload completed: {} successful, {} failed",
+                batch_response.successful.len(),
+                batch_response.failed.len()
+            );
+
+            // Log upload results
+            for upload in &batch_response.successful {
+                debug!(
+                    "Successfully uploaded policy: {} (ARN: {})",
+                    upload.policy_name, upload.policy_arn
+                );
+            }
+            for (index, error) in &batch_response.failed {
+                debug!("Failed to upload policy {index}: {error}");
+            }
+
+            Some(batch_response)
+        } else {
+            None
+        };
 
         output::output_iam_policies(result, upload_result, config.shared.pretty)
             .context("Failed to output merged IAM policy")?;
     }
 
-    Ok(())
-}
+    Ok(())
+}
+
+/// The --fail-on findings of `result`, reported on stderr, e.g. `2 unresolved clients`
+fn failing_findings(
+    config: &GeneratePolicyCliConfig,
+    result: &GeneratePoliciesResult,
+) -> Vec<String> {
+    let mut findings = Vec::new();
+    let diagnostics = result.diagnostics.as_deref().unwrap_or_default();
+    for (finding, kind, name) in [
+        (
+            "unresolved",
+            DiagnosticKind::UnresolvedClient,
+            "unresolved clients",
+        ),
+        (
+            "ambiguous",
+            DiagnosticKind::AmbiguousOperation,
+            "ambiguous operations",
+        ),
+        (
+            "unsupported",
+            DiagnosticKind::UnsupportedPattern,
+            "unsupported patterns",
+        ),
+    ] {
+        if !config.fails_on(finding) {
+            continue;
+        }
+        let found: Vec<&Diagnostic> = diagnostics
+            .iter()
+            .filter(|diagnostic| diagnostic.kind == kind)
+            .collect();
+        if !found.is_empty() {
+            output::print_diagnostics(&found);
+            findings.push(format!("{} {name}", found.len()));
+        }
+    }
+    let sensitive = result.sensitive_actions.as_ref().map_or(0, Vec::len);
+    if config.fails_on("sensitive-action") && sensitive > 0 {
+        findings.push(format!("{sensitive} sensitive actions"));
+    }
+    let unscoped = result.unscoped_actions.as_ref().map_or(0, Vec::len);
+    if config.fails_on("unscoped") && unscoped > 0 {
+        findings.push(format!("{unscoped} unscoped actions"));
+    }
+    let filtered = result.filtered_actions.as_ref().map_or(0, Vec::len);
+    if config.fails_on("filtered-action") && filtered > 0 {
+        findings.push(format!("{filtered} filtered actions"));
+    }
+    findings
+}
+
+/// Load and validate the mapping overrides file at `path`
+fn load_mapping_overrides(path: &Path) -> Result<MappingOverrides> {
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read mapping overrides file: {}", path.display()))?;
+    let overrides: MappingOverrides = serde_json::from_str(&content)
+        .with_context(|| format!("Failed to parse mapping overrides file: {}", path.display()))?;
+    overrides
+        .validate()
+        .with_context(|| format!("Invalid mapping overrides file: {}", path.display()))?;
+    Ok(overrides)
+}
+
+/// Load and validate the custom services file at `path`
+fn load_custom_services(path: &Path) -> Result<CustomServices> {
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read custom services file: {}", path.display()))?;
+    let custom_services: CustomServices = serde_json::from_str(&content)
+        .with_context(|| format!("Failed to parse custom services file: {}", path.display()))?;
+    custom_services
+        .validate()
+        .with_context(|| format!("Invalid custom services file: {}", path.display()))?;
+    Ok(custom_services)
+}
+
+/// Load and validate the principal mappings file at `path`
+fn load_principal_mappings(path: &Path) -> Result<PrincipalMappings> {
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read principals file: {}", path.display()))?;
+    let principal_mappings: PrincipalMappings = serde_json::from_str(&content)
+        .with_context(|| format!("Failed to parse principals file: {}", path.display()))?;
+    principal_mappings
+        .validate()
+        .with_context(|| format!("Invalid principals file: {}", path.display()))?;
+    Ok(principal_mappings)
+}
+
+/// Configuration generating the policies of `shared` with default options
+fn default_generate_config(shared: &SharedConfig, aws_context: AwsContext) -> GeneratePolicyConfig {
+    use iam_policy_autopilot_policy_generation::api::model::ServiceHints;
+
+    let service_hints = shared.service_hints.as_ref().map(|names| ServiceHints {
+        service_names: names.clone(),
+    });
+    GeneratePolicyConfig {
+        extract_sdk_calls_config: ExtractSdkCallsConfig {
+            source_files: shared.source_files.clone(),
+            language: shared.language.clone(),
+            service_hints,
+            exclude_tests: shared.analysis.exclude_tests,
+            included: shared.included(),
+            jobs: shared.analysis.jobs.map(usize::from),
+            max_file_size: shared.analysis.max_file_size,
+            dependencies: shared.dependencies(),
+            plugins: shared.analysis.plugins.clone(),
+            wrappers: shared.analysis.wrappers.clone(),
+            go_binaries: Vec::new(),
+            progress: None,
+            cache_dir: None,
+        },
+        aws_context,
+        individual_policies: false,
+        minimize_policy_size: false,
+        disable_file_system_cache: false,
+        explain_filters: None,
+        terraform_dir: None,
+        terraform_files: Vec::new(),
+        tfstate_paths: Vec::new(),
+        tfvars_files: Vec::new(),
+        explain_resource_filters: None,
+        resource_cutoff: DEFAULT_RESOURCE_CUTOFF,
+        wildcard_resources: false,
+        app_config_files: Vec::new(),
+        resource_answers: ResourceAnswers::default(),
+        resource_prompt: None,
+        service_choices: ServiceChoices::default(),
+        service_prompt: None,
+        mapping_overrides: MappingOverrides::default(),
+        service_reference_lock: None,
+        custom_services: CustomServices::default(),
+        principal_mappings: PrincipalMappings::default(),
+        min_confidence: None,
+        template_variables: false,
+        s3_resource_forms: None,
+        report_unscoped_actions: false,
+        compact_actions: false,
+        match_managed_policies: false,
+        trust_policies: false,
+        resource_policies: false,
+        cross_account_report: false,
+        workload_role_arn: None,
+        suggest_condition_keys: false,
+        observability_permissions: false,
+        collector_config_files: Vec::new(),
+        event_source_permissions: false,
+        s3_multipart_actions: false,
+        kms_via_service: Some(KmsViaService::default()),
+        excluded_services: Vec::new(),
+        action_filters: ActionFilters::default(),
+        split_read_write: false,
+        entry_point_policies: false,
+        detect_runtime: false,
+        restrict_regions: None,
+        network_origins: None,
+        access_analyzer_policy: None,
+        flag_sensitive_actions: false,
+        access_level_summary: false,
+        action_provenance: false,
+        action_inventory: false,
+        analysis_diagnostics: false,
+        coverage_report: false,
+    }
+}
+
+/// Handle the simulate subcommand.
+async fn handle_simulate(config: &SimulateCliConfig) -> Result<()> {
+    info!("Running simulate command");
+
+    config
+        .shared
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    let context = if aws_context.region == "*" {
+        Vec::new()
+    } else {
+        vec![(
+            "aws:RequestedRegion".to_string(),
+            aws_context.region.clone(),
+        )]
+    };
+
+    // The individual policies of the calls are their requests
+    let generate_config = GeneratePolicyConfig {
+        individual_policies: true,
+        ..default_generate_config(&config.shared, aws_context)
+    };
+    let calls = generate_policies(&generate_config).await?;
+    let requests = sample_requests(&calls.policies)
+        .context("Failed to derive requests from the analyzed calls")?;
+
+    let policies = if let Some(path) = &config.policy_file {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read policy file {}", path.display()))?;
+        policy_documents(&content)
+            .with_context(|| format!("Invalid policy file {}", path.display()))?
+            .iter()
+            .map(ToString::to_string)
+            .collect()
+    } else {
+        let result = generate_policies(&GeneratePolicyConfig {
+            individual_policies: false,
+            ..generate_config
+        })
+        .await?;
+        result
+            .policies
+            .iter()
+            .map(|policy| serde_json::to_string(&policy.policy))
+            .collect::<Result<_, _>>()
+            .context("Failed to serialize generated policies")?
+    };
+
+    trace!(
+        "Simulating {} requests against {} policies",
+        requests.len(),
+        policies.len()
+    );
+    let results = PolicySimulator::new()
+        .await
+        .simulate(&policies, &requests, &context)
+        .await
+        .context("Failed to simulate requests with the IAM policy simulator")?;
+    output::output_simulation_results(&results, config.shared.pretty)
+        .context("Failed to output simulation results")?;
+
+    let denied = results.iter().filter(|result| result.is_denied()).count();
+    if denied > 0 {
+        anyhow::bail!("The policies deny {denied} requests of the analyzed code");
+    }
+    Ok(())
+}
+
+/// Handle the check-usage subcommand.
+async fn handle_check_usage(config: &CheckUsageCliConfig) -> Result<()> {
+    info!("Running check-usage command");
+
+    config
+        .shared
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    let region = (aws_context.region != "*").then(|| aws_context.region.clone());
+    let result = generate_policies(&default_generate_config(&config.shared, aws_context)).await?;
+
+    let observed = if let Some(path) = &config.cloudtrail_export {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read CloudTrail export {}", path.display()))?;
+        observed_actions_from_export(&content, config.role_arn.as_deref())
+            .with_context(|| format!("Invalid CloudTrail export {}", path.display()))?
+    } else {
+        let role_arn = config
+            .role_arn
+            .as_deref()
+            .context("--role-arn is required without --cloudtrail-export")?;
+        let days = config.days.unwrap_or(DEFAULT_USAGE_DAYS);
+        let end_time = std::time::SystemTime::now();
+        let start_time = end_time - std::time::Duration::from_secs(u64::from(days) * 24 * 60 * 60);
+        trace!("Looking up CloudTrail events of {role_arn} of the last {days} days");
+        UsageCollector::new(region.as_deref())
+            .await
+            .observed_actions(role_arn, start_time, end_time)
+            .await
+            .context("Failed to look up CloudTrail events")?
+    };
+
+    let comparison = compare_usage(&result.policies, &observed);
+    output::output_usage_comparison(&comparison, config.shared.pretty)
+        .context("Failed to output usage comparison")?;
+    Ok(())
+}
+
+/// Handle the diff subcommand.
+async fn handle_diff(config: &DiffCliConfig) -> Result<()> {
+    info!("Running diff command");
+
+    config
+        .shared
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+
+    let (generated, existing) = if let Some(git_ref) = &config.diff_ref {
+        let changed = git_changes::changed_files(&config.shared.source_files, git_ref)
+            .with_context(|| format!("Failed to find the source files changed since {git_ref}"))?;
+        output::note(&format!(
+            "Analyzing {} of {} source files changed since {git_ref}",
+            changed.current.len(),
+            config.shared.source_files.len()
+        ));
+        let current = SharedConfig {
+            source_files: changed.current,
+            ..config.shared.clone()
+        };
+        let previous = SharedConfig {
+            source_files: changed.previous,
+            ..config.shared.clone()
+        };
+        (
+            generated_documents(&current, aws_context.clone()).await?,
+            generated_documents(&previous, aws_context)
+                .await
+                .with_context(|| format!("Failed to generate the policy at {git_ref}"))?,
+        )
+    } else {
+        let path = config
+            .existing
+            .as_ref()
+            .context("--existing is required without --diff")?;
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read policy file {}", path.display()))?;
+        let existing = policy_documents(&content)
+            .with_context(|| format!("Invalid policy file {}", path.display()))?;
+        (
+            generated_documents(&config.shared, aws_context).await?,
+            existing,
+        )
+    };
+
+    let diff = diff_policies(&generated, &existing);
+    output::output_policy_diff(&diff, config.shared.pretty)
+        .context("Failed to output policy diff")?;
+    Ok(())
+}
+
+/// Handle the changelog subcommand.
+async fn handle_changelog(config: &ChangelogCliConfig) -> Result<()> {
+    info!("Running changelog command");
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    let from = version_documents(&config.shared, &config.from, aws_context.clone())
+        .await
+        .with_context(|| format!("Failed to read the policies of {}", config.from))?;
+    let to = version_documents(&config.shared, &config.to, aws_context)
+        .await
+        .with_context(|| format!("Failed to read the policies of {}", config.to))?;
+
+    let changelog = policy_changelog(&from, &to);
+    output::output_policy_changelog(
+        &changelog,
+        &config.from,
+        &config.to,
+        config.markdown,
+        config.shared.pretty,
+    )
+    .context("Failed to output policy changelog")?;
+    Ok(())
+}
+
+/// JSON documents of the policies of a changelog version: the report at `version` if it is
+/// a file, else the policies generated for the source files at the git ref `version`
+async fn version_documents(
+    shared: &SharedConfig,
+    version: &str,
+    aws_context: AwsContext,
+) -> Result<Vec<serde_json::Value>> {
+    let path = Path::new(version);
+    if path.is_file() {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read report {version}"))?;
+        return policy_documents(&content).with_context(|| format!("Invalid report {version}"));
+    }
+    if shared.source_files.is_empty() {
+        anyhow::bail!("{version} is not a report file, and no source files to analyze at it");
+    }
+
+    let at_ref = git_changes::files_at(&shared.source_files, version)?;
+    output::note(&format!(
+        "Analyzing {} of {} source files at {version}",
+        at_ref.files.len(),
+        shared.source_files.len()
+    ));
+    if at_ref.files.is_empty() {
+        return Ok(Vec::new());
+    }
+    let shared = SharedConfig {
+        source_files: at_ref.files.clone(),
+        ..shared.clone()
+    };
+    generated_documents(&shared, aws_context).await
+}
+
+/// JSON documents of the policies generated for `shared` with default options
+async fn generated_documents(
+    shared: &SharedConfig,
+    aws_context: AwsContext,
+) -> Result<Vec<serde_json::Value>> {
+    let result = generate_policies(&default_generate_config(shared, aws_context)).await?;
+    result
+        .policies
+        .iter()
+        .map(|policy| serde_json::to_value(&policy.policy))
+        .collect::<Result<Vec<_>, _>>()
+        .context("Failed to serialize generated policies")
+}
+
+/// Handle the check-baseline subcommand, returning whether new permissions are required.
+async fn handle_check_baseline(config: &CheckBaselineCliConfig) -> Result<bool> {
+    info!("Running check-baseline command");
+
+    config
+        .shared
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let baseline = if config.baseline.exists() || !config.update_baseline {
+        let content = std::fs::read_to_string(&config.baseline).with_context(|| {
+            format!("Failed to read baseline file {}", config.baseline.display())
+        })?;
+        policy_documents(&content)
+            .with_context(|| format!("Invalid baseline file {}", config.baseline.display()))?
+    } else {
+        Vec::new()
+    };
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    let result = generate_policies(&default_generate_config(&config.shared, aws_context)).await?;
+    let generated = result
+        .policies
+        .iter()
+        .map(|policy| serde_json::to_value(&policy.policy))
+        .collect::<Result<Vec<_>, _>>()
+        .context("Failed to serialize generated policies")?;
+
+    let diff = diff_policies(&generated, &baseline);
+    if !config.update_baseline {
+        output::print_baseline_drift(&diff, &config.baseline);
+    }
+    output::output_policy_diff(&diff, config.shared.pretty)
+        .context("Failed to output policy diff")?;
+
+    if config.update_baseline {
+        output::write_baseline(result, &config.baseline)
+            .with_context(|| format!("Failed to write baseline {}", config.baseline.display()))?;
+        return Ok(false);
+    }
+    Ok(diff.requires_new_permissions())
+}
+
+/// Handle the test subcommand, returning whether a generated policy doesn't match its
+/// golden file.
+async fn handle_test(config: &TestCliConfig) -> Result<bool> {
+    info!("Running test command");
+
+    config
+        .shared
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    let result = generate_policies(&default_generate_config(&config.shared, aws_context)).await?;
+    let generated = golden::named_policies(&result.policies)?;
+
+    if config.update {
+        golden::update(&config.golden, &generated)?;
+        return Ok(false);
+    }
+    let comparisons = golden::compare(&config.golden, &generated)?;
+    output::output_golden_comparisons(&comparisons, config.shared.pretty)
+        .context("Failed to output golden comparisons")?;
+    Ok(comparisons.iter().any(golden::GoldenComparison::is_failure))
+}
+
+/// Handle the hook subcommand, returning whether the policy file was stale.
+async fn handle_hook(config: &HookCliConfig) -> Result<bool> {
+    info!("Running hook command");
+
+    config
+        .shared
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let exists = config.policy_file.exists();
+    if exists {
+        let mut files: Vec<&std::path::Path> = config
+            .shared
+            .source_files
+            .iter()
+            .map(PathBuf::as_path)
+            .collect();
+        files.push(&config.policy_file);
+        if !git_changes::stages_any(&files)? {
+            info!("The commit stages no source file, skipping the analysis");
+            return Ok(false);
+        }
+    }
+    let committed = if exists {
+        let content = std::fs::read_to_string(&config.policy_file).with_context(|| {
+            format!(
+                "Failed to read policy file {}",
+                config.policy_file.display()
+            )
+        })?;
+        policy_documents(&content)
+            .with_context(|| format!("Invalid policy file {}", config.policy_file.display()))?
+    } else {
+        Vec::new()
+    };
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    let result = generate_policies(&default_generate_config(&config.shared, aws_context)).await?;
+    let generated = result
+        .policies
+        .iter()
+        .map(|policy| serde_json::to_value(&policy.policy))
+        .collect::<Result<Vec<_>, _>>()
+        .context("Failed to serialize generated policies")?;
+
+    if exists && diff_policies(&generated, &committed).is_empty() {
+        return Ok(false);
+    }
+    output::write_baseline(result, &config.policy_file).with_context(|| {
+        format!(
+            "Failed to write policy file {}",
+            config.policy_file.display()
+        )
+    })?;
+    output::warn(&format!(
+        "{} was out of date with the source files; review and stage it",
+        config.policy_file.display()
+    ));
+    Ok(true)
+}
+
+/// Handle the terraform-data-source subcommand.
+async fn handle_terraform_data_source() -> Result<()> {
+    info!("Running terraform-data-source command");
+
+    let mut input = String::new();
+    std::io::Read::read_to_string(&mut std::io::stdin(), &mut input)
+        .context("Failed to read the query from stdin")?;
+    let query = terraform_data_source::Query::parse(&input)?;
+    let shared = query.shared_config()?;
+    let result = generate_policies(&default_generate_config(&shared, query.aws_context()?)).await?;
+
+    let values = terraform_data_source::data_source_result(&result)?;
+    println!("{}", serde_json::to_string(&values)?);
+    Ok(())
+}
+
+/// Handle the update-mappings subcommand.
+async fn handle_update_mappings() -> Result<()> {
+    info!("Running update-mappings command");
+
+    let update = update_mappings()
+        .await
+        .context("Failed to update the service references")?;
+    eprintln!(
+        "Saved the service references of {} services to {}",
+        update.services,
+        update.directory.display()
+    );
+    Ok(())
+}
+
+/// Handle the mappings dump subcommand.
+async fn handle_mappings_dump(
+    services: &[String],
+    mapping_overrides: Option<&Path>,
+    pretty: bool,
+) -> Result<()> {
+    info!("Running mappings dump command");
+
+    let mapping_overrides = mapping_overrides
+        .map(load_mapping_overrides)
+        .transpose()?
+        .unwrap_or_default();
+    let dump = dump_mappings(services, mapping_overrides)
+        .await
+        .context("Failed to dump the mappings")?;
+    let json = if pretty {
+        serde_json::to_string_pretty(&dump)?
+    } else {
+        serde_json::to_string(&dump)?
+    };
+    println!("{json}");
+    Ok(())
+}
+
+/// Handle the audit-unused subcommand.
+async fn handle_audit_unused(config: &AuditUnusedCliConfig) -> Result<()> {
+    info!("Running audit-unused command");
+
+    config
+        .shared
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let existing = if let Some(path) = &config.policy_file {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read policy file {}", path.display()))?;
+        policy_documents(&content)
+            .with_context(|| format!("Invalid policy file {}", path.display()))?
+            .into_iter()
+            .map(|document| ExistingPolicy {
+                name: None,
+                document,
+            })
+            .collect()
+    } else {
+        let role_name = config
+            .role_name
+            .as_deref()
+            .context("--role-name is required without --policy-file")?;
+        RolePolicyReader::new()
+            .await
+            .role_policies(role_name)
+            .await
+            .with_context(|| format!("Failed to read the policies of role {role_name}"))?
+    };
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    let result = generate_policies(&default_generate_config(&config.shared, aws_context)).await?;
+    let generated = result
+        .policies
+        .iter()
+        .map(|policy| serde_json::to_value(&policy.policy))
+        .collect::<Result<Vec<_>, _>>()
+        .context("Failed to serialize generated policies")?;
+
+    let audit = audit_unused_permissions(&generated, &existing);
+    output::output_permission_audit(&audit, config.shared.pretty)
+        .context("Failed to output unused permissions")?;
+    Ok(())
+}
+
+/// Handle the apply subcommand.
+async fn handle_apply(config: &ApplyCliConfig) -> Result<()> {
+    info!("Running apply command");
+
+    config
+        .shared
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let role = Role::from_arn(&config.role_arn)?;
+    let aws_context = AwsContext::with_partition(
+        Some(role.partition.clone()),
+        config.region.clone(),
+        role.account.clone(),
+    )?;
+    let result = generate_policies(&default_generate_config(&config.shared, aws_context)).await?;
+    let documents = result
+        .policies
+        .iter()
+        .filter(|policy| policy.is_workload_policy())
+        .map(|policy| serde_json::to_value(&policy.policy))
+        .collect::<Result<Vec<_>, _>>()
+        .context("Failed to serialize generated policies")?;
+    if documents.is_empty() {
+        anyhow::bail!("The source files make no AWS SDK call requiring permissions of the role");
+    }
+
+    let policy_name = config
+        .policy_name
+        .clone()
+        .unwrap_or_else(|| format!("IamPolicyAutopilot-{}", role.name));
+    let applier = PolicyApplier::new().await;
+    let changes = applier
+        .plan(&role, &policy_name, &documents)
+        .await
+        .with_context(|| format!("Failed to read the policies of role {}", role.name))?;
+    if !config.dry_run {
+        applier
+            .apply(&role, &changes)
+            .await
+            .with_context(|| format!("Failed to apply the policies to role {}", role.name))?;
+    }
+    output::output_policy_changes(&changes, config.dry_run, config.shared.pretty)
+        .context("Failed to output policy changes")?;
+    Ok(())
+}
+
+/// Handle the list-calls subcommand
+async fn handle_list_calls(config: &SharedConfig) -> Result<()> {
+    use iam_policy_autopilot_policy_generation::api::model::ServiceHints;
+
+    info!("Running list-calls command");
+
+    config
+        .validate()
+        .context("Configuration validation failed")?;
+
+    let service_hints = config.service_hints.as_ref().map(|names| ServiceHints {
+        service_names: names.clone(),
+    });
+
+    let calls = list_calls(&ExtractSdkCallsConfig {
+        source_files: config.source_files.clone(),
+        language: config.language.clone(),
+        service_hints,
+        exclude_tests: config.analysis.exclude_tests,
+        included: config.included(),
+        jobs: config.analysis.jobs.map(usize::from),
+        max_file_size: config.analysis.max_file_size,
+        dependencies: config.dependencies(),
+        plugins: config.analysis.plugins.clone(),
+        wrappers: config.analysis.wrappers.clone(),
+        go_binaries: Vec::new(),
+        progress: None,
+        cache_dir: None,
+    })
+    .await?;
+
+    output::output_call_inventory(&calls, config.pretty).context("Failed to output SDK calls")
+}
+
+/// Handle the explain subcommand.
+async fn handle_explain(config: &ExplainCliConfig) -> Result<()> {
+    info!("Running explain command");
+
+    let provenance = if let Some(path) = &config.provenance {
+        let content = std::fs::read_to_string(path)
+            .with_context(|| format!("Failed to read provenance file {}", path.display()))?;
+        provenance_actions(&content)
+            .with_context(|| format!("Invalid provenance file {}", path.display()))?
+    } else {
+        config
+            .shared
+            .validate()
+            .context("Configuration validation failed")?;
+
+        let aws_context = config
+            .partition
+            .aws_context(&config.region, &config.account)?;
+        let generate_config = GeneratePolicyConfig {
+            action_provenance: true,
+            ..default_generate_config(&config.shared, aws_context)
+        };
+        generate_policies(&generate_config)
+            .await?
+            .action_provenance
+            .unwrap_or_default()
+    };
+
+    let explained: Vec<&ActionProvenance> = provenance
+        .iter()
+        .filter(|action| action.explains(&config.target))
+        .collect();
+    if explained.is_empty() {
+        anyhow::bail!(
+            "The generated policies grant no action or resource matching {}",
+            config.target
+        );
+    }
+    output::print_explanation(&explained);
+    Ok(())
+}
+
+/// Handle the normalize subcommand.
+fn handle_normalize(policy_file: &Path, keep_ids: bool, pretty: bool) -> Result<()> {
+    info!("Running normalize command");
+
+    let content = std::fs::read_to_string(policy_file)
+        .with_context(|| format!("Failed to read policy file {}", policy_file.display()))?;
+    let documents = policy_documents(&content)
+        .with_context(|| format!("Invalid policy file {}", policy_file.display()))?;
+    let document = match documents.as_slice() {
+        [document] => document.clone(),
+        documents => combined_policy_document(documents),
+    };
+    let normalized = normalize_policy(&document, keep_ids);
+    let json = if pretty {
+        serde_json::to_string_pretty(&normalized)?
+    } else {
+        serde_json::to_string(&normalized)?
+    };
+    println!("{json}");
+    Ok(())
+}
+
+/// Handle the aggregate subcommand.
+async fn handle_aggregate(config: &AggregateCliConfig) -> Result<()> {
+    info!("Running aggregate command");
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    let template = default_generate_config(&config.shared, aws_context);
+    let projects = aggregate::load_projects(&config.paths, config.shared.language.as_deref())?;
+
+    let json_output = if config.merge {
+        let merged = aggregate::merged_policies(&projects, &template).await?;
+        if config.shared.pretty {
+            serde_json::to_string_pretty(&merged)
+        } else {
+            serde_json::to_string(&merged)
+        }
+    } else {
+        let path_policies = aggregate::path_policies(&projects, &template).await?;
+        if let Some(output_dir) = &config.output_dir {
+            return aggregate::write_path_policies(&path_policies, output_dir);
+        }
+        let paths = serde_json::json!({ "Paths": path_policies });
+        if config.shared.pretty {
+            serde_json::to_string_pretty(&paths)
+        } else {
+            serde_json::to_string(&paths)
+        }
+    }
+    .context("Failed to serialize the aggregated policies")?;
+    println!("{json_output}");
+    Ok(())
+}
+
+/// Handle the bench subcommand, returning whether a phase regressed beyond
+/// --max-regression.
+async fn handle_bench(config: &BenchCliConfig) -> Result<bool> {
+    info!("Running bench command");
+
+    let mut shared = config.shared.clone();
+    shared.source_files = remote_sources::directory_source_files(
+        &config.path,
+        shared.language.as_deref(),
+        "--language",
+    )?;
+    let aws_context = AwsContext::with_partition(None, "*".to_string(), "*".to_string())?;
+    let generate_config = default_generate_config(&shared, aws_context);
+
+    let report = bench::run(&generate_config, usize::from(config.iterations)).await?;
+    let mut regressed = false;
+    if let Some(baseline) = &config.baseline {
+        let baseline_report = bench::load_report(baseline)?;
+        for comparison in bench::compare(&report, &baseline_report) {
+            let change = comparison.change_percent();
+            output::note(&format!(
+                "Median {} {:.3}s, {change:+.1}% from {:.3}s in {} {}",
+                comparison.phase,
+                comparison.current_seconds,
+                comparison.baseline_seconds,
+                baseline_report.version,
+                baseline.display()
+            ));
+            if config
+                .max_regression
+                .is_some_and(|max_regression| change > f64::from(max_regression))
+            {
+                output::warn(&format!(
+                    "The {} regressed by more than {}%",
+                    comparison.phase,
+                    config.max_regression.unwrap_or_default()
+                ));
+                regressed = true;
+            }
+        }
+    }
+
+    let json_output = if config.shared.pretty {
+        serde_json::to_string_pretty(&report)
+    } else {
+        serde_json::to_string(&report)
+    }
+    .context("Failed to serialize the bench report")?;
+    println!("{json_output}");
+    Ok(regressed)
+}
+
+/// Handle the lsp subcommand.
+async fn handle_lsp(config: &LspCliConfig) -> Result<()> {
+    info!("Starting language server");
+
+    let aws_context = config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    lsp_server::serve(config.shared.clone(), aws_context).await
+}
+
+/// Handle the serve subcommand.
+async fn handle_serve(mut config: ServeCliConfig) -> Result<()> {
+    info!("Starting HTTP server");
+
+    // Fail at startup rather than in every job
+    config
+        .partition
+        .aws_context(&config.region, &config.account)?;
+    config.allow_path_root = config
+        .allow_path_root
+        .map(|root| {
+            root.canonicalize()
+                .with_context(|| format!("Failed to resolve --allow-path-root {}", root.display()))
+        })
+        .transpose()?;
+    http_server::serve(config).await
+}
+
+/// Actions of a provenance file written by generate-policies --provenance
+fn provenance_actions(content: &str) -> Result<Vec<ActionProvenance>> {
+    let mut value: serde_json::Value = serde_json::from_str(content)?;
+    Ok(serde_json::from_value(value["Actions"].take())?)
+}
+
+/// JSON documents of the policies of a policy file: the output of generate-policies or
+/// a single policy document
+fn policy_documents(content: &str) -> Result<Vec<serde_json::Value>> {
+    let value: serde_json::Value = serde_json::from_str(content)?;
+    if let Some(policies) = value["Policies"].as_array() {
+        Ok(policies
+            .iter()
+            .map(|policy| policy["Policy"].clone())
+            .collect())
+    } else if value.get("Statement").is_some() {
+        Ok(vec![value])
+    } else {
+        anyhow::bail!("expected the output of generate-policies or an IAM policy document")
+    }
+}
+
+/// One policy document of the statements of `documents`
+fn combined_policy_document(documents: &[serde_json::Value]) -> serde_json::Value {
+    let statements: Vec<serde_json::Value> = documents
+        .iter()
+        .flat_map(|document| match &document["Statement"] {
+            serde_json::Value::Array(statements) => statements.clone(),
+            statement => vec![statement.clone()],
+        })
+        .collect();
+    serde_json::json!({
+        "Version": "2012-10-17",
+        "Statement": statements,
+    })
+}
+
+#[cfg(feature = "model-generation")]
+async fn handle_generate_model(
+    source_files: Vec<PathBuf>,
+    entry_points: Vec<String>,
+    library_name: String,
+    language: Option<String>,
+    service_hints: Option<Vec<String>>,
+    pretty: bool,
+) -> Result<()> {
+    use iam_policy_autopilot_policy_generation::api::model::ServiceHints;
+    use iam_policy_autopilot_policy_generation::api::{generate_model, GenerateModelConfig};
+
+    info!("Running generate-model command");
+
+    for file in &source_files {
+        if !file.exists() {
+            anyhow::bail!("Source file does not exist: {}", file.display());
+        }
+    }
+
+    let config = GenerateModelConfig {
+        source_files,
+        language,
+        library_name,
+        entry_points,
+        service_hints: service_hints.map(|service_names| ServiceHints { service_names }),
+    };
+
+    let model = generate_model(&config).await?;
+
+    let json = if pretty {
+        serde_json::to_string_pretty(&model)?
+    } else {
+        serde_json::to_string(&model)?
+    };
+
+    print!("{json}");
+    if pretty {
+        println!();
+    }
+
+    Ok(())
+}
+
+fn show_telemetry_notice(cli: &Cli) {
+    // --- Telemetry: show notice (before execution) ---
+    // Skip CLI notice for variants annotated with #[telemetry(skip)] or #[telemetry(skip_notice)]:
+    //   - `telemetry` subcommand (user is already managing telemetry) — via skip
+    //   - `mcp-server` subcommand (notice is sent via MCP notifications/message instead) — via skip_notice
+    if !cli.command.should_skip_notice() {
+        if let Some(notice) = telemetry::telemetry_notice() {
+            eprintln!("\n{notice}\n");
+        }
+    }
+}
+
+#[tokio::main]
+async fn main() {
+    let cli = Cli::parse();
+
+    show_telemetry_notice(&cli);
+
+    // Build telemetry event using the derived ToTelemetryEvent trait (result data added after execution).
+    // to_telemetry_event() returns None automatically when telemetry is disabled or for #[telemetry(skip)] variants.
+    let mut telemetry_event = cli.command.to_telemetry_event();
+
+    #[cfg(feature = "profiling")]
+    let profiler = match cli.profile.as_deref() {
+        Some(directory) => match profiling::Profiler::start(directory).await {
+            Ok(profiler) => Some(profiler),
+            Err(e) => {
+                print_cli_command_error(e);
+                process::exit(ExitCode::Error.into());
+            }
+        },
+        None => None,
+    };
+
+    let code = match cli.command {
+        Commands::FixAccessDenied { source, yes } => {
+            let error_text = match source {
+                None => {
+                    // No argument provided - read from stdin
+                    use std::io::{self, Read};
+                    let mut buffer = String::new();
+                    match io::stdin().read_to_string(&mut buffer) {
+                        Ok(_) => buffer,
+                        Err(e) => {
+                            eprintln!("iam-policy-autopilot: Failed to read from stdin: {e}");
+                            process::exit(ExitCode::Error.into());
+                        }
+                    }
+                }
+                Some(text) => text,
+            };
+
+            Box::pin(telemetry::span::run_with_telemetry(
+                commands::fix_access_denied(&error_text, yes),
+                &mut telemetry_event,
+            ))
+            .await
+        }
+
+        Commands::ExtractSdkCalls {
+            source_files,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            full_output,
+            service_hints,
+            analysis,
+        } => {
+            // Initialize logging
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(1);
+            }
+
+            let config = SharedConfig {
+                source_files,
+                pretty,
+                language,
+                full_output,
+                service_hints,
+                analysis,
+            };
+
+            match handle_extract_sdk_calls(&config).await {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Duplicate // Exit code 1 for extract-sdk-calls errors
+                }
+            }
+        }
+
+        Commands::GeneratePolicies {
+            source_files,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            full_output,
+            region,
+            account,
+            partition,
+            individual_policies,
+            upload_policies,
+            minimal_policy_size,
+            disable_cache,
+            resource_cutoff,
+            wildcard_resources,
+            app_config,
+            interactive,
+            answers_file,
+            disambiguation_file,
+            mapping_overrides,
+            service_reference_lock,
+            extraction_cache,
+            custom_services,
+            principals,
+            min_confidence,
+            template,
+            s3_resource_forms,
+            report_unscoped,
+            compact_actions,
+            managed_policies,
+            trust_policies,
+            resource_policies,
+            cross_account_report,
+            workload_role_arn,
+            suggest_conditions,
+            observability_permissions,
+            collector_config,
+            event_source_permissions,
+            s3_multipart_actions,
+            no_kms_via_service,
+            kms_via_services,
+            kms_key_arns,
+            exclude_services,
+            deny_actions,
+            allow_actions,
+            split_read_write,
+            per_entry_point,
+            validate,
+            check_no_new_access,
+            forbidden_actions,
+            fail_on,
+            flag_sensitive,
+            access_summary,
+            coverage,
+            provenance,
+            sarif,
+            html_report,
+            action_inventory,
+            metadata,
+            metadata_manifest,
+            split_by_service,
+            runtime,
+            restrict_regions,
+            source_vpce,
+            source_vpc,
+            source_ip,
+            vpc_endpoint_services,
+            access_analyzer_policy,
+            output_format,
+            service_hints,
+            analysis,
+            go_binaries,
+            explain,
+            tf_dir,
+            tf_files,
+            tfstate,
+            tfvars,
+            explain_resources,
+        } => {
+            // Initialize logging
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(1);
+            }
+
+            let config = GeneratePolicyCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty,
+                    language,
+                    full_output,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                individual_policies,
+                upload_policies,
+                minimal_policy_size,
+                disable_cache,
+                resource_cutoff,
+                wildcard_resources,
+                app_config,
+                interactive,
+                answers_file,
+                disambiguation_file,
+                mapping_overrides,
+                service_reference_lock,
+                extraction_cache,
+                custom_services,
+                principals,
+                min_confidence,
+                template,
+                s3_resource_forms,
+                report_unscoped,
+                compact_actions,
+                managed_policies,
+                trust_policies,
+                resource_policies,
+                cross_account_report,
+                workload_role_arn,
+                suggest_conditions,
+                observability_permissions,
+                collector_config,
+                event_source_permissions,
+                s3_multipart_actions,
+                no_kms_via_service,
+                kms_via_services,
+                kms_key_arns,
+                exclude_services,
+                deny_actions,
+                allow_actions,
+                split_read_write,
+                per_entry_point,
+                validate,
+                check_no_new_access,
+                forbidden_actions,
+                fail_on,
+                flag_sensitive,
+                access_summary,
+                coverage,
+                provenance,
+                sarif,
+                html_report,
+                action_inventory,
+                metadata,
+                metadata_manifest,
+                split_by_service,
+                runtime,
+                restrict_regions,
+                source_vpce,
+                source_vpc,
+                source_ip,
+                vpc_endpoint_services,
+                access_analyzer_policy,
+                output_format,
+                explain,
+                tf_dir,
+                tf_files,
+                tfstate,
+                tfvars,
+                explain_resources,
+                go_binaries,
+            };
+
+            let gen_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_generate_policy(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match gen_result {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Duplicate // Exit code 1 for generate-policies errors
+                }
+            }
+        }
+
+        Commands::Simulate {
+            source_files,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            region,
+            account,
+            partition,
+            policy_file,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(1);
+            }
+
+            let config = SimulateCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                policy_file,
+            };
+
+            let sim_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_simulate(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match sim_result {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Duplicate // Exit code 1 for denied requests and simulate errors
+                }
+            }
+        }
+
+        Commands::CheckUsage {
+            source_files,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            region,
+            account,
+            partition,
+            role_arn,
+            days,
+            cloudtrail_export,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(1);
+            }
 
-#[cfg(feature = "model-generation")]
-async fn handle_generate_model(
-    source_files: Vec<PathBuf>,
-    entry_points: Vec<String>,
-    library_name: String,
-    language: Option<String>,
-    service_hints: Option<Vec<String>>,
-    pretty: bool,
-) -> Result<()> {
-    use iam_policy_autopilot_policy_generation::api::model::ServiceHints;
-    use iam_policy_autopilot_policy_generation::api::{generate_model, GenerateModelConfig};
+            let config = CheckUsageCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                role_arn,
+                days,
+                cloudtrail_export,
+            };
 
-    info!("Running generate-model command");
+            let usage_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_check_usage(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match usage_result {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Duplicate // Exit code 1 for check-usage errors
+                }
+            }
+        }
 
-    for file in &source_files {
-        if !file.exists() {
-            anyhow::bail!("Source file does not exist: {}", file.display());
+        Commands::Diff {
+            source_files,
+            existing,
+            diff_ref,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            region,
+            account,
+            partition,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(1);
+            }
+
+            let config = DiffCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                existing,
+                diff_ref,
+            };
+
+            let diff_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_diff(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match diff_result {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Duplicate // Exit code 1 for diff errors
+                }
+            }
         }
-    }
 
-    let config = GenerateModelConfig {
-        source_files,
-        language,
-        library_name,
-        entry_points,
-        service_hints: service_hints.map(|service_names| ServiceHints { service_names }),
-    };
+        Commands::Changelog {
+            source_files,
+            from,
+            to,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            output_format,
+            language,
+            region,
+            account,
+            partition,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(1);
+            }
 
-    let model = generate_model(&config).await?;
+            let config = ChangelogCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                from,
+                to,
+                markdown: output_format == "markdown",
+            };
 
-    let json = if pretty {
-        serde_json::to_string_pretty(&model)?
-    } else {
-        serde_json::to_string(&model)?
-    };
+            let changelog_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_changelog(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match changelog_result {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Duplicate // Exit code 1 for changelog errors
+                }
+            }
+        }
+
+        Commands::CheckBaseline {
+            source_files,
+            baseline,
+            update_baseline,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            region,
+            account,
+            partition,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            let config = CheckBaselineCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                baseline,
+                update_baseline,
+            };
+
+            let baseline_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_check_baseline(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match baseline_result {
+                Ok(false) => ExitCode::Success,
+                Ok(true) => ExitCode::Duplicate, // Exit code 1 for new permissions
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error // Exit code 2 for check-baseline errors, unlike drift
+                }
+            }
+        }
+
+        Commands::Test {
+            source_files,
+            golden,
+            update,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            region,
+            account,
+            partition,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            let config = TestCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                golden,
+                update,
+            };
+
+            let test_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_test(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match test_result {
+                Ok(false) => ExitCode::Success,
+                Ok(true) => ExitCode::Duplicate, // Exit code 1 for policy changes
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error // Exit code 2 for test errors, unlike policy changes
+                }
+            }
+        }
+
+        Commands::Hook {
+            source_files,
+            policy_file,
+            debug,
+            verbose,
+            progress,
+            language,
+            region,
+            account,
+            partition,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            let config = HookCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty: false,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                policy_file,
+            };
+
+            let hook_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_hook(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match hook_result {
+                Ok(false) => ExitCode::Success,
+                Ok(true) => ExitCode::Duplicate, // Exit code 1 for a stale policy file
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error // Exit code 2 for hook errors, unlike a stale file
+                }
+            }
+        }
+
+        Commands::TerraformDataSource { debug } => {
+            if let Err(e) = init_logging(debug, 0, false) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            match Box::pin(telemetry::span::run_with_telemetry(
+                handle_terraform_data_source(),
+                &mut telemetry_event,
+            ))
+            .await
+            {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error // Exit code 2 for terraform-data-source errors
+                }
+            }
+        }
+
+        Commands::UpdateMappings { debug } => {
+            if let Err(e) = init_logging(debug, 0, false) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            match Box::pin(telemetry::span::run_with_telemetry(
+                handle_update_mappings(),
+                &mut telemetry_event,
+            ))
+            .await
+            {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error // Exit code 2 for update-mappings errors
+                }
+            }
+        }
+
+        Commands::Mappings {
+            command:
+                MappingsCommand::Dump {
+                    services,
+                    mapping_overrides,
+                    pretty,
+                    debug,
+                },
+        } => {
+            if let Err(e) = init_logging(debug, 0, false) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            match Box::pin(telemetry::span::run_with_telemetry(
+                handle_mappings_dump(&services, mapping_overrides.as_deref(), pretty),
+                &mut telemetry_event,
+            ))
+            .await
+            {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error // Exit code 2 for mappings errors
+                }
+            }
+        }
 
-    print!("{json}");
-    if pretty {
-        println!();
-    }
+        Commands::AuditUnused {
+            source_files,
+            policy_file,
+            role_name,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            region,
+            account,
+            partition,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(1);
+            }
 
-    Ok(())
-}
+            let config = AuditUnusedCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                policy_file,
+                role_name,
+            };
 
-fn show_telemetry_notice(cli: &Cli) {
-    // --- Telemetry: show notice (before execution) ---
-    // Skip CLI notice for variants annotated with #[telemetry(skip)] or #[telemetry(skip_notice)]:
-    //   - `telemetry` subcommand (user is already managing telemetry) — via skip
-    //   - `mcp-server` subcommand (notice is sent via MCP notifications/message instead) — via skip_notice
-    if !cli.command.should_skip_notice() {
-        if let Some(notice) = telemetry::telemetry_notice() {
-            eprintln!("\n{notice}\n");
+            let audit_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_audit_unused(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match audit_result {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Duplicate // Exit code 1 for audit-unused errors
+                }
+            }
         }
-    }
-}
-
-#[tokio::main]
-async fn main() {
-    let cli = Cli::parse();
 
-    show_telemetry_notice(&cli);
-
-    // Build telemetry event using the derived ToTelemetryEvent trait (result data added after execution).
-    // to_telemetry_event() returns None automatically when telemetry is disabled or for #[telemetry(skip)] variants.
-    let mut telemetry_event = cli.command.to_telemetry_event();
+        Commands::Apply {
+            source_files,
+            role_arn,
+            policy_name,
+            dry_run,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            region,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
 
-    let code = match cli.command {
-        Commands::FixAccessDenied { source, yes } => {
-            let error_text = match source {
-                None => {
-                    // No argument provided - read from stdin
-                    use std::io::{self, Read};
-                    let mut buffer = String::new();
-                    match io::stdin().read_to_string(&mut buffer) {
-                        Ok(_) => buffer,
-                        Err(e) => {
-                            eprintln!("iam-policy-autopilot: Failed to read from stdin: {e}");
-                            process::exit(ExitCode::Error.into());
-                        }
-                    }
-                }
-                Some(text) => text,
+            let config = ApplyCliConfig {
+                shared: SharedConfig {
+                    source_files,
+                    pretty,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                role_arn,
+                policy_name,
+                dry_run,
             };
 
-            Box::pin(telemetry::span::run_with_telemetry(
-                commands::fix_access_denied(&error_text, yes),
+            match Box::pin(telemetry::span::run_with_telemetry(
+                handle_apply(&config),
                 &mut telemetry_event,
             ))
             .await
+            {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error // Exit code 2 for apply errors
+                }
+            }
         }
 
-        Commands::ExtractSdkCalls {
+        Commands::ListCalls {
             source_files,
             debug,
+            verbose,
+            progress,
             pretty,
             language,
-            full_output,
             service_hints,
+            analysis,
         } => {
-            // Initialize logging
-            if let Err(e) = init_logging(debug) {
+            if let Err(e) = init_logging(debug, verbose, progress) {
                 eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
                 process::exit(1);
             }
@@ -831,79 +6085,193 @@ async fn main() {
                 source_files,
                 pretty,
                 language,
-                full_output,
+                full_output: false,
                 service_hints,
+                analysis,
             };
 
-            match handle_extract_sdk_calls(&config).await {
+            let list_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_list_calls(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match list_result {
                 Ok(()) => ExitCode::Success,
                 Err(e) => {
                     print_cli_command_error(e);
-                    ExitCode::Duplicate // Exit code 1 for extract-sdk-calls errors
+                    ExitCode::Duplicate // Exit code 1 for list-calls errors
                 }
             }
         }
 
-        Commands::GeneratePolicies {
+        Commands::Explain {
+            target,
             source_files,
+            provenance,
             debug,
-            pretty,
+            verbose,
+            progress,
             language,
-            full_output,
             region,
             account,
-            individual_policies,
-            upload_policies,
-            minimal_policy_size,
-            disable_cache,
-            resource_cutoff,
+            partition,
             service_hints,
-            explain,
-            tf_dir,
-            tf_files,
-            tfstate,
-            tfvars,
-            explain_resources,
+            analysis,
         } => {
-            // Initialize logging
-            if let Err(e) = init_logging(debug) {
+            if let Err(e) = init_logging(debug, verbose, progress) {
                 eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
                 process::exit(1);
             }
 
-            let config = GeneratePolicyCliConfig {
+            let config = ExplainCliConfig {
                 shared: SharedConfig {
                     source_files,
+                    pretty: false,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                target,
+                provenance,
+            };
+
+            let explain_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_explain(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match explain_result {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Duplicate // Exit code 1 for explain errors
+                }
+            }
+        }
+
+        Commands::Normalize {
+            policy_file,
+            keep_ids,
+            debug,
+            pretty,
+        } => {
+            if let Err(e) = init_logging(debug, 0, false) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            let normalize_result = Box::pin(telemetry::span::run_with_telemetry(
+                async { handle_normalize(&policy_file, keep_ids, pretty) },
+                &mut telemetry_event,
+            ))
+            .await;
+            match normalize_result {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error
+                }
+            }
+        }
+
+        Commands::Aggregate {
+            paths,
+            merge,
+            output_dir,
+            debug,
+            verbose,
+            progress,
+            pretty,
+            language,
+            region,
+            account,
+            partition,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, progress) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            let config = AggregateCliConfig {
+                shared: SharedConfig {
+                    source_files: Vec::new(),
                     pretty,
                     language,
-                    full_output,
+                    full_output: false,
                     service_hints,
+                    analysis,
                 },
                 region,
                 account,
-                individual_policies,
-                upload_policies,
-                minimal_policy_size,
-                disable_cache,
-                resource_cutoff,
-                explain,
-                tf_dir,
-                tf_files,
-                tfstate,
-                tfvars,
-                explain_resources,
+                partition,
+                paths,
+                merge,
+                output_dir,
             };
 
-            let gen_result = Box::pin(telemetry::span::run_with_telemetry(
-                handle_generate_policy(&config),
+            let aggregate_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_aggregate(&config),
                 &mut telemetry_event,
             ))
             .await;
-            match gen_result {
+            match aggregate_result {
                 Ok(()) => ExitCode::Success,
                 Err(e) => {
                     print_cli_command_error(e);
-                    ExitCode::Duplicate // Exit code 1 for generate-policies errors
+                    ExitCode::Error
+                }
+            }
+        }
+
+        Commands::Bench {
+            path,
+            iterations,
+            baseline,
+            max_regression,
+            debug,
+            verbose,
+            pretty,
+            language,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, false) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            let config = BenchCliConfig {
+                shared: SharedConfig {
+                    source_files: Vec::new(),
+                    pretty,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                path,
+                iterations,
+                baseline,
+                max_regression,
+            };
+
+            let bench_result = Box::pin(telemetry::span::run_with_telemetry(
+                handle_bench(&config),
+                &mut telemetry_event,
+            ))
+            .await;
+            match bench_result {
+                Ok(false) => ExitCode::Success,
+                Ok(true) => ExitCode::Duplicate, // Exit code 1 for regressions
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error
                 }
             }
         }
@@ -918,7 +6286,7 @@ async fn main() {
             language,
             service_hints,
         } => {
-            if let Err(e) = init_logging(debug_flag) {
+            if let Err(e) = init_logging(debug_flag, 0, false) {
                 eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
                 process::exit(1);
             }
@@ -941,6 +6309,93 @@ async fn main() {
             }
         }
 
+        Commands::Lsp {
+            debug,
+            verbose,
+            language,
+            region,
+            account,
+            partition,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, false) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            let config = LspCliConfig {
+                shared: SharedConfig {
+                    source_files: Vec::new(),
+                    pretty: false,
+                    language,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+            };
+
+            match handle_lsp(&config).await {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error // Exit code 2 for lsp errors
+                }
+            }
+        }
+
+        Commands::Serve {
+            debug,
+            verbose,
+            port,
+            bind_address,
+            grpc_port,
+            max_concurrent_jobs,
+            max_queued_jobs,
+            allow_path_root,
+            region,
+            account,
+            partition,
+            service_hints,
+            analysis,
+        } => {
+            if let Err(e) = init_logging(debug, verbose, false) {
+                eprintln!("iam-policy-autopilot: Failed to initialize logging: {e}");
+                process::exit(ExitCode::Error.into());
+            }
+
+            let config = ServeCliConfig {
+                shared: SharedConfig {
+                    source_files: Vec::new(),
+                    pretty: false,
+                    language: None,
+                    full_output: false,
+                    service_hints,
+                    analysis,
+                },
+                region,
+                account,
+                partition,
+                port,
+                bind_address,
+                grpc_port,
+                max_concurrent_jobs: usize::from(max_concurrent_jobs),
+                max_queued_jobs: usize::from(max_queued_jobs),
+                allow_path_root,
+            };
+
+            match handle_serve(config).await {
+                Ok(()) => ExitCode::Success,
+                Err(e) => {
+                    print_cli_command_error(e);
+                    ExitCode::Error // Exit code 2 for serve errors
+                }
+            }
+        }
+
         Commands::McpServer {
             transport,
             port,
@@ -988,6 +6443,13 @@ async fn main() {
         }
     };
 
+    #[cfg(feature = "profiling")]
+    if let Some(profiler) = profiler {
+        if let Err(e) = profiler.finish().await {
+            print_cli_command_error(e);
+        }
+    }
+
     // --- Telemetry: emit AFTER execution with result data ---
     telemetry::finalize_and_emit(telemetry_event, code == ExitCode::Success).await;
 
@@ -0,0 +1,275 @@
+//! Metadata of a generate-policies run, as embedded with --metadata.
+//!
+//! A deployed policy traces back to the run that generated it through the version of
+//! the tool, the git commit of the analyzed sources, when the run happened and a digest
+//! of its inputs. These identify the run by a short id, which the policies can carry as
+//! their `Id` or as the prefix of their Sids, and uploaded policies in their description;
+//! the sidecar manifest holds the full metadata to resolve the id with.
+
+use std::fmt::Write;
+use std::path::{Path, PathBuf};
+use std::process::Command;
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::PolicyWithMetadata;
+use serde::Serialize;
+use sha2::{Digest, Sha256};
+
+/// Number of hexadecimal digits of the digest of the metadata identifying the run
+const RUN_ID_LENGTH: usize = 12;
+
+/// Prefix of the Sids of the statements, before the run id
+const SID_PREFIX: &str = "Ipa";
+
+/// What identifies the run that generated a set of policies
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct GenerationMetadata {
+    /// Version of the tool
+    pub(crate) tool_version: String,
+    /// Commit checked out in the repository of the sources, if they're in one
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub(crate) git_commit: Option<String>,
+    /// Whether the sources had changes not committed yet
+    pub(crate) git_dirty: bool,
+    /// When the run happened, in RFC 3339 format
+    pub(crate) timestamp: String,
+    /// SHA-256 digest of the paths and contents of the inputs
+    pub(crate) input_hash: String,
+    /// Short digest of the metadata above
+    pub(crate) run_id: String,
+}
+
+impl GenerationMetadata {
+    /// The metadata of a run on the source files or Go binaries `inputs`, happening now
+    ///
+    /// # Errors
+    /// Returns an error if an input can't be read
+    pub(crate) fn collect(inputs: &[PathBuf]) -> Result<Self> {
+        let (git_commit, git_dirty) = inputs
+            .first()
+            .and_then(|input| input.parent())
+            .map_or((None, false), git_state);
+        Ok(Self::new(
+            git_commit,
+            git_dirty,
+            chrono::Utc::now().to_rfc3339_opts(chrono::SecondsFormat::Secs, true),
+            input_hash(inputs)?,
+        ))
+    }
+
+    fn new(
+        git_commit: Option<String>,
+        git_dirty: bool,
+        timestamp: String,
+        input_hash: String,
+    ) -> Self {
+        let tool_version = env!("CARGO_PKG_VERSION").to_string();
+        let mut hasher = Sha256::new();
+        for part in [
+            tool_version.as_str(),
+            git_commit.as_deref().unwrap_or_default(),
+            if git_dirty { "dirty" } else { "clean" },
+            timestamp.as_str(),
+            input_hash.as_str(),
+        ] {
+            hasher.update(part.as_bytes());
+            hasher.update([0]);
+        }
+        let mut run_id = hex(&hasher.finalize());
+        run_id.truncate(RUN_ID_LENGTH);
+        Self {
+            tool_version,
+            git_commit,
+            git_dirty,
+            timestamp,
+            input_hash,
+            run_id,
+        }
+    }
+
+    /// The `Id` of the policies of the run, e.g. `IamPolicyAutopilot-0.2.3-1a2b3c4d5e6f`
+    pub(crate) fn policy_id(&self) -> String {
+        format!("IamPolicyAutopilot-{}-{}", self.tool_version, self.run_id)
+    }
+
+    /// Prefix of the Sids of the statements of the run, e.g. `Ipa1a2b3c4d5e6f`
+    pub(crate) fn sid_prefix(&self) -> String {
+        format!("{SID_PREFIX}{}", self.run_id)
+    }
+
+    /// Description of the policies of the run as uploaded
+    pub(crate) fn description(&self) -> String {
+        let mut description = format!(
+            "Generated by IAM Policy Autopilot {} at {}",
+            self.tool_version, self.timestamp
+        );
+        if let Some(commit) = &self.git_commit {
+            let _ = write!(description, " from commit {commit}");
+            if self.git_dirty {
+                description.push_str(" with uncommitted changes");
+            }
+        }
+        let _ = write!(
+            description,
+            " (run {}, inputs sha256:{})",
+            self.run_id, self.input_hash
+        );
+        description
+    }
+
+    /// Embed the run in `policies`: as their `Id` with `id`, and as the prefix of their
+    /// Sids with `sid`
+    pub(crate) fn embed(&self, policies: &mut [PolicyWithMetadata], targets: &[String]) {
+        let embeds = |target: &str| targets.iter().any(|embedded| embedded == target);
+        for policy in policies {
+            if embeds("id") {
+                policy.policy.set_id(self.policy_id());
+            }
+            if embeds("sid") {
+                policy.policy.prefix_statement_ids(&self.sid_prefix());
+            }
+        }
+    }
+
+    /// Write the metadata to the sidecar manifest at `path`
+    pub(crate) fn write_manifest(&self, path: &Path) -> Result<()> {
+        let json_output = serde_json::to_string_pretty(self)
+            .context("Failed to serialize the generation metadata")?;
+        std::fs::write(path, format!("{json_output}\n"))
+            .with_context(|| format!("Failed to write metadata manifest {}", path.display()))?;
+        crate::output::note(&format!(
+            "Wrote the metadata of run {} to {}",
+            self.run_id,
+            path.display()
+        ));
+        Ok(())
+    }
+}
+
+/// Commit checked out in the git repository of `directory`, and whether it has changes
+/// not committed yet, if it's in one
+fn git_state(directory: &Path) -> (Option<String>, bool) {
+    let git = |args: &[&str]| {
+        Command::new("git")
+            .arg("-C")
+            .arg(if directory.as_os_str().is_empty() {
+                Path::new(".")
+            } else {
+                directory
+            })
+            .args(args)
+            .output()
+            .ok()
+            .filter(|output| output.status.success())
+            .map(|output| String::from_utf8_lossy(&output.stdout).trim().to_string())
+    };
+    let Some(commit) = git(&["rev-parse", "HEAD"]) else {
+        return (None, false);
+    };
+    let dirty = git(&["status", "--porcelain", "--untracked-files=no"])
+        .is_some_and(|status| !status.is_empty());
+    (Some(commit), dirty)
+}
+
+/// SHA-256 digest of the paths and contents of `inputs`, in path order, the files under
+/// directories included
+fn input_hash(inputs: &[PathBuf]) -> Result<String> {
+    let mut files: Vec<PathBuf> = Vec::new();
+    for input in inputs {
+        if input.is_dir() {
+            files.extend(
+                walkdir::WalkDir::new(input)
+                    .into_iter()
+                    .flatten()
+                    .filter(|entry| entry.file_type().is_file())
+                    .map(walkdir::DirEntry::into_path),
+            );
+        } else {
+            files.push(input.clone());
+        }
+    }
+    files.sort();
+    files.dedup();
+
+    let mut hasher = Sha256::new();
+    for file in &files {
+        let content = std::fs::read(file)
+            .with_context(|| format!("Failed to read input {}", file.display()))?;
+        hasher.update(file.to_string_lossy().as_bytes());
+        hasher.update([0]);
+        hasher.update((content.len() as u64).to_le_bytes());
+        hasher.update(&content);
+    }
+    Ok(hex(&hasher.finalize()))
+}
+
+fn hex(bytes: &[u8]) -> String {
+    bytes
+        .iter()
+        .fold(String::with_capacity(bytes.len() * 2), |mut hex, byte| {
+            let _ = write!(hex, "{byte:02x}");
+            hex
+        })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn metadata() -> GenerationMetadata {
+        GenerationMetadata::new(
+            Some("4f2c1e9".to_string()),
+            false,
+            "2026-10-14T09:30:00Z".to_string(),
+            "ab".repeat(32),
+        )
+    }
+
+    #[test]
+    fn test_run_id_depends_on_the_metadata() {
+        let metadata = metadata();
+
+        assert_eq!(metadata.run_id.len(), RUN_ID_LENGTH);
+        assert_eq!(metadata, self::metadata());
+        let later = GenerationMetadata::new(
+            metadata.git_commit.clone(),
+            false,
+            "2026-10-14T09:31:00Z".to_string(),
+            metadata.input_hash.clone(),
+        );
+        assert_ne!(later.run_id, metadata.run_id);
+        assert_eq!(metadata.sid_prefix(), format!("Ipa{}", metadata.run_id));
+    }
+
+    #[test]
+    fn test_description() {
+        let metadata = metadata();
+
+        assert_eq!(
+            metadata.description(),
+            format!(
+                "Generated by IAM Policy Autopilot {} at 2026-10-14T09:30:00Z from commit \
+                 4f2c1e9 (run {}, inputs sha256:{})",
+                env!("CARGO_PKG_VERSION"),
+                metadata.run_id,
+                "ab".repeat(32)
+            )
+        );
+    }
+
+    #[test]
+    fn test_input_hash() {
+        let directory = tempfile::tempdir().unwrap();
+        let app = directory.path().join("app.py");
+        std::fs::write(&app, "import boto3\n").unwrap();
+        std::fs::write(directory.path().join("jobs.py"), "import os\n").unwrap();
+
+        let hash = input_hash(&[directory.path().to_path_buf()]).unwrap();
+
+        assert_eq!(hash.len(), 64);
+        assert_ne!(hash, input_hash(std::slice::from_ref(&app)).unwrap());
+        std::fs::write(&app, "import boto3\nimport os\n").unwrap();
+        assert_ne!(hash, input_hash(&[directory.path().to_path_buf()]).unwrap());
+    }
+}
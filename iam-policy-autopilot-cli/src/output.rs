@@ -1,9 +1,24 @@
 use anyhow::{Context, Result};
 use iam_policy_autopilot_access_denied::{DenialType, PlanResult};
-use iam_policy_autopilot_policy_generation::api::model::GeneratePoliciesResult;
-use iam_policy_autopilot_tools::BatchUploadResponse;
+use iam_policy_autopilot_policy_generation::api::model::{
+    AmbiguousCall, CallConfidence, GeneratePoliciesResult, InventoriedCall, UnresolvedResource,
+};
+use iam_policy_autopilot_policy_generation::{
+    ActionProvenance, CallSite, CoverageCounts, CoverageReport, DeprecatedCall, Diagnostic,
+    DiagnosticKind, ExcludedServiceCall, FilteredAction, InventoriedAction, Location,
+    NoPermissionCall, Runtime, SensitiveAction, ServiceAccessLevels, Severity, SuppressedCall,
+};
+use iam_policy_autopilot_tools::{
+    BatchUploadResponse, CustomCheck, CustomCheckResult, FindingType, PermissionAudit,
+    PolicyChange, PolicyChangeKind, PolicyChangelog, PolicyDiff, SimulationResult, UsageComparison,
+    ValidationFinding,
+};
 use log::debug;
+use std::collections::{BTreeMap, BTreeSet};
 use std::io::{self, Write};
+use std::path::Path;
+
+use crate::golden::{GoldenComparison, GoldenStatus};
 
 pub(crate) fn note(msg: &str) {
     let _ = writeln!(io::stderr(), "iam-policy-autopilot: {msg}");
@@ -13,6 +28,288 @@ pub(crate) fn warn(msg: &str) {
     let _ = writeln!(io::stderr(), "iam-policy-autopilot (warning): {msg}");
 }
 
+/// Print the calls `autopilot:ignore` annotations excluded from the policies, one per line
+pub(crate) fn print_suppressed_calls(suppressed_calls: &[SuppressedCall]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for call in suppressed_calls {
+        let reason = call
+            .reason
+            .as_ref()
+            .map_or_else(String::new, |reason| format!(": {reason}"));
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot: ignored {} at {}{reason}",
+            call.method_name,
+            call.location.to_gnu_format()
+        );
+    }
+}
+
+/// Print the calls resolved with low confidence, one per line, as `excluded` from the
+/// policies or granted in every service they may be made on
+pub(crate) fn print_low_confidence_calls(calls: &[InventoriedCall], excluded: bool) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for call in calls {
+        let outcome = if excluded {
+            "excluded"
+        } else {
+            "granted in every possible service"
+        };
+        let confidence = format!("{:?}", call.confidence).to_lowercase();
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot (warning): {} at {}:{}:{} resolved to {} with {confidence} \
+             confidence, {outcome}",
+            call.operation,
+            call.file.display(),
+            call.line,
+            call.column,
+            call.services.join(", ")
+        );
+    }
+}
+
+/// Print the calls of deprecated or renamed operations, one per line, with how to migrate
+/// them
+pub(crate) fn print_deprecated_calls(calls: &[DeprecatedCall]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for call in calls {
+        let granted = call
+            .current_operation
+            .as_ref()
+            .map(|operation| format!(" (granted as {}:{operation})", call.service))
+            .unwrap_or_default();
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot (warning): deprecated operation {}:{} at {}{granted}: {}",
+            call.service,
+            call.operation,
+            call.location.to_gnu_format(),
+            call.note
+        );
+    }
+}
+
+/// Print the calls left out of the policies because they need no IAM permission, one per
+/// line, with what authorizes them
+pub(crate) fn print_no_permission_calls(calls: &[NoPermissionCall]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for call in calls {
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot: {}:{} at {} needs no permission: {}",
+            call.service,
+            call.operation,
+            call.location.to_gnu_format(),
+            call.note
+        );
+    }
+}
+
+/// Print the calls whose actions in excluded services were left out, one per line
+pub(crate) fn print_excluded_service_calls(calls: &[ExcludedServiceCall]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for call in calls {
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot: {} at {} excluded: {}",
+            call.operation,
+            call.location.to_gnu_format(),
+            call.actions.join(", ")
+        );
+    }
+}
+
+/// Print the actions the action filters stripped from the policies, one per line
+pub(crate) fn print_filtered_actions(actions: &[FilteredAction]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for action in actions {
+        let sid = action
+            .sid
+            .as_ref()
+            .map(|sid| format!(" of {sid}"))
+            .unwrap_or_default();
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot: stripped {}{sid}: {}",
+            action.action, action.description
+        );
+    }
+}
+
+/// Print analysis diagnostics, one per line
+pub(crate) fn print_diagnostics(diagnostics: &[&Diagnostic]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for diagnostic in diagnostics {
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot (warning): {} at {}: {}",
+            diagnostic.kind.id(),
+            diagnostic.location.to_gnu_format(),
+            diagnostic.message
+        );
+    }
+}
+
+/// Print the number of generated actions of each service by access level, one service
+/// per line
+pub(crate) fn print_access_level_summary(summary: &[ServiceAccessLevels]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for service in summary {
+        let counts: Vec<String> = [
+            ("List", &service.list),
+            ("Read", &service.read),
+            ("Write", &service.write),
+            ("Tagging", &service.tagging),
+            ("Permissions management", &service.permissions_management),
+            ("unknown", &service.unknown),
+        ]
+        .iter()
+        .filter(|(_, actions)| !actions.is_empty())
+        .map(|(level, actions)| format!("{} {level}", actions.len()))
+        .collect();
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot: {}: {}",
+            service.service,
+            counts.join(", ")
+        );
+    }
+}
+
+/// Print the coverage of the call sites in total, by language, and for the files whose
+/// call sites weren't all resolved, one per line
+pub(crate) fn print_coverage(coverage: &CoverageReport) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    let counts = |counts: &CoverageCounts| {
+        format!(
+            "{} call sites, {} resolved, {} partially resolved, {} skipped",
+            counts.call_sites, counts.resolved, counts.partially_resolved, counts.skipped
+        )
+    };
+    let _ = writeln!(
+        w,
+        "iam-policy-autopilot: coverage: {}",
+        counts(&coverage.total)
+    );
+    for language in &coverage.languages {
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot: coverage: {}: {}",
+            language.language,
+            counts(&language.counts)
+        );
+    }
+    for file in coverage
+        .files
+        .iter()
+        .filter(|file| file.counts.resolved < file.counts.call_sites)
+    {
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot: coverage: {}: {}",
+            file.file.display(),
+            counts(&file.counts)
+        );
+    }
+}
+
+/// Print the sensitive actions of the generated statements, one per line
+pub(crate) fn print_sensitive_actions(sensitive_actions: &[SensitiveAction]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for sensitive in sensitive_actions {
+        let severity = match sensitive.severity {
+            Severity::Critical => "critical",
+            Severity::High => "high",
+            Severity::Medium => "medium",
+        };
+        let statement = sensitive
+            .sid
+            .as_ref()
+            .map_or_else(String::new, |sid| format!(" statement {sid}"));
+        let locations = if sensitive.locations.is_empty() {
+            String::new()
+        } else {
+            format!(
+                " (required at {})",
+                sensitive
+                    .locations
+                    .iter()
+                    .map(Location::to_gnu_format)
+                    .collect::<Vec<_>>()
+                    .join(", ")
+            )
+        };
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot ({severity}): policy {}{statement}: {} on {}: {}{locations}",
+            sensitive.policy_index,
+            sensitive.action,
+            sensitive.resources.join(", "),
+            sensitive.reason
+        );
+    }
+}
+
+/// Print the findings of validating the generated policies, one per line
+pub(crate) fn print_validation_findings(findings: &[ValidationFinding]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for finding in findings {
+        let severity = match finding.finding_type {
+            FindingType::Error => "error",
+            FindingType::SecurityWarning => "security warning",
+            FindingType::Warning => "warning",
+            FindingType::Suggestion => "suggestion",
+        };
+        let location = if finding.locations.is_empty() {
+            String::new()
+        } else {
+            format!(" {}", finding.locations.join(", "))
+        };
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot ({severity}): policy {}{location}: {} {} See {}",
+            finding.policy_index,
+            finding.issue_code,
+            finding.finding_details,
+            finding.learn_more_link
+        );
+    }
+}
+
+/// Print the failed custom policy checks of the generated policies, one per line
+pub(crate) fn print_custom_check_results(results: &[CustomCheckResult]) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    for result in results.iter().filter(|result| !result.passed) {
+        let check = match result.check {
+            CustomCheck::NoNewAccess => "CheckNoNewAccess",
+            CustomCheck::AccessNotGranted => "CheckAccessNotGranted",
+        };
+        let reasons = if result.reasons.is_empty() {
+            String::new()
+        } else {
+            format!(" ({})", result.reasons.join("; "))
+        };
+        let _ = writeln!(
+            w,
+            "iam-policy-autopilot (error): policy {}: {check} failed: {}{reasons}",
+            result.policy_index, result.message
+        );
+    }
+}
+
 pub(crate) fn print_plan(plan: &PlanResult) {
     let stderr = io::stderr();
     let mut w = stderr.lock();
@@ -36,6 +333,51 @@ pub(crate) fn prompt_apply_once() {
     let _ = io::stderr().flush();
 }
 
+pub(crate) fn prompt_resource(resource: &UnresolvedResource) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    let _ = writeln!(w);
+    let _ = writeln!(
+        w,
+        "Unresolved resource of {} at {}:{}",
+        resource.action,
+        resource.file.display(),
+        resource.line
+    );
+    let _ = writeln!(w, "  Call:     {}", resource.call);
+    let _ = writeln!(w, "  Resource: {}", resource.arn_pattern);
+    let _ = write!(w, "{} [{}]: ", resource.placeholder, resource.default);
+    let _ = w.flush();
+}
+
+pub(crate) fn prompt_service(call: &AmbiguousCall) {
+    let stderr = io::stderr();
+    let mut w = stderr.lock();
+    let _ = writeln!(w);
+    let _ = writeln!(
+        w,
+        "Ambiguous service of {} at {}:{}",
+        call.operation,
+        call.file.display(),
+        call.line
+    );
+    let _ = writeln!(w, "  Call: {}", call.call);
+    for (index, service) in call.services.iter().enumerate() {
+        let _ = writeln!(w, "  {}) {service}", index + 1);
+    }
+    let _ = write!(w, "Service [all]: ");
+    let _ = w.flush();
+}
+
+pub(crate) fn prompt_service_again(call: &AmbiguousCall) {
+    let _ = write!(
+        io::stderr(),
+        "Enter a number from 1 to {} or a service name, or press Enter to keep them all: ",
+        call.services.len()
+    );
+    let _ = io::stderr().flush();
+}
+
 pub(crate) fn print_apply_success(policy_name: &str, principal_kind: &str, principal_name: &str) {
     let _ = writeln!(
         io::stderr(),
@@ -188,3 +530,1335 @@ pub(crate) fn output_iam_policies(
     debug!("Policy output JSON written to stdout");
     Ok(())
 }
+
+/// Output the comparison of the generated policies with CloudTrail usage as JSON to stdout
+pub(crate) fn output_usage_comparison(comparison: &UsageComparison, pretty: bool) -> Result<()> {
+    note(&format!(
+        "{} actions observed in CloudTrail: {} not generated, {} generated actions never observed",
+        comparison.observed_actions,
+        comparison.observed_not_generated.len(),
+        comparison.generated_not_observed.len()
+    ));
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(comparison)
+            .context("Failed to serialize usage comparison to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(comparison)
+            .context("Failed to serialize usage comparison to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+    Ok(())
+}
+
+/// Output the diff of the generated policy with an existing policy as JSON to stdout
+pub(crate) fn output_policy_diff(diff: &PolicyDiff, pretty: bool) -> Result<()> {
+    if diff.is_empty() {
+        note("The generated policy and the existing policy grant the same permissions");
+    } else {
+        note(&format!(
+            "{} missing actions, {} extra actions, {} actions on other resources, {} actions \
+             under other conditions",
+            diff.missing_actions.len(),
+            diff.extra_actions.len(),
+            diff.resource_differences.len(),
+            diff.condition_differences.len()
+        ));
+    }
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(diff)
+            .context("Failed to serialize policy diff to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(diff)
+            .context("Failed to serialize policy diff to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+    Ok(())
+}
+
+/// Output the permission changes from the version labeled `from` to the one labeled `to`
+/// to stdout, as JSON or with `markdown` as a section for release notes
+pub(crate) fn output_policy_changelog(
+    changelog: &PolicyChangelog,
+    from: &str,
+    to: &str,
+    markdown: bool,
+    pretty: bool,
+) -> Result<()> {
+    if changelog.is_empty() {
+        note(&format!("No permission changes from {from} to {to}"));
+    } else {
+        note(&format!(
+            "{} added actions, {} removed actions, {} actions on added resources, {} actions on \
+             removed resources, {} actions under changed conditions",
+            changelog.added_actions.len(),
+            changelog.removed_actions.len(),
+            changelog.added_resources.len(),
+            changelog.removed_resources.len(),
+            changelog.changed_conditions.len()
+        ));
+    }
+
+    if markdown {
+        print!("{}", changelog.to_markdown(from, to));
+        return Ok(());
+    }
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(changelog)
+            .context("Failed to serialize policy changelog to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(changelog)
+            .context("Failed to serialize policy changelog to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+    Ok(())
+}
+
+/// Output the changes apply made, or would make with `dry_run`, to the managed policies
+/// of a role as JSON to stdout
+pub(crate) fn output_policy_changes(
+    changes: &[PolicyChange],
+    dry_run: bool,
+    pretty: bool,
+) -> Result<()> {
+    for change in changes {
+        let summary = match (change.change, change.attached) {
+            (PolicyChangeKind::Create, _) => "created and attached",
+            (PolicyChangeKind::Update, true) => "updated",
+            (PolicyChangeKind::Update, false) => "updated and attached",
+            (PolicyChangeKind::Attach, _) => "attached",
+            (PolicyChangeKind::Unchanged, _) => "up to date",
+            (PolicyChangeKind::Detach, _) => "detached, as no generated policy is named after it",
+        };
+        if dry_run {
+            note(&format!(
+                "{}: {summary} (dry run, nothing applied)",
+                change.policy_name
+            ));
+        } else {
+            note(&format!("{}: {summary}", change.policy_name));
+        }
+    }
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(changes)
+            .context("Failed to serialize policy changes to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(changes)
+            .context("Failed to serialize policy changes to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+    Ok(())
+}
+
+/// Output the SDK calls of the source files as JSON to stdout
+///
+/// Calls whose service couldn't be resolved are counted on stderr.
+pub(crate) fn output_call_inventory(calls: &[InventoriedCall], pretty: bool) -> Result<()> {
+    let unresolved = calls
+        .iter()
+        .filter(|call| call.confidence == CallConfidence::Low)
+        .count();
+    note(&format!(
+        "Found {} SDK calls, {unresolved} of them on clients whose service couldn't be resolved",
+        calls.len()
+    ));
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(calls)
+            .context("Failed to serialize SDK calls to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(calls)
+            .context("Failed to serialize SDK calls to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+    Ok(())
+}
+
+/// Print each explained action with the resources it's granted on and the calls
+/// requiring it to stdout
+pub(crate) fn print_explanation(provenance: &[&ActionProvenance]) {
+    let stdout = io::stdout();
+    let mut w = stdout.lock();
+    for action in provenance {
+        let _ = writeln!(w, "{} on {}", action.action, action.resources.join(", "));
+        if action.calls.is_empty() {
+            let _ = writeln!(w, "  required by no extracted call");
+        }
+        for call in &action.calls {
+            // Calls spanning several lines are printed on one
+            let expression = call.expression.split_whitespace().collect::<Vec<_>>();
+            let _ = writeln!(
+                w,
+                "  {}: {}",
+                call.location.to_gnu_format(),
+                expression.join(" ")
+            );
+        }
+    }
+}
+
+/// Output the unused permissions of the audited policies as JSON to stdout
+pub(crate) fn output_permission_audit(audit: &PermissionAudit, pretty: bool) -> Result<()> {
+    let actions: usize = audit
+        .unused_permissions
+        .iter()
+        .map(|unused| unused.unused_actions.len())
+        .sum();
+    let resources: usize = audit
+        .unused_permissions
+        .iter()
+        .map(|unused| unused.unused_resources.len())
+        .sum();
+    note(&format!(
+        "{} statements of {} policies have unused permissions: {actions} actions, {resources} \
+         resources",
+        audit.unused_permissions.len(),
+        audit.trimmed_policies.len()
+    ));
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(audit)
+            .context("Failed to serialize unused permissions to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(audit)
+            .context("Failed to serialize unused permissions to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+    Ok(())
+}
+
+/// Report the permissions the generated policy needs beyond the baseline on stderr
+pub(crate) fn print_baseline_drift(diff: &PolicyDiff, baseline: &Path) {
+    for action in &diff.missing_actions {
+        warn(&format!("New permission: {action}"));
+    }
+    for difference in &diff.resource_differences {
+        for resource in &difference.missing_resources {
+            warn(&format!(
+                "New permission: {} on {resource}",
+                difference.action
+            ));
+        }
+    }
+    for difference in &diff.condition_differences {
+        if difference.is_unconditioned() {
+            warn(&format!(
+                "New permission: {} without the conditions of the baseline",
+                difference.action
+            ));
+        }
+    }
+    if !diff.extra_actions.is_empty() {
+        note(&format!(
+            "No longer needed by the code: {}",
+            diff.extra_actions.join(", ")
+        ));
+    }
+
+    if diff.requires_new_permissions() {
+        warn(&format!(
+            "The code requires permissions {} doesn't grant; review them and accept them \
+             with --update-baseline",
+            baseline.display()
+        ));
+    } else {
+        note(&format!(
+            "The code requires no permissions beyond {}",
+            baseline.display()
+        ));
+    }
+}
+
+/// Print the golden files the generated policies don't match to stderr, and the comparisons
+/// as JSON to stdout
+pub(crate) fn output_golden_comparisons(
+    comparisons: &[GoldenComparison],
+    pretty: bool,
+) -> Result<()> {
+    for comparison in comparisons {
+        let file = comparison.file.display();
+        match comparison.status {
+            GoldenStatus::Matches => {}
+            GoldenStatus::Differs => warn(&format!("{} differs from {file}", comparison.name)),
+            GoldenStatus::New => warn(&format!("{} has no golden file {file}", comparison.name)),
+            GoldenStatus::Removed => {
+                warn(&format!("{file} has no generated policy anymore"));
+            }
+        }
+    }
+    let failures = comparisons
+        .iter()
+        .filter(|comparison| comparison.is_failure())
+        .count();
+    if failures == 0 {
+        note(&format!(
+            "All {} generated policies match their golden files",
+            comparisons.len()
+        ));
+    } else {
+        warn(&format!(
+            "{failures} golden files don't match the generated policies; review the changes \
+             and accept them with --update"
+        ));
+    }
+
+    let output = serde_json::json!({ "Policies": comparisons });
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(&output)
+            .context("Failed to serialize golden comparisons to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(&output)
+            .context("Failed to serialize golden comparisons to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+    Ok(())
+}
+
+/// Write the generated policies to a baseline file, in the JSON output format
+pub(crate) fn write_baseline(result: GeneratePoliciesResult, baseline: &Path) -> Result<()> {
+    let policy_output = PolicyOutput {
+        result,
+        upload_result: None,
+    };
+    let json_output =
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(&policy_output)
+            .context("Failed to serialize baseline to pretty JSON")?;
+    std::fs::write(baseline, format!("{json_output}\n"))?;
+    note(&format!("Updated baseline {}", baseline.display()));
+    Ok(())
+}
+
+/// Write the provenance of the generated actions to a sidecar file
+pub(crate) fn write_provenance(provenance: &[ActionProvenance], path: &Path) -> Result<()> {
+    let json_output = iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(
+        &serde_json::json!({ "Actions": provenance }),
+    )
+    .context("Failed to serialize provenance to pretty JSON")?;
+    std::fs::write(path, format!("{json_output}\n"))
+        .with_context(|| format!("Failed to write provenance file {}", path.display()))?;
+    note(&format!(
+        "Wrote the provenance of {} actions to {}",
+        provenance.len(),
+        path.display()
+    ));
+    Ok(())
+}
+
+/// Quote a CSV field if it has a separator, quote or line break, doubling its quotes
+fn csv_field(value: &str) -> String {
+    if value.contains([',', '"', '\n', '\r']) {
+        format!("\"{}\"", value.replace('"', "\"\""))
+    } else {
+        value.to_string()
+    }
+}
+
+/// Write the action inventory as CSV, one row per action, resource and call site
+pub(crate) fn write_action_inventory(inventory: &[InventoriedAction], path: &Path) -> Result<()> {
+    let mut csv = String::from("service,action,resource,file,line,confidence\n");
+    for entry in inventory {
+        let file = entry
+            .file
+            .as_ref()
+            .map(|file| file.display().to_string())
+            .unwrap_or_default();
+        let line = entry.line.map(|line| line.to_string()).unwrap_or_default();
+        let confidence = entry
+            .confidence
+            .map(|confidence| format!("{confidence:?}"))
+            .unwrap_or_default();
+        let row: Vec<String> = [
+            entry.service.as_str(),
+            &entry.action,
+            &entry.resource,
+            &file,
+            &line,
+            &confidence,
+        ]
+        .iter()
+        .map(|field| csv_field(field))
+        .collect();
+        csv.push_str(&row.join(","));
+        csv.push('\n');
+    }
+    std::fs::write(path, csv)
+        .with_context(|| format!("Failed to write action inventory {}", path.display()))?;
+    note(&format!(
+        "Wrote the inventory of {} actions by resource and call site to {}",
+        inventory.len(),
+        path.display()
+    ));
+    Ok(())
+}
+
+/// Write the analysis diagnostics to a SARIF 2.1.0 log, one result per diagnostic
+pub(crate) fn write_sarif(diagnostics: &[Diagnostic], path: &Path) -> Result<()> {
+    let rules: Vec<_> = DiagnosticKind::ALL
+        .iter()
+        .map(|kind| {
+            serde_json::json!({
+                "id": kind.id(),
+                "shortDescription": { "text": kind.description() },
+                "defaultConfiguration": { "level": "warning" },
+            })
+        })
+        .collect();
+    let results: Vec<_> = diagnostics
+        .iter()
+        .map(|diagnostic| {
+            let location = &diagnostic.location;
+            serde_json::json!({
+                "ruleId": diagnostic.kind.id(),
+                "level": "warning",
+                "message": { "text": diagnostic.message },
+                "locations": [{
+                    "physicalLocation": {
+                        "artifactLocation": { "uri": sarif_uri(&location.file_path) },
+                        "region": {
+                            "startLine": location.start_position.0,
+                            "startColumn": location.start_position.1,
+                            "endLine": location.end_position.0,
+                            "endColumn": location.end_position.1,
+                        },
+                    },
+                }],
+            })
+        })
+        .collect();
+    let log = serde_json::json!({
+        "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+        "version": "2.1.0",
+        "runs": [{
+            "tool": {
+                "driver": {
+                    "name": "iam-policy-autopilot",
+                    "version": env!("CARGO_PKG_VERSION"),
+                    "informationUri": "https://github.com/awslabs/iam-policy-autopilot",
+                    "rules": rules,
+                },
+            },
+            "results": results,
+        }],
+    });
+
+    let json_output = iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(&log)
+        .context("Failed to serialize diagnostics to pretty JSON")?;
+    std::fs::write(path, format!("{json_output}\n"))
+        .with_context(|| format!("Failed to write SARIF file {}", path.display()))?;
+    note(&format!(
+        "Wrote {} diagnostics to {}",
+        diagnostics.len(),
+        path.display()
+    ));
+    Ok(())
+}
+
+/// URI of a source file in a SARIF log, relative to the working directory when below it
+/// so code scanning resolves it against the repository
+fn sarif_uri(file_path: &Path) -> String {
+    let relative = std::env::current_dir()
+        .ok()
+        .and_then(|dir| file_path.strip_prefix(dir).ok().map(Path::to_path_buf))
+        .unwrap_or_else(|| file_path.to_path_buf());
+    relative.to_string_lossy().replace('\\', "/")
+}
+
+/// Output the results of simulating the analyzed calls as JSON to stdout
+///
+/// Denied requests are also reported on stderr, with the condition keys the simulation
+/// had no values for if that's why they were denied.
+pub(crate) fn output_simulation_results(results: &[SimulationResult], pretty: bool) -> Result<()> {
+    for result in results.iter().filter(|result| !result.is_allowed()) {
+        let request = format!(
+            "{} on {} is {}",
+            result.request.action, result.request.resource, result.decision
+        );
+        if result.is_denied() {
+            warn(&request);
+        } else {
+            note(&format!(
+                "{request} without values of {}",
+                result.missing_context_keys.join(", ")
+            ));
+        }
+    }
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(results)
+            .context("Failed to serialize simulation results to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(results)
+            .context("Failed to serialize simulation results to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+    Ok(())
+}
+
+/// CloudFormation resource type the generated policies are emitted as
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub(crate) enum CloudFormationPolicyType {
+    /// `AWS::IAM::ManagedPolicy` resources attached to the role
+    Managed,
+    /// `AWS::IAM::RolePolicy` resources embedded in the role
+    Inline,
+}
+
+/// Output IAM policies as a CloudFormation template to stdout
+///
+/// The role the policies are attached to is a `RoleName` parameter. Policies for
+/// calls made with assumed-role credentials are attached to a parameter of their
+/// own, defaulting to the name of the assumed role.
+pub(crate) fn output_cloudformation(
+    result: &GeneratePoliciesResult,
+    policy_type: CloudFormationPolicyType,
+    pretty: bool,
+) -> Result<()> {
+    debug!("Formatting IAM policies output as CloudFormation ({policy_type:?})");
+
+    let mut parameters = serde_json::Map::new();
+    parameters.insert(
+        "RoleName".to_string(),
+        serde_json::json!({
+            "Type": "String",
+            "Description": "Name of the IAM role running the application",
+        }),
+    );
+    let mut role_parameters: BTreeMap<&str, String> = BTreeMap::new();
+    let mut profile_parameters: BTreeMap<&str, String> = BTreeMap::new();
+    let mut principal_parameters: BTreeMap<&str, String> = BTreeMap::new();
+    let mut resources = serde_json::Map::new();
+
+    for (index, policy) in result.policies.iter().enumerate() {
+        let role_parameter = match (
+            policy.assumed_role.as_deref(),
+            policy.credential_profile.as_deref(),
+            policy.named_principal.as_deref(),
+        ) {
+            (None, None, None) => "RoleName".to_string(),
+            (Some(role_arn), _, _) => {
+                let count = role_parameters.len();
+                role_parameters
+                    .entry(role_arn)
+                    .or_insert_with(|| {
+                        let parameter = format!("AssumedRoleName{}", count + 1);
+                        parameters.insert(parameter.clone(), assumed_role_parameter(role_arn));
+                        parameter
+                    })
+                    .clone()
+            }
+            (None, Some(profile), _) => {
+                let count = profile_parameters.len();
+                profile_parameters
+                    .entry(profile)
+                    .or_insert_with(|| {
+                        let parameter = format!("ProfileRoleName{}", count + 1);
+                        parameters.insert(
+                            parameter.clone(),
+                            serde_json::json!({
+                                "Type": "String",
+                                "Description": format!(
+                                    "Name of the IAM role of the {profile} profile the \
+                                     application uses"
+                                ),
+                            }),
+                        );
+                        parameter
+                    })
+                    .clone()
+            }
+            (None, None, Some(principal)) => {
+                let count = principal_parameters.len();
+                principal_parameters
+                    .entry(principal)
+                    .or_insert_with(|| {
+                        let parameter = format!("PrincipalRoleName{}", count + 1);
+                        parameters.insert(
+                            parameter.clone(),
+                            serde_json::json!({
+                                "Type": "String",
+                                "Description": format!(
+                                    "Name of the IAM role of the {principal} principal"
+                                ),
+                            }),
+                        );
+                        parameter
+                    })
+                    .clone()
+            }
+        };
+
+        let logical_id = format!("IamPolicyAutopilotPolicy{}", index + 1);
+        let document =
+            serde_json::to_value(&policy.policy).context("Failed to serialize policy document")?;
+        let resource = match policy_type {
+            CloudFormationPolicyType::Managed => serde_json::json!({
+                "Type": "AWS::IAM::ManagedPolicy",
+                "Properties": {
+                    "PolicyDocument": document,
+                    "Roles": [{ "Ref": role_parameter }],
+                },
+            }),
+            CloudFormationPolicyType::Inline => serde_json::json!({
+                "Type": "AWS::IAM::RolePolicy",
+                "Properties": {
+                    "PolicyName": logical_id,
+                    "PolicyDocument": document,
+                    "RoleName": { "Ref": role_parameter },
+                },
+            }),
+        };
+        resources.insert(logical_id, resource);
+    }
+
+    let template = serde_json::json!({
+        "AWSTemplateFormatVersion": "2010-09-09",
+        "Description": "IAM policies generated by IAM Policy Autopilot",
+        "Parameters": parameters,
+        "Resources": resources,
+    });
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(&template)
+            .context("Failed to serialize CloudFormation template to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(&template)
+            .context("Failed to serialize CloudFormation template to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+
+    debug!("CloudFormation template written to stdout");
+    Ok(())
+}
+
+/// Parameter for the name of a role the application assumes, defaulting to its name
+fn assumed_role_parameter(role_arn: &str) -> serde_json::Value {
+    let role_name = role_arn.rsplit('/').next().unwrap_or(role_arn);
+    serde_json::json!({
+        "Type": "String",
+        "Description": format!("Name of the IAM role {role_arn} the application assumes"),
+        "Default": role_name,
+    })
+}
+
+/// Output the analysis as JSON input of Open Policy Agent to stdout
+///
+/// The document has the generated policies under `Policies`, the services and actions they
+/// grant under `Services` and `Actions`, the latter with their resources and the calls
+/// requiring them, and the calls with the actions they require under `Calls`, so Rego
+/// guardrails can check the code against the services it's allowed to use.
+pub(crate) fn output_opa(result: &GeneratePoliciesResult, pretty: bool) -> Result<()> {
+    debug!("Formatting the analysis as OPA input");
+
+    let provenance = result.action_provenance.as_deref().unwrap_or_default();
+    let services: BTreeSet<&str> = provenance
+        .iter()
+        .filter_map(|action| action.action.split_once(':'))
+        .map(|(service, _)| service)
+        .collect();
+    let mut call_actions: BTreeMap<&CallSite, BTreeSet<&str>> = BTreeMap::new();
+    for action in provenance {
+        for call in &action.calls {
+            call_actions.entry(call).or_default().insert(&action.action);
+        }
+    }
+    let calls: Vec<_> = call_actions
+        .into_iter()
+        .map(|(call, actions)| {
+            serde_json::json!({
+                "Location": call.location,
+                "Expression": call.expression,
+                "Actions": actions,
+            })
+        })
+        .collect();
+    let input = serde_json::json!({
+        "Policies": result.policies,
+        "Services": services,
+        "Actions": provenance,
+        "Calls": calls,
+    });
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(&input)
+            .context("Failed to serialize OPA input to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(&input)
+            .context("Failed to serialize OPA input to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+    Ok(())
+}
+
+/// Output IAM policies as Terraform configuration to stdout
+///
+/// Each policy becomes an `aws_iam_policy_document` data source and an `aws_iam_policy`
+/// resource using its JSON.
+pub(crate) fn output_terraform(result: &GeneratePoliciesResult) -> Result<()> {
+    debug!("Formatting IAM policies output as Terraform");
+
+    let mut hcl = String::new();
+    for (index, policy) in result.policies.iter().enumerate() {
+        let name = format!("iam_policy_autopilot_{}", index + 1);
+        let document =
+            serde_json::to_value(&policy.policy).context("Failed to serialize policy document")?;
+
+        if index > 0 {
+            hcl.push('\n');
+        }
+        if let Some(principal) = &policy.named_principal {
+            hcl.push_str(&format!("# Permissions of the principal {principal}\n"));
+        }
+        if let Some(entry_point) = &policy.entry_point {
+            hcl.push_str(&format!("# Permissions of the entry point {entry_point}\n"));
+        }
+        if let Some(role_arn) = &policy.assumed_role {
+            hcl.push_str(&format!(
+                "# Permissions of calls made with credentials of the assumed role {role_arn}\n"
+            ));
+        } else if let Some(profile) = &policy.credential_profile {
+            hcl.push_str(&format!(
+                "# Permissions of calls made with credentials of the profile {profile}\n"
+            ));
+        }
+        hcl.push_str(&format!("data \"aws_iam_policy_document\" \"{name}\" {{\n"));
+        for statement in document["Statement"].as_array().into_iter().flatten() {
+            for suggestion in suggested_conditions(result, index, statement) {
+                hcl.push_str(&format!("  # {suggestion}\n"));
+            }
+            hcl.push_str(&terraform_statement(statement));
+        }
+        hcl.push_str("}\n\n");
+        hcl.push_str(&format!("resource \"aws_iam_policy\" \"{name}\" {{\n"));
+        hcl.push_str(&format!(
+            "  name   = \"IamPolicyAutopilotGeneratedPolicy_{}\"\n",
+            index + 1
+        ));
+        hcl.push_str(&format!(
+            "  policy = data.aws_iam_policy_document.{name}.json\n"
+        ));
+        hcl.push_str("}\n");
+    }
+
+    print!("{hcl}");
+    debug!("Terraform configuration written to stdout");
+    Ok(())
+}
+
+/// A `statement` block of an `aws_iam_policy_document` for a policy statement's JSON
+fn terraform_statement(statement: &serde_json::Value) -> String {
+    let mut block = String::from("  statement {\n");
+    if let Some(sid) = statement["Sid"].as_str() {
+        block.push_str(&format!("    sid       = {}\n", hcl_string(sid)));
+    }
+    if let Some(effect) = statement["Effect"].as_str() {
+        block.push_str(&format!("    effect    = {}\n", hcl_string(effect)));
+    }
+    block.push_str(&format!(
+        "    actions   = {}\n",
+        hcl_list(&statement["Action"])
+    ));
+    block.push_str(&format!(
+        "    resources = {}\n",
+        hcl_list(&statement["Resource"])
+    ));
+    for (operator, keys) in statement["Condition"].as_object().into_iter().flatten() {
+        for (key, values) in keys.as_object().into_iter().flatten() {
+            block.push_str("\n    condition {\n");
+            block.push_str(&format!("      test     = {}\n", hcl_string(operator)));
+            block.push_str(&format!("      variable = {}\n", hcl_string(key)));
+            block.push_str(&format!("      values   = {}\n", hcl_list(values)));
+            block.push_str("    }\n");
+        }
+    }
+    block.push_str("  }\n");
+    block
+}
+
+/// A list of HCL strings for a JSON string or array of strings
+fn hcl_list(value: &serde_json::Value) -> String {
+    let items: Vec<String> = match value {
+        serde_json::Value::String(item) => vec![hcl_string(item)],
+        serde_json::Value::Array(items) => items
+            .iter()
+            .filter_map(serde_json::Value::as_str)
+            .map(hcl_string)
+            .collect(),
+        _ => vec![],
+    };
+    format!("[{}]", items.join(", "))
+}
+
+/// Quote `value` as an HCL string literal
+///
+/// Besides quotes and backslashes, `${` and `%{` are escaped so templates such as
+/// `arn:aws:s3:::${BUCKET_NAME}/*` stay literal instead of being interpolated.
+fn hcl_string(value: &str) -> String {
+    let escaped = value
+        .replace('\\', "\\\\")
+        .replace('"', "\\\"")
+        .replace('\n', "\\n")
+        .replace("${", "$${")
+        .replace("%{", "%%{");
+    format!("\"{escaped}\"")
+}
+
+/// Language of the CDK code the generated policies are emitted as
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub(crate) enum CdkLanguage {
+    /// `new iam.PolicyStatement({...})` of `aws-cdk-lib/aws-iam`
+    TypeScript,
+    /// `iam.PolicyStatement(...)` of `aws_cdk.aws_iam`
+    Python,
+}
+
+/// Output IAM policies as CDK `PolicyStatement` code to stdout
+///
+/// Each policy becomes a list of statements, to add to a role with
+/// `addToPolicy`/`add_to_policy` or to an `iam.Policy` of the stack.
+pub(crate) fn output_cdk(result: &GeneratePoliciesResult, language: CdkLanguage) -> Result<()> {
+    debug!("Formatting IAM policies output as CDK code ({language:?})");
+
+    let mut code = match language {
+        CdkLanguage::TypeScript => String::from("import * as iam from 'aws-cdk-lib/aws-iam';\n"),
+        CdkLanguage::Python => String::from("from aws_cdk import aws_iam as iam\n"),
+    };
+    for (index, policy) in result.policies.iter().enumerate() {
+        let document =
+            serde_json::to_value(&policy.policy).context("Failed to serialize policy document")?;
+        let comment = match language {
+            CdkLanguage::TypeScript => "//",
+            CdkLanguage::Python => "#",
+        };
+
+        code.push('\n');
+        if let Some(principal) = &policy.named_principal {
+            code.push_str(&format!(
+                "{comment} Permissions of the principal {principal}\n"
+            ));
+        }
+        if let Some(entry_point) = &policy.entry_point {
+            code.push_str(&format!(
+                "{comment} Permissions of the entry point {entry_point}\n"
+            ));
+        }
+        if let Some(role_arn) = &policy.assumed_role {
+            code.push_str(&format!(
+                "{comment} Permissions of calls made with credentials of the assumed role \
+                 {role_arn}\n"
+            ));
+        } else if let Some(profile) = &policy.credential_profile {
+            code.push_str(&format!(
+                "{comment} Permissions of calls made with credentials of the profile {profile}\n"
+            ));
+        }
+        match language {
+            CdkLanguage::TypeScript => code.push_str(&format!(
+                "export const iamPolicyAutopilotStatements{}: iam.PolicyStatement[] = [\n",
+                index + 1
+            )),
+            CdkLanguage::Python => code.push_str(&format!(
+                "iam_policy_autopilot_statements_{} = [\n",
+                index + 1
+            )),
+        }
+        for statement in document["Statement"].as_array().into_iter().flatten() {
+            for suggestion in suggested_conditions(result, index, statement) {
+                match language {
+                    CdkLanguage::TypeScript => code.push_str(&format!("  // {suggestion}\n")),
+                    CdkLanguage::Python => code.push_str(&format!("    # {suggestion}\n")),
+                }
+            }
+            code.push_str(&cdk_statement(statement, language));
+        }
+        code.push_str(match language {
+            CdkLanguage::TypeScript => "];\n",
+            CdkLanguage::Python => "]\n",
+        });
+    }
+
+    print!("{code}");
+    debug!("CDK code written to stdout");
+    Ok(())
+}
+
+/// Condition keys suggested for a statement of the policy at `policy_index`, as comments
+fn suggested_conditions(
+    result: &GeneratePoliciesResult,
+    policy_index: usize,
+    statement: &serde_json::Value,
+) -> Vec<String> {
+    let actions: Vec<&str> = match &statement["Action"] {
+        serde_json::Value::Array(items) => {
+            items.iter().filter_map(serde_json::Value::as_str).collect()
+        }
+        item => item.as_str().into_iter().collect(),
+    };
+    result
+        .condition_key_suggestions
+        .iter()
+        .flatten()
+        .filter(|suggestion| {
+            suggestion.policy_index == policy_index
+                && suggestion.sid.as_deref() == statement["Sid"].as_str()
+                && suggestion.actions == actions
+        })
+        .map(|suggestion| {
+            let values = if suggestion.values.is_empty() {
+                "<values>".to_string()
+            } else {
+                json_list(&serde_json::json!(suggestion.values))
+            };
+            format!(
+                "Suggested condition: {} {} {values}. {}",
+                suggestion.operator, suggestion.condition_key, suggestion.description
+            )
+        })
+        .collect()
+}
+
+/// A `PolicyStatement` construction for a policy statement's JSON
+fn cdk_statement(statement: &serde_json::Value, language: CdkLanguage) -> String {
+    let effect = if statement["Effect"] == "Deny" {
+        "DENY"
+    } else {
+        "ALLOW"
+    };
+    let mut properties: Vec<(&str, String)> = Vec::new();
+    if let Some(sid) = statement["Sid"].as_str() {
+        properties.push(("sid", json_string(sid)));
+    }
+    properties.push(("effect", format!("iam.Effect.{effect}")));
+    properties.push(("actions", json_list(&statement["Action"])));
+    properties.push(("resources", json_list(&statement["Resource"])));
+    if let Some(conditions) = statement["Condition"].as_object() {
+        let conditions: Vec<String> = conditions
+            .iter()
+            .map(|(operator, keys)| {
+                let keys: Vec<String> = keys
+                    .as_object()
+                    .into_iter()
+                    .flatten()
+                    .map(|(key, values)| format!("{}: {}", json_string(key), json_list(values)))
+                    .collect();
+                format!("{}: {{ {} }}", json_string(operator), keys.join(", "))
+            })
+            .collect();
+        properties.push(("conditions", format!("{{ {} }}", conditions.join(", "))));
+    }
+
+    match language {
+        CdkLanguage::TypeScript => {
+            let mut code = String::from("  new iam.PolicyStatement({\n");
+            for (name, value) in properties {
+                code.push_str(&format!("    {name}: {value},\n"));
+            }
+            code.push_str("  }),\n");
+            code
+        }
+        CdkLanguage::Python => {
+            let mut code = String::from("    iam.PolicyStatement(\n");
+            for (name, value) in properties {
+                code.push_str(&format!("        {name}={value},\n"));
+            }
+            code.push_str("    ),\n");
+            code
+        }
+    }
+}
+
+/// A list literal of a JSON string or array of strings, valid TypeScript and Python
+fn json_list(value: &serde_json::Value) -> String {
+    let items: Vec<String> = match value {
+        serde_json::Value::String(item) => vec![json_string(item)],
+        serde_json::Value::Array(items) => items
+            .iter()
+            .filter_map(serde_json::Value::as_str)
+            .map(json_string)
+            .collect(),
+        _ => vec![],
+    };
+    format!("[{}]", items.join(", "))
+}
+
+/// Quote `value` as a JSON string, which TypeScript and Python read as the same literal
+fn json_string(value: &str) -> String {
+    serde_json::Value::String(value.to_string()).to_string()
+}
+
+/// Maximum size of a service control policy, in characters
+/// https://docs.aws.amazon.com/organizations/latest/userguide/orgs_reference_limits.html
+const SCP_SIZE_LIMIT: usize = 5120;
+
+/// How a service control policy limits accounts to the discovered actions
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub(crate) enum ScpStrategy {
+    /// Allow the discovered actions, replacing the default `FullAWSAccess` SCP
+    AllowList,
+    /// Deny every action but the discovered ones, next to `FullAWSAccess`
+    DenyByException,
+}
+
+/// Output the actions of all policies as one service control policy to stdout
+///
+/// SCPs have no principals and their statements are kept to `Resource: "*"` without
+/// conditions, which every SCP strategy supports. When the actions don't fit the SCP
+/// size limit, the services with the most actions are collapsed to `service:*`.
+pub(crate) fn output_scp(
+    result: &GeneratePoliciesResult,
+    strategy: ScpStrategy,
+    pretty: bool,
+) -> Result<()> {
+    debug!("Formatting IAM policies output as service control policy ({strategy:?})");
+
+    let mut actions: BTreeMap<String, Vec<String>> = BTreeMap::new();
+    for policy in &result.policies {
+        let document =
+            serde_json::to_value(&policy.policy).context("Failed to serialize policy document")?;
+        for statement in document["Statement"].as_array().into_iter().flatten() {
+            if statement["Effect"] != "Allow" {
+                continue;
+            }
+            let statement_actions = match &statement["Action"] {
+                serde_json::Value::Array(items) => items.iter().collect(),
+                item => vec![item],
+            };
+            for action in statement_actions
+                .into_iter()
+                .filter_map(serde_json::Value::as_str)
+            {
+                let service = action
+                    .split_once(':')
+                    .map_or(action, |(service, _)| service);
+                actions
+                    .entry(service.to_string())
+                    .or_default()
+                    .push(action.to_string());
+            }
+        }
+    }
+    for service_actions in actions.values_mut() {
+        service_actions.sort();
+        service_actions.dedup();
+    }
+
+    let mut document = scp_document(&actions, strategy);
+    while scp_size(&document)? > SCP_SIZE_LIMIT {
+        let Some((service, _)) = actions
+            .iter()
+            .filter(|(_, service_actions)| service_actions.len() > 1)
+            .max_by_key(|(_, service_actions)| service_actions.len())
+        else {
+            anyhow::bail!(
+                "The {} services of the generated policies don't fit the SCP size limit of \
+                 {SCP_SIZE_LIMIT} characters",
+                actions.len()
+            );
+        };
+        let service = service.clone();
+        note(&format!(
+            "collapsing actions of {service} to {service}:* to fit the SCP size limit"
+        ));
+        actions.insert(service.clone(), vec![format!("{service}:*")]);
+        document = scp_document(&actions, strategy);
+    }
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(&document)
+            .context("Failed to serialize service control policy to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(&document)
+            .context("Failed to serialize service control policy to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+
+    debug!("Service control policy written to stdout");
+    Ok(())
+}
+
+/// Service control policy document for the discovered `actions` of each service
+fn scp_document(
+    actions: &BTreeMap<String, Vec<String>>,
+    strategy: ScpStrategy,
+) -> serde_json::Value {
+    let actions: Vec<&String> = actions.values().flatten().collect();
+    let statement = match strategy {
+        ScpStrategy::AllowList => serde_json::json!({
+            "Sid": "AllowDiscoveredActions",
+            "Effect": "Allow",
+            "Action": actions,
+            "Resource": "*",
+        }),
+        ScpStrategy::DenyByException => serde_json::json!({
+            "Sid": "DenyUndiscoveredActions",
+            "Effect": "Deny",
+            "NotAction": actions,
+            "Resource": "*",
+        }),
+    };
+    serde_json::json!({
+        "Version": "2012-10-17",
+        "Statement": [statement],
+    })
+}
+
+/// Size of an SCP serialized without whitespace
+fn scp_size(document: &serde_json::Value) -> Result<usize> {
+    let json = iam_policy_autopilot_policy_generation::JsonProvider::stringify(document)
+        .context("Failed to serialize service control policy")?;
+    Ok(json.len())
+}
+
+/// Form of the role definition the generated policies are emitted in
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub(crate) enum RoleFormat {
+    /// Properties of an `AWS::IAM::Role` as JSON, for `aws iam create-role` and the like
+    Json,
+    /// CloudFormation template with an `AWS::IAM::Role` resource
+    CloudFormation,
+    /// Terraform configuration with an `aws_iam_role` resource
+    Terraform,
+}
+
+/// Output a role for the code running on `runtime`, with the generated policies, to stdout
+///
+/// The role's trust policy allows the service of the runtime to assume it, and the role
+/// has the managed policies the runtime needs attached, e.g. for a Lambda function to
+/// write its logs. The CloudFormation and Terraform forms add an instance profile for
+/// EC2. Policies of calls made with credentials of an assumed role belong to that role,
+/// so they are left out.
+pub(crate) fn output_role(
+    result: &GeneratePoliciesResult,
+    runtime: Runtime,
+    format: RoleFormat,
+    partition: &str,
+    pretty: bool,
+) -> Result<()> {
+    debug!("Formatting IAM policies output as role for {runtime:?} ({format:?})");
+
+    let mut documents = Vec::new();
+    for policy in result.policies.iter().filter(|p| p.is_workload_policy()) {
+        documents.push(
+            serde_json::to_value(&policy.policy).context("Failed to serialize policy document")?,
+        );
+    }
+    let skipped = result.policies.len() - documents.len();
+    if skipped > 0 {
+        warn(&format!(
+            "left out {skipped} policies of calls made with credentials of assumed roles"
+        ));
+    }
+    let trust_policy = serde_json::json!({
+        "Version": "2012-10-17",
+        "Statement": [{
+            "Effect": "Allow",
+            "Principal": { "Service": runtime.service_principal() },
+            "Action": "sts:AssumeRole",
+        }],
+    });
+    let policies: Vec<serde_json::Value> = documents
+        .iter()
+        .enumerate()
+        .map(|(index, document)| {
+            serde_json::json!({
+                "PolicyName": format!("IamPolicyAutopilotPolicy{}", index + 1),
+                "PolicyDocument": document,
+            })
+        })
+        .collect();
+
+    let role = match format {
+        RoleFormat::Terraform => {
+            print!("{}", terraform_role(runtime, &documents));
+            debug!("Terraform role configuration written to stdout");
+            return Ok(());
+        }
+        RoleFormat::Json => {
+            // Managed policy ARNs can't match partitions by wildcard
+            let partition = if partition == "*" { "aws" } else { partition };
+            let managed_policy_arns: Vec<String> = runtime
+                .managed_policies()
+                .iter()
+                .map(|name| format!("arn:{partition}:iam::aws:policy/{name}"))
+                .collect();
+            serde_json::json!({
+                "AssumeRolePolicyDocument": trust_policy,
+                "ManagedPolicyArns": managed_policy_arns,
+                "Policies": policies,
+            })
+        }
+        RoleFormat::CloudFormation => {
+            let mut properties = serde_json::json!({
+                "AssumeRolePolicyDocument": trust_policy,
+                "Policies": policies,
+            });
+            if !runtime.managed_policies().is_empty() {
+                let managed_policy_arns: Vec<serde_json::Value> = runtime
+                    .managed_policies()
+                    .iter()
+                    .map(|name| {
+                        serde_json::json!({
+                            "Fn::Sub": format!("arn:${{AWS::Partition}}:iam::aws:policy/{name}")
+                        })
+                    })
+                    .collect();
+                properties["ManagedPolicyArns"] = managed_policy_arns.into();
+            }
+            let mut resources = serde_json::Map::new();
+            resources.insert(
+                "IamPolicyAutopilotRole".to_string(),
+                serde_json::json!({ "Type": "AWS::IAM::Role", "Properties": properties }),
+            );
+            if runtime == Runtime::Ec2 {
+                resources.insert(
+                    "IamPolicyAutopilotInstanceProfile".to_string(),
+                    serde_json::json!({
+                        "Type": "AWS::IAM::InstanceProfile",
+                        "Properties": { "Roles": [{ "Ref": "IamPolicyAutopilotRole" }] },
+                    }),
+                );
+            }
+            serde_json::json!({
+                "AWSTemplateFormatVersion": "2010-09-09",
+                "Description": "IAM role generated by IAM Policy Autopilot",
+                "Resources": resources,
+                "Outputs": {
+                    "RoleArn": {
+                        "Value": { "Fn::GetAtt": ["IamPolicyAutopilotRole", "Arn"] },
+                    },
+                },
+            })
+        }
+    };
+
+    let json_output = if pretty {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify_pretty(&role)
+            .context("Failed to serialize role to pretty JSON")?
+    } else {
+        iam_policy_autopilot_policy_generation::JsonProvider::stringify(&role)
+            .context("Failed to serialize role to JSON")?
+    };
+
+    print!("{json_output}");
+    if pretty {
+        println!();
+    }
+
+    debug!("Role written to stdout");
+    Ok(())
+}
+
+/// Terraform configuration of a role for `runtime` with the policy `documents` inline
+fn terraform_role(runtime: Runtime, documents: &[serde_json::Value]) -> String {
+    let mut hcl = String::new();
+    if !runtime.managed_policies().is_empty() {
+        hcl.push_str("data \"aws_partition\" \"current\" {}\n\n");
+    }
+    hcl.push_str("data \"aws_iam_policy_document\" \"iam_policy_autopilot_assume_role\" {\n");
+    hcl.push_str("  statement {\n");
+    hcl.push_str("    actions = [\"sts:AssumeRole\"]\n\n");
+    hcl.push_str("    principals {\n");
+    hcl.push_str("      type        = \"Service\"\n");
+    hcl.push_str(&format!(
+        "      identifiers = [{}]\n",
+        hcl_string(runtime.service_principal())
+    ));
+    hcl.push_str("    }\n");
+    hcl.push_str("  }\n");
+    hcl.push_str("}\n\n");
+    hcl.push_str("resource \"aws_iam_role\" \"iam_policy_autopilot\" {\n");
+    hcl.push_str("  name_prefix        = \"IamPolicyAutopilotRole\"\n");
+    hcl.push_str(
+        "  assume_role_policy = \
+         data.aws_iam_policy_document.iam_policy_autopilot_assume_role.json\n",
+    );
+    hcl.push_str("}\n");
+
+    for (index, name) in runtime.managed_policies().iter().enumerate() {
+        hcl.push_str(&format!(
+            "\nresource \"aws_iam_role_policy_attachment\" \
+             \"iam_policy_autopilot_managed_{}\" {{\n",
+            index + 1
+        ));
+        hcl.push_str("  role       = aws_iam_role.iam_policy_autopilot.name\n");
+        hcl.push_str(&format!(
+            "  policy_arn = \
+             \"arn:${{data.aws_partition.current.partition}}:iam::aws:policy/{name}\"\n"
+        ));
+        hcl.push_str("}\n");
+    }
+
+    for (index, document) in documents.iter().enumerate() {
+        let name = format!("iam_policy_autopilot_{}", index + 1);
+        hcl.push_str(&format!(
+            "\ndata \"aws_iam_policy_document\" \"{name}\" {{\n"
+        ));
+        for statement in document["Statement"].as_array().into_iter().flatten() {
+            hcl.push_str(&terraform_statement(statement));
+        }
+        hcl.push_str("}\n\n");
+        hcl.push_str(&format!("resource \"aws_iam_role_policy\" \"{name}\" {{\n"));
+        hcl.push_str(&format!(
+            "  name   = \"IamPolicyAutopilotGeneratedPolicy_{}\"\n",
+            index + 1
+        ));
+        hcl.push_str("  role   = aws_iam_role.iam_policy_autopilot.id\n");
+        hcl.push_str(&format!(
+            "  policy = data.aws_iam_policy_document.{name}.json\n"
+        ));
+        hcl.push_str("}\n");
+    }
+
+    if runtime == Runtime::Ec2 {
+        hcl.push_str("\nresource \"aws_iam_instance_profile\" \"iam_policy_autopilot\" {\n");
+        hcl.push_str("  name_prefix = \"IamPolicyAutopilotRole\"\n");
+        hcl.push_str("  role        = aws_iam_role.iam_policy_autopilot.name\n");
+        hcl.push_str("}\n");
+    }
+    hcl
+}
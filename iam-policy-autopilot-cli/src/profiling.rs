@@ -0,0 +1,102 @@
+//! CPU and heap profiles of a run, as written with --profile.
+//!
+//! Builds with the `profiling` feature allocate with jemalloc, which samples the heap
+//! allocations once profiling starts, and sample the call stacks of the process with
+//! pprof-rs. When the command finishes, the profiles are written to `cpu.pb` and `heap.pb`
+//! in the profile directory, in the pprof format `go tool pprof` and `pprof -http` read.
+//! jemalloc reads its sampling configuration from `MALLOC_CONF` below when the process
+//! starts.
+
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+use pprof::protos::Message;
+
+use crate::output;
+
+#[global_allocator]
+static ALLOCATOR: tikv_jemallocator::Jemalloc = tikv_jemallocator::Jemalloc;
+
+/// jemalloc options, under the prefixed name of jemalloc's `malloc_conf`: sample an
+/// allocation every 512 KiB once --profile activates the heap profiler
+#[export_name = "_rjem_malloc_conf"]
+static MALLOC_CONF: &[u8] = b"prof:true,prof_active:false,lg_prof_sample:19\0";
+
+/// Frequency the call stacks are sampled at, in hertz
+const CPU_SAMPLING_FREQUENCY: i32 = 1000;
+
+/// Libraries whose frames can't be unwound safely from the sampling signal handler
+const CPU_BLOCKLIST: &[&str] = &["libc", "libgcc", "pthread", "vdso"];
+
+/// Profiles of the running command
+pub(crate) struct Profiler {
+    /// Directory the profiles are written to
+    directory: PathBuf,
+    cpu: pprof::ProfilerGuard<'static>,
+}
+
+impl Profiler {
+    /// Start profiling the process, for the profiles to be written to `directory`
+    pub(crate) async fn start(directory: &Path) -> Result<Self> {
+        std::fs::create_dir_all(directory).with_context(|| {
+            format!(
+                "Failed to create the profile directory {}",
+                directory.display()
+            )
+        })?;
+        heap_profiler()?
+            .lock()
+            .await
+            .activate()
+            .context("Failed to start the heap profiler")?;
+        let cpu = pprof::ProfilerGuardBuilder::default()
+            .frequency(CPU_SAMPLING_FREQUENCY)
+            .blocklist(CPU_BLOCKLIST)
+            .build()
+            .context("Failed to start the CPU profiler")?;
+        Ok(Self {
+            directory: directory.to_path_buf(),
+            cpu,
+        })
+    }
+
+    /// Stop profiling and write the CPU and heap profiles
+    pub(crate) async fn finish(self) -> Result<()> {
+        let profile = self
+            .cpu
+            .report()
+            .build()
+            .and_then(|report| report.pprof())
+            .context("Failed to build the CPU profile")?;
+        let mut cpu = Vec::new();
+        profile
+            .encode(&mut cpu)
+            .context("Failed to encode the CPU profile")?;
+        write_profile(&self.directory.join("cpu.pb"), &cpu)?;
+
+        let heap = heap_profiler()?
+            .lock()
+            .await
+            .dump_pprof()
+            .context("Failed to dump the heap profile")?;
+        write_profile(&self.directory.join("heap.pb"), &heap)?;
+
+        output::note(&format!(
+            "Wrote the CPU and heap profiles to {}",
+            self.directory.display()
+        ));
+        Ok(())
+    }
+}
+
+/// Controls of jemalloc's heap profiling
+fn heap_profiler() -> Result<&'static tokio::sync::Mutex<jemalloc_pprof::JemallocProfCtl>> {
+    jemalloc_pprof::PROF_CTL
+        .as_deref()
+        .context("jemalloc was built without heap profiling, see .cargo/config.toml")
+}
+
+fn write_profile(path: &Path, content: &[u8]) -> Result<()> {
+    std::fs::write(path, content)
+        .with_context(|| format!("Failed to write the profile {}", path.display()))
+}
@@ -0,0 +1,311 @@
+//! Source files of remote git repositories and of directories.
+//!
+//! generate-policies accepts git URLs among its source files, e.g.
+//! `https://github.com/org/service.git#v1.2.0`. Each repository is shallow-cloned at the
+//! ref after `#`, or its default branch, into a temporary directory, and its tracked
+//! files of the analyzed language replace the URL. Commands taking a directory rather
+//! than files, such as serve, list the files under it the same way.
+
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+use std::process::Command;
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::Language;
+use tempfile::TempDir;
+
+use crate::output;
+
+/// URL prefixes of git repositories
+const GIT_URL_PREFIXES: &[&str] = &["https://", "http://", "ssh://", "git://", "git@"];
+
+/// Source files with the repositories of git URLs cloned
+#[derive(Debug)]
+pub(crate) struct SourceFiles {
+    /// Local source files, and the files of the cloned repositories
+    pub(crate) files: Vec<PathBuf>,
+    /// Directories of the cloned repositories, removed when dropped
+    _clones: Vec<TempDir>,
+}
+
+impl SourceFiles {
+    /// Local source files, with no repository cloned
+    pub(crate) const fn local(files: Vec<PathBuf>) -> Self {
+        Self {
+            files,
+            _clones: Vec::new(),
+        }
+    }
+}
+
+/// Clone the repositories of the git URLs of `inputs` and list their source files
+///
+/// The files of a repository are those of `language`, or of the only language its
+/// tracked files are in.
+pub(crate) fn resolve(inputs: &[PathBuf], language: Option<&str>) -> Result<SourceFiles> {
+    let mut files = Vec::new();
+    let mut clones = Vec::new();
+    for input in inputs {
+        let Some(url) = input.to_str().filter(|input| is_git_url(input)) else {
+            files.push(input.clone());
+            continue;
+        };
+        let (url, git_ref) = split_git_url(url)?;
+        let clone = TempDir::new().context("Failed to create a directory for the clone")?;
+        shallow_clone(url, git_ref, clone.path())
+            .with_context(|| format!("Failed to clone {url} at {git_ref}"))?;
+        let repository_files = source_files_by_language(clone.path())
+            .and_then(|by_language| files_of_language(by_language, language, "--language"))
+            .with_context(|| format!("Failed to list the source files of {url}"))?;
+        output::note(&format!(
+            "Analyzing {} source files of {url} at {git_ref}",
+            repository_files.len()
+        ));
+        files.extend(repository_files);
+        clones.push(clone);
+    }
+    Ok(SourceFiles {
+        files,
+        _clones: clones,
+    })
+}
+
+/// Source files of `input`, a directory, a file or a git URL whose repository is cloned
+pub(crate) fn project_source_files(input: &Path, language: Option<&str>) -> Result<SourceFiles> {
+    if input.to_str().is_some_and(is_git_url) {
+        return resolve(&[input.to_path_buf()], language);
+    }
+    directory_source_files(input, language, "--language").map(SourceFiles::local)
+}
+
+/// Whether `input` is the URL of a git repository rather than a local path
+fn is_git_url(input: &str) -> bool {
+    GIT_URL_PREFIXES
+        .iter()
+        .any(|prefix| input.starts_with(prefix))
+}
+
+/// The repository URL and the ref after its `#`, `HEAD` without one
+///
+/// Refs starting with `-` are rejected, as git would read them as options, e.g.
+/// `#--upload-pack=<command>` running the command.
+fn split_git_url(input: &str) -> Result<(&str, &str)> {
+    let Some((url, git_ref)) = input.split_once('#') else {
+        return Ok((input, "HEAD"));
+    };
+    if git_ref.is_empty() || git_ref.starts_with('-') {
+        anyhow::bail!("Invalid git ref '{git_ref}' of {url}");
+    }
+    Ok((url, git_ref))
+}
+
+/// Check out `git_ref` of the repository at `url` into `directory`, without history
+fn shallow_clone(url: &str, git_ref: &str, directory: &Path) -> Result<()> {
+    // Fetching the ref rather than cloning a branch also accepts tags and commits. The URL
+    // and ref follow --end-of-options so that neither is read as an option.
+    git(directory, &["init", "--quiet"])?;
+    git(
+        directory,
+        &[
+            "fetch",
+            "--quiet",
+            "--depth",
+            "1",
+            "--end-of-options",
+            url,
+            git_ref,
+        ],
+    )?;
+    git(directory, &["checkout", "--quiet", "FETCH_HEAD"])?;
+    Ok(())
+}
+
+/// Source files under `root` in `language`, or in the only language they're in; `root`
+/// itself if it's a file. `option` is how the caller passes the language.
+pub(crate) fn directory_source_files(
+    root: &Path,
+    language: Option<&str>,
+    option: &str,
+) -> Result<Vec<PathBuf>> {
+    if root.is_file() {
+        return Ok(vec![root.to_path_buf()]);
+    }
+    files_of_language(directory_files_by_language(root)?, language, option)
+}
+
+/// Source files under `root` by language, or `root` itself if it's a file in a supported
+/// language
+pub(crate) fn directory_files_by_language(root: &Path) -> Result<BTreeMap<String, Vec<PathBuf>>> {
+    if root.is_file() {
+        return Ok(group_by_language([root.to_path_buf()]));
+    }
+    anyhow::ensure!(root.is_dir(), "{} doesn't exist", root.display());
+
+    // Hidden directories such as .git hold no sources of the project
+    let files = walkdir::WalkDir::new(root)
+        .into_iter()
+        .filter_entry(|entry| {
+            entry.depth() == 0 || !entry.file_name().to_string_lossy().starts_with('.')
+        })
+        .flatten()
+        .filter(|entry| entry.file_type().is_file())
+        .map(walkdir::DirEntry::into_path);
+    Ok(group_by_language(files))
+}
+
+/// The files of `language` in `by_language`, or of its only language
+fn files_of_language(
+    mut by_language: BTreeMap<String, Vec<PathBuf>>,
+    language: Option<&str>,
+    option: &str,
+) -> Result<Vec<PathBuf>> {
+    if let Some(language) = language {
+        let language = Language::try_from_str(language)?.to_string();
+        return Ok(by_language.remove(&language).unwrap_or_default());
+    }
+    match by_language.len() {
+        0 => anyhow::bail!("The sources have no file in a supported language"),
+        1 => Ok(by_language.into_values().flatten().collect()),
+        _ => anyhow::bail!(
+            "The sources have files in several languages ({}); pass {option} to choose one",
+            by_language
+                .iter()
+                .map(|(language, files)| format!("{} {language}", files.len()))
+                .collect::<Vec<_>>()
+                .join(", ")
+        ),
+    }
+}
+
+/// Source files of the repository in `directory` by language: the files git tracks, and
+/// the untracked files it doesn't ignore
+pub(crate) fn source_files_by_language(directory: &Path) -> Result<BTreeMap<String, Vec<PathBuf>>> {
+    let files = git(
+        directory,
+        &[
+            "ls-files",
+            "-z",
+            "--cached",
+            "--others",
+            "--exclude-standard",
+        ],
+    )?;
+    Ok(group_by_language(
+        files
+            .split('\0')
+            .filter(|name| !name.is_empty())
+            .map(|name| directory.join(name)),
+    ))
+}
+
+/// `paths` by the language of their extension, without those in no supported language
+pub(crate) fn group_by_language(
+    paths: impl IntoIterator<Item = PathBuf>,
+) -> BTreeMap<String, Vec<PathBuf>> {
+    let mut by_language: BTreeMap<String, Vec<PathBuf>> = BTreeMap::new();
+    for path in paths {
+        if let Some(file_language) = path
+            .extension()
+            .and_then(|extension| extension.to_str())
+            .and_then(|extension| Language::try_from_str(&extension.to_lowercase()).ok())
+        {
+            by_language
+                .entry(file_language.to_string())
+                .or_default()
+                .push(path);
+        }
+    }
+    by_language
+}
+
+/// Run git in `directory`, returning its standard output
+fn git(directory: &Path, args: &[&str]) -> Result<String> {
+    let output = Command::new("git")
+        .arg("-C")
+        .arg(directory)
+        .args(args)
+        .output()
+        .context("Failed to run git")?;
+    if !output.status.success() {
+        anyhow::bail!(
+            "git {} failed: {}",
+            args.first().copied().unwrap_or_default(),
+            String::from_utf8_lossy(&output.stderr).trim()
+        );
+    }
+    Ok(String::from_utf8_lossy(&output.stdout).into_owned())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_is_git_url() {
+        for url in [
+            "https://github.com/org/service.git",
+            "https://github.com/org/service.git#v1.2.0",
+            "ssh://git@github.com/org/service.git",
+            "git@github.com:org/service.git",
+        ] {
+            assert!(is_git_url(url), "{url}");
+        }
+        for path in ["services/api", "/src/service", "file:///src/service"] {
+            assert!(!is_git_url(path), "{path}");
+        }
+    }
+
+    #[test]
+    fn test_split_git_url() {
+        assert_eq!(
+            split_git_url("https://host/repo.git").unwrap(),
+            ("https://host/repo.git", "HEAD")
+        );
+        assert_eq!(
+            split_git_url("https://host/repo.git#v1.2.0").unwrap(),
+            ("https://host/repo.git", "v1.2.0")
+        );
+        assert_eq!(
+            split_git_url("git@host:repo.git#release/2.0").unwrap(),
+            ("git@host:repo.git", "release/2.0")
+        );
+    }
+
+    #[test]
+    fn test_split_git_url_rejects_options() {
+        for url in [
+            "https://host/repo.git#--upload-pack=touch /tmp/pwned",
+            "https://host/repo.git#-q",
+            "https://host/repo.git#",
+        ] {
+            assert!(split_git_url(url).is_err(), "{url}");
+        }
+    }
+
+    #[test]
+    fn test_directory_source_files() {
+        let root = tempfile::tempdir().unwrap();
+        std::fs::create_dir_all(root.path().join("app")).unwrap();
+        std::fs::create_dir_all(root.path().join(".git")).unwrap();
+        std::fs::write(root.path().join("app/handler.py"), "import boto3\n").unwrap();
+        std::fs::write(root.path().join(".git/hook.py"), "import os\n").unwrap();
+        std::fs::write(root.path().join("README.md"), "# App\n").unwrap();
+
+        let files = directory_source_files(root.path(), None, "--language").unwrap();
+
+        assert_eq!(files, vec![root.path().join("app/handler.py")]);
+    }
+
+    #[test]
+    fn test_directory_source_files_in_several_languages() {
+        let root = tempfile::tempdir().unwrap();
+        std::fs::write(root.path().join("handler.py"), "import boto3\n").unwrap();
+        std::fs::write(root.path().join("main.go"), "package main\n").unwrap();
+
+        assert!(directory_source_files(root.path(), None, "--language").is_err());
+        assert_eq!(
+            directory_source_files(root.path(), Some("go"), "--language").unwrap(),
+            vec![root.path().join("main.go")]
+        );
+    }
+}
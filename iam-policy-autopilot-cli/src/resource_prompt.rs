@@ -0,0 +1,149 @@
+//! Interactive resolution of resources and services the source code doesn't name.
+//!
+//! With `--interactive`, generate-policies asks on the terminal for every resource
+//! placeholder it can't resolve statically, and for the service of every call whose
+//! operation exists in several services. Answers are recorded in the `--answers-file`
+//! and choices in the `--disambiguation-file`, which later runs apply without asking
+//! again.
+
+use std::io::{self, BufRead};
+use std::path::Path;
+use std::sync::Mutex;
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::api::model::{
+    AmbiguousCall, ResourceAnswer, ResourceAnswers, ResourcePrompt, ServiceChoice, ServiceChoices,
+    ServicePrompt, UnresolvedResource,
+};
+
+use crate::output;
+
+/// Load the answers recorded in `path`; a file that doesn't exist yet has none.
+pub(crate) fn load_answers(path: &Path) -> Result<ResourceAnswers> {
+    if !path.exists() {
+        return Ok(ResourceAnswers::default());
+    }
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read answers file: {}", path.display()))?;
+    serde_json::from_str(&content)
+        .with_context(|| format!("Failed to parse answers file: {}", path.display()))
+}
+
+/// Write `answers` to `path` for future runs.
+pub(crate) fn save_answers(path: &Path, answers: &ResourceAnswers) -> Result<()> {
+    let content =
+        serde_json::to_string_pretty(answers).context("Failed to serialize resource answers")?;
+    std::fs::write(path, content + "\n")
+        .with_context(|| format!("Failed to write answers file: {}", path.display()))
+}
+
+/// Load the service choices recorded in `path`; a file that doesn't exist yet has none.
+pub(crate) fn load_choices(path: &Path) -> Result<ServiceChoices> {
+    if !path.exists() {
+        return Ok(ServiceChoices::default());
+    }
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read disambiguation file: {}", path.display()))?;
+    let choices: ServiceChoices = serde_json::from_str(&content)
+        .with_context(|| format!("Failed to parse disambiguation file: {}", path.display()))?;
+    choices
+        .validate()
+        .with_context(|| format!("Invalid disambiguation file: {}", path.display()))?;
+    Ok(choices)
+}
+
+/// Write `choices` to `path` for future runs.
+pub(crate) fn save_choices(path: &Path, choices: &ServiceChoices) -> Result<()> {
+    let content =
+        serde_json::to_string_pretty(choices).context("Failed to serialize service choices")?;
+    std::fs::write(path, content + "\n")
+        .with_context(|| format!("Failed to write disambiguation file: {}", path.display()))
+}
+
+/// Prompt reading values from stdin, keeping the answers and choices given.
+#[derive(Debug, Default)]
+pub(crate) struct TerminalPrompt {
+    answers: Mutex<Vec<ResourceAnswer>>,
+    choices: Mutex<Vec<ServiceChoice>>,
+}
+
+impl TerminalPrompt {
+    /// Answers given since the prompt was created.
+    pub(crate) fn take_answers(&self) -> Vec<ResourceAnswer> {
+        self.answers
+            .lock()
+            .map(|mut answers| std::mem::take(&mut *answers))
+            .unwrap_or_default()
+    }
+
+    /// Services chosen since the prompt was created.
+    pub(crate) fn take_choices(&self) -> Vec<ServiceChoice> {
+        self.choices
+            .lock()
+            .map(|mut choices| std::mem::take(&mut *choices))
+            .unwrap_or_default()
+    }
+}
+
+/// A line read from stdin, or `None` at end of input.
+fn read_line() -> Option<String> {
+    let mut line = String::new();
+    match io::stdin().lock().read_line(&mut line) {
+        Ok(0) | Err(_) => None,
+        Ok(_) => Some(line),
+    }
+}
+
+impl ResourcePrompt for TerminalPrompt {
+    /// An empty line accepts the default; end of input leaves the resource wildcarded.
+    fn resolve(&self, resource: &UnresolvedResource) -> Option<String> {
+        output::prompt_resource(resource);
+        let line = read_line()?;
+        let value = match line.trim() {
+            "" => resource.default.clone(),
+            value => value.to_string(),
+        };
+        if let Ok(mut answers) = self.answers.lock() {
+            answers.push(ResourceAnswer {
+                file: resource.file.clone(),
+                call: resource.call.clone(),
+                placeholder: resource.placeholder.clone(),
+                value: value.clone(),
+            });
+        }
+        Some(value)
+    }
+}
+
+impl ServicePrompt for TerminalPrompt {
+    /// Accepts the number or name of a service, asking again for anything else; an empty
+    /// line or end of input keeps every service.
+    fn choose(&self, call: &AmbiguousCall) -> Option<String> {
+        output::prompt_service(call);
+        let service = loop {
+            let line = read_line()?;
+            let reply = line.trim();
+            if reply.is_empty() {
+                return None;
+            }
+            let chosen = reply
+                .parse::<usize>()
+                .ok()
+                .and_then(|number| call.services.get(number.checked_sub(1)?))
+                .or_else(|| call.services.iter().find(|service| *service == reply));
+            if let Some(service) = chosen {
+                break service.clone();
+            }
+            output::prompt_service_again(call);
+        };
+        if let Ok(mut choices) = self.choices.lock() {
+            choices.push(ServiceChoice {
+                file: call.file.clone(),
+                call: call.call.clone(),
+                operation: call.operation.clone(),
+                service: service.clone(),
+            });
+        }
+        Some(service)
+    }
+}
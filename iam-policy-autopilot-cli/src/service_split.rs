@@ -0,0 +1,238 @@
+//! Generated policies split by AWS service, as written with --split-by-service.
+//!
+//! Each service the generated actions belong to gets a policy document of its own,
+//! `policy-<service>.json`, holding the statements granting its actions with their
+//! resources and conditions. A statement granting the actions of several services is
+//! split into one statement per service. The manifest lists the files with the service
+//! and actions of each, for automation assembling roles from them.
+
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::PolicyWithMetadata;
+use serde::Serialize;
+use serde_json::Value;
+
+/// Name of the manifest listing the policy files
+const MANIFEST_FILE: &str = "manifest.json";
+
+/// A policy file of a service, as listed in the manifest
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct ServicePolicyFile {
+    pub(crate) service: String,
+    /// Name of the file, in the output directory
+    pub(crate) file: String,
+    /// Actions the policy grants, sorted
+    pub(crate) actions: Vec<String>,
+}
+
+/// The policy documents of the services of the actions of `policies`, by service prefix
+///
+/// # Errors
+/// Returns an error if a policy can't be serialized
+pub(crate) fn service_policies(policies: &[PolicyWithMetadata]) -> Result<BTreeMap<String, Value>> {
+    let mut statements: BTreeMap<String, Vec<Value>> = BTreeMap::new();
+    for policy in policies {
+        let document =
+            serde_json::to_value(&policy.policy).context("Failed to serialize policy document")?;
+        for statement in document["Statement"].as_array().into_iter().flatten() {
+            let actions = match &statement["Action"] {
+                Value::Array(items) => items.iter().filter_map(Value::as_str).collect(),
+                item => item.as_str().into_iter().collect::<Vec<&str>>(),
+            };
+            let mut by_service: BTreeMap<&str, Vec<&str>> = BTreeMap::new();
+            for action in actions {
+                let service = action
+                    .split_once(':')
+                    .map_or(action, |(service, _)| service);
+                by_service.entry(service).or_default().push(action);
+            }
+            for (service, actions) in by_service {
+                let mut service_statement = statement.clone();
+                service_statement["Action"] = serde_json::json!(actions);
+                statements
+                    .entry(service.to_lowercase())
+                    .or_default()
+                    .push(service_statement);
+            }
+        }
+    }
+    Ok(statements
+        .into_iter()
+        .map(|(service, statements)| {
+            let document = serde_json::json!({
+                "Version": "2012-10-17",
+                "Statement": statements,
+            });
+            (service, document)
+        })
+        .collect())
+}
+
+/// Write the policies of each service to `policy-<service>.json` in `directory`, with the
+/// manifest listing them
+///
+/// Policies for the credentials of an assumed role are left out, as they belong to that
+/// role.
+///
+/// # Errors
+/// Returns an error if the directory or a file can't be written
+pub(crate) fn write_service_policies(
+    policies: &[PolicyWithMetadata],
+    directory: &Path,
+) -> Result<()> {
+    let (own, assumed): (Vec<PolicyWithMetadata>, Vec<PolicyWithMetadata>) = policies
+        .iter()
+        .cloned()
+        .partition(PolicyWithMetadata::is_workload_policy);
+    if !assumed.is_empty() {
+        crate::output::warn(&format!(
+            "Left {} policies for the credentials of assumed roles or profiles out of the service \
+             policies",
+            assumed.len()
+        ));
+    }
+
+    std::fs::create_dir_all(directory).with_context(|| {
+        format!(
+            "Failed to create the output directory {}",
+            directory.display()
+        )
+    })?;
+    let mut manifest = Vec::new();
+    for (service, document) in service_policies(&own)? {
+        let file = format!("policy-{service}.json");
+        let path = directory.join(&file);
+        let json_output = serde_json::to_string_pretty(&document)
+            .context("Failed to serialize the service policy")?;
+        std::fs::write(&path, format!("{json_output}\n"))
+            .with_context(|| format!("Failed to write the policy to {}", path.display()))?;
+        manifest.push(ServicePolicyFile {
+            service,
+            file,
+            actions: actions(&document),
+        });
+    }
+
+    let path = directory.join(MANIFEST_FILE);
+    let json_output = serde_json::to_string_pretty(&serde_json::json!({
+        "Policies": manifest,
+    }))
+    .context("Failed to serialize the manifest")?;
+    std::fs::write(&path, format!("{json_output}\n"))
+        .with_context(|| format!("Failed to write the manifest to {}", path.display()))?;
+    crate::output::note(&format!(
+        "Wrote the policies of {} services to {}",
+        manifest.len(),
+        directory.display()
+    ));
+    Ok(())
+}
+
+/// The actions the statements of `document` grant, sorted and without duplicates
+fn actions(document: &Value) -> Vec<String> {
+    let mut actions: Vec<String> = document["Statement"]
+        .as_array()
+        .into_iter()
+        .flatten()
+        .flat_map(|statement| statement["Action"].as_array().cloned().unwrap_or_default())
+        .filter_map(|action| action.as_str().map(str::to_string))
+        .collect();
+    actions.sort();
+    actions.dedup();
+    actions
+}
+
+#[cfg(test)]
+mod tests {
+    use iam_policy_autopilot_policy_generation::{IamPolicy, PolicyType, Statement};
+
+    use super::*;
+
+    fn policy(statements: Vec<Statement>, assumed_role: Option<&str>) -> PolicyWithMetadata {
+        let mut policy = IamPolicy::new();
+        for statement in statements {
+            policy.add_statement(statement);
+        }
+        PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: assumed_role.map(str::to_string),
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }
+    }
+
+    #[test]
+    fn test_service_policies() {
+        let policies = [policy(
+            vec![
+                Statement::allow(
+                    vec!["s3:GetObject".to_string(), "kms:Decrypt".to_string()],
+                    vec!["*".to_string()],
+                ),
+                Statement::allow(
+                    vec!["s3:PutObject".to_string()],
+                    vec!["arn:aws:s3:::reports/*".to_string()],
+                ),
+            ],
+            None,
+        )];
+
+        let documents = service_policies(&policies).unwrap();
+
+        assert_eq!(documents.keys().collect::<Vec<_>>(), ["kms", "s3"]);
+        let s3 = &documents["s3"]["Statement"];
+        assert_eq!(s3[0]["Action"], serde_json::json!(["s3:GetObject"]));
+        assert_eq!(s3[0]["Resource"], serde_json::json!(["*"]));
+        assert_eq!(s3[1]["Action"], serde_json::json!(["s3:PutObject"]));
+        assert_eq!(actions(&documents["s3"]), ["s3:GetObject", "s3:PutObject"]);
+        assert_eq!(
+            documents["kms"]["Statement"][0]["Action"],
+            serde_json::json!(["kms:Decrypt"])
+        );
+    }
+
+    #[test]
+    fn test_write_service_policies() {
+        let directory = tempfile::tempdir().unwrap();
+        let policies = [
+            policy(
+                vec![Statement::allow(
+                    vec!["dynamodb:GetItem".to_string()],
+                    vec!["*".to_string()],
+                )],
+                None,
+            ),
+            policy(
+                vec![Statement::allow(
+                    vec!["sqs:SendMessage".to_string()],
+                    vec!["*".to_string()],
+                )],
+                Some("arn:aws:iam::123456789012:role/jobs"),
+            ),
+        ];
+
+        write_service_policies(&policies, directory.path()).unwrap();
+
+        assert!(directory.path().join("policy-dynamodb.json").is_file());
+        assert!(!directory.path().join("policy-sqs.json").exists());
+        let manifest: Value = serde_json::from_str(
+            &std::fs::read_to_string(directory.path().join(MANIFEST_FILE)).unwrap(),
+        )
+        .unwrap();
+        assert_eq!(
+            manifest,
+            serde_json::json!({
+                "Policies": [{
+                    "Service": "dynamodb",
+                    "File": "policy-dynamodb.json",
+                    "Actions": ["dynamodb:GetItem"]
+                }]
+            })
+        );
+    }
+}
@@ -0,0 +1,176 @@
+//! Terraform external data source protocol.
+//!
+//! Terraform's `external` data source runs a program with the `query` of the data source
+//! as a JSON object of strings on stdin, and reads its `result` back as a JSON object of
+//! strings from stdout. terraform-data-source speaks it, so a configuration can feed the
+//! policies of its application's code into `aws_iam_role_policy` during plan and apply.
+
+use std::collections::BTreeMap;
+use std::path::PathBuf;
+
+use anyhow::{Context, Result};
+use iam_policy_autopilot_policy_generation::api::model::{
+    AwsContext, DefaultExclusion, GeneratePoliciesResult,
+};
+use serde::Deserialize;
+
+use crate::{remote_sources, AnalysisArgs, SharedConfig};
+
+/// Query of the data source, all values being strings as Terraform passes them
+#[derive(Debug, Deserialize)]
+#[serde(deny_unknown_fields)]
+pub(crate) struct Query {
+    /// Source file or directory, relative to the working directory of Terraform
+    path: PathBuf,
+    /// Language of the source files to analyze, if they're in several
+    language: Option<String>,
+    /// AWS region, `*` by default
+    region: Option<String>,
+    /// AWS account ID, `*` by default
+    account: Option<String>,
+    /// AWS partition, derived from the region by default
+    partition: Option<String>,
+    /// Comma-separated services to filter the analyzed SDK calls to
+    service_hints: Option<String>,
+    /// `true` to skip test files
+    exclude_tests: Option<String>,
+    /// Comma-separated kinds of sources skipped by default to analyze
+    include: Option<String>,
+}
+
+impl Query {
+    /// Parse the query Terraform writes to stdin
+    pub(crate) fn parse(input: &str) -> Result<Self> {
+        serde_json::from_str(input)
+            .context("The query must be a JSON object of strings with at least a path")
+    }
+
+    /// Shared configuration of the analysis of the queried sources
+    pub(crate) fn shared_config(&self) -> Result<SharedConfig> {
+        let source_files = remote_sources::directory_source_files(
+            &self.path,
+            self.language.as_deref(),
+            "the language query argument",
+        )?;
+        let exclude_tests = match self.exclude_tests.as_deref() {
+            None | Some("false") => false,
+            Some("true") => true,
+            Some(other) => anyhow::bail!("exclude_tests must be true or false, not {other:?}"),
+        };
+        let include = self.include.as_deref().map(split_list).unwrap_or_default();
+        if let Some(kind) = include.iter().find(|kind| {
+            !DefaultExclusion::ALL
+                .iter()
+                .any(|exclusion| exclusion.id() == kind.as_str())
+        }) {
+            anyhow::bail!(
+                "include must list ignored, vendored, generated, oversized or mocked, not {kind:?}"
+            );
+        }
+        Ok(SharedConfig {
+            source_files,
+            pretty: false,
+            language: self.language.clone(),
+            full_output: false,
+            service_hints: self.service_hints.as_deref().map(split_list),
+            analysis: AnalysisArgs {
+                exclude_tests,
+                include,
+                ..AnalysisArgs::default()
+            },
+        })
+    }
+
+    /// AWS context of the queried region, account and partition
+    pub(crate) fn aws_context(&self) -> Result<AwsContext> {
+        AwsContext::with_partition(
+            self.partition.clone(),
+            self.region.clone().unwrap_or_else(|| "*".to_string()),
+            self.account.clone().unwrap_or_else(|| "*".to_string()),
+        )
+    }
+}
+
+/// Values of a comma-separated list
+fn split_list(list: &str) -> Vec<String> {
+    list.split(',')
+        .map(str::trim)
+        .filter(|value| !value.is_empty())
+        .map(ToString::to_string)
+        .collect()
+}
+
+/// Result of the data source: the JSON documents of the policies of the principal running
+/// the code as `policies`, their number as `policy_count`, and the document as `policy`
+/// when there's a single one.
+///
+/// Policies for roles the code assumes are left out, as they belong to other roles.
+pub(crate) fn data_source_result(
+    result: &GeneratePoliciesResult,
+) -> Result<BTreeMap<&'static str, String>> {
+    let documents = result
+        .policies
+        .iter()
+        .filter(|policy| policy.is_workload_policy())
+        .map(|policy| serde_json::to_value(&policy.policy))
+        .collect::<Result<Vec<_>, _>>()
+        .context("Failed to serialize generated policies")?;
+
+    let mut values = BTreeMap::new();
+    if let [document] = documents.as_slice() {
+        values.insert("policy", document.to_string());
+    }
+    values.insert("policy_count", documents.len().to_string());
+    values.insert("policies", serde_json::Value::Array(documents).to_string());
+    Ok(values)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_query_defaults() {
+        let root = tempfile::tempdir().unwrap();
+        std::fs::write(root.path().join("handler.py"), "import boto3\n").unwrap();
+        let query = Query::parse(
+            &serde_json::json!({
+                "path": root.path(),
+                "service_hints": "s3, dynamodb",
+                "exclude_tests": "true",
+            })
+            .to_string(),
+        )
+        .unwrap();
+
+        let shared = query.shared_config().unwrap();
+
+        assert_eq!(shared.source_files, vec![root.path().join("handler.py")]);
+        assert_eq!(
+            shared.service_hints,
+            Some(vec!["s3".to_string(), "dynamodb".to_string()])
+        );
+        assert!(shared.analysis.exclude_tests);
+        assert!(shared.analysis.include.is_empty());
+        assert!(query.aws_context().is_ok());
+    }
+
+    #[test]
+    fn test_query_rejects_unknown_arguments() {
+        assert!(Query::parse(r#"{"path": "src", "regions": "us-east-1"}"#).is_err());
+        assert!(Query::parse(r#"{"language": "python"}"#).is_err());
+    }
+
+    #[test]
+    fn test_query_rejects_invalid_values() {
+        let root = tempfile::tempdir().unwrap();
+        std::fs::write(root.path().join("handler.py"), "import boto3\n").unwrap();
+        for (key, value) in [("exclude_tests", "yes"), ("include", "tests")] {
+            let mut arguments = serde_json::json!({ "path": root.path() });
+            arguments[key] = serde_json::Value::from(value);
+            let query = Query::parse(&arguments.to_string()).unwrap();
+
+            assert!(query.shared_config().is_err(), "{key} = {value}");
+        }
+    }
+}
@@ -323,6 +323,198 @@ fn test_generate_policy_empty_file() {
         serde_json::from_str(&stdout).expect("Should produce valid JSON even for empty files");
 }
 
+#[test]
+fn test_generate_policy_trust_policy_of_web_identity_role() {
+    let temp_dir = TempDir::new().expect("Failed to create temp directory");
+    let source_file = temp_dir.path().join("federated.py");
+    fs::write(
+        &source_file,
+        r#"import boto3
+
+sts = boto3.client("sts")
+
+
+def credentials(token):
+    return sts.assume_role_with_web_identity(
+        RoleArn="arn:aws:iam::123456789012:role/Federated",
+        RoleSessionName="app",
+        WebIdentityToken=token,
+    )
+"#,
+    )
+    .expect("Failed to write source file");
+
+    let output = generate_policy_command()
+        .arg("--region")
+        .arg("us-east-1")
+        .arg("--account")
+        .arg("123456789012")
+        .arg("--trust-policies")
+        .arg(source_file.to_str().unwrap())
+        .assert()
+        .success();
+
+    let stdout = String::from_utf8(output.get_output().stdout.clone()).unwrap();
+    let json: Value = serde_json::from_str(&stdout).expect("Should produce valid JSON");
+    let trust_policies = json["TrustPolicies"]
+        .as_array()
+        .expect("Should output the trust policies");
+    assert_eq!(trust_policies.len(), 1);
+    assert_eq!(
+        trust_policies[0]["RoleArn"],
+        "arn:aws:iam::123456789012:role/Federated"
+    );
+    let statement = &trust_policies[0]["Policy"]["Statement"][0];
+    assert_eq!(
+        statement["Principal"]["Federated"][0],
+        "{{OidcProviderArn}}"
+    );
+    assert_eq!(statement["Action"][0], "sts:AssumeRoleWithWebIdentity");
+}
+
+#[test]
+fn test_generate_policy_cloudformation_output() {
+    let test_file = get_simple_test_file("py");
+
+    let output = generate_policy_command()
+        .arg("--region")
+        .arg("us-east-1")
+        .arg("--account")
+        .arg("123456789012")
+        .arg("--output-format")
+        .arg("cloudformation")
+        .arg(test_file.to_str().unwrap())
+        .assert()
+        .success();
+
+    let stdout = String::from_utf8(output.get_output().stdout.clone()).unwrap();
+    let template: Value = serde_json::from_str(&stdout).expect("Invalid JSON output");
+
+    assert_eq!(template["AWSTemplateFormatVersion"], "2010-09-09");
+    assert_eq!(template["Parameters"]["RoleName"]["Type"], "String");
+    let resources = template["Resources"]
+        .as_object()
+        .expect("Template should have resources");
+    assert!(!resources.is_empty(), "Template should contain policies");
+    for resource in resources.values() {
+        assert_eq!(resource["Type"], "AWS::IAM::ManagedPolicy");
+        assert_eq!(
+            resource["Properties"]["PolicyDocument"]["Version"],
+            "2012-10-17"
+        );
+        assert_eq!(
+            resource["Properties"]["Roles"][0]["Ref"].as_str(),
+            Some("RoleName")
+        );
+    }
+}
+
+#[test]
+fn test_generate_policy_terraform_output() {
+    let test_file = get_simple_test_file("py");
+
+    let output = generate_policy_command()
+        .arg("--region")
+        .arg("us-east-1")
+        .arg("--account")
+        .arg("123456789012")
+        .arg("--output-format")
+        .arg("terraform")
+        .arg(test_file.to_str().unwrap())
+        .assert()
+        .success();
+
+    let stdout = String::from_utf8(output.get_output().stdout.clone()).unwrap();
+    assert!(
+        stdout.contains("data \"aws_iam_policy_document\" \"iam_policy_autopilot_1\" {"),
+        "stdout was: {stdout}"
+    );
+    assert!(
+        stdout.contains("resource \"aws_iam_policy\" \"iam_policy_autopilot_1\" {"),
+        "stdout was: {stdout}"
+    );
+    assert!(stdout.contains("policy = data.aws_iam_policy_document.iam_policy_autopilot_1.json"));
+    assert!(stdout.contains("    effect    = \"Allow\""));
+}
+
+#[test]
+fn test_generate_policy_cdk_output() {
+    let test_file = get_simple_test_file("py");
+
+    for (format, expected) in [
+        (
+            "cdk-typescript",
+            ["new iam.PolicyStatement({", "effect: iam.Effect.ALLOW,"],
+        ),
+        (
+            "cdk-python",
+            ["iam.PolicyStatement(", "effect=iam.Effect.ALLOW,"],
+        ),
+    ] {
+        let output = generate_policy_command()
+            .arg("--region")
+            .arg("us-east-1")
+            .arg("--account")
+            .arg("123456789012")
+            .arg("--output-format")
+            .arg(format)
+            .arg(test_file.to_str().unwrap())
+            .assert()
+            .success();
+
+        let stdout = String::from_utf8(output.get_output().stdout.clone()).unwrap();
+        for expected in expected {
+            assert!(stdout.contains(expected), "{format} output was: {stdout}");
+        }
+    }
+}
+
+#[test]
+fn test_generate_policy_scp_output() {
+    let test_file = get_simple_test_file("py");
+
+    for (format, effect, actions_key) in [
+        ("scp", "Allow", "Action"),
+        ("scp-deny", "Deny", "NotAction"),
+    ] {
+        let output = generate_policy_command()
+            .arg("--region")
+            .arg("us-east-1")
+            .arg("--account")
+            .arg("123456789012")
+            .arg("--output-format")
+            .arg(format)
+            .arg(test_file.to_str().unwrap())
+            .assert()
+            .success();
+
+        let scp: Value = serde_json::from_slice(&output.get_output().stdout).unwrap();
+        let statements = scp["Statement"].as_array().unwrap();
+        assert_eq!(statements.len(), 1);
+        assert_eq!(statements[0]["Effect"], effect);
+        assert_eq!(statements[0]["Resource"], "*");
+        assert!(!statements[0][actions_key].as_array().unwrap().is_empty());
+        assert!(statements[0].get("Condition").is_none());
+    }
+}
+
+#[test]
+fn test_generate_policy_cloudformation_rejects_upload() {
+    let test_file = get_simple_test_file("py");
+
+    generate_policy_command()
+        .arg("--output-format")
+        .arg("cloudformation-inline")
+        .arg(test_file.to_str().unwrap())
+        .arg("--upload-policies")
+        .assert()
+        .failure()
+        .code(1)
+        .stderr(predicate::str::contains(
+            "can't be combined with --upload-policies",
+        ));
+}
+
 #[test]
 fn test_comprehensive_real_files_extract_sdk_calls_python() {
     test_comprehensive_real_files_extract_sdk_calls_for_extension("py");
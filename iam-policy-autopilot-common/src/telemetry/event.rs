@@ -81,6 +81,14 @@ impl TelemetryEvent {
         value.record_presence(self, name)
     }
 
+    /// Record a list generically. Called by `#[derive(TelemetryEvent)]` for `#[telemetry(list)]` fields.
+    ///
+    /// Handles `Vec<String>` and `Option<Vec<String>>`, omitting the parameter when empty or `None`.
+    #[must_use]
+    pub fn with_telemetry_list(self, name: &str, value: &impl TelemetryFieldList) -> Self {
+        value.record_list(self, name)
+    }
+
     /// Record a numeric parameter (e.g., count of items in a Vec).
     #[must_use]
     pub fn with_number(mut self, name: impl Into<String>, value: usize) -> Self {
@@ -233,6 +241,32 @@ impl TelemetryFieldPresence for String {
     }
 }
 
+/// Trait for types that can record their values as a telemetry list parameter.
+/// Used by `#[telemetry(list)]` fields in `#[derive(TelemetryEvent)]`.
+pub trait TelemetryFieldList {
+    /// Record the values of this field, unless there are none.
+    fn record_list(&self, event: TelemetryEvent, name: &str) -> TelemetryEvent;
+}
+
+impl TelemetryFieldList for Vec<String> {
+    fn record_list(&self, event: TelemetryEvent, name: &str) -> TelemetryEvent {
+        if self.is_empty() {
+            event
+        } else {
+            event.with_list(name, self)
+        }
+    }
+}
+
+impl TelemetryFieldList for Option<Vec<String>> {
+    fn record_list(&self, event: TelemetryEvent, name: &str) -> TelemetryEvent {
+        match self {
+            Some(values) => values.record_list(event, name),
+            None => event,
+        }
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
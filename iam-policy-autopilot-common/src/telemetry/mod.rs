@@ -106,6 +106,18 @@ pub trait ToTelemetryEvent {
     }
 }
 
+/// Trait for structs whose fields are recorded as fields of the commands flattening them
+/// with `#[telemetry(flatten)]`, such as options shared by several CLI commands.
+///
+/// This is automatically implemented by `#[derive(TelemetryEvent)]` on structs.
+pub trait TelemetryFields {
+    /// Record the collected fields of this value in `event`.
+    fn record_telemetry_fields(&self, event: TelemetryEvent) -> TelemetryEvent;
+
+    /// Return metadata about the fields of this value, as fields of `command`.
+    fn telemetry_fields_of(command: &str) -> Vec<TelemetryFieldInfo>;
+}
+
 /// Metadata about a single telemetry field, used for auto-generating documentation.
 #[derive(Debug, Clone)]
 pub struct TelemetryFieldInfo {
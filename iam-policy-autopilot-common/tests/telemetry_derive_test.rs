@@ -119,6 +119,36 @@ struct TestSkipNoticeStruct {
     name: String,
 }
 
+#[derive(TelemetryEventDerive)]
+#[telemetry(command = "mcp-tool-list")]
+struct TestListInput {
+    #[telemetry(list)]
+    include: Vec<String>,
+}
+
+#[derive(TelemetryEventDerive)]
+struct TestAnalysisArgs {
+    #[telemetry(value)]
+    exclude_tests: bool,
+    #[telemetry(list)]
+    include: Vec<String>,
+    #[telemetry(value, if_present)]
+    jobs: Option<u16>,
+}
+
+#[derive(TelemetryEventDerive)]
+enum TestFlattenCommands {
+    #[telemetry(command = "list-calls")]
+    ListCalls {
+        #[telemetry(value)]
+        pretty: bool,
+        #[telemetry(flatten)]
+        analysis: TestAnalysisArgs,
+        #[telemetry(presence)]
+        output: Option<String>,
+    },
+}
+
 #[derive(TelemetryEventDerive)]
 struct AutoNamedStruct {
     #[telemetry(value)]
@@ -504,6 +534,29 @@ fn struct_none_and_empty_values() {
     );
 }
 
+#[test]
+#[serial]
+fn struct_list_on_vec_omits_empty_field() {
+    let _guard = EnvGuard::enabled();
+
+    let input = TestListInput {
+        include: vec!["vendored".into(), "generated".into()],
+    };
+    let params = input
+        .to_telemetry_event()
+        .expect("should produce event")
+        .params
+        .expect("should have params");
+    assert_eq!(
+        params.get("include"),
+        Some(&serde_json::json!(["vendored", "generated"]))
+    );
+
+    let input = TestListInput { include: vec![] };
+    let event = input.to_telemetry_event().expect("should produce event");
+    assert!(event.params.is_none(), "empty vec → omitted");
+}
+
 // =============================================================================
 // Struct: should_skip_notice() + telemetry_fields()
 // =============================================================================
@@ -532,6 +585,75 @@ fn struct_telemetry_fields_reports_all_fields() {
     );
 }
 
+// =============================================================================
+// Flattened structs
+// =============================================================================
+
+#[test]
+#[serial]
+fn enum_flattened_struct_fields_are_recorded_as_command_fields() {
+    let _guard = EnvGuard::enabled();
+
+    let cmd = TestFlattenCommands::ListCalls {
+        pretty: true,
+        analysis: TestAnalysisArgs {
+            exclude_tests: true,
+            include: vec!["vendored".into()],
+            jobs: None,
+        },
+        output: None,
+    };
+
+    let event = cmd.to_telemetry_event().expect("should produce event");
+    assert_eq!(event.command, "list-calls");
+    let params = event.params.expect("should have params");
+    assert_eq!(params.get("pretty"), Some(&serde_json::json!(true)));
+    assert_eq!(params.get("exclude_tests"), Some(&serde_json::json!(true)));
+    assert_eq!(
+        params.get("include"),
+        Some(&serde_json::json!(["vendored"]))
+    );
+    assert!(
+        params.get("jobs").is_none(),
+        "None → omitted by value_if_present"
+    );
+    assert!(
+        params.get("analysis").is_none(),
+        "flattened field itself is not recorded"
+    );
+    assert_eq!(params.get("output"), Some(&serde_json::json!(false)));
+}
+
+#[test]
+fn enum_telemetry_fields_lists_flattened_fields_in_place() {
+    let fields = TestFlattenCommands::telemetry_fields();
+
+    let rows: Vec<(&str, &str, &str)> = fields
+        .iter()
+        .map(|f| {
+            (
+                f.command.as_str(),
+                f.field_name.as_str(),
+                f.collection_mode.as_str(),
+            )
+        })
+        .collect();
+    assert_eq!(
+        rows,
+        vec![
+            ("list-calls", "pretty", "actual value (boolean)"),
+            ("list-calls", "exclude_tests", "actual value (boolean)"),
+            (
+                "list-calls",
+                "include",
+                "list of values if non-empty, omitted otherwise"
+            ),
+            ("list-calls", "jobs", "value if provided, omitted otherwise"),
+            ("list-calls", "output", "presence (boolean)"),
+        ]
+    );
+}
+
 // =============================================================================
 // Default command name (lowercased struct/variant name)
 // =============================================================================
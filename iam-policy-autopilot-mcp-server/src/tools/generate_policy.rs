@@ -2,7 +2,9 @@ use anyhow::Context;
 use anyhow::Error;
 use anyhow::Result;
 use iam_policy_autopilot_policy_generation::api::model::{
-    AwsContext, ExtractSdkCallsConfig, GeneratePolicyConfig, ServiceHints,
+    ActionFilters, AwsContext, CustomServices, ExtractSdkCallsConfig, GeneratePolicyConfig,
+    KmsViaService, MappingOverrides, PrincipalMappings, ResourceAnswers, ServiceChoices,
+    ServiceHints,
 };
 use iam_policy_autopilot_policy_generation::DEFAULT_RESOURCE_CUTOFF;
 use schemars::JsonSchema;
@@ -37,6 +39,12 @@ pub struct GeneratePoliciesInput {
     #[telemetry(presence)]
     pub account: Option<String>,
 
+    #[schemars(
+        description = "AWS partition (e.g., 'aws', 'aws-cn', 'aws-us-gov'). When omitted, the partition is derived from the region, or wildcarded when no region is provided."
+    )]
+    #[telemetry(presence)]
+    pub partition: Option<String>,
+
     #[schemars(
         description = "List of AWS service names to filter SDK calls by (e.g., ['s3', 'dynamodb']). When provided, the result of source code analysis will be restricted to the provided services. The generated policy may still contain actions from a service not provided as a hint, if IAM Policy Autopilot determines that the action may be needed for the SDK call."
     )]
@@ -107,8 +115,25 @@ pub async fn generate_application_policies(
             // Maybe we should let the llm figure out the language
             language: None,
             service_hints,
+            // Test sources are analyzed, matching the CLI default
+            exclude_tests: false,
+            // Ignored, vendored, generated, oversized and mocked sources are skipped,
+            // matching the CLI default
+            included: Vec::new(),
+            // One job per available CPU
+            jobs: None,
+            // Files above the default size limit are skipped
+            max_file_size: None,
+            dependencies: None,
+            // No plugins, matching the CLI default
+            plugins: Vec::new(),
+            // No wrapper functions, matching the CLI default
+            wrappers: None,
+            go_binaries: Vec::new(),
+            progress: None,
+            cache_dir: None,
         },
-        aws_context: AwsContext::new(region, account)?,
+        aws_context: AwsContext::with_partition(input.partition, region, account)?,
         minimize_policy_size: false,
 
         // true by default, if we want to allow the user to change it we should
@@ -137,6 +162,47 @@ pub async fn generate_application_policies(
             .collect(),
         explain_resource_filters: None,
         resource_cutoff,
+        // Resources are scoped to call-site literals, matching the CLI default
+        wildcard_resources: false,
+        app_config_files: vec![],
+        resource_answers: ResourceAnswers::default(),
+        resource_prompt: None,
+        service_choices: ServiceChoices::default(),
+        service_prompt: None,
+        mapping_overrides: MappingOverrides::default(),
+        service_reference_lock: None,
+        custom_services: CustomServices::default(),
+        principal_mappings: PrincipalMappings::default(),
+        min_confidence: None,
+        template_variables: false,
+        s3_resource_forms: None,
+        report_unscoped_actions: false,
+        compact_actions: false,
+        match_managed_policies: false,
+        trust_policies: false,
+        resource_policies: false,
+        cross_account_report: false,
+        workload_role_arn: None,
+        suggest_condition_keys: false,
+        observability_permissions: false,
+        collector_config_files: vec![],
+        event_source_permissions: false,
+        s3_multipart_actions: false,
+        kms_via_service: Some(KmsViaService::default()),
+        excluded_services: vec![],
+        action_filters: ActionFilters::default(),
+        split_read_write: false,
+        entry_point_policies: false,
+        detect_runtime: false,
+        restrict_regions: None,
+        network_origins: None,
+        access_analyzer_policy: None,
+        flag_sensitive_actions: false,
+        access_level_summary: false,
+        action_provenance: false,
+        action_inventory: false,
+        analysis_diagnostics: false,
+        coverage_report: false,
     };
 
     let result = api::generate_policies(&config).await?;
@@ -212,6 +278,7 @@ mod tests {
             source_files: vec!["path/to/source/file".to_string()],
             region: Some("us-east-1".to_string()),
             account: Some("123456789012".to_string()),
+            partition: None,
             service_hints: None,
             tf_dir: None,
             tf_files: None,
@@ -233,6 +300,10 @@ mod tests {
         let policy = PolicyWithMetadata {
             policy: iam_policy,
             policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
         };
 
         use iam_policy_autopilot_policy_generation::api::model::GeneratePoliciesResult;
@@ -241,6 +312,27 @@ mod tests {
             policies: vec![policy],
             explanations: None,
             resource_binding_explanations: None,
+            template_variables: None,
+            unscoped_actions: None,
+            managed_policy_suggestions: None,
+            trust_policies: None,
+            resource_policies: None,
+            cross_account_access: None,
+            condition_key_suggestions: None,
+            runtime: None,
+            statement_origins: None,
+            sensitive_actions: None,
+            access_level_summary: None,
+            suppressed_calls: None,
+            low_confidence_calls: None,
+            deprecated_calls: None,
+            no_permission_calls: None,
+            excluded_service_calls: None,
+            filtered_actions: None,
+            action_provenance: None,
+            action_inventory: None,
+            diagnostics: None,
+            coverage: None,
         }));
         let result = generate_application_policies(input).await;
 
@@ -258,6 +350,7 @@ mod tests {
             source_files: vec!["path/to/source/file".to_string()],
             region: Some("us-east-1".to_string()),
             account: Some("123456789012".to_string()),
+            partition: None,
             service_hints: None,
             tf_dir: None,
             tf_files: None,
@@ -278,6 +371,7 @@ mod tests {
             source_files: vec!["path/to/source/file".to_string()],
             region: Some("us-east-1".to_string()),
             account: Some("123456789012".to_string()),
+            partition: None,
             service_hints: None,
             tf_dir: None,
             tf_files: None,
@@ -290,6 +384,27 @@ mod tests {
             policies: vec![],
             explanations: None,
             resource_binding_explanations: None,
+            template_variables: None,
+            unscoped_actions: None,
+            managed_policy_suggestions: None,
+            trust_policies: None,
+            resource_policies: None,
+            cross_account_access: None,
+            condition_key_suggestions: None,
+            runtime: None,
+            statement_origins: None,
+            sensitive_actions: None,
+            access_level_summary: None,
+            suppressed_calls: None,
+            low_confidence_calls: None,
+            deprecated_calls: None,
+            no_permission_calls: None,
+            excluded_service_calls: None,
+            filtered_actions: None,
+            action_provenance: None,
+            action_inventory: None,
+            diagnostics: None,
+            coverage: None,
         }));
         let result = generate_application_policies(input).await;
 
@@ -303,6 +418,7 @@ mod tests {
             source_files: vec!["/path/to/file.py".to_string()],
             region: Some("us-west-2".to_string()),
             account: Some("987654321098".to_string()),
+            partition: None,
             service_hints: None,
             tf_dir: None,
             tf_files: None,
@@ -339,6 +455,7 @@ mod tests {
             source_files: vec!["path/to/source/file".to_string()],
             region: Some("us-east-1".to_string()),
             account: Some("123456789012".to_string()),
+            partition: None,
             service_hints: Some(vec!["s3".to_string(), "dynamodb".to_string()]),
             tf_dir: None,
             tf_files: None,
@@ -359,12 +476,37 @@ mod tests {
         let policy = PolicyWithMetadata {
             policy: iam_policy,
             policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
         };
 
         api::set_mock_return(Ok(GeneratePoliciesResult {
             policies: vec![policy],
             explanations: None,
             resource_binding_explanations: None,
+            template_variables: None,
+            unscoped_actions: None,
+            managed_policy_suggestions: None,
+            trust_policies: None,
+            resource_policies: None,
+            cross_account_access: None,
+            condition_key_suggestions: None,
+            runtime: None,
+            statement_origins: None,
+            sensitive_actions: None,
+            access_level_summary: None,
+            suppressed_calls: None,
+            low_confidence_calls: None,
+            deprecated_calls: None,
+            no_permission_calls: None,
+            excluded_service_calls: None,
+            filtered_actions: None,
+            action_provenance: None,
+            action_inventory: None,
+            diagnostics: None,
+            coverage: None,
         }));
         let result = generate_application_policies(input).await;
 
@@ -5,7 +5,7 @@ use relative_path::PathExt;
 use relative_path::RelativePathBuf;
 use serde::{Deserialize, Serialize};
 use serde_json::Value;
-use std::collections::BTreeMap;
+use std::collections::{BTreeMap, BTreeSet};
 use std::env;
 use std::fs;
 use std::io;
@@ -63,6 +63,13 @@ struct SimplifiedPartitionsDefinition {
     partitions: BTreeMap<String, String>,
 }
 
+/// Simplified endpoints definition. Map of partition ids to the endpoint prefixes of
+/// the services available in the partition.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+struct SimplifiedEndpointsDefinition {
+    partitions: BTreeMap<String, BTreeSet<String>>,
+}
+
 include!("src/shared_submodule_model.rs");
 
 impl GitSubmoduleMetadata {
@@ -334,6 +341,13 @@ fn process_botocore_data(
         process_partitions(&partitions_src, &partitions_dst)?;
     }
 
+    // Process endpoints.json
+    let endpoints_src = botocore_path.join("endpoints.json");
+    if endpoints_src.is_file() {
+        let endpoints_dst = output_dir.join("endpoints.json");
+        process_endpoints(&endpoints_src, &endpoints_dst)?;
+    }
+
     // Iterate through service directories
     for entry in fs::read_dir(botocore_path)? {
         let entry = entry?;
@@ -410,6 +424,47 @@ fn process_partitions(
     Ok(())
 }
 
+fn process_endpoints(
+    input_path: &Path,
+    output_path: &Path,
+) -> Result<(), Box<dyn std::error::Error>> {
+    // Read the original endpoints definition
+    let content = fs::read_to_string(input_path)?;
+    let original: Value = serde_json::from_str(&content)?;
+
+    // Extract the services of each partition (required)
+    let simplified_partitions = if let Some(Value::Array(partitions)) = original.get("partitions") {
+        partitions
+            .iter()
+            .map(|partition| {
+                let id = partition
+                    .get("partition")
+                    .and_then(|v| v.as_str())
+                    .map(std::string::ToString::to_string)
+                    .ok_or("expected partition in endpoints partition")?;
+                let services = partition
+                    .get("services")
+                    .and_then(|v| v.as_object())
+                    .map(|services| services.keys().cloned().collect())
+                    .unwrap_or_default();
+                Ok((id, services))
+            })
+            .collect::<Result<BTreeMap<_, _>, Box<dyn std::error::Error>>>()?
+    } else {
+        return Err("expected partitions array in endpoints.json".into());
+    };
+
+    // Convert to simplified structure
+    let simplified = SimplifiedEndpointsDefinition {
+        partitions: simplified_partitions,
+    };
+
+    // Write uncompressed JSON file (rust-embed will handle compression)
+    fs::write(output_path, serde_json::to_string(&simplified)?)?;
+
+    Ok(())
+}
+
 fn find_latest_api_version(
     service_path: &Path,
 ) -> Result<Option<(String, std::path::PathBuf)>, Box<dyn std::error::Error>> {
@@ -3,33 +3,49 @@ use std::sync::Arc;
 
 use log::{info, trace, warn};
 
-use crate::api::model::ServiceHints;
-use crate::extraction::sdk_model::ServiceDiscovery;
-use crate::extraction::ServiceHintsProcessor;
+use crate::api::model::{DefaultExclusion, ExtractSdkCallsConfig, DEFAULT_MAX_FILE_SIZE};
+use crate::extraction::go::binary::go_binary_calls;
+use crate::extraction::plugins::run_plugins;
+use crate::extraction::sdk_model::{ServiceDiscovery, ServiceModelIndex};
+use crate::extraction::shared::{
+    dependency_source_files, disambiguate_by_parameter_shapes, is_generated_content,
+    is_generated_file, is_mocked_content, is_test_content, is_test_file, is_vendored_file,
+    load_wrapper_functions, oversized_reason, wrapper_calls, GitIgnores, RequiredPermission,
+};
+use crate::extraction::{ExtractionMetadata, ServiceHintsProcessor};
 use crate::service_configuration::load_service_configuration;
 use crate::{ExtractedMethods, ExtractionEngine, Language, SourceFile};
 
 use anyhow::{Context, Result};
 
-/// Process source files and extract SDK method calls
+/// Process source files and extract SDK method calls, along with the permissions plugins
+/// report for calls of services without a service reference
 pub(crate) async fn process_source_files(
     extractor: &ExtractionEngine,
-    source_files: &[PathBuf],
-    language_override: Option<&str>,
-    service_hints: Option<ServiceHints>,
-) -> Result<ExtractedMethods> {
-    trace!("Processing {} source files", source_files.len());
+    config: &ExtractSdkCallsConfig,
+) -> Result<(ExtractedMethods, Vec<RequiredPermission>)> {
+    if !config.go_binaries.is_empty() {
+        return process_go_binaries(config).await;
+    }
+
+    let language_override = config.language.as_deref();
+    trace!("Processing {} source files", config.source_files.len());
 
     // Log the files being processed
-    for (i, file) in source_files.iter().enumerate() {
+    for (i, file) in config.source_files.iter().enumerate() {
         trace!("Source file {}: {}", i + 1, file.display());
     }
 
+    // Drop ignored, vendored and generated sources before detecting their language, so
+    // bundled dependencies in another language don't fail the detection
+    let source_files = without_default_exclusions(&config.source_files, &config.included);
+    if source_files.is_empty() && !config.source_files.is_empty() {
+        info!("No source files left after excluding ignored, vendored and generated files");
+        return Ok((no_extracted_methods(Vec::new()), Vec::new()));
+    }
+
     // Convert PathBuf to &Path for language detection
-    let source_file_paths: Vec<&Path> = source_files
-        .iter()
-        .map(std::path::PathBuf::as_path)
-        .collect();
+    let source_file_paths: Vec<&Path> = source_files.iter().map(|path| path.as_path()).collect();
 
     // Determine the programming language to use
     let language = if let Some(override_lang) = language_override {
@@ -53,7 +69,49 @@ pub(crate) async fn process_source_files(
         &language.to_string(),
     );
 
+    // Sources of the dependencies, found from the project's manifests
+    let mut skipped = Vec::new();
+    let dependency_sources = match &config.dependencies {
+        Some(analysis) => {
+            let dependency_sources = dependency_source_files(&source_files, language, analysis);
+            info!(
+                "Analyzing {} source files of {} dependencies",
+                dependency_sources.files.len(),
+                dependency_sources.packages
+            );
+            skipped.extend(dependency_sources.warnings);
+            dependency_sources.files
+        }
+        None => Vec::new(),
+    };
+    let source_files: Vec<&PathBuf> = source_files
+        .into_iter()
+        .chain(&dependency_sources)
+        .collect();
+
+    // Drop test sources before loading them, if requested
+    let source_files: Vec<&PathBuf> = if config.exclude_tests {
+        let (tests, sources): (Vec<&PathBuf>, Vec<&PathBuf>) = source_files
+            .into_iter()
+            .partition(|path| is_test_file(path, language));
+        if !tests.is_empty() {
+            info!("Excluding {} test files from analysis", tests.len());
+            for path in &tests {
+                trace!("Excluded test file: {}", path.display());
+            }
+        }
+        sources
+    } else {
+        source_files
+    };
+
+    if source_files.is_empty() {
+        info!("No source files left to analyze after excluding test files");
+        return Ok((no_extracted_methods(Vec::new()), Vec::new()));
+    }
+
     // Load all source files into SourceFile objects
+    let max_file_size = config.max_file_size.unwrap_or(DEFAULT_MAX_FILE_SIZE);
     let mut loaded_source_files = Vec::new();
     for file_path in source_files {
         let content = std::fs::read_to_string(file_path).context(format!(
@@ -61,30 +119,100 @@ pub(crate) async fn process_source_files(
             file_path.display()
         ))?;
 
+        // Tests recognized by their content, e.g. moto or LocalStack fixtures
+        if config.exclude_tests && is_test_content(&content, language) {
+            info!(
+                "Excluding test file using emulated AWS services: {}",
+                file_path.display()
+            );
+            continue;
+        }
+        // Tests mocking the SDK clients, e.g. with aws-sdk-client-mock, whether or not
+        // tests are excluded
+        if !config.included.contains(&DefaultExclusion::Mocked)
+            && is_mocked_content(&content, language)
+        {
+            info!(
+                "Excluding test file mocking the AWS SDK: {}",
+                file_path.display()
+            );
+            continue;
+        }
+        if !config.included.contains(&DefaultExclusion::Generated) && is_generated_content(&content)
+        {
+            info!("Excluding generated file: {}", file_path.display());
+            continue;
+        }
+        // Oversized and minified files, e.g. bundles, could stall the analysis
+        if !config.included.contains(&DefaultExclusion::Oversized) {
+            if let Some(reason) = oversized_reason(&content, max_file_size) {
+                skipped.push(format!(
+                    "Skipped oversized file {}: {reason}",
+                    file_path.display()
+                ));
+                continue;
+            }
+        }
+
         let source_file = SourceFile::with_language(file_path.clone(), content, language);
         loaded_source_files.push(source_file);
     }
 
+    if loaded_source_files.is_empty() {
+        info!(
+            "No source files left to analyze after excluding test, mocked, generated and \
+             oversized files"
+        );
+        for warning in &skipped {
+            warn!("{warning}");
+        }
+        return Ok((no_extracted_methods(skipped), Vec::new()));
+    }
+
     // Extract SDK method calls from the loaded source files
     let mut results = extractor
         .extract_sdk_method_calls(language, loaded_source_files)
         .await
         .context("Failed to extract SDK method calls from source files")?;
+    results.metadata.warnings.extend(skipped);
+
+    // Calls of operations several services have, made on clients of unknown type, are
+    // narrowed to the services whose input shapes fit the names of their arguments
+    let service_index = ServiceDiscovery::load_service_index(language).await?;
+    disambiguate_by_parameter_shapes(&mut results.methods, &service_index);
+
+    // Calls of in-house SDK wrappers and private services the plugins recognize
+    let plugin_results = run_plugins(&config.plugins, language, &results.metadata.source_files)?;
+    if !config.plugins.is_empty() {
+        info!(
+            "Plugins reported {} SDK calls and {} permissions",
+            plugin_results.calls.len(),
+            plugin_results.permissions.len()
+        );
+    }
+    results.methods.extend(plugin_results.calls);
+
+    // Calls of the helper functions forwarding an operation to a client, whose own client
+    // call the extractor can't resolve
+    if let Some(path) = &config.wrappers {
+        let wrappers = load_wrapper_functions(path)?;
+        let calls = wrapper_calls(
+            &wrappers,
+            language,
+            &results.metadata.source_files,
+            &results.methods,
+            &service_index,
+        );
+        info!(
+            "Resolved {} calls of {} wrapper functions",
+            calls.len(),
+            wrappers.wrappers.len()
+        );
+        results.methods.extend(calls);
+    }
 
     // If service hints are provided, validate and filter the results
-    if let Some(hints) = service_hints {
-        // Load service index and configuration for validation
-        let service_index = ServiceDiscovery::load_service_index(language).await?;
-        let service_config = load_service_configuration()?;
-
-        // Create processor and validate
-        let processor =
-            ServiceHintsProcessor::new(hints, service_config, Arc::clone(&service_index));
-        processor.validate()?;
-
-        // Filter the results
-        processor.filter(&mut results);
-    }
+    filter_by_service_hints(config, &service_index, &mut results)?;
 
     info!(
         "Extraction completed: {} SDK method calls found from {} source files",
@@ -97,7 +225,115 @@ pub(crate) async fn process_source_files(
         warn!("{warning}");
     }
 
-    Ok(results)
+    Ok((results, plugin_results.permissions))
+}
+
+/// Extract the AWS SDK operations linked into the Go binaries of `config`, as calls
+async fn process_go_binaries(
+    config: &ExtractSdkCallsConfig,
+) -> Result<(ExtractedMethods, Vec<RequiredPermission>)> {
+    if !config.source_files.is_empty() {
+        anyhow::bail!("Go binaries are analyzed instead of source files, not along with them");
+    }
+    if config
+        .language
+        .as_deref()
+        .is_some_and(|language| Language::try_from_str(language).ok() != Some(Language::Go))
+    {
+        anyhow::bail!("Go binaries can only be analyzed as Go");
+    }
+    iam_policy_autopilot_common::telemetry::span::record_result_str(
+        "detected_language",
+        &Language::Go.to_string(),
+    );
+
+    let service_index = ServiceDiscovery::load_service_index(Language::Go).await?;
+    let mut results = no_extracted_methods(Vec::new());
+    for path in &config.go_binaries {
+        let calls = go_binary_calls(path, &service_index)?;
+        if calls.is_empty() {
+            results.metadata.warnings.push(format!(
+                "No AWS SDK operations found in the Go binary {}",
+                path.display()
+            ));
+        }
+        results.methods.extend(calls);
+    }
+    results.metadata.update_method_count(results.methods.len());
+    filter_by_service_hints(config, &service_index, &mut results)?;
+
+    info!(
+        "Extraction completed: {} AWS SDK operations found in {} Go binaries",
+        results.methods.len(),
+        config.go_binaries.len()
+    );
+    for warning in &results.metadata.warnings {
+        warn!("{warning}");
+    }
+    Ok((results, Vec::new()))
+}
+
+/// Keep the calls of `results` made on the services hinted in `config`, if any
+fn filter_by_service_hints(
+    config: &ExtractSdkCallsConfig,
+    service_index: &Arc<ServiceModelIndex>,
+    results: &mut ExtractedMethods,
+) -> Result<()> {
+    if let Some(hints) = config.service_hints.clone() {
+        // Load service configuration for validation
+        let service_config = load_service_configuration()?;
+
+        // Create processor and validate
+        let processor =
+            ServiceHintsProcessor::new(hints, service_config, Arc::clone(service_index));
+        processor.validate()?;
+
+        // Filter the results
+        processor.filter(results);
+    }
+    Ok(())
+}
+
+/// `source_files` without the files git ignores, vendored dependencies and the generated
+/// files recognized by their name, except those `included`
+fn without_default_exclusions<'a>(
+    source_files: &'a [PathBuf],
+    included: &[DefaultExclusion],
+) -> Vec<&'a PathBuf> {
+    let mut git_ignores = GitIgnores::default();
+    let mut sources = Vec::new();
+    let mut excluded = 0;
+    for path in source_files {
+        let exclusion = DefaultExclusion::ALL.into_iter().find(|exclusion| {
+            !included.contains(exclusion)
+                && match exclusion {
+                    DefaultExclusion::Ignored => git_ignores.is_ignored(path),
+                    DefaultExclusion::Vendored => is_vendored_file(path),
+                    DefaultExclusion::Generated => is_generated_file(path),
+                    // Recognized by the content of the loaded file
+                    DefaultExclusion::Oversized | DefaultExclusion::Mocked => false,
+                }
+        });
+        match exclusion {
+            Some(exclusion) => {
+                excluded += 1;
+                trace!("Excluded {} file: {}", exclusion.id(), path.display());
+            }
+            None => sources.push(path),
+        }
+    }
+    if excluded > 0 {
+        info!("Excluding {excluded} ignored, vendored or generated files from analysis");
+    }
+    sources
+}
+
+/// The result of analyzing no source files, with the `warnings` of loading them
+fn no_extracted_methods(warnings: Vec<String>) -> ExtractedMethods {
+    ExtractedMethods {
+        methods: vec![],
+        metadata: ExtractionMetadata::new(vec![], warnings),
+    }
 }
 
 #[cfg(test)]
@@ -0,0 +1,174 @@
+use std::collections::BTreeMap;
+
+use anyhow::{anyhow, Context, Result};
+use log::{info, warn};
+
+use crate::api::model::{
+    MappedAction, MappedResource, MappedService, MappingOverrides, MappingsDump,
+};
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::extraction::sdk_model::ServiceDiscovery;
+use crate::{EnrichmentEngine, Language, SdkMethodCall, SdkType};
+
+/// Export the operation to action mappings policy generation uses for `services`, or for
+/// every service if empty, with `mapping_overrides` merged over them
+///
+/// Every operation of the SDK models of the services is enriched like an extracted call,
+/// so the actions include those of forward access sessions and the resources their ARN
+/// formats, uncollapsed.
+///
+/// # Errors
+/// Returns an error for services without an SDK model, or if the SDK models can't be
+/// loaded
+pub async fn dump_mappings(
+    services: &[String],
+    mapping_overrides: MappingOverrides,
+) -> Result<MappingsDump> {
+    let service_index = ServiceDiscovery::load_service_index(Language::Python)
+        .await
+        .context("Failed to load the SDK models")?;
+    let mut service_names: Vec<&String> = if services.is_empty() {
+        service_index.services.keys().collect()
+    } else {
+        services
+            .iter()
+            .map(|service| {
+                service_index
+                    .services
+                    .get_key_value(service)
+                    .map(|(name, _)| name)
+                    .ok_or_else(|| anyhow!("Unknown service '{service}'"))
+            })
+            .collect::<Result<_>>()?
+    };
+    service_names.sort();
+    service_names.dedup();
+
+    let mut engine =
+        EnrichmentEngine::new(false, usize::MAX)?.with_mapping_overrides(mapping_overrides);
+    let mut dump = MappingsDump::default();
+    for service in service_names {
+        info!("Dumping the mappings of {service}");
+        let mut operations: Vec<&String> =
+            service_index.services[service].operations.keys().collect();
+        operations.sort();
+        let calls: Vec<SdkMethodCall> = operations
+            .into_iter()
+            .map(|operation| SdkMethodCall {
+                name: operation.clone(),
+                possible_services: vec![service.clone()],
+                metadata: None,
+            })
+            .collect();
+
+        // A service whose mappings can't be loaded doesn't hide those of the others
+        let enriched = match engine.enrich_methods(&calls, SdkType::Other).await {
+            Ok(enriched) => enriched,
+            Err(e) => {
+                warn!("Skipping the mappings of {service}: {e}");
+                continue;
+            }
+        };
+        dump.services
+            .insert(service.clone(), mapped_service(&enriched));
+    }
+    Ok(dump)
+}
+
+/// The mappings of the enriched operations of one service
+fn mapped_service(enriched: &[EnrichedSdkMethodCall<'_>]) -> MappedService {
+    let operations = enriched
+        .iter()
+        .filter(|call| !call.actions.is_empty())
+        .map(|call| {
+            let actions = call
+                .actions
+                .iter()
+                .map(|action| MappedAction {
+                    name: action.name.clone(),
+                    resources: action
+                        .resources
+                        .iter()
+                        .map(|resource| MappedResource {
+                            name: resource.name.clone(),
+                            arn_formats: resource.arn_patterns.clone().unwrap_or_default(),
+                        })
+                        .collect(),
+                    conditions: action
+                        .conditions
+                        .iter()
+                        .map(|condition| (condition.key.clone(), condition.values.clone()))
+                        .collect::<BTreeMap<_, _>>(),
+                })
+                .collect();
+            (call.method_name.clone(), actions)
+        })
+        .collect();
+    MappedService { operations }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::enrichment::{Action, Condition, Explanation, Operator, Resource};
+
+    #[test]
+    fn test_mapped_service() {
+        let call = SdkMethodCall {
+            name: "GetObject".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: None,
+        };
+        let enriched = EnrichedSdkMethodCall {
+            method_name: "GetObject".to_string(),
+            service: "s3".to_string(),
+            actions: vec![
+                Action::new(
+                    "s3:GetObject".to_string(),
+                    vec![Resource::new(
+                        "object".to_string(),
+                        Some(vec![
+                            "arn:${Partition}:s3:::${BucketName}/${ObjectName}".to_string()
+                        ]),
+                    )],
+                    Vec::new(),
+                    Explanation::default(),
+                ),
+                Action::new(
+                    "kms:Decrypt".to_string(),
+                    vec![Resource::new("*".to_string(), None)],
+                    vec![Condition {
+                        operator: Operator::StringEquals,
+                        key: "kms:ViaService".to_string(),
+                        values: vec!["s3.${Region}.amazonaws.com".to_string()],
+                    }],
+                    Explanation::default(),
+                ),
+            ],
+            sdk_method_call: &call,
+        };
+
+        let service = mapped_service(&[enriched]);
+
+        let actions = &service.operations["GetObject"];
+        assert_eq!(actions[0].name, "s3:GetObject");
+        assert_eq!(
+            actions[0].resources[0].arn_formats,
+            vec!["arn:${Partition}:s3:::${BucketName}/${ObjectName}"]
+        );
+        assert!(actions[0].conditions.is_empty());
+        assert!(actions[1].resources[0].arn_formats.is_empty());
+        assert_eq!(
+            actions[1].conditions["kms:ViaService"],
+            vec!["s3.${Region}.amazonaws.com"]
+        );
+    }
+
+    #[tokio::test]
+    async fn test_unknown_services_are_rejected() {
+        let error = dump_mappings(&["not-a-service".to_string()], MappingOverrides::default())
+            .await
+            .unwrap_err();
+        assert!(error.to_string().contains("not-a-service"));
+    }
+}
@@ -11,15 +11,15 @@ pub async fn extract_sdk_calls(config: &ExtractSdkCallsConfig) -> Result<Extract
     info!("Extracting Sdk Calls");
 
     // Create the extractor
-    let extractor = crate::ExtractionEngine::new();
+    let extractor = crate::ExtractionEngine::new()
+        .with_jobs(config.jobs)
+        .with_progress(config.progress.clone())
+        .with_cache_dir(config.cache_dir.clone())
+        .with_cache_inputs(config.cache_inputs());
 
-    // Process source files
-    process_source_files(
-        &extractor,
-        &config.source_files,
-        config.language.as_deref(),
-        config.service_hints.clone(),
-    )
-    .await
-    .context("Failed to process source files")
+    // Process source files, the permissions plugins report not being SDK calls
+    let (extracted_methods, _) = process_source_files(&extractor, config)
+        .await
+        .context("Failed to process source files")?;
+    Ok(extracted_methods)
 }
@@ -5,7 +5,7 @@ use anyhow::{Context, Result};
 use log::info;
 
 use crate::api::common::process_source_files;
-use crate::api::model::ServiceHints;
+use crate::api::model::{DefaultExclusion, ExtractSdkCallsConfig, ServiceHints};
 use crate::extraction::call_graph::gopls::GoplsCallGraphBuilder;
 use crate::extraction::call_graph::{innermost_enclosing, CallGraphBuilder, FunctionNode};
 use crate::extraction::external_library_models::ExternalLibraryModel;
@@ -92,13 +92,26 @@ pub async fn generate_model(config: &GenerateModelConfig) -> Result<ExternalLibr
             resolve_entry_points(&config.entry_points, graph.nodes())?
         };
 
-        let extracted = {
+        let (extracted, _) = {
             let extractor = crate::ExtractionEngine::new();
             process_source_files(
                 &extractor,
-                &source_files,
-                Some(language.to_string().as_str()),
-                config.service_hints.clone(),
+                &ExtractSdkCallsConfig {
+                    source_files: source_files.clone(),
+                    language: Some(language.to_string()),
+                    service_hints: config.service_hints.clone(),
+                    exclude_tests: false,
+                    // Calls are matched against the call graph of every source file
+                    included: DefaultExclusion::ALL.to_vec(),
+                    jobs: None,
+                    max_file_size: None,
+                    dependencies: None,
+                    plugins: Vec::new(),
+                    wrappers: None,
+                    go_binaries: Vec::new(),
+                    progress: None,
+                    cache_dir: None,
+                },
             )
             .await
             .context("Failed to extract SDK calls")?
@@ -1,24 +1,103 @@
 use anyhow::{Context, Result};
-use std::collections::BTreeMap;
-use std::path::PathBuf;
+use std::collections::{BTreeMap, BTreeSet, HashMap, HashSet};
+use std::path::{Path, PathBuf};
 use std::time::Instant;
 
-use log::{debug, info, trace};
+use log::{debug, info, trace, warn};
 
 use crate::{
     api::{
         common::process_source_files,
-        model::{GeneratePoliciesResult, GeneratePolicyConfig},
+        list_calls::inventoried_call,
+        model::{CallConfidence, GeneratePoliciesResult, GeneratePolicyConfig, InventoriedCall},
     },
+    embedded_data::BotocoreData,
     enrichment::{
+        athena_queries::scope_query_locations,
+        bedrock_models::scope_model_invocations,
+        deprecated_operations,
+        event_sources::{detect_event_sources, enrich_event_sources},
+        excluded_services::exclude_services,
+        instrumentation::{detect_instrumentation, enrich_instrumentation},
+        kms_via_service::apply_kms_via_service,
+        no_permission_operations::separate_no_permission_calls,
+        redshift_data::select_credential_actions,
+        required_permissions::enrich_required_permissions,
+        resource_answers::apply_resource_answers,
+        s3_express::grant_directory_bucket_calls,
+        s3_multipart::expand_multipart_actions,
+        s3_resource_forms::select_s3_resource_forms,
         terraform::{resource_binder::TerraformResourceResolver, ResourceBindingExplanation},
-        Explanation, Explanations,
+        EnrichedSdkMethodCall, Explanation, Explanations, ServiceReferenceLoader,
     },
-    extraction::SdkMethodCall,
-    policy_generation::merge::PolicyMergerConfig,
-    EnrichmentEngine, PolicyGenerationEngine,
+    extraction::javascript::amplify::amplify_api_permissions,
+    extraction::shared::{
+        analysis_diagnostics, apply_service_choices, bind_configured_resources,
+        required_permissions, separate_custom_service_calls, separate_dax_calls,
+        suppress_annotated_calls, ConfidenceEvidence, ConfigValues, DiagnosticKind,
+    },
+    policy_generation::{
+        access_analyzer::{
+            load_access_analyzer_statements, merge_access_analyzer_statements, statement_origins,
+        },
+        access_levels::{access_level_summary, AccessLevel},
+        access_split::split_read_write,
+        action_compaction::compact_actions,
+        action_filters::filter_actions,
+        action_inventory::action_inventory,
+        condition_suggestions::suggest_condition_keys,
+        coverage::coverage_report,
+        cross_account::separate_cross_account_calls,
+        entry_points::{assign_entry_points, detect_entry_points},
+        managed_policies::match_managed_policies,
+        merge::PolicyMergerConfig,
+        network_conditions::restrict_network_origins,
+        principals::assign_principals,
+        provenance::action_provenance,
+        region_conditions::{detect_client_regions, restrict_regions},
+        resource_policies::resource_policies,
+        runtime::detect_runtime,
+        sensitive_actions::sensitive_actions,
+        statement_ids::assign_statement_ids,
+        templates::template_variables,
+        trust_policies::trust_policies,
+        unscoped::{separate_unscopable_actions, unscoped_actions},
+        PolicyWithMetadata,
+    },
+    EnrichmentEngine, PolicyGenerationEngine, SdkMethodCall, ServiceDiscovery,
 };
 
+/// Split `methods` into the calls to generate policies for and the calls resolved with low
+/// confidence, by location
+///
+/// With `min_confidence`, the calls below it are left out of the policies. Otherwise, the
+/// calls of [`CallConfidence::Low`] are listed and kept. Calls without a source location
+/// can't be listed, so they're always kept.
+fn separate_low_confidence_calls(
+    methods: Vec<SdkMethodCall>,
+    evidence: &ConfidenceEvidence,
+    min_confidence: Option<CallConfidence>,
+) -> (Vec<SdkMethodCall>, Vec<InventoriedCall>) {
+    let threshold = min_confidence.unwrap_or(CallConfidence::Medium);
+    let mut kept = Vec::with_capacity(methods.len());
+    let mut low_confidence = Vec::new();
+    for method in methods {
+        match inventoried_call(&method, evidence).filter(|call| call.confidence < threshold) {
+            Some(call) => {
+                low_confidence.push(call);
+                if min_confidence.is_none() {
+                    kept.push(method);
+                }
+            }
+            None => kept.push(method),
+        }
+    }
+    low_confidence.sort_by(|a, b| {
+        (&a.file, a.line, a.column, &a.operation).cmp(&(&b.file, b.line, b.column, &b.operation))
+    });
+    (kept, low_confidence)
+}
+
 /// Check if an action matches a pattern with wildcard support.
 /// Patterns can include `*` which matches any sequence of characters.
 ///
@@ -166,6 +245,135 @@ fn filter_explanations(
     }
 }
 
+/// Drop the actions of services that aren't available in `partition`.
+///
+/// Calls left without any action are dropped as well, so no statement grants actions
+/// that don't exist in e.g. `aws-cn` or `aws-us-gov`.
+fn exclude_unavailable_services<'a>(
+    enriched_calls: Vec<EnrichedSdkMethodCall<'a>>,
+    partition: &str,
+) -> Vec<EnrichedSdkMethodCall<'a>> {
+    let endpoints = BotocoreData::get_endpoints();
+    enriched_calls
+        .into_iter()
+        .filter_map(|mut call| {
+            let action_count = call.actions.len();
+            call.actions.retain(|action| {
+                let service = action.name.split(':').next().unwrap_or_default();
+                let available = endpoints.is_available(partition, service);
+                if !available {
+                    warn!(
+                        "Excluding {} of {}: {service} is not available in {partition}",
+                        action.name, call.method_name
+                    );
+                }
+                available
+            });
+            (action_count == 0 || !call.actions.is_empty()).then_some(call)
+        })
+        .collect()
+}
+
+/// Services whose actions `policies` grant
+fn granted_services(policies: &[PolicyWithMetadata]) -> BTreeSet<&str> {
+    policies
+        .iter()
+        .flat_map(|policy| &policy.policy.statements)
+        .flat_map(|statement| &statement.action)
+        .filter_map(|action| action.split_once(':').map(|(service, _)| service))
+        .collect()
+}
+
+/// All action names of the services whose actions `policies` grant, for action compaction
+async fn load_service_actions(
+    policies: &[PolicyWithMetadata],
+    loader: &ServiceReferenceLoader,
+) -> Result<HashMap<String, Vec<String>>> {
+    let mut service_actions = HashMap::new();
+    for service in granted_services(policies) {
+        if let Some(service_reference) = loader
+            .load(service)
+            .await
+            .with_context(|| format!("Failed to load the actions of {service}"))?
+        {
+            service_actions.insert(
+                service.to_string(),
+                service_reference.actions.keys().cloned().collect(),
+            );
+        }
+    }
+    Ok(service_actions)
+}
+
+/// Condition keys of the actions `policies` grant, by action, for condition key suggestions
+async fn load_action_condition_keys(
+    policies: &[PolicyWithMetadata],
+    loader: &ServiceReferenceLoader,
+) -> Result<HashMap<String, Vec<String>>> {
+    let mut action_condition_keys = HashMap::new();
+    for service in granted_services(policies) {
+        if let Some(service_reference) = loader
+            .load(service)
+            .await
+            .with_context(|| format!("Failed to load the condition keys of {service}"))?
+        {
+            for (name, action) in &service_reference.actions {
+                action_condition_keys
+                    .insert(format!("{service}:{name}"), action.condition_keys.clone());
+            }
+        }
+    }
+    Ok(action_condition_keys)
+}
+
+/// Access levels of the actions of the services whose actions `policies` grant, for the
+/// access level summary
+async fn load_access_levels(
+    policies: &[PolicyWithMetadata],
+    loader: &ServiceReferenceLoader,
+) -> Result<HashMap<String, AccessLevel>> {
+    let mut access_levels = HashMap::new();
+    for service in granted_services(policies) {
+        if let Some(service_reference) = loader
+            .load(service)
+            .await
+            .with_context(|| format!("Failed to load the access levels of {service}"))?
+        {
+            access_levels.extend(service_reference.actions.values().filter_map(|action| {
+                action
+                    .access_level
+                    .map(|level| (format!("{service}:{}", action.name), level))
+            }));
+        }
+    }
+    Ok(access_levels)
+}
+
+/// List and Read actions of the services whose actions `policies` grant, for the
+/// read/write split
+async fn load_read_only_actions(
+    policies: &[PolicyWithMetadata],
+    loader: &ServiceReferenceLoader,
+) -> Result<HashSet<String>> {
+    let mut read_only_actions = HashSet::new();
+    for service in granted_services(policies) {
+        if let Some(service_reference) = loader
+            .load(service)
+            .await
+            .with_context(|| format!("Failed to load the access levels of {service}"))?
+        {
+            read_only_actions.extend(
+                service_reference
+                    .actions
+                    .values()
+                    .filter(|action| !action.is_write())
+                    .map(|action| format!("{service}:{}", action.name)),
+            );
+        }
+    }
+    Ok(read_only_actions)
+}
+
 /// Generate policies for source files, with optional Terraform resource binding.
 ///
 /// When `config.terraform_dir` is set, the pipeline additionally:
@@ -180,14 +388,22 @@ pub async fn generate_policies(config: &GeneratePolicyConfig) -> Result<Generate
         config.aws_context.partition, config.aws_context.region, config.aws_context.account
     );
 
-    let mut enrichment_engine =
-        EnrichmentEngine::new(config.disable_file_system_cache, config.resource_cutoff)?;
-
     // --- Optional Terraform resolution ---
     let has_terraform_inputs = config.terraform_dir.is_some()
         || !config.terraform_files.is_empty()
         || !config.tfstate_paths.is_empty();
 
+    // Terraform binding substitutes the placeholders with the deployed resources,
+    // taking precedence over identifiers known from the call site
+    let call_site_resources = !config.wildcard_resources && !has_terraform_inputs;
+    let mut enrichment_engine =
+        EnrichmentEngine::new(config.disable_file_system_cache, config.resource_cutoff)?
+            .with_call_site_resources(call_site_resources)
+            .with_mapping_overrides(config.mapping_overrides.clone());
+    if let Some(path) = &config.service_reference_lock {
+        enrichment_engine = enrichment_engine.with_service_reference_lock(path.clone())?;
+    }
+
     let terraform_resolver = if has_terraform_inputs {
         if let Some(ref terraform_dir) = config.terraform_dir {
             debug!("Terraform directory provided: {}", terraform_dir.display());
@@ -226,21 +442,48 @@ pub async fn generate_policies(config: &GeneratePolicyConfig) -> Result<Generate
             policies: vec![],
             explanations: None,
             resource_binding_explanations: None,
+            template_variables: None,
+            unscoped_actions: None,
+            managed_policy_suggestions: None,
+            trust_policies: None,
+            resource_policies: None,
+            cross_account_access: None,
+            condition_key_suggestions: None,
+            runtime: None,
+            statement_origins: None,
+            sensitive_actions: None,
+            access_level_summary: None,
+            suppressed_calls: None,
+            low_confidence_calls: None,
+            deprecated_calls: None,
+            no_permission_calls: None,
+            excluded_service_calls: None,
+            filtered_actions: None,
+            action_provenance: None,
+            action_inventory: None,
+            diagnostics: None,
+            coverage: None,
         });
     }
 
     // Create the extractor
-    let extractor = crate::ExtractionEngine::new();
+    let extractor = crate::ExtractionEngine::new()
+        .with_jobs(config.extract_sdk_calls_config.jobs)
+        .with_progress(config.extract_sdk_calls_config.progress.clone())
+        .with_cache_dir(config.extract_sdk_calls_config.cache_dir.clone())
+        .with_cache_inputs(config.extract_sdk_calls_config.cache_inputs());
 
     // Process source files to get extracted methods
-    let extracted_methods = process_source_files(
-        &extractor,
-        &all_source_files,
-        config.extract_sdk_calls_config.language.as_deref(),
-        config.extract_sdk_calls_config.service_hints.clone(),
-    )
-    .await
-    .context("Failed to process source files")?;
+    let (extracted_methods, plugin_permissions) =
+        process_source_files(&extractor, &config.extract_sdk_calls_config)
+            .await
+            .context("Failed to process source files")?;
+    info!(
+        "Extracted {} SDK calls from {} source files in {:?}",
+        extracted_methods.methods.len(),
+        extracted_methods.metadata.source_files.len(),
+        pipeline_start.elapsed()
+    );
 
     // Relies on the invariant that all source files must be of the same language, which we
     // enforce in process_source_files
@@ -250,10 +493,245 @@ pub async fn generate_policies(config: &GeneratePolicyConfig) -> Result<Generate
         .first()
         .map_or(crate::SdkType::Other, |f| f.language.sdk_type());
 
-    let extracted_methods = extracted_methods
-        .methods
-        .into_iter()
-        .collect::<Vec<SdkMethodCall>>();
+    let entry_points = if config.entry_point_policies {
+        let entry_points = detect_entry_points(&extracted_methods.metadata.source_files);
+        if entry_points.is_empty() {
+            warn!("No entry points found, generating policies for all source files together");
+        } else {
+            debug!("Found {} entry points", entry_points.len());
+        }
+        Some(entry_points).filter(|entry_points| !entry_points.is_empty())
+    } else {
+        None
+    };
+    let runtime = if config.detect_runtime {
+        detect_runtime(&extracted_methods.metadata.source_files)
+    } else {
+        None
+    };
+    let requested_regions = match &config.restrict_regions {
+        Some(regions) if regions.is_empty() => {
+            let mut regions = detect_client_regions(&extracted_methods.metadata.source_files);
+            if regions.is_empty() && config.aws_context.region != "*" {
+                regions.push(config.aws_context.region.clone());
+            }
+            if regions.is_empty() {
+                anyhow::bail!(
+                    "No client regions found in the code to restrict the policies to; provide \
+                     the allowed regions"
+                );
+            }
+            info!(
+                "Restricting the policies to the regions {}",
+                regions.join(", ")
+            );
+            Some(regions)
+        }
+        regions => regions.clone(),
+    };
+
+    // Permissions the code declares with `autopilot:require` annotations, and the plugins
+    // report for calls of services without a service reference
+    let mut required = required_permissions(&extracted_methods.metadata.source_files);
+    if !required.is_empty() {
+        info!(
+            "Adding {} permissions declared with autopilot:require",
+            required.len()
+        );
+    }
+    required.extend(plugin_permissions);
+
+    // Permissions of the tracing, metrics and logging instrumentation, which sends telemetry to AWS
+    // without SDK calls of its own, directly or through a collector sidecar
+    let instrumentation = if config.observability_permissions {
+        let collector_configs = config
+            .collector_config_files
+            .iter()
+            .map(|path| {
+                std::fs::read_to_string(path)
+                    .with_context(|| {
+                        format!("Failed to read collector configuration {}", path.display())
+                    })
+                    .map(|content| (path.as_path(), content))
+            })
+            .collect::<Result<Vec<_>>>()?;
+        let collector_configs: Vec<(&Path, &str)> = collector_configs
+            .iter()
+            .map(|(path, content)| (*path, content.as_str()))
+            .collect();
+        detect_instrumentation(&extracted_methods.metadata.source_files, &collector_configs)
+    } else {
+        Vec::new()
+    };
+    if !instrumentation.is_empty() {
+        info!(
+            "Adding the permissions of {} kinds of tracing, metrics and logging instrumentation",
+            instrumentation.len()
+        );
+    }
+
+    // Permissions the execution role needs to read the events of the Lambda handlers
+    let event_sources = if config.event_source_permissions {
+        detect_event_sources(&extracted_methods.metadata.source_files)
+    } else {
+        Vec::new()
+    };
+    if !event_sources.is_empty() {
+        info!(
+            "Adding the permissions of {} Lambda event sources",
+            event_sources.len()
+        );
+    }
+
+    // Calls the code excludes with `autopilot:ignore` annotations
+    let source_files = extracted_methods.metadata.source_files;
+    let (extracted_methods, mut suppressed_calls) =
+        suppress_annotated_calls(extracted_methods.methods, &source_files);
+    if !suppressed_calls.is_empty() {
+        info!(
+            "Excluding {} calls annotated with autopilot:ignore",
+            suppressed_calls.len()
+        );
+    }
+
+    // Calls of services other than AWS ones, granted in their own action namespace or
+    // excluded
+    let (extracted_methods, custom_permissions, custom_calls) =
+        separate_custom_service_calls(extracted_methods, &config.custom_services, &source_files);
+    if !custom_permissions.is_empty() || !custom_calls.is_empty() {
+        info!(
+            "Attributing {} calls to custom services, excluding {}",
+            custom_permissions.len() + custom_calls.len(),
+            custom_calls.len()
+        );
+    }
+    required.extend(custom_permissions);
+    suppressed_calls.extend(custom_calls);
+
+    // Calls of DAX clients, granted the `dax:` actions of the cluster they're made on
+    let (mut extracted_methods, dax_permissions) =
+        separate_dax_calls(extracted_methods, &source_files);
+    if !dax_permissions.is_empty() {
+        info!(
+            "Granting {} DAX permissions to the calls of DAX clients",
+            dax_permissions.len()
+        );
+    }
+    required.extend(dax_permissions);
+
+    // Requests of the Amplify API category, signed with the identity pool's credentials
+    // rather than sent through SDK clients
+    let amplify_permissions = amplify_api_permissions(&source_files);
+    if !amplify_permissions.is_empty() {
+        info!(
+            "Granting {} permissions to the requests of the Amplify API category",
+            amplify_permissions.len()
+        );
+    }
+    required.extend(amplify_permissions);
+    let suppressed_calls = Some(suppressed_calls).filter(|calls| !calls.is_empty());
+
+    // Services chosen for calls whose operation exists in several services
+    let mut service_choices = config.service_choices.clone();
+    apply_service_choices(
+        &mut extracted_methods,
+        &mut service_choices,
+        config.service_prompt.as_deref(),
+        &source_files,
+    );
+
+    // Calls whose services are uncertain, left out below the minimum confidence
+    let service_index = match source_files.first() {
+        Some(source_file) => {
+            Some(ServiceDiscovery::load_service_index(source_file.language).await?)
+        }
+        None => None,
+    };
+    let evidence = ConfidenceEvidence::new(service_index.as_deref(), &source_files);
+    let (extracted_methods, low_confidence_calls) =
+        separate_low_confidence_calls(extracted_methods, &evidence, config.min_confidence);
+    if !low_confidence_calls.is_empty() {
+        if config.min_confidence.is_some() {
+            info!(
+                "Excluding {} calls resolved below the minimum confidence",
+                low_confidence_calls.len()
+            );
+        } else {
+            info!(
+                "Granting {} calls resolved with low confidence in every possible service",
+                low_confidence_calls.len()
+            );
+        }
+    }
+    let low_confidence_calls = Some(low_confidence_calls).filter(|calls| !calls.is_empty());
+
+    // Calls of deprecated operations, reported with how to migrate them
+    let deprecated_calls = deprecated_operations::deprecated_calls(&extracted_methods, sdk)?;
+    if !deprecated_calls.is_empty() {
+        info!(
+            "Found {} calls of deprecated or renamed operations",
+            deprecated_calls.len()
+        );
+    }
+    let deprecated_calls = Some(deprecated_calls).filter(|calls| !calls.is_empty());
+
+    // Calls of operations needing no permission, left out with what authorizes them
+    let (mut extracted_methods, no_permission_calls) =
+        separate_no_permission_calls(extracted_methods, sdk)?;
+    if !no_permission_calls.is_empty() {
+        info!(
+            "Excluding {} calls of operations that need no IAM permission",
+            no_permission_calls.len()
+        );
+    }
+    let no_permission_calls = Some(no_permission_calls).filter(|calls| !calls.is_empty());
+
+    // Places the analysis lost precision, for code-scanning annotations
+    let diagnostics = config
+        .analysis_diagnostics
+        .then(|| analysis_diagnostics(&extracted_methods, &source_files));
+
+    // Call sites left out of the policies, for the coverage report
+    let mut skipped_call_sites: Vec<PathBuf> = Vec::new();
+    if config.coverage_report {
+        skipped_call_sites.extend(
+            suppressed_calls
+                .iter()
+                .flatten()
+                .map(|call| call.location.file_path.clone()),
+        );
+        skipped_call_sites.extend(
+            no_permission_calls
+                .iter()
+                .flatten()
+                .map(|call| call.location.file_path.clone()),
+        );
+        if config.min_confidence.is_some() {
+            skipped_call_sites.extend(
+                low_confidence_calls
+                    .iter()
+                    .flatten()
+                    .map(|call| call.file.clone()),
+            );
+        }
+        let unsupported = diagnostics
+            .clone()
+            .unwrap_or_else(|| analysis_diagnostics(&extracted_methods, &source_files));
+        skipped_call_sites.extend(
+            unsupported
+                .into_iter()
+                .filter(|diagnostic| diagnostic.kind == DiagnosticKind::UnsupportedPattern)
+                .map(|diagnostic| diagnostic.location.file_path),
+        );
+    }
+    let skipped_call_sites: Vec<&Path> = skipped_call_sites.iter().map(PathBuf::as_path).collect();
+
+    // Resource names the code reads from application configuration files
+    if call_site_resources && !config.app_config_files.is_empty() {
+        let config_values = ConfigValues::load(&config.app_config_files)
+            .context("Failed to load application configuration files")?;
+        bind_configured_resources(&mut extracted_methods, &config_values);
+    }
 
     debug!(
         "Extracted {} methods, starting enrichment pipeline",
@@ -261,19 +739,60 @@ pub async fn generate_policies(config: &GeneratePolicyConfig) -> Result<Generate
     );
 
     // Handle empty method lists gracefully
-    if extracted_methods.is_empty() {
+    if extracted_methods.is_empty()
+        && required.is_empty()
+        && instrumentation.is_empty()
+        && event_sources.is_empty()
+    {
         info!("No methods found to process, returning empty policy list");
         return Ok(GeneratePoliciesResult {
             policies: vec![],
             explanations: None,
             resource_binding_explanations: None,
+            template_variables: None,
+            unscoped_actions: None,
+            managed_policy_suggestions: None,
+            // Roles assumed with a web identity token are only named by calls needing no
+            // permission
+            trust_policies: config.trust_policies.then(|| {
+                trust_policies(
+                    &[],
+                    no_permission_calls.as_deref().unwrap_or_default(),
+                    config.workload_role_arn.as_deref(),
+                )
+            }),
+            resource_policies: None,
+            cross_account_access: None,
+            condition_key_suggestions: None,
+            runtime: None,
+            statement_origins: None,
+            sensitive_actions: None,
+            access_level_summary: None,
+            suppressed_calls,
+            low_confidence_calls,
+            deprecated_calls,
+            no_permission_calls,
+            excluded_service_calls: None,
+            filtered_actions: None,
+            action_provenance: None,
+            action_inventory: None,
+            diagnostics,
+            coverage: config
+                .coverage_report
+                .then(|| coverage_report(&source_files, &[], &[], &skipped_call_sites)),
         });
     }
 
     // Run the complete enrichment pipeline
+    let enrichment_start = Instant::now();
     let enriched_results = enrichment_engine
         .enrich_methods(&extracted_methods, sdk)
         .await?;
+    info!(
+        "Enriched {} SDK calls in {:?}",
+        enriched_results.len(),
+        enrichment_start.elapsed()
+    );
 
     let enrichment_duration = pipeline_start.elapsed();
     trace!("Enrichment pipeline completed in {enrichment_duration:?}");
@@ -301,6 +820,42 @@ pub async fn generate_policies(config: &GeneratePolicyConfig) -> Result<Generate
     } else {
         (enriched_results, None)
     };
+    enrichment_engine.write_service_reference_lock().await?;
+
+    // Resources nothing above resolves are taken from recorded answers, or asked for
+    let mut final_enriched = final_enriched;
+    final_enriched.extend(enrich_required_permissions(&required));
+    final_enriched.extend(enrich_instrumentation(&instrumentation));
+    final_enriched.extend(enrich_event_sources(&event_sources));
+    grant_directory_bucket_calls(&mut final_enriched, sdk, call_site_resources);
+    scope_model_invocations(&mut final_enriched, call_site_resources);
+    scope_query_locations(&mut final_enriched, sdk, call_site_resources);
+    select_credential_actions(&mut final_enriched, sdk, call_site_resources);
+    if config.s3_multipart_actions {
+        expand_multipart_actions(&mut final_enriched, sdk);
+    }
+    apply_kms_via_service(&mut final_enriched, config.kms_via_service.as_ref());
+    let mut resource_answers = config.resource_answers.clone();
+    apply_resource_answers(
+        &mut final_enriched,
+        &mut resource_answers,
+        config.resource_prompt.as_deref(),
+    );
+    if let Some(forms) = &config.s3_resource_forms {
+        select_s3_resource_forms(&mut final_enriched, forms);
+    }
+    // Services granted elsewhere, e.g. by a base policy, left out with the calls using them
+    let (final_enriched, excluded_service_calls) =
+        exclude_services(final_enriched, &config.excluded_services);
+    if !excluded_service_calls.is_empty() {
+        info!(
+            "Excluding the actions of {} calls in excluded services",
+            excluded_service_calls.len()
+        );
+    }
+    let excluded_service_calls = Some(excluded_service_calls).filter(|calls| !calls.is_empty());
+    let mut final_enriched =
+        exclude_unavailable_services(final_enriched, &config.aws_context.partition);
 
     // Create policy generation engine with AWS context and merger configuration
     let merger_config = PolicyMergerConfig {
@@ -312,16 +867,48 @@ pub async fn generate_policies(config: &GeneratePolicyConfig) -> Result<Generate
         &config.aws_context.region,
         &config.aws_context.account,
         merger_config,
-    );
+    )
+    .with_template_variables(config.template_variables);
+
+    let coverage = config.coverage_report.then(|| {
+        coverage_report(
+            &source_files,
+            &extracted_methods,
+            &final_enriched,
+            &skipped_call_sites,
+        )
+    });
+    if let Some(coverage) = &coverage {
+        info!(
+            "Resolved {} of {} call sites, {} partially and {} skipped",
+            coverage.total.resolved,
+            coverage.total.call_sites,
+            coverage.total.partially_resolved,
+            coverage.total.skipped
+        );
+    }
+
+    let cross_account = config
+        .cross_account_report
+        .then(|| separate_cross_account_calls(&mut final_enriched, &config.aws_context.account));
+    let unscoped = config
+        .report_unscoped_actions
+        .then(|| unscoped_actions(&final_enriched));
 
     // Generate IAM policies from enriched method calls
     debug!(
         "Generating IAM policies from {} enriched method calls",
         final_enriched.len()
     );
+    let generation_start = Instant::now();
     let result = policy_engine
         .generate_policies(&final_enriched)
         .context("Failed to generate IAM policies")?;
+    info!(
+        "Generated {} policies in {:?}",
+        result.policies.len(),
+        generation_start.elapsed()
+    );
 
     let total_duration = pipeline_start.elapsed();
     debug!(
@@ -330,7 +917,33 @@ pub async fn generate_policies(config: &GeneratePolicyConfig) -> Result<Generate
         result.policies.len()
     );
 
-    let mut final_policies = result.policies;
+    let (mut final_policies, policy_calls) = assign_principals(
+        result.policies,
+        final_enriched.iter().collect(),
+        &config.principal_mappings,
+    );
+
+    let trust = config.trust_policies.then(|| {
+        trust_policies(
+            &final_policies,
+            no_permission_calls.as_deref().unwrap_or_default(),
+            config.workload_role_arn.as_deref(),
+        )
+    });
+    let resource = config.resource_policies.then(|| {
+        resource_policies(
+            &final_policies,
+            &final_enriched,
+            &config.aws_context.partition,
+            &config.aws_context.region,
+            &config.aws_context.account,
+            config.workload_role_arn.as_deref(),
+        )
+    });
+
+    if let Some(entry_points) = &entry_points {
+        final_policies = assign_entry_points(final_policies, &policy_calls, entry_points);
+    }
 
     // Generate explanations only if explain_filters is provided
     let explanations = match &config.explain_filters {
@@ -338,21 +951,132 @@ pub async fn generate_policies(config: &GeneratePolicyConfig) -> Result<Generate
         None => None,
     };
 
+    let mut managed_policy_suggestions = None;
     if !config.individual_policies {
         final_policies = policy_engine
             .merge_policies(&final_policies)
             .context("Failed to merge IAM policies")?;
+        if let Some(unscoped) = &unscoped {
+            separate_unscopable_actions(&mut final_policies, unscoped);
+        }
+        if config.match_managed_policies {
+            managed_policy_suggestions = Some(match_managed_policies(
+                &mut final_policies,
+                &config.aws_context.partition,
+            ));
+        }
+        if config.split_read_write {
+            let read_only_actions = load_read_only_actions(
+                &final_policies,
+                enrichment_engine.service_reference_loader(),
+            )
+            .await?;
+            final_policies = split_read_write(final_policies, &read_only_actions);
+        }
+        if config.compact_actions {
+            let service_actions = load_service_actions(
+                &final_policies,
+                enrichment_engine.service_reference_loader(),
+            )
+            .await?;
+            compact_actions(&mut final_policies, &service_actions);
+        }
+    }
+    let access_analyzer_merge = match &config.access_analyzer_policy {
+        Some(path) => {
+            let observed = load_access_analyzer_statements(path)
+                .context("Failed to load Access Analyzer policy")?;
+            Some(merge_access_analyzer_statements(
+                &mut final_policies,
+                observed,
+            ))
+        }
+        None => None,
+    };
+    if let Some(origins) = &config.network_origins {
+        restrict_network_origins(&mut final_policies, origins);
+    }
+    if !config.individual_policies {
+        assign_statement_ids(&mut final_policies);
     }
+    if let Some(regions) = &requested_regions {
+        restrict_regions(&mut final_policies, regions, &config.aws_context.partition);
+    }
+    let filtered_actions = (!config.action_filters.is_empty())
+        .then(|| filter_actions(&mut final_policies, &config.action_filters));
+    let origins = access_analyzer_merge
+        .as_ref()
+        .map(|merge| statement_origins(&final_policies, merge));
+    let sensitive = config
+        .flag_sensitive_actions
+        .then(|| sensitive_actions(&final_policies, &final_enriched));
+    let provenance = config
+        .action_provenance
+        .then(|| action_provenance(&final_policies, &final_enriched));
+    let inventory = config
+        .action_inventory
+        .then(|| action_inventory(&final_policies, &final_enriched, &evidence));
+    let access_summary = if config.access_level_summary {
+        let access_levels = load_access_levels(
+            &final_policies,
+            enrichment_engine.service_reference_loader(),
+        )
+        .await?;
+        Some(access_level_summary(&final_policies, &access_levels))
+    } else {
+        None
+    };
+
+    let condition_key_suggestions = if config.suggest_condition_keys {
+        let action_condition_keys = load_action_condition_keys(
+            &final_policies,
+            enrichment_engine.service_reference_loader(),
+        )
+        .await?;
+        Some(suggest_condition_keys(
+            &final_policies,
+            &final_enriched,
+            &action_condition_keys,
+            &config.aws_context.account,
+        ))
+    } else {
+        None
+    };
 
     iam_policy_autopilot_common::telemetry::span::record_result_number(
         "num_policies_generated",
         final_policies.len(),
     );
 
+    let template_variables = config
+        .template_variables
+        .then(|| template_variables(&final_policies));
+
     Ok(GeneratePoliciesResult {
         policies: final_policies,
         explanations,
         resource_binding_explanations: binding_explanations,
+        template_variables,
+        unscoped_actions: unscoped,
+        managed_policy_suggestions,
+        trust_policies: trust,
+        resource_policies: resource,
+        cross_account_access: cross_account,
+        condition_key_suggestions,
+        runtime,
+        statement_origins: origins,
+        sensitive_actions: sensitive,
+        access_level_summary: access_summary,
+        suppressed_calls,
+        low_confidence_calls,
+        deprecated_calls,
+        no_permission_calls,
+        excluded_service_calls,
+        filtered_actions,
+        action_provenance: provenance,
+        action_inventory: inventory,
+        diagnostics,
+        coverage,
     })
 }
 
@@ -601,4 +1325,37 @@ mod tests {
         let result_arns: Vec<&str> = result.iter().map(|e| e.arn.as_str()).collect();
         assert_eq!(result_arns, expected_arns, "[{_name}] ARN list mismatch");
     }
+
+    fn located_call(name: &str, services: &[&str], line: usize) -> SdkMethodCall {
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: services.iter().map(ToString::to_string).collect(),
+            metadata: Some(crate::extraction::SdkMethodCallMetadata::new(
+                format!("client.{name}()"),
+                crate::Location::new(PathBuf::from("app.py"), (line, 1), (line, 20)),
+            )),
+        }
+    }
+
+    #[rstest]
+    #[case::listed_and_kept(None, 2, &[3])]
+    #[case::below_medium(Some(CallConfidence::Medium), 1, &[3])]
+    #[case::below_high(Some(CallConfidence::High), 0, &[1, 3])]
+    fn test_separate_low_confidence_calls(
+        #[case] min_confidence: Option<CallConfidence>,
+        #[case] kept: usize,
+        #[case] listed_lines: &[usize],
+    ) {
+        let methods = vec![
+            located_call("list_tags", &["kms", "lambda"], 3),
+            located_call("get_object", &["s3"], 1),
+        ];
+
+        let (methods, low_confidence) =
+            separate_low_confidence_calls(methods, &ConfidenceEvidence::default(), min_confidence);
+
+        assert_eq!(methods.len(), kept);
+        let lines: Vec<usize> = low_confidence.iter().map(|call| call.line).collect();
+        assert_eq!(lines, listed_lines);
+    }
 }
@@ -0,0 +1,117 @@
+use anyhow::{Context, Result};
+use log::info;
+
+use crate::{
+    api::{
+        common::process_source_files,
+        model::{ExtractSdkCallsConfig, InventoriedCall},
+    },
+    extraction::shared::ConfidenceEvidence,
+    SdkMethodCall, ServiceDiscovery,
+};
+
+/// List every AWS SDK call of the source files, independently of policy generation
+pub async fn list_calls(config: &ExtractSdkCallsConfig) -> Result<Vec<InventoriedCall>> {
+    info!("Listing SDK calls");
+
+    // Create the extractor
+    let extractor = crate::ExtractionEngine::new()
+        .with_jobs(config.jobs)
+        .with_progress(config.progress.clone())
+        .with_cache_dir(config.cache_dir.clone())
+        .with_cache_inputs(config.cache_inputs());
+
+    let (extracted_methods, _) = process_source_files(&extractor, config)
+        .await
+        .context("Failed to process source files")?;
+
+    let source_files = &extracted_methods.metadata.source_files;
+    let service_index = match source_files.first() {
+        Some(source_file) => {
+            Some(ServiceDiscovery::load_service_index(source_file.language).await?)
+        }
+        None => None,
+    };
+    let evidence = ConfidenceEvidence::new(service_index.as_deref(), source_files);
+    let mut calls: Vec<InventoriedCall> = extracted_methods
+        .methods
+        .iter()
+        .filter_map(|method| inventoried_call(method, &evidence))
+        .collect();
+    calls.sort_by(|a, b| {
+        (&a.file, a.line, a.column, &a.operation).cmp(&(&b.file, b.line, b.column, &b.operation))
+    });
+    Ok(calls)
+}
+
+/// The inventory entry of an extracted call, if it has a source location
+pub(crate) fn inventoried_call(
+    method: &SdkMethodCall,
+    evidence: &ConfidenceEvidence,
+) -> Option<InventoriedCall> {
+    let metadata = method.metadata.as_ref()?;
+    Some(InventoriedCall {
+        services: method.possible_services.clone(),
+        operation: method.name.clone(),
+        file: metadata.location.file_path.clone(),
+        line: metadata.location.start_line(),
+        column: metadata.location.start_col(),
+        expression: metadata.expr.clone(),
+        resources: metadata.resource_bindings.clone(),
+        assumed_role: metadata.assumed_role.clone(),
+        credential_profile: metadata.credential_profile.clone(),
+        confidence: evidence.confidence(method),
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use rstest::rstest;
+
+    use super::*;
+    use crate::api::model::CallConfidence;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::Location;
+
+    #[rstest]
+    #[case::resolved_client(&["s3"], Some("s3"), CallConfidence::High)]
+    #[case::method_name_only(&["s3"], None, CallConfidence::Medium)]
+    #[case::unresolved_client(&["kms", "lambda"], Some("client"), CallConfidence::Low)]
+    fn test_inventoried_call_confidence(
+        #[case] services: &[&str],
+        #[case] receiver: Option<&str>,
+        #[case] expected: CallConfidence,
+    ) {
+        let mut metadata = SdkMethodCallMetadata::new(
+            "client.list_tags()".to_string(),
+            Location::new(PathBuf::from("app.py"), (4, 5), (4, 23)),
+        );
+        if let Some(receiver) = receiver {
+            metadata = metadata.with_receiver(receiver.to_string());
+        }
+        let method = SdkMethodCall {
+            name: "list_tags".to_string(),
+            possible_services: services.iter().map(ToString::to_string).collect(),
+            metadata: Some(metadata),
+        };
+
+        let evidence = ConfidenceEvidence::default();
+        let call = inventoried_call(&method, &evidence).expect("call has a location");
+
+        assert_eq!(call.confidence, expected);
+        assert_eq!((call.line, call.column), (4, 5));
+    }
+
+    #[test]
+    fn test_calls_without_location_are_not_listed() {
+        let method = SdkMethodCall {
+            name: "list_buckets".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: None,
+        };
+
+        assert!(inventoried_call(&method, &ConfidenceEvidence::default()).is_none());
+    }
+}
@@ -1,16 +1,24 @@
 //! IAM Policy Autopilot Core API Interface
 
+mod dump_mappings;
 mod extract_sdk_calls;
 #[cfg(feature = "model-generation")]
 mod generate_model;
 mod generate_policies;
 mod get_submodule_version;
+mod list_calls;
+mod preload;
+mod update_mappings;
 #[cfg(feature = "model-generation")]
 pub use crate::extraction::external_library_models::ExternalLibraryModel;
+pub use dump_mappings::dump_mappings;
 pub use extract_sdk_calls::extract_sdk_calls;
 #[cfg(feature = "model-generation")]
 pub use generate_model::{generate_model, GenerateModelConfig};
 pub use generate_policies::generate_policies;
 pub use get_submodule_version::{get_boto3_version_info, get_botocore_version_info};
+pub use list_calls::list_calls;
+pub use preload::preload_service_data;
+pub use update_mappings::update_mappings;
 pub(crate) mod common;
 pub mod model;
@@ -2,11 +2,21 @@
 use serde::{Deserialize, Serialize};
 
 use crate::{
-    embedded_data::BotocoreData, enrichment::terraform::ResourceBindingExplanation,
-    enrichment::Explanations, policy_generation::PolicyWithMetadata,
+    embedded_data::BotocoreData,
+    enrichment::terraform::ResourceBindingExplanation,
+    enrichment::{DeprecatedCall, ExcludedServiceCall, Explanations, NoPermissionCall},
+    extraction::{Diagnostic, ProgressObserver, SuppressedCall},
+    policy_generation::{
+        ActionProvenance, ConditionKeySuggestion, CoverageReport, CrossAccountAccess,
+        FilteredAction, InventoriedAction, ManagedPolicySuggestion, PolicyWithMetadata,
+        ResourcePolicy, Runtime, SensitiveAction, ServiceAccessLevels, StatementOrigin,
+        TemplateVariable, TrustPolicy, UnscopedAction,
+    },
 };
 use anyhow::{anyhow, Result};
-use std::path::PathBuf;
+use std::collections::{BTreeMap, HashSet};
+use std::path::{Path, PathBuf};
+use std::sync::Arc;
 
 /// Configuration for generate_policies API
 #[derive(Debug, Clone)]
@@ -47,6 +57,688 @@ pub struct GeneratePolicyConfig {
     pub explain_resource_filters: Option<Vec<String>>,
     /// Resource lists with more than this many entries are collapsed to '*' instead of emitting every resource-specific ARN. Use 0 to collapse every non-empty resource list. Default: 4.
     pub resource_cutoff: usize,
+    /// Keep resource ARNs wildcarded instead of scoping them to identifiers known at the call
+    /// site, such as bucket or table names passed as string literals. Resources bound from
+    /// Terraform inputs are unaffected.
+    pub wildcard_resources: bool,
+    /// Application configuration files (YAML, JSON, TOML or `.env`) the code reads
+    /// resource names from, e.g. `cfg.BucketName` or `process.env.TABLE_NAME`. Their
+    /// values scope resources like literals at the call site.
+    pub app_config_files: Vec<PathBuf>,
+    /// Recorded values for resources the code doesn't name, e.g. loaded from an answers
+    /// file of an earlier interactive run
+    pub resource_answers: ResourceAnswers,
+    /// Asked for the resources no other input resolves; `None` keeps them wildcarded
+    pub resource_prompt: Option<Arc<dyn ResourcePrompt>>,
+    /// Recorded services of calls whose operation exists in several services, e.g. loaded
+    /// from a disambiguation file of an earlier interactive run
+    pub service_choices: ServiceChoices,
+    /// Asked for the service of ambiguous calls without a recorded choice; `None` keeps
+    /// every possible service
+    pub service_prompt: Option<Arc<dyn ServicePrompt>>,
+    /// Operation to action mappings merged over those of the service references, e.g.
+    /// loaded from a mapping overrides file
+    pub mapping_overrides: MappingOverrides,
+    /// Lockfile pinning the service references operations are mapped to actions with;
+    /// services it doesn't pin yet are added to it
+    pub service_reference_lock: Option<PathBuf>,
+    /// Services other than AWS ones whose calls are granted in their own action namespace
+    /// or left out of the policies
+    pub custom_services: CustomServices,
+    /// Named principals the source files are mapped to, for a policy per principal; the
+    /// calls of files none covers stay with the principal running the code
+    pub principal_mappings: PrincipalMappings,
+    /// Leave the calls whose services are less certain out of the policies, listing them
+    /// in the result instead; `None` grants every call
+    pub min_confidence: Option<CallConfidence>,
+    /// Emit parameterized policies: resources that aren't known become template variables
+    /// such as `{{BucketName}}` or `{{AccountId}}`, listed in the result's manifest
+    pub template_variables: bool,
+    /// S3 resource forms to grant access through; `None` emits every form
+    pub s3_resource_forms: Option<Vec<S3ResourceForm>>,
+    /// Report the actions granted on `Resource: "*"`, keeping actions that don't support
+    /// resource-level permissions in statements of their own
+    pub report_unscoped_actions: bool,
+    /// Replace enumerated actions with wildcards such as `s3:GetObject*` where every action
+    /// the wildcard matches is already granted
+    pub compact_actions: bool,
+    /// Move statements AWS managed policies cover out of the policies, suggesting
+    /// the managed policies to attach instead
+    pub match_managed_policies: bool,
+    /// Generate trust policy stubs for the roles the code assumes by a literal ARN
+    pub trust_policies: bool,
+    /// Whether to generate the resource-based policies the analyzed code implies
+    pub resource_policies: bool,
+    /// Whether to report the calls on resources of other accounts, leaving the actions of
+    /// those only a role of the target account can make out of the policies
+    pub cross_account_report: bool,
+    /// Role of the analyzed workload, trusted by the roles it assumes; a
+    /// `{{WorkloadRoleArn}}` placeholder when `None`
+    pub workload_role_arn: Option<String>,
+    /// Whether to suggest condition keys narrowing the generated statements
+    pub suggest_condition_keys: bool,
+    /// Whether to grant the permissions of the tracing, metrics and logging instrumentation
+    /// the code uses, such as X-Ray, ADOT, CloudWatch embedded metrics and watchtower
+    pub observability_permissions: bool,
+    /// OpenTelemetry Collector configurations, e.g. of an ADOT sidecar the code exports OTLP
+    /// to, whose AWS exporters need permissions with `observability_permissions`
+    pub collector_config_files: Vec<PathBuf>,
+    /// Whether to grant the permissions of the event sources of the Lambda handlers the
+    /// code has, such as SQS queues and Kinesis streams
+    pub event_source_permissions: bool,
+    /// Whether to grant multipart uploads `s3:AbortMultipartUpload` and
+    /// `s3:ListMultipartUploadParts` besides `s3:PutObject`
+    pub s3_multipart_actions: bool,
+    /// KMS permissions of the services that encrypt data on behalf of the calls, granted
+    /// with `kms:ViaService`; `None` grants none
+    pub kms_via_service: Option<KmsViaService>,
+    /// Services whose actions the policies leave out, e.g. those of a base policy; the
+    /// calls losing actions are listed in the result instead
+    pub excluded_services: Vec<String>,
+    /// Actions stripped from the generated policies, listed in the result
+    pub action_filters: ActionFilters,
+    /// Whether to split the policies into read-only and write policies
+    pub split_read_write: bool,
+    /// Whether to generate separate policies for each entry point of the code
+    pub entry_point_policies: bool,
+    /// Whether to detect the compute runtime the code runs on, for role definitions
+    pub detect_runtime: bool,
+    /// Regions to restrict the generated statements to with `aws:RequestedRegion`; empty to
+    /// detect them from the code, `None` not to restrict the regions
+    pub restrict_regions: Option<Vec<String>>,
+    /// Networks to restrict the generated statements to, with `aws:SourceVpce`,
+    /// `aws:SourceVpc` and `aws:SourceIp` conditions
+    pub network_origins: Option<NetworkOrigins>,
+    /// Policy generated by IAM Access Analyzer from CloudTrail to merge with the generated
+    /// policies, labeling the origin of each statement
+    pub access_analyzer_policy: Option<PathBuf>,
+    /// Whether to flag privileged and escalation-prone actions of the generated statements
+    pub flag_sensitive_actions: bool,
+    /// Whether to summarize the generated actions by service and IAM access level
+    pub access_level_summary: bool,
+    /// Whether to map the generated actions to the calls requiring them
+    pub action_provenance: bool,
+    /// Whether to inventory the generated actions by resource and call site
+    pub action_inventory: bool,
+    /// Whether to report where the analysis of the code lost precision
+    pub analysis_diagnostics: bool,
+    /// Whether to report how completely the call sites of the code were resolved
+    pub coverage_report: bool,
+}
+
+/// Networks the generated statements allow requests from, for data perimeters
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct NetworkOrigins {
+    /// VPC endpoint IDs requests to the endpoint services have to come through, e.g.
+    /// `vpce-1a2b3c4d` (`aws:SourceVpce`)
+    pub vpc_endpoints: Vec<String>,
+    /// VPC IDs requests to the endpoint services have to come from (`aws:SourceVpc`)
+    pub vpcs: Vec<String>,
+    /// Public IP ranges in CIDR notation requests to other services have to come from
+    /// (`aws:SourceIp`)
+    pub source_ips: Vec<String>,
+    /// Services reached through the VPC endpoints, e.g. `s3`; all services if empty
+    pub endpoint_services: Vec<String>,
+}
+
+/// Form of S3 resource ARNs that statements of S3 actions grant access through
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum S3ResourceForm {
+    /// Buckets and objects, e.g. `arn:aws:s3:::reports/*`
+    Bucket,
+    /// Access points and the objects reached through them
+    AccessPoint,
+    /// Object Lambda Access Points
+    ObjectLambda,
+    /// Multi-Region Access Points
+    MultiRegionAccessPoint,
+}
+
+impl S3ResourceForm {
+    /// Parse a form name: `bucket`, `access-point`, `object-lambda` or
+    /// `multi-region-access-point` (`mrap`)
+    ///
+    /// # Errors
+    /// Returns an error for any other name
+    pub fn parse(name: &str) -> Result<Self> {
+        match name.to_ascii_lowercase().as_str() {
+            "bucket" => Ok(Self::Bucket),
+            "access-point" => Ok(Self::AccessPoint),
+            "object-lambda" => Ok(Self::ObjectLambda),
+            "multi-region-access-point" | "mrap" => Ok(Self::MultiRegionAccessPoint),
+            _ => Err(anyhow!(
+                "Unknown S3 resource form '{name}': expected bucket, access-point, \
+                 object-lambda or multi-region-access-point"
+            )),
+        }
+    }
+
+    /// The form of an S3 resource type of the Service Reference, e.g. `accesspointobject`
+    pub(crate) fn of_resource_type(resource_type: &str) -> Option<Self> {
+        match resource_type {
+            "bucket" | "object" => Some(Self::Bucket),
+            "accesspoint" | "accesspointobject" => Some(Self::AccessPoint),
+            "objectlambdaaccesspoint" => Some(Self::ObjectLambda),
+            "multiregionaccesspoint" | "multiregionaccesspointrequestarn" => {
+                Some(Self::MultiRegionAccessPoint)
+            }
+            _ => None,
+        }
+    }
+}
+
+/// Actions the generated policies may grant, applied to the policies after generation
+///
+/// Organizations forbid some actions whatever the code calls, e.g. `iam:CreateUser`, or only
+/// let workloads have the actions of an approved list. Both lists take actions with
+/// wildcards, e.g. `iam:Create*`.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct ActionFilters {
+    /// Actions to strip from the policies
+    pub deny: Vec<String>,
+    /// Actions the policies may grant, stripping every other; all actions if empty
+    pub allow: Vec<String>,
+}
+
+impl ActionFilters {
+    /// Whether no action is filtered
+    #[must_use]
+    pub fn is_empty(&self) -> bool {
+        self.deny.is_empty() && self.allow.is_empty()
+    }
+}
+
+/// Which services the KMS permissions used on behalf of the calls are granted for, and on
+/// which keys
+///
+/// Services such as S3 and DynamoDB call KMS with the caller's permissions to encrypt and
+/// decrypt its data, so their calls are granted actions like `kms:Decrypt` restricted to
+/// requests made through them with `kms:ViaService`.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct KmsViaService {
+    /// Services to grant the KMS permissions for, e.g. `s3`; all services if empty
+    pub services: Vec<String>,
+    /// Keys to grant the KMS permissions on instead of every key of the account
+    pub key_arns: Vec<KmsKeyArn>,
+}
+
+impl KmsViaService {
+    /// Whether the KMS permissions are granted for the calls of `service`
+    pub(crate) fn grants(&self, service: &str) -> bool {
+        self.services.is_empty() || self.services.iter().any(|granted| granted == service)
+    }
+
+    /// The keys the KMS permissions of the calls of `service` are granted on; every key if
+    /// empty
+    pub(crate) fn key_arns(&self, service: &str) -> Vec<&str> {
+        let for_service: Vec<&str> = self
+            .key_arns
+            .iter()
+            .filter(|key| key.service.as_deref() == Some(service))
+            .map(|key| key.arn.as_str())
+            .collect();
+        if !for_service.is_empty() {
+            return for_service;
+        }
+        self.key_arns
+            .iter()
+            .filter(|key| key.service.is_none())
+            .map(|key| key.arn.as_str())
+            .collect()
+    }
+}
+
+/// KMS key the KMS permissions used on behalf of calls are granted on
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct KmsKeyArn {
+    /// Service whose calls use the key, e.g. `s3`; the calls of every service if `None`
+    pub service: Option<String>,
+    /// ARN of the key or alias, e.g. `arn:aws:kms:us-east-1:123456789012:key/1234abcd-...`
+    pub arn: String,
+}
+
+impl KmsKeyArn {
+    /// Parse `ARN` or `SERVICE=ARN`, e.g.
+    /// `s3=arn:aws:kms:us-east-1:123456789012:alias/reports`
+    ///
+    /// # Errors
+    /// Returns an error if the ARN isn't the ARN of a KMS key or alias
+    pub fn parse(text: &str) -> Result<Self> {
+        let (service, arn) = match text.split_once('=') {
+            Some((service, arn)) => (Some(service.trim().to_string()), arn.trim()),
+            None => (None, text.trim()),
+        };
+        let fields: Vec<&str> = arn.splitn(6, ':').collect();
+        let is_key = matches!(
+            fields.as_slice(),
+            ["arn", _, "kms", _, _, resource]
+                if resource.starts_with("key/") || resource.starts_with("alias/")
+        );
+        if !is_key {
+            return Err(anyhow!(
+                "'{arn}' is not the ARN of a KMS key or alias, e.g. \
+                 arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+            ));
+        }
+        Ok(Self {
+            service,
+            arn: arn.to_string(),
+        })
+    }
+}
+
+/// Values for ARN placeholders of calls whose resources can't be resolved statically
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ResourceAnswers {
+    /// Answers, in the order they were given
+    pub answers: Vec<ResourceAnswer>,
+}
+
+impl ResourceAnswers {
+    /// The answer for `placeholder` of the call `call` in `file`
+    pub(crate) fn value(&self, file: &Path, call: &str, placeholder: &str) -> Option<&str> {
+        self.answers
+            .iter()
+            .find(|answer| {
+                answer.file == file && answer.call == call && answer.placeholder == placeholder
+            })
+            .map(|answer| answer.value.as_str())
+    }
+
+    /// Suggested value for `placeholder`: the latest answer given for it, or `*`
+    pub(crate) fn default_value(&self, placeholder: &str) -> String {
+        self.answers
+            .iter()
+            .rev()
+            .find(|answer| answer.placeholder == placeholder)
+            .map_or_else(|| "*".to_string(), |answer| answer.value.clone())
+    }
+}
+
+/// Value of one ARN placeholder of a call
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ResourceAnswer {
+    /// Source file of the call
+    pub file: PathBuf,
+    /// Expression of the call, which identifies it regardless of the line it's on
+    pub call: String,
+    /// ARN placeholder, e.g. `BucketName`
+    pub placeholder: String,
+    /// Substituted value: a name, a pattern such as `reports-*`, or `*`
+    pub value: String,
+}
+
+/// An ARN placeholder of a call that neither the code nor other inputs resolve
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct UnresolvedResource {
+    /// Source file of the call
+    pub file: PathBuf,
+    /// Line of the call (1-based)
+    pub line: usize,
+    /// Expression of the call
+    pub call: String,
+    /// IAM action the resource is authorized for, e.g. `s3:GetObject`
+    pub action: String,
+    /// ARN pattern containing the placeholder, e.g. `arn:${Partition}:s3:::${BucketName}`
+    pub arn_pattern: String,
+    /// ARN placeholder, e.g. `BucketName`
+    pub placeholder: String,
+    /// Suggested value: the latest answer given for the placeholder, or `*`
+    pub default: String,
+}
+
+/// Source of values for unresolved resources, such as a prompt on an interactive terminal
+pub trait ResourcePrompt: std::fmt::Debug + Send + Sync {
+    /// The value to substitute for the placeholder, recorded as an answer, or `None`
+    /// to leave it wildcarded
+    fn resolve(&self, resource: &UnresolvedResource) -> Option<String>;
+}
+
+/// Services chosen for calls whose operation exists in several services
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ServiceChoices {
+    /// Choices, in the order they were made
+    pub choices: Vec<ServiceChoice>,
+    /// Services pinned for every ambiguous call of some files, packages or variables,
+    /// applied to the calls without a choice of their own
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub overrides: Vec<ServiceOverride>,
+}
+
+impl ServiceChoices {
+    /// The latest service chosen for `operation` of the call `call` in `file`
+    pub(crate) fn service(&self, file: &Path, call: &str, operation: &str) -> Option<&str> {
+        self.choices
+            .iter()
+            .rev()
+            .find(|choice| {
+                choice.file == file && choice.call == call && choice.operation == operation
+            })
+            .map(|choice| choice.service.as_str())
+    }
+
+    /// Check that every override has a condition and valid file globs
+    ///
+    /// # Errors
+    /// Returns an error for the first invalid override
+    pub fn validate(&self) -> Result<()> {
+        for service_override in &self.overrides {
+            if service_override.files.is_none()
+                && service_override.package.is_none()
+                && service_override.variable.is_none()
+            {
+                return Err(anyhow!(
+                    "Override of service '{}' needs Files, Package or Variable",
+                    service_override.service
+                ));
+            }
+            if let Some(files) = &service_override.files {
+                glob::Pattern::new(files)
+                    .map_err(|error| anyhow!("Invalid Files glob '{files}': {error}"))?;
+            }
+        }
+        Ok(())
+    }
+}
+
+/// Service of the ambiguous calls matching every condition of the override
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ServiceOverride {
+    /// Glob of the source files of the calls, as given to the analysis, e.g.
+    /// `internal/ddb/**`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub files: Option<String>,
+    /// Package of the calls: the package Go and Java files declare, or the directory of
+    /// the source file, e.g. `internal/ddb` or `app.storage`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub package: Option<String>,
+    /// Variable the calls are made on, e.g. `ddb` or `self.table`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub variable: Option<String>,
+    /// Service the calls are made on, e.g. `dynamodb`
+    pub service: String,
+}
+
+/// Service chosen for one call
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ServiceChoice {
+    /// Source file of the call
+    pub file: PathBuf,
+    /// Expression of the call, which identifies it regardless of the line it's on
+    pub call: String,
+    /// SDK method of the call, e.g. `list_tags`
+    pub operation: String,
+    /// Service the call is made on, e.g. `kms`
+    pub service: String,
+}
+
+/// Operation to IAM action mappings added to or correcting those of the service
+/// references, e.g. for preview services or internal Smithy services
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct MappingOverrides {
+    /// Overrides by the name of the service in the service reference, e.g. `s3`
+    pub services: BTreeMap<String, ServiceMappingOverride>,
+}
+
+impl MappingOverrides {
+    /// The overrides of `service`, if any
+    pub(crate) fn service(&self, service: &str) -> Option<&ServiceMappingOverride> {
+        self.services.get(service)
+    }
+
+    /// Check that every authorized action is named `service:Action`
+    ///
+    /// # Errors
+    /// Returns an error for the first invalid action name
+    pub fn validate(&self) -> Result<()> {
+        for (service, service_override) in &self.services {
+            for (operation, actions) in &service_override.operations {
+                for action in actions {
+                    let valid = action.split_once(':').is_some_and(|(prefix, name)| {
+                        !prefix.is_empty() && !name.is_empty() && !name.contains(':')
+                    });
+                    if !valid {
+                        return Err(anyhow!(
+                            "Action '{action}' of operation {service}:{operation} must be named \
+                             service:Action"
+                        ));
+                    }
+                }
+            }
+        }
+        Ok(())
+    }
+}
+
+/// Mappings of one service, merged over those of its service reference
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ServiceMappingOverride {
+    /// Actions each operation authorizes, replacing those of the service reference, e.g.
+    /// `GetObject` to `["s3:GetObject", "kms:Decrypt"]`
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub operations: BTreeMap<String, Vec<String>>,
+    /// Resource types each action of the service applies to, replacing those of the
+    /// service reference, e.g. `InvokeThing` to `["thing"]`
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub actions: BTreeMap<String, Vec<String>>,
+    /// ARN format of each resource type of the service, e.g. `thing` to
+    /// `arn:${Partition}:things:${Region}:${Account}:thing/${ThingName}`
+    #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+    pub resources: BTreeMap<String, String>,
+}
+
+/// Services other than AWS ones the analyzed code calls, such as those of Smithy-generated
+/// clients of in-house services or AWS-compatible services behind custom endpoints
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct CustomServices {
+    /// The services, the first matching a call taking it
+    pub services: Vec<CustomService>,
+}
+
+impl CustomServices {
+    /// Check that every service has a condition, valid file globs and a valid action
+    /// namespace
+    ///
+    /// # Errors
+    /// Returns an error for the first invalid service
+    pub fn validate(&self) -> Result<()> {
+        for service in &self.services {
+            if service.files.is_none() && service.package.is_none() && service.variable.is_none() {
+                return Err(anyhow!(
+                    "Custom service '{}' needs Files, Package or Variable",
+                    service.name
+                ));
+            }
+            if let Some(files) = &service.files {
+                glob::Pattern::new(files)
+                    .map_err(|error| anyhow!("Invalid Files glob '{files}': {error}"))?;
+            }
+            if let Some(namespace) = &service.namespace {
+                if namespace.is_empty()
+                    || !namespace
+                        .chars()
+                        .all(|c| c.is_ascii_lowercase() || c.is_ascii_digit() || c == '-')
+                {
+                    return Err(anyhow!(
+                        "Namespace '{namespace}' of custom service '{}' must be lowercase \
+                         letters, digits and dashes",
+                        service.name
+                    ));
+                }
+            }
+        }
+        Ok(())
+    }
+}
+
+/// A service other than the AWS ones, whose calls are those matching every condition
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct CustomService {
+    /// Name of the service, e.g. `widgets`
+    pub name: String,
+    /// Glob of the source files of the calls, as given to the analysis, e.g.
+    /// `internal/widgets/**`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub files: Option<String>,
+    /// Package of the calls: the package Go and Java files declare, or the directory of
+    /// the source file, e.g. `internal/widgets`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub package: Option<String>,
+    /// Variable the calls are made on, e.g. `minio`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub variable: Option<String>,
+    /// Prefix of the actions of the service, e.g. `widgets` for `widgets:GetWidget`;
+    /// `None` excludes the calls from the policies
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub namespace: Option<String>,
+}
+
+/// Helper functions of the analyzed code forwarding an operation to an AWS SDK client, e.g.
+/// `awsutil.Do(client, &s3.GetObjectInput{...})`, whose calls are taken for calls of the
+/// forwarded operation
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct WrapperFunctions {
+    /// The wrapper functions
+    pub wrappers: Vec<WrapperFunction>,
+}
+
+impl WrapperFunctions {
+    /// Check that every wrapper names a function and a valid service
+    ///
+    /// # Errors
+    /// Returns an error for the first invalid wrapper
+    pub fn validate(&self) -> Result<()> {
+        for wrapper in &self.wrappers {
+            let function = &wrapper.function;
+            if function.is_empty()
+                || !function
+                    .chars()
+                    .all(|c| c.is_alphanumeric() || matches!(c, '_' | '$' | '.' | ':'))
+            {
+                return Err(anyhow!(
+                    "Function '{function}' of a wrapper must be a name such as awsutil.Do"
+                ));
+            }
+            if let Some(service) = &wrapper.service {
+                if service.is_empty()
+                    || !service
+                        .chars()
+                        .all(|c| c.is_ascii_lowercase() || c.is_ascii_digit() || c == '-')
+                {
+                    return Err(anyhow!(
+                        "Service '{service}' of wrapper {function} must be lowercase letters, \
+                         digits and dashes"
+                    ));
+                }
+            }
+        }
+        Ok(())
+    }
+}
+
+/// A function forwarding the operation given as one of its arguments to a client
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct WrapperFunction {
+    /// Name of the function as it's called, e.g. `awsutil.Do` or `call_aws`
+    pub function: String,
+    /// Position of the argument naming the operation, 0-based: a string such as
+    /// `"GetObject"` or `"get_object"`, or a request or command such as
+    /// `&s3.GetObjectInput{...}`, `new GetObjectCommand(...)` or
+    /// `GetObjectRequest.builder()...build()`
+    #[serde(default)]
+    pub operation_argument: usize,
+    /// Service of the operation, e.g. `s3`, for operations several services have; every
+    /// service having the operation if `None`
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub service: Option<String>,
+}
+
+/// Named principals of the analyzed code, such as the roles of the workers and the API a
+/// repository backs, each running the calls of the source files mapped to it
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct PrincipalMappings {
+    /// The principals; the calls of a file several principals cover are granted to each
+    pub principals: Vec<PrincipalMapping>,
+}
+
+impl PrincipalMappings {
+    /// Check that every principal has a unique name usable in file names and valid paths
+    ///
+    /// # Errors
+    /// Returns an error for the first invalid principal
+    pub fn validate(&self) -> Result<()> {
+        let mut names = HashSet::new();
+        for principal in &self.principals {
+            let name = &principal.name;
+            if name.is_empty()
+                || !name
+                    .chars()
+                    .all(|c| c.is_ascii_alphanumeric() || matches!(c, '-' | '_' | '.'))
+            {
+                return Err(anyhow!(
+                    "Principal name '{name}' must be letters, digits, dashes, underscores and \
+                     dots"
+                ));
+            }
+            if !names.insert(name) {
+                return Err(anyhow!("Principal '{name}' is mapped more than once"));
+            }
+            if principal.paths.is_empty() {
+                return Err(anyhow!("Principal '{name}' needs Paths"));
+            }
+            for path in &principal.paths {
+                glob::Pattern::new(path)
+                    .map_err(|error| anyhow!("Invalid path '{path}' of '{name}': {error}"))?;
+            }
+        }
+        Ok(())
+    }
+}
+
+/// A named principal and the source files whose calls it runs
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct PrincipalMapping {
+    /// Name of the principal, e.g. `worker-role`
+    pub name: String,
+    /// Directories, files, entry points and globs of the source files, as given to the
+    /// analysis, e.g. `cmd/api`, `workers/**/*.py`
+    pub paths: Vec<String>,
+}
+
+/// A call whose operation exists in several services, none of which the code identifies
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct AmbiguousCall {
+    /// Source file of the call
+    pub file: PathBuf,
+    /// Line of the call (1-based)
+    pub line: usize,
+    /// Expression of the call
+    pub call: String,
+    /// SDK method of the call
+    pub operation: String,
+    /// Services the call may be made on
+    pub services: Vec<String>,
+}
+
+/// Source of the services of ambiguous calls, such as a prompt on an interactive terminal
+pub trait ServicePrompt: std::fmt::Debug + Send + Sync {
+    /// The service the call is made on, recorded as a choice, or `None` to keep every
+    /// possible service
+    fn choose(&self, call: &AmbiguousCall) -> Option<String>;
 }
 
 /// Result of policy generation including policies, action mappings, and explanations
@@ -61,6 +753,74 @@ pub struct GeneratePoliciesResult {
     /// Explanations for where resource ARNs came from (Terraform bindings)
     #[serde(skip_serializing_if = "Option::is_none")]
     pub resource_binding_explanations: Option<Vec<ResourceBindingExplanation>>,
+    /// Variables of parameterized policies to substitute at deploy time (template mode)
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub template_variables: Option<Vec<TemplateVariable>>,
+    /// Actions granted on `Resource: "*"` and why they couldn't be scoped
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub unscoped_actions: Option<Vec<UnscopedAction>>,
+    /// AWS managed policies to attach instead of the statements they cover
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub managed_policy_suggestions: Option<Vec<ManagedPolicySuggestion>>,
+    /// Trust policy stubs of the roles the code assumes
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub trust_policies: Option<Vec<TrustPolicy>>,
+    /// Resource-based policies the analyzed code implies, if requested
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub resource_policies: Option<Vec<ResourcePolicy>>,
+    /// Calls on resources of other accounts and what those accounts must allow, if requested
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub cross_account_access: Option<Vec<CrossAccountAccess>>,
+    /// Condition keys that could narrow the generated statements, if requested
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub condition_key_suggestions: Option<Vec<ConditionKeySuggestion>>,
+    /// Compute runtime the code runs on, if requested and recognized
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub runtime: Option<Runtime>,
+    /// Origins of the statements, if merged with an Access Analyzer policy
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub statement_origins: Option<Vec<StatementOrigin>>,
+    /// Privileged and escalation-prone actions of the generated statements, when flagged
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub sensitive_actions: Option<Vec<SensitiveAction>>,
+    /// Generated actions by service and IAM access level, if requested
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub access_level_summary: Option<Vec<ServiceAccessLevels>>,
+    /// Calls excluded from policy generation by `autopilot:ignore` annotations, if any
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub suppressed_calls: Option<Vec<SuppressedCall>>,
+    /// Calls resolved with low confidence, if any: with a minimum confidence, the calls
+    /// below it, left out of the policies, and otherwise the calls of
+    /// [`CallConfidence::Low`], granted in every service they may be made on
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub low_confidence_calls: Option<Vec<InventoriedCall>>,
+    /// Calls of deprecated or renamed operations, if any
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub deprecated_calls: Option<Vec<DeprecatedCall>>,
+    /// Calls of operations that need no IAM permission, left out of the policies
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub no_permission_calls: Option<Vec<NoPermissionCall>>,
+    /// Calls whose actions in excluded services were left out of the policies, if any
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub excluded_service_calls: Option<Vec<ExcludedServiceCall>>,
+    /// Actions the action filters stripped from the policies, when filtered
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub filtered_actions: Option<Vec<FilteredAction>>,
+    /// Calls requiring each generated action, if requested. It's written to a sidecar
+    /// file rather than output with the policies.
+    #[serde(skip)]
+    pub action_provenance: Option<Vec<ActionProvenance>>,
+    /// Generated actions by resource and call site, if requested. They're written as CSV
+    /// rather than output with the policies.
+    #[serde(skip)]
+    pub action_inventory: Option<Vec<InventoriedAction>>,
+    /// Places in the code where the analysis lost precision, if requested. They're
+    /// written as a SARIF log rather than output with the policies.
+    #[serde(skip)]
+    pub diagnostics: Option<Vec<Diagnostic>>,
+    /// How completely the call sites of the code were resolved, if requested
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub coverage: Option<CoverageReport>,
 }
 
 /// Service hints for filtering SDK method calls
@@ -70,8 +830,65 @@ pub struct ServiceHints {
     pub service_names: Vec<String>,
 }
 
+/// Sources left out of the analysis unless included explicitly
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum DefaultExclusion {
+    /// Files git ignores through `.gitignore` files or `.git/info/exclude`
+    Ignored,
+    /// Dependencies vendored into the project, under `vendor/`, `node_modules/`, `dist/`
+    /// or `site-packages/` directories
+    Vendored,
+    /// Generated code, e.g. `*.pb.go` files or files marked `Code generated ... DO NOT
+    /// EDIT.` or `@generated`
+    Generated,
+    /// Files larger than the size limit, or minified or bundled code such as webpack
+    /// bundles, whose parsing could stall the analysis
+    Oversized,
+    /// JavaScript and TypeScript tests mocking the AWS SDK clients with
+    /// `aws-sdk-client-mock`, `jest.mock('@aws-sdk/...')` or sinon stubs
+    Mocked,
+}
+
+/// Size in bytes above which source files are skipped as oversized by default
+pub const DEFAULT_MAX_FILE_SIZE: u64 = 2 * 1024 * 1024;
+
+impl DefaultExclusion {
+    /// All sources excluded by default
+    pub const ALL: [Self; 5] = [
+        Self::Ignored,
+        Self::Vendored,
+        Self::Generated,
+        Self::Oversized,
+        Self::Mocked,
+    ];
+
+    /// Stable identifier of the exclusion, e.g. `vendored`
+    #[must_use]
+    pub const fn id(self) -> &'static str {
+        match self {
+            Self::Ignored => "ignored",
+            Self::Vendored => "vendored",
+            Self::Generated => "generated",
+            Self::Oversized => "oversized",
+            Self::Mocked => "mocked",
+        }
+    }
+}
+
+/// Third-party dependencies to analyze along with the source files, see
+/// [`ExtractSdkCallsConfig::dependencies`]
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct DependencyAnalysis {
+    /// Levels of dependencies analyzed: 1 for the dependencies the project declares, 2 for
+    /// their dependencies too, and so on
+    pub depth: usize,
+    /// Patterns of the names of the dependencies to analyze, e.g. `typeorm`, `@acme/*` or
+    /// `github.com/acme/*`; all of them if empty
+    pub allowed: Vec<String>,
+}
+
 /// Configuration for extract_sdk_calls Api
-#[derive(Debug, Clone)]
+#[derive(Debug, Clone, Default)]
 pub struct ExtractSdkCallsConfig {
     /// Enable pretty JSON output formatting
     pub source_files: Vec<PathBuf>,
@@ -79,6 +896,107 @@ pub struct ExtractSdkCallsConfig {
     pub language: Option<String>,
     /// Optional service hints for filtering
     pub service_hints: Option<ServiceHints>,
+    /// Skip test sources (e.g., Go `_test.go` files, Python modules using moto or
+    /// LocalStack) so unit tests don't add permissions the production principal never
+    /// needs. Calls on test doubles (e.g., gomock `EXPECT()` recorders) and generated
+    /// mock files are always ignored.
+    pub exclude_tests: bool,
+    /// Sources excluded by default to analyze anyway: files git ignores, vendored
+    /// dependencies, generated code, oversized files and tests mocking the AWS SDK are
+    /// skipped unless listed
+    pub included: Vec<DefaultExclusion>,
+    /// Size in bytes above which source files are skipped as oversized,
+    /// [`DEFAULT_MAX_FILE_SIZE`] if `None`
+    pub max_file_size: Option<u64>,
+    /// Third-party dependencies analyzed along with the source files: the packages of
+    /// `node_modules`, the distributions of the project's virtual environment or the
+    /// modules of the Go module cache, since libraries make AWS calls the project's own
+    /// code never shows. The AWS SDKs themselves are never analyzed.
+    pub dependencies: Option<DependencyAnalysis>,
+    /// Number of files to analyze concurrently, one per available CPU if `None`
+    pub jobs: Option<usize>,
+    /// Executables reporting the calls of in-house SDK wrappers and private services, see
+    /// the plugin protocol in the README
+    pub plugins: Vec<PathBuf>,
+    /// JSON file declaring the [`WrapperFunctions`] of the analyzed code, whose calls are
+    /// resolved to the operations they forward
+    pub wrappers: Option<PathBuf>,
+    /// Compiled Go binaries to analyze instead of source files, when their sources aren't
+    /// available; each AWS SDK operation linked into them is taken for a call (experimental)
+    pub go_binaries: Vec<PathBuf>,
+    /// Receives each analyzed file, e.g. to stream the progress of the analysis
+    pub progress: Option<Arc<dyn ProgressObserver>>,
+    /// Directory caching the calls extracted from the source files, so runs on sources
+    /// that didn't change since a previous run skip their analysis
+    pub cache_dir: Option<PathBuf>,
+}
+
+impl ExtractSdkCallsConfig {
+    /// Files of the options the cached calls are keyed on: the plugins and the wrapper
+    /// definitions
+    pub(crate) fn cache_inputs(&self) -> Vec<PathBuf> {
+        self.plugins.iter().chain(&self.wrappers).cloned().collect()
+    }
+}
+
+/// An AWS SDK call of the source files, as listed by [`list_calls`](crate::api::list_calls)
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct InventoriedCall {
+    /// Services the call may be made on, e.g. `["s3"]`, several if it couldn't be resolved
+    pub services: Vec<String>,
+    /// SDK method called, e.g. `get_object`
+    pub operation: String,
+    /// Source file of the call
+    pub file: PathBuf,
+    /// Line of the call (1-based)
+    pub line: usize,
+    /// Column of the call (1-based)
+    pub column: usize,
+    /// Expression of the call
+    pub expression: String,
+    /// Resource identifiers known from the call site by ARN placeholder, e.g. `BucketName`
+    /// → `my-bucket`
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub resources: BTreeMap<String, String>,
+    /// Role whose assumed credentials the call runs under, if known
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub assumed_role: Option<String>,
+    /// Named profile whose credentials the call runs under, if known
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub credential_profile: Option<String>,
+    /// How certain the service of the call is
+    pub confidence: CallConfidence,
+}
+
+/// How certain the service of a call is
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq, PartialOrd, Ord)]
+pub enum CallConfidence {
+    /// Several services have the operation and the client's service couldn't be resolved
+    Low,
+    /// The only service having the operation, but nothing else identifies it
+    Medium,
+    /// The service of the client the call is made on, or the only service having the
+    /// operation, whose input shape has the call's arguments or whose SDK the source file
+    /// imports
+    High,
+}
+
+impl CallConfidence {
+    /// Parse a confidence name: `low`, `medium` or `high`
+    ///
+    /// # Errors
+    /// Returns an error for any other name
+    pub fn parse(name: &str) -> Result<Self> {
+        match name.to_ascii_lowercase().as_str() {
+            "low" => Ok(Self::Low),
+            "medium" => Ok(Self::Medium),
+            "high" => Ok(Self::High),
+            _ => Err(anyhow!(
+                "Unknown confidence '{name}': expected low, medium or high"
+            )),
+        }
+    }
 }
 
 // Todo: Find a better place for this or refactor rest of the code to use model
@@ -142,7 +1060,117 @@ impl AwsContext {
             account,
         })
     }
+
+    /// Creates a new AwsContext for an explicit partition, e.g. to emit `arn:aws-cn:` ARNs
+    /// while keeping the region wildcarded. Without a partition, it is derived from the
+    /// region as in [`AwsContext::new`].
+    ///
+    /// Returns an error if the partition is unknown to Botocore data, or if the region
+    /// doesn't belong to it.
+    ///
+    /// # Examples
+    /// ```
+    /// use iam_policy_autopilot_policy_generation::api::model::AwsContext;
+    ///
+    /// let ctx = AwsContext::with_partition(
+    ///     Some("aws-cn".to_string()),
+    ///     "*".to_string(),
+    ///     "123456789012".to_string(),
+    /// )
+    /// .unwrap();
+    /// assert_eq!(ctx.partition, "aws-cn");
+    /// assert_eq!(ctx.region, "*");
+    ///
+    /// assert!(AwsContext::with_partition(
+    ///     Some("aws-cn".to_string()),
+    ///     "us-east-1".to_string(),
+    ///     "123456789012".to_string(),
+    /// )
+    /// .is_err());
+    /// ```
+    pub fn with_partition(
+        partition: Option<String>,
+        region: String,
+        account: String,
+    ) -> Result<Self> {
+        let Some(partition) = partition else {
+            return Self::new(region, account);
+        };
+        if partition != "*" {
+            let partitions = &BotocoreData::get_partitions()?.partitions;
+            let region_regex = partitions.get(&partition).ok_or_else(|| {
+                let mut known: Vec<&str> = partitions.keys().map(String::as_str).collect();
+                known.sort_unstable();
+                anyhow!(
+                    "unknown partition {partition}, expected one of: {}",
+                    known.join(", ")
+                )
+            })?;
+            if region != "*" && !region_regex.is_match(&region) {
+                return Err(anyhow!("region {region} is not in partition {partition}"));
+            }
+        }
+        Ok(Self {
+            partition,
+            region,
+            account,
+        })
+    }
+}
+
+/// Operation to action mappings policy generation uses, as exported by
+/// [`dump_mappings`](crate::api::dump_mappings)
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct MappingsDump {
+    /// Mappings by SDK service name, e.g. `s3`
+    pub services: BTreeMap<String, MappedService>,
+}
+
+/// Operation to action mappings of one service
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct MappedService {
+    /// Actions each operation of the service's SDK model requires, by operation name, e.g.
+    /// `GetObject`; operations requiring no action known to the service reference are
+    /// left out
+    pub operations: BTreeMap<String, Vec<MappedAction>>,
+}
+
+/// An action an operation requires
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct MappedAction {
+    /// Name of the action, e.g. `s3:GetObject`
+    pub name: String,
+    /// Resource types the action applies to
+    pub resources: Vec<MappedResource>,
+    /// Values of the condition keys the action is granted with, e.g. `kms:ViaService` for
+    /// actions AWS performs on behalf of the caller
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub conditions: BTreeMap<String, Vec<String>>,
+}
+
+/// A resource type an action applies to
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct MappedResource {
+    /// Name of the resource type, e.g. `object`
+    pub name: String,
+    /// ARN formats of the resource type, e.g.
+    /// `arn:${Partition}:s3:::${BucketName}/${ObjectName}`
+    pub arn_formats: Vec<String>,
+}
+
+/// Service references saved by [`update_mappings`](crate::api::update_mappings)
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct MappingsUpdate {
+    /// Directory the service references were saved to
+    pub directory: PathBuf,
+    /// Number of services whose service reference was saved
+    pub services: usize,
 }
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -192,4 +1220,205 @@ mod tests {
     fn test_aws_context_invalid_partitions() {
         assert!(AwsContext::new("not-a-region".to_string(), "123456789012".to_string()).is_err());
     }
+
+    #[test]
+    fn test_aws_context_explicit_partition() {
+        let context = |partition: Option<&str>, region: &str| {
+            AwsContext::with_partition(
+                partition.map(str::to_string),
+                region.to_string(),
+                "123456789012".to_string(),
+            )
+        };
+
+        let ctx = context(Some("aws-us-gov"), "*").unwrap();
+        assert_eq!(ctx.partition, "aws-us-gov");
+        assert_eq!(ctx.region, "*");
+
+        let ctx = context(Some("aws-cn"), "cn-north-1").unwrap();
+        assert_eq!(ctx.partition, "aws-cn");
+
+        let ctx = context(Some("*"), "us-east-1").unwrap();
+        assert_eq!(ctx.partition, "*");
+        assert_eq!(ctx.region, "us-east-1");
+
+        let ctx = context(None, "us-gov-west-1").unwrap();
+        assert_eq!(ctx.partition, "aws-us-gov");
+
+        assert!(context(Some("aws-cn"), "us-east-1").is_err());
+        assert!(context(Some("not-a-partition"), "*").is_err());
+    }
+
+    #[test]
+    fn test_s3_resource_form_names() {
+        assert_eq!(
+            S3ResourceForm::parse("access-point").unwrap(),
+            S3ResourceForm::AccessPoint
+        );
+        assert_eq!(
+            S3ResourceForm::parse("MRAP").unwrap(),
+            S3ResourceForm::MultiRegionAccessPoint
+        );
+        assert!(S3ResourceForm::parse("outpost").is_err());
+        assert_eq!(
+            S3ResourceForm::of_resource_type("accesspointobject"),
+            Some(S3ResourceForm::AccessPoint)
+        );
+        assert_eq!(S3ResourceForm::of_resource_type("job"), None);
+    }
+
+    #[test]
+    fn test_kms_key_arns() {
+        let reports = "arn:aws:kms:us-east-1:123456789012:alias/reports";
+        let tables = "arn:aws:kms:us-east-1:123456789012:key/1234abcd";
+        let kms_via_service = KmsViaService {
+            services: vec![],
+            key_arns: vec![
+                KmsKeyArn::parse(&format!("s3={reports}")).unwrap(),
+                KmsKeyArn::parse(tables).unwrap(),
+            ],
+        };
+
+        assert_eq!(kms_via_service.key_arns("s3"), vec![reports]);
+        assert_eq!(kms_via_service.key_arns("dynamodb"), vec![tables]);
+        assert!(kms_via_service.grants("sqs"));
+        assert!(KmsKeyArn::parse("arn:aws:s3:::reports").is_err());
+        assert!(KmsKeyArn::parse("s3=key/1234abcd").is_err());
+    }
+
+    #[test]
+    fn test_mapping_overrides_validation() {
+        let overrides: MappingOverrides = serde_json::from_str(
+            r#"{"Services": {"s3": {"Operations": {"GetObject": ["s3:GetObject", "kms:Decrypt"]}}}}"#,
+        )
+        .unwrap();
+        assert!(overrides.validate().is_ok());
+        assert_eq!(
+            overrides.service("s3").unwrap().operations["GetObject"],
+            vec!["s3:GetObject", "kms:Decrypt"]
+        );
+
+        for action in ["GetObject", "s3:", ":GetObject", "s3:Get:Object"] {
+            let overrides = MappingOverrides {
+                services: BTreeMap::from([(
+                    "s3".to_string(),
+                    ServiceMappingOverride {
+                        operations: BTreeMap::from([(
+                            "GetObject".to_string(),
+                            vec![action.to_string()],
+                        )]),
+                        ..ServiceMappingOverride::default()
+                    },
+                )]),
+            };
+            assert!(overrides.validate().is_err(), "{action} should be invalid");
+        }
+    }
+
+    #[test]
+    fn test_custom_services_validation() {
+        let custom_services: CustomServices = serde_json::from_str(
+            r#"{"Services": [
+                {"Name": "widgets", "Package": "internal/widgets", "Namespace": "widgets"},
+                {"Name": "minio", "Variable": "minio"}
+            ]}"#,
+        )
+        .unwrap();
+        assert!(custom_services.validate().is_ok());
+        assert_eq!(custom_services.services[1].namespace, None);
+
+        let service = CustomService {
+            name: "widgets".to_string(),
+            files: None,
+            package: None,
+            variable: None,
+            namespace: Some("widgets".to_string()),
+        };
+        let invalid = [
+            service.clone(),
+            CustomService {
+                files: Some("src/[widgets".to_string()),
+                ..service.clone()
+            },
+            CustomService {
+                variable: Some("widgets".to_string()),
+                namespace: Some("Widgets:".to_string()),
+                ..service
+            },
+        ];
+        for service in invalid {
+            let custom_services = CustomServices {
+                services: vec![service.clone()],
+            };
+            assert!(
+                custom_services.validate().is_err(),
+                "{service:?} should be invalid"
+            );
+        }
+    }
+
+    #[test]
+    fn test_wrapper_functions_validation() {
+        let wrappers: WrapperFunctions = serde_json::from_str(
+            r#"{"Wrappers": [
+                {"Function": "awsutil.Do", "OperationArgument": 1},
+                {"Function": "call_aws", "Service": "dynamodb"}
+            ]}"#,
+        )
+        .unwrap();
+        assert!(wrappers.validate().is_ok());
+        assert_eq!(wrappers.wrappers[1].operation_argument, 0);
+
+        for (function, service) in [("awsutil.Do(client)", None), ("send", Some("S3"))] {
+            let wrappers = WrapperFunctions {
+                wrappers: vec![WrapperFunction {
+                    function: function.to_string(),
+                    operation_argument: 0,
+                    service: service.map(str::to_string),
+                }],
+            };
+            assert!(wrappers.validate().is_err(), "{function} should be invalid");
+        }
+    }
+
+    #[test]
+    fn test_principal_mappings_validation() {
+        let principal_mappings: PrincipalMappings = serde_json::from_str(
+            r#"{"Principals": [
+                {"Name": "worker-role", "Paths": ["workers", "shared/*.py"]},
+                {"Name": "api-role", "Paths": ["cmd/api"]}
+            ]}"#,
+        )
+        .unwrap();
+        assert!(principal_mappings.validate().is_ok());
+
+        let principal = PrincipalMapping {
+            name: "worker-role".to_string(),
+            paths: vec!["workers".to_string()],
+        };
+        let invalid = [
+            vec![PrincipalMapping {
+                name: "worker role".to_string(),
+                ..principal.clone()
+            }],
+            vec![PrincipalMapping {
+                paths: vec![],
+                ..principal.clone()
+            }],
+            vec![PrincipalMapping {
+                paths: vec!["workers/[a".to_string()],
+                ..principal.clone()
+            }],
+            vec![principal.clone(), principal],
+        ];
+        for principals in invalid {
+            let principal_mappings = PrincipalMappings {
+                principals: principals.clone(),
+            };
+            assert!(
+                principal_mappings.validate().is_err(),
+                "{principals:?} should be invalid"
+            );
+        }
+    }
 }
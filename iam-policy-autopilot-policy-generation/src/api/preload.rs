@@ -0,0 +1,18 @@
+use anyhow::Result;
+use log::info;
+
+use crate::extraction::sdk_model::ServiceDiscovery;
+use crate::service_configuration::load_service_configuration;
+use crate::Language;
+
+/// Load the service configuration and the service definitions of `languages` into the
+/// process-wide caches, so the first analysis of a long-running process doesn't wait for
+/// them
+pub async fn preload_service_data(languages: &[Language]) -> Result<()> {
+    load_service_configuration()?;
+    for &language in languages {
+        info!("Preloading the service definitions of {language}");
+        ServiceDiscovery::load_service_index(language).await?;
+    }
+    Ok(())
+}
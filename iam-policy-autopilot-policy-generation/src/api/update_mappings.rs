@@ -0,0 +1,38 @@
+use crate::api::model::MappingsUpdate;
+use crate::enrichment::service_reference::saved_service_references_dir;
+use crate::enrichment::ServiceReferenceLoader;
+use crate::errors::{ExtractorError, Result};
+
+/// Fetches the latest service reference of every AWS service and saves them to
+/// `~/.iam-policy-autopilot/service-reference`, replacing those saved before.
+///
+/// The operation to action mappings of the saved service references are used instead of
+/// fetching them when generating policies, so operations launched since are mapped to
+/// their actions and policies are generated offline. The SDK models the calls are
+/// extracted with are embedded in the binary and only updated with it.
+///
+/// # Returns
+///
+/// Returns the directory the service references were saved to and their number.
+///
+/// # Errors
+///
+/// Returns an error if the home directory can't be resolved, a service reference can't
+/// be fetched or parsed, or the directory can't be written.
+pub async fn update_mappings() -> Result<MappingsUpdate> {
+    let directory = saved_service_references_dir().ok_or_else(|| {
+        ExtractorError::validation(
+            "Failed to resolve the home directory: neither HOME nor USERPROFILE is set",
+        )
+    })?;
+    let loader = ServiceReferenceLoader::new(true)?;
+    let services = loader.save_service_references(&directory).await?;
+    log::info!(
+        "Saved the service references of {services} services to {}",
+        directory.display()
+    );
+    Ok(MappingsUpdate {
+        directory,
+        services,
+    })
+}
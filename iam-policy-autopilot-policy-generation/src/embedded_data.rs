@@ -6,7 +6,7 @@
 //! while maintaining all essential functionality.
 
 use std::borrow::Cow;
-use std::collections::HashMap;
+use std::collections::{HashMap, HashSet};
 use std::sync::LazyLock;
 
 use crate::api::model::GitSubmoduleMetadata;
@@ -14,6 +14,7 @@ use crate::errors::{ExtractorError, Result};
 use crate::extraction::sdk_model::SdkServiceDefinition;
 use regex::Regex;
 use rust_embed::RustEmbed;
+use serde::Deserialize;
 use serde_json::Value;
 
 /// Embedded Python operation name map
@@ -78,6 +79,31 @@ pub(crate) struct PartitionsDefinition {
     pub(crate) partitions: HashMap<String, Regex>,
 }
 
+/// Endpoints definition. Map of partition ID to the endpoint prefixes of its services.
+#[derive(Clone, Debug, Default, Deserialize)]
+pub(crate) struct EndpointsDefinition {
+    pub(crate) partitions: HashMap<String, HashSet<String>>,
+}
+
+impl EndpointsDefinition {
+    /// Whether the service with this prefix is available in `partition`
+    ///
+    /// IAM service prefixes mostly equal endpoint prefixes (`s3`, `dynamodb`, `sqs`). A
+    /// prefix no partition has an endpoint for (e.g. `cloudwatch`, whose endpoint is
+    /// `monitoring`) can't be looked up and counts as available, as does any service of
+    /// an unknown or wildcard partition.
+    pub(crate) fn is_available(&self, partition: &str, service: &str) -> bool {
+        let Some(services) = self.partitions.get(partition) else {
+            return true;
+        };
+        services.contains(service)
+            || !self
+                .partitions
+                .values()
+                .any(|services| services.contains(service))
+    }
+}
+
 /// Embedded AWS service definitions with compression
 ///
 /// This struct provides access to pre-processed AWS service definitions
@@ -209,6 +235,11 @@ impl BotocoreRaw {
         Self::get("partitions.json").map(|file| file.data)
     }
 
+    /// Get the endpoints definition
+    fn get_endpoints() -> Option<Cow<'static, [u8]>> {
+        Self::get("endpoints.json").map(|file| file.data)
+    }
+
     /// Get a service definition file by service name and API version
     fn get_service_definition(service: &str, api_version: &str) -> Option<Cow<'static, [u8]>> {
         let start_time = std::time::Instant::now();
@@ -382,6 +413,25 @@ impl BotocoreData {
         PARTITIONS.as_ref()
     }
 
+    /// Get the parsed endpoints definition
+    ///
+    /// # Returns
+    /// Services available in each partition, or an empty definition (every service
+    /// available) when the endpoints data isn't embedded
+    pub(crate) fn get_endpoints() -> &'static EndpointsDefinition {
+        static ENDPOINTS: LazyLock<EndpointsDefinition> = LazyLock::new(|| {
+            BotocoreRaw::get_endpoints()
+                .and_then(|data| {
+                    serde_json::from_slice(&data)
+                        .inspect_err(|e| log::warn!("Failed to parse endpoints definition: {e}"))
+                        .ok()
+                })
+                .unwrap_or_default()
+        });
+
+        &ENDPOINTS
+    }
+
     /// Get a parsed service definition by service name and API version
     ///
     /// # Arguments
@@ -486,6 +536,26 @@ impl GitSubmoduleVersionInfo {
 mod tests {
     use super::*;
 
+    #[test]
+    fn test_endpoints_service_availability() {
+        let endpoints = EndpointsDefinition {
+            partitions: HashMap::from([
+                (
+                    "aws".to_string(),
+                    HashSet::from(["s3".to_string(), "bedrock".to_string()]),
+                ),
+                ("aws-cn".to_string(), HashSet::from(["s3".to_string()])),
+            ]),
+        };
+
+        assert!(endpoints.is_available("aws-cn", "s3"));
+        assert!(!endpoints.is_available("aws-cn", "bedrock"));
+        assert!(endpoints.is_available("aws", "bedrock"));
+        // Prefixes without endpoints and unknown partitions can't be looked up
+        assert!(endpoints.is_available("aws-cn", "cloudwatch"));
+        assert!(endpoints.is_available("*", "bedrock"));
+    }
+
     #[test]
     fn test_botocore_get_service_definition_returns_none_for_invalid_service() {
         let result = BotocoreRaw::get_service_definition("nonexistent-service", "2023-01-01");
@@ -0,0 +1,226 @@
+//! Query result and catalog locations of Athena queries
+//!
+//! Athena runs queries with the caller's permissions: it reads the tables from the Glue
+//! Data Catalog, scans their data in S3 and writes the results to the output location.
+//! The operation FAS map grants these actions to `StartQueryExecution` calls; here they're
+//! scoped to the output location and database the call passes as literals, e.g.
+//! `ResultConfiguration={"OutputLocation": "s3://query-results/athena/"}` and
+//! `QueryExecutionContext={"Database": "sales"}`. The data locations of the tables are
+//! only known to the catalog, so their reads keep every bucket.
+
+use crate::enrichment::{EnrichedSdkMethodCall, Operation};
+use crate::extraction::shared::SourceSyntax;
+use crate::SdkType;
+
+/// Actions writing the query results, which are granted on the output location only
+const RESULT_ACTIONS: &[&str] = &[
+    "s3:PutObject",
+    "s3:AbortMultipartUpload",
+    "s3:ListMultipartUploadParts",
+    "s3:ListBucketMultipartUploads",
+];
+
+/// (bucket, key prefix) of the literal S3 output location of a query: the
+/// `OutputLocation` property, field or builder method of its result configuration, e.g.
+/// `"OutputLocation": "s3://bucket/prefix/"` in Python,
+/// `OutputLocation: aws.String("s3://bucket/")` in Go or `.outputLocation("s3://bucket/")`
+/// in Java
+fn output_location(syntax: &SourceSyntax) -> Option<(&str, &str)> {
+    let location = syntax.string_of("OutputLocation")?.strip_prefix("s3://")?;
+    let (bucket, prefix) = location.split_once('/').unwrap_or((location, ""));
+    let is_bucket = !bucket.is_empty()
+        && bucket
+            .chars()
+            .all(|c| c.is_ascii_lowercase() || c.is_ascii_digit() || matches!(c, '.' | '-'));
+    is_bucket.then_some((bucket, prefix))
+}
+
+/// The literal database of the query execution context of a query
+fn database(syntax: &SourceSyntax) -> Option<&str> {
+    syntax.string_of("Database").filter(|database| {
+        !database.is_empty()
+            && database
+                .chars()
+                .all(|c| c.is_alphanumeric() || matches!(c, '_' | '-'))
+    })
+}
+
+/// Scope the result writes and catalog reads of the Athena queries of `enriched_calls` to
+/// the output location and database they name
+///
+/// The locations are only scoped with `call_site_resources`; queries naming none, such as
+/// those of workgroups enforcing their own output location, keep every location.
+pub(crate) fn scope_query_locations(
+    enriched_calls: &mut [EnrichedSdkMethodCall<'_>],
+    sdk: SdkType,
+    call_site_resources: bool,
+) {
+    if !call_site_resources {
+        return;
+    }
+    for call in enriched_calls
+        .iter_mut()
+        .filter(|call| call.service == "athena")
+    {
+        if Operation::operation_name(call.sdk_method_call, sdk) != "StartQueryExecution" {
+            continue;
+        }
+        let Some(syntax) = call
+            .sdk_method_call
+            .metadata
+            .as_ref()
+            .and_then(SourceSyntax::of_call)
+        else {
+            continue;
+        };
+        if let Some((bucket, prefix)) = output_location(&syntax) {
+            let objects = format!("{prefix}*");
+            log::debug!(
+                "Scoping the results of {} to s3://{bucket}",
+                call.method_name
+            );
+            for action in call
+                .actions
+                .iter_mut()
+                .filter(|action| RESULT_ACTIONS.contains(&action.name.as_str()))
+            {
+                action.bind_placeholders(&[("BucketName", bucket), ("ObjectName", &objects)]);
+            }
+        }
+        if let Some(database) = database(&syntax) {
+            for action in call
+                .actions
+                .iter_mut()
+                .filter(|action| action.service() == "glue")
+            {
+                action.bind_placeholders(&[("DatabaseName", database)]);
+            }
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation, Resource};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Location, SdkMethodCall};
+
+    fn action(name: &str, arn_pattern: &str) -> Action {
+        Action::new(
+            name.to_string(),
+            vec![Resource::new(
+                "object".to_string(),
+                Some(vec![arn_pattern.to_string()]),
+            )],
+            vec![],
+            Explanation::default(),
+        )
+    }
+
+    fn arn_patterns(call: &EnrichedSdkMethodCall<'_>) -> Vec<String> {
+        call.actions
+            .iter()
+            .flat_map(|action| action.resources[0].arn_patterns.clone().unwrap_or_default())
+            .collect()
+    }
+
+    #[test]
+    fn test_query_locations_are_scoped_to_literals() {
+        let call = SdkMethodCall {
+            name: "start_query_execution".to_string(),
+            possible_services: vec!["athena".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "athena.start_query_execution(QueryString=sql, \
+                 QueryExecutionContext={'Database': 'sales'}, \
+                 ResultConfiguration={'OutputLocation': 's3://query-results/athena/'})"
+                    .to_string(),
+                Location::new(PathBuf::from("app.py"), (8, 1), (10, 80)),
+            )),
+        };
+        let object = "arn:${Partition}:s3:::${BucketName}/${ObjectName}";
+        let mut calls = vec![EnrichedSdkMethodCall {
+            method_name: call.name.clone(),
+            service: "athena".to_string(),
+            actions: vec![
+                action("s3:PutObject", object),
+                action("s3:GetObject", object),
+                action(
+                    "glue:GetTable",
+                    "arn:${Partition}:glue:${Region}:${Account}:\
+                     table/${DatabaseName}/${TableName}",
+                ),
+            ],
+            sdk_method_call: &call,
+        }];
+
+        scope_query_locations(&mut calls, SdkType::Boto3, true);
+
+        assert_eq!(
+            arn_patterns(&calls[0]),
+            vec![
+                "arn:${Partition}:s3:::query-results/athena/*",
+                object,
+                "arn:${Partition}:glue:${Region}:${Account}:table/sales/${TableName}",
+            ]
+        );
+    }
+
+    fn syntax(path: &str, expression: &str) -> SourceSyntax {
+        SourceSyntax::of_call(&SdkMethodCallMetadata::new(
+            expression.to_string(),
+            Location::new(PathBuf::from(path), (1, 1), (1, 1)),
+        ))
+        .unwrap()
+    }
+
+    #[test]
+    fn test_output_location_forms() {
+        for (path, expression) in [
+            (
+                "main.go",
+                "client.StartQueryExecution(ctx, &athena.StartQueryExecutionInput{\
+                 ResultConfiguration: &types.ResultConfiguration{\
+                 OutputLocation: aws.String(\"s3://results/\")}})",
+            ),
+            (
+                "main.go",
+                "client.StartQueryExecution(ctx, &athena.StartQueryExecutionInput{\
+                 ResultConfiguration: &types.ResultConfiguration{\
+                 OutputLocation: `s3://results/`}})",
+            ),
+            (
+                "Queries.java",
+                "athena.startQueryExecution(r -> r.queryString(sql)\
+                 .resultConfiguration(c -> c.outputLocation(\"s3://results/\")))",
+            ),
+            (
+                "queries.js",
+                "client.send(new StartQueryExecutionCommand({ QueryString: sql, \
+                 ResultConfiguration: { OutputLocation: 's3://results/' } }))",
+            ),
+        ] {
+            assert_eq!(
+                output_location(&syntax(path, expression)),
+                Some(("results", "")),
+                "{expression}"
+            );
+        }
+    }
+
+    #[test]
+    fn test_locations_in_comments_and_other_arguments_are_ignored() {
+        let syntax = syntax(
+            "app.py",
+            "athena.start_query_execution(\n\
+             \x20   # ResultConfiguration={'OutputLocation': 's3://old-results/'}\n\
+             \x20   QueryString=\"SELECT * FROM t WHERE path = 's3://data/'\",\n\
+             \x20   WorkGroup='Database')",
+        );
+
+        assert_eq!(output_location(&syntax), None);
+        assert_eq!(database(&syntax), None);
+    }
+}
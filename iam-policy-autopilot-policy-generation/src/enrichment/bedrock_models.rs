@@ -0,0 +1,171 @@
+//! Models invoked through Amazon Bedrock
+//!
+//! `InvokeModel`, `InvokeModelWithResponseStream`, `Converse` and `ConverseStream` are
+//! authorized on the model they invoke. A foundation model ID like
+//! `anthropic.claude-3-haiku-20240307-v1:0` names the foundation model in the Region of
+//! the call, while a prefixed ID like `us.anthropic.claude-3-haiku-20240307-v1:0` names a
+//! cross-Region inference profile, which routes requests to the foundation model in any
+//! Region of its geography: invoking it requires both the profile and the model in every
+//! Region. Model ARNs are used as written.
+
+use crate::enrichment::{EnrichedSdkMethodCall, Resource};
+
+/// Placeholder of the model ID, bound from the call site
+const MODEL_PLACEHOLDER: &str = "ModelId";
+
+/// Actions authorized on the invoked model
+const MODEL_ACTIONS: &[&str] = &[
+    "bedrock:InvokeModel",
+    "bedrock:InvokeModelWithResponseStream",
+];
+
+/// Prefixes of the IDs of cross-Region inference profiles, by geography
+const INFERENCE_PROFILE_PREFIXES: &[&str] =
+    &["us", "us-gov", "eu", "apac", "jp", "au", "ca", "global"];
+
+/// ARN of a foundation model, without Region
+const FOUNDATION_MODEL_ARN: &str = "arn:${Partition}:bedrock:";
+
+/// ARN of an inference profile of the account
+const INFERENCE_PROFILE_ARN: &str =
+    "arn:${Partition}:bedrock:${Region}:${Account}:inference-profile/";
+
+/// The resources invoking the model `model_id` is authorized on
+fn model_resources(model_id: &str) -> Vec<Resource> {
+    if model_id.starts_with("arn:") {
+        let resource_type = model_id
+            .splitn(6, ':')
+            .nth(5)
+            .and_then(|resource| resource.split('/').next())
+            .unwrap_or("foundation-model");
+        return vec![Resource::new(
+            resource_type.to_string(),
+            Some(vec![model_id.to_string()]),
+        )];
+    }
+    match model_id.split_once('.') {
+        Some((prefix, foundation_model)) if INFERENCE_PROFILE_PREFIXES.contains(&prefix) => vec![
+            Resource::new(
+                "inference-profile".to_string(),
+                Some(vec![format!("{INFERENCE_PROFILE_ARN}{model_id}")]),
+            ),
+            Resource::new(
+                "foundation-model".to_string(),
+                Some(vec![format!(
+                    "{FOUNDATION_MODEL_ARN}*::foundation-model/{foundation_model}"
+                )]),
+            ),
+        ],
+        _ => vec![Resource::new(
+            "foundation-model".to_string(),
+            Some(vec![format!(
+                "{FOUNDATION_MODEL_ARN}${{Region}}::foundation-model/{model_id}"
+            )]),
+        )],
+    }
+}
+
+/// Scope the Bedrock model invocations of `enriched_calls` to the models they name
+///
+/// The models are bound from the `modelId` the calls pass, so they're only scoped with
+/// `call_site_resources`; other invocations keep every model.
+pub(crate) fn scope_model_invocations(
+    enriched_calls: &mut [EnrichedSdkMethodCall<'_>],
+    call_site_resources: bool,
+) {
+    if !call_site_resources {
+        return;
+    }
+    for call in enriched_calls {
+        let Some(model_id) = call
+            .sdk_method_call
+            .metadata
+            .as_ref()
+            .and_then(|metadata| metadata.resource_bindings.get(MODEL_PLACEHOLDER))
+        else {
+            continue;
+        };
+        for action in call
+            .actions
+            .iter_mut()
+            .filter(|action| MODEL_ACTIONS.contains(&action.name.as_str()))
+        {
+            log::debug!(
+                "Scoping {} of {} to {model_id}",
+                action.name,
+                call.method_name
+            );
+            action.resources = model_resources(model_id);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::collections::BTreeMap;
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Location, SdkMethodCall};
+
+    fn call(model_id: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "converse".to_string(),
+            possible_services: vec!["bedrock-runtime".to_string()],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    format!("bedrock.converse(modelId=\"{model_id}\")"),
+                    Location::new(PathBuf::from("app.py"), (4, 1), (4, 60)),
+                )
+                .with_resource_bindings(BTreeMap::from([(
+                    MODEL_PLACEHOLDER.to_string(),
+                    model_id.to_string(),
+                )])),
+            ),
+        }
+    }
+
+    fn scoped(model_id: &str) -> Vec<String> {
+        let call = call(model_id);
+        let mut calls = vec![EnrichedSdkMethodCall {
+            method_name: call.name.clone(),
+            service: "bedrock-runtime".to_string(),
+            actions: vec![Action::new(
+                "bedrock:InvokeModel".to_string(),
+                vec![Resource::new("foundation-model".to_string(), None)],
+                vec![],
+                Explanation::default(),
+            )],
+            sdk_method_call: &call,
+        }];
+
+        scope_model_invocations(&mut calls, true);
+
+        calls[0].actions[0]
+            .resources
+            .iter()
+            .flat_map(|resource| resource.arn_patterns.clone().unwrap_or_default())
+            .collect()
+    }
+
+    #[test]
+    fn test_model_invocations_are_scoped_to_the_model() {
+        assert_eq!(
+            scoped("amazon.nova-lite-v1:0"),
+            vec!["arn:${Partition}:bedrock:${Region}::foundation-model/amazon.nova-lite-v1:0"]
+        );
+        assert_eq!(
+            scoped("us.anthropic.claude-3-haiku-20240307-v1:0"),
+            vec![
+                "arn:${Partition}:bedrock:${Region}:${Account}:inference-profile/\
+                 us.anthropic.claude-3-haiku-20240307-v1:0",
+                "arn:${Partition}:bedrock:*::foundation-model/\
+                 anthropic.claude-3-haiku-20240307-v1:0",
+            ]
+        );
+        let provisioned = "arn:aws:bedrock:us-east-1:123456789012:provisioned-model/abc123";
+        assert_eq!(scoped(provisioned), vec![provisioned]);
+    }
+}
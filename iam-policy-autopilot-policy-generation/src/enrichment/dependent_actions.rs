@@ -0,0 +1,252 @@
+//! Actions AWS authorizes alongside an operation, depending on its input
+//!
+//! Some operations are only authorized together with other actions when they're called
+//! with certain input members: creating a Lambda function with a `Role` passes the role
+//! to Lambda, which takes `iam:PassRole`, and launching instances with
+//! `TagSpecifications` tags them, which takes `ec2:CreateTags`. Neither the Service
+//! Reference nor the FAS maps list these, as they don't apply to every call. Each
+//! dependent action is added with a note of the member that requires it.
+//!
+//! Encryption keys used on behalf of the caller, such as `kms:GenerateDataKey` for
+//! sending to an encrypted SQS queue, are covered by the FAS maps instead.
+
+use std::sync::{Arc, OnceLock};
+
+use regex::Regex;
+
+use super::{
+    Action, Condition, Explanation, Operation, OperationSource, Operator, Reason, Resource,
+};
+use crate::SdkMethodCall;
+
+/// A dependent action of an operation called with one of `members`
+struct DependentAction {
+    /// Service and operation requiring the action, e.g. `lambda` and `CreateFunction`
+    service: &'static str,
+    operations: &'static [&'static str],
+    /// Input members requiring the action, one of which the call has to set
+    members: &'static [&'static str],
+    /// Service and name of the dependent action
+    action_service: &'static str,
+    action: &'static str,
+    /// Resource type and ARN of the resources of the dependent action
+    resource: &'static str,
+    arn_pattern: &'static str,
+    /// Condition key narrowing the dependent action and its value, in which
+    /// `{operation}` stands for the operation name
+    condition: Option<(&'static str, &'static str)>,
+    /// Why the action is required
+    note: &'static str,
+}
+
+/// Role ARN of passed roles not named by a literal
+const ROLE_ARN_PATTERN: &str = "arn:${Partition}:iam::${Account}:role/${RoleNameWithPath}";
+
+/// ARN of EC2 resources tagged on creation
+const EC2_RESOURCE_ARN_PATTERN: &str = "arn:${Partition}:ec2:${Region}:${Account}:*/*";
+
+const DEPENDENT_ACTIONS: &[DependentAction] = &[
+    DependentAction {
+        service: "lambda",
+        operations: &["CreateFunction", "UpdateFunctionConfiguration"],
+        members: &["Role"],
+        action_service: "iam",
+        action: "PassRole",
+        resource: "role",
+        arn_pattern: ROLE_ARN_PATTERN,
+        condition: Some(("iam:PassedToService", "lambda.amazonaws.com")),
+        note: "Passing the execution role in Role to Lambda requires iam:PassRole",
+    },
+    DependentAction {
+        service: "ecs",
+        operations: &["RegisterTaskDefinition", "RunTask", "StartTask"],
+        members: &["taskRoleArn", "executionRoleArn"],
+        action_service: "iam",
+        action: "PassRole",
+        resource: "role",
+        arn_pattern: ROLE_ARN_PATTERN,
+        condition: Some(("iam:PassedToService", "ecs-tasks.amazonaws.com")),
+        note: "Passing the task or execution role in taskRoleArn or executionRoleArn to ECS \
+               requires iam:PassRole",
+    },
+    DependentAction {
+        service: "states",
+        operations: &["CreateStateMachine", "UpdateStateMachine"],
+        members: &["roleArn"],
+        action_service: "iam",
+        action: "PassRole",
+        resource: "role",
+        arn_pattern: ROLE_ARN_PATTERN,
+        condition: Some(("iam:PassedToService", "states.amazonaws.com")),
+        note: "Passing the state machine role in roleArn to Step Functions requires \
+               iam:PassRole",
+    },
+    DependentAction {
+        service: "ec2",
+        operations: &[
+            "RunInstances",
+            "CreateVolume",
+            "CreateSnapshot",
+            "CreateSecurityGroup",
+            "CreateLaunchTemplate",
+        ],
+        members: &["TagSpecifications"],
+        action_service: "ec2",
+        action: "CreateTags",
+        resource: "*",
+        arn_pattern: EC2_RESOURCE_ARN_PATTERN,
+        condition: Some(("ec2:CreateAction", "{operation}")),
+        note: "Tagging the created resources with TagSpecifications requires ec2:CreateTags",
+    },
+];
+
+/// Regex matching a literal role ARN, to pass that role only
+static ROLE_ARN_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_role_arn_regex() -> &'static Regex {
+    ROLE_ARN_REGEX.get_or_init(|| {
+        Regex::new(r"arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+").expect("Invalid role ARN regex")
+    })
+}
+
+/// Dependent actions of `operation`, the operation `call` was resolved to
+///
+/// An input member is set if the call assigns it, in any language and nesting: `Role=`
+/// (Python), `Role:` (Go, JavaScript), `"taskRoleArn":` (dictionaries), `.role(` or
+/// `.withRole(` (Java). Roles named by a literal ARN in the call are passed by that ARN
+/// only.
+pub(crate) fn dependent_actions(operation: &Arc<Operation>, call: &SdkMethodCall) -> Vec<Action> {
+    let Some(metadata) = &call.metadata else {
+        return Vec::new();
+    };
+    let mut actions = Vec::new();
+    for dependent in DEPENDENT_ACTIONS.iter().filter(|dependent| {
+        dependent.service == operation.service
+            && dependent.operations.contains(&operation.name.as_str())
+    }) {
+        if !dependent
+            .members
+            .iter()
+            .any(|member| sets_member(&metadata.expr, member))
+        {
+            continue;
+        }
+        let role_arns: Vec<String> = if dependent.arn_pattern == ROLE_ARN_PATTERN {
+            get_role_arn_regex()
+                .find_iter(&metadata.expr)
+                .map(|arn| arn.as_str().to_string())
+                .collect()
+        } else {
+            Vec::new()
+        };
+        let arn_patterns = if role_arns.is_empty() {
+            vec![dependent.arn_pattern.to_string()]
+        } else {
+            role_arns
+        };
+        let conditions = dependent
+            .condition
+            .iter()
+            .map(|(key, value)| Condition {
+                operator: Operator::StringEquals,
+                key: (*key).to_string(),
+                values: vec![value.replace("{operation}", &operation.name)],
+            })
+            .collect();
+        let dependent_operation = Arc::new(Operation {
+            service: dependent.action_service.to_string(),
+            name: dependent.action.to_string(),
+            source: OperationSource::Dependent(dependent.note.to_string()),
+            _private: (),
+        });
+        actions.push(Action::new(
+            format!("{}:{}", dependent.action_service, dependent.action),
+            vec![Resource::new(
+                dependent.resource.to_string(),
+                Some(arn_patterns),
+            )],
+            conditions,
+            Explanation {
+                reasons: vec![Reason::new(vec![
+                    Arc::clone(operation),
+                    dependent_operation,
+                ])],
+            },
+        ));
+    }
+    actions
+}
+
+/// Whether the call expression `expr` assigns `member`
+fn sets_member(expr: &str, member: &str) -> bool {
+    let pattern = format!(r#"(?i)(?:\b|\.with){}["']?\s*[:=(]"#, regex::escape(member));
+    Regex::new(&pattern).is_ok_and(|regex| regex.is_match(expr))
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::Location;
+
+    fn call(expr: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "operation".to_string(),
+            possible_services: vec![],
+            metadata: Some(SdkMethodCallMetadata::new(
+                expr.to_string(),
+                Location::new(PathBuf::from("app.py"), (1, 1), (1, 10)),
+            )),
+        }
+    }
+
+    fn operation(service: &str, name: &str) -> Arc<Operation> {
+        Arc::new(Operation::new(
+            service.to_string(),
+            name.to_string(),
+            OperationSource::Provided,
+        ))
+    }
+
+    #[test]
+    fn test_dependent_actions_of_input_members() {
+        let create_function = operation("lambda", "CreateFunction");
+        let actions = dependent_actions(
+            &create_function,
+            &call(
+                "lambda_client.create_function(FunctionName=\"RoleRotator\", \
+                 Role=\"arn:aws:iam::123456789012:role/rotator\")",
+            ),
+        );
+        assert_eq!(actions.len(), 1);
+        assert_eq!(actions[0].name, "iam:PassRole");
+        assert_eq!(
+            actions[0].resources[0].arn_patterns,
+            Some(vec!["arn:aws:iam::123456789012:role/rotator".to_string()])
+        );
+        assert_eq!(
+            actions[0].conditions[0].values,
+            vec!["lambda.amazonaws.com"]
+        );
+        assert!(matches!(
+            actions[0].explanation.reasons[0].operations[1].source,
+            OperationSource::Dependent(_)
+        ));
+
+        // The function name mentions a role, but no role is passed
+        assert!(dependent_actions(
+            &create_function,
+            &call("lambda_client.create_function(FunctionName=\"RoleRotator\")"),
+        )
+        .is_empty());
+
+        let run_instances = dependent_actions(
+            &operation("ec2", "RunInstances"),
+            &call("client.RunInstances(ctx, &ec2.RunInstancesInput{TagSpecifications: tags})"),
+        );
+        assert_eq!(run_instances[0].name, "ec2:CreateTags");
+        assert_eq!(run_instances[0].conditions[0].values, vec!["RunInstances"]);
+    }
+}
@@ -0,0 +1,126 @@
+//! Calls of deprecated and renamed operations
+//!
+//! Some operations the SDKs still have are deprecated, like S3's `GetBucketLifecycle`,
+//! or stopped working the way they used to, like setting S3 ACLs on buckets that disable
+//! them. The Service Reference may not know renamed ones under their old name, so their
+//! calls would be granted nothing; they're granted the actions of the operation replacing
+//! them instead. The calls are reported with a note on how to migrate them.
+
+use serde::Serialize;
+
+use crate::enrichment::Operation;
+use crate::errors::Result;
+use crate::service_configuration::load_service_configuration;
+use crate::{Location, SdkMethodCall, SdkType};
+
+/// A call of a deprecated or renamed operation
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct DeprecatedCall {
+    /// Service of the operation, e.g. `s3`
+    pub service: String,
+    /// Deprecated operation, e.g. `GetBucketLifecycle`
+    pub operation: String,
+    /// Operation whose actions the call is granted, if the operation was renamed, e.g.
+    /// `GetBucketLifecycleConfiguration`
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub current_operation: Option<String>,
+    /// How to migrate the call
+    pub note: String,
+    /// Source location of the call
+    pub location: Location,
+    /// Expression of the call
+    pub expression: String,
+}
+
+/// The calls of `methods` made to deprecated or renamed operations, by location
+///
+/// # Errors
+/// Returns an error if the service configuration can't be loaded
+pub(crate) fn deprecated_calls(
+    methods: &[SdkMethodCall],
+    sdk: SdkType,
+) -> Result<Vec<DeprecatedCall>> {
+    let service_cfg = load_service_configuration()?;
+    let mut calls = Vec::new();
+    for method in methods {
+        let Some(metadata) = &method.metadata else {
+            continue;
+        };
+        let operation = Operation::operation_name(method, sdk);
+        for service in &method.possible_services {
+            if let Some(deprecated) = service_cfg.deprecated_operation(service, &operation) {
+                calls.push(DeprecatedCall {
+                    service: service.clone(),
+                    operation: operation.clone(),
+                    current_operation: deprecated.operation.clone(),
+                    note: deprecated.note.clone(),
+                    location: metadata.location.clone(),
+                    expression: metadata.expr.clone(),
+                });
+            }
+        }
+    }
+    calls.sort_by(|a, b| {
+        (&a.location, &a.service, &a.operation).cmp(&(&b.location, &b.service, &b.operation))
+    });
+    Ok(calls)
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+
+    fn call(name: &str, services: &[&str], line: usize) -> SdkMethodCall {
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: services.iter().map(ToString::to_string).collect(),
+            metadata: Some(SdkMethodCallMetadata::new(
+                format!("client.{name}()"),
+                Location::new(PathBuf::from("app.py"), (line, 1), (line, 30)),
+            )),
+        }
+    }
+
+    #[test]
+    fn test_deprecated_calls() {
+        let methods = vec![
+            call("put_object_acl", &["s3"], 7),
+            call("get_bucket_lifecycle", &["s3"], 3),
+            call("get_object", &["s3"], 5),
+        ];
+
+        let calls = deprecated_calls(&methods, SdkType::Boto3).unwrap();
+
+        let operations: Vec<(&str, Option<&str>)> = calls
+            .iter()
+            .map(|call| (call.operation.as_str(), call.current_operation.as_deref()))
+            .collect();
+        assert_eq!(
+            operations,
+            vec![
+                (
+                    "GetBucketLifecycle",
+                    Some("GetBucketLifecycleConfiguration")
+                ),
+                ("PutObjectAcl", None),
+            ]
+        );
+        assert!(calls[1].note.contains("AccessControlListNotSupported"));
+    }
+
+    #[tokio::test]
+    async fn test_renamed_operations_are_granted_the_current_actions() {
+        let service_cfg = load_service_configuration().unwrap();
+        let method = call("invoke_async", &["lambda"], 1);
+
+        let operation = Operation::from_call(&method, "lambda", &service_cfg, SdkType::Boto3)
+            .await
+            .unwrap();
+
+        assert_eq!(operation.service_operation_name(), "lambda:Invoke");
+    }
+}
@@ -5,9 +5,11 @@
 //! with resource matching.
 
 use std::collections::{HashMap, HashSet};
+use std::path::PathBuf;
 use std::sync::Arc;
 
 use super::EnrichedSdkMethodCall;
+use crate::api::model::MappingOverrides;
 use crate::enrichment::operation_fas_map::OperationFasMaps;
 use crate::enrichment::{load_operation_fas_map, ResourceMatcher, ServiceReferenceLoader};
 use crate::errors::{ExtractorError, Result};
@@ -25,6 +27,8 @@ pub struct Engine {
     service_reference_loader: ServiceReferenceLoader,
     /// Resource-list cutoff passed to the resource matcher.
     resource_cutoff: usize,
+    /// Whether resources are scoped to identifiers known from the call site.
+    call_site_resources: bool,
 }
 
 impl Engine {
@@ -36,9 +40,51 @@ impl Engine {
         Ok(Self {
             service_reference_loader: ServiceReferenceLoader::new(disable_file_system_cache)?,
             resource_cutoff,
+            call_site_resources: true,
         })
     }
 
+    /// Set whether resources are scoped to identifiers known from the call site.
+    ///
+    /// Enabled by default: a bucket name passed as a string literal scopes the
+    /// statement to that bucket. Disabled, resource ARNs stay wildcarded.
+    #[must_use]
+    pub fn with_call_site_resources(mut self, call_site_resources: bool) -> Self {
+        self.call_site_resources = call_site_resources;
+        self
+    }
+
+    /// Merge `mapping_overrides` over the operation to action mappings of the service
+    /// references, adding or correcting entries, e.g. for preview or internal services.
+    #[must_use]
+    pub fn with_mapping_overrides(mut self, mapping_overrides: MappingOverrides) -> Self {
+        self.service_reference_loader = self
+            .service_reference_loader
+            .with_mapping_overrides(mapping_overrides);
+        self
+    }
+
+    /// Pin the service references to those of the lockfile at `path`, so policies are
+    /// generated with the same mappings across machines and over time.
+    ///
+    /// Services the lockfile doesn't pin yet are pinned to their latest service reference
+    /// when [`Self::write_service_reference_lock`] is called.
+    ///
+    /// # Errors
+    /// Returns an error if the lockfile exists but can't be read or parsed.
+    pub fn with_service_reference_lock(mut self, path: PathBuf) -> Result<Self> {
+        self.service_reference_loader = self.service_reference_loader.with_lockfile(path)?;
+        Ok(self)
+    }
+
+    /// Write the service reference lockfile, if any, when services were pinned.
+    ///
+    /// # Errors
+    /// Returns an error if the lockfile can't be written.
+    pub async fn write_service_reference_lock(&self) -> Result<()> {
+        self.service_reference_loader.write_lockfile().await
+    }
+
     /// Returns a shared reference to the underlying service-reference loader,
     /// so other subsystems (e.g. Terraform resource binding) can reuse the
     /// same HTTP client and cache instead of creating their own.
@@ -71,7 +117,8 @@ impl Engine {
             .await?;
 
         let resource_matcher =
-            ResourceMatcher::new(service_cfg, fas_maps, sdk, self.resource_cutoff);
+            ResourceMatcher::new(service_cfg, fas_maps, sdk, self.resource_cutoff)
+                .with_call_site_resources(self.call_site_resources);
         let enriched_calls = self
             .enrich_all_methods(extracted_methods, &resource_matcher)
             .await?;
@@ -0,0 +1,307 @@
+//! Permissions of the event sources of Lambda handlers
+//!
+//! Lambda reads the records of queues and streams with the function's execution role
+//! before invoking it, so a handler of SQS, Kinesis or DynamoDB stream events needs the
+//! permissions of its event source mapping without making any SDK call. The event
+//! sources are recognized by the event types the code names in type annotations, imports
+//! and decorators, such as `events.SQSEvent` in Go, `SQSEvent` in Java and TypeScript, or
+//! the Powertools data classes in Python, e.g. `@event_source(data_class=SQSEvent)`. Names
+//! in comments and strings aren't uses. Each source found becomes an enriched call of its
+//! own, pointing at the first use of its event type.
+//!
+//! S3 events need no permission of the execution role: S3 invokes the function through
+//! its resource policy.
+
+use std::sync::{Arc, OnceLock};
+
+use regex::Regex;
+
+use super::{
+    Action, EnrichedSdkMethodCall, Explanation, Operation, OperationSource, Reason, Resource,
+};
+use crate::extraction::shared::SourceSyntax;
+use crate::extraction::{SdkMethodCallMetadata, SourceFile};
+use crate::SdkMethodCall;
+
+/// Regex matching the names of the SQS event types: `SQSEvent`, `SQSHandler`
+static SQS_EVENT_REGEX: OnceLock<Regex> = OnceLock::new();
+
+/// Regex matching the names of the Kinesis event types: `KinesisEvent`, `KinesisStreamEvent`,
+/// `KinesisStreamHandler`
+static KINESIS_EVENT_REGEX: OnceLock<Regex> = OnceLock::new();
+
+/// Regex matching the names of the DynamoDB stream event types: `DynamoDBEvent`, `DynamodbEvent`,
+/// `DynamoDBStreamEvent`, `DynamoDBStreamHandler`
+static DYNAMODB_STREAM_EVENT_REGEX: OnceLock<Regex> = OnceLock::new();
+
+/// ARN of an SQS queue
+const QUEUE_ARN: &str = "arn:${Partition}:sqs:${Region}:${Account}:${QueueName}";
+
+/// ARN of a Kinesis stream
+const STREAM_ARN: &str = "arn:${Partition}:kinesis:${Region}:${Account}:stream/${StreamName}";
+
+/// ARN of the stream of a DynamoDB table
+const TABLE_STREAM_ARN: &str =
+    "arn:${Partition}:dynamodb:${Region}:${Account}:table/${TableName}/stream/${StreamLabel}";
+
+/// Source of the events of a Lambda handler
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub(crate) enum EventSource {
+    /// Messages received from an SQS queue
+    Sqs,
+    /// Records read from a Kinesis data stream
+    Kinesis,
+    /// Records read from the stream of a DynamoDB table
+    DynamoDbStream,
+}
+
+impl EventSource {
+    const ALL: [Self; 3] = [Self::Sqs, Self::Kinesis, Self::DynamoDbStream];
+
+    fn event_regex(self) -> &'static Regex {
+        let (regex, pattern) = match self {
+            Self::Sqs => (&SQS_EVENT_REGEX, r"^SQS(?:Event|Handler)$"),
+            Self::Kinesis => (
+                &KINESIS_EVENT_REGEX,
+                r"^Kinesis(?:Stream)?(?:Event|Handler)$",
+            ),
+            Self::DynamoDbStream => (
+                &DYNAMODB_STREAM_EVENT_REGEX,
+                r"^Dynamo(?:DB|db)(?:Stream)?(?:Event|Handler)$",
+            ),
+        };
+        regex.get_or_init(|| Regex::new(pattern).expect("Invalid event type regex"))
+    }
+
+    const fn service(self) -> &'static str {
+        match self {
+            Self::Sqs => "sqs",
+            Self::Kinesis => "kinesis",
+            Self::DynamoDbStream => "dynamodb",
+        }
+    }
+
+    /// The actions the execution role needs to read the events, with their resource type
+    /// and ARN format
+    const fn actions(self) -> &'static [(&'static str, &'static str, Option<&'static str>)] {
+        match self {
+            Self::Sqs => &[
+                ("sqs:ReceiveMessage", "queue", Some(QUEUE_ARN)),
+                ("sqs:DeleteMessage", "queue", Some(QUEUE_ARN)),
+                ("sqs:GetQueueAttributes", "queue", Some(QUEUE_ARN)),
+            ],
+            Self::Kinesis => &[
+                ("kinesis:DescribeStream", "stream", Some(STREAM_ARN)),
+                ("kinesis:DescribeStreamSummary", "stream", Some(STREAM_ARN)),
+                ("kinesis:GetRecords", "stream", Some(STREAM_ARN)),
+                ("kinesis:GetShardIterator", "stream", Some(STREAM_ARN)),
+                ("kinesis:ListShards", "stream", Some(STREAM_ARN)),
+                ("kinesis:ListStreams", "*", None),
+            ],
+            Self::DynamoDbStream => &[
+                ("dynamodb:DescribeStream", "stream", Some(TABLE_STREAM_ARN)),
+                ("dynamodb:GetRecords", "stream", Some(TABLE_STREAM_ARN)),
+                (
+                    "dynamodb:GetShardIterator",
+                    "stream",
+                    Some(TABLE_STREAM_ARN),
+                ),
+                ("dynamodb:ListStreams", "*", None),
+            ],
+        }
+    }
+}
+
+/// Event source of the Lambda handlers of the analyzed code
+#[derive(Debug, Clone)]
+pub(crate) struct HandlerEventSource {
+    pub(crate) source: EventSource,
+    /// The first use of the event type, standing for the call needing the permissions
+    pub(crate) call: SdkMethodCall,
+}
+
+/// The event sources of the handlers of `source_files`, one per source, in the order of
+/// [`EventSource::ALL`]
+pub(crate) fn detect_event_sources(source_files: &[SourceFile]) -> Vec<HandlerEventSource> {
+    let syntax: Vec<SourceSyntax> = source_files.iter().map(SourceSyntax::of).collect();
+    EventSource::ALL
+        .into_iter()
+        .filter_map(|source| {
+            let regex = source.event_regex();
+            let identifier = syntax
+                .iter()
+                .flat_map(|syntax| &syntax.identifiers)
+                .find(|identifier| regex.is_match(&identifier.name))?;
+            log::debug!(
+                "Found {source:?} events in {}",
+                identifier.location.to_gnu_format()
+            );
+            Some(HandlerEventSource {
+                source,
+                call: SdkMethodCall {
+                    name: format!("{source:?}EventSource"),
+                    possible_services: vec![source.service().to_string()],
+                    metadata: Some(SdkMethodCallMetadata::new(
+                        identifier.name.clone(),
+                        identifier.location.clone(),
+                    )),
+                },
+            })
+        })
+        .collect()
+}
+
+/// Enriched calls granting the permissions of `event_sources`
+pub(crate) fn enrich_event_sources(
+    event_sources: &[HandlerEventSource],
+) -> Vec<EnrichedSdkMethodCall<'_>> {
+    event_sources
+        .iter()
+        .filter_map(|event_source| {
+            let call = &event_source.call;
+            let metadata = call.metadata.as_ref()?;
+            let service = event_source.source.service().to_string();
+            let operation = Arc::new(Operation {
+                service: service.clone(),
+                name: call.name.clone(),
+                source: OperationSource::Extracted(metadata.clone()),
+                _private: (),
+            });
+            let actions = event_source
+                .source
+                .actions()
+                .iter()
+                .map(|(action, resource, arn_format)| {
+                    Action::new(
+                        (*action).to_string(),
+                        vec![Resource::new(
+                            (*resource).to_string(),
+                            arn_format.map(|arn_format| vec![arn_format.to_string()]),
+                        )],
+                        vec![],
+                        Explanation {
+                            reasons: vec![Reason::new(vec![Arc::clone(&operation)])],
+                        },
+                    )
+                })
+                .collect();
+            Some(EnrichedSdkMethodCall {
+                method_name: call.name.clone(),
+                service,
+                actions,
+                sdk_method_call: call,
+            })
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::Language;
+
+    fn source_file(path: &str, content: &str, language: Language) -> SourceFile {
+        SourceFile::with_language(PathBuf::from(path), content.to_string(), language)
+    }
+
+    #[test]
+    fn test_detect_event_sources() {
+        let source_files = vec![
+            source_file(
+                "main.go",
+                "package main\n\nfunc handle(ctx context.Context, event events.SQSEvent) error {\n",
+                Language::Go,
+            ),
+            source_file(
+                "handler.ts",
+                "import { DynamoDBStreamHandler } from 'aws-lambda';\n",
+                Language::TypeScript,
+            ),
+            source_file("app.py", "import boto3\n", Language::Python),
+        ];
+
+        let event_sources = detect_event_sources(&source_files);
+
+        let sources: Vec<EventSource> = event_sources.iter().map(|found| found.source).collect();
+        assert_eq!(sources, vec![EventSource::Sqs, EventSource::DynamoDbStream]);
+        let location = &event_sources[0].call.metadata.as_ref().unwrap().location;
+        assert_eq!(location.file_path, PathBuf::from("main.go"));
+        assert_eq!(location.start_line(), 3);
+    }
+
+    #[test]
+    fn test_enrich_event_sources() {
+        let source_files = vec![source_file(
+            "Handler.java",
+            "public class Handler implements RequestHandler<KinesisEvent, Void> {\n",
+            Language::Java,
+        )];
+        let event_sources = detect_event_sources(&source_files);
+
+        let enriched = enrich_event_sources(&event_sources);
+
+        assert_eq!(enriched.len(), 1);
+        assert_eq!(enriched[0].service, "kinesis");
+        let actions: Vec<&str> = enriched[0]
+            .actions
+            .iter()
+            .map(|action| action.name.as_str())
+            .collect();
+        assert!(actions.contains(&"kinesis:GetRecords"));
+        assert!(actions.contains(&"kinesis:GetShardIterator"));
+        assert_eq!(
+            enriched[0].actions[0].resources[0].arn_patterns,
+            Some(vec![STREAM_ARN.to_string()])
+        );
+    }
+
+    #[test]
+    fn test_event_types_in_comments_and_strings_are_ignored() {
+        let source_files = vec![
+            source_file(
+                "main.go",
+                "package main\n\n// func handle(ctx context.Context, event events.SQSEvent) {\n\
+                 func handle(ctx context.Context, event map[string]any) error {\n\
+                 \tlog.Print(\"skipping KinesisEvent records\")\n\treturn nil\n}\n",
+                Language::Go,
+            ),
+            source_file(
+                "app.py",
+                "\"\"\"Handler of DynamoDBStreamEvent records, once streams are enabled\"\"\"\n\
+                 # from aws_lambda_powertools.utilities.data_classes import SQSEvent\n\
+                 def handler(event, context):\n    return {'type': 'SQSEvent'}\n",
+                Language::Python,
+            ),
+            source_file(
+                "Handler.java",
+                "/* implements RequestHandler<KinesisEvent, Void> */\n\
+                 public class Handler implements RequestHandler<Map<String, Object>, Void> {\n}\n",
+                Language::Java,
+            ),
+        ];
+
+        assert!(detect_event_sources(&source_files).is_empty());
+    }
+
+    #[test]
+    fn test_python_data_classes() {
+        let source_files = vec![source_file(
+            "app.py",
+            "# Reads SQS messages\n\
+             from aws_lambda_powertools.utilities.data_classes import event_source\n\
+             from aws_lambda_powertools.utilities.data_classes import KinesisStreamEvent\n\n\
+             @event_source(data_class=KinesisStreamEvent)\n\
+             def handler(event, context):\n    pass\n",
+            Language::Python,
+        )];
+
+        let event_sources = detect_event_sources(&source_files);
+
+        assert_eq!(event_sources.len(), 1);
+        assert_eq!(event_sources[0].source, EventSource::Kinesis);
+        let location = &event_sources[0].call.metadata.as_ref().unwrap().location;
+        assert_eq!(location.start_line(), 3);
+    }
+}
@@ -0,0 +1,155 @@
+//! Actions of services the generated policies leave out
+//!
+//! Platform teams often grant some services to every workload through a base policy, e.g.
+//! `sts` or `logs`, or own them outright, so a team's policies shouldn't grant them again.
+//! Excluded services are dropped from the actions of the calls, including actions other
+//! services take on the caller's behalf, and the calls are reported with what was left
+//! out, so reviewers can still tell the code uses them.
+
+use serde::Serialize;
+
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::Location;
+
+/// A call whose actions in excluded services were left out of the policies
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct ExcludedServiceCall {
+    /// SDK method called, e.g. `get_caller_identity`
+    pub operation: String,
+    /// Actions left out, e.g. `["sts:GetCallerIdentity"]`
+    pub actions: Vec<String>,
+    /// Source location of the call
+    pub location: Location,
+    /// Expression of the call
+    pub expression: String,
+}
+
+/// Drop the actions of `services` from `enriched_calls`, returning the calls that lost
+/// actions, by location
+///
+/// Calls left without any action are dropped as well. Services are matched without regard
+/// to case; calls without a source location can't be reported, so their actions are only
+/// dropped.
+pub(crate) fn exclude_services<'a>(
+    enriched_calls: Vec<EnrichedSdkMethodCall<'a>>,
+    services: &[String],
+) -> (Vec<EnrichedSdkMethodCall<'a>>, Vec<ExcludedServiceCall>) {
+    if services.is_empty() {
+        return (enriched_calls, Vec::new());
+    }
+    let mut kept = Vec::with_capacity(enriched_calls.len());
+    let mut excluded = Vec::new();
+    for mut call in enriched_calls {
+        let (excluded_actions, actions): (Vec<_>, Vec<_>) =
+            call.actions.into_iter().partition(|action| {
+                services
+                    .iter()
+                    .any(|service| service.eq_ignore_ascii_case(action.service()))
+            });
+        call.actions = actions;
+        if !excluded_actions.is_empty() {
+            log::debug!(
+                "Excluding {} actions of {} in excluded services",
+                excluded_actions.len(),
+                call.method_name
+            );
+            if let Some(metadata) = &call.sdk_method_call.metadata {
+                excluded.push(ExcludedServiceCall {
+                    operation: call.method_name.clone(),
+                    actions: excluded_actions
+                        .into_iter()
+                        .map(|action| action.name)
+                        .collect(),
+                    location: metadata.location.clone(),
+                    expression: metadata.expr.clone(),
+                });
+            }
+            if call.actions.is_empty() {
+                continue;
+            }
+        }
+        kept.push(call);
+    }
+    excluded.sort_by(|a, b| (&a.location, &a.operation).cmp(&(&b.location, &b.operation)));
+    (kept, excluded)
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::SdkMethodCall;
+
+    fn call(name: &str, line: usize) -> SdkMethodCall {
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: vec![],
+            metadata: Some(SdkMethodCallMetadata::new(
+                format!("client.{name}()"),
+                Location::new(PathBuf::from("app.py"), (line, 1), (line, 30)),
+            )),
+        }
+    }
+
+    fn enriched<'a>(call: &'a SdkMethodCall, actions: &[&str]) -> EnrichedSdkMethodCall<'a> {
+        EnrichedSdkMethodCall {
+            method_name: call.name.clone(),
+            service: actions[0].split(':').next().unwrap_or_default().to_string(),
+            actions: actions
+                .iter()
+                .map(|action| {
+                    Action::new(
+                        (*action).to_string(),
+                        vec![],
+                        vec![],
+                        Explanation::default(),
+                    )
+                })
+                .collect(),
+            sdk_method_call: call,
+        }
+    }
+
+    #[test]
+    fn test_excluded_services_are_left_out_and_reported() {
+        let identity = call("get_caller_identity", 2);
+        let object = call("get_object", 1);
+        let queue = call("send_message", 3);
+        let calls = vec![
+            enriched(&identity, &["sts:GetCallerIdentity"]),
+            enriched(&object, &["s3:GetObject", "kms:Decrypt"]),
+            enriched(&queue, &["sqs:SendMessage"]),
+        ];
+
+        let (kept, excluded) = exclude_services(calls, &["STS".to_string(), "kms".to_string()]);
+
+        let kept: Vec<Vec<&str>> = kept
+            .iter()
+            .map(|call| {
+                call.actions
+                    .iter()
+                    .map(|action| action.name.as_str())
+                    .collect()
+            })
+            .collect();
+        assert_eq!(kept, vec![vec!["s3:GetObject"], vec!["sqs:SendMessage"]]);
+        let excluded: Vec<(&str, &[String])> = excluded
+            .iter()
+            .map(|call| (call.operation.as_str(), call.actions.as_slice()))
+            .collect();
+        assert_eq!(
+            excluded,
+            vec![
+                ("get_object", &["kms:Decrypt".to_string()][..]),
+                (
+                    "get_caller_identity",
+                    &["sts:GetCallerIdentity".to_string()][..]
+                ),
+            ]
+        );
+    }
+}
@@ -0,0 +1,622 @@
+//! Permissions of tracing, metrics and logging instrumentation
+//!
+//! Instrumented code sends telemetry to AWS without SDK calls of its own: the X-Ray SDK,
+//! Powertools Tracer and OpenTelemetry with the AWS X-Ray extensions (ADOT) send trace
+//! segments and fetch sampling rules, Prometheus remote write sends metrics to Amazon
+//! Managed Service for Prometheus, CloudWatch embedded metric format (EMF) libraries
+//! publish metrics, and logging handlers such as watchtower, winston-cloudwatch and the
+//! logrus CloudWatch hooks send log events to CloudWatch Logs. The instrumentation is
+//! recognized by the libraries the code uses, and each kind found becomes an enriched call
+//! of its own, pointing at the first use. Metrics are restricted to the namespaces the code
+//! sets, if any, log events to the log groups it names, and remote writes to the workspaces
+//! of the endpoints it names.
+//!
+//! Code exporting OTLP to an ADOT or OpenTelemetry Collector sidecar leaves the AWS calls to
+//! the collector, which runs with the same task or pod role. Its configuration is read for
+//! the `awsxray` and `prometheusremotewrite` exporters, the same way as source files.
+
+use std::path::Path;
+use std::sync::{Arc, OnceLock};
+
+use regex::Regex;
+
+use super::{
+    Action, Condition, EnrichedSdkMethodCall, Explanation, Operation, OperationSource, Operator,
+    Reason, Resource,
+};
+use crate::extraction::shared::SourceSyntax;
+use crate::extraction::{SdkMethodCallMetadata, SourceFile};
+use crate::{Location, SdkMethodCall};
+
+/// Libraries tracing with the X-Ray SDK, Powertools Tracer or OpenTelemetry's X-Ray
+/// extensions
+const TRACING_LIBRARIES: &[&str] = &[
+    "aws_xray_sdk",
+    "aws-xray-sdk",
+    "aws-xray-sdk-core",
+    "github.com/aws/aws-xray-sdk-go",
+    "com.amazonaws.xray",
+    "@aws-lambda-powertools/tracer",
+    "aws_lambda_powertools.tracing",
+    "aws_lambda_powertools.Tracer",
+    "opentelemetry.sdk.extension.aws",
+    "@opentelemetry/id-generator-aws-xray",
+    "@opentelemetry/propagator-aws-xray",
+    "go.opentelemetry.io/contrib/propagators/aws/xray",
+    "io.opentelemetry.contrib.awsxray",
+];
+
+/// Collectors exporting traces to X-Ray
+const TRACING_CONFIG_MARKERS: &[&str] = &["awsxrayexporter", "awsxray:", "awsxray/"];
+
+/// Endpoints of Amazon Managed Service for Prometheus workspaces, in the strings of code
+const PROMETHEUS_ENDPOINT_MARKERS: &[&str] = &["/api/v1/remote_write", "aps-workspaces."];
+
+/// Collectors writing metrics to an Amazon Managed Service for Prometheus workspace
+const PROMETHEUS_CONFIG_MARKERS: &[&str] = &[
+    "/api/v1/remote_write",
+    "prometheusremotewrite",
+    "aps-workspaces.",
+];
+
+/// Libraries publishing metrics in the CloudWatch embedded metric format
+const EMBEDDED_METRICS_LIBRARIES: &[&str] = &[
+    "aws_embedded_metrics",
+    "aws-embedded-metrics",
+    "software.amazon.cloudwatchlogs.emf",
+    "@aws-lambda-powertools/metrics",
+    "aws_lambda_powertools.metrics",
+    "aws_lambda_powertools.Metrics",
+];
+
+/// Handlers of logging libraries sending log events to CloudWatch Logs
+const LOG_HANDLER_LIBRARIES: &[&str] = &[
+    "watchtower",
+    "winston-cloudwatch",
+    "winston-aws-cloudwatch",
+    "github.com/kdar/logrus-cloudwatchlogs",
+];
+
+/// ARN of an Amazon Managed Service for Prometheus workspace
+const WORKSPACE_ARN: &str = "arn:${Partition}:aps:${Region}:${Account}:workspace/${WorkspaceId}";
+
+/// ARN of the log streams of a log group
+const LOG_STREAM_ARN: &str = concat!(
+    "arn:${Partition}:logs:${Region}:${Account}:log-group:${LogGroupName}",
+    ":log-stream:${LogStreamName}"
+);
+
+/// Regex capturing the workspaces of remote write endpoints:
+/// `https://aps-workspaces.us-east-1.amazonaws.com/workspaces/ws-1234/api/v1/remote_write`
+static WORKSPACE_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_workspace_regex() -> &'static Regex {
+    WORKSPACE_REGEX.get_or_init(|| {
+        Regex::new(r"aps-workspaces\.[\w-]+\.amazonaws\.com/workspaces/(ws-[\w-]+)")
+            .expect("Invalid workspace regex")
+    })
+}
+
+/// Kind of instrumentation, by the service it sends telemetry to
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub(crate) enum InstrumentationKind {
+    /// Traces sent to AWS X-Ray
+    Tracing,
+    /// Metrics written to Amazon Managed Service for Prometheus
+    PrometheusRemoteWrite,
+    /// Metrics published to CloudWatch in the embedded metric format
+    EmbeddedMetrics,
+    /// Log events sent to CloudWatch Logs by a logging handler
+    CloudWatchLogs,
+}
+
+impl InstrumentationKind {
+    const ALL: [Self; 4] = [
+        Self::Tracing,
+        Self::PrometheusRemoteWrite,
+        Self::EmbeddedMetrics,
+        Self::CloudWatchLogs,
+    ];
+
+    /// Modules, packages and classes whose import instruments code
+    const fn libraries(self) -> &'static [&'static str] {
+        match self {
+            Self::Tracing => TRACING_LIBRARIES,
+            Self::EmbeddedMetrics => EMBEDDED_METRICS_LIBRARIES,
+            Self::CloudWatchLogs => LOG_HANDLER_LIBRARIES,
+            Self::PrometheusRemoteWrite => &[],
+        }
+    }
+
+    /// Text of the string literals of instrumented code
+    const fn endpoint_markers(self) -> &'static [&'static str] {
+        match self {
+            Self::PrometheusRemoteWrite => PROMETHEUS_ENDPOINT_MARKERS,
+            Self::Tracing | Self::EmbeddedMetrics | Self::CloudWatchLogs => &[],
+        }
+    }
+
+    /// Text of the collector configurations exporting to the service
+    const fn config_markers(self) -> &'static [&'static str] {
+        match self {
+            Self::Tracing => TRACING_CONFIG_MARKERS,
+            Self::PrometheusRemoteWrite => PROMETHEUS_CONFIG_MARKERS,
+            Self::EmbeddedMetrics | Self::CloudWatchLogs => &[],
+        }
+    }
+
+    const fn service(self) -> &'static str {
+        match self {
+            Self::Tracing => "xray",
+            Self::PrometheusRemoteWrite => "aps",
+            Self::EmbeddedMetrics => "cloudwatch",
+            Self::CloudWatchLogs => "logs",
+        }
+    }
+
+    /// The actions the instrumentation needs, with their resource type and ARN format
+    const fn actions(self) -> &'static [(&'static str, &'static str, Option<&'static str>)] {
+        match self {
+            Self::Tracing => &[
+                ("xray:PutTraceSegments", "*", None),
+                ("xray:PutTelemetryRecords", "*", None),
+                ("xray:GetSamplingRules", "*", None),
+                ("xray:GetSamplingTargets", "*", None),
+            ],
+            Self::PrometheusRemoteWrite => &[("aps:RemoteWrite", "workspace", Some(WORKSPACE_ARN))],
+            Self::EmbeddedMetrics => &[("cloudwatch:PutMetricData", "*", None)],
+            Self::CloudWatchLogs => &[
+                ("logs:CreateLogStream", "log-stream", Some(LOG_STREAM_ARN)),
+                ("logs:PutLogEvents", "log-stream", Some(LOG_STREAM_ARN)),
+            ],
+        }
+    }
+
+    /// The first use of the instrumentation in a source file, its location and source text
+    fn first_use(self, syntax: &SourceSyntax) -> Option<(Location, String)> {
+        if let Some(import) = syntax
+            .imports
+            .iter()
+            .find(|import| self.libraries().iter().any(|library| import.is_of(library)))
+        {
+            return Some((import.location.clone(), import.module.clone()));
+        }
+        syntax
+            .strings
+            .iter()
+            .find(|string| {
+                self.endpoint_markers()
+                    .iter()
+                    .any(|marker| string.value.contains(marker))
+            })
+            .map(|string| (string.location.clone(), string.value.clone()))
+    }
+
+    /// The names a source file restricts the instrumentation to: the workspaces of the
+    /// remote write endpoints, the namespaces of `Metrics(namespace="Orders")`,
+    /// `new Metrics({ namespace: 'Orders' })` and `metrics.setNamespace("Orders")`, and the
+    /// log groups of `CloudWatchLogHandler(log_group_name="orders")`,
+    /// `new WinstonCloudWatch({ logGroupName: 'orders' })` and
+    /// `logrus_cloudwatchlogs.NewHook("orders", "api", sess)`
+    fn names(self, syntax: &SourceSyntax) -> Vec<String> {
+        let keyword_values = |keywords: &[&str]| {
+            syntax
+                .keyword_strings
+                .iter()
+                .filter(|(name, _)| keywords.contains(&normalized(name).as_str()))
+                .map(|(_, value)| value.clone())
+                .collect::<Vec<_>>()
+        };
+        let first_arguments = |is_setter: &dyn Fn(&str) -> bool| {
+            syntax
+                .calls
+                .iter()
+                .filter(|call| is_setter(&call.callee))
+                .filter_map(|call| call.first_string().map(str::to_string))
+                .collect::<Vec<_>>()
+        };
+        match self {
+            Self::Tracing => Vec::new(),
+            Self::PrometheusRemoteWrite => syntax
+                .strings
+                .iter()
+                .flat_map(|string| get_workspace_regex().captures_iter(&string.value))
+                .map(|captures| captures[1].to_string())
+                .collect(),
+            Self::EmbeddedMetrics => {
+                let mut names = keyword_values(&["namespace"]);
+                names.extend(first_arguments(&|callee: &str| {
+                    callee.rsplit('.').next().is_some_and(|method| {
+                        matches!(
+                            normalized(method).as_str(),
+                            "setnamespace" | "withnamespace"
+                        )
+                    })
+                }));
+                names
+            }
+            Self::CloudWatchLogs => {
+                let mut names = keyword_values(&["loggroup", "loggroupname"]);
+                names.extend(first_arguments(&|callee: &str| {
+                    callee.split_once('.').is_some_and(|(package, function)| {
+                        normalized(package).starts_with("logruscloudwatch")
+                            && function.starts_with("New")
+                    })
+                }));
+                names
+            }
+        }
+    }
+
+    /// The ARN placeholder bound to the names, for the kinds scoping resources with them
+    const fn placeholder(self) -> Option<&'static str> {
+        match self {
+            Self::PrometheusRemoteWrite => Some("${WorkspaceId}"),
+            Self::CloudWatchLogs => Some("${LogGroupName}"),
+            Self::Tracing | Self::EmbeddedMetrics => None,
+        }
+    }
+}
+
+/// `name` in lower case without underscores, so `log_group_name` and `logGroupName` compare
+/// equal
+fn normalized(name: &str) -> String {
+    name.chars()
+        .filter(|c| *c != '_')
+        .map(|c| c.to_ascii_lowercase())
+        .collect()
+}
+
+/// Instrumentation found in the analyzed code
+#[derive(Debug, Clone)]
+pub(crate) struct Instrumentation {
+    pub(crate) kind: InstrumentationKind,
+    /// Names the instrumentation is restricted to: the workspaces of Prometheus remote
+    /// write, the metric namespaces of embedded metrics and the log groups of CloudWatch Logs
+    pub(crate) names: Vec<String>,
+    /// The first use of the instrumentation, standing for the call needing its permissions
+    pub(crate) call: SdkMethodCall,
+}
+
+/// The instrumentation of `source_files` and `collector_configs`, the (path, content) of
+/// collector configurations, one per kind, in the order of [`InstrumentationKind::ALL`]
+///
+/// Source files are matched in their syntax tree, so libraries and endpoints only named in
+/// comments don't count; collector configurations are matched as text.
+pub(crate) fn detect_instrumentation(
+    source_files: &[SourceFile],
+    collector_configs: &[(&Path, &str)],
+) -> Vec<Instrumentation> {
+    let syntax: Vec<SourceSyntax> = source_files.iter().map(SourceSyntax::of).collect();
+    InstrumentationKind::ALL
+        .into_iter()
+        .filter_map(|kind| {
+            let mut uses = Vec::new();
+            let mut names = Vec::new();
+            for syntax in &syntax {
+                if let Some(first_use) = kind.first_use(syntax) {
+                    uses.push(first_use);
+                    names.extend(kind.names(syntax));
+                }
+            }
+            for (path, content) in collector_configs {
+                let Some((line_index, line)) = content.lines().enumerate().find(|(_, line)| {
+                    kind.config_markers()
+                        .iter()
+                        .any(|marker| line.contains(marker))
+                }) else {
+                    continue;
+                };
+                let expression = line.trim();
+                let column = line.len() - line.trim_start().len() + 1;
+                uses.push((
+                    Location::new(
+                        path.to_path_buf(),
+                        (line_index + 1, column),
+                        (line_index + 1, column + expression.len()),
+                    ),
+                    expression.to_string(),
+                ));
+                if kind == InstrumentationKind::PrometheusRemoteWrite {
+                    names.extend(
+                        get_workspace_regex()
+                            .captures_iter(content)
+                            .map(|captures| captures[1].to_string()),
+                    );
+                }
+            }
+            let (location, expression) = uses.into_iter().next()?;
+            names.sort();
+            names.dedup();
+            log::debug!(
+                "Found {kind:?} instrumentation in {}",
+                location.to_gnu_format()
+            );
+            Some(Instrumentation {
+                kind,
+                names,
+                call: SdkMethodCall {
+                    name: format!("{kind:?}"),
+                    possible_services: vec![kind.service().to_string()],
+                    metadata: Some(SdkMethodCallMetadata::new(expression, location)),
+                },
+            })
+        })
+        .collect()
+}
+
+/// Enriched calls granting the permissions of `instrumentation`
+pub(crate) fn enrich_instrumentation(
+    instrumentation: &[Instrumentation],
+) -> Vec<EnrichedSdkMethodCall<'_>> {
+    instrumentation
+        .iter()
+        .filter_map(|instrumentation| {
+            let call = &instrumentation.call;
+            let metadata = call.metadata.as_ref()?;
+            let service = instrumentation.kind.service().to_string();
+            let operation = Arc::new(Operation {
+                service: service.clone(),
+                name: call.name.clone(),
+                source: OperationSource::Extracted(metadata.clone()),
+                _private: (),
+            });
+            let conditions: Vec<Condition> = if instrumentation.kind
+                == InstrumentationKind::EmbeddedMetrics
+                && !instrumentation.names.is_empty()
+            {
+                vec![Condition {
+                    operator: Operator::StringEquals,
+                    key: "cloudwatch:namespace".to_string(),
+                    values: instrumentation.names.clone(),
+                }]
+            } else {
+                Vec::new()
+            };
+            let actions = instrumentation
+                .kind
+                .actions()
+                .iter()
+                .map(|(action, resource, arn_format)| {
+                    Action::new(
+                        (*action).to_string(),
+                        vec![Resource::new(
+                            (*resource).to_string(),
+                            arn_format.map(|arn_format| bound_arns(arn_format, instrumentation)),
+                        )],
+                        conditions.clone(),
+                        Explanation {
+                            reasons: vec![Reason::new(vec![Arc::clone(&operation)])],
+                        },
+                    )
+                })
+                .collect();
+            Some(EnrichedSdkMethodCall {
+                method_name: call.name.clone(),
+                service,
+                actions,
+                sdk_method_call: call,
+            })
+        })
+        .collect()
+}
+
+/// `arn_format` with the placeholder of the instrumentation's kind bound to each of its
+/// names, or left for policy generation to widen without any
+fn bound_arns(arn_format: &str, instrumentation: &Instrumentation) -> Vec<String> {
+    match instrumentation.kind.placeholder() {
+        Some(placeholder) if !instrumentation.names.is_empty() => instrumentation
+            .names
+            .iter()
+            .map(|name| arn_format.replace(placeholder, name))
+            .collect(),
+        _ => vec![arn_format.to_string()],
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::Language;
+
+    fn source_file(path: &str, content: &str, language: Language) -> SourceFile {
+        SourceFile::with_language(PathBuf::from(path), content.to_string(), language)
+    }
+
+    #[test]
+    fn test_detect_instrumentation() {
+        let source_files = [
+            source_file("app.py", "import boto3\n", Language::Python),
+            source_file(
+                "handler.py",
+                "from aws_lambda_powertools import Metrics, Tracer\n\
+                 from aws_xray_sdk.core import patch_all\n\
+                 metrics = Metrics(namespace=\"Orders\")\n",
+                Language::Python,
+            ),
+            source_file(
+                "jobs.py",
+                "from aws_embedded_metrics import metric_scope\n\
+                 metrics.set_namespace('Billing')\n",
+                Language::Python,
+            ),
+        ];
+
+        let instrumentation = detect_instrumentation(&source_files, &[]);
+
+        let kinds: Vec<InstrumentationKind> =
+            instrumentation.iter().map(|found| found.kind).collect();
+        assert_eq!(
+            kinds,
+            vec![
+                InstrumentationKind::Tracing,
+                InstrumentationKind::EmbeddedMetrics
+            ]
+        );
+        let location = &instrumentation[0].call.metadata.as_ref().unwrap().location;
+        assert_eq!(location.file_path, PathBuf::from("handler.py"));
+        assert_eq!(location.start_line(), 1);
+        assert_eq!(instrumentation[1].names, vec!["Billing", "Orders"]);
+    }
+
+    #[test]
+    fn test_enrich_instrumentation() {
+        let source_files = [source_file(
+            "handler.py",
+            "from aws_lambda_powertools import Metrics\nmetrics = Metrics(namespace=\"Orders\")\n",
+            Language::Python,
+        )];
+        let instrumentation = detect_instrumentation(&source_files, &[]);
+
+        let enriched = enrich_instrumentation(&instrumentation);
+
+        assert_eq!(enriched.len(), 1);
+        assert_eq!(enriched[0].service, "cloudwatch");
+        let action = &enriched[0].actions[0];
+        assert_eq!(action.name, "cloudwatch:PutMetricData");
+        assert_eq!(action.resources[0].name, "*");
+        assert_eq!(action.conditions[0].key, "cloudwatch:namespace");
+        assert_eq!(action.conditions[0].values, vec!["Orders"]);
+    }
+
+    #[test]
+    fn test_log_handlers() {
+        let source_files = [
+            source_file(
+                "app.py",
+                "import watchtower\n\
+                 handler = watchtower.CloudWatchLogHandler(log_group_name=\"orders\")\n",
+                Language::Python,
+            ),
+            source_file(
+                "logger.ts",
+                "import WinstonCloudWatch from 'winston-cloudwatch';\n\
+                 logger.add(new WinstonCloudWatch({ logGroupName: 'api' }));\n",
+                Language::TypeScript,
+            ),
+            source_file(
+                "main.go",
+                "package main\n\nimport \"github.com/kdar/logrus-cloudwatchlogs\"\n\n\
+                 func main() {\n\
+                 \thook, err := logrus_cloudwatchlogs.NewHook(\"jobs\", \"worker\", sess)\n}\n",
+                Language::Go,
+            ),
+        ];
+        let instrumentation = detect_instrumentation(&source_files, &[]);
+
+        assert_eq!(instrumentation.len(), 1);
+        assert_eq!(instrumentation[0].kind, InstrumentationKind::CloudWatchLogs);
+        assert_eq!(instrumentation[0].names, vec!["api", "jobs", "orders"]);
+
+        let enriched = enrich_instrumentation(&instrumentation);
+
+        assert_eq!(enriched[0].service, "logs");
+        let actions: Vec<&str> = enriched[0]
+            .actions
+            .iter()
+            .map(|action| action.name.as_str())
+            .collect();
+        assert_eq!(actions, vec!["logs:CreateLogStream", "logs:PutLogEvents"]);
+        let arn_patterns = enriched[0].actions[1].resources[0].arn_patterns.as_ref();
+        assert_eq!(arn_patterns.map(Vec::len), Some(3));
+        assert_eq!(
+            arn_patterns.unwrap()[0],
+            "arn:${Partition}:logs:${Region}:${Account}:log-group:api:log-stream:${LogStreamName}"
+        );
+        assert!(enriched[0].actions[0].conditions.is_empty());
+    }
+
+    #[test]
+    fn test_collector_exporters() {
+        let source_files = [source_file("app.py", "import boto3\n", Language::Python)];
+        let collector_configs = [(
+            Path::new("collector.yaml"),
+            "extensions:\n  sigv4auth:\n    region: us-east-1\n\
+             exporters:\n  awsxray:\n    region: us-east-1\n  prometheusremotewrite:\n    \
+             endpoint: https://aps-workspaces.us-east-1.amazonaws.com/workspaces/\
+             ws-0a1b2c3d/api/v1/remote_write\n    auth:\n      authenticator: sigv4auth\n",
+        )];
+        let instrumentation = detect_instrumentation(&source_files, &collector_configs);
+
+        let kinds: Vec<InstrumentationKind> =
+            instrumentation.iter().map(|found| found.kind).collect();
+        assert_eq!(
+            kinds,
+            vec![
+                InstrumentationKind::Tracing,
+                InstrumentationKind::PrometheusRemoteWrite
+            ]
+        );
+        let location = &instrumentation[0].call.metadata.as_ref().unwrap().location;
+        assert_eq!(location.file_path, PathBuf::from("collector.yaml"));
+        assert_eq!(location.start_line(), 5);
+        assert_eq!(instrumentation[1].names, vec!["ws-0a1b2c3d"]);
+
+        let enriched = enrich_instrumentation(&instrumentation);
+
+        assert_eq!(
+            enriched[1].actions[0].resources[0].arn_patterns,
+            Some(vec![
+                "arn:${Partition}:aps:${Region}:${Account}:workspace/ws-0a1b2c3d".to_string()
+            ])
+        );
+    }
+
+    #[test]
+    fn test_remote_write_endpoints_in_code() {
+        let source_files = [source_file(
+            "metrics.py",
+            "# The old endpoint, ws-11111111, was retired\n\
+             ENDPOINT = \"https://aps-workspaces.us-east-1.amazonaws.com/workspaces/\
+             ws-0a1b2c3d/api/v1/remote_write\"\n",
+            Language::Python,
+        )];
+
+        let instrumentation = detect_instrumentation(&source_files, &[]);
+
+        assert_eq!(instrumentation.len(), 1);
+        assert_eq!(
+            instrumentation[0].kind,
+            InstrumentationKind::PrometheusRemoteWrite
+        );
+        assert_eq!(instrumentation[0].names, vec!["ws-0a1b2c3d"]);
+        let location = &instrumentation[0].call.metadata.as_ref().unwrap().location;
+        assert_eq!(location.start_line(), 2);
+    }
+
+    #[test]
+    fn test_libraries_in_comments_and_strings_are_ignored() {
+        let source_files = [
+            source_file(
+                "app.py",
+                "# import watchtower\n\
+                 # from aws_xray_sdk.core import patch_all\n\
+                 \"\"\"Publishes with aws_embedded_metrics once it's enabled\"\"\"\n\
+                 import boto3\n\
+                 LOG_GROUP = {'log_group_name': LOG_GROUP_NAME}\n",
+                Language::Python,
+            ),
+            source_file(
+                "tracing.ts",
+                "// import { Tracer } from '@aws-lambda-powertools/tracer';\n\
+                 const note = \"see @aws-lambda-powertools/metrics\";\n",
+                Language::TypeScript,
+            ),
+        ];
+
+        assert!(detect_instrumentation(&source_files, &[]).is_empty());
+    }
+
+    #[test]
+    fn test_uninstrumented_code() {
+        let source_files = [source_file(
+            "app.py",
+            "import boto3\ns3 = boto3.client('s3')\n",
+            Language::Python,
+        )];
+
+        assert!(detect_instrumentation(&source_files, &[]).is_empty());
+    }
+}
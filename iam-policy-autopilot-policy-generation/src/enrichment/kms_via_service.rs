@@ -0,0 +1,152 @@
+//! KMS permissions used on behalf of calls through `kms:ViaService`
+//!
+//! The forward access sessions of services like S3 or Secrets Manager call KMS with the
+//! caller's permissions, so their calls are granted KMS actions conditioned on
+//! `kms:ViaService`, e.g. `s3.${region}.amazonaws.com`, on every key of the account. Teams
+//! that know which services encrypt their data with which keys restrict them here.
+
+use crate::api::model::KmsViaService;
+use crate::enrichment::{EnrichedSdkMethodCall, Resource};
+
+/// Condition key naming the service a KMS request is made through
+const VIA_SERVICE_KEY: &str = "kms:ViaService";
+
+/// Resource type of KMS keys
+const KEY_RESOURCE_TYPE: &str = "key";
+
+/// Drop or scope the KMS actions of `enriched_calls` granted through `kms:ViaService`
+///
+/// The actions are dropped with `kms_via_service` `None` or when their service isn't one
+/// it grants, and granted on its keys for their service otherwise.
+pub(crate) fn apply_kms_via_service(
+    enriched_calls: &mut [EnrichedSdkMethodCall<'_>],
+    kms_via_service: Option<&KmsViaService>,
+) {
+    for call in enriched_calls {
+        call.actions.retain_mut(|action| {
+            let Some(service) = action
+                .conditions
+                .iter()
+                .filter(|condition| condition.key == VIA_SERVICE_KEY)
+                .flat_map(|condition| &condition.values)
+                .find_map(|value| value.split('.').next())
+                .map(str::to_string)
+            else {
+                return true;
+            };
+            let Some(kms_via_service) = kms_via_service.filter(|kms| kms.grants(&service)) else {
+                log::debug!(
+                    "Not granting {} through {service} to {}",
+                    action.name,
+                    call.method_name
+                );
+                return false;
+            };
+            let key_arns = kms_via_service.key_arns(&service);
+            if !key_arns.is_empty() {
+                action.resources = vec![Resource::new(
+                    KEY_RESOURCE_TYPE.to_string(),
+                    Some(key_arns.into_iter().map(str::to_string).collect()),
+                )];
+            }
+            true
+        });
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::api::model::KmsKeyArn;
+    use crate::enrichment::{Action, Condition, Explanation, Operator};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Location, SdkMethodCall};
+
+    fn call(service: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "get_object".to_string(),
+            possible_services: vec![service.to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "client.get_object()".to_string(),
+                Location::new(PathBuf::from("app.py"), (2, 1), (2, 20)),
+            )),
+        }
+    }
+
+    fn enriched<'a>(call: &'a SdkMethodCall) -> EnrichedSdkMethodCall<'a> {
+        let service = &call.possible_services[0];
+        let action = |name: &str, conditions: Vec<Condition>| {
+            Action::new(
+                name.to_string(),
+                vec![Resource::new(KEY_RESOURCE_TYPE.to_string(), None)],
+                conditions,
+                Explanation::default(),
+            )
+        };
+        EnrichedSdkMethodCall {
+            method_name: call.name.clone(),
+            service: service.clone(),
+            actions: vec![
+                action(&format!("{service}:GetObject"), vec![]),
+                action(
+                    "kms:Decrypt",
+                    vec![Condition {
+                        operator: Operator::StringEquals,
+                        key: VIA_SERVICE_KEY.to_string(),
+                        values: vec![format!("{service}.${{region}}.amazonaws.com")],
+                    }],
+                ),
+            ],
+            sdk_method_call: call,
+        }
+    }
+
+    fn granted(call: &EnrichedSdkMethodCall<'_>) -> Vec<(&str, Option<Vec<String>>)> {
+        call.actions
+            .iter()
+            .map(|action| {
+                (
+                    action.name.as_str(),
+                    action.resources[0].arn_patterns.clone(),
+                )
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_kms_actions_are_restricted_to_the_configured_services_and_keys() {
+        let key = "arn:aws:kms:us-east-1:123456789012:key/1234abcd";
+        let s3 = call("s3");
+        let secrets = call("secretsmanager");
+        let mut calls = vec![enriched(&s3), enriched(&secrets)];
+
+        apply_kms_via_service(
+            &mut calls,
+            Some(&KmsViaService {
+                services: vec!["s3".to_string()],
+                key_arns: vec![KmsKeyArn::parse(&format!("s3={key}")).unwrap()],
+            }),
+        );
+
+        assert_eq!(
+            granted(&calls[0]),
+            vec![
+                ("s3:GetObject", None),
+                ("kms:Decrypt", Some(vec![key.to_string()]))
+            ]
+        );
+        assert_eq!(granted(&calls[1]), vec![("secretsmanager:GetObject", None)]);
+    }
+
+    #[test]
+    fn test_disabled_kms_via_service_grants_no_kms_actions() {
+        let s3 = call("s3");
+        let mut calls = vec![enriched(&s3)];
+
+        apply_kms_via_service(&mut calls, None);
+
+        assert_eq!(granted(&calls[0]), vec![("s3:GetObject", None)]);
+    }
+}
@@ -24,14 +24,32 @@ use convert_case::{Case, Casing};
 use schemars::JsonSchema;
 use serde::{Deserialize, Serialize};
 
+pub(crate) mod athena_queries;
+pub(crate) mod bedrock_models;
+pub(crate) mod dependent_actions;
+pub(crate) mod deprecated_operations;
 pub(crate) mod engine;
+pub(crate) mod event_sources;
+pub(crate) mod excluded_services;
+pub(crate) mod instrumentation;
+pub(crate) mod kms_via_service;
+pub(crate) mod no_permission_operations;
 pub(crate) mod operation_fas_map;
+pub(crate) mod redshift_data;
+pub(crate) mod required_permissions;
+pub(crate) mod resource_answers;
 pub(crate) mod resource_matcher;
+pub(crate) mod s3_express;
+pub(crate) mod s3_multipart;
+pub(crate) mod s3_resource_forms;
 pub mod service_reference;
 
 pub(crate) mod terraform;
 
+pub use deprecated_operations::DeprecatedCall;
 pub use engine::Engine;
+pub use excluded_services::ExcludedServiceCall;
+pub use no_permission_operations::NoPermissionCall;
 pub(crate) use operation_fas_map::load_operation_fas_map;
 pub(crate) use resource_matcher::ResourceMatcher;
 pub(crate) use service_reference::RemoteServiceReferenceLoader as ServiceReferenceLoader;
@@ -81,41 +99,54 @@ impl Operation {
         }
     }
 
-    pub(crate) async fn from_call(
-        call: &SdkMethodCall,
-        original_service_name: &str,
-        service_cfg: &ServiceConfiguration,
-        sdk: SdkType,
-    ) -> crate::errors::Result<Self> {
-        let service = service_cfg
-            .rename_service_service_reference(original_service_name)
-            .to_string();
-        #[allow(unknown_lints, convert_case_pascal)]
-        let name = if sdk == SdkType::Boto3 {
+    /// Name of the operation `call` makes, as the Service Reference spells it, e.g.
+    /// `GetObject` for the `get_object` method of boto3
+    #[allow(unknown_lints, convert_case_pascal)]
+    pub(crate) fn operation_name(call: &SdkMethodCall, sdk: SdkType) -> String {
+        if sdk == SdkType::Boto3 {
             PythonNameMap::reverse_lookup(&call.name)
                 .map(std::string::ToString::to_string)
                 .unwrap_or_else(|| call.name.to_case(Case::Pascal))
         } else if sdk == SdkType::JavaV2 {
-            // Java SDK v2 extracts method names in camelCase (e.g. `listObjectsV2`, `putObject`).
-            // The service reference operation map uses PascalCase keys (e.g. `ListObjectsV2`).
-            // A simple first-letter capitalisation (`to_case(Case::Pascal)`) is sufficient and
-            // correct even for names that look tricky:
+            // Java SDK v2 extracts method names in camelCase (e.g. `listObjectsV2`,
+            // `putObject`). The service reference operation map uses PascalCase keys (e.g.
+            // `ListObjectsV2`). A simple first-letter capitalisation (`to_case(Case::Pascal)`)
+            // is sufficient and correct even for names that look tricky:
             //
-            // * Version suffixes (V2, V3, …): In Java camelCase the digit is glued to the `V`
-            //   with no word boundary, so `listObjectsV2` → `ListObjectsV2` (not `ListObjectsV 2`).
-            //   This is the *opposite* of the Python problem, where snake_case splits `V2` into
-            //   `_v_2` and requires a post-processing fix.
+            // * Version suffixes (V2, V3, …): In Java camelCase the digit is glued to the
+            //   `V` with no word boundary, so `listObjectsV2` → `ListObjectsV2` (not
+            //   `ListObjectsV 2`). This is the *opposite* of the Python problem, where
+            //   snake_case splits `V2` into `_v_2` and requires a post-processing fix.
             //
             // * Mixed-case brand names (WhatsApp, DynamoDB, …): Java SDK v2 preserves the
-            //   internal capitalisation in its camelCase names (e.g. `sendWhatsAppMessage`).
-            //   `convert_case` treats every uppercase letter as a word boundary, so
-            //   `sendWhatsAppMessage` → `SendWhatsAppMessage` — exactly the PascalCase key used
-            //   in the service reference.
+            //   internal capitalisation in its camelCase names (e.g.
+            //   `sendWhatsAppMessage`). `convert_case` treats every uppercase letter as a word
+            //   boundary, so `sendWhatsAppMessage` → `SendWhatsAppMessage` — exactly the
+            //   PascalCase key used in the service reference.
             call.name.to_case(Case::Pascal)
         } else {
-            // For non-Boto3, non-Java SDKs (Go, JS, TS) the extracted name is already PascalCase.
+            // For non-Boto3, non-Java SDKs (Go, JS, TS) the extracted name is already
+            // PascalCase.
             call.name.clone()
-        };
+        }
+    }
+
+    pub(crate) async fn from_call(
+        call: &SdkMethodCall,
+        original_service_name: &str,
+        service_cfg: &ServiceConfiguration,
+        sdk: SdkType,
+    ) -> crate::errors::Result<Self> {
+        let service = service_cfg
+            .rename_service_service_reference(original_service_name)
+            .to_string();
+        let name = Self::operation_name(call, sdk);
+        // Calls of renamed operations are granted the actions of the operation replacing
+        // them
+        let name = service_cfg
+            .deprecated_operation(original_service_name, &name)
+            .and_then(|deprecated| deprecated.operation.clone())
+            .unwrap_or(name);
 
         Ok(match &call.metadata {
             None => Self {
@@ -195,6 +226,9 @@ pub enum OperationSource {
     Provided,
     /// Operation comes from FAS expansion
     Fas(Vec<FasContext>),
+    /// Operation AWS requires alongside another one called with certain input, with
+    /// why it's required
+    Dependent(String),
 }
 
 impl Serialize for OperationSource {
@@ -206,6 +240,12 @@ impl Serialize for OperationSource {
             Self::Extracted(metadata) => serialize_extracted_metadata(metadata, serializer),
             Self::Provided => serializer.serialize_str("Provided"),
             Self::Fas(_) => serializer.serialize_str("FAS"),
+            Self::Dependent(note) => {
+                use serde::ser::SerializeMap;
+                let mut map = serializer.serialize_map(Some(1))?;
+                map.serialize_entry("Dependent", note)?;
+                map.end()
+            }
         }
     }
 }
@@ -225,6 +265,8 @@ pub struct Explanations {
 impl Explanations {
     const FAS: &str =
         "The explanation contains an operation added due to Forward Access Sessions (FAS). See https://docs.aws.amazon.com/IAM/latest/UserGuide/access_forward_access_sessions.html.";
+    const DEPENDENT: &str =
+        "The explanation contains a dependent action, which AWS requires alongside an operation called with certain input, e.g. iam:PassRole for passing a role to a service. See https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_use_passrole.html.";
 
     pub(crate) fn new(explanations: BTreeMap<String, Explanation>) -> Self {
         let mut documentation: Vec<&'static str> = vec![];
@@ -234,10 +276,12 @@ impl Explanations {
                     match op.source {
                         OperationSource::Extracted(_) | OperationSource::Provided => (),
                         OperationSource::Fas(_) => documentation.push(Self::FAS),
+                        OperationSource::Dependent(_) => documentation.push(Self::DEPENDENT),
                     }
                 }
             }
         }
+        documentation.sort_unstable();
         documentation.dedup();
         Self {
             explanation_for_action: explanations,
@@ -285,12 +329,15 @@ pub struct EnrichedSdkMethodCall<'a> {
 pub enum Operator {
     StringEquals,
     StringLike,
+    IpAddress,
 }
 
 impl Operator {
     pub(crate) fn to_like_version(&self) -> Self {
         match self {
             Self::StringEquals | Self::StringLike => Self::StringLike,
+            // IP ranges already match address patterns
+            Self::IpAddress => Self::IpAddress,
         }
     }
 }
@@ -355,6 +402,20 @@ impl Action {
     pub(crate) fn service(&self) -> &str {
         self.name.split(':').next().unwrap_or(&self.name)
     }
+
+    /// Substitute the `placeholders` of this action's ARN patterns with their values, as
+    /// the resource matcher does for the call's own service
+    pub(crate) fn bind_placeholders(&mut self, placeholders: &[(&str, &str)]) {
+        for pattern in self
+            .resources
+            .iter_mut()
+            .flat_map(|resource| resource.arn_patterns.iter_mut().flatten())
+        {
+            for (placeholder, value) in placeholders {
+                *pattern = pattern.replace(&format!("${{{placeholder}}}"), value);
+            }
+        }
+    }
 }
 
 /// Represents a resource enriched with ARN pattern and metadata
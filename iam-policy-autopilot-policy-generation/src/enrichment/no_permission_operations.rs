@@ -0,0 +1,138 @@
+//! Calls of operations that need no IAM permission
+//!
+//! `sts:GetCallerIdentity` succeeds whatever the caller's policies say, and calls like
+//! `AssumeRoleWithWebIdentity` or Cognito's `InitiateAuth` are made without credentials,
+//! authorized by a trust policy or a token instead. Granting their actions only adds
+//! statements that never take effect, so the calls are left out of the policies and
+//! reported with what authorizes them. The role a call assumes is kept with it, for the
+//! trust policy of the role to allow the caller.
+
+use serde::Serialize;
+
+use crate::enrichment::Operation;
+use crate::errors::Result;
+use crate::extraction::shared::literal_argument;
+use crate::service_configuration::load_service_configuration;
+use crate::{Location, SdkMethodCall, SdkType};
+
+/// A call of an operation that needs no IAM permission
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct NoPermissionCall {
+    /// Service of the operation, e.g. `sts`
+    pub service: String,
+    /// Operation called, e.g. `GetCallerIdentity`
+    pub operation: String,
+    /// Why the call needs no permission
+    pub note: String,
+    /// Source location of the call
+    pub location: Location,
+    /// Expression of the call
+    pub expression: String,
+    /// Role the call assumes, when a literal `RoleArn` names it
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub role_arn: Option<String>,
+}
+
+/// Input member naming the role the STS operations assume
+const ROLE_ARN_MEMBER: &str = "RoleArn";
+
+/// Split `methods` into the calls needing permissions and those of operations that need
+/// none in any of their possible services, by location
+///
+/// # Errors
+/// Returns an error if the service configuration can't be loaded
+pub(crate) fn separate_no_permission_calls(
+    methods: Vec<SdkMethodCall>,
+    sdk: SdkType,
+) -> Result<(Vec<SdkMethodCall>, Vec<NoPermissionCall>)> {
+    let service_cfg = load_service_configuration()?;
+    let mut kept = Vec::with_capacity(methods.len());
+    let mut calls = Vec::new();
+    for method in methods {
+        let operation = Operation::operation_name(&method, sdk);
+        let notes: Option<Vec<_>> = method
+            .possible_services
+            .iter()
+            .map(|service| {
+                service_cfg
+                    .no_permission_operation(service, &operation)
+                    .map(|no_permission| (service, &no_permission.note))
+            })
+            .collect();
+        match (notes, &method.metadata) {
+            (Some(notes), Some(metadata)) if !notes.is_empty() => {
+                let role_arn = literal_argument(&method, ROLE_ARN_MEMBER);
+                calls.extend(notes.into_iter().map(|(service, note)| NoPermissionCall {
+                    service: service.clone(),
+                    operation: operation.clone(),
+                    note: note.clone(),
+                    location: metadata.location.clone(),
+                    expression: metadata.expr.clone(),
+                    role_arn: role_arn.clone(),
+                }));
+            }
+            _ => kept.push(method),
+        }
+    }
+    calls.sort_by(|a, b| {
+        (&a.location, &a.service, &a.operation).cmp(&(&b.location, &b.service, &b.operation))
+    });
+    Ok((kept, calls))
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::extraction::{Parameter, ParameterValue, SdkMethodCallMetadata};
+
+    fn call(name: &str, services: &[&str], line: usize) -> SdkMethodCall {
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: services.iter().map(ToString::to_string).collect(),
+            metadata: Some(SdkMethodCallMetadata::new(
+                format!("client.{name}()"),
+                Location::new(PathBuf::from("app.py"), (line, 1), (line, 30)),
+            )),
+        }
+    }
+
+    #[test]
+    fn test_separate_no_permission_calls() {
+        let mut web_identity = call("assume_role_with_web_identity", &["sts"], 2);
+        if let Some(metadata) = web_identity.metadata.as_mut() {
+            metadata.parameters = vec![Parameter::Keyword {
+                name: "RoleArn".to_string(),
+                value: ParameterValue::Resolved(
+                    "arn:aws:iam::123456789012:role/Federated".to_string(),
+                ),
+                position: 0,
+                type_annotation: None,
+            }];
+        }
+        let methods = vec![
+            call("get_caller_identity", &["sts"], 4),
+            call("assume_role", &["sts"], 5),
+            web_identity,
+            call("get_user", &["cognito-idp", "iam"], 6),
+        ];
+
+        let (kept, calls) = separate_no_permission_calls(methods, SdkType::Boto3).unwrap();
+
+        let kept: Vec<&str> = kept.iter().map(|method| method.name.as_str()).collect();
+        assert_eq!(kept, vec!["assume_role", "get_user"]);
+        let operations: Vec<&str> = calls.iter().map(|call| call.operation.as_str()).collect();
+        assert_eq!(
+            operations,
+            vec!["AssumeRoleWithWebIdentity", "GetCallerIdentity"]
+        );
+        assert!(calls[1].note.contains("explicitly denies"));
+        assert_eq!(
+            calls[0].role_arn.as_deref(),
+            Some("arn:aws:iam::123456789012:role/Federated")
+        );
+        assert_eq!(calls[1].role_arn, None);
+    }
+}
@@ -0,0 +1,271 @@
+//! Credentials of statements run through the Redshift Data API
+//!
+//! `ExecuteStatement` and `BatchExecuteStatement` connect to the database with credentials
+//! the Data API fetches on behalf of the caller, chosen by the parameters of the call: a
+//! `SecretArn` reads them from Secrets Manager, a `WorkgroupName` gets temporary credentials
+//! of Redshift Serverless, and a `ClusterIdentifier` gets temporary credentials of the
+//! cluster, for the `DbUser` named or for the caller's IAM identity. The operation FAS map
+//! grants all of them; here the calls keep the one their parameters choose.
+
+use crate::enrichment::{EnrichedSdkMethodCall, Operation};
+use crate::extraction::shared::SourceSyntax;
+use crate::SdkType;
+
+/// Operations connecting to the database with fetched credentials
+const STATEMENT_OPERATIONS: &[&str] = &["ExecuteStatement", "BatchExecuteStatement"];
+
+/// Actions fetching the credentials of a statement, one per authentication method
+const CREDENTIAL_ACTIONS: &[&str] = &[
+    "secretsmanager:GetSecretValue",
+    "redshift-serverless:GetCredentials",
+    "redshift:GetClusterCredentials",
+    "redshift:GetClusterCredentialsWithIAM",
+];
+
+/// Parameters naming the cluster, user and database, with the placeholder of the cluster
+/// credentials bound to their literals
+const LITERAL_PARAMETERS: &[(&str, &str)] = &[
+    ("ClusterIdentifier", "ClusterName"),
+    ("DbUser", "DbUser"),
+    ("Database", "DbName"),
+];
+
+/// The credential action a statement passing the arguments of `syntax` is authorized by, if
+/// they choose one
+fn credential_action(syntax: &SourceSyntax) -> Option<&'static str> {
+    if syntax.passes("SecretArn") {
+        Some("secretsmanager:GetSecretValue")
+    } else if syntax.passes("WorkgroupName") {
+        Some("redshift-serverless:GetCredentials")
+    } else if syntax.passes("ClusterIdentifier") && syntax.passes("DbUser") {
+        Some("redshift:GetClusterCredentials")
+    } else if syntax.passes("ClusterIdentifier") {
+        Some("redshift:GetClusterCredentialsWithIAM")
+    } else {
+        None
+    }
+}
+
+/// Keep the credential action of the Redshift Data API statements of `enriched_calls` their
+/// parameters choose
+///
+/// Statements whose parameters aren't recognized, e.g. passed as an unpacked dictionary,
+/// keep every credential action. With `call_site_resources`, the cluster credentials are
+/// scoped to the cluster, user and database the call passes as literals.
+pub(crate) fn select_credential_actions(
+    enriched_calls: &mut [EnrichedSdkMethodCall<'_>],
+    sdk: SdkType,
+    call_site_resources: bool,
+) {
+    for call in enriched_calls
+        .iter_mut()
+        .filter(|call| call.service == "redshift-data")
+    {
+        let operation = Operation::operation_name(call.sdk_method_call, sdk);
+        if !STATEMENT_OPERATIONS.contains(&operation.as_str()) {
+            continue;
+        }
+        let Some(syntax) = call
+            .sdk_method_call
+            .metadata
+            .as_ref()
+            .and_then(SourceSyntax::of_call)
+        else {
+            continue;
+        };
+        let Some(credential_action) = credential_action(&syntax) else {
+            continue;
+        };
+        log::debug!(
+            "Granting {} the credentials of {credential_action}",
+            call.method_name
+        );
+        call.actions.retain(|action| {
+            action.name == credential_action || !CREDENTIAL_ACTIONS.contains(&action.name.as_str())
+        });
+        if !call_site_resources {
+            continue;
+        }
+        let literals: Vec<(&str, &str)> = LITERAL_PARAMETERS
+            .iter()
+            .filter_map(|(parameter, placeholder)| {
+                let literal = syntax.string_of(parameter).filter(|literal| {
+                    !literal.is_empty()
+                        && literal
+                            .chars()
+                            .all(|c| c.is_alphanumeric() || matches!(c, '_' | '.' | '-'))
+                })?;
+                Some((*placeholder, literal))
+            })
+            .collect();
+        for action in call
+            .actions
+            .iter_mut()
+            .filter(|action| action.service() == "redshift")
+        {
+            action.bind_placeholders(&literals);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation, Resource};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Location, SdkMethodCall};
+
+    fn syntax(path: &str, expression: &str) -> SourceSyntax {
+        SourceSyntax::of_call(&SdkMethodCallMetadata::new(
+            expression.to_string(),
+            Location::new(PathBuf::from(path), (1, 1), (1, 1)),
+        ))
+        .unwrap()
+    }
+
+    fn call(expression: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "execute_statement".to_string(),
+            possible_services: vec!["redshift-data".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                expression.to_string(),
+                Location::new(PathBuf::from("app.py"), (3, 1), (5, 40)),
+            )),
+        }
+    }
+
+    fn enriched(call: &SdkMethodCall) -> EnrichedSdkMethodCall<'_> {
+        let action = |name: &str, arn_pattern: &str| {
+            Action::new(
+                name.to_string(),
+                vec![Resource::new(
+                    "resource".to_string(),
+                    Some(vec![arn_pattern.to_string()]),
+                )],
+                vec![],
+                Explanation::default(),
+            )
+        };
+        EnrichedSdkMethodCall {
+            method_name: call.name.clone(),
+            service: "redshift-data".to_string(),
+            actions: vec![
+                action("redshift-data:ExecuteStatement", "*"),
+                action(
+                    "redshift-serverless:GetCredentials",
+                    "arn:${Partition}:redshift-serverless:${Region}:${Account}:\
+                     workgroup/${WorkgroupId}",
+                ),
+                action(
+                    "redshift:GetClusterCredentialsWithIAM",
+                    "arn:${Partition}:redshift:${Region}:${Account}:\
+                     dbname:${ClusterName}/${DbName}",
+                ),
+                action(
+                    "redshift:GetClusterCredentials",
+                    "arn:${Partition}:redshift:${Region}:${Account}:\
+                     dbuser:${ClusterName}/${DbUser}",
+                ),
+                action(
+                    "secretsmanager:GetSecretValue",
+                    "arn:${Partition}:secretsmanager:${Region}:${Account}:\
+                     secret:${SecretId}",
+                ),
+            ],
+            sdk_method_call: call,
+        }
+    }
+
+    fn granted(call: &EnrichedSdkMethodCall<'_>) -> Vec<(&str, String)> {
+        call.actions
+            .iter()
+            .map(|action| {
+                (
+                    action.name.as_str(),
+                    action.resources[0].arn_patterns.clone().unwrap_or_default()[0].clone(),
+                )
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_statements_keep_the_credentials_of_their_parameters() {
+        let cluster = call(
+            "client.execute_statement(ClusterIdentifier='analytics', DbUser='reporter', \
+             Database='sales', Sql=sql)",
+        );
+        let secret = call("client.execute_statement(WorkgroupName=wg, SecretArn=arn, Sql=sql)");
+        let unpacked = call("client.execute_statement(**params)");
+        let mut calls = vec![enriched(&cluster), enriched(&secret), enriched(&unpacked)];
+
+        select_credential_actions(&mut calls, SdkType::Boto3, true);
+
+        assert_eq!(
+            granted(&calls[0]),
+            vec![
+                ("redshift-data:ExecuteStatement", "*".to_string()),
+                (
+                    "redshift:GetClusterCredentials",
+                    "arn:${Partition}:redshift:${Region}:${Account}:dbuser:analytics/reporter"
+                        .to_string()
+                ),
+            ]
+        );
+        let names: Vec<&str> = granted(&calls[1])
+            .into_iter()
+            .map(|(name, _)| name)
+            .collect();
+        assert_eq!(
+            names,
+            vec![
+                "redshift-data:ExecuteStatement",
+                "secretsmanager:GetSecretValue"
+            ]
+        );
+        assert_eq!(calls[2].actions.len(), 5);
+    }
+
+    #[test]
+    fn test_cluster_identifier_alone_uses_the_iam_identity() {
+        let go = syntax(
+            "main.go",
+            "client.ExecuteStatement(ctx, &redshiftdata.ExecuteStatementInput{\
+             ClusterIdentifier: aws.String(\"analytics\"), Sql: &sql})",
+        );
+        let java = syntax(
+            "Reports.java",
+            "client.executeStatement(r -> r.clusterIdentifier(cluster).sql(sql))",
+        );
+
+        assert_eq!(
+            credential_action(&go),
+            Some("redshift:GetClusterCredentialsWithIAM")
+        );
+        assert_eq!(
+            credential_action(&java),
+            Some("redshift:GetClusterCredentialsWithIAM")
+        );
+        assert_eq!(
+            credential_action(&syntax("Reports.java", "client.executeStatement(input)")),
+            None
+        );
+    }
+
+    #[test]
+    fn test_parameters_in_comments_and_strings_are_ignored() {
+        let syntax = syntax(
+            "app.py",
+            "client.execute_statement(\n\
+             \x20   # SecretArn=arn,\n\
+             \x20   WorkgroupName=wg,\n\
+             \x20   Sql='SELECT ClusterIdentifier, DbUser FROM clusters')",
+        );
+
+        assert_eq!(
+            credential_action(&syntax),
+            Some("redshift-serverless:GetCredentials")
+        );
+    }
+}
@@ -0,0 +1,88 @@
+//! Permissions declared with `autopilot:require` annotations
+//!
+//! Each annotation becomes an enriched call of its own granting the declared action, so
+//! the permission is merged into the policies like the permissions of extracted calls,
+//! and explanations and provenance point at the annotation.
+
+use std::sync::Arc;
+
+use super::{
+    Action, EnrichedSdkMethodCall, Explanation, Operation, OperationSource, Reason, Resource,
+};
+use crate::extraction::shared::RequiredPermission;
+
+/// Enriched calls granting the permissions `autopilot:require` annotations declare
+pub(crate) fn enrich_required_permissions(
+    permissions: &[RequiredPermission],
+) -> Vec<EnrichedSdkMethodCall<'_>> {
+    permissions
+        .iter()
+        .filter_map(|permission| {
+            let call = &permission.call;
+            let service = call.possible_services.first()?;
+            let metadata = call.metadata.as_ref()?;
+            let operation = Arc::new(Operation {
+                service: service.clone(),
+                name: call.name.clone(),
+                source: OperationSource::Extracted(metadata.clone()),
+                _private: (),
+            });
+            // No ARN patterns render as `*`
+            let arn_patterns =
+                Some(permission.resources.clone()).filter(|resources| !resources.is_empty());
+            Some(EnrichedSdkMethodCall {
+                method_name: call.name.clone(),
+                service: service.clone(),
+                actions: vec![Action::new(
+                    permission.action.clone(),
+                    vec![Resource::new("*".to_string(), arn_patterns)],
+                    vec![],
+                    Explanation {
+                        reasons: vec![Reason::new(vec![operation])],
+                    },
+                )],
+                sdk_method_call: call,
+            })
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Location, SdkMethodCall};
+
+    #[test]
+    fn test_enrich_required_permissions() {
+        let permissions = vec![RequiredPermission {
+            action: "s3:GetObject".to_string(),
+            resources: vec!["arn:aws:s3:::my-bucket/*".to_string()],
+            call: SdkMethodCall {
+                name: "GetObject".to_string(),
+                possible_services: vec!["s3".to_string()],
+                metadata: Some(SdkMethodCallMetadata::new(
+                    "// autopilot:require s3:GetObject arn:aws:s3:::my-bucket/*".to_string(),
+                    Location::new(PathBuf::from("app.js"), (2, 1), (2, 59)),
+                )),
+            },
+        }];
+
+        let enriched = enrich_required_permissions(&permissions);
+
+        assert_eq!(enriched.len(), 1);
+        assert_eq!(enriched[0].service, "s3");
+        let action = &enriched[0].actions[0];
+        assert_eq!(action.name, "s3:GetObject");
+        assert_eq!(
+            action.resources[0].arn_patterns,
+            Some(vec!["arn:aws:s3:::my-bucket/*".to_string()])
+        );
+        assert!(matches!(
+            action.explanation.reasons[0].operations[0].source,
+            OperationSource::Extracted(_)
+        ));
+    }
+}
@@ -0,0 +1,230 @@
+//! Resource identifiers supplied by the user where the code doesn't name them
+//!
+//! ARN placeholders left after call-site, configuration and Terraform binding, e.g.
+//! `${BucketName}` of `s3.get_object(Bucket=bucket_for(tenant), ...)`, are looked up
+//! in recorded [`ResourceAnswers`]. Placeholders without an answer are asked of a
+//! [`ResourcePrompt`], and its answers are recorded so later runs reuse them.
+
+use std::collections::HashSet;
+use std::path::PathBuf;
+
+use crate::api::model::{ResourceAnswer, ResourceAnswers, ResourcePrompt, UnresolvedResource};
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::policy_generation::utils::{get_placeholder_regex, ENVIRONMENT_PLACEHOLDER_PREFIX};
+
+/// Placeholders policy generation fills in from the AWS context
+const CONTEXT_PLACEHOLDERS: &[&str] = &["partition", "region", "account"];
+
+/// Substitute answered values for the unresolved placeholders of each call's resources
+///
+/// Placeholders without a recorded answer are asked of `prompt`, if any, and the
+/// values it returns are added to `answers`.
+pub(crate) fn apply_resource_answers(
+    enriched_calls: &mut [EnrichedSdkMethodCall<'_>],
+    answers: &mut ResourceAnswers,
+    prompt: Option<&dyn ResourcePrompt>,
+) {
+    // Placeholders the prompt left unanswered, so they are asked only once
+    let mut declined: HashSet<(PathBuf, String, String)> = HashSet::new();
+
+    for call in enriched_calls {
+        let Some(metadata) = &call.sdk_method_call.metadata else {
+            continue;
+        };
+        let file = &metadata.location.file_path;
+        for action in &mut call.actions {
+            for resource in &mut action.resources {
+                let Some(patterns) = &mut resource.arn_patterns else {
+                    continue;
+                };
+                for pattern in patterns.iter_mut() {
+                    for placeholder in unresolved_placeholders(pattern) {
+                        let value = if let Some(value) =
+                            answers.value(file, &metadata.expr, &placeholder)
+                        {
+                            value.to_string()
+                        } else {
+                            let key = (file.clone(), metadata.expr.clone(), placeholder.clone());
+                            let Some(prompt) = prompt.filter(|_| !declined.contains(&key)) else {
+                                continue;
+                            };
+                            let question = UnresolvedResource {
+                                file: file.clone(),
+                                line: metadata.location.start_position.0,
+                                call: metadata.expr.clone(),
+                                action: action.name.clone(),
+                                arn_pattern: pattern.clone(),
+                                default: answers.default_value(&placeholder),
+                                placeholder: placeholder.clone(),
+                            };
+                            let Some(value) = prompt.resolve(&question) else {
+                                declined.insert(key);
+                                continue;
+                            };
+                            answers.answers.push(ResourceAnswer {
+                                file: file.clone(),
+                                call: metadata.expr.clone(),
+                                placeholder: placeholder.clone(),
+                                value: value.clone(),
+                            });
+                            value
+                        };
+                        *pattern = pattern.replace(&format!("${{{placeholder}}}"), &value);
+                    }
+                }
+            }
+        }
+    }
+}
+
+/// Placeholders of `pattern` neither the AWS context nor an environment variable fills in
+fn unresolved_placeholders(pattern: &str) -> Vec<String> {
+    let mut placeholders: Vec<String> = get_placeholder_regex()
+        .captures_iter(pattern)
+        .filter_map(|captures| captures.get(1))
+        .map(|placeholder| placeholder.as_str())
+        .filter(|placeholder| {
+            !placeholder.starts_with(ENVIRONMENT_PLACEHOLDER_PREFIX)
+                && !CONTEXT_PLACEHOLDERS.contains(&placeholder.to_lowercase().as_str())
+        })
+        .map(str::to_string)
+        .collect();
+    placeholders.dedup();
+    placeholders
+}
+
+#[cfg(test)]
+mod tests {
+    use std::sync::Mutex;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation, Resource};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Location, SdkMethodCall};
+
+    /// Prompt answering from a fixed list, recording the questions it was asked
+    #[derive(Debug, Default)]
+    struct ScriptedPrompt {
+        replies: Mutex<Vec<Option<String>>>,
+        questions: Mutex<Vec<UnresolvedResource>>,
+    }
+
+    impl ResourcePrompt for ScriptedPrompt {
+        fn resolve(&self, resource: &UnresolvedResource) -> Option<String> {
+            self.questions.lock().unwrap().push(resource.clone());
+            self.replies.lock().unwrap().remove(0)
+        }
+    }
+
+    fn sdk_call(expr: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "get_object".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                expr.to_string(),
+                Location::new(PathBuf::from("app.py"), (7, 1), (7, 40)),
+            )),
+        }
+    }
+
+    fn enriched<'a>(sdk_call: &'a SdkMethodCall, patterns: &[&str]) -> EnrichedSdkMethodCall<'a> {
+        EnrichedSdkMethodCall {
+            method_name: "get_object".to_string(),
+            service: "s3".to_string(),
+            actions: vec![Action::new(
+                "s3:GetObject".to_string(),
+                vec![Resource::new(
+                    "object".to_string(),
+                    Some(patterns.iter().map(ToString::to_string).collect()),
+                )],
+                vec![],
+                Explanation::default(),
+            )],
+            sdk_method_call: sdk_call,
+        }
+    }
+
+    fn arn_patterns(calls: &[EnrichedSdkMethodCall<'_>]) -> Vec<String> {
+        calls
+            .iter()
+            .flat_map(|call| &call.actions)
+            .flat_map(|action| &action.resources)
+            .flat_map(|resource| resource.arn_patterns.clone().unwrap_or_default())
+            .collect()
+    }
+
+    #[test]
+    fn test_recorded_answers_substitute_placeholders() {
+        let call = sdk_call("s3.get_object(Bucket=bucket, Key=key)");
+        let mut calls = vec![enriched(
+            &call,
+            &["arn:${Partition}:s3:::${BucketName}/${ObjectName}"],
+        )];
+        let mut answers = ResourceAnswers {
+            answers: vec![ResourceAnswer {
+                file: PathBuf::from("app.py"),
+                call: "s3.get_object(Bucket=bucket, Key=key)".to_string(),
+                placeholder: "BucketName".to_string(),
+                value: "reports".to_string(),
+            }],
+        };
+
+        apply_resource_answers(&mut calls, &mut answers, None);
+
+        assert_eq!(
+            arn_patterns(&calls),
+            vec!["arn:${Partition}:s3:::reports/${ObjectName}"]
+        );
+        assert_eq!(answers.answers.len(), 1);
+    }
+
+    #[test]
+    fn test_prompt_answers_are_recorded() {
+        let call = sdk_call("s3.get_object(Bucket=bucket, Key=key)");
+        let mut calls = vec![
+            enriched(
+                &call,
+                &["arn:${Partition}:s3:::${BucketName}/${ObjectName}"],
+            ),
+            enriched(
+                &call,
+                &["arn:${Partition}:s3:::${BucketName}/${env:PREFIX}*"],
+            ),
+        ];
+        let prompt = ScriptedPrompt {
+            replies: Mutex::new(vec![Some("reports".to_string()), None]),
+            ..ScriptedPrompt::default()
+        };
+        let mut answers = ResourceAnswers::default();
+
+        apply_resource_answers(&mut calls, &mut answers, Some(&prompt));
+
+        assert_eq!(
+            arn_patterns(&calls),
+            vec![
+                "arn:${Partition}:s3:::reports/${ObjectName}",
+                "arn:${Partition}:s3:::reports/${env:PREFIX}*",
+            ]
+        );
+        // The declined ObjectName is asked once; environment templates never
+        let questions = prompt.questions.lock().unwrap();
+        assert_eq!(
+            questions
+                .iter()
+                .map(|question| (question.placeholder.as_str(), question.line))
+                .collect::<Vec<_>>(),
+            vec![("BucketName", 7), ("ObjectName", 7)]
+        );
+        assert_eq!(questions[0].default, "*");
+        assert_eq!(
+            answers.answers,
+            vec![ResourceAnswer {
+                file: PathBuf::from("app.py"),
+                call: "s3.get_object(Bucket=bucket, Key=key)".to_string(),
+                placeholder: "BucketName".to_string(),
+                value: "reports".to_string(),
+            }]
+        );
+        assert_eq!(answers.default_value("BucketName"), "reports");
+    }
+}
@@ -4,17 +4,30 @@
 //! action maps with Service Definition Files to generate enriched method calls
 //! with complete IAM metadata.
 
-use std::collections::HashMap;
+use std::collections::{BTreeMap, HashMap};
 use std::sync::Arc;
 
 use super::{Action, Context, EnrichedSdkMethodCall, Explanation, OperationKey, Reason, Resource};
+use crate::enrichment::dependent_actions::dependent_actions;
 use crate::enrichment::operation_fas_map::{OperationFasMap, OperationFasMaps};
 use crate::enrichment::service_reference::ServiceReference;
-use crate::enrichment::{Condition, Operation, ServiceReferenceLoader};
+use crate::enrichment::{Condition, Operation, OperationSource, ServiceReferenceLoader};
 use crate::errors::{ExtractorError, Result};
 use crate::service_configuration::ServiceConfiguration;
 use crate::{SdkMethodCall, SdkType};
 
+/// Operations copying an object, which authorize reading the source with the
+/// actions in [`COPY_SOURCE_ACTIONS`]
+const COPY_OPERATIONS: &[&str] = &["s3:CopyObject", "s3:UploadPartCopy"];
+
+/// Actions a copy is authorized against the object it reads, not the one it writes
+const COPY_SOURCE_ACTIONS: &[&str] = &[
+    "s3:GetObject",
+    "s3:GetObjectVersion",
+    "s3:GetObjectTagging",
+    "s3:GetObjectVersionTagging",
+];
+
 /// A node in the FAS expansion dependency graph.
 #[derive(Clone, Debug)]
 struct FasNode {
@@ -155,9 +168,19 @@ pub(crate) struct ResourceMatcher {
     fas_maps: OperationFasMaps,
     sdk: SdkType,
     resource_cutoff: usize,
+    /// Whether ARN placeholders are bound to resources known from the call site
+    #[new(value = "true")]
+    call_site_resources: bool,
 }
 
 impl ResourceMatcher {
+    /// Set whether ARN placeholders are bound to resources known from the call site
+    #[must_use]
+    pub(crate) fn with_call_site_resources(mut self, call_site_resources: bool) -> Self {
+        self.call_site_resources = call_site_resources;
+        self
+    }
+
     /// Enrich a parsed method call with OperationAction maps, FAS maps, and Service
     /// Reference data
     pub(crate) async fn enrich_method_call<'b>(
@@ -219,6 +242,9 @@ impl ResourceMatcher {
 
         let initial =
             Operation::from_call(parsed_call, service_name, &self.service_cfg, self.sdk).await?;
+        // Call-site resource bindings describe the call's own resources; operations
+        // reached through FAS expansion on other services keep their ARN patterns.
+        let call_service = initial.service.clone();
 
         log::debug!("Expanded {initial:?}");
         // Use fixed-point algorithm to safely expand FAS operations until no new operations are found
@@ -235,6 +261,11 @@ impl ResourceMatcher {
             );
             log::debug!("  with context {:?}", op.context());
 
+            // Dependent actions depend on the input of the call itself
+            if op.service == call_service && !matches!(op.source, OperationSource::Fas(_)) {
+                enriched_actions.extend(dependent_actions(op, parsed_call));
+            }
+
             // Find the corresponding SDF using the cache
             let service_reference = service_reference_loader.load(&op.service).await?;
             match service_reference {
@@ -255,11 +286,27 @@ impl ResourceMatcher {
                                 operation_to_authorized_action.name
                             );
                             for action in &operation_to_authorized_action.authorized_actions {
-                                let enriched_resources = self
+                                let mut enriched_resources = self
                                     .find_resources_for_action_in_service_reference(
                                         &action.name,
                                         &service_reference,
                                     )?;
+                                if self.call_site_resources && op.service == call_service {
+                                    if let Some(metadata) = &parsed_call.metadata {
+                                        let resource_bindings = if COPY_OPERATIONS
+                                            .contains(&op.service_operation_name().as_str())
+                                            && COPY_SOURCE_ACTIONS.contains(&action.name.as_str())
+                                        {
+                                            &metadata.source_resource_bindings
+                                        } else {
+                                            &metadata.resource_bindings
+                                        };
+                                        enriched_resources = Self::bind_call_site_resources(
+                                            enriched_resources,
+                                            resource_bindings,
+                                        );
+                                    }
+                                }
                                 let enriched_resources =
                                     self.apply_resource_cutoff(enriched_resources);
 
@@ -363,6 +410,39 @@ impl ResourceMatcher {
         )))
     }
 
+    /// Substitute ARN placeholders with values known from the call site
+    ///
+    /// `arn:${Partition}:s3:::${BucketName}/${ObjectName}` with `BucketName` bound
+    /// to `my-bucket` becomes `arn:${Partition}:s3:::my-bucket/${ObjectName}`.
+    /// Unbound placeholders are left for policy generation to widen.
+    fn bind_call_site_resources(
+        resources: Vec<Resource>,
+        resource_bindings: &BTreeMap<String, String>,
+    ) -> Vec<Resource> {
+        if resource_bindings.is_empty() {
+            return resources;
+        }
+        resources
+            .into_iter()
+            .map(|resource| {
+                let arn_patterns = resource.arn_patterns.map(|patterns| {
+                    patterns
+                        .into_iter()
+                        .map(|pattern| {
+                            resource_bindings.iter().fold(
+                                pattern,
+                                |pattern, (placeholder, value)| {
+                                    pattern.replace(&format!("${{{placeholder}}}"), value)
+                                },
+                            )
+                        })
+                        .collect()
+                });
+                Resource::new(resource.name, arn_patterns)
+            })
+            .collect()
+    }
+
     fn apply_resource_cutoff(&self, resources: Vec<Resource>) -> Vec<Resource> {
         if self.resource_cutoff < resources.len() {
             vec![Resource::new("*".to_string(), None)]
@@ -441,6 +521,8 @@ mod tests {
             rename_services_service_reference: HashMap::new(),
             smithy_botocore_service_name_mapping: HashMap::new(),
             resource_overrides: HashMap::new(),
+            deprecated_operations: HashMap::new(),
+            no_permission_operations: HashMap::new(),
         })
     }
 
@@ -462,6 +544,8 @@ mod tests {
             .collect(),
             smithy_botocore_service_name_mapping: HashMap::new(),
             resource_overrides: HashMap::new(),
+            deprecated_operations: HashMap::new(),
+            no_permission_operations: HashMap::new(),
         })
     }
 
@@ -715,6 +799,199 @@ mod tests {
         assert!(action.resources.iter().all(|resource| resource.name != "*"));
     }
 
+    #[tokio::test]
+    async fn test_call_site_resource_bindings_substitute_arn_placeholders() {
+        use crate::extraction::SdkMethodCallMetadata;
+        use crate::Location;
+        use std::path::PathBuf;
+
+        let config = create_empty_service_config();
+        let matcher = ResourceMatcher::new(
+            config,
+            HashMap::new(),
+            SdkType::Boto3,
+            crate::DEFAULT_RESOURCE_CUTOFF,
+        );
+
+        let mock_server = wiremock::MockServer::start().await;
+        let loader = ServiceReferenceLoader::new(true)
+            .unwrap()
+            .with_mapping_url(mock_server.uri());
+        mock_s3_service_reference_with_resources(&mock_server, 1).await;
+
+        let metadata = SdkMethodCallMetadata::new(
+            "pd.read_csv(\"s3://resource-0/data.csv\")".to_string(),
+            Location::new(PathBuf::from("test.py"), (1, 1), (1, 35)),
+        )
+        .with_resource_bindings(BTreeMap::from([(
+            "ResourceName".to_string(),
+            "data.csv".to_string(),
+        )]));
+        let parsed_call = SdkMethodCall {
+            metadata: Some(metadata),
+            ..create_test_parsed_method_call()
+        };
+        let enriched_calls = matcher
+            .enrich_method_call(&parsed_call, &loader)
+            .await
+            .unwrap();
+
+        let action = &enriched_calls[0].actions[0];
+        assert_eq!(
+            action.resources,
+            vec![Resource::new(
+                "resource-0".to_string(),
+                Some(vec!["arn:${Partition}:s3:::resource-0/data.csv".to_string()])
+            )]
+        );
+    }
+
+    #[tokio::test]
+    async fn test_copy_source_bindings_scope_reads_of_the_source() {
+        use crate::extraction::SdkMethodCallMetadata;
+        use crate::Location;
+        use std::path::PathBuf;
+
+        let config = create_empty_service_config();
+        let matcher = ResourceMatcher::new(
+            config,
+            HashMap::new(),
+            SdkType::Boto3,
+            crate::DEFAULT_RESOURCE_CUTOFF,
+        );
+
+        let mock_server = wiremock::MockServer::start().await;
+        let loader = ServiceReferenceLoader::new(true)
+            .unwrap()
+            .with_mapping_url(mock_server.uri());
+        mock_remote_service_reference::mock_server_service_reference_response(
+            &mock_server,
+            "s3",
+            serde_json::json!({
+                "Name": "s3",
+                "Actions": [
+                    { "Name": "GetObject", "Resources": [{ "Name": "object" }] },
+                    { "Name": "PutObject", "Resources": [{ "Name": "object" }] }
+                ],
+                "Resources": [
+                    {
+                        "Name": "object",
+                        "ARNFormats": ["arn:${Partition}:s3:::${BucketName}/${ObjectName}"]
+                    }
+                ],
+                "Operations": [
+                    {
+                        "Name": "CopyObject",
+                        "AuthorizedActions": [
+                            { "Name": "GetObject", "Service": "s3" },
+                            { "Name": "PutObject", "Service": "s3" }
+                        ],
+                        "SDK": [
+                            { "Name": "s3", "Method": "copy_object", "Package": "Boto3" }
+                        ]
+                    }
+                ]
+            }),
+        )
+        .await;
+
+        let mut metadata = SdkMethodCallMetadata::new(
+            "s3.copy_object(CopySource=\"src/a.csv\", Bucket=\"dst\", Key=\"b.csv\")".to_string(),
+            Location::new(PathBuf::from("test.py"), (1, 1), (1, 70)),
+        )
+        .with_resource_bindings(BTreeMap::from([
+            ("BucketName".to_string(), "dst".to_string()),
+            ("ObjectName".to_string(), "b.csv".to_string()),
+        ]));
+        metadata.source_resource_bindings = BTreeMap::from([
+            ("BucketName".to_string(), "src".to_string()),
+            ("ObjectName".to_string(), "a.csv".to_string()),
+        ]);
+        let parsed_call = SdkMethodCall {
+            name: "copy_object".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(metadata),
+        };
+        let enriched_calls = matcher
+            .enrich_method_call(&parsed_call, &loader)
+            .await
+            .unwrap();
+
+        let resources = |name: &str| {
+            enriched_calls[0]
+                .actions
+                .iter()
+                .find(|action| action.name == name)
+                .unwrap_or_else(|| panic!("{name} should be granted"))
+                .resources
+                .clone()
+        };
+        assert_eq!(
+            resources("s3:GetObject"),
+            vec![Resource::new(
+                "object".to_string(),
+                Some(vec!["arn:${Partition}:s3:::src/a.csv".to_string()])
+            )]
+        );
+        assert_eq!(
+            resources("s3:PutObject"),
+            vec![Resource::new(
+                "object".to_string(),
+                Some(vec!["arn:${Partition}:s3:::dst/b.csv".to_string()])
+            )]
+        );
+    }
+
+    #[tokio::test]
+    async fn test_call_site_resource_bindings_can_be_disabled() {
+        use crate::extraction::SdkMethodCallMetadata;
+        use crate::Location;
+        use std::path::PathBuf;
+
+        let config = create_empty_service_config();
+        let matcher = ResourceMatcher::new(
+            config,
+            HashMap::new(),
+            SdkType::Boto3,
+            crate::DEFAULT_RESOURCE_CUTOFF,
+        )
+        .with_call_site_resources(false);
+
+        let mock_server = wiremock::MockServer::start().await;
+        let loader = ServiceReferenceLoader::new(true)
+            .unwrap()
+            .with_mapping_url(mock_server.uri());
+        mock_s3_service_reference_with_resources(&mock_server, 1).await;
+
+        let metadata = SdkMethodCallMetadata::new(
+            "s3.get_object(Bucket=\"resource-0\", Key=\"data.csv\")".to_string(),
+            Location::new(PathBuf::from("test.py"), (1, 1), (1, 50)),
+        )
+        .with_resource_bindings(BTreeMap::from([(
+            "ResourceName".to_string(),
+            "data.csv".to_string(),
+        )]));
+        let parsed_call = SdkMethodCall {
+            metadata: Some(metadata),
+            ..create_test_parsed_method_call()
+        };
+        let enriched_calls = matcher
+            .enrich_method_call(&parsed_call, &loader)
+            .await
+            .unwrap();
+
+        let action = &enriched_calls[0].actions[0];
+        assert_eq!(
+            action.resources,
+            vec![Resource::new(
+                "resource-0".to_string(),
+                Some(vec![
+                    "arn:${Partition}:s3:::resource-0/${ResourceName}".to_string()
+                ])
+            )]
+        );
+    }
+
     #[tokio::test]
     async fn test_fallback_for_service_without_operation_action_map() {
         let parsed_call = SdkMethodCall {
@@ -857,6 +1134,8 @@ mod tests {
             .collect(),
             smithy_botocore_service_name_mapping: HashMap::new(),
             resource_overrides: HashMap::new(),
+            deprecated_operations: HashMap::new(),
+            no_permission_operations: HashMap::new(),
         };
 
         // NOTE: execute-api:SendMessage is intentionally NOT included;
@@ -971,6 +1250,8 @@ mod tests {
             rename_services_service_reference: HashMap::new(),
             smithy_botocore_service_name_mapping: HashMap::new(),
             resource_overrides,
+            deprecated_operations: HashMap::new(),
+            no_permission_operations: HashMap::new(),
         };
 
         let (mock_server, service_reference_loader) =
@@ -1089,6 +1370,8 @@ mod tests {
             rename_services_service_reference: HashMap::new(),
             smithy_botocore_service_name_mapping: HashMap::new(),
             resource_overrides,
+            deprecated_operations: HashMap::new(),
+            no_permission_operations: HashMap::new(),
         };
 
         let (_mock_server, service_reference_loader) =
@@ -0,0 +1,247 @@
+//! S3 Express One Zone directory buckets
+//!
+//! Directory buckets, named `<base>--<zone id>--x-s3`, aren't authorized by `s3:` actions
+//! but by those of the `s3express` service. Their object operations are authorized once
+//! per session: the SDKs call `CreateSession` for the bucket and sign the operations with
+//! the session credentials, so they all require `s3express:CreateSession`. Bucket
+//! operations on the Regional endpoint have `s3express` actions of their own.
+
+use crate::enrichment::{Action, EnrichedSdkMethodCall, Operation, Resource};
+use crate::SdkType;
+
+/// Suffix of directory bucket names
+const DIRECTORY_BUCKET_SUFFIX: &str = "--x-s3";
+
+/// Placeholder of the bucket name in S3 ARNs, bound from the call site
+const BUCKET_PLACEHOLDER: &str = "BucketName";
+
+/// ARN of a directory bucket
+const DIRECTORY_BUCKET_ARN: &str = "arn:${Partition}:s3express:${Region}:${Account}:bucket/";
+
+/// Action authorizing the object operations of a directory bucket
+const CREATE_SESSION_ACTION: &str = "s3express:CreateSession";
+
+/// S3 operations only made on directory buckets
+const DIRECTORY_BUCKET_OPERATIONS: &[&str] = &["CreateSession", "ListDirectoryBuckets"];
+
+/// Actions of the bucket operations of directory buckets, by S3 operation. Other
+/// operations are object operations, authorized by [`CREATE_SESSION_ACTION`].
+const BUCKET_OPERATION_ACTIONS: &[(&str, &str)] = &[
+    ("CreateBucket", "s3express:CreateBucket"),
+    ("DeleteBucket", "s3express:DeleteBucket"),
+    (
+        "DeleteBucketLifecycle",
+        "s3express:PutLifecycleConfiguration",
+    ),
+    ("DeleteBucketPolicy", "s3express:DeleteBucketPolicy"),
+    (
+        "GetBucketEncryption",
+        "s3express:GetEncryptionConfiguration",
+    ),
+    (
+        "GetBucketLifecycleConfiguration",
+        "s3express:GetLifecycleConfiguration",
+    ),
+    ("GetBucketPolicy", "s3express:GetBucketPolicy"),
+    (
+        "ListDirectoryBuckets",
+        "s3express:ListAllMyDirectoryBuckets",
+    ),
+    (
+        "PutBucketEncryption",
+        "s3express:PutEncryptionConfiguration",
+    ),
+    (
+        "PutBucketLifecycleConfiguration",
+        "s3express:PutLifecycleConfiguration",
+    ),
+    ("PutBucketPolicy", "s3express:PutBucketPolicy"),
+];
+
+/// Whether `bucket` names a directory bucket
+fn is_directory_bucket(bucket: &str) -> bool {
+    bucket.ends_with(DIRECTORY_BUCKET_SUFFIX)
+}
+
+/// Grant the S3 calls made on directory buckets the `s3express` actions authorizing them
+/// instead of their `s3:` actions
+///
+/// Calls are made on directory buckets when the bucket they pass is named like one, or
+/// when their operation only exists for directory buckets, like `CreateSession`. Actions
+/// of other services, like `kms:GenerateDataKey` for encrypted objects, are kept. The
+/// buckets are granted by name with `call_site_resources`.
+pub(crate) fn grant_directory_bucket_calls(
+    enriched_calls: &mut [EnrichedSdkMethodCall<'_>],
+    sdk: SdkType,
+    call_site_resources: bool,
+) {
+    for call in enriched_calls
+        .iter_mut()
+        .filter(|call| call.service == "s3")
+    {
+        let operation = Operation::operation_name(call.sdk_method_call, sdk);
+        let bucket = call
+            .sdk_method_call
+            .metadata
+            .as_ref()
+            .and_then(|metadata| metadata.resource_bindings.get(BUCKET_PLACEHOLDER));
+        if !bucket.is_some_and(|bucket| is_directory_bucket(bucket))
+            && !DIRECTORY_BUCKET_OPERATIONS.contains(&operation.as_str())
+        {
+            continue;
+        }
+
+        let Some(explanation) = call
+            .actions
+            .iter()
+            .find(|action| action.service() == "s3" || action.service() == "s3express")
+            .map(|action| action.explanation.clone())
+        else {
+            continue;
+        };
+        let action = BUCKET_OPERATION_ACTIONS
+            .iter()
+            .find(|(bucket_operation, _)| *bucket_operation == operation)
+            .map_or(CREATE_SESSION_ACTION, |(_, action)| action);
+        let resources = if operation == "ListDirectoryBuckets" {
+            vec![]
+        } else {
+            let bucket = bucket.filter(|_| call_site_resources).map_or_else(
+                || format!("${{{BUCKET_PLACEHOLDER}}}"),
+                std::string::ToString::to_string,
+            );
+            vec![Resource::new(
+                "bucket".to_string(),
+                Some(vec![format!("{DIRECTORY_BUCKET_ARN}{bucket}")]),
+            )]
+        };
+        log::debug!(
+            "Granting {} as {action}: it's made on a directory bucket",
+            call.method_name
+        );
+        call.actions
+            .retain(|action| action.service() != "s3" && action.service() != "s3express");
+        call.actions.insert(
+            0,
+            Action::new(action.to_string(), resources, vec![], explanation),
+        );
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::collections::BTreeMap;
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::Explanation;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Location, SdkMethodCall};
+
+    fn call(name: &str, bucket: Option<&str>) -> SdkMethodCall {
+        let mut metadata = SdkMethodCallMetadata::new(
+            format!("s3.{name}(Bucket=bucket)"),
+            Location::new(PathBuf::from("app.py"), (3, 1), (3, 40)),
+        );
+        if let Some(bucket) = bucket {
+            metadata = metadata.with_resource_bindings(BTreeMap::from([(
+                BUCKET_PLACEHOLDER.to_string(),
+                bucket.to_string(),
+            )]));
+        }
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(metadata),
+        }
+    }
+
+    fn enriched<'a>(call: &'a SdkMethodCall, actions: &[&str]) -> EnrichedSdkMethodCall<'a> {
+        EnrichedSdkMethodCall {
+            method_name: call.name.clone(),
+            service: "s3".to_string(),
+            actions: actions
+                .iter()
+                .map(|action| {
+                    Action::new(
+                        (*action).to_string(),
+                        vec![Resource::new("object".to_string(), None)],
+                        vec![],
+                        Explanation::default(),
+                    )
+                })
+                .collect(),
+            sdk_method_call: call,
+        }
+    }
+
+    fn granted(call: &EnrichedSdkMethodCall<'_>) -> Vec<(String, Vec<String>)> {
+        call.actions
+            .iter()
+            .map(|action| {
+                let arns = action
+                    .resources
+                    .iter()
+                    .flat_map(|resource| resource.arn_patterns.clone().unwrap_or_default())
+                    .collect();
+                (action.name.clone(), arns)
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_directory_bucket_calls_are_granted_s3express_actions() {
+        let get_object = call("get_object", Some("logs--usw2-az1--x-s3"));
+        let put_policy = call("put_bucket_policy", Some("logs--usw2-az1--x-s3"));
+        let create_session = call("create_session", None);
+        let classic = call("get_object", Some("reports"));
+        let mut calls = vec![
+            enriched(&get_object, &["s3:GetObject", "kms:Decrypt"]),
+            enriched(&put_policy, &["s3:PutBucketPolicy"]),
+            enriched(&create_session, &["s3express:CreateSession"]),
+            enriched(&classic, &["s3:GetObject"]),
+        ];
+
+        grant_directory_bucket_calls(&mut calls, SdkType::Boto3, true);
+
+        let bucket = "arn:${Partition}:s3express:${Region}:${Account}:bucket/logs--usw2-az1--x-s3";
+        assert_eq!(
+            granted(&calls[0]),
+            vec![
+                (
+                    "s3express:CreateSession".to_string(),
+                    vec![bucket.to_string()]
+                ),
+                ("kms:Decrypt".to_string(), vec![]),
+            ]
+        );
+        assert_eq!(
+            granted(&calls[1]),
+            vec![(
+                "s3express:PutBucketPolicy".to_string(),
+                vec![bucket.to_string()]
+            )]
+        );
+        assert_eq!(
+            granted(&calls[2]),
+            vec![(
+                "s3express:CreateSession".to_string(),
+                vec![
+                    "arn:${Partition}:s3express:${Region}:${Account}:bucket/${BucketName}"
+                        .to_string()
+                ]
+            )]
+        );
+        assert_eq!(
+            granted(&calls[3]),
+            vec![("s3:GetObject".to_string(), vec![])]
+        );
+    }
+
+    #[test]
+    fn test_is_directory_bucket() {
+        assert!(is_directory_bucket("logs--use1-az4--x-s3"));
+        assert!(!is_directory_bucket("logs"));
+        assert!(!is_directory_bucket("x-s3"));
+    }
+}
@@ -0,0 +1,159 @@
+//! Multipart upload lifecycle of large S3 objects
+//!
+//! `CreateMultipartUpload`, `UploadPart` and `CompleteMultipartUpload` are authorized by
+//! `s3:PutObject`, which is all their calls are granted. The upload managers of the SDKs,
+//! which switch to multipart uploads past their part size threshold, also abort the uploads
+//! that fail and list the parts of those they resume; without `s3:AbortMultipartUpload` and
+//! `s3:ListMultipartUploadParts`, failed uploads leave their parts behind and resumed ones
+//! fail with `AccessDenied`.
+
+use crate::enrichment::{Action, EnrichedSdkMethodCall, Operation};
+use crate::SdkType;
+
+/// Operations of a multipart upload authorized by `s3:PutObject`
+const MULTIPART_OPERATIONS: &[&str] = &[
+    "CreateMultipartUpload",
+    "UploadPart",
+    "CompleteMultipartUpload",
+];
+
+/// Action authorizing the upload of an object
+const PUT_OBJECT_ACTION: &str = "s3:PutObject";
+
+/// Actions of the multipart upload lifecycle the upload managers need
+const MULTIPART_LIFECYCLE_ACTIONS: &[&str] =
+    &["s3:AbortMultipartUpload", "s3:ListMultipartUploadParts"];
+
+/// Grant the multipart upload calls of `enriched_calls` the actions of the whole multipart
+/// upload lifecycle, on the objects they're granted `s3:PutObject` on
+///
+/// Upload managers such as `upload_file`, `@aws-sdk/lib-storage`'s `Upload` or Go's
+/// `manager.Uploader` are expanded to the operations of a multipart upload by the
+/// extractors, so their calls get the actions too.
+pub(crate) fn expand_multipart_actions(
+    enriched_calls: &mut [EnrichedSdkMethodCall<'_>],
+    sdk: SdkType,
+) {
+    for call in enriched_calls
+        .iter_mut()
+        .filter(|call| call.service == "s3")
+    {
+        let operation = Operation::operation_name(call.sdk_method_call, sdk);
+        if !MULTIPART_OPERATIONS.contains(&operation.as_str()) {
+            continue;
+        }
+        let Some(put_object) = call
+            .actions
+            .iter()
+            .find(|action| action.name == PUT_OBJECT_ACTION)
+            .cloned()
+        else {
+            continue;
+        };
+        for lifecycle_action in MULTIPART_LIFECYCLE_ACTIONS {
+            if call
+                .actions
+                .iter()
+                .any(|action| action.name == *lifecycle_action)
+            {
+                continue;
+            }
+            log::debug!(
+                "Granting {lifecycle_action} to {}: it's part of a multipart upload",
+                call.method_name
+            );
+            call.actions.push(Action::new(
+                (*lifecycle_action).to_string(),
+                put_object.resources.clone(),
+                vec![],
+                put_object.explanation.clone(),
+            ));
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Explanation, Resource};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Location, SdkMethodCall};
+
+    fn call(name: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                format!("s3.{name}(Bucket='uploads')"),
+                Location::new(PathBuf::from("app.py"), (6, 1), (6, 40)),
+            )),
+        }
+    }
+
+    fn enriched<'a>(call: &'a SdkMethodCall, actions: &[&str]) -> EnrichedSdkMethodCall<'a> {
+        EnrichedSdkMethodCall {
+            method_name: call.name.clone(),
+            service: "s3".to_string(),
+            actions: actions
+                .iter()
+                .map(|action| {
+                    Action::new(
+                        (*action).to_string(),
+                        vec![Resource::new(
+                            "object".to_string(),
+                            Some(vec!["arn:${Partition}:s3:::uploads/*".to_string()]),
+                        )],
+                        vec![],
+                        Explanation::default(),
+                    )
+                })
+                .collect(),
+            sdk_method_call: call,
+        }
+    }
+
+    fn action_names(call: &EnrichedSdkMethodCall<'_>) -> Vec<&str> {
+        call.actions
+            .iter()
+            .map(|action| action.name.as_str())
+            .collect()
+    }
+
+    #[test]
+    fn test_multipart_uploads_are_granted_the_lifecycle_actions() {
+        let upload_part = call("upload_part");
+        let create = call("create_multipart_upload");
+        let put_object = call("put_object");
+        let mut calls = vec![
+            enriched(&upload_part, &["s3:PutObject"]),
+            enriched(&create, &["s3:PutObject", "s3:AbortMultipartUpload"]),
+            enriched(&put_object, &["s3:PutObject"]),
+        ];
+
+        expand_multipart_actions(&mut calls, SdkType::Boto3);
+
+        assert_eq!(
+            action_names(&calls[0]),
+            vec![
+                "s3:PutObject",
+                "s3:AbortMultipartUpload",
+                "s3:ListMultipartUploadParts"
+            ]
+        );
+        assert_eq!(
+            action_names(&calls[1]),
+            vec![
+                "s3:PutObject",
+                "s3:AbortMultipartUpload",
+                "s3:ListMultipartUploadParts"
+            ]
+        );
+        assert_eq!(action_names(&calls[2]), vec!["s3:PutObject"]);
+        assert_eq!(
+            calls[0].actions[2].resources[0].arn_patterns,
+            Some(vec!["arn:${Partition}:s3:::uploads/*".to_string()])
+        );
+    }
+}
@@ -0,0 +1,116 @@
+//! Selection of the S3 resource forms policies grant access through
+//!
+//! S3 actions authorize several resource types for the same data: `s3:GetObject` is
+//! granted on `arn:aws:s3:::bucket/key`, on objects reached through access points and
+//! through Object Lambda or Multi-Region Access Points. Teams that only use some of
+//! these forms select them, and the resources of the other forms are dropped.
+
+use crate::api::model::S3ResourceForm;
+use crate::enrichment::EnrichedSdkMethodCall;
+
+/// Services whose actions authorize S3 resource forms
+const S3_SERVICES: &[&str] = &["s3", "s3-object-lambda"];
+
+/// Drop the resources of S3 actions whose form isn't in `forms`
+///
+/// Resources of other types (e.g. `job`) are kept, and so are all resources of an
+/// action authorized only on unselected forms, such as `s3:GetAccessPoint`.
+pub(crate) fn select_s3_resource_forms(
+    enriched_calls: &mut [EnrichedSdkMethodCall<'_>],
+    forms: &[S3ResourceForm],
+) {
+    for action in enriched_calls
+        .iter_mut()
+        .flat_map(|call| &mut call.actions)
+        .filter(|action| S3_SERVICES.contains(&action.service()))
+    {
+        let selected = |name: &str| {
+            S3ResourceForm::of_resource_type(name).is_none_or(|form| forms.contains(&form))
+        };
+        if action
+            .resources
+            .iter()
+            .any(|resource| selected(&resource.name))
+        {
+            action.resources.retain(|resource| selected(&resource.name));
+        } else {
+            log::debug!(
+                "Keeping the resources of {}: it isn't authorized on a selected S3 form",
+                action.name
+            );
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation, Resource};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Location, SdkMethodCall};
+
+    fn action(name: &str, resource_types: &[&str]) -> Action {
+        Action::new(
+            name.to_string(),
+            resource_types
+                .iter()
+                .map(|resource_type| Resource::new((*resource_type).to_string(), None))
+                .collect(),
+            vec![],
+            Explanation::default(),
+        )
+    }
+
+    #[test]
+    fn test_unselected_s3_forms_are_dropped() {
+        let sdk_call = SdkMethodCall {
+            name: "get_object".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "s3.get_object(Bucket=bucket, Key=key)".to_string(),
+                Location::new(PathBuf::from("app.py"), (3, 1), (3, 40)),
+            )),
+        };
+        let mut calls = vec![EnrichedSdkMethodCall {
+            method_name: "get_object".to_string(),
+            service: "s3".to_string(),
+            actions: vec![
+                action(
+                    "s3:GetObject",
+                    &[
+                        "accesspointobject",
+                        "object",
+                        "objectlambdaaccesspoint",
+                        "multiregionaccesspointrequestarn",
+                    ],
+                ),
+                action("s3:GetAccessPoint", &["accesspoint"]),
+                action("s3:DescribeJob", &["job"]),
+                action("s3tables:GetTableBucket", &["bucket", "accesspoint"]),
+            ],
+            sdk_method_call: &sdk_call,
+        }];
+
+        select_s3_resource_forms(&mut calls, &[S3ResourceForm::Bucket]);
+
+        assert_eq!(
+            calls[0]
+                .actions
+                .iter()
+                .map(|action| action
+                    .resources
+                    .iter()
+                    .map(|resource| resource.name.as_str())
+                    .collect::<Vec<_>>())
+                .collect::<Vec<_>>(),
+            vec![
+                vec!["object"],
+                vec!["accesspoint"],
+                vec!["job"],
+                vec!["bucket", "accesspoint"],
+            ]
+        );
+    }
+}
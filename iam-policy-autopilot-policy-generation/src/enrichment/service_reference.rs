@@ -4,25 +4,38 @@
 //! from the filesystem with exact service name matching and caching for
 //! performance optimization.
 
+use crate::api::model::{MappingOverrides, ServiceMappingOverride};
 use crate::enrichment::Context;
 use crate::errors::ExtractorError;
+use crate::policy_generation::AccessLevel;
 use crate::providers::JsonProvider;
 use reqwest::{Client, Url};
-use serde::{Deserialize, Deserializer};
+use serde::{Deserialize, Deserializer, Serialize};
 use serde_json::Value;
 use std::{
-    collections::HashMap,
-    path::PathBuf,
+    collections::{BTreeMap, HashMap},
+    path::{Path, PathBuf},
+    sync::Arc,
     time::{Duration, SystemTime},
 };
 use tokio::fs;
-use tokio::sync::{OnceCell, RwLock};
+use tokio::sync::{OnceCell, RwLock, Semaphore};
+use tokio::task::JoinSet;
 
 type OperationName = String;
 const IAM_POLICY_AUTOPILOT: &str = "IAMPolicyAutopilot";
 // Cache files for 5 minutes.
 // We can allow cache duration override in future.
 const DEFAULT_CACHE_DURATION_IN_SECONDS: u64 = 300;
+/// Directory, under the user's home directory, of the service references saved by
+/// `update-mappings`, which are used instead of being fetched
+const SAVED_SERVICE_REFERENCES_DIR: &str = ".iam-policy-autopilot/service-reference";
+/// Service references fetched at once when saving them all
+const CONCURRENT_FETCHES: usize = 16;
+/// Age of pinned service references past which they're reported as stale, as AWS adds
+/// operations and actions to the service references continuously
+const STALE_PIN_AGE_IN_DAYS: u64 = 90;
+const SECONDS_PER_DAY: u64 = 24 * 60 * 60;
 /// Service Reference data structure
 ///
 /// Represents the complete service reference loaded from service reference endpoint.
@@ -137,6 +150,17 @@ pub(crate) struct Action {
     pub(crate) resources: Vec<String>,
     #[serde(rename = "ActionConditionKeys")]
     pub(crate) condition_keys: Vec<String>,
+    /// Access level of the action, if annotated
+    #[serde(skip)]
+    pub(crate) access_level: Option<AccessLevel>,
+}
+
+impl Action {
+    /// Whether the action's access level is Write, Permissions management or Tagging.
+    /// Actions without access level annotations count as writes.
+    pub(crate) fn is_write(&self) -> bool {
+        self.access_level.is_none_or(AccessLevel::is_write)
+    }
 }
 
 #[derive(Debug, Clone, Deserialize, PartialEq, Eq)]
@@ -225,6 +249,28 @@ where
         #[serde(rename = "ActionConditionKeys")]
         #[serde(default)]
         condition_keys: Vec<String>,
+        #[serde(rename = "Annotations")]
+        #[serde(default)]
+        annotations: Option<TempAnnotations>,
+    }
+
+    #[derive(Deserialize)]
+    struct TempAnnotations {
+        #[serde(rename = "Properties")]
+        properties: TempProperties,
+    }
+
+    #[derive(Deserialize)]
+    #[serde(rename_all = "PascalCase")]
+    struct TempProperties {
+        #[serde(default)]
+        is_list: bool,
+        #[serde(default)]
+        is_write: bool,
+        #[serde(default)]
+        is_permission_management: bool,
+        #[serde(default)]
+        is_tagging_only: bool,
     }
 
     let actions: Vec<TempAction> = Vec::deserialize(deserializer)?;
@@ -235,6 +281,20 @@ where
                 name: temp_action.name.clone(),
                 resources: temp_action.resources.into_iter().map(|r| r.name).collect(),
                 condition_keys: temp_action.condition_keys,
+                access_level: temp_action.annotations.map(|annotations| {
+                    let properties = annotations.properties;
+                    if properties.is_permission_management {
+                        AccessLevel::PermissionsManagement
+                    } else if properties.is_tagging_only {
+                        AccessLevel::Tagging
+                    } else if properties.is_write {
+                        AccessLevel::Write
+                    } else if properties.is_list {
+                        AccessLevel::List
+                    } else {
+                        AccessLevel::Read
+                    }
+                }),
             };
             (temp_action.name, action)
         })
@@ -312,6 +372,50 @@ pub(crate) struct RemoteServiceReferenceLoader {
     service_cache: RwLock<HashMap<String, (ServiceReference, SystemTime)>>,
     mapping_url: String,
     disable_file_system_cache: bool,
+    /// Directory of the service references saved by `update-mappings`, if any
+    saved_service_references_dir: Option<PathBuf>,
+    /// Mappings merged over those of the loaded service references
+    mapping_overrides: MappingOverrides,
+    /// Lockfile pinning the service references, if any
+    lockfile: Option<Lockfile>,
+}
+
+/// A lockfile pinning the service references policy generation uses, so the same
+/// operations map to the same actions across machines and over time
+#[derive(Debug)]
+struct Lockfile {
+    path: PathBuf,
+    lock: RwLock<ServiceReferenceLock>,
+}
+
+/// Content of a lockfile
+#[derive(Debug, Default, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+struct ServiceReferenceLock {
+    /// Pinned service references by service name
+    services: BTreeMap<String, PinnedServiceReference>,
+    /// Whether services were pinned since the lockfile was read
+    #[serde(skip)]
+    changed: bool,
+}
+
+/// A service reference pinned by a lockfile
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+struct PinnedServiceReference {
+    /// When the service reference was pinned, in seconds since the Unix epoch
+    pinned_at: u64,
+    /// The service reference, as served by the service reference endpoint
+    service_reference: Value,
+}
+
+/// Directory the service references saved by `update-mappings` are kept in:
+/// `~/.iam-policy-autopilot/service-reference`, using `$HOME` on Unix/macOS and
+/// `%USERPROFILE%` on Windows
+pub(crate) fn saved_service_references_dir() -> Option<PathBuf> {
+    std::env::var_os("HOME")
+        .or_else(|| std::env::var_os("USERPROFILE"))
+        .map(|home| PathBuf::from(home).join(SAVED_SERVICE_REFERENCES_DIR))
 }
 
 const DEFAULT_MAPPING_URL: &str = "https://servicereference.us-east-1.amazonaws.com";
@@ -327,6 +431,9 @@ impl RemoteServiceReferenceLoader {
             service_cache: RwLock::new(HashMap::new()),
             mapping_url,
             disable_file_system_cache,
+            saved_service_references_dir: saved_service_references_dir(),
+            mapping_overrides: MappingOverrides::default(),
+            lockfile: None,
         })
     }
 
@@ -341,6 +448,9 @@ impl RemoteServiceReferenceLoader {
             service_cache: RwLock::new(HashMap::new()),
             mapping_url: String::new(),
             disable_file_system_cache: true,
+            saved_service_references_dir: None,
+            mapping_overrides: MappingOverrides::default(),
+            lockfile: None,
         };
         // Pre-initialize with an empty mapping so no network call is ever made.
         let _ = loader
@@ -351,13 +461,99 @@ impl RemoteServiceReferenceLoader {
         Ok(loader)
     }
 
+    /// Merges `mapping_overrides` over the mappings of the loaded service references
+    pub(crate) fn with_mapping_overrides(mut self, mapping_overrides: MappingOverrides) -> Self {
+        self.mapping_overrides = mapping_overrides;
+        self
+    }
+
+    /// Pins the service references to those of the lockfile at `path`, which services
+    /// without a pinned service reference are added to by [`Self::write_lockfile`]
+    ///
+    /// A missing lockfile pins no services yet. Services pinned more than 90 days ago are
+    /// reported as stale.
+    pub(crate) fn with_lockfile(mut self, path: PathBuf) -> crate::errors::Result<Self> {
+        let lock = match std::fs::read_to_string(&path) {
+            Ok(content) => serde_json::from_str::<ServiceReferenceLock>(&content).map_err(|e| {
+                ExtractorError::service_reference_parse_error_with_source(
+                    path.display().to_string(),
+                    "Failed to parse the service reference lockfile".to_string(),
+                    e,
+                )
+            })?,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => ServiceReferenceLock::default(),
+            Err(e) => return Err(ExtractorError::file_system("read", &path, e)),
+        };
+
+        let now = seconds_since_epoch();
+        let stale: Vec<&str> = lock
+            .services
+            .iter()
+            .filter(|(_, pinned)| {
+                now.saturating_sub(pinned.pinned_at) > STALE_PIN_AGE_IN_DAYS * SECONDS_PER_DAY
+            })
+            .map(|(service, _)| service.as_str())
+            .collect();
+        if !stale.is_empty() {
+            log::warn!(
+                "The service references of {} pinned by {} are more than {STALE_PIN_AGE_IN_DAYS} \
+                 days old: operations and actions AWS added since aren't mapped. Remove them \
+                 from the lockfile to pin the latest ones",
+                stale.join(", "),
+                path.display()
+            );
+        }
+
+        self.lockfile = Some(Lockfile {
+            path,
+            lock: RwLock::new(lock),
+        });
+        Ok(self)
+    }
+
+    /// Writes the lockfile, if any, when services were pinned since it was read
+    pub(crate) async fn write_lockfile(&self) -> crate::errors::Result<()> {
+        let Some(lockfile) = &self.lockfile else {
+            return Ok(());
+        };
+        let mut lock = lockfile.lock.write().await;
+        if !lock.changed {
+            return Ok(());
+        }
+        let content = serde_json::to_string_pretty(&*lock).map_err(|e| {
+            ExtractorError::validation(format!(
+                "Failed to serialize the service reference lockfile: {e}"
+            ))
+        })?;
+        fs::write(&lockfile.path, content)
+            .await
+            .map_err(|e| ExtractorError::file_system("write", &lockfile.path, e))?;
+        log::debug!(
+            "Pinned {} service references in {}",
+            lock.services.len(),
+            lockfile.path.display()
+        );
+        lock.changed = false;
+        Ok(())
+    }
+
     /// Sets a custom mapping URL (e.g., a mock server) and resets the cached mapping
-    /// so the next call fetches from the new URL.
+    /// so the next call fetches from the new URL. Service references saved by
+    /// `update-mappings` are ignored, so they don't shadow those the URL serves.
     #[cfg(test)]
 
     pub(crate) fn with_mapping_url(mut self, url: String) -> Self {
         self.mapping_url = url;
         self.service_reference_mapping = OnceCell::new();
+        self.saved_service_references_dir = None;
+        self
+    }
+
+    /// Uses the service references saved in `directory`
+    #[cfg(test)]
+
+    pub(crate) fn with_saved_service_references_dir(mut self, directory: PathBuf) -> Self {
+        self.saved_service_references_dir = Some(directory);
         self
     }
 
@@ -482,9 +678,26 @@ impl RemoteServiceReferenceLoader {
         }
     }
 
+    /// The service reference of `service_name`, with the mapping overrides of the service
+    /// merged over it
+    ///
+    /// Services without a service reference have one of their overrides alone.
     pub(crate) async fn load(
         &self,
         service_name: &str,
+    ) -> crate::errors::Result<Option<ServiceReference>> {
+        let service_ref = self.load_service_reference(service_name).await?;
+        Ok(match self.mapping_overrides.service(service_name) {
+            Some(service_override) => {
+                Some(merge_overrides(service_name, service_ref, service_override))
+            }
+            None => service_ref,
+        })
+    }
+
+    async fn load_service_reference(
+        &self,
+        service_name: &str,
     ) -> crate::errors::Result<Option<ServiceReference>> {
         if let Some((cached, timestamp)) = self.service_cache.read().await.get(service_name) {
             if let Ok(elapsed) = SystemTime::now().duration_since(*timestamp) {
@@ -494,6 +707,26 @@ impl RemoteServiceReferenceLoader {
             }
         }
 
+        if let Some(lockfile) = &self.lockfile {
+            let service_ref = self.load_pinned(lockfile, service_name).await?;
+            if let Some(service_ref) = &service_ref {
+                self.service_cache.write().await.insert(
+                    service_name.to_string(),
+                    (service_ref.clone(), SystemTime::now()),
+                );
+            }
+            return Ok(service_ref);
+        }
+
+        // Service references saved by `update-mappings` take precedence
+        if let Some(service_ref) = self.load_saved(service_name).await {
+            self.service_cache.write().await.insert(
+                service_name.to_string(),
+                (service_ref.clone(), SystemTime::now()),
+            );
+            return Ok(Some(service_ref));
+        }
+
         // check temp file
         let cache_path = Self::get_cache_path(service_name);
         if !self.disable_file_system_cache && Self::is_cache_valid(&cache_path).await {
@@ -513,39 +746,8 @@ impl RemoteServiceReferenceLoader {
 
         match service_url {
             Some(service_url) => {
-                let service_reference_content = self
-                    .client
-                    .get(service_url.as_ref())
-                    .send()
-                    .await
-                    .map_err(|e| {
-                        ExtractorError::service_reference_parse_error_with_source(
-                            service_name,
-                            "Failed to fetch service reference data".to_string(),
-                            e,
-                        )
-                    })?
-                    .text()
-                    .await
-                    .map_err(|e| {
-                        ExtractorError::service_reference_parse_error_with_source(
-                            service_name,
-                            "Failed to read service reference response".to_string(),
-                            e,
-                        )
-                    })?;
-
-                let service_ref: ServiceReference = JsonProvider::parse(&service_reference_content)
-                    .await
-                    .map_err(|e| {
-                        ExtractorError::service_reference_parse_error_with_source(
-                            service_name,
-                            format!(
-                                "Failed to parse service reference content. Detailed error: {e}"
-                            ),
-                            e,
-                        )
-                    })?;
+                let (service_reference_content, service_ref) =
+                    Self::fetch(&self.client, service_name, service_url).await?;
                 // persist content into the temp file as well
                 if !self.disable_file_system_cache {
                     let _ = fs::write(&cache_path, &service_reference_content).await;
@@ -559,6 +761,267 @@ impl RemoteServiceReferenceLoader {
             None => Ok(None),
         }
     }
+
+    /// The service reference of `service_name` pinned by `lockfile`, pinning the saved or
+    /// latest one if it has none
+    async fn load_pinned(
+        &self,
+        lockfile: &Lockfile,
+        service_name: &str,
+    ) -> crate::errors::Result<Option<ServiceReference>> {
+        let parse_error = |e| {
+            ExtractorError::service_reference_parse_error_with_source(
+                service_name,
+                format!(
+                    "Failed to parse the service reference pinned by {}",
+                    lockfile.path.display()
+                ),
+                e,
+            )
+        };
+        let pinned = lockfile
+            .lock
+            .read()
+            .await
+            .services
+            .get(service_name)
+            .map(|pinned| pinned.service_reference.clone());
+        if let Some(service_reference) = pinned {
+            return serde_json::from_value(service_reference)
+                .map(Some)
+                .map_err(parse_error);
+        }
+
+        let content = match self.read_saved(service_name).await {
+            Some((_, content)) => content,
+            None => {
+                let mapping = self.get_or_init_mapping().await?;
+                let Some(service_url) = mapping.service_reference_mapping.get(service_name) else {
+                    return Ok(None);
+                };
+                Self::fetch(&self.client, service_name, service_url)
+                    .await?
+                    .0
+            }
+        };
+        let service_reference: Value = serde_json::from_str(&content).map_err(parse_error)?;
+        let service_ref = serde_json::from_value(service_reference.clone()).map_err(parse_error)?;
+        let mut lock = lockfile.lock.write().await;
+        lock.services.insert(
+            service_name.to_string(),
+            PinnedServiceReference {
+                pinned_at: seconds_since_epoch(),
+                service_reference,
+            },
+        );
+        lock.changed = true;
+        Ok(Some(service_ref))
+    }
+
+    /// The path and content of the service reference of `service_name` saved by
+    /// `update-mappings`, if any
+    async fn read_saved(&self, service_name: &str) -> Option<(PathBuf, String)> {
+        let path = self
+            .saved_service_references_dir
+            .as_ref()?
+            .join(format!("{service_name}.json"));
+        let content = fs::read_to_string(&path).await.ok()?;
+        Some((path, content))
+    }
+
+    /// The service reference of `service_name` saved by `update-mappings`, if any
+    async fn load_saved(&self, service_name: &str) -> Option<ServiceReference> {
+        let (path, content) = self.read_saved(service_name).await?;
+        match JsonProvider::parse::<ServiceReference>(&content).await {
+            Ok(service_ref) => Some(service_ref),
+            Err(e) => {
+                log::warn!(
+                    "Ignoring the saved service reference {}, which can't be parsed: {e}",
+                    path.display()
+                );
+                None
+            }
+        }
+    }
+
+    /// Fetch the service reference of `service_name` from `service_url`
+    ///
+    /// # Returns
+    /// The content of the service reference and its parsed form
+    async fn fetch(
+        client: &Client,
+        service_name: &str,
+        service_url: &Url,
+    ) -> crate::errors::Result<(String, ServiceReference)> {
+        let service_reference_content = client
+            .get(service_url.as_ref())
+            .send()
+            .await
+            .map_err(|e| {
+                ExtractorError::service_reference_parse_error_with_source(
+                    service_name,
+                    "Failed to fetch service reference data".to_string(),
+                    e,
+                )
+            })?
+            .text()
+            .await
+            .map_err(|e| {
+                ExtractorError::service_reference_parse_error_with_source(
+                    service_name,
+                    "Failed to read service reference response".to_string(),
+                    e,
+                )
+            })?;
+
+        let service_ref: ServiceReference = JsonProvider::parse(&service_reference_content)
+            .await
+            .map_err(|e| {
+            ExtractorError::service_reference_parse_error_with_source(
+                service_name,
+                format!("Failed to parse service reference content. Detailed error: {e}"),
+                e,
+            )
+        })?;
+        Ok((service_reference_content, service_ref))
+    }
+
+    /// Fetch the latest service reference of every service and save them to `directory`,
+    /// replacing those saved before
+    ///
+    /// The service references are written to a sibling directory first, so those saved
+    /// before are kept if any fetch fails.
+    ///
+    /// # Returns
+    /// Number of saved service references
+    pub(crate) async fn save_service_references(
+        &self,
+        directory: &Path,
+    ) -> crate::errors::Result<usize> {
+        let mut services: Vec<(String, Url)> = self
+            .get_or_init_mapping()
+            .await?
+            .service_reference_mapping
+            .iter()
+            .map(|(service, url)| (service.clone(), url.clone()))
+            .collect();
+        services.sort();
+
+        let staging = directory.with_extension("partial");
+        let _ = fs::remove_dir_all(&staging).await;
+        fs::create_dir_all(&staging)
+            .await
+            .map_err(|e| ExtractorError::file_system("create", &staging, e))?;
+
+        let semaphore = Arc::new(Semaphore::new(CONCURRENT_FETCHES));
+        let mut join_set = JoinSet::new();
+        for (service_name, service_url) in services.iter().cloned() {
+            let client = self.client.clone();
+            let semaphore = semaphore.clone();
+            join_set.spawn(async move {
+                let _permit = semaphore.acquire_owned().await.map_err(|e| {
+                    ExtractorError::validation(format!("Failed to acquire semaphore permit: {e}"))
+                })?;
+                let (content, _) = Self::fetch(&client, &service_name, &service_url).await?;
+                Ok::<_, ExtractorError>((service_name, content))
+            });
+        }
+
+        while let Some(result) = join_set.join_next().await {
+            let (service_name, content) = result.map_err(|e| {
+                ExtractorError::validation(format!("Service reference fetch task failed: {e}"))
+            })??;
+            let path = staging.join(format!("{service_name}.json"));
+            fs::write(&path, content)
+                .await
+                .map_err(|e| ExtractorError::file_system("write", &path, e))?;
+            log::debug!("Saved the service reference of {service_name}");
+        }
+
+        let _ = fs::remove_dir_all(directory).await;
+        fs::rename(&staging, directory)
+            .await
+            .map_err(|e| ExtractorError::file_system("rename", directory, e))?;
+        self.service_cache.write().await.clear();
+        Ok(services.len())
+    }
+}
+
+/// The current time in seconds since the Unix epoch
+fn seconds_since_epoch() -> u64 {
+    SystemTime::now()
+        .duration_since(SystemTime::UNIX_EPOCH)
+        .map_or(0, |elapsed| elapsed.as_secs())
+}
+
+/// `service_ref` of `service_name`, or an empty one for services without a service
+/// reference, with `service_override` merged over it
+///
+/// Overridden operations authorize the actions of the override instead of those of the
+/// service reference, keeping their SDK methods.
+fn merge_overrides(
+    service_name: &str,
+    service_ref: Option<ServiceReference>,
+    service_override: &ServiceMappingOverride,
+) -> ServiceReference {
+    let mut service_ref = service_ref.unwrap_or_else(|| ServiceReference {
+        actions: HashMap::new(),
+        service_name: service_name.to_string(),
+        resources: HashMap::new(),
+        operation_to_authorized_actions: None,
+        boto3_method_to_operation: HashMap::new(),
+    });
+
+    if !service_override.operations.is_empty() {
+        let prefix = service_ref.service_name.to_lowercase();
+        let operations = service_ref
+            .operation_to_authorized_actions
+            .get_or_insert_with(HashMap::new);
+        for (operation, actions) in &service_override.operations {
+            let name = format!("{prefix}:{operation}");
+            let sdk = operations
+                .remove(&name)
+                .map(|existing| existing.sdk)
+                .unwrap_or_default();
+            let authorized_actions = actions
+                .iter()
+                .map(|action| AuthorizedAction {
+                    name: action.clone(),
+                    service: action.split(':').next().unwrap_or_default().to_string(),
+                    context: None,
+                })
+                .collect();
+            log::debug!("Overriding the actions of {name} with {actions:?}");
+            operations.insert(
+                name.clone(),
+                Operation {
+                    name,
+                    authorized_actions,
+                    sdk,
+                },
+            );
+        }
+    }
+
+    for (action, resources) in &service_override.actions {
+        service_ref
+            .actions
+            .entry(action.clone())
+            .or_insert_with(|| Action {
+                name: action.clone(),
+                resources: Vec::new(),
+                condition_keys: Vec::new(),
+                access_level: None,
+            })
+            .resources
+            .clone_from(resources);
+    }
+    for (resource, arn_format) in &service_override.resources {
+        service_ref
+            .resources
+            .insert(resource.clone(), vec![arn_format.clone()]);
+    }
+    service_ref
 }
 
 #[cfg(test)]
@@ -765,6 +1228,136 @@ mod tests {
         let _ = fs::remove_file(&cache_path).await;
     }
 
+    #[tokio::test]
+    async fn test_saved_service_references_are_used_offline() {
+        let (_mock_server, loader) =
+            mock_remote_service_reference::setup_mock_server_with_loader().await;
+        let home = tempfile::tempdir().unwrap();
+        let directory = home.path().join("service-reference");
+        std::fs::create_dir_all(&directory).unwrap();
+        std::fs::write(directory.join("retired.json"), "{}").unwrap();
+
+        let saved = loader.save_service_references(&directory).await.unwrap();
+        assert_eq!(saved, 1);
+        assert!(directory.join("s3.json").exists());
+        // Services saved before are replaced
+        assert!(!directory.join("retired.json").exists());
+        assert!(!directory.with_extension("partial").exists());
+
+        // An unreachable endpoint isn't needed for the saved services
+        let offline = RemoteServiceReferenceLoader::new(true)
+            .unwrap()
+            .with_mapping_url("http://127.0.0.1:1".to_string())
+            .with_saved_service_references_dir(directory);
+        let service_ref = offline.load("s3").await.unwrap().unwrap();
+        assert_eq!(service_ref.service_name, "s3");
+        assert!(offline.load("sqs").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn test_mapping_overrides_are_merged_over_service_references() {
+        let (_mock_server, loader) =
+            mock_remote_service_reference::setup_mock_server_with_loader().await;
+        let overrides: MappingOverrides = serde_json::from_str(
+            r#"{
+                "Services": {
+                    "s3": {
+                        "Operations": {
+                            "GetObject": ["s3:GetObject", "kms:Decrypt"],
+                            "PreviewOperation": ["s3:PreviewAction"]
+                        }
+                    },
+                    "things": {
+                        "Operations": {"InvokeThing": ["things:InvokeThing"]},
+                        "Actions": {"InvokeThing": ["thing"]},
+                        "Resources": {
+                            "thing": "arn:${Partition}:things:${Region}:${Account}:thing/${ThingName}"
+                        }
+                    }
+                }
+            }"#,
+        )
+        .unwrap();
+        let loader = loader.with_mapping_overrides(overrides);
+
+        let s3 = loader.load("s3").await.unwrap().unwrap();
+        let operations = s3.operation_to_authorized_actions.as_ref().unwrap();
+        let actions: Vec<&str> = operations["s3:GetObject"]
+            .authorized_actions
+            .iter()
+            .map(|action| action.name.as_str())
+            .collect();
+        assert_eq!(actions, vec!["s3:GetObject", "kms:Decrypt"]);
+        assert_eq!(
+            operations["s3:GetObject"].authorized_actions[1].service,
+            "kms"
+        );
+        assert!(operations.contains_key("s3:PreviewOperation"));
+        // Overridden operations keep their SDK methods
+        assert!(!operations["s3:GetObject"].sdk.is_empty());
+        assert!(s3.actions.contains_key("AbortMultipartUpload"));
+
+        // The service has no service reference
+        let things = loader.load("things").await.unwrap().unwrap();
+        assert_eq!(things.service_name, "things");
+        assert_eq!(things.actions["InvokeThing"].resources, vec!["thing"]);
+        assert_eq!(
+            things.resources["thing"],
+            vec!["arn:${Partition}:things:${Region}:${Account}:thing/${ThingName}"]
+        );
+
+        assert!(loader.load("sqs").await.unwrap().is_none());
+    }
+
+    #[tokio::test]
+    async fn test_lockfile_pins_service_references() {
+        let (_mock_server, loader) =
+            mock_remote_service_reference::setup_mock_server_with_loader().await;
+        let directory = tempfile::tempdir().unwrap();
+        let path = directory.path().join("service-reference.lock");
+
+        let loader = loader.with_lockfile(path.clone()).unwrap();
+        let service_ref = loader.load("s3").await.unwrap().unwrap();
+        loader.write_lockfile().await.unwrap();
+        let lock: ServiceReferenceLock =
+            serde_json::from_str(&std::fs::read_to_string(&path).unwrap()).unwrap();
+        assert_eq!(lock.services.keys().collect::<Vec<_>>(), vec!["s3"]);
+
+        // The pinned service references are used without the endpoint
+        let offline = RemoteServiceReferenceLoader::new(true)
+            .unwrap()
+            .with_mapping_url("http://127.0.0.1:1".to_string())
+            .with_lockfile(path.clone())
+            .unwrap();
+        assert_eq!(offline.load("s3").await.unwrap().unwrap(), service_ref);
+        assert!(offline.load("sqs").await.is_err());
+    }
+
+    #[tokio::test]
+    async fn test_invalid_lockfile_is_rejected() {
+        let directory = tempfile::tempdir().unwrap();
+        let path = directory.path().join("service-reference.lock");
+        std::fs::write(&path, "not json").unwrap();
+
+        let loader = RemoteServiceReferenceLoader::new(true).unwrap();
+        assert!(loader.with_lockfile(path).is_err());
+    }
+
+    #[tokio::test]
+    async fn test_failed_update_keeps_saved_service_references() {
+        let home = tempfile::tempdir().unwrap();
+        let directory = home.path().join("service-reference");
+        std::fs::create_dir_all(&directory).unwrap();
+        std::fs::write(directory.join("s3.json"), "{}").unwrap();
+
+        let loader = RemoteServiceReferenceLoader::new(true)
+            .unwrap()
+            .with_mapping_url("http://127.0.0.1:1".to_string());
+
+        assert!(loader.save_service_references(&directory).await.is_err());
+        assert!(directory.join("s3.json").exists());
+    }
+
     #[tokio::test]
     async fn test_service_reference_deserialization() {
         let json = r#"{
@@ -809,6 +1402,50 @@ mod tests {
         assert_eq!(operation.authorized_actions[0].name, "s3:GetObject");
     }
 
+    #[tokio::test]
+    async fn test_action_access_level_deserialization() {
+        let json = r#"{
+            "Name": "s3",
+            "Actions": [
+                {
+                    "Name": "GetObject",
+                    "Annotations": {"Properties": {"IsList": false, "IsWrite": false}}
+                },
+                {
+                    "Name": "ListBucket",
+                    "Annotations": {"Properties": {"IsList": true, "IsWrite": false}}
+                },
+                {
+                    "Name": "PutBucketPolicy",
+                    "Annotations": {"Properties": {"IsPermissionManagement": true}}
+                },
+                {
+                    "Name": "PutObject",
+                    "Annotations": {"Properties": {"IsWrite": true}}
+                },
+                {
+                    "Name": "DeleteObject"
+                }
+            ],
+            "Resources": []
+        }"#;
+
+        let service_ref: ServiceReference = serde_json::from_str(json).unwrap();
+        assert!(!service_ref.actions["GetObject"].is_write());
+        assert!(!service_ref.actions["ListBucket"].is_write());
+        assert!(service_ref.actions["PutBucketPolicy"].is_write());
+        assert!(service_ref.actions["PutObject"].is_write());
+        assert!(service_ref.actions["DeleteObject"].is_write());
+        assert_eq!(
+            service_ref.actions["ListBucket"].access_level,
+            Some(AccessLevel::List)
+        );
+        assert_eq!(
+            service_ref.actions["PutBucketPolicy"].access_level,
+            Some(AccessLevel::PermissionsManagement)
+        );
+    }
+
     #[tokio::test]
     async fn test_service_reference_deserialization_empty_authorized_actions() {
         let json = r#"{
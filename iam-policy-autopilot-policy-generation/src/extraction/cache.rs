@@ -0,0 +1,355 @@
+//! On-disk cache of the SDK calls extracted from source files
+//!
+//! Each file's calls are cached under a SHA-256 digest of the version of the crate, the
+//! language, the file's path and content, and the project-wide inputs of its extraction,
+//! so runs on unchanged sources, e.g. a CI run on every push to a monorepo, read the calls
+//! back instead of analyzing the files again.
+//!
+//! The calls of a file depend on the constants and client factories of all files of its
+//! language, and for JavaScript and TypeScript on the `tsconfig.json` and `package.json`
+//! of their directories, so changing any of them analyzes every file of the language
+//! again. Java files are analyzed on their own, and only changed ones are analyzed again.
+//! The files of the options of the run, e.g. the wrapper definitions and the plugins, are
+//! part of the key as well.
+
+use std::collections::BTreeSet;
+use std::fmt::Write;
+use std::path::{Path, PathBuf};
+
+use serde::{Deserialize, Serialize};
+use sha2::{Digest, Sha256};
+
+use crate::extraction::SourceFile;
+use crate::{Language, SdkMethodCall};
+
+/// Build of the cached entries, part of every key, so entries of other releases, which
+/// may find other calls or serialize [`SdkMethodCall`] differently, are not read back
+const EXTRACTION_CACHE_VERSION: &str = env!("CARGO_PKG_VERSION");
+
+/// Project configuration files the JavaScript and TypeScript extraction reads in the
+/// directories of the source files
+const PROJECT_CONFIG_FILES: &[&str] = &["tsconfig.json", "package.json"];
+
+/// The cached extraction of one source file
+#[derive(Debug, Clone, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct CachedFile {
+    /// SDK calls of the file
+    pub(crate) methods: Vec<SdkMethodCall>,
+    /// Warnings of the extraction the file was analyzed in
+    pub(crate) warnings: Vec<String>,
+}
+
+/// The cached calls of the source files of a language
+#[derive(Debug)]
+pub(crate) struct ExtractionCache {
+    /// Directory holding the entries
+    cache_dir: PathBuf,
+    language: Language,
+    /// Digest of the inputs of the extraction of every file: the project-wide sources
+    /// and configuration, and the files of the options
+    project: String,
+}
+
+impl ExtractionCache {
+    /// The cache in `cache_dir` of the extraction of `source_files` in `language`, with
+    /// the options read from the `inputs` files, e.g. the wrapper definitions
+    pub(crate) fn new(
+        cache_dir: &Path,
+        language: Language,
+        source_files: &[SourceFile],
+        inputs: &[PathBuf],
+    ) -> Self {
+        let mut hasher = Sha256::new();
+        if language != Language::Java {
+            let mut project: Vec<&SourceFile> = source_files.iter().collect();
+            project.sort_by(|a, b| a.path.cmp(&b.path));
+            for source_file in project {
+                hash_file(
+                    &mut hasher,
+                    &source_file.path,
+                    Some(source_file.content.as_bytes()),
+                );
+            }
+        }
+        if matches!(language, Language::JavaScript | Language::TypeScript) {
+            let directories: BTreeSet<&Path> = source_files
+                .iter()
+                .flat_map(|source_file| source_file.path.ancestors().skip(1))
+                .collect();
+            for directory in directories {
+                for name in PROJECT_CONFIG_FILES {
+                    let path = directory.join(name);
+                    if let Ok(content) = std::fs::read(&path) {
+                        hash_file(&mut hasher, &path, Some(&content));
+                    }
+                }
+            }
+        }
+        for input in inputs {
+            let content = std::fs::read(input).ok();
+            hash_file(&mut hasher, input, content.as_deref());
+        }
+        Self {
+            cache_dir: cache_dir.to_path_buf(),
+            language,
+            project: hex_digest(hasher),
+        }
+    }
+
+    /// File holding the entry of `source_file`
+    fn entry_path(&self, source_file: &SourceFile) -> PathBuf {
+        let mut hasher = Sha256::new();
+        hasher.update(EXTRACTION_CACHE_VERSION.as_bytes());
+        hasher.update([0]);
+        hasher.update(self.language.to_string().as_bytes());
+        hasher.update([0]);
+        hasher.update(self.project.as_bytes());
+        hash_file(
+            &mut hasher,
+            &source_file.path,
+            Some(source_file.content.as_bytes()),
+        );
+        let key = hex_digest(hasher);
+        self.cache_dir.join(format!("extraction-{key}.json"))
+    }
+
+    /// The cached extraction of `source_file`, if it was extracted before unchanged
+    ///
+    /// Unreadable entries are reported and treated as missing, so a corrupted cache
+    /// only costs an analysis.
+    pub(crate) fn load(&self, source_file: &SourceFile) -> Option<CachedFile> {
+        let path = self.entry_path(source_file);
+        let content = match std::fs::read_to_string(&path) {
+            Ok(content) => content,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return None,
+            Err(e) => {
+                log::warn!(
+                    "Failed to read the extraction cache {}: {e}",
+                    path.display()
+                );
+                return None;
+            }
+        };
+        match serde_json::from_str(&content) {
+            Ok(entry) => {
+                log::debug!(
+                    "Read the extracted calls of {} from {}",
+                    source_file.path.display(),
+                    path.display()
+                );
+                Some(entry)
+            }
+            Err(e) => {
+                log::warn!(
+                    "Ignoring the invalid extraction cache {}: {e}",
+                    path.display()
+                );
+                None
+            }
+        }
+    }
+
+    /// Cache the extraction of `source_file`
+    ///
+    /// Failing to write the cache doesn't fail the extraction; the entry is written to a
+    /// temporary file first, so concurrent runs never read a partial entry.
+    pub(crate) fn store(&self, source_file: &SourceFile, entry: &CachedFile) {
+        let path = self.entry_path(source_file);
+        let result = serde_json::to_string(entry)
+            .map_err(std::io::Error::other)
+            .and_then(|content| {
+                std::fs::create_dir_all(&self.cache_dir)?;
+                let temporary = path.with_extension(format!("{}.tmp", std::process::id()));
+                std::fs::write(&temporary, content)?;
+                std::fs::rename(&temporary, &path)
+            });
+        match result {
+            Ok(()) => log::debug!(
+                "Cached the extracted calls of {} in {}",
+                source_file.path.display(),
+                path.display()
+            ),
+            Err(e) => log::warn!(
+                "Failed to write the extraction cache {}: {e}",
+                path.display()
+            ),
+        }
+    }
+}
+
+/// Add the path and content of a file to `hasher`, `None` for a missing file
+fn hash_file(hasher: &mut Sha256, path: &Path, content: Option<&[u8]>) {
+    hasher.update(path.to_string_lossy().as_bytes());
+    hasher.update([0]);
+    match content {
+        Some(content) => {
+            hasher.update((content.len() as u64).to_le_bytes());
+            hasher.update(content);
+        }
+        None => hasher.update(u64::MAX.to_le_bytes()),
+    }
+}
+
+/// The hexadecimal digest of `hasher`
+fn hex_digest(hasher: Sha256) -> String {
+    hasher
+        .finalize()
+        .iter()
+        .fold(String::with_capacity(64), |mut key, byte| {
+            let _ = write!(key, "{byte:02x}");
+            key
+        })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::Location;
+
+    fn source_file(path: &str, content: &str) -> SourceFile {
+        SourceFile::with_language(PathBuf::from(path), content.to_string(), Language::Python)
+    }
+
+    #[test]
+    fn test_cached_calls_are_read_back() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let app = source_file("app.py", "s3.get_object(Bucket='b', Key='k')\n");
+        let jobs = source_file("jobs.py", "import boto3\n");
+        let project = [app.clone(), jobs.clone()];
+        let cache = ExtractionCache::new(cache_dir.path(), Language::Python, &project, &[]);
+        let entry = CachedFile {
+            methods: vec![SdkMethodCall {
+                name: "get_object".to_string(),
+                possible_services: vec!["s3".to_string()],
+                metadata: Some(SdkMethodCallMetadata::new(
+                    "s3.get_object(Bucket='b', Key='k')".to_string(),
+                    Location::new(PathBuf::from("app.py"), (1, 1), (1, 35)),
+                )),
+            }],
+            warnings: vec!["Skipped app.py:3, a dynamic client".to_string()],
+        };
+
+        assert!(cache.load(&app).is_none());
+        cache.store(&app, &entry);
+
+        let cache = ExtractionCache::new(cache_dir.path(), Language::Python, &project, &[]);
+        assert_eq!(cache.load(&app), Some(entry));
+        // Entries are kept per file
+        assert!(cache.load(&jobs).is_none());
+    }
+
+    #[test]
+    fn test_changed_sources_miss_the_cache() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let app = source_file("app.py", "import boto3\n");
+        let settings = source_file("settings.py", "QUEUE_SERVICE = 'sqs'\n");
+        ExtractionCache::new(
+            cache_dir.path(),
+            Language::Python,
+            &[app.clone(), settings],
+            &[],
+        )
+        .store(&app, &CachedFile::default());
+
+        let changed = source_file("app.py", "import boto3\nimport os\n");
+        assert!(
+            ExtractionCache::new(cache_dir.path(), Language::Python, &[changed.clone()], &[])
+                .load(&changed)
+                .is_none()
+        );
+        let moved = source_file("main.py", "import boto3\n");
+        assert!(
+            ExtractionCache::new(cache_dir.path(), Language::Python, &[moved.clone()], &[])
+                .load(&moved)
+                .is_none()
+        );
+        assert!(
+            ExtractionCache::new(cache_dir.path(), Language::Go, &[app.clone()], &[])
+                .load(&app)
+                .is_none()
+        );
+    }
+
+    #[test]
+    fn test_changed_project_inputs_miss_the_cache() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        // The service of the client of app.py is a constant of settings.py
+        let app = source_file(
+            "app.py",
+            "import boto3\nfrom settings import QUEUE_SERVICE\nboto3.client(QUEUE_SERVICE).list_queues()\n",
+        );
+        let settings = source_file("settings.py", "QUEUE_SERVICE = 'sqs'\n");
+        let wrappers = cache_dir.path().join("wrappers.json");
+        std::fs::write(&wrappers, "[]").unwrap();
+        let inputs = [wrappers.clone()];
+        let project = [app.clone(), settings];
+        ExtractionCache::new(cache_dir.path(), Language::Python, &project, &inputs)
+            .store(&app, &CachedFile::default());
+        assert!(
+            ExtractionCache::new(cache_dir.path(), Language::Python, &project, &inputs)
+                .load(&app)
+                .is_some()
+        );
+
+        let changed = [
+            app.clone(),
+            source_file("settings.py", "QUEUE_SERVICE = 'sns'\n"),
+        ];
+        assert!(
+            ExtractionCache::new(cache_dir.path(), Language::Python, &changed, &inputs)
+                .load(&app)
+                .is_none()
+        );
+        assert!(
+            ExtractionCache::new(cache_dir.path(), Language::Python, &project, &[])
+                .load(&app)
+                .is_none()
+        );
+        std::fs::write(&wrappers, "[{}]").unwrap();
+        assert!(
+            ExtractionCache::new(cache_dir.path(), Language::Python, &project, &inputs)
+                .load(&app)
+                .is_none()
+        );
+    }
+
+    #[test]
+    fn test_java_files_are_cached_independently() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let java_file = |path: &str, content: &str| {
+            SourceFile::with_language(PathBuf::from(path), content.to_string(), Language::Java)
+        };
+        let app = java_file("App.java", "class App {}\n");
+        ExtractionCache::new(
+            cache_dir.path(),
+            Language::Java,
+            &[app.clone(), java_file("Jobs.java", "class Jobs {}\n")],
+            &[],
+        )
+        .store(&app, &CachedFile::default());
+
+        assert!(ExtractionCache::new(
+            cache_dir.path(),
+            Language::Java,
+            &[
+                app.clone(),
+                java_file("Jobs.java", "class Jobs { int n; }\n")
+            ],
+            &[],
+        )
+        .load(&app)
+        .is_some());
+    }
+
+    #[test]
+    fn test_invalid_entries_are_ignored() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let app = source_file("app.py", "import boto3\n");
+        let cache = ExtractionCache::new(cache_dir.path(), Language::Python, &[app.clone()], &[]);
+        std::fs::write(cache.entry_path(&app), "not json").unwrap();
+
+        assert!(cache.load(&app).is_none());
+    }
+}
@@ -5,23 +5,36 @@
 //! extraction process, coordinating file system operations, JSON parsing, and tree-sitter
 //! source code analysis.
 
+use std::collections::{HashMap, HashSet};
 use std::fmt::Write;
 use std::path::{Path, PathBuf};
 use std::sync::Arc;
-use std::time::Instant;
+use std::time::{Duration, Instant};
 use tokio::task::JoinSet;
 
 use crate::errors::{ExtractorError, Result};
-use crate::extraction::extractor::Extractor;
-use crate::extraction::framework::{extract, LanguageExtractor};
+use crate::extraction::cache::{CachedFile, ExtractionCache};
+use crate::extraction::extractor::{Extractor, ExtractorResult};
+use crate::extraction::framework::{default_jobs, extract_with_jobs, LanguageExtractor};
 use crate::extraction::java::JavaLanguageExtractor;
+use crate::extraction::progress::{Progress, ProgressObserver};
 use crate::extraction::sdk_model::ServiceDiscovery;
+use crate::extraction::shared::bind_literal_resources;
 use crate::extraction::{self, ExtractedMethods, ExtractionMetadata, SourceFile};
 use crate::Language;
 
 /// Core business logic for extracting method definitions and SDK method calls from source code.
 #[non_exhaustive]
-pub struct Engine;
+pub struct Engine {
+    /// Number of files analyzed concurrently
+    jobs: usize,
+    /// Receives each analyzed file
+    progress: Option<Arc<dyn ProgressObserver>>,
+    /// Directory caching the calls extracted from a set of source files
+    cache_dir: Option<PathBuf>,
+    /// Files of the options of the extraction, whose changes invalidate the cache
+    cache_inputs: Vec<PathBuf>,
+}
 
 impl Default for Engine {
     fn default() -> Self {
@@ -33,7 +46,43 @@ impl Engine {
     /// Create a new SDK method extractor with the specified providers.
     #[must_use]
     pub fn new() -> Self {
-        Self
+        Self {
+            jobs: default_jobs(),
+            progress: None,
+            cache_dir: None,
+            cache_inputs: Vec::new(),
+        }
+    }
+
+    /// Analyze up to `jobs` files concurrently, or one file per available CPU if `None`.
+    #[must_use]
+    pub fn with_jobs(mut self, jobs: Option<usize>) -> Self {
+        self.jobs = jobs.map_or_else(default_jobs, |jobs| jobs.max(1));
+        self
+    }
+
+    /// Report each analyzed file to `progress`, if any.
+    #[must_use]
+    pub fn with_progress(mut self, progress: Option<Arc<dyn ProgressObserver>>) -> Self {
+        self.progress = progress;
+        self
+    }
+
+    /// Cache the calls extracted from each source file in `cache_dir`, if any, so
+    /// extracting again reads the calls of unchanged files back and analyzes only the
+    /// changed ones.
+    #[must_use]
+    pub fn with_cache_dir(mut self, cache_dir: Option<PathBuf>) -> Self {
+        self.cache_dir = cache_dir;
+        self
+    }
+
+    /// Key the cached calls on the content of the `inputs` files as well, e.g. the wrapper
+    /// definitions and plugins of the run, so changing them analyzes the sources again.
+    #[must_use]
+    pub fn with_cache_inputs(mut self, inputs: Vec<PathBuf>) -> Self {
+        self.cache_inputs = inputs;
+        self
     }
 
     /// Extract SDK method calls from loaded source files with validation against AWS SDK service definitions.
@@ -54,6 +103,114 @@ impl Engine {
         language: Language,
         source_files: Vec<SourceFile>,
     ) -> Result<ExtractedMethods> {
+        let Some(cache_dir) = self
+            .cache_dir
+            .as_deref()
+            .filter(|_| !source_files.is_empty())
+        else {
+            return self.extract(language, source_files).await;
+        };
+        let cache = ExtractionCache::new(cache_dir, language, &source_files, &self.cache_inputs);
+        let mut entries: HashMap<PathBuf, CachedFile> = source_files
+            .iter()
+            .filter_map(|source_file| {
+                cache
+                    .load(source_file)
+                    .map(|entry| (source_file.path.clone(), entry))
+            })
+            .collect();
+        let missed: HashSet<PathBuf> = source_files
+            .iter()
+            .map(|source_file| source_file.path.clone())
+            .filter(|path| !entries.contains_key(path))
+            .collect();
+        log::info!(
+            "Reusing the SDK method calls cached for {} of {} source files",
+            entries.len(),
+            source_files.len()
+        );
+
+        // Calls without the location of an analyzed file, which can't be cached with it
+        let mut unattributed = Vec::new();
+        let mut metadata = if missed.is_empty() {
+            ExtractionMetadata::new(source_files, Vec::new())
+        } else {
+            let extracted = self
+                .extract_files(language, source_files, Some(&missed))
+                .await?;
+            let mut analyzed: HashMap<PathBuf, CachedFile> = missed
+                .iter()
+                .map(|path| {
+                    let entry = CachedFile {
+                        methods: Vec::new(),
+                        // Warnings aren't attributed to files, so each analyzed file keeps
+                        // those of the extraction it was analyzed in
+                        warnings: extracted.metadata.warnings.clone(),
+                    };
+                    (path.clone(), entry)
+                })
+                .collect();
+            for method in extracted.methods {
+                let entry = method
+                    .metadata
+                    .as_ref()
+                    .and_then(|metadata| analyzed.get_mut(&metadata.location.file_path));
+                match entry {
+                    Some(entry) => entry.methods.push(method),
+                    None => unattributed.push(method),
+                }
+            }
+            // Entries missing these calls would lose them when read back
+            if unattributed.is_empty() {
+                for source_file in &extracted.metadata.source_files {
+                    if let Some(entry) = analyzed.get(&source_file.path) {
+                        cache.store(source_file, entry);
+                    }
+                }
+            }
+            entries.extend(analyzed);
+            extracted.metadata
+        };
+
+        // Calls and warnings in the order of the source files
+        let mut methods = Vec::new();
+        let mut warnings: Vec<String> = Vec::new();
+        for source_file in &metadata.source_files {
+            if let Some(entry) = entries.remove(&source_file.path) {
+                methods.extend(entry.methods);
+                for warning in entry.warnings {
+                    if !warnings.contains(&warning) {
+                        warnings.push(warning);
+                    }
+                }
+            }
+        }
+        methods.extend(unattributed);
+        metadata.warnings = warnings;
+        metadata.update_method_count(methods.len());
+        Ok(ExtractedMethods { methods, metadata })
+    }
+
+    /// Extract SDK method calls from loaded source files, without the cache.
+    async fn extract(
+        &self,
+        language: Language,
+        source_files: Vec<SourceFile>,
+    ) -> Result<ExtractedMethods> {
+        self.extract_files(language, source_files, None).await
+    }
+
+    /// Extract the SDK method calls of the `analyzed` source files, or of all of them if
+    /// `None`, resolving them against the constants and clients of all source files.
+    async fn extract_files(
+        &self,
+        language: Language,
+        source_files: Vec<SourceFile>,
+        analyzed: Option<&HashSet<PathBuf>>,
+    ) -> Result<ExtractedMethods> {
+        let is_analyzed = |source_file: &SourceFile| {
+            analyzed.is_none_or(|paths| paths.contains(&source_file.path))
+        };
         let start_time = Instant::now();
 
         // Validate that source files are provided
@@ -79,7 +236,14 @@ impl Engine {
         // Java uses the new LanguageExtractor framework.
         if language == Language::Java {
             let mut metadata = ExtractionMetadata::new(source_files.clone(), Vec::new());
-            let method_calls = run(&JavaLanguageExtractor, source_files, &service_index).await?;
+            let method_calls = run(
+                &JavaLanguageExtractor,
+                source_files.into_iter().filter(is_analyzed).collect(),
+                &service_index,
+                self.jobs,
+                self.progress.clone(),
+            )
+            .await?;
             metadata.update_method_count(method_calls.len());
 
             let total_duration = start_time.elapsed();
@@ -98,55 +262,70 @@ impl Engine {
         // Legacy languages (Python, Go, JS, TS) use the old Extractor trait path.
         #[allow(unreachable_patterns)]
         let extractor: Arc<dyn Extractor + Send + Sync> = match language {
-            Language::Python => Arc::new(extraction::python::extractor::PythonExtractor::new()),
-            Language::Go => Arc::new(extraction::go::extractor::GoExtractor::new()),
-            Language::JavaScript => {
-                Arc::new(extraction::javascript::extractor::JavaScriptExtractor::new())
-            }
-            Language::TypeScript => {
-                Arc::new(extraction::typescript::extractor::TypeScriptExtractor::new())
-            }
+            Language::Python => Arc::new(
+                extraction::python::extractor::PythonExtractor::new()
+                    .with_project_sources(&source_files),
+            ),
+            Language::Go => Arc::new(
+                extraction::go::extractor::GoExtractor::new().with_project_sources(&source_files),
+            ),
+            Language::JavaScript => Arc::new(
+                extraction::javascript::extractor::JavaScriptExtractor::new()
+                    .with_project_sources(&source_files),
+            ),
+            Language::TypeScript => Arc::new(
+                extraction::typescript::extractor::TypeScriptExtractor::new()
+                    .with_project_sources(&source_files),
+            ),
             _ => return Err(ExtractorError::unsupported_language_override(language)),
         };
 
         // Initialize metadata with loaded files
-        let mut metadata = ExtractionMetadata::new(source_files.clone(), Vec::new());
-
-        // Extract SDK method calls from all source files concurrently
-        let mut all_extraction_results = Vec::new();
+        let mut metadata = ExtractionMetadata::new(source_files, Vec::new());
+
+        // Extract SDK method calls from the source files concurrently, at most `jobs` at a
+        // time. Each file's calls are validated as soon as it's parsed and its AST dropped,
+        // so the ASTs held in memory are bounded by the number of jobs rather than the size
+        // of the repository.
+        let analyzed_files: Vec<SourceFile> = metadata
+            .source_files
+            .iter()
+            .filter(|source_file| is_analyzed(source_file))
+            .cloned()
+            .collect();
+        log::debug!(
+            "Analyzing {} source files with {} jobs",
+            analyzed_files.len(),
+            self.jobs
+        );
+        let extractor_name = language.to_string();
+        let mut progress = Progress::new(analyzed_files.len(), self.progress.clone());
+        let mut method_calls: Vec<crate::SdkMethodCall> = Vec::new();
         let mut join_set = JoinSet::new();
 
-        for source_file in source_files {
+        for source_file in analyzed_files {
+            while join_set.len() >= self.jobs {
+                if let Some(result) = join_set.join_next().await {
+                    let (path, elapsed, result) = extraction_result(result)?;
+                    progress.file_analyzed(&path, &extractor_name, elapsed);
+                    method_calls.extend(validated_calls(&*extractor, result, &service_index));
+                }
+            }
             let extractor = extractor.clone();
-            join_set.spawn(async move { extractor.parse(&source_file).await });
+            join_set.spawn(async move {
+                let file_start = Instant::now();
+                let result = extractor.parse(&source_file).await;
+                (source_file.path, file_start.elapsed(), result)
+            });
         }
 
         // Collect results from concurrent tasks
         while let Some(result) = join_set.join_next().await {
-            match result {
-                Ok(extraction_result) => {
-                    all_extraction_results.push(extraction_result);
-                }
-                Err(e) => {
-                    return Err(ExtractorError::method_extraction(
-                        "unsupported",
-                        PathBuf::from("unknown"),
-                        format!("Task execution failed: {e}"),
-                    ));
-                }
-            }
+            let (path, elapsed, result) = extraction_result(result)?;
+            progress.file_analyzed(&path, &extractor_name, elapsed);
+            method_calls.extend(validated_calls(&*extractor, result, &service_index));
         }
 
-        extractor.filter_map(&mut all_extraction_results, &service_index);
-
-        // Disambiguate and validate method calls against SDK definitions
-        extractor.disambiguate(&mut all_extraction_results, &service_index);
-
-        let method_calls: Vec<crate::SdkMethodCall> = all_extraction_results
-            .into_iter()
-            .flat_map(super::extractor::ExtractorResult::method_calls)
-            .collect::<Vec<_>>();
-
         // Update metadata with final method count
         metadata.update_method_count(method_calls.len());
 
@@ -210,15 +389,45 @@ impl Engine {
     }
 }
 
+/// Result of the extraction task of a file, failing if the task panicked or was cancelled.
+fn extraction_result(
+    result: std::result::Result<(PathBuf, Duration, ExtractorResult), tokio::task::JoinError>,
+) -> Result<(PathBuf, Duration, ExtractorResult)> {
+    result.map_err(|e| {
+        ExtractorError::method_extraction(
+            "unsupported",
+            PathBuf::from("unknown"),
+            format!("Task execution failed: {e}"),
+        )
+    })
+}
+
+/// The calls of the parsed file `result`, filtered, mapped, disambiguated and validated
+/// against SDK definitions; the AST of the file is dropped on return.
+fn validated_calls(
+    extractor: &(dyn Extractor + Send + Sync),
+    result: ExtractorResult,
+    service_index: &crate::extraction::ServiceModelIndex,
+) -> Vec<crate::SdkMethodCall> {
+    let mut results = [result];
+    extractor.filter_map(&mut results, service_index);
+    extractor.disambiguate(&mut results, service_index);
+    let [result] = results;
+    result.method_calls()
+}
+
 /// Run the two-phase extraction pipeline (extract → match) for a [`LanguageExtractor`].
 async fn run<E: LanguageExtractor>(
     extractor: &E,
     source_files: Vec<SourceFile>,
     service_index: &crate::extraction::ServiceModelIndex,
+    jobs: usize,
+    progress: Option<Arc<dyn ProgressObserver>>,
 ) -> Result<Vec<crate::SdkMethodCall>> {
-    let ir = extract(extractor, source_files).await?;
+    let ir = extract_with_jobs(extractor, source_files, jobs, progress).await?;
     let utilities = extractor.utilities_model();
     let mut calls = extractor.match_calls(&ir, service_index, utilities);
+    bind_literal_resources(&mut calls, None);
 
     // Sort by (name, location) to produce a deterministic output order regardless
     // of which blocking task finished first during extraction.
@@ -323,4 +532,117 @@ def helper_function():
         // Should return an error for empty source files list
         assert!(result.is_err());
     }
+
+    #[tokio::test]
+    async fn test_cached_files_are_read_back_and_changed_ones_analyzed() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let engine = Engine::new().with_cache_dir(Some(cache_dir.path().to_path_buf()));
+        let source_file = |path: &str, content: &str| {
+            SourceFile::with_language(PathBuf::from(path), content.to_string(), Language::Python)
+        };
+        let app = source_file(
+            "app.py",
+            "import boto3\nboto3.client('s3').list_buckets()\n",
+        );
+        let jobs = source_file(
+            "jobs.py",
+            "import boto3\nboto3.client('sqs').send_message(QueueUrl=url, MessageBody=body)\n",
+        );
+        let names = |extracted: &ExtractedMethods| {
+            let mut names: Vec<String> = extracted
+                .methods
+                .iter()
+                .map(|method| method.name.clone())
+                .collect();
+            names.sort();
+            names
+        };
+
+        let extracted = engine
+            .extract_sdk_method_calls(Language::Python, vec![app.clone(), jobs.clone()])
+            .await
+            .unwrap();
+        assert_eq!(names(&extracted), vec!["list_buckets", "send_message"]);
+
+        // The entries of unchanged sources are read back with their warnings
+        let project = [app.clone(), jobs.clone()];
+        let cache = ExtractionCache::new(cache_dir.path(), Language::Python, &project, &[]);
+        let mut entry = cache.load(&jobs).expect("cached entry of jobs.py");
+        entry
+            .warnings
+            .push("Skipped a dynamic client in jobs.py".to_string());
+        cache.store(&jobs, &entry);
+        let extracted = engine
+            .extract_sdk_method_calls(Language::Python, vec![app, jobs.clone()])
+            .await
+            .unwrap();
+        assert_eq!(names(&extracted), vec!["list_buckets", "send_message"]);
+        assert_eq!(
+            extracted.metadata.warnings,
+            vec!["Skipped a dynamic client in jobs.py"]
+        );
+        assert_eq!(extracted.metadata.total_methods, 2);
+
+        // Changing a file analyzes the files of the project again
+        let extracted = engine
+            .extract_sdk_method_calls(
+                Language::Python,
+                vec![
+                    source_file(
+                        "app.py",
+                        "import boto3\nboto3.client('sqs').list_queues()\n",
+                    ),
+                    jobs,
+                ],
+            )
+            .await
+            .unwrap();
+        assert_eq!(names(&extracted), vec!["list_queues", "send_message"]);
+        assert!(extracted.metadata.warnings.is_empty());
+    }
+
+    #[tokio::test]
+    async fn test_cached_calls_of_dependent_files_are_not_read_back() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let engine = Engine::new().with_cache_dir(Some(cache_dir.path().to_path_buf()));
+        let source_file = |path: &str, content: &str| {
+            SourceFile::with_language(PathBuf::from(path), content.to_string(), Language::Python)
+        };
+        // The service of the client of jobs.py is a constant of settings.py
+        let app = source_file(
+            "app/jobs.py",
+            "import boto3\nfrom app import settings\n\nclient = boto3.client(settings.TOPIC_SERVICE)\nclient.list_tags_for_resource(ResourceArn=arn)\n",
+        );
+        let services = |extracted: &ExtractedMethods| {
+            extracted
+                .methods
+                .iter()
+                .flat_map(|method| method.possible_services.clone())
+                .collect::<Vec<_>>()
+        };
+
+        let extracted = engine
+            .extract_sdk_method_calls(
+                Language::Python,
+                vec![
+                    app.clone(),
+                    source_file("app/settings.py", "TOPIC_SERVICE = 'sns'\n"),
+                ],
+            )
+            .await
+            .unwrap();
+        assert_eq!(services(&extracted), vec!["sns"]);
+
+        let extracted = engine
+            .extract_sdk_method_calls(
+                Language::Python,
+                vec![
+                    app,
+                    source_file("app/settings.py", "TOPIC_SERVICE = 'ecs'\n"),
+                ],
+            )
+            .await
+            .unwrap();
+        assert_eq!(services(&extracted), vec!["ecs"]);
+    }
 }
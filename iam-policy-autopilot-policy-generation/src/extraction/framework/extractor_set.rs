@@ -1,14 +1,17 @@
 //! [`LanguageExtractorSet<L, IR>`] — runs all registered [`SdkExtractor`]s on source files
 //! using a **single AST scan per file**.
 
-use std::collections::HashSet;
+use std::collections::{BTreeMap, HashSet, VecDeque};
+use std::path::PathBuf;
 use std::sync::Arc;
+use std::time::{Duration, Instant};
 
-use ast_grep_config::from_yaml_string;
+use ast_grep_config::{from_yaml_string, RuleConfig};
 use ast_grep_core::tree_sitter::LanguageExt;
 use serde::Deserialize;
 
 use crate::errors::{ExtractorError, Result};
+use crate::extraction::progress::{Progress, ProgressObserver};
 use crate::extraction::SourceFile;
 
 use super::sdk_extractor::SdkExtractor;
@@ -36,10 +39,11 @@ pub(crate) struct DuplicateDiscriminatorError {
 /// indicates a bug in the extractor set definition.
 ///
 /// # Extraction
-/// [`extract_from_files`] fans out across files using `spawn_blocking` (CPU-bound AST work),
-/// merges the per-file `IR` values via `IR::extend_from`, and returns the combined result.
-/// The `IR` type must implement `Default` (for the initial accumulator) and [`IrExtend`]
-/// (for merging).
+/// [`extract_from_files`] compiles the combined rule once, fans out across files using
+/// `spawn_blocking` (CPU-bound AST work), a bounded number of files at a time, merges the
+/// per-file `IR` values via `IR::extend_from`, and returns the combined result. The `IR`
+/// type must implement `Default` (for the initial accumulator) and [`IrExtend`] (for
+/// merging).
 ///
 /// [`extract_from_files`]: LanguageExtractorSet::extract_from_files
 pub(crate) struct LanguageExtractorSet<L: LanguageExt, IR> {
@@ -78,20 +82,34 @@ where
     /// Fan out across `source_files` using `spawn_blocking`, merge results, and return
     /// the combined IR.
     ///
+    /// At most `jobs` files are parsed at once, so the ASTs held in memory are bounded by
+    /// the number of workers rather than the size of the repository. Each analyzed file
+    /// is reported to `observer`.
+    ///
     /// This is the method called by the [`LanguageExtractor::extract`] provided default.
     /// It contains the full parallel extraction pipeline; language modules do not need
     /// to reimplement it.
     ///
     /// [`LanguageExtractor::extract`]: super::language_extractor::LanguageExtractor::extract
-    pub(crate) async fn extract_from_files(&self, source_files: Vec<SourceFile>) -> Result<IR> {
-        // Build the combined rule YAML once — it is the same for all files.
-        let combined_yaml = Arc::new(self.build_combined_rule());
+    pub(crate) async fn extract_from_files(
+        &self,
+        source_files: Vec<SourceFile>,
+        jobs: usize,
+        observer: Option<Arc<dyn ProgressObserver>>,
+    ) -> Result<IR> {
+        // Build and compile the combined rule once — it is the same for all files, and
+        // compiling it costs more than parsing most files.
+        let combined_yaml = self.build_combined_rule();
         log::trace!("LanguageExtractorSet combined rule:\n{combined_yaml}");
+        let rule = Arc::new(compile_combined_rule::<L>(&combined_yaml)?);
 
         let language = self.language;
+        let extractor_name = language_name::<L>().to_lowercase();
+        let mut progress = Progress::new(source_files.len(), observer);
 
-        let mut handles: Vec<tokio::task::JoinHandle<Result<IR>>> =
-            Vec::with_capacity(source_files.len());
+        let jobs = jobs.max(1);
+        let mut handles: VecDeque<FileExtraction<IR>> = VecDeque::with_capacity(jobs);
+        let mut combined_result = IR::default();
 
         for source_file in source_files {
             if !source_file.language.matches(self.language) {
@@ -105,29 +123,35 @@ where
                 ));
             }
 
-            let yaml = Arc::clone(&combined_yaml);
+            // Await the oldest file before starting another once every worker is busy
+            if handles.len() == jobs {
+                if let Some(handle) = handles.pop_front() {
+                    let (path, elapsed, result) = join_extraction(handle).await?;
+                    progress.file_analyzed(&path, &extractor_name, elapsed);
+                    combined_result.extend_from(result);
+                }
+            }
+
+            let rule = Arc::clone(&rule);
             let extractors = Arc::clone(&self.extractors);
 
-            handles.push(tokio::task::spawn_blocking(move || {
+            handles.push_back(tokio::task::spawn_blocking(move || {
                 log::debug!(
                     "LanguageExtractorSet: processing file '{}'",
                     source_file.path.display()
                 );
-                extract_from_file_with_yaml(&source_file, &yaml, &extractors, language)
+                let file_start = Instant::now();
+                let result =
+                    extract_from_file_with_rule(&source_file, &rule, &extractors, language);
+                Ok((source_file.path, file_start.elapsed(), result))
             }));
         }
 
         // Await in submission order so that the merged IR is deterministic.
-        let mut combined_result = IR::default();
         for handle in handles {
-            let file_result = handle.await.map_err(|e| {
-                ExtractorError::method_extraction(
-                    "unknown",
-                    std::path::PathBuf::from("unknown"),
-                    format!("Extraction task panicked: {e}"),
-                )
-            })??;
-            combined_result.extend_from(file_result);
+            let (path, elapsed, result) = join_extraction(handle).await?;
+            progress.file_analyzed(&path, &extractor_name, elapsed);
+            combined_result.extend_from(result);
         }
 
         Ok(combined_result)
@@ -138,12 +162,8 @@ where
     /// Each extractor provides a rule body (content under `rule:`). These are assembled as
     /// items under `rule:\n  any:`.
     pub(crate) fn build_combined_rule(&self) -> String {
-        // Derive the display language name (PascalCase) from the type name for the rule id
-        // and language field. e.g. "ast_grep_language::Java" → "Java"
-        let lang_name = std::any::type_name::<L>()
-            .split("::")
-            .last()
-            .unwrap_or("Unknown");
+        // The display language name (PascalCase) is used for the rule id and language field.
+        let lang_name = language_name::<L>();
 
         let mut yaml = format!("id: {lang_name}_combined\nlanguage: {lang_name}\nrule:\n  any:\n");
 
@@ -173,36 +193,49 @@ where
     }
 }
 
-/// Process a single source file using the pre-built combined rule YAML.
+/// Compile the combined rule YAML built by [`LanguageExtractorSet::build_combined_rule`].
+fn compile_combined_rule<L>(combined_yaml: &str) -> Result<RuleConfig<L>>
+where
+    L: LanguageExt + for<'de> Deserialize<'de>,
+{
+    let globals = ast_grep_config::GlobalRules::default();
+    from_yaml_string::<L>(combined_yaml, &globals)
+        .map_err(|e| e.to_string())
+        .and_then(|configs| {
+            configs
+                .into_iter()
+                .next()
+                .ok_or_else(|| "the combined rule is empty".to_string())
+        })
+        .map_err(|e| {
+            ExtractorError::method_extraction(
+                "unknown",
+                PathBuf::from("unknown"),
+                format!("Failed to compile combined rule: {e}"),
+            )
+        })
+}
+
+/// Process a single source file using the pre-compiled combined rule.
 ///
 /// This free function is called from `spawn_blocking` tasks in `extract_from_files`.
 /// It is generic over `L` and `IR` so the compiler monomorphises it for each language.
-fn extract_from_file_with_yaml<L, IR>(
+fn extract_from_file_with_rule<L, IR>(
     source_file: &SourceFile,
-    combined_yaml: &str,
+    config: &RuleConfig<L>,
     extractors: &[Box<dyn SdkExtractor<L, ExtractionResult = IR>>],
     language: L,
-) -> Result<IR>
+) -> IR
 where
-    L: LanguageExt + Copy + for<'de> Deserialize<'de>,
+    L: LanguageExt + Copy,
     IR: Default,
 {
-    let globals = ast_grep_config::GlobalRules::default();
-    let configs = from_yaml_string::<L>(combined_yaml, &globals).map_err(|e| {
-        ExtractorError::method_extraction(
-            "unknown",
-            source_file.path.clone(),
-            format!("Failed to compile combined rule: {e}"),
-        )
-    })?;
-
-    let config = &configs[0];
-
     // Parse the source file into an AST using the language instance.
     // All ast-grep language types are unit structs (Copy), so passing by value is cheap.
     let ast_grep = language.ast_grep(&source_file.content);
 
     let mut result = IR::default();
+    let mut matches: BTreeMap<&str, usize> = BTreeMap::new();
 
     // Single AST scan — route each match to the correct extractor via discriminator label.
     for node_match in ast_grep.root().find_all(&config.matcher) {
@@ -211,12 +244,40 @@ where
         for extractor in extractors {
             if env.get_match(extractor.discriminator_label()).is_some() {
                 extractor.process(&node_match, source_file, &mut result);
+                *matches.entry(extractor.discriminator_label()).or_default() += 1;
                 break;
             }
         }
     }
 
-    Ok(result)
+    log::debug!(
+        "LanguageExtractorSet: matches in '{}' by extractor: {matches:?}",
+        source_file.path.display()
+    );
+
+    result
+}
+
+/// Extraction task of a file, yielding its path, how long it took and its IR
+type FileExtraction<IR> = tokio::task::JoinHandle<Result<(PathBuf, Duration, IR)>>;
+
+/// Display name of the ast-grep language type `L`, e.g. "ast_grep_language::Java" → "Java"
+fn language_name<L>() -> &'static str {
+    std::any::type_name::<L>()
+        .split("::")
+        .last()
+        .unwrap_or("Unknown")
+}
+
+/// Await the extraction task of a file, surfacing a panic as an extraction error.
+async fn join_extraction<IR>(handle: FileExtraction<IR>) -> Result<(PathBuf, Duration, IR)> {
+    handle.await.map_err(|e| {
+        ExtractorError::method_extraction(
+            "unknown",
+            PathBuf::from("unknown"),
+            format!("Extraction task panicked: {e}"),
+        )
+    })?
 }
 
 // ================================================================================================
@@ -1,8 +1,11 @@
 //! [`LanguageExtractor`] trait — the top-level contract for two-phase SDK call extraction.
 
+use std::sync::Arc;
+
 use ast_grep_language::LanguageExt;
 
 use crate::errors::Result;
+use crate::extraction::progress::ProgressObserver;
 use crate::extraction::{SdkMethodCall, ServiceModelIndex, SourceFile};
 
 use super::extractor_set::{IrExtend, LanguageExtractorSet};
@@ -124,13 +127,30 @@ pub(crate) trait LanguageExtractor: Send + Sync {
 /// Phase 1 — parallel AST extraction.
 ///
 /// Fans out across `source_files` using `spawn_blocking` (CPU-bound tree-sitter work),
-/// merges per-file results via `IR::extend_from`, and returns the combined IR.
-pub(crate) async fn extract<E: LanguageExtractor>(
+/// at most `jobs` files at a time, merges per-file results via `IR::extend_from`, and
+/// returns the combined IR. Each analyzed file is reported to `observer`.
+pub(crate) async fn extract_with_jobs<E: LanguageExtractor>(
     extractor: &E,
     source_files: Vec<SourceFile>,
+    jobs: usize,
+    observer: Option<Arc<dyn ProgressObserver>>,
 ) -> Result<E::ExtractionResult> {
     extractor
         .extractor_set()
-        .extract_from_files(source_files)
+        .extract_from_files(source_files, jobs, observer)
         .await
 }
+
+/// Convenience wrapper for tests, extracting with the default number of jobs
+#[cfg(test)]
+pub(crate) async fn extract<E: LanguageExtractor>(
+    extractor: &E,
+    source_files: Vec<SourceFile>,
+) -> Result<E::ExtractionResult> {
+    extract_with_jobs(extractor, source_files, default_jobs(), None).await
+}
+
+/// Files analyzed concurrently by default: one per available CPU
+pub(crate) fn default_jobs() -> usize {
+    std::thread::available_parallelism().map_or(1, std::num::NonZeroUsize::get)
+}
@@ -30,6 +30,8 @@ pub(crate) mod utilities_model;
 
 // Re-export the primary public surface of the framework.
 pub(crate) use extractor_set::{IrExtend, LanguageExtractorSet};
-pub(crate) use language_extractor::{extract, LanguageExtractor};
+#[cfg(test)]
+pub(crate) use language_extractor::extract;
+pub(crate) use language_extractor::{default_jobs, extract_with_jobs, LanguageExtractor};
 pub(crate) use sdk_extractor::SdkExtractor;
 pub(crate) use utilities_model::{UtilitiesModel, UtilityMethod, UtilityOperation};
@@ -58,8 +58,10 @@ pub(crate) struct UtilityMethod {
 
 /// The complete utilities model for a language's SDK.
 ///
-/// Maps `service_name → method_name → UtilityMethod`.
+/// Maps `service_name → method_name → [UtilityMethod]`. Several classes of a service may
+/// offer a method of the same name (e.g. `DynamoDbTable.query` and Spring Cloud AWS's
+/// `DynamoDbTemplate.query`), told apart by their `receiver_class`.
 /// Loaded once per process from the language's embedded JSON file via a `LazyLock` static.
 pub(crate) struct UtilitiesModel {
-    pub(crate) services: HashMap<String, HashMap<String, UtilityMethod>>,
+    pub(crate) services: HashMap<String, HashMap<String, Vec<UtilityMethod>>>,
 }
@@ -0,0 +1,234 @@
+//! AWS SDK operations linked into compiled Go binaries
+//!
+//! Go binaries keep the names of their functions in the symbol table the runtime uses for
+//! stack traces (`pclntab`), which stripping with `-ldflags="-s -w"` doesn't remove. The
+//! linker leaves out the SDK operations a program never calls, so the methods of the
+//! service clients it holds are the operations it may call:
+//!
+//! ```text
+//! github.com/aws/aws-sdk-go-v2/service/s3.(*Client).GetObject
+//! github.com/aws/aws-sdk-go-v2/service/s3.(*PresignClient).PresignPutObject
+//! github.com/aws/aws-sdk-go/service/dynamodb.(*DynamoDB).QueryRequest
+//! ```
+//!
+//! The analysis is coarse: nothing tells which resources the operations are called on, and
+//! programs calling client methods through reflection link all operations of the client.
+
+use std::collections::BTreeSet;
+use std::path::Path;
+use std::sync::OnceLock;
+
+use anyhow::{Context, Result};
+use regex::bytes::Regex;
+
+use crate::extraction::sdk_model::ServiceModelIndex;
+use crate::extraction::SdkMethodCallMetadata;
+use crate::{Location, SdkMethodCall};
+
+/// Marker of the build information the Go toolchain writes into every binary since Go 1.13
+const GO_BUILD_INFO_MAGIC: &[u8] = b"\xff Go buildinf:";
+
+/// Regex capturing the SDK module, service package, client type and method of the symbols
+/// of the methods of AWS SDK for Go v1 and v2 service clients, vendored ones included
+static SDK_SYMBOL_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_sdk_symbol_regex() -> &'static Regex {
+    SDK_SYMBOL_REGEX.get_or_init(|| {
+        Regex::new(concat!(
+            r"github\.com/aws/(aws-sdk-go(?:-v2)?)/service/([a-z0-9]+)",
+            r"\.\(\*([A-Za-z0-9_]+)\)\.([A-Za-z][A-Za-z0-9_]*)"
+        ))
+        .expect("Invalid SDK symbol regex")
+    })
+}
+
+/// The operation a method of a service client calls, if it calls one
+///
+/// Operations of the v2 SDK are methods of the `Client`, and register their middlewares
+/// with `addOperation<Op>Middlewares`; presigned requests are made with
+/// `PresignClient.Presign<Op>`. Every operation `<Op>` of the v1 SDK builds its request with
+/// `<Op>Request`, whether it is called with or without a context, or through a paginator.
+fn operation_of(v2: bool, client_type: &str, method: &str) -> Option<String> {
+    let operation = match (v2, client_type) {
+        (true, "Client") => method
+            .strip_prefix("addOperation")
+            .and_then(|method| method.strip_suffix("Middlewares"))
+            .unwrap_or(method),
+        (true, "PresignClient") => method.strip_prefix("Presign")?,
+        (true, _) => return None,
+        (false, _) => method.strip_suffix("Request")?,
+    };
+    operation
+        .starts_with(|c: char| c.is_ascii_uppercase())
+        .then(|| operation.to_string())
+}
+
+/// The AWS SDK calls the Go binary at `path` may make, one per linked operation
+///
+/// Operations are attributed to the service of their SDK package, or to all services having
+/// them if the package isn't named after its service, e.g. `cloudwatchlogs` for `logs`.
+/// The calls are located at the binary, and their expression is the symbol they were found
+/// by.
+///
+/// # Errors
+/// Returns an error if the file can't be read or isn't a Go binary
+pub(crate) fn go_binary_calls(
+    path: &Path,
+    service_index: &ServiceModelIndex,
+) -> Result<Vec<SdkMethodCall>> {
+    let content = std::fs::read(path)
+        .with_context(|| format!("Failed to read the Go binary {}", path.display()))?;
+    if !content
+        .windows(GO_BUILD_INFO_MAGIC.len())
+        .any(|window| window == GO_BUILD_INFO_MAGIC)
+    {
+        anyhow::bail!(
+            "{} is not a Go binary, or was built before Go 1.13",
+            path.display()
+        );
+    }
+
+    let mut operations = BTreeSet::new();
+    for captures in get_sdk_symbol_regex().captures_iter(&content) {
+        let (Ok(module), Ok(service), Ok(client_type), Ok(method)) = (
+            std::str::from_utf8(&captures[1]),
+            std::str::from_utf8(&captures[2]),
+            std::str::from_utf8(&captures[3]),
+            std::str::from_utf8(&captures[4]),
+        ) else {
+            continue;
+        };
+        if let Some(operation) = operation_of(module == "aws-sdk-go-v2", client_type, method) {
+            let package = format!("github.com/aws/{module}/service/{service}");
+            operations.insert((service.to_string(), operation, package));
+        }
+    }
+
+    let calls: Vec<SdkMethodCall> = operations
+        .into_iter()
+        .filter_map(|(package_service, operation, package)| {
+            let services: Vec<String> = service_index
+                .method_lookup
+                .get(&operation)?
+                .iter()
+                .map(|service_ref| service_ref.service_name.clone())
+                .collect();
+            let possible_services = if services.contains(&package_service) {
+                vec![package_service]
+            } else {
+                services
+            };
+            Some(SdkMethodCall {
+                metadata: Some(SdkMethodCallMetadata::new(
+                    format!("{package}.{operation}"),
+                    Location::new(path.to_path_buf(), (1, 1), (1, 1)),
+                )),
+                name: operation,
+                possible_services,
+            })
+        })
+        .collect();
+    log::debug!(
+        "Found {} AWS SDK operations linked into {}",
+        calls.len(),
+        path.display()
+    );
+    Ok(calls)
+}
+
+#[cfg(test)]
+mod tests {
+    use rstest::rstest;
+
+    use super::*;
+    use crate::extraction::sdk_model::ServiceDiscovery;
+    use crate::Language;
+
+    /// Bytes standing for a Go binary whose symbol table holds `symbols`
+    fn binary(symbols: &[&str]) -> Vec<u8> {
+        let mut content = b"\x7fELF\x02\x01\x01\0".to_vec();
+        content.extend_from_slice(GO_BUILD_INFO_MAGIC);
+        content.extend_from_slice(b"\x08\x02go1.22.4\0");
+        for symbol in symbols {
+            content.extend_from_slice(symbol.as_bytes());
+            content.push(0);
+        }
+        content
+    }
+
+    #[rstest]
+    #[case::v2_operation(true, "Client", "GetObject", Some("GetObject"))]
+    #[case::v2_middlewares(true, "Client", "addOperationPutObjectMiddlewares", Some("PutObject"))]
+    #[case::v2_presigned(true, "PresignClient", "PresignGetObject", Some("GetObject"))]
+    #[case::v2_unexported(true, "Client", "invokeOperation", None)]
+    #[case::v2_paginator(true, "ListObjectsV2Paginator", "NextPage", None)]
+    #[case::v1_request(false, "S3", "ListObjectsV2Request", Some("ListObjectsV2"))]
+    #[case::v1_with_context(false, "S3", "ListObjectsV2WithContext", None)]
+    fn test_operation_of(
+        #[case] v2: bool,
+        #[case] client_type: &str,
+        #[case] method: &str,
+        #[case] expected: Option<&str>,
+    ) {
+        assert_eq!(operation_of(v2, client_type, method).as_deref(), expected);
+    }
+
+    #[tokio::test]
+    async fn test_go_binary_calls() {
+        let service_index = ServiceDiscovery::load_service_index(Language::Go)
+            .await
+            .unwrap();
+        let directory = tempfile::tempdir().unwrap();
+        let path = directory.path().join("agent");
+        std::fs::write(
+            &path,
+            binary(&[
+                "github.com/aws/aws-sdk-go-v2/service/s3.(*Client).GetObject",
+                "github.com/aws/aws-sdk-go-v2/service/s3.(*Client).GetObject.func1",
+                "github.com/aws/aws-sdk-go-v2/service/s3.(*Client).Options",
+                "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs.(*Client).PutLogEvents",
+                "example.com/agent/vendor/github.com/aws/aws-sdk-go/service/sqs.(*SQS).\
+                 SendMessageRequest",
+                "github.com/aws/aws-sdk-go/service/sqs.(*SendMessageInput).SetQueueUrl",
+            ]),
+        )
+        .unwrap();
+
+        let calls = go_binary_calls(&path, &service_index).unwrap();
+
+        let operations: Vec<(&str, &[String])> = calls
+            .iter()
+            .map(|call| (call.name.as_str(), call.possible_services.as_slice()))
+            .collect();
+        assert_eq!(
+            operations,
+            vec![
+                ("PutLogEvents", ["logs".to_string()].as_slice()),
+                ("GetObject", ["s3".to_string()].as_slice()),
+                ("SendMessage", ["sqs".to_string()].as_slice()),
+            ]
+        );
+        let metadata = calls[1].metadata.as_ref().unwrap();
+        assert_eq!(metadata.location.file_path, path);
+        assert_eq!(
+            metadata.expr,
+            "github.com/aws/aws-sdk-go-v2/service/s3.GetObject"
+        );
+    }
+
+    #[test]
+    fn test_other_files_are_not_go_binaries() {
+        let service_index = ServiceModelIndex {
+            services: Default::default(),
+            method_lookup: Default::default(),
+            waiter_lookup: Default::default(),
+        };
+        let directory = tempfile::tempdir().unwrap();
+        let path = directory.path().join("agent.py");
+        std::fs::write(&path, "import boto3\n").unwrap();
+
+        let error = go_binary_calls(&path, &service_index).unwrap_err();
+
+        assert!(error.to_string().contains("is not a Go binary"));
+    }
+}
@@ -0,0 +1,365 @@
+//! Functions returning AWS SDK clients, across the files and packages of a Go project
+//!
+//! Clients are commonly created by a helper of another package than the code calling
+//! them, which then doesn't import the service's package:
+//!
+//! ```go
+//! // internal/aws/clients.go
+//! package aws
+//!
+//! func NewS3(cfg aws.Config) *s3.Client { return s3.NewFromConfig(cfg) }
+//!
+//! // handler.go
+//! client := aws.NewS3(cfg)
+//! client.GetObject(ctx, input)
+//! ```
+//!
+//! This module records the service of the client each function and method of the
+//! project returns, by its result type, so calls on the variables assigned the result
+//! of such a function are attributed to the service.
+
+use std::collections::HashMap;
+
+use ast_grep_core::tree_sitter::LanguageExt;
+use ast_grep_language::Go;
+
+use crate::extraction::go::node_kinds;
+use crate::extraction::go::types::{GoImportInfo, ImportInfo};
+use crate::extraction::SdkMethodCall;
+use crate::SourceFile;
+
+type GoNode<'a> = ast_grep_core::Node<'a, ast_grep_core::tree_sitter::StrDoc<Go>>;
+
+/// Functions of all project packages returning a client of an AWS service
+#[derive(Debug, Default)]
+pub(crate) struct GoClientFactories {
+    /// Service of the returned client by function name; `None` for names declared
+    /// several times with clients of different services
+    factories: HashMap<String, Option<String>>,
+}
+
+impl GoClientFactories {
+    /// Collect the client factories of every project source file
+    pub(crate) fn from_source_files(source_files: &[SourceFile]) -> Self {
+        let mut factories = Self::default();
+        for source_file in source_files {
+            if source_file.path.to_string_lossy().ends_with("_test.go") {
+                continue;
+            }
+            let ast_grep = Go.ast_grep(&source_file.content);
+            factories.collect(&ast_grep.root());
+        }
+        log::debug!(
+            "Collected {} Go client factories",
+            factories.factories.values().flatten().count()
+        );
+        factories
+    }
+
+    /// Collect the factories of a single parsed file
+    fn collect(&mut self, root: &GoNode<'_>) {
+        let imports = file_imports(root);
+        for declaration in root.children().filter(|node| {
+            matches!(
+                &*node.kind(),
+                node_kinds::FUNCTION_DECLARATION | node_kinds::METHOD_DECLARATION
+            )
+        }) {
+            let (Some(name), Some(result)) =
+                (declaration.field("name"), declaration.field("result"))
+            else {
+                continue;
+            };
+            let Some(service) = client_service(&result.text(), &imports) else {
+                continue;
+            };
+            log::debug!("Tracked Go client factory '{}' -> {service}", name.text());
+            self.factories
+                .entry(name.text().to_string())
+                .and_modify(|existing| {
+                    if existing.as_deref() != Some(service.as_str()) {
+                        *existing = None;
+                    }
+                })
+                .or_insert(Some(service));
+        }
+    }
+
+    /// Service of the client returned by a call of `callee`, e.g. `NewS3` or
+    /// `clients.NewS3`
+    fn service(&self, callee: &str) -> Option<&str> {
+        let name = callee.rsplit('.').next().unwrap_or(callee);
+        self.factories.get(name)?.as_deref()
+    }
+
+    /// Attribute the `method_calls` of the file `root` made on the result of a client
+    /// factory, directly or through a variable, to the service of the client
+    pub(crate) fn attribute_calls(&self, root: &GoNode<'_>, method_calls: &mut [SdkMethodCall]) {
+        if self.factories.is_empty() {
+            return;
+        }
+        let variables = self.client_variables(root);
+        for method_call in method_calls {
+            let Some(receiver) = method_call
+                .metadata
+                .as_ref()
+                .and_then(|metadata| metadata.receiver.as_deref())
+            else {
+                continue;
+            };
+            // `clients.NewS3(cfg).GetObject(...)`
+            let service = match called_function(receiver) {
+                Some(callee) => self.service(callee),
+                None => variables.get(receiver).and_then(Option::as_deref),
+            };
+            if let Some(service) = service {
+                method_call.possible_services = vec![service.to_string()];
+            }
+        }
+    }
+
+    /// Variables and fields of the file `root` assigned the result of a client factory:
+    /// `client := clients.NewS3(cfg)`, `client, err := ...`, `h.s3 = NewS3(cfg)`,
+    /// `var client = NewS3(cfg)`; `None` for those assigned clients of different services
+    fn client_variables(&self, root: &GoNode<'_>) -> HashMap<String, Option<String>> {
+        let mut variables: HashMap<String, Option<String>> = HashMap::new();
+        for assignment in root.dfs().filter(|node| {
+            matches!(
+                &*node.kind(),
+                node_kinds::SHORT_VAR_DECLARATION
+                    | node_kinds::ASSIGNMENT_STATEMENT
+                    | node_kinds::VAR_SPEC
+            )
+        }) {
+            let (target, value) = if assignment.kind() == node_kinds::VAR_SPEC {
+                (
+                    assignment.field("name"),
+                    assignment
+                        .field("value")
+                        .and_then(|values| values.children().find(GoNode::is_named)),
+                )
+            } else {
+                (
+                    assignment
+                        .field("left")
+                        .and_then(|targets| targets.children().find(GoNode::is_named)),
+                    assignment
+                        .field("right")
+                        .and_then(|values| values.children().find(GoNode::is_named)),
+                )
+            };
+            let (Some(target), Some(value)) = (target, value) else {
+                continue;
+            };
+            if value.kind() != node_kinds::CALL_EXPRESSION {
+                continue;
+            }
+            let Some(service) = value
+                .field("function")
+                .and_then(|callee| self.service(&callee.text()))
+            else {
+                continue;
+            };
+            variables
+                .entry(target.text().to_string())
+                .and_modify(|existing| {
+                    if existing.as_deref() != Some(service) {
+                        *existing = None;
+                    }
+                })
+                .or_insert_with(|| Some(service.to_string()));
+        }
+        variables
+    }
+}
+
+/// The function `expression` calls, if it's a call: `clients.NewS3` for
+/// `clients.NewS3(load(cfg))`
+fn called_function(expression: &str) -> Option<&str> {
+    let expression = expression.trim();
+    if !expression.ends_with(')') {
+        return None;
+    }
+    let mut depth = 0usize;
+    for (index, character) in expression.char_indices().rev() {
+        match character {
+            ')' => depth += 1,
+            '(' => {
+                depth = depth.checked_sub(1)?;
+                if depth == 0 {
+                    return Some(expression[..index].trim()).filter(|callee| !callee.is_empty());
+                }
+            }
+            _ => {}
+        }
+    }
+    None
+}
+
+/// The imports of the file `root`
+fn file_imports(root: &GoNode<'_>) -> GoImportInfo {
+    let mut imports = GoImportInfo::new();
+    for spec in root
+        .dfs()
+        .filter(|node| node.kind() == node_kinds::IMPORT_SPEC)
+    {
+        let Some(path) = spec.field("path") else {
+            continue;
+        };
+        let path_text = path.text();
+        let import_path = path_text.trim_matches('"');
+        let local_name = spec.field("name").map_or_else(
+            || {
+                import_path
+                    .rsplit('/')
+                    .next()
+                    .unwrap_or(import_path)
+                    .to_string()
+            },
+            |name| name.text().to_string(),
+        );
+        imports.add_import(ImportInfo::new(
+            import_path.to_string(),
+            local_name,
+            path.start_pos().line() + 1,
+        ));
+    }
+    imports
+}
+
+/// Service of the client the result type `result` returns first, e.g. `s3` for
+/// `(*s3.Client, error)`, with the service packages of `imports`
+fn client_service(result: &str, imports: &GoImportInfo) -> Option<String> {
+    let first = result
+        .trim()
+        .trim_start_matches('(')
+        .split(',')
+        .next()?
+        .trim()
+        .trim_end_matches(')');
+    // The type of a named result, e.g. `client *s3.Client`
+    let first = first.rsplit(' ').next()?.trim_start_matches('*');
+    let package = first.strip_suffix(".Client")?;
+    imports.service_mappings.get(package).cloned()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Language, Location};
+
+    const CLIENTS: &str = r#"package clients
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	ddb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+func NewS3(cfg aws.Config) *s3.Client {
+	return s3.NewFromConfig(cfg)
+}
+
+func NewTables(cfg aws.Config) (client *ddb.Client, err error) {
+	return ddb.NewFromConfig(cfg), nil
+}
+
+func (f *Factory) Storage() *s3.Client {
+	return f.s3
+}
+
+func Region(cfg aws.Config) string {
+	return cfg.Region
+}
+"#;
+
+    fn factories(files: &[(&str, &str)]) -> GoClientFactories {
+        let source_files: Vec<SourceFile> = files
+            .iter()
+            .map(|(path, content)| {
+                SourceFile::with_language(PathBuf::from(path), (*content).to_string(), Language::Go)
+            })
+            .collect();
+        GoClientFactories::from_source_files(&source_files)
+    }
+
+    fn call(receiver: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "GetObject".to_string(),
+            possible_services: Vec::new(),
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    format!("{receiver}.GetObject(ctx, input)"),
+                    Location::new(PathBuf::from("handler.go"), (1, 1), (1, 30)),
+                )
+                .with_receiver(receiver.to_string()),
+            ),
+        }
+    }
+
+    #[test]
+    fn test_factories_by_result_type() {
+        let factories = factories(&[("internal/clients/clients.go", CLIENTS)]);
+
+        assert_eq!(factories.service("clients.NewS3"), Some("s3"));
+        assert_eq!(factories.service("NewTables"), Some("dynamodb"));
+        assert_eq!(factories.service("f.Storage"), Some("s3"));
+        assert_eq!(factories.service("clients.Region"), None);
+    }
+
+    #[test]
+    fn test_factories_declared_with_different_services_are_ignored() {
+        let other = r#"package other
+
+import "github.com/aws/aws-sdk-go-v2/service/sqs"
+
+func NewS3() *sqs.Client { return nil }
+"#;
+        let factories = factories(&[("clients.go", CLIENTS), ("other/other.go", other)]);
+
+        assert_eq!(factories.service("NewS3"), None);
+        assert_eq!(factories.service("NewTables"), Some("dynamodb"));
+    }
+
+    #[test]
+    fn test_calls_on_factory_results_are_attributed() {
+        let factories = factories(&[("internal/clients/clients.go", CLIENTS)]);
+        let handler = r#"package main
+
+func handle(ctx context.Context, cfg aws.Config) {
+	client := clients.NewS3(cfg)
+	tables, err := clients.NewTables(cfg)
+	var storage = factory.Storage()
+	other := newClient()
+}
+"#;
+        let ast_grep = Go.ast_grep(handler);
+        let mut method_calls = vec![
+            call("client"),
+            call("tables"),
+            call("storage"),
+            call("clients.NewS3(load(cfg))"),
+            call("other"),
+        ];
+
+        factories.attribute_calls(&ast_grep.root(), &mut method_calls);
+
+        let services: Vec<Vec<String>> = method_calls
+            .into_iter()
+            .map(|method_call| method_call.possible_services)
+            .collect();
+        assert_eq!(
+            services,
+            vec![
+                vec!["s3".to_string()],
+                vec!["dynamodb".to_string()],
+                vec!["s3".to_string()],
+                vec!["s3".to_string()],
+                Vec::new(),
+            ]
+        );
+    }
+}
@@ -60,8 +60,17 @@ impl<'a> GoMethodDisambiguator<'a> {
                     self.validate_method_against_services(&method_call, service_refs);
 
                 if !valid_services.is_empty() {
+                    // Services known from the client the call is made on, e.g. one returned
+                    // by a factory of another package, take precedence over imports
+                    let known_services: Vec<String> = valid_services
+                        .iter()
+                        .filter(|service| method_call.possible_services.contains(service))
+                        .cloned()
+                        .collect();
                     // Filter services based on imports if import information is available
-                    let filtered_services = if let Some(imports) = import_info {
+                    let filtered_services = if !known_services.is_empty() {
+                        known_services
+                    } else if let Some(imports) = import_info {
                         self.filter_services_by_imports(&valid_services, imports)
                     } else {
                         valid_services
@@ -1028,4 +1037,39 @@ mod tests {
         assert_eq!(result[0].possible_services, vec!["sqs"]);
         assert_eq!(result[0].name, "CreateQueue");
     }
+
+    #[test]
+    fn test_known_receiver_service_takes_precedence_over_imports() {
+        use crate::extraction::go::types::{GoImportInfo, ImportInfo};
+
+        let service_index = create_test_service_index();
+        let disambiguator = GoMethodDisambiguator::new(&service_index);
+
+        // The file uses s3control itself, and an s3 client from a factory of another package
+        let mut import_info = GoImportInfo::new();
+        import_info.add_import(ImportInfo::new(
+            "github.com/aws/aws-sdk-go-v2/service/s3control".to_string(),
+            "s3control".to_string(),
+            5,
+        ));
+        let metadata = SdkMethodCallMetadata::new(
+            "client.GetObject(ctx, input)".to_string(),
+            Location::new(PathBuf::new(), (1, 1), (1, 30)),
+        )
+        .with_parameters(vec![
+            Parameter::context("ctx".to_string(), 0),
+            Parameter::expression("input".to_string(), 1),
+        ])
+        .with_receiver("client".to_string());
+        let method_call = SdkMethodCall {
+            name: "GetObject".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(metadata),
+        };
+
+        let result = disambiguator.disambiguate_method_calls(vec![method_call], Some(&import_info));
+
+        assert_eq!(result.len(), 1);
+        assert_eq!(result[0].possible_services, vec!["s3"]);
+    }
 }
@@ -1,36 +1,28 @@
 //! SDK method extraction for Go using ast-grep
 
 use crate::extraction::extractor::{Extractor, ExtractorResult};
+use crate::extraction::go::client_factories::GoClientFactories;
 use crate::extraction::go::disambiguation::GoMethodDisambiguator;
 use crate::extraction::go::features_extractor::GoFeaturesExtractor;
 use crate::extraction::go::node_kinds;
 use crate::extraction::go::paginator_extractor::GoPaginatorExtractor;
-use crate::extraction::go::types::{GoImportInfo, ImportInfo};
+use crate::extraction::go::project_constants::GoConstants;
+use crate::extraction::go::test_doubles::{is_generated_mock_file, is_mock_expectation};
+use crate::extraction::go::types::{is_vendored_aws_sdk_file, GoImportInfo, ImportInfo};
 use crate::extraction::go::waiter_extractor::GoWaiterExtractor;
+use crate::extraction::shared::bind_literal_resources;
 use crate::extraction::{
     AstWithSourceFile, Parameter, ParameterValue, SdkMethodCall, SdkMethodCallMetadata,
 };
 use crate::{Location, ServiceModelIndex, SourceFile};
-use ast_grep_config::from_yaml_string;
+use ast_grep_config::{from_yaml_string, RuleConfig};
 use ast_grep_core::tree_sitter::LanguageExt;
 use ast_grep_language::Go;
 use async_trait::async_trait;
+use std::sync::{Arc, OnceLock};
 
-pub(crate) struct GoExtractor {}
-
-impl GoExtractor {
-    /// Create a new Go extractor instance
-    pub(crate) fn new() -> Self {
-        Self {}
-    }
-
-    /// Extract import statements from Go source code using ast-grep
-    fn extract_imports(&self, ast: &AstWithSourceFile<Go>) -> GoImportInfo {
-        let mut import_info = GoImportInfo::new();
-        let root = ast.ast.root();
-
-        // AST-grep configuration for extracting import statements
-        let import_config = r"
+/// Rule matching import statements
+const IMPORT_RULE_YAML: &str = r"
 id: import_extraction
 language: Go
 rule:
@@ -41,19 +33,8 @@ rule:
     kind: interpreted_string_literal
 ";
 
-        let globals = ast_grep_config::GlobalRules::default();
-        let config =
-            &from_yaml_string::<Go>(import_config, &globals).expect("import rule should parse")[0];
-
-        // Find all import statements
-        for node_match in root.find_all(&config.matcher) {
-            if let Some(import) = self.parse_import(&node_match) {
-                import_info.add_import(import);
-            }
-        }
-
-        // Also handle import declarations with aliases
-        let import_alias_config = r"
+/// Rule matching aliased import statements
+const IMPORT_ALIAS_RULE_YAML: &str = r"
 id: import_alias_extraction
 language: Go
 rule:
@@ -69,8 +50,90 @@ rule:
         kind: interpreted_string_literal
 ";
 
-        let alias_config = &from_yaml_string::<Go>(import_alias_config, &globals)
-            .expect("import alias rule should parse")[0];
+/// Rule matching method calls with attribute access: `receiver.Method(args)`
+const METHOD_CALL_RULE_YAML: &str = r"
+id: method_call_extraction
+language: Go
+rule:
+  kind: call_expression
+  all:
+    - has:
+        field: function
+        kind: selector_expression
+        all:
+          - has:
+              field: field
+              pattern: $METHOD
+              kind: field_identifier
+          - has:
+              field: operand
+              pattern: $OBJ
+    - has:
+        field: arguments
+        pattern: $$$ARGS
+        kind: argument_list
+";
+
+/// Rules compiled once and shared by every parsed file, as compiling costs more than
+/// matching most files
+static IMPORT_RULE: OnceLock<RuleConfig<Go>> = OnceLock::new();
+static IMPORT_ALIAS_RULE: OnceLock<RuleConfig<Go>> = OnceLock::new();
+static METHOD_CALL_RULE: OnceLock<RuleConfig<Go>> = OnceLock::new();
+
+fn compiled_rule(rule: &'static OnceLock<RuleConfig<Go>>, yaml: &str) -> &'static RuleConfig<Go> {
+    rule.get_or_init(|| {
+        let globals = ast_grep_config::GlobalRules::default();
+        from_yaml_string::<Go>(yaml, &globals)
+            .expect("rule should parse")
+            .swap_remove(0)
+    })
+}
+
+/// The rule matching method calls with attribute access, e.g. `client.GetObject(ctx, input)`
+pub(crate) fn method_call_rule() -> &'static RuleConfig<Go> {
+    compiled_rule(&METHOD_CALL_RULE, METHOD_CALL_RULE_YAML)
+}
+
+pub(crate) struct GoExtractor {
+    project_constants: Arc<GoConstants>,
+    client_factories: Arc<GoClientFactories>,
+}
+
+impl GoExtractor {
+    /// Create a new Go extractor instance
+    pub(crate) fn new() -> Self {
+        Self {
+            project_constants: Arc::default(),
+            client_factories: Arc::default(),
+        }
+    }
+
+    /// Collect the package-level constants of all project sources, so resource
+    /// names declared elsewhere (`config.OrdersTable`) scope the calls using them, and the
+    /// functions returning clients, so calls on clients created in another package are
+    /// attributed to their service.
+    pub(crate) fn with_project_sources(mut self, source_files: &[SourceFile]) -> Self {
+        self.project_constants = Arc::new(GoConstants::from_source_files(source_files));
+        self.client_factories = Arc::new(GoClientFactories::from_source_files(source_files));
+        self
+    }
+
+    /// Extract import statements from Go source code using ast-grep
+    fn extract_imports(&self, ast: &AstWithSourceFile<Go>) -> GoImportInfo {
+        let mut import_info = GoImportInfo::new();
+        let root = ast.ast.root();
+
+        let config = compiled_rule(&IMPORT_RULE, IMPORT_RULE_YAML);
+
+        // Find all import statements
+        for node_match in root.find_all(&config.matcher) {
+            if let Some(import) = self.parse_import(&node_match) {
+                import_info.add_import(import);
+            }
+        }
+
+        // Also handle import declarations with aliases
+        let alias_config = compiled_rule(&IMPORT_ALIAS_RULE, IMPORT_ALIAS_RULE_YAML);
 
         // Find all aliased import statements
         for node_match in root.find_all(&alias_config.matcher) {
@@ -423,44 +486,47 @@ impl Extractor for GoExtractor {
         &self,
         source_file: &SourceFile,
     ) -> crate::extraction::extractor::ExtractorResult {
+        // The vendored SDK implements the operations rather than calling them; analyzing it
+        // would add every operation of every vendored service to the policy.
+        if is_vendored_aws_sdk_file(&source_file.path) {
+            log::debug!(
+                "Skipping vendored AWS SDK source file: {}",
+                source_file.path.display()
+            );
+            let ast = AstWithSourceFile::new(Go.ast_grep(""), source_file.clone());
+            return ExtractorResult::Go(ast, Vec::new(), GoImportInfo::new());
+        }
+
+        // Generated mocks implement SDK client interfaces for tests; they never call AWS.
+        if is_generated_mock_file(&source_file.content) {
+            log::debug!(
+                "Skipping generated mock file: {}",
+                source_file.path.display()
+            );
+            let ast = AstWithSourceFile::new(Go.ast_grep(""), source_file.clone());
+            return ExtractorResult::Go(ast, Vec::new(), GoImportInfo::new());
+        }
+
         let ast_grep = Go.ast_grep(&source_file.content);
         let ast = AstWithSourceFile::new(ast_grep, source_file.clone());
         let root = ast.ast.root();
 
         let mut method_calls = Vec::new();
 
-        let config = r"
-id: method_call_extraction
-language: Go
-rule:
-  kind: call_expression
-  all:
-    - has:
-        field: function
-        kind: selector_expression
-        all:
-          - has:
-              field: field
-              pattern: $METHOD
-              kind: field_identifier
-          - has:
-              field: operand
-              pattern: $OBJ
-    - has:
-        field: arguments
-        pattern: $$$ARGS
-        kind: argument_list
-        ";
-
-        let globals = ast_grep_config::GlobalRules::default();
-        let config = &from_yaml_string::<Go>(config, &globals).expect("rule should parse")[0];
+        let config = method_call_rule();
 
         // Find all method calls with attribute access: receiver.method(args)
         for node_match in root.find_all(&config.matcher) {
             if let Some(method_call) = self.parse_method_call(&node_match, source_file) {
+                // Expectations recorded on mocks name an operation without calling it
+                if is_mock_expectation(&method_call) {
+                    continue;
+                }
                 method_calls.push(method_call);
             }
         }
+        self.client_factories
+            .attribute_calls(&root, &mut method_calls);
 
         // Extract import information
         let import_info = self.extract_imports(&ast);
@@ -510,6 +576,8 @@ rule:
 
                     // Replace the method calls in place
                     *method_calls = filtered_and_mapped;
+
+                    bind_literal_resources(method_calls, Some(&*self.project_constants));
                 }
                 ExtractorResult::JavaScript(_, _) => {
                     // This shouldn't happen in Go extractor, but handle gracefully
@@ -642,6 +710,137 @@ func main() {
         );
     }
 
+    #[tokio::test]
+    async fn test_vendored_aws_sdk_sources_are_skipped() {
+        let extractor = GoExtractor::new();
+
+        let sdk_code = r#"
+package s3
+
+import "context"
+
+func (c *Client) GetObject(ctx context.Context, params *GetObjectInput) (*GetObjectOutput, error) {
+    result, metadata, err := c.invokeOperation(ctx, "GetObject", params)
+    return result.(*GetObjectOutput), err
+}
+        "#;
+        let source_file = SourceFile::with_language(
+            PathBuf::from("app/vendor/github.com/aws/aws-sdk-go-v2/service/s3/api_op_GetObject.go"),
+            sdk_code.to_string(),
+            crate::Language::Go,
+        );
+
+        let result = extractor.parse(&source_file).await;
+        assert!(result.method_calls_ref().is_empty());
+        assert!(result
+            .go_import_info()
+            .is_some_and(|info| info.imports.is_empty()));
+    }
+
+    #[tokio::test]
+    async fn test_mock_expectations_are_not_sdk_calls() {
+        let extractor = GoExtractor::new();
+
+        let test_code = r#"
+package storage
+
+import (
+    "context"
+    "testing"
+    "github.com/aws/aws-sdk-go-v2/service/s3"
+    "go.uber.org/mock/gomock"
+)
+
+func TestUpload(t *testing.T) {
+    ctrl := gomock.NewController(t)
+    mockS3 := NewMockS3API(ctrl)
+    mockS3.EXPECT().PutObject(gomock.Any(), gomock.Any()).Return(&s3.PutObjectOutput{}, nil)
+
+    client := newIntegrationClient(t)
+    client.GetObject(context.TODO(), &s3.GetObjectInput{})
+}
+        "#;
+        let source_file = SourceFile::with_language(
+            PathBuf::from("storage/upload_test.go"),
+            test_code.to_string(),
+            crate::Language::Go,
+        );
+
+        let result = extractor.parse(&source_file).await;
+        let names: Vec<&str> = result
+            .method_calls_ref()
+            .iter()
+            .map(|call| call.name.as_str())
+            .collect();
+        assert!(!names.contains(&"PutObject"), "found calls: {names:?}");
+        assert!(names.contains(&"GetObject"), "found calls: {names:?}");
+    }
+
+    #[tokio::test]
+    async fn test_generated_mock_files_are_skipped() {
+        let extractor = GoExtractor::new();
+
+        let mock_code = r#"
+// Code generated by MockGen. DO NOT EDIT.
+// Source: storage.go
+
+package mocks
+
+import (
+    context "context"
+    s3 "github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func (m *MockS3API) GetObject(arg0 context.Context, arg1 *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+    m.ctrl.T.Helper()
+    ret := m.ctrl.Call(m, "GetObject", arg0, arg1)
+    return ret[0].(*s3.GetObjectOutput), nil
+}
+        "#;
+        let source_file = SourceFile::with_language(
+            PathBuf::from("mocks/mock_storage.go"),
+            mock_code.to_string(),
+            crate::Language::Go,
+        );
+
+        let result = extractor.parse(&source_file).await;
+        assert!(result.method_calls_ref().is_empty());
+    }
+
+    #[tokio::test]
+    async fn test_vendored_import_path_resolves_service() {
+        let extractor = GoExtractor::new();
+
+        let app_code = r#"
+package main
+
+import (
+    "context"
+    "example.com/app/vendor/github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func run(client *s3.Client) {
+    client.ListBuckets(context.TODO(), &s3.ListBucketsInput{})
+}
+        "#;
+        let source_file = SourceFile::with_language(
+            PathBuf::from("app/main.go"),
+            app_code.to_string(),
+            crate::Language::Go,
+        );
+
+        let result = extractor.parse(&source_file).await;
+        let import_info = result.go_import_info().expect("Go result");
+        assert_eq!(
+            import_info.service_mappings.get("s3"),
+            Some(&"s3".to_string())
+        );
+        assert!(result
+            .method_calls_ref()
+            .iter()
+            .any(|call| call.name == "ListBuckets"));
+    }
+
     #[tokio::test]
     async fn test_chained_method_call_parsing() {
         let extractor = GoExtractor::new();
@@ -3,12 +3,12 @@
 //! This module handles extraction of Go AWS SDK v2 feature methods like S3 Upload/Download,
 //! and other specialized SDK features.
 
+use crate::extraction::go::extractor::method_call_rule;
 use crate::extraction::go::features::{FeatureMethod, GoSdkV2Features};
 use crate::extraction::go::types::GoImportInfo;
 use crate::extraction::go::utils;
 use crate::extraction::{AstWithSourceFile, SdkMethodCall, SdkMethodCallMetadata};
 use crate::Location;
-use ast_grep_config::from_yaml_string;
 use ast_grep_language::Go;
 
 /// Information about a discovered feature method call
@@ -68,32 +68,8 @@ impl GoFeaturesExtractor {
         let root = ast.ast.root();
         let mut calls = Vec::new();
 
-        // Use the same pattern as the main extractor for method calls
-        let config = r"
-id: method_call_extraction
-language: Go
-rule:
-  kind: call_expression
-  all:
-    - has:
-        field: function
-        kind: selector_expression
-        all:
-          - has:
-              field: field
-              pattern: $METHOD
-              kind: field_identifier
-          - has:
-              field: operand
-              pattern: $OBJ
-    - has:
-        field: arguments
-        pattern: $$$ARGS
-        kind: argument_list
-        ";
-
-        let globals = ast_grep_config::GlobalRules::default();
-        let config = &from_yaml_string::<Go>(config, &globals).expect("rule should parse")[0];
+        // The same rule as the main extractor for method calls
+        let config = method_call_rule();
 
         for node_match in root.find_all(&config.matcher) {
             let env = node_match.get_env();
@@ -1,10 +1,14 @@
 //! SDK method extraction and disambiguation for Go
+pub(crate) mod binary;
+pub(crate) mod client_factories;
 pub(crate) mod disambiguation;
 pub(crate) mod extractor;
 pub(crate) mod features;
 pub(crate) mod features_extractor;
 pub(crate) mod node_kinds;
 pub(crate) mod paginator_extractor;
+pub(crate) mod project_constants;
+pub(crate) mod test_doubles;
 pub(crate) mod types;
 pub(crate) mod utils;
 pub(crate) mod waiter_extractor;
@@ -38,3 +38,41 @@ pub(crate) const RIGHT_PAREN: &str = ")";
 
 /// Comma separator token
 pub(crate) const COMMA: &str = ",";
+
+/// The `package name` clause of a file
+pub(crate) const PACKAGE_CLAUSE: &str = "package_clause";
+
+/// A package name (e.g., `config` in `package config`)
+pub(crate) const PACKAGE_IDENTIFIER: &str = "package_identifier";
+
+/// A `const` declaration, holding one spec or a parenthesized group of them
+pub(crate) const CONST_DECLARATION: &str = "const_declaration";
+
+/// A `var` declaration, holding one spec or a parenthesized group of them
+pub(crate) const VAR_DECLARATION: &str = "var_declaration";
+
+/// One `name = value` line of a `const` declaration
+pub(crate) const CONST_SPEC: &str = "const_spec";
+
+/// One `name = value` line of a `var` declaration
+pub(crate) const VAR_SPEC: &str = "var_spec";
+
+/// A plain identifier (e.g., a variable or constant name)
+pub(crate) const IDENTIFIER: &str = "identifier";
+
+/// One import of an `import` declaration, with an optional local name
+pub(crate) const IMPORT_SPEC: &str = "import_spec";
+
+/// A top-level `func` declaration
+pub(crate) const FUNCTION_DECLARATION: &str = "function_declaration";
+
+/// A `func` declaration with a receiver
+pub(crate) const METHOD_DECLARATION: &str = "method_declaration";
+
+/// A `name := value` declaration
+pub(crate) const SHORT_VAR_DECLARATION: &str = "short_var_declaration";
+
+/// A `name = value` assignment
+pub(crate) const ASSIGNMENT_STATEMENT: &str = "assignment_statement";
+
+pub(crate) const CALL_EXPRESSION: &str = "call_expression";
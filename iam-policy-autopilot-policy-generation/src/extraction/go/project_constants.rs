@@ -0,0 +1,309 @@
+//! Constants shared between the files and packages of a Go project
+//!
+//! Resource names are commonly declared once and used wherever clients are called:
+//!
+//! ```go
+//! // internal/config/config.go
+//! package config
+//!
+//! const OrdersTable = "orders"
+//!
+//! var Buckets = BucketConfig{Reports: os.Getenv("REPORTS_BUCKET")}
+//!
+//! // handler.go
+//! client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String(config.OrdersTable)})
+//! ```
+//!
+//! This module records the string constants, environment variable reads and
+//! fields of struct literals declared at package level, so that `tableName` in
+//! another file of the same package and `config.OrdersTable` in another package
+//! resolve to their value.
+
+use std::collections::{HashMap, HashSet};
+use std::path::{Path, PathBuf};
+
+use ast_grep_core::tree_sitter::LanguageExt;
+use ast_grep_language::Go;
+
+use crate::extraction::go::node_kinds;
+use crate::extraction::shared::{ProjectConstants, ResourceValue};
+use crate::SourceFile;
+
+type GoNode<'a> = ast_grep_core::Node<'a, ast_grep_core::tree_sitter::StrDoc<Go>>;
+
+/// Values of one package's constants: name (or `Var.Field`) -> values
+type PackageConstants = HashMap<String, HashSet<ResourceValue>>;
+
+/// Package-level constants of all project packages
+#[derive(Debug, Default)]
+pub(crate) struct GoConstants {
+    /// Constants by package directory
+    packages: HashMap<PathBuf, PackageConstants>,
+    /// Package directories by package name, as qualified identifiers name them
+    package_dirs: HashMap<String, HashSet<PathBuf>>,
+}
+
+impl GoConstants {
+    /// Collect the package-level constants of every project source file
+    pub(crate) fn from_source_files(source_files: &[SourceFile]) -> Self {
+        let mut constants = Self::default();
+        for source_file in source_files {
+            // Test fixtures commonly redeclare names with other values
+            if source_file.path.to_string_lossy().ends_with("_test.go") {
+                continue;
+            }
+            let ast_grep = Go.ast_grep(&source_file.content);
+            constants.collect(&source_file.path, &ast_grep.root());
+        }
+        log::debug!(
+            "Collected Go constants from {} packages",
+            constants.packages.len()
+        );
+        constants
+    }
+
+    /// Collect the constants of a single parsed file
+    fn collect(&mut self, path: &Path, root: &GoNode<'_>) {
+        let dir = path.parent().map(Path::to_path_buf).unwrap_or_default();
+        let package_constants = self.packages.entry(dir.clone()).or_default();
+
+        for declaration in root.children() {
+            match &*declaration.kind() {
+                node_kinds::PACKAGE_CLAUSE => {
+                    if let Some(name) = declaration
+                        .children()
+                        .find(|child| child.kind() == node_kinds::PACKAGE_IDENTIFIER)
+                    {
+                        self.package_dirs
+                            .entry(name.text().to_string())
+                            .or_default()
+                            .insert(dir.clone());
+                    }
+                }
+                node_kinds::CONST_DECLARATION | node_kinds::VAR_DECLARATION => {
+                    for spec in declaration.dfs().filter(|node| {
+                        matches!(&*node.kind(), node_kinds::CONST_SPEC | node_kinds::VAR_SPEC)
+                    }) {
+                        collect_spec(&spec, package_constants);
+                    }
+                }
+                _ => {}
+            }
+        }
+    }
+}
+
+impl ProjectConstants for GoConstants {
+    /// Unqualified names refer to the package of the file at `path`; qualified ones
+    /// (`config.OrdersTable`) to the packages of that name
+    fn constant_value(&self, path: &Path, expression: &str) -> Option<ResourceValue> {
+        let dir = path.parent().unwrap_or(Path::new(""));
+        if let Some(values) = self
+            .packages
+            .get(dir)
+            .and_then(|constants| constants.get(expression))
+        {
+            return single_value(expression, values.iter());
+        }
+
+        let (package, name) = expression.split_once('.')?;
+        let values: Vec<_> = self
+            .package_dirs
+            .get(package)?
+            .iter()
+            .filter_map(|dir| self.packages.get(dir)?.get(name))
+            .flatten()
+            .collect();
+        single_value(expression, values.into_iter())
+    }
+}
+
+/// The value of a constant, unless it's declared with different values
+fn single_value<'a>(
+    expression: &str,
+    values: impl Iterator<Item = &'a ResourceValue>,
+) -> Option<ResourceValue> {
+    let values: HashSet<_> = values.collect();
+    if values.len() == 1 {
+        values.into_iter().next().cloned()
+    } else {
+        log::debug!("Go constant '{expression}' has conflicting values {values:?}");
+        None
+    }
+}
+
+/// Record the names a `const` or `var` spec binds, e.g. `const a, b = "x", "y"`
+fn collect_spec(spec: &GoNode<'_>, constants: &mut PackageConstants) {
+    let Some(values) = spec.field("value") else {
+        return;
+    };
+    let names = spec
+        .children()
+        .filter(|child| child.kind() == node_kinds::IDENTIFIER);
+    let values = values.children().filter(GoNode::is_named);
+    for (name, value) in names.zip(values) {
+        collect_value(&name.text(), &value, constants);
+    }
+}
+
+/// Record the value of `name`, and of its fields when it's a struct literal
+fn collect_value(name: &str, value: &GoNode<'_>, constants: &mut PackageConstants) {
+    let value = if value.kind() == node_kinds::UNARY_EXPRESSION {
+        match value.field("operand") {
+            Some(operand) => operand,
+            None => return,
+        }
+    } else {
+        value.clone()
+    };
+
+    if value.kind() == node_kinds::COMPOSITE_LITERAL {
+        let Some(body) = value.field("body") else {
+            return;
+        };
+        for element in body
+            .children()
+            .filter(|child| child.kind() == node_kinds::KEYED_ELEMENT)
+        {
+            let mut parts = element.children().filter(GoNode::is_named);
+            let (Some(field), Some(field_value)) = (parts.next(), parts.next()) else {
+                continue;
+            };
+            let field_value = if field_value.kind() == node_kinds::LITERAL_ELEMENT {
+                match field_value.children().find(GoNode::is_named) {
+                    Some(inner) => inner,
+                    None => continue,
+                }
+            } else {
+                field_value
+            };
+            collect_value(
+                &format!("{name}.{}", field.text().trim()),
+                &field_value,
+                constants,
+            );
+        }
+        return;
+    }
+
+    let text = value.text();
+    let text = text.trim();
+    let text = text
+        .strip_prefix("aws.String(")
+        .and_then(|inner| inner.strip_suffix(')'))
+        .unwrap_or(text);
+    if let Some(value) = ResourceValue::parse(text.trim(), &['"', '`']) {
+        constants.entry(name.to_string()).or_default().insert(value);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn constants_from(files: &[(&str, &str)]) -> GoConstants {
+        let source_files: Vec<SourceFile> = files
+            .iter()
+            .map(|(path, content)| {
+                SourceFile::with_language(
+                    PathBuf::from(path),
+                    (*content).to_string(),
+                    crate::Language::Go,
+                )
+            })
+            .collect();
+        GoConstants::from_source_files(&source_files)
+    }
+
+    #[test]
+    fn test_constants_resolve_across_files_and_packages() {
+        let constants = constants_from(&[
+            (
+                "app/config.go",
+                r#"
+package main
+
+const tableName = "orders"
+
+const (
+	indexName, bucketName = "by-customer", `reports`
+)
+
+func handler() {
+	const local = "ignored"
+}
+"#,
+            ),
+            (
+                "app/internal/config/config.go",
+                r#"
+package config
+
+import "os"
+
+var Buckets = &BucketConfig{
+	Reports: os.Getenv("REPORTS_BUCKET"),
+	Archive: aws.String("archive"),
+	Nested:  Names{Queue: "jobs"},
+}
+"#,
+            ),
+        ]);
+        let handler = Path::new("app/main.go");
+        let resolve = |expression| constants.constant_value(handler, expression);
+
+        assert_eq!(
+            resolve("tableName"),
+            Some(ResourceValue::Literal("orders".to_string()))
+        );
+        assert_eq!(
+            resolve("bucketName"),
+            Some(ResourceValue::Literal("reports".to_string()))
+        );
+        assert_eq!(
+            resolve("indexName"),
+            Some(ResourceValue::Literal("by-customer".to_string()))
+        );
+        assert_eq!(
+            resolve("config.Buckets.Reports"),
+            Some(ResourceValue::Environment("REPORTS_BUCKET".to_string()))
+        );
+        assert_eq!(
+            resolve("config.Buckets.Archive"),
+            Some(ResourceValue::Literal("archive".to_string()))
+        );
+        assert_eq!(
+            resolve("config.Buckets.Nested.Queue"),
+            Some(ResourceValue::Literal("jobs".to_string()))
+        );
+        assert_eq!(resolve("local"), None);
+        // Unqualified names only refer to the caller's own package
+        assert_eq!(
+            constants.constant_value(Path::new("other/main.go"), "tableName"),
+            None
+        );
+    }
+
+    #[test]
+    fn test_conflicting_constants_are_unresolved() {
+        let constants = constants_from(&[
+            (
+                "a/config.go",
+                "package shared\n\nconst Table = \"orders\"\n",
+            ),
+            (
+                "b/config.go",
+                "package shared\n\nconst Table = \"invoices\"\n",
+            ),
+        ]);
+
+        assert_eq!(
+            constants.constant_value(Path::new("app/main.go"), "shared.Table"),
+            None
+        );
+        assert_eq!(
+            constants.constant_value(Path::new("a/handler.go"), "Table"),
+            Some(ResourceValue::Literal("orders".to_string()))
+        );
+    }
+}
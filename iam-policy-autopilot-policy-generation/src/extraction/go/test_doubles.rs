@@ -0,0 +1,94 @@
+//! Recognition of Go test doubles for AWS SDK clients.
+//!
+//! Unit tests commonly replace SDK clients with generated mocks (gomock/MockGen,
+//! mockery, counterfeiter) or testify mocks. Neither the mock implementations nor the
+//! expectations recorded on them are real SDK calls, so they must not add permissions.
+
+use crate::SdkMethodCall;
+
+/// Header prefixes written by common Go mock generators
+const GENERATED_MOCK_HEADERS: &[&str] = &[
+    "// Code generated by MockGen.",
+    "// Code generated by mockery",
+    "// Code generated by counterfeiter.",
+];
+
+/// Check whether a Go file was produced by a mock generator.
+///
+/// Generators place a `// Code generated by <tool>` comment before the package clause,
+/// so only the file header is inspected.
+pub(crate) fn is_generated_mock_file(content: &str) -> bool {
+    content
+        .lines()
+        .map(str::trim)
+        .take_while(|line| !line.starts_with("package "))
+        .any(|line| {
+            GENERATED_MOCK_HEADERS
+                .iter()
+                .any(|header| line.starts_with(header))
+        })
+}
+
+/// Check whether a call records an expectation on a mock rather than calling a client.
+///
+/// Matches gomock and mockery expecter calls such as
+/// `mockS3.EXPECT().GetObject(gomock.Any(), gomock.Any())`, whose method name is an
+/// SDK operation but whose receiver is the mock's recorder.
+pub(crate) fn is_mock_expectation(call: &SdkMethodCall) -> bool {
+    call.metadata
+        .as_ref()
+        .and_then(|metadata| metadata.receiver.as_deref())
+        .is_some_and(|receiver| receiver.trim_end().ends_with(".EXPECT()"))
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::Location;
+
+    fn call_with_receiver(receiver: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "GetObject".to_string(),
+            possible_services: vec![],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    format!("{receiver}.GetObject(ctx, input)"),
+                    Location::new(PathBuf::from("s3_test.go"), (1, 1), (1, 10)),
+                )
+                .with_receiver(receiver.to_string()),
+            ),
+        }
+    }
+
+    #[test]
+    fn test_is_generated_mock_file() {
+        let mockgen =
+            "// Code generated by MockGen. DO NOT EDIT.\n// Source: s3.go\n\npackage mocks\n";
+        let mockery = "// Code generated by mockery v2.42.0. DO NOT EDIT.\n\npackage mocks\n";
+        let counterfeiter =
+            "// Code generated by counterfeiter. DO NOT EDIT.\npackage storagefakes\n";
+        assert!(is_generated_mock_file(mockgen));
+        assert!(is_generated_mock_file(mockery));
+        assert!(is_generated_mock_file(counterfeiter));
+
+        // Other generated code and mentions after the package clause are not mocks
+        let protoc = "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage pb\n";
+        let mention = "package main\n\n// Code generated by MockGen. is expected here\n";
+        assert!(!is_generated_mock_file(protoc));
+        assert!(!is_generated_mock_file(mention));
+    }
+
+    #[test]
+    fn test_is_mock_expectation() {
+        assert!(is_mock_expectation(&call_with_receiver("mockS3.EXPECT()")));
+        assert!(is_mock_expectation(&call_with_receiver(
+            "s.client.EXPECT()"
+        )));
+
+        assert!(!is_mock_expectation(&call_with_receiver("client")));
+        assert!(!is_mock_expectation(&call_with_receiver("s.s3Client")));
+    }
+}
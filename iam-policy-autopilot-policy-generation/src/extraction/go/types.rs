@@ -2,11 +2,70 @@
 
 use serde::{Deserialize, Serialize};
 use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+
+/// Module path of the AWS SDK for Go v2
+const AWS_SDK_GO_V2_MODULE: &str = "github.com/aws/aws-sdk-go-v2";
+
+/// Module paths whose sources make up the AWS SDK for Go v2 itself
+const AWS_SDK_GO_V2_SOURCE_MODULES: &[&str] = &[AWS_SDK_GO_V2_MODULE, "github.com/aws/smithy-go"];
+
+/// Strip a vendor directory prefix from a Go import path.
+///
+/// GOPATH-era projects (and some monorepos) import vendored packages through their
+/// full path, e.g. `example.com/app/vendor/github.com/aws/aws-sdk-go-v2/service/s3`.
+/// The package that is actually compiled is the one after the last `vendor/` segment,
+/// so that is what should be used for service resolution.
+///
+/// Examples:
+/// - "example.com/app/vendor/github.com/aws/aws-sdk-go-v2/service/s3" -> "github.com/aws/aws-sdk-go-v2/service/s3"
+/// - "vendor/github.com/aws/aws-sdk-go-v2/service/s3" -> "github.com/aws/aws-sdk-go-v2/service/s3"
+/// - "github.com/aws/aws-sdk-go-v2/service/s3" -> unchanged
+pub(crate) fn strip_vendor_prefix(import_path: &str) -> &str {
+    if let Some(stripped) = import_path.strip_prefix("vendor/") {
+        return strip_vendor_prefix(stripped);
+    }
+    import_path
+        .rfind("/vendor/")
+        .map_or(import_path, |pos| &import_path[pos + "/vendor/".len()..])
+}
+
+/// Check whether a source file is part of a vendored copy of the AWS SDK for Go v2.
+///
+/// Repositories that vendor their dependencies (`go mod vendor`) contain the SDK's own
+/// sources under `vendor/github.com/aws/aws-sdk-go-v2/...`. Those files are the
+/// implementation of the operations, not calls made by the application, so analyzing
+/// them would attribute every operation of every vendored service to the application.
+/// Other vendored packages are still analyzed, since third-party libraries may call the SDK.
+pub(crate) fn is_vendored_aws_sdk_file(path: &Path) -> bool {
+    let normalized = PathBuf::from(path.to_string_lossy().replace('\\', "/"));
+    let components: Vec<_> = normalized
+        .components()
+        .map(|component| component.as_os_str().to_string_lossy())
+        .collect();
+    // A `vendor` component followed by the module path and a file within the module
+    components
+        .iter()
+        .enumerate()
+        .filter(|(_, component)| *component == "vendor")
+        .any(|(pos, _)| {
+            let vendored = &components[pos + 1..];
+            AWS_SDK_GO_V2_SOURCE_MODULES.iter().any(|module| {
+                let module: Vec<_> = module.split('/').collect();
+                vendored.len() > module.len()
+                    && vendored
+                        .iter()
+                        .zip(&module)
+                        .all(|(component, part)| component == part)
+            })
+        })
+}
 
 /// Information about a single import with rename support for Go
 #[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
 pub(crate) struct ImportInfo {
-    /// Original import path (e.g., "github.com/aws/aws-sdk-go-v2/service/s3")
+    /// Original import path with any vendor directory prefix removed
+    /// (e.g., "github.com/aws/aws-sdk-go-v2/service/s3")
     pub(crate) original_name: String,
     /// Local name used in the code (e.g., "s3", "myS3")
     pub(crate) local_name: String,
@@ -21,6 +80,7 @@ pub(crate) struct ImportInfo {
 impl ImportInfo {
     /// Create a new ImportInfo with the given names and line position
     pub(crate) fn new(original_name: String, local_name: String, line: usize) -> Self {
+        let original_name = strip_vendor_prefix(&original_name).to_string();
         let is_renamed = original_name != local_name;
         let service_name = Self::extract_service_name(&original_name);
 
@@ -38,18 +98,16 @@ impl ImportInfo {
     /// - "github.com/aws/aws-sdk-go-v2/service/s3" -> Some("s3")
     /// - "github.com/aws/aws-sdk-go-v2/service/dynamodb" -> Some("dynamodb")
     /// - "github.com/aws/aws-sdk-go-v2/aws" -> None (not a service)
+    /// - "example.com/app/vendor/github.com/aws/aws-sdk-go-v2/service/s3" -> Some("s3")
     fn extract_service_name(import_path: &str) -> Option<String> {
+        let import_path = strip_vendor_prefix(import_path);
         // Check if this is an AWS SDK service import
-        if import_path.starts_with("github.com/aws/aws-sdk-go-v2/service/") {
-            // Extract the service name after the last slash
-            if let Some(service) = import_path.strip_prefix("github.com/aws/aws-sdk-go-v2/service/")
-            {
-                // Handle cases where there might be additional path components
-                let service_name = service.split('/').next().unwrap_or(service);
-                return Some(service_name.to_string());
-            }
-        }
-        None
+        let service = import_path
+            .strip_prefix(AWS_SDK_GO_V2_MODULE)?
+            .strip_prefix("/service/")?;
+        // Handle cases where there might be additional path components
+        let service_name = service.split('/').next().unwrap_or(service);
+        Some(service_name.to_string())
     }
 }
 
@@ -132,6 +190,72 @@ mod tests {
         );
     }
 
+    #[test]
+    fn test_extract_service_name_from_vendored_import() {
+        assert_eq!(
+            ImportInfo::extract_service_name(
+                "example.com/app/vendor/github.com/aws/aws-sdk-go-v2/service/s3"
+            ),
+            Some("s3".to_string())
+        );
+        assert_eq!(
+            ImportInfo::extract_service_name("vendor/github.com/aws/aws-sdk-go-v2/service/sqs"),
+            Some("sqs".to_string())
+        );
+        assert_eq!(
+            ImportInfo::extract_service_name(
+                "example.com/app/vendor/github.com/aws/aws-sdk-go-v2/config"
+            ),
+            None
+        );
+    }
+
+    #[test]
+    fn test_vendored_import_is_normalized() {
+        let import_info = ImportInfo::new(
+            "example.com/app/vendor/github.com/aws/aws-sdk-go-v2/feature/s3/manager".to_string(),
+            "manager".to_string(),
+            3,
+        );
+        assert_eq!(
+            import_info.original_name,
+            "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+        );
+        assert!(import_info.is_renamed);
+    }
+
+    #[test]
+    fn test_is_vendored_aws_sdk_file() {
+        assert!(is_vendored_aws_sdk_file(Path::new(
+            "app/vendor/github.com/aws/aws-sdk-go-v2/service/s3/api_op_GetObject.go"
+        )));
+        assert!(is_vendored_aws_sdk_file(Path::new(
+            "vendor/github.com/aws/smithy-go/middleware/stack.go"
+        )));
+        assert!(is_vendored_aws_sdk_file(Path::new(
+            r"app\vendor\github.com\aws\aws-sdk-go-v2\aws\config.go"
+        )));
+        // A later component merely ending in `vendor` doesn't hide the `vendor` directory
+        assert!(is_vendored_aws_sdk_file(Path::new(
+            "app/vendor/github.com/aws/aws-sdk-go-v2/internal/myvendor/model.go"
+        )));
+
+        // Application code and other vendored libraries are still analyzed
+        assert!(!is_vendored_aws_sdk_file(Path::new("app/internal/s3.go")));
+        assert!(!is_vendored_aws_sdk_file(Path::new(
+            "app/vendor/github.com/example/storage/s3.go"
+        )));
+        assert!(!is_vendored_aws_sdk_file(Path::new(
+            "app/myvendor/github.com/aws/aws-sdk-go-v2/service/s3/api.go"
+        )));
+        assert!(!is_vendored_aws_sdk_file(Path::new(
+            "app/vendor/github.com/aws/aws-sdk-go-v2-extras/client.go"
+        )));
+        assert!(!is_vendored_aws_sdk_file(Path::new(
+            "app/vendor/github.com/aws/aws-sdk-go-v2"
+        )));
+    }
+
     #[test]
     fn test_import_info_creation() {
         let import_info = ImportInfo::new(
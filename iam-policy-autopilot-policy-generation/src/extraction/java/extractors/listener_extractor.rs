@@ -0,0 +1,120 @@
+//! [`JavaListenerExtractor`] — extracts Spring Cloud AWS `@SqsListener` annotations from Java
+//! source files.
+//!
+//! The annotated method only receives the messages; the listener container behind the
+//! annotation polls and acknowledges them. Each annotation is captured as a [`Listener`] with
+//! the queues it names, whether given positionally (`@SqsListener("orders")`), as an array
+//! (`@SqsListener({"orders", "refunds"})`) or through the `value` and `queueNames` elements.
+
+use ast_grep_language::Java;
+
+use crate::extraction::framework::SdkExtractor;
+use crate::extraction::java::extractors::utils::{
+    self, JavaNodeMatch, ANNOTATION_ARGUMENT_LIST, ELEMENT_VALUE_ARRAY_INITIALIZER,
+    ELEMENT_VALUE_PAIR, IDENTIFIER,
+};
+use crate::extraction::java::types::{ExtractionResult, Listener};
+use crate::extraction::ParameterValue;
+use crate::Location;
+use crate::SourceFile;
+
+/// Annotation elements naming the queues to listen to
+const QUEUE_ELEMENTS: &[&str] = &["value", "queueNames"];
+
+/// Extracts `@SqsListener` annotations, simple or fully-qualified.
+///
+/// # Rule body
+///
+/// ```yaml
+/// kind: annotation
+/// has:
+///   field: name
+///   regex: '(^|\.)SqsListener$'
+///   pattern: $LISTENER_ANNOTATION
+/// ```
+///
+/// The label `$LISTENER_ANNOTATION` is the discriminator (captures the annotation name).
+pub(crate) struct JavaListenerExtractor;
+
+impl SdkExtractor<Java> for JavaListenerExtractor {
+    type ExtractionResult = ExtractionResult;
+
+    fn rule_yaml(&self) -> &'static str {
+        r"kind: annotation
+has:
+  field: name
+  regex: '(^|\.)SqsListener$'
+  pattern: $LISTENER_ANNOTATION"
+    }
+
+    fn discriminator_label(&self) -> &'static str {
+        "LISTENER_ANNOTATION"
+    }
+
+    fn process(
+        &self,
+        node_match: &JavaNodeMatch<'_>,
+        source_file: &SourceFile,
+        result: &mut ExtractionResult,
+    ) {
+        let Some(annotation) = node_match.get_env().get_match("LISTENER_ANNOTATION") else {
+            return;
+        };
+        let annotation = annotation.text().to_string();
+
+        let node = node_match.get_node();
+        let mut queues = Vec::new();
+        if let Some(arguments) = node
+            .children()
+            .find(|child| child.kind().as_ref() == ANNOTATION_ARGUMENT_LIST)
+        {
+            // Named nodes only, skipping the parentheses and commas
+            for argument in arguments.children().filter(|child| child.is_named()) {
+                if argument.kind().as_ref() != ELEMENT_VALUE_PAIR {
+                    collect_queues(&argument, &mut queues);
+                    continue;
+                }
+                // `key = value`: the key is the first identifier, the value the last child
+                let is_queue_element = argument
+                    .children()
+                    .find(|child| child.kind().as_ref() == IDENTIFIER)
+                    .is_some_and(|key| QUEUE_ELEMENTS.contains(&key.text().as_ref()));
+                if let Some(value) = argument.children().last().filter(|_| is_queue_element) {
+                    collect_queues(&value, &mut queues);
+                }
+            }
+        }
+
+        result.listeners.push(Listener {
+            expr: node.text().to_string(),
+            annotation,
+            queues,
+            location: Location::from_node(source_file.path.clone(), node),
+        });
+    }
+}
+
+/// Push the queues of an annotation element value, a single queue or an array of them
+fn collect_queues(
+    value: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Java>>,
+    queues: &mut Vec<ParameterValue>,
+) {
+    if value.kind().as_ref() == ELEMENT_VALUE_ARRAY_INITIALIZER {
+        for element in value.children().filter(|child| child.is_named()) {
+            collect_queues(&element, queues);
+        }
+    } else {
+        queues.push(utils::resolve_java_literal(value));
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::java_extractor_test;
+
+    java_extractor_test!(
+        "tests/java/extractors/listeners/*.java",
+        crate::extraction::java::types::Listener,
+        listeners
+    );
+}
@@ -6,6 +6,7 @@
 //! [`JavaLanguageExtractor::extractor_set`]: crate::extraction::java::JavaLanguageExtractor::extractor_set
 
 pub(crate) mod import_extractor;
+pub(crate) mod listener_extractor;
 pub(crate) mod method_extractor;
 pub(crate) mod paginator_extractor;
 pub(crate) mod utility_import_extractor;
@@ -48,7 +48,7 @@ static IMPORT_TABLE: LazyLock<Vec<ImportEntry>> = LazyLock::new(|| {
     let mut table = Vec::new();
 
     for (service_name, features) in &JAVA_UTILITIES_MODEL.services {
-        for feature in features.values() {
+        for feature in features.values().flatten() {
             let Some(prefix) = &feature.import_prefix else {
                 continue;
             };
@@ -116,6 +116,11 @@ mod tests {
         "software.amazon.awssdk.enhanced.dynamodb.DynamoDbTable",
         Some(("dynamodb", "DynamoDbTable"))
     )]
+    #[case("io.awspring.cloud.s3.S3Template", Some(("s3", "S3Template")))]
+    #[case(
+        "io.awspring.cloud.sqs.annotation.SqsListener",
+        Some(("sqs", "SqsListener"))
+    )]
     #[case(
         "software.amazon.awssdk.services.cloudfront.utils.CloudFrontUtilities",
         None
@@ -138,6 +138,15 @@ pub(crate) const TYPE_IDENTIFIER: &str = "type_identifier";
 /// Its first `type_identifier` child holds the raw class name without type arguments.
 const GENERIC_TYPE: &str = "generic_type";
 
+/// The `(...)` argument list of an annotation (e.g. `("orders")` in `@SqsListener("orders")`)
+pub(crate) const ANNOTATION_ARGUMENT_LIST: &str = "annotation_argument_list";
+
+/// A named annotation argument (e.g. `queueNames = "orders"`)
+pub(crate) const ELEMENT_VALUE_PAIR: &str = "element_value_pair";
+
+/// An array annotation argument (e.g. `{"orders", "refunds"}`)
+pub(crate) const ELEMENT_VALUE_ARRAY_INITIALIZER: &str = "element_value_array_initializer";
+
 /// Left parenthesis token
 pub(crate) const LEFT_PAREN: &str = "(";
 
@@ -0,0 +1,108 @@
+//! [`match_listeners`] — maps Spring Cloud AWS `@SqsListener` annotations to the operations
+//! of the listener container polling their queues.
+//!
+//! The annotation is Spring's when the file imports it, by name or with its package, or names
+//! it fully qualified. Queues given as literal names or URLs scope the operations to them;
+//! constants and property placeholders such as `${queues.orders}` are only known at runtime,
+//! so listeners naming any keep the unscoped resource.
+
+use std::collections::{BTreeMap, HashMap};
+use std::path::PathBuf;
+
+use crate::extraction::java::types::{ExtractionResult, Listener, UtilityImport};
+use crate::extraction::shared::resource_literals::queue_name;
+use crate::extraction::{ParameterValue, SdkMethodCall, SdkMethodCallMetadata};
+
+/// Package of the Spring Cloud AWS `@SqsListener` annotation
+const SQS_LISTENER_PACKAGE: &str = "io.awspring.cloud.sqs.annotation";
+
+/// Operations the listener container performs for each queue: it resolves the queue URL and
+/// attributes on startup, polls the queue and acknowledges processed messages in batches
+const LISTENER_OPERATIONS: &[&str] = &[
+    "GetQueueUrl",
+    "GetQueueAttributes",
+    "ReceiveMessage",
+    "DeleteMessageBatch",
+];
+
+/// Match the listener annotations of an [`ExtractionResult`], in source order.
+///
+/// Emits one [`SdkMethodCall`] per operation and queue, with the queue name bound to the
+/// `QueueName` placeholder, or one per operation when no queue is known statically.
+pub(crate) fn match_listeners(
+    result: &ExtractionResult,
+    utility_imports_by_file: &HashMap<PathBuf, Vec<&UtilityImport>>,
+) -> Vec<SdkMethodCall> {
+    let mut output = Vec::new();
+
+    for listener in &result.listeners {
+        let file_utility_imports: &[&UtilityImport] = utility_imports_by_file
+            .get(&listener.location.file_path)
+            .map(Vec::as_slice)
+            .unwrap_or(&[]);
+        if !is_spring_listener(listener, file_utility_imports) {
+            continue;
+        }
+
+        let queues: Option<Vec<String>> = listener
+            .queues
+            .iter()
+            .map(|queue| match queue {
+                ParameterValue::Resolved(queue) => listener_queue(queue),
+                ParameterValue::Unresolved(_) => None,
+            })
+            .collect();
+        let queues = match queues {
+            Some(queues) if !queues.is_empty() => queues.into_iter().map(Some).collect(),
+            _ => vec![None],
+        };
+
+        for queue in &queues {
+            let bindings: BTreeMap<String, String> = queue
+                .iter()
+                .map(|queue| ("QueueName".to_string(), queue.clone()))
+                .collect();
+            for operation in LISTENER_OPERATIONS {
+                let metadata =
+                    SdkMethodCallMetadata::new(listener.expr.clone(), listener.location.clone())
+                        .with_resource_bindings(bindings.clone());
+                output.push(SdkMethodCall {
+                    name: (*operation).to_string(),
+                    possible_services: vec!["sqs".to_string()],
+                    metadata: Some(metadata),
+                });
+            }
+        }
+    }
+
+    output
+}
+
+/// Whether the annotation is Spring Cloud AWS's `@SqsListener`
+fn is_spring_listener(listener: &Listener, file_utility_imports: &[&UtilityImport]) -> bool {
+    match listener.annotation.rsplit_once('.') {
+        Some((package, _)) => package == SQS_LISTENER_PACKAGE,
+        None => file_utility_imports.iter().any(|ui| {
+            ui.expr.strip_suffix(&format!(".{}", ui.class_name)) == Some(SQS_LISTENER_PACKAGE)
+                && (ui.class_name == listener.annotation || ui.class_name == "*")
+        }),
+    }
+}
+
+/// The queue named by a literal name or queue URL, or `None` for property placeholders
+fn listener_queue(queue: &str) -> Option<String> {
+    if queue.contains("${") || queue.contains("#{") {
+        return None;
+    }
+    queue_name(queue)
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::java_matcher_test;
+
+    java_matcher_test!(
+        "tests/java/matchers/listeners/*.json",
+        test_listener_matching
+    );
+}
@@ -5,10 +5,15 @@
 //! - [`waiter`]       — waiter calls × `waiter_lookup`
 //! - [`paginator`]    — paginator calls × `method_lookup`
 //! - [`utility`]      — utility calls × `java-sdk-v2-utilities.json`
+//! - [`listener`]     — Spring Cloud AWS `@SqsListener` annotations
+//!
+//! [`table_names`] derives the tables of DynamoDB Enhanced Client calls for the utility matcher.
 
+pub(crate) mod listener;
 pub(crate) mod naming;
 pub(crate) mod paginator;
 pub(crate) mod service_call;
+pub(crate) mod table_names;
 pub(crate) mod utility;
 pub(crate) mod waiter;
 
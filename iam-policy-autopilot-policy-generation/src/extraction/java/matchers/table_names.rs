@@ -0,0 +1,123 @@
+//! Table names of DynamoDB Enhanced Client calls, for resource scoping.
+//!
+//! Enhanced Client operations act on the table of a `DynamoDbTable`, named when the table is
+//! created from the client:
+//!
+//! ```java
+//! DynamoDbTable<Order> orders = client.table("orders", TableSchema.fromBean(Order.class));
+//! orders.putItem(order);                       // table/orders
+//! orders.index("by-customer").query(request);  // table/orders/index/by-customer
+//! ```
+//!
+//! The table name is read from the `table(...)` call initializing the receiver, or from the
+//! call chain the method is called on. Receivers declared elsewhere, e.g. a parameter typed
+//! `DynamoDbTable<Order>`, are resolved through the bean class of their type argument: the
+//! bean annotations (`@DynamoDbBean`, `@DynamoDbImmutable`) don't name the table, so the
+//! `table(...)` calls of the project creating a table from a `TableSchema` of the same bean
+//! class do, as long as they all name the same table.
+
+use std::collections::{BTreeMap, HashMap};
+use std::sync::LazyLock;
+
+use regex::Regex;
+
+use crate::extraction::java::types::{Call, ExtractionResult};
+use crate::extraction::{Parameter, ParameterValue};
+
+/// Enhanced Client classes whose operations act on a single table or index
+pub(super) const TABLE_CLASSES: &[&str] = &[
+    "DynamoDbTable",
+    "DynamoDbAsyncTable",
+    "DynamoDbIndex",
+    "DynamoDbAsyncIndex",
+];
+
+/// `client.table("orders", ...)`, capturing the table name
+static TABLE_CALL: LazyLock<Regex> = LazyLock::new(|| {
+    Regex::new(r#"\.table\(\s*"([^"]+)""#).expect("table call regex should be valid")
+});
+
+/// `table.index("by-customer")`, capturing the index name
+static INDEX_CALL: LazyLock<Regex> = LazyLock::new(|| {
+    Regex::new(r#"\.index\(\s*"([^"]+)"\s*\)"#).expect("index call regex should be valid")
+});
+
+/// `TableSchema.fromBean(Order.class)` and its immutable and generic variants, capturing the
+/// bean class
+static TABLE_SCHEMA: LazyLock<Regex> = LazyLock::new(|| {
+    Regex::new(r"TableSchema\s*\.\s*from(?:Bean|ImmutableClass|Class)\(\s*([\w.]+)\.class\s*\)")
+        .expect("table schema regex should be valid")
+});
+
+/// `DynamoDbTable<Order>` and the other table classes, capturing the type argument
+static TABLE_TYPE: LazyLock<Regex> = LazyLock::new(|| {
+    Regex::new(r"DynamoDb(?:Async)?(?:Table|Index)\s*<\s*([\w.]+)\s*>")
+        .expect("table type regex should be valid")
+});
+
+/// Table names of the project by bean class, `None` for bean classes of several tables
+pub(super) struct BeanTables(HashMap<String, Option<String>>);
+
+impl BeanTables {
+    /// Collect the `table(name, TableSchema.fromBean(Bean.class))` calls naming their table
+    /// with a literal
+    pub(super) fn new(result: &ExtractionResult) -> Self {
+        let mut tables: HashMap<String, Option<String>> = HashMap::new();
+        for call in result.calls.iter().filter(|call| call.method == "table") {
+            let Some(ParameterValue::Resolved(table)) =
+                call.parameters.first().map(Parameter::value)
+            else {
+                continue;
+            };
+            let Some(ParameterValue::Unresolved(schema)) =
+                call.parameters.get(1).map(Parameter::value)
+            else {
+                continue;
+            };
+            let Some(bean) = TABLE_SCHEMA.captures(&schema).map(|c| simple_name(&c[1])) else {
+                continue;
+            };
+            tables
+                .entry(bean)
+                .and_modify(|known| {
+                    if known.as_deref() != Some(table.as_str()) {
+                        *known = None;
+                    }
+                })
+                .or_insert(Some(table));
+        }
+        Self(tables)
+    }
+
+    /// `TableName` and `IndexName` bindings of a call on a table or index receiver
+    pub(super) fn bindings(&self, call: &Call) -> BTreeMap<String, String> {
+        let declaration = call.receiver_declaration.as_ref();
+        // The receiver's initializer, or the chain the method is called on
+        let source = declaration.map_or(call.expr.as_str(), |d| d.expr.as_str());
+
+        let mut bindings = BTreeMap::new();
+        let table = capture(&TABLE_CALL, source).or_else(|| {
+            let bean = declaration
+                .and_then(|d| d.type_name.as_deref())
+                .and_then(|type_name| capture(&TABLE_TYPE, type_name))
+                .or_else(|| capture(&TABLE_TYPE, source))?;
+            self.0.get(&simple_name(&bean)).cloned().flatten()
+        });
+        if let Some(table) = table {
+            bindings.insert("TableName".to_string(), table);
+            if let Some(index) = capture(&INDEX_CALL, source) {
+                bindings.insert("IndexName".to_string(), index);
+            }
+        }
+        bindings
+    }
+}
+
+fn capture(regex: &Regex, text: &str) -> Option<String> {
+    regex.captures(text).map(|captures| captures[1].to_string())
+}
+
+/// `com.example.Order` → `Order`
+fn simple_name(class: &str) -> String {
+    class.rsplit('.').next().unwrap_or(class).to_string()
+}
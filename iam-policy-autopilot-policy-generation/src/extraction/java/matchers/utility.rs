@@ -26,17 +26,18 @@
 //!   complex receiver expression like a chained call):
 //!   Fall back to import evidence from the same file:
 //!   - A specific `UtilityImport` with `class_name == feature.receiver_class` exists, **or**
-//!   - A wildcard `UtilityImport` (`class_name == "*"`) for the same service exists
-//!     (covers `import ...dynamodb.*` — the package is in scope).
+//!   - A wildcard `UtilityImport` (`class_name == "*"`) for the same service and under the
+//!     feature's import prefix exists (covers `import ...dynamodb.*` — the package is in scope).
 //!
 //! The raw `receiver` string is not used for matching: utility classes are always used via
 //! instance methods on objects created through factory/builder patterns (e.g.
 //! `S3TransferManager.create()`), so the receiver text never equals the class name.
 
-use std::collections::HashMap;
+use std::collections::{BTreeMap, HashMap, HashSet};
 use std::path::PathBuf;
 
 use crate::extraction::framework::UtilitiesModel;
+use crate::extraction::java::matchers::table_names::{BeanTables, TABLE_CLASSES};
 use crate::extraction::java::types::{ExtractionResult, UtilityImport};
 use crate::extraction::{SdkMethodCall, SdkMethodCallMetadata, ServiceModelIndex};
 
@@ -54,6 +55,7 @@ pub(crate) fn match_utilities(
     utility_imports_by_file: &HashMap<PathBuf, Vec<&UtilityImport>>,
 ) -> Vec<SdkMethodCall> {
     let mut output = Vec::new();
+    let bean_tables = BeanTables::new(result);
 
     for call in &result.calls {
         // Collect utility imports from the same file for Tier-2 import-based fallback.
@@ -61,14 +63,24 @@ pub(crate) fn match_utilities(
             .get(&call.location.file_path)
             .map(Vec::as_slice)
             .unwrap_or(&[]);
+        // Operations already emitted for the call: with import evidence alone, the same-named
+        // methods of several classes match, e.g. `putItem` of `DynamoDbTable` and
+        // `DynamoDbAsyncTable` for a `var` receiver under `import ...enhanced.dynamodb.*`
+        let mut emitted = HashSet::new();
 
         // For each service in the model, look for a feature whose receiver_class matches
         // and whose method name matches the call method.
-        for (service_name, methods) in &model.services {
-            let Some(utility_method) = methods.get(&call.method) else {
-                continue;
-            };
-
+        // Several classes of a service may offer the method, e.g. `DynamoDbTable.query`
+        // and `DynamoDbTemplate.query`; the receiver class tells them apart.
+        for (service_name, utility_method) in
+            model.services.iter().flat_map(|(service, methods)| {
+                methods
+                    .get(&call.method)
+                    .into_iter()
+                    .flatten()
+                    .map(move |method| (service, method))
+            })
+        {
             // Extract the resolved type name from receiver_declaration, if available.
             let resolved_type = call
                 .receiver_declaration
@@ -105,10 +117,17 @@ pub(crate) fn match_utilities(
                         let class_imported = file_utility_imports
                             .iter()
                             .any(|ui| ui.class_name == *expected_class);
-                        // 2b: a wildcard UtilityImport for the same service exists in the file
-                        let wildcard_imported = file_utility_imports
-                            .iter()
-                            .any(|ui| ui.class_name == "*" && &ui.utility_name == service_name);
+                        // 2b: a wildcard UtilityImport for the same service exists in the file,
+                        //     under the feature's package so that `io.awspring.cloud.dynamodb.*`
+                        //     doesn't stand in for `DynamoDbTable`
+                        let wildcard_imported = file_utility_imports.iter().any(|ui| {
+                            ui.class_name == "*"
+                                && &ui.utility_name == service_name
+                                && utility_method
+                                    .import_prefix
+                                    .as_ref()
+                                    .is_none_or(|prefix| ui.expr.starts_with(prefix.as_str()))
+                        });
 
                         class_imported || wildcard_imported
                     }
@@ -121,10 +140,25 @@ pub(crate) fn match_utilities(
                 continue;
             }
 
+            // Enhanced Client tables and indexes scope their operations to the table they name
+            let bindings = if utility_method
+                .receiver_class
+                .as_deref()
+                .is_some_and(|class| TABLE_CLASSES.contains(&class))
+            {
+                bean_tables.bindings(call)
+            } else {
+                BTreeMap::new()
+            };
+
             // Emit one SdkMethodCall per operation in the utility method.
             for op in &utility_method.operations {
+                if !emitted.insert((op.service.as_str(), op.name.as_str())) {
+                    continue;
+                }
                 let metadata = SdkMethodCallMetadata::new(call.expr.clone(), call.location.clone())
-                    .with_parameters(call.parameters.clone());
+                    .with_parameters(call.parameters.clone())
+                    .with_resource_bindings(bindings.clone());
 
                 output.push(SdkMethodCall {
                     name: op.name.clone(),
@@ -35,6 +35,7 @@ use crate::extraction::framework::{
     LanguageExtractor, LanguageExtractorSet, SdkExtractor, UtilitiesModel, UtilityMethod,
     UtilityOperation,
 };
+use crate::extraction::java::matchers::listener::match_listeners;
 use crate::extraction::java::matchers::paginator::match_paginators;
 use crate::extraction::java::matchers::service_call::match_service_calls;
 use crate::extraction::java::matchers::utility::match_utilities;
@@ -80,10 +81,11 @@ pub(crate) static JAVA_UTILITIES_MODEL: LazyLock<UtilitiesModel> = LazyLock::new
     //
     // Framework UtilitiesModel schema:
     // {
-    //   services: HashMap<service_name, HashMap<method_name, UtilityMethod>>
+    //   services: HashMap<service_name, HashMap<method_name, Vec<UtilityMethod>>>
     // }
     //
     // Normalisation: use MethodName as the method key, map Operations to UtilityOperation.
+    // Features of different receiver classes sharing a MethodName are kept side by side.
 
     #[derive(serde::Deserialize)]
     #[serde(rename_all = "PascalCase")]
@@ -115,29 +117,27 @@ pub(crate) static JAVA_UTILITIES_MODEL: LazyLock<UtilitiesModel> = LazyLock::new
     let raw: JavaUtilitiesModelRaw =
         serde_json::from_slice(&data).expect("java-sdk-v2-utilities.json must be valid JSON");
 
-    // Normalise: service_name → method_name → UtilityMethod
+    // Normalise: service_name → method_name → [UtilityMethod]
     let services = raw
         .services
         .into_iter()
         .map(|(service_name, features)| {
-            let methods = features
-                .into_values()
-                .map(|feature| {
-                    let method = UtilityMethod {
-                        operations: feature
-                            .operations
-                            .into_iter()
-                            .map(|op| UtilityOperation {
-                                service: op.service,
-                                name: op.name,
-                            })
-                            .collect(),
-                        receiver_class: Some(feature.receiver_class),
-                        import_prefix: Some(feature.import),
-                    };
-                    (feature.method_name, method)
-                })
-                .collect();
+            let mut methods: HashMap<String, Vec<UtilityMethod>> = HashMap::new();
+            for feature in features.into_values() {
+                let method = UtilityMethod {
+                    operations: feature
+                        .operations
+                        .into_iter()
+                        .map(|op| UtilityOperation {
+                            service: op.service,
+                            name: op.name,
+                        })
+                        .collect(),
+                    receiver_class: Some(feature.receiver_class),
+                    import_prefix: Some(feature.import),
+                };
+                methods.entry(feature.method_name).or_default().push(method);
+            }
             (service_name, methods)
         })
         .collect();
@@ -164,6 +164,7 @@ impl LanguageExtractor for JavaLanguageExtractor {
 
     fn extractor_set(&self) -> LanguageExtractorSet<Java, ExtractionResult> {
         use crate::extraction::java::extractors::import_extractor::JavaImportExtractor;
+        use crate::extraction::java::extractors::listener_extractor::JavaListenerExtractor;
         use crate::extraction::java::extractors::method_extractor::JavaMethodCallExtractor;
         use crate::extraction::java::extractors::paginator_extractor::JavaPaginatorExtractor;
         use crate::extraction::java::extractors::waiter_extractor::JavaWaiterCallExtractor;
@@ -176,6 +177,7 @@ impl LanguageExtractor for JavaLanguageExtractor {
                 Box::new(JavaPaginatorExtractor),
                 Box::new(JavaWaiterCallExtractor),
                 Box::new(JavaMethodCallExtractor),
+                Box::new(JavaListenerExtractor),
             ],
         )
         .expect("default_aws_v2 extractor labels must be unique")
@@ -188,8 +190,8 @@ impl LanguageExtractor for JavaLanguageExtractor {
     /// Phase 2 — convert the [`ExtractionResult`] IR into validated [`SdkMethodCall`]s.
     ///
     /// Builds a per-file import index (Java requires per-file import scoping), then
-    /// delegates to the four focused sub-matchers in order:
-    /// service calls → waiters → paginators → utilities.
+    /// delegates to the five focused sub-matchers in order:
+    /// service calls → waiters → paginators → utilities → listeners.
     fn match_calls(
         &self,
         ir: &ExtractionResult,
@@ -234,6 +236,7 @@ impl LanguageExtractor for JavaLanguageExtractor {
                 &utility_imports_by_file,
             ));
         }
+        output.extend(match_listeners(ir, &utility_imports_by_file));
 
         output
     }
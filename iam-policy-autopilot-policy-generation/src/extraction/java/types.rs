@@ -7,7 +7,7 @@
 //! [`JavaLanguageExtractor::match_calls`]: super::JavaLanguageExtractor
 
 use crate::extraction::framework::IrExtend;
-use crate::extraction::Parameter;
+use crate::extraction::{Parameter, ParameterValue};
 use crate::Location;
 
 // ================================================================================================
@@ -170,6 +170,29 @@ pub(crate) struct UtilityImport {
     pub(crate) location: Location,
 }
 
+// ================================================================================================
+// Listener
+// ================================================================================================
+
+/// A message listener annotation extracted from a Java source file.
+///
+/// Example: `@SqsListener("orders")` on a method → `annotation = "SqsListener"`,
+/// `queues = [Resolved("orders")]`. The listener container, not the annotated method, calls the
+/// service, so these have no method invocation for the other extractors to find.
+#[derive(Debug, Clone, PartialEq, Eq, Hash, serde::Deserialize, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct Listener {
+    /// Raw annotation text, e.g. `@SqsListener("orders")`
+    pub(crate) expr: String,
+    /// Annotation name as written, e.g. `"SqsListener"` or a fully-qualified name
+    pub(crate) annotation: String,
+    /// Queues the listener names: string literals as written (names, URLs or `${...}`
+    /// property placeholders) are resolved, other expressions such as constants aren't
+    pub(crate) queues: Vec<ParameterValue>,
+    /// Source location of the annotation
+    pub(crate) location: Location,
+}
+
 // ================================================================================================
 // ExtractionResult
 // ================================================================================================
@@ -194,6 +217,8 @@ pub(crate) struct ExtractionResult {
     pub(crate) waiters: Vec<Waiter>,
     /// Paginator usages
     pub(crate) paginators: Vec<Paginator>,
+    /// Message listener annotations
+    pub(crate) listeners: Vec<Listener>,
 }
 
 // ================================================================================================
@@ -207,5 +232,6 @@ impl IrExtend for ExtractionResult {
         self.calls.extend(other.calls);
         self.waiters.extend(other.waiters);
         self.paginators.extend(other.paginators);
+        self.listeners.extend(other.listeners);
     }
 }
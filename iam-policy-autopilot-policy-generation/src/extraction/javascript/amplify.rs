@@ -0,0 +1,675 @@
+//! AWS Amplify JavaScript libraries: the storage, API and auth categories
+//!
+//! Amplify apps never construct SDK clients; the library calls AWS with the credentials
+//! of the Cognito identity pool:
+//!
+//! ```ts
+//! import { uploadData } from "aws-amplify/storage";
+//! import { generateClient } from "aws-amplify/data";
+//!
+//! await uploadData({ path: `public/photos/${file.name}`, data: file }).result;
+//! const client = generateClient<Schema>();
+//! await client.models.Todo.create({ content });
+//! ```
+//!
+//! This module maps the calls of the v5 category objects (`Storage.put`, `API.graphql`,
+//! `Auth.signIn`) and of the v6 functions to the operations they perform. Storage calls
+//! are S3 object operations, scoped to the object's path when it's a literal or a
+//! template. Auth calls are Cognito operations, which user pool tokens or the app client
+//! authorize rather than IAM. GraphQL and REST requests aren't SDK operations, so they're
+//! granted `appsync:GraphQL` and `execute-api:Invoke` directly.
+
+use std::collections::BTreeMap;
+use std::sync::OnceLock;
+
+use ast_grep_core::tree_sitter::{LanguageExt, StrDoc};
+use ast_grep_core::Node;
+use ast_grep_language::{JavaScript, TypeScript};
+use regex::Regex;
+
+use crate::extraction::javascript::dynamodb_orms::{
+    argument, find_matches, object_parameters, parameter_object, parameter_string, string_value,
+    usage,
+};
+use crate::extraction::javascript::scanner::ASTScanner;
+use crate::extraction::javascript::shared::{
+    CommandUsage, ExtractionUtils, OBJECT_PLACEHOLDER, S3_SERVICE,
+};
+use crate::extraction::javascript::types::SublibraryInfo;
+use crate::extraction::shared::RequiredPermission;
+use crate::extraction::{AstWithSourceFile, Parameter, ParameterValue, SdkMethodCall};
+use crate::{Language, SourceFile};
+
+/// Module of the v5 category objects, and prefix of the v6 category modules
+const AMPLIFY_MODULE: &str = "aws-amplify";
+const STORAGE_PACKAGE: &str = "@aws-amplify/storage";
+const API_PACKAGE: &str = "@aws-amplify/api";
+const AUTH_PACKAGE: &str = "@aws-amplify/auth";
+
+/// Modules of the v6 functions of each category
+const STORAGE_MODULES: [&str; 2] = ["aws-amplify/storage", STORAGE_PACKAGE];
+const API_MODULES: [&str; 2] = ["aws-amplify/api", API_PACKAGE];
+const AUTH_MODULES: [&str; 2] = ["aws-amplify/auth", AUTH_PACKAGE];
+/// Modules exporting `generateClient`; Amplify Gen 2 apps import it from `aws-amplify/data`
+const CLIENT_MODULES: [&str; 3] = ["aws-amplify/api", "aws-amplify/data", API_PACKAGE];
+
+const COGNITO_USER_POOLS_SERVICE: &str = "cognito-idp";
+const COGNITO_IDENTITY_SERVICE: &str = "cognito-identity";
+
+/// GraphQL APIs, whose fields are of the form `types/{type}/fields/{field}`
+const GRAPHQL_API_ARN: &str = "arn:${Partition}:appsync:${Region}:${Account}:apis/*";
+/// Stages of the REST APIs, whose resources are of the form `{method}/{path}`
+const REST_API_ARN: &str = "arn:${Partition}:execute-api:${Region}:${Account}:*/*";
+
+/// v5 `Storage` methods and the S3 operations they perform
+const STORAGE_METHODS: &[(&str, &str)] = &[
+    ("get", "GetObject"),
+    ("put", "PutObject"),
+    ("remove", "DeleteObject"),
+    ("list", "ListObjectsV2"),
+    ("copy", "CopyObject"),
+    ("getProperties", "HeadObject"),
+];
+
+/// v6 storage functions and the S3 operations they perform
+const STORAGE_FUNCTIONS: &[(&str, &str)] = &[
+    ("downloadData", "GetObject"),
+    ("getUrl", "GetObject"),
+    ("uploadData", "PutObject"),
+    ("remove", "DeleteObject"),
+    ("list", "ListObjectsV2"),
+    ("copy", "CopyObject"),
+    ("getProperties", "HeadObject"),
+];
+
+/// S3 operations not scoped to the object of the call: listings, and copies, which read
+/// one object and write another
+const UNSCOPED_STORAGE_OPERATIONS: [&str; 2] = ["ListObjectsV2", "CopyObject"];
+
+/// REST methods of the v5 `API` object and v6 API functions, and their HTTP methods
+const REST_METHODS: &[(&str, &str)] = &[
+    ("get", "GET"),
+    ("post", "POST"),
+    ("put", "PUT"),
+    ("del", "DELETE"),
+    ("patch", "PATCH"),
+    ("head", "HEAD"),
+];
+
+/// Methods of the models of a `generateClient()` client and the GraphQL type of the
+/// fields they request
+const MODEL_METHOD_TYPES: &[(&str, &str)] = &[
+    ("get", "Query"),
+    ("list", "Query"),
+    ("observeQuery", "Query"),
+    ("create", "Mutation"),
+    ("update", "Mutation"),
+    ("delete", "Mutation"),
+    ("onCreate", "Subscription"),
+    ("onUpdate", "Subscription"),
+    ("onDelete", "Subscription"),
+];
+
+/// Identity pool operations fetching the credentials of the signed-in or guest user
+const CREDENTIALS_OPERATIONS: &[&str] = &["GetId", "GetCredentialsForIdentity"];
+
+/// v5 `Auth` methods and the operations they perform
+const AUTH_METHODS: &[(&str, &[&str])] = &[
+    ("signIn", &["InitiateAuth", "RespondToAuthChallenge"]),
+    ("sendCustomChallengeAnswer", &["RespondToAuthChallenge"]),
+    ("signUp", &["SignUp"]),
+    ("confirmSignUp", &["ConfirmSignUp"]),
+    ("resendSignUp", &["ResendConfirmationCode"]),
+    ("forgotPassword", &["ForgotPassword"]),
+    ("forgotPasswordSubmit", &["ConfirmForgotPassword"]),
+    ("changePassword", &["ChangePassword"]),
+    ("currentAuthenticatedUser", &["GetUser"]),
+    ("userAttributes", &["GetUser"]),
+    ("updateUserAttributes", &["UpdateUserAttributes"]),
+    ("deleteUser", &["DeleteUser"]),
+    ("signOut", &["RevokeToken"]),
+    ("currentCredentials", CREDENTIALS_OPERATIONS),
+];
+
+/// v6 auth functions and the operations they perform
+const AUTH_FUNCTIONS: &[(&str, &[&str])] = &[
+    ("signIn", &["InitiateAuth", "RespondToAuthChallenge"]),
+    ("confirmSignIn", &["RespondToAuthChallenge"]),
+    ("signUp", &["SignUp"]),
+    ("confirmSignUp", &["ConfirmSignUp"]),
+    ("resendSignUpCode", &["ResendConfirmationCode"]),
+    ("resetPassword", &["ForgotPassword"]),
+    ("confirmResetPassword", &["ConfirmForgotPassword"]),
+    ("updatePassword", &["ChangePassword"]),
+    ("fetchUserAttributes", &["GetUser"]),
+    ("updateUserAttributes", &["UpdateUserAttributes"]),
+    ("updateUserAttribute", &["UpdateUserAttributes"]),
+    ("deleteUser", &["DeleteUser"]),
+    ("signOut", &["RevokeToken"]),
+    ("fetchAuthSession", CREDENTIALS_OPERATIONS),
+];
+
+/// Regex matching the interpolations of a template literal
+static INTERPOLATION_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_interpolation_regex() -> &'static Regex {
+    INTERPOLATION_REGEX
+        .get_or_init(|| Regex::new(r"\$\{[^}]*\}").expect("Invalid interpolation regex"))
+}
+
+/// Extract the S3 and Cognito operations of the Amplify storage and auth categories
+pub(crate) fn extract_amplify_operations<T>(scanner: &ASTScanner<T>) -> Vec<SdkMethodCall>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let imports = scanner.scan_non_sdk_imports();
+    let mut operations = Vec::new();
+
+    for storage in imported_names(&imports, &[AMPLIFY_MODULE, STORAGE_PACKAGE], "Storage") {
+        for (method, operation) in STORAGE_METHODS {
+            // `put(key, object, options)` takes the object before its options
+            let options_position = if *method == "put" { 2 } else { 1 };
+            for node_match in find_matches(scanner, &format!("{storage}.{method}($$$ARGS)")) {
+                let object = v5_object_key(scanner, node_match.get_node(), options_position);
+                operations.push(storage_call(
+                    operation,
+                    object,
+                    &usage(scanner, &node_match),
+                ));
+            }
+        }
+    }
+    for (function, operation) in STORAGE_FUNCTIONS {
+        for local in imported_names(&imports, &STORAGE_MODULES, function) {
+            for node_match in find_matches(scanner, &format!("{local}($$$ARGS)")) {
+                let object = v6_object_path(scanner, node_match.get_node());
+                operations.push(storage_call(
+                    operation,
+                    object,
+                    &usage(scanner, &node_match),
+                ));
+            }
+        }
+    }
+
+    let auth_methods = imported_names(&imports, &[AMPLIFY_MODULE, AUTH_PACKAGE], "Auth")
+        .into_iter()
+        .flat_map(|auth| {
+            AUTH_METHODS
+                .iter()
+                .map(move |(method, operations)| (format!("{auth}.{method}"), *method, *operations))
+        });
+    let auth_functions = AUTH_FUNCTIONS.iter().flat_map(|(function, operations)| {
+        imported_names(&imports, &AUTH_MODULES, function)
+            .into_iter()
+            .map(move |local| (local, *function, *operations))
+    });
+    for (callee, method, method_operations) in auth_methods.chain(auth_functions) {
+        for node_match in find_matches(scanner, &format!("{callee}($$$ARGS)")) {
+            // `signOut({ global: true })` signs the user out of all their devices
+            let method_operations: &[&str] =
+                if method == "signOut" && node_match.text().contains("global") {
+                    &["GlobalSignOut"]
+                } else {
+                    method_operations
+                };
+            let call_usage = usage(scanner, &node_match);
+            operations.extend(method_operations.iter().map(|operation| {
+                let service = if CREDENTIALS_OPERATIONS.contains(operation) {
+                    COGNITO_IDENTITY_SERVICE
+                } else {
+                    COGNITO_USER_POOLS_SERVICE
+                };
+                ExtractionUtils::build_sdk_method_call(operation, service, &call_usage)
+            }));
+        }
+    }
+
+    operations
+}
+
+/// Permissions of the GraphQL and REST requests of the Amplify API category
+///
+/// Amplify signs the requests to APIs using IAM authorization with the credentials of
+/// the identity pool. GraphQL requests are granted on the type of the fields of model
+/// and custom operations; REST requests on the HTTP method and path of the call, when
+/// it's a literal or a template.
+pub(crate) fn amplify_api_permissions(source_files: &[SourceFile]) -> Vec<RequiredPermission> {
+    let mut permissions = Vec::new();
+    for source_file in source_files
+        .iter()
+        .filter(|source_file| source_file.content.contains(AMPLIFY_MODULE))
+    {
+        match source_file.language {
+            Language::TypeScript => {
+                let ast = AstWithSourceFile::new(
+                    TypeScript.ast_grep(&source_file.content),
+                    source_file.clone(),
+                );
+                permissions.extend(api_permissions(&ASTScanner::new(ast, TypeScript.into())));
+            }
+            Language::JavaScript => {
+                let ast = AstWithSourceFile::new(
+                    JavaScript.ast_grep(&source_file.content),
+                    source_file.clone(),
+                );
+                permissions.extend(api_permissions(&ASTScanner::new(ast, JavaScript.into())));
+            }
+            _ => {}
+        }
+    }
+    permissions
+}
+
+/// Permissions of the API category calls of one file
+fn api_permissions<T>(scanner: &ASTScanner<T>) -> Vec<RequiredPermission>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let imports = scanner.scan_non_sdk_imports();
+    let mut permissions = Vec::new();
+
+    // v5: API.graphql({ query }), API.get(apiName, path, init)
+    for api in imported_names(&imports, &[AMPLIFY_MODULE, API_PACKAGE], "API") {
+        for node_match in find_matches(scanner, &format!("{api}.graphql($$$ARGS)")) {
+            permissions.push(graphql_permission("*", "*", &usage(scanner, &node_match)));
+        }
+        for (method, http_method) in REST_METHODS {
+            for node_match in find_matches(scanner, &format!("{api}.{method}($$$ARGS)")) {
+                let path = argument(node_match.get_node(), 1)
+                    .and_then(|path| expression_pattern(scanner, &path.text()));
+                permissions.push(rest_permission(
+                    http_method,
+                    path.as_deref(),
+                    &usage(scanner, &node_match),
+                ));
+            }
+        }
+    }
+
+    // v6: get({ apiName, path, options })
+    for (function, http_method) in REST_METHODS {
+        for local in imported_names(&imports, &API_MODULES, function) {
+            for node_match in find_matches(scanner, &format!("{local}($$$ARGS)")) {
+                let input = object_argument(node_match.get_node()).unwrap_or_default();
+                let path =
+                    parameter_value(&input, "path").and_then(|path| value_pattern(scanner, path));
+                permissions.push(rest_permission(
+                    http_method,
+                    path.as_deref(),
+                    &usage(scanner, &node_match),
+                ));
+            }
+        }
+    }
+
+    // v6: const client = generateClient<Schema>(), whose models and custom operations
+    // send GraphQL requests
+    for generate_client in imported_names(&imports, &CLIENT_MODULES, "generateClient") {
+        for client in graphql_clients(scanner, &generate_client) {
+            for node_match in find_matches(scanner, &format!("{client}.graphql($$$ARGS)")) {
+                permissions.push(graphql_permission("*", "*", &usage(scanner, &node_match)));
+            }
+            let pattern = format!("{client}.models.$MODEL.$METHOD($$$ARGS)");
+            for node_match in find_matches(scanner, &pattern) {
+                let method = node_match
+                    .get_env()
+                    .get_match("METHOD")
+                    .map(|method| method.text().to_string());
+                let field_type = MODEL_METHOD_TYPES
+                    .iter()
+                    .find(|(model_method, _)| method.as_deref() == Some(*model_method))
+                    .map_or("*", |(_, field_type)| *field_type);
+                permissions.push(graphql_permission(
+                    field_type,
+                    "*",
+                    &usage(scanner, &node_match),
+                ));
+            }
+            for (operations, field_type) in [("queries", "Query"), ("mutations", "Mutation")] {
+                let pattern = format!("{client}.{operations}.$FIELD($$$ARGS)");
+                for node_match in find_matches(scanner, &pattern) {
+                    let Some(field) = node_match
+                        .get_env()
+                        .get_match("FIELD")
+                        .map(|field| field.text().to_string())
+                    else {
+                        continue;
+                    };
+                    permissions.push(graphql_permission(
+                        field_type,
+                        &field,
+                        &usage(scanner, &node_match),
+                    ));
+                }
+            }
+        }
+    }
+
+    permissions
+}
+
+/// Local names of the imports of `name` from any of `modules`
+fn imported_names(imports: &[SublibraryInfo], modules: &[&str], name: &str) -> Vec<String> {
+    imports
+        .iter()
+        .filter(|imported| modules.contains(&imported.sublibrary.as_str()))
+        .flat_map(|imported| &imported.imports)
+        .filter(|import_info| import_info.original_name == name)
+        .map(|import_info| import_info.local_name.clone())
+        .collect()
+}
+
+/// Variables `const client = generateClient()` assigns clients to
+fn graphql_clients<T>(scanner: &ASTScanner<T>, generate_client: &str) -> Vec<String>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    find_matches(scanner, "const $VAR = $INIT")
+        .into_iter()
+        .filter_map(|node_match| {
+            let env = node_match.get_env();
+            let init = env.get_match("INIT")?.text();
+            // `generateClient()`, or `generateClient<Schema>()` in TypeScript
+            init.strip_prefix(generate_client)
+                .is_some_and(|call| call.starts_with(['(', '<']))
+                .then(|| env.get_match("VAR").map(|var| var.text().to_string()))?
+        })
+        .collect()
+}
+
+/// S3 call of a storage operation, scoped to `object` unless the operation concerns
+/// other resources
+fn storage_call(
+    operation: &str,
+    object: Option<String>,
+    usage: &CommandUsage<'_>,
+) -> SdkMethodCall {
+    let mut bindings = BTreeMap::new();
+    if let Some(object) = object.filter(|_| !UNSCOPED_STORAGE_OPERATIONS.contains(&operation)) {
+        bindings.insert(OBJECT_PLACEHOLDER.to_string(), object);
+    }
+    ExtractionUtils::with_resource_bindings(
+        ExtractionUtils::build_sdk_method_call(operation, S3_SERVICE, usage),
+        &bindings,
+    )
+}
+
+/// Object of a v5 storage call, `Storage.get(key, { level })`, under the prefix of its
+/// access level; the options are the argument at `options_position`
+fn v5_object_key<T>(
+    scanner: &ASTScanner<T>,
+    call: &Node<'_, StrDoc<T>>,
+    options_position: usize,
+) -> Option<String>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let options = match argument(call, options_position) {
+        Some(options) if options.kind() == "object" => object_parameters(Some(&options)),
+        Some(_) => return None,
+        None => Vec::new(),
+    };
+    if parameter_value(&options, "customPrefix").is_some() {
+        return None;
+    }
+    let level = parameter_string(scanner, &options, "level");
+    let key = argument(call, 0)
+        .and_then(|key| expression_pattern(scanner, &key.text()))
+        .unwrap_or_else(|| "*".to_string());
+    Some(format!(
+        "{}{key}",
+        access_level_prefix(level.as_deref().unwrap_or("public"))?
+    ))
+}
+
+/// Object of a v6 storage call: the `path` of its input, or its deprecated `key` under
+/// the prefix of the `accessLevel` option
+fn v6_object_path<T>(scanner: &ASTScanner<T>, call: &Node<'_, StrDoc<T>>) -> Option<String>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let input = object_argument(call)?;
+    if let Some(path) = parameter_value(&input, "path") {
+        // Paths computed by a callback, `({ identityId }) => ...`, aren't known
+        return value_pattern(scanner, path);
+    }
+    let key = parameter_value(&input, "key")
+        .and_then(|key| value_pattern(scanner, key))
+        .unwrap_or_else(|| "*".to_string());
+    let level = parameter_string(scanner, &parameter_object(&input, "options"), "accessLevel");
+    Some(format!(
+        "{}{key}",
+        access_level_prefix(level.as_deref().unwrap_or("guest"))?
+    ))
+}
+
+/// Key prefix of an access level; protected and private objects are under the identity
+/// ID of their owner
+fn access_level_prefix(level: &str) -> Option<&'static str> {
+    match level {
+        "public" | "guest" => Some("public/"),
+        "protected" => Some("protected/*/"),
+        "private" => Some("private/*/"),
+        _ => None,
+    }
+}
+
+/// Properties of the object literal passed as first argument of a call
+fn object_argument<T>(call: &Node<'_, StrDoc<T>>) -> Option<Vec<Parameter>>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    argument(call, 0)
+        .filter(|input| input.kind() == "object")
+        .map(|input| object_parameters(Some(&input)))
+}
+
+fn parameter_value<'a>(parameters: &'a [Parameter], property: &str) -> Option<&'a ParameterValue> {
+    parameters.iter().find_map(|parameter| match parameter {
+        Parameter::Keyword { name, value, .. } if name == property => Some(value),
+        _ => None,
+    })
+}
+
+/// Pattern of the strings a parameter value evaluates to, see [`expression_pattern`]
+fn value_pattern<T>(scanner: &ASTScanner<T>, value: &ParameterValue) -> Option<String>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    match value {
+        ParameterValue::Resolved(value) => Some(value.clone()),
+        ParameterValue::Unresolved(expression) => expression_pattern(scanner, expression),
+    }
+}
+
+/// Pattern of the strings an expression evaluates to: a literal or string constant, or a
+/// template literal with `*` for its interpolations
+fn expression_pattern<T>(scanner: &ASTScanner<T>, expression: &str) -> Option<String>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    string_value(scanner, expression).or_else(|| {
+        let template = expression.trim().strip_prefix('`')?.strip_suffix('`')?;
+        Some(
+            get_interpolation_regex()
+                .replace_all(template, "*")
+                .into_owned(),
+        )
+    })
+}
+
+/// `appsync:GraphQL` on the fields `field` of type `field_type`
+fn graphql_permission(
+    field_type: &str,
+    field: &str,
+    usage: &CommandUsage<'_>,
+) -> RequiredPermission {
+    RequiredPermission {
+        action: "appsync:GraphQL".to_string(),
+        resources: vec![format!(
+            "{GRAPHQL_API_ARN}/types/{field_type}/fields/{field}"
+        )],
+        call: ExtractionUtils::build_sdk_method_call("GraphQL", "appsync", usage),
+    }
+}
+
+/// `execute-api:Invoke` on the resources at `path` for an HTTP method, any path when it's
+/// not known
+fn rest_permission(
+    http_method: &str,
+    path: Option<&str>,
+    usage: &CommandUsage<'_>,
+) -> RequiredPermission {
+    let path = path.map_or("*", |path| path.trim_start_matches('/'));
+    RequiredPermission {
+        action: "execute-api:Invoke".to_string(),
+        resources: vec![format!("{REST_API_ARN}/{http_method}/{path}")],
+        call: ExtractionUtils::build_sdk_method_call("Invoke", "execute-api", usage),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+
+    fn typescript(source_code: &str) -> SourceFile {
+        SourceFile::with_language(
+            PathBuf::from("app.ts"),
+            source_code.to_string(),
+            Language::TypeScript,
+        )
+    }
+
+    fn extract_typescript(source_code: &str) -> Vec<SdkMethodCall> {
+        let source_file = typescript(source_code);
+        let ast = AstWithSourceFile::new(TypeScript.ast_grep(&source_file.content), source_file);
+        extract_amplify_operations(&ASTScanner::new(ast, TypeScript.into()))
+    }
+
+    /// (operation, service, object) of each extracted call
+    fn scoped_operations(operations: &[SdkMethodCall]) -> Vec<(&str, &str, Option<&str>)> {
+        operations
+            .iter()
+            .map(|operation| {
+                let bindings = &operation
+                    .metadata
+                    .as_ref()
+                    .expect("metadata")
+                    .resource_bindings;
+                (
+                    operation.name.as_str(),
+                    operation.possible_services[0].as_str(),
+                    bindings.get(OBJECT_PLACEHOLDER).map(String::as_str),
+                )
+            })
+            .collect()
+    }
+
+    #[test]
+    fn test_v5_storage_and_auth() {
+        let operations = extract_typescript(
+            r#"
+import { Auth, Storage } from "aws-amplify";
+
+const AVATAR = "avatar.png";
+
+export async function profile(file: File) {
+    await Auth.signIn(username, password);
+    await Storage.put(AVATAR, file, { level: "private" });
+    const url = await Storage.get(`photos/${id}.jpg`);
+    await Storage.list("photos/", { level: "protected" });
+    await Auth.signOut({ global: true });
+}
+            "#,
+        );
+
+        assert_eq!(
+            scoped_operations(&operations),
+            vec![
+                ("GetObject", "s3", Some("public/photos/*.jpg")),
+                ("PutObject", "s3", Some("private/*/avatar.png")),
+                ("ListObjectsV2", "s3", None),
+                ("InitiateAuth", "cognito-idp", None),
+                ("RespondToAuthChallenge", "cognito-idp", None),
+                ("GlobalSignOut", "cognito-idp", None),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_v6_storage_and_auth() {
+        let operations = extract_typescript(
+            r#"
+import { uploadData, downloadData as download, remove } from "aws-amplify/storage";
+import { fetchAuthSession, signUp } from "aws-amplify/auth";
+
+async function handler(file: File, input: RemoveInput) {
+    await signUp({ username, password });
+    await uploadData({ path: `public/uploads/${file.name}`, data: file }).result;
+    await download({ path: ({ identityId }) => `private/${identityId}/notes.txt` }).result;
+    await download({ key: "report.pdf", options: { accessLevel: "protected" } }).result;
+    await remove(input);
+    const session = await fetchAuthSession();
+}
+            "#,
+        );
+
+        assert_eq!(
+            scoped_operations(&operations),
+            vec![
+                ("GetObject", "s3", None),
+                ("GetObject", "s3", Some("protected/*/report.pdf")),
+                ("PutObject", "s3", Some("public/uploads/*")),
+                ("DeleteObject", "s3", None),
+                ("SignUp", "cognito-idp", None),
+                ("GetId", "cognito-identity", None),
+                ("GetCredentialsForIdentity", "cognito-identity", None),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_api_permissions() {
+        let permissions = amplify_api_permissions(&[typescript(
+            r#"
+import { API } from "aws-amplify";
+import { post } from "aws-amplify/api";
+import { generateClient } from "aws-amplify/data";
+import type { Schema } from "../amplify/data/resource";
+
+const client = generateClient<Schema>();
+
+async function handler(id: string) {
+    const { data: todos } = await client.models.Todo.list();
+    await client.models.Todo.create({ content: "Write docs" });
+    await client.queries.sayHello({ name: "Amplify" });
+    await API.graphql({ query: listTodos });
+    await API.get("orders", `/orders/${id}`, {});
+    await post({ apiName: "orders", path: "/orders" }).response;
+}
+            "#,
+        )]);
+
+        let granted: Vec<(&str, &str)> = permissions
+            .iter()
+            .map(|permission| (permission.action.as_str(), permission.resources[0].as_str()))
+            .collect();
+        let graphql = |fields: &str| format!("{GRAPHQL_API_ARN}/types/{fields}");
+        let rest = |resource: &str| format!("{REST_API_ARN}/{resource}");
+        assert_eq!(
+            granted,
+            vec![
+                ("appsync:GraphQL", graphql("*/fields/*").as_str()),
+                ("execute-api:Invoke", rest("GET/orders/*").as_str()),
+                ("execute-api:Invoke", rest("POST/orders").as_str()),
+                ("appsync:GraphQL", graphql("Query/fields/*").as_str()),
+                ("appsync:GraphQL", graphql("Mutation/fields/*").as_str()),
+                ("appsync:GraphQL", graphql("Query/fields/sayHello").as_str()),
+            ]
+        );
+        assert_eq!(permissions[0].call.possible_services, vec!["appsync"]);
+    }
+}
@@ -38,7 +38,10 @@ impl ArgumentExtractor {
     }
 
     /// Parse object literal text and create Parameters with proper value resolution
-    fn parse_object_literal_with_resolution(obj_text: &str) -> Vec<Parameter> {
+    ///
+    /// Used for nested objects whose text is already known, e.g. the `RequestItems`
+    /// value of a DynamoDB batch command.
+    pub(crate) fn parse_object_literal_with_resolution(obj_text: &str) -> Vec<Parameter> {
         let mut parameters = Vec::new();
         let trimmed = obj_text.trim();
 
@@ -0,0 +1,843 @@
+//! DynamoDB ORMs: Dynamoose, ElectroDB and dynamodb-onetable
+//!
+//! Many teams model their tables with an ORM and never build SDK commands:
+//!
+//! ```ts
+//! const Order = new Entity(
+//!     { model, attributes, indexes: { byCustomer: { index: "gsi1", pk, sk } } },
+//!     { table: "orders", client },
+//! );
+//! await Order.query.byCustomer({ customerId }).go();
+//! ```
+//!
+//! This module finds the models a file defines, the table and indexes they
+//! map to, and the DynamoDB operations their methods perform. Calls are scoped
+//! to the model's table, and to the index of a query, when those are literals
+//! or string constants.
+
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+
+use ast_grep_core::tree_sitter::StrDoc;
+use ast_grep_core::{Node, NodeMatch};
+
+use crate::extraction::javascript::argument_extractor::ArgumentExtractor;
+use crate::extraction::javascript::scanner::ASTScanner;
+use crate::extraction::javascript::shared::{
+    CommandUsage, ExtractionUtils, DYNAMODB_SERVICE, TABLE_PLACEHOLDER,
+};
+use crate::extraction::javascript::types::SublibraryInfo;
+use crate::extraction::{Parameter, ParameterValue, SdkMethodCall};
+use crate::Location;
+
+const DYNAMOOSE_MODULE: &str = "dynamoose";
+const ELECTRODB_MODULE: &str = "electrodb";
+const ONETABLE_MODULE: &str = "dynamodb-onetable";
+
+/// Service reference placeholder for index names in DynamoDB index ARNs
+const INDEX_PLACEHOLDER: &str = "IndexName";
+
+/// Dynamoose model methods and the operations they perform
+const DYNAMOOSE_METHODS: &[(&str, &[&str])] = &[
+    ("get", &["GetItem"]),
+    ("create", &["PutItem"]),
+    ("update", &["UpdateItem"]),
+    ("delete", &["DeleteItem"]),
+    ("query", &["Query"]),
+    ("scan", &["Scan"]),
+    ("batchGet", &["BatchGetItem"]),
+    ("batchPut", &["BatchWriteItem"]),
+    ("batchDelete", &["BatchWriteItem"]),
+];
+
+/// Operations Dynamoose performs when it initializes a model's table; its
+/// `create` and `waitForActive` options default to true
+const DYNAMOOSE_TABLE_INITIALIZATION: [&str; 2] = ["DescribeTable", "CreateTable"];
+
+/// ElectroDB entity methods and the operations they perform
+const ELECTRODB_METHODS: &[(&str, &[&str])] = &[
+    ("get", &["GetItem"]),
+    ("put", &["PutItem"]),
+    ("create", &["PutItem"]),
+    ("upsert", &["UpdateItem"]),
+    ("update", &["UpdateItem"]),
+    ("patch", &["UpdateItem"]),
+    ("delete", &["DeleteItem"]),
+    ("remove", &["DeleteItem"]),
+    // Query an index matching the attributes, or scan
+    ("match", &["Query", "Scan"]),
+    ("find", &["Query", "Scan"]),
+];
+
+/// ElectroDB entity methods turning into batch operations when passed an array
+const ELECTRODB_BATCH_METHODS: &[(&str, &str)] = &[
+    ("get", "BatchGetItem"),
+    ("put", "BatchWriteItem"),
+    ("delete", "BatchWriteItem"),
+];
+
+/// OneTable model methods and the operations they perform
+const ONETABLE_MODEL_METHODS: &[(&str, &[&str])] = &[
+    ("get", &["GetItem"]),
+    ("find", &["Query"]),
+    ("scan", &["Scan"]),
+    ("create", &["PutItem"]),
+    ("update", &["UpdateItem"]),
+    ("upsert", &["UpdateItem"]),
+    ("remove", &["DeleteItem"]),
+];
+
+/// OneTable table methods and the operations they perform
+const ONETABLE_TABLE_METHODS: &[(&str, &[&str])] = &[
+    ("createTable", &["CreateTable"]),
+    ("deleteTable", &["DeleteTable"]),
+    ("describeTable", &["DescribeTable"]),
+    ("updateTable", &["UpdateTable"]),
+    ("batchGet", &["BatchGetItem"]),
+    ("batchWrite", &["BatchWriteItem"]),
+    ("getItem", &["GetItem"]),
+    ("putItem", &["PutItem"]),
+    ("updateItem", &["UpdateItem"]),
+    ("deleteItem", &["DeleteItem"]),
+    ("queryItems", &["Query"]),
+    ("scanItems", &["Scan"]),
+];
+
+/// Table and index a model's operations are scoped to
+#[derive(Debug, Clone, Default)]
+struct ModelTable {
+    /// Table name, when known
+    table: Option<String>,
+    /// ElectroDB access patterns served by a secondary index: access pattern -> index
+    indexes: HashMap<String, String>,
+}
+
+impl ModelTable {
+    fn resource_bindings(&self, index: Option<&str>) -> BTreeMap<String, String> {
+        let mut bindings = BTreeMap::new();
+        if let Some(table) = &self.table {
+            bindings.insert(TABLE_PLACEHOLDER.to_string(), table.clone());
+            if let Some(index) = index {
+                bindings.insert(INDEX_PLACEHOLDER.to_string(), index.to_string());
+            }
+        }
+        bindings
+    }
+}
+
+/// Extract the DynamoDB operations of Dynamoose, ElectroDB and OneTable models
+pub(crate) fn extract_orm_operations<T>(scanner: &ASTScanner<T>) -> Vec<SdkMethodCall>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let imports = scanner.scan_non_sdk_imports();
+    let mut operations = Vec::new();
+
+    for dynamoose in imported_names(&imports, DYNAMOOSE_MODULE, None) {
+        operations.extend(dynamoose_operations(scanner, &dynamoose));
+    }
+    for entity in imported_names(&imports, ELECTRODB_MODULE, Some("Entity")) {
+        let services = imported_names(&imports, ELECTRODB_MODULE, Some("Service"));
+        operations.extend(electrodb_operations(scanner, &entity, &services));
+    }
+    for table in imported_names(&imports, ONETABLE_MODULE, Some("Table")) {
+        operations.extend(onetable_operations(scanner, &table));
+    }
+
+    operations
+}
+
+/// Local names of an ORM module's imports; `None` accepts any import (default
+/// and namespace imports included)
+fn imported_names(imports: &[SublibraryInfo], module: &str, name: Option<&str>) -> Vec<String> {
+    imports
+        .iter()
+        .filter(|imported| imported.sublibrary == module)
+        .flat_map(|imported| &imported.imports)
+        .filter(|import_info| name.is_none_or(|name| import_info.original_name == name))
+        .map(|import_info| import_info.local_name.clone())
+        .collect()
+}
+
+/// Operations of `dynamoose.model(name, schema, options)` models
+///
+/// The table is named after the model (with the `prefix` and `suffix` options),
+/// unless a `new dynamoose.Table(name, [models])` groups it into another table.
+/// Queries are scoped to the index named by `.using(index)`.
+fn dynamoose_operations<T>(scanner: &ASTScanner<T>, dynamoose: &str) -> Vec<SdkMethodCall>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let mut models: Vec<(String, ModelTable, bool, CommandUsage<'_>)> = Vec::new();
+    for pattern in [
+        format!("const $VAR = {dynamoose}.model($NAME, $SCHEMA)"),
+        format!("const $VAR = {dynamoose}.model($NAME, $SCHEMA, $OPTIONS)"),
+    ] {
+        for node_match in find_matches(scanner, &pattern) {
+            let env = node_match.get_env();
+            let Some(variable) = env.get_match("VAR").map(|node| node.text().to_string()) else {
+                continue;
+            };
+            let options = object_parameters(env.get_match("OPTIONS"));
+            let table = env
+                .get_match("NAME")
+                .and_then(|name| string_value(scanner, &name.text()))
+                .map(|name| {
+                    let affix = |option: &str| {
+                        parameter_string(scanner, &options, option).unwrap_or_default()
+                    };
+                    format!("{}{name}{}", affix("prefix"), affix("suffix"))
+                });
+            let creates_table = !disables(&options, "create");
+            models.push((
+                variable,
+                ModelTable {
+                    table,
+                    ..ModelTable::default()
+                },
+                creates_table,
+                usage(scanner, &node_match),
+            ));
+        }
+    }
+
+    for pattern in [
+        format!("new {dynamoose}.Table($NAME, $MODELS)"),
+        format!("new {dynamoose}.Table($NAME, $MODELS, $OPTIONS)"),
+    ] {
+        for node_match in find_matches(scanner, &pattern) {
+            let env = node_match.get_env();
+            let (Some(name), Some(grouped)) = (env.get_match("NAME"), env.get_match("MODELS"))
+            else {
+                continue;
+            };
+            let table = string_value(scanner, &name.text());
+            let creates_table = !disables(&object_parameters(env.get_match("OPTIONS")), "create");
+            let grouped = grouped.text();
+            let grouped: Vec<_> = grouped
+                .trim_matches(|c| c == '[' || c == ']')
+                .split(',')
+                .map(str::trim)
+                .collect();
+            for (variable, model_table, model_creates_table, _) in &mut models {
+                if grouped.contains(&variable.as_str()) {
+                    model_table.table.clone_from(&table);
+                    *model_creates_table = creates_table;
+                }
+            }
+        }
+    }
+
+    let mut operations = Vec::new();
+    for (variable, model_table, creates_table, definition) in &models {
+        let initialization = if *creates_table {
+            &DYNAMOOSE_TABLE_INITIALIZATION[..]
+        } else {
+            &DYNAMOOSE_TABLE_INITIALIZATION[..1]
+        };
+        operations.extend(orm_calls(initialization, model_table, None, definition));
+
+        for (method, method_operations) in DYNAMOOSE_METHODS {
+            for node_match in find_matches(scanner, &format!("{variable}.{method}($$$ARGS)")) {
+                let index = (*method == "query")
+                    .then(|| chained_call_argument(node_match.get_node(), "using"))
+                    .flatten()
+                    .and_then(|index| string_value(scanner, &index.text()));
+                operations.extend(orm_calls(
+                    method_operations,
+                    model_table,
+                    index.as_deref(),
+                    &usage(scanner, &node_match),
+                ));
+            }
+        }
+
+        // Documents are created to be saved: `await new User({ id }).save()`
+        for node_match in find_matches(scanner, &format!("new {variable}($$$ARGS)")) {
+            operations.extend(orm_calls(
+                &["PutItem"],
+                model_table,
+                None,
+                &usage(scanner, &node_match),
+            ));
+        }
+    }
+    operations
+}
+
+/// Operations of ElectroDB entities and services
+///
+/// The table is the `table` option of the entity or of a `Service` joining it.
+/// `entity.query.<accessPattern>(...)` is scoped to the index the access
+/// pattern's `index` names, or to the table for the primary index.
+fn electrodb_operations<T>(
+    scanner: &ASTScanner<T>,
+    entity: &str,
+    services: &[String],
+) -> Vec<SdkMethodCall>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let mut entities: Vec<(String, ModelTable)> = Vec::new();
+    for pattern in [
+        format!("const $VAR = new {entity}($SCHEMA)"),
+        format!("const $VAR = new {entity}($SCHEMA, $CONFIG)"),
+    ] {
+        for node_match in find_matches(scanner, &pattern) {
+            let env = node_match.get_env();
+            let Some(variable) = env.get_match("VAR").map(|node| node.text().to_string()) else {
+                continue;
+            };
+            let schema = object_parameters(env.get_match("SCHEMA"));
+            let indexes = parameter_object(&schema, "indexes")
+                .into_iter()
+                .filter_map(|access_pattern| match access_pattern {
+                    Parameter::Keyword { name, value, .. } => {
+                        let definition = ArgumentExtractor::parse_object_literal_with_resolution(
+                            value.as_string(),
+                        );
+                        parameter_string(scanner, &definition, "index").map(|index| (name, index))
+                    }
+                    _ => None,
+                })
+                .collect();
+            let config = object_parameters(env.get_match("CONFIG"));
+            entities.push((
+                variable,
+                ModelTable {
+                    table: parameter_string(scanner, &config, "table")
+                        .or_else(|| parameter_string(scanner, &schema, "table")),
+                    indexes,
+                },
+            ));
+        }
+    }
+
+    let mut operations = Vec::new();
+    for service in services {
+        for node_match in find_matches(
+            scanner,
+            &format!("const $VAR = new {service}($ENTITIES, $CONFIG)"),
+        ) {
+            let env = node_match.get_env();
+            let Some(variable) = env.get_match("VAR").map(|node| node.text().to_string()) else {
+                continue;
+            };
+            let service_table = ModelTable {
+                table: parameter_string(
+                    scanner,
+                    &object_parameters(env.get_match("CONFIG")),
+                    "table",
+                ),
+                ..ModelTable::default()
+            };
+            let joined: Vec<_> = object_parameters(env.get_match("ENTITIES"))
+                .into_iter()
+                .filter_map(|parameter| match parameter {
+                    Parameter::Keyword { value, .. } => Some(value.as_string().to_string()),
+                    _ => None,
+                })
+                .collect();
+            for (entity_variable, entity_table) in &mut entities {
+                if entity_table.table.is_none() && joined.contains(entity_variable) {
+                    entity_table.table.clone_from(&service_table.table);
+                }
+            }
+
+            // Collections and transactions operate on the service's table
+            for (pattern, collection_operations) in [
+                (
+                    format!("{variable}.collections.$NAME($$$ARGS)"),
+                    &["Query"][..],
+                ),
+                (
+                    format!("{variable}.transaction.write($$$ARGS)"),
+                    &["TransactWriteItems"],
+                ),
+                (
+                    format!("{variable}.transaction.get($$$ARGS)"),
+                    &["TransactGetItems"],
+                ),
+            ] {
+                for node_match in find_matches(scanner, &pattern) {
+                    operations.extend(orm_calls(
+                        collection_operations,
+                        &service_table,
+                        None,
+                        &usage(scanner, &node_match),
+                    ));
+                }
+            }
+        }
+    }
+
+    for (variable, entity_table) in &entities {
+        for (method, method_operations) in ELECTRODB_METHODS {
+            for node_match in find_matches(scanner, &format!("{variable}.{method}($$$ARGS)")) {
+                let batch_operation = ELECTRODB_BATCH_METHODS
+                    .iter()
+                    .find(|(batch_method, _)| batch_method == method)
+                    .filter(|_| {
+                        argument(node_match.get_node(), 0)
+                            .is_some_and(|argument| argument.kind() == "array")
+                    })
+                    .map(|(_, batch_operation)| [*batch_operation]);
+                let method_operations = match &batch_operation {
+                    Some(batch_operation) => &batch_operation[..],
+                    None => *method_operations,
+                };
+                operations.extend(orm_calls(
+                    method_operations,
+                    entity_table,
+                    None,
+                    &usage(scanner, &node_match),
+                ));
+            }
+        }
+
+        for node_match in find_matches(scanner, &format!("{variable}.query.$ACCESS($$$ARGS)")) {
+            let access_pattern = node_match
+                .get_env()
+                .get_match("ACCESS")
+                .map(|access| access.text().to_string())
+                .unwrap_or_default();
+            operations.extend(orm_calls(
+                &["Query"],
+                entity_table,
+                entity_table
+                    .indexes
+                    .get(&access_pattern)
+                    .map(String::as_str),
+                &usage(scanner, &node_match),
+            ));
+        }
+        for node_match in find_matches(scanner, &format!("{variable}.scan")) {
+            operations.extend(orm_calls(
+                &["Scan"],
+                entity_table,
+                None,
+                &usage(scanner, &node_match),
+            ));
+        }
+    }
+    operations
+}
+
+/// Operations of a OneTable `Table` and the models it hands out
+///
+/// The table is the `name` option of `new Table({ name, schema, client })`;
+/// model calls passing `{ index: "gs1" }` options are scoped to that index.
+fn onetable_operations<T>(scanner: &ASTScanner<T>, table_class: &str) -> Vec<SdkMethodCall>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let mut operations = Vec::new();
+
+    for table_match in find_matches(
+        scanner,
+        &format!("const $VAR = new {table_class}($OPTIONS)"),
+    ) {
+        let env = table_match.get_env();
+        let Some(table_variable) = env.get_match("VAR").map(|node| node.text().to_string()) else {
+            continue;
+        };
+        let model_table = ModelTable {
+            table: parameter_string(
+                scanner,
+                &object_parameters(env.get_match("OPTIONS")),
+                "name",
+            ),
+            ..ModelTable::default()
+        };
+
+        for (method, method_operations) in ONETABLE_TABLE_METHODS {
+            for node_match in find_matches(scanner, &format!("{table_variable}.{method}($$$ARGS)"))
+            {
+                operations.extend(orm_calls(
+                    method_operations,
+                    &model_table,
+                    None,
+                    &usage(scanner, &node_match),
+                ));
+            }
+        }
+        for node_match in find_matches(scanner, &format!("{table_variable}.transact($$$ARGS)")) {
+            let transaction = argument(node_match.get_node(), 0)
+                .and_then(|operation| string_value(scanner, &operation.text()));
+            let transact_operations = match transaction.as_deref() {
+                Some("write") => &["TransactWriteItems"][..],
+                Some("get") => &["TransactGetItems"],
+                _ => &["TransactWriteItems", "TransactGetItems"],
+            };
+            operations.extend(orm_calls(
+                transact_operations,
+                &model_table,
+                None,
+                &usage(scanner, &node_match),
+            ));
+        }
+
+        let models = [
+            format!("const $VAR = {table_variable}.getModel($NAME)"),
+            format!("const $VAR = {table_variable}.getModel<$TYPE>($NAME)"),
+        ]
+        .iter()
+        .flat_map(|pattern| find_matches(scanner, pattern))
+        .filter_map(|node_match| {
+            node_match
+                .get_env()
+                .get_match("VAR")
+                .map(|node| node.text().to_string())
+        })
+        .collect::<BTreeSet<_>>();
+
+        for model in models {
+            for (method, method_operations) in ONETABLE_MODEL_METHODS {
+                for node_match in find_matches(scanner, &format!("{model}.{method}($$$ARGS)")) {
+                    let index = argument(node_match.get_node(), 1).and_then(|options| {
+                        let options = ArgumentExtractor::parse_object_literal_with_resolution(
+                            &options.text(),
+                        );
+                        parameter_string(scanner, &options, "index")
+                    });
+                    operations.extend(orm_calls(
+                        method_operations,
+                        &model_table,
+                        index.as_deref(),
+                        &usage(scanner, &node_match),
+                    ));
+                }
+            }
+        }
+    }
+    operations
+}
+
+/// DynamoDB calls for ORM operations at one usage site
+fn orm_calls(
+    operations: &[&str],
+    model_table: &ModelTable,
+    index: Option<&str>,
+    usage: &CommandUsage<'_>,
+) -> Vec<SdkMethodCall> {
+    let bindings = model_table.resource_bindings(index);
+    operations
+        .iter()
+        .map(|operation| {
+            ExtractionUtils::with_resource_bindings(
+                ExtractionUtils::build_sdk_method_call(operation, DYNAMODB_SERVICE, usage),
+                &bindings,
+            )
+        })
+        .collect()
+}
+
+pub(super) fn find_matches<'a, T>(
+    scanner: &'a ASTScanner<T>,
+    pattern: &str,
+) -> Vec<NodeMatch<'a, StrDoc<T>>>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    scanner.find_all_matches(pattern).unwrap_or_default()
+}
+
+/// Usage site of a matched library call; library arguments aren't SDK input parameters
+pub(super) fn usage<'a, T>(
+    scanner: &ASTScanner<T>,
+    node_match: &NodeMatch<'a, StrDoc<T>>,
+) -> CommandUsage<'a>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let location = Location::from_node(scanner.ast_grep.source_file.path.clone(), node_match);
+    CommandUsage::new(node_match.text(), location, Vec::new())
+}
+
+pub(super) fn object_parameters<T>(node: Option<&Node<'_, StrDoc<T>>>) -> Vec<Parameter>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    node.map(|node| ArgumentExtractor::parse_object_literal_with_resolution(&node.text()))
+        .unwrap_or_default()
+}
+
+/// Properties of the object literal held by a parameter
+pub(super) fn parameter_object(parameters: &[Parameter], property: &str) -> Vec<Parameter> {
+    parameters
+        .iter()
+        .find_map(|parameter| match parameter {
+            Parameter::Keyword { name, value, .. } if name == property => Some(
+                ArgumentExtractor::parse_object_literal_with_resolution(value.as_string()),
+            ),
+            _ => None,
+        })
+        .unwrap_or_default()
+}
+
+/// String value of a parameter: a literal, or a string constant of the file
+pub(super) fn parameter_string<T>(
+    scanner: &ASTScanner<T>,
+    parameters: &[Parameter],
+    property: &str,
+) -> Option<String>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    parameters.iter().find_map(|parameter| match parameter {
+        Parameter::Keyword { name, value, .. } if name == property => match value {
+            ParameterValue::Resolved(value) => Some(value.clone()),
+            ParameterValue::Unresolved(expression) => scanner.string_constant(expression.trim()),
+        },
+        _ => None,
+    })
+}
+
+/// Whether an option is set to `false`
+fn disables(options: &[Parameter], option: &str) -> bool {
+    options.iter().any(|parameter| {
+        matches!(parameter, Parameter::Keyword { name, value: ParameterValue::Resolved(value), .. }
+            if name == option && value == "false")
+    })
+}
+
+/// Value of a string literal or string constant expression
+pub(super) fn string_value<T>(scanner: &ASTScanner<T>, expression: &str) -> Option<String>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let expression = expression.trim();
+    ['"', '\'', '`']
+        .iter()
+        .find_map(|quote| expression.strip_prefix(*quote)?.strip_suffix(*quote))
+        .filter(|literal| !literal.contains("${"))
+        .map(str::to_string)
+        .or_else(|| scanner.string_constant(expression))
+}
+
+/// Argument at `position` of a call expression
+pub(super) fn argument<'r, T>(
+    call: &Node<'r, StrDoc<T>>,
+    position: usize,
+) -> Option<Node<'r, StrDoc<T>>>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    call.field("arguments")?
+        .children()
+        .filter(Node::is_named)
+        .nth(position)
+}
+
+/// First argument of a `.method(...)` call further along the call chain of `call`
+///
+/// For `User.query("email").eq(email).using("EmailIndex").exec()` and method
+/// `using`, this is `"EmailIndex"`.
+fn chained_call_argument<'r, T>(
+    call: &Node<'r, StrDoc<T>>,
+    method: &str,
+) -> Option<Node<'r, StrDoc<T>>>
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let mut current = call.parent();
+    while let Some(node) = current {
+        match node.kind().as_ref() {
+            "member_expression" => {
+                if node
+                    .field("property")
+                    .is_some_and(|property| property.text() == method)
+                {
+                    return node.parent().and_then(|chained| argument(&chained, 0));
+                }
+            }
+            "call_expression" => {}
+            _ => return None,
+        }
+        current = node.parent();
+    }
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::SourceFile;
+    use ast_grep_core::tree_sitter::LanguageExt;
+    use ast_grep_language::{JavaScript, TypeScript};
+    use std::path::PathBuf;
+
+    use crate::extraction::AstWithSourceFile;
+
+    /// (operation, table, index) of each extracted call
+    fn scoped_operations(
+        operations: &[SdkMethodCall],
+    ) -> Vec<(String, Option<String>, Option<String>)> {
+        operations
+            .iter()
+            .map(|operation| {
+                assert_eq!(
+                    operation.possible_services,
+                    vec![DYNAMODB_SERVICE.to_string()]
+                );
+                let bindings = &operation
+                    .metadata
+                    .as_ref()
+                    .expect("metadata")
+                    .resource_bindings;
+                (
+                    operation.name.clone(),
+                    bindings.get(TABLE_PLACEHOLDER).cloned(),
+                    bindings.get(INDEX_PLACEHOLDER).cloned(),
+                )
+            })
+            .collect()
+    }
+
+    fn scoped(
+        operation: &str,
+        table: Option<&str>,
+        index: Option<&str>,
+    ) -> (String, Option<String>, Option<String>) {
+        (
+            operation.to_string(),
+            table.map(str::to_string),
+            index.map(str::to_string),
+        )
+    }
+
+    fn extract_typescript(source_code: &str) -> Vec<SdkMethodCall> {
+        let source_file = SourceFile::with_language(
+            PathBuf::new(),
+            source_code.to_string(),
+            crate::Language::TypeScript,
+        );
+        let ast = AstWithSourceFile::new(TypeScript.ast_grep(&source_file.content), source_file);
+        extract_orm_operations(&ASTScanner::new(ast, TypeScript.into()))
+    }
+
+    #[test]
+    fn test_dynamoose_models() {
+        let source_code = r#"
+const dynamoose = require("dynamoose");
+
+const User = dynamoose.model("User", userSchema, { prefix: "prod-" });
+const Audit = dynamoose.model("Audit", auditSchema, { create: false });
+
+async function handler(event) {
+    const user = await User.get({ id: event.id });
+    const byEmail = await User.query("email").eq(event.email).using("EmailIndex").exec();
+    await new Audit({ id: event.id, action: "read" }).save();
+}
+        "#;
+        let source_file = SourceFile::with_language(
+            PathBuf::new(),
+            source_code.to_string(),
+            crate::Language::JavaScript,
+        );
+        let ast = AstWithSourceFile::new(JavaScript.ast_grep(&source_file.content), source_file);
+        let operations = extract_orm_operations(&ASTScanner::new(ast, JavaScript.into()));
+
+        assert_eq!(
+            scoped_operations(&operations),
+            vec![
+                scoped("DescribeTable", Some("prod-User"), None),
+                scoped("CreateTable", Some("prod-User"), None),
+                scoped("GetItem", Some("prod-User"), None),
+                scoped("Query", Some("prod-User"), Some("EmailIndex")),
+                // `create: false` leaves the Audit table to infrastructure code
+                scoped("DescribeTable", Some("Audit"), None),
+                scoped("PutItem", Some("Audit"), None),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_electrodb_entities_and_services() {
+        let operations = extract_typescript(
+            r#"
+import { Entity, Service } from "electrodb";
+
+const TABLE = "app-data";
+
+const Order = new Entity(
+    {
+        model: { entity: "order", version: "1", service: "shop" },
+        attributes: { orderId: { type: "string" }, customerId: { type: "string" } },
+        indexes: {
+            primary: { pk: { field: "pk", composite: ["orderId"] }, sk: { field: "sk", composite: [] } },
+            byCustomer: { index: "gsi1", pk: { field: "gsi1pk", composite: ["customerId"] }, sk: { field: "gsi1sk", composite: ["orderId"] } },
+        },
+    },
+    { client },
+);
+const Customer = new Entity(customerSchema, { table: "customers", client });
+const shop = new Service({ order: Order }, { table: TABLE, client });
+
+export async function listOrders(customerId: string, ids: string[]) {
+    await Order.query.byCustomer({ customerId }).go();
+    await Order.get(ids.map((orderId) => ({ orderId }))).go();
+    await Order.get([{ orderId: "a" }, { orderId: "b" }]).go();
+    await Customer.patch({ customerId }).set({ active: true }).go();
+    await shop.collections.customerOrders({ customerId }).go();
+}
+            "#,
+        );
+
+        assert_eq!(
+            scoped_operations(&operations),
+            vec![
+                scoped("Query", Some("app-data"), None),
+                scoped("GetItem", Some("app-data"), None),
+                scoped("BatchGetItem", Some("app-data"), None),
+                scoped("Query", Some("app-data"), Some("gsi1")),
+                scoped("UpdateItem", Some("customers"), None),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_onetable_models() {
+        let operations = extract_typescript(
+            r#"
+import { Table } from "dynamodb-onetable";
+
+const table = new Table({ client, name: "MyApp", schema: Schema, partial: true });
+const User = table.getModel<UserType>("User");
+
+export async function setup() {
+    await table.createTable();
+}
+
+export async function findUsers(email: string) {
+    const users = await User.find({ email }, { index: "gs1" });
+    await User.remove({ id: users[0].id });
+    await table.transact("write", transaction);
+}
+            "#,
+        );
+
+        assert_eq!(
+            scoped_operations(&operations),
+            vec![
+                scoped("CreateTable", Some("MyApp"), None),
+                scoped("TransactWriteItems", Some("MyApp"), None),
+                scoped("Query", Some("MyApp"), Some("gs1")),
+                scoped("DeleteItem", Some("MyApp"), None),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_models_without_orm_imports() {
+        let operations = extract_typescript(
+            r#"
+import { Table } from "./local-table";
+
+const table = new Table({ name: "MyApp" });
+await table.createTable();
+            "#,
+        );
+
+        assert!(operations.is_empty());
+    }
+}
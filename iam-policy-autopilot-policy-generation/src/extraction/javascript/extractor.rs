@@ -6,20 +6,35 @@ use ast_grep_core::tree_sitter::LanguageExt;
 use ast_grep_language::JavaScript;
 use async_trait::async_trait;
 use std::collections::HashSet;
+use std::sync::Arc;
 
 use crate::extraction::extractor::{Extractor, ExtractorResult};
+use crate::extraction::javascript::project_clients::ProjectClients;
 use crate::extraction::javascript::scanner::ASTScanner;
 use crate::extraction::javascript::shared::ExtractionUtils;
+use crate::extraction::shared::bind_literal_resources;
 use crate::extraction::AstWithSourceFile;
 use crate::{ServiceModelIndex, SourceFile};
 
 /// JavaScript extractor for AWS SDK method calls
-pub(crate) struct JavaScriptExtractor;
+pub(crate) struct JavaScriptExtractor {
+    project_clients: Arc<ProjectClients>,
+}
 
 impl JavaScriptExtractor {
     /// Create a new JavaScript extractor instance
     pub(crate) fn new() -> Self {
-        Self
+        Self {
+            project_clients: Arc::default(),
+        }
+    }
+
+    /// Collect the clients constructed in all project sources, so clients imported
+    /// from wrapper modules and workspace packages (`import { s3 } from "@org/aws-clients"`)
+    /// are recognized in the files using them.
+    pub(crate) fn with_project_sources(mut self, source_files: &[SourceFile]) -> Self {
+        self.project_clients = Arc::new(ProjectClients::from_source_files(source_files));
+        self
     }
 }
 
@@ -37,7 +52,8 @@ impl Extractor for JavaScriptExtractor {
         let ast = AstWithSourceFile::new(ast_grep, source_file.clone());
 
         // Create scanner with the pre-built AST
-        let mut scanner = ASTScanner::new(ast.clone(), JavaScript.into());
+        let mut scanner = ASTScanner::new(ast.clone(), JavaScript.into())
+            .with_project_clients(Arc::clone(&self.project_clients));
 
         let scan_results = match scanner.scan_all() {
             Ok(results) => results,
@@ -132,15 +148,11 @@ impl Extractor for JavaScriptExtractor {
                 }
             });
 
+            // Scope each usage to its literal resources before duplicates merge
+            bind_literal_resources(method_calls, Some(&*self.project_clients));
+
             // Then: Deduplicate by (operation_name, service) pairs
-            // JavaScript SDK v3 may extract the same operation from multiple sources
-            // (e.g., QueryCommandInput and paginateQuery both infer Query operation)
-            let mut seen = HashSet::new();
-            method_calls.retain(|call| {
-                // Create a key from operation name and all possible services
-                let key = (call.name.clone(), call.possible_services.clone());
-                seen.insert(key)
-            });
+            ExtractionUtils::deduplicate_operations(method_calls);
         }
     }
 
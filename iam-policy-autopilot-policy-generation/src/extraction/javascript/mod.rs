@@ -3,8 +3,12 @@
 //! This module provides functionality for extracting AWS SDK method calls
 //! from JavaScript and TypeScript source code using ast-grep patterns.
 
+pub(crate) mod amplify;
 pub(crate) mod argument_extractor;
+pub(crate) mod dynamodb_orms;
 pub(crate) mod extractor;
+pub(crate) mod project_clients;
 pub(crate) mod scanner;
 pub(crate) mod shared;
+pub(crate) mod test_doubles;
 pub(crate) mod types;
@@ -0,0 +1,597 @@
+//! Clients shared between the modules of a JavaScript/TypeScript project
+//!
+//! Monorepos commonly construct clients once in a wrapper package and import
+//! them wherever they are used:
+//!
+//! ```ts
+//! // packages/aws-clients/src/index.ts
+//! export const s3 = new S3Client({});
+//!
+//! // services/reports/src/handler.ts
+//! import { s3 } from "@org/aws-clients";
+//! await s3.getObject({ Bucket: "reports", Key: key });
+//! ```
+//!
+//! The consuming file has no `@aws-sdk` import of its own. This module records
+//! the clients each project file constructs and resolves import specifiers to
+//! project files through relative paths, `tsconfig.json` path aliases and the
+//! package names of workspace packages (pnpm, yarn and npm workspaces).
+//!
+//! Resource names are shared the same way (`export const TABLE_NAME = "orders"`),
+//! so the module-level constants of each file and the names it imports are
+//! recorded too, resolving `TABLE_NAME` or `config.tables.orders` at call sites.
+
+use std::collections::{HashMap, HashSet};
+use std::path::{Component, Path, PathBuf};
+
+use ast_grep_core::tree_sitter::LanguageExt;
+use ast_grep_language::{JavaScript, TypeScript};
+use serde::Deserialize;
+
+use crate::extraction::javascript::scanner::ASTScanner;
+use crate::extraction::shared::{ProjectConstants, ResourceValue};
+use crate::extraction::AstWithSourceFile;
+use crate::{Language, SourceFile};
+
+/// Extensions tried, in order, for import specifiers without one
+const SOURCE_EXTENSIONS: [&str; 6] = ["ts", "tsx", "js", "jsx", "mjs", "cjs"];
+
+/// package.json fields naming a package's entry point, in order of preference
+/// (`source` points at the TypeScript sources of packages built to `dist`)
+const PACKAGE_ENTRY_FIELDS: [&str; 4] = ["source", "module", "main", "types"];
+
+const TSCONFIG_FILE: &str = "tsconfig.json";
+const PACKAGE_MANIFEST_FILE: &str = "package.json";
+
+/// A client constructed at module level of a project file
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) struct SharedClient {
+    /// Original AWS client type name (e.g., "S3Client")
+    pub(crate) original_client_type: String,
+    /// AWS SDK sublibrary (e.g., "client-s3")
+    pub(crate) sublibrary: String,
+}
+
+/// What one project file makes importable
+#[derive(Debug, Default)]
+struct ModuleExports {
+    /// Clients by variable name
+    clients: HashMap<String, SharedClient>,
+    /// Specifiers of `export * from "..."` and `export { ... } from "..."`
+    reexports: Vec<String>,
+    /// Values of module-level constants by name or property path
+    constants: HashMap<String, ResourceValue>,
+    /// Imported names: local name -> (module specifier, imported name, `*` for namespaces)
+    imports: HashMap<String, (String, String)>,
+}
+
+/// `compilerOptions.paths` of one tsconfig.json
+#[derive(Debug)]
+struct PathAliases {
+    /// Directory alias targets are relative to (`baseUrl`, or the tsconfig directory)
+    base_dir: PathBuf,
+    /// (pattern, targets), longest pattern prefix first as TypeScript matches them
+    paths: Vec<(String, Vec<String>)>,
+}
+
+#[derive(Debug, Default, Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct TsConfig {
+    #[serde(default)]
+    compiler_options: CompilerOptions,
+}
+
+#[derive(Debug, Default, Deserialize)]
+#[serde(rename_all = "camelCase")]
+struct CompilerOptions {
+    base_url: Option<String>,
+    #[serde(default)]
+    paths: HashMap<String, Vec<String>>,
+}
+
+/// Clients of all project files and how import specifiers resolve to those files
+#[derive(Debug, Default)]
+pub(crate) struct ProjectClients {
+    modules: HashMap<PathBuf, ModuleExports>,
+    /// Workspace packages: package name -> package directory
+    packages: HashMap<String, PathBuf>,
+    /// Entry point fields of each workspace package directory
+    package_entries: HashMap<PathBuf, Vec<String>>,
+    /// Path aliases by tsconfig.json directory
+    aliases: HashMap<PathBuf, PathAliases>,
+}
+
+impl ProjectClients {
+    /// Collect the clients of every project source file, and the tsconfig.json
+    /// and package.json files found in the directories above them
+    pub(crate) fn from_source_files(source_files: &[SourceFile]) -> Self {
+        let mut project = Self::default();
+        let mut visited_dirs = HashSet::new();
+
+        for source_file in source_files {
+            let exports = if source_file.language == Language::TypeScript {
+                let ast = AstWithSourceFile::new(
+                    TypeScript.ast_grep(&source_file.content),
+                    source_file.clone(),
+                );
+                module_exports(ASTScanner::new(ast, TypeScript.into()))
+            } else {
+                let ast = AstWithSourceFile::new(
+                    JavaScript.ast_grep(&source_file.content),
+                    source_file.clone(),
+                );
+                module_exports(ASTScanner::new(ast, JavaScript.into()))
+            };
+            project
+                .modules
+                .insert(normalize(&source_file.path), exports);
+
+            for dir in source_file.path.ancestors().skip(1) {
+                if !visited_dirs.insert(dir.to_path_buf()) {
+                    break;
+                }
+                project.load_directory_config(dir);
+            }
+        }
+
+        log::debug!(
+            "Indexed JavaScript/TypeScript modules: {} files, {} workspace packages, {} tsconfig files",
+            project.modules.len(),
+            project.packages.len(),
+            project.aliases.len()
+        );
+        project
+    }
+
+    /// Read the tsconfig.json and package.json of a directory, if present
+    fn load_directory_config(&mut self, dir: &Path) {
+        let dir = normalize(dir);
+
+        if let Some(tsconfig) = read_jsonc::<TsConfig>(&dir.join(TSCONFIG_FILE)) {
+            let options = tsconfig.compiler_options;
+            let base_dir = options
+                .base_url
+                .map_or_else(|| dir.clone(), |base_url| normalize(&dir.join(base_url)));
+            let mut paths: Vec<_> = options.paths.into_iter().collect();
+            paths.sort_by_key(|(pattern, _)| {
+                std::cmp::Reverse(pattern.split('*').next().unwrap_or_default().len())
+            });
+            self.aliases
+                .insert(dir.clone(), PathAliases { base_dir, paths });
+        }
+
+        if let Some(manifest) = read_jsonc::<serde_json::Value>(&dir.join(PACKAGE_MANIFEST_FILE)) {
+            if let Some(name) = manifest.get("name").and_then(serde_json::Value::as_str) {
+                self.packages.insert(name.to_string(), dir.clone());
+            }
+            let entries = PACKAGE_ENTRY_FIELDS
+                .iter()
+                .filter_map(|field| manifest.get(*field).and_then(serde_json::Value::as_str))
+                .map(str::to_string)
+                .collect();
+            self.package_entries.insert(dir, entries);
+        }
+    }
+
+    /// The client imported as `name` from `specifier` by the file at `importer`
+    pub(crate) fn shared_client(
+        &self,
+        importer: &Path,
+        specifier: &str,
+        name: &str,
+    ) -> Option<&SharedClient> {
+        let module = self.resolve(importer, specifier)?;
+        self.exported_client(&module, name, &mut HashSet::new())
+    }
+
+    /// Look up a client in a module and the modules it re-exports
+    fn exported_client(
+        &self,
+        module: &Path,
+        name: &str,
+        visited: &mut HashSet<PathBuf>,
+    ) -> Option<&SharedClient> {
+        self.exported(module, &|exports| exports.clients.get(name), visited)
+    }
+
+    /// Look up a constant in a module and the modules it re-exports
+    fn exported_constant(&self, module: &Path, name: &str) -> Option<&ResourceValue> {
+        self.exported(
+            module,
+            &|exports| exports.constants.get(name),
+            &mut HashSet::new(),
+        )
+    }
+
+    /// Look up an export in a module and the modules it re-exports
+    fn exported<'a, V>(
+        &'a self,
+        module: &Path,
+        lookup: &impl Fn(&'a ModuleExports) -> Option<&'a V>,
+        visited: &mut HashSet<PathBuf>,
+    ) -> Option<&'a V> {
+        if !visited.insert(module.to_path_buf()) {
+            return None;
+        }
+        let exports = self.modules.get(module)?;
+        if let Some(export) = lookup(exports) {
+            return Some(export);
+        }
+        exports.reexports.iter().find_map(|reexport| {
+            let target = self.resolve(module, reexport)?;
+            self.exported(&target, lookup, visited)
+        })
+    }
+
+    /// Resolve an import specifier to a project file
+    fn resolve(&self, importer: &Path, specifier: &str) -> Option<PathBuf> {
+        if specifier.starts_with('.') {
+            return self.resolve_file(&importer.parent()?.join(specifier));
+        }
+        self.resolve_alias(importer, specifier)
+            .or_else(|| self.resolve_package(specifier))
+    }
+
+    /// Resolve a specifier through the paths of the importer's nearest tsconfig.json
+    fn resolve_alias(&self, importer: &Path, specifier: &str) -> Option<PathBuf> {
+        let aliases = normalize(importer)
+            .ancestors()
+            .skip(1)
+            .find_map(|dir| self.aliases.get(dir))?;
+
+        aliases.paths.iter().find_map(|(pattern, targets)| {
+            let wildcard = match pattern.split_once('*') {
+                Some((prefix, suffix)) => specifier
+                    .strip_prefix(prefix)?
+                    .strip_suffix(suffix)?
+                    .to_string(),
+                None if pattern == specifier => String::new(),
+                None => return None,
+            };
+            targets.iter().find_map(|target| {
+                self.resolve_file(&aliases.base_dir.join(target.replacen('*', &wildcard, 1)))
+            })
+        })
+    }
+
+    /// Resolve a specifier naming a workspace package, or a path inside one
+    fn resolve_package(&self, specifier: &str) -> Option<PathBuf> {
+        let segments = if specifier.starts_with('@') { 2 } else { 1 };
+        let mut parts = specifier.splitn(segments + 1, '/');
+        let name = parts.by_ref().take(segments).collect::<Vec<_>>().join("/");
+        let subpath = parts.next();
+        let dir = self.packages.get(&name)?;
+
+        if let Some(subpath) = subpath {
+            return self
+                .resolve_file(&dir.join(subpath))
+                .or_else(|| self.resolve_file(&dir.join("src").join(subpath)));
+        }
+        self.package_entries
+            .get(dir)
+            .into_iter()
+            .flatten()
+            .find_map(|entry| self.resolve_file(&dir.join(entry)))
+            .or_else(|| self.resolve_file(&dir.join("src/index")))
+            .or_else(|| self.resolve_file(&dir.join("index")))
+    }
+
+    /// The project file a module path refers to, trying extensions and `index` files
+    ///
+    /// ESM-style `./s3.js` imports also resolve to the TypeScript source next to
+    /// them; compiled entry points (`dist/index.js`) rely on the `source` field or
+    /// the `src/index` fallback.
+    fn resolve_file(&self, base: &Path) -> Option<PathBuf> {
+        let base = normalize(base);
+        let stem = match base.extension().and_then(|extension| extension.to_str()) {
+            Some("js" | "jsx" | "mjs" | "cjs" | "ts" | "tsx") => base.with_extension(""),
+            _ => base.clone(),
+        };
+
+        let index = base.join("index");
+
+        std::iter::once(base.clone())
+            .chain(
+                SOURCE_EXTENSIONS
+                    .iter()
+                    .map(|extension| with_extension(&stem, extension)),
+            )
+            .chain(
+                SOURCE_EXTENSIONS
+                    .iter()
+                    .map(|extension| with_extension(&index, extension)),
+            )
+            .find(|candidate| self.modules.contains_key(candidate))
+    }
+}
+
+impl ProjectConstants for ProjectClients {
+    /// Constants of the file at `path` itself, or imported by it: `TABLE_NAME` for
+    /// `import { TABLE_NAME } from "./config"`, `config.TABLE_NAME` for
+    /// `import * as config from "./config"`
+    fn constant_value(&self, path: &Path, expression: &str) -> Option<ResourceValue> {
+        let module = normalize(path);
+        let exports = self.modules.get(&module)?;
+        if let Some(value) = exports.constants.get(expression) {
+            return Some(value.clone());
+        }
+
+        let (local, property) = match expression.split_once('.') {
+            Some((local, property)) => (local, Some(property)),
+            None => (expression, None),
+        };
+        let (specifier, imported) = exports.imports.get(local)?;
+        let name = match (imported.as_str(), property) {
+            ("*", Some(property)) => property.to_string(),
+            ("*", None) => return None,
+            (imported, Some(property)) => format!("{imported}.{property}"),
+            (imported, None) => imported.to_string(),
+        };
+        let target = self.resolve(&module, specifier)?;
+        self.exported_constant(&target, &name).cloned()
+    }
+}
+
+/// Clients, constants, imports and re-exports of one parsed module
+fn module_exports<T>(mut scanner: ASTScanner<T>) -> ModuleExports
+where
+    T: ast_grep_language::LanguageExt,
+{
+    let clients = scanner
+        .scan_client_instantiations()
+        .unwrap_or_default()
+        .into_iter()
+        // `this.s3` fields of injected clients aren't importable
+        .filter(|client| !client.variable.contains('.'))
+        .map(|client| {
+            (
+                client.variable,
+                SharedClient {
+                    original_client_type: client.original_client_type,
+                    sublibrary: client.sublibrary,
+                },
+            )
+        })
+        .collect();
+
+    let imports = scanner
+        .scan_non_sdk_imports()
+        .into_iter()
+        .flat_map(|imported| {
+            let specifier = imported.sublibrary;
+            imported.imports.into_iter().map(move |import_info| {
+                (
+                    import_info.local_name,
+                    (specifier.clone(), import_info.original_name),
+                )
+            })
+        })
+        .collect();
+
+    ModuleExports {
+        clients,
+        reexports: scanner.scan_reexports(),
+        constants: scanner.scan_module_constants(),
+        imports,
+    }
+}
+
+/// Append an extension, keeping dots in the file name (`s3.service` -> `s3.service.ts`)
+fn with_extension(path: &Path, extension: &str) -> PathBuf {
+    let mut path = path.as_os_str().to_owned();
+    path.push(".");
+    path.push(extension);
+    PathBuf::from(path)
+}
+
+/// Lexically normalize a path, resolving `.` and `..` components
+fn normalize(path: &Path) -> PathBuf {
+    let mut normalized = PathBuf::new();
+    for component in path.components() {
+        match component {
+            Component::CurDir => {}
+            Component::ParentDir => {
+                if !normalized.pop() {
+                    normalized.push(component);
+                }
+            }
+            _ => normalized.push(component),
+        }
+    }
+    normalized
+}
+
+/// Read a JSON file that may contain comments and trailing commas, as tsconfig.json does
+fn read_jsonc<T: serde::de::DeserializeOwned>(path: &Path) -> Option<T> {
+    let content = std::fs::read_to_string(path).ok()?;
+    match serde_json::from_str(&strip_json_comments(&content)) {
+        Ok(value) => Some(value),
+        Err(e) => {
+            log::debug!("Failed to parse {}: {e}", path.display());
+            None
+        }
+    }
+}
+
+/// Remove `//` and `/* */` comments and trailing commas outside of strings
+fn strip_json_comments(content: &str) -> String {
+    let mut stripped = String::with_capacity(content.len());
+    let mut chars = content.chars().peekable();
+    let mut in_string = false;
+
+    while let Some(ch) = chars.next() {
+        if in_string {
+            stripped.push(ch);
+            match ch {
+                '\\' => stripped.extend(chars.next()),
+                '"' => in_string = false,
+                _ => {}
+            }
+            continue;
+        }
+        match (ch, chars.peek()) {
+            ('"', _) => {
+                in_string = true;
+                stripped.push(ch);
+            }
+            ('/', Some('/')) => {
+                for next in chars.by_ref() {
+                    if next == '\n' {
+                        stripped.push('\n');
+                        break;
+                    }
+                }
+            }
+            ('/', Some('*')) => {
+                chars.next();
+                let mut previous = '\0';
+                for next in chars.by_ref() {
+                    if previous == '*' && next == '/' {
+                        break;
+                    }
+                    previous = next;
+                }
+            }
+            (',', _) => {
+                let rest = chars.clone().find(|c| !c.is_whitespace());
+                if !matches!(rest, Some('}' | ']')) {
+                    stripped.push(ch);
+                }
+            }
+            _ => stripped.push(ch),
+        }
+    }
+    stripped
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    fn write(root: &Path, relative_path: &str, content: &str) -> SourceFile {
+        let path = root.join(relative_path);
+        std::fs::create_dir_all(path.parent().expect("parent directory")).expect("create dir");
+        std::fs::write(&path, content).expect("write file");
+        SourceFile::with_language(path, content.to_string(), Language::TypeScript)
+    }
+
+    #[test]
+    fn test_strip_json_comments() {
+        let tsconfig = r#"{
+  // Path aliases
+  "compilerOptions": { /* shared */ "paths": { "@lib/*": ["lib/*"], }, "url": "http://x" },
+}"#;
+        let value: serde_json::Value =
+            serde_json::from_str(&strip_json_comments(tsconfig)).expect("valid JSON");
+        assert_eq!(value["compilerOptions"]["paths"]["@lib/*"][0], "lib/*");
+        assert_eq!(value["compilerOptions"]["url"], "http://x");
+    }
+
+    #[test]
+    fn test_shared_clients_resolve_across_packages() {
+        let tmp = TempDir::new().expect("create temp dir");
+        let root = tmp.path();
+        write(
+            root,
+            "package.json",
+            r#"{ "name": "monorepo", "private": true }"#,
+        );
+        write(
+            root,
+            "tsconfig.json",
+            r#"{ "compilerOptions": { "baseUrl": ".", "paths": { "@shared/*": ["libs/shared/src/*"] } } }"#,
+        );
+        write(
+            root,
+            "packages/aws-clients/package.json",
+            r#"{ "name": "@org/aws-clients", "main": "dist/index.js" }"#,
+        );
+        let sources = vec![
+            write(root, "packages/aws-clients/src/index.ts", "export * from \"./s3\";\n"),
+            write(
+                root,
+                "packages/aws-clients/src/s3.ts",
+                "import { S3Client } from \"@aws-sdk/client-s3\";\nexport const s3 = new S3Client({});\n",
+            ),
+            write(
+                root,
+                "libs/shared/src/queues.ts",
+                "import { SQS } from \"@aws-sdk/client-sqs\";\nexport const queue = new SQS({});\n",
+            ),
+            write(root, "services/api/src/handler.ts", "export {};\n"),
+        ];
+        let project = ProjectClients::from_source_files(&sources);
+        let importer = root.join("services/api/src/handler.ts");
+
+        let s3 = SharedClient {
+            original_client_type: "S3Client".to_string(),
+            sublibrary: "client-s3".to_string(),
+        };
+        assert_eq!(
+            project.shared_client(&importer, "@org/aws-clients", "s3"),
+            Some(&s3)
+        );
+        assert_eq!(
+            project.shared_client(&importer, "../../../packages/aws-clients/src/s3.js", "s3"),
+            Some(&s3)
+        );
+        assert_eq!(
+            project
+                .shared_client(&importer, "@shared/queues", "queue")
+                .map(|client| client.sublibrary.as_str()),
+            Some("client-sqs")
+        );
+        assert_eq!(
+            project.shared_client(&importer, "@org/aws-clients", "dynamodb"),
+            None
+        );
+        assert_eq!(project.shared_client(&importer, "@org/unknown", "s3"), None);
+    }
+
+    #[test]
+    fn test_constants_resolve_through_imports() {
+        let tmp = TempDir::new().expect("create temp dir");
+        let root = tmp.path();
+        let sources = vec![
+            write(
+                root,
+                "src/config/index.ts",
+                "export * from \"./tables\";\nexport const config = { buckets: { reports: process.env.REPORTS_BUCKET }, queue: `jobs-${stage}` } as const;\n",
+            ),
+            write(
+                root,
+                "src/config/tables.ts",
+                "export const TABLE_NAME = \"orders\";\n",
+            ),
+            write(
+                root,
+                "src/handler.ts",
+                "import { TABLE_NAME as ORDERS, config } from \"./config\";\nimport * as settings from \"./config\";\nconst INDEX_NAME = 'by-customer';\n",
+            ),
+        ];
+        let project = ProjectClients::from_source_files(&sources);
+        let handler = root.join("src/handler.ts");
+        let resolve = |expression| project.constant_value(&handler, expression);
+
+        assert_eq!(
+            resolve("INDEX_NAME"),
+            Some(ResourceValue::Literal("by-customer".to_string()))
+        );
+        assert_eq!(
+            resolve("ORDERS"),
+            Some(ResourceValue::Literal("orders".to_string()))
+        );
+        assert_eq!(
+            resolve("settings.TABLE_NAME"),
+            Some(ResourceValue::Literal("orders".to_string()))
+        );
+        assert_eq!(
+            resolve("config.buckets.reports"),
+            Some(ResourceValue::Environment("REPORTS_BUCKET".to_string()))
+        );
+        // Template literals with substitutions have no single value
+        assert_eq!(resolve("config.queue"), None);
+        assert_eq!(resolve("TABLE_NAME"), None);
+    }
+}
@@ -1,11 +1,13 @@
 //! Core JavaScript/TypeScript scanning logic for AWS SDK extraction
 
+use crate::extraction::javascript::project_clients::ProjectClients;
 use crate::extraction::javascript::shared::CommandUsage;
 use crate::extraction::javascript::types::{
     ClientInstantiation, ImportInfo, JavaScriptScanResults, MethodCall, SublibraryInfo,
     ValidClientTypes,
 };
-use crate::extraction::AstWithSourceFile;
+use crate::extraction::shared::ResourceValue;
+use crate::extraction::{AstWithSourceFile, Parameter};
 use crate::Location;
 
 use ast_grep_core::matcher::Pattern;
@@ -13,6 +15,55 @@ use ast_grep_core::{tree_sitter, MatchStrictness, NodeMatch};
 use ast_grep_core::{Doc, Node};
 
 use std::collections::HashMap;
+use std::sync::Arc;
+
+/// Function instrumenting an SDK v3 client for X-Ray tracing; exported by
+/// `aws-xray-sdk-core` and a method of the Powertools `Tracer`
+const XRAY_CAPTURE_FUNCTION: &str = "captureAWSv3Client";
+
+/// OpenTelemetry instrumentation tracing SDK calls (used by ADOT)
+const OTEL_AWS_INSTRUMENTATION: &str = "AwsInstrumentation";
+
+/// Callees of an X-Ray capture call: `AWSXRay.captureAWSv3Client`, `tracer.captureAWSv3Client`
+/// or a named import
+fn xray_capture_callees() -> [String; 2] {
+    [
+        format!("$TRACER.{XRAY_CAPTURE_FUNCTION}"),
+        XRAY_CAPTURE_FUNCTION.to_string(),
+    ]
+}
+
+/// TypeScript parameter kinds that may carry a type annotation
+const TYPED_PARAMETER_KINDS: [&str; 2] = ["required_parameter", "optional_parameter"];
+
+/// TypeScript class field kind, e.g. `@Inject(S3_CLIENT) private readonly s3!: S3Client;`
+const CLASS_FIELD_KIND: &str = "public_field_definition";
+
+/// Modifiers turning a constructor parameter into a class property
+const PARAMETER_PROPERTY_MODIFIERS: [&str; 2] = ["accessibility_modifier", "readonly"];
+
+/// npm scope of the middy middleware engine and its official middlewares
+const MIDDY_SCOPE: &str = "@middy/";
+
+/// Export statement kind; re-exports carry a `source` field (`export * from "./s3"`)
+const EXPORT_STATEMENT_KIND: &str = "export_statement";
+
+/// `const` and `let` declaration kind
+const LEXICAL_DECLARATION_KIND: &str = "lexical_declaration";
+
+/// One `name = value` of a declaration
+const VARIABLE_DECLARATOR_KIND: &str = "variable_declarator";
+
+/// Object literal kind and its `key: value` entries
+const OBJECT_KIND: &str = "object";
+const PAIR_KIND: &str = "pair";
+
+/// TypeScript wrappers of a value, e.g. `{ ... } as const`
+const TYPE_ASSERTION_KINDS: [&str; 3] = [
+    "as_expression",
+    "satisfies_expression",
+    "parenthesized_expression",
+];
 
 fn parse_object_literal(obj_text: &str) -> HashMap<String, String> {
     let mut result = HashMap::new();
@@ -100,6 +151,41 @@ fn parse_key_value_pair(pair: &str, result: &mut HashMap<String, String>) {
     }
 }
 
+/// Record the value of the constant `name`, and of its properties when it's an object
+fn collect_constant<D: Doc>(
+    name: String,
+    value: &Node<'_, D>,
+    constants: &mut HashMap<String, ResourceValue>,
+) {
+    let mut value = value.clone();
+    while TYPE_ASSERTION_KINDS.contains(&&*value.kind()) {
+        let Some(inner) = value.children().find(Node::is_named) else {
+            return;
+        };
+        value = inner;
+    }
+
+    if value.kind() == OBJECT_KIND {
+        for pair in value.children().filter(|child| child.kind() == PAIR_KIND) {
+            let (Some(key), Some(property)) = (pair.field("key"), pair.field("value")) else {
+                continue;
+            };
+            let key = key.text();
+            let key = key.trim_matches(['"', '\'']);
+            collect_constant(format!("{name}.{key}"), &property, constants);
+        }
+        return;
+    }
+
+    match ResourceValue::parse(&value.text(), &['"', '\'', '`']) {
+        Some(ResourceValue::Literal(literal)) if literal.contains("${") => {}
+        Some(value) => {
+            constants.insert(name, value);
+        }
+        None => {}
+    }
+}
+
 /// Core AST scanner for JavaScript/TypeScript AWS SDK usage patterns
 pub(crate) struct ASTScanner<T>
 where
@@ -108,6 +194,8 @@ where
     /// Pre-built AST grep root passed from extractor
     pub(crate) ast_grep: AstWithSourceFile<T>,
     pub(crate) language: ast_grep_language::SupportLang,
+    /// Clients constructed in other project files, importable by this one
+    project_clients: Option<Arc<ProjectClients>>,
 }
 
 impl<T> ASTScanner<T>
@@ -119,7 +207,17 @@ where
         ast_grep: AstWithSourceFile<T>,
         language: ast_grep_language::SupportLang,
     ) -> Self {
-        Self { ast_grep, language }
+        Self {
+            ast_grep,
+            language,
+            project_clients: None,
+        }
+    }
+
+    /// Recognize clients this file imports from other project files
+    pub(crate) fn with_project_clients(mut self, project_clients: Arc<ProjectClients>) -> Self {
+        self.project_clients = Some(project_clients);
+        self
     }
 
     fn parse_and_add_imports(
@@ -157,10 +255,15 @@ where
             return None;
         }
 
-        // Check for rename syntax: "OriginalName as LocalName"
-        if let Some(as_pos) = import_item.find(" as ") {
-            let original_name = import_item[..as_pos].trim().to_string();
-            let local_name = import_item[as_pos + 4..].trim().to_string();
+        // Check for rename syntax: "OriginalName as LocalName", or "OriginalName: LocalName"
+        // when destructuring a require or dynamic import
+        let rename = import_item
+            .find(" as ")
+            .map(|as_pos| (&import_item[..as_pos], &import_item[as_pos + 4..]))
+            .or_else(|| import_item.split_once(':'));
+        if let Some((original_name, local_name)) = rename {
+            let original_name = original_name.trim().to_string();
+            let local_name = local_name.trim().to_string();
             Some(ImportInfo::new(
                 original_name,
                 local_name,
@@ -179,8 +282,90 @@ where
         }
     }
 
+    /// Resolve the module name of an import, require or dynamic import
+    ///
+    /// Besides string literals, accepts template literals whose substitutions are
+    /// string constants of the file, e.g. `` require(`@aws-sdk/client-${SERVICE}`) ``
+    /// with `const SERVICE = "s3"`. Returns `None` for names that can't be resolved.
+    fn module_specifier(&self, module_node: &Node<'_, tree_sitter::StrDoc<T>>) -> Option<String> {
+        let text = module_node.text();
+        let Some(template) = text.strip_prefix('`').and_then(|t| t.strip_suffix('`')) else {
+            return Some(text.trim_matches('"').trim_matches('\'').to_string());
+        };
+
+        let mut resolved = String::new();
+        let mut rest = template;
+        while let Some(start) = rest.find("${") {
+            resolved.push_str(&rest[..start]);
+            let (expression, after) = rest[start + 2..].split_once('}')?;
+            resolved.push_str(&self.string_constant(expression.trim())?);
+            rest = after;
+        }
+        resolved.push_str(rest);
+        Some(resolved)
+    }
+
+    /// Value of the file's only `const NAME = "literal"` declaration for `name`
+    pub(crate) fn string_constant(&self, name: &str) -> Option<String> {
+        let value = self.const_initializer(name)?;
+        let value = value.trim();
+        ['"', '\'', '`']
+            .iter()
+            .find_map(|quote| value.strip_prefix(*quote)?.strip_suffix(*quote))
+            .filter(|literal| !literal.contains("${"))
+            .map(str::to_string)
+    }
+
+    /// Initializer text of the file's only `const` declaration of `name`
+    ///
+    /// Returns `None` when `name` isn't an identifier or is declared more than
+    /// once (e.g., in different functions), since the value is then ambiguous.
+    fn const_initializer(&self, name: &str) -> Option<String> {
+        let is_identifier =
+            !name.is_empty() && name.chars().all(|c| c.is_alphanumeric() || c == '_');
+        if !is_identifier {
+            return None;
+        }
+
+        let patterns = [
+            format!("const {name} = $VALUE"),
+            format!("const {name}: $TYPE = $VALUE"), // TypeScript: const input: PutObjectCommandInput = {...}
+        ];
+        let mut initializers = patterns
+            .iter()
+            .filter_map(|pattern| self.find_all_matches(pattern).ok())
+            .flatten()
+            .filter_map(|declaration| {
+                declaration
+                    .get_env()
+                    .get_match("VALUE")
+                    .map(|value| value.text().to_string())
+            });
+        let initializer = initializers.next()?;
+        initializers.next().is_none().then_some(initializer)
+    }
+
+    /// Parameters of a Command's input argument
+    ///
+    /// Input built separately and passed by name (`new PutObjectCommand(input)`)
+    /// is resolved through the `const` declaration holding the object literal.
+    fn command_input_parameters(
+        &self,
+        argument: Option<&Node<'_, tree_sitter::StrDoc<T>>>,
+    ) -> Vec<Parameter> {
+        use crate::extraction::javascript::argument_extractor::ArgumentExtractor;
+
+        if let Some(initializer) = argument
+            .and_then(|argument| self.const_initializer(argument.text().trim()))
+            .filter(|initializer| initializer.trim_start().starts_with('{'))
+        {
+            return ArgumentExtractor::parse_object_literal_with_resolution(&initializer);
+        }
+        ArgumentExtractor::extract_object_parameters(argument)
+    }
+
     /// Execute a pattern match against the AST using relaxed strictness to handle inline comments
-    fn find_all_matches(
+    pub(crate) fn find_all_matches(
         &self,
         pattern: &str,
     ) -> Result<Vec<NodeMatch<'_, tree_sitter::StrDoc<T>>>, String> {
@@ -199,82 +384,105 @@ where
         &self,
         command_name: &str,
     ) -> Option<CommandUsage<'_>> {
-        use crate::extraction::javascript::argument_extractor::ArgumentExtractor;
+        self.find_all_command_instantiations_with_args(command_name)
+            .into_iter()
+            .next()
+    }
 
+    /// Find every instantiation of a Command and extract its arguments, in source order
+    pub(crate) fn find_all_command_instantiations_with_args(
+        &self,
+        command_name: &str,
+    ) -> Vec<CommandUsage<'_>> {
         let pattern = format!("new {command_name}($ARGS)");
 
-        if let Ok(matches) = self.find_all_matches(&pattern) {
-            if let Some(first_match) = matches.first() {
+        let Ok(matches) = self.find_all_matches(&pattern) else {
+            return Vec::new();
+        };
+        matches
+            .iter()
+            .map(|node_match| {
                 let location =
-                    Location::from_node(self.ast_grep.source_file.path.clone(), first_match);
-                let env = first_match.get_env();
+                    Location::from_node(self.ast_grep.source_file.path.clone(), node_match);
+                let env = node_match.get_env();
 
                 // Extract arguments from the ARGS node
                 // env.get_match returns Option<&Node>, so pass directly
                 let args_node = env.get_match("ARGS");
-                let parameters = ArgumentExtractor::extract_object_parameters(args_node);
+                let parameters = self.command_input_parameters(args_node);
 
-                return Some(CommandUsage::new(first_match.text(), location, parameters));
-            }
-        }
-        None
+                CommandUsage::new(node_match.text(), location, parameters)
+            })
+            .collect()
+    }
+
+    /// Find the calls instrumenting SDK clients for tracing, in source order
+    ///
+    /// Covers X-Ray's `captureAWSv3Client` (directly or via the Powertools `Tracer`)
+    /// and OpenTelemetry's `new AwsInstrumentation()`.
+    pub(crate) fn find_tracing_instrumentation(&self) -> Vec<CommandUsage<'_>> {
+        let patterns = xray_capture_callees()
+            .map(|callee| format!("{callee}($$$ARGS)"))
+            .into_iter()
+            .chain([format!("new {OTEL_AWS_INSTRUMENTATION}($$$ARGS)")]);
+
+        let mut usages: Vec<CommandUsage<'_>> = patterns
+            .filter_map(|pattern| self.find_all_matches(&pattern).ok())
+            .flatten()
+            .map(|node_match| {
+                let location =
+                    Location::from_node(self.ast_grep.source_file.path.clone(), &node_match);
+                CommandUsage::new(node_match.text(), location, Vec::new())
+            })
+            .collect();
+        usages.sort_by_key(|usage| usage.location.start_position);
+        usages
     }
 
-    /// Find paginate function call and extract operation parameters (2nd argument)
-    pub(crate) fn find_paginate_function_with_args(
+    /// Find every paginate function call and extract its operation parameters
+    /// (2nd argument), in source order
+    pub(crate) fn find_paginate_function_calls_with_args(
         &self,
         function_name: &str,
-    ) -> Option<CommandUsage<'_>> {
+    ) -> Vec<CommandUsage<'_>> {
+        self.find_client_input_calls_with_args(function_name)
+    }
+
+    /// Find every waiter function call and extract its operation parameters
+    /// (2nd argument), in source order
+    ///
+    /// Awaited calls are matched through their inner call expression.
+    pub(crate) fn find_waiter_function_calls_with_args(
+        &self,
+        function_name: &str,
+    ) -> Vec<CommandUsage<'_>> {
+        self.find_client_input_calls_with_args(function_name)
+    }
+
+    /// Find every `function_name(client, input)` call, extracting parameters from `input`
+    fn find_client_input_calls_with_args(&self, function_name: &str) -> Vec<CommandUsage<'_>> {
         use crate::extraction::javascript::argument_extractor::ArgumentExtractor;
 
         // Use explicit two-argument pattern
         let pattern = format!("{function_name}($ARG1, $ARG2)");
 
-        if let Ok(matches) = self.find_all_matches(&pattern) {
-            if let Some(first_match) = matches.first() {
+        let Ok(matches) = self.find_all_matches(&pattern) else {
+            return Vec::new();
+        };
+        matches
+            .iter()
+            .map(|node_match| {
                 let location =
-                    Location::from_node(self.ast_grep.source_file.path.clone(), first_match);
-                let env = first_match.get_env();
+                    Location::from_node(self.ast_grep.source_file.path.clone(), node_match);
+                let env = node_match.get_env();
 
                 // Extract parameters from second argument (ARG2 = operation params)
                 let second_arg = env.get_match("ARG2");
                 let parameters = ArgumentExtractor::extract_object_parameters(second_arg);
 
-                return Some(CommandUsage::new(first_match.text(), location, parameters));
-            }
-        }
-        None
-    }
-
-    /// Find waiter function call and extract operation parameters (2nd argument)
-    pub(crate) fn find_waiter_function_with_args(
-        &self,
-        function_name: &str,
-    ) -> Option<CommandUsage<'_>> {
-        use crate::extraction::javascript::argument_extractor::ArgumentExtractor;
-
-        // Try patterns with and without await keyword using explicit two-argument pattern
-        let patterns = [
-            format!("await {function_name}($ARG1, $ARG2)"), // With await
-            format!("{function_name}($ARG1, $ARG2)"),       // Without await
-        ];
-
-        for pattern in &patterns {
-            if let Ok(matches) = self.find_all_matches(pattern) {
-                if let Some(first_match) = matches.first() {
-                    let location =
-                        Location::from_node(self.ast_grep.source_file.path.clone(), first_match);
-                    let env = first_match.get_env();
-
-                    // Extract parameters from second argument (ARG2 = operation params)
-                    let second_arg = env.get_match("ARG2");
-                    let parameters = ArgumentExtractor::extract_object_parameters(second_arg);
-
-                    return Some(CommandUsage::new(first_match.text(), location, parameters));
-                }
-            }
-        }
-        None
+                CommandUsage::new(node_match.text(), location, parameters)
+            })
+            .collect()
     }
 
     /// Find CommandInput type usage position (TypeScript-specific)
@@ -338,9 +546,7 @@ where
                                         .and_then(|args| args.children().nth(idx));
                                     ArgumentExtractor::extract_object_parameters(child.as_ref())
                                 }
-                                None => ArgumentExtractor::extract_object_parameters(
-                                    env.get_match("ARGS"),
-                                ),
+                                None => self.command_input_parameters(env.get_match("ARGS")),
                             };
 
                             results.push((
@@ -426,8 +632,9 @@ where
             let imports_node = env.get_match("IMPORTS");
 
             if let (Some(module_node), Some(imports_node)) = (module_node, imports_node) {
-                let module_text_cow = module_node.text();
-                let module_text = module_text_cow.trim_matches('"').trim_matches('\'');
+                let Some(module_text) = self.module_specifier(module_node) else {
+                    continue;
+                };
 
                 // Check if it's an AWS SDK statement
                 if let Some(sublibrary) = module_text.strip_prefix("@aws-sdk/") {
@@ -479,8 +686,9 @@ where
             let namespace_node = env.get_match("NAMESPACE");
 
             if let (Some(module_node), Some(namespace_node)) = (module_node, namespace_node) {
-                let module_text_cow = module_node.text();
-                let module_text = module_text_cow.trim_matches('"').trim_matches('\'');
+                let Some(module_text) = self.module_specifier(module_node) else {
+                    continue;
+                };
 
                 // Check if it's an AWS SDK statement
                 if let Some(sublibrary) = module_text.strip_prefix("@aws-sdk/") {
@@ -497,13 +705,180 @@ where
         }
     }
 
-    /// Scan for AWS SDK CommonJS requires
+    /// String literals and environment variable reads bound by the file's
+    /// module-level `const` declarations
+    ///
+    /// Properties of object literals are keyed by their path, e.g. `config.tables.orders`
+    /// for `export const config = { tables: { orders: "orders" } }`.
+    pub(crate) fn scan_module_constants(&self) -> HashMap<String, ResourceValue> {
+        let mut constants = HashMap::new();
+        for statement in self.ast_grep.ast.root().children() {
+            let declaration = if statement.kind() == EXPORT_STATEMENT_KIND {
+                match statement.field("declaration") {
+                    Some(declaration) => declaration,
+                    None => continue,
+                }
+            } else {
+                statement
+            };
+            if declaration.kind() != LEXICAL_DECLARATION_KIND
+                || !declaration.text().starts_with("const")
+            {
+                continue;
+            }
+            for declarator in declaration
+                .children()
+                .filter(|child| child.kind() == VARIABLE_DECLARATOR_KIND)
+            {
+                let (Some(name), Some(value)) =
+                    (declarator.field("name"), declarator.field("value"))
+                else {
+                    continue;
+                };
+                // Destructuring patterns bind values this file can't see
+                if name.kind() != "identifier" {
+                    continue;
+                }
+                collect_constant(name.text().to_string(), &value, &mut constants);
+            }
+        }
+        constants
+    }
+
+    /// Module specifiers re-exported by this file, e.g. `export * from "./s3"`
+    pub(crate) fn scan_reexports(&self) -> Vec<String> {
+        self.ast_grep
+            .ast
+            .root()
+            .dfs()
+            .filter(|node| node.kind() == EXPORT_STATEMENT_KIND)
+            .filter_map(|node| node.field("source"))
+            .filter_map(|source| self.module_specifier(&source))
+            .collect()
+    }
+
+    /// Imports and requires of modules other than the AWS SDK, one entry per statement
+    ///
+    /// The `sublibrary` of each entry is the full module specifier (e.g., `@org/aws-clients`).
+    pub(crate) fn scan_non_sdk_imports(&self) -> Vec<SublibraryInfo> {
+        const PATTERNS: &[&str] = &[
+            "import $IMPORTS from $MODULE",
+            "const $IMPORTS = require($MODULE)",
+            "let $IMPORTS = require($MODULE)",
+            "const $IMPORTS = await import($MODULE)",
+        ];
+
+        let mut results = Vec::new();
+        for pattern in PATTERNS {
+            let Ok(matches) = self.find_all_matches(pattern) else {
+                continue;
+            };
+            for node_match in matches {
+                let env = node_match.get_env();
+                let (Some(module_node), Some(imports_node)) =
+                    (env.get_match("MODULE"), env.get_match("IMPORTS"))
+                else {
+                    continue;
+                };
+                let Some(specifier) = self.module_specifier(module_node) else {
+                    continue;
+                };
+                if specifier.starts_with("@aws-sdk/") {
+                    continue;
+                }
+
+                let mut imported = SublibraryInfo::new(specifier);
+                self.parse_and_add_imports(
+                    &imports_node.text(),
+                    &mut imported,
+                    node_match.get_node(),
+                );
+                results.push(imported);
+            }
+        }
+        results
+    }
+
+    /// Clients imported from other project files, e.g. `import { s3 } from "@org/aws-clients"`
+    fn scan_shared_clients(&self) -> Vec<ClientInstantiation> {
+        let Some(project_clients) = &self.project_clients else {
+            return Vec::new();
+        };
+        let importer = &self.ast_grep.source_file.path;
+        let mut results = Vec::new();
+
+        for imported in self.scan_non_sdk_imports() {
+            let specifier = &imported.sublibrary;
+            for import_info in &imported.imports {
+                let Some(client) =
+                    project_clients.shared_client(importer, specifier, &import_info.original_name)
+                else {
+                    continue;
+                };
+                log::debug!(
+                    "Client '{}' imported from '{specifier}' is a {}",
+                    import_info.local_name,
+                    client.original_client_type
+                );
+                results.push(ClientInstantiation {
+                    variable: import_info.local_name.clone(),
+                    client_type: client.original_client_type.clone(),
+                    original_client_type: client.original_client_type.clone(),
+                    sublibrary: client.sublibrary.clone(),
+                    arguments: HashMap::new(),
+                    line: import_info.location.start_position.0,
+                });
+            }
+        }
+        results
+    }
+
+    /// Find the middy middlewares this file applies, as (package, middleware call) pairs
+    ///
+    /// Matches calls of the default export of `@middy/*` packages, e.g.
+    /// `middy(handler).use(ssm({ fetchData: { config: "/app/config" } }))`,
+    /// with the parameters of their options object.
+    pub(crate) fn find_middy_middlewares(&self) -> Vec<(String, CommandUsage<'_>)> {
+        use crate::extraction::javascript::argument_extractor::ArgumentExtractor;
+
+        let mut middlewares = Vec::new();
+        for imported in self.scan_non_sdk_imports() {
+            if !imported.sublibrary.starts_with(MIDDY_SCOPE) {
+                continue;
+            }
+            for import_info in &imported.imports {
+                let local_name = &import_info.local_name;
+                let calls = [format!("{local_name}($ARGS)"), format!("{local_name}()")];
+                for node_match in calls
+                    .iter()
+                    .filter_map(|pattern| self.find_all_matches(pattern).ok())
+                    .flatten()
+                {
+                    let location =
+                        Location::from_node(self.ast_grep.source_file.path.clone(), &node_match);
+                    let parameters = ArgumentExtractor::extract_object_parameters(
+                        node_match.get_env().get_match("ARGS"),
+                    );
+                    middlewares.push((
+                        imported.sublibrary.clone(),
+                        CommandUsage::new(node_match.text(), location, parameters),
+                    ));
+                }
+            }
+        }
+        middlewares.sort_by_key(|(_, usage)| usage.location.start_position);
+        middlewares
+    }
+
+    /// Scan for AWS SDK CommonJS requires and awaited dynamic imports
     pub(crate) fn scan_aws_requires(&mut self) -> Result<Vec<SublibraryInfo>, String> {
         // Support multiple require patterns (const, let, var - both destructuring and default imports)
         const REQUIRE_PATTERNS: &[&str] = &[
             "const $IMPORTS = require($MODULE)", // Destructuring: const { S3Client } = require(...)
             "let $IMPORTS = require($MODULE)",   // Destructuring: let { S3Client } = require(...)
             "var $IMPORTS = require($MODULE)", // Destructuring: var { S3Client } = require(...) [legacy]
+            "const $IMPORTS = await import($MODULE)", // Lazy loading: const { S3Client } = await import(...)
+            "let $IMPORTS = await import($MODULE)", // Lazy loading: let { S3Client } = await import(...)
         ];
 
         let mut all_requires = Vec::new();
@@ -564,22 +939,33 @@ where
     pub(crate) fn scan_client_instantiations(
         &mut self,
     ) -> Result<Vec<ClientInstantiation>, String> {
-        // Patterns to match client instantiations
+        // Patterns to match client instantiations, including document clients
+        // wrapping another client (`DynamoDBDocumentClient.from(client, options)`)
         const PATTERNS: &[&str] = &[
             "const $VAR = new $CLIENT($ARGS)",
             "let $VAR = new $CLIENT($ARGS)",
+            "const $VAR = $CLIENT.from($$$ARGS)",
+            "let $VAR = $CLIENT.from($$$ARGS)",
         ];
+        // Clients instrumented inline, e.g. `AWSXRay.captureAWSv3Client(new S3Client({}))`
+        let traced_patterns = xray_capture_callees().into_iter().flat_map(|callee| {
+            ["const", "let"]
+                .map(|declaration| format!("{declaration} $VAR = {callee}(new $CLIENT($ARGS))"))
+        });
 
         let client_info = self.get_valid_client_types()?;
+        let mut results = self.scan_shared_clients();
 
         if client_info.is_empty() {
-            return Ok(Vec::new());
+            return Ok(results);
         }
 
-        let mut results = Vec::new();
-
-        for pattern in PATTERNS {
-            let matches = self.find_all_matches(pattern)?;
+        for pattern in PATTERNS
+            .iter()
+            .map(ToString::to_string)
+            .chain(traced_patterns)
+        {
+            let matches = self.find_all_matches(&pattern)?;
             Self::process_client_instantiation_matches(
                 matches,
                 &client_info.client_types,
@@ -589,9 +975,83 @@ where
             )?;
         }
 
+        results.extend(self.scan_injected_clients(&client_info));
+
         Ok(results)
     }
 
+    /// Scan for clients received through typed parameters and class fields
+    ///
+    /// Dependency injection frameworks (NestJS, InversifyJS) construct clients in
+    /// a module file and hand them to services, e.g.
+    /// `constructor(@Inject(S3_CLIENT) private readonly s3: S3Client) {}`. The type
+    /// annotation identifies the client, so calls on `this.s3` (or on a typed
+    /// parameter `s3` of any function) are attributed without the construction site.
+    fn scan_injected_clients(&self, client_info: &ValidClientTypes) -> Vec<ClientInstantiation> {
+        let mut results = Vec::new();
+
+        for node in self.ast_grep.ast.root().dfs() {
+            let kind = node.kind();
+            let (name_node, variables) = if TYPED_PARAMETER_KINDS.contains(&&*kind) {
+                let Some(name_node) = node.field("pattern") else {
+                    continue;
+                };
+                let name = name_node.text().to_string();
+                let is_property = node
+                    .children()
+                    .any(|child| PARAMETER_PROPERTY_MODIFIERS.contains(&&*child.kind()));
+                let variables = if is_property {
+                    vec![format!("this.{name}"), name]
+                } else {
+                    vec![name]
+                };
+                (name_node, variables)
+            } else if kind == CLASS_FIELD_KIND {
+                let Some(name_node) = node.field("name") else {
+                    continue;
+                };
+                let variables = vec![format!("this.{}", name_node.text())];
+                (name_node, variables)
+            } else {
+                continue;
+            };
+
+            let Some(type_node) = node.field("type") else {
+                continue;
+            };
+            let type_text = type_node.text();
+            let client_type = type_text.trim_start_matches(':').trim();
+            if !client_info.client_types.iter().any(|t| t == client_type) {
+                continue;
+            }
+
+            let original_client_type = client_info
+                .name_mappings
+                .get(client_type)
+                .cloned()
+                .unwrap_or_else(|| client_type.to_string());
+            let sublibrary = client_info
+                .sublibrary_mappings
+                .get(client_type)
+                .cloned()
+                .unwrap_or_else(|| "unknown".to_string());
+            let line = name_node.start_pos().line() + 1;
+
+            for variable in variables {
+                results.push(ClientInstantiation {
+                    variable,
+                    client_type: client_type.to_string(),
+                    original_client_type: original_client_type.clone(),
+                    sublibrary: sublibrary.clone(),
+                    arguments: HashMap::new(),
+                    line,
+                });
+            }
+        }
+
+        results
+    }
+
     /// Generic processing for client instantiation matches - works for both JavaScript and TypeScript
     fn process_client_instantiation_matches<U>(
         matches: Vec<NodeMatch<U>>,
@@ -1062,18 +1522,18 @@ async function listAllTables() {
         let scanner = ASTScanner::new(ast, JavaScript.into());
 
         // Should find paginateQuery call at line ~7
-        let paginate_query = scanner.find_paginate_function_with_args("paginateQuery");
-        assert!(paginate_query.is_some(), "Should find paginateQuery call");
+        let paginate_query = scanner.find_paginate_function_calls_with_args("paginateQuery");
+        assert_eq!(paginate_query.len(), 1, "Should find paginateQuery call");
 
         // Should find PaginateList call (renamed) at line ~14
-        let paginate_list = scanner.find_paginate_function_with_args("PaginateList");
-        assert!(paginate_list.is_some(), "Should find PaginateList call");
+        let paginate_list = scanner.find_paginate_function_calls_with_args("PaginateList");
+        assert_eq!(paginate_list.len(), 1, "Should find PaginateList call");
 
-        // Should return None for function that wasn't called
-        let missing_function_pos = scanner.find_paginate_function_with_args("paginateScan");
+        // Should find nothing for function that wasn't called
+        let missing_function_pos = scanner.find_paginate_function_calls_with_args("paginateScan");
         assert!(
-            missing_function_pos.is_none(),
-            "Should return None for unused function"
+            missing_function_pos.is_empty(),
+            "Should find nothing for unused function"
         );
 
         println!("✅ Paginate function position heuristics working correctly");
@@ -1434,6 +1894,108 @@ async function testOperations() {
         println!("   📊 Total operations extracted: {}", operations.len());
     }
 
+    #[test]
+    fn test_dynamodb_document_client_table_scoping() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+
+        let typescript_source = r#"
+import { DynamoDBClient } from "@aws-sdk/client-dynamodb";
+import {
+    DynamoDBDocumentClient,
+    DynamoDBDocument,
+    GetCommand,
+    BatchWriteCommand,
+    TransactWriteCommand,
+} from "@aws-sdk/lib-dynamodb";
+
+const client = new DynamoDBClient({ region: "us-west-2" });
+const docClient = DynamoDBDocumentClient.from(client);
+const doc = DynamoDBDocument.from(client, { marshallOptions: { removeUndefinedValues: true } });
+
+async function handler(orderId: string) {
+    await docClient.send(new GetCommand({ TableName: "Orders", Key: { id: orderId } }));
+    await docClient.send(new BatchWriteCommand({
+        RequestItems: { "Orders": [{ PutRequest: { Item: { id: orderId } } }] }
+    }));
+    await docClient.send(new TransactWriteCommand({
+        TransactItems: [
+            { Put: { TableName: "Orders", Item: { id: orderId } } },
+            { Delete: { TableName: "Audit", Key: { id: orderId } } }
+        ]
+    }));
+    await doc.put({ TableName: 'Customers', Item: { id: orderId } });
+}
+        "#;
+
+        let ast = create_ts_ast(typescript_source);
+        let mut scanner = ASTScanner::new(ast, TypeScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+
+        let clients: Vec<_> = scan_results
+            .client_instantiations
+            .iter()
+            .map(|c| (c.variable.as_str(), c.sublibrary.as_str()))
+            .collect();
+        assert!(clients.contains(&("docClient", "lib-dynamodb")));
+        assert!(clients.contains(&("doc", "lib-dynamodb")));
+
+        let mut operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+        operations.extend(ExtractionUtils::extract_operations_from_method_calls(
+            &scan_results,
+        ));
+
+        let table_of = |name: &str| {
+            let op = operations
+                .iter()
+                .find(|op| op.name == name)
+                .unwrap_or_else(|| panic!("Should find {name} operation"));
+            assert_eq!(op.possible_services, vec!["dynamodb".to_string()]);
+            op.metadata
+                .as_ref()
+                .and_then(|metadata| metadata.resource_bindings.get("TableName"))
+                .cloned()
+        };
+
+        assert_eq!(table_of("GetItem"), Some("Orders".to_string()));
+        assert_eq!(table_of("BatchWriteItem"), Some("Orders".to_string()));
+        // Transactions spanning several tables can't be scoped to one
+        assert_eq!(table_of("TransactWriteItems"), None);
+        assert_eq!(table_of("PutItem"), Some("Customers".to_string()));
+    }
+
+    #[test]
+    fn test_dynamodb_document_command_tables_must_agree() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+
+        let javascript_source = r#"
+import { GetCommand, PutCommand } from "@aws-sdk/lib-dynamodb";
+
+const first = new GetCommand({ TableName: "Orders", Key: { id: "1" } });
+const second = new GetCommand({ TableName: "Customers", Key: { id: "2" } });
+const dynamic = new PutCommand({ TableName: tableName, Item: { id: "3" } });
+        "#;
+
+        let ast = create_js_ast(javascript_source);
+        let mut scanner = ASTScanner::new(ast, JavaScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+        let operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+
+        for name in ["GetItem", "PutItem"] {
+            let op = operations
+                .iter()
+                .find(|op| op.name == name)
+                .unwrap_or_else(|| panic!("Should find {name} operation"));
+            assert!(
+                op.metadata
+                    .as_ref()
+                    .is_some_and(|metadata| metadata.resource_bindings.is_empty()),
+                "{name} must not be scoped to a single table"
+            );
+        }
+    }
+
     #[test]
     fn test_s3_storage_library_expansions() {
         use crate::extraction::javascript::shared::ExtractionUtils;
@@ -1509,8 +2071,8 @@ async function uploadLargeFile() {
             if let Some(op) = op {
                 assert_eq!(
                     op.possible_services,
-                    vec!["storage".to_string()],
-                    "{} should be mapped to 'storage' service",
+                    vec!["s3".to_string()],
+                    "{} should be mapped to 's3' service",
                     expected_op
                 );
 
@@ -1521,13 +2083,26 @@ async function uploadLargeFile() {
                     expected_op
                 );
 
-                // Verify parameters were extracted from Upload constructor
+                // Verify the PutObject input under `params` was extracted and scopes the call
                 if let Some(metadata) = &op.metadata {
                     assert!(
-                        !metadata.parameters.is_empty(),
-                        "{} should have parameters extracted from Upload constructor",
+                        metadata.parameters.iter().any(|p| matches!(
+                            p,
+                            crate::extraction::Parameter::Keyword { name, .. } if name == "Bucket"
+                        )),
+                        "{} should have parameters extracted from Upload params",
                         expected_op
                     );
+                    assert_eq!(
+                        metadata.resource_bindings,
+                        std::collections::BTreeMap::from([
+                            ("BucketName".to_string(), "my-bucket".to_string()),
+                            (
+                                "ObjectName".to_string(),
+                                "uploads/large-file.dat".to_string()
+                            ),
+                        ])
+                    );
                 }
             }
         }
@@ -1541,4 +2116,542 @@ async function uploadLargeFile() {
         println!("   ✓ Upload → PutObjectTagging");
         println!("   📊 Total operations extracted: {}", operations.len());
     }
+
+    #[test]
+    fn test_s3_presigner_operations() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+        use std::collections::BTreeMap;
+
+        let typescript_source = r#"
+import { S3Client, GetObjectCommand } from "@aws-sdk/client-s3";
+import { getSignedUrl } from "@aws-sdk/s3-request-presigner";
+import { createPresignedPost } from "@aws-sdk/s3-presigned-post";
+
+const client = new S3Client({ region: "us-west-2" });
+
+export async function downloadUrl(key: string) {
+    return getSignedUrl(client, new GetObjectCommand({ Bucket: "reports", Key: key }), { expiresIn: 3600 });
+}
+
+export async function uploadForm() {
+    return await createPresignedPost(client, {
+        Bucket: "uploads",
+        Key: "incoming/${filename}",
+        Conditions: [["content-length-range", 0, 10485760], { acl: "private" }],
+        Fields: { acl: "private" },
+        Expires: 600,
+    });
+}
+        "#;
+
+        let ast = create_ts_ast(typescript_source);
+        let mut scanner = ASTScanner::new(ast, TypeScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+        let operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+
+        let get_object = operations.iter().find(|op| op.name == "GetObject");
+        assert!(
+            get_object.is_some(),
+            "Should find GetObject signed by getSignedUrl"
+        );
+
+        let post_bindings = BTreeMap::from([
+            ("BucketName".to_string(), "uploads".to_string()),
+            ("ObjectName".to_string(), "incoming/*".to_string()),
+        ]);
+        for expected_op in ["PutObject", "PutObjectAcl"] {
+            let op = operations
+                .iter()
+                .find(|op| op.name == expected_op)
+                .unwrap_or_else(|| panic!("Should find {expected_op} from createPresignedPost"));
+            assert_eq!(op.possible_services, vec!["s3".to_string()]);
+            assert_eq!(
+                op.metadata.as_ref().map(|m| &m.resource_bindings),
+                Some(&post_bindings)
+            );
+        }
+        assert!(
+            !operations.iter().any(|op| op.name == "PutObjectTagging"),
+            "The policy doesn't allow a tagging field"
+        );
+    }
+
+    #[test]
+    fn test_presigned_post_key_prefix_condition() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+
+        let javascript_source = r#"
+const { createPresignedPost } = require("@aws-sdk/s3-presigned-post");
+
+const post = createPresignedPost(s3, {
+    Bucket: "uploads",
+    Key: key,
+    Conditions: [["starts-with", "$key", "users/"]],
+});
+        "#;
+
+        let ast = create_js_ast(javascript_source);
+        let mut scanner = ASTScanner::new(ast, JavaScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+        let operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+
+        let op = operations
+            .iter()
+            .find(|op| op.name == "PutObject")
+            .expect("Should find PutObject from createPresignedPost");
+        assert_eq!(
+            op.metadata
+                .as_ref()
+                .and_then(|m| m.resource_bindings.get("ObjectName"))
+                .map(String::as_str),
+            Some("users/*")
+        );
+        assert_eq!(operations.len(), 1);
+    }
+
+    #[test]
+    fn test_xray_instrumented_clients() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+
+        let javascript_source = r#"
+const AWSXRay = require("aws-xray-sdk-core");
+const { S3 } = require("@aws-sdk/client-s3");
+const { DynamoDBClient, GetItemCommand } = require("@aws-sdk/client-dynamodb");
+const { Tracer } = require("@aws-lambda-powertools/tracer");
+
+const tracer = new Tracer();
+const s3 = AWSXRay.captureAWSv3Client(new S3({ region: "us-east-1" }));
+const dynamodb = tracer.captureAWSv3Client(new DynamoDBClient({}));
+
+exports.handler = async () => {
+    await s3.getObject({ Bucket: "reports", Key: "latest.csv" });
+    await dynamodb.send(new GetItemCommand({ TableName: "Orders", Key: {} }));
+};
+        "#;
+
+        let ast = create_js_ast(javascript_source);
+        let mut scanner = ASTScanner::new(ast, JavaScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+
+        let clients: Vec<_> = scan_results
+            .client_instantiations
+            .iter()
+            .map(|c| (c.variable.as_str(), c.client_type.as_str()))
+            .collect();
+        assert!(clients.contains(&("s3", "S3")));
+        assert!(clients.contains(&("dynamodb", "DynamoDBClient")));
+
+        let mut operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+        operations.extend(ExtractionUtils::extract_operations_from_method_calls(
+            &scan_results,
+        ));
+
+        for (name, service) in [
+            ("GetObject", "s3"),
+            ("GetItem", "dynamodb"),
+            ("PutTraceSegments", "xray"),
+            ("PutTelemetryRecords", "xray"),
+        ] {
+            assert!(
+                operations
+                    .iter()
+                    .any(|op| op.name == name && op.possible_services == vec![service.to_string()]),
+                "Should find {service}:{name}"
+            );
+        }
+    }
+
+    #[test]
+    fn test_uninstrumented_clients_need_no_xray_operations() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+
+        let javascript_source = r#"
+const { S3Client, GetObjectCommand } = require("@aws-sdk/client-s3");
+const s3 = wrap(new S3Client({}));
+s3.send(new GetObjectCommand({ Bucket: "reports", Key: "latest.csv" }));
+        "#;
+
+        let ast = create_js_ast(javascript_source);
+        let mut scanner = ASTScanner::new(ast, JavaScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+        let operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+
+        assert!(scan_results.client_instantiations.is_empty());
+        assert!(!operations
+            .iter()
+            .any(|op| op.possible_services.contains(&"xray".to_string())));
+    }
+
+    #[test]
+    fn test_dependency_injected_clients() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+
+        let typescript_source = r#"
+import { Injectable, Inject } from "@nestjs/common";
+import { S3, S3Client } from "@aws-sdk/client-s3";
+import { SQS } from "@aws-sdk/client-sqs";
+
+@Injectable()
+export class ReportsService {
+    @Inject("QUEUE_CLIENT") private readonly queue!: SQS;
+
+    constructor(
+        @Inject("S3_CLIENT") private readonly s3: S3,
+        private readonly raw: S3Client,
+        region: string,
+    ) {}
+
+    async publish(key: string) {
+        await this.s3.putObject({ Bucket: "reports", Key: key });
+        await this.queue.sendMessage({ QueueUrl: "https://sqs", MessageBody: key });
+    }
+}
+
+export async function archive(reports: S3, key: string) {
+    await reports.copyObject({ Bucket: "archive", Key: key, CopySource: key });
+}
+        "#;
+
+        let ast = create_ts_ast(typescript_source);
+        let mut scanner = ASTScanner::new(ast, TypeScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+
+        let clients: Vec<_> = scan_results
+            .client_instantiations
+            .iter()
+            .map(|c| (c.variable.as_str(), c.sublibrary.as_str()))
+            .collect();
+        for expected in [
+            ("this.s3", "client-s3"),
+            ("s3", "client-s3"),
+            ("this.raw", "client-s3"),
+            ("this.queue", "client-sqs"),
+            ("reports", "client-s3"),
+        ] {
+            assert!(
+                clients.contains(&expected),
+                "Should find client {expected:?}"
+            );
+        }
+        assert!(!clients.iter().any(|(variable, _)| *variable == "region"));
+
+        let operations = ExtractionUtils::extract_operations_from_method_calls(&scan_results);
+        for (name, service) in [
+            ("PutObject", "s3"),
+            ("SendMessage", "sqs"),
+            ("CopyObject", "s3"),
+        ] {
+            assert!(
+                operations
+                    .iter()
+                    .any(|op| op.name == name && op.possible_services == vec![service.to_string()]),
+                "Should find {service}:{name} on an injected client"
+            );
+        }
+    }
+
+    #[test]
+    fn test_dynamic_import_and_template_require() {
+        let javascript_source = r#"
+const SERVICE = "dynamodb";
+const { DynamoDBClient, GetItemCommand: GetItem } = require(`@aws-sdk/client-${SERVICE}`);
+const { SQSClient } = require(`@aws-sdk/client-${queueService}`);
+
+exports.handler = async () => {
+    const { S3Client, GetObjectCommand } = await import("@aws-sdk/client-s3");
+    const s3 = new S3Client({});
+    return s3.send(new GetObjectCommand({ Bucket: "reports", Key: "latest.csv" }));
+};
+        "#;
+
+        let ast = create_js_ast(javascript_source);
+        let mut scanner = ASTScanner::new(ast, JavaScript.into());
+        let (_imports, requires) = scanner.scan_all_aws_imports().unwrap();
+
+        let imported = |sublibrary: &str| -> Vec<(String, String)> {
+            requires
+                .iter()
+                .filter(|info| info.sublibrary == sublibrary)
+                .flat_map(|info| &info.imports)
+                .map(|import| (import.original_name.clone(), import.local_name.clone()))
+                .collect()
+        };
+
+        assert_eq!(
+            imported("client-dynamodb"),
+            vec![
+                ("DynamoDBClient".to_string(), "DynamoDBClient".to_string()),
+                ("GetItemCommand".to_string(), "GetItem".to_string()),
+            ]
+        );
+        let s3_imports = imported("client-s3");
+        assert!(s3_imports.contains(&(
+            "GetObjectCommand".to_string(),
+            "GetObjectCommand".to_string()
+        )));
+        // Substitutions that aren't string constants of the file stay unresolved
+        assert!(!requires
+            .iter()
+            .any(|info| info.sublibrary.starts_with("client-$")));
+    }
+
+    #[test]
+    fn test_commands_constructed_before_sending() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+
+        let typescript_source = r#"
+import {
+    SQSClient,
+    SendMessageCommand,
+    DeleteMessageCommand,
+    SendMessageCommandInput,
+} from "@aws-sdk/client-sqs";
+import * as SNS from "@aws-sdk/client-sns";
+
+const client = new SQSClient({});
+
+const input: SendMessageCommandInput = { QueueUrl: "https://sqs.us-east-1.amazonaws.com/123456789012/jobs", MessageBody: "hi" };
+const commands = [new SendMessageCommand(input)];
+const handlers = {
+    done: (handle: string) => new DeleteMessageCommand({ QueueUrl: "https://sqs.us-east-1.amazonaws.com/123456789012/jobs", ReceiptHandle: handle }),
+};
+
+export function buildNotification(message: string) {
+    return new SNS.PublishCommand({ TopicArn: "arn:aws:sns:us-east-1:123456789012:alerts", Message: message });
+}
+
+export async function flush(handle: string) {
+    for (const command of commands) {
+        await client.send(command);
+    }
+    await client.send(handlers.done(handle));
+}
+        "#;
+
+        let ast = create_ts_ast(typescript_source);
+        let mut scanner = ASTScanner::new(ast, TypeScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+        let operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+
+        let parameter_names = |name: &str| -> Vec<String> {
+            operations
+                .iter()
+                .find(|op| op.name == name)
+                .unwrap_or_else(|| panic!("Should find {name} operation"))
+                .metadata
+                .as_ref()
+                .map(|metadata| {
+                    metadata
+                        .parameters
+                        .iter()
+                        .filter_map(|parameter| match parameter {
+                            Parameter::Keyword { name, .. } => Some(name.clone()),
+                            _ => None,
+                        })
+                        .collect()
+                })
+                .unwrap_or_default()
+        };
+
+        // Input passed by name resolves to its declaration
+        assert_eq!(
+            parameter_names("SendMessage"),
+            vec!["QueueUrl".to_string(), "MessageBody".to_string()]
+        );
+        assert!(parameter_names("DeleteMessage").contains(&"ReceiptHandle".to_string()));
+        assert!(parameter_names("Publish").contains(&"TopicArn".to_string()));
+    }
+
+    #[test]
+    fn test_clients_imported_from_project_modules() {
+        use crate::extraction::javascript::project_clients::ProjectClients;
+        use crate::extraction::javascript::shared::ExtractionUtils;
+        use std::sync::Arc;
+
+        let clients_source = r#"
+import { S3Client } from "@aws-sdk/client-s3";
+import { SQSClient as Queue } from "@aws-sdk/client-sqs";
+
+export const s3 = new S3Client({});
+export const queue = new Queue({});
+        "#;
+        let handler_source = r#"
+import { s3, queue as jobs } from "../lib/clients";
+const { s3: storage } = require("../lib/clients");
+
+export async function handler() {
+    await s3.getObject({ Bucket: "reports", Key: "daily.csv" });
+    await storage.deleteObject({ Bucket: "reports", Key: "daily.csv" });
+    await jobs.sendMessage({ QueueUrl: "https://sqs.us-east-1.amazonaws.com/123456789012/jobs", MessageBody: "done" });
+}
+        "#;
+        let source_files = vec![
+            SourceFile::with_language(
+                PathBuf::from("/app/lib/clients.ts"),
+                clients_source.to_string(),
+                crate::Language::TypeScript,
+            ),
+            SourceFile::with_language(
+                PathBuf::from("/app/handlers/report.ts"),
+                handler_source.to_string(),
+                crate::Language::TypeScript,
+            ),
+        ];
+        let project_clients = Arc::new(ProjectClients::from_source_files(&source_files));
+
+        let ast =
+            AstWithSourceFile::new(TypeScript.ast_grep(handler_source), source_files[1].clone());
+        let mut scanner = ASTScanner::new(ast, TypeScript.into())
+            .with_project_clients(Arc::clone(&project_clients));
+        let scan_results = scanner.scan_all().unwrap();
+        let operations = ExtractionUtils::extract_operations_from_method_calls(&scan_results);
+        for (name, service) in [
+            ("GetObject", "s3"),
+            ("DeleteObject", "s3"),
+            ("SendMessage", "sqs"),
+        ] {
+            assert!(
+                operations
+                    .iter()
+                    .any(|op| op.name == name && op.possible_services == vec![service.to_string()]),
+                "Should find {service}:{name} on an imported client"
+            );
+        }
+
+        // Without the project index the handler has no clients
+        let ast = create_ts_ast(handler_source);
+        let mut scanner = ASTScanner::new(ast, TypeScript.into());
+        assert!(scanner.scan_client_instantiations().unwrap().is_empty());
+    }
+
+    #[test]
+    fn test_middy_middleware_operations() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+
+        let javascript_source = r#"
+import middy from "@middy/core";
+import ssm from "@middy/ssm";
+import secretsManager from "@middy/secrets-manager";
+import httpErrorHandler from "@middy/http-error-handler";
+import { DynamoDBClient, GetItemCommand } from "@aws-sdk/client-dynamodb";
+
+const client = new DynamoDBClient({});
+
+const lambdaHandler = async (event, context) => {
+    return client.send(new GetItemCommand({ TableName: context.config.table, Key: event.key }));
+};
+
+export const handler = middy(lambdaHandler)
+    .use(ssm({ fetchData: { config: "/orders/config/" }, setToContext: true }))
+    .use(secretsManager({ fetchData: { db: "prod/orders/db" } }))
+    .use(httpErrorHandler());
+        "#;
+
+        let ast = create_js_ast(javascript_source);
+        let mut scanner = ASTScanner::new(ast, JavaScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+        let operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+        let middleware_operations: Vec<_> = operations
+            .iter()
+            .filter(|op| op.name != "GetItem")
+            .map(|op| (op.possible_services[0].as_str(), op.name.as_str()))
+            .collect();
+
+        // A path is fetched by path only; the handler's own calls are still extracted
+        assert_eq!(
+            middleware_operations,
+            vec![
+                ("ssm", "GetParametersByPath"),
+                ("secretsmanager", "GetSecretValue")
+            ]
+        );
+        assert!(operations.iter().any(|op| op.name == "GetItem"));
+    }
+
+    #[test]
+    fn test_middy_ssm_parameter_names() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+
+        let typescript_source = r#"
+import middy from "@middy/core";
+import ssmMiddleware from "@middy/ssm";
+
+export const handler = middy()
+    .use(ssmMiddleware({ fetchData: { apiKey: "/orders/api-key", [name]: prefix } }))
+    .handler(async () => ({ statusCode: 200 }));
+        "#;
+
+        let ast = create_ts_ast(typescript_source);
+        let mut scanner = ASTScanner::new(ast, TypeScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+        let operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+        let names: Vec<_> = operations.iter().map(|op| op.name.as_str()).collect();
+
+        // A computed entry may be a path
+        assert_eq!(names, vec!["GetParameters", "GetParametersByPath"]);
+    }
+
+    #[test]
+    fn test_each_command_usage_is_scoped_to_its_literal_resources() {
+        use crate::extraction::javascript::shared::ExtractionUtils;
+        use crate::extraction::shared::bind_literal_resources;
+
+        let javascript_source = r#"
+import { S3Client, GetObjectCommand, paginateListObjectsV2 } from "@aws-sdk/client-s3";
+
+const client = new S3Client({});
+await client.send(new GetObjectCommand({ Bucket: "reports", Key: "latest.csv" }));
+await client.send(new GetObjectCommand({ Bucket: "archive", Key: "2024.csv" }));
+for await (const page of paginateListObjectsV2({ client }, { Bucket: "reports" })) {}
+for await (const page of paginateListObjectsV2({ client }, { Bucket: bucketName })) {}
+        "#;
+
+        let ast = create_js_ast(javascript_source);
+        let mut scanner = ASTScanner::new(ast, JavaScript.into());
+        let scan_results = scanner.scan_all().unwrap();
+        let mut operations =
+            ExtractionUtils::extract_operations_from_imports(&scan_results, &mut scanner);
+        bind_literal_resources(&mut operations, None);
+        ExtractionUtils::deduplicate_operations(&mut operations);
+
+        let bindings_of = |name: &str| -> Vec<Vec<(String, String)>> {
+            operations
+                .iter()
+                .filter(|op| op.name == name)
+                .map(|op| {
+                    op.metadata
+                        .as_ref()
+                        .unwrap()
+                        .resource_bindings
+                        .clone()
+                        .into_iter()
+                        .collect()
+                })
+                .collect()
+        };
+        let binding = |placeholder: &str, value: &str| (placeholder.to_string(), value.to_string());
+
+        assert_eq!(
+            bindings_of("GetObject"),
+            vec![
+                vec![
+                    binding("BucketName", "reports"),
+                    binding("ObjectName", "latest.csv")
+                ],
+                vec![
+                    binding("BucketName", "archive"),
+                    binding("ObjectName", "2024.csv")
+                ],
+            ]
+        );
+        // One paginator names no bucket literally, so the operation stays unscoped
+        assert_eq!(bindings_of("ListObjectsV2"), vec![Vec::new()]);
+    }
 }
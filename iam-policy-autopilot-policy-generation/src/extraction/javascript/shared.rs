@@ -3,16 +3,106 @@
 //! This module contains common functionality shared between JavaScript and TypeScript
 //! extractors.
 
-use std::collections::HashSet;
+use std::collections::{BTreeMap, BTreeSet, HashSet};
+use std::sync::OnceLock;
 
-use crate::extraction::javascript::types::{ImportInfo, JavaScriptScanResults};
+use crate::extraction::javascript::argument_extractor::ArgumentExtractor;
+use crate::extraction::javascript::types::{ImportInfo, JavaScriptScanResults, MethodCall};
+use crate::extraction::javascript::{amplify, dynamodb_orms};
 use crate::extraction::{Parameter, ParameterValue, SdkMethodCall, SdkMethodCallMetadata};
 use crate::Location;
+use regex::Regex;
 use rust_embed::RustEmbed;
 use serde::Deserialize;
 use std::borrow::Cow;
 use std::collections::HashMap;
 
+/// Service whose document client (`@aws-sdk/lib-dynamodb`) scopes calls to tables
+pub(crate) const DYNAMODB_SERVICE: &str = "dynamodb";
+
+/// Service reference placeholder for table names in DynamoDB ARNs
+pub(crate) const TABLE_PLACEHOLDER: &str = "TableName";
+
+/// lib-* sublibraries named after a feature rather than the service they call
+const LIBRARY_SERVICES: &[(&str, &str)] = &[("lib-storage", "s3")];
+
+/// Service of lib-storage's `Upload`, presigned POST policies and Amplify storage
+pub(crate) const S3_SERVICE: &str = "s3";
+
+/// Service reference placeholder for bucket names in S3 ARNs
+const BUCKET_PLACEHOLDER: &str = "BucketName";
+
+/// Service reference placeholder for object keys in S3 ARNs
+pub(crate) const OBJECT_PLACEHOLDER: &str = "ObjectName";
+
+/// Service receiving the trace data of instrumented clients
+const XRAY_SERVICE: &str = "xray";
+
+/// Operations the tracing instrumentation itself calls to send trace data
+const TRACING_OPERATIONS: [&str; 2] = ["PutTraceSegments", "PutTelemetryRecords"];
+
+/// Sublibrary providing `createPresignedPost`
+const PRESIGNED_POST_SUBLIBRARY: &str = "s3-presigned-post";
+
+/// Function creating a presigned POST policy for browser uploads
+const PRESIGNED_POST_FUNCTION: &str = "createPresignedPost";
+
+/// Key placeholder S3 replaces with the uploaded file's name
+const FILENAME_VARIABLE: &str = "${filename}";
+
+/// `["starts-with", "$key", "<prefix>"]` conditions of a presigned POST policy
+/// Middy middlewares fetching from AWS at runtime: (package, service, operations)
+///
+/// `@middy/ssm` calls `GetParametersByPath` for `fetchData` paths ending in `/`
+/// and `GetParameters` for the others; see [`ExtractionUtils::ssm_middleware_operations`].
+const MIDDY_MIDDLEWARES: &[(&str, &str, &[&str])] = &[
+    (
+        "@middy/ssm",
+        "ssm",
+        &["GetParameters", "GetParametersByPath"],
+    ),
+    (
+        "@middy/secrets-manager",
+        "secretsmanager",
+        &["GetSecretValue"],
+    ),
+    (
+        "@middy/appconfig",
+        "appconfigdata",
+        &["StartConfigurationSession", "GetLatestConfiguration"],
+    ),
+    ("@middy/s3", "s3", &["GetObject"]),
+    ("@middy/dynamodb", "dynamodb", &["GetItem"]),
+    ("@middy/sts", "sts", &["AssumeRole"]),
+    (
+        "@middy/service-discovery",
+        "servicediscovery",
+        &["DiscoverInstances"],
+    ),
+];
+
+/// Package of the middy SSM Parameter Store middleware
+const MIDDY_SSM_PACKAGE: &str = "@middy/ssm";
+
+static KEY_PREFIX_CONDITION_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn key_prefix_condition_regex() -> &'static Regex {
+    KEY_PREFIX_CONDITION_REGEX.get_or_init(|| {
+        Regex::new(r#"\[\s*["']starts-with["']\s*,\s*["']\$key["']\s*,\s*["']([^"']*)["']\s*\]"#)
+            .expect("Invalid starts-with condition regex")
+    })
+}
+
+/// Literal `TableName` properties inside nested command input, e.g. `TransactItems`
+static TABLE_NAME_LITERAL_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn table_name_literal_regex() -> &'static Regex {
+    TABLE_NAME_LITERAL_REGEX.get_or_init(|| {
+        Regex::new(r#"TableName["']?\s*:\s*(?:"([^"]*)"|'([^']*)'|`([^`$]*)`)"#)
+            .expect("Invalid TableName literal regex")
+    })
+}
+
 /// Embedded JavaScript SDK v3 libraries mapping
 ///
 /// This struct provides access to the JavaScript SDK v3 libraries mapping configuration
@@ -43,13 +133,21 @@ struct JsV3LibrariesMapping {
     library_operation_expansions: HashMap<String, HashMap<String, Vec<String>>>,
 }
 
+/// JS v3 libraries mapping, parsed on the first JavaScript or TypeScript analysis rather
+/// than for every analyzed file
+static LIBRARIES_MAPPING: OnceLock<Option<JsV3LibrariesMapping>> = OnceLock::new();
+
 /// Load JS v3 libraries mapping from embedded data
-fn load_libraries_mapping() -> Option<JsV3LibrariesMapping> {
-    let content_bytes = JsV3Libraries::get_libraries_mapping()?;
+fn load_libraries_mapping() -> Option<&'static JsV3LibrariesMapping> {
+    LIBRARIES_MAPPING
+        .get_or_init(|| {
+            let content_bytes = JsV3Libraries::get_libraries_mapping()?;
 
-    let content = std::str::from_utf8(&content_bytes).ok()?;
+            let content = std::str::from_utf8(&content_bytes).ok()?;
 
-    serde_json::from_str(content).ok()
+            serde_json::from_str(content).ok()
+        })
+        .as_ref()
 }
 
 /// Result of finding a command/function instantiation with its arguments
@@ -113,7 +211,7 @@ impl ExtractionUtils {
         method_calls.extend(Self::extract_command_operations(
             scan_results,
             scanner,
-            lib_mappings.as_ref(),
+            lib_mappings,
             &mut handled_names,
         ));
 
@@ -121,7 +219,7 @@ impl ExtractionUtils {
         method_calls.extend(Self::extract_paginate_operations(
             scan_results,
             scanner,
-            lib_mappings.as_ref(),
+            lib_mappings,
             &mut handled_names,
         ));
 
@@ -144,10 +242,30 @@ impl ExtractionUtils {
         method_calls.extend(Self::extract_library_class_operations(
             scan_results,
             scanner,
-            lib_mappings.as_ref(),
+            lib_mappings,
             &handled_names,
         ));
 
+        // Extract operations from presigned POST policies (createPresignedPost -> PutObject)
+        // Presigned URLs need no handling: getSignedUrl(client, new GetObjectCommand(...))
+        // is covered by the Command extraction above
+        method_calls.extend(Self::extract_presigned_post_operations(
+            scan_results,
+            scanner,
+        ));
+
+        // Extract the X-Ray operations of tracing instrumentation (e.g., AWSXRay.captureAWSv3Client)
+        method_calls.extend(Self::extract_tracing_operations(scanner));
+
+        // Extract the operations of middy middlewares fetching configuration (e.g., @middy/ssm)
+        method_calls.extend(Self::extract_middleware_operations(scanner));
+
+        // Extract the operations of DynamoDB ORM models (Dynamoose, ElectroDB, OneTable)
+        method_calls.extend(dynamodb_orms::extract_orm_operations(scanner));
+
+        // Extract the S3 and Cognito operations of the Amplify storage and auth categories
+        method_calls.extend(amplify::extract_amplify_operations(scanner));
+
         method_calls
     }
 
@@ -182,9 +300,18 @@ impl ExtractionUtils {
                         handled_names.insert(import_info.original_name.clone());
                         // Try to find the actual constructor instantiation with arguments
                         // Use the local name for the search (handles renames)
-                        let result = scanner
-                            .find_command_instantiation_with_args(&import_info.local_name)
-                            .unwrap_or_else(|| import_info.into()); // Fallback to import position with no params
+                        let mut usages = scanner
+                            .find_all_command_instantiations_with_args(&import_info.local_name);
+                        let table_bindings = if is_lib && service == DYNAMODB_SERVICE {
+                            Self::document_table_bindings(
+                                usages.iter().map(|usage| usage.parameters.as_slice()),
+                            )
+                        } else {
+                            BTreeMap::new()
+                        };
+                        if usages.is_empty() {
+                            usages.push(import_info.into()); // Fallback to import position with no params
+                        }
 
                         // Check if this needs library expansion (lib-* sublibraries)
                         let expanded = Self::expand_lib_names(
@@ -194,15 +321,20 @@ impl ExtractionUtils {
                             lib_mappings,
                         );
 
-                        // Create operations for each expanded command name
-                        for command_name in expanded {
-                            // Extract operation name by removing "Command" suffix
-                            if let Some(operation_name) = command_name.strip_suffix("Command") {
-                                operations.push(Self::build_sdk_method_call(
-                                    operation_name,
-                                    &service,
-                                    &result,
-                                ));
+                        // Create operations for each usage of each expanded command name
+                        for usage in &usages {
+                            for command_name in &expanded {
+                                // Extract operation name by removing "Command" suffix
+                                if let Some(operation_name) = command_name.strip_suffix("Command") {
+                                    operations.push(Self::with_resource_bindings(
+                                        Self::build_sdk_method_call(
+                                            operation_name,
+                                            &service,
+                                            usage,
+                                        ),
+                                        &table_bindings,
+                                    ));
+                                }
                             }
                         }
                     }
@@ -214,11 +346,19 @@ impl ExtractionUtils {
                         scanner.find_namespace_command_with_args(namespace, "Command")
                     {
                         handled_names.insert(command_name.clone());
+                        let table_bindings = if is_lib && service == DYNAMODB_SERVICE {
+                            Self::document_table_bindings([usage.parameters.as_slice()])
+                        } else {
+                            BTreeMap::new()
+                        };
                         let expanded =
                             Self::expand_lib_names(is_lib, &service, &command_name, lib_mappings);
                         for name in expanded {
                             if let Some(op) = name.strip_suffix("Command") {
-                                operations.push(Self::build_sdk_method_call(op, &service, &usage));
+                                operations.push(Self::with_resource_bindings(
+                                    Self::build_sdk_method_call(op, &service, &usage),
+                                    &table_bindings,
+                                ));
                             }
                         }
                     }
@@ -260,9 +400,11 @@ impl ExtractionUtils {
                         handled_names.insert(import_info.original_name.clone());
                         // Try to find the actual paginate function call with arguments
                         // Use the local name for the search (handles renames)
-                        let result = scanner
-                            .find_paginate_function_with_args(&import_info.local_name)
-                            .unwrap_or_else(|| import_info.into()); // Fallback to import position with no params
+                        let mut usages =
+                            scanner.find_paginate_function_calls_with_args(&import_info.local_name);
+                        if usages.is_empty() {
+                            usages.push(import_info.into()); // Fallback to import position with no params
+                        }
 
                         // Check if this needs library expansion (lib-* sublibraries)
                         let expanded = Self::expand_lib_names(
@@ -272,16 +414,18 @@ impl ExtractionUtils {
                             lib_mappings,
                         );
 
-                        for name in expanded {
-                            let operation_name = name
-                                .strip_suffix("Command")
-                                .or_else(|| name.strip_prefix("paginate"))
-                                .unwrap_or(&name);
-                            operations.push(Self::build_sdk_method_call(
-                                operation_name,
-                                &service,
-                                &result,
-                            ));
+                        for usage in &usages {
+                            for name in &expanded {
+                                let operation_name = name
+                                    .strip_suffix("Command")
+                                    .or_else(|| name.strip_prefix("paginate"))
+                                    .unwrap_or(name);
+                                operations.push(Self::build_sdk_method_call(
+                                    operation_name,
+                                    &service,
+                                    usage,
+                                ));
+                            }
                         }
                     }
                 }
@@ -339,18 +483,22 @@ impl ExtractionUtils {
                         handled_names.insert(import_info.original_name.clone());
                         // Try to find the actual waiter function call with arguments
                         // Use the local name for the search (handles renames)
-                        let result = scanner
-                            .find_waiter_function_with_args(&import_info.local_name)
-                            .unwrap_or_else(|| import_info.into()); // Fallback to import position with no params
+                        let mut usages =
+                            scanner.find_waiter_function_calls_with_args(&import_info.local_name);
+                        if usages.is_empty() {
+                            usages.push(import_info.into()); // Fallback to import position with no params
+                        }
 
                         // Keep PascalCase waiter name
                         // e.g., "BucketExists" from "waitUntilBucketExists"
                         // This will be resolved to the actual operation (e.g., "HeadBucket") in filter_map
-                        operations.push(Self::build_sdk_method_call(
-                            waiter_name,
-                            &service,
-                            &result,
-                        ));
+                        for usage in &usages {
+                            operations.push(Self::build_sdk_method_call(
+                                waiter_name,
+                                &service,
+                                usage,
+                            ));
+                        }
                     }
                 }
 
@@ -481,14 +629,15 @@ impl ExtractionUtils {
                             .find_command_instantiation_with_args(&import_info.local_name)
                             .unwrap_or_else(|| import_info.into()); // Fallback to import position with no params
 
+                        let (result, object_bindings) = Self::library_class_input(&service, result);
+
                         // Create operations for each expanded command
                         for command_name in expanded_commands {
                             // Extract operation name by removing "Command" suffix
                             if let Some(operation_name) = command_name.strip_suffix("Command") {
-                                operations.push(Self::build_sdk_method_call(
-                                    operation_name,
-                                    &service,
-                                    &result,
+                                operations.push(Self::with_resource_bindings(
+                                    Self::build_sdk_method_call(operation_name, &service, &result),
+                                    &object_bindings,
                                 ));
                             }
                         }
@@ -510,10 +659,13 @@ impl ExtractionUtils {
                             let usages =
                                 scanner.find_namespace_command_with_args(namespace, class_name);
                             for (_matched_name, usage) in usages {
+                                let (usage, object_bindings) =
+                                    Self::library_class_input(&service, usage);
                                 for command_name in expanded_commands {
                                     if let Some(op) = command_name.strip_suffix("Command") {
-                                        operations.push(Self::build_sdk_method_call(
-                                            op, &service, &usage,
+                                        operations.push(Self::with_resource_bindings(
+                                            Self::build_sdk_method_call(op, &service, &usage),
+                                            &object_bindings,
                                         ));
                                     }
                                 }
@@ -527,11 +679,250 @@ impl ExtractionUtils {
         operations
     }
 
+    /// Extract operations from `createPresignedPost(client, { Bucket, Key, Conditions, Fields })`
+    ///
+    /// The signer needs `s3:PutObject` on the keys the policy allows, plus
+    /// `s3:PutObjectAcl` or `s3:PutObjectTagging` when the policy lets the form
+    /// set an `acl` or `tagging` field.
+    fn extract_presigned_post_operations<T>(
+        scan_results: &JavaScriptScanResults,
+        scanner: &mut crate::extraction::javascript::scanner::ASTScanner<T>,
+    ) -> Vec<SdkMethodCall>
+    where
+        T: ast_grep_language::LanguageExt,
+    {
+        let mut operations = Vec::new();
+
+        for import_source in [&scan_results.imports, &scan_results.requires] {
+            for sublibrary_info in import_source {
+                if sublibrary_info.sublibrary != PRESIGNED_POST_SUBLIBRARY {
+                    continue;
+                }
+
+                for import_info in &sublibrary_info.imports {
+                    if import_info.original_name != PRESIGNED_POST_FUNCTION {
+                        continue;
+                    }
+                    // Same (client, input) shape as paginators
+                    let mut usages =
+                        scanner.find_paginate_function_calls_with_args(&import_info.local_name);
+                    if usages.is_empty() {
+                        usages.push(import_info.into()); // Fallback to import position with no params
+                    }
+                    for usage in &usages {
+                        let (actions, object_bindings) =
+                            Self::presigned_post_policy(&usage.parameters);
+                        for action in actions {
+                            operations.push(Self::with_resource_bindings(
+                                Self::build_sdk_method_call(action, S3_SERVICE, usage),
+                                &object_bindings,
+                            ));
+                        }
+                    }
+                }
+            }
+        }
+
+        operations
+    }
+
+    /// X-Ray operations needed by clients instrumented for tracing
+    ///
+    /// Operations on the instrumented clients themselves are extracted as usual;
+    /// the client scan sees through `captureAWSv3Client(new S3Client(...))`.
+    fn extract_tracing_operations<T>(
+        scanner: &crate::extraction::javascript::scanner::ASTScanner<T>,
+    ) -> Vec<SdkMethodCall>
+    where
+        T: ast_grep_language::LanguageExt,
+    {
+        let Some(usage) = scanner.find_tracing_instrumentation().into_iter().next() else {
+            return Vec::new();
+        };
+        TRACING_OPERATIONS
+            .iter()
+            .map(|operation| Self::build_sdk_method_call(operation, XRAY_SERVICE, &usage))
+            .collect()
+    }
+
+    /// Operations called at runtime by the middy middlewares a handler uses
+    ///
+    /// Middlewares such as `@middy/ssm` and `@middy/secrets-manager` fetch their
+    /// `fetchData` before the handler runs, with the function's own role.
+    fn extract_middleware_operations<T>(
+        scanner: &crate::extraction::javascript::scanner::ASTScanner<T>,
+    ) -> Vec<SdkMethodCall>
+    where
+        T: ast_grep_language::LanguageExt,
+    {
+        let mut operations = Vec::new();
+
+        for (package, usage) in scanner.find_middy_middlewares() {
+            let Some((_, service, middleware_operations)) = MIDDY_MIDDLEWARES
+                .iter()
+                .find(|(middleware, _, _)| *middleware == package)
+            else {
+                continue;
+            };
+            let middleware_operations = if package == MIDDY_SSM_PACKAGE {
+                Self::ssm_middleware_operations(&usage.parameters)
+            } else {
+                middleware_operations.to_vec()
+            };
+            operations.extend(
+                middleware_operations
+                    .iter()
+                    .map(|operation| Self::build_sdk_method_call(operation, service, &usage)),
+            );
+        }
+
+        operations
+    }
+
+    /// SSM operations of `ssm({ fetchData: { ... } })`
+    ///
+    /// Paths (values ending in `/`) are fetched with `GetParametersByPath`,
+    /// names with `GetParameters`. Both are needed when a value isn't a literal.
+    fn ssm_middleware_operations(parameters: &[Parameter]) -> Vec<&'static str> {
+        let fetch_data = parameters.iter().find_map(|parameter| match parameter {
+            Parameter::Keyword { name, value, .. } if name == "fetchData" => Some(
+                ArgumentExtractor::parse_object_literal_with_resolution(value.as_string()),
+            ),
+            _ => None,
+        });
+        let Some(fetch_data) = fetch_data.filter(|fetch_data| !fetch_data.is_empty()) else {
+            return vec!["GetParameters", "GetParametersByPath"];
+        };
+
+        let (mut by_name, mut by_path) = (false, false);
+        for parameter in &fetch_data {
+            match parameter {
+                Parameter::Keyword {
+                    value: ParameterValue::Resolved(path),
+                    ..
+                } if path.ends_with('/') => by_path = true,
+                Parameter::Keyword {
+                    value: ParameterValue::Resolved(_),
+                    ..
+                } => by_name = true,
+                _ => (by_name, by_path) = (true, true),
+            }
+        }
+        [("GetParameters", by_name), ("GetParametersByPath", by_path)]
+            .into_iter()
+            .filter_map(|(operation, needed)| needed.then_some(operation))
+            .collect()
+    }
+
+    /// S3 operations and resource bindings of a presigned POST policy's input
+    ///
+    /// The object is the literal `Key` (`${filename}` standing for any name), or
+    /// the prefix of a `["starts-with", "$key", prefix]` condition.
+    fn presigned_post_policy(
+        parameters: &[Parameter],
+    ) -> (Vec<&'static str>, BTreeMap<String, String>) {
+        let value_of = |property: &str| {
+            parameters.iter().find_map(|parameter| match parameter {
+                Parameter::Keyword { name, value, .. } if name == property => Some(value),
+                _ => None,
+            })
+        };
+        let policy_fields = [value_of("Fields"), value_of("Conditions")]
+            .into_iter()
+            .flatten()
+            .map(|value| value.as_string().to_lowercase())
+            .collect::<Vec<_>>()
+            .join(" ");
+        let allows_field = |field: &str| {
+            policy_fields
+                .split(|c: char| !(c.is_alphanumeric() || c == '-'))
+                .any(|token| token == field)
+        };
+
+        let mut actions = vec!["PutObject"];
+        if allows_field("acl") {
+            actions.push("PutObjectAcl");
+        }
+        if allows_field("tagging") {
+            actions.push("PutObjectTagging");
+        }
+
+        let mut bindings = BTreeMap::new();
+        if let Some(ParameterValue::Resolved(bucket)) = value_of("Bucket") {
+            let key_pattern = match value_of("Key") {
+                Some(ParameterValue::Resolved(key)) => key.replace(FILENAME_VARIABLE, "*"),
+                _ => value_of("Conditions")
+                    .and_then(|conditions| {
+                        key_prefix_condition_regex()
+                            .captures(conditions.as_string())
+                            .and_then(|captures| captures.get(1))
+                    })
+                    .map_or_else(|| "*".to_string(), |prefix| format!("{}*", prefix.as_str())),
+            };
+            bindings.insert(BUCKET_PLACEHOLDER.to_string(), bucket.clone());
+            bindings.insert(OBJECT_PLACEHOLDER.to_string(), key_pattern);
+        }
+        (actions, bindings)
+    }
+
+    /// Command input and resource bindings of a lib-* class instantiation
+    ///
+    /// lib-storage's `new Upload({ client, params })` takes the `PutObject` input
+    /// under `params`; its `Bucket` and `Key` scope the multipart upload actions
+    /// when `Bucket` is a literal. Other classes keep their arguments as-is.
+    fn library_class_input<'a>(
+        service: &str,
+        mut usage: CommandUsage<'a>,
+    ) -> (CommandUsage<'a>, BTreeMap<String, String>) {
+        let mut bindings = BTreeMap::new();
+        if service != S3_SERVICE {
+            return (usage, bindings);
+        }
+
+        let Some(params) = usage
+            .parameters
+            .iter()
+            .find_map(|parameter| match parameter {
+                Parameter::Keyword { name, value, .. } if name == "params" => Some(
+                    ArgumentExtractor::parse_object_literal_with_resolution(value.as_string()),
+                ),
+                _ => None,
+            })
+        else {
+            return (usage, bindings);
+        };
+
+        let literal = |property: &str| {
+            params.iter().find_map(|parameter| match parameter {
+                Parameter::Keyword {
+                    name,
+                    value: ParameterValue::Resolved(value),
+                    ..
+                } if name == property => Some(value.clone()),
+                _ => None,
+            })
+        };
+        if let Some(bucket) = literal("Bucket") {
+            bindings.insert(BUCKET_PLACEHOLDER.to_string(), bucket);
+            bindings.insert(
+                OBJECT_PLACEHOLDER.to_string(),
+                literal("Key").unwrap_or_else(|| "*".to_string()),
+            );
+        }
+        usage.parameters = params;
+        (usage, bindings)
+    }
+
     /// Extract operations from direct client method calls (e.g., client.getObject())
+    ///
+    /// Methods of lib-* document clients (e.g., `docClient.get(...)` on a
+    /// `DynamoDBDocument`) are expanded through the library mappings like their
+    /// Command equivalents.
     pub(crate) fn extract_operations_from_method_calls(
         scan_results: &JavaScriptScanResults,
     ) -> Vec<SdkMethodCall> {
         let mut operations = Vec::new();
+        let lib_mappings = load_libraries_mapping();
 
         // Process method calls to find direct operations on clients
         for method_call in &scan_results.method_calls {
@@ -551,6 +942,16 @@ impl ExtractionUtils {
             // e.g., "getObject" -> "GetObject"
             let operation_name = Self::camel_case_to_pascal_case(&method_call.method_name);
 
+            if method_call.client_sublibrary.starts_with("lib-") {
+                operations.extend(Self::library_method_operations(
+                    method_call,
+                    &operation_name,
+                    &service,
+                    lib_mappings,
+                ));
+                continue;
+            }
+
             // Convert method arguments to parameters
             let parameters = Self::convert_arguments_to_parameters(&method_call.arguments);
 
@@ -571,6 +972,185 @@ impl ExtractionUtils {
         operations
     }
 
+    /// Operations for a method call on a lib-* client, e.g. `docClient.put({ TableName: "Users" })`
+    fn library_method_operations(
+        method_call: &MethodCall,
+        operation_name: &str,
+        service: &str,
+        lib_mappings: Option<&JsV3LibrariesMapping>,
+    ) -> Vec<SdkMethodCall> {
+        // Re-parse the argument object so literal values stay distinguishable
+        // from identifiers, which table scoping relies on
+        let parameters = method_call
+            .expr
+            .split_once('(')
+            .and_then(|(_, rest)| rest.rsplit_once(')'))
+            .map(|(arguments, _)| {
+                ArgumentExtractor::parse_object_literal_with_resolution(arguments)
+            })
+            .unwrap_or_default();
+        let table_bindings = if service == DYNAMODB_SERVICE {
+            Self::document_table_bindings([parameters.as_slice()])
+        } else {
+            BTreeMap::new()
+        };
+
+        Self::expand_lib_names(
+            true,
+            service,
+            &format!("{operation_name}Command"),
+            lib_mappings,
+        )
+        .iter()
+        .filter_map(|command_name| command_name.strip_suffix("Command"))
+        .map(|operation| {
+            let metadata =
+                SdkMethodCallMetadata::new(method_call.expr.clone(), method_call.location.clone())
+                    .with_parameters(parameters.clone())
+                    .with_receiver(method_call.client_variable.clone())
+                    .with_resource_bindings(table_bindings.clone());
+            SdkMethodCall {
+                name: operation.to_string(),
+                possible_services: vec![service.to_string()],
+                metadata: Some(metadata),
+            }
+        })
+        .collect()
+    }
+
+    /// Resource bindings scoping DynamoDB document client calls to the table they use
+    ///
+    /// Tables come from `TableName`, the keys of `RequestItems` (batch commands) and
+    /// the `TableName`s inside `TransactItems`. Calls stay unscoped unless every
+    /// usage names the same literal table.
+    fn document_table_bindings<'p>(
+        usages: impl IntoIterator<Item = &'p [Parameter]>,
+    ) -> BTreeMap<String, String> {
+        let mut tables = BTreeSet::new();
+        for parameters in usages {
+            match Self::document_command_tables(parameters) {
+                Some(usage_tables) if !usage_tables.is_empty() => tables.extend(usage_tables),
+                _ => return BTreeMap::new(),
+            }
+        }
+
+        let mut tables = tables.into_iter();
+        match (tables.next(), tables.next()) {
+            (Some(table), None) => BTreeMap::from([(TABLE_PLACEHOLDER.to_string(), table)]),
+            _ => BTreeMap::new(),
+        }
+    }
+
+    /// Literal tables named by one document command's input
+    ///
+    /// Returns `None` when some table isn't a literal (identifiers, computed
+    /// `RequestItems` keys, spread input), so the command can't be scoped.
+    fn document_command_tables(parameters: &[Parameter]) -> Option<Vec<String>> {
+        let mut tables = Vec::new();
+        for parameter in parameters {
+            let Parameter::Keyword { name, value, .. } = parameter else {
+                continue;
+            };
+            match (name.as_str(), value) {
+                ("TableName", ParameterValue::Resolved(table)) => tables.push(table.clone()),
+                ("TableName", ParameterValue::Unresolved(_)) => return None,
+                ("RequestItems", value) => {
+                    let text = value.as_string();
+                    if text.contains("...") {
+                        return None;
+                    }
+                    for item in ArgumentExtractor::parse_object_literal_with_resolution(text) {
+                        let Parameter::Keyword { name: table, .. } = item else {
+                            continue;
+                        };
+                        if table.starts_with('[') {
+                            return None;
+                        }
+                        tables.push(table);
+                    }
+                }
+                ("TransactItems", value) => {
+                    let text = value.as_string();
+                    let literals: Vec<String> = table_name_literal_regex()
+                        .captures_iter(text)
+                        .filter_map(|captures| {
+                            captures
+                                .iter()
+                                .skip(1)
+                                .flatten()
+                                .next()
+                                .map(|table| table.as_str().to_string())
+                        })
+                        .collect();
+                    if text.contains("...") || literals.len() != text.matches("TableName").count() {
+                        return None;
+                    }
+                    tables.extend(literals);
+                }
+                _ => {}
+            }
+        }
+        Some(tables)
+    }
+
+    /// Attach resource bindings to an extracted call
+    pub(crate) fn with_resource_bindings(
+        mut call: SdkMethodCall,
+        resource_bindings: &BTreeMap<String, String>,
+    ) -> SdkMethodCall {
+        if !resource_bindings.is_empty() {
+            call.metadata = call
+                .metadata
+                .map(|metadata| metadata.with_resource_bindings(resource_bindings.clone()));
+        }
+        call
+    }
+
+    /// Deduplicate calls by (operation name, services), keeping the first of each
+    /// distinct set of resource bindings
+    ///
+    /// JavaScript SDK v3 may extract the same operation from multiple sources
+    /// (e.g., QueryCommandInput and paginateQuery both infer Query operation).
+    /// Usages scoped to different resources are all kept, so each contributes its
+    /// resource; when some usage isn't scoped, a single unscoped call covers them all.
+    pub(crate) fn deduplicate_operations(method_calls: &mut Vec<SdkMethodCall>) {
+        let mut groups: Vec<Vec<SdkMethodCall>> = Vec::new();
+        let mut seen: HashMap<(String, Vec<String>), usize> = HashMap::new();
+
+        for call in method_calls.drain(..) {
+            let key = (call.name.clone(), call.possible_services.clone());
+            if let Some(&index) = seen.get(&key) {
+                groups[index].push(call);
+            } else {
+                seen.insert(key, groups.len());
+                groups.push(vec![call]);
+            }
+        }
+
+        let is_scoped = |call: &SdkMethodCall| {
+            call.metadata
+                .as_ref()
+                .is_some_and(|metadata| !metadata.resource_bindings.is_empty())
+        };
+        for group in groups {
+            if group.iter().all(is_scoped) {
+                let mut kept_bindings = HashSet::new();
+                method_calls.extend(group.into_iter().filter(|call| {
+                    kept_bindings.insert(
+                        call.metadata
+                            .as_ref()
+                            .map(|metadata| metadata.resource_bindings.clone()),
+                    )
+                }));
+            } else if let Some(mut kept) = group.into_iter().next() {
+                if let Some(metadata) = kept.metadata.as_mut() {
+                    metadata.resource_bindings.clear();
+                }
+                method_calls.push(kept);
+            }
+        }
+    }
+
     /// Convert camelCase to PascalCase for method names
     /// e.g., "getObject" -> "GetObject", "listTables" -> "ListTables"
     pub(crate) fn camel_case_to_pascal_case(input: &str) -> String {
@@ -593,7 +1173,13 @@ impl ExtractionUtils {
         // "client-s3" -> Some("s3")
         // "lib-dynamodb" -> Some("dynamodb")
         // "client-lambda" -> Some("lambda")
-        if let Some(service) = sublibrary.strip_prefix("client-") {
+        // "lib-storage" -> Some("s3")
+        if let Some((_, service)) = LIBRARY_SERVICES
+            .iter()
+            .find(|(library, _)| *library == sublibrary)
+        {
+            Some((*service).to_string())
+        } else if let Some(service) = sublibrary.strip_prefix("client-") {
             Some(service.to_string())
         } else {
             sublibrary
@@ -645,7 +1231,7 @@ impl ExtractionUtils {
     }
 
     /// Build an `SdkMethodCall` from a matched operation name, service, and usage site.
-    fn build_sdk_method_call(
+    pub(crate) fn build_sdk_method_call(
         operation_name: &str,
         service: &str,
         usage: &CommandUsage<'_>,
@@ -664,6 +1250,7 @@ impl ExtractionUtils {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use std::path::PathBuf;
 
     #[test]
     fn test_extract_service_from_sublibrary() {
@@ -686,7 +1273,7 @@ mod tests {
         );
         assert_eq!(
             ExtractionUtils::extract_service_from_sublibrary("lib-storage"),
-            Some("storage".to_string())
+            Some("s3".to_string())
         );
 
         // Test unsuccessful pattern matching (None cases)
@@ -749,4 +1336,57 @@ mod tests {
         assert_eq!(ExtractionUtils::camel_case_to_pascal_case("query"), "Query");
         assert_eq!(ExtractionUtils::camel_case_to_pascal_case(""), "");
     }
+
+    #[test]
+    fn test_deduplicate_operations_keeps_distinct_bindings() {
+        let call = |table: Option<&str>| SdkMethodCall {
+            name: "GetItem".to_string(),
+            possible_services: vec!["dynamodb".to_string()],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    String::new(),
+                    Location::new(PathBuf::new(), (1, 1), (1, 1)),
+                )
+                .with_resource_bindings(
+                    table
+                        .map(|table| BTreeMap::from([("TableName".to_string(), table.to_string())]))
+                        .unwrap_or_default(),
+                ),
+            ),
+        };
+        let bindings = |calls: &[SdkMethodCall]| -> Vec<BTreeMap<String, String>> {
+            calls
+                .iter()
+                .map(|call| call.metadata.as_ref().unwrap().resource_bindings.clone())
+                .collect()
+        };
+
+        let mut same_table = vec![call(Some("Orders")), call(Some("Orders"))];
+        ExtractionUtils::deduplicate_operations(&mut same_table);
+        assert_eq!(
+            bindings(&same_table),
+            vec![BTreeMap::from([(
+                "TableName".to_string(),
+                "Orders".to_string()
+            )])]
+        );
+
+        let mut unscoped_duplicate = vec![call(Some("Orders")), call(None)];
+        ExtractionUtils::deduplicate_operations(&mut unscoped_duplicate);
+        assert_eq!(bindings(&unscoped_duplicate), vec![BTreeMap::new()]);
+
+        let mut different_tables = vec![
+            call(Some("Orders")),
+            call(Some("Customers")),
+            call(Some("Orders")),
+        ];
+        ExtractionUtils::deduplicate_operations(&mut different_tables);
+        assert_eq!(
+            bindings(&different_tables),
+            vec![
+                BTreeMap::from([("TableName".to_string(), "Orders".to_string())]),
+                BTreeMap::from([("TableName".to_string(), "Customers".to_string())]),
+            ]
+        );
+    }
 }
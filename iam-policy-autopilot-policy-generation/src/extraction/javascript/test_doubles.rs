@@ -0,0 +1,104 @@
+//! Recognition of JavaScript and TypeScript tests mocking the AWS SDK clients.
+//!
+//! Unit tests replace the clients with `aws-sdk-client-mock`
+//! (`mockClient(S3Client).on(GetObjectCommand)`), with jest module mocks
+//! (`jest.mock('@aws-sdk/client-s3')`) or with sinon stubs of the client methods. The
+//! commands such files send or stub are never sent to AWS, and their fixtures name
+//! resources the production role never touches, so they must not add permissions.
+
+/// Module of `aws-sdk-client-mock`, and the prefix of its companion packages such as
+/// `aws-sdk-client-mock-jest`
+const CLIENT_MOCK_MODULE: &str = "aws-sdk-client-mock";
+
+/// Calls of the jest API replacing a module with a mock
+const JEST_MODULE_MOCKS: &[&str] = &["jest.mock(", "jest.doMock(", "jest.unstable_mockModule("];
+
+/// Check whether a JavaScript or TypeScript file mocks the AWS SDK clients.
+///
+/// Matches imports of `aws-sdk-client-mock`, jest module mocks of the AWS SDK, and
+/// files importing both sinon and the AWS SDK, whose stubs replace the client methods.
+pub(crate) fn mocks_aws_sdk(content: &str) -> bool {
+    let mut imports_sinon = false;
+    let mut imports_sdk = false;
+    for line in content.lines().map(str::trim) {
+        if imports_module(line, |module| module.starts_with(CLIENT_MOCK_MODULE))
+            || JEST_MODULE_MOCKS.iter().any(|mock| {
+                line.split_once(mock)
+                    .and_then(|(_, arguments)| quoted_module(arguments))
+                    .is_some_and(is_sdk_module)
+            })
+        {
+            return true;
+        }
+        imports_sinon |= imports_module(line, |module| module == "sinon");
+        imports_sdk |= imports_module(line, is_sdk_module);
+    }
+    imports_sinon && imports_sdk
+}
+
+/// Whether `module` is a package of the AWS SDK, v2 (`aws-sdk`) or v3 (`@aws-sdk/...`)
+fn is_sdk_module(module: &str) -> bool {
+    module == "aws-sdk" || module.starts_with("aws-sdk/") || module.starts_with("@aws-sdk/")
+}
+
+/// Whether a line imports or requires a module matching `matches`
+fn imports_module(line: &str, matches: impl Fn(&str) -> bool) -> bool {
+    // `}` closes the specifiers of an import spanning several lines
+    let module = if ["import ", "export ", "}"]
+        .iter()
+        .any(|keyword| line.starts_with(keyword))
+    {
+        line.rsplit_once(" from ")
+            .map_or_else(|| line.strip_prefix("import "), |(_, source)| Some(source))
+            .and_then(quoted_module)
+    } else {
+        line.split_once("require(")
+            .and_then(|(_, arguments)| quoted_module(arguments))
+    };
+    module.is_some_and(matches)
+}
+
+/// The module named by the string literal at the start of `text`, ignoring whitespace
+fn quoted_module(text: &str) -> Option<&str> {
+    let text = text.trim_start();
+    let quote = text
+        .chars()
+        .next()
+        .filter(|c| matches!(c, '\'' | '"' | '`'))?;
+    let rest = &text[1..];
+    rest.find(quote).map(|end| &rest[..end])
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use rstest::rstest;
+
+    #[rstest]
+    #[case::client_mock(
+        "import { mockClient } from 'aws-sdk-client-mock';\n\
+         const s3Mock = mockClient(S3Client);\n",
+        true
+    )]
+    #[case::client_mock_jest_matchers("require(\"aws-sdk-client-mock-jest\");\n", true)]
+    #[case::jest_mock_v3("jest.mock('@aws-sdk/client-dynamodb');\n", true)]
+    #[case::jest_mock_v2("jest.mock(\"aws-sdk\", () => ({ S3: jest.fn() }));\n", true)]
+    #[case::sinon_stub(
+        "import sinon from 'sinon';\nimport { S3Client } from '@aws-sdk/client-s3';\n\
+         sinon.stub(S3Client.prototype, 'send').resolves({});\n",
+        true
+    )]
+    #[case::jest_mock_of_other_module(
+        "import { S3Client } from '@aws-sdk/client-s3';\njest.mock('./config');\n",
+        false
+    )]
+    #[case::sinon_without_sdk("const sinon = require('sinon');\n", false)]
+    #[case::production(
+        "import { S3Client, GetObjectCommand } from '@aws-sdk/client-s3';\n\
+         await new S3Client({}).send(new GetObjectCommand(input));\n",
+        false
+    )]
+    fn test_mocks_aws_sdk(#[case] content: &str, #[case] expected: bool) {
+        assert_eq!(mocks_aws_sdk(content), expected);
+    }
+}
@@ -7,6 +7,7 @@
 use serde::{Deserialize, Serialize};
 use std::path::{Path, PathBuf};
 
+pub(crate) mod cache;
 pub(crate) mod engine;
 pub(crate) mod external_library_models;
 pub(crate) mod extractor;
@@ -14,6 +15,8 @@ pub(crate) mod framework;
 pub(crate) mod go;
 pub(crate) mod java;
 pub(crate) mod javascript;
+pub(crate) mod plugins;
+pub(crate) mod progress;
 pub(crate) mod python;
 pub(crate) mod sdk_model;
 pub(crate) mod service_hints;
@@ -28,6 +31,8 @@ pub(crate) mod call_graph;
 
 // Re-export main types for convenience
 pub use engine::Engine;
+pub use progress::{ProgressObserver, PROGRESS_LOG_TARGET};
+pub use shared::{Diagnostic, DiagnosticKind, SuppressedCall};
 // Not part of the stable public API — exposed only for integration tests in tests/.
 #[doc(hidden)]
 pub use sdk_model::ServiceDiscovery;
@@ -39,6 +44,7 @@ pub use self::{core::*, output::*};
 
 /// Core data structures for source file parsing and method extraction
 pub mod core {
+    use std::collections::BTreeMap;
     use std::sync::Arc;
 
     use schemars::JsonSchema;
@@ -145,6 +151,26 @@ pub mod core {
         /// Receiver variable name (e.g., "s3_client", "ec2Client")
         #[serde(skip_serializing_if = "Option::is_none")]
         pub(crate) receiver: Option<String>,
+
+        /// Concrete values for resource ARN placeholders known from the call site,
+        /// e.g. `BucketName` -> `my-bucket` for a `s3://my-bucket/...` URI
+        #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+        pub(crate) resource_bindings: BTreeMap<String, String>,
+
+        /// Concrete values for the ARN placeholders of the object a copy reads,
+        /// e.g. `BucketName` -> `reports` for `CopySource="reports/latest.csv"`
+        #[serde(default, skip_serializing_if = "BTreeMap::is_empty")]
+        pub(crate) source_resource_bindings: BTreeMap<String, String>,
+
+        /// Role whose assumed credentials the call runs under, e.g. the `RoleArn`
+        /// passed to `sts.assume_role` that built the receiver's client
+        #[serde(default, skip_serializing_if = "Option::is_none")]
+        pub(crate) assumed_role: Option<String>,
+
+        /// Named profile whose credentials the call runs under, e.g. the `profile_name` of
+        /// the `boto3.Session` that built the receiver's client
+        #[serde(default, skip_serializing_if = "Option::is_none")]
+        pub(crate) credential_profile: Option<String>,
     }
 
     impl SdkMethodCallMetadata {
@@ -162,6 +188,10 @@ pub mod core {
                 location,
                 parameters: Vec::new(),
                 receiver: None,
+                resource_bindings: BTreeMap::new(),
+                source_resource_bindings: BTreeMap::new(),
+                assumed_role: None,
+                credential_profile: None,
             }
         }
 
@@ -179,6 +209,30 @@ pub mod core {
             self
         }
 
+        /// Set concrete values for resource ARN placeholders (e.g. `BucketName`)
+        #[must_use]
+        pub(crate) fn with_resource_bindings(
+            mut self,
+            resource_bindings: BTreeMap<String, String>,
+        ) -> Self {
+            self.resource_bindings = resource_bindings;
+            self
+        }
+
+        /// Set the role whose assumed credentials the call runs under
+        #[must_use]
+        pub(crate) fn with_assumed_role(mut self, assumed_role: String) -> Self {
+            self.assumed_role = Some(assumed_role);
+            self
+        }
+
+        /// Set the named profile whose credentials the call runs under
+        #[must_use]
+        pub(crate) fn with_credential_profile(mut self, credential_profile: String) -> Self {
+            self.credential_profile = Some(credential_profile);
+            self
+        }
+
         /// Returns whether this method call uses dictionary unpacking
         /// If true, parameter validation should be skipped
         pub(crate) fn has_dictionary_unpacking(&self) -> bool {
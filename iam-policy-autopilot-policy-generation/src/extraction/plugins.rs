@@ -0,0 +1,325 @@
+//! Extractor plugins reporting the AWS calls of in-house SDK wrappers and private services.
+//!
+//! A plugin is an executable run once per analysis. It reads a JSON request with the
+//! language and the analyzed source files on stdin:
+//!
+//! ```json
+//! {"Language": "python", "Files": [{"Path": "app/orders.py", "Content": "..."}]}
+//! ```
+//!
+//! and writes the calls it recognizes as a JSON object on stdout:
+//!
+//! ```json
+//! {"Calls": [{"Service": "s3", "Operation": "GetObject", "Path": "app/orders.py",
+//!             "Line": 12, "Column": 5, "Expression": "storage.read(key)"}]}
+//! ```
+//!
+//! Calls naming an operation of an AWS service are analyzed like extracted SDK calls.
+//! Calls of services without a service reference, e.g. private services, list the
+//! `Actions` they require, and optionally the `Resources` they're required on, which are
+//! granted like those of `autopilot:require` annotations.
+
+use std::collections::HashSet;
+use std::io::Write;
+use std::path::{Path, PathBuf};
+use std::process::{Command, Stdio};
+
+use anyhow::{Context, Result};
+use log::{debug, warn};
+use serde::{Deserialize, Serialize};
+
+use crate::extraction::sdk_model::ServiceDiscovery;
+use crate::extraction::shared::RequiredPermission;
+use crate::extraction::{SdkMethodCallMetadata, SourceFile};
+use crate::{Language, Location, SdkMethodCall};
+
+/// Request written to the stdin of plugins
+#[derive(Debug, Serialize)]
+#[serde(rename_all = "PascalCase")]
+struct PluginRequest<'a> {
+    language: Language,
+    files: Vec<PluginFile<'a>>,
+}
+
+/// A source file of the request
+#[derive(Debug, Serialize)]
+#[serde(rename_all = "PascalCase")]
+struct PluginFile<'a> {
+    path: &'a Path,
+    content: &'a str,
+}
+
+/// Response plugins write to stdout
+#[derive(Debug, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+struct PluginResponse {
+    #[serde(default)]
+    calls: Vec<PluginCall>,
+}
+
+/// A call recognized by a plugin
+#[derive(Debug, Deserialize)]
+#[serde(rename_all = "PascalCase")]
+struct PluginCall {
+    /// Service of the call, e.g. `s3`
+    service: String,
+    /// Operation of the call, as named by the service, e.g. `GetObject`
+    operation: String,
+    /// Source file of the call, as given in the request
+    path: PathBuf,
+    /// Line and column of the call, 1-based
+    line: usize,
+    #[serde(default = "first_column")]
+    column: usize,
+    /// Expression of the call, the operation if not given
+    expression: Option<String>,
+    /// Actions the call requires, for services without a service reference
+    #[serde(default)]
+    actions: Vec<String>,
+    /// Resources the actions are required on, none for `*`
+    #[serde(default)]
+    resources: Vec<String>,
+}
+
+const fn first_column() -> usize {
+    1
+}
+
+/// Calls and permissions reported by plugins
+#[derive(Debug, Default)]
+pub(crate) struct PluginResults {
+    /// Calls of AWS operations, analyzed like extracted calls
+    pub(crate) calls: Vec<SdkMethodCall>,
+    /// Permissions of calls listing the actions they require
+    pub(crate) permissions: Vec<RequiredPermission>,
+}
+
+/// Run `plugins` on `source_files`, all of `language`
+pub(crate) fn run_plugins(
+    plugins: &[PathBuf],
+    language: Language,
+    source_files: &[SourceFile],
+) -> Result<PluginResults> {
+    let mut results = PluginResults::default();
+    if plugins.is_empty() {
+        return Ok(results);
+    }
+
+    let request = serde_json::to_vec(&PluginRequest {
+        language,
+        files: source_files
+            .iter()
+            .map(|source_file| PluginFile {
+                path: &source_file.path,
+                content: &source_file.content,
+            })
+            .collect(),
+    })
+    .context("Failed to serialize the plugin request")?;
+    let analyzed: HashSet<&Path> = source_files
+        .iter()
+        .map(|source_file| source_file.path.as_path())
+        .collect();
+
+    for plugin in plugins {
+        let output = run_plugin(plugin, &request)?;
+        let response: PluginResponse = serde_json::from_slice(&output)
+            .with_context(|| format!("Invalid response of plugin {}", plugin.display()))?;
+        debug!(
+            "Plugin {} reported {} calls",
+            plugin.display(),
+            response.calls.len()
+        );
+        for call in response.calls {
+            if !analyzed.contains(call.path.as_path()) {
+                warn!(
+                    "Skipping call of plugin {} in {}, which isn't analyzed",
+                    plugin.display(),
+                    call.path.display()
+                );
+                continue;
+            }
+            add_call(&mut results, call, language);
+        }
+    }
+    Ok(results)
+}
+
+/// Run `plugin` with `request` on stdin, returning its stdout
+fn run_plugin(plugin: &Path, request: &[u8]) -> Result<Vec<u8>> {
+    let mut child = Command::new(plugin)
+        .stdin(Stdio::piped())
+        .stdout(Stdio::piped())
+        .stderr(Stdio::piped())
+        .spawn()
+        .with_context(|| format!("Failed to run plugin {}", plugin.display()))?;
+    let mut stdin = child
+        .stdin
+        .take()
+        .context("Failed to open the stdin of the plugin")?;
+    // Written concurrently with reading the output, so a plugin answering before reading
+    // the whole request doesn't block on a full pipe
+    let output = std::thread::scope(|scope| {
+        let writer = scope.spawn(move || stdin.write_all(request));
+        let output = child.wait_with_output();
+        // A plugin may exit without reading the request, closing the pipe
+        let _ = writer.join();
+        output
+    })
+    .with_context(|| format!("Failed to run plugin {}", plugin.display()))?;
+    if !output.status.success() {
+        anyhow::bail!(
+            "Plugin {} failed ({}): {}",
+            plugin.display(),
+            output.status,
+            String::from_utf8_lossy(&output.stderr).trim()
+        );
+    }
+    Ok(output.stdout)
+}
+
+/// Add `call` to `results`, as a call or as the permissions it lists
+fn add_call(results: &mut PluginResults, call: PluginCall, language: Language) {
+    let expression = call.expression.unwrap_or_else(|| call.operation.clone());
+    let location = Location::new(
+        call.path,
+        (call.line, call.column),
+        (call.line, call.column + expression.len().saturating_sub(1)),
+    );
+    let metadata = SdkMethodCallMetadata::new(expression, location);
+    if call.actions.is_empty() {
+        results.calls.push(SdkMethodCall {
+            name: ServiceDiscovery::operation_to_method_name(&call.operation, language),
+            possible_services: vec![call.service],
+            metadata: Some(metadata),
+        });
+        return;
+    }
+    for action in call.actions {
+        results.permissions.push(RequiredPermission {
+            action,
+            resources: call.resources.clone(),
+            call: SdkMethodCall {
+                name: call.operation.clone(),
+                possible_services: vec![call.service.clone()],
+                metadata: Some(metadata.clone()),
+            },
+        });
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn response(json: &str) -> PluginResults {
+        let response: PluginResponse = serde_json::from_str(json).unwrap();
+        let mut results = PluginResults::default();
+        for call in response.calls {
+            add_call(&mut results, call, Language::Python);
+        }
+        results
+    }
+
+    #[test]
+    fn test_plugin_calls_of_aws_operations() {
+        let results = response(
+            r#"{"Calls": [{"Service": "s3", "Operation": "GetObject", "Path": "app.py",
+                "Line": 12, "Column": 5, "Expression": "storage.read(key)"}]}"#,
+        );
+
+        assert!(results.permissions.is_empty());
+        let call = &results.calls[0];
+        assert_eq!(call.name, "get_object");
+        assert_eq!(call.possible_services, vec!["s3".to_string()]);
+        let metadata = call.metadata.as_ref().unwrap();
+        assert_eq!(metadata.expr, "storage.read(key)");
+        assert_eq!(
+            metadata.location,
+            Location::new(PathBuf::from("app.py"), (12, 5), (12, 21))
+        );
+    }
+
+    #[test]
+    fn test_plugin_calls_listing_actions() {
+        let results = response(
+            r#"{"Calls": [{"Service": "ledger", "Operation": "PostEntry", "Path": "app.py",
+                "Line": 3, "Actions": ["ledger:PostEntry", "ledger:ReadAccount"],
+                "Resources": ["arn:aws:ledger:::book/main"]}]}"#,
+        );
+
+        assert!(results.calls.is_empty());
+        let actions: Vec<&str> = results
+            .permissions
+            .iter()
+            .map(|permission| permission.action.as_str())
+            .collect();
+        assert_eq!(actions, vec!["ledger:PostEntry", "ledger:ReadAccount"]);
+        let permission = &results.permissions[0];
+        assert_eq!(permission.resources, vec!["arn:aws:ledger:::book/main"]);
+        assert_eq!(permission.call.name, "PostEntry");
+        assert_eq!(
+            permission.call.metadata.as_ref().unwrap().location,
+            Location::new(PathBuf::from("app.py"), (3, 1), (3, 9))
+        );
+    }
+
+    #[test]
+    fn test_plugin_response_without_calls() {
+        let results = response("{}");
+
+        assert!(results.calls.is_empty());
+        assert!(results.permissions.is_empty());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn test_run_plugins() {
+        use std::os::unix::fs::PermissionsExt;
+
+        let directory = tempfile::tempdir().unwrap();
+        let plugin = directory.path().join("plugin.sh");
+        std::fs::write(
+            &plugin,
+            r#"#!/bin/sh
+cat > /dev/null
+echo '{"Calls": [
+  {"Service": "s3", "Operation": "GetObject", "Path": "app.py", "Line": 1},
+  {"Service": "s3", "Operation": "PutObject", "Path": "other.py", "Line": 1}
+]}'
+"#,
+        )
+        .unwrap();
+        std::fs::set_permissions(&plugin, std::fs::Permissions::from_mode(0o755)).unwrap();
+        let source_files = vec![SourceFile::with_language(
+            PathBuf::from("app.py"),
+            "storage.read(key)\n".to_string(),
+            Language::Python,
+        )];
+
+        let results = run_plugins(&[plugin], Language::Python, &source_files).unwrap();
+
+        let names: Vec<&str> = results
+            .calls
+            .iter()
+            .map(|call| call.name.as_str())
+            .collect();
+        assert_eq!(names, vec!["get_object"]);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn test_run_failing_plugin() {
+        let source_files = vec![SourceFile::with_language(
+            PathBuf::from("app.py"),
+            String::new(),
+            Language::Python,
+        )];
+
+        let error = run_plugins(&[PathBuf::from("false")], Language::Python, &source_files)
+            .unwrap_err()
+            .to_string();
+
+        assert!(error.contains("Plugin false failed"), "{error}");
+    }
+}
@@ -0,0 +1,56 @@
+//! Progress of the extraction across source files
+//!
+//! Every analyzed file is reported on the [`PROGRESS_LOG_TARGET`] log target, so callers
+//! can show progress without enabling the rest of the logs, and with its timing on the
+//! default target at INFO level. A [`ProgressObserver`] receives it too, e.g. to stream it
+//! to the client of a server running several analyses at once.
+
+use std::path::Path;
+use std::sync::Arc;
+use std::time::Duration;
+
+/// Log target on which the extraction reports each analyzed file at INFO level
+pub const PROGRESS_LOG_TARGET: &str = "iam_policy_autopilot::progress";
+
+/// Receives the progress of an extraction
+pub trait ProgressObserver: std::fmt::Debug + Send + Sync {
+    /// The file at `path` was analyzed, the `done`th out of `total`
+    fn file_analyzed(&self, path: &Path, done: usize, total: usize);
+}
+
+/// Counts the files analyzed out of all the files to analyze
+pub(crate) struct Progress {
+    done: usize,
+    total: usize,
+    observer: Option<Arc<dyn ProgressObserver>>,
+}
+
+impl Progress {
+    pub(crate) fn new(total: usize, observer: Option<Arc<dyn ProgressObserver>>) -> Self {
+        Self {
+            done: 0,
+            total,
+            observer,
+        }
+    }
+
+    /// Report that `extractor` analyzed the file at `path` in `elapsed`
+    pub(crate) fn file_analyzed(&mut self, path: &Path, extractor: &str, elapsed: Duration) {
+        self.done += 1;
+        log::info!(
+            target: PROGRESS_LOG_TARGET,
+            "[{}/{}] {}",
+            self.done,
+            self.total,
+            path.display()
+        );
+        log::info!(
+            "Analyzed {} with the {extractor} extractor in {:.2}ms",
+            path.display(),
+            elapsed.as_secs_f64() * 1000.0
+        );
+        if let Some(observer) = &self.observer {
+            observer.file_analyzed(path, self.done, self.total);
+        }
+    }
+}
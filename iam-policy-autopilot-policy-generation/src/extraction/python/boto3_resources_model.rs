@@ -3,6 +3,9 @@
 //! Parses boto3 resources JSON specifications and utility mappings for resource-based AWS SDK patterns.
 
 use crate::embedded_data::Boto3Data;
+use crate::extraction::python::common::ArgumentExtractor;
+use crate::extraction::shared::resource_literals::{copy_source_object, mapping_entries};
+use crate::extraction::{Parameter, ParameterValue};
 use convert_case::{Case, Casing};
 use serde::Deserialize;
 use std::collections::HashMap;
@@ -54,6 +57,95 @@ pub struct Boto3ResourcesModel {
 #[derive(Debug, Clone)]
 pub struct ClientUtilityMethod {
     pub(crate) operations: Vec<ServiceOperation>,
+    /// Call-site parameter names, in positional order
+    pub(crate) accepted_params: Vec<String>,
+    /// Call-site parameters the method can't be called without. When empty, call
+    /// sites are checked against each operation's own required parameters instead.
+    pub(crate) required_args: Vec<String>,
+}
+
+impl ClientUtilityMethod {
+    /// Whether a call provides every required argument, positionally or by keyword
+    pub(crate) fn accepts_call(&self, arguments: &[Parameter]) -> bool {
+        if arguments
+            .iter()
+            .any(|arg| matches!(arg, Parameter::DictionarySplat { .. }))
+        {
+            return true;
+        }
+        let positional_count = arguments
+            .iter()
+            .filter(|arg| matches!(arg, Parameter::Positional { .. }))
+            .count();
+        self.required_args.iter().all(|required| {
+            let provided_positionally = self
+                .accepted_params
+                .iter()
+                .position(|name| name == required)
+                .is_some_and(|index| index < positional_count);
+            provided_positionally
+                || arguments
+                    .iter()
+                    .any(|arg| matches!(arg, Parameter::Keyword { name, .. } if name == required))
+        })
+    }
+
+    /// Call-site arguments to pass on to one of the method's operations
+    ///
+    /// Positional arguments are named after `accepted_params`, and only the
+    /// operation's own parameters are kept: `upload_file(f, bucket, key)` issues
+    /// `PutObject(Bucket=bucket, Key=key)`, not `PutObject(Filename=f, ...)`.
+    /// Operations taking their parameters from one argument read its entries
+    /// instead: `copy({'Bucket': b, 'Key': k}, ...)` heads `HeadObject(Bucket=b, Key=k)`.
+    pub(crate) fn operation_arguments(
+        &self,
+        arguments: &[Parameter],
+        operation: &ServiceOperation,
+    ) -> Vec<Parameter> {
+        let mut operation_arguments = Vec::new();
+        for arg in arguments {
+            let named = match arg {
+                Parameter::Positional {
+                    value,
+                    position,
+                    type_annotation,
+                    ..
+                } => self
+                    .accepted_params
+                    .get(*position)
+                    .map(|name| (name, value, type_annotation)),
+                Parameter::Keyword {
+                    name,
+                    value,
+                    type_annotation,
+                    ..
+                } => Some((name, value, type_annotation)),
+                Parameter::DictionarySplat { .. } => {
+                    operation_arguments.push(arg.clone());
+                    continue;
+                }
+            };
+            let Some((name, value, type_annotation)) = named else {
+                continue;
+            };
+            if let Some(source) = &operation.arguments_from {
+                if name == source {
+                    operation_arguments
+                        .extend(operation.source_arguments(value, operation_arguments.len()));
+                }
+                continue;
+            }
+            if operation.required_params.contains(name) {
+                operation_arguments.push(Parameter::Keyword {
+                    name: name.clone(),
+                    value: value.clone(),
+                    position: operation_arguments.len(),
+                    type_annotation: type_annotation.clone(),
+                });
+            }
+        }
+        operation_arguments
+    }
 }
 
 /// Resource-level utility methods for a specific resource type
@@ -82,6 +174,46 @@ pub struct IdentifierMapping {
 pub struct ServiceOperation {
     pub operation: String,
     pub required_params: Vec<String>,
+    /// Call-site argument whose entries are the operation's parameters, e.g. the
+    /// `CopySource` mapping naming the object a managed copy heads
+    #[serde(default)]
+    pub arguments_from: Option<String>,
+}
+
+impl ServiceOperation {
+    /// Parameters of the operation read from the value of its `arguments_from`
+    /// argument: the entries of a mapping, or the bucket and key of a `bucket/key` string
+    pub(crate) fn source_arguments(
+        &self,
+        value: &ParameterValue,
+        position: usize,
+    ) -> Vec<Parameter> {
+        let entries = match value {
+            ParameterValue::Resolved(text) => copy_source_object(text)
+                .map(|(bucket, key)| {
+                    vec![
+                        ("Bucket".to_string(), ParameterValue::Resolved(bucket)),
+                        ("Key".to_string(), ParameterValue::Resolved(key)),
+                    ]
+                })
+                .unwrap_or_default(),
+            ParameterValue::Unresolved(text) => mapping_entries(text)
+                .into_iter()
+                .map(|(key, value)| (key, ArgumentExtractor::extract_parameter_value(value)))
+                .collect(),
+        };
+        entries
+            .into_iter()
+            .filter(|(name, _)| self.required_params.contains(name))
+            .enumerate()
+            .map(|(index, (name, value))| Parameter::Keyword {
+                name,
+                value,
+                position: position + index,
+                type_annotation: None,
+            })
+            .collect()
+    }
 }
 
 /// Resource constructor specification from service.has
@@ -153,6 +285,8 @@ struct UtilityMethodJson {
     operations: Vec<ServiceOperation>,
     accepted_params: Vec<String>,
     #[serde(default)]
+    required_args: Vec<String>,
+    #[serde(default)]
     identifier_mappings: Vec<IdentifierMapping>,
 }
 
@@ -359,6 +493,8 @@ impl Boto3ResourcesModel {
                     method_name.clone(),
                     ClientUtilityMethod {
                         operations: method_spec.operations.clone(),
+                        accepted_params: method_spec.accepted_params.clone(),
+                        required_args: method_spec.required_args.clone(),
                     },
                 );
             }
@@ -1,10 +1,14 @@
 //! Common utilities for Python extraction
 //!
 //! This module provides shared functionality used across multiple Python extractors,
-//! including argument parsing and parameter filtering.
+//! including argument parsing, parameter filtering and string constant resolution.
 
 pub mod argument_extractor;
 pub mod parameter_filter;
+pub(crate) mod string_constants;
+pub(crate) mod string_values;
 
 pub use argument_extractor::ArgumentExtractor;
 pub use parameter_filter::ParameterFilter;
+pub(crate) use string_constants::StringConstants;
+pub(crate) use string_values::StringValueResolver;
@@ -0,0 +1,325 @@
+//! String constant resolution for Python sources
+//!
+//! Collects string constants assigned at module level (`SERVICE = "s3"`) and in
+//! class bodies (settings classes, `class Service(Enum): S3 = "s3"`) so that
+//! expressions such as `SERVICE`, `settings.S3_SERVICE` or `Service.S3.value`
+//! can be resolved to their literal value, including across files of a project.
+//!
+//! Constants read from the environment (`BUCKET = os.environ["BUCKET_NAME"]`) are
+//! kept too, so resources named through them can be templated.
+
+use std::collections::{HashMap, HashSet};
+use std::path::Path;
+
+use ast_grep_core::tree_sitter::LanguageExt;
+use ast_grep_language::Python;
+
+use crate::extraction::python::node_kinds;
+use crate::extraction::shared::{ProjectConstants, ResourceValue};
+use crate::SourceFile;
+
+/// String constants defined in one or more Python modules
+#[derive(Debug, Default)]
+pub(crate) struct StringConstants {
+    /// Module-level constants: module name -> (constant name -> values)
+    module_constants: HashMap<String, HashMap<String, HashSet<ResourceValue>>>,
+    /// Class-level constants: class name -> (attribute name -> values)
+    class_constants: HashMap<String, HashMap<String, HashSet<ResourceValue>>>,
+}
+
+impl StringConstants {
+    /// Collect constants from every source file of a project
+    pub(crate) fn from_source_files(source_files: &[SourceFile]) -> Self {
+        let mut constants = Self::default();
+        for source_file in source_files {
+            let ast_grep = Python.ast_grep(&source_file.content);
+            constants.collect(&source_file.path, &ast_grep.root());
+        }
+        log::debug!(
+            "Collected Python string constants from {} modules and {} classes",
+            constants.module_constants.len(),
+            constants.class_constants.len()
+        );
+        constants
+    }
+
+    /// Collect constants from a single parsed module
+    pub(crate) fn collect(
+        &mut self,
+        path: &Path,
+        root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) {
+        // Files without a usable name (e.g. in-memory sources) still contribute
+        // bare-name constants, which is all lookups within the same file need.
+        let module_name = module_name(path).unwrap_or_default();
+
+        for assignment in root
+            .dfs()
+            .filter(|node| node.kind() == node_kinds::ASSIGNMENT)
+        {
+            let (Some(target), Some(value)) = (assignment.field("left"), assignment.field("right"))
+            else {
+                continue;
+            };
+            if target.kind() != node_kinds::IDENTIFIER {
+                continue;
+            }
+            let Some(value) = string_literal_value(&value)
+                .map(ResourceValue::Literal)
+                .or_else(|| ResourceValue::environment_read(&value.text()))
+            else {
+                continue;
+            };
+            let name = target.text().to_string();
+
+            // Only plain statements directly in the module or a class body count;
+            // assignments inside functions or conditionals aren't constants.
+            let Some(statement) = assignment.parent() else {
+                continue;
+            };
+            if statement.kind() != node_kinds::EXPRESSION_STATEMENT {
+                continue;
+            }
+            let Some(scope) = statement.parent() else {
+                continue;
+            };
+
+            if scope.kind() == node_kinds::MODULE {
+                self.module_constants
+                    .entry(module_name.clone())
+                    .or_default()
+                    .entry(name)
+                    .or_default()
+                    .insert(value);
+            } else if scope.kind() == node_kinds::BLOCK {
+                let Some(class_node) = scope
+                    .parent()
+                    .filter(|node| node.kind() == node_kinds::CLASS_DEFINITION)
+                else {
+                    continue;
+                };
+                let Some(class_name) = class_node.field("name") else {
+                    continue;
+                };
+                self.class_constants
+                    .entry(class_name.text().to_string())
+                    .or_default()
+                    .entry(name)
+                    .or_default()
+                    .insert(value);
+            }
+        }
+    }
+
+    /// Resolve an expression to the string constant it refers to
+    ///
+    /// - `NAME` -> module-level constant `NAME`
+    /// - `Owner.NAME` -> class constant `Owner.NAME`, else constant `NAME` of module `Owner`
+    /// - `Owner.NAME.value` -> same as `Owner.NAME` (enum members)
+    ///
+    /// Qualified names that match neither a class nor a module (e.g. `settings` imported
+    /// from a framework) fall back to a module-level constant with the same name.
+    /// Returns `None` when the expression is unknown or names conflicting values.
+    pub(crate) fn resolve(&self, expr: &str) -> Option<String> {
+        match self.resolve_value(expr)? {
+            ResourceValue::Literal(value) => Some(value),
+            ResourceValue::Environment(_) => None,
+        }
+    }
+
+    /// Resolve an expression to the string literal or environment variable read it
+    /// refers to, as [`Self::resolve`] does
+    fn resolve_value(&self, expr: &str) -> Option<ResourceValue> {
+        let expr = expr.trim();
+        let expr = expr
+            .strip_suffix(".value")
+            .filter(|owner| owner.contains('.'))
+            .unwrap_or(expr);
+
+        let mut segments = expr.rsplit('.').map(str::trim);
+        let name = segments.next()?;
+        let owner = segments.next();
+
+        let values = match owner {
+            None => self.module_constant_values(name),
+            Some(owner) => self
+                .class_constants
+                .get(owner)
+                .and_then(|constants| constants.get(name))
+                .or_else(|| {
+                    self.module_constants
+                        .get(owner)
+                        .and_then(|constants| constants.get(name))
+                })
+                .cloned()
+                .or_else(|| self.module_constant_values(name)),
+        }?;
+
+        if values.len() == 1 {
+            values.into_iter().next()
+        } else {
+            log::debug!("String constant '{expr}' has conflicting values {values:?}");
+            None
+        }
+    }
+
+    /// All values assigned to a module-level constant in any module
+    fn module_constant_values(&self, name: &str) -> Option<HashSet<ResourceValue>> {
+        let values: HashSet<ResourceValue> = self
+            .module_constants
+            .values()
+            .filter_map(|constants| constants.get(name))
+            .flatten()
+            .cloned()
+            .collect();
+        (!values.is_empty()).then_some(values)
+    }
+}
+
+impl ProjectConstants for StringConstants {
+    fn constant_value(&self, _path: &Path, expression: &str) -> Option<ResourceValue> {
+        self.resolve_value(expression)
+    }
+}
+
+/// Extract the value of a plain (non-interpolated, unprefixed) string literal node
+pub(crate) fn string_literal_value(
+    node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+) -> Option<String> {
+    if node.kind() != node_kinds::STRING {
+        return None;
+    }
+    let text = node.text();
+    let quote = text.chars().next()?;
+    if quote != '\'' && quote != '"' {
+        // f-strings, byte strings and other prefixed literals
+        return None;
+    }
+    let value = text.trim_matches(quote);
+    Some(value.to_string())
+}
+
+/// Python module name for a file: `settings.py` -> `settings`, `config/__init__.py` -> `config`
+fn module_name(path: &Path) -> Option<String> {
+    let stem = path.file_stem()?.to_str()?;
+    if stem == "__init__" {
+        return path
+            .parent()
+            .and_then(Path::file_name)
+            .and_then(std::ffi::OsStr::to_str)
+            .map(str::to_string);
+    }
+    Some(stem.to_string())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use rstest::rstest;
+    use std::path::PathBuf;
+
+    fn constants_from(files: &[(&str, &str)]) -> StringConstants {
+        let source_files: Vec<SourceFile> = files
+            .iter()
+            .map(|(path, content)| {
+                SourceFile::with_language(
+                    PathBuf::from(path),
+                    (*content).to_string(),
+                    crate::Language::Python,
+                )
+            })
+            .collect();
+        StringConstants::from_source_files(&source_files)
+    }
+
+    #[rstest]
+    #[case("SERVICE", Some("s3"))]
+    #[case("constants.SERVICE", Some("s3"))]
+    #[case("settings.QUEUE_SERVICE", Some("sqs"))]
+    #[case("app.settings.QUEUE_SERVICE", Some("sqs"))]
+    #[case("Service.DYNAMODB", Some("dynamodb"))]
+    #[case("Service.DYNAMODB.value", Some("dynamodb"))]
+    #[case("config.TOPIC_SERVICE", Some("sns"))]
+    #[case("UNKNOWN", None)]
+    #[case("LOCAL_ONLY", None)]
+    #[case("FORMATTED", None)]
+    fn test_resolve_constants(#[case] expr: &str, #[case] expected: Option<&str>) {
+        let constants = constants_from(&[
+            (
+                "app/constants.py",
+                r#"
+SERVICE = "s3"
+FORMATTED = f"{SERVICE}-backup"
+
+def build():
+    LOCAL_ONLY = "ec2"
+"#,
+            ),
+            ("app/settings.py", "QUEUE_SERVICE: str = 'sqs'\n"),
+            (
+                "app/enums.py",
+                r#"
+from enum import Enum
+
+class Service(str, Enum):
+    DYNAMODB = "dynamodb"
+"#,
+            ),
+            ("app/config/__init__.py", "TOPIC_SERVICE = 'sns'\n"),
+        ]);
+
+        assert_eq!(constants.resolve(expr).as_deref(), expected);
+    }
+
+    #[test]
+    fn test_conflicting_values_are_unresolved() {
+        let constants =
+            constants_from(&[("a.py", "SERVICE = 's3'\n"), ("b.py", "SERVICE = 'sqs'\n")]);
+
+        assert_eq!(constants.resolve("SERVICE"), None);
+        assert_eq!(constants.resolve("a.SERVICE").as_deref(), Some("s3"));
+        assert_eq!(constants.resolve("b.SERVICE").as_deref(), Some("sqs"));
+    }
+
+    #[test]
+    fn test_framework_settings_fall_back_to_constant_name() {
+        // `from django.conf import settings` exposes values from e.g. `project/settings/base.py`
+        let constants = constants_from(&[("project/settings/base.py", "S3_SERVICE = 's3'\n")]);
+
+        assert_eq!(
+            constants.resolve("settings.S3_SERVICE").as_deref(),
+            Some("s3")
+        );
+    }
+
+    #[test]
+    fn test_environment_constants_resolve_as_resource_values() {
+        let constants = constants_from(&[(
+            "app/config.py",
+            r#"
+import os
+
+TABLE_NAME = "orders"
+BUCKET_NAME = os.environ["BUCKET_NAME"]
+
+class Settings:
+    QUEUE = os.getenv("QUEUE_NAME", "jobs")
+"#,
+        )]);
+
+        assert_eq!(
+            constants.constant_value(Path::new("app/handler.py"), "config.TABLE_NAME"),
+            Some(ResourceValue::Literal("orders".to_string()))
+        );
+        assert_eq!(
+            constants.constant_value(Path::new("app/handler.py"), "BUCKET_NAME"),
+            Some(ResourceValue::Environment("BUCKET_NAME".to_string()))
+        );
+        assert_eq!(
+            constants.constant_value(Path::new("app/handler.py"), "Settings.QUEUE"),
+            Some(ResourceValue::Environment("QUEUE_NAME".to_string()))
+        );
+        // Environment reads aren't string values, e.g. of service names
+        assert_eq!(constants.resolve("BUCKET_NAME"), None);
+    }
+}
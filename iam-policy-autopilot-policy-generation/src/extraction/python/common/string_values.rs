@@ -0,0 +1,300 @@
+//! Static string value resolution for Python expressions
+//!
+//! Resolves an expression used as an argument (e.g. `client.get_paginator(op_name)`)
+//! to every string value it can statically take:
+//!
+//! - String literals: `'list_objects_v2'`
+//! - Loop variables over a literal sequence: `for op_name in ['list_users', 'list_roles']:`
+//! - Local assignments of literals: `op_name = 'list_users'`
+//! - Parameters with a literal default: `def scan(client, op_name='list_users'):`
+//! - Module-level, class-level and project constants (see [`StringConstants`])
+
+use ast_grep_language::Python;
+
+use crate::extraction::python::common::string_constants::{string_literal_value, StringConstants};
+use crate::extraction::python::node_kinds;
+use crate::extraction::AstWithSourceFile;
+
+/// Resolves expressions in one file to the string values they can take
+pub(crate) struct StringValueResolver<'a> {
+    file_constants: StringConstants,
+    project_constants: Option<&'a StringConstants>,
+}
+
+impl<'a> StringValueResolver<'a> {
+    /// Create a resolver for `ast`, optionally falling back to constants defined
+    /// elsewhere in the project
+    pub(crate) fn new(
+        ast: &AstWithSourceFile<Python>,
+        project_constants: Option<&'a StringConstants>,
+    ) -> Self {
+        let mut file_constants = StringConstants::default();
+        file_constants.collect(&ast.source_file.path, &ast.ast.root());
+        Self {
+            file_constants,
+            project_constants,
+        }
+    }
+
+    /// All string values `node` can statically take, in source order
+    ///
+    /// Returns an empty list when the value can't be derived (e.g. a parameter
+    /// without a literal default, or a call result).
+    pub(crate) fn resolve(
+        &self,
+        node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) -> Vec<String> {
+        if let Some(value) = string_literal_value(node) {
+            return vec![value];
+        }
+
+        let kind = node.kind();
+        if kind == node_kinds::IDENTIFIER {
+            if let Some(values) = local_binding_values(node, &node.text()) {
+                return values;
+            }
+        } else if kind != node_kinds::ATTRIBUTE {
+            return Vec::new();
+        }
+
+        let expr = node.text();
+        self.file_constants
+            .resolve(&expr)
+            .or_else(|| self.project_constants.and_then(|c| c.resolve(&expr)))
+            .into_iter()
+            .collect()
+    }
+}
+
+/// Values of the innermost local binding of `name` visible from `node`
+///
+/// Returns `None` when `name` isn't bound by an enclosing loop or function, so
+/// module-level constants apply; `Some` (possibly empty) when a local binding
+/// shadows them.
+fn local_binding_values(
+    node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    name: &str,
+) -> Option<Vec<String>> {
+    let mut current = node.parent();
+    while let Some(ancestor) = current {
+        let kind = ancestor.kind();
+        if kind == node_kinds::FOR_STATEMENT || kind == node_kinds::FOR_IN_CLAUSE {
+            if ancestor
+                .field("left")
+                .is_some_and(|left| left.text() == name)
+            {
+                return Some(
+                    ancestor
+                        .field("right")
+                        .map(|right| sequence_string_values(&right))
+                        .unwrap_or_default(),
+                );
+            }
+        } else if kind == node_kinds::FUNCTION_DEFINITION {
+            let assigned = local_assignment_values(&ancestor, name);
+            if !assigned.is_empty() {
+                return Some(assigned);
+            }
+            if let Some(values) = parameter_default_values(&ancestor, name) {
+                return Some(values);
+            }
+        }
+        current = ancestor.parent();
+    }
+    None
+}
+
+/// String elements of a literal list, tuple or set
+fn sequence_string_values(
+    node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+) -> Vec<String> {
+    let kind = node.kind();
+    if kind != node_kinds::LIST && kind != node_kinds::TUPLE && kind != node_kinds::SET {
+        return Vec::new();
+    }
+    let mut values = Vec::new();
+    for value in node
+        .children()
+        .filter_map(|child| string_literal_value(&child))
+    {
+        if !values.contains(&value) {
+            values.push(value);
+        }
+    }
+    values
+}
+
+/// Literal values assigned to `name` directly in `function` (not in nested functions)
+fn local_assignment_values(
+    function: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    name: &str,
+) -> Vec<String> {
+    let function_id = function.node_id();
+    let mut values = Vec::new();
+
+    for assignment in function
+        .dfs()
+        .filter(|node| node.kind() == node_kinds::ASSIGNMENT)
+    {
+        if !assignment
+            .field("left")
+            .is_some_and(|left| left.kind() == node_kinds::IDENTIFIER && left.text() == name)
+        {
+            continue;
+        }
+        let enclosing_function = assignment
+            .ancestors()
+            .find(|node| node.kind() == node_kinds::FUNCTION_DEFINITION);
+        if enclosing_function.map(|f| f.node_id()) != Some(function_id) {
+            continue;
+        }
+        if let Some(value) = assignment
+            .field("right")
+            .and_then(|right| string_literal_value(&right))
+        {
+            if !values.contains(&value) {
+                values.push(value);
+            }
+        }
+    }
+    values
+}
+
+/// Literal default of parameter `name` of `function`
+///
+/// Returns `None` if `function` has no such parameter, `Some(vec![])` if the
+/// parameter exists without a literal default.
+fn parameter_default_values(
+    function: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    name: &str,
+) -> Option<Vec<String>> {
+    let parameters = function.field("parameters")?;
+    for parameter in parameters.children() {
+        let kind = parameter.kind();
+        if kind == node_kinds::IDENTIFIER {
+            if parameter.text() == name {
+                return Some(Vec::new());
+            }
+        } else if kind == node_kinds::TYPED_PARAMETER {
+            let matches = parameter
+                .children()
+                .find(|child| child.kind() == node_kinds::IDENTIFIER)
+                .is_some_and(|identifier| identifier.text() == name);
+            if matches {
+                return Some(Vec::new());
+            }
+        } else if kind == node_kinds::DEFAULT_PARAMETER
+            || kind == node_kinds::TYPED_DEFAULT_PARAMETER
+        {
+            if parameter
+                .field("name")
+                .is_some_and(|param_name| param_name.text() == name)
+            {
+                return Some(
+                    parameter
+                        .field("value")
+                        .and_then(|value| string_literal_value(&value))
+                        .into_iter()
+                        .collect(),
+                );
+            }
+        }
+    }
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::SourceFile;
+    use ast_grep_core::tree_sitter::LanguageExt;
+    use rstest::rstest;
+    use std::path::PathBuf;
+
+    fn create_ast(source_code: &str) -> AstWithSourceFile<Python> {
+        let source_file = SourceFile::with_language(
+            PathBuf::new(),
+            source_code.to_string(),
+            crate::Language::Python,
+        );
+        let ast_grep = Python.ast_grep(&source_file.content);
+        AstWithSourceFile::new(ast_grep, source_file)
+    }
+
+    /// Resolve the first argument of the first `get_paginator` call
+    fn resolve_get_paginator_arg(
+        source_code: &str,
+        project_constants: Option<&StringConstants>,
+    ) -> Vec<String> {
+        let ast = create_ast(source_code);
+        let resolver = StringValueResolver::new(&ast, project_constants);
+        let root = ast.ast.root();
+        let call = root
+            .find("$CLIENT.get_paginator($OP)")
+            .expect("get_paginator call");
+        let op = call.get_env().get_match("OP").expect("argument").clone();
+        resolver.resolve(&op)
+    }
+
+    #[rstest]
+    #[case::literal("client.get_paginator('list_users')\n", &["list_users"])]
+    #[case::module_constant(
+        "OP = 'list_roles'\nclient.get_paginator(OP)\n",
+        &["list_roles"]
+    )]
+    #[case::loop_over_list(
+        "for op in ['list_users', 'list_roles', 'list_users']:\n    client.get_paginator(op)\n",
+        &["list_users", "list_roles"]
+    )]
+    #[case::loop_over_tuple(
+        "def scan(client):\n    for op in ('list_buckets',):\n        client.get_paginator(op)\n",
+        &["list_buckets"]
+    )]
+    #[case::comprehension(
+        "pages = [client.get_paginator(op) for op in ['scan', 'query']]\n",
+        &["scan", "query"]
+    )]
+    #[case::parameter_default(
+        "def scan(client, op='list_objects_v2'):\n    client.get_paginator(op)\n",
+        &["list_objects_v2"]
+    )]
+    #[case::typed_parameter_default(
+        "def scan(client, op: str = \"list_objects_v2\"):\n    client.get_paginator(op)\n",
+        &["list_objects_v2"]
+    )]
+    #[case::local_assignments(
+        "def scan(client, flag):\n    op = 'scan'\n    if flag:\n        op = 'query'\n    client.get_paginator(op)\n",
+        &["scan", "query"]
+    )]
+    #[case::parameter_shadows_constant(
+        "op = 'list_users'\ndef scan(client, op):\n    client.get_paginator(op)\n",
+        &[]
+    )]
+    #[case::parameter_without_default(
+        "def scan(client, op=None):\n    client.get_paginator(op)\n",
+        &[]
+    )]
+    #[case::call_result("client.get_paginator(pick())\n", &[])]
+    #[case::unknown_name("client.get_paginator(op)\n", &[])]
+    fn test_resolve_string_values(#[case] source_code: &str, #[case] expected: &[&str]) {
+        assert_eq!(resolve_get_paginator_arg(source_code, None), expected);
+    }
+
+    #[test]
+    fn test_resolve_project_constant() {
+        let project = [SourceFile::with_language(
+            PathBuf::from("app/operations.py"),
+            "LIST_USERS = 'list_users'\n".to_string(),
+            crate::Language::Python,
+        )];
+        let constants = StringConstants::from_source_files(&project);
+
+        assert_eq!(
+            resolve_get_paginator_arg(
+                "from app import operations\nclient.get_paginator(operations.LIST_USERS)\n",
+                Some(&constants),
+            ),
+            vec!["list_users".to_string()]
+        );
+    }
+}
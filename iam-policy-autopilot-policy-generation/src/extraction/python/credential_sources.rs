@@ -0,0 +1,431 @@
+//! Clients built from assumed-role or named profile credentials
+//!
+//! Code that switches roles through STS builds new clients from the returned
+//! credentials:
+//!
+//! ```python
+//! response = sts.assume_role(RoleArn="arn:aws:iam::123456789012:role/Reader", RoleSessionName="job")
+//! credentials = response["Credentials"]
+//! s3 = boto3.client(
+//!     "s3",
+//!     aws_access_key_id=credentials["AccessKeyId"],
+//!     aws_secret_access_key=credentials["SecretAccessKey"],
+//!     aws_session_token=credentials["SessionToken"],
+//! )
+//! s3.list_buckets()
+//! ```
+//!
+//! Calls on such clients need permissions on the assumed role rather than on
+//! the principal running the code, which only needs `sts:AssumeRole`. The same
+//! goes for sessions of a named profile of the shared config files, e.g.
+//! `boto3.Session(profile_name="reports")`, whose calls run as the principal the
+//! profile is configured with. This module tracks which variables hold an STS
+//! response, its credentials, or a session or client built from them, and the
+//! credentials each one belongs to.
+
+use std::collections::{HashMap, HashSet};
+
+use ast_grep_language::Python;
+
+use crate::extraction::python::common::{StringConstants, StringValueResolver};
+use crate::extraction::python::node_kinds;
+use crate::extraction::AstWithSourceFile;
+
+/// STS operations returning credentials for the role named by `RoleArn`
+const ASSUME_ROLE_METHODS: [&str; 3] = [
+    "assume_role",
+    "assume_role_with_web_identity",
+    "assume_role_with_saml",
+];
+
+/// Parameter naming the role to assume
+const ROLE_ARN_PARAMETER: &str = "RoleArn";
+
+/// Keyword argument naming the profile of `boto3.Session`
+const PROFILE_PARAMETER: &str = "profile_name";
+
+/// Keyword arguments passing explicit credentials to `boto3.client`,
+/// `boto3.resource` and `boto3.Session`
+const CREDENTIAL_KEYWORDS: [&str; 3] = [
+    "aws_access_key_id",
+    "aws_secret_access_key",
+    "aws_session_token",
+];
+
+/// Credentials a client is built from, other than those of the principal running the code
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) enum CredentialSource {
+    /// Credentials of the role with this ARN, returned by STS
+    AssumedRole(String),
+    /// Credentials of the named profile of the shared config files
+    Profile(String),
+}
+
+/// Variables bound to assumed-role or profile credentials in one Python module
+#[derive(Debug, Default)]
+pub(crate) struct CredentialSources {
+    /// (function name, variable name) -> credentials; `None` is module scope
+    bindings: HashMap<(Option<String>, String), CredentialSource>,
+    /// (function name, variable name) of other local assignments, which shadow
+    /// module-level bindings
+    shadowed: HashSet<(String, String)>,
+}
+
+impl CredentialSources {
+    /// Collect the variables of a module that hold assumed-role or profile credentials
+    ///
+    /// The role is the literal `RoleArn` and the profile the literal `profile_name`
+    /// (resolved through constants where possible), or the source text of their
+    /// expression otherwise.
+    pub(crate) fn collect(
+        ast: &AstWithSourceFile<Python>,
+        project_constants: Option<&StringConstants>,
+    ) -> Self {
+        let resolver = StringValueResolver::new(ast, project_constants);
+        let mut sources = Self::default();
+
+        for assignment in ast
+            .ast
+            .root()
+            .dfs()
+            .filter(|node| node.kind() == node_kinds::ASSIGNMENT)
+        {
+            let (Some(target), Some(value)) = (assignment.field("left"), assignment.field("right"))
+            else {
+                continue;
+            };
+            if target.kind() != node_kinds::IDENTIFIER {
+                continue;
+            }
+            let name = target.text().to_string();
+            let scope = enclosing_function(&assignment);
+            match sources.source_of(&value, scope.as_deref(), &resolver) {
+                Some(source) => {
+                    log::debug!("Variable '{name}' holds credentials of {source:?}");
+                    sources.bindings.insert((scope, name), source);
+                }
+                None => {
+                    if let Some(function) = scope {
+                        sources.shadowed.insert((function, name));
+                    }
+                }
+            }
+        }
+        sources
+    }
+
+    /// Credentials the variable `receiver` holds in `current_function`
+    pub(crate) fn source_for_receiver(
+        &self,
+        receiver: &str,
+        current_function: Option<&str>,
+    ) -> Option<&CredentialSource> {
+        if let Some(function) = current_function {
+            let (function, receiver) = (function.to_string(), receiver.to_string());
+            if let Some(source) = self
+                .bindings
+                .get(&(Some(function.clone()), receiver.clone()))
+            {
+                return Some(source);
+            }
+            if self.shadowed.contains(&(function, receiver)) {
+                return None;
+            }
+        }
+        self.bindings.get(&(None, receiver.to_string()))
+    }
+
+    /// Credentials an expression evaluates to
+    ///
+    /// Follows subscripts, attribute accesses and method calls on tracked
+    /// variables (`response["Credentials"]`, `credentials.get("AccessKeyId")`,
+    /// `session.client("s3")`), `assume_role` calls, calls passing tracked
+    /// credentials as `aws_*` keyword arguments, and sessions of a `profile_name`.
+    fn source_of(
+        &self,
+        node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+        scope: Option<&str>,
+        resolver: &StringValueResolver,
+    ) -> Option<CredentialSource> {
+        let kind = node.kind();
+        if kind == node_kinds::IDENTIFIER {
+            return self.source_for_receiver(&node.text(), scope).cloned();
+        }
+        if kind == node_kinds::SUBSCRIPT {
+            return self.source_of(&node.field("value")?, scope, resolver);
+        }
+        if kind == node_kinds::ATTRIBUTE {
+            return self.source_of(&node.field("object")?, scope, resolver);
+        }
+        if kind != node_kinds::CALL {
+            return None;
+        }
+
+        let function = node.field("function")?;
+        let keyword_arguments: Vec<_> = node
+            .field("arguments")
+            .map(|arguments| {
+                arguments
+                    .children()
+                    .filter(|argument| argument.kind() == node_kinds::KEYWORD_ARGUMENT)
+                    .collect()
+            })
+            .unwrap_or_default();
+        let keyword_value = |name: &str| {
+            keyword_arguments.iter().find_map(|argument| {
+                argument
+                    .field("name")
+                    .filter(|keyword| keyword.text() == name)
+                    .and_then(|_| argument.field("value"))
+            })
+        };
+
+        if function.kind() == node_kinds::ATTRIBUTE
+            && function
+                .field("attribute")
+                .is_some_and(|method| ASSUME_ROLE_METHODS.contains(&&*method.text()))
+        {
+            let role_arn = keyword_value(ROLE_ARN_PARAMETER)?;
+            let values = resolver.resolve(&role_arn);
+            return Some(CredentialSource::AssumedRole(match values.as_slice() {
+                [role] => role.clone(),
+                _ => role_arn.text().to_string(),
+            }));
+        }
+
+        if let Some(source) = CREDENTIAL_KEYWORDS.iter().find_map(|keyword| {
+            keyword_value(keyword).and_then(|value| self.source_of(&value, scope, resolver))
+        }) {
+            return Some(source);
+        }
+
+        if let Some(profile) = keyword_value(PROFILE_PARAMETER) {
+            let values = resolver.resolve(&profile);
+            return match values.as_slice() {
+                [name] => Some(CredentialSource::Profile(name.clone())),
+                _ if profile.text() == "None" => None,
+                _ => Some(CredentialSource::Profile(profile.text().to_string())),
+            };
+        }
+
+        if function.kind() == node_kinds::ATTRIBUTE {
+            return self.source_of(&function.field("object")?, scope, resolver);
+        }
+        None
+    }
+}
+
+/// Name of the innermost function enclosing `node`, `None` at module level
+fn enclosing_function(
+    node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+) -> Option<String> {
+    node.ancestors()
+        .find(|ancestor| ancestor.kind() == node_kinds::FUNCTION_DEFINITION)
+        .and_then(|function| function.field("name"))
+        .map(|name| name.text().to_string())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::SourceFile;
+    use ast_grep_core::tree_sitter::LanguageExt;
+    use rstest::rstest;
+    use std::path::PathBuf;
+
+    fn collect(source_code: &str) -> CredentialSources {
+        let source_file = SourceFile::with_language(
+            PathBuf::new(),
+            source_code.to_string(),
+            crate::Language::Python,
+        );
+        let ast_grep = Python.ast_grep(&source_file.content);
+        let ast = AstWithSourceFile::new(ast_grep, source_file);
+        CredentialSources::collect(&ast, None)
+    }
+
+    const READER_ROLE: &str = "arn:aws:iam::123456789012:role/Reader";
+
+    #[rstest]
+    #[case::client_from_credentials(
+        r#"
+response = sts.assume_role(RoleArn="arn:aws:iam::123456789012:role/Reader", RoleSessionName="job")
+credentials = response["Credentials"]
+s3 = boto3.client(
+    "s3",
+    aws_access_key_id=credentials["AccessKeyId"],
+    aws_secret_access_key=credentials["SecretAccessKey"],
+    aws_session_token=credentials["SessionToken"],
+)
+"#,
+        "s3",
+        None,
+        Some(READER_ROLE)
+    )]
+    #[case::session_client(
+        r#"
+def read(sts):
+    creds = sts.assume_role(RoleArn=READER, RoleSessionName="job")["Credentials"]
+    session = boto3.Session(
+        aws_access_key_id=creds.get("AccessKeyId"),
+        aws_secret_access_key=creds.get("SecretAccessKey"),
+        aws_session_token=creds.get("SessionToken"),
+    )
+    table = session.resource("dynamodb").Table("orders")
+"#,
+        "table",
+        Some("read"),
+        Some("READER")
+    )]
+    #[case::constant_role_arn(
+        r#"
+READER = "arn:aws:iam::123456789012:role/Reader"
+response = sts.assume_role(RoleArn=READER, RoleSessionName="job")
+s3 = boto3.client("s3", aws_access_key_id=response["Credentials"]["AccessKeyId"])
+"#,
+        "s3",
+        None,
+        Some(READER_ROLE)
+    )]
+    #[case::module_client_in_function(
+        r#"
+response = sts.assume_role(RoleArn="arn:aws:iam::123456789012:role/Reader", RoleSessionName="job")
+s3 = boto3.client("s3", aws_access_key_id=response["Credentials"]["AccessKeyId"])
+
+def handler(event, context):
+    s3.list_buckets()
+"#,
+        "s3",
+        Some("handler"),
+        Some(READER_ROLE)
+    )]
+    #[case::sts_client_stays_with_principal(
+        r#"
+sts = boto3.client("sts")
+response = sts.assume_role(RoleArn="arn:aws:iam::123456789012:role/Reader", RoleSessionName="job")
+"#,
+        "sts",
+        None,
+        None
+    )]
+    #[case::plain_client(
+        r#"
+response = sts.assume_role(RoleArn="arn:aws:iam::123456789012:role/Reader", RoleSessionName="job")
+s3 = boto3.client("s3")
+"#,
+        "s3",
+        None,
+        None
+    )]
+    #[case::local_client_shadows_module_client(
+        r#"
+response = sts.assume_role(RoleArn="arn:aws:iam::123456789012:role/Reader", RoleSessionName="job")
+s3 = boto3.client("s3", aws_access_key_id=response["Credentials"]["AccessKeyId"])
+
+def upload():
+    s3 = boto3.client("s3")
+"#,
+        "s3",
+        Some("upload"),
+        None
+    )]
+    #[case::other_function_scope(
+        r#"
+def assume(sts):
+    response = sts.assume_role(RoleArn="arn:aws:iam::123456789012:role/Reader", RoleSessionName="job")
+    s3 = boto3.client("s3", aws_access_key_id=response["Credentials"]["AccessKeyId"])
+
+def upload():
+    s3 = boto3.client("s3")
+"#,
+        "s3",
+        Some("upload"),
+        None
+    )]
+    fn test_assumed_role_for_receiver(
+        #[case] source_code: &str,
+        #[case] receiver: &str,
+        #[case] current_function: Option<&str>,
+        #[case] expected: Option<&str>,
+    ) {
+        let sources = collect(source_code);
+
+        assert_eq!(
+            sources.source_for_receiver(receiver, current_function),
+            expected
+                .map(|role| CredentialSource::AssumedRole(role.to_string()))
+                .as_ref()
+        );
+    }
+
+    #[rstest]
+    #[case::session_profile(
+        r#"
+session = boto3.Session(profile_name="reports")
+s3 = session.client("s3")
+"#,
+        "s3",
+        Some("reports")
+    )]
+    #[case::constant_profile(
+        r#"
+PROFILE = "reports"
+s3 = boto3.Session(profile_name=PROFILE).client("s3")
+"#,
+        "s3",
+        Some("reports")
+    )]
+    #[case::environment_profile(
+        r#"
+s3 = boto3.Session(profile_name=os.environ["AWS_PROFILE"]).client("s3")
+"#,
+        "s3",
+        Some(r#"os.environ["AWS_PROFILE"]"#)
+    )]
+    #[case::default_profile(
+        r#"
+s3 = boto3.Session(profile_name=None).client("s3")
+"#,
+        "s3",
+        None
+    )]
+    fn test_profile_for_receiver(
+        #[case] source_code: &str,
+        #[case] receiver: &str,
+        #[case] expected: Option<&str>,
+    ) {
+        let sources = collect(source_code);
+
+        assert_eq!(
+            sources.source_for_receiver(receiver, None),
+            expected
+                .map(|profile| CredentialSource::Profile(profile.to_string()))
+                .as_ref()
+        );
+    }
+
+    #[test]
+    fn test_role_chaining() {
+        let sources = collect(
+            r#"
+first = sts.assume_role(RoleArn="arn:aws:iam::123456789012:role/Hop", RoleSessionName="a")["Credentials"]
+hop_sts = boto3.client("sts", aws_access_key_id=first["AccessKeyId"])
+second = hop_sts.assume_role(RoleArn="arn:aws:iam::210987654321:role/Target", RoleSessionName="b")
+target_s3 = boto3.client("s3", aws_access_key_id=second["Credentials"]["AccessKeyId"])
+"#,
+        );
+
+        assert_eq!(
+            sources.source_for_receiver("hop_sts", None),
+            Some(&CredentialSource::AssumedRole(
+                "arn:aws:iam::123456789012:role/Hop".to_string()
+            ))
+        );
+        assert_eq!(
+            sources.source_for_receiver("target_s3", None),
+            Some(&CredentialSource::AssumedRole(
+                "arn:aws:iam::210987654321:role/Target".to_string()
+            ))
+        );
+    }
+}
@@ -2,38 +2,68 @@
 
 use crate::extraction::external_library_models::LibraryModelRegistry;
 use crate::extraction::extractor::{Extractor, ExtractorResult};
-use crate::extraction::python::common::ArgumentExtractor;
+use crate::extraction::python::common::string_constants::string_literal_value;
+use crate::extraction::python::common::{ArgumentExtractor, StringConstants};
+use crate::extraction::python::credential_sources::{CredentialSource, CredentialSources};
 use crate::extraction::python::disambiguation::MethodDisambiguator;
 use crate::extraction::python::library_call_extractor::LibraryCallExtractor;
+use crate::extraction::python::node_kinds;
 use crate::extraction::python::paginator_extractor::PaginatorExtractor;
 use crate::extraction::python::resource_direct_calls_extractor::ResourceDirectCallsExtractor;
-use crate::extraction::python::variable_type_tracker::VariableTypeTracker;
+use crate::extraction::python::s3_path_extractor::S3PathExtractor;
+use crate::extraction::python::variable_type_tracker::{ClientFactories, VariableTypeTracker};
 use crate::extraction::python::waiters_extractor::WaitersExtractor;
-use crate::extraction::{AstWithSourceFile, SdkMethodCall, SdkMethodCallMetadata};
+use crate::extraction::sdk_model::ServiceDiscovery;
+use crate::extraction::shared::bind_literal_resources;
+use crate::extraction::{AstWithSourceFile, Parameter, SdkMethodCall, SdkMethodCallMetadata};
 use crate::{Language, Location, ServiceModelIndex, SourceFile};
 use ast_grep_core::tree_sitter::LanguageExt;
 use ast_grep_language::Python;
 use async_trait::async_trait;
+use std::sync::{Arc, OnceLock};
+
+/// Built-in external library models of Python, loaded on the first Python analysis and
+/// kept for the next ones of the process
+static LIBRARY_MODEL_REGISTRY: OnceLock<Option<LibraryModelRegistry>> = OnceLock::new();
 
 pub(crate) struct PythonExtractor {
-    library_model_registry: Option<LibraryModelRegistry>,
+    library_model_registry: Option<&'static LibraryModelRegistry>,
+    string_constants: Arc<StringConstants>,
+    client_factories: Arc<ClientFactories>,
 }
 
 impl PythonExtractor {
     /// Create a new Python extractor, loading built-in external library models.
     pub(crate) fn new() -> Self {
-        let library_model_registry = match LibraryModelRegistry::load(Language::Python) {
-            Ok(registry) => Some(registry),
-            Err(e) => {
-                log::warn!("Failed to load external library model registry: {e:#}");
-                None
-            }
-        };
+        let library_model_registry = LIBRARY_MODEL_REGISTRY
+            .get_or_init(|| match LibraryModelRegistry::load(Language::Python) {
+                Ok(registry) => Some(registry),
+                Err(e) => {
+                    log::warn!("Failed to load external library model registry: {e:#}");
+                    None
+                }
+            })
+            .as_ref();
         Self {
             library_model_registry,
+            string_constants: Arc::default(),
+            client_factories: Arc::default(),
         }
     }
 
+    /// Collect string constants from all project sources, so service, paginator and
+    /// waiter names such as `boto3.client(SERVICE)` resolve even when `SERVICE` is
+    /// defined in another file, and the functions returning clients, so clients created by
+    /// a helper of another module are attributed to their service.
+    pub(crate) fn with_project_sources(mut self, source_files: &[SourceFile]) -> Self {
+        self.string_constants = Arc::new(StringConstants::from_source_files(source_files));
+        self.client_factories = Arc::new(ClientFactories::from_source_files(
+            source_files,
+            &self.string_constants,
+        ));
+        self
+    }
+
     /// Parse a single method call match into a SdkMethodCall
     ///
     /// Uses the VariableTypeTracker to resolve unknown receivers when possible.
@@ -43,6 +73,7 @@ impl PythonExtractor {
         source_file: &SourceFile,
         tracker: &VariableTypeTracker,
         current_function: Option<&str>,
+        current_class: Option<&str>,
     ) -> Option<SdkMethodCall> {
         let env = node_match.get_env();
 
@@ -60,46 +91,33 @@ impl PythonExtractor {
 
         // Extract arguments - get_multiple_matches returns Vec<Node> directly
         let args_nodes = env.get_multiple_matches("ARGS");
-        let arguments = ArgumentExtractor::extract_arguments(&args_nodes);
 
         // Try to resolve the receiver's service type using the tracker
         let possible_services = if let Some(ref receiver_name) = receiver {
-            // Use get_service_for_variable_in_context to respect Python's scoping rules (LEGB)
-            // This checks: 1) function-local variables, 2) parameters (first match), 3) module scope
-            if let Some(service) =
-                tracker.get_service_for_variable_in_context(receiver_name, current_function)
-            {
-                // Found a single service type (from variable assignment or first parameter match)
-                log::debug!(
-                    "Resolved receiver '{receiver_name}' to service '{service}' for method '{method_name}'"
-                );
-                vec![service.clone()]
-            } else if let Some(func_name) = current_function {
-                // Not found as a variable. Check if it's a parameter with multiple possible types.
-                // get_services_for_parameter returns ALL possible types, not just the first.
-                if let Some(services) = tracker.get_services_for_parameter(func_name, receiver_name)
-                {
-                    let service_vec: Vec<String> = services.iter().cloned().collect();
-                    log::debug!(
-                        "Resolved parameter '{receiver_name}' in function '{func_name}' to multiple services {service_vec:?} for method '{method_name}'"
-                    );
-                    service_vec
-                } else {
-                    log::debug!(
-                        "Could not resolve receiver '{receiver_name}' for method '{method_name}' - will use disambiguation"
-                    );
-                    Vec::new() // Will be determined later during service validation
-                }
-            } else {
-                log::debug!(
-                    "Could not resolve receiver '{receiver_name}' for method '{method_name}' - will use disambiguation"
-                );
-                Vec::new() // Will be determined later during service validation
-            }
+            Self::resolve_receiver_services(
+                tracker,
+                receiver_name,
+                &method_name,
+                current_function,
+                current_class,
+            )
         } else {
             Vec::new()
         };
 
+        // botocore's low-level dispatch names the operation in its first argument
+        let (method_name, arguments) =
+            match low_level_api_call(&method_name, &args_nodes, !possible_services.is_empty()) {
+                Some((operation_method, arguments)) => {
+                    log::debug!("Resolved low-level '{method_name}' call to '{operation_method}'");
+                    (operation_method, arguments)
+                }
+                None => (
+                    method_name.to_string(),
+                    ArgumentExtractor::extract_arguments(&args_nodes),
+                ),
+            };
+
         let metadata = SdkMethodCallMetadata::new(
             node_match.text().to_string(),
             Location::from_node(source_file.path.clone(), node_match.get_node()),
@@ -112,7 +130,7 @@ impl PythonExtractor {
         };
 
         let method_call = SdkMethodCall {
-            name: method_name.to_string(),
+            name: method_name,
             possible_services,
             metadata: Some(metadata),
         };
@@ -120,6 +138,232 @@ impl PythonExtractor {
         log::debug!("Found method call: {method_call:?}");
         Some(method_call)
     }
+
+    /// Resolve the services a method receiver may belong to
+    ///
+    /// Returns an empty list when the receiver is unknown, leaving the choice to
+    /// disambiguation.
+    fn resolve_receiver_services(
+        tracker: &VariableTypeTracker,
+        receiver_name: &str,
+        method_name: &str,
+        current_function: Option<&str>,
+        current_class: Option<&str>,
+    ) -> Vec<String> {
+        // Use get_service_for_variable_in_context to respect Python's scoping rules (LEGB)
+        // This checks: 1) function-local variables, 2) parameters (first match), 3) module scope
+        if let Some(service) =
+            tracker.get_service_for_variable_in_context(receiver_name, current_function)
+        {
+            // Found a single service type (from variable assignment or first parameter match)
+            log::debug!(
+                "Resolved receiver '{receiver_name}' to service '{service}' for method '{method_name}'"
+            );
+            vec![service.clone()]
+        } else if let Some(type_info) =
+            tracker.get_type_info_for_attribute_in_context(receiver_name, current_class)
+        {
+            // Attribute receiver such as `self.s3`, resolved against the enclosing class
+            log::debug!(
+                "Resolved attribute receiver '{receiver_name}' to service '{}' for method '{method_name}'",
+                type_info.service_name
+            );
+            vec![type_info.service_name.clone()]
+        } else if let Some(func_name) = current_function {
+            // Not found as a variable. Check if it's a parameter with multiple possible types.
+            // get_services_for_parameter returns ALL possible types, not just the first.
+            if let Some(services) = tracker.get_services_for_parameter(func_name, receiver_name) {
+                let service_vec: Vec<String> = services.iter().cloned().collect();
+                log::debug!(
+                    "Resolved parameter '{receiver_name}' in function '{func_name}' to multiple services {service_vec:?} for method '{method_name}'"
+                );
+                service_vec
+            } else {
+                log::debug!(
+                    "Could not resolve receiver '{receiver_name}' for method '{method_name}' - will use disambiguation"
+                );
+                Vec::new() // Will be determined later during service validation
+            }
+        } else {
+            log::debug!(
+                "Could not resolve receiver '{receiver_name}' for method '{method_name}' - will use disambiguation"
+            );
+            Vec::new() // Will be determined later during service validation
+        }
+    }
+
+    /// Parse a bound method passed as an argument, e.g. `retry_call(s3.get_object, ...)`,
+    /// `backoff.on_exception(...)(s3.get_object)` or `executor.submit(s3.upload_file, ...)`
+    ///
+    /// The wrapper supplies the arguments, so the call is only reported when the
+    /// receiver is a known client or resource.
+    fn parse_method_reference(
+        attribute: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+        source_file: &SourceFile,
+        tracker: &VariableTypeTracker,
+        current_function: Option<&str>,
+        current_class: Option<&str>,
+    ) -> Option<SdkMethodCall> {
+        let receiver = attribute.field("object")?.text().to_string();
+        let method_name = attribute.field("attribute")?.text().to_string();
+
+        let possible_services = Self::resolve_receiver_services(
+            tracker,
+            &receiver,
+            &method_name,
+            current_function,
+            current_class,
+        );
+        if possible_services.is_empty() {
+            return None;
+        }
+
+        // Arguments are unknown here; model them like `**kwargs` so required
+        // parameters aren't expected at the reference site.
+        let wrapper_call = attribute
+            .ancestors()
+            .find(|ancestor| ancestor.kind() == node_kinds::CALL)
+            .map_or_else(
+                || attribute.text().to_string(),
+                |call| call.text().to_string(),
+            );
+        let metadata = SdkMethodCallMetadata::new(
+            attribute.text().to_string(),
+            Location::from_node(source_file.path.clone(), attribute),
+        )
+        .with_parameters(vec![Parameter::DictionarySplat {
+            expression: wrapper_call,
+            position: 0,
+        }])
+        .with_receiver(receiver);
+
+        let method_call = SdkMethodCall {
+            name: method_name,
+            possible_services,
+            metadata: Some(metadata),
+        };
+        log::debug!("Found method reference: {method_call:?}");
+        Some(method_call)
+    }
+}
+
+/// Attribute a call on a client built from assumed-role or profile credentials to them
+fn with_credential_source(
+    mut call: SdkMethodCall,
+    credential_sources: &CredentialSources,
+    current_function: Option<&str>,
+) -> SdkMethodCall {
+    let source = call
+        .metadata
+        .as_ref()
+        .and_then(|metadata| metadata.receiver.as_deref())
+        .and_then(|receiver| credential_sources.source_for_receiver(receiver, current_function))
+        .cloned();
+    if let Some(source) = source {
+        log::debug!("Call '{}' runs under {source:?}", call.name);
+        call.metadata = call.metadata.take().map(|metadata| match source {
+            CredentialSource::AssumedRole(role) => metadata.with_assumed_role(role),
+            CredentialSource::Profile(profile) => metadata.with_credential_profile(profile),
+        });
+    }
+    call
+}
+
+/// Whether `node` is passed as an argument of a call: `f(node)` or `f(key=node)`
+fn is_call_argument(
+    node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+) -> bool {
+    let Some(parent) = node.parent() else {
+        return false;
+    };
+    if parent.kind() == node_kinds::ARGUMENT_LIST {
+        return true;
+    }
+    parent.kind() == node_kinds::KEYWORD_ARGUMENT
+        && parent
+            .field("value")
+            .is_some_and(|value| value.node_id() == node.node_id())
+        && parent
+            .parent()
+            .is_some_and(|list| list.kind() == node_kinds::ARGUMENT_LIST)
+}
+
+/// Resolve a botocore low-level operation call to the boto3 method it's equivalent to
+///
+/// Handles `client._make_api_call('GetObject', {'Bucket': b, 'Key': k})`, and
+/// `client.call('GetObject', ...)` when the receiver is a known client (`call` is
+/// too common a method name to trust otherwise). Keys of a literal parameter dict
+/// become keyword parameters; any other parameter expression is treated like
+/// `**params`, since its keys aren't known.
+fn low_level_api_call(
+    method_name: &str,
+    args_nodes: &[ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>],
+    receiver_is_client: bool,
+) -> Option<(String, Vec<Parameter>)> {
+    if method_name != "_make_api_call" && !(method_name == "call" && receiver_is_client) {
+        return None;
+    }
+
+    let mut positional = args_nodes.iter().filter(|arg| {
+        arg.is_named()
+            && arg.kind() != node_kinds::COMMENT
+            && arg.kind() != node_kinds::KEYWORD_ARGUMENT
+            && arg.kind() != node_kinds::DICTIONARY_SPLAT
+    });
+    let operation_name = string_literal_value(positional.next()?)?;
+    let operation_method =
+        ServiceDiscovery::operation_to_method_name(&operation_name, Language::Python);
+
+    let mut arguments = Vec::new();
+    if let Some(params) = positional.next() {
+        if params.kind() == node_kinds::DICTIONARY {
+            for pair in params.children() {
+                if pair.kind() == node_kinds::DICTIONARY_SPLAT {
+                    arguments.push(Parameter::DictionarySplat {
+                        expression: pair.text().to_string(),
+                        position: arguments.len(),
+                    });
+                    continue;
+                }
+                if pair.kind() != node_kinds::PAIR {
+                    continue;
+                }
+                let (Some(key), Some(value)) = (pair.field("key"), pair.field("value")) else {
+                    continue;
+                };
+                let Some(name) = string_literal_value(&key) else {
+                    continue;
+                };
+                arguments.push(Parameter::Keyword {
+                    name,
+                    value: ArgumentExtractor::extract_parameter_value(&value.text()),
+                    position: arguments.len(),
+                    type_annotation: None,
+                });
+            }
+        } else {
+            arguments.push(Parameter::DictionarySplat {
+                expression: params.text().to_string(),
+                position: arguments.len(),
+            });
+        }
+    }
+
+    // Keyword arguments passed directly, e.g. `client.call('GetObject', Bucket=b, Key=k)`
+    for arg in args_nodes {
+        if ArgumentExtractor::is_keyword_argument(arg) {
+            if let Some(param) = ArgumentExtractor::parse_keyword_argument(arg, arguments.len()) {
+                arguments.push(param);
+            }
+        } else if ArgumentExtractor::is_dictionary_splat(arg) {
+            arguments.push(Parameter::DictionarySplat {
+                expression: arg.text().to_string(),
+                position: arguments.len(),
+            });
+        }
+    }
+
+    Some((operation_method, arguments))
 }
 
 impl Default for PythonExtractor {
@@ -139,8 +383,11 @@ impl Extractor for PythonExtractor {
         let root = ast.ast.root();
 
         // Step 1: Track boto3 variable assignments
-        let mut tracker = VariableTypeTracker::new();
+        let mut tracker = VariableTypeTracker::new()
+            .with_project_constants(Arc::clone(&self.string_constants))
+            .with_client_factories(Arc::clone(&self.client_factories));
         tracker.track_boto3_assignments(&ast);
+        let credential_sources = CredentialSources::collect(&ast, Some(&*self.string_constants));
         log::debug!("Variable tracking complete");
 
         // Step 2: Build a map of line ranges to function names for context tracking
@@ -160,6 +407,19 @@ impl Extractor for PythonExtractor {
             }
         }
 
+        // Class line ranges, so `self.attr` receivers resolve against the enclosing class
+        let class_ranges: Vec<(String, std::ops::Range<usize>)> = root
+            .dfs()
+            .filter(|node| node.kind() == node_kinds::CLASS_DEFINITION)
+            .filter_map(|node| {
+                let class_name = node.field("name")?.text().to_string();
+                Some((
+                    class_name,
+                    node.start_pos().line()..node.end_pos().line() + 1,
+                ))
+            })
+            .collect();
+
         let mut method_calls = Vec::new();
 
         let pattern = "$OBJ.$METHOD($$$ARGS)";
@@ -180,10 +440,56 @@ impl Extractor for PythonExtractor {
                 log::debug!("Method call at line {call_line} is in function '{func}'");
             }
 
-            if let Some(call) =
-                self.parse_method_call(&node_match, source_file, &tracker, current_function)
-            {
-                method_calls.push(call);
+            let current_class = class_ranges
+                .iter()
+                .filter(|(_, range)| range.contains(&call_line))
+                .min_by_key(|(_, range)| range.end - range.start)
+                .map(|(name, _)| name.as_str());
+
+            if let Some(call) = self.parse_method_call(
+                &node_match,
+                source_file,
+                &tracker,
+                current_function,
+                current_class,
+            ) {
+                method_calls.push(with_credential_source(
+                    call,
+                    &credential_sources,
+                    current_function,
+                ));
+            }
+        }
+
+        // Bound methods handed to wrappers (retry helpers, executors, partials)
+        for attribute in root
+            .dfs()
+            .filter(|node| node.kind() == node_kinds::ATTRIBUTE && is_call_argument(node))
+        {
+            let line = attribute.start_pos().line();
+            let current_function = function_ranges
+                .iter()
+                .filter(|(_, range)| range.contains(&line))
+                .min_by_key(|(_, range)| range.end - range.start)
+                .map(|(name, _)| name.as_str());
+            let current_class = class_ranges
+                .iter()
+                .filter(|(_, range)| range.contains(&line))
+                .min_by_key(|(_, range)| range.end - range.start)
+                .map(|(name, _)| name.as_str());
+
+            if let Some(call) = Self::parse_method_reference(
+                &attribute,
+                source_file,
+                &tracker,
+                current_function,
+                current_class,
+            ) {
+                method_calls.push(with_credential_source(
+                    call,
+                    &credential_sources,
+                    current_function,
+                ));
             }
         }
 
@@ -197,8 +503,10 @@ impl Extractor for PythonExtractor {
     ) {
         let method_disambiguator = MethodDisambiguator::new(service_index);
         let resource_extractor = ResourceDirectCallsExtractor::new(service_index);
-        let waiters_extractor = WaitersExtractor::new(service_index);
-        let paginator_extractor = PaginatorExtractor::new(service_index);
+        let waiters_extractor =
+            WaitersExtractor::new(service_index).with_project_constants(&self.string_constants);
+        let paginator_extractor =
+            PaginatorExtractor::new(service_index).with_project_constants(&self.string_constants);
 
         for extractor_result in extractor_results.iter_mut() {
             match extractor_result {
@@ -220,13 +528,17 @@ impl Extractor for PythonExtractor {
                     // go through the disambiguator. The disambiguator's parameter-shape
                     // validation would reject them because library call arguments
                     // differ from the underlying SDK operation's input shape.
-                    let library_calls = if let Some(registry) = &self.library_model_registry {
+                    let library_calls = if let Some(registry) = self.library_model_registry {
                         let library_extractor = LibraryCallExtractor::new(registry);
                         library_extractor.extract_library_method_calls(ast)
                     } else {
                         Vec::new()
                     };
 
+                    // S3 access through s3fs/fsspec/pandas/pyarrow paths maps to
+                    // known S3 operations and bypasses the disambiguator as well
+                    let s3_path_calls = S3PathExtractor::extract_s3_path_calls(ast);
+
                     // Disambiguate only the direct SDK calls (not library-derived ones)
                     let filtered_and_mapped =
                         method_disambiguator.disambiguate_method_calls(method_calls.clone());
@@ -234,6 +546,9 @@ impl Extractor for PythonExtractor {
                     // Merge: disambiguated direct calls + library-derived calls
                     *method_calls = filtered_and_mapped;
                     method_calls.extend(library_calls);
+                    method_calls.extend(s3_path_calls);
+
+                    bind_literal_resources(method_calls, Some(&*self.string_constants));
                 }
                 ExtractorResult::Go(_, _, _) => {
                     // This shouldn't happen in Python extractor, but handle gracefully
@@ -284,6 +599,199 @@ mod tests {
         assert_eq!(result.method_calls_ref()[0].name, "get_object");
     }
 
+    #[tokio::test]
+    async fn test_self_attribute_receivers_resolve_to_service() {
+        let extractor = PythonExtractor::new();
+        let source_code = r#"
+import boto3
+
+class BaseStore:
+    def __init__(self):
+        self.s3 = boto3.client('s3')
+
+class ReportStore(BaseStore):
+    def save(self, body):
+        self.s3.put_object(Bucket='reports', Key='latest', Body=body)
+
+def unrelated(self):
+    self.s3.put_object(Bucket='reports', Key='latest', Body=b'')
+"#;
+        let source_file =
+            SourceFile::with_language(PathBuf::new(), source_code.to_string(), Language::Python);
+        let result = extractor.parse(&source_file).await;
+
+        let put_objects: Vec<_> = result
+            .method_calls_ref()
+            .iter()
+            .filter(|call| call.name == "put_object")
+            .collect();
+        assert_eq!(put_objects.len(), 2);
+        assert_eq!(put_objects[0].possible_services, vec!["s3".to_string()]);
+        // Outside any class, `self` has no known type
+        assert!(put_objects[1].possible_services.is_empty());
+    }
+
+    #[tokio::test]
+    async fn test_calls_with_assumed_role_credentials_carry_the_role() {
+        let extractor = PythonExtractor::new();
+        let source_code = r#"
+import boto3
+
+sts = boto3.client('sts')
+
+def copy_reports():
+    response = sts.assume_role(RoleArn='arn:aws:iam::123456789012:role/Reader', RoleSessionName='copy')
+    credentials = response['Credentials']
+    reader = boto3.client(
+        's3',
+        aws_access_key_id=credentials['AccessKeyId'],
+        aws_secret_access_key=credentials['SecretAccessKey'],
+        aws_session_token=credentials['SessionToken'],
+    )
+    body = reader.get_object(Bucket='reports', Key='latest')['Body'].read()
+    boto3.client('s3').put_object(Bucket='archive', Key='latest', Body=body)
+"#;
+        let source_file =
+            SourceFile::with_language(PathBuf::new(), source_code.to_string(), Language::Python);
+        let result = extractor.parse(&source_file).await;
+
+        let assumed_role_of = |name: &str| {
+            result
+                .method_calls_ref()
+                .iter()
+                .find(|call| call.name == name)
+                .and_then(|call| call.metadata.as_ref())
+                .expect("call with metadata")
+                .assumed_role
+                .clone()
+        };
+        assert_eq!(assumed_role_of("assume_role"), None);
+        assert_eq!(
+            assumed_role_of("get_object").as_deref(),
+            Some("arn:aws:iam::123456789012:role/Reader")
+        );
+        assert_eq!(assumed_role_of("put_object"), None);
+    }
+
+    #[tokio::test]
+    async fn test_calls_with_profile_credentials_carry_the_profile() {
+        let extractor = PythonExtractor::new();
+        let source_code = r#"
+import boto3
+
+reports = boto3.Session(profile_name='reports').client('s3')
+reports.get_object(Bucket='reports', Key='latest')
+boto3.client('s3').put_object(Bucket='archive', Key='latest', Body=b'')
+"#;
+        let source_file =
+            SourceFile::with_language(PathBuf::new(), source_code.to_string(), Language::Python);
+        let result = extractor.parse(&source_file).await;
+
+        let metadata_of = |name: &str| {
+            result
+                .method_calls_ref()
+                .iter()
+                .find(|call| call.name == name)
+                .and_then(|call| call.metadata.clone())
+                .expect("call with metadata")
+        };
+        let get_object = metadata_of("get_object");
+        assert_eq!(get_object.credential_profile.as_deref(), Some("reports"));
+        assert_eq!(get_object.assumed_role, None);
+        assert_eq!(metadata_of("put_object").credential_profile, None);
+    }
+
+    #[tokio::test]
+    async fn test_botocore_low_level_calls_resolve_to_operations() {
+        let extractor = PythonExtractor::new();
+        let source_code = r#"
+import botocore.session
+
+s3 = botocore.session.get_session().create_client('s3')
+s3._make_api_call('GetObject', {'Bucket': 'reports', 'Key': key})
+s3.call('ListObjectsV2', params)
+queue.call('SendMessage', {'QueueUrl': url})
+"#;
+        let source_file =
+            SourceFile::with_language(PathBuf::new(), source_code.to_string(), Language::Python);
+        let result = extractor.parse(&source_file).await;
+        let calls = result.method_calls_ref();
+
+        let get_object = calls
+            .iter()
+            .find(|call| call.name == "get_object")
+            .expect("_make_api_call should resolve to get_object");
+        assert_eq!(get_object.possible_services, vec!["s3".to_string()]);
+        let parameter_names: Vec<_> = get_object
+            .metadata
+            .as_ref()
+            .expect("metadata")
+            .parameters
+            .iter()
+            .filter_map(|param| match param {
+                Parameter::Keyword { name, .. } => Some(name.as_str()),
+                _ => None,
+            })
+            .collect();
+        assert_eq!(parameter_names, vec!["Bucket", "Key"]);
+
+        let list_objects = calls
+            .iter()
+            .find(|call| call.name == "list_objects_v2")
+            .expect("call on a known client should resolve to list_objects_v2");
+        assert!(list_objects
+            .metadata
+            .as_ref()
+            .expect("metadata")
+            .has_dictionary_unpacking());
+
+        // `call` on an unknown receiver is left as-is
+        assert!(calls.iter().all(|call| call.name != "send_message"));
+        assert!(calls.iter().any(|call| call.name == "call"));
+    }
+
+    #[tokio::test]
+    async fn test_bound_methods_passed_to_wrappers() {
+        let extractor = PythonExtractor::new();
+        let source_code = r#"
+import backoff
+import boto3
+from tenacity import Retrying
+
+s3 = boto3.client('s3')
+
+def fetch(key):
+    return backoff.on_exception(backoff.expo, Exception)(s3.get_object)(Bucket='b', Key=key)
+
+def upload(executor, path):
+    executor.submit(s3.upload_file, path, 'b', 'k')
+    Retrying()(fn=s3.delete_object, Bucket='b', Key='k')
+    run_with_retry(unknown.get_object)
+"#;
+        let source_file =
+            SourceFile::with_language(PathBuf::new(), source_code.to_string(), Language::Python);
+        let result = extractor.parse(&source_file).await;
+
+        let references: Vec<_> = result
+            .method_calls_ref()
+            .iter()
+            .filter(|call| {
+                call.metadata
+                    .as_ref()
+                    .is_some_and(|metadata| metadata.has_dictionary_unpacking())
+            })
+            .map(|call| (call.name.as_str(), call.possible_services.clone()))
+            .collect();
+        assert_eq!(
+            references,
+            vec![
+                ("get_object", vec!["s3".to_string()]),
+                ("upload_file", vec!["s3".to_string()]),
+                ("delete_object", vec!["s3".to_string()]),
+            ]
+        );
+    }
+
     #[tokio::test]
     async fn test_method_call_with_comments() {
         let extractor = PythonExtractor::new();
@@ -245,6 +245,30 @@ fn resolve_aliased_import_plain(
     }
 }
 
+/// Resolve a module expression such as `params` or `wr.s3` to its canonical
+/// dotted path.
+///
+/// Exact import names are looked up directly. Otherwise the first segment is
+/// resolved to the module it binds and the remaining attributes are appended.
+/// `import X.Y` binds `X` itself (recorded as `"X" -> "X.Y"`), so `X.Y` resolves
+/// to `X.Y` rather than `X.Y.Y`.
+fn resolve_module_path(
+    object_text: &str,
+    resolved_imports: &HashMap<String, String>,
+) -> Option<String> {
+    if let Some(canonical_path) = resolved_imports.get(object_text) {
+        return Some(canonical_path.clone());
+    }
+    let (first, rest) = object_text.split_once('.')?;
+    let canonical_path = resolved_imports.get(first)?;
+    let bound_module = if canonical_path.starts_with(&format!("{first}.")) {
+        first
+    } else {
+        canonical_path.as_str()
+    };
+    Some(format!("{bound_module}.{rest}"))
+}
+
 // ─── LibraryCallExtractor ───────────────────────────────────────────
 
 /// Extracts external library calls from Python source code and maps them to
@@ -412,14 +436,16 @@ impl<'a> LibraryCallExtractor<'a> {
     }
 
     /// Check if the object resolves to an imported module matching the pattern's module path.
+    ///
+    /// The object may also be an attribute path below an imported package, e.g.
+    /// `wr.s3` after `import awswrangler as wr` resolves to `awswrangler.s3`.
     fn matches_imported_module(
         &self,
         object_text: &str,
         resolved_imports: &HashMap<String, String>,
         pattern_module_path: &str,
     ) -> bool {
-        resolved_imports
-            .get(object_text)
+        resolve_module_path(object_text, resolved_imports)
             .is_some_and(|canonical_path| canonical_path == pattern_module_path)
     }
 
@@ -642,11 +668,64 @@ mod tests {
         );
     }
 
+    #[rstest]
+    #[case::s3_read_parquet(
+        "import awswrangler as wr\ndf = wr.s3.read_parquet(path=\"s3://bucket/prefix/\")\n",
+        &[("s3", "list_objects_v2"), ("s3", "head_object"), ("s3", "get_object")],
+    )]
+    #[case::athena_read_sql_query(
+        "import awswrangler as wr\ndf = wr.athena.read_sql_query(\"SELECT 1\", database=\"db\")\n",
+        &[
+            ("athena", "start_query_execution"),
+            ("athena", "get_query_execution"),
+            ("athena", "get_query_results"),
+            ("athena", "stop_query_execution"),
+            ("glue", "get_database"),
+            ("glue", "get_table"),
+            ("glue", "get_partitions"),
+            ("s3", "get_bucket_location"),
+            ("s3", "list_objects_v2"),
+            ("s3", "get_object"),
+            ("s3", "put_object"),
+        ],
+    )]
+    #[case::catalog_unaliased(
+        "import awswrangler\nawswrangler.catalog.create_parquet_table(database=\"db\", table=\"t\", path=\"s3://b/t/\", columns_types={})\n",
+        &[("glue", "get_table"), ("glue", "create_table"), ("glue", "update_table")],
+    )]
+    #[case::submodule_import(
+        "from awswrangler import catalog\ncatalog.delete_table_if_exists(database=\"db\", table=\"t\")\n",
+        &[("glue", "delete_table")],
+    )]
+    #[case::dotted_import(
+        "import awswrangler.s3\nawswrangler.s3.to_parquet(df, path=\"s3://bucket/key.parquet\")\n",
+        &[
+            ("s3", "put_object"),
+            ("s3", "create_multipart_upload"),
+            ("s3", "upload_part"),
+            ("s3", "complete_multipart_upload"),
+            ("s3", "abort_multipart_upload"),
+        ],
+    )]
+    fn test_awswrangler_call_extraction(#[case] source: &str, #[case] expected: &[(&str, &str)]) {
+        let ast = create_test_ast(source);
+        let registry = load_python_registry();
+        let extractor = LibraryCallExtractor::new(&registry);
+        let results = extractor.extract_library_method_calls(&ast);
+
+        let actual: Vec<(&str, &str)> = results
+            .iter()
+            .map(|call| (call.possible_services[0].as_str(), call.name.as_str()))
+            .collect();
+        assert_eq!(actual, expected);
+    }
+
     #[rstest]
     #[case::no_matching_imports("import os\nimport json\nresult = os.path.join(\"/a\", \"b\")\n")]
     #[case::matching_import_no_matching_call(
         "from aws_lambda_powertools.utilities import parameters\nx = parameters.some_other_function()\n",
     )]
+    #[case::unrelated_s3_attribute("import awswrangler as wr\nwr.config.s3_endpoint_url = \"http://localhost\"\nfs.s3.read_parquet(\"x\")\n")]
     fn test_no_library_calls_detected(#[case] source: &str) {
         let ast = create_test_ast(source);
         let registry = load_python_registry();
@@ -3,11 +3,14 @@ pub(crate) mod extractor;
 
 pub(crate) mod boto3_resources_model;
 pub(crate) mod common;
+pub(crate) mod credential_sources;
 pub(crate) mod disambiguation;
 pub(crate) mod library_call_extractor;
 pub(crate) mod node_kinds;
 pub(crate) mod paginator_extractor;
 pub(crate) mod resource_direct_calls_extractor;
+pub(crate) mod s3_path_extractor;
+pub(crate) mod test_doubles;
 pub(crate) mod variable_type_tracker;
 pub(crate) mod waiters_extractor;
 
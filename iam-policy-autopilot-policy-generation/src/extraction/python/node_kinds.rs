@@ -39,3 +39,82 @@ pub(crate) const CALL: &str = "call";
 
 /// An attribute access node (e.g., `obj.attr`)
 pub(crate) const ATTRIBUTE: &str = "attribute";
+
+/// An assignment (e.g., `x = 1`, `x: int = 1`)
+pub(crate) const ASSIGNMENT: &str = "assignment";
+
+/// A statement wrapping an expression or assignment
+pub(crate) const EXPRESSION_STATEMENT: &str = "expression_statement";
+
+/// The root node of a Python file
+pub(crate) const MODULE: &str = "module";
+
+/// A string literal (including f-strings and prefixed strings)
+pub(crate) const STRING: &str = "string";
+
+/// A `for` loop statement (e.g., `for x in items: ...`)
+pub(crate) const FOR_STATEMENT: &str = "for_statement";
+
+/// A `for ... in ...` clause of a comprehension (e.g., `[f(x) for x in items]`)
+pub(crate) const FOR_IN_CLAUSE: &str = "for_in_clause";
+
+/// A list literal (e.g., `[a, b]`)
+pub(crate) const LIST: &str = "list";
+
+/// A tuple literal (e.g., `(a, b)`)
+pub(crate) const TUPLE: &str = "tuple";
+
+/// A set literal (e.g., `{a, b}`)
+pub(crate) const SET: &str = "set";
+
+/// A parameter with a type annotation (e.g., `name: str`)
+pub(crate) const TYPED_PARAMETER: &str = "typed_parameter";
+
+/// A parameter with a default value (e.g., `name='x'`)
+pub(crate) const DEFAULT_PARAMETER: &str = "default_parameter";
+
+/// A parameter with a type annotation and default value (e.g., `name: str = 'x'`)
+pub(crate) const TYPED_DEFAULT_PARAMETER: &str = "typed_default_parameter";
+
+/// A dictionary literal (e.g., `{'Bucket': name}`)
+pub(crate) const DICTIONARY: &str = "dictionary";
+
+/// A key-value pair in a dictionary literal (e.g., `'Bucket': name`)
+pub(crate) const PAIR: &str = "pair";
+
+/// An `import module` statement
+pub(crate) const IMPORT_STATEMENT: &str = "import_statement";
+
+/// A `from module import name` statement
+pub(crate) const IMPORT_FROM_STATEMENT: &str = "import_from_statement";
+
+/// A dotted name (e.g., `botocore.session` in an import)
+pub(crate) const DOTTED_NAME: &str = "dotted_name";
+
+/// An import with an alias (e.g., `Session as BotocoreSession`)
+pub(crate) const ALIASED_IMPORT: &str = "aliased_import";
+
+/// A decorator line of a decorated definition (e.g., `@contextmanager`)
+pub(crate) const DECORATOR: &str = "decorator";
+
+/// A `yield` expression
+pub(crate) const YIELD: &str = "yield";
+pub(crate) const RETURN_STATEMENT: &str = "return_statement";
+
+/// One item of a `with` statement (e.g., `open(path) as f`)
+pub(crate) const WITH_ITEM: &str = "with_item";
+
+/// An `expression as target` pattern (e.g., in a `with` item)
+pub(crate) const AS_PATTERN: &str = "as_pattern";
+
+/// The parenthesized arguments of a call (e.g., `(a, key=b)`)
+pub(crate) const ARGUMENT_LIST: &str = "argument_list";
+
+/// The `*` of a `from module import *` statement
+pub(crate) const WILDCARD_IMPORT: &str = "wildcard_import";
+
+/// A list splat/unpacking operator (e.g., `*args`)
+pub(crate) const LIST_SPLAT: &str = "list_splat";
+
+/// A subscript expression (e.g., `response["Credentials"]`)
+pub(crate) const SUBSCRIPT: &str = "subscript";
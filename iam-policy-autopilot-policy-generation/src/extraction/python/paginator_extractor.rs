@@ -6,7 +6,10 @@
 
 use std::path::Path;
 
-use crate::extraction::python::common::{ArgumentExtractor, ParameterFilter};
+use crate::extraction::python::common::{
+    ArgumentExtractor, ParameterFilter, StringConstants, StringValueResolver,
+};
+use crate::extraction::python::node_kinds;
 use crate::extraction::shared::{
     ChainedPaginatorCallInfo, PaginatorCallInfo, PaginatorCallPattern, PaginatorCreationInfo,
 };
@@ -29,14 +32,27 @@ use ast_grep_language::Python;
 /// - Arguments from paginate call (filtered to remove pagination-specific params)
 /// - Position information from the paginate call (most specific)
 /// - Client receiver from get_paginator call
+///
+/// When the operation name is a variable (`client.get_paginator(op_name)`), every
+/// statically derivable value produces its own synthetic call.
 pub(crate) struct PaginatorExtractor<'a> {
     service_index: &'a ServiceModelIndex,
+    project_constants: Option<&'a StringConstants>,
 }
 
 impl<'a> PaginatorExtractor<'a> {
     /// Create a new paginator extractor with a service model index
     pub(crate) fn new(service_index: &'a ServiceModelIndex) -> Self {
-        Self { service_index }
+        Self {
+            service_index,
+            project_constants: None,
+        }
+    }
+
+    /// Resolve operation names that reference constants defined elsewhere in the project
+    pub(crate) fn with_project_constants(mut self, constants: &'a StringConstants) -> Self {
+        self.project_constants = Some(constants);
+        self
     }
 
     /// Extract paginate method calls from the AST
@@ -53,22 +69,24 @@ impl<'a> PaginatorExtractor<'a> {
         &self,
         ast: &AstWithSourceFile<Python>,
     ) -> Vec<SdkMethodCall> {
+        let resolver = StringValueResolver::new(ast, self.project_constants);
+
         // Step 1: Find all get_paginator calls
-        let paginators = self.find_get_paginator_calls(ast);
+        let paginators = self.find_get_paginator_calls(ast, &resolver);
 
         // Step 2: Find all paginate calls
         let paginate_calls = self.find_paginate_calls(ast);
 
         // Step 3: Find all chained paginator calls (client.get_paginator().paginate())
-        let chained_calls = self.find_chained_paginator_calls(ast);
+        let chained_calls = self.find_chained_paginator_calls(ast, &resolver);
 
         // Step 4: Match paginate calls to their paginators and create synthetic method calls
         let mut synthetic_calls = Vec::new();
         let mut matched_paginator_indices = std::collections::HashSet::new();
 
         for paginate_call in &paginate_calls {
-            if let Some((paginator, paginator_idx)) =
-                self.match_paginate_to_paginator_with_index(paginate_call, &paginators)
+            for (paginator, paginator_idx) in
+                self.match_paginate_to_paginators(paginate_call, &paginators)
             {
                 let pattern = PaginatorCallPattern::Matched {
                     creation: paginator,
@@ -100,6 +118,7 @@ impl<'a> PaginatorExtractor<'a> {
     fn find_get_paginator_calls(
         &self,
         ast: &AstWithSourceFile<Python>,
+        resolver: &StringValueResolver,
     ) -> Vec<PaginatorCreationInfo> {
         let root = ast.ast.root();
         let mut paginators = Vec::new();
@@ -108,10 +127,10 @@ impl<'a> PaginatorExtractor<'a> {
         let get_paginator_pattern = "$PAGINATOR = $CLIENT.get_paginator($OPERATION $$$ARGS)";
 
         for node_match in root.find_all(get_paginator_pattern) {
-            if let Some(paginator_info) =
-                self.parse_get_paginator_call(&node_match, &ast.source_file.path)
+            if let Some(paginator_infos) =
+                self.parse_get_paginator_call(&node_match, &ast.source_file.path, resolver)
             {
-                paginators.push(paginator_info);
+                paginators.extend(paginator_infos);
             }
         }
 
@@ -141,6 +160,7 @@ impl<'a> PaginatorExtractor<'a> {
     fn find_chained_paginator_calls(
         &self,
         ast: &AstWithSourceFile<Python>,
+        resolver: &StringValueResolver,
     ) -> Vec<ChainedPaginatorCallInfo> {
         let root = ast.ast.root();
         let mut chained_calls = Vec::new();
@@ -150,22 +170,23 @@ impl<'a> PaginatorExtractor<'a> {
             "$CLIENT.get_paginator($OPERATION $$$GET_ARGS).paginate($$$PAGINATE_ARGS)";
 
         for node_match in root.find_all(chained_pattern) {
-            if let Some(chained_info) =
-                self.parse_chained_paginator_call(&node_match, &ast.source_file.path)
+            if let Some(chained_infos) =
+                self.parse_chained_paginator_call(&node_match, &ast.source_file.path, resolver)
             {
-                chained_calls.push(chained_info);
+                chained_calls.extend(chained_infos);
             }
         }
 
         chained_calls
     }
 
-    /// Parse a get_paginator call into PaginatorCreationInfo
+    /// Parse a get_paginator call into one PaginatorCreationInfo per possible operation name
     fn parse_get_paginator_call(
         &self,
         node_match: &ast_grep_core::NodeMatch<ast_grep_core::tree_sitter::StrDoc<Python>>,
         file_path: &Path,
-    ) -> Option<PaginatorCreationInfo> {
+        resolver: &StringValueResolver,
+    ) -> Option<Vec<PaginatorCreationInfo>> {
         let env = node_match.get_env();
 
         // Extract paginator variable name
@@ -174,23 +195,26 @@ impl<'a> PaginatorExtractor<'a> {
         // Extract client receiver name
         let client_receiver = env.get_match("CLIENT")?.text().to_string();
 
-        // Extract operation name (remove quotes)
-        let operation_node = env.get_match("OPERATION")?;
-        let operation_text = operation_node.text();
-        let operation_name = self.extract_quoted_string(&operation_text)?;
+        // Extract operation names (literal, or all values of a variable)
+        let operation_names = self.resolve_operation_names(env.get_match("OPERATION")?, resolver);
 
         let node = node_match.get_node();
         let location = Location::from_node(file_path.to_path_buf(), node);
         let expr = node_match.text().to_string();
 
-        Some(PaginatorCreationInfo {
-            variable_name,
-            operation_name,
-            client_receiver,
-            location,
-            creation_arguments: Vec::new(), // Python doesn't have creation arguments
-            expr,
-        })
+        Some(
+            operation_names
+                .into_iter()
+                .map(|operation_name| PaginatorCreationInfo {
+                    variable_name: variable_name.clone(),
+                    operation_name,
+                    client_receiver: client_receiver.clone(),
+                    location: location.clone(),
+                    creation_arguments: Vec::new(), // Python doesn't have creation arguments
+                    expr: expr.clone(),
+                })
+                .collect(),
+        )
     }
 
     /// Parse a paginate call into PaginatorCallInfo
@@ -217,34 +241,67 @@ impl<'a> PaginatorExtractor<'a> {
         })
     }
 
-    /// Parse a chained paginator call into ChainedPaginatorCallInfo
+    /// Parse a chained paginator call into one ChainedPaginatorCallInfo per possible operation name
     fn parse_chained_paginator_call(
         &self,
         node_match: &ast_grep_core::NodeMatch<ast_grep_core::tree_sitter::StrDoc<Python>>,
         file_path: &Path,
-    ) -> Option<ChainedPaginatorCallInfo> {
+        resolver: &StringValueResolver,
+    ) -> Option<Vec<ChainedPaginatorCallInfo>> {
         let env = node_match.get_env();
 
         // Extract client receiver name
         let client_receiver = env.get_match("CLIENT")?.text().to_string();
 
-        // Extract operation name (remove quotes)
-        let operation_node = env.get_match("OPERATION")?;
-        let operation_text = operation_node.text();
-        let operation_name = self.extract_quoted_string(&operation_text)?;
+        // Extract operation names (literal, or all values of a variable)
+        let operation_names = self.resolve_operation_names(env.get_match("OPERATION")?, resolver);
 
         // Extract paginate arguments and filter out pagination-specific ones
         let paginate_args_nodes = env.get_multiple_matches("PAGINATE_ARGS");
         let all_arguments = self.extract_arguments(&paginate_args_nodes);
         let filtered_arguments = self.filter_pagination_parameters(all_arguments);
 
-        Some(ChainedPaginatorCallInfo {
-            client_receiver,
-            operation_name,
-            arguments: filtered_arguments,
-            expr: node_match.text().to_string(),
-            location: Location::from_node(file_path.to_path_buf(), node_match.get_node()),
-        })
+        let expr = node_match.text().to_string();
+        let location = Location::from_node(file_path.to_path_buf(), node_match.get_node());
+
+        Some(
+            operation_names
+                .into_iter()
+                .map(|operation_name| ChainedPaginatorCallInfo {
+                    client_receiver: client_receiver.clone(),
+                    operation_name,
+                    arguments: filtered_arguments.clone(),
+                    expr: expr.clone(),
+                    location: location.clone(),
+                })
+                .collect(),
+        )
+    }
+
+    /// Resolve the operation name argument of get_paginator
+    ///
+    /// String literals are used as-is; variables expand to every value they can
+    /// statically take (constants, loop variables over literal lists, parameter defaults).
+    fn resolve_operation_names(
+        &self,
+        operation_node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+        resolver: &StringValueResolver,
+    ) -> Vec<String> {
+        if operation_node.kind() == node_kinds::STRING {
+            return self
+                .extract_quoted_string(&operation_node.text())
+                .into_iter()
+                .collect();
+        }
+
+        let operation_names = resolver.resolve(operation_node);
+        if operation_names.is_empty() {
+            log::debug!(
+                "Could not statically resolve paginator operation name '{}'",
+                operation_node.text()
+            );
+        }
+        operation_names
     }
 
     /// Extract arguments from argument nodes
@@ -255,34 +312,36 @@ impl<'a> PaginatorExtractor<'a> {
         ArgumentExtractor::extract_arguments(args_nodes)
     }
 
-    /// Match a paginate call to its corresponding get_paginator call, returning both paginator and index
-    fn match_paginate_to_paginator_with_index<'b>(
+    /// Match a paginate call to its corresponding get_paginator call, returning paginators and indices
+    ///
+    /// A get_paginator call with a variable operation name yields several paginators at the
+    /// same location; the paginate call matches all of them.
+    fn match_paginate_to_paginators<'b>(
         &self,
         paginate_call: &PaginatorCallInfo,
         paginators: &'b [PaginatorCreationInfo],
-    ) -> Option<(&'b PaginatorCreationInfo, usize)> {
+    ) -> Vec<(&'b PaginatorCreationInfo, usize)> {
         // Find paginator with matching variable name
         // Conservative approach: use the closest preceding paginator with the same name
-        let mut best_match = None;
-        let mut best_distance = usize::MAX;
-        let mut best_idx = 0;
+        let candidates = || {
+            paginators.iter().enumerate().filter(|(_, paginator)| {
+                paginator.variable_name == paginate_call.paginator_var
+                    // Only consider paginators that come before the paginate call
+                    && paginator.location.start_line() < paginate_call.location.start_line()
+            })
+        };
 
-        for (idx, paginator) in paginators.iter().enumerate() {
-            if paginator.variable_name == paginate_call.paginator_var {
-                // Only consider paginators that come before the paginate call
-                if paginator.location.start_line() < paginate_call.location.start_line() {
-                    let distance =
-                        paginate_call.location.start_line() - paginator.location.start_line();
-                    if distance < best_distance {
-                        best_distance = distance;
-                        best_match = Some(paginator);
-                        best_idx = idx;
-                    }
-                }
-            }
-        }
+        let Some(closest_line) = candidates()
+            .map(|(_, paginator)| paginator.location.start_line())
+            .max()
+        else {
+            return Vec::new();
+        };
 
-        best_match.map(|p| (p, best_idx))
+        candidates()
+            .filter(|(_, paginator)| paginator.location.start_line() == closest_line)
+            .map(|(idx, paginator)| (paginator, idx))
+            .collect()
     }
 
     /// Filter out pagination-specific parameters
@@ -618,4 +677,71 @@ page_iterator = paginator.paginate(
         assert!(param_names.contains(&"Prefix".to_string()));
         assert!(!param_names.contains(&"PaginationConfig".to_string()));
     }
+
+    #[test]
+    fn test_variable_operation_names_expand_to_all_values() {
+        let service_index = create_test_service_index();
+        let extractor = PaginatorExtractor::new(&service_index);
+
+        let source_code = r#"
+import boto3
+
+LIST_OBJECTS = 'list_objects_v2'
+client = boto3.client('s3')
+
+for operation in ['list_objects_v2', 'list_object_versions']:
+    paginator = client.get_paginator(operation)
+    for page in paginator.paginate(Bucket='bucket'):
+        pass
+
+def list_uploads(client, operation='list_multipart_uploads'):
+    return client.get_paginator(operation)
+
+client.get_paginator(LIST_OBJECTS).paginate(Bucket='bucket')
+client.get_paginator(unknown_operation).paginate(Bucket='bucket')
+"#;
+
+        let ast = create_test_ast(source_code);
+        let calls = extractor.extract_paginate_method_calls(&ast);
+
+        let names: Vec<&str> = calls.iter().map(|call| call.name.as_str()).collect();
+        assert_eq!(
+            names,
+            vec![
+                // Loop values, each matched to the paginate call
+                "list_objects_v2",
+                "list_object_versions",
+                // Chained call with a module constant
+                "list_objects_v2",
+            ]
+        );
+        for call in &calls {
+            assert!(call
+                .metadata
+                .as_ref()
+                .unwrap()
+                .parameters
+                .iter()
+                .any(|param| matches!(param, Parameter::Keyword { name, .. } if name == "Bucket")));
+        }
+    }
+
+    #[test]
+    fn test_parameter_default_operation_name() {
+        let service_index = create_test_service_index();
+        let extractor = PaginatorExtractor::new(&service_index);
+
+        let source_code = r#"
+def list_uploads(client, operation='list_multipart_uploads'):
+    paginator = client.get_paginator(operation)
+    return paginator
+"#;
+
+        let ast = create_test_ast(source_code);
+        let calls = extractor.extract_paginate_method_calls(&ast);
+
+        // Unmatched get_paginator still produces a synthetic call
+        assert_eq!(calls.len(), 1);
+        assert_eq!(calls[0].name, "list_multipart_uploads");
+    }
 }
@@ -48,6 +48,7 @@ use ast_grep_language::Python;
 use convert_case::{Case, Casing};
 use std::collections::{HashMap, HashSet};
 use std::path::Path;
+use std::sync::OnceLock;
 
 /// Information about a discovered resource constructor call
 #[derive(Debug, Clone)]
@@ -90,16 +91,21 @@ struct ResolvedResource {
     identifiers: HashMap<String, ParameterValue>,
 }
 
+/// Resource models of the services boto3 has resources for, loaded once: extractors are
+/// created for every analyzed file
+static RESOURCES_REGISTRY: OnceLock<Boto3ResourcesRegistry> = OnceLock::new();
+
 /// Extractor for boto3 resource direct call patterns
 pub(crate) struct ResourceDirectCallsExtractor<'a> {
-    registry: Boto3ResourcesRegistry,
+    registry: &'static Boto3ResourcesRegistry,
     service_index: &'a ServiceModelIndex,
 }
 
 impl<'a> ResourceDirectCallsExtractor<'a> {
     /// Create a new resource direct calls extractor with ServiceModelIndex access
     pub(crate) fn new(service_index: &'a ServiceModelIndex) -> Self {
-        let registry = Boto3ResourcesRegistry::load_common_services_with_utilities();
+        let registry = RESOURCES_REGISTRY
+            .get_or_init(Boto3ResourcesRegistry::load_common_services_with_utilities);
         Self {
             registry,
             service_index,
@@ -116,7 +122,7 @@ impl<'a> ResourceDirectCallsExtractor<'a> {
         ast: &AstWithSourceFile<Python>,
     ) -> Vec<SdkMethodCall> {
         // Step 1: Find all resource constructors using service-agnostic matching
-        let mut constructors = self.find_resource_constructors(ast, &self.registry);
+        let mut constructors = self.find_resource_constructors(ast, self.registry);
 
         // Step 2: Find all method calls on resource objects
         let mut method_calls = self.find_resource_method_calls(ast);
@@ -127,7 +133,7 @@ impl<'a> ResourceDirectCallsExtractor<'a> {
         // constructor + method-call pair sharing a unique variable name so the matching
         // step below joins them (injecting the accumulated identifiers).
         let (chained_constructors, chained_method_calls) =
-            self.find_chained_subresource_calls(ast, &self.registry);
+            self.find_chained_subresource_calls(ast, self.registry);
         constructors.extend(chained_constructors);
         method_calls.extend(chained_method_calls);
 
@@ -238,8 +244,38 @@ impl<'a> ResourceDirectCallsExtractor<'a> {
         for operation in &utility_method.operations {
             let mut parameters = Vec::new();
 
+            // Operations on another object (e.g. the head of a copy's source) take
+            // their parameters from one argument instead of the resource's identifiers
+            let (identifier_mappings, arguments) =
+                match &operation.arguments_from {
+                    Some(source) => {
+                        let source_value = method_call.arguments.iter().enumerate().find_map(
+                            |(arg_index, param)| match param {
+                                Parameter::Positional { value, .. }
+                                    if utility_method.accepted_params.get(arg_index)
+                                        == Some(source) =>
+                                {
+                                    Some(value)
+                                }
+                                Parameter::Keyword { name, value, .. } if name == source => {
+                                    Some(value)
+                                }
+                                _ => None,
+                            },
+                        );
+                        if let Some(value) = source_value {
+                            parameters.extend(operation.source_arguments(value, 0));
+                        }
+                        (&[][..], &[][..])
+                    }
+                    None => (
+                        &utility_method.identifier_mappings[..],
+                        &method_call.arguments[..],
+                    ),
+                };
+
             // Inject identifier parameters from constructor based on identifier_mappings
-            for id_mapping in &utility_method.identifier_mappings {
+            for id_mapping in identifier_mappings {
                 if let Some(constructor_arg) = constructor
                     .constructor_args
                     .get(id_mapping.constructor_arg_index)
@@ -263,7 +299,7 @@ impl<'a> ResourceDirectCallsExtractor<'a> {
 
             // Add method call arguments (positional mapping from utility method spec)
             // Only add parameters that are actually needed by the operation
-            for (arg_index, param) in method_call.arguments.iter().enumerate() {
+            for (arg_index, param) in arguments.iter().enumerate() {
                 // Map positional arguments using accepted_params
                 let param_to_add = if let Parameter::Positional {
                     value,
@@ -1285,19 +1321,35 @@ impl<'a> ResourceDirectCallsExtractor<'a> {
                     // Check client utility methods with parameter count filtering
                     if let Some(client_method) = boto3_model.get_client_utility_method(&method_name)
                     {
+                        // Managed transfers (`upload_file`, `copy`, ...) declare the arguments
+                        // they can't be called without and issue every operation of the
+                        // transfer, whether or not the call site names its parameters.
+                        let managed_transfer = !client_method.required_args.is_empty();
+                        if managed_transfer && !client_method.accepts_call(&arguments) {
+                            continue;
+                        }
+
                         // Generate synthetic for each operation
                         for operation in &client_method.operations {
                             // Filter: Skip if call site has fewer args than required
                             // Client methods show all parameters at call site (unlike resource methods
                             // where constructor parameters are hidden)
-                            if arguments.len() < operation.required_params.len() {
+                            if !managed_transfer
+                                && arguments.len() < operation.required_params.len()
+                            {
                                 continue; // Not enough arguments to satisfy this operation
                             }
 
+                            let operation_arguments = if managed_transfer {
+                                client_method.operation_arguments(&arguments, operation)
+                            } else {
+                                arguments.clone()
+                            };
+
                             calls.push(self.generate_tier3_utility_synthetic(
                                 service_name,
                                 &operation.operation,
-                                &arguments,
+                                &operation_arguments,
                                 &operation.required_params,
                                 node_match.text().to_string(),
                                 &location,
@@ -1600,4 +1652,104 @@ def handle():
             );
         }
     }
+
+    /// Managed transfers issue every operation of the transfer, with positional
+    /// call-site arguments mapped to the operation's parameter names.
+    #[rstest]
+    #[case::upload_file(
+        "def handle(s3):\n    s3.upload_file('/tmp/f', 'my-bucket', 'my-key', ExtraArgs={'ACL': 'private'})\n",
+        &["put_object", "create_multipart_upload", "upload_part", "complete_multipart_upload", "abort_multipart_upload"]
+    )]
+    #[case::download_file_keywords(
+        "def handle(s3):\n    s3.download_file(Bucket='my-bucket', Key='my-key', Filename='/tmp/f')\n",
+        &["head_object", "get_object"]
+    )]
+    #[case::copy(
+        "def handle(s3):\n    s3.copy({'Bucket': 'src', 'Key': 'k'}, 'my-bucket', 'my-key')\n",
+        &["copy_object", "upload_part_copy", "abort_multipart_upload"]
+    )]
+    #[tokio::test]
+    async fn managed_transfer_expands_to_all_operations(
+        #[case] source: &str,
+        #[case] operations: &[&str],
+    ) {
+        let service_index = ServiceDiscovery::load_service_index(Language::Python)
+            .await
+            .expect("failed to load service index");
+        let extractor = ResourceDirectCallsExtractor::new(&service_index);
+
+        let ast = create_test_ast(source);
+        let calls = extractor.extract_resource_method_calls(&ast);
+
+        for operation in operations {
+            let call = calls
+                .iter()
+                .find(|c| c.name == *operation)
+                .unwrap_or_else(|| panic!("{operation} should be extracted, got: {calls:?}"));
+            assert_eq!(
+                keyword_value(call, "Bucket"),
+                Some(&ParameterValue::Resolved("my-bucket".into())),
+                "call: {call:?}"
+            );
+            assert_eq!(
+                keyword_value(call, "Key"),
+                Some(&ParameterValue::Resolved("my-key".into())),
+                "call: {call:?}"
+            );
+            assert_eq!(keyword_value(call, "Filename"), None, "call: {call:?}");
+            assert_eq!(keyword_value(call, "ExtraArgs"), None, "call: {call:?}");
+        }
+    }
+
+    /// Managed copies head the source object, not the destination, and pass the
+    /// source on to the operations reading it.
+    #[rstest]
+    #[case::client(
+        "def handle(s3):\n    s3.copy({'Bucket': 'src', 'Key': 'k'}, 'my-bucket', 'my-key')\n"
+    )]
+    #[case::client_keywords(
+        "def handle(s3):\n    s3.copy(Bucket='my-bucket', Key='my-key', CopySource={'Bucket': 'src', 'Key': 'k'})\n"
+    )]
+    #[case::object(
+        "import boto3\ns3 = boto3.resource('s3')\nobj = s3.Object('my-bucket', 'my-key')\nobj.copy({'Bucket': 'src', 'Key': 'k'})\n"
+    )]
+    #[tokio::test]
+    async fn managed_copy_heads_the_copy_source(#[case] source: &str) {
+        let service_index = ServiceDiscovery::load_service_index(Language::Python)
+            .await
+            .expect("failed to load service index");
+        let extractor = ResourceDirectCallsExtractor::new(&service_index);
+
+        let ast = create_test_ast(source);
+        let calls = extractor.extract_resource_method_calls(&ast);
+
+        let head_object = calls
+            .iter()
+            .find(|c| c.name == "head_object")
+            .unwrap_or_else(|| panic!("head_object should be extracted, got: {calls:?}"));
+        assert_eq!(
+            keyword_value(head_object, "Bucket"),
+            Some(&ParameterValue::Resolved("src".into())),
+            "call: {head_object:?}"
+        );
+        assert_eq!(
+            keyword_value(head_object, "Key"),
+            Some(&ParameterValue::Resolved("k".into())),
+            "call: {head_object:?}"
+        );
+
+        for operation in ["copy_object", "upload_part_copy"] {
+            let call = calls
+                .iter()
+                .find(|c| c.name == operation)
+                .unwrap_or_else(|| panic!("{operation} should be extracted, got: {calls:?}"));
+            assert_eq!(
+                keyword_value(call, "CopySource"),
+                Some(&ParameterValue::Unresolved(
+                    "{'Bucket': 'src', 'Key': 'k'}".into()
+                )),
+                "call: {call:?}"
+            );
+        }
+    }
 }
@@ -0,0 +1,722 @@
+//! S3 access through filesystem and dataframe libraries
+//!
+//! Data code often reaches S3 through s3fs/fsspec or pandas/pyarrow path
+//! arguments instead of a boto3 client:
+//!
+//! ```python
+//! fs = s3fs.S3FileSystem()
+//! with fs.open("my-bucket/raw/events.json") as f: ...
+//! df = pd.read_parquet("s3://my-bucket/curated/")
+//! df.to_csv(f"s3://my-bucket/exports/{day}.csv")
+//! ```
+//!
+//! Each call maps to the S3 operations the library issues for it. Calls on an
+//! `S3FileSystem` are always S3 access; other calls count only when a path
+//! argument is an `s3://` URI. When the bucket (and key) is written out
+//! literally, the operations carry resource bindings so that the generated
+//! policy is scoped to the referenced bucket and key prefix.
+
+use std::collections::{BTreeMap, HashSet};
+
+use ast_grep_language::Python;
+
+use crate::extraction::python::node_kinds;
+use crate::extraction::sdk_model::ServiceDiscovery;
+use crate::extraction::{
+    AstWithSourceFile, Parameter, ParameterValue, SdkMethodCall, SdkMethodCallMetadata,
+};
+use crate::{Language, Location};
+
+/// URI schemes that address S3 (`s3a`/`s3n` are the Hadoop spellings)
+const S3_SCHEMES: [&str; 3] = ["s3://", "s3a://", "s3n://"];
+
+/// fsspec protocol names that select s3fs
+const S3_PROTOCOLS: [&str; 3] = ["s3", "s3a", "s3n"];
+
+/// Service reference placeholder for bucket names in S3 ARNs
+const BUCKET_PLACEHOLDER: &str = "BucketName";
+
+/// Service reference placeholder for object keys in S3 ARNs
+const OBJECT_PLACEHOLDER: &str = "ObjectName";
+
+/// Kind of S3 access a path argument is used for
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum S3Access {
+    Read,
+    Write,
+    List,
+    Metadata,
+    Delete,
+}
+
+impl S3Access {
+    /// S3 operations issued for this access
+    fn operations(self) -> &'static [&'static str] {
+        match self {
+            Self::Read => &["GetObject"],
+            // Large writes go through a multipart upload
+            Self::Write => &[
+                "PutObject",
+                "CreateMultipartUpload",
+                "UploadPart",
+                "CompleteMultipartUpload",
+                "AbortMultipartUpload",
+            ],
+            Self::List => &["ListObjectsV2"],
+            Self::Metadata => &["HeadObject"],
+            Self::Delete => &["DeleteObject"],
+        }
+    }
+}
+
+/// A path argument of a library call and the access it is used for
+struct PathArgument {
+    /// Positional index of the argument
+    position: usize,
+    /// Keyword names the argument may be passed as
+    keywords: &'static [&'static str],
+    /// Access the call performs on the path; `None` means it depends on the
+    /// `mode` argument, as for `open`
+    access: Option<&'static [S3Access]>,
+}
+
+/// Shorthand for a path argument with fixed access
+const fn path(
+    position: usize,
+    keywords: &'static [&'static str],
+    access: &'static [S3Access],
+) -> PathArgument {
+    PathArgument {
+        position,
+        keywords,
+        access: Some(access),
+    }
+}
+
+const READ: &[S3Access] = &[S3Access::Read];
+const WRITE: &[S3Access] = &[S3Access::Write];
+const LIST: &[S3Access] = &[S3Access::List];
+const LIST_READ: &[S3Access] = &[S3Access::List, S3Access::Read];
+const STAT: &[S3Access] = &[S3Access::Metadata, S3Access::List];
+const LIST_DELETE: &[S3Access] = &[S3Access::List, S3Access::Delete];
+const DELETE: &[S3Access] = &[S3Access::Delete];
+const READ_DELETE: &[S3Access] = &[S3Access::Read, S3Access::Delete];
+
+const PATH: &[&str] = &["path"];
+const RPATH: &[&str] = &["rpath"];
+const PATH1: &[&str] = &["path1"];
+const PATH2: &[&str] = &["path2"];
+const URLPATH: &[&str] = &["urlpath", "uri"];
+
+/// `open`-style path argument whose access follows the `mode` argument
+const OPEN_PATH: PathArgument = PathArgument {
+    position: 0,
+    keywords: URLPATH,
+    access: None,
+};
+
+/// `S3FileSystem` (s3fs / fsspec `AbstractFileSystem`) methods
+const FILESYSTEM_METHODS: &[(&str, &[PathArgument])] = &[
+    ("open", &[OPEN_PATH]),
+    ("cat", &[path(0, PATH, READ)]),
+    ("cat_file", &[path(0, PATH, READ)]),
+    ("read_text", &[path(0, PATH, READ)]),
+    ("read_bytes", &[path(0, PATH, READ)]),
+    ("head", &[path(0, PATH, READ)]),
+    ("tail", &[path(0, PATH, READ)]),
+    ("get", &[path(0, RPATH, READ)]),
+    ("get_file", &[path(0, RPATH, READ)]),
+    ("download", &[path(0, RPATH, READ)]),
+    ("put", &[path(1, RPATH, WRITE)]),
+    ("put_file", &[path(1, RPATH, WRITE)]),
+    ("upload", &[path(1, RPATH, WRITE)]),
+    ("pipe", &[path(0, PATH, WRITE)]),
+    ("pipe_file", &[path(0, PATH, WRITE)]),
+    ("write_text", &[path(0, PATH, WRITE)]),
+    ("write_bytes", &[path(0, PATH, WRITE)]),
+    ("touch", &[path(0, PATH, WRITE)]),
+    ("ls", &[path(0, PATH, LIST)]),
+    ("listdir", &[path(0, PATH, LIST)]),
+    ("glob", &[path(0, PATH, LIST)]),
+    ("find", &[path(0, PATH, LIST)]),
+    ("walk", &[path(0, PATH, LIST)]),
+    ("du", &[path(0, PATH, LIST)]),
+    ("isdir", &[path(0, PATH, LIST)]),
+    ("info", &[path(0, PATH, STAT)]),
+    ("exists", &[path(0, PATH, STAT)]),
+    ("isfile", &[path(0, PATH, STAT)]),
+    ("size", &[path(0, PATH, STAT)]),
+    ("modified", &[path(0, PATH, STAT)]),
+    ("rm", &[path(0, PATH, LIST_DELETE)]),
+    ("delete", &[path(0, PATH, LIST_DELETE)]),
+    ("rm_file", &[path(0, PATH, DELETE)]),
+    ("copy", &[path(0, PATH1, READ), path(1, PATH2, WRITE)]),
+    ("cp", &[path(0, PATH1, READ), path(1, PATH2, WRITE)]),
+    ("cp_file", &[path(0, PATH1, READ), path(1, PATH2, WRITE)]),
+    ("mv", &[path(0, PATH1, READ_DELETE), path(1, PATH2, WRITE)]),
+    (
+        "rename",
+        &[path(0, PATH1, READ_DELETE), path(1, PATH2, WRITE)],
+    ),
+];
+
+/// Path keywords of pandas readers
+const READER_PATH: &[&str] = &[
+    "filepath_or_buffer",
+    "path",
+    "path_or_buf",
+    "path_or_buffer",
+    "io",
+    "source",
+];
+
+/// Path keywords of pandas writers
+const WRITER_PATH: &[&str] = &["path_or_buf", "path", "excel_writer", "fname"];
+
+/// Functions that read or write an `s3://` URI: pandas readers and
+/// `DataFrame.to_*` writers, pyarrow (`pq.read_table`, `pq.write_table`,
+/// `ds.dataset`) and fsspec/smart_open `open`
+const URI_FUNCTIONS: &[(&str, &[PathArgument])] = &[
+    ("open", &[OPEN_PATH]),
+    (
+        "open_files",
+        &[PathArgument {
+            position: 0,
+            keywords: URLPATH,
+            access: Some(LIST_READ),
+        }],
+    ),
+    ("read_csv", &[path(0, READER_PATH, READ)]),
+    ("read_json", &[path(0, READER_PATH, READ)]),
+    ("read_excel", &[path(0, READER_PATH, READ)]),
+    ("read_feather", &[path(0, READER_PATH, READ)]),
+    ("read_pickle", &[path(0, READER_PATH, READ)]),
+    ("read_fwf", &[path(0, READER_PATH, READ)]),
+    ("read_xml", &[path(0, READER_PATH, READ)]),
+    ("read_sas", &[path(0, READER_PATH, READ)]),
+    ("read_stata", &[path(0, READER_PATH, READ)]),
+    // Parquet and ORC readers accept dataset directories
+    ("read_parquet", &[path(0, READER_PATH, LIST_READ)]),
+    ("read_orc", &[path(0, READER_PATH, LIST_READ)]),
+    ("read_table", &[path(0, READER_PATH, LIST_READ)]),
+    ("ParquetDataset", &[path(0, &["path_or_paths"], LIST_READ)]),
+    ("dataset", &[path(0, READER_PATH, LIST_READ)]),
+    ("ParquetFile", &[path(0, READER_PATH, READ)]),
+    ("read_metadata", &[path(0, &["where"], READ)]),
+    ("read_schema", &[path(0, &["where"], READ)]),
+    ("to_csv", &[path(0, WRITER_PATH, WRITE)]),
+    ("to_json", &[path(0, WRITER_PATH, WRITE)]),
+    ("to_parquet", &[path(0, WRITER_PATH, WRITE)]),
+    ("to_excel", &[path(0, WRITER_PATH, WRITE)]),
+    ("to_feather", &[path(0, WRITER_PATH, WRITE)]),
+    ("to_orc", &[path(0, WRITER_PATH, WRITE)]),
+    ("to_pickle", &[path(0, WRITER_PATH, WRITE)]),
+    ("to_xml", &[path(0, WRITER_PATH, WRITE)]),
+    ("write_table", &[path(1, &["where"], WRITE)]),
+    ("write_to_dataset", &[path(1, &["root_path"], WRITE)]),
+    ("write_dataset", &[path(1, &["base_dir"], WRITE)]),
+];
+
+/// Statically known parts of an S3 path argument
+#[derive(Debug, PartialEq, Eq)]
+struct S3Path {
+    /// Text of the path up to the first interpolation
+    literal_prefix: String,
+    /// Whether the whole path is literal (no f-string interpolation)
+    complete: bool,
+}
+
+/// Bucket and key pattern an S3 path refers to
+#[derive(Debug, PartialEq, Eq)]
+struct S3Location {
+    bucket: String,
+    /// Exact key, or a prefix pattern ending in `*`
+    key_pattern: String,
+    /// The exact key when the path is fully literal
+    key: Option<String>,
+}
+
+/// Extracts S3 operations from s3fs, fsspec, pandas and pyarrow calls
+pub(crate) struct S3PathExtractor;
+
+impl S3PathExtractor {
+    /// Extract S3 operations from every library call in `ast` that accesses an S3 path
+    pub(crate) fn extract_s3_path_calls(ast: &AstWithSourceFile<Python>) -> Vec<SdkMethodCall> {
+        let root = ast.ast.root();
+        let filesystems = Self::collect_filesystem_variables(&root);
+        let mut calls = Vec::new();
+
+        for call in root.dfs().filter(|node| node.kind() == node_kinds::CALL) {
+            let Some(function) = call.field("function") else {
+                continue;
+            };
+            let (receiver, name) = if function.kind() == node_kinds::ATTRIBUTE {
+                let (Some(object), Some(attribute)) =
+                    (function.field("object"), function.field("attribute"))
+                else {
+                    continue;
+                };
+                (Some(object), attribute.text().to_string())
+            } else if function.kind() == node_kinds::IDENTIFIER {
+                (None, function.text().to_string())
+            } else {
+                continue;
+            };
+
+            let on_filesystem = receiver
+                .as_ref()
+                .is_some_and(|object| Self::is_filesystem(object, &filesystems));
+            let table = if on_filesystem {
+                FILESYSTEM_METHODS
+            } else {
+                URI_FUNCTIONS
+            };
+            let Some((_, path_arguments)) = table.iter().find(|(method, _)| *method == name) else {
+                continue;
+            };
+
+            for path_argument in *path_arguments {
+                let Some(argument) =
+                    argument_node(&call, path_argument.position, path_argument.keywords)
+                else {
+                    continue;
+                };
+                let known_path = s3_path(&argument);
+                let location = match &known_path {
+                    Some(known) if has_s3_scheme(&known.literal_prefix) => s3_location(known),
+                    // s3fs accepts `bucket/key` without a scheme
+                    Some(known) if on_filesystem => s3_location(known),
+                    // A non-literal path is S3 access only on a filesystem
+                    None if on_filesystem => None,
+                    _ => continue,
+                };
+                let access = path_argument
+                    .access
+                    .unwrap_or_else(|| open_mode_access(&call));
+                calls.extend(Self::sdk_method_calls(
+                    &call,
+                    receiver.as_ref().map(|object| object.text().to_string()),
+                    access,
+                    location.as_ref(),
+                    ast,
+                ));
+            }
+        }
+
+        calls
+    }
+
+    /// Names bound to an S3 filesystem: `s3fs.S3FileSystem(...)`,
+    /// `S3FileSystem(...)` or `fsspec.filesystem("s3")`
+    fn collect_filesystem_variables(
+        root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) -> HashSet<String> {
+        root.dfs()
+            .filter(|node| node.kind() == node_kinds::ASSIGNMENT)
+            .filter_map(|assignment| {
+                let target = assignment.field("left")?;
+                let value = assignment.field("right")?;
+                is_filesystem_constructor(&value).then(|| target.text().to_string())
+            })
+            .collect()
+    }
+
+    /// Whether a call receiver is an S3 filesystem
+    fn is_filesystem(
+        object: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+        filesystems: &HashSet<String>,
+    ) -> bool {
+        filesystems.contains(&*object.text()) || is_filesystem_constructor(object)
+    }
+
+    /// One `SdkMethodCall` per S3 operation of `access`
+    fn sdk_method_calls(
+        call: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+        receiver: Option<String>,
+        access: &[S3Access],
+        location: Option<&S3Location>,
+        ast: &AstWithSourceFile<Python>,
+    ) -> Vec<SdkMethodCall> {
+        let mut resource_bindings = BTreeMap::new();
+        if let Some(location) = location {
+            resource_bindings.insert(BUCKET_PLACEHOLDER.to_string(), location.bucket.clone());
+            resource_bindings.insert(OBJECT_PLACEHOLDER.to_string(), location.key_pattern.clone());
+        }
+
+        let source_location = Location::from_node(ast.source_file.path.clone(), call);
+        access
+            .iter()
+            .flat_map(|access| access.operations())
+            .map(|operation| {
+                let mut metadata =
+                    SdkMethodCallMetadata::new(call.text().to_string(), source_location.clone())
+                        .with_parameters(location.map_or_else(Vec::new, |location| {
+                            operation_parameters(operation, location)
+                        }))
+                        .with_resource_bindings(resource_bindings.clone());
+                if let Some(receiver) = &receiver {
+                    metadata = metadata.with_receiver(receiver.clone());
+                }
+                SdkMethodCall {
+                    name: ServiceDiscovery::operation_to_method_name(operation, Language::Python),
+                    possible_services: vec!["s3".to_string()],
+                    metadata: Some(metadata),
+                }
+            })
+            .collect()
+    }
+}
+
+/// Whether `node` constructs an S3 filesystem
+fn is_filesystem_constructor(
+    node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+) -> bool {
+    if node.kind() != node_kinds::CALL {
+        return false;
+    }
+    let Some(function) = node.field("function") else {
+        return false;
+    };
+    let callee = function.text();
+    let name = callee.rsplit('.').next().unwrap_or_default();
+    if name == "S3FileSystem" {
+        return true;
+    }
+    if name != "filesystem" {
+        return false;
+    }
+    argument_node(node, 0, &["protocol"])
+        .and_then(|protocol| s3_path(&protocol))
+        .is_some_and(|protocol| {
+            protocol.complete && S3_PROTOCOLS.contains(&protocol.literal_prefix.as_str())
+        })
+}
+
+/// The argument at `position`, or passed as one of `keywords`
+fn argument_node<'r>(
+    call: &ast_grep_core::Node<'r, ast_grep_core::tree_sitter::StrDoc<Python>>,
+    position: usize,
+    keywords: &[&str],
+) -> Option<ast_grep_core::Node<'r, ast_grep_core::tree_sitter::StrDoc<Python>>> {
+    let arguments = call.field("arguments")?;
+    let mut positional = 0;
+    for argument in arguments.children().filter(|child| child.is_named()) {
+        let kind = argument.kind();
+        if kind == node_kinds::KEYWORD_ARGUMENT {
+            let is_path = argument
+                .field("name")
+                .is_some_and(|name| keywords.contains(&&*name.text()));
+            if is_path {
+                return argument.field("value");
+            }
+        } else if kind != node_kinds::COMMENT
+            && kind != node_kinds::DICTIONARY_SPLAT
+            && kind != node_kinds::LIST_SPLAT
+        {
+            if positional == position {
+                return Some(argument);
+            }
+            positional += 1;
+        }
+    }
+    None
+}
+
+/// Access of an `open(path, mode)` call: writing modes (`w`, `a`, `x`) write,
+/// anything else (including the default `rb`) reads
+fn open_mode_access(
+    call: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+) -> &'static [S3Access] {
+    let writes = argument_node(call, 1, &["mode"])
+        .and_then(|mode| s3_path(&mode))
+        .is_some_and(|mode| mode.literal_prefix.contains(['w', 'a', 'x']));
+    if writes {
+        WRITE
+    } else {
+        READ
+    }
+}
+
+/// Statically known text of a string or f-string argument
+///
+/// Byte strings and non-string expressions return `None`.
+fn s3_path(
+    node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+) -> Option<S3Path> {
+    if node.kind() != node_kinds::STRING {
+        return None;
+    }
+    let text = node.text();
+    let quote_index = text.find(['\'', '"'])?;
+    let prefix = text[..quote_index].to_ascii_lowercase();
+    if prefix.contains('b') {
+        return None;
+    }
+    let body = &text[quote_index..];
+    let quote = if body.starts_with("'''") || body.starts_with("\"\"\"") {
+        &body[..3]
+    } else {
+        &body[..1]
+    };
+    let content = body
+        .strip_prefix(quote)?
+        .strip_suffix(quote)
+        .unwrap_or_default();
+
+    if prefix.contains('f') {
+        if let Some(interpolation) = content.find('{') {
+            return Some(S3Path {
+                literal_prefix: content[..interpolation].to_string(),
+                complete: false,
+            });
+        }
+    }
+    Some(S3Path {
+        literal_prefix: content.to_string(),
+        complete: true,
+    })
+}
+
+fn has_s3_scheme(path: &str) -> bool {
+    S3_SCHEMES.iter().any(|scheme| path.starts_with(scheme))
+}
+
+/// Bucket and key pattern of a path, if the bucket is written out literally
+///
+/// Keys that are interpolated, contain glob characters or name a directory
+/// (trailing `/`) become prefix patterns: `s3://b/raw/{day}.json` -> `raw/*`.
+fn s3_location(path: &S3Path) -> Option<S3Location> {
+    let without_scheme = S3_SCHEMES
+        .iter()
+        .find_map(|scheme| path.literal_prefix.strip_prefix(scheme))
+        .unwrap_or(&path.literal_prefix);
+
+    let (bucket, key) = match without_scheme.split_once('/') {
+        Some((bucket, key)) => (bucket, key),
+        // `s3://bucket` or `s3://buck{et}`: a complete bucket only
+        None if path.complete => (without_scheme, ""),
+        None => return None,
+    };
+    if bucket.is_empty() || bucket.contains(['*', '?', '[']) {
+        return None;
+    }
+
+    let glob = key.find(['*', '?', '[']);
+    let key_pattern = match glob {
+        Some(index) => format!("{}*", &key[..index]),
+        None if !path.complete || key.is_empty() || key.ends_with('/') => format!("{key}*"),
+        None => key.to_string(),
+    };
+    let key = (path.complete && glob.is_none() && !key.is_empty()).then(|| key.to_string());
+
+    Some(S3Location {
+        bucket: bucket.to_string(),
+        key_pattern,
+        key,
+    })
+}
+
+/// Literal parameters of an S3 operation for a located path
+fn operation_parameters(operation: &str, location: &S3Location) -> Vec<Parameter> {
+    let mut parameters = vec![("Bucket", location.bucket.clone())];
+    if let Some(key) = &location.key {
+        let key_parameter = if operation == "ListObjectsV2" {
+            "Prefix"
+        } else {
+            "Key"
+        };
+        parameters.push((key_parameter, key.clone()));
+    }
+    parameters
+        .into_iter()
+        .enumerate()
+        .map(|(position, (name, value))| Parameter::Keyword {
+            name: name.to_string(),
+            value: ParameterValue::Resolved(value),
+            position,
+            type_annotation: None,
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::SourceFile;
+    use ast_grep_core::tree_sitter::LanguageExt;
+    use rstest::rstest;
+    use std::path::PathBuf;
+
+    fn create_test_ast(source_code: &str) -> AstWithSourceFile<Python> {
+        let source_file = SourceFile::with_language(
+            PathBuf::new(),
+            source_code.to_string(),
+            crate::Language::Python,
+        );
+        let ast_grep = Python.ast_grep(&source_file.content);
+        AstWithSourceFile::new(ast_grep, source_file)
+    }
+
+    /// `(operation, BucketName binding, ObjectName binding)` of each extracted call
+    fn extract(source_code: &str) -> Vec<(String, Option<String>, Option<String>)> {
+        let ast = create_test_ast(source_code);
+        S3PathExtractor::extract_s3_path_calls(&ast)
+            .into_iter()
+            .map(|call| {
+                let bindings = call.metadata.expect("metadata").resource_bindings;
+                (
+                    call.name,
+                    bindings.get(BUCKET_PLACEHOLDER).cloned(),
+                    bindings.get(OBJECT_PLACEHOLDER).cloned(),
+                )
+            })
+            .collect()
+    }
+
+    fn scoped(
+        operation: &str,
+        bucket: &str,
+        object: &str,
+    ) -> (String, Option<String>, Option<String>) {
+        (
+            operation.to_string(),
+            Some(bucket.to_string()),
+            Some(object.to_string()),
+        )
+    }
+
+    fn unscoped(operation: &str) -> (String, Option<String>, Option<String>) {
+        (operation.to_string(), None, None)
+    }
+
+    fn write_operations(
+        bucket: &str,
+        object: &str,
+    ) -> Vec<(String, Option<String>, Option<String>)> {
+        [
+            "put_object",
+            "create_multipart_upload",
+            "upload_part",
+            "complete_multipart_upload",
+            "abort_multipart_upload",
+        ]
+        .iter()
+        .map(|operation| scoped(operation, bucket, object))
+        .collect()
+    }
+
+    #[rstest]
+    #[case::pandas_read_csv(
+        "import pandas as pd\ndf = pd.read_csv('s3://my-bucket/raw/data.csv')\n",
+        vec![scoped("get_object", "my-bucket", "raw/data.csv")]
+    )]
+    #[case::pandas_read_parquet_directory(
+        "df = pd.read_parquet(path=\"s3://my-bucket/curated/\")\n",
+        vec![
+            scoped("list_objects_v2", "my-bucket", "curated/*"),
+            scoped("get_object", "my-bucket", "curated/*"),
+        ]
+    )]
+    #[case::dataframe_writer_fstring(
+        "df.to_csv(f\"s3://exports/{day}/report.csv\", index=False)\n",
+        write_operations("exports", "*")
+    )]
+    #[case::pyarrow_write_table(
+        "import pyarrow.parquet as pq\npq.write_table(table, 's3a://lake/events/part-0.parquet')\n",
+        write_operations("lake", "events/part-0.parquet")
+    )]
+    #[case::glob_key(
+        "files = fsspec.open_files('s3://logs/2024/*.gz')\n",
+        vec![scoped("list_objects_v2", "logs", "2024/*"), scoped("get_object", "logs", "2024/*")]
+    )]
+    #[case::fsspec_open_for_writing(
+        "with fsspec.open('s3://my-bucket/out.json', 'w') as f:\n    f.write(data)\n",
+        write_operations("my-bucket", "out.json")
+    )]
+    #[case::interpolated_bucket(
+        "df = pd.read_csv(f's3://{bucket}/data.csv')\n",
+        vec![unscoped("get_object")]
+    )]
+    #[case::local_path("df = pd.read_csv('data/local.csv')\n", vec![])]
+    #[case::schemeless_path_without_filesystem("client.ls('my-bucket/prefix')\n", vec![])]
+    fn test_uri_functions(
+        #[case] source_code: &str,
+        #[case] expected: Vec<(String, Option<String>, Option<String>)>,
+    ) {
+        assert_eq!(extract(source_code), expected);
+    }
+
+    #[rstest]
+    #[case::s3fs_open(
+        "import s3fs\nfs = s3fs.S3FileSystem(anon=False)\nwith fs.open('my-bucket/raw/events.json') as f:\n    pass\n",
+        vec![scoped("get_object", "my-bucket", "raw/events.json")]
+    )]
+    #[case::imported_constructor(
+        "from s3fs import S3FileSystem\nfs = S3FileSystem()\nfs.ls('s3://my-bucket/raw/')\n",
+        vec![scoped("list_objects_v2", "my-bucket", "raw/*")]
+    )]
+    #[case::fsspec_filesystem(
+        "import fsspec\nfs = fsspec.filesystem('s3')\nfs.rm('my-bucket/tmp/', recursive=True)\n",
+        vec![
+            scoped("list_objects_v2", "my-bucket", "tmp/*"),
+            scoped("delete_object", "my-bucket", "tmp/*"),
+        ]
+    )]
+    #[case::inline_filesystem(
+        "s3fs.S3FileSystem().exists('my-bucket/flag')\n",
+        vec![
+            scoped("head_object", "my-bucket", "flag"),
+            scoped("list_objects_v2", "my-bucket", "flag"),
+        ]
+    )]
+    #[case::copy_between_buckets(
+        "fs = s3fs.S3FileSystem()\nfs.copy('src-bucket/a.csv', 'dst-bucket/a.csv')\n",
+        [vec![scoped("get_object", "src-bucket", "a.csv")], write_operations("dst-bucket", "a.csv")].concat()
+    )]
+    #[case::upload_keyword(
+        "fs = s3fs.S3FileSystem()\nfs.put('/tmp/out.csv', rpath='my-bucket/out.csv')\n",
+        write_operations("my-bucket", "out.csv")
+    )]
+    #[case::non_literal_path(
+        "fs = s3fs.S3FileSystem()\nfs.cat(object_path)\n",
+        vec![unscoped("get_object")]
+    )]
+    #[case::other_filesystem(
+        "import fsspec\nfs = fsspec.filesystem('gcs')\nfs.cat('my-bucket/data.csv')\n",
+        vec![]
+    )]
+    fn test_filesystem_methods(
+        #[case] source_code: &str,
+        #[case] expected: Vec<(String, Option<String>, Option<String>)>,
+    ) {
+        assert_eq!(extract(source_code), expected);
+    }
+
+    #[test]
+    fn test_literal_key_becomes_parameter() {
+        let ast = create_test_ast("pd.read_csv('s3://my-bucket/raw/data.csv')\n");
+        let calls = S3PathExtractor::extract_s3_path_calls(&ast);
+
+        assert_eq!(calls.len(), 1);
+        let metadata = calls[0].metadata.as_ref().expect("metadata");
+        assert_eq!(calls[0].possible_services, vec!["s3"]);
+        assert_eq!(
+            metadata.parameters,
+            vec![
+                Parameter::Keyword {
+                    name: "Bucket".to_string(),
+                    value: ParameterValue::Resolved("my-bucket".to_string()),
+                    position: 0,
+                    type_annotation: None,
+                },
+                Parameter::Keyword {
+                    name: "Key".to_string(),
+                    value: ParameterValue::Resolved("raw/data.csv".to_string()),
+                    position: 1,
+                    type_annotation: None,
+                },
+            ]
+        );
+        assert_eq!(metadata.receiver.as_deref(), Some("pd"));
+    }
+}
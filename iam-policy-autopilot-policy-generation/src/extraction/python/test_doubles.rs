@@ -0,0 +1,84 @@
+//! Recognition of Python test code that runs against emulated AWS services.
+//!
+//! Tests commonly replace AWS with moto (`@mock_aws`, `with mock_s3():`) or point
+//! clients at a LocalStack container (`endpoint_url="http://localhost:4566"`).
+//! Fixtures in such files create buckets, tables and queues the production role
+//! never touches, so their calls must not add permissions.
+
+/// Modules whose import marks a file as emulator-backed test code
+const EMULATOR_MODULES: &[&str] = &["moto", "localstack_client"];
+
+/// Decorators enabling moto's mocks that may be used without importing moto directly
+const MOTO_DECORATORS: &[&str] = &["@mock_aws", "@moto."];
+
+/// Endpoint fragments of services emulated on the local machine
+const LOCAL_ENDPOINT_MARKERS: &[&str] = &["localhost", "127.0.0.1", "localstack", ":4566"];
+
+/// Check whether a Python file tests against moto or LocalStack instead of AWS.
+///
+/// Matches imports of `moto` or `localstack_client`, moto decorators, and
+/// `endpoint_url` arguments pointing at a local emulator.
+pub(crate) fn uses_aws_emulator(content: &str) -> bool {
+    content.lines().map(str::trim).any(|line| {
+        imports_emulator_module(line)
+            || MOTO_DECORATORS
+                .iter()
+                .any(|decorator| line.starts_with(decorator))
+            || (line.contains("endpoint_url")
+                && LOCAL_ENDPOINT_MARKERS
+                    .iter()
+                    .any(|marker| line.contains(marker)))
+    })
+}
+
+/// Whether a line is `import <module>...` or `from <module>... import` for an emulator module
+fn imports_emulator_module(line: &str) -> bool {
+    let Some(imported) = line
+        .strip_prefix("import ")
+        .or_else(|| line.strip_prefix("from "))
+    else {
+        return false;
+    };
+    let module = imported
+        .split(|c: char| c.is_whitespace() || c == ',')
+        .next()
+        .unwrap_or_default();
+    let top_level = module.split('.').next().unwrap_or_default();
+    EMULATOR_MODULES.contains(&top_level)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use rstest::rstest;
+
+    #[rstest]
+    #[case::mock_aws_import(
+        "from moto import mock_aws\n\n@mock_aws\ndef test_upload():\n    pass\n",
+        true
+    )]
+    #[case::legacy_decorator("import moto\n\n@moto.mock_s3\ndef test_upload():\n    pass\n", true)]
+    #[case::submodule_import("from moto.core import DEFAULT_ACCOUNT_ID\n", true)]
+    #[case::decorator_from_fixture_module(
+        "from tests.fixtures import mock_aws\n\n@mock_aws\ndef test_upload():\n    pass\n",
+        true
+    )]
+    #[case::localstack_endpoint(
+        "s3 = boto3.client('s3', endpoint_url='http://localhost:4566')\n",
+        true
+    )]
+    #[case::localstack_host(
+        "sqs = boto3.client('sqs', endpoint_url=\"http://localstack:4566\")\n",
+        true
+    )]
+    #[case::localstack_client("import localstack_client.session as boto3\n", true)]
+    #[case::production_client("s3 = boto3.client('s3')\n", false)]
+    #[case::custom_endpoint(
+        "s3 = boto3.client('s3', endpoint_url='https://s3.eu-west-1.amazonaws.com')\n",
+        false
+    )]
+    #[case::similar_module_name("import motor\nfrom motorists import Driver\n", false)]
+    fn test_uses_aws_emulator(#[case] content: &str, #[case] expected: bool) {
+        assert_eq!(uses_aws_emulator(content), expected);
+    }
+}
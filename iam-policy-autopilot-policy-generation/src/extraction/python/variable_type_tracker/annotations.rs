@@ -0,0 +1,250 @@
+//! Type annotations from `mypy_boto3_*` stubs
+//!
+//! Code using the boto3-stubs packages annotates clients and resources with
+//! service-specific types:
+//!
+//! ```python
+//! from mypy_boto3_s3 import S3Client
+//! from mypy_boto3_dynamodb.service_resource import Table
+//!
+//! def upload(client: S3Client, table: "Table") -> None: ...
+//! ```
+//!
+//! The stub module name identifies the service (`mypy_boto3_cognito_idp` ->
+//! `cognito-idp`), so annotated names resolve even when the client is
+//! constructed in another file.
+
+use std::collections::HashMap;
+
+use ast_grep_language::Python;
+
+use super::types::{SdkObjectKind, VariableTypeInfo};
+use crate::extraction::python::node_kinds;
+
+/// Prefix of every boto3-stubs service module
+const STUBS_MODULE_PREFIX: &str = "mypy_boto3_";
+
+/// mypy_boto3 types imported by one Python module
+#[derive(Debug, Default)]
+pub(super) struct Boto3TypeAnnotations {
+    /// Imported type names: local name -> qualified type (`S3Client` -> `mypy_boto3_s3.S3Client`)
+    imported_types: HashMap<String, String>,
+    /// Imported stub modules: local name -> module (`s3_types` -> `mypy_boto3_s3`)
+    imported_modules: HashMap<String, String>,
+}
+
+impl Boto3TypeAnnotations {
+    /// Collect the mypy_boto3 imports of a module, including those guarded by `TYPE_CHECKING`
+    pub(super) fn collect(
+        root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) -> Self {
+        let mut annotations = Self::default();
+
+        for statement in root.dfs() {
+            let kind = statement.kind();
+            if kind == node_kinds::IMPORT_FROM_STATEMENT {
+                let Some(module) = statement.field("module_name") else {
+                    continue;
+                };
+                let module_name = module.text().to_string();
+                if !module_name.starts_with(STUBS_MODULE_PREFIX) {
+                    continue;
+                }
+                for (name, local_name) in imported_names(&statement, Some(module.node_id())) {
+                    annotations
+                        .imported_types
+                        .insert(local_name, format!("{module_name}.{name}"));
+                }
+            } else if kind == node_kinds::IMPORT_STATEMENT {
+                for (name, local_name) in imported_names(&statement, None) {
+                    if name.starts_with(STUBS_MODULE_PREFIX) {
+                        annotations.imported_modules.insert(local_name, name);
+                    }
+                }
+            }
+        }
+        annotations
+    }
+
+    /// Resolve a type annotation to the client or resource type it names
+    ///
+    /// Accepts bare and quoted names (`S3Client`, `"S3Client"`), module-qualified
+    /// names (`mypy_boto3_s3.S3Client`) and wrappers such as `Optional[S3Client]`
+    /// or `S3Client | None`. Annotations naming types of different services are
+    /// ambiguous and return `None`.
+    pub(super) fn resolve(
+        &self,
+        annotation: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) -> Option<VariableTypeInfo> {
+        let text = annotation.text();
+        let mut resolved: Option<VariableTypeInfo> = None;
+
+        for token in text
+            .split(|c: char| !(c.is_alphanumeric() || c == '_' || c == '.'))
+            .filter(|token| !token.is_empty())
+        {
+            let Some(type_info) = self
+                .qualified_type(token)
+                .and_then(|qualified| type_info_for_qualified_type(&qualified))
+            else {
+                continue;
+            };
+            match &resolved {
+                None => resolved = Some(type_info),
+                Some(existing) if existing.service_name == type_info.service_name => {}
+                Some(existing) => {
+                    log::debug!(
+                        "Annotation '{text}' names types of services '{}' and '{}'",
+                        existing.service_name,
+                        type_info.service_name
+                    );
+                    return None;
+                }
+            }
+        }
+        resolved
+    }
+
+    /// Fully qualified stub type for a name used in an annotation
+    fn qualified_type(&self, name: &str) -> Option<String> {
+        if let Some(qualified) = self.imported_types.get(name) {
+            return Some(qualified.clone());
+        }
+        let (owner, type_name) = name.rsplit_once('.')?;
+        if let Some(module) = self.imported_modules.get(owner) {
+            return Some(format!("{module}.{type_name}"));
+        }
+        owner
+            .starts_with(STUBS_MODULE_PREFIX)
+            .then(|| name.to_string())
+    }
+}
+
+/// Type information for a qualified stub type such as `mypy_boto3_s3.client.S3Client`
+///
+/// Clients and service resources are recognised by name; other classes from a
+/// `service_resource` module (`Table`, `Bucket`) are sub-resources. Paginators,
+/// waiters and type definitions aren't SDK objects we track.
+fn type_info_for_qualified_type(qualified: &str) -> Option<VariableTypeInfo> {
+    let (module, _) = qualified.split_once('.')?;
+    let service_name = module.strip_prefix(STUBS_MODULE_PREFIX)?.replace('_', "-");
+    let (_, type_name) = qualified.rsplit_once('.')?;
+
+    let kind = if type_name.ends_with("ServiceResource") {
+        SdkObjectKind::Resource
+    } else if type_name.ends_with("Client") {
+        SdkObjectKind::Client
+    } else if qualified.contains(".service_resource.") {
+        SdkObjectKind::ResourceCollection
+    } else {
+        return None;
+    };
+
+    Some(VariableTypeInfo::from_lsp_type(
+        qualified.to_string(),
+        service_name,
+        kind,
+    ))
+}
+
+/// Names bound by an import statement as `(imported name, local name)` pairs
+///
+/// `skip_node_id` excludes the module name of a `from ... import` statement.
+fn imported_names(
+    statement: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    skip_node_id: Option<usize>,
+) -> Vec<(String, String)> {
+    let mut names = Vec::new();
+    for imported in statement.children() {
+        if Some(imported.node_id()) == skip_node_id {
+            continue;
+        }
+        let kind = imported.kind();
+        if kind == node_kinds::DOTTED_NAME {
+            let name = imported.text().to_string();
+            names.push((name.clone(), name));
+        } else if kind == node_kinds::ALIASED_IMPORT {
+            if let (Some(name), Some(alias)) = (imported.field("name"), imported.field("alias")) {
+                names.push((name.text().to_string(), alias.text().to_string()));
+            }
+        }
+    }
+    names
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use ast_grep_core::tree_sitter::LanguageExt;
+    use rstest::rstest;
+
+    #[rstest]
+    #[case::top_level_import(
+        "from mypy_boto3_s3 import S3Client\nx: S3Client\n",
+        Some(("s3", SdkObjectKind::Client))
+    )]
+    #[case::client_submodule(
+        "from mypy_boto3_cognito_idp.client import CognitoIdentityProviderClient\nx: CognitoIdentityProviderClient\n",
+        Some(("cognito-idp", SdkObjectKind::Client))
+    )]
+    #[case::aliased_type(
+        "from mypy_boto3_sqs import SQSClient as Queue\nx: Queue\n",
+        Some(("sqs", SdkObjectKind::Client))
+    )]
+    #[case::service_resource(
+        "from mypy_boto3_dynamodb import DynamoDBServiceResource\nx: DynamoDBServiceResource\n",
+        Some(("dynamodb", SdkObjectKind::Resource))
+    )]
+    #[case::sub_resource(
+        "from mypy_boto3_dynamodb.service_resource import Table\nx: Table\n",
+        Some(("dynamodb", SdkObjectKind::ResourceCollection))
+    )]
+    #[case::quoted("from mypy_boto3_s3 import S3Client\nx: \"S3Client\"\n", Some(("s3", SdkObjectKind::Client)))]
+    #[case::optional(
+        "from typing import Optional\nfrom mypy_boto3_s3 import S3Client\nx: Optional[S3Client]\n",
+        Some(("s3", SdkObjectKind::Client))
+    )]
+    #[case::union_with_none(
+        "from mypy_boto3_s3 import S3Client\nx: S3Client | None\n",
+        Some(("s3", SdkObjectKind::Client))
+    )]
+    #[case::module_import(
+        "import mypy_boto3_s3 as s3_types\nx: s3_types.S3Client\n",
+        Some(("s3", SdkObjectKind::Client))
+    )]
+    #[case::qualified("import mypy_boto3_s3.client\nx: mypy_boto3_s3.client.S3Client\n", Some(("s3", SdkObjectKind::Client)))]
+    #[case::paginator(
+        "from mypy_boto3_s3.paginator import ListObjectsV2Paginator\nx: ListObjectsV2Paginator\n",
+        None
+    )]
+    #[case::type_def(
+        "from mypy_boto3_s3.type_defs import ObjectTypeDef\nx: ObjectTypeDef\n",
+        None
+    )]
+    #[case::conflicting_services(
+        "from mypy_boto3_s3 import S3Client\nfrom mypy_boto3_sqs import SQSClient\nx: S3Client | SQSClient\n",
+        None
+    )]
+    #[case::unrelated("from botocore.client import BaseClient\nx: BaseClient\n", None)]
+    fn test_resolve_annotation(
+        #[case] source_code: &str,
+        #[case] expected: Option<(&str, SdkObjectKind)>,
+    ) {
+        let ast_grep = Python.ast_grep(source_code);
+        let root = ast_grep.root();
+        let annotations = Boto3TypeAnnotations::collect(&root);
+        let annotation = root
+            .dfs()
+            .find(|node| node.kind() == node_kinds::ASSIGNMENT)
+            .and_then(|assignment| assignment.field("type"))
+            .expect("annotated assignment");
+
+        let resolved = annotations.resolve(&annotation);
+        assert_eq!(
+            resolved
+                .as_ref()
+                .map(|info| (info.service_name.as_str(), info.kind.clone().expect("kind"))),
+            expected
+        );
+    }
+}
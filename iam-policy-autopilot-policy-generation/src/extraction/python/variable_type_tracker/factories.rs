@@ -0,0 +1,243 @@
+//! Functions returning boto3 clients and resources, across the files of a project
+//!
+//! Clients are commonly created by a helper, in another module than the code calling them:
+//!
+//! ```python
+//! # app/aws.py
+//! def new_s3():
+//!     return boto3.client("s3")
+//!
+//! def make_client(name):
+//!     return session.client(name)
+//!
+//! # app/handler.py
+//! from app.aws import make_client, new_s3
+//!
+//! s3 = new_s3()
+//! sqs = make_client("sqs")
+//! ```
+//!
+//! The SDK objects module-level functions return are collected from every project file,
+//! so objects assigned from calls of them are typed like those created in place.
+
+use std::collections::HashMap;
+use std::sync::Arc;
+
+use super::types::{SdkObjectKind, VariableTypeInfo, VariableTypeTracker};
+use crate::extraction::python::common::StringConstants;
+use crate::extraction::python::node_kinds;
+use crate::extraction::AstWithSourceFile;
+use crate::SourceFile;
+use ast_grep_core::tree_sitter::LanguageExt;
+use ast_grep_language::Python;
+
+type PythonNode<'a> = ast_grep_core::Node<'a, ast_grep_core::tree_sitter::StrDoc<Python>>;
+
+/// What a client factory returns
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(super) enum ClientFactory {
+    /// An object of a fixed service: `def new_s3(): return boto3.client('s3')`
+    Fixed(VariableTypeInfo),
+    /// An object of the service passed as an argument:
+    /// `def make_client(name): return boto3.client(name)`
+    ServiceArgument {
+        /// Position of the parameter naming the service
+        position: usize,
+        /// Name of the parameter, for calls passing it by keyword
+        parameter: String,
+        kind: SdkObjectKind,
+    },
+}
+
+/// Module-level functions of a project returning boto3 clients and resources
+#[derive(Debug, Default)]
+pub(crate) struct ClientFactories {
+    /// Factories by function name; `None` for names defined several times with different
+    /// results, which calls can't be attributed by
+    factories: HashMap<String, Option<ClientFactory>>,
+}
+
+impl ClientFactories {
+    /// Collect the client factories of every project source file, resolving service names
+    /// against the project's `constants`
+    pub(crate) fn from_source_files(
+        source_files: &[SourceFile],
+        constants: &Arc<StringConstants>,
+    ) -> Self {
+        let mut factories = Self::default();
+        for source_file in source_files {
+            let ast =
+                AstWithSourceFile::new(Python.ast_grep(&source_file.content), source_file.clone());
+            let mut tracker =
+                VariableTypeTracker::new().with_project_constants(Arc::clone(constants));
+            tracker.track_boto3_assignments(&ast);
+            for (name, factory) in tracker.returned_objects(&ast.ast.root()) {
+                factories.insert(name, factory);
+            }
+        }
+        log::debug!(
+            "Collected {} Python client factories",
+            factories.factories.values().flatten().count()
+        );
+        factories
+    }
+
+    fn insert(&mut self, name: String, factory: ClientFactory) {
+        self.factories
+            .entry(name)
+            .and_modify(|existing| {
+                if existing.as_ref() != Some(&factory) {
+                    *existing = None;
+                }
+            })
+            .or_insert(Some(factory));
+    }
+
+    /// The factory a call of `callee` calls, e.g. `make_client` or `aws.make_client`
+    pub(super) fn get(&self, callee: &str) -> Option<&ClientFactory> {
+        let (object, name) = callee.rsplit_once('.').unwrap_or(("", callee));
+        // Methods aren't collected, so `self.make_client()` is another function
+        if object == "self" || object == "cls" {
+            return None;
+        }
+        self.factories.get(name)?.as_ref()
+    }
+}
+
+impl VariableTypeTracker {
+    /// The client factories among the module-level functions of a tracked file
+    ///
+    /// Functions whose returns resolve to different objects aren't factories.
+    fn returned_objects(&self, root: &PythonNode<'_>) -> Vec<(String, ClientFactory)> {
+        let mut factories = Vec::new();
+        for function in root
+            .dfs()
+            .filter(|node| node.kind() == node_kinds::FUNCTION_DEFINITION)
+        {
+            let Some(name) = function.field("name").map(|name| name.text().to_string()) else {
+                continue;
+            };
+            // Methods and nested functions aren't callable by name from other modules
+            if self.conflicted_functions.contains(&name)
+                || function.ancestors().any(|ancestor| {
+                    ancestor.kind() == node_kinds::CLASS_DEFINITION
+                        || ancestor.kind() == node_kinds::FUNCTION_DEFINITION
+                })
+            {
+                continue;
+            }
+            let parameters = function
+                .field("parameters")
+                .map(|parameters| {
+                    let text = parameters.text();
+                    Self::extract_all_params(text.trim_start_matches('(').trim_end_matches(')'))
+                })
+                .unwrap_or_default();
+
+            let function_id = function.node_id();
+            let mut returned: Vec<ClientFactory> = function
+                .dfs()
+                .filter(|node| node.kind() == node_kinds::RETURN_STATEMENT)
+                .filter(|node| {
+                    node.ancestors()
+                        .find(|ancestor| ancestor.kind() == node_kinds::FUNCTION_DEFINITION)
+                        .is_some_and(|enclosing| enclosing.node_id() == function_id)
+                })
+                .filter_map(|statement| {
+                    let value = statement.children().find(|child| child.is_named())?;
+                    self.returned_object(&value, &name, &parameters)
+                })
+                .collect();
+            returned.dedup();
+            if let [factory] = returned.as_slice() {
+                log::debug!("Tracked client factory '{name}': {factory:?}");
+                factories.push((name, factory.clone()));
+            }
+        }
+        factories
+    }
+
+    /// The object `value`, returned by `function`, is
+    fn returned_object(
+        &self,
+        value: &PythonNode<'_>,
+        function: &str,
+        parameters: &[String],
+    ) -> Option<ClientFactory> {
+        if let Some(type_info) = self.resolve_attribute_value(value, Some(function), "") {
+            return Some(ClientFactory::Fixed(type_info));
+        }
+
+        // `boto3.client(name)` or `session.resource(service_name=name)` with a parameter
+        if value.kind() != node_kinds::CALL {
+            return None;
+        }
+        let callee = value.field("function")?.text().to_string();
+        let kind = match callee.rsplit('.').next().unwrap_or_default() {
+            "client" | "create_client" => SdkObjectKind::Client,
+            "resource" => SdkObjectKind::Resource,
+            _ => return None,
+        };
+        let argument = value.field("arguments")?.children().find_map(|argument| {
+            if argument.kind() == node_kinds::KEYWORD_ARGUMENT {
+                let is_service = argument
+                    .field("name")
+                    .is_some_and(|name| name.text() == "service_name");
+                return is_service.then(|| argument.field("value")).flatten();
+            }
+            argument
+                .is_named()
+                .then_some(argument)
+                .filter(|argument| argument.kind() != node_kinds::COMMENT)
+        })?;
+        if argument.kind() != node_kinds::IDENTIFIER {
+            return None;
+        }
+        let parameter = argument.text().to_string();
+        let position = parameters.iter().position(|name| *name == parameter)?;
+        Some(ClientFactory::ServiceArgument {
+            position,
+            parameter,
+            kind,
+        })
+    }
+
+    /// The object a call of a client factory returns, e.g. `make_client('s3')`
+    pub(super) fn resolve_factory_call(&self, call: &PythonNode<'_>) -> Option<VariableTypeInfo> {
+        let callee = call.field("function")?.text().to_string();
+        match self.client_factories.get(&callee)? {
+            ClientFactory::Fixed(type_info) => Some(type_info.clone()),
+            ClientFactory::ServiceArgument {
+                position,
+                parameter,
+                kind,
+            } => {
+                let arguments = call.field("arguments")?;
+                let argument = arguments
+                    .children()
+                    .filter(|argument| argument.kind() == node_kinds::KEYWORD_ARGUMENT)
+                    .find(|argument| {
+                        argument
+                            .field("name")
+                            .is_some_and(|name| name.text() == parameter.as_str())
+                    })
+                    .and_then(|argument| argument.field("value"))
+                    .or_else(|| {
+                        arguments
+                            .children()
+                            .filter(|argument| {
+                                argument.is_named()
+                                    && argument.kind() != node_kinds::KEYWORD_ARGUMENT
+                                    && argument.kind() != node_kinds::COMMENT
+                            })
+                            .nth(*position)
+                    })?;
+                let service_name = self.resolve_service_name(&argument)?;
+                Some(VariableTypeInfo::from_service_with_kind(
+                    service_name,
+                    kind.clone(),
+                ))
+            }
+        }
+    }
+}
@@ -0,0 +1,211 @@
+//! Names under which a module refers to boto3
+//!
+//! Besides the usual `import boto3`, code may alias the module
+//! (`import boto3 as b3`), load it dynamically
+//! (`b3 = importlib.import_module("boto3")`) or import its members directly
+//! (`from boto3 import client`, `from boto3 import *`).
+
+use std::collections::HashMap;
+
+use ast_grep_language::Python;
+
+use crate::extraction::python::common::string_constants::string_literal_value;
+use crate::extraction::python::node_kinds;
+
+/// boto3 members that create clients, resources and sessions
+const BOTO3_MEMBERS: [&str; 3] = ["client", "resource", "Session"];
+
+/// Functions that load a module by name
+const IMPORT_FUNCTIONS: [&str; 3] = ["importlib.import_module", "import_module", "__import__"];
+
+/// How one Python module refers to boto3
+#[derive(Debug)]
+pub(super) struct Boto3Imports {
+    /// Local names bound to the boto3 module itself
+    module_names: Vec<String>,
+    /// Local names bound to boto3 members: local name -> member name
+    member_names: HashMap<String, String>,
+}
+
+impl Default for Boto3Imports {
+    fn default() -> Self {
+        Self {
+            module_names: vec!["boto3".to_string()],
+            member_names: HashMap::new(),
+        }
+    }
+}
+
+impl Boto3Imports {
+    /// Collect the boto3 names bound anywhere in a module
+    pub(super) fn collect(
+        root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) -> Self {
+        let mut imports = Self::default();
+
+        for node in root.dfs() {
+            let kind = node.kind();
+            if kind == node_kinds::IMPORT_STATEMENT {
+                // `import boto3 as b3`
+                for imported in node
+                    .children()
+                    .filter(|child| child.kind() == node_kinds::ALIASED_IMPORT)
+                {
+                    let (Some(name), Some(alias)) =
+                        (imported.field("name"), imported.field("alias"))
+                    else {
+                        continue;
+                    };
+                    if name.text() == "boto3" {
+                        imports.add_module_name(alias.text().to_string());
+                    }
+                }
+            } else if kind == node_kinds::IMPORT_FROM_STATEMENT {
+                imports.collect_member_imports(&node);
+            } else if kind == node_kinds::ASSIGNMENT {
+                // `b3 = importlib.import_module("boto3")`
+                let (Some(target), Some(value)) = (node.field("left"), node.field("right")) else {
+                    continue;
+                };
+                if target.kind() == node_kinds::IDENTIFIER && is_boto3_import_call(&value) {
+                    imports.add_module_name(target.text().to_string());
+                }
+            }
+        }
+
+        log::debug!(
+            "boto3 module names: {:?}, member names: {:?}",
+            imports.module_names,
+            imports.member_names
+        );
+        imports
+    }
+
+    /// Record names bound by `from boto3 import ...`
+    fn collect_member_imports(
+        &mut self,
+        statement: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) {
+        let Some(module) = statement.field("module_name") else {
+            return;
+        };
+        if module.text() != "boto3" {
+            return;
+        }
+
+        for imported in statement.children() {
+            if imported.node_id() == module.node_id() {
+                continue;
+            }
+            let kind = imported.kind();
+            if kind == node_kinds::WILDCARD_IMPORT {
+                for member in BOTO3_MEMBERS {
+                    self.member_names
+                        .insert(member.to_string(), member.to_string());
+                }
+            } else if kind == node_kinds::DOTTED_NAME {
+                let name = imported.text().to_string();
+                if BOTO3_MEMBERS.contains(&name.as_str()) {
+                    self.member_names.insert(name.clone(), name);
+                }
+            } else if kind == node_kinds::ALIASED_IMPORT {
+                let (Some(name), Some(alias)) = (imported.field("name"), imported.field("alias"))
+                else {
+                    continue;
+                };
+                let name = name.text().to_string();
+                if BOTO3_MEMBERS.contains(&name.as_str()) {
+                    self.member_names.insert(alias.text().to_string(), name);
+                }
+            }
+        }
+    }
+
+    fn add_module_name(&mut self, name: String) {
+        if !self.module_names.contains(&name) {
+            self.module_names.push(name);
+        }
+    }
+
+    /// Whether `name` refers to the boto3 module
+    pub(super) fn is_module(&self, name: &str) -> bool {
+        self.module_names.iter().any(|module| module == name)
+    }
+
+    /// The boto3 member a bare local name refers to, e.g. `client` after `from boto3 import *`
+    pub(super) fn member(&self, name: &str) -> Option<&str> {
+        self.member_names.get(name).map(String::as_str)
+    }
+
+    /// Every callee expression that refers to boto3 member `member`
+    ///
+    /// `callees("client")` yields `boto3.client`, `b3.client` for each module
+    /// alias, and bare names such as `client` imported from boto3.
+    pub(super) fn callees(&self, member: &str) -> Vec<String> {
+        let mut callees: Vec<String> = self
+            .module_names
+            .iter()
+            .map(|module| format!("{module}.{member}"))
+            .collect();
+        let mut imported: Vec<&String> = self
+            .member_names
+            .iter()
+            .filter(|(_, imported_member)| imported_member.as_str() == member)
+            .map(|(local_name, _)| local_name)
+            .collect();
+        imported.sort();
+        callees.extend(imported.into_iter().cloned());
+        callees
+    }
+}
+
+/// Whether `value` is a call loading the boto3 module by name
+fn is_boto3_import_call(
+    value: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+) -> bool {
+    if value.kind() != node_kinds::CALL {
+        return false;
+    }
+    let Some(function) = value.field("function") else {
+        return false;
+    };
+    let callee = function.text();
+    if !IMPORT_FUNCTIONS.iter().any(|name| *name == callee) {
+        return false;
+    }
+    value
+        .field("arguments")
+        .and_then(|arguments| arguments.children().find(|arg| arg.is_named()))
+        .and_then(|first| string_literal_value(&first))
+        .is_some_and(|module| module == "boto3")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use ast_grep_core::tree_sitter::LanguageExt;
+    use rstest::rstest;
+
+    #[rstest]
+    #[case::plain_import("import boto3\n", "client", &["boto3.client"])]
+    #[case::module_alias("import boto3 as b3\n", "resource", &["boto3.resource", "b3.resource"])]
+    #[case::importlib(
+        "import importlib\naws = importlib.import_module('boto3')\n",
+        "client",
+        &["boto3.client", "aws.client"]
+    )]
+    #[case::dunder_import("aws = __import__(\"boto3\")\n", "Session", &["boto3.Session", "aws.Session"])]
+    #[case::star_import("from boto3 import *\n", "client", &["boto3.client", "client"])]
+    #[case::member_import(
+        "from boto3 import resource, client as make_client\n",
+        "client",
+        &["boto3.client", "make_client"]
+    )]
+    #[case::other_module("from botocore import *\nx = importlib.import_module('json')\n", "client", &["boto3.client"])]
+    fn test_callees(#[case] source_code: &str, #[case] member: &str, #[case] expected: &[&str]) {
+        let ast_grep = Python.ast_grep(source_code);
+        let imports = Boto3Imports::collect(&ast_grep.root());
+
+        assert_eq!(imports.callees(member), expected);
+    }
+}
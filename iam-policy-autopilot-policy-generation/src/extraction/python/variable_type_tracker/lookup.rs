@@ -115,4 +115,58 @@ impl VariableTypeTracker {
                     .collect()
             })
     }
+
+    /// Look up the type information for an attribute receiver like `self.s3`
+    ///
+    /// - `self.attr` / `cls.attr` resolve against `class_name`, the class enclosing the call
+    /// - `ClassName.attr` resolves against `ClassName` directly
+    ///
+    /// Base classes are searched after the class itself (depth-first, declaration order),
+    /// so a client assigned in a parent's `__init__` resolves in subclass methods.
+    pub(crate) fn get_type_info_for_attribute_in_context(
+        &self,
+        receiver: &str,
+        class_name: Option<&str>,
+    ) -> Option<&VariableTypeInfo> {
+        let (object, attribute) = receiver.split_once('.')?;
+        let (object, attribute) = (object.trim(), attribute.trim());
+        if attribute.contains('.') {
+            return None;
+        }
+
+        let owner = if object == "self" || object == "cls" {
+            class_name?
+        } else {
+            object
+        };
+
+        let mut visited = HashSet::new();
+        self.find_class_attribute(owner, attribute, &mut visited)
+    }
+
+    /// Find an attribute on a class or, failing that, on its base classes
+    fn find_class_attribute(
+        &self,
+        class_name: &str,
+        attribute: &str,
+        visited: &mut HashSet<String>,
+    ) -> Option<&VariableTypeInfo> {
+        // Guard against cyclic hierarchies from same-named classes in one file
+        if !visited.insert(class_name.to_string()) {
+            return None;
+        }
+
+        if let Some(type_info) = self
+            .class_attributes
+            .get(class_name)
+            .and_then(|attributes| attributes.get(attribute))
+        {
+            return Some(type_info);
+        }
+
+        self.class_bases
+            .get(class_name)?
+            .iter()
+            .find_map(|base| self.find_class_attribute(base, attribute, visited))
+    }
 }
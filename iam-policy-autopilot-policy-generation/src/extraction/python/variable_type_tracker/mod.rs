@@ -4,15 +4,22 @@
 //! SDK method call extraction precision when variables are passed across
 //! function boundaries.
 //!
+//! Objects returned by module-level functions of any project file, such as
+//! `def create_client(): return boto3.client('s3')`, are typed at the call sites of the
+//! function, see [`factories`].
+//!
 //! ## Not Yet Supported
 //!
-//! - **Function return values**: `def create_client(): return boto3.client('s3')`
-//! - **Class attributes**: `self.client = boto3.client('s3')`
+//! - **Instance attributes set outside the class**: `handler.client = boto3.client('s3')`
 
+mod annotations;
+mod factories;
+mod imports;
 mod lookup;
 mod tracking;
 mod types;
 
+pub(crate) use factories::ClientFactories;
 pub(crate) use types::VariableTypeTracker;
 
 #[cfg(test)]
@@ -1,9 +1,12 @@
+use super::factories::ClientFactories;
 use super::types::*;
+use crate::extraction::python::common::StringConstants;
 use crate::extraction::AstWithSourceFile;
 use crate::SourceFile;
 use ast_grep_core::tree_sitter::LanguageExt;
 use ast_grep_language::Python;
 use rstest::rstest;
+use std::sync::Arc;
 
 fn create_ast(source_code: &str) -> AstWithSourceFile<Python> {
     let source_file = SourceFile::with_language(
@@ -142,6 +145,20 @@ upload(s3)
     "s3",
     SdkObjectKind::Client
 )]
+#[case(
+    "session.create_client('s3')",
+    "botocore.session.get_session()",
+    "s3",
+    "s3",
+    SdkObjectKind::Client
+)]
+#[case(
+    "session.create_client('sqs', region_name='us-east-1')",
+    "botocore.session.Session()",
+    "queue",
+    "sqs",
+    SdkObjectKind::Client
+)]
 fn test_session_factory_tracking(
     #[case] factory_call: &str,
     #[case] session_init: &str,
@@ -182,6 +199,49 @@ def create_clients():
     );
 }
 
+#[rstest]
+#[case::chained_get_session(
+    "import botocore.session\n\nclient = botocore.session.get_session().create_client('s3')\n"
+)]
+#[case::chained_session(
+    "import botocore.session\n\nclient = botocore.session.Session().create_client('s3')\n"
+)]
+#[case::imported_get_session(
+    "from botocore.session import get_session\n\nclient = get_session().create_client('s3')\n"
+)]
+#[case::imported_session_in_function(
+    "from botocore.session import Session\n\ndef run():\n    session = Session()\n    client = session.create_client('s3')\n"
+)]
+#[case::aliased_import(
+    "from botocore.session import Session as CoreSession\n\nsession = CoreSession()\nclient = session.create_client('s3')\n"
+)]
+fn test_botocore_client_tracking(#[case] source_code: &str) {
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    let info = tracker
+        .get_type_info_for_variable_in_context("client", Some("run"))
+        .expect("botocore client should be tracked");
+    assert_eq!(info.service_name, "s3");
+    assert_eq!(info.kind, Some(SdkObjectKind::Client));
+}
+
+#[test]
+fn test_session_not_imported_from_botocore_not_matched() {
+    let source_code = r#"
+from sqlalchemy.orm import Session
+
+session = Session()
+client = session.create_client('s3')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert!(tracker.get_service_for_variable("client").is_none());
+}
+
 #[test]
 fn test_return_value_not_tracked() {
     // Return value tracking is not yet supported — documenting the limitation.
@@ -1179,3 +1239,693 @@ def process_data():
         tracker.get_service_for_variable_in_context("client", Some("process_data"));
     assert_ne!(module_service, function_service);
 }
+
+// ========== Class Attribute Tests ==========
+
+fn attribute_service<'a>(
+    tracker: &'a VariableTypeTracker,
+    receiver: &str,
+    class_name: &str,
+) -> Option<&'a String> {
+    tracker
+        .get_type_info_for_attribute_in_context(receiver, Some(class_name))
+        .map(|info| &info.service_name)
+}
+
+#[test]
+fn test_self_attribute_assigned_in_init() {
+    let source_code = r#"
+import boto3
+
+class Uploader:
+    def __init__(self):
+        self.s3 = boto3.client('s3')
+        self.dynamodb = boto3.resource('dynamodb')
+
+    def upload(self):
+        self.s3.put_object(Bucket='b', Key='k', Body=b'data')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    let info = tracker
+        .get_type_info_for_attribute_in_context("self.s3", Some("Uploader"))
+        .unwrap();
+    assert_eq!(info.service_name, "s3");
+    assert_eq!(info.kind, Some(SdkObjectKind::Client));
+
+    let info = tracker
+        .get_type_info_for_attribute_in_context("self.dynamodb", Some("Uploader"))
+        .unwrap();
+    assert_eq!(info.service_name, "dynamodb");
+    assert_eq!(info.kind, Some(SdkObjectKind::Resource));
+
+    // Without a class context, `self` can't be resolved
+    assert!(tracker
+        .get_type_info_for_attribute_in_context("self.s3", None)
+        .is_none());
+}
+
+#[test]
+fn test_class_level_attribute() {
+    let source_code = r#"
+import boto3
+
+class Notifier:
+    sns = boto3.client('sns')
+
+    def notify(self):
+        self.sns.publish(TopicArn='arn', Message='hi')
+
+Notifier.sns.publish(TopicArn='arn', Message='hi')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        attribute_service(&tracker, "self.sns", "Notifier"),
+        Some(&"sns".to_string())
+    );
+    assert_eq!(
+        tracker
+            .get_type_info_for_attribute_in_context("Notifier.sns", None)
+            .map(|info| info.service_name.as_str()),
+        Some("sns")
+    );
+}
+
+#[test]
+fn test_attribute_inherited_from_base_class() {
+    let source_code = r#"
+import boto3
+
+class BaseHandler:
+    def __init__(self):
+        self.client = boto3.client('sqs')
+
+class OrderHandler(BaseHandler):
+    def handle(self):
+        self.client.send_message(QueueUrl='url', MessageBody='body')
+
+class AuditHandler(handlers.OrderHandler):
+    def __init__(self):
+        super().__init__()
+        self.client = boto3.client('sns')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        attribute_service(&tracker, "self.client", "OrderHandler"),
+        Some(&"sqs".to_string())
+    );
+    // Subclass assignment overrides the inherited attribute
+    assert_eq!(
+        attribute_service(&tracker, "self.client", "AuditHandler"),
+        Some(&"sns".to_string())
+    );
+}
+
+#[test]
+fn test_attribute_from_tracked_variable_and_session() {
+    let source_code = r#"
+import boto3
+
+s3_client = boto3.client('s3')
+
+class Worker:
+    def __init__(self, region):
+        session = boto3.Session(region_name=region)
+        self.sqs = session.client('sqs')
+        self.s3 = s3_client
+
+    @classmethod
+    def configure(cls):
+        cls.kms = boto3.client('kms')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        attribute_service(&tracker, "self.sqs", "Worker"),
+        Some(&"sqs".to_string())
+    );
+    assert_eq!(
+        attribute_service(&tracker, "self.s3", "Worker"),
+        Some(&"s3".to_string())
+    );
+    assert_eq!(
+        attribute_service(&tracker, "cls.kms", "Worker"),
+        Some(&"kms".to_string())
+    );
+}
+
+#[test]
+fn test_resource_derived_attributes() {
+    let source_code = r#"
+import boto3
+
+class Repository:
+    def __init__(self):
+        self.dynamodb = boto3.resource('dynamodb')
+        self.table = self.dynamodb.Table('orders')
+        self.s3 = boto3.client('s3')
+        self.response = self.s3.get_object(Bucket='b', Key='k')
+
+    def archive_order(self):
+        archive = self.dynamodb.Table('archive')
+        archive.put_item(Item={})
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    let info = tracker
+        .get_type_info_for_attribute_in_context("self.table", Some("Repository"))
+        .unwrap();
+    assert_eq!(info.service_name, "dynamodb");
+    assert_eq!(info.kind, Some(SdkObjectKind::ResourceCollection));
+
+    // Client responses are data, not SDK objects
+    assert!(tracker
+        .get_type_info_for_attribute_in_context("self.response", Some("Repository"))
+        .is_none());
+
+    // Local variable derived from a resource attribute
+    assert_eq!(
+        tracker.get_service_for_variable_in_context("archive", Some("archive_order")),
+        Some(&"dynamodb".to_string())
+    );
+}
+
+#[test]
+fn test_attributes_scoped_to_their_class() {
+    let source_code = r#"
+import boto3
+
+class S3Store:
+    def __init__(self):
+        self.client = boto3.client('s3')
+
+class Ec2Store:
+    def __init__(self):
+        self.client = boto3.client('ec2')
+
+    def helper(self):
+        def inner():
+            self.other = boto3.client('kms')
+        other = boto3.client('sts')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        attribute_service(&tracker, "self.client", "S3Store"),
+        Some(&"s3".to_string())
+    );
+    assert_eq!(
+        attribute_service(&tracker, "self.client", "Ec2Store"),
+        Some(&"ec2".to_string())
+    );
+    // Assignments in nested functions and plain locals are not class attributes
+    assert!(attribute_service(&tracker, "self.other", "Ec2Store").is_none());
+    assert!(attribute_service(&tracker, "self.client", "Unknown").is_none());
+}
+
+// ========== Service Name Constant Tests ==========
+
+#[rstest]
+#[case("SERVICE = 's3'\nclient = boto3.client(SERVICE)\n", "s3")]
+#[case(
+    "SERVICE: str = \"sqs\"\nclient = boto3.client(SERVICE, region_name='us-east-1')\n",
+    "sqs"
+)]
+#[case(
+    "class Config:\n    TOPICS = 'sns'\n\nclient = boto3.client(Config.TOPICS)\n",
+    "sns"
+)]
+#[case(
+    "SERVICE = 'kms'\ndef make():\n    client = boto3.client(SERVICE)\n    return client\n",
+    "kms"
+)]
+fn test_service_name_from_constant_in_same_file(#[case] body: &str, #[case] expected: &str) {
+    let source_code = format!("import boto3\n{body}");
+    let ast = create_ast(&source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    let function = body.contains("def make").then_some("make");
+    assert_eq!(
+        tracker.get_service_for_variable_in_context("client", function),
+        Some(&expected.to_string())
+    );
+}
+
+#[test]
+fn test_service_name_from_constant_in_other_file() {
+    let project = [
+        SourceFile::with_language(
+            "app/settings.py".into(),
+            "QUEUE_SERVICE = 'sqs'\n".to_string(),
+            crate::Language::Python,
+        ),
+        SourceFile::with_language(
+            "app/enums.py".into(),
+            "class Service(Enum):\n    DYNAMODB = 'dynamodb'\n".to_string(),
+            crate::Language::Python,
+        ),
+    ];
+    let constants = Arc::new(StringConstants::from_source_files(&project));
+
+    let source_code = r#"
+import boto3
+from django.conf import settings
+from app.enums import Service
+
+queue = boto3.client(settings.QUEUE_SERVICE)
+db = boto3.resource(Service.DYNAMODB.value)
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new().with_project_constants(constants);
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        tracker.get_service_for_variable("queue"),
+        Some(&"sqs".to_string())
+    );
+    assert_eq!(
+        tracker.get_service_for_variable("db"),
+        Some(&"dynamodb".to_string())
+    );
+}
+
+#[test]
+fn test_unresolvable_service_name_is_not_tracked() {
+    let source_code = r#"
+import boto3
+
+def make(service_name):
+    client = boto3.client(service_name)
+
+unknown = boto3.client(UNDEFINED)
+formatted = boto3.client(f"{prefix}s3")
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert!(tracker
+        .get_service_for_variable_in_context("client", Some("make"))
+        .is_none());
+    assert!(tracker.get_service_for_variable("unknown").is_none());
+    assert!(tracker.get_service_for_variable("formatted").is_none());
+}
+
+// ========== boto3 Import Form Tests (parameterized) ==========
+
+#[rstest]
+#[case::star_import("from boto3 import *\n\nclient = client('s3')\n")]
+#[case::member_import("from boto3 import client as make_client\n\nclient = make_client('s3')\n")]
+#[case::module_alias("import boto3 as b3\n\nclient = b3.client('s3')\n")]
+#[case::importlib(
+    "import importlib\n\naws = importlib.import_module('boto3')\nclient = aws.client('s3')\n"
+)]
+#[case::importlib_session(
+    "from importlib import import_module\n\naws = import_module('boto3')\nsession = aws.Session()\nclient = session.client('s3')\n"
+)]
+#[case::star_import_session(
+    "from boto3 import *\n\nsession = Session()\nclient = session.client('s3')\n"
+)]
+fn test_boto3_import_forms(#[case] source_code: &str) {
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        tracker.get_service_for_variable("client"),
+        Some(&"s3".to_string())
+    );
+}
+
+#[test]
+fn test_star_imported_resource_on_class_attribute() {
+    let source_code = r#"
+from boto3 import *
+
+class Store:
+    def __init__(self):
+        self.db = resource('dynamodb')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    let info = tracker
+        .get_type_info_for_attribute_in_context("self.db", Some("Store"))
+        .expect("attribute should be tracked");
+    assert_eq!(info.service_name, "dynamodb");
+    assert_eq!(info.kind, Some(SdkObjectKind::Resource));
+}
+
+#[test]
+fn test_unrelated_client_function_not_tracked() {
+    let source_code = r#"
+import boto3
+from myapp.http import client
+
+api = client('s3')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert!(tracker.get_service_for_variable("api").is_none());
+}
+
+// ========== mypy_boto3 Type Annotation Tests ==========
+
+#[test]
+fn test_annotated_parameters_resolve_without_construction_site() {
+    let source_code = r#"
+from typing import TYPE_CHECKING, Optional
+
+if TYPE_CHECKING:
+    from mypy_boto3_s3 import S3Client
+    from mypy_boto3_dynamodb.service_resource import Table
+
+def upload(client: S3Client, table: "Table", queue_client=None):
+    client.put_object(Bucket='b', Key='k')
+
+def download(client: Optional[S3Client] = None):
+    client.get_object(Bucket='b', Key='k')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    let client = tracker
+        .get_type_info_for_variable_in_context("client", Some("upload"))
+        .expect("annotated client parameter");
+    assert_eq!(client.service_name, "s3");
+    assert_eq!(client.kind, Some(SdkObjectKind::Client));
+    assert_eq!(
+        client.qualified_type.as_deref(),
+        Some("mypy_boto3_s3.S3Client")
+    );
+
+    let table = tracker
+        .get_type_info_for_variable_in_context("table", Some("upload"))
+        .expect("annotated table parameter");
+    assert_eq!(table.service_name, "dynamodb");
+    assert_eq!(table.kind, Some(SdkObjectKind::ResourceCollection));
+
+    assert_eq!(
+        tracker.get_service_for_variable_in_context("client", Some("download")),
+        Some(&"s3".to_string())
+    );
+    assert!(tracker
+        .get_service_for_variable_in_context("queue_client", Some("upload"))
+        .is_none());
+}
+
+#[test]
+fn test_annotated_variables_and_dataclass_fields() {
+    let source_code = r#"
+from dataclasses import dataclass
+from mypy_boto3_sqs import SQSClient
+import mypy_boto3_sns as sns_types
+
+queue: SQSClient = make_queue_client()
+
+@dataclass
+class Notifier:
+    topics: sns_types.SNSClient
+    region: str = "us-east-1"
+
+    def notify(self):
+        self.topics.publish(TopicArn='arn', Message='hi')
+
+class Worker:
+    def __init__(self, client):
+        self.queue: SQSClient = client
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        tracker.get_service_for_variable("queue"),
+        Some(&"sqs".to_string())
+    );
+    assert_eq!(
+        attribute_service(&tracker, "self.topics", "Notifier"),
+        Some(&"sns".to_string())
+    );
+    assert!(attribute_service(&tracker, "self.region", "Notifier").is_none());
+    assert_eq!(
+        attribute_service(&tracker, "self.queue", "Worker"),
+        Some(&"sqs".to_string())
+    );
+}
+
+#[test]
+fn test_assignment_overrides_annotation() {
+    let source_code = r#"
+import boto3
+from mypy_boto3_s3 import S3Client
+
+def run(client: S3Client):
+    client = boto3.client('ec2')
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        tracker.get_service_for_variable_in_context("client", Some("run")),
+        Some(&"ec2".to_string())
+    );
+}
+
+// ========== Context Manager Tests ==========
+
+#[test]
+fn test_contextmanager_yielding_client() {
+    let source_code = r#"
+import boto3
+from contextlib import contextmanager
+
+@contextmanager
+def s3_client():
+    client = boto3.client('s3')
+    try:
+        yield client
+    finally:
+        client.close()
+
+class Repository:
+    def __init__(self):
+        self.db = boto3.resource('dynamodb')
+
+    @contextlib.contextmanager
+    def table(self):
+        yield self.db
+
+    def save(self, item):
+        with self.table() as db:
+            db.Table('items').put_item(Item=item)
+
+def download(key):
+    with s3_client() as s3, open('out', 'wb') as out:
+        s3.download_fileobj('bucket', key, out)
+
+with s3_client() as module_s3:
+    module_s3.list_buckets()
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        tracker.get_service_for_variable_in_context("s3", Some("download")),
+        Some(&"s3".to_string())
+    );
+    assert!(tracker
+        .get_service_for_variable_in_context("out", Some("download"))
+        .is_none());
+    assert_eq!(
+        tracker.get_service_for_variable("module_s3"),
+        Some(&"s3".to_string())
+    );
+    let db = tracker
+        .get_type_info_for_variable_in_context("db", Some("save"))
+        .expect("db should be tracked");
+    assert_eq!(db.service_name, "dynamodb");
+    assert_eq!(db.kind, Some(SdkObjectKind::Resource));
+}
+
+#[rstest]
+#[case::direct_client("import boto3\n\nwith boto3.client('s3') as client:\n    pass\n")]
+#[case::closing(
+    "import boto3\nfrom contextlib import closing\n\nwith closing(boto3.client('s3')) as client:\n    pass\n"
+)]
+#[case::aiobotocore(
+    "from aiobotocore.session import get_session\n\nsession = get_session()\nasync def main():\n    async with session.create_client('s3') as client:\n        await client.list_buckets()\n"
+)]
+#[case::async_contextmanager(
+    "from contextlib import asynccontextmanager\nfrom aiobotocore.session import get_session\n\n@asynccontextmanager\nasync def s3():\n    async with get_session().create_client('s3') as c:\n        yield c\n\nasync def main():\n    async with s3() as client:\n        await client.list_buckets()\n"
+)]
+fn test_with_statement_targets(#[case] source_code: &str) {
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        tracker.get_service_for_variable_in_context("client", Some("main")),
+        Some(&"s3".to_string())
+    );
+}
+
+#[test]
+fn test_undecorated_generator_not_treated_as_context_manager() {
+    let source_code = r#"
+import boto3
+
+def clients():
+    yield boto3.client('s3')
+
+with clients() as client:
+    pass
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new();
+    tracker.track_boto3_assignments(&ast);
+
+    assert!(tracker.get_service_for_variable("client").is_none());
+}
+
+// ========== Client Factory Tests ==========
+
+fn client_factories(files: &[(&str, &str)]) -> Arc<ClientFactories> {
+    let project: Vec<SourceFile> = files
+        .iter()
+        .map(|(path, content)| {
+            SourceFile::with_language(
+                (*path).into(),
+                (*content).to_string(),
+                crate::Language::Python,
+            )
+        })
+        .collect();
+    let constants = Arc::new(StringConstants::from_source_files(&project));
+    Arc::new(ClientFactories::from_source_files(&project, &constants))
+}
+
+#[test]
+fn test_client_factories_of_other_modules() {
+    let factories = client_factories(&[(
+        "app/aws.py",
+        r#"
+import boto3
+
+session = boto3.Session()
+
+def new_s3():
+    return boto3.client('s3')
+
+def new_table_resource():
+    db = session.resource('dynamodb')
+    return db
+
+def make_client(name, region=None):
+    return session.client(name, region_name=region)
+
+class Clients:
+    def sqs(self):
+        return boto3.client('sqs')
+"#,
+    )]);
+
+    let source_code = r#"
+from app import aws
+from app.aws import make_client, new_s3
+
+s3 = new_s3()
+kms = make_client(name='kms')
+
+def handle():
+    db = aws.new_table_resource()
+    queue = aws.make_client('sqs', 'us-east-1')
+    other = make_client(service)
+
+class Worker:
+    def __init__(self):
+        self.s3 = aws.new_s3()
+        self.sqs = self.sqs()
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new().with_client_factories(factories);
+    tracker.track_boto3_assignments(&ast);
+
+    assert_eq!(
+        tracker.get_service_for_variable("s3"),
+        Some(&"s3".to_string())
+    );
+    assert_eq!(
+        tracker.get_service_for_variable("kms"),
+        Some(&"kms".to_string())
+    );
+    let db = tracker
+        .get_type_info_for_variable_in_context("db", Some("handle"))
+        .expect("db should be tracked");
+    assert_eq!(db.service_name, "dynamodb");
+    assert_eq!(db.kind, Some(SdkObjectKind::Resource));
+    assert_eq!(
+        tracker.get_service_for_variable_in_context("queue", Some("handle")),
+        Some(&"sqs".to_string())
+    );
+    assert!(tracker
+        .get_service_for_variable_in_context("other", Some("handle"))
+        .is_none());
+    assert_eq!(
+        tracker
+            .get_type_info_for_attribute_in_context("self.s3", Some("Worker"))
+            .map(|type_info| type_info.service_name.as_str()),
+        Some("s3")
+    );
+    // Methods aren't factories callable by name
+    assert!(tracker
+        .get_type_info_for_attribute_in_context("self.sqs", Some("Worker"))
+        .is_none());
+}
+
+#[test]
+fn test_client_factories_defined_differently_are_ignored() {
+    let factories = client_factories(&[
+        ("app/storage.py", "import boto3\n\ndef client():\n    return boto3.client('s3')\n"),
+        ("app/queue.py", "import boto3\n\ndef client():\n    return boto3.client('sqs')\n"),
+        (
+            "app/either.py",
+            "import boto3\n\ndef either(flag):\n    if flag:\n        return boto3.client('s3')\n    return boto3.client('sqs')\n",
+        ),
+    ]);
+
+    let source_code = r#"
+from app.storage import client
+from app.either import either
+
+c = client()
+e = either(True)
+"#;
+    let ast = create_ast(source_code);
+    let mut tracker = VariableTypeTracker::new().with_client_factories(factories);
+    tracker.track_boto3_assignments(&ast);
+
+    assert!(tracker.get_service_for_variable("c").is_none());
+    assert!(tracker.get_service_for_variable("e").is_none());
+}
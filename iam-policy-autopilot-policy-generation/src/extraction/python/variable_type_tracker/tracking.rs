@@ -1,4 +1,7 @@
+use super::annotations::Boto3TypeAnnotations;
+use super::imports::Boto3Imports;
 use super::types::{SdkObjectKind, VariableTypeInfo, VariableTypeTracker};
+use crate::extraction::python::common::string_constants::string_literal_value;
 use crate::extraction::python::node_kinds;
 use crate::extraction::AstWithSourceFile;
 use ast_grep_language::Python;
@@ -9,24 +12,54 @@ impl VariableTypeTracker {
     ///
     /// This is the main entry point that orchestrates all tracking patterns:
     ///
-    /// 1. **Client assignments**: `boto3.client('service')` at module and function level
+    /// 1. **Client assignments**: `boto3.client('service')` at module and function level, including
+    ///    aliased, star-imported and `importlib`-loaded boto3 (`b3.client(...)`, `client(...)`)
     /// 2. **Resource assignments**: `boto3.resource('service')` at module and function level
-    /// 3. **Session-based assignments**: `session = boto3.Session(...)` then `session.client('service')`
+    /// 3. **Session-based assignments**: `session = boto3.Session(...)` then `session.client('service')`,
+    ///    and botocore sessions: `botocore.session.get_session().create_client('service')`
     /// 4. **Aliases**: `my_client = s3_client` at module and function level
     /// 5. **Function calls**: Infer parameter types from arguments at call sites
-    /// 6. **Resource-derived variables**: `table = dynamodb.Table('name')`, `bucket = s3.Bucket('name')`
+    /// 6. **Class attributes**: `self.s3 = boto3.client('s3')` in methods, `s3 = boto3.client('s3')` in class bodies
+    /// 7. **Context managers**: `with s3_client() as s3` where `s3_client` is a `@contextmanager`
+    ///    yielding a client, and `async with session.create_client('s3') as s3`
+    /// 8. **Type annotations**: `client: S3Client` parameters, variables and dataclass fields
+    ///    annotated with `mypy_boto3_*` stub types
+    /// 9. **Resource-derived variables**: `table = dynamodb.Table('name')`, `bucket = s3.Bucket('name')`
+    /// 10. **Client factories**: `s3 = new_s3()` and `sqs = make_client('sqs')` where the
+    ///     functions, defined in any project file, return a client
     pub(crate) fn track_boto3_assignments(&mut self, ast: &AstWithSourceFile<Python>) {
         let root = ast.ast.root();
 
+        self.file_constants.collect(&ast.source_file.path, &root);
         self.detect_conflicted_function_names(&root);
         self.collect_local_assignment_targets(&root);
-        self.track_boto3_factory_assignments(&root, "client", SdkObjectKind::Client);
-        self.track_boto3_factory_assignments(&root, "resource", SdkObjectKind::Resource);
+        // Annotations first, so an explicit assignment in the same scope takes precedence
+        self.track_type_annotations(&root);
+        self.boto3_imports = Boto3Imports::collect(&root);
+        for factory in self.boto3_imports.callees("client") {
+            self.track_boto3_factory_assignments(&root, &factory, SdkObjectKind::Client);
+        }
+        for factory in self.boto3_imports.callees("resource") {
+            self.track_boto3_factory_assignments(&root, &factory, SdkObjectKind::Resource);
+        }
+        self.session_constructors = session_constructors(&root, &self.boto3_imports);
+        // Chained botocore form: `botocore.session.get_session().create_client('s3')`
+        for constructor in self.session_constructors.clone() {
+            self.track_boto3_factory_assignments(
+                &root,
+                &format!("{constructor}().create_client"),
+                SdkObjectKind::Client,
+            );
+        }
         self.track_session_variables(&root);
         self.track_session_factory_assignments(&root, "client", SdkObjectKind::Client);
         self.track_session_factory_assignments(&root, "resource", SdkObjectKind::Resource);
+        self.track_session_factory_assignments(&root, "create_client", SdkObjectKind::Client);
+        self.track_client_factory_assignments(&root);
         self.track_aliases(&root);
         self.track_function_calls(&root);
+        self.track_class_attributes(&root);
+        self.track_context_managers(&root);
         self.track_resource_derived_variables(&root);
     }
 
@@ -104,14 +137,128 @@ impl VariableTypeTracker {
         }
     }
 
-    /// Track boto3 factory assignments (client or resource) at both function and module level.
+    /// Track parameters, variables and class attributes annotated with `mypy_boto3_*` types
+    ///
+    /// - `def upload(client: S3Client)` -> `client` is s3 within `upload`
+    /// - `client: S3Client = make_client()` -> `client` is s3 in the enclosing scope
+    /// - `s3: S3Client` in a (data)class body, or `self.s3: S3Client = ...` in a method ->
+    ///   attribute `s3` of the class is s3
+    fn track_type_annotations(
+        &mut self,
+        root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) {
+        let annotations = Boto3TypeAnnotations::collect(root);
+
+        for node in root.dfs() {
+            let kind = node.kind();
+            if kind == node_kinds::FUNCTION_DEFINITION {
+                let (Some(func_name), Some(parameters)) =
+                    (node.field("name"), node.field("parameters"))
+                else {
+                    continue;
+                };
+                let func_name = func_name.text().to_string();
+                for parameter in parameters.children() {
+                    let param_name = if parameter.kind() == node_kinds::TYPED_PARAMETER {
+                        parameter
+                            .children()
+                            .find(|child| child.kind() == node_kinds::IDENTIFIER)
+                    } else if parameter.kind() == node_kinds::TYPED_DEFAULT_PARAMETER {
+                        parameter.field("name")
+                    } else {
+                        continue;
+                    };
+                    let Some(param_name) = param_name.map(|name| name.text().to_string()) else {
+                        continue;
+                    };
+                    let Some(type_info) = parameter
+                        .field("type")
+                        .and_then(|annotation| annotations.resolve(&annotation))
+                    else {
+                        continue;
+                    };
+                    log::debug!(
+                        "Tracked annotated parameter in function '{func_name}': {param_name} -> {}",
+                        type_info.service_name
+                    );
+                    self.function_scopes
+                        .entry(func_name.clone())
+                        .or_default()
+                        .insert(param_name, type_info);
+                }
+            } else if kind == node_kinds::ASSIGNMENT {
+                let (Some(target), Some(type_info)) = (
+                    node.field("left"),
+                    node.field("type")
+                        .and_then(|annotation| annotations.resolve(&annotation)),
+                ) else {
+                    continue;
+                };
+
+                if let Some(class_node) = node
+                    .ancestors()
+                    .find(|ancestor| ancestor.kind() == node_kinds::CLASS_DEFINITION)
+                {
+                    if let (Some(class_name), Some((attribute, _))) = (
+                        class_node.field("name"),
+                        class_attribute_target(&node, &target, class_node.node_id()),
+                    ) {
+                        log::debug!(
+                            "Tracked annotated class attribute: {}.{attribute} -> {}",
+                            class_name.text(),
+                            type_info.service_name
+                        );
+                        self.class_attributes
+                            .entry(class_name.text().to_string())
+                            .or_default()
+                            .insert(attribute, type_info);
+                        continue;
+                    }
+                }
+
+                if target.kind() != node_kinds::IDENTIFIER {
+                    continue;
+                }
+                let var_name = target.text().to_string();
+                let enclosing_function = node
+                    .ancestors()
+                    .find(|ancestor| ancestor.kind() == node_kinds::FUNCTION_DEFINITION)
+                    .and_then(|function| function.field("name"));
+                match enclosing_function {
+                    Some(func_name) => {
+                        log::debug!(
+                            "Tracked annotated variable in function '{}': {var_name} -> {}",
+                            func_name.text(),
+                            type_info.service_name
+                        );
+                        self.function_scopes
+                            .entry(func_name.text().to_string())
+                            .or_default()
+                            .insert(var_name, type_info);
+                    }
+                    None => {
+                        log::debug!(
+                            "Tracked annotated variable at module level: {var_name} -> {}",
+                            type_info.service_name
+                        );
+                        self.module_scope.insert(var_name, type_info);
+                    }
+                }
+            }
+        }
+    }
+
+    /// Track factory assignments such as `boto3.client` or `boto3.resource` at both
+    /// function and module level.
+    ///
+    /// `factory` is the full callee expression, e.g. `boto3.client`.
     fn track_boto3_factory_assignments(
         &mut self,
         root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
-        factory_method: &str,
+        factory: &str,
         kind: SdkObjectKind,
     ) {
-        let assign_pattern = format!("$VAR = boto3.{factory_method}($$$ARGS)");
+        let assign_pattern = format!("$VAR = {factory}($$$ARGS)");
 
         // First, track function-level assignments
         let func_def_pattern = "def $FUNC($$$): $$$BODY";
@@ -138,13 +285,13 @@ impl VariableTypeTracker {
                     continue;
                 };
 
-                let Some(service_name) = Self::extract_first_positional_string_arg(assign_env)
+                let Some(service_name) = self.extract_first_positional_service_arg(assign_env)
                 else {
                     continue;
                 };
 
                 log::debug!(
-                    "Tracked boto3.{factory_method} assignment in function '{func_name}': {var_name} -> {service_name}"
+                    "Tracked {factory} assignment in function '{func_name}': {var_name} -> {service_name}"
                 );
 
                 self.function_scopes
@@ -171,12 +318,12 @@ impl VariableTypeTracker {
                 continue;
             };
 
-            let Some(service_name) = Self::extract_first_positional_string_arg(env) else {
+            let Some(service_name) = self.extract_first_positional_service_arg(env) else {
                 continue;
             };
 
             log::debug!(
-                "Tracked boto3.{factory_method} assignment at module level: {var_name} -> {service_name}"
+                "Tracked {factory} assignment at module level: {var_name} -> {service_name}"
             );
             self.module_scope.insert(
                 var_name,
@@ -185,9 +332,10 @@ impl VariableTypeTracker {
         }
     }
 
-    /// Extract the first positional string argument from a matched `$$$ARGS` list.
-    /// Skips keyword arguments and commas; returns the unquoted string content.
-    fn extract_first_positional_string_arg(
+    /// Extract the service name from the first positional argument of a matched
+    /// `$$$ARGS` list. Skips keyword arguments and commas.
+    fn extract_first_positional_service_arg(
+        &self,
         env: &ast_grep_core::meta_var::MetaVarEnv<ast_grep_core::tree_sitter::StrDoc<Python>>,
     ) -> Option<String> {
         let arg_nodes = env.get_multiple_matches("ARGS");
@@ -200,17 +348,72 @@ impl VariableTypeTracker {
             if arg_node.kind() == node_kinds::KEYWORD_ARGUMENT {
                 continue;
             }
-            return Some(Self::extract_string_literal(trimmed));
+            return self.resolve_service_name(arg_node);
         }
         None
     }
 
-    /// Track `boto3.Session(...)` assignments to identify session variables
+    /// Extract the service name from the first positional argument of a call node
+    fn first_positional_service_arg(
+        &self,
+        call: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) -> Option<String> {
+        let arguments = call.field("arguments")?;
+        let first = arguments.children().find(|arg| {
+            arg.is_named()
+                && arg.kind() != node_kinds::KEYWORD_ARGUMENT
+                && arg.kind() != node_kinds::COMMENT
+        })?;
+        self.resolve_service_name(&first)
+    }
+
+    /// Resolve a service name argument to its value
+    ///
+    /// Accepts a string literal or a reference to a string constant defined in this
+    /// file or elsewhere in the project: `SERVICE`, `settings.S3_SERVICE`,
+    /// `Service.S3.value`. Anything else (function parameters, f-strings, calls)
+    /// is left unresolved rather than guessed.
+    pub(super) fn resolve_service_name(
+        &self,
+        node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) -> Option<String> {
+        if let Some(value) = string_literal_value(node) {
+            return Some(value);
+        }
+
+        if node.kind() != node_kinds::IDENTIFIER && node.kind() != node_kinds::ATTRIBUTE {
+            log::debug!(
+                "Service name argument '{}' is not statically known",
+                node.text()
+            );
+            return None;
+        }
+
+        let expr = node.text();
+        let resolved = self
+            .file_constants
+            .resolve(&expr)
+            .or_else(|| self.project_constants.resolve(&expr));
+        match &resolved {
+            Some(service_name) => {
+                log::debug!("Resolved service name constant '{expr}' -> '{service_name}'");
+            }
+            None => log::debug!("Could not resolve service name constant '{expr}'"),
+        }
+        resolved
+    }
+
+    /// Track `boto3.Session(...)` and botocore session assignments to identify
+    /// session variables
     fn track_session_variables(
         &mut self,
         root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
     ) {
-        let pattern = "$VAR = boto3.Session($$$ARGS)";
+        let patterns: Vec<String> = self
+            .session_constructors
+            .iter()
+            .map(|constructor| format!("$VAR = {constructor}($$$ARGS)"))
+            .collect();
 
         // Track function-level session variables
         let func_def_pattern = "def $FUNC($$$): $$$BODY";
@@ -223,51 +426,54 @@ impl VariableTypeTracker {
             };
 
             let caller_node_id = func_match.get_node().node_id();
-            for node_match in func_match.get_node().find_all(pattern) {
-                if has_intervening_function(&node_match, caller_node_id) {
+            for pattern in &patterns {
+                for node_match in func_match.get_node().find_all(pattern.as_str()) {
+                    if has_intervening_function(&node_match, caller_node_id) {
+                        continue;
+                    }
+
+                    let assign_env = node_match.get_env();
+                    let var_name = if let Some(var_node) = assign_env.get_match("VAR") {
+                        var_node.text().to_string()
+                    } else {
+                        continue;
+                    };
+
+                    log::debug!("Tracked session assignment in function '{func_name}': {var_name}");
+                    self.session_variables
+                        .entry(Some(func_name.clone()))
+                        .or_default()
+                        .insert(var_name);
+                }
+            }
+        }
+
+        // Track module-level session variables
+        for pattern in &patterns {
+            for node_match in root.find_all(pattern.as_str()) {
+                if is_inside_function(&node_match) {
                     continue;
                 }
 
-                let assign_env = node_match.get_env();
-                let var_name = if let Some(var_node) = assign_env.get_match("VAR") {
+                let env = node_match.get_env();
+                let var_name = if let Some(var_node) = env.get_match("VAR") {
                     var_node.text().to_string()
                 } else {
                     continue;
                 };
 
-                log::debug!(
-                    "Tracked boto3.Session assignment in function '{func_name}': {var_name}"
-                );
+                log::debug!("Tracked session assignment at module level: {var_name}");
                 self.session_variables
-                    .entry(Some(func_name.clone()))
+                    .entry(None)
                     .or_default()
                     .insert(var_name);
             }
         }
-
-        // Track module-level session variables
-        for node_match in root.find_all(pattern) {
-            if is_inside_function(&node_match) {
-                continue;
-            }
-
-            let env = node_match.get_env();
-            let var_name = if let Some(var_node) = env.get_match("VAR") {
-                var_node.text().to_string()
-            } else {
-                continue;
-            };
-
-            log::debug!("Tracked boto3.Session assignment at module level: {var_name}");
-            self.session_variables
-                .entry(None)
-                .or_default()
-                .insert(var_name);
-        }
     }
 
-    /// Track `session.client('service')` / `session.resource('service')` assignments
-    /// where `session` is a known boto3.Session() variable in the same scope.
+    /// Track `session.client('service')` / `session.resource('service')` /
+    /// `session.create_client('service')` assignments where `session` is a known
+    /// session variable in the same scope.
     fn track_session_factory_assignments(
         &mut self,
         root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
@@ -330,7 +536,7 @@ impl VariableTypeTracker {
                     continue;
                 };
 
-                let Some(service_name) = Self::extract_first_positional_string_arg(assign_env)
+                let Some(service_name) = self.extract_first_positional_service_arg(assign_env)
                 else {
                     continue;
                 };
@@ -379,7 +585,7 @@ impl VariableTypeTracker {
                 continue;
             };
 
-            let Some(service_name) = Self::extract_first_positional_string_arg(env) else {
+            let Some(service_name) = self.extract_first_positional_service_arg(env) else {
                 continue;
             };
 
@@ -393,6 +599,55 @@ impl VariableTypeTracker {
         }
     }
 
+    /// Track variables assigned the result of a client factory at module and function
+    /// level
+    ///
+    /// Pattern: `s3 = new_s3()`, `sqs = aws.make_client('sqs')` where the functions are
+    /// defined in any file of the project and return a client or resource
+    fn track_client_factory_assignments(
+        &mut self,
+        root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) {
+        for assignment in root
+            .dfs()
+            .filter(|node| node.kind() == node_kinds::ASSIGNMENT)
+        {
+            let (Some(target), Some(value)) = (assignment.field("left"), assignment.field("right"))
+            else {
+                continue;
+            };
+            if target.kind() != node_kinds::IDENTIFIER || value.kind() != node_kinds::CALL {
+                continue;
+            }
+            let Some(type_info) = self.resolve_factory_call(&value) else {
+                continue;
+            };
+
+            let func_name = assignment
+                .ancestors()
+                .find(|ancestor| ancestor.kind() == node_kinds::FUNCTION_DEFINITION)
+                .and_then(|function| function.field("name"))
+                .map(|name| name.text().to_string());
+            let var_name = target.text().to_string();
+            log::debug!(
+                "Tracked client factory assignment in {}: {var_name} -> {}",
+                func_name.as_deref().unwrap_or("module scope"),
+                type_info.service_name
+            );
+            match func_name {
+                Some(func_name) => {
+                    self.function_scopes
+                        .entry(func_name)
+                        .or_default()
+                        .insert(var_name, type_info);
+                }
+                None => {
+                    self.module_scope.insert(var_name, type_info);
+                }
+            }
+        }
+    }
+
     /// Track simple variable aliases at module and function level
     /// Pattern: `my_client = s3_client` where s3_client is already tracked
     fn track_aliases(
@@ -714,6 +969,387 @@ impl VariableTypeTracker {
             .collect()
     }
 
+    /// Track boto3 clients and resources stored as class attributes
+    ///
+    /// Patterns:
+    /// - `self.s3 = boto3.client('s3')` inside a method (typically `__init__`)
+    /// - `cls.s3 = session.client('s3')` inside a classmethod
+    /// - `s3 = boto3.client('s3')` directly in the class body
+    /// - `self.s3 = s3_client` where `s3_client` is already tracked
+    /// - `self.table = self.dynamodb.Table('name')` derived from another attribute
+    ///
+    /// Base classes are recorded as well, so lookups on a subclass fall back
+    /// to attributes assigned by its parents.
+    fn track_class_attributes(
+        &mut self,
+        root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) {
+        let class_nodes: Vec<_> = root
+            .dfs()
+            .filter(|node| node.kind() == node_kinds::CLASS_DEFINITION)
+            .collect();
+
+        // Record inheritance first so attribute values can reference parent attributes
+        for class_node in &class_nodes {
+            let Some(class_name) = class_node.field("name").map(|n| n.text().to_string()) else {
+                continue;
+            };
+            let Some(superclasses) = class_node.field("superclasses") else {
+                continue;
+            };
+
+            // `class S3Handler(base.Handler, metaclass=ABCMeta)` -> ["Handler"]
+            let bases: Vec<String> = superclasses
+                .children()
+                .filter(|base| {
+                    base.kind() == node_kinds::IDENTIFIER || base.kind() == node_kinds::ATTRIBUTE
+                })
+                .filter_map(|base| {
+                    base.text()
+                        .rsplit('.')
+                        .next()
+                        .map(|name| name.trim().to_string())
+                })
+                .collect();
+
+            if !bases.is_empty() {
+                self.class_bases
+                    .entry(class_name)
+                    .or_default()
+                    .extend(bases);
+            }
+        }
+
+        let assign_pattern = "$TARGET = $VALUE";
+        for class_node in &class_nodes {
+            let Some(class_name) = class_node.field("name").map(|n| n.text().to_string()) else {
+                continue;
+            };
+
+            let class_node_id = class_node.node_id();
+            for node_match in class_node.find_all(assign_pattern) {
+                let env = node_match.get_env();
+                let (Some(target), Some(value)) = (env.get_match("TARGET"), env.get_match("VALUE"))
+                else {
+                    continue;
+                };
+
+                let Some((attribute, method_name)) =
+                    class_attribute_target(node_match.get_node(), target, class_node_id)
+                else {
+                    continue;
+                };
+
+                let Some(type_info) =
+                    self.resolve_attribute_value(value, method_name.as_deref(), &class_name)
+                else {
+                    continue;
+                };
+
+                log::debug!(
+                    "Tracked class attribute: {}.{} -> service '{}'",
+                    class_name,
+                    attribute,
+                    type_info.service_name
+                );
+                self.class_attributes
+                    .entry(class_name.clone())
+                    .or_default()
+                    .insert(attribute, type_info);
+            }
+        }
+    }
+
+    /// Resolve the type of an expression such as a value assigned to a class attribute
+    ///
+    /// `method_name` is the enclosing function (None for class-body or module-level
+    /// expressions) and is used to resolve local variables, parameters and session
+    /// variables; `class_name` resolves `self.attr` references.
+    pub(super) fn resolve_attribute_value(
+        &self,
+        value: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+        method_name: Option<&str>,
+        class_name: &str,
+    ) -> Option<VariableTypeInfo> {
+        let kind = value.kind();
+
+        if kind == node_kinds::IDENTIFIER {
+            return self
+                .get_type_info_for_variable_in_context(&value.text(), method_name)
+                .cloned();
+        }
+
+        if kind == node_kinds::ATTRIBUTE {
+            return self
+                .get_type_info_for_attribute_in_context(&value.text(), Some(class_name))
+                .cloned();
+        }
+
+        if kind != node_kinds::CALL {
+            return None;
+        }
+
+        let function = value.field("function")?;
+        // `client('s3')` after `from boto3 import client` (or `import *`)
+        if function.kind() == node_kinds::IDENTIFIER {
+            let factory_kind = match self.boto3_imports.member(&function.text()) {
+                Some("client") => SdkObjectKind::Client,
+                Some("resource") => SdkObjectKind::Resource,
+                // `make_client('s3')`: a project function returning a client
+                _ => return self.resolve_factory_call(value),
+            };
+            let service_name = self.first_positional_service_arg(value)?;
+            return Some(VariableTypeInfo::from_service_with_kind(
+                service_name,
+                factory_kind,
+            ));
+        }
+        if function.kind() != node_kinds::ATTRIBUTE {
+            return None;
+        }
+        let object = function.field("object")?;
+        let called = function.field("attribute")?.text().to_string();
+
+        let factory_kind = match called.as_str() {
+            "client" | "create_client" => Some(SdkObjectKind::Client),
+            "resource" => Some(SdkObjectKind::Resource),
+            _ => None,
+        };
+
+        if let Some(factory_kind) = factory_kind {
+            let object_name = object.text().to_string();
+            // `get_session().create_client('s3')`: a session constructed in place
+            let is_session_call = object.kind() == node_kinds::CALL
+                && object.field("function").is_some_and(|function| {
+                    self.session_constructors
+                        .iter()
+                        .any(|constructor| *constructor == function.text())
+                });
+            if !is_session_call
+                && !self.boto3_imports.is_module(&object_name)
+                && !self.is_session_in_context(&object_name, method_name)
+            {
+                return None;
+            }
+            let service_name = self.first_positional_service_arg(value)?;
+            return Some(VariableTypeInfo::from_service_with_kind(
+                service_name,
+                factory_kind,
+            ));
+        }
+
+        // `aws.make_client('s3')`: a project function of another module
+        if let Some(type_info) = self.resolve_factory_call(value) {
+            return Some(type_info);
+        }
+        if called == "get_paginator" || called == "get_waiter" {
+            return None;
+        }
+
+        // `self.s3.Bucket('name')`: only resources have sub-resource constructors;
+        // a client method call returns response data, not an SDK object.
+        let base = self.resolve_attribute_value(&object, method_name, class_name)?;
+        if base.kind == Some(SdkObjectKind::Client) {
+            return None;
+        }
+        Some(VariableTypeInfo::from_service_with_kind(
+            base.service_name,
+            SdkObjectKind::ResourceCollection,
+        ))
+    }
+
+    /// Track variables bound by `with` statements to SDK objects
+    ///
+    /// - `with s3_client() as s3:` where `s3_client` is decorated with
+    ///   `@contextmanager` / `@asynccontextmanager` and yields a known client
+    /// - `with boto3.client('s3') as s3:` and `async with session.create_client('s3') as s3:`
+    /// - `with closing(boto3.client('s3')) as s3:`
+    fn track_context_managers(
+        &mut self,
+        root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    ) {
+        // Direct `with` targets first, so context managers can yield them
+        self.track_with_targets(root, &HashMap::new());
+
+        // Generator-based context managers, by function name -> type of the yielded value
+        let mut context_managers: HashMap<String, VariableTypeInfo> = HashMap::new();
+        for decorated in root
+            .dfs()
+            .filter(|node| node.kind() == node_kinds::DECORATED_DEFINITION)
+        {
+            if !decorated
+                .children()
+                .filter(|child| child.kind() == node_kinds::DECORATOR)
+                .any(|decorator| is_context_manager_decorator(&decorator.text()))
+            {
+                continue;
+            }
+            let Some(function) = decorated
+                .field("definition")
+                .filter(|node| node.kind() == node_kinds::FUNCTION_DEFINITION)
+            else {
+                continue;
+            };
+            let Some(func_name) = function.field("name").map(|name| name.text().to_string()) else {
+                continue;
+            };
+            let class_name = enclosing_class_name(&function).unwrap_or_default();
+
+            let function_id = function.node_id();
+            let yielded = function
+                .dfs()
+                .filter(|node| node.kind() == node_kinds::YIELD)
+                .filter(|node| {
+                    node.ancestors()
+                        .find(|ancestor| ancestor.kind() == node_kinds::FUNCTION_DEFINITION)
+                        .is_some_and(|enclosing| enclosing.node_id() == function_id)
+                })
+                .find_map(|yield_node| {
+                    let value = yield_node.children().find(|child| child.is_named())?;
+                    self.resolve_attribute_value(&value, Some(&func_name), &class_name)
+                });
+            if let Some(type_info) = yielded {
+                log::debug!(
+                    "Tracked context manager '{func_name}' yielding service '{}'",
+                    type_info.service_name
+                );
+                context_managers.insert(func_name, type_info);
+            }
+        }
+
+        if !context_managers.is_empty() {
+            self.track_with_targets(root, &context_managers);
+        }
+    }
+
+    /// Bind `with ... as name` targets whose value resolves to an SDK object
+    fn track_with_targets(
+        &mut self,
+        root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+        context_managers: &HashMap<String, VariableTypeInfo>,
+    ) {
+        for as_pattern in root
+            .dfs()
+            .filter(|node| node.kind() == node_kinds::AS_PATTERN)
+        {
+            if !as_pattern
+                .parent()
+                .is_some_and(|parent| parent.kind() == node_kinds::WITH_ITEM)
+            {
+                continue;
+            }
+            let (Some(value), Some(alias)) = (
+                as_pattern.children().find(|child| child.is_named()),
+                as_pattern.field("alias"),
+            ) else {
+                continue;
+            };
+            let Some(target) = alias
+                .children()
+                .find(|child| child.is_named())
+                .filter(|target| target.kind() == node_kinds::IDENTIFIER)
+            else {
+                continue;
+            };
+
+            let function = as_pattern
+                .ancestors()
+                .find(|ancestor| ancestor.kind() == node_kinds::FUNCTION_DEFINITION);
+            let func_name = function
+                .as_ref()
+                .and_then(|function| function.field("name"))
+                .map(|name| name.text().to_string());
+            let class_name = function
+                .as_ref()
+                .and_then(enclosing_class_name)
+                .unwrap_or_default();
+
+            let Some(type_info) = self.resolve_context_value(
+                &value,
+                func_name.as_deref(),
+                &class_name,
+                context_managers,
+            ) else {
+                continue;
+            };
+
+            let var_name = target.text().to_string();
+            log::debug!(
+                "Tracked with-statement target in {}: {var_name} -> {}",
+                func_name.as_deref().unwrap_or("module scope"),
+                type_info.service_name
+            );
+            match func_name {
+                Some(func_name) => {
+                    self.function_scopes
+                        .entry(func_name)
+                        .or_default()
+                        .insert(var_name, type_info);
+                }
+                None => {
+                    self.module_scope.insert(var_name, type_info);
+                }
+            }
+        }
+    }
+
+    /// Resolve the object a `with` item binds its target to
+    fn resolve_context_value(
+        &self,
+        value: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+        function_name: Option<&str>,
+        class_name: &str,
+        context_managers: &HashMap<String, VariableTypeInfo>,
+    ) -> Option<VariableTypeInfo> {
+        if value.kind() == node_kinds::CALL {
+            let callee = value.field("function")?.text().to_string();
+            let callee_name = callee.rsplit('.').next().unwrap_or_default();
+            if let Some(type_info) = context_managers.get(callee_name) {
+                return Some(type_info.clone());
+            }
+            // `contextlib.closing(client)` yields its argument
+            if callee_name == "closing" {
+                let argument = value
+                    .field("arguments")?
+                    .children()
+                    .find(|arg| arg.is_named() && arg.kind() != node_kinds::COMMENT)?;
+                return self.resolve_context_value(
+                    &argument,
+                    function_name,
+                    class_name,
+                    context_managers,
+                );
+            }
+        }
+        self.resolve_attribute_value(value, function_name, class_name)
+    }
+
+    /// Check whether `name` is a known session variable in the given function,
+    /// falling back to module scope unless the function shadows it locally.
+    fn is_session_in_context(&self, name: &str, function_name: Option<&str>) -> bool {
+        if let Some(func_name) = function_name {
+            let in_func_sessions = self
+                .session_variables
+                .get(&Some(func_name.to_string()))
+                .is_some_and(|vars| vars.contains(name));
+            if in_func_sessions {
+                return true;
+            }
+
+            let locally_shadowed = self
+                .local_assignments
+                .get(func_name)
+                .is_some_and(|vars| vars.contains(name));
+            if locally_shadowed {
+                return false;
+            }
+        }
+
+        self.session_variables
+            .get(&None)
+            .is_some_and(|vars| vars.contains(name))
+    }
+
     /// Track resource-derived variables like Table, Bucket, etc.
     ///
     /// Patterns:
@@ -774,6 +1410,14 @@ impl VariableTypeTracker {
 
                 let type_info = self
                     .get_type_info_for_variable_in_context(&resource_name, Some(&func_name))
+                    .or_else(|| {
+                        // `bucket = self.s3.Bucket('name')` inside a method
+                        let class_name = enclosing_class_name(func_match.get_node())?;
+                        self.get_type_info_for_attribute_in_context(
+                            &resource_name,
+                            Some(&class_name),
+                        )
+                    })
                     .cloned();
 
                 if let Some(type_info) = type_info {
@@ -855,20 +1499,58 @@ impl VariableTypeTracker {
             }
         }
     }
+}
 
-    /// Extract string content from a string literal
-    ///
-    /// Handles both single and double quotes:
-    /// - `'s3'` -> `s3`
-    /// - `"dynamodb"` -> `dynamodb`
-    pub(super) fn extract_string_literal(raw: &str) -> String {
-        raw.trim()
-            .trim_start_matches('\'')
-            .trim_start_matches('"')
-            .trim_end_matches('\'')
-            .trim_end_matches('"')
-            .to_string()
+/// Names exported by `botocore.session` that construct a session
+const BOTOCORE_SESSION_CONSTRUCTORS: [&str; 2] = ["Session", "get_session"];
+
+/// Callee expressions that construct a session in this module
+///
+/// Includes every name for `boto3.Session` and the qualified botocore constructors,
+/// plus the local names of `Session` / `get_session` imported from `botocore.session`
+/// (or `aiobotocore.session`, whose sessions create clients the same way).
+fn session_constructors(
+    root: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    boto3_imports: &Boto3Imports,
+) -> Vec<String> {
+    let mut constructors = boto3_imports.callees("Session");
+    constructors.push("botocore.session.Session".to_string());
+    constructors.push("botocore.session.get_session".to_string());
+
+    for statement in root
+        .dfs()
+        .filter(|node| node.kind() == node_kinds::IMPORT_FROM_STATEMENT)
+    {
+        let Some(module) = statement.field("module_name") else {
+            continue;
+        };
+        if module.text() != "botocore.session" && module.text() != "aiobotocore.session" {
+            continue;
+        }
+        for imported in statement.children() {
+            if imported.node_id() == module.node_id() {
+                continue;
+            }
+            let (name, local_name) = if imported.kind() == node_kinds::ALIASED_IMPORT {
+                let (Some(name), Some(alias)) = (imported.field("name"), imported.field("alias"))
+                else {
+                    continue;
+                };
+                (name.text().to_string(), alias.text().to_string())
+            } else if imported.kind() == node_kinds::DOTTED_NAME {
+                let name = imported.text().to_string();
+                (name.clone(), name)
+            } else {
+                continue;
+            };
+            if BOTOCORE_SESSION_CONSTRUCTORS.contains(&name.as_str())
+                && !constructors.contains(&local_name)
+            {
+                constructors.push(local_name);
+            }
+        }
     }
+    constructors
 }
 
 /// Check if a matched node is inside a function definition
@@ -905,6 +1587,82 @@ fn has_intervening_function(
     false
 }
 
+/// Whether a decorator (`@contextmanager`, `@contextlib.asynccontextmanager`) turns a
+/// generator function into a context manager
+fn is_context_manager_decorator(decorator: &str) -> bool {
+    let expression = decorator.trim_start_matches('@').trim();
+    let name = expression.rsplit('.').next().unwrap_or_default();
+    name == "contextmanager" || name == "asynccontextmanager"
+}
+
+/// Determine the class attribute written by an assignment inside `class_node_id`
+///
+/// Returns the attribute name and the enclosing method name (None for assignments
+/// directly in the class body). Assignments in nested classes or in functions
+/// nested inside methods are ignored.
+fn class_attribute_target(
+    assignment: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    target: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+    class_node_id: usize,
+) -> Option<(String, Option<String>)> {
+    let mut method = None;
+    let mut current = assignment.parent();
+    loop {
+        let node = current?;
+        if node.node_id() == class_node_id {
+            break;
+        }
+        let kind = node.kind();
+        if kind == node_kinds::CLASS_DEFINITION {
+            return None;
+        }
+        if kind == node_kinds::FUNCTION_DEFINITION {
+            if method.is_some() {
+                return None;
+            }
+            method = Some(node.clone());
+        }
+        current = node.parent();
+    }
+
+    let Some(method) = method else {
+        // Class body: `s3 = boto3.client('s3')`
+        return (target.kind() == node_kinds::IDENTIFIER)
+            .then(|| (target.text().to_string(), None));
+    };
+
+    // Method body: `self.s3 = ...` / `cls.s3 = ...`
+    if target.kind() != node_kinds::ATTRIBUTE {
+        return None;
+    }
+    let object = target.field("object")?;
+    if object.text() != "self" && object.text() != "cls" {
+        return None;
+    }
+    let attribute = target.field("attribute")?.text().to_string();
+    let method_name = method.field("name")?.text().to_string();
+    Some((attribute, Some(method_name)))
+}
+
+/// Name of the class a method is defined in, if the function is a method
+fn enclosing_class_name(
+    func_node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+) -> Option<String> {
+    let mut current = func_node.parent();
+    while let Some(node) = current {
+        let kind = node.kind();
+        if kind == node_kinds::CLASS_DEFINITION {
+            return node.field("name").map(|name| name.text().to_string());
+        }
+        if kind == node_kinds::BLOCK || kind == node_kinds::DECORATED_DEFINITION {
+            current = node.parent();
+            continue;
+        }
+        return None;
+    }
+    None
+}
+
 /// Check if a function definition is a method (immediate child of a class body)
 fn is_method(
     func_match: &ast_grep_core::NodeMatch<ast_grep_core::tree_sitter::StrDoc<Python>>,
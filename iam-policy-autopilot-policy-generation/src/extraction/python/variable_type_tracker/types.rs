@@ -1,4 +1,9 @@
 use std::collections::{HashMap, HashSet};
+use std::sync::Arc;
+
+use super::factories::ClientFactories;
+use super::imports::Boto3Imports;
+use crate::extraction::python::common::StringConstants;
 
 /// Type information for a boto3 variable
 ///
@@ -33,11 +38,10 @@ impl VariableTypeInfo {
         }
     }
 
-    /// Create from LSP type information (future use)
+    /// Create from a fully qualified type like "mypy_boto3_s3.client.S3Client"
     ///
-    /// This will be used when we integrate with a real LSP server to get
-    /// full qualified types like "mypy_boto3_s3.client.S3Client".
-    #[allow(dead_code)]
+    /// Used for `mypy_boto3_*` type annotations, and in the future for types
+    /// reported by an LSP server.
     pub(crate) fn from_lsp_type(
         qualified_type: String,
         service_name: String,
@@ -89,9 +93,36 @@ pub(crate) struct VariableTypeTracker {
     /// Used to match session.client()/session.resource() only in the correct scope.
     pub(super) session_variables: HashMap<Option<String>, HashSet<String>>,
 
+    /// Callee expressions that construct a session in the file being tracked
+    /// (`boto3.Session`, `botocore.session.get_session`, ...).
+    pub(super) session_constructors: Vec<String>,
+
     /// All assignment target names per function, used to detect local shadowing
     /// of module-level session variables.
     pub(super) local_assignments: HashMap<String, HashSet<String>>,
+
+    /// Class attribute assignments: class_name -> (attribute_name -> type_info).
+    /// Populated from `self.attr = ...` inside methods and `attr = ...` in class bodies.
+    pub(super) class_attributes: HashMap<String, HashMap<String, VariableTypeInfo>>,
+
+    /// Base classes by class name, in declaration order, so attributes assigned
+    /// in a parent class resolve on subclasses.
+    pub(super) class_bases: HashMap<String, Vec<String>>,
+
+    /// Names under which the file being tracked refers to boto3 and its members
+    pub(super) boto3_imports: Boto3Imports,
+
+    /// String constants defined in the file being tracked, used to resolve
+    /// service names like `boto3.client(SERVICE)`.
+    pub(super) file_constants: StringConstants,
+
+    /// String constants defined anywhere in the project, consulted when the
+    /// file itself doesn't define the referenced constant.
+    pub(super) project_constants: Arc<StringConstants>,
+
+    /// Functions of the project returning SDK objects, so objects assigned from
+    /// calls such as `make_client('s3')` are typed.
+    pub(super) client_factories: Arc<ClientFactories>,
 }
 
 impl VariableTypeTracker {
@@ -102,7 +133,26 @@ impl VariableTypeTracker {
             parameter_types: HashMap::new(),
             conflicted_functions: HashSet::new(),
             session_variables: HashMap::new(),
+            session_constructors: Vec::new(),
             local_assignments: HashMap::new(),
+            class_attributes: HashMap::new(),
+            class_bases: HashMap::new(),
+            boto3_imports: Boto3Imports::default(),
+            file_constants: StringConstants::default(),
+            project_constants: Arc::default(),
+            client_factories: Arc::default(),
         }
     }
+
+    /// Resolve service names against constants collected from the whole project
+    pub(crate) fn with_project_constants(mut self, constants: Arc<StringConstants>) -> Self {
+        self.project_constants = constants;
+        self
+    }
+
+    /// Type objects returned by calls of the client factories of the whole project
+    pub(crate) fn with_client_factories(mut self, factories: Arc<ClientFactories>) -> Self {
+        self.client_factories = factories;
+        self
+    }
 }
@@ -6,7 +6,10 @@
 
 use std::path::Path;
 
-use crate::extraction::python::common::{ArgumentExtractor, ParameterFilter};
+use crate::extraction::python::common::{
+    ArgumentExtractor, ParameterFilter, StringConstants, StringValueResolver,
+};
+use crate::extraction::python::node_kinds;
 use crate::extraction::shared::{
     ChainedWaiterCallInfo, WaiterCallInfo, WaiterCallPattern, WaiterCreationInfo,
 };
@@ -28,14 +31,27 @@ use ast_grep_language::Python;
 /// - Arguments from wait call
 /// - Position information from the wait call
 /// - Client receiver from get_waiter call
+///
+/// When the waiter name is a variable (`client.get_waiter(waiter_name)`), every
+/// statically derivable value produces its own synthetic calls.
 pub(crate) struct WaitersExtractor<'a> {
     service_index: &'a ServiceModelIndex,
+    project_constants: Option<&'a StringConstants>,
 }
 
 impl<'a> WaitersExtractor<'a> {
     /// Create a new waiters extractor with a service model index
     pub(crate) fn new(service_index: &'a ServiceModelIndex) -> Self {
-        Self { service_index }
+        Self {
+            service_index,
+            project_constants: None,
+        }
+    }
+
+    /// Resolve waiter names that reference constants defined elsewhere in the project
+    pub(crate) fn with_project_constants(mut self, constants: &'a StringConstants) -> Self {
+        self.project_constants = Some(constants);
+        self
     }
 
     /// Extract waiter method calls from the AST
@@ -51,21 +67,23 @@ impl<'a> WaitersExtractor<'a> {
         &self,
         ast: &AstWithSourceFile<Python>,
     ) -> Vec<SdkMethodCall> {
+        let resolver = StringValueResolver::new(ast, self.project_constants);
+
         // Step 1: Find all get_waiter calls
-        let waiters = self.find_get_waiter_calls(ast);
+        let waiters = self.find_get_waiter_calls(ast, &resolver);
 
         // Step 2: Find all wait calls
         let wait_calls = self.find_wait_calls(ast);
 
         // Step 3: Find all chained waiter calls (client.get_waiter().wait())
-        let chained_calls = self.find_chained_waiter_calls(ast);
+        let chained_calls = self.find_chained_waiter_calls(ast, &resolver);
 
         // Step 4: Match wait calls to their waiters
         let mut synthetic_calls = Vec::new();
         let mut matched_waiter_indices = std::collections::HashSet::new();
 
         for wait_call in wait_calls {
-            if let Some((waiter, waiter_idx)) = self.match_wait_to_waiter(&wait_call, &waiters) {
+            for (waiter, waiter_idx) in self.match_wait_to_waiters(&wait_call, &waiters) {
                 // Create synthetic calls for matched waiter + wait (one per candidate service)
                 let matched_calls = WaiterCallPattern::Matched {
                     creation: waiter,
@@ -131,7 +149,11 @@ impl<'a> WaitersExtractor<'a> {
     }
 
     /// Find all get_waiter calls in the AST
-    fn find_get_waiter_calls(&self, ast: &AstWithSourceFile<Python>) -> Vec<WaiterCreationInfo> {
+    fn find_get_waiter_calls(
+        &self,
+        ast: &AstWithSourceFile<Python>,
+        resolver: &StringValueResolver,
+    ) -> Vec<WaiterCreationInfo> {
         let root = ast.ast.root();
         let mut waiters = Vec::new();
 
@@ -139,10 +161,10 @@ impl<'a> WaitersExtractor<'a> {
         let get_waiter_pattern = "$WAITER = $CLIENT.get_waiter($NAME $$$ARGS)";
 
         for node_match in root.find_all(get_waiter_pattern) {
-            if let Some(waiter_info) =
-                self.parse_get_waiter_call(&node_match, &ast.source_file.path)
+            if let Some(waiter_infos) =
+                self.parse_get_waiter_call(&node_match, &ast.source_file.path, resolver)
             {
-                waiters.push(waiter_info);
+                waiters.extend(waiter_infos);
             }
         }
 
@@ -171,6 +193,7 @@ impl<'a> WaitersExtractor<'a> {
     fn find_chained_waiter_calls(
         &self,
         ast: &AstWithSourceFile<Python>,
+        resolver: &StringValueResolver,
     ) -> Vec<ChainedWaiterCallInfo> {
         let root = ast.ast.root();
         let mut chained_calls = Vec::new();
@@ -179,22 +202,23 @@ impl<'a> WaitersExtractor<'a> {
         let chained_pattern = "$CLIENT.get_waiter($NAME $$$WAITER_ARGS).wait($$$WAIT_ARGS)";
 
         for node_match in root.find_all(chained_pattern) {
-            if let Some(chained_info) =
-                self.parse_chained_waiter_call(&node_match, &ast.source_file.path)
+            if let Some(chained_infos) =
+                self.parse_chained_waiter_call(&node_match, &ast.source_file.path, resolver)
             {
-                chained_calls.push(chained_info);
+                chained_calls.extend(chained_infos);
             }
         }
 
         chained_calls
     }
 
-    /// Parse a get_waiter call into WaiterCreationInfo
+    /// Parse a get_waiter call into one WaiterCreationInfo per possible waiter name
     fn parse_get_waiter_call(
         &self,
         node_match: &ast_grep_core::NodeMatch<ast_grep_core::tree_sitter::StrDoc<Python>>,
         file_path: &Path,
-    ) -> Option<WaiterCreationInfo> {
+        resolver: &StringValueResolver,
+    ) -> Option<Vec<WaiterCreationInfo>> {
         let env = node_match.get_env();
 
         // Extract waiter variable name
@@ -203,18 +227,24 @@ impl<'a> WaitersExtractor<'a> {
         // Extract client receiver name
         let client_receiver = env.get_match("CLIENT")?.text().to_string();
 
-        // Extract waiter name (remove quotes and keep as-is from code, should be snake_case)
-        let name_node = env.get_match("NAME")?;
-        let name_text = name_node.text();
-        let waiter_name = self.extract_quoted_string(&name_text)?;
-
-        Some(WaiterCreationInfo {
-            variable_name,
-            waiter_name,
-            client_receiver,
-            expr: node_match.text().to_string(),
-            location: Location::from_node(file_path.to_path_buf(), node_match.get_node()),
-        })
+        // Extract waiter names (keep as-is from code, should be snake_case)
+        let waiter_names = self.resolve_waiter_names(env.get_match("NAME")?, resolver);
+
+        let expr = node_match.text().to_string();
+        let location = Location::from_node(file_path.to_path_buf(), node_match.get_node());
+
+        Some(
+            waiter_names
+                .into_iter()
+                .map(|waiter_name| WaiterCreationInfo {
+                    variable_name: variable_name.clone(),
+                    waiter_name,
+                    client_receiver: client_receiver.clone(),
+                    expr: expr.clone(),
+                    location: location.clone(),
+                })
+                .collect(),
+        )
     }
 
     /// Parse a wait call into WaiterCallInfo
@@ -240,62 +270,98 @@ impl<'a> WaitersExtractor<'a> {
         })
     }
 
-    /// Parse a chained waiter call into ChainedWaiterCallInfo
+    /// Parse a chained waiter call into one ChainedWaiterCallInfo per possible waiter name
     fn parse_chained_waiter_call(
         &self,
         node_match: &ast_grep_core::NodeMatch<ast_grep_core::tree_sitter::StrDoc<Python>>,
         file_path: &Path,
-    ) -> Option<ChainedWaiterCallInfo> {
+        resolver: &StringValueResolver,
+    ) -> Option<Vec<ChainedWaiterCallInfo>> {
         let env = node_match.get_env();
 
         // Extract client receiver name
         let client_receiver = env.get_match("CLIENT")?.text().to_string();
 
-        // Extract waiter name (remove quotes and keep as-is from code, should be snake_case)
-        let name_node = env.get_match("NAME")?;
-        let name_text = name_node.text();
-        let waiter_name = self.extract_quoted_string(&name_text)?;
+        // Extract waiter names (keep as-is from code, should be snake_case)
+        let waiter_names = self.resolve_waiter_names(env.get_match("NAME")?, resolver);
 
         // Extract wait arguments (keep all, including WaiterConfig)
         let wait_args_nodes = env.get_multiple_matches("WAIT_ARGS");
         let arguments = ArgumentExtractor::extract_arguments(&wait_args_nodes);
 
-        Some(ChainedWaiterCallInfo {
-            client_receiver,
-            waiter_name,
-            arguments,
-            expr: node_match.text().to_string(),
-            location: Location::from_node(file_path.to_path_buf(), node_match.get_node()),
-        })
+        let expr = node_match.text().to_string();
+        let location = Location::from_node(file_path.to_path_buf(), node_match.get_node());
+
+        Some(
+            waiter_names
+                .into_iter()
+                .map(|waiter_name| ChainedWaiterCallInfo {
+                    client_receiver: client_receiver.clone(),
+                    waiter_name,
+                    arguments: arguments.clone(),
+                    expr: expr.clone(),
+                    location: location.clone(),
+                })
+                .collect(),
+        )
     }
 
-    /// Match a wait call to its corresponding get_waiter call
-    fn match_wait_to_waiter<'b>(
+    /// Resolve the waiter name argument of get_waiter
+    ///
+    /// String literals are used as-is; variables expand to every value they can
+    /// statically take (constants, loop variables over literal lists, parameter defaults).
+    fn resolve_waiter_names(
+        &self,
+        name_node: &ast_grep_core::Node<ast_grep_core::tree_sitter::StrDoc<Python>>,
+        resolver: &StringValueResolver,
+    ) -> Vec<String> {
+        if name_node.kind() == node_kinds::STRING {
+            return self
+                .extract_quoted_string(&name_node.text())
+                .into_iter()
+                .collect();
+        }
+
+        let waiter_names = resolver.resolve(name_node);
+        if waiter_names.is_empty() {
+            log::debug!(
+                "Could not statically resolve waiter name '{}'",
+                name_node.text()
+            );
+        }
+        waiter_names
+    }
+
+    /// Match a wait call to its corresponding get_waiter calls
+    ///
+    /// A get_waiter call with a variable waiter name yields several waiters at the
+    /// same location; the wait call matches all of them.
+    fn match_wait_to_waiters<'b>(
         &self,
         wait_call: &WaiterCallInfo,
         waiters: &'b [WaiterCreationInfo],
-    ) -> Option<(&'b WaiterCreationInfo, usize)> {
+    ) -> Vec<(&'b WaiterCreationInfo, usize)> {
         // Find waiter with matching variable name
         // Use the closest preceding waiter with the same name
-        let mut best_match = None;
-        let mut best_distance = usize::MAX;
-        let mut best_idx = 0;
+        let candidates = || {
+            waiters.iter().enumerate().filter(|(_, waiter)| {
+                waiter.variable_name == wait_call.waiter_var
+                    // Only consider waiters that come before the wait call
+                    && waiter.location.start_line() < wait_call.location.start_line()
+            })
+        };
 
-        for (idx, waiter) in waiters.iter().enumerate() {
-            if waiter.variable_name == wait_call.waiter_var {
-                // Only consider waiters that come before the wait call
-                if waiter.location.start_line() < wait_call.location.start_line() {
-                    let distance = wait_call.location.start_line() - waiter.location.start_line();
-                    if distance < best_distance {
-                        best_distance = distance;
-                        best_match = Some(waiter);
-                        best_idx = idx;
-                    }
-                }
-            }
-        }
+        let Some(closest_line) = candidates()
+            .map(|(_, waiter)| waiter.location.start_line())
+            .max()
+        else {
+            return Vec::new();
+        };
 
-        best_match.map(|w| (w, best_idx))
+        candidates()
+            .filter(|(_, waiter)| waiter.location.start_line() == closest_line)
+            .map(|(idx, waiter)| (waiter, idx))
+            .collect()
     }
 
     /// Get required parameters for an operation from the service index
@@ -507,8 +573,9 @@ waiter = ec2_client.get_waiter('instance_terminated')
         let ast = create_test_ast(source_code);
         let service_index = create_test_service_index();
         let extractor = WaitersExtractor::new(&service_index);
+        let resolver = StringValueResolver::new(&ast, None);
 
-        let waiters = extractor.find_get_waiter_calls(&ast);
+        let waiters = extractor.find_get_waiter_calls(&ast, &resolver);
 
         assert_eq!(waiters.len(), 1);
         assert_eq!(waiters[0].variable_name, "waiter");
@@ -583,8 +650,9 @@ dynamodb_client.get_waiter('table_exists').wait(TableName=table_name)
         let ast = create_test_ast(source_code);
         let service_index = create_test_service_index();
         let extractor = WaitersExtractor::new(&service_index);
+        let resolver = StringValueResolver::new(&ast, None);
 
-        let chained_calls = extractor.find_chained_waiter_calls(&ast);
+        let chained_calls = extractor.find_chained_waiter_calls(&ast, &resolver);
 
         assert_eq!(chained_calls.len(), 1);
         assert_eq!(chained_calls[0].client_receiver, "dynamodb_client");
@@ -610,4 +678,43 @@ dynamodb_client.get_waiter('table_exists').wait(TableName='test-table')
         assert_eq!(calls[0].name, "describe_table");
         assert_eq!(calls[0].possible_services, &["dynamodb"]);
     }
+
+    #[test]
+    fn test_variable_waiter_names() {
+        let source_code = r#"
+import boto3
+ec2_client = boto3.client('ec2')
+
+for state in ('instance_running', 'instance_terminated'):
+    waiter = ec2_client.get_waiter(state)
+    waiter.wait(InstanceIds=['i-1234567890abcdef0'])
+
+def wait_for_table(client, name='table_exists'):
+    client.get_waiter(name).wait(TableName='test-table')
+"#;
+
+        let ast = create_test_ast(source_code);
+        let service_index = create_test_service_index();
+        let extractor = WaitersExtractor::new(&service_index);
+        let resolver = StringValueResolver::new(&ast, None);
+
+        let waiters = extractor.find_get_waiter_calls(&ast, &resolver);
+        let waiter_names: Vec<&str> = waiters.iter().map(|w| w.waiter_name.as_str()).collect();
+        assert_eq!(
+            waiter_names,
+            vec!["instance_running", "instance_terminated"]
+        );
+
+        let chained_calls = extractor.find_chained_waiter_calls(&ast, &resolver);
+        assert_eq!(chained_calls.len(), 1);
+        assert_eq!(chained_calls[0].waiter_name, "table_exists");
+
+        let calls = extractor.extract_waiter_method_calls(&ast);
+        assert!(calls
+            .iter()
+            .any(|call| call.name == "describe_instances" && call.possible_services == ["ec2"]));
+        assert!(calls
+            .iter()
+            .any(|call| call.name == "describe_table" && call.possible_services == ["dynamodb"]));
+    }
 }
@@ -172,9 +172,9 @@ pub(crate) struct ServiceMethodRef {
 #[doc(hidden)]
 pub struct ServiceDiscovery;
 
-/// Global cache for service model indexes by language
+/// Global cache for service model indexes by method naming, see [`ServiceDiscovery::index_key`]
 /// Uses OnceLock for thread-safe lazy initialization
-static SERVICE_INDEX_CACHE: OnceLock<RwLock<HashMap<String, Arc<ServiceModelIndex>>>> =
+static SERVICE_INDEX_CACHE: OnceLock<RwLock<HashMap<&'static str, Arc<ServiceModelIndex>>>> =
     OnceLock::new();
 
 impl ServiceDiscovery {
@@ -218,7 +218,7 @@ impl ServiceDiscovery {
     /// This function uses a cache to avoid reloading the same service index multiple times
     /// for the same language. The cache is thread-safe and shared across all calls.
     pub(crate) async fn load_service_index(language: Language) -> Result<Arc<ServiceModelIndex>> {
-        let language_key = language.to_string();
+        let language_key = Self::index_key(language);
 
         // Initialize cache if needed
         let cache = SERVICE_INDEX_CACHE.get_or_init(|| RwLock::new(HashMap::new()));
@@ -226,7 +226,7 @@ impl ServiceDiscovery {
         // Check if we already have this index cached
         {
             let read_guard = cache.read().await;
-            if let Some(cached_index) = read_guard.get(&language_key) {
+            if let Some(cached_index) = read_guard.get(language_key) {
                 log::debug!("Using cached service index for language '{language}'");
                 return Ok(Arc::clone(cached_index));
             }
@@ -292,6 +292,19 @@ impl ServiceDiscovery {
         Ok(index)
     }
 
+    /// Key of the cached service index of `language`
+    ///
+    /// The index differs between languages only by the method names of the operations, so
+    /// the Go, JavaScript and TypeScript SDKs, whose methods are named like the operations,
+    /// share one index rather than each loading every service definition again.
+    const fn index_key(language: Language) -> &'static str {
+        match language {
+            Language::Python => "python",
+            Language::Go | Language::JavaScript | Language::TypeScript => "operation",
+            Language::Java => "java",
+        }
+    }
+
     /// Load services in parallel using embedded data
     async fn load_services(
         services: Vec<SdkModel>,
@@ -656,4 +669,26 @@ mod tests {
         assert_eq!(index1.services.len(), index2.services.len());
         assert_eq!(index1.method_lookup.len(), index2.method_lookup.len());
     }
+
+    #[tokio::test]
+    async fn test_languages_naming_methods_alike_share_the_service_index() {
+        for operation in ["GetObject", "ListObjectsV2", "SendWhatsAppMessage"] {
+            let method_name = ServiceDiscovery::operation_to_method_name(operation, Language::Go);
+            for language in [Language::JavaScript, Language::TypeScript] {
+                assert_eq!(
+                    ServiceDiscovery::operation_to_method_name(operation, language),
+                    method_name
+                );
+            }
+        }
+
+        let go = ServiceDiscovery::load_service_index(Language::Go)
+            .await
+            .expect("Failed to load service index");
+        let typescript = ServiceDiscovery::load_service_index(Language::TypeScript)
+            .await
+            .expect("Failed to load service index");
+
+        assert!(Arc::ptr_eq(&go, &typescript));
+    }
 }
@@ -0,0 +1,413 @@
+//! Source annotations excluding calls from policy generation, or declaring permissions
+//! the extractor can't see.
+//!
+//! Developers exclude calls the deployed workload never makes, e.g. migrations or admin
+//! tooling living next to the service code, with an `autopilot:ignore` comment (`//` or
+//! `/* */` in Go, Java, JavaScript and TypeScript, `#` in Python). Text following the
+//! annotation is kept as the reason, e.g. `# autopilot:ignore run once by operators`.
+//!
+//! A comment trailing code ignores the calls on its line. A comment on its own line
+//! ignores the calls of the statement following it, including the block that statement
+//! opens, so a whole function or branch is excluded by annotating its first line. Blocks
+//! are recognized by indentation, which formatted code follows in every language.
+//!
+//! Calls the extractor can't see, e.g. operations named at runtime, declare their
+//! permissions with `autopilot:require <action> [<resource>...]` comments, e.g.
+//! `// autopilot:require s3:GetObject arn:aws:s3:::my-bucket/*`. Actions without
+//! resources are required on `*`. The annotation stands for the call requiring the
+//! permission, so explanations and provenance point at it.
+
+use std::collections::HashMap;
+use std::path::Path;
+use std::sync::OnceLock;
+
+use regex::Regex;
+use serde::Serialize;
+
+use crate::extraction::{SdkMethodCallMetadata, SourceFile};
+use crate::{Location, SdkMethodCall};
+
+/// Annotation excluding calls from policy generation
+const IGNORE_DIRECTIVE: &str = "ignore";
+
+/// Annotation declaring a required permission
+const REQUIRE_DIRECTIVE: &str = "require";
+
+/// A call excluded from policy generation by an `autopilot:ignore` annotation
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct SuppressedCall {
+    /// Name of the SDK method, e.g. `delete_bucket`
+    pub method_name: String,
+    /// Source location of the call
+    pub location: Location,
+    /// Expression of the call, e.g. `s3.delete_bucket(Bucket=name)`
+    pub expression: String,
+    /// Reason given with the annotation, if any
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub reason: Option<String>,
+}
+
+/// A permission declared with an `autopilot:require` annotation
+#[derive(Debug, Clone)]
+pub(crate) struct RequiredPermission {
+    /// The required action, e.g. `s3:GetObject`
+    pub(crate) action: String,
+    /// Resources the action is required on, none for `*`
+    pub(crate) resources: Vec<String>,
+    /// The annotation, standing for the call requiring the permission
+    pub(crate) call: SdkMethodCall,
+}
+
+/// An `autopilot:<directive> <argument>` comment and the lines it applies to
+#[derive(Debug, Clone, PartialEq, Eq)]
+struct Annotation<'a> {
+    directive: &'a str,
+    argument: &'a str,
+    /// The comment, as written
+    comment: &'a str,
+    /// Line and first and last column, 1-based, of the comment
+    line: usize,
+    columns: (usize, usize),
+    /// First and last line, 1-based, the annotation applies to
+    lines: (usize, usize),
+    /// Whether the annotation trails code, applying to calls spanning its line rather
+    /// than starting in the annotated statement
+    trailing: bool,
+}
+
+/// Regex matching annotations in line and block comments
+static ANNOTATION_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_annotation_regex() -> &'static Regex {
+    ANNOTATION_REGEX.get_or_init(|| {
+        Regex::new(r"(?://|#|/\*)\s*autopilot:([a-z]+)\b[ \t]*(.*?)[ \t]*(?:\*/)?[ \t]*$")
+            .expect("Invalid annotation regex")
+    })
+}
+
+/// Regex matching IAM actions, e.g. `s3:GetObject` or `s3:Get*`
+static ACTION_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_action_regex() -> &'static Regex {
+    ACTION_REGEX.get_or_init(|| {
+        Regex::new(r"^([a-z0-9-]+):([A-Za-z0-9*]+)$").expect("Invalid action regex")
+    })
+}
+
+/// Split `methods` into the calls to generate policies for and the calls annotations in
+/// `source_files` exclude
+pub(crate) fn suppress_annotated_calls(
+    methods: Vec<SdkMethodCall>,
+    source_files: &[SourceFile],
+) -> (Vec<SdkMethodCall>, Vec<SuppressedCall>) {
+    let ignored: HashMap<&Path, Vec<Annotation<'_>>> = source_files
+        .iter()
+        .filter(|source_file| source_file.content.contains("autopilot:"))
+        .map(|source_file| {
+            let annotations = annotations(&source_file.content)
+                .into_iter()
+                .filter(|annotation| annotation.directive == IGNORE_DIRECTIVE)
+                .collect();
+            (source_file.path.as_path(), annotations)
+        })
+        .collect();
+    if ignored.values().all(Vec::is_empty) {
+        return (methods, Vec::new());
+    }
+
+    let mut suppressed = Vec::new();
+    let methods = methods
+        .into_iter()
+        .filter(|method| {
+            let Some(metadata) = &method.metadata else {
+                return true;
+            };
+            let location = &metadata.location;
+            let Some(annotation) =
+                ignored
+                    .get(location.file_path.as_path())
+                    .and_then(|annotations| {
+                        annotations
+                            .iter()
+                            .find(|annotation| annotation.applies_to(location))
+                    })
+            else {
+                return true;
+            };
+            suppressed.push(SuppressedCall {
+                method_name: method.name.clone(),
+                location: location.clone(),
+                expression: metadata.expr.clone(),
+                reason: Some(annotation.argument.to_string()).filter(|reason| !reason.is_empty()),
+            });
+            false
+        })
+        .collect();
+    (methods, suppressed)
+}
+
+/// Permissions the `autopilot:require` annotations of `source_files` declare, in file and
+/// line order
+pub(crate) fn required_permissions(source_files: &[SourceFile]) -> Vec<RequiredPermission> {
+    let mut permissions = Vec::new();
+    for source_file in source_files
+        .iter()
+        .filter(|source_file| source_file.content.contains("autopilot:"))
+    {
+        for annotation in annotations(&source_file.content)
+            .into_iter()
+            .filter(|annotation| annotation.directive == REQUIRE_DIRECTIVE)
+        {
+            let location = Location::new(
+                source_file.path.clone(),
+                (annotation.line, annotation.columns.0),
+                (annotation.line, annotation.columns.1),
+            );
+            let mut arguments = annotation.argument.split_whitespace();
+            let Some((action, service, operation)) = arguments.next().and_then(|action| {
+                let captures = get_action_regex().captures(action)?;
+                Some((action, captures.get(1)?.as_str(), captures.get(2)?.as_str()))
+            }) else {
+                log::warn!(
+                    "Skipping autopilot:require annotation without an action at {}",
+                    location.to_gnu_format()
+                );
+                continue;
+            };
+            permissions.push(RequiredPermission {
+                action: action.to_string(),
+                resources: arguments.map(str::to_string).collect(),
+                call: SdkMethodCall {
+                    name: operation.to_string(),
+                    possible_services: vec![service.to_string()],
+                    metadata: Some(SdkMethodCallMetadata::new(
+                        annotation.comment.to_string(),
+                        location,
+                    )),
+                },
+            });
+        }
+    }
+    permissions
+}
+
+impl Annotation<'_> {
+    /// Whether the annotation applies to the call at `location`
+    fn applies_to(&self, location: &Location) -> bool {
+        let (first, last) = self.lines;
+        if self.trailing {
+            location.start_line() <= first && first <= location.end_line()
+        } else {
+            (first..=last).contains(&location.start_line())
+        }
+    }
+}
+
+/// Annotations of `content`, in line order
+fn annotations(content: &str) -> Vec<Annotation<'_>> {
+    let lines: Vec<&str> = content.lines().collect();
+    let mut annotations = Vec::new();
+    for (index, line) in lines.iter().enumerate() {
+        let Some(captures) = get_annotation_regex().captures(line) else {
+            continue;
+        };
+        let (Some(comment), Some(directive), Some(argument)) =
+            (captures.get(0), captures.get(1), captures.get(2))
+        else {
+            continue;
+        };
+        let trailing = !line[..comment.start()].trim().is_empty();
+        let applies_to = if trailing {
+            (index + 1, index + 1)
+        } else {
+            statement_after(&lines, index).map_or((index + 1, index + 1), |(first, last)| {
+                (first + 1, last + 1)
+            })
+        };
+        annotations.push(Annotation {
+            directive: directive.as_str(),
+            argument: argument.as_str(),
+            comment: comment.as_str().trim_end(),
+            line: index + 1,
+            columns: (comment.start() + 1, comment.end()),
+            lines: applies_to,
+            trailing,
+        });
+    }
+    annotations
+}
+
+/// First and last line index of the statement following the comment at line `index`,
+/// including the block it opens: the lines indented deeper than the statement, and the
+/// closing lines at its indentation, e.g. `}` or `} else {`. Decorators and Java
+/// annotations belong to the declaration they precede.
+fn statement_after(lines: &[&str], index: usize) -> Option<(usize, usize)> {
+    let first = (index + 1..lines.len()).find(|&i| {
+        let line = lines[i].trim();
+        !line.is_empty() && !is_comment(line)
+    })?;
+    let indentation = indentation_of(lines[first]);
+    let mut last = first;
+    for (i, line) in lines.iter().enumerate().skip(first + 1) {
+        let trimmed = line.trim();
+        if trimmed.is_empty() {
+            continue;
+        }
+        if indentation_of(line) > indentation
+            || (indentation_of(line) == indentation && lines[last].trim().starts_with('@'))
+        {
+            last = i;
+        } else if indentation_of(line) == indentation && trimmed.starts_with(['}', ')', ']']) {
+            last = i;
+            if !trimmed.ends_with(['{', '(', '[', ':']) {
+                break;
+            }
+        } else {
+            break;
+        }
+    }
+    Some((first, last))
+}
+
+/// Whether a trimmed line only holds a comment
+fn is_comment(line: &str) -> bool {
+    line.starts_with("//")
+        || line.starts_with('#')
+        || line.starts_with("/*")
+        || line.starts_with('*')
+}
+
+fn indentation_of(line: &str) -> usize {
+    line.len() - line.trim_start().len()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::Language;
+
+    fn call(name: &str, line: usize) -> SdkMethodCall {
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                format!("s3.{name}()"),
+                Location::new(PathBuf::from("app.py"), (line, 5), (line, 20)),
+            )),
+        }
+    }
+
+    #[test]
+    fn test_suppress_annotated_calls() {
+        let content = r"import boto3
+
+s3 = boto3.client('s3')
+s3.list_buckets()
+s3.delete_bucket(Bucket=name)  # autopilot:ignore run once by operators
+
+# autopilot:ignore
+def migrate():
+    s3.put_bucket_policy(Bucket=name)
+
+    s3.put_bucket_acl(Bucket=name)
+
+def handler(event, context):
+    s3.get_object(Bucket=name)
+";
+        let source_file = SourceFile::with_language(
+            PathBuf::from("app.py"),
+            content.to_string(),
+            Language::Python,
+        );
+        let methods = vec![
+            call("list_buckets", 4),
+            call("delete_bucket", 5),
+            call("put_bucket_policy", 9),
+            call("put_bucket_acl", 11),
+            call("get_object", 14),
+        ];
+
+        let (methods, suppressed) = suppress_annotated_calls(methods, &[source_file]);
+
+        let names: Vec<&str> = methods.iter().map(|method| method.name.as_str()).collect();
+        assert_eq!(names, vec!["list_buckets", "get_object"]);
+        let suppressed: Vec<(&str, Option<&str>)> = suppressed
+            .iter()
+            .map(|call| (call.method_name.as_str(), call.reason.as_deref()))
+            .collect();
+        assert_eq!(
+            suppressed,
+            vec![
+                ("delete_bucket", Some("run once by operators")),
+                ("put_bucket_policy", None),
+                ("put_bucket_acl", None),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_annotation_on_brace_block() {
+        let content = concat!(
+            "func run() {\n",
+            "\t// autopilot:ignore\n",
+            "\tif admin {\n",
+            "\t\tclient.DeleteBucket(ctx, input)\n",
+            "\t} else {\n",
+            "\t\tclient.ListBuckets(ctx, input)\n",
+            "\t}\n",
+            "\tclient.GetObject(ctx, input) /* autopilot:ignore */\n",
+            "}\n",
+        );
+
+        let annotations: Vec<(&str, usize, (usize, usize), bool)> = annotations(content)
+            .iter()
+            .map(|annotation| {
+                (
+                    annotation.directive,
+                    annotation.line,
+                    annotation.lines,
+                    annotation.trailing,
+                )
+            })
+            .collect();
+
+        assert_eq!(
+            annotations,
+            vec![("ignore", 2, (3, 7), false), ("ignore", 8, (8, 8), true)]
+        );
+    }
+
+    #[test]
+    fn test_required_permissions() {
+        let content = concat!(
+            "const operation = `Get${kind}`;\n",
+            "// autopilot:require s3:GetObject arn:aws:s3:::my-bucket/*\n",
+            "await client.send(new commands[operation](input)); // autopilot:require kms:Decrypt\n",
+            "// autopilot:require run once\n",
+        );
+        let source_file = SourceFile::with_language(
+            PathBuf::from("app.js"),
+            content.to_string(),
+            Language::JavaScript,
+        );
+
+        let permissions = required_permissions(&[source_file]);
+
+        assert_eq!(permissions.len(), 2);
+        assert_eq!(permissions[0].action, "s3:GetObject");
+        assert_eq!(permissions[0].resources, vec!["arn:aws:s3:::my-bucket/*"]);
+        assert_eq!(permissions[0].call.name, "GetObject");
+        assert_eq!(permissions[0].call.possible_services, vec!["s3"]);
+        let metadata = permissions[0].call.metadata.as_ref().unwrap();
+        assert_eq!(
+            metadata.expr,
+            "// autopilot:require s3:GetObject arn:aws:s3:::my-bucket/*"
+        );
+        assert_eq!(metadata.location.start_position, (2, 1));
+        assert_eq!(permissions[1].action, "kms:Decrypt");
+        assert!(permissions[1].resources.is_empty());
+    }
+}
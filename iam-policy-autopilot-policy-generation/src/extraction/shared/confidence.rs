@@ -0,0 +1,286 @@
+//! Confidence in the services extracted calls are resolved to
+//!
+//! The service of a call is certain when it's the service of the client the call is made
+//! on. Otherwise it's inferred from the method name, which two kinds of evidence can
+//! corroborate: the argument names of the call all being members of the operation's input
+//! shape, e.g. `SecretId` for `get_secret_value`, and the source file importing the SDK of
+//! the service, e.g. `@aws-sdk/client-secrets-manager`. A call of an operation several
+//! services have, none of which the code identifies, stays uncertain.
+
+use std::collections::{HashMap, HashSet};
+use std::path::Path;
+
+use crate::api::model::CallConfidence;
+use crate::extraction::sdk_model::ServiceModelIndex;
+use crate::extraction::shared::parameter_shapes::{call_arguments, has_members, input_shape};
+use crate::extraction::shared::source_syntax::Argument;
+use crate::extraction::shared::SourceSyntax;
+use crate::extraction::{ParameterValue, SdkMethodCall, SourceFile};
+
+/// Prefixes of the modules of the SDK packages of services, followed by the package
+/// name: `@aws-sdk/client-secrets-manager` in JavaScript and TypeScript,
+/// `github.com/aws/aws-sdk-go-v2/service/s3` in Go and `software.amazon.awssdk.services.kms`
+/// in Java
+const SERVICE_PACKAGE_PREFIXES: &[&str] = &[
+    "@aws-sdk/client-",
+    "github.com/aws/aws-sdk-go-v2/service/",
+    "github.com/aws/aws-sdk-go/service/",
+    "software.amazon.awssdk.services.",
+];
+
+/// Text of files importing a service's SDK or constructing boto3 clients. Files without
+/// any aren't parsed.
+const SERVICE_IMPORT_MARKERS: &[&str] = &["client", "resource", "aws-sdk", "awssdk"];
+
+/// Evidence of the services of extracted calls, beyond their method name
+#[derive(Debug, Default)]
+pub(crate) struct ConfidenceEvidence<'a> {
+    /// SDK models of the services, whose input shapes the arguments of calls are matched
+    /// with; no argument evidence if `None`
+    service_index: Option<&'a ServiceModelIndex>,
+    /// Services whose SDK each source file imports, as normalized by [`normalized`]
+    imported_services: HashMap<&'a Path, HashSet<String>>,
+}
+
+impl<'a> ConfidenceEvidence<'a> {
+    /// Evidence of the calls of `source_files`, matching their arguments with the input
+    /// shapes of `service_index`, if any
+    pub(crate) fn new(
+        service_index: Option<&'a ServiceModelIndex>,
+        source_files: &'a [SourceFile],
+    ) -> Self {
+        let imported_services = source_files
+            .iter()
+            .map(|source_file| {
+                let services = if SERVICE_IMPORT_MARKERS
+                    .iter()
+                    .any(|marker| source_file.content.contains(marker))
+                {
+                    imported_services(&SourceSyntax::of(source_file))
+                } else {
+                    HashSet::new()
+                };
+                (source_file.path.as_path(), services)
+            })
+            .collect();
+        Self {
+            service_index,
+            imported_services,
+        }
+    }
+
+    /// How certain the services of `method` are
+    pub(crate) fn confidence(&self, method: &SdkMethodCall) -> CallConfidence {
+        let [service] = method.possible_services.as_slice() else {
+            return CallConfidence::Low;
+        };
+        let Some(metadata) = &method.metadata else {
+            return CallConfidence::Medium;
+        };
+        // A single service is certain when it comes from the client the call is made on
+        if metadata.receiver.is_some()
+            || self.arguments_match(method, service)
+            || self.imports(&metadata.location.file_path, service)
+        {
+            CallConfidence::High
+        } else {
+            CallConfidence::Medium
+        }
+    }
+
+    /// Whether the call names arguments, all of them members of the input shape of its
+    /// operation in `service`
+    fn arguments_match(&self, method: &SdkMethodCall, service: &str) -> bool {
+        let Some(service_index) = self.service_index else {
+            return false;
+        };
+        let arguments = call_arguments(method);
+        !arguments.is_empty()
+            && input_shape(service_index, &method.name, service)
+                .is_some_and(|shape| has_members(shape, &arguments))
+    }
+
+    /// Whether `file` imports the SDK of `service`
+    fn imports(&self, file: &Path, service: &str) -> bool {
+        self.imported_services
+            .get(file)
+            .is_some_and(|services| services.contains(&normalized(service)))
+    }
+}
+
+/// Services whose SDK the file of `syntax` imports or constructs boto3 clients of,
+/// as normalized by [`normalized`]
+///
+/// Imports and calls are read from the syntax tree, so those in comments, docstrings and
+/// other string literals don't count.
+fn imported_services(syntax: &SourceSyntax) -> HashSet<String> {
+    let packages = syntax.imports.iter().filter_map(|import| {
+        SERVICE_PACKAGE_PREFIXES.iter().find_map(|prefix| {
+            let package = import.module.strip_prefix(prefix)?;
+            package.split(['/', '.']).next()
+        })
+    });
+    // `boto3.client("s3")`, `session.resource(service_name="dynamodb")`
+    let clients = syntax
+        .calls
+        .iter()
+        .filter(|call| {
+            call.callee.contains('.') && matches!(call.method.as_str(), "client" | "resource")
+        })
+        .filter_map(|call| {
+            call.first_string().or_else(|| {
+                call.arguments.iter().find_map(|argument| match argument {
+                    Argument {
+                        name: Some(name),
+                        value: ParameterValue::Resolved(service),
+                    } if name == "service_name" => Some(service.as_str()),
+                    _ => None,
+                })
+            })
+        });
+    packages
+        .chain(clients)
+        .filter(|service| !service.is_empty())
+        .map(normalized)
+        .collect()
+}
+
+/// `service` in lowercase without separators, so SDK package names such as
+/// `secrets-manager` compare equal to service names such as `secretsmanager`
+fn normalized(service: &str) -> String {
+    service
+        .chars()
+        .filter(char::is_ascii_alphanumeric)
+        .map(|character| character.to_ascii_lowercase())
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use rstest::rstest;
+
+    use super::*;
+    use crate::extraction::sdk_model::ServiceDiscovery;
+    use crate::extraction::{Parameter, ParameterValue, SdkMethodCallMetadata};
+    use crate::{Language, Location};
+
+    fn call(name: &str, services: &[&str], parameters: Vec<Parameter>) -> SdkMethodCall {
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: services.iter().map(ToString::to_string).collect(),
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    format!("{name}()"),
+                    Location::new(PathBuf::from("app.py"), (1, 1), (1, 10)),
+                )
+                .with_parameters(parameters),
+            ),
+        }
+    }
+
+    fn keyword(name: &str) -> Parameter {
+        Parameter::Keyword {
+            name: name.to_string(),
+            value: ParameterValue::Unresolved("value".to_string()),
+            position: 0,
+            type_annotation: None,
+        }
+    }
+
+    #[tokio::test]
+    async fn test_arguments_matching_the_input_shape_corroborate_the_service() {
+        let service_index = ServiceDiscovery::load_service_index(Language::Python)
+            .await
+            .unwrap();
+        let evidence = ConfidenceEvidence::new(Some(&service_index), &[]);
+
+        let matching = call(
+            "get_secret_value",
+            &["secretsmanager"],
+            vec![keyword("SecretId")],
+        );
+        assert_eq!(evidence.confidence(&matching), CallConfidence::High);
+
+        let unknown = call(
+            "get_secret_value",
+            &["secretsmanager"],
+            vec![keyword("Bucket")],
+        );
+        assert_eq!(evidence.confidence(&unknown), CallConfidence::Medium);
+
+        let ambiguous = call(
+            "put_resource_policy",
+            &["secretsmanager", "logs"],
+            vec![keyword("SecretId")],
+        );
+        assert_eq!(evidence.confidence(&ambiguous), CallConfidence::Low);
+    }
+
+    #[rstest]
+    #[case::python_client("app.py", "s3 = boto3.client('s3')", "s3", true)]
+    #[case::python_resource(
+        "app.py",
+        "boto3.resource(service_name=\"dynamodb\")",
+        "dynamodb",
+        true
+    )]
+    #[case::javascript(
+        "app.js",
+        "import { GetSecretValueCommand } from '@aws-sdk/client-secrets-manager';",
+        "secretsmanager",
+        true
+    )]
+    #[case::go(
+        "main.go",
+        "package main\n\nimport \"github.com/aws/aws-sdk-go-v2/service/sqs\"\n",
+        "sqs",
+        true
+    )]
+    #[case::java(
+        "App.java",
+        "import software.amazon.awssdk.services.kms.KmsClient;",
+        "kms",
+        true
+    )]
+    #[case::other_service("app.py", "s3 = boto3.client('s3')", "kms", false)]
+    #[case::comment("app.py", "# kms = boto3.client('kms')\nimport boto3\n", "kms", false)]
+    #[case::docstring(
+        "app.py",
+        "\"\"\"Uses boto3.client('kms') to decrypt\"\"\"\nimport boto3\n",
+        "kms",
+        false
+    )]
+    #[case::string(
+        "app.js",
+        "const help = \"npm install @aws-sdk/client-kms\";\n",
+        "kms",
+        false
+    )]
+    fn test_imports_corroborate_the_service(
+        #[case] path: &str,
+        #[case] content: &str,
+        #[case] service: &str,
+        #[case] expected: bool,
+    ) {
+        let path = PathBuf::from(path);
+        let source_files = vec![SourceFile::with_language(
+            path.clone(),
+            content.to_string(),
+            SourceFile::detect_language(&path).unwrap(),
+        )];
+        let evidence = ConfidenceEvidence::new(None, &source_files);
+
+        let mut call = call("operation", &[service], Vec::new());
+        if let Some(metadata) = &mut call.metadata {
+            metadata.location.file_path = path;
+        }
+        let expected = if expected {
+            CallConfidence::High
+        } else {
+            CallConfidence::Medium
+        };
+        assert_eq!(evidence.confidence(&call), expected);
+    }
+}
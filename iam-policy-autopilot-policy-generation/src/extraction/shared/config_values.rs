@@ -0,0 +1,327 @@
+//! Resource names read from application configuration files
+//!
+//! Code commonly reads resource names from configuration instead of naming them:
+//! `s3.get_object(Bucket=cfg.storage.bucket, ...)` with `storage: {bucket: reports}`
+//! in `config.yaml`, or `process.env.TABLE_NAME` with `TABLE_NAME=orders` in `.env`.
+//! Given those files (YAML, JSON, TOML or `.env`), the values scope resources the
+//! way literals at the call site do.
+//!
+//! References match configuration keys by their trailing segments, ignoring case,
+//! `_` and `-`: `cfg.BucketName` matches `bucket_name`, and `settings.storage.bucket`
+//! matches `bucket = "reports"` in the `[storage]` table.
+
+use std::collections::{HashMap, HashSet};
+use std::path::{Path, PathBuf};
+
+use crate::errors::{ExtractorError, Result};
+use crate::extraction::shared::{ProjectConstants, ResourceValue};
+
+/// String values of application configuration files
+#[derive(Debug, Default)]
+pub(crate) struct ConfigValues {
+    /// (normalized key path, value) of every string setting
+    values: Vec<(Vec<String>, String)>,
+    /// Variables set by `.env` files
+    environment: HashMap<String, String>,
+}
+
+impl ConfigValues {
+    /// Load configuration files; later files override variables of earlier `.env` files
+    pub(crate) fn load(paths: &[PathBuf]) -> Result<Self> {
+        let mut config = Self::default();
+        for path in paths {
+            let content = std::fs::read_to_string(path)
+                .map_err(|e| ExtractorError::file_system("read configuration file", path, e))?;
+            config.add_file(path, &content)?;
+        }
+        log::debug!(
+            "Loaded {} configuration values and {} environment variables from {} files",
+            config.values.len(),
+            config.environment.len(),
+            paths.len()
+        );
+        Ok(config)
+    }
+
+    /// Add the settings of one file, parsed according to its name
+    fn add_file(&mut self, path: &Path, content: &str) -> Result<()> {
+        let file_name = path
+            .file_name()
+            .and_then(std::ffi::OsStr::to_str)
+            .unwrap_or_default();
+        let extension = path
+            .extension()
+            .and_then(std::ffi::OsStr::to_str)
+            .map(str::to_ascii_lowercase);
+
+        if file_name == ".env"
+            || file_name.starts_with(".env.")
+            || extension.as_deref() == Some("env")
+        {
+            for (variable, value) in dotenv_variables(content) {
+                self.add_value(&[variable.as_str()], &value);
+                self.environment.insert(variable, value);
+            }
+            return Ok(());
+        }
+
+        let document: serde_json::Value = match extension.as_deref() {
+            Some("json") => serde_json::from_str(content).map_err(|e| invalid_file(path, e))?,
+            Some("yaml" | "yml") => {
+                serde_yaml::from_str(content).map_err(|e| invalid_file(path, e))?
+            }
+            Some("toml") => {
+                for (key, value) in toml_values(content) {
+                    let key: Vec<&str> = key.iter().map(String::as_str).collect();
+                    self.add_value(&key, &value);
+                }
+                return Ok(());
+            }
+            _ => {
+                return Err(ExtractorError::validation(format!(
+                    "Unsupported configuration file '{}': expected a .json, .yaml, .yml, \
+                     .toml or .env file",
+                    path.display()
+                )))
+            }
+        };
+        self.add_document(&mut Vec::new(), &document);
+        Ok(())
+    }
+
+    /// Add the string leaves of a JSON or YAML document
+    fn add_document<'a>(&mut self, key: &mut Vec<&'a str>, document: &'a serde_json::Value) {
+        match document {
+            serde_json::Value::Object(properties) => {
+                for (name, value) in properties {
+                    key.push(name);
+                    self.add_document(key, value);
+                    key.pop();
+                }
+            }
+            serde_json::Value::String(value) if !key.is_empty() => self.add_value(key, value),
+            _ => {}
+        }
+    }
+
+    fn add_value(&mut self, key: &[&str], value: &str) {
+        let key = key.iter().map(|segment| normalize(segment)).collect();
+        self.values.push((key, value.to_string()));
+    }
+
+    /// Value an `.env` file sets for `variable`
+    pub(crate) fn environment_value(&self, variable: &str) -> Option<&str> {
+        self.environment.get(variable).map(String::as_str)
+    }
+}
+
+impl ProjectConstants for ConfigValues {
+    /// The setting a reference to a loaded configuration object names, e.g.
+    /// `cfg.storage.bucket`; its first segment is the object, not a key
+    fn constant_value(&self, _path: &Path, expression: &str) -> Option<ResourceValue> {
+        let reference: Vec<String> = expression.split('.').skip(1).map(normalize).collect();
+        if reference.is_empty() {
+            return None;
+        }
+        let values: HashSet<&str> = self
+            .values
+            .iter()
+            .filter(|(key, _)| key.ends_with(&reference) || reference.ends_with(key))
+            .map(|(_, value)| value.as_str())
+            .collect();
+        if values.len() == 1 {
+            values
+                .into_iter()
+                .next()
+                .map(|value| ResourceValue::Literal(value.to_string()))
+        } else {
+            if !values.is_empty() {
+                log::debug!(
+                    "Configuration reference '{expression}' matches several values {values:?}"
+                );
+            }
+            None
+        }
+    }
+}
+
+fn invalid_file(
+    path: &Path,
+    source: impl std::error::Error + Send + Sync + 'static,
+) -> ExtractorError {
+    ExtractorError::Configuration {
+        message: format!("Failed to parse configuration file '{}'", path.display()),
+        source: Some(Box::new(source)),
+    }
+}
+
+/// Key segments compare ignoring case and separators: `BucketName` = `bucket_name`
+fn normalize(segment: &str) -> String {
+    segment
+        .chars()
+        .filter(|c| *c != '_' && *c != '-')
+        .flat_map(char::to_lowercase)
+        .collect()
+}
+
+/// `NAME=value` lines of a `.env` file
+fn dotenv_variables(content: &str) -> Vec<(String, String)> {
+    content
+        .lines()
+        .filter_map(|line| {
+            let line = line.trim();
+            let line = line.strip_prefix("export ").unwrap_or(line);
+            if line.starts_with('#') {
+                return None;
+            }
+            let (name, value) = line.split_once('=')?;
+            let name = name.trim();
+            if name.is_empty() {
+                return None;
+            }
+            Some((name.to_string(), unquote(value.trim())))
+        })
+        .collect()
+}
+
+/// String settings of a TOML document, keyed by their table and dotted key
+///
+/// Only what names resources is read: `[table]` headers and `key = "string"` pairs.
+fn toml_values(content: &str) -> Vec<(Vec<String>, String)> {
+    let mut table: Vec<String> = Vec::new();
+    let mut values = Vec::new();
+    for line in content.lines() {
+        let line = line.trim();
+        if line.is_empty() || line.starts_with('#') {
+            continue;
+        }
+        if let Some(header) = line.strip_prefix('[') {
+            // Entries of arrays of tables (`[[table]]`) have no single key
+            table = if header.starts_with('[') {
+                vec![String::new()]
+            } else {
+                header
+                    .split(']')
+                    .next()
+                    .unwrap_or_default()
+                    .split('.')
+                    .map(|segment| unquote(segment.trim()))
+                    .collect()
+            };
+            continue;
+        }
+        let Some((key, value)) = line.split_once('=') else {
+            continue;
+        };
+        let value = value.trim();
+        let Some(value) = ['"', '\'']
+            .iter()
+            .find_map(|quote| value.strip_prefix(*quote)?.split_once(*quote))
+            .map(|(value, _)| value.to_string())
+        else {
+            continue;
+        };
+        if table.iter().any(String::is_empty) {
+            continue;
+        }
+        let key = table
+            .iter()
+            .cloned()
+            .chain(key.split('.').map(|segment| unquote(segment.trim())))
+            .collect();
+        values.push((key, value));
+    }
+    values
+}
+
+/// The contents of a quoted value, or the value without a trailing comment
+fn unquote(value: &str) -> String {
+    for quote in ['"', '\''] {
+        if let Some(contents) = value
+            .strip_prefix(quote)
+            .and_then(|rest| rest.split_once(quote))
+        {
+            return contents.0.to_string();
+        }
+    }
+    value
+        .split(" #")
+        .next()
+        .unwrap_or_default()
+        .trim()
+        .to_string()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn config_from(files: &[(&str, &str)]) -> ConfigValues {
+        let mut config = ConfigValues::default();
+        for (path, content) in files {
+            config.add_file(Path::new(path), content).unwrap();
+        }
+        config
+    }
+
+    fn literal(value: &str) -> Option<ResourceValue> {
+        Some(ResourceValue::Literal(value.to_string()))
+    }
+
+    #[test]
+    fn test_references_match_configuration_keys() {
+        let config = config_from(&[
+            (
+                "config/app.yaml",
+                "storage:\n  ReportsBucket: reports\n  retries: 3\nqueue_url: https://sqs.us-east-1.amazonaws.com/123456789012/jobs\n",
+            ),
+            ("config/tables.json", r#"{"tables": {"orders": "orders-prod"}}"#),
+            (
+                "config/settings.toml",
+                "# Lambda settings\n[functions]\nprocessor = \"process-orders\" # deployed by CI\n\n[[workers]]\nname = \"ignored\"\n",
+            ),
+            (".env", "export TABLE_NAME=\"invoices\"\n# comment\nSTAGE=prod\n"),
+        ]);
+        let resolve = |expression| config.constant_value(Path::new("app.py"), expression);
+
+        assert_eq!(resolve("cfg.storage.reports_bucket"), literal("reports"));
+        assert_eq!(resolve("cfg.ReportsBucket"), literal("reports"));
+        assert_eq!(resolve("settings.tables.orders"), literal("orders-prod"));
+        assert_eq!(
+            resolve("config.functions.processor"),
+            literal("process-orders")
+        );
+        assert_eq!(resolve("settings.table_name"), literal("invoices"));
+        assert_eq!(resolve("cfg.storage.retries"), None);
+        assert_eq!(resolve("cfg.name"), None);
+        assert_eq!(resolve("TABLE_NAME"), None);
+        assert_eq!(config.environment_value("TABLE_NAME"), Some("invoices"));
+        assert_eq!(config.environment_value("STAGE"), Some("prod"));
+    }
+
+    #[test]
+    fn test_ambiguous_references_are_unresolved() {
+        let config = config_from(&[(
+            "config.yaml",
+            "reports:\n  bucket: reports\narchive:\n  bucket: archive\n",
+        )]);
+
+        assert_eq!(
+            config.constant_value(Path::new("app.go"), "cfg.Bucket"),
+            None
+        );
+        assert_eq!(
+            config.constant_value(Path::new("app.go"), "cfg.Archive.Bucket"),
+            literal("archive")
+        );
+    }
+
+    #[test]
+    fn test_unsupported_files_are_rejected() {
+        let mut config = ConfigValues::default();
+        assert!(config
+            .add_file(Path::new("config.ini"), "[a]\nb=c\n")
+            .is_err());
+        assert!(config.add_file(Path::new("config.json"), "{").is_err());
+    }
+}
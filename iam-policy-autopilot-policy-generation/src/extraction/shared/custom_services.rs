@@ -0,0 +1,193 @@
+//! Calls of services other than the AWS ones
+//!
+//! Clients of in-house services generated with Smithy, and AWS SDK clients configured with
+//! the endpoint of an AWS-compatible service, e.g. MinIO, have operations named like AWS
+//! ones, so their calls resolve to AWS services by name:
+//!
+//! ```python
+//! widgets = WidgetsClient(endpoint="https://widgets.internal")
+//! widgets.get_object(Key=key)   # not s3:GetObject
+//!
+//! minio = boto3.client("s3", endpoint_url="http://minio:9000")
+//! minio.put_object(Bucket=bucket, Key=key, Body=body)
+//! ```
+//!
+//! Registered [`CustomServices`] take the calls matching their file glob, package and
+//! variable away from the AWS services. Calls of a service with an action namespace are
+//! granted the action of their operation in it, e.g. `widgets:GetObject` on `*`; those of
+//! a service without one are left out of the policies and listed as suppressed calls.
+
+use crate::api::model::CustomServices;
+use crate::extraction::shared::service_choices::{call_matches, declared_packages};
+use crate::extraction::shared::{RequiredPermission, SuppressedCall};
+use crate::extraction::SourceFile;
+use crate::SdkMethodCall;
+
+/// Take the calls of `custom_services` out of `methods`
+///
+/// # Returns
+/// The calls of AWS services, the permissions of the calls of custom services with an
+/// action namespace, and the calls of those without one
+pub(crate) fn separate_custom_service_calls(
+    methods: Vec<SdkMethodCall>,
+    custom_services: &CustomServices,
+    source_files: &[SourceFile],
+) -> (
+    Vec<SdkMethodCall>,
+    Vec<RequiredPermission>,
+    Vec<SuppressedCall>,
+) {
+    if custom_services.services.is_empty() {
+        return (methods, Vec::new(), Vec::new());
+    }
+    let packages = declared_packages(source_files);
+    let mut permissions = Vec::new();
+    let mut excluded = Vec::new();
+    let methods = methods
+        .into_iter()
+        .filter(|method| {
+            let Some(metadata) = &method.metadata else {
+                return true;
+            };
+            let file = &metadata.location.file_path;
+            let Some(service) = custom_services.services.iter().find(|service| {
+                call_matches(
+                    service.files.as_deref(),
+                    service.package.as_deref(),
+                    service.variable.as_deref(),
+                    file,
+                    packages.get(file.as_path()).copied(),
+                    metadata.receiver.as_deref(),
+                )
+            }) else {
+                return true;
+            };
+            log::debug!(
+                "Attributed {} at {} to the custom service {}",
+                method.name,
+                metadata.location.to_gnu_format(),
+                service.name
+            );
+            match &service.namespace {
+                Some(namespace) => {
+                    let operation = operation_name(&method.name);
+                    permissions.push(RequiredPermission {
+                        action: format!("{namespace}:{operation}"),
+                        resources: Vec::new(),
+                        call: SdkMethodCall {
+                            name: operation,
+                            possible_services: vec![namespace.clone()],
+                            metadata: method.metadata.clone(),
+                        },
+                    });
+                }
+                None => excluded.push(SuppressedCall {
+                    method_name: method.name.clone(),
+                    location: metadata.location.clone(),
+                    expression: metadata.expr.clone(),
+                    reason: Some(format!("call of the custom service {}", service.name)),
+                }),
+            }
+            false
+        })
+        .collect();
+    (methods, permissions, excluded)
+}
+
+/// The operation an SDK method calls, e.g. `GetWidget` for `get_widget` or `getWidget`
+pub(crate) fn operation_name(method_name: &str) -> String {
+    method_name
+        .split('_')
+        .flat_map(|word| {
+            let mut characters = word.chars();
+            characters
+                .next()
+                .map(|first| first.to_ascii_uppercase())
+                .into_iter()
+                .chain(characters)
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use rstest::rstest;
+
+    use super::*;
+    use crate::api::model::CustomService;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::Location;
+
+    fn call(file: &str, receiver: &str, name: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    format!("{receiver}.{name}()"),
+                    Location::new(PathBuf::from(file), (1, 1), (1, 30)),
+                )
+                .with_receiver(receiver.to_string()),
+            ),
+        }
+    }
+
+    fn custom_service(
+        name: &str,
+        files: Option<&str>,
+        variable: Option<&str>,
+        namespace: Option<&str>,
+    ) -> CustomService {
+        CustomService {
+            name: name.to_string(),
+            files: files.map(ToString::to_string),
+            package: None,
+            variable: variable.map(ToString::to_string),
+            namespace: namespace.map(ToString::to_string),
+        }
+    }
+
+    #[test]
+    fn test_custom_service_calls_are_separated() {
+        let custom_services = CustomServices {
+            services: vec![
+                custom_service("widgets", Some("widgets/**"), None, Some("widgets")),
+                custom_service("minio", None, Some("minio"), None),
+            ],
+        };
+        let methods = vec![
+            call("widgets/client.py", "client", "get_object"),
+            call("app.py", "minio", "put_object"),
+            call("app.py", "s3", "get_object"),
+        ];
+
+        let (methods, permissions, excluded) =
+            separate_custom_service_calls(methods, &custom_services, &[]);
+
+        assert_eq!(methods.len(), 1);
+        assert_eq!(
+            methods[0].metadata.as_ref().unwrap().expr,
+            "s3.get_object()"
+        );
+        assert_eq!(permissions.len(), 1);
+        assert_eq!(permissions[0].action, "widgets:GetObject");
+        assert!(permissions[0].resources.is_empty());
+        assert_eq!(permissions[0].call.possible_services, vec!["widgets"]);
+        assert_eq!(excluded.len(), 1);
+        assert_eq!(excluded[0].method_name, "put_object");
+        assert_eq!(
+            excluded[0].reason.as_deref(),
+            Some("call of the custom service minio")
+        );
+    }
+
+    #[rstest]
+    #[case::snake_case("get_widget", "GetWidget")]
+    #[case::camel_case("getWidget", "GetWidget")]
+    #[case::pascal_case("GetWidget", "GetWidget")]
+    fn test_operation_name(#[case] method_name: &str, #[case] expected: &str) {
+        assert_eq!(operation_name(method_name), expected);
+    }
+}
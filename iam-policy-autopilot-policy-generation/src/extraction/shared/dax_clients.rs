@@ -0,0 +1,372 @@
+//! Calls of DynamoDB Accelerator (DAX) clients
+//!
+//! The DAX SDKs implement the DynamoDB item operations, so their calls resolve to
+//! DynamoDB by name, but they're made on the DAX cluster, which is authorized by `dax:`
+//! actions on the cluster rather than `dynamodb:` ones:
+//!
+//! ```python
+//! ENDPOINT = "dax://orders.l6fzcv.dax-clusters.us-east-1.amazonaws.com"
+//! dax = AmazonDaxClient(endpoint_url=ENDPOINT)
+//! dax.get_item(TableName="orders", Key=key)   # dax:GetItem on cache/orders
+//! ```
+//!
+//! DAX clients are recognized in the syntax tree by the variables their constructors are
+//! assigned to, in Python (`amazondax`), Go (`aws-dax-go`, `aws-dax-go-v2`), Java
+//! (`ClusterDaxClient`, `AmazonDaxClientBuilder`) and JavaScript (`amazon-dax-client`,
+//! `DaxDocument`), so constructors in comments and strings don't count. Calls on them are
+//! granted the `dax:` actions of their operation, on the cluster the file names in an
+//! endpoint string, if it names one. Calls whose receiver isn't known, as in Java, are
+//! attributed to DAX in files constructing DAX clients and no DynamoDB ones.
+
+use std::collections::{HashMap, HashSet};
+use std::path::Path;
+use std::sync::OnceLock;
+
+use regex::Regex;
+
+use crate::extraction::shared::custom_services::operation_name;
+use crate::extraction::shared::{RequiredPermission, SourceSyntax};
+use crate::extraction::SourceFile;
+use crate::SdkMethodCall;
+
+/// Classes whose constructors, builders and factory methods build DAX clients:
+/// `AmazonDaxClient(...)` and `AmazonDaxClient.resource(...)` in Python,
+/// `ClusterDaxClient.builder()` and `AmazonDaxClientBuilder.standard()` in Java, and
+/// `new AmazonDaxClient(...)` and `new DaxDocument(...)` in JavaScript and TypeScript
+const DAX_CLIENT_CLASSES: &[&str] = &[
+    "AmazonDaxClient",
+    "ClusterDaxClient",
+    "ClusterDaxAsyncClient",
+    "AmazonDaxClientBuilder",
+    "AmazonDaxAsyncClientBuilder",
+    "DaxDocument",
+];
+
+/// Import path of the DAX SDKs for Go, v1 and v2, whose clients `dax.New` and
+/// `dax.NewWithSDKConfig` build
+const GO_DAX_IMPORT: &str = "github.com/aws/aws-dax-go";
+
+/// Calls building DynamoDB clients in Go, Java and JavaScript, other than the
+/// `client("dynamodb")` and `resource("dynamodb")` of boto3
+const DYNAMODB_CLIENT_CALLEES: &[&str] = &[
+    "dynamodb.New",
+    "dynamodb.NewFromConfig",
+    "DynamoDbClient.builder",
+    "DynamoDbClient.create",
+    "DynamoDbAsyncClient.builder",
+    "DynamoDbAsyncClient.create",
+    "AmazonDynamoDBClientBuilder.standard",
+    "AmazonDynamoDBClientBuilder.defaultClient",
+    "AmazonDynamoDBAsyncClientBuilder.standard",
+    "AmazonDynamoDBAsyncClientBuilder.defaultClient",
+    "DynamoDB",
+    "DynamoDBClient",
+];
+
+/// Regex matching DAX cluster endpoints, capturing the cluster name, e.g. `orders` in
+/// `dax://orders.l6fzcv.dax-clusters.us-east-1.amazonaws.com` or
+/// `orders.l6fzcv.clustercfg.dax.use1.cache.amazonaws.com:8111`
+static CLUSTER_ENDPOINT_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_cluster_endpoint_regex() -> &'static Regex {
+    CLUSTER_ENDPOINT_REGEX.get_or_init(|| {
+        Regex::new(r"\b([a-z][a-z0-9-]*)\.[a-z0-9]+\.(?:dax-clusters|clustercfg\.dax)\.")
+            .expect("Invalid DAX cluster endpoint regex")
+    })
+}
+
+/// ARN of a DAX cluster, followed by its name
+const CLUSTER_ARN: &str = "arn:${Partition}:dax:${Region}:${Account}:cache/";
+
+/// Actions of the DynamoDB operations DAX clients implement
+const DAX_OPERATION_ACTIONS: &[(&str, &[&str])] = &[
+    ("BatchGetItem", &["dax:BatchGetItem"]),
+    ("BatchWriteItem", &["dax:BatchWriteItem"]),
+    ("DeleteItem", &["dax:DeleteItem"]),
+    ("GetItem", &["dax:GetItem"]),
+    ("PutItem", &["dax:PutItem"]),
+    ("Query", &["dax:Query"]),
+    ("Scan", &["dax:Scan"]),
+    ("TransactGetItems", &["dax:GetItem"]),
+    (
+        "TransactWriteItems",
+        &[
+            "dax:ConditionCheckItem",
+            "dax:DeleteItem",
+            "dax:PutItem",
+            "dax:UpdateItem",
+        ],
+    ),
+    ("UpdateItem", &["dax:UpdateItem"]),
+];
+
+/// DAX clients one source file constructs
+#[derive(Debug)]
+struct DaxClients<'a> {
+    /// Variables the clients are assigned to
+    variables: HashSet<&'a str>,
+    /// Whether the file constructs DynamoDB clients too
+    constructs_dynamodb_clients: bool,
+    /// The cluster, if the file names a single one
+    cluster: Option<&'a str>,
+}
+
+impl<'a> DaxClients<'a> {
+    /// The DAX clients `syntax`, the syntax tree of one source file, constructs, if any
+    fn of(syntax: &'a SourceSyntax) -> Option<Self> {
+        let imports_go_dax = syntax
+            .imports
+            .iter()
+            .any(|import| import.module.starts_with(GO_DAX_IMPORT));
+        let variables: HashSet<&str> = syntax
+            .assignments
+            .iter()
+            .filter(|assignment| {
+                is_dax_client_callee(&assignment.callee)
+                    || (imports_go_dax && assignment.callee.starts_with("dax.New"))
+            })
+            .map(|assignment| assignment.target.as_str())
+            .collect();
+        if variables.is_empty() {
+            return None;
+        }
+        let clusters: HashSet<&str> = syntax
+            .strings
+            .iter()
+            .filter_map(|string| get_cluster_endpoint_regex().captures(&string.value))
+            .filter_map(|captures| captures.get(1))
+            .map(|cluster| cluster.as_str())
+            .collect();
+        Some(Self {
+            variables,
+            constructs_dynamodb_clients: syntax.calls.iter().any(|call| {
+                DYNAMODB_CLIENT_CALLEES.contains(&call.callee.as_str())
+                    || ((call.callee.ends_with(".client") || call.callee.ends_with(".resource"))
+                        && call.first_string() == Some("dynamodb"))
+            }),
+            cluster: if clusters.len() == 1 {
+                clusters.into_iter().next()
+            } else {
+                None
+            },
+        })
+    }
+
+    /// Whether a call on `receiver` is made on one of the clients
+    fn calls_on(&self, receiver: Option<&str>) -> bool {
+        match receiver {
+            Some(receiver) => {
+                self.variables.contains(receiver)
+                    || receiver.rsplit('.').next().is_some_and(|name| {
+                        self.variables
+                            .iter()
+                            .any(|variable| variable.rsplit('.').next() == Some(name))
+                    })
+            }
+            None => !self.constructs_dynamodb_clients,
+        }
+    }
+}
+
+/// Whether `callee` constructs a DAX client, through the class or its builder
+fn is_dax_client_callee(callee: &str) -> bool {
+    let callee = callee.strip_prefix("amazondax.").unwrap_or(callee);
+    callee
+        .split('.')
+        .next()
+        .is_some_and(|class| DAX_CLIENT_CLASSES.contains(&class))
+}
+
+/// Take the calls of DAX clients out of `methods`
+///
+/// # Returns
+/// The other calls, and the `dax:` permissions of the calls of DAX clients
+pub(crate) fn separate_dax_calls(
+    methods: Vec<SdkMethodCall>,
+    source_files: &[SourceFile],
+) -> (Vec<SdkMethodCall>, Vec<RequiredPermission>) {
+    let syntax: Vec<(&Path, SourceSyntax)> = source_files
+        .iter()
+        .map(|source_file| (source_file.path.as_path(), SourceSyntax::of(source_file)))
+        .collect();
+    let clients: HashMap<&Path, DaxClients<'_>> = syntax
+        .iter()
+        .filter_map(|(path, syntax)| DaxClients::of(syntax).map(|clients| (*path, clients)))
+        .collect();
+    if clients.is_empty() {
+        return (methods, Vec::new());
+    }
+
+    let mut permissions = Vec::new();
+    let methods = methods
+        .into_iter()
+        .filter(|method| {
+            let Some(metadata) = &method.metadata else {
+                return true;
+            };
+            let operation = operation_name(&method.name);
+            let Some((_, actions)) = DAX_OPERATION_ACTIONS
+                .iter()
+                .find(|(dax_operation, _)| *dax_operation == operation)
+            else {
+                return true;
+            };
+            let Some(clients) = clients.get(metadata.location.file_path.as_path()) else {
+                return true;
+            };
+            if !method.possible_services.iter().any(|s| s == "dynamodb")
+                || !clients.calls_on(metadata.receiver.as_deref())
+            {
+                return true;
+            }
+            log::debug!(
+                "Attributed {} at {} to a DAX client",
+                method.name,
+                metadata.location.to_gnu_format()
+            );
+            let cluster = clients.cluster.unwrap_or("*");
+            for action in *actions {
+                permissions.push(RequiredPermission {
+                    action: (*action).to_string(),
+                    resources: vec![format!("{CLUSTER_ARN}{cluster}")],
+                    call: SdkMethodCall {
+                        name: operation.clone(),
+                        possible_services: vec!["dax".to_string()],
+                        metadata: method.metadata.clone(),
+                    },
+                });
+            }
+            false
+        })
+        .collect();
+    (methods, permissions)
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::{Language, Location};
+
+    fn call(file: &str, receiver: Option<&str>, name: &str) -> SdkMethodCall {
+        let metadata = SdkMethodCallMetadata::new(
+            format!("{}.{name}()", receiver.unwrap_or("client")),
+            Location::new(PathBuf::from(file), (3, 1), (3, 30)),
+        );
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: vec!["dynamodb".to_string()],
+            metadata: Some(match receiver {
+                Some(receiver) => metadata.with_receiver(receiver.to_string()),
+                None => metadata,
+            }),
+        }
+    }
+
+    fn source_file(path: &str, content: &str, language: Language) -> SourceFile {
+        SourceFile::with_language(PathBuf::from(path), content.to_string(), language)
+    }
+
+    #[test]
+    fn test_dax_calls_are_separated() {
+        let source_files = vec![
+            source_file(
+                "app.py",
+                "import boto3\nfrom amazondax import AmazonDaxClient\n\
+                 ENDPOINT = \"dax://orders.l6fzcv.dax-clusters.us-east-1.amazonaws.com\"\n\
+                 dax = AmazonDaxClient(endpoint_url=ENDPOINT)\n\
+                 ddb = boto3.client(\"dynamodb\")\n",
+                Language::Python,
+            ),
+            source_file(
+                "Orders.java",
+                "DynamoDbClient client = ClusterDaxClient.builder().build();\n",
+                Language::Java,
+            ),
+        ];
+        let methods = vec![
+            call("app.py", Some("dax"), "get_item"),
+            call("app.py", Some("ddb"), "put_item"),
+            call("app.py", Some("dax"), "describe_table"),
+            call("Orders.java", None, "transactWriteItems"),
+        ];
+
+        let (methods, permissions) = separate_dax_calls(methods, &source_files);
+
+        assert_eq!(
+            methods
+                .iter()
+                .map(|method| method.name.as_str())
+                .collect::<Vec<_>>(),
+            ["put_item", "describe_table"]
+        );
+        assert_eq!(permissions[0].action, "dax:GetItem");
+        assert_eq!(
+            permissions[0].resources,
+            ["arn:${Partition}:dax:${Region}:${Account}:cache/orders"]
+        );
+        assert_eq!(permissions[0].call.possible_services, ["dax"]);
+        assert_eq!(
+            permissions[1..]
+                .iter()
+                .map(|permission| permission.action.as_str())
+                .collect::<Vec<_>>(),
+            [
+                "dax:ConditionCheckItem",
+                "dax:DeleteItem",
+                "dax:PutItem",
+                "dax:UpdateItem"
+            ]
+        );
+        assert_eq!(
+            permissions[1].resources,
+            ["arn:${Partition}:dax:${Region}:${Account}:cache/*"]
+        );
+    }
+
+    #[test]
+    fn test_go_dax_clients() {
+        let content = "package main\n\nimport \"github.com/aws/aws-dax-go-v2/dax\"\n\n\
+                       func main() {\n\tclient, err := dax.New(cfg)\n}\n";
+        let syntax = SourceSyntax::of(&source_file("main.go", content, Language::Go));
+
+        let clients = DaxClients::of(&syntax).unwrap();
+
+        assert!(clients.calls_on(Some("client")));
+        assert!(!clients.calls_on(Some("s3")));
+    }
+
+    #[test]
+    fn test_constructors_in_comments_and_strings_are_ignored() {
+        let source_files = vec![source_file(
+            "app.py",
+            "import boto3\n\
+             # dax = AmazonDaxClient(endpoint_url=ENDPOINT)\n\
+             HELP = \"dax = AmazonDaxClient(endpoint_url=...) to use the cache\"\n\
+             dax = boto3.client(\"dynamodb\")\n",
+            Language::Python,
+        )];
+        let methods = vec![call("app.py", Some("dax"), "get_item")];
+
+        let (methods, permissions) = separate_dax_calls(methods, &source_files);
+
+        assert_eq!(methods.len(), 1);
+        assert!(permissions.is_empty());
+    }
+
+    #[test]
+    fn test_clusters_in_comments_are_ignored() {
+        let content = "from amazondax import AmazonDaxClient\n\
+                       # was dax://legacy.l6fzcv.dax-clusters.us-east-1.amazonaws.com\n\
+                       ENDPOINT = \"dax://orders.l6fzcv.dax-clusters.us-east-1.amazonaws.com\"\n\
+                       dax = AmazonDaxClient(endpoint_url=ENDPOINT)\n";
+        let syntax = SourceSyntax::of(&source_file("app.py", content, Language::Python));
+
+        let clients = DaxClients::of(&syntax).unwrap();
+
+        assert_eq!(clients.cluster, Some("orders"));
+        assert!(!clients.constructs_dynamodb_clients);
+    }
+}
@@ -0,0 +1,784 @@
+//! Third-party dependencies analyzed along with the sources of the project
+//!
+//! Libraries such as ORMs, storage adapters and job queues call AWS on behalf of the code
+//! using them, so their calls never show in the project's own sources. With a
+//! [`DependencyAnalysis`], the sources of the dependencies the project declares are
+//! analyzed too, and those of their dependencies down to its depth: the packages of
+//! `node_modules` for JavaScript and TypeScript, the distributions installed in the
+//! project's virtual environment for Python, and the modules of the module cache for Go.
+//!
+//! If the allow-list isn't empty, only the dependencies it matches are analyzed, but the
+//! dependencies of the others are still followed. The AWS SDKs are never analyzed: their
+//! sources implement the operations rather than call them.
+
+use std::collections::{BTreeSet, HashMap, VecDeque};
+use std::path::{Path, PathBuf};
+use std::sync::OnceLock;
+
+use regex::Regex;
+
+use crate::api::model::DependencyAnalysis;
+use crate::Language;
+
+/// Packages of the AWS SDKs and their runtimes, by name pattern
+const SDK_PACKAGES: &[&str] = &[
+    "boto3",
+    "botocore",
+    "s3transfer",
+    "aiobotocore",
+    "aioboto3",
+    "boto3-stubs",
+    "mypy-boto3-*",
+    "types-boto3*",
+    "aws-sdk",
+    "@aws-sdk/*",
+    "@aws-crypto/*",
+    "@smithy/*",
+    "github.com/aws/aws-sdk-go",
+    "github.com/aws/aws-sdk-go-v2",
+    "github.com/aws/aws-sdk-go-v2/*",
+    "github.com/aws/smithy-go",
+];
+
+/// Fields of a package.json listing the packages installed along with it
+const NPM_DEPENDENCY_FIELDS: [&str; 2] = ["dependencies", "optionalDependencies"];
+
+/// Files of a Python project declaring its dependencies
+const PYTHON_MANIFESTS: [&str; 4] = ["requirements.txt", "pyproject.toml", "setup.py", "Pipfile"];
+
+/// Directories of a Python project its virtual environment is usually created in
+const VIRTUAL_ENV_DIRECTORIES: [&str; 3] = [".venv", "venv", "env"];
+
+/// Regex capturing the name of a Python requirement, e.g. `sqlalchemy` in
+/// `SQLAlchemy[asyncio]>=2.0`
+static REQUIREMENT_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_requirement_regex() -> &'static Regex {
+    REQUIREMENT_REGEX.get_or_init(|| {
+        Regex::new(r"^\s*([A-Za-z0-9][A-Za-z0-9._-]*)").expect("Invalid requirement regex")
+    })
+}
+
+/// Regex capturing the requirements of the PEP 621 `dependencies` array of a pyproject.toml
+static PYPROJECT_DEPENDENCIES_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_pyproject_dependencies_regex() -> &'static Regex {
+    PYPROJECT_DEPENDENCIES_REGEX.get_or_init(|| {
+        Regex::new(r#"(?m)^dependencies\s*=\s*\[([^\]]*)\]"#)
+            .expect("Invalid pyproject dependencies regex")
+    })
+}
+
+/// The source files of the dependencies of a project, as [`dependency_source_files`]
+/// finds them
+#[derive(Debug, Default)]
+pub(crate) struct DependencySources {
+    pub(crate) files: Vec<PathBuf>,
+    /// Number of dependencies the files are of
+    pub(crate) packages: usize,
+    /// Why dependencies couldn't be analyzed
+    pub(crate) warnings: Vec<String>,
+}
+
+/// An installed dependency
+#[derive(Debug, Clone, PartialEq, Eq, PartialOrd, Ord)]
+enum Package {
+    /// npm package, by its directory in a `node_modules`
+    Npm { name: String, directory: PathBuf },
+    /// Python distribution, by its `.dist-info` directory in a `site-packages`
+    Python { name: String, dist_info: PathBuf },
+    /// Go module, by its directory in the module cache
+    Go { path: String, directory: PathBuf },
+}
+
+impl Package {
+    /// Name the allow-list matches, normalized for Python distributions
+    fn name(&self) -> &str {
+        match self {
+            Self::Npm { name, .. } | Self::Python { name, .. } => name,
+            Self::Go { path, .. } => path,
+        }
+    }
+}
+
+/// Finds the installed dependencies of a project
+#[derive(Debug, Default)]
+struct Resolver {
+    /// Active Python virtual environment, `VIRTUAL_ENV`
+    virtual_env: Option<PathBuf>,
+    /// Go module cache, `GOMODCACHE` or `pkg/mod` in the first `GOPATH` entry
+    go_module_cache: Option<PathBuf>,
+    /// `.dist-info` directories of the distributions installed in each `site-packages`,
+    /// by normalized name
+    distributions: HashMap<PathBuf, HashMap<String, PathBuf>>,
+}
+
+/// The source files of the dependencies of the project of `source_files` to analyze along
+/// with them, as `analysis` configures it
+pub(crate) fn dependency_source_files(
+    source_files: &[&PathBuf],
+    language: Language,
+    analysis: &DependencyAnalysis,
+) -> DependencySources {
+    let go_module_cache = std::env::var_os("GOMODCACHE")
+        .filter(|cache| !cache.is_empty())
+        .map(PathBuf::from)
+        .or_else(|| {
+            std::env::var_os("GOPATH")
+                .and_then(|gopath| std::env::split_paths(&gopath).next())
+                .or_else(|| std::env::var_os("HOME").map(|home| PathBuf::from(home).join("go")))
+                .map(|gopath| gopath.join("pkg").join("mod"))
+        });
+    let mut resolver = Resolver {
+        virtual_env: std::env::var_os("VIRTUAL_ENV").map(PathBuf::from),
+        go_module_cache,
+        distributions: HashMap::new(),
+    };
+    find_dependency_sources(source_files, language, analysis, &mut resolver)
+}
+
+fn find_dependency_sources(
+    source_files: &[&PathBuf],
+    language: Language,
+    analysis: &DependencyAnalysis,
+    resolver: &mut Resolver,
+) -> DependencySources {
+    let mut sources = DependencySources::default();
+    if language == Language::Java {
+        sources.warnings.push(
+            "Dependencies of Java projects aren't analyzed: they're installed compiled".to_string(),
+        );
+        return sources;
+    }
+    let mut allowed = Vec::new();
+    for pattern in &analysis.allowed {
+        match glob::Pattern::new(&normalized_name(language, pattern)) {
+            Ok(pattern) => allowed.push(pattern),
+            Err(e) => sources.warnings.push(format!(
+                "Ignoring the invalid dependency pattern {pattern}: {e}"
+            )),
+        }
+    }
+    let sdk_packages: Vec<glob::Pattern> = SDK_PACKAGES
+        .iter()
+        .filter_map(|pattern| glob::Pattern::new(pattern).ok())
+        .collect();
+
+    let mut queue: VecDeque<(Package, usize)> = project_roots(source_files, language)
+        .into_iter()
+        .flat_map(|root| resolver.project_dependencies(&root, language, &mut sources.warnings))
+        .map(|package| (package, 1))
+        .collect();
+    let mut visited = BTreeSet::new();
+    while let Some((package, depth)) = queue.pop_front() {
+        if sdk_packages.iter().any(|sdk| sdk.matches(package.name()))
+            || !visited.insert(package.clone())
+        {
+            continue;
+        }
+        if allowed.is_empty()
+            || allowed
+                .iter()
+                .any(|pattern| pattern.matches(package.name()))
+        {
+            let files = package_source_files(&package, language);
+            log::debug!(
+                "Analyzing {} source files of the dependency {} (depth {depth})",
+                files.len(),
+                package.name()
+            );
+            if !files.is_empty() {
+                sources.packages += 1;
+                sources.files.extend(files);
+            }
+        }
+        if depth < analysis.depth {
+            queue.extend(
+                resolver
+                    .package_dependencies(&package)
+                    .into_iter()
+                    .map(|dependency| (dependency, depth + 1)),
+            );
+        }
+    }
+    sources
+}
+
+/// Directories of the projects of `source_files`: the nearest directory above each one
+/// declaring the dependencies of its language
+fn project_roots(source_files: &[&PathBuf], language: Language) -> BTreeSet<PathBuf> {
+    let working_directory = std::env::current_dir().unwrap_or_default();
+    let manifests: &[&str] = match language {
+        Language::JavaScript | Language::TypeScript => &["package.json"],
+        Language::Python => &PYTHON_MANIFESTS,
+        Language::Go => &["go.mod"],
+        Language::Java => &[],
+    };
+    source_files
+        .iter()
+        .filter_map(|path| {
+            let path = working_directory.join(path);
+            path.ancestors()
+                .skip(1)
+                .find(|directory| {
+                    manifests
+                        .iter()
+                        .any(|manifest| directory.join(manifest).is_file())
+                })
+                .map(Path::to_path_buf)
+        })
+        .collect()
+}
+
+impl Resolver {
+    /// The installed dependencies the project in `root` declares
+    fn project_dependencies(
+        &mut self,
+        root: &Path,
+        language: Language,
+        warnings: &mut Vec<String>,
+    ) -> Vec<Package> {
+        match language {
+            Language::JavaScript | Language::TypeScript => {
+                if !root
+                    .ancestors()
+                    .any(|directory| directory.join("node_modules").is_dir())
+                {
+                    warnings.push(format!(
+                        "Not analyzing the dependencies of {}: they aren't installed in a \
+                         node_modules directory",
+                        root.display()
+                    ));
+                }
+                npm_dependencies(root)
+            }
+            Language::Python => {
+                let Some(site_packages) = self.site_packages(root) else {
+                    warnings.push(format!(
+                        "Not analyzing the dependencies of {}: no virtual environment was \
+                         found, activate it or create it in .venv",
+                        root.display()
+                    ));
+                    return Vec::new();
+                };
+                let mut requirements = Vec::new();
+                for name in ["requirements.txt", "requirements-prod.txt"] {
+                    if let Ok(content) = std::fs::read_to_string(root.join(name)) {
+                        requirements.extend(
+                            content
+                                .lines()
+                                .filter(|line| !line.trim_start().starts_with(['#', '-']))
+                                .filter_map(requirement_name),
+                        );
+                    }
+                }
+                if let Ok(content) = std::fs::read_to_string(root.join("pyproject.toml")) {
+                    if let Some(captures) = get_pyproject_dependencies_regex().captures(&content) {
+                        requirements.extend(
+                            captures[1]
+                                .split(',')
+                                .map(|requirement| requirement.trim().trim_matches(['"', '\'']))
+                                .filter_map(requirement_name),
+                        );
+                    }
+                }
+                requirements
+                    .iter()
+                    .filter_map(|name| self.python_distribution(&site_packages, name))
+                    .collect()
+            }
+            Language::Go => {
+                if self
+                    .go_module_cache
+                    .as_deref()
+                    .is_none_or(|cache| !cache.is_dir())
+                {
+                    warnings.push(format!(
+                        "Not analyzing the dependencies of {}: the Go module cache wasn't \
+                         found, set GOMODCACHE",
+                        root.display()
+                    ));
+                    return Vec::new();
+                }
+                self.go_modules(root)
+            }
+            Language::Java => Vec::new(),
+        }
+    }
+
+    /// The installed dependencies of `package`
+    fn package_dependencies(&mut self, package: &Package) -> Vec<Package> {
+        match package {
+            Package::Npm { directory, .. } => npm_dependencies(directory),
+            Package::Python { dist_info, .. } => {
+                let Some(site_packages) = dist_info.parent().map(Path::to_path_buf) else {
+                    return Vec::new();
+                };
+                std::fs::read_to_string(dist_info.join("METADATA"))
+                    .unwrap_or_default()
+                    .lines()
+                    .filter_map(|line| line.strip_prefix("Requires-Dist:"))
+                    // Requirements of extras aren't installed unless the extra is requested
+                    .filter(|requirement| !requirement.contains("extra =="))
+                    .filter_map(requirement_name)
+                    .filter_map(|name| self.python_distribution(&site_packages, &name))
+                    .collect()
+            }
+            Package::Go { directory, .. } => self.go_modules(directory),
+        }
+    }
+
+    /// The `site-packages` of the virtual environment of the Python project in `root`
+    fn site_packages(&self, root: &Path) -> Option<PathBuf> {
+        VIRTUAL_ENV_DIRECTORIES
+            .iter()
+            .map(|directory| root.join(directory))
+            .chain(self.virtual_env.clone())
+            .find_map(|virtual_env| {
+                // Lib/site-packages on Windows, lib/python3.X/site-packages elsewhere
+                let windows = virtual_env.join("Lib").join("site-packages");
+                if windows.is_dir() {
+                    return Some(windows);
+                }
+                std::fs::read_dir(virtual_env.join("lib"))
+                    .ok()?
+                    .flatten()
+                    .map(|entry| entry.path().join("site-packages"))
+                    .find(|site_packages| site_packages.is_dir())
+            })
+    }
+
+    /// The distribution named `name` installed in `site_packages`
+    fn python_distribution(&mut self, site_packages: &Path, name: &str) -> Option<Package> {
+        let distributions = self
+            .distributions
+            .entry(site_packages.to_path_buf())
+            .or_insert_with(|| {
+                std::fs::read_dir(site_packages)
+                    .into_iter()
+                    .flatten()
+                    .flatten()
+                    .filter_map(|entry| {
+                        let file_name = entry.file_name();
+                        let (name, _version) = file_name
+                            .to_str()?
+                            .strip_suffix(".dist-info")?
+                            .rsplit_once('-')?;
+                        Some((normalized_name(Language::Python, name), entry.path()))
+                    })
+                    .collect()
+            });
+        let name = normalized_name(Language::Python, name);
+        distributions.get(&name).map(|dist_info| Package::Python {
+            name,
+            dist_info: dist_info.clone(),
+        })
+    }
+
+    /// The modules of the module cache the go.mod in `directory` requires directly
+    fn go_modules(&self, directory: &Path) -> Vec<Package> {
+        let Some(cache) = &self.go_module_cache else {
+            return Vec::new();
+        };
+        let content = std::fs::read_to_string(directory.join("go.mod")).unwrap_or_default();
+        go_requirements(&content)
+            .into_iter()
+            .filter_map(|(path, version)| {
+                let directory = cache.join(format!(
+                    "{}@{}",
+                    escape_module_path(&path),
+                    escape_module_path(&version)
+                ));
+                directory
+                    .is_dir()
+                    .then_some(Package::Go { path, directory })
+            })
+            .collect()
+    }
+}
+
+/// The packages the package.json in `directory` depends on, as installed in the nearest
+/// `node_modules` above it
+fn npm_dependencies(directory: &Path) -> Vec<Package> {
+    let Some(manifest) = std::fs::read_to_string(directory.join("package.json"))
+        .ok()
+        .and_then(|content| serde_json::from_str::<serde_json::Value>(&content).ok())
+    else {
+        return Vec::new();
+    };
+    NPM_DEPENDENCY_FIELDS
+        .iter()
+        .filter_map(|field| manifest.get(*field)?.as_object())
+        .flat_map(|dependencies| dependencies.keys())
+        .filter_map(|name| {
+            directory
+                .ancestors()
+                .map(|ancestor| ancestor.join("node_modules").join(name))
+                .find(|installed| installed.join("package.json").is_file())
+                .map(|directory| Package::Npm {
+                    name: name.clone(),
+                    directory,
+                })
+        })
+        .collect()
+}
+
+/// The name of the package a Python requirement specifier requires
+fn requirement_name(requirement: &str) -> Option<String> {
+    get_requirement_regex()
+        .captures(requirement)
+        .map(|captures| captures[1].to_string())
+}
+
+/// The modules with their version a go.mod requires directly, without the `// indirect` ones
+fn go_requirements(go_mod: &str) -> Vec<(String, String)> {
+    let mut requirements = Vec::new();
+    let mut in_block = false;
+    for line in go_mod.lines() {
+        let line = line.trim();
+        let requirement = if in_block {
+            if line.starts_with(')') {
+                in_block = false;
+                continue;
+            }
+            line
+        } else if let Some(rest) = line.strip_prefix("require") {
+            let rest = rest.trim_start();
+            if rest.starts_with('(') {
+                in_block = true;
+                continue;
+            }
+            rest
+        } else {
+            continue;
+        };
+        if requirement.contains("// indirect") {
+            continue;
+        }
+        let mut fields = requirement.split_whitespace();
+        if let (Some(path), Some(version)) = (fields.next(), fields.next()) {
+            if !path.starts_with("//") {
+                requirements.push((path.to_string(), version.to_string()));
+            }
+        }
+    }
+    requirements
+}
+
+/// A module path or version as the module cache stores it, upper case letters escaped as
+/// `!` and their lower case, e.g. `github.com/!burnt!sushi/toml`
+fn escape_module_path(path: &str) -> String {
+    path.chars()
+        .fold(String::with_capacity(path.len()), |mut escaped, c| {
+            if c.is_ascii_uppercase() {
+                escaped.push('!');
+                escaped.push(c.to_ascii_lowercase());
+            } else {
+                escaped.push(c);
+            }
+            escaped
+        })
+}
+
+/// `name` as the packages of `language` are compared, per PEP 503 for Python: lower case,
+/// with runs of `-`, `_` and `.` as `-`
+fn normalized_name(language: Language, name: &str) -> String {
+    if language != Language::Python {
+        return name.to_string();
+    }
+    let mut normalized = String::with_capacity(name.len());
+    for c in name.chars() {
+        if matches!(c, '-' | '_' | '.') {
+            if !normalized.ends_with('-') {
+                normalized.push('-');
+            }
+        } else {
+            normalized.push(c.to_ascii_lowercase());
+        }
+    }
+    normalized
+}
+
+/// The source files of `package` in `language`
+fn package_source_files(package: &Package, language: Language) -> Vec<PathBuf> {
+    match package {
+        Package::Npm { directory, .. } => {
+            let extensions: &[&str] = if language == Language::TypeScript {
+                &["js", "mjs", "cjs", "ts", "mts", "cts"]
+            } else {
+                &["js", "mjs", "cjs"]
+            };
+            walk_sources(directory, "package.json", |path| {
+                let name = path.file_name().and_then(|name| name.to_str());
+                !name.is_some_and(|name| {
+                    name.ends_with(".d.ts") || name.ends_with(".d.mts") || name.ends_with(".d.cts")
+                }) && path
+                    .extension()
+                    .and_then(|extension| extension.to_str())
+                    .is_some_and(|extension| extensions.contains(&extension))
+            })
+        }
+        Package::Python { dist_info, .. } => {
+            let Some(site_packages) = dist_info.parent() else {
+                return Vec::new();
+            };
+            // The files a distribution installs are listed in its RECORD
+            std::fs::read_to_string(dist_info.join("RECORD"))
+                .unwrap_or_default()
+                .lines()
+                .filter_map(|line| line.split(',').next())
+                .filter(|file| {
+                    file.ends_with(".py")
+                        && !file.starts_with("..")
+                        && !file.contains("__pycache__")
+                })
+                .map(|file| site_packages.join(file))
+                .filter(|path| path.is_file())
+                .collect()
+        }
+        Package::Go { directory, .. } => walk_sources(directory, "go.mod", |path| {
+            path.extension().is_some_and(|extension| extension == "go")
+                && !path.to_string_lossy().ends_with("_test.go")
+        }),
+    }
+}
+
+/// The files under `directory` `is_source` accepts, without those of hidden directories,
+/// nested `node_modules`, `testdata` and `vendor` directories, and nested packages, whose
+/// directories hold a `manifest` of their own
+fn walk_sources(
+    directory: &Path,
+    manifest: &str,
+    is_source: impl Fn(&Path) -> bool,
+) -> Vec<PathBuf> {
+    walkdir::WalkDir::new(directory)
+        .into_iter()
+        .filter_entry(|entry| {
+            entry.depth() == 0
+                || !entry.file_type().is_dir()
+                || !(entry.file_name().to_string_lossy().starts_with('.')
+                    || ["node_modules", "testdata", "vendor"]
+                        .iter()
+                        .any(|excluded| entry.file_name() == *excluded)
+                    || entry.path().join(manifest).is_file())
+        })
+        .flatten()
+        .filter(|entry| entry.file_type().is_file() && is_source(entry.path()))
+        .map(walkdir::DirEntry::into_path)
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use rstest::rstest;
+
+    use super::*;
+
+    fn write(path: &Path, content: &str) {
+        std::fs::create_dir_all(path.parent().unwrap()).unwrap();
+        std::fs::write(path, content).unwrap();
+    }
+
+    fn analysis(depth: usize, allowed: &[&str]) -> DependencyAnalysis {
+        DependencyAnalysis {
+            depth,
+            allowed: allowed.iter().map(ToString::to_string).collect(),
+        }
+    }
+
+    fn file_names(sources: &DependencySources, root: &Path) -> Vec<String> {
+        let mut names: Vec<String> = sources
+            .files
+            .iter()
+            .map(|path| {
+                path.strip_prefix(root)
+                    .unwrap()
+                    .to_string_lossy()
+                    .replace('\\', "/")
+            })
+            .collect();
+        names.sort();
+        names
+    }
+
+    /// A JavaScript project depending on an ORM, which depends on a storage adapter
+    fn npm_project(root: &Path) -> PathBuf {
+        write(
+            &root.join("package.json"),
+            r#"{"dependencies": {"orm": "1.0.0", "@aws-sdk/client-s3": "3.0.0"},
+                "devDependencies": {"jest": "29.0.0"}}"#,
+        );
+        write(&root.join("src/app.js"), "");
+        let orm = root.join("node_modules/orm");
+        write(
+            &orm.join("package.json"),
+            r#"{"dependencies": {"adapter": "1.0.0"}}"#,
+        );
+        write(&orm.join("lib/index.js"), "");
+        write(&orm.join("lib/index.d.ts"), "");
+        write(&root.join("node_modules/adapter/package.json"), "{}");
+        write(&root.join("node_modules/adapter/index.js"), "");
+        write(
+            &root.join("node_modules/@aws-sdk/client-s3/package.json"),
+            "{}",
+        );
+        write(&root.join("node_modules/@aws-sdk/client-s3/index.js"), "");
+        write(&root.join("node_modules/jest/package.json"), "{}");
+        write(&root.join("node_modules/jest/index.js"), "");
+        root.join("src/app.js")
+    }
+
+    #[rstest]
+    #[case::direct_dependencies(1, &[], &["node_modules/orm/lib/index.js"])]
+    #[case::transitive_dependencies(
+        2,
+        &[],
+        &["node_modules/adapter/index.js", "node_modules/orm/lib/index.js"]
+    )]
+    #[case::allowed_dependencies(2, &["adapter"], &["node_modules/adapter/index.js"])]
+    fn test_npm_dependencies(
+        #[case] depth: usize,
+        #[case] allowed: &[&str],
+        #[case] expected: &[&str],
+    ) {
+        let directory = tempfile::tempdir().unwrap();
+        let root = directory.path();
+        let app = npm_project(root);
+
+        let sources = find_dependency_sources(
+            &[&app],
+            Language::JavaScript,
+            &analysis(depth, allowed),
+            &mut Resolver::default(),
+        );
+
+        assert_eq!(file_names(&sources, root), expected);
+        assert_eq!(sources.packages, expected.len());
+        assert!(sources.warnings.is_empty());
+    }
+
+    #[test]
+    fn test_python_dependencies() {
+        let directory = tempfile::tempdir().unwrap();
+        let root = directory.path();
+        write(
+            &root.join("requirements.txt"),
+            "# Runtime\nSQLAlchemy[asyncio]>=2.0\nboto3==1.34.0\n-r extra.txt\n",
+        );
+        write(&root.join("app.py"), "");
+        let site_packages = root.join(".venv/lib/python3.12/site-packages");
+        write(
+            &site_packages.join("sqlalchemy-2.0.30.dist-info/METADATA"),
+            "Name: SQLAlchemy\nRequires-Dist: greenlet!=0.4.17\n\
+             Requires-Dist: asyncpg; extra == \"asyncpg\"\n",
+        );
+        write(
+            &site_packages.join("sqlalchemy-2.0.30.dist-info/RECORD"),
+            "sqlalchemy/__init__.py,sha256=abc,100\n\
+             sqlalchemy/__pycache__/__init__.cpython-312.pyc,,\n\
+             sqlalchemy/cyextension/util.so,sha256=def,200\n\
+             ../../bin/sqlalchemy,sha256=ghi,10\n",
+        );
+        write(&site_packages.join("sqlalchemy/__init__.py"), "");
+        write(
+            &site_packages.join("greenlet-3.0.3.dist-info/RECORD"),
+            "greenlet/__init__.py,,\n",
+        );
+        write(&site_packages.join("greenlet/__init__.py"), "");
+        write(
+            &site_packages.join("boto3-1.34.0.dist-info/RECORD"),
+            "boto3/__init__.py,,\n",
+        );
+        write(&site_packages.join("boto3/__init__.py"), "");
+        let app = root.join("app.py");
+
+        let sources = find_dependency_sources(
+            &[&app],
+            Language::Python,
+            &analysis(2, &["SQLAlchemy", "greenlet"]),
+            &mut Resolver::default(),
+        );
+
+        let prefix = ".venv/lib/python3.12/site-packages/";
+        assert_eq!(
+            file_names(&sources, root),
+            vec![
+                format!("{prefix}greenlet/__init__.py"),
+                format!("{prefix}sqlalchemy/__init__.py"),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_go_dependencies() {
+        let directory = tempfile::tempdir().unwrap();
+        let root = directory.path().join("app");
+        let cache = directory.path().join("mod");
+        write(
+            &root.join("go.mod"),
+            "module example.com/app\n\ngo 1.22\n\nrequire github.com/Acme/storage v1.2.0\n\n\
+             require (\n\tgithub.com/aws/aws-sdk-go-v2/service/s3 v1.50.0\n\
+             \tgolang.org/x/sync v0.6.0 // indirect\n)\n",
+        );
+        write(&root.join("main.go"), "");
+        let storage = cache.join("github.com/!acme/storage@v1.2.0");
+        write(&storage.join("go.mod"), "module github.com/Acme/storage\n");
+        write(&storage.join("storage.go"), "");
+        write(&storage.join("storage_test.go"), "");
+        write(&storage.join("testdata/fixture.go"), "");
+        write(
+            &storage.join("tools/go.mod"),
+            "module github.com/Acme/storage/tools\n",
+        );
+        write(&storage.join("tools/tools.go"), "");
+        write(
+            &cache.join("github.com/aws/aws-sdk-go-v2/service/s3@v1.50.0/api.go"),
+            "",
+        );
+        let main = root.join("main.go");
+
+        let sources = find_dependency_sources(
+            &[&main],
+            Language::Go,
+            &analysis(1, &[]),
+            &mut Resolver {
+                go_module_cache: Some(cache),
+                ..Resolver::default()
+            },
+        );
+
+        assert_eq!(
+            file_names(&sources, directory.path()),
+            vec!["mod/github.com/!acme/storage@v1.2.0/storage.go"]
+        );
+    }
+
+    #[test]
+    fn test_missing_installations_are_reported() {
+        let directory = tempfile::tempdir().unwrap();
+        write(&directory.path().join("requirements.txt"), "sqlalchemy\n");
+        let app = directory.path().join("app.py");
+
+        let sources = find_dependency_sources(
+            &[&app],
+            Language::Python,
+            &analysis(1, &[]),
+            &mut Resolver::default(),
+        );
+
+        assert!(sources.files.is_empty());
+        assert_eq!(sources.warnings.len(), 1);
+        assert!(sources.warnings[0].contains("no virtual environment was found"));
+    }
+
+    #[rstest]
+    #[case::python(Language::Python, "SQLAlchemy_Utils", "sqlalchemy-utils")]
+    #[case::npm(Language::JavaScript, "@Acme/orm", "@Acme/orm")]
+    fn test_normalized_name(
+        #[case] language: Language,
+        #[case] name: &str,
+        #[case] expected: &str,
+    ) {
+        assert_eq!(normalized_name(language, name), expected);
+    }
+}
@@ -0,0 +1,359 @@
+//! Diagnostics on the code the analysis couldn't fully understand
+//!
+//! Each diagnostic points at the line where the analysis lost precision, so it can be
+//! reported as a code-scanning annotation: calls on clients whose service couldn't be
+//! resolved, operations whose name exists in several services, and client methods
+//! invoked by a name computed at runtime, which the extractors can't see at all.
+
+use std::collections::HashSet;
+
+use serde::Serialize;
+
+use crate::extraction::shared::source_syntax::{Argument, Call};
+use crate::extraction::shared::SourceSyntax;
+use crate::extraction::{ParameterValue, SourceFile};
+use crate::{Language, Location, SdkMethodCall};
+
+/// What a diagnostic reports
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq, Hash)]
+#[serde(rename_all = "kebab-case")]
+pub enum DiagnosticKind {
+    /// The service of the client a call is made on couldn't be resolved
+    UnresolvedClient,
+    /// The operation of a call exists in several services and none could be chosen
+    AmbiguousOperation,
+    /// A client method is invoked in a way the extractors don't analyze
+    UnsupportedPattern,
+}
+
+impl DiagnosticKind {
+    /// All kinds of diagnostics
+    pub const ALL: [Self; 3] = [
+        Self::UnresolvedClient,
+        Self::AmbiguousOperation,
+        Self::UnsupportedPattern,
+    ];
+
+    /// Stable identifier of the kind, e.g. `unresolved-client`
+    #[must_use]
+    pub const fn id(self) -> &'static str {
+        match self {
+            Self::UnresolvedClient => "unresolved-client",
+            Self::AmbiguousOperation => "ambiguous-operation",
+            Self::UnsupportedPattern => "unsupported-pattern",
+        }
+    }
+
+    /// What diagnostics of the kind mean for the generated policies
+    #[must_use]
+    pub const fn description(self) -> &'static str {
+        match self {
+            Self::UnresolvedClient => {
+                "The service of the client couldn't be resolved, so the policies grant the \
+                 call's actions in every service having the operation"
+            }
+            Self::AmbiguousOperation => {
+                "The operation exists in several services, so the policies grant its actions \
+                 in all of them; pass --service-hints to narrow it down"
+            }
+            Self::UnsupportedPattern => {
+                "The client method is named at runtime, so its permissions aren't in the \
+                 policies; declare them with an autopilot:require annotation"
+            }
+        }
+    }
+}
+
+/// A place in the code where the analysis lost precision
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct Diagnostic {
+    /// What the diagnostic reports
+    pub kind: DiagnosticKind,
+    /// Description of the problem at this location
+    pub message: String,
+    /// Source location the diagnostic applies to
+    pub location: Location,
+}
+
+/// Names files invoking methods by a name computed at runtime contain: `getattr` in
+/// Python, `[` of computed members in JavaScript and TypeScript, `MethodByName` in Go
+/// and `getMethod` in Java. Files without any aren't parsed.
+const DYNAMIC_INVOCATION_NAMES: &[&str] = &["getattr", "[", "MethodByName", "getMethod"];
+
+/// Diagnostics for the extracted `methods` and the `source_files` they were extracted from
+pub(crate) fn analysis_diagnostics(
+    methods: &[SdkMethodCall],
+    source_files: &[SourceFile],
+) -> Vec<Diagnostic> {
+    let mut diagnostics: Vec<Diagnostic> = methods.iter().filter_map(call_diagnostic).collect();
+    for source_file in source_files {
+        diagnostics.extend(unsupported_patterns(source_file, methods));
+    }
+    diagnostics.sort_by(|a, b| a.location.cmp(&b.location).then(a.message.cmp(&b.message)));
+    diagnostics
+}
+
+/// Diagnostic for a call whose service couldn't be narrowed down to one
+fn call_diagnostic(method: &SdkMethodCall) -> Option<Diagnostic> {
+    if method.possible_services.len() < 2 {
+        return None;
+    }
+    let metadata = method.metadata.as_ref()?;
+    let services = method.possible_services.join(", ");
+    let (kind, message) = match &metadata.receiver {
+        Some(receiver) => (
+            DiagnosticKind::UnresolvedClient,
+            format!(
+                "The service of the client `{receiver}` couldn't be resolved, so `{}` is \
+                 assumed to call any of {services}",
+                method.name
+            ),
+        ),
+        None => (
+            DiagnosticKind::AmbiguousOperation,
+            format!(
+                "`{}` is an operation of {services}, so the policies grant its actions in \
+                 all of them; pass --service-hints to narrow it down",
+                method.name
+            ),
+        ),
+    };
+    Some(Diagnostic {
+        kind,
+        message,
+        location: metadata.location.clone(),
+    })
+}
+
+/// Client methods of `source_file` invoked by a name computed at runtime
+///
+/// Clients and invocations are matched in the syntax tree, so code in comments and
+/// string literals doesn't count.
+fn unsupported_patterns(source_file: &SourceFile, methods: &[SdkMethodCall]) -> Vec<Diagnostic> {
+    if !DYNAMIC_INVOCATION_NAMES
+        .iter()
+        .any(|name| source_file.content.contains(name))
+    {
+        return Vec::new();
+    }
+    let syntax = SourceSyntax::of(source_file);
+
+    // Variables holding clients: receivers of the file's calls and constructed clients
+    let clients: HashSet<&str> = methods
+        .iter()
+        .filter_map(|method| method.metadata.as_ref())
+        .filter(|metadata| metadata.location.file_path == source_file.path)
+        .filter_map(|metadata| metadata.receiver.as_deref())
+        .chain(
+            syntax
+                .assignments
+                .iter()
+                .filter(|assignment| {
+                    is_client_constructor(source_file.language, &assignment.callee)
+                })
+                .map(|assignment| assignment.target.as_str()),
+        )
+        .map(variable_name)
+        .collect();
+    if clients.is_empty() {
+        return Vec::new();
+    }
+
+    syntax
+        .calls
+        .iter()
+        .filter_map(|call| {
+            let client = dynamic_invocation_client(source_file.language, call, &syntax.calls)?;
+            clients.contains(variable_name(client)).then(|| Diagnostic {
+                kind: DiagnosticKind::UnsupportedPattern,
+                message: format!(
+                    "The method called on the client `{client}` is named at runtime, so its \
+                     permissions aren't in the policies; declare them with an \
+                     autopilot:require annotation"
+                ),
+                location: call.location.clone(),
+            })
+        })
+        .collect()
+}
+
+/// Whether the call `callee` (see [`Call::callee`]) constructs an SDK client:
+/// `boto3.client(...)` and `session.resource(...)` in Python, `new S3Client(...)` in
+/// JavaScript and TypeScript, `s3.NewFromConfig(...)` in Go, and `S3Client.create()` or
+/// `S3Client.builder()` in Java
+fn is_client_constructor(language: Language, callee: &str) -> bool {
+    let (class, function) = callee.rsplit_once('.').unwrap_or(("", callee));
+    match language {
+        Language::Python => !class.is_empty() && matches!(function, "client" | "resource"),
+        Language::JavaScript | Language::TypeScript => {
+            class.is_empty() && function.ends_with("Client")
+        }
+        Language::Go => !class.is_empty() && function == "NewFromConfig",
+        Language::Java => class.ends_with("Client") && matches!(function, "create" | "builder"),
+    }
+}
+
+/// The variable of the client `call` invokes a method of by a name computed at runtime:
+/// `s3` of `getattr(s3, name)` in Python, `s3[name](...)` in JavaScript and TypeScript,
+/// `reflect.ValueOf(s3).MethodByName(name)` in Go and `s3.getClass().getMethod(name)` in
+/// Java
+fn dynamic_invocation_client<'a>(
+    language: Language,
+    call: &'a Call,
+    calls: &'a [Call],
+) -> Option<&'a str> {
+    match language {
+        Language::Python => (call.callee == "getattr" && call.method == "getattr")
+            .then(|| first_expression(call))
+            .flatten(),
+        Language::JavaScript | Language::TypeScript => {
+            let (object, _) = call.callee.split_once('[')?;
+            (call.method.is_empty() && !object.is_empty()).then_some(object)
+        }
+        Language::Go => {
+            if call.method != "MethodByName" {
+                return None;
+            }
+            // `reflect.ValueOf(s3)`, which starts where the invocation does
+            calls
+                .iter()
+                .find(|value_of| {
+                    value_of.callee == "reflect.ValueOf"
+                        && value_of.method == "ValueOf"
+                        && value_of.location.start_position == call.location.start_position
+                })
+                .and_then(first_expression)
+        }
+        Language::Java => (call.method == "getMethod")
+            .then(|| call.callee.strip_suffix(".getClass"))
+            .flatten(),
+    }
+}
+
+/// The source text of the first argument of `call`, if it's an expression
+fn first_expression(call: &Call) -> Option<&str> {
+    match call.arguments.first() {
+        Some(Argument {
+            name: None,
+            value: ParameterValue::Unresolved(expression),
+        }) => Some(expression),
+        _ => None,
+    }
+}
+
+/// Last segment of a possibly qualified variable, e.g. `s3` for `self.s3`
+fn variable_name(variable: &str) -> &str {
+    variable.rsplit('.').next().unwrap_or(variable)
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use rstest::rstest;
+
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+
+    fn call(name: &str, services: &[&str], receiver: Option<&str>, line: usize) -> SdkMethodCall {
+        let mut metadata = SdkMethodCallMetadata::new(
+            format!("client.{name}()"),
+            Location::new(PathBuf::from("app.py"), (line, 1), (line, 20)),
+        );
+        if let Some(receiver) = receiver {
+            metadata = metadata.with_receiver(receiver.to_string());
+        }
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: services.iter().map(ToString::to_string).collect(),
+            metadata: Some(metadata),
+        }
+    }
+
+    #[test]
+    fn test_call_diagnostics() {
+        let methods = vec![
+            call("get_object", &["s3"], Some("s3"), 1),
+            call("list_tags", &["kms", "lambda"], Some("client"), 2),
+            call("describe_things", &["iot", "iotwireless"], None, 3),
+        ];
+
+        let diagnostics = analysis_diagnostics(&methods, &[]);
+
+        let kinds: Vec<_> = diagnostics.iter().map(|d| d.kind).collect();
+        assert_eq!(
+            kinds,
+            vec![
+                DiagnosticKind::UnresolvedClient,
+                DiagnosticKind::AmbiguousOperation
+            ]
+        );
+        assert!(diagnostics[0].message.contains("`client`"));
+        assert_eq!(diagnostics[1].location.start_position, (3, 1));
+    }
+
+    #[test]
+    fn test_dynamic_invocations() {
+        let source = concat!(
+            "import boto3\n",
+            "s3 = boto3.client('s3')\n",
+            "operation = getattr(s3, name)\n",
+            "value = getattr(config, 'bucket')\n",
+            "# operation = getattr(s3, name)\n",
+            "print('getattr(s3, name)')\n",
+        );
+        let source_file = SourceFile::with_language(
+            PathBuf::from("app.py"),
+            source.to_string(),
+            Language::Python,
+        );
+
+        let diagnostics = analysis_diagnostics(&[], &[source_file]);
+
+        assert_eq!(diagnostics.len(), 1);
+        assert_eq!(diagnostics[0].kind, DiagnosticKind::UnsupportedPattern);
+        assert_eq!(diagnostics[0].location.start_position, (3, 13));
+        assert_eq!(diagnostics[0].location.end_position, (3, 30));
+    }
+
+    #[rstest]
+    #[case::javascript(
+        "app.js",
+        Language::JavaScript,
+        "const s3 = new S3Client({});\n// s3[name](input)\nawait s3[name](input);\n",
+        3
+    )]
+    #[case::go(
+        "main.go",
+        Language::Go,
+        "package main\n\nfunc main() {\n\tclient := s3.NewFromConfig(cfg)\n\
+         \treflect.ValueOf(client).MethodByName(name).Call(nil)\n}\n",
+        5
+    )]
+    #[case::java(
+        "App.java",
+        Language::Java,
+        "class App {\n  void run() {\n    S3Client s3 = S3Client.create();\n\
+         \x20   s3.getClass().getMethod(name).invoke(s3);\n  }\n}\n",
+        4
+    )]
+    fn test_dynamic_invocations_in_the_syntax_tree(
+        #[case] path: &str,
+        #[case] language: Language,
+        #[case] source: &str,
+        #[case] line: usize,
+    ) {
+        let source_file =
+            SourceFile::with_language(PathBuf::from(path), source.to_string(), language);
+
+        let diagnostics = analysis_diagnostics(&[], &[source_file]);
+
+        let lines: Vec<_> = diagnostics
+            .iter()
+            .map(|diagnostic| diagnostic.location.start_line())
+            .collect();
+        assert_eq!(lines, [line], "diagnostics: {diagnostics:?}");
+        assert_eq!(diagnostics[0].kind, DiagnosticKind::UnsupportedPattern);
+    }
+}
@@ -0,0 +1,289 @@
+//! Detection of sources left out of the analysis by default: files git ignores,
+//! dependencies vendored into the project, generated code, and files too large or
+//! minified to analyze in reasonable time.
+
+use std::collections::HashMap;
+use std::path::{Component, Path, PathBuf};
+use std::sync::OnceLock;
+
+use ignore::gitignore::{Gitignore, GitignoreBuilder};
+use ignore::Match;
+use log::warn;
+use regex::Regex;
+
+/// Directories holding third-party code rather than the project's own
+const VENDORED_DIRECTORIES: [&str; 4] = ["vendor", "node_modules", "dist", "site-packages"];
+
+/// File name suffixes of the output of code generators and bundlers
+const GENERATED_FILE_SUFFIXES: [&str; 6] = [
+    ".pb.go",
+    "_pb2.py",
+    "_pb2_grpc.py",
+    "_pb.js",
+    "_pb.ts",
+    ".min.js",
+];
+
+/// Number of leading lines of a file searched for a generated code marker
+const GENERATED_MARKER_LINES: usize = 20;
+
+/// Size in bytes from which a file is minified if its lines are long on average
+const MINIFIED_MIN_SIZE: usize = 4096;
+
+/// Average length in bytes of the lines of minified and bundled code, e.g. webpack output
+const MINIFIED_AVERAGE_LINE_LENGTH: usize = 500;
+
+/// Regex matching the comments code generators mark their output with
+static GENERATED_MARKER_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_generated_marker_regex() -> &'static Regex {
+    GENERATED_MARKER_REGEX.get_or_init(|| {
+        Regex::new(concat!(
+            r"^\s*(?://|#|/?\*+)\s*",
+            r"(?:Code generated\b.*\bDO NOT EDIT\.|@generated\b|",
+            r"Generated by the protocol buffer compiler\.)",
+        ))
+        .expect("Invalid generated marker regex")
+    })
+}
+
+/// Check whether a file is in a directory of vendored dependencies, e.g. `vendor/` or
+/// `node_modules/`.
+///
+/// Only the directories below the working directory are considered for absolute paths,
+/// so a project checked out under a `vendor` directory is still analyzed.
+pub(crate) fn is_vendored_file(path: &Path) -> bool {
+    let path = std::env::current_dir()
+        .ok()
+        .and_then(|working_directory| path.strip_prefix(working_directory).ok())
+        .unwrap_or(path);
+    path.parent().is_some_and(|directory| {
+        directory.components().any(|component| {
+            matches!(component, Component::Normal(name)
+                if VENDORED_DIRECTORIES.iter().any(|vendored| name == *vendored))
+        })
+    })
+}
+
+/// Check whether a file is generated code by its name, e.g. `*.pb.go` files `protoc`
+/// generates.
+pub(crate) fn is_generated_file(path: &Path) -> bool {
+    path.file_name()
+        .and_then(|name| name.to_str())
+        .is_some_and(|name| {
+            GENERATED_FILE_SUFFIXES
+                .iter()
+                .any(|suffix| name.ends_with(suffix))
+        })
+}
+
+/// Check whether a loaded source is generated code by the marker comment at its top,
+/// e.g. Go's `// Code generated ... DO NOT EDIT.` or `@generated`.
+pub(crate) fn is_generated_content(content: &str) -> bool {
+    let regex = get_generated_marker_regex();
+    content
+        .lines()
+        .take(GENERATED_MARKER_LINES)
+        .any(|line| regex.is_match(line))
+}
+
+/// Why the loaded source `content` is skipped as oversized, if it is: larger than
+/// `max_file_size` bytes, or minified or bundled code, e.g. a webpack bundle, whose
+/// parsing could stall the whole analysis.
+pub(crate) fn oversized_reason(content: &str, max_file_size: u64) -> Option<String> {
+    let size = content.len();
+    if u64::try_from(size).unwrap_or(u64::MAX) > max_file_size {
+        return Some(format!(
+            "its {size} bytes exceed the size limit of {max_file_size} bytes"
+        ));
+    }
+    if size < MINIFIED_MIN_SIZE {
+        return None;
+    }
+    let average_line_length = size / content.lines().count().max(1);
+    (average_line_length > MINIFIED_AVERAGE_LINE_LENGTH)
+        .then(|| format!("it's minified, with lines of {average_line_length} bytes on average"))
+}
+
+/// `.gitignore` rules of the repositories the analyzed files are in
+#[derive(Default)]
+pub(crate) struct GitIgnores {
+    /// Rules of each directory, `None` if it has none
+    directories: HashMap<PathBuf, Option<Gitignore>>,
+}
+
+impl GitIgnores {
+    /// Check whether git ignores the file at `path`.
+    ///
+    /// The `.gitignore` files of the file's directory and of its ancestors up to the root
+    /// of the repository apply, the closest one first, then the repository's
+    /// `.git/info/exclude`. Files outside of a repository are never ignored.
+    pub(crate) fn is_ignored(&mut self, path: &Path) -> bool {
+        let Ok(path) = path.canonicalize() else {
+            return false;
+        };
+        let directories: Vec<&Path> = path.ancestors().skip(1).collect();
+        let Some(root) = directories
+            .iter()
+            .position(|directory| directory.join(".git").exists())
+        else {
+            return false;
+        };
+        for (index, directory) in directories[..=root].iter().enumerate() {
+            let rules = self
+                .directories
+                .entry(directory.to_path_buf())
+                .or_insert_with(|| directory_rules(directory, index == root));
+            match rules
+                .as_ref()
+                .map(|rules| rules.matched_path_or_any_parents(&path, false))
+            {
+                Some(Match::Ignore(_)) => return true,
+                Some(Match::Whitelist(_)) => return false,
+                Some(Match::None) | None => {}
+            }
+        }
+        false
+    }
+}
+
+/// The ignore rules of `directory`, if it has any
+fn directory_rules(directory: &Path, repository_root: bool) -> Option<Gitignore> {
+    let mut files = Vec::new();
+    // Rules added last take precedence, and `.gitignore` files over `info/exclude`
+    if repository_root {
+        files.push(directory.join(".git").join("info").join("exclude"));
+    }
+    files.push(directory.join(".gitignore"));
+    files.retain(|file| file.is_file());
+    if files.is_empty() {
+        return None;
+    }
+
+    let mut builder = GitignoreBuilder::new(directory);
+    for file in &files {
+        if let Some(error) = builder.add(file) {
+            warn!("Failed to read ignore rules of {}: {error}", file.display());
+        }
+    }
+    builder
+        .build()
+        .map_err(|error| {
+            warn!(
+                "Failed to build the ignore rules of {}: {error}",
+                directory.display()
+            );
+        })
+        .ok()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use rstest::rstest;
+
+    #[rstest]
+    #[case("vendor/github.com/aws/aws-sdk-go-v2/client.go", true)]
+    #[case("web/node_modules/@aws-sdk/client-s3/index.js", true)]
+    #[case("dist/app.js", true)]
+    #[case(".venv/lib/python3.12/site-packages/boto3/session.py", true)]
+    #[case("internal/vendors/client.go", false)]
+    #[case("src/dist.py", false)]
+    #[case("vendor.go", false)]
+    fn test_is_vendored_file(#[case] path: &str, #[case] expected: bool) {
+        assert_eq!(is_vendored_file(Path::new(path)), expected);
+    }
+
+    #[rstest]
+    #[case("api/service.pb.go", true)]
+    #[case("api/service_pb2.py", true)]
+    #[case("api/service_pb2_grpc.py", true)]
+    #[case("public/app.min.js", true)]
+    #[case("api/service.go", false)]
+    #[case("api/pb.go", false)]
+    fn test_is_generated_file(#[case] path: &str, #[case] expected: bool) {
+        assert_eq!(is_generated_file(Path::new(path)), expected);
+    }
+
+    #[rstest]
+    #[case::go(
+        "// Code generated by smithy-go-codegen DO NOT EDIT.\n\npackage s3\n",
+        true
+    )]
+    #[case::marker("/**\n * @generated\n */\nexport const client = 1;\n", true)]
+    #[case::protobuf(
+        "# -*- coding: utf-8 -*-\n# Generated by the protocol buffer compiler.  DO NOT EDIT!\n",
+        true
+    )]
+    #[case::handwritten("package s3\n\nfunc Get() {}\n", false)]
+    #[case::string_literal("const note = \"// Code generated ... DO NOT EDIT.\";\n", false)]
+    fn test_is_generated_content(#[case] content: &str, #[case] expected: bool) {
+        assert_eq!(is_generated_content(content), expected);
+    }
+
+    #[test]
+    fn test_generated_marker_below_the_header_is_ignored() {
+        let content = format!(
+            "{}// Code generated by mockgen. DO NOT EDIT.\n",
+            "x = 1\n".repeat(GENERATED_MARKER_LINES)
+        );
+        assert!(!is_generated_content(&content));
+    }
+
+    #[test]
+    fn test_oversized_reason() {
+        let source = "s3.get_object(Bucket=bucket, Key=key)\n".repeat(200);
+        assert_eq!(oversized_reason(&source, 1024 * 1024), None);
+        assert_eq!(
+            oversized_reason(&source, 1024).as_deref(),
+            Some("its 7800 bytes exceed the size limit of 1024 bytes")
+        );
+
+        let bundle = format!("{}\n", "var a=require('aws-sdk');".repeat(400));
+        assert_eq!(
+            oversized_reason(&bundle, 1024 * 1024).as_deref(),
+            Some("it's minified, with lines of 10001 bytes on average")
+        );
+
+        // Short files of a few long lines aren't minified
+        assert_eq!(oversized_reason(&"x".repeat(1000), 1024 * 1024), None);
+    }
+
+    #[test]
+    fn test_git_ignores() {
+        let repository = tempfile::tempdir().unwrap();
+        let root = repository.path();
+        std::fs::create_dir_all(root.join(".git/info")).unwrap();
+        std::fs::create_dir_all(root.join("build")).unwrap();
+        std::fs::create_dir_all(root.join("src/scripts")).unwrap();
+        std::fs::write(root.join(".gitignore"), "build/\n*.gen.py\n").unwrap();
+        std::fs::write(root.join(".git/info/exclude"), "scratch.py\n").unwrap();
+        std::fs::write(root.join("src/.gitignore"), "!keep.gen.py\n").unwrap();
+        for file in [
+            "build/app.py",
+            "src/app.py",
+            "src/model.gen.py",
+            "src/keep.gen.py",
+            "src/scripts/scratch.py",
+        ] {
+            std::fs::write(root.join(file), "import boto3\n").unwrap();
+        }
+
+        let mut git_ignores = GitIgnores::default();
+
+        assert!(git_ignores.is_ignored(&root.join("build/app.py")));
+        assert!(git_ignores.is_ignored(&root.join("src/model.gen.py")));
+        assert!(git_ignores.is_ignored(&root.join("src/scripts/scratch.py")));
+        assert!(!git_ignores.is_ignored(&root.join("src/app.py")));
+        assert!(!git_ignores.is_ignored(&root.join("src/keep.gen.py")));
+    }
+
+    #[test]
+    fn test_files_outside_of_a_repository_are_not_ignored() {
+        let directory = tempfile::tempdir().unwrap();
+        std::fs::write(directory.path().join(".gitignore"), "*.py\n").unwrap();
+        std::fs::write(directory.path().join("app.py"), "import boto3\n").unwrap();
+
+        assert!(!GitIgnores::default().is_ignored(&directory.path().join("app.py")));
+    }
+}
@@ -1,3 +1,38 @@
+pub(crate) mod annotations;
+pub(crate) mod confidence;
+pub(crate) mod config_values;
+pub(crate) mod custom_services;
+pub(crate) mod dax_clients;
+pub(crate) mod dependencies;
+pub(crate) mod diagnostics;
+pub(crate) mod excluded_files;
 pub mod extraction_utils;
+pub(crate) mod parameter_shapes;
+pub(crate) mod resource_literals;
+pub(crate) mod service_choices;
+pub(crate) mod source_syntax;
+pub(crate) mod test_files;
+pub(crate) mod wrapper_functions;
 
+pub use annotations::SuppressedCall;
+pub(crate) use annotations::{required_permissions, suppress_annotated_calls, RequiredPermission};
+pub(crate) use confidence::ConfidenceEvidence;
+pub(crate) use config_values::ConfigValues;
+pub(crate) use custom_services::separate_custom_service_calls;
+pub(crate) use dax_clients::separate_dax_calls;
+pub(crate) use dependencies::dependency_source_files;
+pub(crate) use diagnostics::analysis_diagnostics;
+pub use diagnostics::{Diagnostic, DiagnosticKind};
+pub(crate) use excluded_files::{
+    is_generated_content, is_generated_file, is_vendored_file, oversized_reason, GitIgnores,
+};
 pub(crate) use extraction_utils::*;
+pub(crate) use parameter_shapes::disambiguate_by_parameter_shapes;
+pub(crate) use resource_literals::{
+    bind_configured_resources, bind_literal_resources, literal_argument, ProjectConstants,
+    ResourceValue,
+};
+pub(crate) use service_choices::apply_service_choices;
+pub(crate) use source_syntax::SourceSyntax;
+pub(crate) use test_files::{is_mocked_content, is_test_content, is_test_file};
+pub(crate) use wrapper_functions::{load_wrapper_functions, wrapper_calls};
@@ -0,0 +1,268 @@
+//! Disambiguation of calls by the shapes of their arguments
+//!
+//! A call whose client isn't known resolves to every service with an operation of its name,
+//! e.g. `put_resource_policy` to Secrets Manager, DynamoDB, CloudWatch Logs and others.
+//! The names of its arguments usually tell them apart: `SecretId` is a member of the input
+//! shape of Secrets Manager's operation only, `ResourceArn`, `Policy` and
+//! `ExpectedRevisionId` together of DynamoDB's. Calls are narrowed to the services whose
+//! input shape has every argument the call names, preferring those whose required members
+//! the call all passes. A call whose arguments fit several services equally stays
+//! ambiguous.
+
+use crate::extraction::sdk_model::{ServiceModelIndex, Shape};
+use crate::extraction::{Parameter, SdkMethodCall};
+
+/// Narrow the possible services of the ambiguous `methods` to those whose input shape fits
+/// the arguments of the call best
+pub(crate) fn disambiguate_by_parameter_shapes(
+    methods: &mut [SdkMethodCall],
+    service_index: &ServiceModelIndex,
+) {
+    for method in methods {
+        if method.possible_services.len() < 2 {
+            continue;
+        }
+        let arguments = call_arguments(method);
+        if arguments.is_empty() {
+            continue;
+        }
+
+        let fitting: Vec<(&String, &Shape)> = method
+            .possible_services
+            .iter()
+            .filter_map(|service| {
+                let shape = input_shape(service_index, &method.name, service)?;
+                has_members(shape, &arguments).then_some((service, shape))
+            })
+            .collect();
+        let complete: Vec<&String> = fitting
+            .iter()
+            .filter(|(_, shape)| passes_required_members(shape, &arguments))
+            .map(|(service, _)| *service)
+            .collect();
+        let narrowed: Vec<String> = if complete.is_empty() {
+            fitting
+                .iter()
+                .map(|(service, _)| (*service).clone())
+                .collect()
+        } else {
+            complete.into_iter().cloned().collect()
+        };
+
+        // Calls no service fits are left alone: their arguments may be built elsewhere
+        if !narrowed.is_empty() && narrowed.len() < method.possible_services.len() {
+            log::debug!(
+                "Narrowed {} from {:?} to {:?} by the names of its arguments",
+                method.name,
+                method.possible_services,
+                narrowed
+            );
+            method.possible_services = narrowed;
+        }
+    }
+}
+
+/// Names of the arguments of `method`: its keyword arguments and the fields of the struct
+/// literals it's passed
+pub(crate) fn call_arguments(method: &SdkMethodCall) -> Vec<&str> {
+    let Some(metadata) = &method.metadata else {
+        return Vec::new();
+    };
+    metadata
+        .parameters
+        .iter()
+        .flat_map(|parameter| match parameter {
+            Parameter::Keyword { name, .. } => vec![name.as_str()],
+            Parameter::Positional {
+                struct_fields: Some(fields),
+                ..
+            } => fields.iter().map(String::as_str).collect(),
+            _ => Vec::new(),
+        })
+        .collect()
+}
+
+/// Input shape of the operation `method_name` of `service`, if it has one
+pub(crate) fn input_shape<'a>(
+    service_index: &'a ServiceModelIndex,
+    method_name: &str,
+    service: &str,
+) -> Option<&'a Shape> {
+    let method_ref = service_index
+        .method_lookup
+        .get(method_name)?
+        .iter()
+        .find(|method_ref| method_ref.service_name == service)?;
+    let definition = service_index.services.get(&method_ref.service_name)?;
+    let operation = definition.operations.get(&method_ref.operation_name)?;
+    definition.shapes.get(&operation.input.as_ref()?.shape)
+}
+
+/// Whether every one of `arguments` is a member of `shape`
+///
+/// Member names are matched case-insensitively, as AWS models aren't consistent.
+pub(crate) fn has_members(shape: &Shape, arguments: &[&str]) -> bool {
+    arguments.iter().all(|argument| {
+        shape
+            .members
+            .keys()
+            .any(|member| member.eq_ignore_ascii_case(argument))
+    })
+}
+
+/// Whether `arguments` include every required member of `shape`
+fn passes_required_members(shape: &Shape, arguments: &[&str]) -> bool {
+    shape.required.iter().flatten().all(|required| {
+        arguments
+            .iter()
+            .any(|argument| argument.eq_ignore_ascii_case(required))
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use std::collections::HashMap;
+    use std::path::PathBuf;
+
+    use rstest::rstest;
+
+    use super::*;
+    use crate::extraction::sdk_model::{
+        Operation, SdkServiceDefinition, ServiceMetadata, ServiceMethodRef, ShapeReference,
+    };
+    use crate::extraction::{ParameterValue, SdkMethodCallMetadata};
+    use crate::Location;
+
+    const ALL_SERVICES: &[&str] = &["secretsmanager", "dynamodb", "kinesis", "logs"];
+
+    /// `put_resource_policy` of services with the given input members and required members
+    fn service_index(services: &[(&str, &[&str], &[&str])]) -> ServiceModelIndex {
+        let mut definitions = HashMap::new();
+        let mut method_refs = Vec::new();
+        for (service, members, required) in services {
+            let shape = Shape {
+                type_name: "structure".to_string(),
+                members: members
+                    .iter()
+                    .map(|member| {
+                        let reference = ShapeReference {
+                            shape: "String".to_string(),
+                        };
+                        ((*member).to_string(), reference)
+                    })
+                    .collect(),
+                required: Some(required.iter().map(ToString::to_string).collect()),
+            };
+            let operation = Operation {
+                name: "PutResourcePolicy".to_string(),
+                input: Some(ShapeReference {
+                    shape: "PutResourcePolicyRequest".to_string(),
+                }),
+            };
+            definitions.insert(
+                (*service).to_string(),
+                SdkServiceDefinition {
+                    version: Some("2.0".to_string()),
+                    metadata: ServiceMetadata {
+                        api_version: "2017-10-17".to_string(),
+                        service_id: (*service).to_string(),
+                    },
+                    operations: HashMap::from([("PutResourcePolicy".to_string(), operation)]),
+                    shapes: HashMap::from([("PutResourcePolicyRequest".to_string(), shape)]),
+                },
+            );
+            method_refs.push(ServiceMethodRef {
+                service_name: (*service).to_string(),
+                operation_name: "PutResourcePolicy".to_string(),
+            });
+        }
+        ServiceModelIndex {
+            services: definitions,
+            method_lookup: HashMap::from([("put_resource_policy".to_string(), method_refs)]),
+            waiter_lookup: HashMap::new(),
+        }
+    }
+
+    fn call(arguments: &[&str]) -> SdkMethodCall {
+        let parameters = arguments
+            .iter()
+            .enumerate()
+            .map(|(position, name)| Parameter::Keyword {
+                name: (*name).to_string(),
+                value: ParameterValue::Unresolved("value".to_string()),
+                position,
+                type_annotation: None,
+            })
+            .collect();
+        SdkMethodCall {
+            name: "put_resource_policy".to_string(),
+            possible_services: ALL_SERVICES.iter().map(ToString::to_string).collect(),
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    "client.put_resource_policy()".to_string(),
+                    Location::new(PathBuf::from("app.py"), (1, 1), (1, 30)),
+                )
+                .with_parameters(parameters),
+            ),
+        }
+    }
+
+    #[rstest]
+    #[case::unique_member(&["SecretId", "ResourcePolicy"], &["secretsmanager"])]
+    #[case::case_insensitive(&["secretId"], &["secretsmanager"])]
+    #[case::optional_member(&["ResourceArn", "Policy", "ExpectedRevisionId"], &["dynamodb"])]
+    #[case::required_members(&["resourceArn", "expectedRevisionId"], &["logs"])]
+    #[case::equally_fitting(&["ResourceArn", "Policy"], &["dynamodb", "kinesis"])]
+    #[case::no_fitting_service(&["Bucket"], ALL_SERVICES)]
+    #[case::no_arguments(&[], ALL_SERVICES)]
+    fn test_disambiguate_by_parameter_shapes(
+        #[case] arguments: &[&str],
+        #[case] expected: &[&str],
+    ) {
+        let service_index = service_index(&[
+            (
+                "secretsmanager",
+                &["SecretId", "ResourcePolicy", "BlockPublicPolicy"],
+                &["SecretId", "ResourcePolicy"],
+            ),
+            (
+                "dynamodb",
+                &["ResourceArn", "Policy", "ExpectedRevisionId"],
+                &["ResourceArn", "Policy"],
+            ),
+            (
+                "kinesis",
+                &["ResourceARN", "Policy"],
+                &["ResourceARN", "Policy"],
+            ),
+            (
+                "logs",
+                &[
+                    "policyName",
+                    "policyDocument",
+                    "resourceArn",
+                    "expectedRevisionId",
+                ],
+                &[],
+            ),
+        ]);
+        let mut methods = vec![call(arguments)];
+
+        disambiguate_by_parameter_shapes(&mut methods, &service_index);
+
+        assert_eq!(methods[0].possible_services, expected);
+    }
+
+    #[test]
+    fn test_struct_fields_are_arguments() {
+        let mut method = call(&[]);
+        method.metadata.as_mut().unwrap().parameters = vec![Parameter::Positional {
+            value: ParameterValue::Unresolved("&secretsmanager.PutResourcePolicyInput{}".into()),
+            position: 1,
+            type_annotation: None,
+            struct_fields: Some(vec!["SecretId".to_string()]),
+        }];
+
+        assert_eq!(call_arguments(&method), vec!["SecretId"]);
+    }
+}
@@ -0,0 +1,1336 @@
+//! Scoping of resources to identifiers written as string literals at call sites.
+//!
+//! `s3.get_object(Bucket="reports", Key="latest.csv")` only reads one object, so its
+//! statement can name `arn:${Partition}:s3:::reports/latest.csv` instead of every
+//! object in every bucket. Literals are recognized in keyword arguments (Python,
+//! JavaScript, TypeScript), Go input struct literals and Java request builders.
+//!
+//! Identifiers read from environment variables (`os.environ["BUCKET_NAME"]`,
+//! `process.env.BUCKET_NAME`, `os.Getenv("BUCKET_NAME")`, `System.getenv("BUCKET_NAME")`)
+//! are bound to `${env:BUCKET_NAME}`, which policies render as the template
+//! `arn:aws:s3:::${BUCKET_NAME}/*` so they document which variable controls scoping.
+//!
+//! Call sites naming a constant instead (`TableName: tableName` with
+//! `const tableName = "orders"` in another file) are resolved through the
+//! [`ProjectConstants`] the language extractor collected from the whole project.
+//!
+//! Copies read the object `CopySource` names and write the one `Bucket` and `Key`
+//! name, so the source is bound separately and scopes only the reads of the source.
+
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use crate::extraction::shared::config_values::ConfigValues;
+use crate::extraction::{Parameter, ParameterValue};
+use crate::policy_generation::utils::ENVIRONMENT_PLACEHOLDER_PREFIX;
+use crate::SdkMethodCall;
+
+/// How a literal input member scopes a resource ARN placeholder
+struct LiteralResource {
+    service: &'static str,
+    /// Input member holding the identifier, e.g. `Bucket`
+    member: &'static str,
+    placeholder: &'static str,
+    /// Placeholder that must be bound first, e.g. an object key only names an
+    /// object within a known bucket
+    requires: Option<&'static str>,
+    /// Turns the literal into the placeholder value, or rejects it
+    identifier: fn(&str) -> Option<String>,
+    /// Whether the value of an environment variable can stand in for the
+    /// placeholder as-is; queue URLs and parameter paths need rewriting first
+    environment: bool,
+}
+
+/// Member to placeholder mappings, in binding order
+const LITERAL_RESOURCES: &[LiteralResource] = &[
+    LiteralResource {
+        service: "s3",
+        member: "Bucket",
+        placeholder: "BucketName",
+        requires: None,
+        identifier: bucket_name,
+        environment: true,
+    },
+    LiteralResource {
+        service: "s3",
+        member: "Key",
+        placeholder: "ObjectName",
+        requires: Some("BucketName"),
+        identifier: object_key,
+        environment: true,
+    },
+    LiteralResource {
+        service: "dynamodb",
+        member: "TableName",
+        placeholder: "TableName",
+        requires: None,
+        identifier: resource_name,
+        environment: true,
+    },
+    LiteralResource {
+        service: "dynamodb",
+        member: "IndexName",
+        placeholder: "IndexName",
+        requires: Some("TableName"),
+        identifier: resource_name,
+        environment: true,
+    },
+    LiteralResource {
+        service: "sqs",
+        member: "QueueUrl",
+        placeholder: "QueueName",
+        requires: None,
+        identifier: queue_name,
+        environment: false,
+    },
+    LiteralResource {
+        service: "lambda",
+        member: "FunctionName",
+        placeholder: "FunctionName",
+        requires: None,
+        identifier: resource_name,
+        environment: true,
+    },
+    LiteralResource {
+        service: "ssm",
+        member: "Name",
+        placeholder: "ParameterName",
+        requires: None,
+        identifier: parameter_name,
+        environment: false,
+    },
+    LiteralResource {
+        service: "ssm",
+        member: "Path",
+        placeholder: "ParameterName",
+        requires: None,
+        identifier: parameter_path,
+        environment: false,
+    },
+    LiteralResource {
+        service: "secretsmanager",
+        member: "SecretId",
+        placeholder: "SecretId",
+        requires: None,
+        identifier: secret_name,
+        environment: false,
+    },
+    LiteralResource {
+        service: "secretsmanager",
+        member: "Name",
+        placeholder: "SecretId",
+        requires: None,
+        identifier: secret_name,
+        environment: false,
+    },
+    LiteralResource {
+        service: "sagemaker-runtime",
+        member: "EndpointName",
+        placeholder: "EndpointName",
+        requires: None,
+        identifier: endpoint_name,
+        environment: true,
+    },
+    LiteralResource {
+        service: "bedrock-runtime",
+        member: "modelId",
+        placeholder: "ModelId",
+        requires: None,
+        identifier: model_id,
+        environment: true,
+    },
+    LiteralResource {
+        service: "bedrock-runtime",
+        member: "ModelId",
+        placeholder: "ModelId",
+        requires: None,
+        identifier: model_id,
+        environment: true,
+    },
+    LiteralResource {
+        service: "bedrock-agent-runtime",
+        member: "knowledgeBaseId",
+        placeholder: "KnowledgeBaseId",
+        requires: None,
+        identifier: resource_name,
+        environment: true,
+    },
+    LiteralResource {
+        service: "bedrock-agent-runtime",
+        member: "KnowledgeBaseId",
+        placeholder: "KnowledgeBaseId",
+        requires: None,
+        identifier: resource_name,
+        environment: true,
+    },
+];
+
+/// Member to placeholder mappings of the object a copy reads, bound separately
+/// from the resources the call writes
+const SOURCE_RESOURCES: &[LiteralResource] = &[
+    LiteralResource {
+        service: "s3",
+        member: "CopySource.Bucket",
+        placeholder: "BucketName",
+        requires: None,
+        identifier: bucket_name,
+        environment: true,
+    },
+    LiteralResource {
+        service: "s3",
+        member: "CopySource.Key",
+        placeholder: "ObjectName",
+        requires: Some("BucketName"),
+        identifier: object_key,
+        environment: true,
+    },
+];
+
+/// Input member naming the object a copy reads, as `bucket/key` or as a mapping
+/// of `Bucket` and `Key`
+pub(crate) const COPY_SOURCE_MEMBER: &str = "CopySource";
+
+/// Value of an expression naming a resource
+#[derive(Debug, Clone, PartialEq, Eq, Hash)]
+pub(crate) enum ResourceValue {
+    Literal(String),
+    /// Read from the named environment variable
+    Environment(String),
+}
+
+impl ResourceValue {
+    /// A string literal delimited by one of `quotes`, or an environment variable read
+    pub(crate) fn parse(text: &str, quotes: &[char]) -> Option<Self> {
+        quoted(text, quotes)
+            .map(Self::Literal)
+            .or_else(|| Self::environment_read(text))
+    }
+
+    /// The environment variable read by `text`, e.g. `os.environ["BUCKET_NAME"]`
+    pub(crate) fn environment_read(text: &str) -> Option<Self> {
+        environment_variable(text).map(Self::Environment)
+    }
+}
+
+/// Constants defined across a project, which call sites name instead of literals
+pub(crate) trait ProjectConstants {
+    /// Value of the constant `expression` (e.g. `tableName`, `config.Tables.Orders`)
+    /// refers to in the file at `path`
+    fn constant_value(&self, path: &Path, expression: &str) -> Option<ResourceValue>;
+}
+
+/// Functions reading an environment variable named by their first argument
+const ENVIRONMENT_CALLS: &[&str] = &[
+    "os.environ.get(",
+    "environ.get(",
+    "os.getenv(",
+    "getenv(",
+    "os.Getenv(",
+    "System.getenv(",
+];
+
+/// Mappings subscripted with an environment variable name
+const ENVIRONMENT_MAPPINGS: &[&str] = &["os.environ[", "environ[", "process.env["];
+
+/// Bind ARN placeholders of each call to the resources named by application configuration
+///
+/// References to configuration settings scope resources like literals, and
+/// `${env:NAME}` bindings of variables set by `.env` files become their values.
+pub(crate) fn bind_configured_resources(method_calls: &mut [SdkMethodCall], config: &ConfigValues) {
+    bind_literal_resources(method_calls, Some(config));
+
+    let environment_prefix = format!("${{{ENVIRONMENT_PLACEHOLDER_PREFIX}");
+    for call in method_calls {
+        let Some(metadata) = call.metadata.as_mut() else {
+            continue;
+        };
+        let bindings = metadata
+            .resource_bindings
+            .iter_mut()
+            .chain(metadata.source_resource_bindings.iter_mut());
+        for (placeholder, binding) in bindings {
+            let Some(value) = binding
+                .strip_prefix(&environment_prefix)
+                .and_then(|variable| variable.strip_suffix('}'))
+                .and_then(|variable| config.environment_value(variable))
+            else {
+                continue;
+            };
+            let identifier = LITERAL_RESOURCES
+                .iter()
+                .filter(|resource| {
+                    resource.environment
+                        && resource.placeholder == placeholder
+                        && call
+                            .possible_services
+                            .iter()
+                            .any(|service| service == resource.service)
+                })
+                .find_map(|resource| (resource.identifier)(value));
+            if let Some(identifier) = identifier {
+                *binding = identifier;
+            }
+        }
+    }
+}
+
+/// Bind ARN placeholders of each call to the literal identifiers it passes
+///
+/// Bindings the extractors already made are kept; literals only fill in the rest.
+pub(crate) fn bind_literal_resources(
+    method_calls: &mut [SdkMethodCall],
+    constants: Option<&dyn ProjectConstants>,
+) {
+    for call in method_calls {
+        let Some(metadata) = call.metadata.as_mut() else {
+            continue;
+        };
+        let values = ArgumentValues {
+            path: &metadata.location.file_path,
+            constants,
+        };
+        let arguments = values.literal_arguments(&metadata.parameters);
+        if arguments.is_empty() {
+            continue;
+        }
+        bind_resources(
+            LITERAL_RESOURCES,
+            &call.possible_services,
+            &arguments,
+            &mut metadata.resource_bindings,
+        );
+        bind_resources(
+            SOURCE_RESOURCES,
+            &call.possible_services,
+            &arguments,
+            &mut metadata.source_resource_bindings,
+        );
+    }
+}
+
+/// The string literal `call` passes for the input member `member`, e.g. `RoleArn`
+pub(crate) fn literal_argument(call: &SdkMethodCall, member: &str) -> Option<String> {
+    let metadata = call.metadata.as_ref()?;
+    let values = ArgumentValues {
+        path: &metadata.location.file_path,
+        constants: None,
+    };
+    match values
+        .literal_arguments(&metadata.parameters)
+        .remove(member)?
+    {
+        ResourceValue::Literal(literal) => Some(literal),
+        ResourceValue::Environment(_) => None,
+    }
+}
+
+/// Bind the placeholders of `resources` to the identifiers among `arguments`
+fn bind_resources(
+    resources: &[LiteralResource],
+    services: &[String],
+    arguments: &BTreeMap<String, ResourceValue>,
+    bindings: &mut BTreeMap<String, String>,
+) {
+    for resource in resources {
+        if !services.iter().any(|service| service == resource.service)
+            || resource
+                .requires
+                .is_some_and(|required| !bindings.contains_key(required))
+        {
+            continue;
+        }
+        let identifier = match arguments.get(resource.member) {
+            Some(ResourceValue::Literal(literal)) => (resource.identifier)(literal),
+            Some(ResourceValue::Environment(variable)) if resource.environment => {
+                Some(format!("${{{ENVIRONMENT_PLACEHOLDER_PREFIX}{variable}}}"))
+            }
+            _ => None,
+        };
+        if let Some(identifier) = identifier {
+            bindings
+                .entry(resource.placeholder.to_string())
+                .or_insert(identifier);
+        }
+    }
+}
+
+/// Resolves argument expressions of one call site
+struct ArgumentValues<'a> {
+    path: &'a Path,
+    constants: Option<&'a dyn ProjectConstants>,
+}
+
+impl ArgumentValues<'_> {
+    /// Input members passed as string literals, environment variable reads or
+    /// project constants, keyed by their PascalCase name
+    fn literal_arguments(&self, parameters: &[Parameter]) -> BTreeMap<String, ResourceValue> {
+        let mut arguments = BTreeMap::new();
+        for parameter in parameters {
+            match parameter {
+                Parameter::Keyword {
+                    name,
+                    value: ParameterValue::Resolved(value),
+                    ..
+                } => {
+                    arguments.insert(name.clone(), ResourceValue::Literal(value.clone()));
+                }
+                Parameter::Keyword {
+                    name,
+                    value: ParameterValue::Unresolved(text),
+                    ..
+                } if name == COPY_SOURCE_MEMBER && text.trim_start().starts_with('{') => {
+                    arguments.extend(mapping_entries(text).into_iter().filter_map(
+                        |(key, value)| {
+                            Some((
+                                format!("{COPY_SOURCE_MEMBER}.{key}"),
+                                self.value(value, &['"', '\'', '`'])?,
+                            ))
+                        },
+                    ));
+                }
+                Parameter::Keyword {
+                    name,
+                    value: ParameterValue::Unresolved(text),
+                    ..
+                } => {
+                    if let Some(value) = self.value(text, &[]) {
+                        arguments.insert(name.clone(), value);
+                    }
+                }
+                Parameter::Positional {
+                    value: ParameterValue::Unresolved(text),
+                    struct_fields: Some(_),
+                    ..
+                } => arguments.extend(self.go_struct_literals(text)),
+                Parameter::Positional {
+                    value: ParameterValue::Unresolved(text),
+                    ..
+                } => arguments.extend(self.java_builder_literals(text)),
+                _ => {}
+            }
+        }
+        if let Some(ResourceValue::Literal(copy_source)) = arguments.get(COPY_SOURCE_MEMBER) {
+            if let Some((bucket, key)) = copy_source_object(copy_source) {
+                arguments.insert(
+                    format!("{COPY_SOURCE_MEMBER}.Bucket"),
+                    ResourceValue::Literal(bucket),
+                );
+                arguments.insert(
+                    format!("{COPY_SOURCE_MEMBER}.Key"),
+                    ResourceValue::Literal(key),
+                );
+            }
+        }
+        // Java builders name the copied object with `sourceBucket` and `sourceKey`
+        for (setter, member) in [("SourceBucket", "Bucket"), ("SourceKey", "Key")] {
+            if let Some(value) = arguments.get(setter).cloned() {
+                arguments
+                    .entry(format!("{COPY_SOURCE_MEMBER}.{member}"))
+                    .or_insert(value);
+            }
+        }
+        // Concatenations like `'a' + 'b'` look quoted at both ends
+        arguments.retain(|_, argument| match argument {
+            ResourceValue::Literal(value) => !value.is_empty() && !value.contains(['"', '\'', '`']),
+            ResourceValue::Environment(_) => true,
+        });
+        arguments
+    }
+
+    /// Fields of a Go input struct literal, e.g. `&s3.GetObjectInput{Bucket: aws.String("b")}`
+    fn go_struct_literals(&self, text: &str) -> Vec<(String, ResourceValue)> {
+        let (Some(start), Some(end)) = (text.find('{'), text.rfind('}')) else {
+            return Vec::new();
+        };
+        if end <= start {
+            return Vec::new();
+        }
+        split_top_level(&text[start + 1..end], ',')
+            .into_iter()
+            .filter_map(|field| {
+                let (name, value) = field.split_once(':')?;
+                let value = value.trim();
+                let value = value
+                    .strip_prefix("aws.String(")
+                    .and_then(|value| value.strip_suffix(')'))
+                    .unwrap_or(value);
+                Some((
+                    name.trim().to_string(),
+                    self.value(value.trim(), &['"', '`'])?,
+                ))
+            })
+            .collect()
+    }
+
+    /// Setters of a Java request builder chain, e.g.
+    /// `GetObjectRequest.builder().bucket("b").build()` or `r -> r.bucket("b")`
+    ///
+    /// Only the chain itself is read; setters of nested builders belong to other shapes.
+    fn java_builder_literals(&self, text: &str) -> Vec<(String, ResourceValue)> {
+        if !text.contains(".builder()") && !text.contains("->") {
+            return Vec::new();
+        }
+        split_top_level(text, '.')
+            .into_iter()
+            .filter_map(|setter| {
+                let (name, value) = setter.trim().split_once('(')?;
+                let mut chars = name.chars();
+                let member = chars.next()?.to_uppercase().collect::<String>() + chars.as_str();
+                Some((member, self.value(value.strip_suffix(')')?.trim(), &['"'])?))
+            })
+            .collect()
+    }
+
+    /// A literal delimited by one of `quotes`, an environment variable read, or the
+    /// value of the project constant `text` names
+    fn value(&self, text: &str, quotes: &[char]) -> Option<ResourceValue> {
+        ResourceValue::parse(text, quotes).or_else(|| {
+            let constants = self.constants?;
+            constants.constant_value(self.path, &reference(text)?)
+        })
+    }
+}
+
+/// Calls looking up a setting by its key, e.g. `viper.GetString("storage.bucket")`
+const KEY_LOOKUPS: &[&str] = &[".get(", ".Get(", ".GetString(", ".getString("];
+
+/// `text` as a chain of field accesses, e.g. `config.Tables.Orders`
+///
+/// Lookups of string keys (`config["tables"]["orders"]`, `settings.get("tables")`)
+/// read as field accesses of the same names.
+fn reference(text: &str) -> Option<String> {
+    let mut reference = String::new();
+    let mut rest = text.trim();
+    while !rest.is_empty() {
+        let lookup = rest
+            .strip_prefix('[')
+            .map(|subscript| (subscript, ']'))
+            .or_else(|| {
+                KEY_LOOKUPS
+                    .iter()
+                    .find_map(|lookup| rest.strip_prefix(lookup))
+                    .map(|call| (call, ')'))
+            });
+        if let Some((lookup, close)) = lookup {
+            let (key, after) = lookup.split_once(close)?;
+            let key = quoted(key.trim(), &['"', '\''])?;
+            if key.is_empty() {
+                return None;
+            }
+            reference.push('.');
+            reference.push_str(&key);
+            rest = after;
+            continue;
+        }
+        let end = rest
+            .char_indices()
+            .skip(1)
+            .find(|(_, c)| matches!(c, '.' | '['))
+            .map_or(rest.len(), |(index, _)| index);
+        let (field, after) = rest.split_at(end);
+        if !is_reference(field.strip_prefix('.').unwrap_or(field)) {
+            return None;
+        }
+        reference.push_str(field);
+        rest = after;
+    }
+    Some(reference)
+}
+
+/// Whether `text` is a plain name or a chain of field accesses, e.g. `config.Tables.Orders`
+fn is_reference(text: &str) -> bool {
+    text.split('.').all(|segment| {
+        segment
+            .chars()
+            .next()
+            .is_some_and(|c| c.is_alphabetic() || c == '_' || c == '$')
+            && segment
+                .chars()
+                .all(|c| c.is_alphanumeric() || c == '_' || c == '$')
+    })
+}
+
+/// Name of the environment variable `text` reads, e.g. `BUCKET_NAME` for
+/// `os.environ.get("BUCKET_NAME", "reports")` or `process.env.BUCKET_NAME ?? "reports"`
+///
+/// Fallbacks are ignored: the variable still controls which resource is used.
+fn environment_variable(text: &str) -> Option<String> {
+    let text = [" ?? ", " || ", " or "]
+        .iter()
+        .fold(text, |text, fallback| {
+            text.split_once(fallback).map_or(text, |(read, _)| read)
+        })
+        .trim()
+        .trim_end_matches('!');
+    let name = if let Some(name) = text.strip_prefix("process.env.") {
+        name.to_string()
+    } else if let Some(arguments) = ENVIRONMENT_CALLS
+        .iter()
+        .find_map(|function| text.strip_prefix(function)?.strip_suffix(')'))
+    {
+        quoted(
+            split_top_level(arguments, ',').first()?.trim(),
+            &['"', '\''],
+        )?
+    } else {
+        let key = ENVIRONMENT_MAPPINGS
+            .iter()
+            .find_map(|mapping| text.strip_prefix(mapping)?.strip_suffix(']'))?;
+        quoted(key.trim(), &['"', '\''])?
+    };
+    (!name.is_empty() && name.chars().all(|c| c.is_ascii_alphanumeric() || c == '_'))
+        .then_some(name)
+}
+
+/// Entries of a mapping literal with string or bare keys, e.g. `{'Bucket': b, 'Key': k}`
+/// or `{ Bucket: b, Key: k }`, as the key and the value's text
+pub(crate) fn mapping_entries(text: &str) -> Vec<(String, &str)> {
+    let Some(contents) = text
+        .trim()
+        .strip_prefix('{')
+        .and_then(|text| text.strip_suffix('}'))
+    else {
+        return Vec::new();
+    };
+    split_top_level(contents, ',')
+        .into_iter()
+        .filter_map(|entry| {
+            let (key, value) = split_top_level(entry, ':')
+                .first()
+                .map(|key| (*key, &entry[key.len()..]))?;
+            let value = value.strip_prefix(':')?.trim();
+            let key = key.trim();
+            let key = quoted(key, &['"', '\'']).unwrap_or_else(|| key.to_string());
+            is_reference(&key).then_some((key, value))
+        })
+        .collect()
+}
+
+/// Bucket and key of a `CopySource` string, e.g. `reports/latest.csv` or
+/// `/reports/latest.csv?versionId=1`
+pub(crate) fn copy_source_object(copy_source: &str) -> Option<(String, String)> {
+    let object = copy_source.trim_start_matches('/');
+    let object = object
+        .split_once("?versionId=")
+        .map_or(object, |(object, _)| object);
+    let (bucket, key) = object.split_once('/')?;
+    Some((bucket_name(bucket)?, object_key(key)?))
+}
+
+/// Split `text` at `separator` outside of brackets and string literals
+fn split_top_level(text: &str, separator: char) -> Vec<&str> {
+    let mut parts = Vec::new();
+    let mut depth = 0usize;
+    let mut quote = None;
+    let mut escaped = false;
+    let mut start = 0;
+    for (index, c) in text.char_indices() {
+        if let Some(open) = quote {
+            if escaped {
+                escaped = false;
+            } else if c == '\\' {
+                escaped = true;
+            } else if c == open {
+                quote = None;
+            }
+            continue;
+        }
+        match c {
+            '"' | '\'' | '`' => quote = Some(c),
+            '(' | '[' | '{' => depth += 1,
+            ')' | ']' | '}' => depth = depth.saturating_sub(1),
+            c if c == separator && depth == 0 => {
+                parts.push(&text[start..index]);
+                start = index + c.len_utf8();
+            }
+            _ => {}
+        }
+    }
+    parts.push(&text[start..]);
+    parts
+}
+
+/// Contents of a string literal delimited by one of `quotes`
+fn quoted(text: &str, quotes: &[char]) -> Option<String> {
+    let quote = text.chars().next().filter(|c| quotes.contains(c))?;
+    let contents = text.strip_prefix(quote)?.strip_suffix(quote)?;
+    Some(contents.to_string())
+}
+
+/// Bucket names never contain `:` or `/`; those are access point or outpost ARNs
+fn bucket_name(literal: &str) -> Option<String> {
+    (!literal.contains([':', '/'])).then(|| literal.to_string())
+}
+
+fn object_key(literal: &str) -> Option<String> {
+    (!literal.is_empty()).then(|| literal.to_string())
+}
+
+/// Names only; ARNs and qualified names (`my-function:prod`) are left unscoped
+fn resource_name(literal: &str) -> Option<String> {
+    (!literal.contains(':')).then(|| literal.to_string())
+}
+
+/// The queue name is the last segment of a queue URL
+pub(crate) fn queue_name(literal: &str) -> Option<String> {
+    literal
+        .trim_end_matches('/')
+        .rsplit('/')
+        .next()
+        .filter(|name| !name.is_empty() && !name.contains(':'))
+        .map(str::to_string)
+}
+
+/// Parameter ARNs drop the leading `/` of hierarchical names
+fn parameter_name(literal: &str) -> Option<String> {
+    (!literal.contains(':')).then(|| literal.trim_start_matches('/').to_string())
+}
+
+/// A path covers the parameters beneath it
+fn parameter_path(literal: &str) -> Option<String> {
+    (!literal.contains(':')).then(|| format!("{}*", literal.trim_matches('/')))
+}
+
+/// Secrets Manager appends `-` and six random characters to the names in its ARNs, so a
+/// name only matches the real secret followed by the suffix wildcard
+fn secret_name(literal: &str) -> Option<String> {
+    (!literal.contains(':')).then(|| format!("{literal}-??????"))
+}
+
+/// SageMaker lowercases the names in its ARNs, so policies must name endpoints in lowercase
+fn endpoint_name(literal: &str) -> Option<String> {
+    resource_name(literal).map(|name| name.to_lowercase())
+}
+
+/// Bedrock model IDs contain colons (`amazon.nova-lite-v1:0`), and ARNs of provisioned
+/// models or inference profiles stand in for them
+fn model_id(literal: &str) -> Option<String> {
+    (!literal.contains(char::is_whitespace)).then(|| literal.to_string())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::Location;
+    use std::path::PathBuf;
+
+    fn keyword(name: &str, value: ParameterValue) -> Parameter {
+        Parameter::Keyword {
+            name: name.to_string(),
+            value,
+            position: 0,
+            type_annotation: None,
+        }
+    }
+
+    fn positional(text: &str, struct_fields: Option<Vec<String>>) -> Parameter {
+        Parameter::Positional {
+            value: ParameterValue::Unresolved(text.to_string()),
+            position: 1,
+            type_annotation: None,
+            struct_fields,
+        }
+    }
+
+    fn bindings(service: &str, parameters: Vec<Parameter>) -> BTreeMap<String, String> {
+        let mut calls = vec![SdkMethodCall {
+            name: "Operation".to_string(),
+            possible_services: vec![service.to_string()],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    String::new(),
+                    Location::new(PathBuf::new(), (1, 1), (1, 1)),
+                )
+                .with_parameters(parameters),
+            ),
+        }];
+        bind_literal_resources(&mut calls, None);
+        calls.remove(0).metadata.unwrap().resource_bindings
+    }
+
+    fn binding(placeholder: &str, value: &str) -> (String, String) {
+        (placeholder.to_string(), value.to_string())
+    }
+
+    #[test]
+    fn test_keyword_literals_scope_resources() {
+        assert_eq!(
+            bindings(
+                "s3",
+                vec![
+                    keyword("Bucket", ParameterValue::Resolved("reports".to_string())),
+                    keyword(
+                        "Key",
+                        ParameterValue::Resolved("2024/summary.csv".to_string())
+                    ),
+                ]
+            ),
+            BTreeMap::from([
+                binding("BucketName", "reports"),
+                binding("ObjectName", "2024/summary.csv"),
+            ])
+        );
+        assert_eq!(
+            bindings(
+                "sqs",
+                vec![keyword(
+                    "QueueUrl",
+                    ParameterValue::Resolved(
+                        "https://sqs.us-east-1.amazonaws.com/123456789012/orders".to_string()
+                    ),
+                )]
+            ),
+            BTreeMap::from([binding("QueueName", "orders")])
+        );
+        assert_eq!(
+            bindings(
+                "ssm",
+                vec![keyword(
+                    "Path",
+                    ParameterValue::Resolved("/app/prod/".to_string())
+                )]
+            ),
+            BTreeMap::from([binding("ParameterName", "app/prod*")])
+        );
+        assert_eq!(
+            bindings(
+                "ssm",
+                vec![keyword(
+                    "Name",
+                    ParameterValue::Resolved("/app/prod/db-password".to_string())
+                )]
+            ),
+            BTreeMap::from([binding("ParameterName", "app/prod/db-password")])
+        );
+        assert_eq!(
+            bindings(
+                "bedrock-runtime",
+                vec![keyword(
+                    "modelId",
+                    ParameterValue::Resolved("amazon.nova-lite-v1:0".to_string())
+                )]
+            ),
+            BTreeMap::from([binding("ModelId", "amazon.nova-lite-v1:0")])
+        );
+        assert_eq!(
+            bindings(
+                "sagemaker-runtime",
+                vec![keyword(
+                    "EndpointName",
+                    ParameterValue::Resolved("Churn-Predictor".to_string())
+                )]
+            ),
+            BTreeMap::from([binding("EndpointName", "churn-predictor")])
+        );
+        assert_eq!(
+            bindings(
+                "secretsmanager",
+                vec![keyword(
+                    "SecretId",
+                    ParameterValue::Resolved("prod/orders/db".to_string())
+                )]
+            ),
+            BTreeMap::from([binding("SecretId", "prod/orders/db-??????")])
+        );
+    }
+
+    fn source_bindings(parameters: Vec<Parameter>) -> BTreeMap<String, String> {
+        let mut calls = vec![SdkMethodCall {
+            name: "CopyObject".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    String::new(),
+                    Location::new(PathBuf::new(), (1, 1), (1, 1)),
+                )
+                .with_parameters(parameters),
+            ),
+        }];
+        bind_literal_resources(&mut calls, None);
+        calls.remove(0).metadata.unwrap().source_resource_bindings
+    }
+
+    #[test]
+    fn test_copy_source_scopes_the_source_object() {
+        let source = BTreeMap::from([
+            binding("BucketName", "src"),
+            binding("ObjectName", "in/a.csv"),
+        ]);
+        assert_eq!(
+            source_bindings(vec![
+                keyword(
+                    "CopySource",
+                    ParameterValue::Unresolved("{'Bucket': 'src', 'Key': 'in/a.csv'}".to_string())
+                ),
+                keyword("Bucket", ParameterValue::Resolved("dst".to_string())),
+            ]),
+            source
+        );
+        assert_eq!(
+            source_bindings(vec![keyword(
+                "CopySource",
+                ParameterValue::Resolved("/src/in/a.csv?versionId=3".to_string())
+            )]),
+            source
+        );
+        assert_eq!(
+            source_bindings(vec![positional(
+                "&s3.CopyObjectInput{Bucket: aws.String(\"dst\"), CopySource: aws.String(\"src/in/a.csv\")}",
+                Some(vec!["Bucket".to_string(), "CopySource".to_string()]),
+            )]),
+            source
+        );
+        assert_eq!(
+            source_bindings(vec![positional(
+                "CopyObjectRequest.builder().sourceBucket(\"src\").sourceKey(\"in/a.csv\").build()",
+                None,
+            )]),
+            source
+        );
+        // The destination scopes the call's own resources only
+        assert_eq!(
+            bindings(
+                "s3",
+                vec![
+                    keyword(
+                        "CopySource",
+                        ParameterValue::Resolved("src/in/a.csv".to_string())
+                    ),
+                    keyword("Bucket", ParameterValue::Resolved("dst".to_string())),
+                ]
+            ),
+            BTreeMap::from([binding("BucketName", "dst")])
+        );
+        assert_eq!(
+            source_bindings(vec![keyword(
+                "CopySource",
+                ParameterValue::Unresolved("copy_source".to_string())
+            )]),
+            BTreeMap::new()
+        );
+    }
+
+    #[test]
+    fn test_secret_names() {
+        // The `Name` of `CreateSecret` names the secret like a `SecretId`
+        assert_eq!(
+            bindings(
+                "secretsmanager",
+                vec![
+                    keyword(
+                        "Name",
+                        ParameterValue::Resolved("prod/orders/db".to_string())
+                    ),
+                    keyword(
+                        "SecretString",
+                        ParameterValue::Unresolved("password".to_string())
+                    ),
+                ]
+            ),
+            BTreeMap::from([binding("SecretId", "prod/orders/db-??????")])
+        );
+        // A complete ARN already ends with the suffix, and a partial one can't be told apart
+        // from it, so neither is suffixed with the wildcard
+        for arn in [
+            "arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/orders/db-AbCdEf",
+            "arn:aws:secretsmanager:us-east-1:123456789012:secret:prod/orders/db",
+        ] {
+            assert!(bindings(
+                "secretsmanager",
+                vec![keyword(
+                    "SecretId",
+                    ParameterValue::Resolved(arn.to_string())
+                )]
+            )
+            .is_empty());
+        }
+    }
+
+    #[test]
+    fn test_unscopable_arguments_stay_unbound() {
+        // An object key without a literal bucket names no particular object
+        assert!(bindings(
+            "s3",
+            vec![
+                keyword("Bucket", ParameterValue::Unresolved("bucket".to_string())),
+                keyword("Key", ParameterValue::Resolved("report.csv".to_string())),
+            ]
+        )
+        .is_empty());
+        // ARNs and qualified names aren't plain names
+        assert!(bindings(
+            "lambda",
+            vec![keyword(
+                "FunctionName",
+                ParameterValue::Resolved("processor:live".to_string())
+            )]
+        )
+        .is_empty());
+        // Members only scope resources of their own service
+        assert!(bindings(
+            "sns",
+            vec![keyword(
+                "Name",
+                ParameterValue::Resolved("alerts".to_string())
+            )]
+        )
+        .is_empty());
+        assert!(bindings(
+            "dynamodb",
+            vec![keyword(
+                "TableName",
+                ParameterValue::Resolved("orders' + suffix + '".to_string())
+            )]
+        )
+        .is_empty());
+    }
+
+    #[test]
+    fn test_go_struct_literal_fields() {
+        let input = r#"&dynamodb.QueryInput{
+            TableName: aws.String("orders"),
+            IndexName: aws.String("by-customer"),
+            KeyConditionExpression: aws.String("pk = :pk, sk > :sk"),
+            Limit: aws.Int32(10),
+        }"#;
+        assert_eq!(
+            bindings(
+                "dynamodb",
+                vec![
+                    positional("ctx", None),
+                    positional(
+                        input,
+                        Some(vec![
+                            "TableName".to_string(),
+                            "IndexName".to_string(),
+                            "KeyConditionExpression".to_string(),
+                            "Limit".to_string(),
+                        ]),
+                    ),
+                ]
+            ),
+            BTreeMap::from([
+                binding("IndexName", "by-customer"),
+                binding("TableName", "orders"),
+            ])
+        );
+    }
+
+    #[test]
+    fn test_java_builder_setters() {
+        assert_eq!(
+            bindings(
+                "s3",
+                vec![positional(
+                    r#"PutObjectRequest.builder().bucket("my.bucket").key("a.txt").tagging(Tagging.builder().tagSet(Tag.builder().key("env").build()).build()).build()"#,
+                    None,
+                )]
+            ),
+            BTreeMap::from([
+                binding("BucketName", "my.bucket"),
+                binding("ObjectName", "a.txt"),
+            ])
+        );
+        assert_eq!(
+            bindings(
+                "lambda",
+                vec![positional(r#"r -> r.functionName("processor")"#, None)]
+            ),
+            BTreeMap::from([binding("FunctionName", "processor")])
+        );
+    }
+
+    #[test]
+    fn test_extractor_bindings_take_precedence() {
+        let mut calls = vec![SdkMethodCall {
+            name: "GetObject".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    String::new(),
+                    Location::new(PathBuf::new(), (1, 1), (1, 1)),
+                )
+                .with_parameters(vec![
+                    keyword("Bucket", ParameterValue::Resolved("reports".to_string())),
+                    keyword("Key", ParameterValue::Resolved("latest.csv".to_string())),
+                ])
+                .with_resource_bindings(BTreeMap::from([binding("ObjectName", "2024/*")])),
+            ),
+        }];
+        bind_literal_resources(&mut calls, None);
+        assert_eq!(
+            calls[0].metadata.as_ref().unwrap().resource_bindings,
+            BTreeMap::from([
+                binding("BucketName", "reports"),
+                binding("ObjectName", "2024/*"),
+            ])
+        );
+    }
+
+    #[test]
+    fn test_environment_variables_template_resources() {
+        assert_eq!(
+            bindings(
+                "s3",
+                vec![
+                    keyword(
+                        "Bucket",
+                        ParameterValue::Unresolved(r#"os.environ["BUCKET_NAME"]"#.to_string())
+                    ),
+                    keyword(
+                        "Key",
+                        ParameterValue::Unresolved(
+                            r#"os.environ.get('REPORT_KEY', 'latest.csv')"#.to_string()
+                        )
+                    ),
+                ]
+            ),
+            BTreeMap::from([
+                binding("BucketName", "${env:BUCKET_NAME}"),
+                binding("ObjectName", "${env:REPORT_KEY}"),
+            ])
+        );
+        assert_eq!(
+            bindings(
+                "dynamodb",
+                vec![keyword(
+                    "TableName",
+                    ParameterValue::Unresolved("process.env.TABLE_NAME! ?? 'orders'".to_string())
+                )]
+            ),
+            BTreeMap::from([binding("TableName", "${env:TABLE_NAME}")])
+        );
+        assert_eq!(
+            bindings(
+                "dynamodb",
+                vec![positional(
+                    r#"&dynamodb.GetItemInput{TableName: aws.String(os.Getenv("TABLE_NAME"))}"#,
+                    Some(vec!["TableName".to_string()]),
+                )]
+            ),
+            BTreeMap::from([binding("TableName", "${env:TABLE_NAME}")])
+        );
+        assert_eq!(
+            bindings(
+                "lambda",
+                vec![positional(
+                    r#"InvokeRequest.builder().functionName(System.getenv("FUNCTION_NAME")).build()"#,
+                    None,
+                )]
+            ),
+            BTreeMap::from([binding("FunctionName", "${env:FUNCTION_NAME}")])
+        );
+        // A queue URL isn't a queue name, so the variable can't stand in for it
+        assert!(bindings(
+            "sqs",
+            vec![keyword(
+                "QueueUrl",
+                ParameterValue::Unresolved("process.env.QUEUE_URL".to_string())
+            )]
+        )
+        .is_empty());
+        assert!(bindings(
+            "s3",
+            vec![keyword(
+                "Bucket",
+                ParameterValue::Unresolved("os.environ[bucket_variable]".to_string())
+            )]
+        )
+        .is_empty());
+    }
+
+    #[test]
+    fn test_project_constants_scope_resources() {
+        struct Constants;
+
+        impl ProjectConstants for Constants {
+            fn constant_value(&self, _path: &Path, expression: &str) -> Option<ResourceValue> {
+                match expression {
+                    "config.Tables.Orders" => Some(ResourceValue::Literal("orders".to_string())),
+                    "BUCKET" => Some(ResourceValue::Environment("BUCKET_NAME".to_string())),
+                    _ => None,
+                }
+            }
+        }
+
+        let mut calls = vec![
+            SdkMethodCall {
+                name: "GetItem".to_string(),
+                possible_services: vec!["dynamodb".to_string()],
+                metadata: Some(
+                    SdkMethodCallMetadata::new(
+                        String::new(),
+                        Location::new(PathBuf::new(), (1, 1), (1, 1)),
+                    )
+                    .with_parameters(vec![positional(
+                        "&dynamodb.GetItemInput{TableName: aws.String(config.Tables.Orders)}",
+                        Some(vec!["TableName".to_string()]),
+                    )]),
+                ),
+            },
+            SdkMethodCall {
+                name: "GetObject".to_string(),
+                possible_services: vec!["s3".to_string()],
+                metadata: Some(
+                    SdkMethodCallMetadata::new(
+                        String::new(),
+                        Location::new(PathBuf::new(), (1, 1), (1, 1)),
+                    )
+                    .with_parameters(vec![
+                        keyword("Bucket", ParameterValue::Unresolved("BUCKET".to_string())),
+                        keyword("Key", ParameterValue::Unresolved("key()".to_string())),
+                    ]),
+                ),
+            },
+        ];
+        bind_literal_resources(&mut calls, Some(&Constants));
+        assert_eq!(
+            calls[0].metadata.as_ref().unwrap().resource_bindings,
+            BTreeMap::from([binding("TableName", "orders")])
+        );
+        assert_eq!(
+            calls[1].metadata.as_ref().unwrap().resource_bindings,
+            BTreeMap::from([binding("BucketName", "${env:BUCKET_NAME}")])
+        );
+    }
+
+    #[test]
+    fn test_key_lookups_read_as_references() {
+        assert_eq!(
+            reference(r#"config["storage"]['bucket']"#).as_deref(),
+            Some("config.storage.bucket")
+        );
+        assert_eq!(
+            reference(r#"viper.GetString("storage.bucket")"#).as_deref(),
+            Some("viper.storage.bucket")
+        );
+        assert_eq!(
+            reference(r#"settings.tables.get("orders")"#).as_deref(),
+            Some("settings.tables.orders")
+        );
+        assert_eq!(
+            reference("config.Tables.Orders").as_deref(),
+            Some("config.Tables.Orders")
+        );
+        assert_eq!(reference("config[key]"), None);
+        assert_eq!(reference("getBucket()"), None);
+        assert_eq!(reference("prefix + name"), None);
+    }
+
+    #[test]
+    fn test_configured_resources() {
+        let dir = tempfile::TempDir::new().unwrap();
+        let config_file = dir.path().join("config.yaml");
+        std::fs::write(&config_file, "storage:\n  bucket: reports\n").unwrap();
+        let dotenv_file = dir.path().join(".env");
+        std::fs::write(
+            &dotenv_file,
+            "TABLE_NAME=orders\nFUNCTION_NAME=arn:aws:lambda:x\n",
+        )
+        .unwrap();
+        let config = ConfigValues::load(&[config_file, dotenv_file]).unwrap();
+
+        let call = |service: &str, parameters| SdkMethodCall {
+            name: "Operation".to_string(),
+            possible_services: vec![service.to_string()],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    String::new(),
+                    Location::new(PathBuf::new(), (1, 1), (1, 1)),
+                )
+                .with_parameters(parameters),
+            ),
+        };
+        let mut calls = vec![
+            call(
+                "s3",
+                vec![keyword(
+                    "Bucket",
+                    ParameterValue::Unresolved(r#"config["storage"]["bucket"]"#.to_string()),
+                )],
+            ),
+            call(
+                "dynamodb",
+                vec![keyword(
+                    "TableName",
+                    ParameterValue::Unresolved("process.env.TABLE_NAME".to_string()),
+                )],
+            ),
+            call(
+                "lambda",
+                vec![keyword(
+                    "FunctionName",
+                    ParameterValue::Unresolved("process.env.FUNCTION_NAME".to_string()),
+                )],
+            ),
+        ];
+        bind_literal_resources(&mut calls, None);
+        bind_configured_resources(&mut calls, &config);
+
+        let bindings: Vec<_> = calls
+            .into_iter()
+            .map(|call| call.metadata.unwrap().resource_bindings)
+            .collect();
+        assert_eq!(
+            bindings,
+            vec![
+                BTreeMap::from([binding("BucketName", "reports")]),
+                BTreeMap::from([binding("TableName", "orders")]),
+                // An ARN isn't a function name, so the template stays
+                BTreeMap::from([binding("FunctionName", "${env:FUNCTION_NAME}")]),
+            ]
+        );
+    }
+
+    #[tokio::test]
+    async fn test_extracted_endpoint_names_scope_endpoint_arns() {
+        use crate::extraction::engine::Engine;
+        use crate::extraction::SourceFile;
+        use crate::policy_generation::utils::ArnParser;
+        use crate::Language;
+
+        let source = r#"
+import boto3
+
+runtime = boto3.client('sagemaker-runtime')
+runtime.invoke_endpoint(EndpointName="MyEndpoint", Body=b'{}')
+runtime.invoke_endpoint(EndpointName=endpoint_name, Body=b'{}')
+"#;
+        let source_file = SourceFile::with_language(
+            PathBuf::from("app.py"),
+            source.to_string(),
+            Language::Python,
+        );
+        let extracted = Engine::new()
+            .extract_sdk_method_calls(Language::Python, vec![source_file])
+            .await
+            .unwrap();
+
+        // The ARN pattern of the endpoint resource, bound as by the resource matcher
+        let pattern = "arn:${Partition}:sagemaker:${Region}:${Account}:endpoint/${EndpointName}";
+        let parser = ArnParser::new("aws", "us-east-1", "123456789012");
+        let arns: Vec<_> = extracted
+            .methods
+            .iter()
+            .filter(|call| call.name == "invoke_endpoint")
+            .map(|call| {
+                let bindings = &call.metadata.as_ref().unwrap().resource_bindings;
+                let arn = bindings
+                    .get("EndpointName")
+                    .map_or(pattern.to_string(), |name| {
+                        pattern.replace("${EndpointName}", name)
+                    });
+                parser.process_arn_pattern(&arn).unwrap()
+            })
+            .collect();
+        assert_eq!(
+            arns,
+            vec![
+                "arn:aws:sagemaker:us-east-1:123456789012:endpoint/myendpoint",
+                // A name only known at runtime leaves the endpoint unscoped
+                "arn:aws:sagemaker:us-east-1:123456789012:endpoint/*",
+            ]
+        );
+    }
+}
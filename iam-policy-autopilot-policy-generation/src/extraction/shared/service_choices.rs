@@ -0,0 +1,359 @@
+//! Services chosen by the user for calls whose operation exists in several services
+//!
+//! A call such as `client.list_tags(...)` on a client whose service can't be resolved
+//! may be made on any service having the operation. Such calls are looked up in
+//! recorded [`ServiceChoices`], first by call, then in the overrides pinning the service
+//! of whole files, packages or variables. Calls without a choice are asked of a
+//! [`ServicePrompt`], and its choices are recorded so later runs reuse them.
+
+use std::collections::{HashMap, HashSet};
+use std::path::{Path, PathBuf};
+use std::sync::OnceLock;
+
+use regex::Regex;
+
+use crate::api::model::{
+    AmbiguousCall, ServiceChoice, ServiceChoices, ServiceOverride, ServicePrompt,
+};
+use crate::extraction::SourceFile;
+use crate::SdkMethodCall;
+
+/// Regex matching the package Go and Java files declare
+static PACKAGE_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_package_regex() -> &'static Regex {
+    PACKAGE_REGEX.get_or_init(|| {
+        Regex::new(r"(?m)^\s*package\s+([\w.]+)\s*;?\s*$").expect("Invalid package regex")
+    })
+}
+
+/// Narrow the possible services of each ambiguous call down to the chosen one
+///
+/// Calls without a recorded choice nor a matching override are asked of `prompt`, if
+/// any, and the services it chooses are added to `choices`. Choices and overrides of
+/// services the call can't be made on, e.g. recorded before the code changed, are
+/// ignored. The packages of overrides are those `source_files` declare.
+pub(crate) fn apply_service_choices(
+    methods: &mut [SdkMethodCall],
+    choices: &mut ServiceChoices,
+    prompt: Option<&dyn ServicePrompt>,
+    source_files: &[SourceFile],
+) {
+    // Calls the prompt left unanswered, so they are asked only once
+    let mut declined: HashSet<(PathBuf, String, String)> = HashSet::new();
+    let packages = declared_packages(source_files);
+
+    for method in methods {
+        if method.possible_services.len() < 2 {
+            continue;
+        }
+        let Some(metadata) = &method.metadata else {
+            continue;
+        };
+        let file = &metadata.location.file_path;
+        let possible = |service: &str| method.possible_services.iter().any(|s| s == service);
+        let service = if let Some(service) = choices
+            .service(file, &metadata.expr, &method.name)
+            .filter(|service| possible(service))
+        {
+            service.to_string()
+        } else if let Some(service_override) = choices.overrides.iter().find(|service_override| {
+            possible(&service_override.service)
+                && override_matches(
+                    service_override,
+                    file,
+                    packages.get(file.as_path()).copied(),
+                    metadata.receiver.as_deref(),
+                )
+        }) {
+            service_override.service.clone()
+        } else {
+            let key = (file.clone(), metadata.expr.clone(), method.name.clone());
+            let Some(prompt) = prompt.filter(|_| !declined.contains(&key)) else {
+                continue;
+            };
+            let question = AmbiguousCall {
+                file: file.clone(),
+                line: metadata.location.start_position.0,
+                call: metadata.expr.clone(),
+                operation: method.name.clone(),
+                services: method.possible_services.clone(),
+            };
+            let Some(service) = prompt.choose(&question).filter(|service| possible(service)) else {
+                declined.insert(key);
+                continue;
+            };
+            choices.choices.push(ServiceChoice {
+                file: file.clone(),
+                call: metadata.expr.clone(),
+                operation: method.name.clone(),
+                service: service.clone(),
+            });
+            service
+        };
+        method.possible_services = vec![service];
+    }
+}
+
+/// The package each of `source_files` declares, if any: that of Go and Java files
+pub(crate) fn declared_packages(source_files: &[SourceFile]) -> HashMap<&Path, &str> {
+    source_files
+        .iter()
+        .filter_map(|source_file| {
+            let captures = get_package_regex().captures(&source_file.content)?;
+            Some((source_file.path.as_path(), captures.get(1)?.as_str()))
+        })
+        .collect()
+}
+
+/// Whether a call on `receiver` in `file`, which declares `package`, matches every
+/// condition of `service_override`
+fn override_matches(
+    service_override: &ServiceOverride,
+    file: &Path,
+    package: Option<&str>,
+    receiver: Option<&str>,
+) -> bool {
+    call_matches(
+        service_override.files.as_deref(),
+        service_override.package.as_deref(),
+        service_override.variable.as_deref(),
+        file,
+        package,
+        receiver,
+    )
+}
+
+/// Whether a call on `receiver` in `file`, which declares `package`, is in the files
+/// matching the glob `files`, of the package `expected_package` and on `variable`, for
+/// those given
+pub(crate) fn call_matches(
+    files: Option<&str>,
+    expected_package: Option<&str>,
+    variable: Option<&str>,
+    file: &Path,
+    package: Option<&str>,
+    receiver: Option<&str>,
+) -> bool {
+    let file = file.strip_prefix("./").unwrap_or(file);
+    let files_match = files.is_none_or(|files| {
+        glob::Pattern::new(files).is_ok_and(|pattern| {
+            pattern.matches_path_with(
+                file,
+                glob::MatchOptions {
+                    require_literal_separator: true,
+                    ..glob::MatchOptions::new()
+                },
+            )
+        })
+    });
+    let package_matches = expected_package
+        .is_none_or(|expected| package == Some(expected) || directory_is_package(file, expected));
+    let variable_matches = variable.is_none_or(|variable| receiver == Some(variable));
+    files_match && package_matches && variable_matches
+}
+
+/// Whether the directory of `file` ends with the directories of `package`, separated by
+/// `/` or `.`, e.g. `src/internal/ddb/table.go` for `internal/ddb`
+fn directory_is_package(file: &Path, package: &str) -> bool {
+    let Some(directory) = file.parent() else {
+        return false;
+    };
+    let directories: Vec<_> = directory
+        .components()
+        .filter_map(|component| component.as_os_str().to_str())
+        .collect();
+    let expected: Vec<&str> = package
+        .split(['/', '.'])
+        .filter(|part| !part.is_empty())
+        .collect();
+    !expected.is_empty() && directories.ends_with(&expected)
+}
+
+#[cfg(test)]
+mod tests {
+    use std::sync::Mutex;
+
+    use rstest::rstest;
+
+    use super::*;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::Location;
+
+    /// Prompt choosing from a fixed list, recording the calls it was asked about
+    #[derive(Debug, Default)]
+    struct ScriptedPrompt {
+        replies: Mutex<Vec<Option<String>>>,
+        questions: Mutex<Vec<AmbiguousCall>>,
+    }
+
+    impl ServicePrompt for ScriptedPrompt {
+        fn choose(&self, call: &AmbiguousCall) -> Option<String> {
+            self.questions.lock().unwrap().push(call.clone());
+            self.replies.lock().unwrap().remove(0)
+        }
+    }
+
+    fn ambiguous_call(line: usize) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "list_tags".to_string(),
+            possible_services: vec!["kms".to_string(), "lambda".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "client.list_tags(Resource=arn)".to_string(),
+                Location::new(PathBuf::from("app.py"), (line, 1), (line, 31)),
+            )),
+        }
+    }
+
+    #[test]
+    fn test_recorded_choices_narrow_services() {
+        let mut methods = vec![ambiguous_call(4)];
+        let mut choices = ServiceChoices {
+            choices: vec![ServiceChoice {
+                file: PathBuf::from("app.py"),
+                call: "client.list_tags(Resource=arn)".to_string(),
+                operation: "list_tags".to_string(),
+                service: "lambda".to_string(),
+            }],
+            ..ServiceChoices::default()
+        };
+
+        apply_service_choices(&mut methods, &mut choices, None, &[]);
+
+        assert_eq!(methods[0].possible_services, vec!["lambda".to_string()]);
+        assert_eq!(choices.choices.len(), 1);
+    }
+
+    fn call_in(file: &str, receiver: &str) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "put_resource_policy".to_string(),
+            possible_services: vec!["dynamodb".to_string(), "secretsmanager".to_string()],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    format!("{receiver}.put_resource_policy()"),
+                    Location::new(PathBuf::from(file), (1, 1), (1, 30)),
+                )
+                .with_receiver(receiver.to_string()),
+            ),
+        }
+    }
+
+    fn service_override(
+        files: Option<&str>,
+        package: Option<&str>,
+        variable: Option<&str>,
+    ) -> ServiceOverride {
+        ServiceOverride {
+            files: files.map(ToString::to_string),
+            package: package.map(ToString::to_string),
+            variable: variable.map(ToString::to_string),
+            service: "dynamodb".to_string(),
+        }
+    }
+
+    #[rstest]
+    #[case::files(service_override(Some("internal/ddb/**"), None, None), true)]
+    #[case::other_files(service_override(Some("internal/sm/**"), None, None), false)]
+    #[case::directory_package(service_override(None, Some("internal/ddb"), None), true)]
+    #[case::declared_package(service_override(None, Some("tables"), None), true)]
+    #[case::other_package(service_override(None, Some("ddb.internal"), None), false)]
+    #[case::variable(service_override(None, None, Some("client")), true)]
+    #[case::all_conditions(
+        service_override(Some("internal/**/*.go"), Some("tables"), Some("other")),
+        false
+    )]
+    fn test_overrides_pin_the_service(
+        #[case] service_override: ServiceOverride,
+        #[case] pinned: bool,
+    ) {
+        let mut methods = vec![call_in("./internal/ddb/table.go", "client")];
+        let source_files = vec![SourceFile::with_language(
+            PathBuf::from("./internal/ddb/table.go"),
+            "package tables\n\nfunc put() {}\n".to_string(),
+            crate::Language::Go,
+        )];
+        let mut choices = ServiceChoices {
+            overrides: vec![service_override],
+            ..ServiceChoices::default()
+        };
+
+        apply_service_choices(&mut methods, &mut choices, None, &source_files);
+
+        assert_eq!(methods[0].possible_services.len() == 1, pinned);
+        if pinned {
+            assert_eq!(methods[0].possible_services, vec!["dynamodb".to_string()]);
+        }
+        assert!(choices.choices.is_empty());
+    }
+
+    #[test]
+    fn test_recorded_choices_take_precedence_over_overrides() {
+        let mut methods = vec![call_in("app.py", "client")];
+        let mut choices = ServiceChoices {
+            choices: vec![ServiceChoice {
+                file: PathBuf::from("app.py"),
+                call: "client.put_resource_policy()".to_string(),
+                operation: "put_resource_policy".to_string(),
+                service: "secretsmanager".to_string(),
+            }],
+            overrides: vec![service_override(None, None, Some("client"))],
+        };
+
+        apply_service_choices(&mut methods, &mut choices, None, &[]);
+
+        assert_eq!(
+            methods[0].possible_services,
+            vec!["secretsmanager".to_string()]
+        );
+    }
+
+    #[test]
+    fn test_validate_overrides() {
+        let mut choices = ServiceChoices {
+            overrides: vec![service_override(None, None, None)],
+            ..ServiceChoices::default()
+        };
+        assert!(choices.validate().is_err());
+
+        choices.overrides = vec![service_override(Some("src/[ddb"), None, None)];
+        assert!(choices.validate().is_err());
+
+        choices.overrides = vec![service_override(Some("src/ddb/**"), None, None)];
+        assert!(choices.validate().is_ok());
+    }
+
+    #[test]
+    fn test_prompt_choices_are_recorded_and_asked_once() {
+        let mut methods = vec![ambiguous_call(4), ambiguous_call(9)];
+        let mut choices = ServiceChoices::default();
+        let prompt = ScriptedPrompt {
+            replies: Mutex::new(vec![Some("kms".to_string())]),
+            ..ScriptedPrompt::default()
+        };
+
+        apply_service_choices(&mut methods, &mut choices, Some(&prompt), &[]);
+
+        let questions = prompt.questions.lock().unwrap();
+        assert_eq!(questions.len(), 1);
+        assert_eq!(questions[0].line, 4);
+        assert!(methods
+            .iter()
+            .all(|method| method.possible_services == vec!["kms".to_string()]));
+        assert_eq!(choices.choices[0].service, "kms");
+    }
+
+    #[test]
+    fn test_declined_and_unknown_choices_keep_every_service() {
+        let mut methods = vec![ambiguous_call(4)];
+        let mut choices = ServiceChoices::default();
+        let prompt = ScriptedPrompt {
+            replies: Mutex::new(vec![Some("s3".to_string())]),
+            ..ScriptedPrompt::default()
+        };
+
+        apply_service_choices(&mut methods, &mut choices, Some(&prompt), &[]);
+
+        assert_eq!(methods[0].possible_services.len(), 2);
+        assert!(choices.choices.is_empty());
+    }
+}
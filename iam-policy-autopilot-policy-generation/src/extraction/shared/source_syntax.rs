@@ -0,0 +1,718 @@
+//! Language-neutral view of the syntax tree of a source file
+//!
+//! Enrichments that recognize libraries, clients and handler signatures by the names code
+//! uses match the nodes collected here rather than lines of text, so a name that only
+//! appears in a comment, a docstring or another string literal isn't taken for a use.
+
+use ast_grep_core::tree_sitter::{LanguageExt, StrDoc};
+use ast_grep_core::Node;
+use ast_grep_language::{Go, Java, JavaScript, Python, TypeScript};
+
+use crate::extraction::{ParameterValue, SdkMethodCallMetadata, SourceFile};
+use crate::{Language, Location};
+
+/// Kinds of the string literals of the grammars
+const STRING_KINDS: &[&str] = &[
+    "string",
+    "template_string",
+    "interpreted_string_literal",
+    "raw_string_literal",
+    "string_literal",
+];
+
+/// Kinds of the calls and constructor invocations of the grammars
+const CALL_KINDS: &[&str] = &[
+    "call",
+    "call_expression",
+    "method_invocation",
+    "new_expression",
+    "object_creation_expression",
+];
+
+/// An identifier of a source file: a name of a variable, type, field or package
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) struct Identifier {
+    pub(crate) name: String,
+    pub(crate) location: Location,
+}
+
+/// An import of a module, package or class
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) struct Import {
+    /// `aws_xray_sdk.core`, `aws_lambda_powertools.Tracer` for
+    /// `from aws_lambda_powertools import Tracer`, `github.com/aws/aws-dax-go/dax`,
+    /// `@aws-lambda-powertools/tracer` or `com.amazonaws.xray.AWSXRay`
+    pub(crate) module: String,
+    pub(crate) location: Location,
+}
+
+impl Import {
+    /// Whether this imports `module`, or a module, package or class in it
+    pub(crate) fn is_of(&self, module: &str) -> bool {
+        self.module
+            .strip_prefix(module)
+            .is_some_and(|rest| rest.is_empty() || rest.starts_with(['.', '/']))
+    }
+}
+
+/// The value of a string literal, without its quotes
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) struct StringLiteral {
+    pub(crate) value: String,
+    pub(crate) location: Location,
+}
+
+/// A call or constructor invocation of a source file
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) struct Call {
+    /// The called expression up to its first argument list, without whitespace and `new`:
+    /// `boto3.client`, `AmazonDaxClient` for `new AmazonDaxClient(...)`, and
+    /// `ClusterDaxClient.builder` for `ClusterDaxClient.builder().build()`
+    pub(crate) callee: String,
+    /// The name of the function or method called: `client` for `boto3.client(...)`, `region`
+    /// for `S3Client.builder().region(...)`
+    pub(crate) method: String,
+    pub(crate) arguments: Vec<Argument>,
+    /// The source text of each argument in order, keyword arguments and object literals
+    /// included as written: `&s3.GetObjectInput{...}`, `TableName="orders"`
+    pub(crate) argument_sources: Vec<String>,
+    /// The source text of the call
+    pub(crate) expression: String,
+    pub(crate) location: Location,
+}
+
+impl Call {
+    /// The first argument, if it's a string literal
+    pub(crate) fn first_string(&self) -> Option<&str> {
+        match self.arguments.first() {
+            Some(Argument {
+                name: None,
+                value: ParameterValue::Resolved(value),
+            }) => Some(value),
+            _ => None,
+        }
+    }
+}
+
+/// An argument of a call
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) struct Argument {
+    /// The keyword of a keyword argument, or the key of a property of an object literal
+    /// passed as argument, e.g. `region` of `new S3Client({ region: 'eu-west-1' })`
+    pub(crate) name: Option<String>,
+    /// The value of a string literal, or the source text of other expressions
+    pub(crate) value: ParameterValue,
+}
+
+/// A variable or field assigned the result of a call: `dax = AmazonDaxClient(...)`,
+/// `client, err := dax.New(cfg)`, `with AmazonDaxClient(...) as dax`
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub(crate) struct Assignment {
+    /// The variable, or the attribute or field such as `self.dax`
+    pub(crate) target: String,
+    /// [`Call::callee`] of the assigned call
+    pub(crate) callee: String,
+}
+
+/// The nodes of one source file the enrichments match, in source order
+#[derive(Debug, Default)]
+pub(crate) struct SourceSyntax {
+    /// Identifiers, including those of type annotations, imports and decorators
+    pub(crate) identifiers: Vec<Identifier>,
+    /// Imports, including the `require` calls of CommonJS modules
+    pub(crate) imports: Vec<Import>,
+    pub(crate) calls: Vec<Call>,
+    pub(crate) assignments: Vec<Assignment>,
+    /// Names of the keyword arguments, object properties and struct fields: `Sql` of
+    /// `Sql=sql`, `{ Sql: sql }` and `Sql: &sql`
+    pub(crate) keywords: Vec<String>,
+    /// (name, value) of the keyword arguments, object properties and struct fields set to
+    /// string literals: `namespace="Orders"`, `{ logGroupName: 'orders' }`,
+    /// `Database: aws.String("sales")`
+    pub(crate) keyword_strings: Vec<(String, String)>,
+    pub(crate) strings: Vec<StringLiteral>,
+}
+
+impl SourceSyntax {
+    /// Parse `source_file` in its language
+    pub(crate) fn of(source_file: &SourceFile) -> Self {
+        match source_file.language {
+            Language::Python => Self::collect(Python, source_file),
+            Language::Go => Self::collect(Go, source_file),
+            Language::JavaScript => Self::collect(JavaScript, source_file),
+            Language::TypeScript => Self::collect(TypeScript, source_file),
+            Language::Java => Self::collect(Java, source_file),
+        }
+    }
+
+    /// Parse the expression of an extracted SDK call in the language of its file
+    ///
+    /// The locations of the nodes are relative to the expression.
+    pub(crate) fn of_call(metadata: &SdkMethodCallMetadata) -> Option<Self> {
+        let language = SourceFile::detect_language(&metadata.location.file_path)?;
+        Some(Self::of(&SourceFile::with_language(
+            metadata.location.file_path.clone(),
+            metadata.expr.clone(),
+            language,
+        )))
+    }
+
+    /// Whether a keyword argument, object property, struct field or builder method named
+    /// `name`, ignoring case, is given a value
+    pub(crate) fn passes(&self, name: &str) -> bool {
+        self.keywords
+            .iter()
+            .chain(self.calls.iter().map(|call| &call.method))
+            .any(|passed| passed.eq_ignore_ascii_case(name))
+    }
+
+    /// The string literal a keyword argument, object property, struct field or builder
+    /// method named `name`, ignoring case, is given: `sales` for `Database='sales'`,
+    /// `{ Database: 'sales' }`, `Database: aws.String("sales")` or `.database("sales")`
+    pub(crate) fn string_of(&self, name: &str) -> Option<&str> {
+        self.keyword_strings
+            .iter()
+            .find(|(keyword, _)| keyword.eq_ignore_ascii_case(name))
+            .map(|(_, value)| value.as_str())
+            .or_else(|| {
+                self.calls
+                    .iter()
+                    .filter(|call| call.method.eq_ignore_ascii_case(name))
+                    .find_map(Call::first_string)
+            })
+    }
+
+    fn collect<L: LanguageExt>(language: L, source_file: &SourceFile) -> Self {
+        let ast_grep = language.ast_grep(&source_file.content);
+        let mut syntax = Self::default();
+        for node in ast_grep.root().dfs() {
+            let kind = node.kind();
+            // `identifier`, and the `type_identifier`, `field_identifier` and
+            // `property_identifier` of the grammars distinguishing them
+            if kind.ends_with("identifier") {
+                syntax.identifiers.push(Identifier {
+                    name: node.text().to_string(),
+                    location: location(source_file, &node),
+                });
+            } else if STRING_KINDS.contains(&&*kind) {
+                syntax.strings.push(StringLiteral {
+                    value: unquote(&node.text()).to_string(),
+                    location: location(source_file, &node),
+                });
+            } else if CALL_KINDS.contains(&&*kind) {
+                let callee = callee(&node);
+                let call = Call {
+                    method: method(&node, &callee),
+                    callee,
+                    arguments: arguments(&node),
+                    argument_sources: argument_nodes(&node)
+                        .map(|argument| argument.text().to_string())
+                        .collect(),
+                    expression: node.text().to_string(),
+                    location: location(source_file, &node),
+                };
+                // CommonJS imports
+                if call.callee == "require" {
+                    syntax
+                        .imports
+                        .extend(call.first_string().map(|module| Import {
+                            module: module.to_string(),
+                            location: call.location.clone(),
+                        }));
+                }
+                syntax.calls.push(call);
+            } else if let Some(assignment) = assignment(&node) {
+                syntax.assignments.push(assignment);
+            } else if let Some((name, value)) = keyword(&node) {
+                if let Some(value) = value {
+                    syntax.keyword_strings.push((name.clone(), value));
+                }
+                syntax.keywords.push(name);
+            } else {
+                syntax
+                    .imports
+                    .extend(imports(&node).into_iter().map(|module| Import {
+                        module,
+                        location: location(source_file, &node),
+                    }));
+            }
+        }
+        syntax
+    }
+}
+
+fn location<L: LanguageExt>(source_file: &SourceFile, node: &Node<'_, StrDoc<L>>) -> Location {
+    Location::from_node(source_file.path.clone(), node)
+}
+
+/// The value of a string literal: `orders` for `'orders'`, `r"orders"` or `` `orders` ``
+fn unquote(literal: &str) -> &str {
+    let literal = literal.trim_start_matches(['r', 'R', 'b', 'B', 'u', 'U', 'f', 'F']);
+    ["\"\"\"", "'''", "\"", "'", "`"]
+        .into_iter()
+        .find_map(|quote| literal.strip_prefix(quote)?.strip_suffix(quote))
+        .unwrap_or(literal)
+}
+
+/// [`Call::callee`] of a call node
+fn callee<L: LanguageExt>(call: &Node<'_, StrDoc<L>>) -> String {
+    let text = call.text();
+    let callee = text.split('(').next().unwrap_or_default().trim_start();
+    let callee = callee
+        .strip_prefix("new")
+        .filter(|rest| rest.starts_with(char::is_whitespace))
+        .unwrap_or(callee);
+    callee.chars().filter(|c| !c.is_whitespace()).collect()
+}
+
+/// [`Call::method`] of a call node: the name ending the callee before its argument list
+fn method<L: LanguageExt>(call: &Node<'_, StrDoc<L>>, callee: &str) -> String {
+    let name_of = |text: &str| {
+        text.trim_end()
+            .rsplit(|c: char| !(c.is_alphanumeric() || c == '_' || c == '$'))
+            .next()
+            .unwrap_or_default()
+            .to_string()
+    };
+    match call.field("arguments") {
+        Some(arguments) => {
+            let text = call.text();
+            let end = arguments.range().start - call.range().start;
+            text.get(..end).map(name_of).unwrap_or_default()
+        }
+        None => name_of(callee),
+    }
+}
+
+/// [`Call::arguments`] of a call node, with the properties of object literals as arguments
+/// of their own
+fn arguments<L: LanguageExt>(call: &Node<'_, StrDoc<L>>) -> Vec<Argument> {
+    let value = |node: &Node<'_, StrDoc<L>>| {
+        if STRING_KINDS.contains(&&*node.kind()) {
+            ParameterValue::Resolved(unquote(&node.text()).to_string())
+        } else {
+            ParameterValue::Unresolved(node.text().to_string())
+        }
+    };
+    let mut values = Vec::new();
+    for argument in argument_nodes(call) {
+        match &*argument.kind() {
+            "keyword_argument" => {
+                if let (Some(name), Some(keyword_value)) =
+                    (argument.field("name"), argument.field("value"))
+                {
+                    values.push(Argument {
+                        name: Some(name.text().to_string()),
+                        value: value(&keyword_value),
+                    });
+                }
+            }
+            "object" => {
+                for pair in argument.children().filter(|child| child.kind() == "pair") {
+                    if let (Some(key), Some(pair_value)) = (pair.field("key"), pair.field("value"))
+                    {
+                        values.push(Argument {
+                            name: Some(unquote(&key.text()).to_string()),
+                            value: value(&pair_value),
+                        });
+                    }
+                }
+            }
+            _ => values.push(Argument {
+                name: None,
+                value: value(&argument),
+            }),
+        }
+    }
+    values
+}
+
+/// The argument nodes of a call node, without the comments between them
+fn argument_nodes<'r, L: LanguageExt>(
+    call: &Node<'r, StrDoc<L>>,
+) -> impl Iterator<Item = Node<'r, StrDoc<L>>> {
+    call.field("arguments")
+        .into_iter()
+        .flat_map(|arguments| arguments.children().collect::<Vec<_>>())
+        .filter(|child| child.is_named() && !child.kind().contains("comment"))
+}
+
+/// The assignment of a call's result `node` makes, if it makes one
+fn assignment<L: LanguageExt>(node: &Node<'_, StrDoc<L>>) -> Option<Assignment> {
+    let (target, value) = match &*node.kind() {
+        // Python `with ... as target`
+        "as_pattern" => (
+            node.field("alias")?,
+            node.children().find(|child| child.is_named())?,
+        ),
+        "assignment"
+        | "assignment_expression"
+        | "assignment_statement"
+        | "short_var_declaration" => (node.field("left")?, node.field("right")?),
+        "variable_declarator" | "var_spec" => (node.field("name")?, node.field("value")?),
+        _ => return None,
+    };
+    let target = first_of_list(target)?.text().to_string();
+    let mut value = first_of_list(value)?;
+    if value.kind().starts_with("await") {
+        value = value.children().find(|child| child.is_named())?;
+    }
+    let is_name = !target.is_empty()
+        && target
+            .chars()
+            .all(|c| c.is_alphanumeric() || matches!(c, '_' | '$' | '.'));
+    (is_name && CALL_KINDS.contains(&&*value.kind())).then(|| Assignment {
+        target,
+        callee: callee(&value),
+    })
+}
+
+/// The first expression of a Go expression list, e.g. the client of `client, err := ...`,
+/// or `node` itself
+fn first_of_list<'r, L: LanguageExt>(node: Node<'r, StrDoc<L>>) -> Option<Node<'r, StrDoc<L>>> {
+    if node.kind() == "expression_list" {
+        node.children().find(|child| child.is_named())
+    } else {
+        Some(node)
+    }
+}
+
+/// The name of `node`, if it's a keyword argument, object property or struct field, with
+/// its value if it's a string literal
+fn keyword<L: LanguageExt>(node: &Node<'_, StrDoc<L>>) -> Option<(String, Option<String>)> {
+    let (name, value) = match &*node.kind() {
+        "keyword_argument" => (node.field("name")?, node.field("value")?),
+        "pair" => (node.field("key")?, node.field("value")?),
+        // Go `Field: value` of a composite literal
+        "keyed_element" => {
+            let mut elements = node
+                .children()
+                .filter(|child| child.is_named() && !child.kind().contains("comment"));
+            (
+                literal_element(elements.next()?),
+                literal_element(elements.next()?),
+            )
+        }
+        _ => return None,
+    };
+    // Go `aws.String("...")`
+    let value = match value.field("arguments") {
+        Some(arguments) if callee(&value) == "aws.String" => {
+            arguments.children().find(|child| child.is_named())?
+        }
+        _ => value,
+    };
+    let value = STRING_KINDS
+        .contains(&&*value.kind())
+        .then(|| unquote(&value.text()).to_string());
+    Some((unquote(&name.text()).to_string(), value))
+}
+
+/// The expression of a Go `literal_element`, or `node` itself
+fn literal_element<'r, L: LanguageExt>(node: Node<'r, StrDoc<L>>) -> Node<'r, StrDoc<L>> {
+    let expression = if node.kind() == "literal_element" {
+        node.children().find(|child| child.is_named())
+    } else {
+        None
+    };
+    expression.unwrap_or(node)
+}
+
+/// What `node` imports, if it's an import
+fn imports<L: LanguageExt>(node: &Node<'_, StrDoc<L>>) -> Vec<String> {
+    let unquoted = |literal: Node<'_, StrDoc<L>>| unquote(&literal.text()).to_string();
+    match &*node.kind() {
+        // JavaScript and TypeScript `import ... from 'module'`
+        "import_statement" if node.field("source").is_some() => {
+            node.field("source").map(unquoted).into_iter().collect()
+        }
+        // Python `import a.b, c as d`
+        "import_statement" => node
+            .children()
+            .filter(|child| child.is_named())
+            .filter_map(|child| match &*child.kind() {
+                "aliased_import" => child.field("name"),
+                _ => Some(child),
+            })
+            .map(|module| module.text().to_string())
+            .collect(),
+        // Python `from module import name, other as alias`
+        "import_from_statement" => {
+            let Some(module) = node.field("module_name") else {
+                return Vec::new();
+            };
+            let module = module.text().to_string();
+            let names: Vec<String> = node
+                .children()
+                .skip_while(|child| child.kind() != "import")
+                .filter_map(|child| match &*child.kind() {
+                    "aliased_import" => child.field("name"),
+                    "dotted_name" => Some(child),
+                    _ => None,
+                })
+                .map(|name| format!("{module}.{}", name.text()))
+                .collect();
+            std::iter::once(module).chain(names).collect()
+        }
+        // Go `import "path"`
+        "import_spec" => node.field("path").map(unquoted).into_iter().collect(),
+        // Java `import a.b.C;`
+        "import_declaration" => node
+            .children()
+            .find(|child| child.is_named())
+            .map(|name| name.text().to_string())
+            .into_iter()
+            .collect(),
+        _ => Vec::new(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+
+    fn syntax(path: &str, content: &str, language: Language) -> SourceSyntax {
+        SourceSyntax::of(&SourceFile::with_language(
+            PathBuf::from(path),
+            content.to_string(),
+            language,
+        ))
+    }
+
+    fn modules(syntax: &SourceSyntax) -> Vec<&str> {
+        syntax
+            .imports
+            .iter()
+            .map(|import| import.module.as_str())
+            .collect()
+    }
+
+    fn names(path: &str, content: &str, language: Language) -> Vec<String> {
+        syntax(path, content, language)
+            .identifiers
+            .into_iter()
+            .map(|identifier| identifier.name)
+            .collect()
+    }
+
+    #[test]
+    fn test_identifiers_outside_comments_and_strings() {
+        let names = names(
+            "app.py",
+            "# handler(event: SQSEvent)\n\"\"\"Reads KinesisEvent records\"\"\"\n\
+             def handler(event: DynamoDBStreamEvent, context):\n    return 'SQSEvent'\n",
+            Language::Python,
+        );
+
+        assert!(names.contains(&"DynamoDBStreamEvent".to_string()));
+        assert!(names.contains(&"handler".to_string()));
+        assert!(!names.contains(&"SQSEvent".to_string()));
+        assert!(!names.contains(&"KinesisEvent".to_string()));
+    }
+
+    #[test]
+    fn test_type_identifiers() {
+        let names = names(
+            "main.go",
+            "package main\n\n// func handle(event events.KinesisEvent)\n\
+             func handle(ctx context.Context, event events.SQSEvent) error {\n\treturn nil\n}\n",
+            Language::Go,
+        );
+
+        assert!(names.contains(&"SQSEvent".to_string()));
+        assert!(!names.contains(&"KinesisEvent".to_string()));
+    }
+
+    #[test]
+    fn test_imports() {
+        let python = syntax(
+            "app.py",
+            "# import watchtower\nimport aws_xray_sdk.core, os as system\n\
+             from aws_lambda_powertools import Metrics, Tracer as T\n",
+            Language::Python,
+        );
+        assert_eq!(
+            modules(&python),
+            [
+                "aws_xray_sdk.core",
+                "os",
+                "aws_lambda_powertools",
+                "aws_lambda_powertools.Metrics",
+                "aws_lambda_powertools.Tracer"
+            ]
+        );
+        assert!(python.imports[0].is_of("aws_xray_sdk"));
+        assert!(!python.imports[0].is_of("aws_xray"));
+        assert!(!python
+            .imports
+            .iter()
+            .any(|import| import.is_of("watchtower")));
+
+        let typescript = syntax(
+            "logger.ts",
+            "import { Tracer } from '@aws-lambda-powertools/tracer';\n\
+             const WinstonCloudWatch = require('winston-cloudwatch');\n",
+            Language::TypeScript,
+        );
+        assert_eq!(
+            modules(&typescript),
+            ["@aws-lambda-powertools/tracer", "winston-cloudwatch"]
+        );
+
+        let go = syntax(
+            "main.go",
+            "package main\n\nimport (\n\t\"context\"\n\n\
+             \tdax \"github.com/aws/aws-dax-go-v2/dax\"\n)\n",
+            Language::Go,
+        );
+        assert_eq!(
+            modules(&go),
+            ["context", "github.com/aws/aws-dax-go-v2/dax"]
+        );
+
+        let java = syntax(
+            "App.java",
+            "import com.amazonaws.xray.AWSXRay;\nimport static java.util.Objects.requireNonNull;\n",
+            Language::Java,
+        );
+        assert_eq!(
+            modules(&java),
+            [
+                "com.amazonaws.xray.AWSXRay",
+                "java.util.Objects.requireNonNull"
+            ]
+        );
+    }
+
+    #[test]
+    fn test_calls_and_assignments() {
+        let python = syntax(
+            "app.py",
+            "ddb = boto3.client('dynamodb')\n# dax = AmazonDaxClient()\n\
+             self.dax = amazondax.AmazonDaxClient(endpoint_url=ENDPOINT)\n\
+             with AmazonDaxClient.resource(endpoint_url=ENDPOINT) as cache:\n    pass\n",
+            Language::Python,
+        );
+        assert_eq!(python.calls[0].callee, "boto3.client");
+        assert_eq!(python.calls[0].method, "client");
+        assert_eq!(python.calls[0].first_string(), Some("dynamodb"));
+        assert_eq!(
+            python.assignments,
+            [
+                Assignment {
+                    target: "ddb".to_string(),
+                    callee: "boto3.client".to_string()
+                },
+                Assignment {
+                    target: "self.dax".to_string(),
+                    callee: "amazondax.AmazonDaxClient".to_string()
+                },
+                Assignment {
+                    target: "cache".to_string(),
+                    callee: "AmazonDaxClient.resource".to_string()
+                },
+            ]
+        );
+
+        let go = syntax(
+            "main.go",
+            "package main\n\nfunc main() {\n\tclient, err := dax.New(cfg)\n}\n",
+            Language::Go,
+        );
+        assert_eq!(go.assignments[0].target, "client");
+        assert_eq!(go.assignments[0].callee, "dax.New");
+
+        let java = syntax(
+            "Orders.java",
+            "class Orders {\n  DynamoDbClient client = ClusterDaxClient.builder()\n\
+             \x20     .build();\n}\n",
+            Language::Java,
+        );
+        assert_eq!(java.assignments[0].target, "client");
+        assert_eq!(java.assignments[0].callee, "ClusterDaxClient.builder");
+
+        let javascript = syntax(
+            "app.js",
+            "const dax = new AmazonDaxClient({ endpoints: [ENDPOINT] });\n\
+             let doc = await DaxDocument.create(dax);\n",
+            Language::JavaScript,
+        );
+        assert_eq!(javascript.assignments[0].target, "dax");
+        assert_eq!(javascript.assignments[0].callee, "AmazonDaxClient");
+        assert_eq!(javascript.assignments[1].callee, "DaxDocument.create");
+    }
+
+    #[test]
+    fn test_string_values() {
+        let go = syntax(
+            "main.go",
+            "package main\n\n// const endpoint = \"dax://old.abc.dax-clusters.amazonaws.com\"\n\
+             const endpoint = `dax://orders.l6fzcv.dax-clusters.us-east-1.amazonaws.com`\n",
+            Language::Go,
+        );
+
+        assert_eq!(go.strings.len(), 1);
+        assert_eq!(
+            go.strings[0].value,
+            "dax://orders.l6fzcv.dax-clusters.us-east-1.amazonaws.com"
+        );
+        assert_eq!(go.strings[0].location.start_line(), 4);
+    }
+
+    #[test]
+    fn test_keyword_strings() {
+        let python = syntax(
+            "app.py",
+            "metrics = Metrics(namespace=\"Orders\", service=SERVICE)\n",
+            Language::Python,
+        );
+        let typescript = syntax(
+            "logger.ts",
+            "logger.add(new WinstonCloudWatch({ logGroupName: 'api', 'logStreamName': 'web' }));\n",
+            Language::TypeScript,
+        );
+
+        assert_eq!(
+            python.keyword_strings,
+            [("namespace".to_string(), "Orders".to_string())]
+        );
+        assert_eq!(
+            typescript.keyword_strings,
+            [
+                ("logGroupName".to_string(), "api".to_string()),
+                ("logStreamName".to_string(), "web".to_string())
+            ]
+        );
+    }
+
+    #[test]
+    fn test_arguments_of_calls() {
+        let metadata = |path: &str, expression: &str| {
+            SdkMethodCallMetadata::new(
+                expression.to_string(),
+                Location::new(PathBuf::from(path), (1, 1), (1, 1)),
+            )
+        };
+        let go = SourceSyntax::of_call(&metadata(
+            "main.go",
+            "client.ExecuteStatement(ctx, &redshiftdata.ExecuteStatementInput{\n\
+             \tClusterIdentifier: aws.String(\"analytics\"),\n\tSql: &sql,\n})",
+        ))
+        .unwrap();
+        let java = SourceSyntax::of_call(&metadata(
+            "Reports.java",
+            "client.executeStatement(r -> r.clusterIdentifier(cluster).database(\"sales\"))",
+        ))
+        .unwrap();
+
+        assert_eq!(go.string_of("clusteridentifier"), Some("analytics"));
+        assert!(go.passes("Sql"));
+        assert!(!go.passes("DbUser"));
+        assert!(java.passes("ClusterIdentifier"));
+        assert_eq!(java.string_of("ClusterIdentifier"), None);
+        assert_eq!(java.string_of("Database"), Some("sales"));
+        assert!(SourceSyntax::of_call(&metadata("Makefile", "run")).is_none());
+    }
+}
@@ -0,0 +1,98 @@
+//! Detection of test sources that should not contribute to a production policy.
+
+use std::path::Path;
+
+use crate::extraction::javascript::test_doubles::mocks_aws_sdk;
+use crate::extraction::python::test_doubles::uses_aws_emulator;
+use crate::Language;
+
+/// Check whether a file is a test source by the naming conventions of its language.
+///
+/// Only file naming is considered here; see [`is_test_content`] for test code
+/// recognized by what it does. Test doubles that are always ignored are detected
+/// in the language extractors.
+pub(crate) fn is_test_file(path: &Path, language: Language) -> bool {
+    let Some(file_name) = path.file_name().and_then(|name| name.to_str()) else {
+        return false;
+    };
+    match language {
+        // `go test` only compiles files ending in `_test.go`, and `go build` never does
+        Language::Go => file_name.ends_with("_test.go"),
+        Language::Python | Language::JavaScript | Language::TypeScript | Language::Java => false,
+    }
+}
+
+/// Check whether a loaded source is test code by its content.
+///
+/// Python modules exercising moto or LocalStack set up fixtures against emulated
+/// services, so they count as tests wherever they live.
+pub(crate) fn is_test_content(content: &str, language: Language) -> bool {
+    match language {
+        Language::Python => uses_aws_emulator(content),
+        Language::Go | Language::JavaScript | Language::TypeScript | Language::Java => false,
+    }
+}
+
+/// Check whether a loaded source is a test mocking the AWS SDK clients.
+///
+/// JavaScript and TypeScript tests mock the clients with `aws-sdk-client-mock`, jest
+/// module mocks or sinon stubs, whatever their file names. Unlike emulator-backed tests,
+/// they're skipped by default, see [`DefaultExclusion::Mocked`].
+///
+/// [`DefaultExclusion::Mocked`]: crate::api::model::DefaultExclusion::Mocked
+pub(crate) fn is_mocked_content(content: &str, language: Language) -> bool {
+    match language {
+        Language::JavaScript | Language::TypeScript => mocks_aws_sdk(content),
+        Language::Python | Language::Go | Language::Java => false,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use rstest::rstest;
+
+    #[rstest]
+    #[case("internal/storage/s3_test.go", true)]
+    #[case("s3_test.go", true)]
+    #[case("internal/storage/s3.go", false)]
+    #[case("internal/testdata/s3.go", false)]
+    #[case("internal/storage/test.go", false)]
+    #[case("internal/storage/s3_test.go.orig", false)]
+    fn test_go_test_files(#[case] path: &str, #[case] expected: bool) {
+        assert_eq!(is_test_file(Path::new(path), Language::Go), expected);
+    }
+
+    #[test]
+    fn test_go_convention_does_not_apply_to_other_languages() {
+        assert!(!is_test_file(Path::new("s3_test.go"), Language::Python));
+    }
+
+    #[rstest]
+    #[case::moto("from moto import mock_aws\n", Language::Python, true)]
+    #[case::production("import boto3\n", Language::Python, false)]
+    #[case::other_language("from moto import mock_aws\n", Language::Go, false)]
+    fn test_is_test_content(
+        #[case] content: &str,
+        #[case] language: Language,
+        #[case] expected: bool,
+    ) {
+        assert_eq!(is_test_content(content, language), expected);
+    }
+
+    #[rstest]
+    #[case::client_mock(
+        "import { mockClient } from 'aws-sdk-client-mock';\n",
+        Language::TypeScript,
+        true
+    )]
+    #[case::jest_mock("jest.mock('@aws-sdk/client-s3');\n", Language::JavaScript, true)]
+    #[case::other_language("jest.mock('@aws-sdk/client-s3');\n", Language::Python, false)]
+    fn test_is_mocked_content(
+        #[case] content: &str,
+        #[case] language: Language,
+        #[case] expected: bool,
+    ) {
+        assert_eq!(is_mocked_content(content, language), expected);
+    }
+}
@@ -0,0 +1,376 @@
+//! Calls of helper functions forwarding an operation to an AWS SDK client
+//!
+//! Codebases often route their AWS calls through a helper adding retries, metrics or
+//! error mapping, so the client call itself only appears once, on an operation the
+//! helper doesn't know:
+//!
+//! ```go
+//! func Do[T any](client *s3.Client, input T) error { ... }
+//!
+//! awsutil.Do(client, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: key})
+//! ```
+//!
+//! Registered [`WrapperFunctions`] turn each call of the helper into a call of the
+//! operation its argument names, whatever the language: a string naming the operation,
+//! or a request or command whose type is named after it. The calls are matched in the
+//! syntax tree, so a call of the helper in a comment or a string literal isn't taken for
+//! one.
+
+use std::collections::HashSet;
+use std::path::Path;
+use std::sync::OnceLock;
+
+use anyhow::{Context, Result};
+use convert_case::{Case, Casing};
+use regex::Regex;
+
+use crate::api::model::{WrapperFunction, WrapperFunctions};
+use crate::extraction::sdk_model::{ServiceDiscovery, ServiceModelIndex};
+use crate::extraction::shared::source_syntax::{Call, SourceSyntax};
+use crate::extraction::{SdkMethodCallMetadata, SourceFile};
+use crate::{Language, SdkMethodCall};
+
+/// Regex matching the type of a request or command named after its operation, e.g.
+/// `GetObjectInput`, `GetObjectRequest` or `GetObjectCommand`
+static REQUEST_TYPE_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_request_type_regex() -> &'static Regex {
+    REQUEST_TYPE_REGEX.get_or_init(|| {
+        Regex::new(r"\b([A-Z][A-Za-z0-9]*?)(?:Input|Request|Command)\b")
+            .expect("Invalid request type regex")
+    })
+}
+
+/// Load and validate the wrapper functions file at `path`
+pub(crate) fn load_wrapper_functions(path: &Path) -> Result<WrapperFunctions> {
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("Failed to read wrapper functions file: {}", path.display()))?;
+    let wrappers: WrapperFunctions = serde_json::from_str(&content)
+        .with_context(|| format!("Failed to parse wrapper functions file: {}", path.display()))?;
+    wrappers
+        .validate()
+        .with_context(|| format!("Invalid wrapper functions file: {}", path.display()))?;
+    Ok(wrappers)
+}
+
+/// The calls of the operations the calls of `wrappers` in `source_files` forward
+///
+/// Calls whose operation argument names no operation of the service index, e.g. a
+/// variable, are skipped, as are those the extractor already found on the same line,
+/// such as a command constructed in the argument.
+pub(crate) fn wrapper_calls(
+    wrappers: &WrapperFunctions,
+    language: Language,
+    source_files: &[SourceFile],
+    extracted: &[SdkMethodCall],
+    service_index: &ServiceModelIndex,
+) -> Vec<SdkMethodCall> {
+    let extracted_lines: HashSet<(&Path, usize, &str)> = extracted
+        .iter()
+        .filter_map(|call| {
+            let metadata = call.metadata.as_ref()?;
+            Some((
+                metadata.location.file_path.as_path(),
+                metadata.location.start_line(),
+                call.name.as_str(),
+            ))
+        })
+        .collect();
+    let mut calls = Vec::new();
+    for source_file in source_files {
+        if !wrappers
+            .wrappers
+            .iter()
+            .any(|wrapper| source_file.content.contains(&wrapper.function))
+        {
+            continue;
+        }
+        let syntax = SourceSyntax::of(source_file);
+        for wrapper in &wrappers.wrappers {
+            for site in syntax
+                .calls
+                .iter()
+                .filter(|call| call.callee == wrapper.function)
+            {
+                let Some(call) = forwarded_call(wrapper, site, language, service_index) else {
+                    log::debug!(
+                        "No operation resolved for the call of wrapper {}: {}",
+                        wrapper.function,
+                        site.expression
+                    );
+                    continue;
+                };
+                if !extracted_lines.contains(&(
+                    source_file.path.as_path(),
+                    site.location.start_line(),
+                    call.name.as_str(),
+                )) {
+                    calls.push(call);
+                }
+            }
+        }
+    }
+    calls
+}
+
+/// The call of the operation the call `site` of `wrapper` forwards
+fn forwarded_call(
+    wrapper: &WrapperFunction,
+    site: &Call,
+    language: Language,
+    service_index: &ServiceModelIndex,
+) -> Option<SdkMethodCall> {
+    let operation = operation_name(site.argument_sources.get(wrapper.operation_argument)?)?;
+    let name = ServiceDiscovery::operation_to_method_name(&operation, language);
+    let mut possible_services: Vec<String> = service_index
+        .method_lookup
+        .get(&name)?
+        .iter()
+        .map(|method| method.service_name.clone())
+        .filter(|service| {
+            wrapper
+                .service
+                .as_ref()
+                .is_none_or(|wrapped| wrapped == service)
+        })
+        .collect();
+    possible_services.sort();
+    possible_services.dedup();
+    if possible_services.is_empty() {
+        return None;
+    }
+    Some(SdkMethodCall {
+        name,
+        possible_services,
+        metadata: Some(SdkMethodCallMetadata::new(
+            site.expression.clone(),
+            site.location.clone(),
+        )),
+    })
+}
+
+/// The operation an argument names, e.g. `GetObject` for `"get_object"` or
+/// `&s3.GetObjectInput{...}`
+fn operation_name(argument: &str) -> Option<String> {
+    if let Some(literal) = string_literal(argument) {
+        if !literal
+            .chars()
+            .all(|c| c.is_ascii_alphanumeric() || matches!(c, '_' | '-'))
+        {
+            return None;
+        }
+        if literal.contains(['_', '-']) {
+            return Some(literal.to_case(Case::Pascal));
+        }
+        let mut characters = literal.chars();
+        let first = characters.next()?;
+        return Some(first.to_uppercase().chain(characters).collect());
+    }
+    get_request_type_regex()
+        .captures(argument)
+        .map(|captures| captures[1].to_string())
+}
+
+/// The content of `argument` if it's a single string literal
+fn string_literal(argument: &str) -> Option<&str> {
+    let quote = argument
+        .chars()
+        .next()
+        .filter(|c| matches!(c, '"' | '\'' | '`'))?;
+    let content = argument.strip_prefix(quote)?.strip_suffix(quote)?;
+    (!content.is_empty() && !content.contains(quote)).then_some(content)
+}
+
+#[cfg(test)]
+mod tests {
+    use std::collections::HashMap;
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::extraction::sdk_model::ServiceMethodRef;
+    use crate::Location;
+
+    fn service_index(methods: &[(&str, &[&str])]) -> ServiceModelIndex {
+        ServiceModelIndex {
+            services: HashMap::new(),
+            method_lookup: methods
+                .iter()
+                .map(|(name, services)| {
+                    (
+                        (*name).to_string(),
+                        services
+                            .iter()
+                            .map(|service| ServiceMethodRef {
+                                service_name: (*service).to_string(),
+                                operation_name: String::new(),
+                            })
+                            .collect(),
+                    )
+                })
+                .collect(),
+            waiter_lookup: HashMap::new(),
+        }
+    }
+
+    fn wrappers(function: &str, operation_argument: usize) -> WrapperFunctions {
+        WrapperFunctions {
+            wrappers: vec![WrapperFunction {
+                function: function.to_string(),
+                operation_argument,
+                service: None,
+            }],
+        }
+    }
+
+    #[test]
+    fn test_go_wrapper_calls_resolve_to_the_forwarded_operation() {
+        let content = "package store\n\n\
+                       func Do[T any](client *s3.Client, input T) error { return nil }\n\n\
+                       func load() {\n\
+                       \tawsutil.Do(client, &s3.GetObjectInput{\n\
+                       \t\tBucket: aws.String(\"reports\"),\n\
+                       \t})\n\
+                       \tawsutil.Do(client, input)\n\
+                       }\n";
+        let source_files = [SourceFile::with_language(
+            PathBuf::from("store.go"),
+            content.to_string(),
+            Language::Go,
+        )];
+        let index = service_index(&[("GetObject", &["s3"])]);
+
+        let calls = wrapper_calls(
+            &wrappers("awsutil.Do", 1),
+            Language::Go,
+            &source_files,
+            &[],
+            &index,
+        );
+
+        assert_eq!(calls.len(), 1);
+        assert_eq!(calls[0].name, "GetObject");
+        assert_eq!(calls[0].possible_services, vec!["s3".to_string()]);
+        let metadata = calls[0].metadata.as_ref().unwrap();
+        assert_eq!(
+            metadata.location,
+            Location::new(PathBuf::from("store.go"), (6, 2), (8, 4))
+        );
+        assert!(metadata
+            .expr
+            .starts_with("awsutil.Do(client, &s3.GetObjectInput{"));
+    }
+
+    #[test]
+    fn test_python_wrapper_definitions_and_known_calls_are_skipped() {
+        let content = "def call_aws(operation, **kwargs):\n    pass\n\n\
+                       call_aws(\"put_item\", TableName=\"orders\")\n\
+                       call_aws('send_message', QueueUrl=url)\n";
+        let source_files = [SourceFile::with_language(
+            PathBuf::from("app.py"),
+            content.to_string(),
+            Language::Python,
+        )];
+        let index = service_index(&[("put_item", &["dynamodb"]), ("send_message", &["sqs"])]);
+        let extracted = [SdkMethodCall {
+            name: "send_message".to_string(),
+            possible_services: vec!["sqs".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "queue.send_message(QueueUrl=url)".to_string(),
+                Location::new(PathBuf::from("app.py"), (5, 1), (5, 30)),
+            )),
+        }];
+
+        let calls = wrapper_calls(
+            &wrappers("call_aws", 0),
+            Language::Python,
+            &source_files,
+            &extracted,
+            &index,
+        );
+
+        let names: Vec<&str> = calls.iter().map(|call| call.name.as_str()).collect();
+        assert_eq!(names, vec!["put_item"]);
+    }
+
+    #[rstest::rstest]
+    #[case::python(
+        "app.py",
+        Language::Python,
+        "call_aws",
+        "put_item",
+        "\"\"\"Reads through call_aws(\"get_item\", TableName=table)\"\"\"\n\
+         import helpers\n\n\
+         retries = 3  # call_aws(\"delete_item\", TableName=table)\n\
+         print('call_aws(\"scan\")')\n\
+         call_aws(\"put_item\", TableName=\"orders\")\n"
+    )]
+    #[case::go(
+        "store.go",
+        Language::Go,
+        "awsutil.Do",
+        "PutItem",
+        "package store\n\n\
+         /* awsutil.Do(client, &dynamodb.GetItemInput{}) */\n\
+         func load() {\n\
+         \tretries := 3 // awsutil.Do(client, &dynamodb.DeleteItemInput{})\n\
+         \tlog.Print(\"awsutil.Do(client, &dynamodb.ScanInput{})\")\n\
+         \tawsutil.Do(client, &dynamodb.PutItemInput{})\n\
+         }\n"
+    )]
+    #[case::javascript(
+        "store.js",
+        Language::JavaScript,
+        "callAws",
+        "PutItem",
+        "/**\n * Reads through callAws(client, new GetItemCommand({}))\n */\n\
+         const retries = 3; // callAws(client, new DeleteItemCommand({}))\n\
+         console.log(`callAws(client, new ScanCommand({}))`);\n\
+         callAws(client, new PutItemCommand({ TableName: 'orders' }));\n"
+    )]
+    fn test_wrapper_calls_in_comments_and_strings_are_skipped(
+        #[case] path: &str,
+        #[case] language: Language,
+        #[case] function: &str,
+        #[case] expected: &str,
+        #[case] content: &str,
+    ) {
+        let source_files = [SourceFile::with_language(
+            PathBuf::from(path),
+            content.to_string(),
+            language,
+        )];
+        let index = service_index(&[
+            ("GetItem", &["dynamodb"]),
+            ("get_item", &["dynamodb"]),
+            ("DeleteItem", &["dynamodb"]),
+            ("delete_item", &["dynamodb"]),
+            ("Scan", &["dynamodb"]),
+            ("scan", &["dynamodb"]),
+            ("PutItem", &["dynamodb"]),
+            ("put_item", &["dynamodb"]),
+        ]);
+
+        let calls = wrapper_calls(
+            &wrappers(function, usize::from(language != Language::Python)),
+            language,
+            &source_files,
+            &[],
+            &index,
+        );
+
+        let names: Vec<&str> = calls.iter().map(|call| call.name.as_str()).collect();
+        assert_eq!(names, vec![expected]);
+    }
+
+    #[rstest::rstest]
+    #[case::pascal_case_string("\"GetObject\"", Some("GetObject"))]
+    #[case::snake_case_string("'list_objects_v2'", Some("ListObjectsV2"))]
+    #[case::camel_case_string("`putItem`", Some("PutItem"))]
+    #[case::js_command("new SendMessageCommand({ QueueUrl: url })", Some("SendMessage"))]
+    #[case::java_request("PutItemRequest.builder().tableName(table).build()", Some("PutItem"))]
+    #[case::variable("input", None)]
+    #[case::interpolated("\"get_${kind}\"", None)]
+    fn test_operation_name(#[case] argument: &str, #[case] expected: Option<&str>) {
+        assert_eq!(operation_name(argument).as_deref(), expected);
+    }
+}
@@ -39,13 +39,25 @@ pub(crate) mod model_generation;
 use std::fmt::Display;
 use std::path::PathBuf;
 
-pub use enrichment::{Engine as EnrichmentEngine, Explanation};
-pub use extraction::{Engine as ExtractionEngine, ExtractedMethods, SdkMethodCall, SourceFile};
+pub use enrichment::{
+    DeprecatedCall, Engine as EnrichmentEngine, ExcludedServiceCall, Explanation, NoPermissionCall,
+};
+pub use extraction::{
+    Diagnostic, DiagnosticKind, Engine as ExtractionEngine, ExtractedMethods, ProgressObserver,
+    SdkMethodCall, SourceFile, SuppressedCall, PROGRESS_LOG_TARGET,
+};
 // Not part of the stable public API — exposed only for integration tests in tests/.
 #[doc(hidden)]
 pub use extraction::ServiceDiscovery;
 pub use policy_generation::{
-    Effect, Engine as PolicyGenerationEngine, IamPolicy, PolicyType, PolicyWithMetadata, Statement,
+    AccessLevel, ActionProvenance, CallSite, ConditionKeySuggestion, CoverageCounts,
+    CoverageReport, CrossAccountAccess, CrossAccountRequirement, Effect,
+    Engine as PolicyGenerationEngine, FileCoverage, FilterReason, FilteredAction, IamPolicy,
+    InventoriedAction, LanguageCoverage, ManagedPolicySuggestion, PolicyType, PolicyWithMetadata,
+    ResourcePolicy, ResourcePolicyDocument, ResourcePolicyType, ResourceStatement, Runtime,
+    SensitiveAction, ServiceAccessLevels, Severity, Statement, StatementOrigin, StatementSource,
+    TemplateVariable, TrustPolicy, TrustPolicyDocument, TrustStatement, UnscopedAction,
+    UnscopedReason,
 };
 
 // Re-export commonly used types for convenience
@@ -0,0 +1,305 @@
+//! Reconciliation with policies generated by IAM Access Analyzer
+//!
+//! Access Analyzer generates a policy from the actions a role used according to
+//! CloudTrail, which covers calls the static analysis can't see, such as operations
+//! named at runtime, but misses code paths that didn't run. Merging both keeps the
+//! statements of either, labeled with where their actions come from.
+//!
+//! Access Analyzer leaves placeholders such as `${BucketName}` in the resources it
+//! couldn't name, which are granted on all names as `*`.
+
+use std::collections::BTreeSet;
+use std::path::Path;
+use std::sync::OnceLock;
+
+use regex::Regex;
+use serde::Serialize;
+use serde_json::Value;
+
+use crate::errors::{ExtractorError, Result};
+use crate::policy_generation::{Effect, IamPolicy, PolicyType, PolicyWithMetadata, Statement};
+
+/// Where the actions of a statement come from
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq)]
+pub enum StatementSource {
+    /// Only the static analysis of the code requires them
+    StaticAnalysis,
+    /// Only the Access Analyzer policy grants them
+    AccessAnalyzer,
+    /// Both require them
+    Both,
+}
+
+/// Origin of a statement of the generated policies
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct StatementOrigin {
+    /// Index of the policy of the statement
+    pub policy_index: usize,
+    /// Index of the statement in its policy
+    pub statement_index: usize,
+    /// Sid of the statement, if it has one
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub sid: Option<String>,
+    /// Where the actions of the statement come from
+    pub source: StatementSource,
+}
+
+/// Actions of the static analysis and of an Access Analyzer policy, to label statements by
+#[derive(Debug, Clone)]
+pub(crate) struct AccessAnalyzerMerge {
+    /// Actions granted by the Access Analyzer policy
+    observed_actions: BTreeSet<String>,
+    /// Actions granted by the static analysis, before the merge
+    static_actions: BTreeSet<String>,
+}
+
+/// Regex matching the placeholders of Access Analyzer policies, e.g. `${BucketName}`
+static PLACEHOLDER_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_placeholder_regex() -> &'static Regex {
+    PLACEHOLDER_REGEX.get_or_init(|| Regex::new(r"\$\{[^}]*\}").expect("Invalid placeholder regex"))
+}
+
+/// Load the Allow statements of an Access Analyzer policy file
+///
+/// The file is the generated policy document, or the `GetGeneratedPolicy` response
+/// with the documents under `generatedPolicyResult.generatedPolicies[].policy`.
+pub(crate) fn load_access_analyzer_statements(path: &Path) -> Result<Vec<Statement>> {
+    let content = std::fs::read_to_string(path)
+        .map_err(|e| ExtractorError::file_system("read Access Analyzer policy", path, e))?;
+    let parse = |content: &str| {
+        serde_json::from_str::<Value>(content).map_err(|source| ExtractorError::JsonParsing {
+            context: format!("Access Analyzer policy {}", path.display()),
+            source,
+        })
+    };
+    let value = parse(&content)?;
+    let documents = match value["generatedPolicyResult"]["generatedPolicies"].as_array() {
+        Some(generated) => generated
+            .iter()
+            .filter_map(|generated| generated["policy"].as_str())
+            .map(parse)
+            .collect::<Result<Vec<_>>>()?,
+        None if value.get("Statement").is_some() => vec![value],
+        None => {
+            return Err(ExtractorError::policy_generation(format!(
+                "{} is neither a policy document nor a GetGeneratedPolicy response",
+                path.display()
+            )))
+        }
+    };
+
+    let strings = |value: &Value| -> Vec<String> {
+        match value {
+            Value::String(value) => vec![value.clone()],
+            Value::Array(values) => values
+                .iter()
+                .filter_map(|value| value.as_str().map(ToString::to_string))
+                .collect(),
+            _ => Vec::new(),
+        }
+    };
+    let mut statements = Vec::new();
+    for document in &documents {
+        let document_statements = match &document["Statement"] {
+            Value::Array(statements) => statements.clone(),
+            statement => vec![statement.clone()],
+        };
+        for statement in document_statements {
+            if statement["Effect"] != "Allow" {
+                continue;
+            }
+            let actions = strings(&statement["Action"]);
+            let mut resources: Vec<String> = strings(&statement["Resource"])
+                .iter()
+                .map(|resource| {
+                    get_placeholder_regex()
+                        .replace_all(resource, "*")
+                        .into_owned()
+                })
+                .collect();
+            resources.dedup();
+            if !actions.is_empty() {
+                statements.push(Statement::allow(actions, resources));
+            }
+        }
+    }
+    log::debug!(
+        "Loaded {} statements from Access Analyzer policy {}",
+        statements.len(),
+        path.display()
+    );
+    Ok(statements)
+}
+
+/// Add the actions of `observed` the static analysis didn't grant to `policies`
+///
+/// They're added as statements of their own to the policy of the principal running
+/// the code, which is created if there's none.
+pub(crate) fn merge_access_analyzer_statements(
+    policies: &mut Vec<PolicyWithMetadata>,
+    observed: Vec<Statement>,
+) -> AccessAnalyzerMerge {
+    let static_actions: BTreeSet<String> = policies
+        .iter()
+        .flat_map(|policy| allowed_actions(&policy.policy.statements))
+        .collect();
+    let observed_actions: BTreeSet<String> = allowed_actions(&observed).collect();
+
+    let added: Vec<Statement> = observed
+        .into_iter()
+        .filter_map(|mut statement| {
+            statement
+                .action
+                .retain(|action| !granted(&static_actions, action));
+            (!statement.action.is_empty()).then_some(statement)
+        })
+        .collect();
+    if !added.is_empty() {
+        let principal_policy = policies.iter_mut().find(|policy| {
+            policy.policy_type == PolicyType::Identity
+                && policy.is_workload_policy()
+                && policy.entry_point.is_none()
+        });
+        if let Some(policy) = principal_policy {
+            policy.policy.statements.extend(added);
+        } else {
+            let mut policy = IamPolicy::new();
+            for statement in added {
+                policy.add_statement(statement);
+            }
+            policies.push(PolicyWithMetadata {
+                policy,
+                policy_type: PolicyType::Identity,
+                assumed_role: None,
+                credential_profile: None,
+                entry_point: None,
+                named_principal: None,
+            });
+        }
+    }
+
+    AccessAnalyzerMerge {
+        observed_actions,
+        static_actions,
+    }
+}
+
+/// Origins of the statements of `policies`, once the statements are final
+pub(crate) fn statement_origins(
+    policies: &[PolicyWithMetadata],
+    merge: &AccessAnalyzerMerge,
+) -> Vec<StatementOrigin> {
+    let mut origins = Vec::new();
+    for (policy_index, policy) in policies.iter().enumerate() {
+        for (statement_index, statement) in policy.policy.statements.iter().enumerate() {
+            if statement.effect != Effect::Allow {
+                continue;
+            }
+            let in_static = statement
+                .action
+                .iter()
+                .all(|action| granted(&merge.static_actions, action));
+            let in_observed = statement
+                .action
+                .iter()
+                .all(|action| granted(&merge.observed_actions, action));
+            let source = match (in_static, in_observed) {
+                (true, true) => StatementSource::Both,
+                (false, true) => StatementSource::AccessAnalyzer,
+                _ => StatementSource::StaticAnalysis,
+            };
+            origins.push(StatementOrigin {
+                policy_index,
+                statement_index,
+                sid: statement.sid.clone(),
+                source,
+            });
+        }
+    }
+    origins
+}
+
+fn allowed_actions(statements: &[Statement]) -> impl Iterator<Item = String> + '_ {
+    statements
+        .iter()
+        .filter(|statement| statement.effect == Effect::Allow)
+        .flat_map(|statement| statement.action.iter().cloned())
+}
+
+/// Whether `action` is granted by one of `actions`, which may have wildcards
+fn granted(actions: &BTreeSet<String>, action: &str) -> bool {
+    actions.iter().any(|granted| {
+        granted.eq_ignore_ascii_case(action)
+            || (granted.contains('*')
+                && Regex::new(&format!(
+                    "(?i)^{}$",
+                    regex::escape(granted).replace(r"\*", ".*")
+                ))
+                .is_ok_and(|regex| regex.is_match(action)))
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use std::io::Write;
+
+    use super::*;
+
+    #[test]
+    fn test_access_analyzer_policy_merged() {
+        let generated_policy = serde_json::json!({
+            "Version": "2012-10-17",
+            "Statement": [{
+                "Effect": "Allow",
+                "Action": ["s3:GetObject", "sns:Publish"],
+                "Resource": "arn:aws:sns:us-east-1:123456789012:${TopicName}"
+            }]
+        });
+        let mut file = tempfile::NamedTempFile::new().unwrap();
+        write!(
+            file,
+            "{}",
+            serde_json::json!({
+                "generatedPolicyResult": {
+                    "generatedPolicies": [{ "policy": generated_policy.to_string() }]
+                }
+            })
+        )
+        .unwrap();
+        let observed = load_access_analyzer_statements(file.path()).unwrap();
+        assert_eq!(
+            observed[0].resource,
+            vec!["arn:aws:sns:us-east-1:123456789012:*"]
+        );
+
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["s3:GetObject".to_string(), "s3:PutObject".to_string()],
+            vec!["*".to_string()],
+        ));
+        let mut policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+
+        let merge = merge_access_analyzer_statements(&mut policies, observed);
+
+        assert_eq!(policies[0].policy.statements[1].action, vec!["sns:Publish"]);
+        assert_eq!(
+            statement_origins(&policies, &merge)
+                .iter()
+                .map(|origin| origin.source)
+                .collect::<Vec<_>>(),
+            vec![
+                StatementSource::StaticAnalysis,
+                StatementSource::AccessAnalyzer
+            ]
+        );
+    }
+}
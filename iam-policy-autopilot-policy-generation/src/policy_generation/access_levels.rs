@@ -0,0 +1,184 @@
+//! Summary of generated policies by IAM access level
+//!
+//! The access levels of the actions a policy grants on each service give reviewers a
+//! quick overview of its risk without reading every statement: List and Read actions
+//! disclose resources, Write and Tagging actions change them, and Permissions management
+//! actions change who can access them. A wildcard counts at the access level of every
+//! action it grants.
+
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+
+use serde::Serialize;
+
+use crate::policy_generation::{Effect, PolicyWithMetadata};
+
+/// IAM access level of an action, as annotated in the service reference
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq, PartialOrd, Ord, Hash)]
+pub enum AccessLevel {
+    /// Lists resources, e.g. `s3:ListBucket`
+    List,
+    /// Reads resources, e.g. `s3:GetObject`
+    Read,
+    /// Creates, changes or deletes resources, e.g. `s3:PutObject`
+    Write,
+    /// Only changes the tags of resources, e.g. `s3:PutObjectTagging`
+    Tagging,
+    /// Changes who can access resources, e.g. `s3:PutBucketPolicy`
+    PermissionsManagement,
+}
+
+impl AccessLevel {
+    /// Whether actions of the access level mutate resources or their permissions
+    #[must_use]
+    pub const fn is_write(self) -> bool {
+        !matches!(self, Self::List | Self::Read)
+    }
+}
+
+/// Actions the generated policies grant on a service, by access level
+#[derive(Debug, Clone, Default, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct ServiceAccessLevels {
+    /// Service prefix, e.g. `s3`
+    pub service: String,
+    /// List actions
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub list: Vec<String>,
+    /// Read actions
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub read: Vec<String>,
+    /// Write actions
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub write: Vec<String>,
+    /// Tagging actions
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub tagging: Vec<String>,
+    /// Permissions management actions
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub permissions_management: Vec<String>,
+    /// Actions of unknown access level, e.g. missing from the service reference
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub unknown: Vec<String>,
+}
+
+impl ServiceAccessLevels {
+    /// The actions of `level`
+    fn actions_mut(&mut self, level: AccessLevel) -> &mut Vec<String> {
+        match level {
+            AccessLevel::List => &mut self.list,
+            AccessLevel::Read => &mut self.read,
+            AccessLevel::Write => &mut self.write,
+            AccessLevel::Tagging => &mut self.tagging,
+            AccessLevel::PermissionsManagement => &mut self.permissions_management,
+        }
+    }
+}
+
+/// Summarize the actions the Allow statements of `policies` grant by service and access
+/// level, sorted by service
+///
+/// `access_levels` holds the access levels of the actions of the services the policies
+/// use, keyed by action, e.g. `s3:GetObject`.
+pub(crate) fn access_level_summary(
+    policies: &[PolicyWithMetadata],
+    access_levels: &HashMap<String, AccessLevel>,
+) -> Vec<ServiceAccessLevels> {
+    let actions: BTreeSet<&String> = policies
+        .iter()
+        .flat_map(|policy| &policy.policy.statements)
+        .filter(|statement| statement.effect == Effect::Allow)
+        .flat_map(|statement| &statement.action)
+        .collect();
+
+    let mut summary: BTreeMap<&str, ServiceAccessLevels> = BTreeMap::new();
+    for action in actions {
+        let service = action.split_once(':').map_or("*", |(service, _)| service);
+        let levels: BTreeSet<AccessLevel> = match access_levels.get(action.as_str()) {
+            Some(level) => BTreeSet::from([*level]),
+            None if action.contains('*') => access_levels
+                .iter()
+                .filter(|(known, _)| grants(action, known))
+                .map(|(_, level)| *level)
+                .collect(),
+            None => BTreeSet::new(),
+        };
+        let service_levels = summary
+            .entry(service)
+            .or_insert_with(|| ServiceAccessLevels {
+                service: service.to_string(),
+                ..ServiceAccessLevels::default()
+            });
+        if levels.is_empty() {
+            service_levels.unknown.push(action.clone());
+        }
+        for level in levels {
+            service_levels.actions_mut(level).push(action.clone());
+        }
+    }
+    summary.into_values().collect()
+}
+
+/// Whether the granted action, possibly with wildcards, grants `action`
+fn grants(granted: &str, action: &str) -> bool {
+    regex::Regex::new(&format!(
+        "(?i)^{}$",
+        regex::escape(granted).replace(r"\*", ".*")
+    ))
+    .is_ok_and(|regex| regex.is_match(action))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+
+    #[test]
+    fn test_access_level_summary() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec![
+                "s3:GetObject".to_string(),
+                "s3:Put*".to_string(),
+                "sqs:SendMessage".to_string(),
+            ],
+            vec!["*".to_string()],
+        ));
+        let policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+        let access_levels = HashMap::from([
+            ("s3:GetObject".to_string(), AccessLevel::Read),
+            ("s3:PutObject".to_string(), AccessLevel::Write),
+            (
+                "s3:PutBucketPolicy".to_string(),
+                AccessLevel::PermissionsManagement,
+            ),
+            ("s3:ListBucket".to_string(), AccessLevel::List),
+        ]);
+
+        let summary = access_level_summary(&policies, &access_levels);
+
+        assert_eq!(
+            summary,
+            vec![
+                ServiceAccessLevels {
+                    service: "s3".to_string(),
+                    read: vec!["s3:GetObject".to_string()],
+                    write: vec!["s3:Put*".to_string()],
+                    permissions_management: vec!["s3:Put*".to_string()],
+                    ..ServiceAccessLevels::default()
+                },
+                ServiceAccessLevels {
+                    service: "sqs".to_string(),
+                    unknown: vec!["sqs:SendMessage".to_string()],
+                    ..ServiceAccessLevels::default()
+                },
+            ]
+        );
+    }
+}
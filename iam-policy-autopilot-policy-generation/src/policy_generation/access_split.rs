@@ -0,0 +1,151 @@
+//! Split of generated policies into read-only and write policies
+//!
+//! Read access can often be granted broadly, while mutations warrant stricter controls
+//! such as a permissions boundary or a separately assumed role. Actions are classified
+//! by their IAM access level: List and Read actions are read-only, Write, Permissions
+//! management and Tagging actions mutate. Actions of unknown access level, including
+//! wildcards, count as writes, so the read-only policy never grants a mutation.
+
+use std::collections::HashSet;
+
+use crate::policy_generation::{IamPolicy, PolicyWithMetadata, Statement};
+
+/// Id of the policies granting the read-only actions
+const READ_POLICY_ID: &str = "IamPolicyAutopilotRead";
+
+/// Id of the policies granting the mutating actions
+const WRITE_POLICY_ID: &str = "IamPolicyAutopilotWrite";
+
+/// Split each of `policies` into a read-only and a write policy, in that order
+///
+/// `read_only_actions` holds the List and Read actions of the services the policies
+/// use, e.g. `s3:GetObject`. Statements granting both kinds of actions are split into
+/// two statements with the same resources and conditions. Policies left without
+/// statements are dropped.
+pub(crate) fn split_read_write(
+    policies: Vec<PolicyWithMetadata>,
+    read_only_actions: &HashSet<String>,
+) -> Vec<PolicyWithMetadata> {
+    let mut split = Vec::with_capacity(policies.len() * 2);
+    for policy in policies {
+        let mut read = IamPolicy::new();
+        read.id = READ_POLICY_ID.to_string();
+        let mut write = IamPolicy::new();
+        write.id = WRITE_POLICY_ID.to_string();
+        for statement in policy.policy.statements {
+            let (read_actions, write_actions): (Vec<String>, Vec<String>) = statement
+                .action
+                .iter()
+                .cloned()
+                .partition(|action| read_only_actions.contains(action));
+            if !read_actions.is_empty() {
+                read.add_statement(Statement {
+                    action: read_actions,
+                    ..statement.clone()
+                });
+            }
+            if !write_actions.is_empty() {
+                write.add_statement(Statement {
+                    action: write_actions,
+                    ..statement
+                });
+            }
+        }
+        for policy_part in [read, write] {
+            if !policy_part.statements.is_empty() {
+                split.push(PolicyWithMetadata {
+                    policy: policy_part,
+                    policy_type: policy.policy_type,
+                    assumed_role: policy.assumed_role.clone(),
+                    credential_profile: policy.credential_profile.clone(),
+                    entry_point: policy.entry_point.clone(),
+                    named_principal: policy.named_principal.clone(),
+                });
+            }
+        }
+    }
+    split
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::policy_generation::PolicyType;
+
+    fn strings(values: &[&str]) -> Vec<String> {
+        values.iter().map(ToString::to_string).collect()
+    }
+
+    #[test]
+    fn test_policies_split_into_read_and_write() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            strings(&["s3:GetObject", "s3:PutObject", "s3:Get*"]),
+            strings(&["arn:aws:s3:::reports/*"]),
+        ));
+        policy.add_statement(Statement::allow(
+            strings(&["s3:ListBucket"]),
+            strings(&["arn:aws:s3:::reports"]),
+        ));
+        let mut assumed = IamPolicy::new();
+        assumed.add_statement(Statement::allow(
+            strings(&["sqs:SendMessage"]),
+            strings(&["*"]),
+        ));
+        let policies = vec![
+            PolicyWithMetadata {
+                policy,
+                policy_type: PolicyType::Identity,
+                assumed_role: None,
+                credential_profile: None,
+                entry_point: None,
+                named_principal: None,
+            },
+            PolicyWithMetadata {
+                policy: assumed,
+                policy_type: PolicyType::Identity,
+                assumed_role: Some("arn:aws:iam::123456789012:role/Jobs".to_string()),
+                credential_profile: None,
+                entry_point: None,
+                named_principal: None,
+            },
+        ];
+        let read_only_actions =
+            HashSet::from(["s3:GetObject".to_string(), "s3:ListBucket".to_string()]);
+
+        let split = split_read_write(policies, &read_only_actions);
+
+        assert_eq!(
+            split
+                .iter()
+                .map(|policy| (
+                    policy.policy.id.as_str(),
+                    policy.assumed_role.is_some(),
+                    policy
+                        .policy
+                        .statements
+                        .iter()
+                        .map(|statement| statement.action.clone())
+                        .collect::<Vec<_>>()
+                ))
+                .collect::<Vec<_>>(),
+            vec![
+                (
+                    READ_POLICY_ID,
+                    false,
+                    vec![strings(&["s3:GetObject"]), strings(&["s3:ListBucket"])]
+                ),
+                (
+                    WRITE_POLICY_ID,
+                    false,
+                    vec![strings(&["s3:PutObject", "s3:Get*"])]
+                ),
+                (WRITE_POLICY_ID, true, vec![strings(&["sqs:SendMessage"])]),
+            ]
+        );
+        assert_eq!(
+            split[1].policy.statements[0].resource,
+            strings(&["arn:aws:s3:::reports/*"])
+        );
+    }
+}
@@ -0,0 +1,180 @@
+//! Compaction of enumerated actions into equivalent wildcards
+//!
+//! A statement granting `s3:GetObject`, `s3:GetObjectAcl`, `s3:GetObjectAttributes`,
+//! ... and every other action starting with `s3:GetObject` can grant `s3:GetObject*`
+//! instead. A wildcard is only used when each action of the service it matches is
+//! already granted, so compaction makes policies smaller without widening them.
+//! Wildcards end at a word of the action name, e.g. `GetObject*`, not `GetObj*`.
+
+use std::collections::{BTreeMap, HashMap};
+
+use crate::policy_generation::PolicyWithMetadata;
+
+/// Replace the actions of each statement with wildcards matching only granted actions
+///
+/// `service_actions` holds every action name (without the service prefix) of the
+/// services the policies use; actions of other services are left as they are.
+pub(crate) fn compact_actions(
+    policies: &mut [PolicyWithMetadata],
+    service_actions: &HashMap<String, Vec<String>>,
+) {
+    for statement in policies
+        .iter_mut()
+        .flat_map(|policy| &mut policy.policy.statements)
+    {
+        let mut by_service: BTreeMap<&str, Vec<&str>> = BTreeMap::new();
+        for action in &statement.action {
+            if let Some((service, name)) = action.split_once(':') {
+                by_service.entry(service).or_default().push(name);
+            }
+        }
+
+        let mut compacted = Vec::with_capacity(statement.action.len());
+        for action in &statement.action {
+            let Some((service, _)) = action.split_once(':') else {
+                compacted.push(action.clone());
+                continue;
+            };
+            let Some(names) = by_service.remove(service) else {
+                continue;
+            };
+            match service_actions.get(service) {
+                Some(all_names) => compacted.extend(
+                    compact_service_actions(&names, all_names)
+                        .into_iter()
+                        .map(|name| format!("{service}:{name}")),
+                ),
+                None => compacted.extend(names.iter().map(|name| format!("{service}:{name}"))),
+            }
+        }
+        compacted.sort();
+        compacted.dedup();
+
+        if compacted.len() < statement.action.len() {
+            log::debug!(
+                "Compacted {} actions into {}",
+                statement.action.len(),
+                compacted.len()
+            );
+            statement.action = compacted;
+        }
+    }
+}
+
+/// Compact the granted action `names` of one service with all its actions `all_names`
+fn compact_service_actions(names: &[&str], all_names: &[String]) -> Vec<String> {
+    // IAM matches action names ignoring case
+    let granted: Vec<String> = names.iter().map(|name| name.to_lowercase()).collect();
+    let all: Vec<String> = all_names.iter().map(|name| name.to_lowercase()).collect();
+    let is_granted = |name: &String| granted.contains(name);
+
+    let mut covered = vec![false; names.len()];
+    let mut result = Vec::new();
+    for (index, name) in names.iter().enumerate() {
+        if covered[index] {
+            continue;
+        }
+        // The shortest prefix whose matching actions are all granted covers the most
+        let wildcard = word_prefixes(name).find_map(|prefix| {
+            let lower = prefix.to_lowercase();
+            let matching: Vec<usize> = granted
+                .iter()
+                .enumerate()
+                .filter(|(_, granted)| granted.starts_with(&lower))
+                .map(|(other, _)| other)
+                .collect();
+            (matching.len() > 1
+                && all
+                    .iter()
+                    .filter(|name| name.starts_with(&lower))
+                    .all(is_granted))
+            .then_some((prefix, matching))
+        });
+        match wildcard {
+            Some((prefix, matching)) => {
+                for other in matching {
+                    covered[other] = true;
+                }
+                result.push(format!("{prefix}*"));
+            }
+            None => result.push((*name).to_string()),
+        }
+    }
+    result
+}
+
+/// Prefixes of a CamelCase action name ending at a word, shortest first:
+/// `GetObjectAcl` has `Get`, `GetObject` and `GetObjectAcl`
+fn word_prefixes(name: &str) -> impl Iterator<Item = &str> {
+    name.char_indices()
+        .skip(1)
+        .filter(|(_, c)| c.is_ascii_uppercase())
+        .map(|(index, _)| &name[..index])
+        .chain(std::iter::once(name))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+
+    fn strings(values: &[&str]) -> Vec<String> {
+        values.iter().map(ToString::to_string).collect()
+    }
+
+    #[test]
+    fn test_actions_compact_only_when_every_match_is_granted() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            strings(&[
+                "s3:GetObject",
+                "s3:GetObjectAcl",
+                "s3:GetObjectTagging",
+                "s3:PutObject",
+                "s3:PutObjectAcl",
+                "sqs:SendMessage",
+            ]),
+            strings(&["*"]),
+        ));
+        let mut policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+        let service_actions = HashMap::from([(
+            "s3".to_string(),
+            strings(&[
+                "GetBucketAcl",
+                "GetObject",
+                "GetObjectAcl",
+                "GetObjectTagging",
+                "PutObject",
+                "PutObjectAcl",
+                "PutObjectLegalHold",
+            ]),
+        )]);
+
+        compact_actions(&mut policies, &service_actions);
+
+        assert_eq!(
+            policies[0].policy.statements[0].action,
+            strings(&[
+                "s3:GetObject*",
+                "s3:PutObject",
+                "s3:PutObjectAcl",
+                "sqs:SendMessage",
+            ])
+        );
+    }
+
+    #[test]
+    fn test_word_prefixes() {
+        assert_eq!(
+            word_prefixes("GetObjectAcl").collect::<Vec<_>>(),
+            vec!["Get", "GetObject", "GetObjectAcl"]
+        );
+    }
+}
@@ -0,0 +1,179 @@
+//! Actions stripped from the generated policies by the action filters
+//!
+//! The analysis grants what the code calls, but an organization may forbid some actions
+//! outright, e.g. `iam:CreateUser`, or only approve a list of them. The filters apply to the
+//! final policies, whatever input their actions come from, and every stripped action is
+//! reported with the filter stripping it, so a pipeline can fail on them instead.
+
+use serde::Serialize;
+
+use crate::api::model::ActionFilters;
+use crate::policy_generation::{Effect, PolicyWithMetadata};
+
+/// Why an action was stripped from the policies
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq)]
+pub enum FilterReason {
+    /// The action is granted by or grants an action of the denylist
+    Denied,
+    /// No action of the allowlist grants the action
+    NotAllowed,
+}
+
+/// An action the action filters stripped from a statement
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct FilteredAction {
+    /// Sid of the statement
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub sid: Option<String>,
+    /// The stripped action, e.g. `iam:CreateUser`, or the wildcard granting it
+    pub action: String,
+    /// Resources of the statement
+    pub resources: Vec<String>,
+    /// Why the action was stripped
+    pub reason: FilterReason,
+    /// Human-readable explanation of the reason, naming the matching denylist action
+    pub description: String,
+}
+
+/// Strip the actions of the Allow statements of `policies` that `filters` don't let them
+/// grant, in statement order
+///
+/// A wildcard action granting a denied action is stripped whole, as it can't be narrowed
+/// without the service's action list. Statements left without actions are dropped, and
+/// policies left without statements too.
+pub(crate) fn filter_actions(
+    policies: &mut Vec<PolicyWithMetadata>,
+    filters: &ActionFilters,
+) -> Vec<FilteredAction> {
+    let mut filtered = Vec::new();
+    for policy in policies.iter_mut() {
+        for statement in &mut policy.policy.statements {
+            if statement.effect != Effect::Allow {
+                continue;
+            }
+            let (sid, resources) = (&statement.sid, &statement.resource);
+            statement.action.retain(|action| {
+                let (reason, description) = if let Some(denied) = filters
+                    .deny
+                    .iter()
+                    .find(|denied| grants(denied, action) || grants(action, denied))
+                {
+                    (FilterReason::Denied, format!("Denied by {denied}"))
+                } else if !filters.allow.is_empty()
+                    && !filters.allow.iter().any(|allowed| grants(allowed, action))
+                {
+                    (
+                        FilterReason::NotAllowed,
+                        "Not granted by any allowed action".to_string(),
+                    )
+                } else {
+                    return true;
+                };
+                log::debug!("Stripping {action} from the policies: {description}");
+                filtered.push(FilteredAction {
+                    sid: sid.clone(),
+                    action: action.clone(),
+                    resources: resources.clone(),
+                    reason,
+                    description,
+                });
+                false
+            });
+        }
+        policy
+            .policy
+            .statements
+            .retain(|statement| !statement.action.is_empty());
+    }
+    policies.retain(|policy| !policy.policy.statements.is_empty());
+    filtered
+}
+
+/// Whether the granted action, possibly with wildcards, grants `action`
+fn grants(granted: &str, action: &str) -> bool {
+    granted.eq_ignore_ascii_case(action)
+        || (granted.contains('*')
+            && regex::Regex::new(&format!(
+                "(?i)^{}$",
+                regex::escape(granted).replace(r"\*", ".*")
+            ))
+            .is_ok_and(|regex| regex.is_match(action)))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+
+    fn policy(statements: &[&[&str]]) -> PolicyWithMetadata {
+        let mut policy = IamPolicy::new();
+        for actions in statements {
+            policy.add_statement(Statement::allow(
+                actions.iter().map(ToString::to_string).collect(),
+                vec!["*".to_string()],
+            ));
+        }
+        PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }
+    }
+
+    fn actions(policies: &[PolicyWithMetadata]) -> Vec<Vec<&str>> {
+        policies
+            .iter()
+            .flat_map(|policy| &policy.policy.statements)
+            .map(|statement| statement.action.iter().map(String::as_str).collect())
+            .collect()
+    }
+
+    #[test]
+    fn test_denied_actions_are_stripped() {
+        let mut policies = vec![
+            policy(&[&["iam:CreateUser", "iam:GetUser"], &["s3:*"]]),
+            policy(&[&["iam:CreateAccessKey"]]),
+        ];
+        let filters = ActionFilters {
+            deny: vec!["iam:Create*".to_string(), "s3:DeleteBucket".to_string()],
+            allow: vec![],
+        };
+
+        let filtered = filter_actions(&mut policies, &filters);
+
+        assert_eq!(actions(&policies), vec![vec!["iam:GetUser"]]);
+        let stripped: Vec<(&str, &str)> = filtered
+            .iter()
+            .map(|action| (action.action.as_str(), action.description.as_str()))
+            .collect();
+        assert_eq!(
+            stripped,
+            vec![
+                ("iam:CreateUser", "Denied by iam:Create*"),
+                ("s3:*", "Denied by s3:DeleteBucket"),
+                ("iam:CreateAccessKey", "Denied by iam:Create*"),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_actions_outside_the_allowlist_are_stripped() {
+        let mut policies = vec![policy(&[&["s3:GetObject", "s3:PutObject", "sqs:*"]])];
+        let filters = ActionFilters {
+            deny: vec![],
+            allow: vec!["s3:Get*".to_string(), "sqs:SendMessage".to_string()],
+        };
+
+        let filtered = filter_actions(&mut policies, &filters);
+
+        assert_eq!(actions(&policies), vec![vec!["s3:GetObject"]]);
+        assert!(filtered
+            .iter()
+            .all(|action| action.reason == FilterReason::NotAllowed));
+        assert_eq!(filtered.len(), 2);
+    }
+}
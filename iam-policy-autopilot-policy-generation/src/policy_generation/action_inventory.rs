@@ -0,0 +1,174 @@
+//! Inventory of the generated actions, one entry per action, resource and call site
+//!
+//! Audit processes track the permissions of a workload in spreadsheets and GRC tooling,
+//! which take flat rows rather than policy documents. The inventory flattens the
+//! [provenance](super::provenance) of the generated actions into such rows, each with the
+//! confidence of the service of its call.
+
+use std::collections::HashMap;
+use std::path::PathBuf;
+
+use serde::Serialize;
+
+use crate::api::model::CallConfidence;
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::extraction::shared::ConfidenceEvidence;
+use crate::policy_generation::provenance::action_provenance;
+use crate::policy_generation::PolicyWithMetadata;
+use crate::Location;
+
+/// A generated action on a resource, and a call requiring it
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct InventoriedAction {
+    /// Service of the action, e.g. `s3`
+    pub service: String,
+    /// The granted action, e.g. `s3:GetObject`, or the wildcard granting the call's actions
+    pub action: String,
+    /// Resource the action is granted on
+    pub resource: String,
+    /// Source file of the call, `None` for actions of other inputs, such as an Access
+    /// Analyzer policy
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub file: Option<PathBuf>,
+    /// Line of the call (1-based)
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub line: Option<usize>,
+    /// How certain the service of the call is
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub confidence: Option<CallConfidence>,
+}
+
+/// Inventory of the actions the Allow statements of `policies` grant, sorted by action,
+/// resource and call site
+///
+/// A call site of several calls, e.g. a chained call, has the lowest confidence of them.
+pub(crate) fn action_inventory(
+    policies: &[PolicyWithMetadata],
+    enriched_calls: &[EnrichedSdkMethodCall<'_>],
+    evidence: &ConfidenceEvidence<'_>,
+) -> Vec<InventoriedAction> {
+    let mut confidences: HashMap<&Location, CallConfidence> = HashMap::new();
+    for call in enriched_calls {
+        if let Some(metadata) = &call.sdk_method_call.metadata {
+            let confidence = evidence.confidence(call.sdk_method_call);
+            confidences
+                .entry(&metadata.location)
+                .and_modify(|lowest| *lowest = (*lowest).min(confidence))
+                .or_insert(confidence);
+        }
+    }
+
+    let mut inventory = Vec::new();
+    for provenance in action_provenance(policies, enriched_calls) {
+        let service = provenance
+            .action
+            .split_once(':')
+            .map_or(provenance.action.as_str(), |(service, _)| service);
+        for resource in &provenance.resources {
+            let entry = |location: Option<&Location>| InventoriedAction {
+                service: service.to_string(),
+                action: provenance.action.clone(),
+                resource: resource.clone(),
+                file: location.map(|location| location.file_path.clone()),
+                line: location.map(Location::start_line),
+                confidence: location.and_then(|location| confidences.get(location).copied()),
+            };
+            if provenance.calls.is_empty() {
+                inventory.push(entry(None));
+            }
+            inventory.extend(
+                provenance
+                    .calls
+                    .iter()
+                    .map(|call| entry(Some(&call.location))),
+            );
+        }
+    }
+    inventory
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::enrichment::{Action, Explanation};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+    use crate::SdkMethodCall;
+
+    #[test]
+    fn test_action_inventory() {
+        let sdk_call = SdkMethodCall {
+            name: "get_object".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "s3.get_object(Bucket='reports', Key=key)".to_string(),
+                Location::new(PathBuf::from("app.py"), (14, 5), (14, 45)),
+            )),
+        };
+        let calls = vec![EnrichedSdkMethodCall {
+            method_name: "get_object".to_string(),
+            service: "s3".to_string(),
+            actions: vec![Action::new(
+                "s3:GetObject".to_string(),
+                vec![],
+                vec![],
+                Explanation::default(),
+            )],
+            sdk_method_call: &sdk_call,
+        }];
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["s3:GetObject".to_string()],
+            vec![
+                "arn:aws:s3:::reports/*".to_string(),
+                "arn:aws:s3:::archive/*".to_string(),
+            ],
+        ));
+        policy.add_statement(Statement::allow(
+            vec!["kms:Decrypt".to_string()],
+            vec!["*".to_string()],
+        ));
+        let policies = [PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+
+        let inventory = action_inventory(&policies, &calls, &ConfidenceEvidence::default());
+
+        let rows: Vec<(&str, &str, Option<usize>, Option<CallConfidence>)> = inventory
+            .iter()
+            .map(|entry| {
+                (
+                    entry.action.as_str(),
+                    entry.resource.as_str(),
+                    entry.line,
+                    entry.confidence,
+                )
+            })
+            .collect();
+        assert_eq!(
+            rows,
+            vec![
+                ("kms:Decrypt", "*", None, None),
+                (
+                    "s3:GetObject",
+                    "arn:aws:s3:::archive/*",
+                    Some(14),
+                    Some(CallConfidence::Medium)
+                ),
+                (
+                    "s3:GetObject",
+                    "arn:aws:s3:::reports/*",
+                    Some(14),
+                    Some(CallConfidence::Medium)
+                ),
+            ]
+        );
+        assert_eq!(inventory[1].service, "s3");
+    }
+}
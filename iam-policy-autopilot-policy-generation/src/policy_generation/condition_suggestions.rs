@@ -0,0 +1,300 @@
+//! Suggestions of condition keys narrowing generated statements
+//!
+//! Some restrictions can't be derived from the calls alone, but the analysis knows
+//! where they apply: a `ListBucket` statement can be limited to the key prefixes the
+//! code lists with `s3:prefix`, DynamoDB item access to partition keys with
+//! `dynamodb:LeadingKeys`. Suggestions name the statement, the condition and the
+//! values known from the code, for reviewers to add where they fit.
+
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+
+use serde::Serialize;
+
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::extraction::{Parameter, ParameterValue};
+use crate::policy_generation::{PolicyWithMetadata, Statement};
+
+/// Actions listing the keys of a bucket, which `s3:prefix` narrows
+const LIST_BUCKET_ACTIONS: &[&str] = &["s3:ListBucket", "s3:ListBucketVersions"];
+
+/// A condition key that could narrow a generated statement
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct ConditionKeySuggestion {
+    /// Index of the policy in the result
+    pub policy_index: usize,
+    /// Sid of the statement
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub sid: Option<String>,
+    /// Actions of the statement the condition applies to
+    pub actions: Vec<String>,
+    /// Condition key, e.g. `s3:prefix`
+    pub condition_key: String,
+    /// Condition operator, e.g. `StringLike`
+    pub operator: String,
+    /// Values known from the code; empty when they have to be filled in
+    pub values: Vec<String>,
+    /// What the condition restricts
+    pub description: String,
+}
+
+/// Suggest condition keys for the statements of `policies`, in statement order
+///
+/// `action_condition_keys` holds the condition keys the Service Reference lists for
+/// each action, e.g. `dynamodb:GetItem` to `dynamodb:LeadingKeys`. `account` fills in
+/// the `aws:ResourceAccount` of resources in any account.
+pub(crate) fn suggest_condition_keys(
+    policies: &[PolicyWithMetadata],
+    enriched_calls: &[EnrichedSdkMethodCall<'_>],
+    action_condition_keys: &HashMap<String, Vec<String>>,
+    account: &str,
+) -> Vec<ConditionKeySuggestion> {
+    let prefixes = listed_prefixes(enriched_calls);
+
+    let mut suggestions = Vec::new();
+    for (policy_index, policy) in policies.iter().enumerate() {
+        for statement in &policy.policy.statements {
+            let suggest =
+                |condition_key: &str, operator: &str, values: Vec<String>, description: &str| {
+                    ConditionKeySuggestion {
+                        policy_index,
+                        sid: statement.sid.clone(),
+                        actions: statement.action.clone(),
+                        condition_key: condition_key.to_string(),
+                        operator: operator.to_string(),
+                        values,
+                        description: description.to_string(),
+                    }
+                };
+
+            let values = s3_prefixes(statement, &prefixes);
+            if !values.is_empty() {
+                suggestions.push(suggest(
+                    "s3:prefix",
+                    "StringLike",
+                    values,
+                    "Limits listing to the key prefixes the code lists",
+                ));
+            }
+            if supports_condition_key(statement, action_condition_keys, "dynamodb:LeadingKeys") {
+                suggestions.push(suggest(
+                    "dynamodb:LeadingKeys",
+                    "ForAllValues:StringEquals",
+                    vec![],
+                    "Limits item access to the partition key values of the principal, e.g. \
+                     ${aws:PrincipalTag/TenantId} for per-tenant tables",
+                ));
+            }
+            if statement.resource.iter().any(|arn| has_any_account(arn)) {
+                let values = if is_account_id(account) {
+                    vec![account.to_string()]
+                } else {
+                    vec![]
+                };
+                suggestions.push(suggest(
+                    "aws:ResourceAccount",
+                    "StringEquals",
+                    values,
+                    "Limits the resources of any account to those of the workload's account",
+                ));
+            }
+        }
+    }
+    suggestions
+}
+
+/// Literal `Prefix` arguments of calls listing bucket keys, by bucket (`*` if unknown)
+fn listed_prefixes<'a>(
+    enriched_calls: &'a [EnrichedSdkMethodCall<'_>],
+) -> BTreeMap<&'a str, BTreeSet<&'a str>> {
+    let mut prefixes: BTreeMap<&str, BTreeSet<&str>> = BTreeMap::new();
+    for call in enriched_calls {
+        if !call
+            .actions
+            .iter()
+            .any(|action| LIST_BUCKET_ACTIONS.contains(&action.name.as_str()))
+        {
+            continue;
+        }
+        let Some(metadata) = &call.sdk_method_call.metadata else {
+            continue;
+        };
+        let prefix = metadata
+            .parameters
+            .iter()
+            .find_map(|parameter| match parameter {
+                Parameter::Keyword {
+                    name,
+                    value: ParameterValue::Resolved(value),
+                    ..
+                } if name.eq_ignore_ascii_case("Prefix") => Some(value.as_str()),
+                _ => None,
+            });
+        if let Some(prefix) = prefix {
+            let bucket = metadata
+                .resource_bindings
+                .get("BucketName")
+                .map_or("*", String::as_str);
+            prefixes.entry(bucket).or_default().insert(prefix);
+        }
+    }
+    prefixes
+}
+
+/// `s3:prefix` values of a statement listing buckets the code lists with prefixes
+fn s3_prefixes(statement: &Statement, prefixes: &BTreeMap<&str, BTreeSet<&str>>) -> Vec<String> {
+    if !statement
+        .action
+        .iter()
+        .any(|action| LIST_BUCKET_ACTIONS.contains(&action.as_str()))
+    {
+        return vec![];
+    }
+    let mut values = BTreeSet::new();
+    for resource in &statement.resource {
+        let bucket = resource
+            .splitn(6, ':')
+            .nth(5)
+            .filter(|_| resource.split(':').nth(2) == Some("s3"))
+            .unwrap_or("*");
+        for (listed_bucket, listed) in prefixes {
+            if *listed_bucket == bucket || *listed_bucket == "*" || bucket == "*" {
+                values.extend(listed.iter().map(|prefix| format!("{prefix}*")));
+            }
+        }
+    }
+    values.into_iter().collect()
+}
+
+/// Whether every action of `statement` supports `condition_key`, which it doesn't use yet
+fn supports_condition_key(
+    statement: &Statement,
+    action_condition_keys: &HashMap<String, Vec<String>>,
+    condition_key: &str,
+) -> bool {
+    statement
+        .condition
+        .iter()
+        .all(|condition| condition.key != condition_key)
+        && statement.action.iter().all(|action| {
+            action_condition_keys
+                .get(action)
+                .is_some_and(|keys| keys.iter().any(|key| key == condition_key))
+        })
+}
+
+/// Whether `arn` matches resources of any account, e.g. `arn:aws:sqs:us-east-1:*:jobs`
+fn has_any_account(arn: &str) -> bool {
+    arn.split(':')
+        .nth(4)
+        .is_some_and(|account| account.contains('*'))
+}
+
+fn is_account_id(account: &str) -> bool {
+    account.len() == 12 && account.bytes().all(|b| b.is_ascii_digit())
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::policy_generation::{IamPolicy, PolicyType};
+    use crate::{Location, SdkMethodCall};
+
+    #[test]
+    fn test_condition_keys_suggested_for_statements() {
+        let mut metadata = SdkMethodCallMetadata::new(
+            "s3.list_objects_v2(Bucket=\"reports\", Prefix=\"daily/\")".to_string(),
+            Location::new(PathBuf::from("app.py"), (1, 1), (1, 50)),
+        )
+        .with_parameters(vec![Parameter::Keyword {
+            name: "Prefix".to_string(),
+            value: ParameterValue::Resolved("daily/".to_string()),
+            position: 1,
+            type_annotation: None,
+        }]);
+        metadata
+            .resource_bindings
+            .insert("BucketName".to_string(), "reports".to_string());
+        let sdk_call = SdkMethodCall {
+            name: "list_objects_v2".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(metadata),
+        };
+        let calls = vec![EnrichedSdkMethodCall {
+            method_name: "list_objects_v2".to_string(),
+            service: "s3".to_string(),
+            actions: vec![Action::new(
+                "s3:ListBucket".to_string(),
+                vec![],
+                vec![],
+                Explanation::default(),
+            )],
+            sdk_method_call: &sdk_call,
+        }];
+
+        let mut policy = IamPolicy::new();
+        policy.add_statement(
+            Statement::allow(
+                vec!["s3:ListBucket".to_string()],
+                vec!["arn:aws:s3:::reports".to_string()],
+            )
+            .with_sid("S3BucketRead".to_string()),
+        );
+        policy.add_statement(Statement::allow(
+            vec!["dynamodb:GetItem".to_string(), "dynamodb:Query".to_string()],
+            vec!["arn:aws:dynamodb:us-east-1:*:table/Orders".to_string()],
+        ));
+        let policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+        let action_condition_keys = HashMap::from([
+            (
+                "dynamodb:GetItem".to_string(),
+                vec!["dynamodb:LeadingKeys".to_string()],
+            ),
+            (
+                "dynamodb:Query".to_string(),
+                vec![
+                    "dynamodb:LeadingKeys".to_string(),
+                    "dynamodb:Select".to_string(),
+                ],
+            ),
+        ]);
+
+        let suggestions =
+            suggest_condition_keys(&policies, &calls, &action_condition_keys, "123456789012");
+
+        assert_eq!(
+            suggestions
+                .iter()
+                .map(|suggestion| (
+                    suggestion.sid.as_deref(),
+                    suggestion.condition_key.as_str(),
+                    suggestion.values.clone()
+                ))
+                .collect::<Vec<_>>(),
+            vec![
+                (
+                    Some("S3BucketRead"),
+                    "s3:prefix",
+                    vec!["daily/*".to_string()]
+                ),
+                (None, "dynamodb:LeadingKeys", vec![]),
+                (
+                    None,
+                    "aws:ResourceAccount",
+                    vec!["123456789012".to_string()]
+                ),
+            ]
+        );
+    }
+}
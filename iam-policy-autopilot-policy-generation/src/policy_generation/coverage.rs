@@ -0,0 +1,274 @@
+//! Coverage of the AWS SDK calls found in the analyzed code
+//!
+//! Each call site is counted once, as resolved when the policies grant its actions with
+//! its service and resources known, partially resolved when they grant them in every
+//! service the call may be made on or on resources widened to `*`, and skipped when the
+//! policies grant nothing for it: calls excluded by annotations or below the minimum
+//! confidence, operations the Service Reference doesn't know, and client methods named at
+//! runtime. The counts tell how much of the code a generated policy accounts for.
+
+use std::collections::{BTreeMap, HashMap};
+use std::path::{Path, PathBuf};
+
+use serde::Serialize;
+
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::extraction::SourceFile;
+use crate::{Language, Location, SdkMethodCall};
+
+/// How completely the analysis resolved a call site
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub(crate) enum CallResolution {
+    /// The actions are granted in the call's service, on the resources it's made on
+    Resolved,
+    /// The actions are granted in several possible services, or on widened resources
+    PartiallyResolved,
+    /// No actions are granted for the call
+    Skipped,
+}
+
+/// Numbers of call sites by how completely they were resolved
+#[derive(Debug, Clone, Default, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct CoverageCounts {
+    /// AWS SDK call sites found
+    pub call_sites: usize,
+    /// Call sites whose service and resources are known
+    pub resolved: usize,
+    /// Call sites granted in several possible services or on widened resources
+    pub partially_resolved: usize,
+    /// Call sites the policies grant nothing for
+    pub skipped: usize,
+}
+
+impl CoverageCounts {
+    fn add(&mut self, resolution: CallResolution) {
+        self.call_sites += 1;
+        match resolution {
+            CallResolution::Resolved => self.resolved += 1,
+            CallResolution::PartiallyResolved => self.partially_resolved += 1,
+            CallResolution::Skipped => self.skipped += 1,
+        }
+    }
+}
+
+/// Coverage of the call sites of a language
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct LanguageCoverage {
+    pub language: Language,
+    #[serde(flatten)]
+    pub counts: CoverageCounts,
+}
+
+/// Coverage of the call sites of a source file
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct FileCoverage {
+    pub file: PathBuf,
+    /// Language of the file, unless it isn't one of the analyzed source files, like a Go
+    /// binary
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub language: Option<Language>,
+    #[serde(flatten)]
+    pub counts: CoverageCounts,
+}
+
+/// Coverage of the call sites of a run, in total, by language and by file
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct CoverageReport {
+    #[serde(flatten)]
+    pub total: CoverageCounts,
+    /// Coverage by language, in language name order
+    pub languages: Vec<LanguageCoverage>,
+    /// Coverage by file, in path order
+    pub files: Vec<FileCoverage>,
+}
+
+/// Coverage of the call sites of `methods`, the calls the policies were generated for, and
+/// of the `skipped` call sites, which were left out before
+///
+/// Calls without a source location aren't call sites of the code, so they aren't counted.
+pub(crate) fn coverage_report(
+    source_files: &[SourceFile],
+    methods: &[SdkMethodCall],
+    enriched_calls: &[EnrichedSdkMethodCall<'_>],
+    skipped: &[&Path],
+) -> CoverageReport {
+    let mut enriched_by_call: HashMap<(&Location, &str), Vec<&EnrichedSdkMethodCall<'_>>> =
+        HashMap::new();
+    for enriched in enriched_calls {
+        if let Some(metadata) = &enriched.sdk_method_call.metadata {
+            enriched_by_call
+                .entry((&metadata.location, enriched.sdk_method_call.name.as_str()))
+                .or_default()
+                .push(enriched);
+        }
+    }
+
+    let mut resolutions: Vec<(&Path, CallResolution)> = methods
+        .iter()
+        .filter_map(|method| {
+            let metadata = method.metadata.as_ref()?;
+            let enriched = enriched_by_call
+                .get(&(&metadata.location, method.name.as_str()))
+                .map_or(&[][..], Vec::as_slice);
+            Some((
+                metadata.location.file_path.as_path(),
+                call_resolution(method, enriched),
+            ))
+        })
+        .collect();
+    resolutions.extend(skipped.iter().map(|file| (*file, CallResolution::Skipped)));
+
+    let languages: HashMap<&Path, Language> = source_files
+        .iter()
+        .map(|source_file| (source_file.path.as_path(), source_file.language))
+        .collect();
+    let mut total = CoverageCounts::default();
+    let mut by_language: BTreeMap<String, LanguageCoverage> = BTreeMap::new();
+    let mut by_file: BTreeMap<&Path, FileCoverage> = BTreeMap::new();
+    for (file, resolution) in resolutions {
+        let language = languages.get(file).copied();
+        total.add(resolution);
+        if let Some(language) = language {
+            by_language
+                .entry(language.to_string())
+                .or_insert_with(|| LanguageCoverage {
+                    language,
+                    counts: CoverageCounts::default(),
+                })
+                .counts
+                .add(resolution);
+        }
+        by_file
+            .entry(file)
+            .or_insert_with(|| FileCoverage {
+                file: file.to_path_buf(),
+                language,
+                counts: CoverageCounts::default(),
+            })
+            .counts
+            .add(resolution);
+    }
+
+    CoverageReport {
+        total,
+        languages: by_language.into_values().collect(),
+        files: by_file.into_values().collect(),
+    }
+}
+
+/// How completely `method` was resolved, given the `enriched` calls it became
+fn call_resolution(
+    method: &SdkMethodCall,
+    enriched: &[&EnrichedSdkMethodCall<'_>],
+) -> CallResolution {
+    let actions: Vec<_> = enriched.iter().flat_map(|call| &call.actions).collect();
+    if actions.is_empty() {
+        return CallResolution::Skipped;
+    }
+    let widened = actions.iter().any(|action| {
+        action
+            .resources
+            .iter()
+            .any(|resource| resource.arn_patterns.is_none())
+    });
+    if method.possible_services.len() > 1 || widened {
+        CallResolution::PartiallyResolved
+    } else {
+        CallResolution::Resolved
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::enrichment::{Action, Explanation, Resource};
+    use crate::extraction::SdkMethodCallMetadata;
+
+    fn method(name: &str, services: &[&str], file: &str, line: usize) -> SdkMethodCall {
+        SdkMethodCall {
+            name: name.to_string(),
+            possible_services: services.iter().map(ToString::to_string).collect(),
+            metadata: Some(SdkMethodCallMetadata::new(
+                format!("client.{name}()"),
+                Location::new(PathBuf::from(file), (line, 1), (line, 20)),
+            )),
+        }
+    }
+
+    fn enriched<'a>(
+        method: &'a SdkMethodCall,
+        action: &str,
+        arn_patterns: Option<Vec<String>>,
+    ) -> EnrichedSdkMethodCall<'a> {
+        EnrichedSdkMethodCall {
+            method_name: method.name.clone(),
+            service: method.possible_services[0].clone(),
+            actions: vec![Action::new(
+                action.to_string(),
+                vec![Resource::new("bucket".to_string(), arn_patterns)],
+                vec![],
+                Explanation::default(),
+            )],
+            sdk_method_call: method,
+        }
+    }
+
+    #[test]
+    fn test_coverage_report() {
+        let source_files = vec![SourceFile::with_language(
+            PathBuf::from("app.py"),
+            String::new(),
+            Language::Python,
+        )];
+        let methods = vec![
+            method("get_object", &["s3"], "app.py", 3),
+            method("list_tags", &["kms", "lambda"], "app.py", 4),
+            method("put_object", &["s3"], "app.py", 5),
+            method("describe_things", &["s3"], "jobs.py", 2),
+        ];
+        let enriched_calls = vec![
+            enriched(
+                &methods[0],
+                "s3:GetObject",
+                Some(vec!["arn:aws:s3:::reports".to_string()]),
+            ),
+            enriched(&methods[1], "kms:ListResourceTags", Some(vec![])),
+            enriched(&methods[2], "s3:PutObject", None),
+        ];
+
+        let report = coverage_report(
+            &source_files,
+            &methods,
+            &enriched_calls,
+            &[Path::new("app.py")],
+        );
+
+        assert_eq!(
+            report.total,
+            CoverageCounts {
+                call_sites: 5,
+                resolved: 1,
+                partially_resolved: 2,
+                skipped: 2,
+            }
+        );
+        assert_eq!(report.languages.len(), 1);
+        assert_eq!(report.languages[0].language, Language::Python);
+        assert_eq!(report.languages[0].counts.call_sites, 4);
+        assert_eq!(
+            report
+                .files
+                .iter()
+                .map(|file| (file.file.as_path(), file.language, file.counts.call_sites))
+                .collect::<Vec<_>>(),
+            vec![
+                (Path::new("app.py"), Some(Language::Python), 4),
+                (Path::new("jobs.py"), None, 1),
+            ]
+        );
+    }
+}
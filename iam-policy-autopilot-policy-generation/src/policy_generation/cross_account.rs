@@ -0,0 +1,272 @@
+//! Report of calls on resources of other accounts
+//!
+//! An identity policy only grants access to resources of its own account. A call naming
+//! the literal ARN of a resource in another account also needs the target account to
+//! allow it: the resource's own policy, e.g. a queue or key policy, or the trust policy of
+//! a role assumed there. Services without resource-based policies can't be reached from
+//! another account at all; their calls have to be made with the credentials of a role in
+//! the target account, so their statements in the workload's policies would never work.
+
+use std::sync::OnceLock;
+
+use regex::Regex;
+use serde::Serialize;
+
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::Location;
+
+/// Services whose resources have resource-based policies allowing principals of other
+/// accounts
+const RESOURCE_POLICY_SERVICES: &[&str] = &[
+    "backup",
+    "dynamodb",
+    "ecr",
+    "es",
+    "events",
+    "glacier",
+    "glue",
+    "kinesis",
+    "kms",
+    "lambda",
+    "logs",
+    "s3",
+    "secretsmanager",
+    "sns",
+    "sqs",
+];
+
+/// Actions assuming a role, authorized by the role's trust policy as well
+const ASSUME_ROLE_ACTIONS: &[&str] = &["sts:AssumeRole", "sts:TagSession"];
+
+/// Regex matching literal ARNs of resources in an account
+static ACCOUNT_ARN_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn account_arn_regex() -> &'static Regex {
+    ACCOUNT_ARN_REGEX.get_or_init(|| {
+        Regex::new(r"arn:aws[a-z-]*:([a-z0-9-]+):[a-z0-9-]*:(\d{12}):[A-Za-z0-9_.:/+=@-]+")
+            .expect("Invalid account ARN regex")
+    })
+}
+
+/// What a call on a resource of another account needs from the target account
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq, PartialOrd, Ord)]
+pub enum CrossAccountRequirement {
+    /// The resource-based policy of the resource has to allow the workload
+    ResourcePolicy,
+    /// The trust policy of the assumed role has to trust the workload
+    TrustPolicy,
+    /// The service has no resource-based policies, so the call has to be made with the
+    /// credentials of a role in the target account
+    RoleAssumption,
+}
+
+impl CrossAccountRequirement {
+    fn description(self, account: &str) -> String {
+        match self {
+            Self::ResourcePolicy => format!(
+                "The resource-based policy of the resource in account {account} has to allow \
+                 the workload as well"
+            ),
+            Self::TrustPolicy => format!(
+                "The trust policy of the role in account {account} has to trust the workload"
+            ),
+            Self::RoleAssumption => format!(
+                "The service has no resource-based policies, so the call has to be made with \
+                 the credentials of a role in account {account}; the actions were left out of \
+                 the policies"
+            ),
+        }
+    }
+}
+
+/// A call on a resource of another account than the workload's
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct CrossAccountAccess {
+    /// ARN of the resource, as written in the call
+    pub resource_arn: String,
+    /// Account of the resource
+    pub account: String,
+    /// Actions of the call on the resource
+    pub actions: Vec<String>,
+    /// What the target account has to allow for the call to succeed
+    pub requirement: CrossAccountRequirement,
+    /// Human-readable explanation of the requirement
+    pub description: String,
+    /// Source location of the call
+    pub location: Location,
+    /// Expression of the call
+    pub expression: String,
+}
+
+/// Collect the calls of `enriched_calls` naming resources of other accounts than
+/// `account`, by location
+///
+/// The actions of calls on services without resource-based policies are left out of the
+/// calls, as no identity policy of the workload can grant them. Nothing is reported when
+/// `account` isn't an account ID, e.g. `*`.
+pub(crate) fn separate_cross_account_calls(
+    enriched_calls: &mut [EnrichedSdkMethodCall<'_>],
+    account: &str,
+) -> Vec<CrossAccountAccess> {
+    if account.len() != 12 || !account.bytes().all(|byte| byte.is_ascii_digit()) {
+        return Vec::new();
+    }
+    let mut accesses = Vec::new();
+    for call in enriched_calls {
+        let Some(metadata) = &call.sdk_method_call.metadata else {
+            continue;
+        };
+        for arn in account_arn_regex().captures_iter(&metadata.expr) {
+            let (service, resource_account) = (&arn[1], &arn[2]);
+            if resource_account == account {
+                continue;
+            }
+            let is_role = service == "iam" && arn[0].contains(":role/");
+            let requirement = if is_role {
+                CrossAccountRequirement::TrustPolicy
+            } else if RESOURCE_POLICY_SERVICES.contains(&service) {
+                CrossAccountRequirement::ResourcePolicy
+            } else {
+                CrossAccountRequirement::RoleAssumption
+            };
+            let actions: Vec<String> = call
+                .actions
+                .iter()
+                .filter(|action| {
+                    if is_role {
+                        ASSUME_ROLE_ACTIONS.contains(&action.name.as_str())
+                    } else {
+                        action.service() == service
+                    }
+                })
+                .map(|action| action.name.clone())
+                .collect();
+            if actions.is_empty() {
+                continue;
+            }
+            if requirement == CrossAccountRequirement::RoleAssumption {
+                log::debug!(
+                    "Not granting {} the actions on {}: they need a role of its account",
+                    call.method_name,
+                    &arn[0]
+                );
+                call.actions
+                    .retain(|action| !actions.contains(&action.name));
+            }
+            accesses.push(CrossAccountAccess {
+                resource_arn: arn[0].to_string(),
+                account: resource_account.to_string(),
+                actions,
+                requirement,
+                description: requirement.description(resource_account),
+                location: metadata.location.clone(),
+                expression: metadata.expr.clone(),
+            });
+        }
+    }
+    accesses.sort_by(|a, b| (&a.location, &a.resource_arn).cmp(&(&b.location, &b.resource_arn)));
+    accesses
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::SdkMethodCall;
+
+    const WORKLOAD_ACCOUNT: &str = "111111111111";
+
+    fn call(expression: &str, line: usize) -> SdkMethodCall {
+        SdkMethodCall {
+            name: "call".to_string(),
+            possible_services: vec![],
+            metadata: Some(SdkMethodCallMetadata::new(
+                expression.to_string(),
+                Location::new(PathBuf::from("app.py"), (line, 1), (line, 80)),
+            )),
+        }
+    }
+
+    fn enriched<'a>(call: &'a SdkMethodCall, actions: &[&str]) -> EnrichedSdkMethodCall<'a> {
+        EnrichedSdkMethodCall {
+            method_name: call.name.clone(),
+            service: actions[0].split(':').next().unwrap_or_default().to_string(),
+            actions: actions
+                .iter()
+                .map(|action| {
+                    Action::new(
+                        (*action).to_string(),
+                        vec![],
+                        vec![],
+                        Explanation::default(),
+                    )
+                })
+                .collect(),
+            sdk_method_call: call,
+        }
+    }
+
+    #[test]
+    fn test_cross_account_calls_are_reported_by_requirement() {
+        let topic = call(
+            "sns.publish(TopicArn='arn:aws:sns:us-east-1:222222222222:alerts', Message=m)",
+            1,
+        );
+        let role = call(
+            "sts.assume_role(RoleArn='arn:aws:iam::222222222222:role/Reader')",
+            2,
+        );
+        let state_machine = call(
+            "sfn.start_execution(\
+             stateMachineArn='arn:aws:states:us-east-1:222222222222:stateMachine:etl')",
+            3,
+        );
+        let own = call(
+            "sfn.start_execution(\
+             stateMachineArn='arn:aws:states:us-east-1:111111111111:stateMachine:etl')",
+            4,
+        );
+        let mut calls = vec![
+            enriched(&topic, &["sns:Publish"]),
+            enriched(&role, &["sts:AssumeRole"]),
+            enriched(&state_machine, &["states:StartExecution"]),
+            enriched(&own, &["states:StartExecution"]),
+        ];
+
+        let accesses = separate_cross_account_calls(&mut calls, WORKLOAD_ACCOUNT);
+
+        let requirements: Vec<(&str, CrossAccountRequirement)> = accesses
+            .iter()
+            .map(|access| (access.actions[0].as_str(), access.requirement))
+            .collect();
+        assert_eq!(
+            requirements,
+            vec![
+                ("sns:Publish", CrossAccountRequirement::ResourcePolicy),
+                ("sts:AssumeRole", CrossAccountRequirement::TrustPolicy),
+                (
+                    "states:StartExecution",
+                    CrossAccountRequirement::RoleAssumption
+                ),
+            ]
+        );
+        assert_eq!(calls[0].actions.len(), 1);
+        assert!(calls[2].actions.is_empty());
+        assert_eq!(calls[3].actions.len(), 1);
+    }
+
+    #[test]
+    fn test_nothing_is_reported_without_an_account_id() {
+        let queue = call(
+            "sqs.send_message(QueueUrl='arn:aws:sqs:us-east-1:222222222222:q')",
+            1,
+        );
+        let mut calls = vec![enriched(&queue, &["sqs:SendMessage"])];
+
+        assert!(separate_cross_account_calls(&mut calls, "*").is_empty());
+    }
+}
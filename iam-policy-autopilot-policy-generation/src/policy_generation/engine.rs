@@ -49,6 +49,15 @@ impl<'a> Engine<'a> {
         }
     }
 
+    /// Render resources that aren't known as template variables (e.g. `{{BucketName}}`,
+    /// `{{AccountId}}`) instead of wildcards, for substitution at deploy time
+    #[must_use]
+    pub fn with_template_variables(mut self, template_variables: bool) -> Self {
+        self.arn_parser = self.arn_parser.with_templates(template_variables);
+        self.condition_processor = self.condition_processor.with_templates(template_variables);
+        self
+    }
+
     /// Generate IAM policies from enriched method calls
     ///
     /// Creates one IAM policy per EnrichedSdkMethodCall, with each Action becoming
@@ -91,6 +100,18 @@ impl<'a> Engine<'a> {
         let policy_with_metadata = PolicyWithMetadata {
             policy,
             policy_type: PolicyType::Identity,
+            assumed_role: enriched_call
+                .sdk_method_call
+                .metadata
+                .as_ref()
+                .and_then(|metadata| metadata.assumed_role.clone()),
+            credential_profile: enriched_call
+                .sdk_method_call
+                .metadata
+                .as_ref()
+                .and_then(|metadata| metadata.credential_profile.clone()),
+            entry_point: None,
+            named_principal: None,
         };
 
         Ok(policy_with_metadata)
@@ -219,6 +240,11 @@ impl<'a> Engine<'a> {
     /// permissive resource grants. If the merged result would exceed IAM size limits,
     /// multiple policies are created as needed.
     ///
+    /// Policies for calls made with assumed-role credentials are merged per role
+    /// and never combined with the policies of the principal running the code,
+    /// which come first in the result. Policies of different entry points are never
+    /// merged either.
+    ///
     /// # Arguments
     /// * `policies` - Slice of IAM policies to merge
     ///
@@ -237,18 +263,39 @@ impl<'a> Engine<'a> {
                 ExtractorError::policy_generation("Cannot merge policies with different types"),
             ),
             Some(first) => {
-                let policies = policies
-                    .iter()
-                    .map(|p| p.policy.clone())
-                    .collect::<Vec<_>>();
-                let merged = self.policy_merger.merge_policies(&policies)?;
-                Ok(merged
-                    .iter()
-                    .map(|policy| PolicyWithMetadata {
-                        policy: policy.clone(),
+                let mut by_role: BTreeMap<
+                    (Option<&str>, Option<&str>, Option<&str>, Option<&str>),
+                    Vec<IamPolicy>,
+                > = BTreeMap::new();
+                for policy in policies {
+                    by_role
+                        .entry((
+                            policy.named_principal.as_deref(),
+                            policy.entry_point.as_deref(),
+                            policy.assumed_role.as_deref(),
+                            policy.credential_profile.as_deref(),
+                        ))
+                        .or_default()
+                        .push(policy.policy.clone());
+                }
+
+                let mut merged_policies = Vec::new();
+                for (
+                    (named_principal, entry_point, assumed_role, credential_profile),
+                    role_policies,
+                ) in by_role
+                {
+                    let merged = self.policy_merger.merge_policies(&role_policies)?;
+                    merged_policies.extend(merged.into_iter().map(|policy| PolicyWithMetadata {
+                        policy,
                         policy_type: first.policy_type,
-                    })
-                    .collect::<Vec<_>>())
+                        assumed_role: assumed_role.map(str::to_string),
+                        credential_profile: credential_profile.map(str::to_string),
+                        entry_point: entry_point.map(str::to_string),
+                        named_principal: named_principal.map(str::to_string),
+                    }));
+                }
+                Ok(merged_policies)
             }
         }
     }
@@ -279,6 +326,27 @@ impl<'a> Engine<'a> {
             policies,
             explanations: Some(explanations),
             resource_binding_explanations: None,
+            template_variables: None,
+            unscoped_actions: None,
+            managed_policy_suggestions: None,
+            trust_policies: None,
+            resource_policies: None,
+            cross_account_access: None,
+            condition_key_suggestions: None,
+            runtime: None,
+            statement_origins: None,
+            sensitive_actions: None,
+            access_level_summary: None,
+            suppressed_calls: None,
+            low_confidence_calls: None,
+            deprecated_calls: None,
+            no_permission_calls: None,
+            excluded_service_calls: None,
+            filtered_actions: None,
+            action_provenance: None,
+            action_inventory: None,
+            diagnostics: None,
+            coverage: None,
         })
     }
 }
@@ -562,6 +630,10 @@ mod tests {
         let policy1 = PolicyWithMetadata {
             policy: policy1,
             policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
         };
 
         let mut policy2 = IamPolicy::new();
@@ -572,6 +644,10 @@ mod tests {
         let policy2 = PolicyWithMetadata {
             policy: policy2,
             policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
         };
 
         let merged = engine.merge_policies(&[policy1, policy2]).unwrap();
@@ -587,6 +663,81 @@ mod tests {
         assert_eq!(statement.resource, vec!["arn:aws:s3:::bucket/*"]);
     }
 
+    #[test]
+    fn test_merge_policies_keeps_assumed_roles_separate() {
+        let engine = create_test_engine();
+        let reader_role = "arn:aws:iam::123456789012:role/Reader";
+
+        let policy_for = |action: &str, assumed_role: Option<&str>| {
+            let mut policy = IamPolicy::new();
+            policy.add_statement(create_test_statement(
+                vec![action],
+                vec!["arn:aws:s3:::bucket/*"],
+            ));
+            PolicyWithMetadata {
+                policy,
+                policy_type: PolicyType::Identity,
+                assumed_role: assumed_role.map(str::to_string),
+                credential_profile: None,
+                entry_point: None,
+                named_principal: None,
+            }
+        };
+
+        let merged = engine
+            .merge_policies(&[
+                policy_for("s3:GetObject", Some(reader_role)),
+                policy_for("sts:AssumeRole", None),
+                policy_for("s3:ListBucket", Some(reader_role)),
+            ])
+            .unwrap();
+
+        assert_eq!(merged.len(), 2);
+        assert_eq!(merged[0].assumed_role, None);
+        assert_eq!(
+            merged[0].policy.statements[0].action,
+            vec!["sts:AssumeRole"]
+        );
+        assert_eq!(merged[1].assumed_role.as_deref(), Some(reader_role));
+        assert_eq!(merged[1].policy.statements.len(), 1);
+        let actions = &merged[1].policy.statements[0].action;
+        assert!(actions.contains(&"s3:GetObject".to_string()));
+        assert!(actions.contains(&"s3:ListBucket".to_string()));
+    }
+
+    #[test]
+    fn test_merge_policies_keeps_profiles_separate() {
+        let engine = create_test_engine();
+
+        let policy_for = |action: &str, credential_profile: Option<&str>| {
+            let mut policy = IamPolicy::new();
+            policy.add_statement(create_test_statement(
+                vec![action],
+                vec!["arn:aws:s3:::bucket/*"],
+            ));
+            PolicyWithMetadata {
+                policy,
+                policy_type: PolicyType::Identity,
+                assumed_role: None,
+                credential_profile: credential_profile.map(str::to_string),
+                entry_point: None,
+                named_principal: None,
+            }
+        };
+
+        let merged = engine
+            .merge_policies(&[
+                policy_for("s3:GetObject", Some("reports")),
+                policy_for("s3:PutObject", None),
+            ])
+            .unwrap();
+
+        assert_eq!(merged.len(), 2);
+        assert!(merged[0].is_workload_policy());
+        assert_eq!(merged[1].credential_profile.as_deref(), Some("reports"));
+        assert_eq!(merged[1].policy.statements[0].action, vec!["s3:GetObject"]);
+    }
+
     #[test]
     fn test_merge_policies_empty() {
         let engine = create_test_engine();
@@ -0,0 +1,283 @@
+//! Entry points of the analyzed code, for a policy per entry point
+//!
+//! The functions and commands of a monorepo run with roles of their own, and none of
+//! them should be granted what only the others call. Entry points are Go `main`
+//! packages, Lambda handler files and CLI subcommand directories such as `cmd/serve`.
+//! A source file belongs to the innermost entry point containing it. Other files are
+//! shared code: without an import graph to tell which entry points use them, their
+//! calls are granted to the entry points of the nearest directory containing any.
+
+use std::collections::BTreeSet;
+use std::path::{Path, PathBuf};
+
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::policy_generation::PolicyWithMetadata;
+use crate::{Language, SourceFile};
+
+/// Directories whose subdirectories are CLI subcommands
+const SUBCOMMAND_PARENTS: &[&str] = &["cmd", "cmds", "commands", "subcommands"];
+
+/// Top-level definitions of Python Lambda handlers, by naming convention
+const PYTHON_HANDLERS: &[&str] = &[
+    "def lambda_handler",
+    "def handler",
+    "async def lambda_handler",
+    "async def handler",
+];
+
+/// Exports of JavaScript and TypeScript Lambda handlers, by naming convention
+const JAVASCRIPT_HANDLERS: &[&str] = &[
+    "exports.handler",
+    "module.exports.handler",
+    "export const handler",
+    "export function handler",
+    "export async function handler",
+];
+
+/// Interfaces of Java Lambda handlers
+const JAVA_HANDLERS: &[&str] = &[
+    "implements RequestHandler<",
+    "implements RequestStreamHandler",
+];
+
+/// Entry points of `source_files`: the directories of Go `main` packages and CLI
+/// subcommands, and Lambda handler files
+pub(crate) fn detect_entry_points(source_files: &[SourceFile]) -> Vec<PathBuf> {
+    let mut entry_points = BTreeSet::new();
+    for source_file in source_files {
+        let path = &source_file.path;
+        if is_go_main_package(source_file) {
+            entry_points.insert(path.parent().unwrap_or(Path::new("")).to_path_buf());
+        } else if is_lambda_handler(source_file) {
+            entry_points.insert(path.clone());
+        }
+        let components: Vec<_> = path.components().collect();
+        // The subcommand is a directory, not the file itself
+        for index in 0..components.len().saturating_sub(2) {
+            if SUBCOMMAND_PARENTS
+                .iter()
+                .any(|parent| components[index].as_os_str() == *parent)
+            {
+                entry_points.insert(components[..=index + 1].iter().collect());
+            }
+        }
+    }
+    entry_points.into_iter().collect()
+}
+
+/// Assign the policies of `enriched_calls` to the entry points of their source files
+///
+/// `policies` holds the policy of each of `enriched_calls`, in the same order. The
+/// policy of a call in shared code is copied to each entry point sharing it.
+pub(crate) fn assign_entry_points(
+    policies: Vec<PolicyWithMetadata>,
+    enriched_calls: &[&EnrichedSdkMethodCall<'_>],
+    entry_points: &[PathBuf],
+) -> Vec<PolicyWithMetadata> {
+    let mut assigned = Vec::with_capacity(policies.len());
+    for (policy, call) in policies.into_iter().zip(enriched_calls) {
+        let owners = match &call.sdk_method_call.metadata {
+            Some(metadata) => owning_entry_points(entry_points, &metadata.location.file_path),
+            None => entry_points.iter().collect(),
+        };
+        for entry_point in owners {
+            assigned.push(PolicyWithMetadata {
+                entry_point: Some(entry_point_name(entry_point)),
+                ..policy.clone()
+            });
+        }
+    }
+    assigned
+}
+
+/// The innermost entry point containing `file`, or those of the nearest directory
+/// containing any if `file` is shared code
+fn owning_entry_points<'a>(entry_points: &'a [PathBuf], file: &Path) -> Vec<&'a PathBuf> {
+    if let Some(entry_point) = entry_points
+        .iter()
+        .filter(|entry_point| file.starts_with(entry_point))
+        .max_by_key(|entry_point| entry_point.components().count())
+    {
+        return vec![entry_point];
+    }
+    file.ancestors()
+        .skip(1)
+        .map(|directory| {
+            entry_points
+                .iter()
+                .filter(|entry_point| entry_point.starts_with(directory))
+                .collect::<Vec<_>>()
+        })
+        .find(|owners| !owners.is_empty())
+        .unwrap_or_else(|| entry_points.iter().collect())
+}
+
+fn entry_point_name(entry_point: &Path) -> String {
+    if entry_point.as_os_str().is_empty() {
+        ".".to_string()
+    } else {
+        entry_point.display().to_string()
+    }
+}
+
+fn is_go_main_package(source_file: &SourceFile) -> bool {
+    source_file.language == Language::Go
+        && source_file
+            .content
+            .lines()
+            .any(|line| starts_with_name(line.trim_start(), "package main"))
+}
+
+/// Whether `source_file` defines a Lambda handler by the naming conventions of its language
+pub(crate) fn is_lambda_handler(source_file: &SourceFile) -> bool {
+    let content = &source_file.content;
+    match source_file.language {
+        Language::Python => content.lines().any(|line| {
+            PYTHON_HANDLERS
+                .iter()
+                .any(|handler| starts_with_name(line, handler))
+        }),
+        Language::JavaScript | Language::TypeScript => content.lines().any(|line| {
+            JAVASCRIPT_HANDLERS
+                .iter()
+                .any(|handler| starts_with_name(line.trim_start(), handler))
+        }),
+        Language::Java => JAVA_HANDLERS
+            .iter()
+            .any(|handler| content.contains(handler)),
+        Language::Go => false,
+    }
+}
+
+/// Whether `line` starts with `prefix`, ending with a whole name: `def handler(` but
+/// not `def handler_factory(`
+fn starts_with_name(line: &str, prefix: &str) -> bool {
+    line.strip_prefix(prefix).is_some_and(|rest| {
+        rest.chars()
+            .next()
+            .is_none_or(|c| !(c.is_alphanumeric() || c == '_' || c == '$'))
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::enrichment::Action;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+    use crate::{Explanation, Location, SdkMethodCall};
+
+    fn source_file(path: &str, content: &str, language: Language) -> SourceFile {
+        SourceFile::with_language(PathBuf::from(path), content.to_string(), language)
+    }
+
+    #[test]
+    fn test_entry_points_detected() {
+        let go = [
+            source_file("cmd/server/main.go", "package main\n", Language::Go),
+            source_file("cmd/server/routes.go", "package main\n", Language::Go),
+            source_file("internal/db/db.go", "package db\n", Language::Go),
+            source_file(
+                "tools/migrate/main.go",
+                "package main // tool",
+                Language::Go,
+            ),
+        ];
+        assert_eq!(
+            detect_entry_points(&go),
+            vec![PathBuf::from("cmd/server"), PathBuf::from("tools/migrate")]
+        );
+
+        let python = [
+            source_file(
+                "functions/orders/app.py",
+                "def lambda_handler(event, context):\n    pass\n",
+                Language::Python,
+            ),
+            source_file(
+                "functions/orders/models.py",
+                "def handler_factory():\n    pass\n",
+                Language::Python,
+            ),
+            source_file("cli/commands/export/run.py", "", Language::Python),
+        ];
+        assert_eq!(
+            detect_entry_points(&python),
+            vec![
+                PathBuf::from("cli/commands/export"),
+                PathBuf::from("functions/orders/app.py"),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_shared_code_policies_copied_to_sharing_entry_points() {
+        let entry_points = vec![
+            PathBuf::from("functions/orders/app.py"),
+            PathBuf::from("functions/users/app.py"),
+        ];
+        let calls: Vec<SdkMethodCall> = [
+            "functions/orders/app.py",
+            "functions/users/models.py",
+            "lib/db.py",
+        ]
+        .iter()
+        .map(|path| SdkMethodCall {
+            name: "get_item".to_string(),
+            possible_services: vec!["dynamodb".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "table.get_item()".to_string(),
+                Location::new(PathBuf::from(path), (1, 1), (1, 10)),
+            )),
+        })
+        .collect();
+        let enriched_calls: Vec<EnrichedSdkMethodCall> = calls
+            .iter()
+            .map(|call| EnrichedSdkMethodCall {
+                method_name: call.name.clone(),
+                service: "dynamodb".to_string(),
+                actions: vec![Action::new(
+                    "dynamodb:GetItem".to_string(),
+                    vec![],
+                    vec![],
+                    Explanation::default(),
+                )],
+                sdk_method_call: call,
+            })
+            .collect();
+        let policies = enriched_calls
+            .iter()
+            .map(|_| {
+                let mut policy = IamPolicy::new();
+                policy.add_statement(Statement::allow(
+                    vec!["dynamodb:GetItem".to_string()],
+                    vec!["*".to_string()],
+                ));
+                PolicyWithMetadata {
+                    policy,
+                    policy_type: PolicyType::Identity,
+                    assumed_role: None,
+                    credential_profile: None,
+                    entry_point: None,
+                    named_principal: None,
+                }
+            })
+            .collect();
+
+        let enriched_calls: Vec<&EnrichedSdkMethodCall> = enriched_calls.iter().collect();
+        let assigned = assign_entry_points(policies, &enriched_calls, &entry_points);
+
+        assert_eq!(
+            assigned
+                .iter()
+                .map(|policy| policy.entry_point.as_deref())
+                .collect::<Vec<_>>(),
+            vec![
+                Some("functions/orders/app.py"),
+                Some("functions/users/app.py"),
+                Some("functions/orders/app.py"),
+                Some("functions/users/app.py"),
+            ]
+        );
+    }
+}
@@ -0,0 +1,296 @@
+//! Matching of generated statements against AWS managed policies
+//!
+//! Statements whose actions an AWS managed policy already grants can be replaced by
+//! attaching that policy, which AWS maintains as services add actions. The catalog
+//! lists read-only and narrowly scoped managed policies only: full access policies
+//! grant far more than any application uses. Each entry lists a subset of what the
+//! managed policy grants, so a suggestion never covers an action the policy doesn't.
+
+use std::collections::BTreeMap;
+
+use serde::Serialize;
+
+use crate::policy_generation::{Effect, PolicyWithMetadata, Statement};
+
+/// AWS managed policies with the action patterns they grant on all resources
+const MANAGED_POLICIES: &[(&str, &[&str])] = &[
+    (
+        "AmazonS3ReadOnlyAccess",
+        &[
+            "s3:Describe*",
+            "s3:Get*",
+            "s3:List*",
+            "s3-object-lambda:Get*",
+            "s3-object-lambda:List*",
+        ],
+    ),
+    (
+        "AmazonDynamoDBReadOnlyAccess",
+        &[
+            "dynamodb:BatchGetItem",
+            "dynamodb:Describe*",
+            "dynamodb:GetItem",
+            "dynamodb:List*",
+            "dynamodb:PartiQLSelect",
+            "dynamodb:Query",
+            "dynamodb:Scan",
+        ],
+    ),
+    (
+        "AmazonSQSReadOnlyAccess",
+        &[
+            "sqs:GetQueueAttributes",
+            "sqs:GetQueueUrl",
+            "sqs:ListDeadLetterSourceQueues",
+            "sqs:ListQueues",
+        ],
+    ),
+    (
+        "AmazonSNSReadOnlyAccess",
+        &["sns:GetTopicAttributes", "sns:List*"],
+    ),
+    ("AWSLambda_ReadOnlyAccess", &["lambda:Get*", "lambda:List*"]),
+    (
+        "AmazonKinesisReadOnlyAccess",
+        &["kinesis:Describe*", "kinesis:Get*", "kinesis:List*"],
+    ),
+    (
+        "AmazonSSMReadOnlyAccess",
+        &["ssm:Describe*", "ssm:Get*", "ssm:List*"],
+    ),
+    (
+        "CloudWatchLogsReadOnlyAccess",
+        &[
+            "logs:Describe*",
+            "logs:FilterLogEvents",
+            "logs:Get*",
+            "logs:List*",
+            "logs:StartQuery",
+            "logs:StopQuery",
+            "logs:TestMetricFilter",
+        ],
+    ),
+    (
+        "CloudWatchReadOnlyAccess",
+        &[
+            "cloudwatch:Describe*",
+            "cloudwatch:Get*",
+            "cloudwatch:List*",
+            "logs:Describe*",
+            "logs:Get*",
+            "logs:List*",
+        ],
+    ),
+    (
+        "AmazonEC2ReadOnlyAccess",
+        &[
+            "autoscaling:Describe*",
+            "ec2:Describe*",
+            "elasticloadbalancing:Describe*",
+        ],
+    ),
+    (
+        "IAMReadOnlyAccess",
+        &[
+            "iam:GenerateCredentialReport",
+            "iam:GenerateServiceLastAccessedDetails",
+            "iam:Get*",
+            "iam:List*",
+            "iam:SimulateCustomPolicy",
+            "iam:SimulatePrincipalPolicy",
+        ],
+    ),
+    (
+        "AWSXrayWriteOnlyAccess",
+        &[
+            "xray:GetSamplingRules",
+            "xray:GetSamplingStatisticSummaries",
+            "xray:GetSamplingTargets",
+            "xray:PutTelemetryRecords",
+            "xray:PutTraceSegments",
+        ],
+    ),
+];
+
+/// An AWS managed policy to attach instead of the generated statements it covers
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct ManagedPolicySuggestion {
+    /// Name of the managed policy, e.g. `AmazonDynamoDBReadOnlyAccess`
+    pub policy_name: String,
+    /// ARN of the managed policy in the partition of the generated policies
+    pub policy_arn: String,
+    /// Role to attach the managed policy to, when the covered actions are called with
+    /// credentials of an assumed role
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub assumed_role: Option<String>,
+    /// Profile to attach the managed policy to the principal of, when the covered actions
+    /// are called with credentials of a named profile
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub credential_profile: Option<String>,
+    /// Generated actions the managed policy grants, which the residual policies leave out
+    pub covered_actions: Vec<String>,
+}
+
+/// Remove the statements an AWS managed policy covers from `policies`, returning the
+/// managed policies to attach instead, those of the principal running the code first
+///
+/// Only allow statements without conditions are covered, since managed policies carry
+/// none. Managed policies grant on all resources, so attaching one widens statements
+/// scoped to specific resources. Policies left without statements are dropped.
+pub(crate) fn match_managed_policies(
+    policies: &mut Vec<PolicyWithMetadata>,
+    partition: &str,
+) -> Vec<ManagedPolicySuggestion> {
+    let mut covered: BTreeMap<(Option<String>, Option<String>, usize), Vec<String>> =
+        BTreeMap::new();
+    for policy in policies.iter_mut() {
+        let (assumed_role, credential_profile) = (&policy.assumed_role, &policy.credential_profile);
+        policy.policy.statements.retain(|statement| {
+            let Some(index) = covering_policy(statement) else {
+                return true;
+            };
+            covered
+                .entry((assumed_role.clone(), credential_profile.clone(), index))
+                .or_default()
+                .extend(statement.action.iter().cloned());
+            false
+        });
+    }
+    policies.retain(|policy| !policy.policy.statements.is_empty());
+
+    covered
+        .into_iter()
+        .map(|((assumed_role, credential_profile, index), mut actions)| {
+            actions.sort();
+            actions.dedup();
+            let (name, _) = MANAGED_POLICIES[index];
+            ManagedPolicySuggestion {
+                policy_name: name.to_string(),
+                policy_arn: format!("arn:{partition}:iam::aws:policy/{name}"),
+                assumed_role,
+                credential_profile,
+                covered_actions: actions,
+            }
+        })
+        .collect()
+}
+
+/// Index of the first managed policy granting every action of `statement`
+fn covering_policy(statement: &Statement) -> Option<usize> {
+    if statement.effect != Effect::Allow || !statement.condition.is_empty() {
+        return None;
+    }
+    MANAGED_POLICIES.iter().position(|(_, patterns)| {
+        statement.action.iter().all(|action| {
+            patterns
+                .iter()
+                .any(|pattern| pattern_matches(pattern, action))
+        })
+    })
+}
+
+/// Whether `pattern`, an action or action prefix ending with `*`, matches `action`;
+/// IAM matches action names ignoring case
+fn pattern_matches(pattern: &str, action: &str) -> bool {
+    let action = action.to_lowercase();
+    let pattern = pattern.to_lowercase();
+    match pattern.strip_suffix('*') {
+        Some(prefix) => action.starts_with(prefix) && !action.contains('*'),
+        None => action == pattern,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::enrichment::{Condition, Operator};
+    use crate::policy_generation::{IamPolicy, PolicyType};
+
+    fn statement(actions: &[&str], resource: &str) -> Statement {
+        Statement::allow(
+            actions.iter().map(ToString::to_string).collect(),
+            vec![resource.to_string()],
+        )
+    }
+
+    #[test]
+    fn test_covered_statements_move_to_managed_policies() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(statement(
+            &["dynamodb:GetItem", "dynamodb:Query"],
+            "arn:aws:dynamodb:us-east-1:123456789012:table/Orders",
+        ));
+        policy.add_statement(statement(
+            &["dynamodb:PutItem"],
+            "arn:aws:dynamodb:us-east-1:123456789012:table/Orders",
+        ));
+        policy.add_statement(
+            statement(&["s3:GetObject"], "arn:aws:s3:::reports/*").with_conditions(vec![
+                Condition {
+                    operator: Operator::StringEquals,
+                    key: "s3:ExistingObjectTag/team".to_string(),
+                    values: vec!["reports".to_string()],
+                },
+            ]),
+        );
+        let mut assumed = IamPolicy::new();
+        assumed.add_statement(statement(&["s3:ListAllMyBuckets"], "*"));
+        let mut policies = vec![
+            PolicyWithMetadata {
+                policy,
+                policy_type: PolicyType::Identity,
+                assumed_role: None,
+                credential_profile: None,
+                entry_point: None,
+                named_principal: None,
+            },
+            PolicyWithMetadata {
+                policy: assumed,
+                policy_type: PolicyType::Identity,
+                assumed_role: Some("arn:aws:iam::123456789012:role/Reports".to_string()),
+                credential_profile: None,
+                entry_point: None,
+                named_principal: None,
+            },
+        ];
+
+        let suggestions = match_managed_policies(&mut policies, "aws");
+
+        assert_eq!(
+            suggestions,
+            vec![
+                ManagedPolicySuggestion {
+                    policy_name: "AmazonDynamoDBReadOnlyAccess".to_string(),
+                    policy_arn: "arn:aws:iam::aws:policy/AmazonDynamoDBReadOnlyAccess".to_string(),
+                    assumed_role: None,
+                    credential_profile: None,
+                    covered_actions: vec![
+                        "dynamodb:GetItem".to_string(),
+                        "dynamodb:Query".to_string()
+                    ],
+                },
+                ManagedPolicySuggestion {
+                    policy_name: "AmazonS3ReadOnlyAccess".to_string(),
+                    policy_arn: "arn:aws:iam::aws:policy/AmazonS3ReadOnlyAccess".to_string(),
+                    assumed_role: Some("arn:aws:iam::123456789012:role/Reports".to_string()),
+                    credential_profile: None,
+                    covered_actions: vec!["s3:ListAllMyBuckets".to_string()],
+                },
+            ]
+        );
+        assert_eq!(policies.len(), 1);
+        assert_eq!(
+            policies[0]
+                .policy
+                .statements
+                .iter()
+                .map(|statement| statement.action.clone())
+                .collect::<Vec<_>>(),
+            vec![
+                vec!["dynamodb:PutItem".to_string()],
+                vec!["s3:GetObject".to_string()],
+            ]
+        );
+    }
+}
@@ -7,14 +7,53 @@
 use serde::{Deserialize, Serialize, Serializer};
 use std::collections::HashMap;
 
+pub(crate) mod access_analyzer;
+pub(crate) mod access_levels;
+pub(crate) mod access_split;
+pub(crate) mod action_compaction;
+pub(crate) mod action_filters;
+pub(crate) mod action_inventory;
+pub(crate) mod condition_suggestions;
+pub(crate) mod coverage;
+pub(crate) mod cross_account;
 pub(crate) mod engine;
+pub(crate) mod entry_points;
+pub(crate) mod managed_policies;
 pub(crate) mod merge;
+pub(crate) mod network_conditions;
+pub(crate) mod principals;
+pub(crate) mod provenance;
+pub(crate) mod region_conditions;
+pub(crate) mod resource_policies;
+pub(crate) mod runtime;
+pub(crate) mod sensitive_actions;
+pub(crate) mod statement_ids;
+pub(crate) mod templates;
+pub(crate) mod trust_policies;
+pub(crate) mod unscoped;
 pub(crate) mod utils;
 
 #[cfg(test)]
 mod integration_tests;
 
+pub use access_analyzer::{StatementOrigin, StatementSource};
+pub use access_levels::{AccessLevel, ServiceAccessLevels};
+pub use action_filters::{FilterReason, FilteredAction};
+pub use action_inventory::InventoriedAction;
+pub use condition_suggestions::ConditionKeySuggestion;
+pub use coverage::{CoverageCounts, CoverageReport, FileCoverage, LanguageCoverage};
+pub use cross_account::{CrossAccountAccess, CrossAccountRequirement};
 pub use engine::Engine;
+pub use managed_policies::ManagedPolicySuggestion;
+pub use provenance::{ActionProvenance, CallSite};
+pub use resource_policies::{
+    ResourcePolicy, ResourcePolicyDocument, ResourcePolicyType, ResourceStatement,
+};
+pub use runtime::Runtime;
+pub use sensitive_actions::{SensitiveAction, Severity};
+pub use templates::TemplateVariable;
+pub use trust_policies::{TrustPolicy, TrustPolicyDocument, TrustStatement};
+pub use unscoped::{UnscopedAction, UnscopedReason};
 
 use crate::enrichment::Condition;
 
@@ -30,6 +69,7 @@ where
         let operator_str = match condition.operator {
             crate::enrichment::Operator::StringEquals => "StringEquals",
             crate::enrichment::Operator::StringLike => "StringLike",
+            crate::enrichment::Operator::IpAddress => "IpAddress",
         };
 
         condition_map
@@ -108,6 +148,49 @@ pub struct PolicyWithMetadata {
     pub policy: IamPolicy,
     /// Type of the policy
     pub policy_type: PolicyType,
+    /// Role whose assumed credentials the policy's calls run under (e.g. the
+    /// `RoleArn` passed to `sts.assume_role`), `None` for the principal running the code
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub assumed_role: Option<String>,
+    /// Named profile of the shared AWS config files whose credentials the policy's calls
+    /// run under (e.g. the `profile_name` of a `boto3.Session`), `None` for the principal
+    /// running the code
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub credential_profile: Option<String>,
+    /// Entry point of the analyzed code the policy is for (e.g. the `cmd/server` Go
+    /// `main` package), with per-entry-point policies
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub entry_point: Option<String>,
+    /// Principal the principal mappings map the policy's source files to (e.g.
+    /// `worker-role`), `None` for files no mapping covers
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub named_principal: Option<String>,
+}
+
+impl PolicyWithMetadata {
+    /// Whether the policy's calls run under the credentials of the principal running the
+    /// code, rather than those of an assumed role or a named profile
+    #[must_use]
+    pub fn is_workload_policy(&self) -> bool {
+        self.assumed_role.is_none() && self.credential_profile.is_none()
+    }
+
+    /// Principal the policy's calls run as: the assumed role, a
+    /// `{{ProfilePrincipalArn:<profile>}}` placeholder for the principal of a named profile,
+    /// a `{{PrincipalArn:<name>}}` placeholder for a mapped principal, or `workload_role`
+    /// for the principal running the code
+    pub(crate) fn principal(&self, workload_role: &str) -> String {
+        match (
+            &self.assumed_role,
+            &self.credential_profile,
+            &self.named_principal,
+        ) {
+            (Some(role), _, _) => role.clone(),
+            (None, Some(profile), _) => format!("{{{{ProfilePrincipalArn:{profile}}}}}"),
+            (None, None, Some(name)) => format!("{{{{PrincipalArn:{name}}}}}"),
+            (None, None, None) => workload_role.to_string(),
+        }
+    }
 }
 
 impl IamPolicy {
@@ -125,6 +208,22 @@ impl IamPolicy {
     pub fn add_statement(&mut self, statement: Statement) {
         self.statements.push(statement);
     }
+
+    /// Set the policy ID
+    pub fn set_id(&mut self, id: String) {
+        self.id = id;
+    }
+
+    /// Prefix the Sids of the statements with `prefix`, which has to be alphanumeric;
+    /// statements without a Sid are named after their position, e.g. `{prefix}Statement2`
+    pub fn prefix_statement_ids(&mut self, prefix: &str) {
+        for (index, statement) in self.statements.iter_mut().enumerate() {
+            statement.sid = Some(match &statement.sid {
+                Some(sid) => format!("{prefix}{sid}"),
+                None => format!("{prefix}Statement{}", index + 1),
+            });
+        }
+    }
 }
 
 impl Default for IamPolicy {
@@ -202,6 +301,32 @@ mod tests {
         assert_eq!(statement.sid, Some("AllowS3GetObject".to_string()));
     }
 
+    #[test]
+    fn test_prefix_statement_ids() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(
+            Statement::allow(vec!["s3:GetObject".to_string()], vec!["*".to_string()])
+                .with_sid("S3ObjectRead".to_string()),
+        );
+        policy.add_statement(Statement::allow(
+            vec!["sqs:SendMessage".to_string()],
+            vec!["*".to_string()],
+        ));
+        policy.set_id("IamPolicyAutopilot-run".to_string());
+
+        policy.prefix_statement_ids("Ipa1a2b");
+
+        assert_eq!(policy.id, "IamPolicyAutopilot-run");
+        assert_eq!(
+            policy.statements[0].sid.as_deref(),
+            Some("Ipa1a2bS3ObjectRead")
+        );
+        assert_eq!(
+            policy.statements[1].sid.as_deref(),
+            Some("Ipa1a2bStatement2")
+        );
+    }
+
     #[test]
     fn test_policy_serialization() {
         let mut policy = IamPolicy::new();
@@ -275,6 +400,10 @@ mod tests {
         let policy_with_metadata = PolicyWithMetadata {
             policy,
             policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
         };
 
         let json = serde_json::to_string(&policy_with_metadata).unwrap();
@@ -283,6 +412,15 @@ mod tests {
         assert!(json.contains("\"Policy\":"));
         assert!(json.contains("\"PolicyType\":\"Identity\""));
         assert!(json.contains("\"Version\":\"2012-10-17\""));
+        assert!(!json.contains("AssumedRole"));
+
+        let assumed_role_policy = PolicyWithMetadata {
+            assumed_role: Some("arn:aws:iam::123456789012:role/Reader".to_string()),
+            credential_profile: None,
+            ..policy_with_metadata
+        };
+        let json = serde_json::to_string(&assumed_role_policy).unwrap();
+        assert!(json.contains("\"AssumedRole\":\"arn:aws:iam::123456789012:role/Reader\""));
     }
 
     #[rstest::rstest]
@@ -0,0 +1,168 @@
+//! Restriction of generated statements to the networks the workload calls AWS from
+//!
+//! Data perimeters only allow requests from expected networks: calls to services the
+//! workload reaches through VPC endpoints have to come through those endpoints
+//! (`aws:SourceVpce`, `aws:SourceVpc`), other calls from its public IP ranges
+//! (`aws:SourceIp`). The networks aren't visible in the code, so they're configured.
+//!
+//! Requests through a VPC endpoint don't have an `aws:SourceIp`, and requests over the
+//! internet neither `aws:SourceVpce` nor `aws:SourceVpc`, so a statement is restricted
+//! to one kind of network: the actions of the services reached through VPC endpoints
+//! to the endpoints, all others to the IP ranges.
+
+use crate::api::model::NetworkOrigins;
+use crate::enrichment::{Condition, Operator};
+use crate::policy_generation::{Effect, PolicyWithMetadata, Statement};
+
+/// Condition key of the VPC endpoint a request came through
+const SOURCE_VPCE_KEY: &str = "aws:SourceVpce";
+
+/// Condition key of the VPC of the endpoint a request came through
+const SOURCE_VPC_KEY: &str = "aws:SourceVpc";
+
+/// Condition key of the public IP address a request came from
+const SOURCE_IP_KEY: &str = "aws:SourceIp";
+
+/// Add the network origin conditions of `origins` to the Allow statements of `policies`
+///
+/// Statements granting actions of services reached through VPC endpoints and of other
+/// services are split in two, as they're restricted to different networks. Statements
+/// without a configured network are left as they are.
+pub(crate) fn restrict_network_origins(
+    policies: &mut [PolicyWithMetadata],
+    origins: &NetworkOrigins,
+) {
+    let through_endpoints = !origins.vpc_endpoints.is_empty() || !origins.vpcs.is_empty();
+    for policy in policies {
+        let mut statements = Vec::with_capacity(policy.policy.statements.len());
+        for statement in std::mem::take(&mut policy.policy.statements) {
+            if statement.effect != Effect::Allow {
+                statements.push(statement);
+                continue;
+            }
+            let (endpoint_actions, other_actions): (Vec<String>, Vec<String>) =
+                statement.action.iter().cloned().partition(|action| {
+                    through_endpoints && reached_through_endpoints(action, origins)
+                });
+            let split = !endpoint_actions.is_empty() && !other_actions.is_empty();
+            if !endpoint_actions.is_empty() {
+                let mut conditions = Vec::new();
+                if !origins.vpc_endpoints.is_empty() {
+                    conditions.push(condition(SOURCE_VPCE_KEY, &origins.vpc_endpoints));
+                }
+                if !origins.vpcs.is_empty() {
+                    conditions.push(condition(SOURCE_VPC_KEY, &origins.vpcs));
+                }
+                statements.push(with_origin(&statement, endpoint_actions, conditions));
+            }
+            if !other_actions.is_empty() {
+                let conditions = if origins.source_ips.is_empty() {
+                    vec![]
+                } else {
+                    vec![Condition {
+                        operator: Operator::IpAddress,
+                        key: SOURCE_IP_KEY.to_string(),
+                        values: origins.source_ips.clone(),
+                    }]
+                };
+                statements.push(Statement {
+                    // The Sid stays with the first part, the other one is named later
+                    sid: statement.sid.clone().filter(|_| !split),
+                    ..with_origin(&statement, other_actions, conditions)
+                });
+            }
+        }
+        policy.policy.statements = statements;
+    }
+}
+
+/// Whether `action` belongs to a service reached through the VPC endpoints
+fn reached_through_endpoints(action: &str, origins: &NetworkOrigins) -> bool {
+    origins.endpoint_services.is_empty()
+        || action.split_once(':').is_some_and(|(service, _)| {
+            origins
+                .endpoint_services
+                .iter()
+                .any(|endpoint_service| endpoint_service.eq_ignore_ascii_case(service))
+        })
+}
+
+fn condition(key: &str, values: &[String]) -> Condition {
+    Condition {
+        operator: Operator::StringEquals,
+        key: key.to_string(),
+        values: values.to_vec(),
+    }
+}
+
+/// `statement` granting `actions`, with `conditions` added to its own
+fn with_origin(
+    statement: &Statement,
+    actions: Vec<String>,
+    conditions: Vec<Condition>,
+) -> Statement {
+    let mut condition = statement.condition.clone();
+    condition.extend(conditions);
+    Statement {
+        action: actions,
+        condition,
+        ..statement.clone()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::policy_generation::{IamPolicy, PolicyType};
+
+    #[test]
+    fn test_statements_restricted_to_network_origins() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["s3:GetObject".to_string(), "sqs:SendMessage".to_string()],
+            vec!["*".to_string()],
+        ));
+        let mut policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+        let origins = NetworkOrigins {
+            vpc_endpoints: vec!["vpce-1a2b3c4d".to_string()],
+            vpcs: vec![],
+            source_ips: vec!["203.0.113.0/24".to_string()],
+            endpoint_services: vec!["s3".to_string()],
+        };
+
+        restrict_network_origins(&mut policies, &origins);
+
+        assert_eq!(
+            policies[0]
+                .policy
+                .statements
+                .iter()
+                .map(|statement| (
+                    statement.action.clone(),
+                    statement
+                        .condition
+                        .iter()
+                        .map(|condition| (condition.operator.clone(), condition.key.as_str()))
+                        .collect::<Vec<_>>()
+                ))
+                .collect::<Vec<_>>(),
+            vec![
+                (
+                    vec!["s3:GetObject".to_string()],
+                    vec![(Operator::StringEquals, SOURCE_VPCE_KEY)]
+                ),
+                (
+                    vec!["sqs:SendMessage".to_string()],
+                    vec![(Operator::IpAddress, SOURCE_IP_KEY)]
+                ),
+            ]
+        );
+    }
+}
@@ -0,0 +1,177 @@
+//! Policies of the named principals the source files are mapped to
+//!
+//! A single repository often backs several IAM roles: the workers of a queue run with a
+//! role of their own, the API with another, and neither should be granted what only the
+//! other calls. [`PrincipalMappings`] name the directories, files, entry points or globs
+//! each principal runs, and the calls of those files are granted in its policy. Calls of
+//! files no principal covers stay with the principal running the code.
+
+use std::path::Path;
+
+use crate::api::model::PrincipalMappings;
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::policy_generation::PolicyWithMetadata;
+
+/// Assign the policies of `enriched_calls` to the principals mapped to their source files
+///
+/// `policies` holds the policy of each of `enriched_calls`, in the same order. The policy
+/// of a call in a file several principals cover is copied to each of them; the calls are
+/// returned along, the call of each policy in the same order.
+pub(crate) fn assign_principals<'c, 'a>(
+    policies: Vec<PolicyWithMetadata>,
+    enriched_calls: Vec<&'c EnrichedSdkMethodCall<'a>>,
+    principal_mappings: &PrincipalMappings,
+) -> (Vec<PolicyWithMetadata>, Vec<&'c EnrichedSdkMethodCall<'a>>) {
+    if principal_mappings.principals.is_empty() {
+        return (policies, enriched_calls);
+    }
+    let mut assigned = Vec::with_capacity(policies.len());
+    let mut assigned_calls = Vec::with_capacity(enriched_calls.len());
+    for (policy, call) in policies.into_iter().zip(enriched_calls) {
+        let principals: Vec<&str> = call
+            .sdk_method_call
+            .metadata
+            .as_ref()
+            .map(|metadata| {
+                principal_mappings
+                    .principals
+                    .iter()
+                    .filter(|principal| {
+                        principal
+                            .paths
+                            .iter()
+                            .any(|path| covers(path, &metadata.location.file_path))
+                    })
+                    .map(|principal| principal.name.as_str())
+                    .collect()
+            })
+            .unwrap_or_default();
+        if principals.is_empty() {
+            assigned.push(policy);
+            assigned_calls.push(call);
+            continue;
+        }
+        for principal in principals {
+            assigned.push(PolicyWithMetadata {
+                named_principal: Some(principal.to_string()),
+                ..policy.clone()
+            });
+            assigned_calls.push(call);
+        }
+    }
+    (assigned, assigned_calls)
+}
+
+/// Whether the directory, file or glob `path` covers the source file `file`
+fn covers(path: &str, file: &Path) -> bool {
+    let file = file.strip_prefix("./").unwrap_or(file);
+    let path = path.strip_prefix("./").unwrap_or(path);
+    file.starts_with(path.trim_end_matches('/'))
+        || glob::Pattern::new(path).is_ok_and(|pattern| {
+            pattern.matches_path_with(
+                file,
+                glob::MatchOptions {
+                    require_literal_separator: true,
+                    ..glob::MatchOptions::new()
+                },
+            )
+        })
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::api::model::PrincipalMapping;
+    use crate::enrichment::Action;
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+    use crate::{Explanation, Location, SdkMethodCall};
+
+    #[test]
+    fn test_policies_are_assigned_to_the_principals_of_their_files() {
+        let calls: Vec<SdkMethodCall> = [
+            "workers/resize.py",
+            "./cmd/api/main.go",
+            "shared/queue.py",
+            "scripts/seed.py",
+        ]
+        .iter()
+        .map(|path| SdkMethodCall {
+            name: "send_message".to_string(),
+            possible_services: vec!["sqs".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "queue.send_message()".to_string(),
+                Location::new(PathBuf::from(path), (1, 1), (1, 20)),
+            )),
+        })
+        .collect();
+        let enriched_calls: Vec<EnrichedSdkMethodCall> = calls
+            .iter()
+            .map(|call| EnrichedSdkMethodCall {
+                method_name: call.name.clone(),
+                service: "sqs".to_string(),
+                actions: vec![Action::new(
+                    "sqs:SendMessage".to_string(),
+                    vec![],
+                    vec![],
+                    Explanation::default(),
+                )],
+                sdk_method_call: call,
+            })
+            .collect();
+        let policies = enriched_calls
+            .iter()
+            .map(|_| {
+                let mut policy = IamPolicy::new();
+                policy.add_statement(Statement::allow(
+                    vec!["sqs:SendMessage".to_string()],
+                    vec!["*".to_string()],
+                ));
+                PolicyWithMetadata {
+                    policy,
+                    policy_type: PolicyType::Identity,
+                    assumed_role: None,
+                    credential_profile: None,
+                    entry_point: None,
+                    named_principal: None,
+                }
+            })
+            .collect();
+        let principal_mappings = PrincipalMappings {
+            principals: vec![
+                PrincipalMapping {
+                    name: "worker-role".to_string(),
+                    paths: vec!["workers/".to_string(), "shared/*.py".to_string()],
+                },
+                PrincipalMapping {
+                    name: "api-role".to_string(),
+                    paths: vec!["cmd/api".to_string(), "shared".to_string()],
+                },
+            ],
+        };
+
+        let (assigned, assigned_calls) = assign_principals(
+            policies,
+            enriched_calls.iter().collect(),
+            &principal_mappings,
+        );
+
+        assert_eq!(
+            assigned
+                .iter()
+                .map(|policy| policy.named_principal.as_deref())
+                .collect::<Vec<_>>(),
+            vec![
+                Some("worker-role"),
+                Some("api-role"),
+                Some("worker-role"),
+                Some("api-role"),
+                None,
+            ]
+        );
+        assert_eq!(assigned_calls.len(), assigned.len());
+        assert!(std::ptr::eq(assigned_calls[3], &enriched_calls[2]));
+    }
+}
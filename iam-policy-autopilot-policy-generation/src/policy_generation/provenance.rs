@@ -0,0 +1,183 @@
+//! Provenance of the generated actions
+//!
+//! Reviewers asking why a policy grants an action, e.g. `kms:Decrypt`, need the calls
+//! requiring it. The provenance maps each action the generated policies grant to the
+//! resources it's granted on and the source locations and expressions of these calls,
+//! so the question is answered without rerunning the analysis.
+
+use std::collections::{BTreeMap, BTreeSet};
+
+use serde::{Deserialize, Serialize};
+
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::policy_generation::{Effect, PolicyWithMetadata};
+use crate::Location;
+
+/// A call requiring a generated action
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq, Eq, PartialOrd, Ord)]
+#[serde(rename_all = "PascalCase")]
+pub struct CallSite {
+    /// Source location of the call
+    pub location: Location,
+    /// Expression of the call, e.g. `kms.decrypt(CiphertextBlob=blob)`
+    pub expression: String,
+}
+
+/// Why the generated policies grant an action
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct ActionProvenance {
+    /// The granted action, e.g. `kms:Decrypt`, or the wildcard granting the calls' actions
+    pub action: String,
+    /// Resources the action is granted on
+    pub resources: Vec<String>,
+    /// Calls requiring the action, by source location
+    pub calls: Vec<CallSite>,
+}
+
+impl ActionProvenance {
+    /// Whether this is the provenance of `target`, an action or a resource ARN, either of
+    /// them possibly with wildcards, e.g. `kms:Decrypt`, `s3:Put*` or `arn:aws:s3:::bucket/*`
+    #[must_use]
+    pub fn explains(&self, target: &str) -> bool {
+        grants(&self.action, target)
+            || grants(target, &self.action)
+            || self
+                .resources
+                .iter()
+                .any(|resource| grants(resource, target) || grants(target, resource))
+    }
+}
+
+/// Provenance of the actions the Allow statements of `policies` grant, sorted by action
+pub(crate) fn action_provenance(
+    policies: &[PolicyWithMetadata],
+    enriched_calls: &[EnrichedSdkMethodCall<'_>],
+) -> Vec<ActionProvenance> {
+    let mut resources: BTreeMap<&str, BTreeSet<&str>> = BTreeMap::new();
+    for statement in policies
+        .iter()
+        .flat_map(|policy| &policy.policy.statements)
+        .filter(|statement| statement.effect == Effect::Allow)
+    {
+        for action in &statement.action {
+            resources
+                .entry(action)
+                .or_default()
+                .extend(statement.resource.iter().map(String::as_str));
+        }
+    }
+
+    resources
+        .into_iter()
+        .map(|(action, action_resources)| {
+            let calls: BTreeSet<CallSite> = enriched_calls
+                .iter()
+                .filter(|call| {
+                    call.actions
+                        .iter()
+                        .any(|required| grants(action, &required.name))
+                })
+                .filter_map(|call| call.sdk_method_call.metadata.as_ref())
+                .map(|metadata| CallSite {
+                    location: metadata.location.clone(),
+                    expression: metadata.expr.clone(),
+                })
+                .collect();
+            ActionProvenance {
+                action: action.to_string(),
+                resources: action_resources.into_iter().map(String::from).collect(),
+                calls: calls.into_iter().collect(),
+            }
+        })
+        .collect()
+}
+
+/// Whether the granted action, possibly with wildcards, grants `action`
+fn grants(granted: &str, action: &str) -> bool {
+    granted.eq_ignore_ascii_case(action)
+        || (granted.contains('*')
+            && regex::Regex::new(&format!(
+                "(?i)^{}$",
+                regex::escape(granted).replace(r"\*", ".*")
+            ))
+            .is_ok_and(|regex| regex.is_match(action)))
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+    use crate::SdkMethodCall;
+
+    #[test]
+    fn test_action_provenance() {
+        let sdk_call = SdkMethodCall {
+            name: "decrypt".to_string(),
+            possible_services: vec!["kms".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "kms.decrypt(CiphertextBlob=blob)".to_string(),
+                Location::new(PathBuf::from("app.py"), (8, 5), (8, 37)),
+            )),
+        };
+        let calls = vec![EnrichedSdkMethodCall {
+            method_name: "decrypt".to_string(),
+            service: "kms".to_string(),
+            actions: vec![Action::new(
+                "kms:Decrypt".to_string(),
+                vec![],
+                vec![],
+                Explanation::default(),
+            )],
+            sdk_method_call: &sdk_call,
+        }];
+
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["kms:Decrypt".to_string(), "s3:GetObject".to_string()],
+            vec!["*".to_string()],
+        ));
+        let policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+
+        let provenance = action_provenance(&policies, &calls);
+
+        assert_eq!(
+            provenance[0],
+            ActionProvenance {
+                action: "kms:Decrypt".to_string(),
+                resources: vec!["*".to_string()],
+                calls: vec![CallSite {
+                    location: Location::new(PathBuf::from("app.py"), (8, 5), (8, 37)),
+                    expression: "kms.decrypt(CiphertextBlob=blob)".to_string(),
+                }],
+            }
+        );
+        assert!(provenance[1].calls.is_empty());
+    }
+
+    #[test]
+    fn test_explains() {
+        let provenance = ActionProvenance {
+            action: "s3:Get*".to_string(),
+            resources: vec!["arn:aws:s3:::my-bucket/*".to_string()],
+            calls: vec![],
+        };
+
+        assert!(provenance.explains("s3:GetObject"));
+        assert!(provenance.explains("s3:*"));
+        assert!(provenance.explains("arn:aws:s3:::my-bucket/reports/2024.csv"));
+        assert!(!provenance.explains("s3:PutObject"));
+        assert!(!provenance.explains("arn:aws:s3:::other-bucket/key"));
+    }
+}
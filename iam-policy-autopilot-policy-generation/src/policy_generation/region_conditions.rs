@@ -0,0 +1,270 @@
+//! Restriction of generated statements to the regions the workload uses
+//!
+//! Security baselines commonly require every statement of a workload role to be limited
+//! with `aws:RequestedRegion`, so leaked credentials can't be used in other regions. The
+//! regions are given as an allow-list or detected from the regions the code configures
+//! its clients with: the region arguments of the calls constructing and configuring
+//! clients, e.g. `boto3.client("s3", region_name="eu-west-1")`, found in the syntax tree.
+
+use std::collections::BTreeSet;
+use std::sync::OnceLock;
+
+use log::debug;
+use regex::Regex;
+
+use crate::enrichment::{Condition, Operator};
+use crate::extraction::shared::source_syntax::Call;
+use crate::extraction::shared::SourceSyntax;
+use crate::extraction::ParameterValue;
+use crate::policy_generation::{Effect, PolicyWithMetadata};
+use crate::SourceFile;
+
+/// Condition key of the region a request is sent to
+const REQUESTED_REGION_KEY: &str = "aws:RequestedRegion";
+
+/// Services without regional endpoints, whose requests are sent to the region of
+/// their global endpoint
+const GLOBAL_SERVICES: &[&str] = &[
+    "account",
+    "budgets",
+    "ce",
+    "cloudfront",
+    "globalaccelerator",
+    "iam",
+    "organizations",
+    "route53",
+    "route53domains",
+    "shield",
+    "support",
+    "waf",
+];
+
+/// Regex matching a region name, e.g. `eu-west-1` or `us-gov-west-1`
+static REGION_REGEX: OnceLock<Regex> = OnceLock::new();
+
+/// Regex matching a region constant of the AWS SDK for Java, e.g. `Region.EU_WEST_1`
+static JAVA_REGION_CONSTANT_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_region_regex() -> &'static Regex {
+    REGION_REGEX.get_or_init(|| {
+        Regex::new(r"^[a-z]{2}(?:-gov|-isob?)?-[a-z]+-\d$").expect("Invalid region regex")
+    })
+}
+
+fn get_java_region_constant_regex() -> &'static Regex {
+    JAVA_REGION_CONSTANT_REGEX.get_or_init(|| {
+        Regex::new(r"^Regions?\.([A-Z]{2}(?:_GOV|_ISOB?)?_[A-Z]+_\d)$")
+            .expect("Invalid Java region constant regex")
+    })
+}
+
+/// Regions `source_files` configure their clients with, ordered by name
+///
+/// The regions are the literal values of the region arguments of calls: keyword arguments
+/// and properties named region, as in `boto3.client("s3", region_name="eu-west-1")` and
+/// `new S3Client({ region: 'eu-west-1' })`, and the first argument of region setters such
+/// as `config.WithRegion("us-west-2")`, `.region(Region.AP_SOUTHEAST_2)` and
+/// `Region.of("eu-north-1")`.
+pub(crate) fn detect_client_regions(source_files: &[SourceFile]) -> Vec<String> {
+    let mut regions = BTreeSet::new();
+    for source_file in source_files {
+        let syntax = SourceSyntax::of(source_file);
+        for call in &syntax.calls {
+            regions.extend(call_regions(call));
+        }
+    }
+    debug!("Detected client regions: {regions:?}");
+    regions.into_iter().collect()
+}
+
+/// The regions `call` configures
+fn call_regions(call: &Call) -> Vec<String> {
+    let is_region_setter = {
+        let method = call.method.to_ascii_lowercase();
+        method.ends_with("region") || (method == "of" && call.callee.ends_with("Region.of"))
+    };
+    call.arguments
+        .iter()
+        .enumerate()
+        .filter(|(position, argument)| match &argument.name {
+            Some(name) => matches!(
+                name.replace('_', "").to_ascii_lowercase().as_str(),
+                "region" | "regionname"
+            ),
+            None => is_region_setter && *position == 0,
+        })
+        .filter_map(|(_, argument)| region(&argument.value))
+        .collect()
+}
+
+/// The region of a region argument: a region name literal or a Java region constant
+fn region(value: &ParameterValue) -> Option<String> {
+    match value {
+        ParameterValue::Resolved(name) => {
+            let name = name.to_ascii_lowercase();
+            get_region_regex().is_match(&name).then_some(name)
+        }
+        ParameterValue::Unresolved(expression) => get_java_region_constant_regex()
+            .captures(expression.trim())
+            .map(|captures| captures[1].to_ascii_lowercase().replace('_', "-")),
+    }
+}
+
+/// Limit the Allow statements of `policies` to `regions` with `aws:RequestedRegion`
+///
+/// Statements granting actions of global services such as IAM also allow the region of
+/// the global endpoint of `partition`, where their requests are sent. Statements that
+/// already have an `aws:RequestedRegion` condition are left as they are.
+pub(crate) fn restrict_regions(
+    policies: &mut [PolicyWithMetadata],
+    regions: &[String],
+    partition: &str,
+) {
+    for policy in policies {
+        for statement in &mut policy.policy.statements {
+            if statement.effect != Effect::Allow
+                || statement
+                    .condition
+                    .iter()
+                    .any(|condition| condition.key == REQUESTED_REGION_KEY)
+            {
+                continue;
+            }
+            let mut values: BTreeSet<String> = regions.iter().cloned().collect();
+            if statement
+                .action
+                .iter()
+                .any(|action| is_global_action(action))
+            {
+                values.insert(global_endpoint_region(partition).to_string());
+            }
+            statement.condition.push(Condition {
+                operator: Operator::StringEquals,
+                key: REQUESTED_REGION_KEY.to_string(),
+                values: values.into_iter().collect(),
+            });
+        }
+    }
+}
+
+fn is_global_action(action: &str) -> bool {
+    action
+        .split_once(':')
+        .is_some_and(|(service, _)| GLOBAL_SERVICES.contains(&service))
+}
+
+/// Region of the endpoints of global services in `partition`
+fn global_endpoint_region(partition: &str) -> &'static str {
+    match partition {
+        "aws-cn" => "cn-north-1",
+        "aws-us-gov" => "us-gov-west-1",
+        _ => "us-east-1",
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+    use crate::Language;
+
+    #[test]
+    fn test_client_regions_detected() {
+        let source_files = [
+            SourceFile::with_language(
+                PathBuf::from("app.py"),
+                "s3 = boto3.client(\"s3\", region_name=\"eu-west-1\")\n".to_string(),
+                Language::Python,
+            ),
+            SourceFile::with_language(
+                PathBuf::from("main.go"),
+                "package main\n\nfunc main() {\n\
+                 \tcfg, _ := config.LoadDefaultConfig(\n\
+                 \t\tctx, config.WithRegion(\"us-gov-west-1\"))\n}\n"
+                    .to_string(),
+                Language::Go,
+            ),
+            SourceFile::with_language(
+                PathBuf::from("App.java"),
+                "S3Client.builder().region(Region.AP_SOUTHEAST_2).build();\n".to_string(),
+                Language::Java,
+            ),
+            SourceFile::with_language(
+                PathBuf::from("index.js"),
+                "const client = new S3Client({ region: 'eu-west-1' });\n".to_string(),
+                Language::JavaScript,
+            ),
+        ];
+
+        assert_eq!(
+            detect_client_regions(&source_files),
+            vec!["ap-southeast-2", "eu-west-1", "us-gov-west-1"]
+        );
+    }
+
+    #[test]
+    fn test_regions_outside_client_arguments_are_ignored() {
+        let source_files = [
+            SourceFile::with_language(
+                PathBuf::from("app.py"),
+                "# s3 = boto3.client(\"s3\", region_name=\"ap-south-1\")\n\
+                 HELP = 'region_name=\"sa-east-1\" pins the region'\n\
+                 s3 = boto3.client(\"s3\", region_name=REGION)\n\
+                 log.info(\"copying from eu-central-1\")\n"
+                    .to_string(),
+                Language::Python,
+            ),
+            SourceFile::with_language(
+                PathBuf::from("index.js"),
+                "// const client = new S3Client({ region: 'me-south-1' });\n\
+                 const client = new S3Client({ region: 'eu-west-1', endpoint: 'us-east-2' });\n"
+                    .to_string(),
+                Language::JavaScript,
+            ),
+        ];
+
+        assert_eq!(detect_client_regions(&source_files), vec!["eu-west-1"]);
+    }
+
+    #[test]
+    fn test_statements_restricted_to_regions() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["s3:GetObject".to_string()],
+            vec!["*".to_string()],
+        ));
+        policy.add_statement(Statement::allow(
+            vec!["iam:GetRole".to_string()],
+            vec!["*".to_string()],
+        ));
+        let mut policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+
+        restrict_regions(&mut policies, &["eu-west-1".to_string()], "aws");
+
+        let values: Vec<_> = policies[0]
+            .policy
+            .statements
+            .iter()
+            .map(|statement| {
+                assert_eq!(statement.condition.len(), 1);
+                statement.condition[0].values.clone()
+            })
+            .collect();
+        assert_eq!(
+            values,
+            vec![
+                vec!["eu-west-1".to_string()],
+                vec!["eu-west-1".to_string(), "us-east-1".to_string()],
+            ]
+        );
+    }
+}
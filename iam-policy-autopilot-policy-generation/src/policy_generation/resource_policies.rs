@@ -0,0 +1,395 @@
+//! Resource-based policies implied by the analyzed code
+//!
+//! Some calls connect services that then call each other: subscribing a queue to a
+//! topic, sending bucket notifications or routing EventBridge rules to functions, queues
+//! or topics. Such deliveries are authorized by the resource policy of the destination,
+//! e.g. a queue policy allowing `sns.amazonaws.com` to send messages from the topic, or
+//! a Lambda permission. Keys of other accounts the policies grant KMS actions on need
+//! key policy statements allowing the workload as well.
+//!
+//! Destinations are recognized by the literal ARNs of the calls; their sources (topic,
+//! bucket or rule) are named by literal arguments, or left as `{{SourceArn}}`.
+
+use std::collections::{BTreeMap, BTreeSet};
+use std::sync::OnceLock;
+
+use regex::Regex;
+use serde::Serialize;
+
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::extraction::{Parameter, ParameterValue};
+use crate::policy_generation::{Effect, PolicyWithMetadata};
+
+/// Principal of the workload the policies are generated for, when its role isn't given
+const WORKLOAD_ROLE_PLACEHOLDER: &str = "{{WorkloadRoleArn}}";
+
+/// Source of a delivery the code configures, when no literal argument names it
+const SOURCE_ARN_PLACEHOLDER: &str = "{{SourceArn}}";
+
+/// Kind of a resource-based policy
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq, PartialOrd, Ord)]
+pub enum ResourcePolicyType {
+    /// Policy of an SQS queue, set with `SetQueueAttributes`
+    QueuePolicy,
+    /// Policy of an SNS topic, set with `SetTopicAttributes`
+    TopicPolicy,
+    /// Policy of a KMS key, set with `PutKeyPolicy`
+    KeyPolicy,
+    /// Policy of a Lambda function, whose statements are added with `AddPermission`
+    LambdaPermission,
+}
+
+/// A resource-based policy of a resource the analyzed code uses
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct ResourcePolicy {
+    /// ARN of the resource the policy is for
+    pub resource_arn: String,
+    /// Kind of the policy
+    pub policy_type: ResourcePolicyType,
+    /// The policy document
+    pub policy: ResourcePolicyDocument,
+}
+
+/// A resource-based policy document
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct ResourcePolicyDocument {
+    /// Policy language version
+    pub version: String,
+    /// Statements allowing the principals that access the resource
+    pub statement: Vec<ResourceStatement>,
+}
+
+/// A statement of a resource-based policy
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct ResourceStatement {
+    /// Allow, for the principals
+    pub effect: Effect,
+    /// Allowed principals by type, e.g. `Service` principals or `AWS` role ARNs
+    pub principal: BTreeMap<String, Vec<String>>,
+    /// Actions the principals may call
+    pub action: Vec<String>,
+    /// The resource, or `*` for key policies
+    pub resource: Vec<String>,
+    /// Conditions by operator and key, e.g. the `aws:SourceArn` of service principals
+    #[serde(skip_serializing_if = "BTreeMap::is_empty")]
+    pub condition: BTreeMap<String, BTreeMap<String, Vec<String>>>,
+}
+
+/// A call configuring deliveries of a service to the resources it names
+struct DeliveryPattern {
+    /// Action of the call, e.g. `sns:Subscribe`
+    action: &'static str,
+    /// Service principal delivering, e.g. `sns.amazonaws.com`
+    principal: &'static str,
+    /// Argument naming the source of the deliveries
+    source_argument: &'static str,
+}
+
+/// Calls configuring deliveries
+const DELIVERY_PATTERNS: &[DeliveryPattern] = &[
+    DeliveryPattern {
+        action: "sns:Subscribe",
+        principal: "sns.amazonaws.com",
+        source_argument: "TopicArn",
+    },
+    DeliveryPattern {
+        action: "s3:PutBucketNotification",
+        principal: "s3.amazonaws.com",
+        source_argument: "Bucket",
+    },
+    DeliveryPattern {
+        action: "events:PutTargets",
+        principal: "events.amazonaws.com",
+        source_argument: "Rule",
+    },
+];
+
+/// Regex matching literal ARNs of the destinations of deliveries
+static DESTINATION_ARN_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_destination_arn_regex() -> &'static Regex {
+    DESTINATION_ARN_REGEX.get_or_init(|| {
+        Regex::new(r"arn:aws[a-z-]*:(lambda|sqs|sns):[a-z0-9-]+:\d{12}:[A-Za-z0-9_.:/-]+")
+            .expect("Invalid destination ARN regex")
+    })
+}
+
+/// Resource policies allowing the deliveries `enriched_calls` configure and the KMS
+/// actions `policies` grant on keys of other accounts, by resource ARN
+///
+/// Sources named by their names, such as rules, are in the `partition`, `region` and
+/// `account` of the workload. Key policies allow the role or profile of the policy
+/// granting the actions, or `workload_role` for the policy of the analyzed code itself;
+/// `{{WorkloadRoleArn}}` when not given.
+pub(crate) fn resource_policies(
+    policies: &[PolicyWithMetadata],
+    enriched_calls: &[EnrichedSdkMethodCall<'_>],
+    partition: &str,
+    region: &str,
+    account: &str,
+    workload_role: Option<&str>,
+) -> Vec<ResourcePolicy> {
+    // Resource ARN to its statements, by principal, action and source
+    let mut allowed: BTreeMap<String, BTreeSet<(String, String, Option<String>)>> = BTreeMap::new();
+
+    for call in enriched_calls {
+        let Some(pattern) = DELIVERY_PATTERNS.iter().find(|pattern| {
+            call.actions
+                .iter()
+                .any(|action| action.name.eq_ignore_ascii_case(pattern.action))
+        }) else {
+            continue;
+        };
+        let Some(metadata) = &call.sdk_method_call.metadata else {
+            continue;
+        };
+        let source = metadata
+            .parameters
+            .iter()
+            .find_map(|parameter| match parameter {
+                Parameter::Keyword {
+                    name,
+                    value: ParameterValue::Resolved(value),
+                    ..
+                } if name.eq_ignore_ascii_case(pattern.source_argument) => Some(value.as_str()),
+                _ => None,
+            })
+            .map(|source| source_arn(pattern.action, source, partition, region, account));
+        for destination in get_destination_arn_regex().find_iter(&metadata.expr) {
+            let destination = destination.as_str();
+            if Some(destination) == source.as_deref() {
+                continue;
+            }
+            let Some(action) = delivery_action(destination) else {
+                continue;
+            };
+            allowed.entry(destination.to_string()).or_default().insert((
+                pattern.principal.to_string(),
+                action.to_string(),
+                Some(
+                    source
+                        .clone()
+                        .unwrap_or_else(|| SOURCE_ARN_PLACEHOLDER.to_string()),
+                ),
+            ));
+        }
+    }
+
+    let workload_role = workload_role.unwrap_or(WORKLOAD_ROLE_PLACEHOLDER);
+    for policy in policies {
+        let principal = policy.principal(workload_role);
+        let statements = policy
+            .policy
+            .statements
+            .iter()
+            .filter(|statement| statement.effect == Effect::Allow);
+        for statement in statements {
+            let keys: Vec<&String> = statement
+                .resource
+                .iter()
+                .filter(|arn| is_foreign_key_arn(arn, account))
+                .collect();
+            for action in statement
+                .action
+                .iter()
+                .filter(|action| action.to_ascii_lowercase().starts_with("kms:"))
+            {
+                for key in &keys {
+                    allowed.entry((*key).clone()).or_default().insert((
+                        principal.clone(),
+                        action.clone(),
+                        None,
+                    ));
+                }
+            }
+        }
+    }
+
+    allowed
+        .into_iter()
+        .filter_map(|(resource_arn, statements)| {
+            let policy_type = policy_type(&resource_arn)?;
+            // Statements of the same principal and source share their actions
+            let mut grouped: BTreeMap<(String, Option<String>), Vec<String>> = BTreeMap::new();
+            for (principal, action, source) in statements {
+                grouped.entry((principal, source)).or_default().push(action);
+            }
+            let statement = grouped
+                .into_iter()
+                .map(|((principal, source), action)| {
+                    let (principal_type, resource, condition) = match source {
+                        Some(source) => (
+                            "Service",
+                            resource_arn.clone(),
+                            BTreeMap::from([(
+                                "ArnLike".to_string(),
+                                BTreeMap::from([("aws:SourceArn".to_string(), vec![source])]),
+                            )]),
+                        ),
+                        None => ("AWS", "*".to_string(), BTreeMap::new()),
+                    };
+                    ResourceStatement {
+                        effect: Effect::Allow,
+                        principal: BTreeMap::from([(principal_type.to_string(), vec![principal])]),
+                        action,
+                        resource: vec![resource],
+                        condition,
+                    }
+                })
+                .collect();
+            Some(ResourcePolicy {
+                resource_arn,
+                policy_type,
+                policy: ResourcePolicyDocument {
+                    version: "2012-10-17".to_string(),
+                    statement,
+                },
+            })
+        })
+        .collect()
+}
+
+/// ARN of the source of deliveries named by the literal `argument` of `action`'s call
+fn source_arn(
+    action: &str,
+    argument: &str,
+    partition: &str,
+    region: &str,
+    account: &str,
+) -> String {
+    if argument.starts_with("arn:") {
+        return argument.to_string();
+    }
+    match action {
+        "s3:PutBucketNotification" => format!("arn:{partition}:s3:::{argument}"),
+        "events:PutTargets" => format!("arn:{partition}:events:{region}:{account}:rule/{argument}"),
+        _ => argument.to_string(),
+    }
+}
+
+/// Action deliveries to the destination `arn` call
+fn delivery_action(arn: &str) -> Option<&'static str> {
+    match arn.split(':').nth(2)? {
+        "lambda" => Some("lambda:InvokeFunction"),
+        "sqs" => Some("sqs:SendMessage"),
+        "sns" => Some("sns:Publish"),
+        _ => None,
+    }
+}
+
+/// Kind of the resource policy of the resource `arn`
+fn policy_type(arn: &str) -> Option<ResourcePolicyType> {
+    match arn.split(':').nth(2)? {
+        "lambda" => Some(ResourcePolicyType::LambdaPermission),
+        "sqs" => Some(ResourcePolicyType::QueuePolicy),
+        "sns" => Some(ResourcePolicyType::TopicPolicy),
+        "kms" => Some(ResourcePolicyType::KeyPolicy),
+        _ => None,
+    }
+}
+
+/// Whether `arn` names one KMS key of an account other than `account`
+fn is_foreign_key_arn(arn: &str, account: &str) -> bool {
+    let mut parts = arn.splitn(6, ':');
+    parts.next() == Some("arn")
+        && parts.nth(1) == Some("kms")
+        && parts.nth(1).is_some_and(|key_account| {
+            key_account.len() == 12
+                && key_account.bytes().all(|b| b.is_ascii_digit())
+                && key_account != account
+        })
+        && parts
+            .next()
+            .is_some_and(|resource| resource.starts_with("key/") && !resource.contains(['*', '{']))
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+    use crate::{Location, SdkMethodCall};
+
+    #[test]
+    fn test_resource_policies() {
+        let topic = "arn:aws:sns:us-east-1:123456789012:orders";
+        let queue = "arn:aws:sqs:us-east-1:123456789012:order-events";
+        let key = "arn:aws:kms:us-east-1:210987654321:key/1234abcd-12ab-34cd-56ef-1234567890ab";
+        let sdk_call = SdkMethodCall {
+            name: "subscribe".to_string(),
+            possible_services: vec!["sns".to_string()],
+            metadata: Some(
+                SdkMethodCallMetadata::new(
+                    format!("sns.subscribe(TopicArn=\"{topic}\", Endpoint=\"{queue}\")"),
+                    Location::new(PathBuf::from("app.py"), (4, 1), (4, 70)),
+                )
+                .with_parameters(vec![Parameter::Keyword {
+                    name: "TopicArn".to_string(),
+                    value: ParameterValue::Resolved(topic.to_string()),
+                    position: 0,
+                    type_annotation: None,
+                }]),
+            ),
+        };
+        let calls = vec![EnrichedSdkMethodCall {
+            method_name: "subscribe".to_string(),
+            service: "sns".to_string(),
+            actions: vec![Action::new(
+                "sns:Subscribe".to_string(),
+                vec![],
+                vec![],
+                Explanation::default(),
+            )],
+            sdk_method_call: &sdk_call,
+        }];
+
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["kms:Decrypt".to_string()],
+            vec![key.to_string()],
+        ));
+        let policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+        let resource_policies =
+            resource_policies(&policies, &calls, "aws", "us-east-1", "123456789012", None);
+
+        assert_eq!(resource_policies.len(), 2);
+        assert_eq!(resource_policies[0].resource_arn, key);
+        assert_eq!(
+            resource_policies[0].policy_type,
+            ResourcePolicyType::KeyPolicy
+        );
+        assert_eq!(
+            resource_policies[0].policy.statement[0].principal["AWS"],
+            vec![WORKLOAD_ROLE_PLACEHOLDER]
+        );
+        assert_eq!(
+            resource_policies[1].policy.statement[0],
+            ResourceStatement {
+                effect: Effect::Allow,
+                principal: BTreeMap::from([(
+                    "Service".to_string(),
+                    vec!["sns.amazonaws.com".to_string()]
+                )]),
+                action: vec!["sqs:SendMessage".to_string()],
+                resource: vec![queue.to_string()],
+                condition: BTreeMap::from([(
+                    "ArnLike".to_string(),
+                    BTreeMap::from([("aws:SourceArn".to_string(), vec![topic.to_string()])])
+                )]),
+            }
+        );
+    }
+}
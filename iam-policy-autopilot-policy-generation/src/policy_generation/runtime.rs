@@ -0,0 +1,120 @@
+//! Detection of the compute runtime the analyzed code runs on
+//!
+//! A complete role definition needs a trust policy allowing the service that runs the
+//! code to assume the role. The runtime is recognized by what the code uses of it: a
+//! Lambda handler or the Lambda runtime library, the ECS task metadata endpoint, or
+//! the EC2 instance metadata service.
+
+use log::debug;
+use serde::Serialize;
+
+use crate::policy_generation::entry_points::is_lambda_handler;
+use crate::SourceFile;
+
+/// Code using the Lambda runtime, besides handlers named by convention
+const LAMBDA_MARKERS: &[&str] = &[
+    "github.com/aws/aws-lambda-go/lambda",
+    "AWS_LAMBDA_FUNCTION_NAME",
+    "aws_lambda_powertools",
+    "@aws-lambda-powertools/",
+];
+
+/// Code reading the ECS task metadata endpoint
+const ECS_MARKERS: &[&str] = &["ECS_CONTAINER_METADATA_URI"];
+
+/// Code reading the EC2 instance metadata service
+const EC2_MARKERS: &[&str] = &[
+    "169.254.169.254",
+    "github.com/aws/aws-sdk-go-v2/feature/ec2/imds",
+    "ec2_metadata",
+    "Ec2MetadataClient",
+];
+
+/// Compute service running the analyzed code, which assumes its role
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq)]
+pub enum Runtime {
+    /// AWS Lambda function
+    Lambda,
+    /// Amazon ECS task, including Fargate
+    Ecs,
+    /// Amazon EC2 instance, through an instance profile
+    Ec2,
+}
+
+impl Runtime {
+    /// Service principal assuming the role of the code
+    #[must_use]
+    pub const fn service_principal(self) -> &'static str {
+        match self {
+            Self::Lambda => "lambda.amazonaws.com",
+            Self::Ecs => "ecs-tasks.amazonaws.com",
+            Self::Ec2 => "ec2.amazonaws.com",
+        }
+    }
+
+    /// Names of the AWS managed policies every role of the runtime needs, e.g. for a
+    /// Lambda function to write its logs
+    #[must_use]
+    pub const fn managed_policies(self) -> &'static [&'static str] {
+        match self {
+            Self::Lambda => &["service-role/AWSLambdaBasicExecutionRole"],
+            Self::Ecs | Self::Ec2 => &[],
+        }
+    }
+}
+
+/// The runtime `source_files` run on, if recognized
+///
+/// Lambda takes precedence over ECS and ECS over EC2, since code written for a more
+/// specific runtime can still read the metadata of a more general one.
+pub(crate) fn detect_runtime(source_files: &[SourceFile]) -> Option<Runtime> {
+    let uses = |markers: &[&str]| {
+        source_files.iter().any(|source_file| {
+            markers
+                .iter()
+                .any(|marker| source_file.content.contains(marker))
+        })
+    };
+    let runtime = if source_files.iter().any(is_lambda_handler) || uses(LAMBDA_MARKERS) {
+        Some(Runtime::Lambda)
+    } else if uses(ECS_MARKERS) {
+        Some(Runtime::Ecs)
+    } else if uses(EC2_MARKERS) {
+        Some(Runtime::Ec2)
+    } else {
+        None
+    };
+    debug!("Detected runtime: {runtime:?}");
+    runtime
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::Language;
+
+    fn source_file(content: &str) -> SourceFile {
+        SourceFile::with_language(
+            PathBuf::from("app.py"),
+            content.to_string(),
+            Language::Python,
+        )
+    }
+
+    #[test]
+    fn test_runtime_detected_from_code() {
+        let handler = source_file("def lambda_handler(event, context):\n    pass\n");
+        let ecs = source_file("uri = os.environ[\"ECS_CONTAINER_METADATA_URI_V4\"]\n");
+        let ec2 = source_file("requests.get(\"http://169.254.169.254/latest/meta-data/\")\n");
+
+        assert_eq!(
+            detect_runtime(&[ec2.clone(), handler]),
+            Some(Runtime::Lambda)
+        );
+        assert_eq!(detect_runtime(&[ec2.clone(), ecs]), Some(Runtime::Ecs));
+        assert_eq!(detect_runtime(&[ec2]), Some(Runtime::Ec2));
+        assert_eq!(detect_runtime(&[source_file("import boto3\n")]), None);
+    }
+}
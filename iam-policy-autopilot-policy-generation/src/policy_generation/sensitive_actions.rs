@@ -0,0 +1,391 @@
+//! Report of privileged and escalation-prone actions in generated statements
+//!
+//! A few actions deserve a security review wherever they're granted: they change who
+//! can do what (`iam:PutRolePolicy`, `s3:PutBucketPolicy`), destroy data beyond
+//! recovery (`kms:ScheduleKeyDeletion`) or hide activity (`cloudtrail:StopLogging`).
+//! Others only do on all resources, such as `sts:AssumeRole` on `*`. Each finding names
+//! the statement, a severity, and the source locations of the calls requiring it, so
+//! reviewers can focus on the risky parts of a policy.
+
+use std::collections::BTreeSet;
+
+use serde::Serialize;
+
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::policy_generation::{Effect, PolicyWithMetadata};
+use crate::Location;
+
+/// Severity of a sensitive action
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq, PartialOrd, Ord)]
+pub enum Severity {
+    /// The action can change who can do what, or let the code escalate its privileges
+    Critical,
+    /// The action can make data public or unrecoverable, or disable auditing
+    High,
+    /// The action is commonly abused together with others, e.g. to run code as a role
+    Medium,
+}
+
+/// A sensitive action, which is only flagged if granted on `*` when `unscoped_only`
+struct SensitiveActionRule {
+    action: &'static str,
+    unscoped_only: bool,
+    severity: Severity,
+    reason: &'static str,
+}
+
+const fn rule(
+    action: &'static str,
+    unscoped_only: bool,
+    severity: Severity,
+    reason: &'static str,
+) -> SensitiveActionRule {
+    SensitiveActionRule {
+        action,
+        unscoped_only,
+        severity,
+        reason,
+    }
+}
+
+/// Sensitive actions, by decreasing severity; a granted action is flagged by the first
+/// rule it grants
+const SENSITIVE_ACTIONS: &[SensitiveActionRule] = &[
+    rule(
+        "*",
+        false,
+        Severity::Critical,
+        "Grants every action of every service",
+    ),
+    rule(
+        "iam:*",
+        false,
+        Severity::Critical,
+        "Grants every IAM action, including creating administrators",
+    ),
+    rule(
+        "iam:CreatePolicyVersion",
+        false,
+        Severity::Critical,
+        "Can replace the permissions of managed policies, escalating privileges",
+    ),
+    rule(
+        "iam:SetDefaultPolicyVersion",
+        false,
+        Severity::Critical,
+        "Can restore broader versions of managed policies, escalating privileges",
+    ),
+    rule(
+        "iam:AttachRolePolicy",
+        false,
+        Severity::Critical,
+        "Can attach any managed policy to roles, escalating privileges",
+    ),
+    rule(
+        "iam:AttachUserPolicy",
+        false,
+        Severity::Critical,
+        "Can attach any managed policy to users, escalating privileges",
+    ),
+    rule(
+        "iam:AttachGroupPolicy",
+        false,
+        Severity::Critical,
+        "Can attach any managed policy to groups, escalating privileges",
+    ),
+    rule(
+        "iam:PutRolePolicy",
+        false,
+        Severity::Critical,
+        "Can grant roles any permission inline, escalating privileges",
+    ),
+    rule(
+        "iam:PutUserPolicy",
+        false,
+        Severity::Critical,
+        "Can grant users any permission inline, escalating privileges",
+    ),
+    rule(
+        "iam:PutGroupPolicy",
+        false,
+        Severity::Critical,
+        "Can grant groups any permission inline, escalating privileges",
+    ),
+    rule(
+        "iam:UpdateAssumeRolePolicy",
+        false,
+        Severity::Critical,
+        "Can let any principal assume roles",
+    ),
+    rule(
+        "iam:CreateAccessKey",
+        false,
+        Severity::Critical,
+        "Can create credentials of other users",
+    ),
+    rule(
+        "iam:CreateLoginProfile",
+        false,
+        Severity::Critical,
+        "Can set console passwords of other users",
+    ),
+    rule(
+        "iam:UpdateLoginProfile",
+        false,
+        Severity::Critical,
+        "Can change console passwords of other users",
+    ),
+    rule(
+        "iam:AddUserToGroup",
+        false,
+        Severity::Critical,
+        "Can add users to more privileged groups",
+    ),
+    rule(
+        "iam:PassRole",
+        true,
+        Severity::Critical,
+        "Can pass any role to services, running code with its permissions",
+    ),
+    rule(
+        "kms:ScheduleKeyDeletion",
+        false,
+        Severity::High,
+        "Deleting a key makes the data it encrypts unrecoverable",
+    ),
+    rule(
+        "kms:PutKeyPolicy",
+        false,
+        Severity::High,
+        "Can change who may use or manage keys",
+    ),
+    rule(
+        "kms:CreateGrant",
+        true,
+        Severity::High,
+        "Can delegate the use of any key",
+    ),
+    rule(
+        "sts:AssumeRole",
+        true,
+        Severity::High,
+        "Can assume any role that trusts the account",
+    ),
+    rule(
+        "s3:PutBucketPolicy",
+        false,
+        Severity::High,
+        "Can make buckets public or grant other accounts access",
+    ),
+    rule(
+        "s3:DeleteBucketPolicy",
+        false,
+        Severity::High,
+        "Can remove the protections of bucket policies",
+    ),
+    rule(
+        "s3:PutBucketAcl",
+        false,
+        Severity::High,
+        "Can make buckets public through ACLs",
+    ),
+    rule(
+        "s3:PutBucketPublicAccessBlock",
+        false,
+        Severity::High,
+        "Can turn off the public access block of buckets",
+    ),
+    rule(
+        "s3:PutAccountPublicAccessBlock",
+        false,
+        Severity::High,
+        "Can turn off the public access block of the account",
+    ),
+    rule(
+        "cloudtrail:StopLogging",
+        false,
+        Severity::High,
+        "Disables the audit log of the account",
+    ),
+    rule(
+        "cloudtrail:DeleteTrail",
+        false,
+        Severity::High,
+        "Deletes the audit log of the account",
+    ),
+    rule(
+        "organizations:LeaveOrganization",
+        false,
+        Severity::High,
+        "Removes the account from the guardrails of its organization",
+    ),
+    rule(
+        "lambda:UpdateFunctionCode",
+        true,
+        Severity::Medium,
+        "Can run any code as the execution roles of all functions",
+    ),
+    rule(
+        "lambda:AddPermission",
+        false,
+        Severity::Medium,
+        "Can let other principals invoke functions",
+    ),
+    rule(
+        "secretsmanager:GetSecretValue",
+        true,
+        Severity::Medium,
+        "Can read every secret of the account",
+    ),
+    rule(
+        "ssm:SendCommand",
+        true,
+        Severity::Medium,
+        "Can run commands on every managed instance",
+    ),
+];
+
+/// A sensitive action granted by a generated statement
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct SensitiveAction {
+    /// Index of the policy in the result
+    pub policy_index: usize,
+    /// Sid of the statement
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub sid: Option<String>,
+    /// The granted action, e.g. `iam:PassRole`, or the wildcard granting it
+    pub action: String,
+    /// Resources of the statement
+    pub resources: Vec<String>,
+    /// Severity of the action
+    pub severity: Severity,
+    /// Why the action is sensitive
+    pub reason: String,
+    /// Source locations of the calls requiring the action
+    pub locations: Vec<Location>,
+}
+
+/// Flag the sensitive actions of the statements of `policies`, in statement order
+pub(crate) fn sensitive_actions(
+    policies: &[PolicyWithMetadata],
+    enriched_calls: &[EnrichedSdkMethodCall<'_>],
+) -> Vec<SensitiveAction> {
+    let mut findings = Vec::new();
+    for (policy_index, policy) in policies.iter().enumerate() {
+        for statement in &policy.policy.statements {
+            if statement.effect != Effect::Allow {
+                continue;
+            }
+            let unscoped = statement.resource.iter().any(|resource| resource == "*");
+            for action in &statement.action {
+                let Some(rule) = SENSITIVE_ACTIONS
+                    .iter()
+                    .find(|rule| (unscoped || !rule.unscoped_only) && grants(action, rule.action))
+                else {
+                    continue;
+                };
+                let locations: BTreeSet<&Location> = enriched_calls
+                    .iter()
+                    .filter(|call| {
+                        call.actions
+                            .iter()
+                            .any(|required| grants(action, &required.name))
+                    })
+                    .filter_map(|call| call.sdk_method_call.metadata.as_ref())
+                    .map(|metadata| &metadata.location)
+                    .collect();
+                findings.push(SensitiveAction {
+                    policy_index,
+                    sid: statement.sid.clone(),
+                    action: action.clone(),
+                    resources: statement.resource.clone(),
+                    severity: rule.severity,
+                    reason: rule.reason.to_string(),
+                    locations: locations.into_iter().cloned().collect(),
+                });
+            }
+        }
+    }
+    findings
+}
+
+/// Whether the granted action, possibly with wildcards, grants `action`
+fn grants(granted: &str, action: &str) -> bool {
+    granted.eq_ignore_ascii_case(action)
+        || (granted.contains('*')
+            && regex::Regex::new(&format!(
+                "(?i)^{}$",
+                regex::escape(granted).replace(r"\*", ".*")
+            ))
+            .is_ok_and(|regex| regex.is_match(action)))
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+    use crate::SdkMethodCall;
+
+    #[test]
+    fn test_sensitive_actions_flagged_with_locations() {
+        let sdk_call = SdkMethodCall {
+            name: "assume_role".to_string(),
+            possible_services: vec!["sts".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "sts.assume_role(RoleArn=role_arn)".to_string(),
+                Location::new(PathBuf::from("app.py"), (12, 5), (12, 38)),
+            )),
+        };
+        let calls = vec![EnrichedSdkMethodCall {
+            method_name: "assume_role".to_string(),
+            service: "sts".to_string(),
+            actions: vec![Action::new(
+                "sts:AssumeRole".to_string(),
+                vec![],
+                vec![],
+                Explanation::default(),
+            )],
+            sdk_method_call: &sdk_call,
+        }];
+
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["sts:AssumeRole".to_string(), "s3:GetObject".to_string()],
+            vec!["*".to_string()],
+        ));
+        policy.add_statement(Statement::allow(
+            vec!["sts:AssumeRole".to_string(), "iam:Put*".to_string()],
+            vec!["arn:aws:iam::123456789012:role/worker".to_string()],
+        ));
+        let policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+
+        let findings = sensitive_actions(&policies, &calls);
+
+        assert_eq!(
+            findings
+                .iter()
+                .map(|finding| (finding.action.as_str(), finding.severity))
+                .collect::<Vec<_>>(),
+            vec![
+                ("sts:AssumeRole", Severity::High),
+                ("iam:Put*", Severity::Critical),
+            ]
+        );
+        assert_eq!(
+            findings[0].locations,
+            vec![Location::new(PathBuf::from("app.py"), (12, 5), (12, 38))]
+        );
+    }
+}
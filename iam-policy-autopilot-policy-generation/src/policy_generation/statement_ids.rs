@@ -0,0 +1,223 @@
+//! Descriptive statement IDs for merged policies
+//!
+//! Merged statements are named after the service, resource type and access they
+//! grant, e.g. `S3ObjectReadWrite` or `DynamoDbTableRead`. Names only depend on the
+//! statement, so regenerating a policy for the same code keeps its Sids stable.
+
+use std::collections::HashMap;
+
+use crate::policy_generation::{PolicyWithMetadata, Statement};
+
+/// Service prefixes whose display name isn't the capitalized prefix
+const SERVICE_NAMES: &[(&str, &str)] = &[
+    ("apigateway", "ApiGateway"),
+    ("cloudformation", "CloudFormation"),
+    ("cloudfront", "CloudFront"),
+    ("cloudwatch", "CloudWatch"),
+    ("codebuild", "CodeBuild"),
+    ("dynamodb", "DynamoDb"),
+    ("elasticloadbalancing", "ElasticLoadBalancing"),
+    ("eventbridge", "EventBridge"),
+    ("kinesisanalytics", "KinesisAnalytics"),
+    ("logs", "CloudWatchLogs"),
+    ("secretsmanager", "SecretsManager"),
+    ("stepfunctions", "StepFunctions"),
+    ("states", "StepFunctions"),
+];
+
+/// Action name prefixes of actions that only read
+const READ_PREFIXES: &[&str] = &[
+    "BatchGet", "Describe", "Get", "Head", "List", "Lookup", "Query", "Receive", "Scan", "Search",
+    "Select",
+];
+
+/// Name the statements of `policies` that don't have a Sid yet
+///
+/// Statements with the same name in a policy are numbered from the second one on,
+/// e.g. `S3ObjectRead` and `S3ObjectRead2`.
+pub(crate) fn assign_statement_ids(policies: &mut [PolicyWithMetadata]) {
+    for policy in policies {
+        let mut counts: HashMap<String, usize> = policy
+            .policy
+            .statements
+            .iter()
+            .filter_map(|statement| statement.sid.clone())
+            .map(|sid| (sid, 1))
+            .collect();
+        for statement in &mut policy.policy.statements {
+            if statement.sid.is_some() {
+                continue;
+            }
+            let name = statement_name(statement);
+            let count = counts.entry(name.clone()).or_default();
+            *count += 1;
+            statement.sid = Some(if *count == 1 {
+                name
+            } else {
+                format!("{name}{count}")
+            });
+        }
+    }
+}
+
+/// `{Service}{ResourceType}{Access}` of a statement, leaving out what it doesn't share
+fn statement_name(statement: &Statement) -> String {
+    let mut services: Vec<&str> = statement
+        .action
+        .iter()
+        .filter_map(|action| action.split_once(':').map(|(service, _)| service))
+        .collect();
+    services.sort_unstable();
+    services.dedup();
+    let service = match services.as_slice() {
+        [service] => service_name(service),
+        _ => "MultiService".to_string(),
+    };
+
+    let mut resource_types: Vec<String> = statement
+        .resource
+        .iter()
+        .map(|resource| resource_type(resource))
+        .collect::<Option<_>>()
+        .unwrap_or_default();
+    resource_types.sort_unstable();
+    resource_types.dedup();
+    let resource_type = match resource_types.as_slice() {
+        [resource_type] => pascal_case(resource_type),
+        _ => String::new(),
+    };
+
+    let reads = statement
+        .action
+        .iter()
+        .filter(|action| is_read(action))
+        .count();
+    let access = if reads == statement.action.len() {
+        "Read"
+    } else if reads == 0 {
+        "Write"
+    } else {
+        "ReadWrite"
+    };
+
+    format!("{service}{resource_type}{access}")
+}
+
+fn service_name(prefix: &str) -> String {
+    SERVICE_NAMES
+        .iter()
+        .find(|(service, _)| *service == prefix)
+        .map_or_else(|| pascal_case(prefix), |(_, name)| (*name).to_string())
+}
+
+/// Resource type of an ARN, e.g. `table` of `arn:aws:dynamodb:...:table/Orders`
+///
+/// S3 bucket and object ARNs have no type, region or account, so they are told apart by
+/// the key. `None` if the resource is `*` or its ARN doesn't start with a type.
+fn resource_type(resource: &str) -> Option<String> {
+    let mut parts = resource.splitn(6, ':').skip(2);
+    let (service, region, account, path) =
+        (parts.next()?, parts.next()?, parts.next()?, parts.next()?);
+    if service == "s3" && region.is_empty() && account.is_empty() {
+        let resource_type = if path.contains('/') {
+            "object"
+        } else {
+            "bucket"
+        };
+        return Some(resource_type.to_string());
+    }
+    let (resource_type, _) = path.split_once(['/', ':'])?;
+    resource_type
+        .chars()
+        .all(|c| c.is_ascii_alphanumeric() || c == '-')
+        .then(|| resource_type.to_string())
+}
+
+fn is_read(action: &str) -> bool {
+    let name = action.split_once(':').map_or(action, |(_, name)| name);
+    READ_PREFIXES.iter().any(|prefix| name.starts_with(prefix))
+}
+
+/// `Table` of `table`, `S3Outposts` of `s3-outposts`
+fn pascal_case(value: &str) -> String {
+    value
+        .split(|c: char| !c.is_ascii_alphanumeric())
+        .map(|word| {
+            let mut chars = word.chars();
+            chars.next().map_or_else(String::new, |first| {
+                first.to_ascii_uppercase().to_string() + chars.as_str()
+            })
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::policy_generation::{IamPolicy, PolicyType};
+
+    fn statement(actions: &[&str], resources: &[&str]) -> Statement {
+        Statement::allow(
+            actions.iter().map(ToString::to_string).collect(),
+            resources.iter().map(ToString::to_string).collect(),
+        )
+    }
+
+    #[test]
+    fn test_statement_ids_describe_service_resource_and_access() {
+        let mut policy = IamPolicy::new();
+        for statement in [
+            statement(
+                &["s3:GetObject", "s3:PutObject"],
+                &["arn:aws:s3:::reports/*"],
+            ),
+            statement(&["s3:ListBucket"], &["arn:aws:s3:::reports"]),
+            statement(
+                &["s3:DescribeJob"],
+                &["arn:aws:s3:us-east-1:123456789012:job/*"],
+            ),
+            statement(
+                &["dynamodb:GetItem", "dynamodb:Query"],
+                &["arn:aws:dynamodb:us-east-1:123456789012:table/Orders"],
+            ),
+            statement(
+                &["dynamodb:Scan"],
+                &["arn:aws:dynamodb:us-east-1:123456789012:table/Users"],
+            ),
+            statement(&["sqs:SendMessage"], &["*"]),
+            statement(&["s3:ListAllMyBuckets"], &["*"]).with_sid("S3Read".to_string()),
+            statement(&["s3:ListAllMyBuckets", "sts:GetCallerIdentity"], &["*"]),
+        ] {
+            policy.add_statement(statement);
+        }
+        let mut policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+
+        assign_statement_ids(&mut policies);
+
+        assert_eq!(
+            policies[0]
+                .policy
+                .statements
+                .iter()
+                .map(|statement| statement.sid.as_deref())
+                .collect::<Vec<_>>(),
+            vec![
+                Some("S3ObjectReadWrite"),
+                Some("S3BucketRead"),
+                Some("S3JobRead"),
+                Some("DynamoDbTableRead"),
+                Some("DynamoDbTableRead2"),
+                Some("SqsWrite"),
+                Some("S3Read"),
+                Some("MultiServiceRead"),
+            ]
+        );
+    }
+}
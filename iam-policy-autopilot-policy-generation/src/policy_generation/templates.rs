@@ -0,0 +1,140 @@
+//! Manifest of the template variables of parameterized policies
+//!
+//! In template mode, resources the analysis can't name are rendered as template
+//! variables (`arn:aws:s3:::{{BucketName}}/*`) instead of wildcards. The manifest
+//! lists each variable with the resources and condition values using it, so a
+//! deployment pipeline knows what to substitute.
+
+use std::collections::BTreeMap;
+use std::sync::OnceLock;
+
+use regex::Regex;
+use serde::Serialize;
+
+use crate::policy_generation::utils::{
+    ACCOUNT_TEMPLATE_VARIABLE, PARTITION_TEMPLATE_VARIABLE, REGION_TEMPLATE_VARIABLE,
+};
+use crate::policy_generation::PolicyWithMetadata;
+
+/// Regex matching template variables such as `{{BucketName}}`
+static TEMPLATE_VARIABLE_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_template_variable_regex() -> &'static Regex {
+    TEMPLATE_VARIABLE_REGEX
+        .get_or_init(|| Regex::new(r"\{\{([^{}]+)\}\}").expect("Invalid template variable regex"))
+}
+
+/// A variable of parameterized policies, substituted at deploy time
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct TemplateVariable {
+    /// Variable name, used as `{{Name}}` in the policies
+    pub name: String,
+    /// What the variable stands for
+    pub description: String,
+    /// Resources and condition values containing the variable
+    pub used_in: Vec<String>,
+}
+
+/// Collect the template variables of `policies`, ordered by name
+pub(crate) fn template_variables(policies: &[PolicyWithMetadata]) -> Vec<TemplateVariable> {
+    let mut used_in: BTreeMap<&str, Vec<&str>> = BTreeMap::new();
+    let values = policies
+        .iter()
+        .flat_map(|policy| &policy.policy.statements)
+        .flat_map(|statement| {
+            statement.resource.iter().chain(
+                statement
+                    .condition
+                    .iter()
+                    .flat_map(|condition| &condition.values),
+            )
+        });
+    for value in values {
+        for captures in get_template_variable_regex().captures_iter(value) {
+            if let Some(name) = captures.get(1) {
+                let uses = used_in.entry(name.as_str()).or_default();
+                if !uses.contains(&value.as_str()) {
+                    uses.push(value);
+                }
+            }
+        }
+    }
+
+    used_in
+        .into_iter()
+        .map(|(name, uses)| TemplateVariable {
+            name: name.to_string(),
+            description: description(name),
+            used_in: uses.into_iter().map(str::to_string).collect(),
+        })
+        .collect()
+}
+
+fn description(name: &str) -> String {
+    match name {
+        PARTITION_TEMPLATE_VARIABLE => "AWS partition, e.g. aws".to_string(),
+        REGION_TEMPLATE_VARIABLE => "AWS region, e.g. us-east-1".to_string(),
+        ACCOUNT_TEMPLATE_VARIABLE => "AWS account ID".to_string(),
+        _ if name
+            .chars()
+            .all(|c| c.is_ascii_uppercase() || c.is_ascii_digit() || c == '_') =>
+        {
+            format!("Value of the environment variable {name} the code reads the resource from")
+        }
+        _ => format!("Resource identifier substituted for the {name} ARN placeholder"),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::enrichment::{Condition, Operator};
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+
+    #[test]
+    fn test_template_variables_manifest() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(
+            Statement::allow(
+                vec!["s3:GetObject".to_string()],
+                vec![
+                    "arn:aws:s3:::{{BucketName}}/*".to_string(),
+                    "arn:aws:s3:::{{REPORTS_BUCKET}}/*".to_string(),
+                ],
+            )
+            .with_conditions(vec![Condition {
+                operator: Operator::StringEquals,
+                key: "kms:ViaService".to_string(),
+                values: vec!["s3.{{Region}}.amazonaws.com".to_string()],
+            }]),
+        );
+        policy.add_statement(Statement::allow(
+            vec!["s3:ListBucket".to_string()],
+            vec!["arn:aws:s3:::{{BucketName}}".to_string()],
+        ));
+        let policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+
+        let variables = template_variables(&policies);
+
+        assert_eq!(
+            variables
+                .iter()
+                .map(|variable| (variable.name.as_str(), variable.used_in.len()))
+                .collect::<Vec<_>>(),
+            vec![("BucketName", 2), ("REPORTS_BUCKET", 1), ("Region", 1)]
+        );
+        assert_eq!(
+            variables[1].description,
+            "Value of the environment variable REPORTS_BUCKET the code reads the resource from"
+        );
+        assert_eq!(variables[2].description, "AWS region, e.g. us-east-1");
+    }
+}
@@ -0,0 +1,268 @@
+//! Trust policy stubs for the roles the analyzed code assumes
+//!
+//! Assuming a role takes two policies: the caller needs `sts:AssumeRole` on the role,
+//! and the role's trust policy has to allow the caller. The generated policies cover
+//! the former; for every role named by a literal ARN, a trust policy stub trusting the
+//! principal that assumes it covers the latter. Assuming a role with a web identity token
+//! takes no permission, so those calls are left out of the generated policies and the
+//! roles they name are read from the calls themselves.
+
+use std::collections::{BTreeMap, BTreeSet};
+
+use serde::Serialize;
+
+use crate::enrichment::NoPermissionCall;
+use crate::policy_generation::{Effect, PolicyWithMetadata};
+
+/// Actions assuming a role with the credentials of an AWS principal
+const ASSUME_ROLE_ACTIONS: &[&str] = &["sts:AssumeRole", "sts:TagSession"];
+
+/// Action assuming a role with a token of an OpenID Connect identity provider
+const ASSUME_ROLE_WITH_WEB_IDENTITY: &str = "sts:AssumeRoleWithWebIdentity";
+
+/// Principal of the workload the policies are generated for, when its role isn't given
+const WORKLOAD_ROLE_PLACEHOLDER: &str = "{{WorkloadRoleArn}}";
+
+/// Identity provider trusted for web identity federation, which the code doesn't name
+const OIDC_PROVIDER_PLACEHOLDER: &str = "{{OidcProviderArn}}";
+
+/// Trust policy of a role the analyzed code assumes
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct TrustPolicy {
+    /// ARN of the assumed role the trust policy is for
+    pub role_arn: String,
+    /// The trust policy document
+    pub policy: TrustPolicyDocument,
+}
+
+/// A role trust policy document
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct TrustPolicyDocument {
+    /// Policy language version
+    pub version: String,
+    /// Statements trusting the principals that assume the role
+    pub statement: Vec<TrustStatement>,
+}
+
+/// A statement of a trust policy
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct TrustStatement {
+    /// Allow, for the trusted principals
+    pub effect: Effect,
+    /// Trusted principals by type, e.g. `AWS` role ARNs or a `Federated` provider
+    pub principal: BTreeMap<String, Vec<String>>,
+    /// Actions the principals may assume the role with
+    pub action: Vec<String>,
+}
+
+/// Trust policies of the roles `policies` grant assuming on a literal ARN, by role ARN
+///
+/// The principal assuming a role is the role or profile whose policy grants
+/// `sts:AssumeRole`, or `workload_role` for the policy of the analyzed code itself;
+/// `{{WorkloadRoleArn}}` when not given. Roles the code assumes that no statement names
+/// are trusted by the workload role. Roles that `no_permission_calls` assume with a web
+/// identity token trust the identity provider.
+pub(crate) fn trust_policies(
+    policies: &[PolicyWithMetadata],
+    no_permission_calls: &[NoPermissionCall],
+    workload_role: Option<&str>,
+) -> Vec<TrustPolicy> {
+    let workload_role = workload_role.unwrap_or(WORKLOAD_ROLE_PLACEHOLDER);
+    // Role ARN to the principals trusted by type, with their actions
+    let mut trusted: BTreeMap<&str, BTreeMap<(&str, &str), BTreeSet<&str>>> = BTreeMap::new();
+    let principals: Vec<String> = policies
+        .iter()
+        .map(|policy| policy.principal(workload_role))
+        .collect();
+    for (policy, principal) in policies.iter().zip(&principals) {
+        let principal = principal.as_str();
+        let statements = policy
+            .policy
+            .statements
+            .iter()
+            .filter(|statement| statement.effect == Effect::Allow);
+        for statement in statements {
+            for action in &statement.action {
+                let principal = if ASSUME_ROLE_ACTIONS
+                    .iter()
+                    .any(|assume| assume.eq_ignore_ascii_case(action))
+                {
+                    ("AWS", principal)
+                } else if action.eq_ignore_ascii_case(ASSUME_ROLE_WITH_WEB_IDENTITY) {
+                    ("Federated", OIDC_PROVIDER_PLACEHOLDER)
+                } else {
+                    continue;
+                };
+                for role_arn in statement.resource.iter().filter(|arn| is_role_arn(arn)) {
+                    trusted
+                        .entry(role_arn.as_str())
+                        .or_default()
+                        .entry(principal)
+                        .or_default()
+                        .insert(action.as_str());
+                }
+            }
+        }
+    }
+    for role_arn in no_permission_calls
+        .iter()
+        .filter(|call| {
+            format!("{}:{}", call.service, call.operation)
+                .eq_ignore_ascii_case(ASSUME_ROLE_WITH_WEB_IDENTITY)
+        })
+        .filter_map(|call| call.role_arn.as_deref())
+        .filter(|arn| is_role_arn(arn))
+    {
+        trusted
+            .entry(role_arn)
+            .or_default()
+            .entry(("Federated", OIDC_PROVIDER_PLACEHOLDER))
+            .or_default()
+            .insert(ASSUME_ROLE_WITH_WEB_IDENTITY);
+    }
+    for role_arn in policies
+        .iter()
+        .filter_map(|policy| policy.assumed_role.as_deref())
+        .filter(|arn| is_role_arn(arn))
+    {
+        trusted.entry(role_arn).or_insert_with(|| {
+            BTreeMap::from([(("AWS", workload_role), BTreeSet::from(["sts:AssumeRole"]))])
+        });
+    }
+
+    trusted
+        .into_iter()
+        .map(|(role_arn, principals)| {
+            // Principals assuming the role with the same actions share a statement
+            let mut statements: BTreeMap<Vec<String>, BTreeMap<String, Vec<String>>> =
+                BTreeMap::new();
+            for ((principal_type, principal), actions) in principals {
+                statements
+                    .entry(actions.into_iter().map(str::to_string).collect())
+                    .or_default()
+                    .entry(principal_type.to_string())
+                    .or_default()
+                    .push(principal.to_string());
+            }
+            TrustPolicy {
+                role_arn: role_arn.to_string(),
+                policy: TrustPolicyDocument {
+                    version: "2012-10-17".to_string(),
+                    statement: statements
+                        .into_iter()
+                        .map(|(action, principal)| TrustStatement {
+                            effect: Effect::Allow,
+                            principal,
+                            action,
+                        })
+                        .collect(),
+                },
+            }
+        })
+        .collect()
+}
+
+/// Whether `arn` names one IAM role, without wildcards or template variables
+fn is_role_arn(arn: &str) -> bool {
+    let mut parts = arn.splitn(6, ':');
+    parts.next() == Some("arn")
+        && parts.nth(1) == Some("iam")
+        && parts.nth(1).is_some_and(|account| {
+            account.len() == 12 && account.bytes().all(|b| b.is_ascii_digit())
+        })
+        && parts.next().is_some_and(|resource| {
+            resource.starts_with("role/") && !resource.contains(['*', '?', '{', '$'])
+        })
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::policy_generation::{IamPolicy, PolicyType, Statement};
+    use crate::Location;
+
+    fn policy(assumed_role: Option<&str>, statements: Vec<Statement>) -> PolicyWithMetadata {
+        let mut policy = IamPolicy::new();
+        for statement in statements {
+            policy.add_statement(statement);
+        }
+        PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: assumed_role.map(str::to_string),
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }
+    }
+
+    fn allow(action: &str, resources: &[&str]) -> Statement {
+        Statement::allow(
+            vec![action.to_string()],
+            resources.iter().map(ToString::to_string).collect(),
+        )
+    }
+
+    #[test]
+    fn test_trust_policies_trust_the_assuming_principal() {
+        let reader = "arn:aws:iam::123456789012:role/Reader";
+        let archiver = "arn:aws:iam::123456789012:role/Archiver";
+        let policies = vec![
+            policy(
+                None,
+                vec![allow("sts:AssumeRole", &[reader, "arn:aws:iam::*:role/*"])],
+            ),
+            policy(Some(reader), vec![allow("sts:AssumeRole", &[archiver])]),
+            policy(Some(archiver), vec![allow("s3:PutObject", &["*"])]),
+        ];
+
+        let web_identity_call = NoPermissionCall {
+            service: "sts".to_string(),
+            operation: "AssumeRoleWithWebIdentity".to_string(),
+            note: String::new(),
+            location: Location::new(PathBuf::from("app.py"), (3, 1), (3, 40)),
+            expression: "sts.assume_role_with_web_identity()".to_string(),
+            role_arn: Some("arn:aws:iam::123456789012:role/Federated".to_string()),
+        };
+
+        let trust = trust_policies(
+            &policies,
+            &[web_identity_call],
+            Some("arn:aws:iam::123456789012:role/App"),
+        );
+
+        let principals = |role_arn: &str| {
+            trust
+                .iter()
+                .find(|policy| policy.role_arn == role_arn)
+                .map(|policy| policy.policy.statement[0].principal.clone())
+        };
+        assert_eq!(trust.len(), 3);
+        assert_eq!(
+            principals(archiver),
+            Some(BTreeMap::from([(
+                "AWS".to_string(),
+                vec![reader.to_string()]
+            )]))
+        );
+        assert_eq!(
+            principals(reader),
+            Some(BTreeMap::from([(
+                "AWS".to_string(),
+                vec!["arn:aws:iam::123456789012:role/App".to_string()]
+            )]))
+        );
+        assert_eq!(
+            principals("arn:aws:iam::123456789012:role/Federated"),
+            Some(BTreeMap::from([(
+                "Federated".to_string(),
+                vec![OIDC_PROVIDER_PLACEHOLDER.to_string()]
+            )]))
+        );
+    }
+}
@@ -0,0 +1,262 @@
+//! Report of actions granted on `Resource: "*"`
+//!
+//! Some actions don't support resource-level permissions at all (e.g.
+//! `s3:ListAllMyBuckets`), so `*` is the only resource a policy can grant them on.
+//! Others are widened to `*` by the analysis, when a resource list exceeds the
+//! resource cutoff or the Service Reference has no ARN format for a resource type.
+//! The report tells the two apart, and statements of the former are kept separate
+//! so reviewers can see which wildcards are deliberate.
+
+use std::collections::{BTreeMap, HashSet};
+
+use serde::Serialize;
+
+use crate::enrichment::EnrichedSdkMethodCall;
+use crate::policy_generation::{PolicyWithMetadata, Statement};
+
+/// Statement ID of statements granting actions without resource-level permissions
+const UNSCOPABLE_STATEMENT_ID: &str = "NoResourceLevelPermissions";
+
+/// Why an action is granted on `Resource: "*"`
+#[derive(Debug, Clone, Copy, Serialize, PartialEq, Eq, PartialOrd, Ord)]
+pub enum UnscopedReason {
+    /// The action doesn't support resource-level permissions; `*` is required
+    ResourceLevelPermissionsNotSupported,
+    /// The action's resource list exceeded the resource cutoff and was collapsed
+    ResourceCutoff,
+    /// The Service Reference has no ARN format for one of the action's resource types
+    UnknownArnFormat,
+}
+
+impl UnscopedReason {
+    fn description(self) -> &'static str {
+        match self {
+            Self::ResourceLevelPermissionsNotSupported => {
+                "The action doesn't support resource-level permissions, so it can only be \
+                 granted on all resources"
+            }
+            Self::ResourceCutoff => {
+                "The action applies to more resource types than the resource cutoff, so its \
+                 resources were collapsed to '*'; raise --resource-cutoff to list them"
+            }
+            Self::UnknownArnFormat => {
+                "The Service Reference lists no ARN format for a resource type of the action, \
+                 so the resource couldn't be scoped"
+            }
+        }
+    }
+}
+
+/// An action granted on `Resource: "*"`, with the reason it couldn't be scoped
+#[derive(Debug, Clone, Serialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub struct UnscopedAction {
+    /// IAM action, e.g. `s3:ListAllMyBuckets`
+    pub action: String,
+    /// Why the action couldn't be scoped to specific resources
+    pub reason: UnscopedReason,
+    /// Human-readable explanation of the reason
+    pub description: String,
+}
+
+/// Collect the actions of `enriched_calls` that are granted on `*`, ordered by action
+pub(crate) fn unscoped_actions(
+    enriched_calls: &[EnrichedSdkMethodCall<'_>],
+) -> Vec<UnscopedAction> {
+    let mut reasons: BTreeMap<&str, UnscopedReason> = BTreeMap::new();
+    for action in enriched_calls.iter().flat_map(|call| &call.actions) {
+        let reason = if action.resources.is_empty() {
+            UnscopedReason::ResourceLevelPermissionsNotSupported
+        } else if let Some(resource) = action
+            .resources
+            .iter()
+            .find(|resource| resource.arn_patterns.is_none())
+        {
+            if resource.name == "*" {
+                UnscopedReason::ResourceCutoff
+            } else {
+                UnscopedReason::UnknownArnFormat
+            }
+        } else {
+            continue;
+        };
+        // Where calls disagree, the reason the analysis could address wins
+        reasons
+            .entry(action.name.as_str())
+            .and_modify(|existing| *existing = (*existing).max(reason))
+            .or_insert(reason);
+    }
+
+    reasons
+        .into_iter()
+        .map(|(action, reason)| UnscopedAction {
+            action: action.to_string(),
+            reason,
+            description: reason.description().to_string(),
+        })
+        .collect()
+}
+
+/// Move actions without resource-level permissions out of `*` statements they share
+/// with actions the analysis widened, into statements of their own
+pub(crate) fn separate_unscopable_actions(
+    policies: &mut [PolicyWithMetadata],
+    unscoped: &[UnscopedAction],
+) {
+    let unscopable: HashSet<&str> = unscoped
+        .iter()
+        .filter(|action| action.reason == UnscopedReason::ResourceLevelPermissionsNotSupported)
+        .map(|action| action.action.as_str())
+        .collect();
+
+    for policy in policies {
+        let mut statements = Vec::with_capacity(policy.policy.statements.len());
+        let mut count = 0;
+        for mut statement in std::mem::take(&mut policy.policy.statements) {
+            if statement.resource != ["*"]
+                || !statement
+                    .action
+                    .iter()
+                    .any(|action| unscopable.contains(action.as_str()))
+            {
+                statements.push(statement);
+                continue;
+            }
+            let (own, widened): (Vec<String>, Vec<String>) = statement
+                .action
+                .drain(..)
+                .partition(|action| unscopable.contains(action.as_str()));
+            if !widened.is_empty() {
+                statements.push(
+                    Statement::allow(widened, statement.resource.clone())
+                        .with_conditions(statement.condition.clone()),
+                );
+            }
+            count += 1;
+            let sid = if count == 1 {
+                UNSCOPABLE_STATEMENT_ID.to_string()
+            } else {
+                format!("{UNSCOPABLE_STATEMENT_ID}{count}")
+            };
+            statements.push(
+                Statement::allow(own, statement.resource)
+                    .with_conditions(statement.condition)
+                    .with_sid(sid),
+            );
+        }
+        policy.policy.statements = statements;
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::PathBuf;
+
+    use super::*;
+    use crate::enrichment::{Action, Explanation, Resource};
+    use crate::extraction::SdkMethodCallMetadata;
+    use crate::policy_generation::{IamPolicy, PolicyType};
+    use crate::{Location, SdkMethodCall};
+
+    fn action(name: &str, resources: Vec<Resource>) -> Action {
+        Action::new(name.to_string(), resources, vec![], Explanation::default())
+    }
+
+    #[test]
+    fn test_unscoped_actions_report_reasons() {
+        let sdk_call = SdkMethodCall {
+            name: "list_buckets".to_string(),
+            possible_services: vec!["s3".to_string()],
+            metadata: Some(SdkMethodCallMetadata::new(
+                "s3.list_buckets()".to_string(),
+                Location::new(PathBuf::from("app.py"), (1, 1), (1, 18)),
+            )),
+        };
+        let calls = vec![EnrichedSdkMethodCall {
+            method_name: "list_buckets".to_string(),
+            service: "s3".to_string(),
+            actions: vec![
+                action("s3:ListAllMyBuckets", vec![]),
+                action(
+                    "s3:GetObject",
+                    vec![Resource::new(
+                        "object".to_string(),
+                        Some(vec![
+                            "arn:${Partition}:s3:::${BucketName}/${ObjectName}".to_string()
+                        ]),
+                    )],
+                ),
+                action("s3:PutObject", vec![Resource::new("*".to_string(), None)]),
+                action(
+                    "s3:GetJobTagging",
+                    vec![Resource::new("job".to_string(), None)],
+                ),
+            ],
+            sdk_method_call: &sdk_call,
+        }];
+
+        let report = unscoped_actions(&calls);
+
+        assert_eq!(
+            report
+                .iter()
+                .map(|action| (action.action.as_str(), action.reason))
+                .collect::<Vec<_>>(),
+            vec![
+                ("s3:GetJobTagging", UnscopedReason::UnknownArnFormat),
+                (
+                    "s3:ListAllMyBuckets",
+                    UnscopedReason::ResourceLevelPermissionsNotSupported
+                ),
+                ("s3:PutObject", UnscopedReason::ResourceCutoff),
+            ]
+        );
+    }
+
+    #[test]
+    fn test_unscopable_actions_get_their_own_statement() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec![
+                "s3:ListAllMyBuckets".to_string(),
+                "s3:PutObject".to_string(),
+            ],
+            vec!["*".to_string()],
+        ));
+        policy.add_statement(Statement::allow(
+            vec!["s3:GetObject".to_string()],
+            vec!["arn:aws:s3:::reports/*".to_string()],
+        ));
+        let mut policies = vec![PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+        let unscoped = vec![UnscopedAction {
+            action: "s3:ListAllMyBuckets".to_string(),
+            reason: UnscopedReason::ResourceLevelPermissionsNotSupported,
+            description: String::new(),
+        }];
+
+        separate_unscopable_actions(&mut policies, &unscoped);
+
+        let statements = &policies[0].policy.statements;
+        assert_eq!(
+            statements
+                .iter()
+                .map(|statement| (statement.sid.as_deref(), statement.action.clone()))
+                .collect::<Vec<_>>(),
+            vec![
+                (None, vec!["s3:PutObject".to_string()]),
+                (
+                    Some("NoResourceLevelPermissions"),
+                    vec!["s3:ListAllMyBuckets".to_string()]
+                ),
+                (None, vec!["s3:GetObject".to_string()]),
+            ]
+        );
+    }
+}
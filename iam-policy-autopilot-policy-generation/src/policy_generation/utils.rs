@@ -7,28 +7,50 @@ use crate::errors::{ExtractorError, Result};
 use regex::{Captures, Regex};
 use std::sync::OnceLock;
 
+/// Prefix of placeholders standing for the value of an environment variable
+///
+/// `${env:BUCKET_NAME}` is rendered as `${BUCKET_NAME}`, so the policy shows which
+/// variable scopes the resource instead of widening it to `*`.
+pub(crate) const ENVIRONMENT_PLACEHOLDER_PREFIX: &str = "env:";
+
 /// Regex pattern to match ARN placeholder variables in the format ${VariableName}
 static ARN_PLACEHOLDER_REGEX: OnceLock<Regex> = OnceLock::new();
 
 /// Get the compiled regex for ARN placeholder matching
-fn get_placeholder_regex() -> &'static Regex {
+pub(crate) fn get_placeholder_regex() -> &'static Regex {
     ARN_PLACEHOLDER_REGEX
         .get_or_init(|| Regex::new(r"\$\{([^}]+)\}").expect("Invalid ARN placeholder regex"))
 }
 
+/// Template variables standing for the partition, region and account when they aren't known
+pub(crate) const PARTITION_TEMPLATE_VARIABLE: &str = "Partition";
+pub(crate) const REGION_TEMPLATE_VARIABLE: &str = "Region";
+pub(crate) const ACCOUNT_TEMPLATE_VARIABLE: &str = "AccountId";
+
+/// Render a template variable, e.g. `{{BucketName}}`, for deploy-time substitution
+pub(crate) fn template_variable(name: &str) -> String {
+    format!("{{{{{name}}}}}")
+}
+
 /// Process a value by replacing placeholder variables with case-insensitive matching
 ///
 /// Replaces the following placeholders (case-insensitive):
 /// - ${partition} or ${Partition} -> provided partition value
 /// - ${region} or ${Region} -> provided region value
 /// - ${account} or ${Account} -> provided account value
+/// - ${env:NAME} -> ${NAME}, a template for the environment variable's value
 /// - All other ${...} -> "*" (wildcard)
 ///
+/// With `templates`, placeholders become template variables instead of wildcards:
+/// `{{BucketName}}`, `{{NAME}}` for environment variables, and `{{Partition}}`,
+/// `{{Region}}` or `{{AccountId}}` where the AWS context is `*`.
+///
 /// # Arguments
 /// * `value` - The value containing placeholder variables
 /// * `partition` - The partition value to substitute
 /// * `region` - The region value to substitute
 /// * `account` - The account value to substitute
+/// * `templates` - Whether to render template variables instead of wildcards
 ///
 /// # Returns
 /// A tuple containing the processed value and a boolean indicating
@@ -41,6 +63,7 @@ fn process_placeholder_value(
     partition: &str,
     region: &str,
     account: &str,
+    templates: bool,
 ) -> Result<(String, bool)> {
     // Check for empty placeholders like ${}
     if value.contains("${}") {
@@ -55,33 +78,31 @@ fn process_placeholder_value(
     let result = regex
         .replace_all(value, |caps: &Captures| {
             if let Some(placeholder) = caps.get(1).map(|m| m.as_str()) {
-                match placeholder.to_lowercase().as_str() {
-                    "partition" => {
-                        if partition == "*" {
-                            wildcards_introduced = true;
-                        }
-                        partition
-                    }
-                    "region" => {
-                        if region == "*" {
-                            wildcards_introduced = true;
-                        }
-                        region
-                    }
-                    "account" => {
-                        if account == "*" {
-                            wildcards_introduced = true;
-                        }
-                        account
-                    }
-                    _ => {
-                        wildcards_introduced = true;
-                        "*" // All other variables become wildcards
-                    }
+                if let Some(variable) = placeholder.strip_prefix(ENVIRONMENT_PLACEHOLDER_PREFIX) {
+                    return if templates {
+                        template_variable(variable)
+                    } else {
+                        format!("${{{variable}}}")
+                    };
+                }
+                let (value, template) = match placeholder.to_lowercase().as_str() {
+                    "partition" => (partition, PARTITION_TEMPLATE_VARIABLE),
+                    "region" => (region, REGION_TEMPLATE_VARIABLE),
+                    "account" => (account, ACCOUNT_TEMPLATE_VARIABLE),
+                    // All other variables become wildcards
+                    _ => ("*", placeholder),
+                };
+                if value != "*" {
+                    value.to_string()
+                } else if templates {
+                    template_variable(template)
+                } else {
+                    wildcards_introduced = true;
+                    "*".to_string()
                 }
             } else {
                 wildcards_introduced = true;
-                "*" // Fallback (should not happen due to validation)
+                "*".to_string() // Fallback (should not happen due to validation)
             }
         })
         .to_string();
@@ -98,6 +119,8 @@ pub(crate) struct ArnParser<'a> {
     region: &'a str,
     /// AWS account number (e.g., "123456789012")
     account: &'a str,
+    /// Render template variables instead of wildcards
+    templates: bool,
 }
 
 impl<'a> ArnParser<'a> {
@@ -107,15 +130,24 @@ impl<'a> ArnParser<'a> {
             partition,
             region,
             account,
+            templates: false,
         }
     }
 
+    /// Render unresolved placeholders as template variables, e.g. `{{BucketName}}`
+    #[must_use]
+    pub(crate) fn with_templates(mut self, templates: bool) -> Self {
+        self.templates = templates;
+        self
+    }
+
     /// Process an ARN pattern by replacing placeholder variables
     ///
     /// Replaces the following placeholders (case-insensitive):
     /// - ${Partition} or ${partition} -> provided partition value
     /// - ${Region} or ${region} -> provided region value
     /// - ${Account} or ${account} -> provided account value
+    /// - ${env:NAME} -> ${NAME}, a template for the environment variable's value
     /// - All other ${...} -> "*" (wildcard)
     ///
     /// # Arguments
@@ -127,8 +159,13 @@ impl<'a> ArnParser<'a> {
     /// # Errors
     /// Returns an error if the pattern contains invalid placeholders (e.g., empty placeholders like ${})
     pub(crate) fn process_arn_pattern(&self, pattern: &str) -> Result<String> {
-        let (result, _wildcards_introduced) =
-            process_placeholder_value(pattern, self.partition, self.region, self.account)?;
+        let (result, _wildcards_introduced) = process_placeholder_value(
+            pattern,
+            self.partition,
+            self.region,
+            self.account,
+            self.templates,
+        )?;
         Ok(result)
     }
 
@@ -159,6 +196,8 @@ pub(crate) struct ConditionValueProcessor<'a> {
     region: &'a str,
     /// AWS account number (e.g., "123456789012")
     account: &'a str,
+    /// Render template variables instead of wildcards
+    templates: bool,
 }
 
 impl<'a> ConditionValueProcessor<'a> {
@@ -168,9 +207,17 @@ impl<'a> ConditionValueProcessor<'a> {
             partition,
             region,
             account,
+            templates: false,
         }
     }
 
+    /// Render unresolved placeholders as template variables, e.g. `{{BucketName}}`
+    #[must_use]
+    pub(crate) fn with_templates(mut self, templates: bool) -> Self {
+        self.templates = templates;
+        self
+    }
+
     /// Process a condition value by replacing placeholder variables
     ///
     /// Replaces the following placeholders (case-insensitive):
@@ -189,7 +236,13 @@ impl<'a> ConditionValueProcessor<'a> {
     /// # Errors
     /// Returns an error if the value contains invalid placeholders (e.g., empty placeholders like ${})
     pub(crate) fn process_condition_value(&self, value: &str) -> Result<(String, bool)> {
-        process_placeholder_value(value, self.partition, self.region, self.account)
+        process_placeholder_value(
+            value,
+            self.partition,
+            self.region,
+            self.account,
+            self.templates,
+        )
     }
 
     /// Process multiple condition values
@@ -298,6 +351,17 @@ mod tests {
         );
     }
 
+    #[test]
+    fn test_environment_placeholders_are_kept_as_templates() {
+        let parser = create_test_parser();
+        assert_eq!(
+            parser
+                .process_arn_pattern("arn:${Partition}:s3:::${env:BUCKET_NAME}/${ObjectName}")
+                .unwrap(),
+            "arn:aws:s3:::${BUCKET_NAME}/*"
+        );
+    }
+
     #[test]
     fn test_edge_cases() {
         let parser = create_test_parser();
@@ -638,4 +702,30 @@ mod tests {
             .unwrap();
         assert_eq!(result, "arn:aws:s3:us-east-1:*:bucket/*");
     }
+
+    #[test]
+    fn test_templates_replace_unresolved_placeholders() {
+        let parser = ArnParser::new("aws", "*", "*").with_templates(true);
+        assert_eq!(
+            parser
+                .process_arn_pattern(
+                    "arn:${Partition}:dynamodb:${Region}:${Account}:table/${TableName}"
+                )
+                .unwrap(),
+            "arn:aws:dynamodb:{{Region}}:{{AccountId}}:table/{{TableName}}"
+        );
+        assert_eq!(
+            parser
+                .process_arn_pattern("arn:${Partition}:s3:::${env:BUCKET_NAME}/*")
+                .unwrap(),
+            "arn:aws:s3:::{{BUCKET_NAME}}/*"
+        );
+
+        let processor = ConditionValueProcessor::new("*", "*", "123456789012").with_templates(true);
+        let (value, wildcards_introduced) = processor
+            .process_condition_value("s3.${region}.amazonaws.com")
+            .unwrap();
+        assert_eq!(value, "s3.{{Region}}.amazonaws.com");
+        assert!(!wildcards_introduced);
+    }
 }
@@ -24,6 +24,26 @@ pub(crate) struct OperationRename {
     pub(crate) operation: String,
 }
 
+/// A deprecated or renamed operation
+#[derive(Clone, Debug, Deserialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct DeprecatedOperation {
+    /// Operation replacing it, whose actions calls of the deprecated operation are granted,
+    /// if it was renamed
+    #[serde(default)]
+    pub(crate) operation: Option<String>,
+    /// How to migrate the calls of the operation
+    pub(crate) note: String,
+}
+
+/// An operation whose calls need no IAM permission of the caller
+#[derive(Clone, Debug, Deserialize, PartialEq, Eq)]
+#[serde(rename_all = "PascalCase")]
+pub(crate) struct NoPermissionOperation {
+    /// Why the calls need no permission, e.g. what authorizes them instead
+    pub(crate) note: String,
+}
+
 /// Service configuration
 #[derive(Clone, Debug, Deserialize)]
 #[serde(rename_all = "PascalCase")]
@@ -36,6 +56,14 @@ pub(crate) struct ServiceConfiguration {
     pub(crate) smithy_botocore_service_name_mapping: HashMap<String, String>,
     /// Resource overrides
     pub(crate) resource_overrides: HashMap<String, HashMap<String, String>>,
+    /// Deprecated and renamed operations, by `<service>:<Operation>` with the Botocore
+    /// service name
+    #[serde(default)]
+    pub(crate) deprecated_operations: HashMap<String, DeprecatedOperation>,
+    /// Operations requiring no IAM permission, or authorized by something other than the
+    /// caller's permissions, by `<service>:<Operation>` with the Botocore service name
+    #[serde(default)]
+    pub(crate) no_permission_operations: HashMap<String, NoPermissionOperation>,
 }
 
 impl ServiceConfiguration {
@@ -49,6 +77,28 @@ impl ServiceConfiguration {
         }
     }
 
+    /// The deprecation of `operation` of `service`, a Botocore service name, if it's
+    /// deprecated or renamed
+    pub(crate) fn deprecated_operation(
+        &self,
+        service: &str,
+        operation: &str,
+    ) -> Option<&DeprecatedOperation> {
+        self.deprecated_operations
+            .get(&format!("{service}:{operation}"))
+    }
+
+    /// Why `operation` of `service`, a Botocore service name, needs no IAM permission, if it
+    /// doesn't
+    pub(crate) fn no_permission_operation(
+        &self,
+        service: &str,
+        operation: &str,
+    ) -> Option<&NoPermissionOperation> {
+        self.no_permission_operations
+            .get(&format!("{service}:{operation}"))
+    }
+
     pub(crate) fn rename_service_service_reference<'a>(&self, original: &'a str) -> Cow<'a, str> {
         match self.rename_services_service_reference.get(original) {
             Some(renamed) => Cow::Owned(renamed.clone()),
@@ -177,6 +227,8 @@ mod tests {
             rename_services_service_reference: HashMap::new(),
             smithy_botocore_service_name_mapping: HashMap::new(),
             resource_overrides: HashMap::new(),
+            deprecated_operations: HashMap::new(),
+            no_permission_operations: HashMap::new(),
         };
 
         // Test service renaming
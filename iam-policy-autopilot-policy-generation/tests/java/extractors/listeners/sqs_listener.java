@@ -0,0 +1,18 @@
+import io.awspring.cloud.sqs.annotation.SqsListener;
+
+class OrderListener {
+    @SqsListener("orders")
+    void onOrder(String order) {}
+
+    @SqsListener(queueNames = {"refunds", "${queues.returns}"}, maxConcurrentMessages = "5")
+    void onRefund(String refund) {}
+
+    @io.awspring.cloud.sqs.annotation.SqsListener(value = "https://sqs.us-east-1.amazonaws.com/123456789012/audit")
+    void onAudit(String event) {}
+
+    @SqsListener(QUEUE)
+    void onConstant(String event) {}
+
+    @Override
+    public String toString() { return ""; }
+}
@@ -0,0 +1,6 @@
+import com.example.messaging.SqsListener;
+
+class OrderListener {
+    @SqsListener("orders")
+    void onOrder(String order) {}
+}
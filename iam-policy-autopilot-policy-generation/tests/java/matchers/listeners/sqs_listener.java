@@ -0,0 +1,9 @@
+import io.awspring.cloud.sqs.annotation.SqsListener;
+
+class OrderListener {
+    @SqsListener({"orders", "https://sqs.us-east-1.amazonaws.com/123456789012/refunds"})
+    void onOrder(String order) {}
+
+    @SqsListener("${queues.audit}")
+    void onAudit(String event) {}
+}
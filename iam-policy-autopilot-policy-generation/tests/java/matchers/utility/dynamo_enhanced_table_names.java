@@ -0,0 +1,25 @@
+import software.amazon.awssdk.enhanced.dynamodb.DynamoDbAsyncTable;
+import software.amazon.awssdk.enhanced.dynamodb.DynamoDbEnhancedClient;
+import software.amazon.awssdk.enhanced.dynamodb.DynamoDbTable;
+import software.amazon.awssdk.enhanced.dynamodb.TableSchema;
+
+class OrderRepository {
+    private final DynamoDbTable<Order> orders =
+        enhancedClient.table("orders", TableSchema.fromBean(Order.class));
+
+    void save(Order order) {
+        orders.putItem(order);
+    }
+
+    void archive(DynamoDbTable<Order> table, Order order) {
+        table.deleteItem(order);
+    }
+
+    void byCustomer(DynamoDbEnhancedClient client, QueryEnhancedRequest request) {
+        client.table("orders", TableSchema.fromBean(Order.class)).index("by-customer").query(request);
+    }
+
+    void audit(DynamoDbAsyncTable<Event> events, Event event) {
+        events.putItem(event);
+    }
+}
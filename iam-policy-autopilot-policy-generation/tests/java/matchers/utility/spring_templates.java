@@ -0,0 +1,20 @@
+import io.awspring.cloud.dynamodb.DynamoDbTemplate;
+import io.awspring.cloud.s3.S3Template;
+import io.awspring.cloud.sqs.operations.SqsTemplate;
+import software.amazon.awssdk.enhanced.dynamodb.DynamoDbTable;
+
+class OrderService {
+    private final S3Template s3Template;
+    private final SqsTemplate sqsTemplate;
+    private final DynamoDbTemplate dynamoDbTemplate;
+
+    void place(Order order) {
+        s3Template.upload("invoices", order.key(), stream);
+        sqsTemplate.send("orders", order);
+        dynamoDbTemplate.query(request, Order.class);
+    }
+
+    void find(DynamoDbTable<Order> table) {
+        table.query(request);
+    }
+}
@@ -18,7 +18,8 @@ use serde::Deserialize;
 
 use iam_policy_autopilot_policy_generation::api::generate_policies;
 use iam_policy_autopilot_policy_generation::api::model::{
-    AwsContext, ExtractSdkCallsConfig, GeneratePolicyConfig,
+    ActionFilters, AwsContext, CustomServices, ExtractSdkCallsConfig, GeneratePolicyConfig,
+    KmsViaService, MappingOverrides, PrincipalMappings, ResourceAnswers, ServiceChoices,
 };
 
 // ---------------------------------------------------------------------------
@@ -79,6 +80,7 @@ fn build_config(fixture_dir: &Path, inputs: &TestInputs) -> GeneratePolicyConfig
             source_files: inputs.source_files.iter().map(|f| resolve(f)).collect(),
             language: Some(inputs.language.clone()),
             service_hints: None,
+            ..Default::default()
         },
         aws_context: AwsContext::new(inputs.region.clone(), inputs.account.clone()).unwrap(),
         individual_policies: inputs.individual_policies,
@@ -95,6 +97,46 @@ fn build_config(fixture_dir: &Path, inputs: &TestInputs) -> GeneratePolicyConfig
         tfvars_files: inputs.tfvars.iter().map(|f| resolve(f)).collect(),
         explain_resource_filters: inputs.explain_resource_filters.clone(),
         resource_cutoff: iam_policy_autopilot_policy_generation::DEFAULT_RESOURCE_CUTOFF,
+        wildcard_resources: false,
+        app_config_files: vec![],
+        resource_answers: ResourceAnswers::default(),
+        resource_prompt: None,
+        service_choices: ServiceChoices::default(),
+        service_prompt: None,
+        mapping_overrides: MappingOverrides::default(),
+        service_reference_lock: None,
+        custom_services: CustomServices::default(),
+        principal_mappings: PrincipalMappings::default(),
+        min_confidence: None,
+        template_variables: false,
+        s3_resource_forms: None,
+        report_unscoped_actions: false,
+        compact_actions: false,
+        match_managed_policies: false,
+        trust_policies: false,
+        resource_policies: false,
+        cross_account_report: false,
+        workload_role_arn: None,
+        suggest_condition_keys: false,
+        observability_permissions: false,
+        collector_config_files: vec![],
+        event_source_permissions: false,
+        s3_multipart_actions: false,
+        kms_via_service: Some(KmsViaService::default()),
+        excluded_services: vec![],
+        action_filters: ActionFilters::default(),
+        split_read_write: false,
+        entry_point_policies: false,
+        detect_runtime: false,
+        restrict_regions: None,
+        network_origins: None,
+        access_analyzer_policy: None,
+        flag_sensitive_actions: false,
+        access_level_summary: false,
+        action_provenance: false,
+        action_inventory: false,
+        analysis_diagnostics: false,
+        coverage_report: false,
     }
 }
 
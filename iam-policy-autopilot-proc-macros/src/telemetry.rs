@@ -50,8 +50,33 @@
 //! | `#[telemetry(presence, default = "x")]` | Records `value != "x"` (String fields only) |
 //! | `#[telemetry(count)]` | Records the length of a Vec as an integer |
 //! | `#[telemetry(value, if_present)]` | Records value if `Some`, omits if `None` (Option fields) |
-//! | `#[telemetry(list)]` | Records as list if non-empty, omits otherwise (Option<Vec<String>> fields) |
+//! | `#[telemetry(list)]` | Records as list if non-empty, omits otherwise (Vec<String> or Option<Vec<String>> fields) |
+//! | `#[telemetry(flatten)]` | Records the fields of a struct deriving `TelemetryEvent` as fields of the command |
 //! | (no attribute) | Field is skipped |
+//!
+//! # Flattened structs
+//!
+//! Options shared by several commands, e.g. a clap `#[command(flatten)]` struct, derive
+//! `TelemetryEvent` once and are flattened into each command, whose events and
+//! `telemetry_fields()` then list the fields of the struct in its place:
+//!
+//! ```ignore
+//! #[derive(Args, TelemetryEvent)]
+//! struct AnalysisArgs {
+//!     #[telemetry(value)]
+//!     exclude_tests: bool,
+//! }
+//!
+//! #[derive(TelemetryEvent)]
+//! enum Commands {
+//!     #[telemetry(command = "generate-policies")]
+//!     GeneratePolicies {
+//!         #[command(flatten)]
+//!         #[telemetry(flatten)]
+//!         analysis: AnalysisArgs,
+//!     },
+//! }
+//! ```
 
 use std::fmt;
 
@@ -91,6 +116,7 @@ const KNOWN_FIELD_ATTRS: &[&str] = &[
     "count",
     "if_present",
     "list",
+    "flatten",
     "default",
 ];
 
@@ -127,8 +153,10 @@ enum FieldMode {
     Count,
     /// Records the value if the `Option` field is `Some`, omits the field entirely if `None`.
     ValueIfPresent,
-    /// Records `Option<Vec<String>>` as a JSON array if non-empty, omits otherwise.
+    /// Records `Vec<String>` or `Option<Vec<String>>` as a JSON array if non-empty, omits otherwise.
     List,
+    /// Records the fields of a struct deriving `TelemetryEvent` as fields of the container.
+    Flatten,
 }
 
 /// Human-readable description of each `FieldMode` without type information.
@@ -143,6 +171,7 @@ impl fmt::Display for FieldMode {
             Self::Count => write!(f, "count of items"),
             Self::ValueIfPresent => write!(f, "value if provided, omitted otherwise"),
             Self::List => write!(f, "list of values if non-empty, omitted otherwise"),
+            Self::Flatten => write!(f, "fields of the flattened struct"),
         }
     }
 }
@@ -270,8 +299,9 @@ fn parse_container_attrs(attrs: &[syn::Attribute]) -> syn::Result<ContainerAttrs
 /// Parse field-level `#[telemetry(...)]` attributes to determine how a field is recorded.
 ///
 /// Scans all attributes for `#[telemetry(...)]`, extracting flags like `skip`, `value`,
-/// `presence`, `if_present`, `list`, and `default = "..."`. The flags are combined with
-/// the following priority: `skip` > `list` > `value + if_present` > `value` > `presence`.
+/// `presence`, `if_present`, `list`, `flatten`, and `default = "..."`. The flags are combined
+/// with the following priority: `skip` > `flatten` > `list` > `value + if_present` > `value` >
+/// `presence`.
 ///
 /// Fields without any `#[telemetry(...)]` attribute default to `FieldMode::Skip`.
 ///
@@ -295,6 +325,7 @@ fn parse_field_mode(attrs: &[syn::Attribute]) -> syn::Result<FieldMode> {
         let mut has_count = false;
         let mut has_if_present = false;
         let mut has_list = false;
+        let mut has_flatten = false;
         let mut default_val: Option<String> = None;
 
         for meta in &nested {
@@ -308,6 +339,7 @@ fn parse_field_mode(attrs: &[syn::Attribute]) -> syn::Result<FieldMode> {
                 Meta::Path(path) if path.is_ident("count") => has_count = true,
                 Meta::Path(path) if path.is_ident("if_present") => has_if_present = true,
                 Meta::Path(path) if path.is_ident("list") => has_list = true,
+                Meta::Path(path) if path.is_ident("flatten") => has_flatten = true,
                 // Key-value: `default = "some_string"` — only valid with `presence`
                 Meta::NameValue(nv) if nv.path.is_ident("default") => {
                     if let Expr::Lit(expr_lit) = &nv.value {
@@ -336,10 +368,13 @@ fn parse_field_mode(attrs: &[syn::Attribute]) -> syn::Result<FieldMode> {
 
         // Phase 2: Resolve the collected flags into a single FieldMode.
         // Priority order ensures deterministic behavior when multiple flags are present:
-        //   skip > list > (value + if_present) > value > presence
+        //   skip > flatten > list > (value + if_present) > value > presence
         if has_skip {
             return Ok(FieldMode::Skip);
         }
+        if has_flatten {
+            return Ok(FieldMode::Flatten);
+        }
         if has_list {
             return Ok(FieldMode::List);
         }
@@ -417,15 +452,45 @@ fn generate_field_code(
             event = event.with_number(#name_str, #accessor.len());
         }),
         FieldMode::List => Some(quote! {
-            if let Some(ref items) = #accessor {
-                if !items.is_empty() {
-                    event = event.with_list(#name_str, items);
-                }
-            }
+            event = event.with_telemetry_list(#name_str, #ref_accessor);
+        }),
+        FieldMode::Flatten => Some(quote! {
+            event = iam_policy_autopilot_common::telemetry::TelemetryFields::record_telemetry_fields(
+                #ref_accessor,
+                event,
+            );
         }),
     }
 }
 
+/// Generate the statement adding the metadata of a field to the `fields` vector built by
+/// `telemetry_fields()`, as a field of the command `command` evaluates to.
+///
+/// Flattened fields add the metadata of each field of their struct instead.
+fn field_info_statement(
+    command: &proc_macro2::TokenStream,
+    field: &syn::Field,
+) -> syn::Result<proc_macro2::TokenStream> {
+    let mode = parse_field_mode(&field.attrs)?;
+    let field_type = &field.ty;
+    if let FieldMode::Flatten = mode {
+        return Ok(quote! {
+            fields.extend(
+                <#field_type as iam_policy_autopilot_common::telemetry::TelemetryFields>::telemetry_fields_of(#command),
+            );
+        });
+    }
+    let field_name_str = field.ident.as_ref().expect("named field").to_string();
+    let mode_description = collection_mode_description(&mode, field_type);
+    Ok(quote! {
+        fields.push(iam_policy_autopilot_common::telemetry::TelemetryFieldInfo {
+            command: #command.to_string(),
+            field_name: #field_name_str.to_string(),
+            collection_mode: #mode_description.to_string(),
+        });
+    })
+}
+
 /// Generate a wildcard match pattern for an enum variant based on its field shape.
 ///
 /// Produces the correct destructuring syntax for each variant kind:
@@ -492,16 +557,7 @@ fn derive_for_enum(
         // --- telemetry_fields entries ---
         if let Fields::Named(fields_named) = &variant.fields {
             for field in &fields_named.named {
-                let field_name_str = field.ident.as_ref().expect("named field").to_string();
-                let mode = parse_field_mode(&field.attrs)?;
-                let mode_description = collection_mode_description(&mode, &field.ty);
-                field_info_entries.push(quote! {
-                    iam_policy_autopilot_common::telemetry::TelemetryFieldInfo {
-                        command: #command_name.to_string(),
-                        field_name: #field_name_str.to_string(),
-                        collection_mode: #mode_description.to_string(),
-                    }
-                });
+                field_info_entries.push(field_info_statement(&quote!(#command_name), field)?);
             }
         }
 
@@ -569,9 +625,10 @@ fn derive_for_enum(
             }
 
             fn telemetry_fields() -> Vec<iam_policy_autopilot_common::telemetry::TelemetryFieldInfo> {
-                vec![
-                    #(#field_info_entries,)*
-                ]
+                #[allow(unused_mut)]
+                let mut fields = Vec::new();
+                #(#field_info_entries)*
+                fields
             }
 
             fn should_skip_notice(&self) -> bool {
@@ -596,6 +653,8 @@ fn derive_for_enum(
 /// Also generates:
 /// - `telemetry_fields()` — metadata about all fields for documentation
 /// - `should_skip_notice()` — returns `true` if `skip` or `skip_notice` is set
+/// - a `TelemetryFields` implementation, recording the same fields in the events of the
+///   commands flattening the struct with `#[telemetry(flatten)]`
 fn derive_for_struct(
     input: &DeriveInput,
     data_struct: &syn::DataStruct,
@@ -618,16 +677,10 @@ fn derive_for_struct(
             let field_ident = field.ident.as_ref().expect("named field");
             let field_name_str = field_ident.to_string();
             let mode = parse_field_mode(&field.attrs)?;
-            let mode_description = collection_mode_description(&mode, &field.ty);
-
-            // Collect field info for telemetry_fields()
-            field_info_entries.push(quote! {
-                iam_policy_autopilot_common::telemetry::TelemetryFieldInfo {
-                    command: #command_name.to_string(),
-                    field_name: #field_name_str.to_string(),
-                    collection_mode: #mode_description.to_string(),
-                }
-            });
+
+            // Collect field info for telemetry_fields(), under the command passed to
+            // telemetry_fields_of()
+            field_info_entries.push(field_info_statement(&quote!(command), field)?);
 
             // Collect field recording code for to_telemetry_event()
             let accessor = quote! { self.#field_ident };
@@ -653,21 +706,36 @@ fn derive_for_struct(
                 if !iam_policy_autopilot_common::telemetry::is_telemetry_enabled() {
                     return None;
                 }
-                let mut event = iam_policy_autopilot_common::telemetry::TelemetryEvent::new(#command_name);
-                #(#field_recording_code)*
-                Some(event)
+                let event = iam_policy_autopilot_common::telemetry::TelemetryEvent::new(#command_name);
+                Some(iam_policy_autopilot_common::telemetry::TelemetryFields::record_telemetry_fields(self, event))
             }
 
             fn telemetry_fields() -> Vec<iam_policy_autopilot_common::telemetry::TelemetryFieldInfo> {
-                vec![
-                    #(#field_info_entries,)*
-                ]
+                <Self as iam_policy_autopilot_common::telemetry::TelemetryFields>::telemetry_fields_of(#command_name)
             }
 
             fn should_skip_notice(&self) -> bool {
                 #skip_notice
             }
         }
+
+        impl #impl_generics iam_policy_autopilot_common::telemetry::TelemetryFields for #struct_name #ty_generics #where_clause {
+            #[allow(unused_mut)]
+            fn record_telemetry_fields(
+                &self,
+                mut event: iam_policy_autopilot_common::telemetry::TelemetryEvent,
+            ) -> iam_policy_autopilot_common::telemetry::TelemetryEvent {
+                #(#field_recording_code)*
+                event
+            }
+
+            fn telemetry_fields_of(command: &str) -> Vec<iam_policy_autopilot_common::telemetry::TelemetryFieldInfo> {
+                #[allow(unused_mut)]
+                let mut fields = Vec::new();
+                #(#field_info_entries)*
+                fields
+            }
+        }
     };
 
     Ok(expanded)
@@ -705,6 +773,7 @@ mod tests {
     #[case::count(FieldMode::Count, "count of items")]
     #[case::value_if_present(FieldMode::ValueIfPresent, "value if provided, omitted otherwise")]
     #[case::list(FieldMode::List, "list of values if non-empty, omitted otherwise")]
+    #[case::flatten(FieldMode::Flatten, "fields of the flattened struct")]
     fn field_mode_display(#[case] mode: FieldMode, #[case] expected: &str) {
         assert_eq!(mode.to_string(), expected);
     }
@@ -819,6 +888,7 @@ mod tests {
             }
             (FieldMode::ValueIfPresent, "ValueIfPresent") => {}
             (FieldMode::List, "List") => {}
+            (FieldMode::Flatten, "Flatten") => {}
             _ => panic!("FieldMode {mode:?} did not match expected pattern `{expected_pattern}`"),
         }
     }
@@ -838,6 +908,7 @@ mod tests {
         "ValueIfPresent"
     )]
     #[case::list(vec![make_telemetry_attr(quote!(list))], "List")]
+    #[case::flatten(vec![make_telemetry_attr(quote!(flatten))], "Flatten")]
     #[case::skip_takes_priority(
         vec![make_telemetry_attr(quote!(skip, value))],
         "Skip"
@@ -935,7 +1006,12 @@ mod tests {
     #[case::list(FieldCodeTestCase {
         mode: FieldMode::List,
         field_type: "Option<Vec<String>>",
-        expected_output: Some("with_list"),
+        expected_output: Some("with_telemetry_list"),
+    })]
+    #[case::flatten(FieldCodeTestCase {
+        mode: FieldMode::Flatten,
+        field_type: "AnalysisArgs",
+        expected_output: Some("record_telemetry_fields"),
     })]
     fn generate_field_code_for_mode(#[case] test_case: FieldCodeTestCase) {
         let accessor = quote!(self.field);
@@ -1034,4 +1110,38 @@ mod tests {
             "collection_mode_description({mode:?}, {type_str})"
         );
     }
+
+    // =========================================================================
+    // field_info_statement — flattened and plain fields
+    // =========================================================================
+
+    #[test]
+    fn field_info_statement_lists_flattened_fields_in_place() {
+        let input: syn::ItemStruct = parse_quote! {
+            struct Args {
+                #[telemetry(value)]
+                pretty: bool,
+                #[telemetry(flatten)]
+                analysis: AnalysisArgs,
+            }
+        };
+        let fields: Vec<&syn::Field> = input.fields.iter().collect();
+        let command = quote!("generate-policies");
+
+        let plain = field_info_statement(&command, fields[0])
+            .expect("should generate")
+            .to_string();
+        assert!(plain.contains("fields . push"), "got: {plain}");
+        assert!(plain.contains("\"actual value (boolean)\""), "got: {plain}");
+
+        let flattened = field_info_statement(&command, fields[1])
+            .expect("should generate")
+            .to_string();
+        assert!(flattened.contains("fields . extend"), "got: {flattened}");
+        assert!(flattened.contains("< AnalysisArgs as"), "got: {flattened}");
+        assert!(
+            flattened.contains("telemetry_fields_of (\"generate-policies\")"),
+            "got: {flattened}"
+        );
+    }
 }
@@ -0,0 +1,305 @@
+//! CloudTrail usage cross-check
+//!
+//! This module compares generated policies with the actions a role was observed using in
+//! CloudTrail, queried from the event history or read from an export. Actions observed
+//! but not generated point at calls the static analysis missed, e.g. computed operation
+//! names; actions generated but never observed at code paths that didn't run in the time
+//! window, or permissions the code doesn't need.
+//!
+//! The event history only records management events, so data events such as
+//! `s3:GetObject` are only observed in exports of trails logging them.
+
+use std::collections::BTreeSet;
+use std::sync::OnceLock;
+use std::time::SystemTime;
+
+use aws_config::BehaviorVersion;
+use aws_sdk_cloudtrail::operation::lookup_events::LookupEventsError;
+use aws_sdk_cloudtrail::primitives::DateTime;
+use aws_sdk_cloudtrail::Client as CloudTrailClient;
+use aws_smithy_runtime_api::client::result::SdkError;
+use iam_policy_autopilot_policy_generation::PolicyWithMetadata;
+use regex::Regex;
+use serde_json::Value;
+use thiserror::Error;
+
+/// Event sources whose service prefix differs from their endpoint name
+const SERVICE_PREFIXES: &[(&str, &str)] = &[("monitoring", "cloudwatch"), ("tagging", "tag")];
+
+/// Errors that can occur during usage collection
+#[derive(Error, Debug)]
+pub enum UsageError {
+    /// AWS CloudTrail lookup events error
+    #[error("AWS CloudTrail lookup events error: {0}")]
+    LookupEvents(#[from] SdkError<LookupEventsError, aws_smithy_runtime_api::http::Response>),
+
+    /// JSON serialization error
+    #[error("JSON serialization error: {0}")]
+    JsonSerialization(#[from] serde_json::Error),
+}
+
+/// Result type for usage operations
+pub type UsageResult<T> = Result<T, UsageError>;
+
+/// Differences between the generated policies and the observed actions
+#[derive(Debug, Clone, Default, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct UsageComparison {
+    /// Number of distinct actions observed
+    pub observed_actions: usize,
+    /// Actions observed in CloudTrail that no generated statement grants
+    pub observed_not_generated: Vec<String>,
+    /// Generated actions matching no observed action
+    pub generated_not_observed: Vec<String>,
+}
+
+/// CloudTrail event history client
+pub struct UsageCollector {
+    client: CloudTrailClient,
+}
+
+impl UsageCollector {
+    /// Create a new UsageCollector with default AWS configuration, querying the event
+    /// history of `region` or the configured region
+    pub async fn new(region: Option<&str>) -> Self {
+        let mut loader = aws_config::defaults(BehaviorVersion::latest());
+        if let Some(region) = region {
+            loader = loader.region(aws_config::Region::new(region.to_string()));
+        }
+        let config = loader.load().await;
+
+        Self {
+            client: CloudTrailClient::new(&config),
+        }
+    }
+
+    /// Create a new UsageCollector with custom AWS configuration
+    #[must_use]
+    pub fn with_client(client: CloudTrailClient) -> Self {
+        Self { client }
+    }
+
+    /// Actions `role_arn` was observed using between `start_time` and `end_time`
+    ///
+    /// The event history can't be looked up by role, so every event of the time window
+    /// is read and filtered by the role that issued its session.
+    pub async fn observed_actions(
+        &self,
+        role_arn: &str,
+        start_time: SystemTime,
+        end_time: SystemTime,
+    ) -> UsageResult<BTreeSet<String>> {
+        let mut actions = BTreeSet::new();
+        let mut events = 0;
+        let mut next_token = None;
+        loop {
+            let response = self
+                .client
+                .lookup_events()
+                .start_time(DateTime::from(start_time))
+                .end_time(DateTime::from(end_time))
+                .set_next_token(next_token)
+                .send()
+                .await?;
+
+            for event in response.events() {
+                events += 1;
+                let Some(record) = event.cloud_trail_event() else {
+                    continue;
+                };
+                let record: Value = serde_json::from_str(record)?;
+                actions.extend(event_action(&record, Some(role_arn)));
+            }
+
+            next_token = response.next_token().map(ToString::to_string);
+            if next_token.is_none() {
+                break;
+            }
+        }
+
+        log::debug!(
+            "Read {events} CloudTrail events: {} actions of {role_arn}",
+            actions.len()
+        );
+        Ok(actions)
+    }
+}
+
+/// Actions of the events of a CloudTrail export, of `role_arn` only if given
+///
+/// Exports are CloudTrail log files (`{"Records": [...]}`), or the JSON results of
+/// Athena or CloudTrail Lake queries: an array or JSON lines of events with at least
+/// their `eventSource` and `eventName`, in any case. Events without a `userIdentity`
+/// are kept, as queries commonly select the role's events only.
+pub fn observed_actions_from_export(
+    content: &str,
+    role_arn: Option<&str>,
+) -> UsageResult<BTreeSet<String>> {
+    let records: Vec<Value> = match serde_json::from_str::<Value>(content) {
+        Ok(Value::Object(mut log_file)) => match log_file.remove("Records") {
+            Some(Value::Array(records)) => records,
+            _ => vec![Value::Object(log_file)],
+        },
+        Ok(Value::Array(records)) => records,
+        _ => content
+            .lines()
+            .filter(|line| !line.trim().is_empty())
+            .map(serde_json::from_str)
+            .collect::<Result<_, _>>()?,
+    };
+    Ok(records
+        .iter()
+        .filter_map(|record| event_action(record, role_arn))
+        .collect())
+}
+
+/// Compare the actions granted by `policies` with the `observed` actions
+#[must_use]
+pub fn compare_usage(
+    policies: &[PolicyWithMetadata],
+    observed: &BTreeSet<String>,
+) -> UsageComparison {
+    let generated: BTreeSet<String> = policies
+        .iter()
+        .filter_map(|policy| serde_json::to_value(&policy.policy).ok())
+        .flat_map(|document| granted_actions(&document))
+        .collect();
+    UsageComparison {
+        observed_actions: observed.len(),
+        observed_not_generated: observed
+            .iter()
+            .filter(|action| !generated.iter().any(|pattern| matches(pattern, action)))
+            .cloned()
+            .collect(),
+        generated_not_observed: generated
+            .iter()
+            .filter(|pattern| !observed.iter().any(|action| matches(pattern, action)))
+            .cloned()
+            .collect(),
+    }
+}
+
+/// Actions of the Allow statements of a policy document
+fn granted_actions(document: &Value) -> Vec<String> {
+    document["Statement"]
+        .as_array()
+        .into_iter()
+        .flatten()
+        .filter(|statement| statement["Effect"] == "Allow")
+        .flat_map(|statement| statement["Action"].as_array().into_iter().flatten())
+        .filter_map(|action| action.as_str().map(ToString::to_string))
+        .collect()
+}
+
+/// Whether the action `pattern`, possibly with wildcards, matches `action`
+pub(crate) fn matches(pattern: &str, action: &str) -> bool {
+    let pattern = format!(
+        "(?i)^{}$",
+        regex::escape(pattern)
+            .replace(r"\*", ".*")
+            .replace(r"\?", ".")
+    );
+    Regex::new(&pattern).is_ok_and(|regex| regex.is_match(action))
+}
+
+/// Regex matching the API version suffix of event names, e.g. `CreateFunction20150331`
+static API_VERSION_SUFFIX_REGEX: OnceLock<Regex> = OnceLock::new();
+
+fn get_api_version_suffix_regex() -> &'static Regex {
+    API_VERSION_SUFFIX_REGEX
+        .get_or_init(|| Regex::new(r"\d{8}(?:v\d+)?$").expect("Invalid API version suffix regex"))
+}
+
+/// IAM action of a CloudTrail event, if it's `role_arn`'s or no role is given
+fn event_action(record: &Value, role_arn: Option<&str>) -> Option<String> {
+    if let Some(role_arn) = role_arn {
+        if let Some(identity) = field(record, "userIdentity") {
+            let issuer = field(identity, "sessionContext")
+                .and_then(|context| field(context, "sessionIssuer"))
+                .and_then(|issuer| field(issuer, "arn"));
+            let principals = [issuer, field(identity, "arn")];
+            if !principals
+                .iter()
+                .flatten()
+                .any(|arn| arn.as_str() == Some(role_arn))
+            {
+                return None;
+            }
+        }
+    }
+    let source = field(record, "eventSource")?.as_str()?;
+    let name = field(record, "eventName")?.as_str()?;
+    let endpoint = source.trim_end_matches(".amazonaws.com");
+    let service = SERVICE_PREFIXES
+        .iter()
+        .find(|(name, _)| *name == endpoint)
+        .map_or(endpoint, |(_, prefix)| prefix);
+    let name = get_api_version_suffix_regex().replace(name, "");
+    Some(format!("{service}:{name}"))
+}
+
+/// Field `name` of `value`, in any case, as Athena lowercases column names
+fn field<'a>(value: &'a Value, name: &str) -> Option<&'a Value> {
+    value
+        .as_object()?
+        .iter()
+        .find(|(key, _)| key.eq_ignore_ascii_case(name))
+        .map(|(_, value)| value)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use iam_policy_autopilot_policy_generation::{IamPolicy, PolicyType, Statement};
+
+    #[test]
+    fn test_observed_actions_from_export() {
+        let log_file = r#"{"Records": [
+            {"eventSource": "lambda.amazonaws.com", "eventName": "CreateFunction20150331",
+             "userIdentity": {"arn": "arn:aws:sts::123456789012:assumed-role/app/session",
+               "sessionContext": {"sessionIssuer": {"arn": "arn:aws:iam::123456789012:role/app"}}}},
+            {"eventSource": "s3.amazonaws.com", "eventName": "ListBuckets",
+             "userIdentity": {"arn": "arn:aws:iam::123456789012:user/admin"}}
+        ]}"#;
+        assert_eq!(
+            observed_actions_from_export(log_file, Some("arn:aws:iam::123456789012:role/app"))
+                .unwrap(),
+            BTreeSet::from(["lambda:CreateFunction".to_string()])
+        );
+
+        let athena_rows = "{\"eventsource\": \"monitoring.amazonaws.com\", \"eventname\": \
+                           \"PutMetricData\"}\n";
+        assert_eq!(
+            observed_actions_from_export(athena_rows, None).unwrap(),
+            BTreeSet::from(["cloudwatch:PutMetricData".to_string()])
+        );
+    }
+
+    #[test]
+    fn test_compare_usage() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["s3:GetObject*".to_string(), "sqs:SendMessage".to_string()],
+            vec!["*".to_string()],
+        ));
+        let policies = [PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+        let observed =
+            BTreeSet::from(["s3:GetObjectTagging".to_string(), "sns:Publish".to_string()]);
+
+        assert_eq!(
+            compare_usage(&policies, &observed),
+            UsageComparison {
+                observed_actions: 2,
+                observed_not_generated: vec!["sns:Publish".to_string()],
+                generated_not_observed: vec!["sqs:SendMessage".to_string()],
+            }
+        );
+    }
+}
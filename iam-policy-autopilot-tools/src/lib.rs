@@ -12,6 +12,38 @@ use iam_policy_autopilot_policy_generation::{IamPolicy, PolicyWithMetadata};
 use regex::Regex;
 use thiserror::Error;
 
+mod cloudtrail_usage;
+mod policy_applier;
+mod policy_audit;
+mod policy_changelog;
+mod policy_diff;
+mod policy_normalize;
+mod policy_simulator;
+mod policy_validator;
+
+pub use cloudtrail_usage::{
+    compare_usage, observed_actions_from_export, UsageCollector, UsageComparison, UsageError,
+    UsageResult,
+};
+pub use policy_applier::{
+    policy_names, ApplyError, ApplyResult, PolicyApplier, PolicyChange, PolicyChangeKind, Role,
+};
+pub use policy_audit::{
+    audit_unused_permissions, AuditError, AuditResult, ExistingPolicy, PermissionAudit,
+    RolePolicyReader, UnusedPermission,
+};
+pub use policy_changelog::{policy_changelog, ConditionChange, PolicyChangelog, ResourceChange};
+pub use policy_diff::{diff_policies, ConditionDifference, PolicyDiff, ResourceDifference};
+pub use policy_normalize::normalize_policy;
+pub use policy_simulator::{
+    sample_requests, PolicySimulator, SimulationRequest, SimulationResult, SimulatorError,
+    SimulatorResult,
+};
+pub use policy_validator::{
+    CustomCheck, CustomCheckResult, FindingType, PolicyValidator, ValidationFinding,
+    ValidatorError, ValidatorResult,
+};
+
 /// Default name constant used for generated policy names
 const DEFAULT_NAME: &str = "IamPolicyAutopilotGeneratedPolicy";
 
@@ -56,6 +88,8 @@ pub type UploaderResult<T> = Result<T, UploaderError>;
 /// IAM Policy Uploader client
 pub struct PolicyUploader {
     client: IamClient,
+    /// Description of the uploaded policies
+    description: Option<String>,
 }
 
 /// Response from uploading a policy
@@ -87,13 +121,26 @@ impl PolicyUploader {
 
         let client = IamClient::new(&config);
 
-        Ok(Self { client })
+        Ok(Self {
+            client,
+            description: None,
+        })
     }
 
     /// Create a new PolicyUploader with custom AWS configuration
     #[must_use]
     pub fn with_client(client: IamClient) -> Self {
-        Self { client }
+        Self {
+            client,
+            description: None,
+        }
+    }
+
+    /// Describe the uploaded policies with `description`, e.g. how they were generated
+    #[must_use]
+    pub fn with_description(mut self, description: String) -> Self {
+        self.description = Some(description);
+        self
     }
 
     /// Validate that a policy name follows AWS naming requirements
@@ -296,6 +343,7 @@ impl PolicyUploader {
             .create_policy()
             .policy_name(&policy_name)
             .policy_document(policy_document)
+            .set_description(self.description.clone())
             .send()
             .await?;
 
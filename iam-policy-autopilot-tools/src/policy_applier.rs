@@ -0,0 +1,566 @@
+//! Direct policy application
+//!
+//! This module keeps customer managed policies attached to a role in sync with the
+//! policies generated for its code, for teams without infrastructure as code. Each
+//! generated policy document is created as a managed policy, or set as the new default
+//! version of the existing one when it grants other permissions, and attached to the role.
+//! The oldest versions are pruned to stay within the IAM quota of versions per policy, and
+//! the policies named after documents no longer generated are detached from the role.
+
+use std::collections::HashSet;
+
+use aws_config::BehaviorVersion;
+use aws_sdk_iam::operation::attach_role_policy::AttachRolePolicyError;
+use aws_sdk_iam::operation::create_policy::CreatePolicyError;
+use aws_sdk_iam::operation::create_policy_version::CreatePolicyVersionError;
+use aws_sdk_iam::operation::delete_policy_version::DeletePolicyVersionError;
+use aws_sdk_iam::operation::detach_role_policy::DetachRolePolicyError;
+use aws_sdk_iam::operation::get_policy::GetPolicyError;
+use aws_sdk_iam::operation::get_policy_version::GetPolicyVersionError;
+use aws_sdk_iam::operation::list_attached_role_policies::ListAttachedRolePoliciesError;
+use aws_sdk_iam::operation::list_policy_versions::ListPolicyVersionsError;
+use aws_sdk_iam::Client as IamClient;
+use aws_smithy_runtime_api::client::result::SdkError;
+use serde_json::Value;
+use thiserror::Error;
+
+use crate::policy_diff::{diff_policies, PolicyDiff};
+
+/// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_iam-quotas.html#reference_iam-quotas-entities
+const MAX_POLICY_VERSIONS: usize = 5;
+
+/// Errors that can occur while applying policies
+#[derive(Error, Debug)]
+pub enum ApplyError {
+    /// AWS IAM list attached role policies error
+    #[error("AWS IAM list attached role policies error: {0}")]
+    ListAttachedRolePolicies(
+        #[from] SdkError<ListAttachedRolePoliciesError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// AWS IAM get policy error
+    #[error("AWS IAM get policy error: {0}")]
+    GetPolicy(#[from] SdkError<GetPolicyError, aws_smithy_runtime_api::http::Response>),
+
+    /// AWS IAM get policy version error
+    #[error("AWS IAM get policy version error: {0}")]
+    GetPolicyVersion(
+        #[from] SdkError<GetPolicyVersionError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// AWS IAM create policy error
+    #[error("AWS IAM create policy error: {0}")]
+    CreatePolicy(#[from] SdkError<CreatePolicyError, aws_smithy_runtime_api::http::Response>),
+
+    /// AWS IAM list policy versions error
+    #[error("AWS IAM list policy versions error: {0}")]
+    ListPolicyVersions(
+        #[from] SdkError<ListPolicyVersionsError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// AWS IAM delete policy version error
+    #[error("AWS IAM delete policy version error: {0}")]
+    DeletePolicyVersion(
+        #[from] SdkError<DeletePolicyVersionError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// AWS IAM create policy version error
+    #[error("AWS IAM create policy version error: {0}")]
+    CreatePolicyVersion(
+        #[from] SdkError<CreatePolicyVersionError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// AWS IAM attach role policy error
+    #[error("AWS IAM attach role policy error: {0}")]
+    AttachRolePolicy(
+        #[from] SdkError<AttachRolePolicyError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// AWS IAM detach role policy error
+    #[error("AWS IAM detach role policy error: {0}")]
+    DetachRolePolicy(
+        #[from] SdkError<DetachRolePolicyError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// ARN that isn't the ARN of an IAM role
+    #[error("Invalid role ARN '{0}': expected arn:<partition>:iam::<account>:role/<name>")]
+    InvalidRoleArn(String),
+
+    /// Policy document that isn't URL encoded JSON
+    #[error("Invalid policy document of {0}: {1}")]
+    PolicyDocument(String, String),
+
+    /// JSON serialization error
+    #[error("JSON serialization error: {0}")]
+    JsonSerialization(#[from] serde_json::Error),
+}
+
+/// Result type for apply operations
+pub type ApplyResult<T> = Result<T, ApplyError>;
+
+/// An IAM role, from its ARN
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Role {
+    /// Partition of the role, e.g. `aws`
+    pub partition: String,
+    /// Account ID of the role
+    pub account: String,
+    /// Name of the role, without its path
+    pub name: String,
+}
+
+impl Role {
+    /// Parse a role ARN, e.g. `arn:aws:iam::123456789012:role/service-role/app`
+    #[allow(clippy::result_large_err)]
+    pub fn from_arn(arn: &str) -> ApplyResult<Self> {
+        let invalid = || ApplyError::InvalidRoleArn(arn.to_string());
+        let parts: Vec<&str> = arn.splitn(6, ':').collect();
+        let ["arn", partition, "iam", "", account, resource] = parts.as_slice() else {
+            return Err(invalid());
+        };
+        let name = resource
+            .strip_prefix("role/")
+            .and_then(|path| path.rsplit('/').next())
+            .filter(|name| !name.is_empty())
+            .ok_or_else(invalid)?;
+        if partition.is_empty() || account.is_empty() {
+            return Err(invalid());
+        }
+        Ok(Self {
+            partition: partition.to_string(),
+            account: account.to_string(),
+            name: name.to_string(),
+        })
+    }
+
+    /// ARN of the customer managed policy `policy_name` of the role's account
+    #[must_use]
+    pub fn policy_arn(&self, policy_name: &str) -> String {
+        format!(
+            "arn:{}:iam::{}:policy/{policy_name}",
+            self.partition, self.account
+        )
+    }
+}
+
+/// What applying a generated policy document changes
+#[derive(Debug, Clone, Copy, PartialEq, Eq, serde::Serialize)]
+pub enum PolicyChangeKind {
+    /// The managed policy is created and attached to the role
+    Create,
+    /// A new default version of the managed policy is created, and the policy attached to
+    /// the role if it isn't
+    Update,
+    /// The managed policy is up to date, and gets attached to the role
+    Attach,
+    /// The managed policy is up to date and attached to the role
+    Unchanged,
+    /// The managed policy is named after a document no longer generated, e.g.
+    /// `<name>-3` once the permissions fit in two policies, and gets detached from the role
+    Detach,
+}
+
+/// The change applying a generated policy document makes to a managed policy
+#[derive(Debug, Clone, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct PolicyChange {
+    /// Name of the managed policy
+    pub policy_name: String,
+    /// ARN of the managed policy
+    pub policy_arn: String,
+    /// What applying the document changes
+    pub change: PolicyChangeKind,
+    /// Whether the managed policy is already attached to the role
+    pub attached: bool,
+    /// Permissions the generated document grants differently from the default version of
+    /// the managed policy, all of them missing if it doesn't exist and extra if it's detached
+    pub diff: PolicyDiff,
+    /// The generated policy document, `null` if the managed policy is detached
+    #[serde(skip)]
+    pub document: Value,
+}
+
+/// Names of the managed policies of `count` generated documents: `policy_name`, then
+/// `policy_name` with the position of the document appended
+#[must_use]
+pub fn policy_names(policy_name: &str, count: usize) -> Vec<String> {
+    (1..=count)
+        .map(|position| match position {
+            1 => policy_name.to_string(),
+            _ => format!("{policy_name}-{position}"),
+        })
+        .collect()
+}
+
+/// IAM client applying generated policies to a role
+pub struct PolicyApplier {
+    client: IamClient,
+}
+
+impl PolicyApplier {
+    /// Create a new PolicyApplier with default AWS configuration
+    pub async fn new() -> Self {
+        let config = aws_config::defaults(BehaviorVersion::latest()).load().await;
+
+        Self {
+            client: IamClient::new(&config),
+        }
+    }
+
+    /// Create a new PolicyApplier with custom AWS configuration
+    #[must_use]
+    pub fn with_client(client: IamClient) -> Self {
+        Self { client }
+    }
+
+    /// The changes applying the generated `documents` to `role` makes, as the managed
+    /// policies named by [`policy_names`], followed by the detachment of the policies of
+    /// further positions attached to the role
+    pub async fn plan(
+        &self,
+        role: &Role,
+        policy_name: &str,
+        documents: &[Value],
+    ) -> ApplyResult<Vec<PolicyChange>> {
+        let attached = self.attached_policy_arns(&role.name).await?;
+
+        let mut changes = Vec::new();
+        for (policy_name, document) in policy_names(policy_name, documents.len())
+            .into_iter()
+            .zip(documents)
+        {
+            let policy_arn = role.policy_arn(&policy_name);
+            let current = self.default_version(&policy_arn).await?;
+            let diff = diff_policies(std::slice::from_ref(document), current.as_slice());
+            let attached = attached.contains(&policy_arn);
+            let change = match (&current, diff.is_empty(), attached) {
+                (None, _, _) => PolicyChangeKind::Create,
+                (Some(_), false, _) => PolicyChangeKind::Update,
+                (Some(_), true, false) => PolicyChangeKind::Attach,
+                (Some(_), true, true) => PolicyChangeKind::Unchanged,
+            };
+            changes.push(PolicyChange {
+                policy_name,
+                policy_arn,
+                change,
+                attached,
+                diff,
+                document: document.clone(),
+            });
+        }
+        for (policy_name, policy_arn) in
+            stale_policies(role, policy_name, documents.len(), &attached)
+        {
+            let current = self.default_version(&policy_arn).await?;
+            let diff = diff_policies(&[], current.as_slice());
+            changes.push(PolicyChange {
+                policy_name,
+                policy_arn,
+                change: PolicyChangeKind::Detach,
+                attached: true,
+                diff,
+                document: Value::Null,
+            });
+        }
+        Ok(changes)
+    }
+
+    /// Apply the `changes` planned for `role`
+    pub async fn apply(&self, role: &Role, changes: &[PolicyChange]) -> ApplyResult<()> {
+        for change in changes {
+            let document = serde_json::to_string(&change.document)?;
+            match change.change {
+                PolicyChangeKind::Create => {
+                    self.client
+                        .create_policy()
+                        .policy_name(&change.policy_name)
+                        .policy_document(document)
+                        .send()
+                        .await?;
+                    log::info!("Created policy {}", change.policy_name);
+                }
+                PolicyChangeKind::Update => {
+                    self.prune_versions(&change.policy_arn).await?;
+                    self.client
+                        .create_policy_version()
+                        .policy_arn(&change.policy_arn)
+                        .policy_document(document)
+                        .set_as_default(true)
+                        .send()
+                        .await?;
+                    log::info!("Updated policy {}", change.policy_name);
+                }
+                PolicyChangeKind::Detach => {
+                    self.client
+                        .detach_role_policy()
+                        .role_name(&role.name)
+                        .policy_arn(&change.policy_arn)
+                        .send()
+                        .await?;
+                    log::info!("Detached policy {} from {}", change.policy_name, role.name);
+                }
+                PolicyChangeKind::Attach | PolicyChangeKind::Unchanged => {}
+            }
+            if !change.attached {
+                self.client
+                    .attach_role_policy()
+                    .role_name(&role.name)
+                    .policy_arn(&change.policy_arn)
+                    .send()
+                    .await?;
+                log::info!("Attached policy {} to {}", change.policy_name, role.name);
+            }
+        }
+        Ok(())
+    }
+
+    /// ARNs of the managed policies attached to `role_name`
+    async fn attached_policy_arns(&self, role_name: &str) -> ApplyResult<HashSet<String>> {
+        let mut arns = HashSet::new();
+        let mut marker = None;
+        loop {
+            let response = self
+                .client
+                .list_attached_role_policies()
+                .role_name(role_name)
+                .set_marker(marker)
+                .send()
+                .await?;
+            arns.extend(
+                response
+                    .attached_policies()
+                    .iter()
+                    .filter_map(|policy| policy.policy_arn().map(ToString::to_string)),
+            );
+            marker = response.marker().map(ToString::to_string);
+            if !response.is_truncated {
+                break;
+            }
+        }
+        Ok(arns)
+    }
+
+    /// Document of the default version of the managed policy `policy_arn`, `None` if it
+    /// doesn't exist
+    async fn default_version(&self, policy_arn: &str) -> ApplyResult<Option<Value>> {
+        let policy = match self.client.get_policy().policy_arn(policy_arn).send().await {
+            Ok(policy) => policy,
+            Err(error)
+                if error
+                    .as_service_error()
+                    .is_some_and(GetPolicyError::is_no_such_entity_exception) =>
+            {
+                return Ok(None);
+            }
+            Err(error) => return Err(error.into()),
+        };
+        let Some(version_id) = policy
+            .policy()
+            .and_then(|policy| policy.default_version_id())
+        else {
+            return Ok(None);
+        };
+        let version = self
+            .client
+            .get_policy_version()
+            .policy_arn(policy_arn)
+            .version_id(version_id)
+            .send()
+            .await?;
+        version
+            .policy_version()
+            .and_then(|version| version.document())
+            .map(|document| policy_document(policy_arn, document))
+            .transpose()
+    }
+
+    /// Delete the oldest versions of `policy_arn` that leave no room for a new one
+    async fn prune_versions(&self, policy_arn: &str) -> ApplyResult<()> {
+        let response = self
+            .client
+            .list_policy_versions()
+            .policy_arn(policy_arn)
+            .send()
+            .await?;
+        let versions: Vec<(&str, bool)> = response
+            .versions()
+            .iter()
+            .filter_map(|version| {
+                version
+                    .version_id()
+                    .map(|id| (id, version.is_default_version))
+            })
+            .collect();
+        for version_id in versions_to_prune(&versions) {
+            self.client
+                .delete_policy_version()
+                .policy_arn(policy_arn)
+                .version_id(version_id)
+                .send()
+                .await?;
+            log::debug!("Deleted version {version_id} of {policy_arn}");
+        }
+        Ok(())
+    }
+}
+
+/// Names and ARNs of the managed policies among the `attached` ones of `role` named after a
+/// position beyond the `count` generated documents, by position
+fn stale_policies(
+    role: &Role,
+    policy_name: &str,
+    count: usize,
+    attached: &HashSet<String>,
+) -> Vec<(String, String)> {
+    let prefix = format!("{}-", role.policy_arn(policy_name));
+    let mut stale: Vec<(usize, &String)> = attached
+        .iter()
+        .filter_map(|arn| {
+            let suffix = arn.strip_prefix(&prefix)?;
+            let position = suffix.parse::<usize>().ok()?;
+            // Only the names policy_names gives, e.g. not `<name>-03`
+            (position > count.max(1) && position.to_string() == suffix).then_some((position, arn))
+        })
+        .collect();
+    stale.sort_unstable();
+    stale
+        .into_iter()
+        .map(|(position, arn)| (format!("{policy_name}-{position}"), arn.clone()))
+        .collect()
+}
+
+/// The oldest non-default of `versions`, by ID and whether they're the default, to delete
+/// so a new version fits in the quota
+fn versions_to_prune<'a>(versions: &[(&'a str, bool)]) -> Vec<&'a str> {
+    let mut deletable: Vec<&str> = versions
+        .iter()
+        .filter(|(_, default)| !default)
+        .map(|(id, _)| *id)
+        .collect();
+    // Version IDs are `v` followed by a number increasing with each version
+    deletable.sort_by_key(|id| {
+        id.trim_start_matches('v')
+            .parse::<u64>()
+            .unwrap_or_default()
+    });
+    let excess = (versions.len() + 1).saturating_sub(MAX_POLICY_VERSIONS);
+    deletable.truncate(excess);
+    deletable
+}
+
+/// Parse a policy document as IAM returns it, URL encoded
+#[allow(clippy::result_large_err)]
+fn policy_document(policy: &str, document: &str) -> ApplyResult<Value> {
+    let decoded = percent_encoding::percent_decode_str(document)
+        .decode_utf8()
+        .map_err(|e| ApplyError::PolicyDocument(policy.to_string(), e.to_string()))?;
+    Ok(serde_json::from_str(&decoded)?)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_role_from_arn() {
+        let role = Role::from_arn("arn:aws-cn:iam::123456789012:role/service-role/app").unwrap();
+
+        assert_eq!(
+            role,
+            Role {
+                partition: "aws-cn".to_string(),
+                account: "123456789012".to_string(),
+                name: "app".to_string(),
+            }
+        );
+        assert_eq!(
+            role.policy_arn("AppPolicy"),
+            "arn:aws-cn:iam::123456789012:policy/AppPolicy"
+        );
+    }
+
+    #[test]
+    fn test_role_from_invalid_arn() {
+        for arn in [
+            "app",
+            "arn:aws:iam::123456789012:user/app",
+            "arn:aws:s3:::bucket",
+            "arn:aws:iam::123456789012:role/",
+        ] {
+            assert!(Role::from_arn(arn).is_err(), "{arn}");
+        }
+    }
+
+    #[test]
+    fn test_policy_names() {
+        assert_eq!(policy_names("AppPolicy", 1), vec!["AppPolicy"]);
+        assert_eq!(
+            policy_names("AppPolicy", 3),
+            vec!["AppPolicy", "AppPolicy-2", "AppPolicy-3"]
+        );
+    }
+
+    #[test]
+    fn test_versions_to_prune() {
+        let versions = [
+            ("v10", false),
+            ("v12", true),
+            ("v9", false),
+            ("v11", false),
+            ("v8", false),
+        ];
+        assert_eq!(versions_to_prune(&versions), vec!["v8"]);
+        assert!(versions_to_prune(&versions[..4]).is_empty());
+    }
+
+    #[test]
+    fn test_versions_to_prune_keeps_the_default_version() {
+        for default in 0..MAX_POLICY_VERSIONS {
+            let ids = ["v1", "v2", "v3", "v4", "v5"];
+            let versions: Vec<(&str, bool)> = ids
+                .iter()
+                .enumerate()
+                .map(|(index, id)| (*id, index == default))
+                .collect();
+
+            let pruned = versions_to_prune(&versions);
+
+            assert_eq!(pruned.len(), 1, "default {}", ids[default]);
+            assert_ne!(pruned[0], ids[default]);
+        }
+    }
+
+    #[test]
+    fn test_stale_policies() {
+        let role = Role::from_arn("arn:aws:iam::123456789012:role/app").unwrap();
+        let attached: HashSet<String> = [
+            "AppPolicy",
+            "AppPolicy-2",
+            "AppPolicy-4",
+            "AppPolicy-3",
+            "AppPolicy-03",
+            "AppPolicy-old",
+            "OtherPolicy-5",
+        ]
+        .into_iter()
+        .map(|name| role.policy_arn(name))
+        .chain(["arn:aws:iam::210987654321:policy/AppPolicy-5".to_string()])
+        .collect();
+
+        assert_eq!(
+            stale_policies(&role, "AppPolicy", 2, &attached),
+            vec![
+                (
+                    "AppPolicy-3".to_string(),
+                    "arn:aws:iam::123456789012:policy/AppPolicy-3".to_string()
+                ),
+                (
+                    "AppPolicy-4".to_string(),
+                    "arn:aws:iam::123456789012:policy/AppPolicy-4".to_string()
+                ),
+            ]
+        );
+        assert_eq!(stale_policies(&role, "AppPolicy", 1, &attached).len(), 3);
+        assert!(stale_policies(&role, "AppPolicy", 4, &attached).is_empty());
+    }
+}
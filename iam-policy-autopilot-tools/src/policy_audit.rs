@@ -0,0 +1,382 @@
+//! Unused permission audit
+//!
+//! This module audits the policies attached to a role against the policies generated for
+//! its code, for incremental least-privilege cleanup of legacy roles. Actions of an Allow
+//! statement that no generated action matches are unused, and so are the resources of a
+//! statement that none of its used actions is generated on. The audited policies are
+//! also returned trimmed of their unused permissions.
+//!
+//! Deny and `NotAction` statements are kept as they are: they don't grant permissions.
+
+use aws_config::BehaviorVersion;
+use aws_sdk_iam::operation::get_policy::GetPolicyError;
+use aws_sdk_iam::operation::get_policy_version::GetPolicyVersionError;
+use aws_sdk_iam::operation::get_role_policy::GetRolePolicyError;
+use aws_sdk_iam::operation::list_attached_role_policies::ListAttachedRolePoliciesError;
+use aws_sdk_iam::operation::list_role_policies::ListRolePoliciesError;
+use aws_sdk_iam::Client as IamClient;
+use aws_smithy_runtime_api::client::result::SdkError;
+use serde_json::Value;
+use thiserror::Error;
+
+use crate::cloudtrail_usage::matches;
+use crate::policy_diff::{grants, resource_matches, strings};
+
+/// Errors that can occur during the audit
+#[derive(Error, Debug)]
+pub enum AuditError {
+    /// AWS IAM list attached role policies error
+    #[error("AWS IAM list attached role policies error: {0}")]
+    ListAttachedRolePolicies(
+        #[from] SdkError<ListAttachedRolePoliciesError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// AWS IAM get policy error
+    #[error("AWS IAM get policy error: {0}")]
+    GetPolicy(#[from] SdkError<GetPolicyError, aws_smithy_runtime_api::http::Response>),
+
+    /// AWS IAM get policy version error
+    #[error("AWS IAM get policy version error: {0}")]
+    GetPolicyVersion(
+        #[from] SdkError<GetPolicyVersionError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// AWS IAM list role policies error
+    #[error("AWS IAM list role policies error: {0}")]
+    ListRolePolicies(
+        #[from] SdkError<ListRolePoliciesError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// AWS IAM get role policy error
+    #[error("AWS IAM get role policy error: {0}")]
+    GetRolePolicy(#[from] SdkError<GetRolePolicyError, aws_smithy_runtime_api::http::Response>),
+
+    /// Policy document that isn't URL encoded JSON
+    #[error("Invalid policy document of {0}: {1}")]
+    PolicyDocument(String, String),
+
+    /// JSON serialization error
+    #[error("JSON serialization error: {0}")]
+    JsonSerialization(#[from] serde_json::Error),
+}
+
+/// Result type for audit operations
+pub type AuditResult<T> = Result<T, AuditError>;
+
+/// A policy to audit
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct ExistingPolicy {
+    /// Name of the policy, if it has one, e.g. the name of an attached managed policy
+    pub name: Option<String>,
+    /// The policy document
+    pub document: Value,
+}
+
+/// Unused permissions of a statement of the audited policies
+#[derive(Debug, Clone, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct UnusedPermission {
+    /// Index of the audited policy of the statement
+    pub policy_index: usize,
+    /// Name of the audited policy, if it has one
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub policy_name: Option<String>,
+    /// Index of the statement in its policy
+    pub statement_index: usize,
+    /// Sid of the statement, if it has one
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub sid: Option<String>,
+    /// Actions of the statement matching no generated action
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub unused_actions: Vec<String>,
+    /// Resources of the statement none of its used actions is generated on
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub unused_resources: Vec<String>,
+}
+
+/// Result of auditing policies for unused permissions
+#[derive(Debug, Clone, Default, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct PermissionAudit {
+    /// Statements with unused permissions
+    pub unused_permissions: Vec<UnusedPermission>,
+    /// The audited policies without their unused permissions, in the same order.
+    /// Statements without used actions are removed.
+    pub trimmed_policies: Vec<Value>,
+}
+
+/// IAM client reading the policies of a role
+pub struct RolePolicyReader {
+    client: IamClient,
+}
+
+impl RolePolicyReader {
+    /// Create a new RolePolicyReader with default AWS configuration
+    pub async fn new() -> Self {
+        let config = aws_config::defaults(BehaviorVersion::latest()).load().await;
+
+        Self {
+            client: IamClient::new(&config),
+        }
+    }
+
+    /// Create a new RolePolicyReader with custom AWS configuration
+    #[must_use]
+    pub fn with_client(client: IamClient) -> Self {
+        Self { client }
+    }
+
+    /// The managed policies attached to `role_name`, at their default version, and its
+    /// inline policies
+    pub async fn role_policies(&self, role_name: &str) -> AuditResult<Vec<ExistingPolicy>> {
+        let mut policies = Vec::new();
+
+        let mut marker = None;
+        loop {
+            let response = self
+                .client
+                .list_attached_role_policies()
+                .role_name(role_name)
+                .set_marker(marker)
+                .send()
+                .await?;
+            for attached in response.attached_policies() {
+                let Some(policy_arn) = attached.policy_arn() else {
+                    continue;
+                };
+                let policy = self
+                    .client
+                    .get_policy()
+                    .policy_arn(policy_arn)
+                    .send()
+                    .await?;
+                let Some(version_id) = policy
+                    .policy()
+                    .and_then(|policy| policy.default_version_id())
+                else {
+                    continue;
+                };
+                let version = self
+                    .client
+                    .get_policy_version()
+                    .policy_arn(policy_arn)
+                    .version_id(version_id)
+                    .send()
+                    .await?;
+                if let Some(document) = version
+                    .policy_version()
+                    .and_then(|version| version.document())
+                {
+                    policies.push(ExistingPolicy {
+                        name: attached.policy_name().map(ToString::to_string),
+                        document: policy_document(policy_arn, document)?,
+                    });
+                }
+            }
+            marker = response.marker().map(ToString::to_string);
+            if !response.is_truncated {
+                break;
+            }
+        }
+
+        let mut marker = None;
+        loop {
+            let response = self
+                .client
+                .list_role_policies()
+                .role_name(role_name)
+                .set_marker(marker)
+                .send()
+                .await?;
+            for policy_name in response.policy_names() {
+                let policy = self
+                    .client
+                    .get_role_policy()
+                    .role_name(role_name)
+                    .policy_name(policy_name)
+                    .send()
+                    .await?;
+                policies.push(ExistingPolicy {
+                    name: Some(policy_name.clone()),
+                    document: policy_document(policy_name, policy.policy_document())?,
+                });
+            }
+            marker = response.marker().map(ToString::to_string);
+            if !response.is_truncated {
+                break;
+            }
+        }
+
+        log::debug!("Read {} policies of role {role_name}", policies.len());
+        Ok(policies)
+    }
+}
+
+/// Parse a policy document as IAM returns it, URL encoded
+fn policy_document(policy: &str, document: &str) -> AuditResult<Value> {
+    let decoded = percent_encoding::percent_decode_str(document)
+        .decode_utf8()
+        .map_err(|e| AuditError::PolicyDocument(policy.to_string(), e.to_string()))?;
+    Ok(serde_json::from_str(&decoded)?)
+}
+
+/// Audit the `existing` policies for permissions the `generated` policy documents don't need
+#[must_use]
+pub fn audit_unused_permissions(
+    generated: &[Value],
+    existing: &[ExistingPolicy],
+) -> PermissionAudit {
+    let generated = grants(generated);
+    let mut audit = PermissionAudit::default();
+
+    for (policy_index, policy) in existing.iter().enumerate() {
+        let mut trimmed = policy.document.clone();
+        let statements = match &policy.document["Statement"] {
+            Value::Array(statements) => statements.clone(),
+            statement => vec![statement.clone()],
+        };
+
+        let mut trimmed_statements = Vec::new();
+        for (statement_index, statement) in statements.into_iter().enumerate() {
+            let actions = strings(&statement["Action"]);
+            if statement["Effect"] != "Allow" || actions.is_empty() {
+                trimmed_statements.push(statement);
+                continue;
+            }
+
+            // Generated resources of the generated actions each action pattern matches
+            let used_resources = |pattern: &str| -> Vec<&String> {
+                generated
+                    .iter()
+                    .filter(|grant| {
+                        grant
+                            .actions
+                            .iter()
+                            .any(|action| matches(pattern, action) || matches(action, pattern))
+                    })
+                    .flat_map(|grant| &grant.resources)
+                    .collect()
+            };
+            let (used_actions, unused_actions): (Vec<String>, Vec<String>) = actions
+                .into_iter()
+                .partition(|pattern| !used_resources(pattern).is_empty());
+            let resources = strings(&statement["Resource"]);
+            let (used, unused_resources): (Vec<String>, Vec<String>) = if used_actions.is_empty() {
+                (Vec::new(), Vec::new())
+            } else {
+                resources.into_iter().partition(|resource| {
+                    used_actions.iter().any(|pattern| {
+                        used_resources(pattern)
+                            .iter()
+                            .any(|generated| resource_matches(resource, generated))
+                    })
+                })
+            };
+
+            let trim_resources = !unused_resources.is_empty();
+            if !unused_actions.is_empty() || trim_resources {
+                audit.unused_permissions.push(UnusedPermission {
+                    policy_index,
+                    policy_name: policy.name.clone(),
+                    statement_index,
+                    sid: statement["Sid"].as_str().map(ToString::to_string),
+                    unused_actions,
+                    unused_resources,
+                });
+            }
+            // Statements without a Resource, e.g. with NotResource, keep their elements
+            if used_actions.is_empty() || (trim_resources && used.is_empty()) {
+                continue;
+            }
+            let mut statement = statement;
+            statement["Action"] = Value::from(used_actions);
+            if trim_resources {
+                statement["Resource"] = Value::from(used);
+            }
+            trimmed_statements.push(statement);
+        }
+
+        trimmed["Statement"] = Value::from(trimmed_statements);
+        audit.trimmed_policies.push(trimmed);
+    }
+
+    log::debug!(
+        "Audited {} policies: {} statements with unused permissions",
+        existing.len(),
+        audit.unused_permissions.len()
+    );
+    audit
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn test_audit_unused_permissions() {
+        let generated = json!({
+            "Version": "2012-10-17",
+            "Statement": [{
+                "Effect": "Allow",
+                "Action": ["s3:GetObject"],
+                "Resource": ["arn:aws:s3:::reports/*"]
+            }]
+        });
+        let existing = ExistingPolicy {
+            name: Some("LegacyAccess".to_string()),
+            document: json!({
+                "Version": "2012-10-17",
+                "Statement": [
+                    {
+                        "Sid": "Storage",
+                        "Effect": "Allow",
+                        "Action": ["s3:GetObject", "s3:DeleteBucket"],
+                        "Resource": ["arn:aws:s3:::reports/*", "arn:aws:s3:::archive/*"]
+                    },
+                    {
+                        "Effect": "Allow",
+                        "Action": "sqs:*",
+                        "Resource": "*"
+                    },
+                    {
+                        "Effect": "Deny",
+                        "Action": "s3:DeleteObject",
+                        "Resource": "*"
+                    }
+                ]
+            }),
+        };
+
+        let audit = audit_unused_permissions(&[generated], &[existing]);
+
+        assert_eq!(
+            audit.unused_permissions[0],
+            UnusedPermission {
+                policy_index: 0,
+                policy_name: Some("LegacyAccess".to_string()),
+                statement_index: 0,
+                sid: Some("Storage".to_string()),
+                unused_actions: vec!["s3:DeleteBucket".to_string()],
+                unused_resources: vec!["arn:aws:s3:::archive/*".to_string()],
+            }
+        );
+        assert_eq!(audit.unused_permissions[1].unused_actions, vec!["sqs:*"]);
+        assert_eq!(
+            audit.trimmed_policies[0]["Statement"],
+            json!([
+                {
+                    "Sid": "Storage",
+                    "Effect": "Allow",
+                    "Action": ["s3:GetObject"],
+                    "Resource": ["arn:aws:s3:::reports/*"]
+                },
+                {
+                    "Effect": "Deny",
+                    "Action": "s3:DeleteObject",
+                    "Resource": "*"
+                }
+            ])
+        );
+    }
+}
@@ -0,0 +1,256 @@
+//! Policy changelog
+//!
+//! This module lists the permission changes between two versions of the generated policies,
+//! e.g. the policies generated at two commits or saved by two runs of generate-policies, for
+//! release notes and change tickets. It is the policy diff read forwards: the actions and
+//! resources the later version grants that the earlier one doesn't are added, those only the
+//! earlier version grants are removed, and the actions both grant under other conditions are
+//! changed.
+
+use std::fmt::Write;
+
+use serde_json::Value;
+
+use crate::policy_diff::diff_policies;
+
+/// Permission changes from one version of the policies to another
+#[derive(Debug, Clone, Default, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct PolicyChangelog {
+    /// Actions granted by the later version only
+    pub added_actions: Vec<String>,
+    /// Actions granted by the earlier version only
+    pub removed_actions: Vec<String>,
+    /// Resources the later version grants an action of both versions on
+    pub added_resources: Vec<ResourceChange>,
+    /// Resources only the earlier version granted an action of both versions on
+    pub removed_resources: Vec<ResourceChange>,
+    /// Actions of both versions granted under other conditions
+    pub changed_conditions: Vec<ConditionChange>,
+}
+
+/// Resources an action was granted on, or no longer is
+#[derive(Debug, Clone, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ResourceChange {
+    /// The action
+    pub action: String,
+    /// The added or removed resources
+    pub resources: Vec<String>,
+}
+
+/// Conditions an action is granted under by both versions
+#[derive(Debug, Clone, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ConditionChange {
+    /// The action
+    pub action: String,
+    /// `Condition` blocks of the earlier statements granting the action, `{}` if one has none
+    pub from: Vec<Value>,
+    /// `Condition` blocks of the later statements granting the action
+    pub to: Vec<Value>,
+}
+
+impl PolicyChangelog {
+    /// Whether both versions grant the same permissions
+    #[must_use]
+    pub fn is_empty(&self) -> bool {
+        self.added_actions.is_empty()
+            && self.removed_actions.is_empty()
+            && self.added_resources.is_empty()
+            && self.removed_resources.is_empty()
+            && self.changed_conditions.is_empty()
+    }
+
+    /// Markdown listing the changes from the version labeled `from` to the one labeled `to`,
+    /// e.g. for a release note
+    #[must_use]
+    pub fn to_markdown(&self, from: &str, to: &str) -> String {
+        let mut markdown = format!("## Permission changes from {from} to {to}\n");
+        if self.is_empty() {
+            markdown.push_str("\nNo permission changes.\n");
+            return markdown;
+        }
+
+        let actions = |markdown: &mut String, title: &str, actions: &[String]| {
+            if actions.is_empty() {
+                return;
+            }
+            let _ = writeln!(markdown, "\n### {title}\n");
+            for action in actions {
+                let _ = writeln!(markdown, "- `{action}`");
+            }
+        };
+        let resources = |markdown: &mut String, title: &str, changes: &[ResourceChange]| {
+            if changes.is_empty() {
+                return;
+            }
+            let _ = writeln!(markdown, "\n### {title}\n");
+            for change in changes {
+                let resources: Vec<String> = change
+                    .resources
+                    .iter()
+                    .map(|resource| format!("`{resource}`"))
+                    .collect();
+                let _ = writeln!(
+                    markdown,
+                    "- `{}` on {}",
+                    change.action,
+                    resources.join(", ")
+                );
+            }
+        };
+        actions(&mut markdown, "Added actions", &self.added_actions);
+        actions(&mut markdown, "Removed actions", &self.removed_actions);
+        resources(&mut markdown, "Added resources", &self.added_resources);
+        resources(&mut markdown, "Removed resources", &self.removed_resources);
+        if !self.changed_conditions.is_empty() {
+            markdown.push_str("\n### Changed conditions\n\n");
+            for change in &self.changed_conditions {
+                let _ = writeln!(
+                    markdown,
+                    "- `{}`: from {} to {}",
+                    change.action,
+                    conditions(&change.from),
+                    conditions(&change.to)
+                );
+            }
+        }
+        markdown
+    }
+}
+
+/// The `Condition` blocks of a version, `none` for statements without one
+fn conditions(conditions: &[Value]) -> String {
+    conditions
+        .iter()
+        .map(|condition| {
+            if condition.as_object().is_some_and(serde_json::Map::is_empty) {
+                "none".to_string()
+            } else {
+                format!("`{condition}`")
+            }
+        })
+        .collect::<Vec<_>>()
+        .join(", ")
+}
+
+/// List the permission changes from the `from` policy documents to the `to` ones
+#[must_use]
+pub fn policy_changelog(from: &[Value], to: &[Value]) -> PolicyChangelog {
+    let diff = diff_policies(to, from);
+    let mut changelog = PolicyChangelog {
+        added_actions: diff.missing_actions,
+        removed_actions: diff.extra_actions,
+        ..PolicyChangelog::default()
+    };
+    for difference in diff.resource_differences {
+        if !difference.missing_resources.is_empty() {
+            changelog.added_resources.push(ResourceChange {
+                action: difference.action.clone(),
+                resources: difference.missing_resources,
+            });
+        }
+        if !difference.extra_resources.is_empty() {
+            changelog.removed_resources.push(ResourceChange {
+                action: difference.action,
+                resources: difference.extra_resources,
+            });
+        }
+    }
+    changelog.changed_conditions = diff
+        .condition_differences
+        .into_iter()
+        .map(|difference| ConditionChange {
+            action: difference.action,
+            from: difference.existing,
+            to: difference.generated,
+        })
+        .collect();
+    changelog
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn test_policy_changelog() {
+        let from = json!({
+            "Version": "2012-10-17",
+            "Statement": [
+                {
+                    "Effect": "Allow",
+                    "Action": ["s3:GetObject", "sqs:DeleteQueue"],
+                    "Resource": ["arn:aws:s3:::reports/2025/*", "arn:aws:sqs:*:*:jobs"]
+                },
+                {
+                    "Effect": "Allow",
+                    "Action": "sqs:SendMessage",
+                    "Resource": "arn:aws:sqs:*:*:jobs"
+                }
+            ]
+        });
+        let to = json!({
+            "Version": "2012-10-17",
+            "Statement": [
+                {
+                    "Effect": "Allow",
+                    "Action": ["s3:GetObject", "s3:PutObject"],
+                    "Resource": ["arn:aws:s3:::reports/2026/*"]
+                },
+                {
+                    "Effect": "Allow",
+                    "Action": "sqs:SendMessage",
+                    "Resource": "arn:aws:sqs:*:*:jobs",
+                    "Condition": {"StringEquals": {"aws:RequestedRegion": "us-east-1"}}
+                }
+            ]
+        });
+
+        let changelog = policy_changelog(&[from], &[to]);
+
+        assert_eq!(changelog.added_actions, vec!["s3:PutObject"]);
+        assert_eq!(changelog.removed_actions, vec!["sqs:DeleteQueue"]);
+        assert_eq!(
+            changelog.added_resources,
+            vec![ResourceChange {
+                action: "s3:GetObject".to_string(),
+                resources: vec!["arn:aws:s3:::reports/2026/*".to_string()],
+            }]
+        );
+        assert_eq!(
+            changelog.removed_resources,
+            vec![ResourceChange {
+                action: "s3:GetObject".to_string(),
+                resources: vec!["arn:aws:s3:::reports/2025/*".to_string()],
+            }]
+        );
+        assert_eq!(changelog.changed_conditions[0].from, vec![json!({})]);
+
+        let markdown = changelog.to_markdown("v1.2.0", "HEAD");
+        assert!(markdown.starts_with("## Permission changes from v1.2.0 to HEAD\n"));
+        assert!(markdown.contains("### Added actions\n\n- `s3:PutObject`\n"));
+        assert!(markdown.contains(
+            "- `s3:GetObject` on `arn:aws:s3:::reports/2025/*`\n\n### Changed conditions"
+        ));
+        assert!(markdown.contains("- `sqs:SendMessage`: from none to `{\"StringEquals\":"));
+    }
+
+    #[test]
+    fn test_policy_changelog_without_changes() {
+        let policy = json!({
+            "Version": "2012-10-17",
+            "Statement": {"Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}
+        });
+
+        let changelog = policy_changelog(&[policy.clone()], &[policy]);
+
+        assert!(changelog.is_empty());
+        assert_eq!(
+            changelog.to_markdown("a.json", "b.json"),
+            "## Permission changes from a.json to b.json\n\nNo permission changes.\n"
+        );
+    }
+}
@@ -0,0 +1,347 @@
+//! Policy diff
+//!
+//! This module compares generated policies with an existing policy, e.g. the one attached
+//! to a role today. Generated actions the existing policy doesn't grant are missing, and
+//! actions it grants that no generated action matches are extra. For the actions both
+//! grant, the resources and conditions they're granted on are compared.
+//!
+//! Only Allow statements listing their actions are compared: `NotAction` statements and
+//! Deny statements are left out.
+
+use std::collections::BTreeSet;
+
+use serde_json::Value;
+
+use crate::cloudtrail_usage::matches;
+
+/// Differences between generated policies and an existing policy
+#[derive(Debug, Clone, Default, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct PolicyDiff {
+    /// Generated actions the existing policy doesn't grant
+    pub missing_actions: Vec<String>,
+    /// Actions of the existing policy matching no generated action
+    pub extra_actions: Vec<String>,
+    /// Actions both grant, on different resources
+    pub resource_differences: Vec<ResourceDifference>,
+    /// Actions both grant, under different conditions
+    pub condition_differences: Vec<ConditionDifference>,
+}
+
+impl PolicyDiff {
+    /// Whether the policies grant the same actions on the same resources and conditions
+    #[must_use]
+    pub fn is_empty(&self) -> bool {
+        self.missing_actions.is_empty()
+            && self.extra_actions.is_empty()
+            && self.resource_differences.is_empty()
+            && self.condition_differences.is_empty()
+    }
+
+    /// Whether the generated policies need permissions the existing policy doesn't grant
+    ///
+    /// These are missing actions and resources, and actions the generated policies grant
+    /// without conditions where the existing policy only grants them under conditions.
+    /// Extra permissions and narrower conditions don't need any.
+    #[must_use]
+    pub fn requires_new_permissions(&self) -> bool {
+        !self.missing_actions.is_empty()
+            || self
+                .resource_differences
+                .iter()
+                .any(|difference| !difference.missing_resources.is_empty())
+            || self
+                .condition_differences
+                .iter()
+                .any(ConditionDifference::is_unconditioned)
+    }
+}
+
+/// Resources an action is granted on by the generated policies but not the existing
+/// policy, or the other way around
+#[derive(Debug, Clone, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ResourceDifference {
+    /// The generated action
+    pub action: String,
+    /// Generated resources the existing policy doesn't grant the action on
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub missing_resources: Vec<String>,
+    /// Resources of the existing policy that aren't generated, e.g. `*` for a scoped action
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub extra_resources: Vec<String>,
+}
+
+/// Conditions an action is granted under by the generated and the existing policies
+#[derive(Debug, Clone, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ConditionDifference {
+    /// The generated action
+    pub action: String,
+    /// `Condition` blocks of the generated statements granting the action, `{}` if one
+    /// has none
+    pub generated: Vec<Value>,
+    /// `Condition` blocks of the existing statements granting the action
+    pub existing: Vec<Value>,
+}
+
+impl ConditionDifference {
+    /// Whether the generated policies grant the action without conditions, unlike the
+    /// existing policy
+    #[must_use]
+    pub fn is_unconditioned(&self) -> bool {
+        let unconditioned = |conditions: &[Value]| {
+            conditions
+                .iter()
+                .any(|condition| condition.as_object().is_some_and(serde_json::Map::is_empty))
+        };
+        unconditioned(&self.generated) && !unconditioned(&self.existing)
+    }
+}
+
+/// An Allow statement of a policy document
+pub(crate) struct Grant {
+    pub(crate) actions: Vec<String>,
+    pub(crate) resources: Vec<String>,
+    condition: Value,
+}
+
+/// Compare the `generated` policy documents with the `existing` ones
+#[must_use]
+pub fn diff_policies(generated: &[Value], existing: &[Value]) -> PolicyDiff {
+    let generated = grants(generated);
+    let existing = grants(existing);
+    let generated_actions: BTreeSet<&String> =
+        generated.iter().flat_map(|grant| &grant.actions).collect();
+
+    let mut diff = PolicyDiff {
+        extra_actions: existing
+            .iter()
+            .flat_map(|grant| &grant.actions)
+            .filter(|pattern| {
+                !generated_actions
+                    .iter()
+                    .any(|action| matches(pattern, action) || matches(action, pattern))
+            })
+            .cloned()
+            .collect::<BTreeSet<_>>()
+            .into_iter()
+            .collect(),
+        ..PolicyDiff::default()
+    };
+
+    for action in generated_actions {
+        let granting = |grants: &[Grant], exact: bool| -> Vec<(Vec<String>, Value)> {
+            grants
+                .iter()
+                .filter(|grant| {
+                    grant.actions.iter().any(|pattern| {
+                        if exact {
+                            pattern == action
+                        } else {
+                            matches(pattern, action)
+                        }
+                    })
+                })
+                .map(|grant| (grant.resources.clone(), grant.condition.clone()))
+                .collect()
+        };
+        let generated_grants = granting(&generated, true);
+        let existing_grants = granting(&existing, false);
+        if existing_grants.is_empty() {
+            diff.missing_actions.push(action.clone());
+            continue;
+        }
+
+        let generated_resources: BTreeSet<&String> = generated_grants
+            .iter()
+            .flat_map(|(resources, _)| resources)
+            .collect();
+        let existing_resources: BTreeSet<&String> = existing_grants
+            .iter()
+            .flat_map(|(resources, _)| resources)
+            .collect();
+        let service = action.split(':').next().unwrap_or_default();
+        let difference = ResourceDifference {
+            action: action.clone(),
+            missing_resources: generated_resources
+                .iter()
+                .filter(|resource| {
+                    !existing_resources
+                        .iter()
+                        .any(|pattern| resource_matches(pattern, resource))
+                })
+                .map(|resource| (*resource).clone())
+                .collect(),
+            // Statements granting actions of several services list the resources of all
+            extra_resources: existing_resources
+                .iter()
+                .filter(|resource| {
+                    !generated_resources.contains(*resource)
+                        && resource_service(resource)
+                            .is_none_or(|arn_service| arn_service == service)
+                })
+                .map(|resource| (*resource).clone())
+                .collect(),
+        };
+        if !difference.missing_resources.is_empty() || !difference.extra_resources.is_empty() {
+            diff.resource_differences.push(difference);
+        }
+
+        let conditions = |grants: &[(Vec<String>, Value)]| -> Vec<Value> {
+            let mut conditions: Vec<Value> = Vec::new();
+            for (_, condition) in grants {
+                if !conditions.contains(condition) {
+                    conditions.push(condition.clone());
+                }
+            }
+            conditions
+        };
+        let generated_conditions = conditions(&generated_grants);
+        let existing_conditions = conditions(&existing_grants);
+        let same = generated_conditions.len() == existing_conditions.len()
+            && generated_conditions
+                .iter()
+                .all(|condition| existing_conditions.contains(condition));
+        if !same {
+            diff.condition_differences.push(ConditionDifference {
+                action: action.clone(),
+                generated: generated_conditions,
+                existing: existing_conditions,
+            });
+        }
+    }
+
+    log::debug!(
+        "Diffed policies: {} missing actions, {} extra actions",
+        diff.missing_actions.len(),
+        diff.extra_actions.len()
+    );
+    diff
+}
+
+/// Allow statements of policy documents
+pub(crate) fn grants(documents: &[Value]) -> Vec<Grant> {
+    let mut grants = Vec::new();
+    for document in documents {
+        let statements = match &document["Statement"] {
+            Value::Array(statements) => statements.iter().collect(),
+            statement => vec![statement],
+        };
+        for statement in statements {
+            if statement["Effect"] != "Allow" {
+                continue;
+            }
+            let actions = strings(&statement["Action"]);
+            if actions.is_empty() {
+                continue;
+            }
+            grants.push(Grant {
+                actions,
+                resources: strings(&statement["Resource"]),
+                condition: statement
+                    .get("Condition")
+                    .cloned()
+                    .unwrap_or_else(|| Value::Object(serde_json::Map::new())),
+            });
+        }
+    }
+    grants
+}
+
+/// Strings of a policy element, which is a string or an array of strings
+pub(crate) fn strings(value: &Value) -> Vec<String> {
+    match value {
+        Value::String(value) => vec![value.clone()],
+        Value::Array(values) => values
+            .iter()
+            .filter_map(|value| value.as_str().map(ToString::to_string))
+            .collect(),
+        _ => Vec::new(),
+    }
+}
+
+/// Whether the resource `pattern`, possibly with wildcards, matches `resource`
+///
+/// ARNs are case sensitive, unlike actions.
+pub(crate) fn resource_matches(pattern: &str, resource: &str) -> bool {
+    pattern == resource
+        || (pattern.contains(['*', '?'])
+            && regex::Regex::new(&format!(
+                "^{}$",
+                regex::escape(pattern)
+                    .replace(r"\*", ".*")
+                    .replace(r"\?", ".")
+            ))
+            .is_ok_and(|regex| regex.is_match(resource)))
+}
+
+/// Service of a resource ARN, unless it's a wildcard
+fn resource_service(resource: &str) -> Option<&str> {
+    resource
+        .split(':')
+        .nth(2)
+        .filter(|service| !service.contains('*'))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn test_diff_policies() {
+        let generated = json!({
+            "Version": "2012-10-17",
+            "Statement": [
+                {
+                    "Effect": "Allow",
+                    "Action": ["s3:GetObject", "s3:PutObject"],
+                    "Resource": ["arn:aws:s3:::reports/*"]
+                },
+                {
+                    "Effect": "Allow",
+                    "Action": ["sqs:SendMessage"],
+                    "Resource": ["arn:aws:sqs:us-east-1:123456789012:jobs"],
+                    "Condition": {"StringEquals": {"aws:RequestedRegion": "us-east-1"}}
+                }
+            ]
+        });
+        let existing = json!({
+            "Version": "2012-10-17",
+            "Statement": [
+                {
+                    "Effect": "Allow",
+                    "Action": ["s3:Get*", "sqs:SendMessage", "sqs:DeleteQueue"],
+                    "Resource": "*"
+                },
+                {
+                    "Effect": "Deny",
+                    "Action": "s3:PutObject",
+                    "Resource": "*"
+                }
+            ]
+        });
+
+        let diff = diff_policies(&[generated], &[existing]);
+
+        assert_eq!(diff.missing_actions, vec!["s3:PutObject"]);
+        assert_eq!(diff.extra_actions, vec!["sqs:DeleteQueue"]);
+        assert_eq!(
+            diff.resource_differences[0],
+            ResourceDifference {
+                action: "s3:GetObject".to_string(),
+                missing_resources: Vec::new(),
+                extra_resources: vec!["*".to_string()],
+            }
+        );
+        assert_eq!(diff.condition_differences.len(), 1);
+        assert_eq!(diff.condition_differences[0].action, "sqs:SendMessage");
+        assert_eq!(diff.condition_differences[0].existing, vec![json!({})]);
+        assert!(!diff.is_empty());
+        assert!(diff.requires_new_permissions());
+
+        // Narrower conditions than the existing policy's need no new permissions
+        assert!(!diff.condition_differences[0].is_unconditioned());
+    }
+}
@@ -0,0 +1,343 @@
+//! Policy normalization
+//!
+//! This module rewrites IAM policy documents in the canonical form of the generated
+//! policies, so that a human-written policy and a generated one granting the same
+//! permissions are equal, and a textual diff of two normalized policies only shows the
+//! permissions they differ by:
+//!
+//! - actions, resources, principals and condition values are lists, sorted and without
+//!   duplicates, with the service prefixes of actions in lowercase
+//! - actions and resources a wildcard of the same list covers are left out, so a
+//!   statement granting `*` lists no other resource
+//! - statements differing only by their actions are merged
+//! - statements are sorted, Allow before Deny, by their actions then resources, and the
+//!   policy and statement ids are dropped unless kept
+//!
+//! Normalization doesn't change what a policy allows or denies.
+
+use std::cmp::Ordering;
+
+use serde_json::{Map, Value};
+
+use crate::cloudtrail_usage::matches;
+use crate::policy_diff::{resource_matches, strings};
+
+/// Policy language version of documents without one
+const DEFAULT_VERSION: &str = "2012-10-17";
+
+/// Elements of a statement listing actions, matched case-insensitively
+const ACTION_ELEMENTS: &[&str] = &["Action", "NotAction"];
+
+/// Elements of a statement listing resources
+const RESOURCE_ELEMENTS: &[&str] = &["Resource", "NotResource"];
+
+/// Elements of a statement listing principals by type
+const PRINCIPAL_ELEMENTS: &[&str] = &["Principal", "NotPrincipal"];
+
+/// Order of the elements of a normalized statement; others follow in name order
+const STATEMENT_ELEMENTS: &[&str] = &[
+    "Sid",
+    "Effect",
+    "Principal",
+    "NotPrincipal",
+    "Action",
+    "NotAction",
+    "Resource",
+    "NotResource",
+    "Condition",
+];
+
+/// Normalize the policy `document`, keeping its `Id` and statement `Sid`s if `keep_ids`
+///
+/// Statements that aren't objects are kept as they are, after the others.
+#[must_use]
+pub fn normalize_policy(document: &Value, keep_ids: bool) -> Value {
+    let statements = match &document["Statement"] {
+        Value::Array(statements) => statements.clone(),
+        Value::Null => Vec::new(),
+        statement => vec![statement.clone()],
+    };
+    let (objects, others): (Vec<Value>, Vec<Value>) =
+        statements.into_iter().partition(Value::is_object);
+
+    let mut normalized: Vec<Map<String, Value>> = Vec::new();
+    for statement in objects {
+        let Value::Object(statement) = statement else {
+            continue;
+        };
+        let statement = normalize_statement(statement, keep_ids);
+        // Merge the statements differing only by their actions
+        if let Some(merged) = normalized
+            .iter_mut()
+            .find(|merged| differ_by_actions(merged, &statement))
+        {
+            let mut actions = strings(&merged["Action"]);
+            actions.extend(strings(&statement["Action"]));
+            merged.insert("Action".to_string(), normalized_actions(&actions));
+            continue;
+        }
+        if statement.is_empty() {
+            continue;
+        }
+        normalized.push(statement);
+    }
+    normalized.sort_by(compare_statements);
+
+    let mut policy = Map::new();
+    policy.insert(
+        "Version".to_string(),
+        document
+            .get("Version")
+            .cloned()
+            .unwrap_or_else(|| Value::String(DEFAULT_VERSION.to_string())),
+    );
+    if keep_ids {
+        if let Some(id) = document.get("Id") {
+            policy.insert("Id".to_string(), id.clone());
+        }
+    }
+    let mut statements: Vec<Value> = normalized.into_iter().map(Value::Object).collect();
+    statements.extend(others);
+    policy.insert("Statement".to_string(), Value::Array(statements));
+    Value::Object(policy)
+}
+
+/// The elements of `statement` in canonical form and order
+fn normalize_statement(mut statement: Map<String, Value>, keep_ids: bool) -> Map<String, Value> {
+    if !keep_ids {
+        statement.remove("Sid");
+    }
+    for element in ACTION_ELEMENTS {
+        if let Some(actions) = statement.get_mut(*element) {
+            *actions = normalized_actions(&strings(actions));
+        }
+    }
+    for element in RESOURCE_ELEMENTS {
+        if let Some(resources) = statement.get_mut(*element) {
+            *resources = normalized_resources(&strings(resources));
+        }
+    }
+    for element in PRINCIPAL_ELEMENTS {
+        if let Some(Value::Object(principals)) = statement.get_mut(*element) {
+            for principal in principals.values_mut() {
+                *principal = sorted_values(principal);
+            }
+        }
+    }
+    if let Some(Value::Object(operators)) = statement.get_mut("Condition") {
+        for keys in operators.values_mut() {
+            if let Value::Object(keys) = keys {
+                for values in keys.values_mut() {
+                    *values = sorted_values(values);
+                }
+            }
+        }
+    }
+
+    let mut normalized = Map::new();
+    for element in STATEMENT_ELEMENTS {
+        if let Some(value) = statement.remove(*element) {
+            normalized.insert((*element).to_string(), value);
+        }
+    }
+    let mut others: Vec<(String, Value)> = statement.into_iter().collect();
+    others.sort_by(|a, b| a.0.cmp(&b.0));
+    normalized.extend(others);
+    normalized
+}
+
+/// `actions` sorted, without duplicates or the actions another one covers, with their
+/// service prefix in lowercase. Of the spellings of an action, the first in byte order is
+/// kept, e.g. `GetObject` rather than `getobject`.
+fn normalized_actions(actions: &[String]) -> Value {
+    let mut actions: Vec<String> = actions
+        .iter()
+        .map(|action| match action.trim().split_once(':') {
+            Some((service, name)) => format!("{}:{name}", service.to_lowercase()),
+            None => action.trim().to_string(),
+        })
+        .collect();
+    actions.sort_by_cached_key(|action| (action.to_lowercase(), action.clone()));
+    actions.dedup_by(|a, b| a.eq_ignore_ascii_case(b));
+    let kept = without_covered(&actions, matches);
+    Value::Array(kept.into_iter().map(Value::String).collect())
+}
+
+/// `resources` sorted, without duplicates or the resources another one covers
+fn normalized_resources(resources: &[String]) -> Value {
+    let mut resources: Vec<String> = resources
+        .iter()
+        .map(|resource| resource.trim().to_string())
+        .collect();
+    resources.sort();
+    resources.dedup();
+    let kept = without_covered(&resources, resource_matches);
+    Value::Array(kept.into_iter().map(Value::String).collect())
+}
+
+/// The `patterns` no other one covers strictly according to `covers`
+fn without_covered(patterns: &[String], covers: fn(&str, &str) -> bool) -> Vec<String> {
+    patterns
+        .iter()
+        .filter(|pattern| {
+            !patterns
+                .iter()
+                .any(|other| other != *pattern && covers(other, pattern) && !covers(pattern, other))
+        })
+        .cloned()
+        .collect()
+}
+
+/// A value or list of values as a sorted list without duplicates
+fn sorted_values(values: &Value) -> Value {
+    let mut values = match values {
+        Value::Array(values) => values.clone(),
+        value => vec![value.clone()],
+    };
+    values.sort_by_key(ToString::to_string);
+    values.dedup();
+    Value::Array(values)
+}
+
+/// Whether the statements grant their actions to the same principals, on the same
+/// resources and under the same conditions
+fn differ_by_actions(a: &Map<String, Value>, b: &Map<String, Value>) -> bool {
+    let without_actions = |statement: &Map<String, Value>| {
+        let mut statement = statement.clone();
+        statement.remove("Action");
+        statement.remove("Sid");
+        statement
+    };
+    a.contains_key("Action")
+        && b.contains_key("Action")
+        && !a.contains_key("Sid")
+        && !b.contains_key("Sid")
+        && without_actions(a) == without_actions(b)
+}
+
+/// Order of normalized statements: Allow first, then by actions, resources and the rest
+fn compare_statements(a: &Map<String, Value>, b: &Map<String, Value>) -> Ordering {
+    let key = |statement: &Map<String, Value>| {
+        let lowercase = |element: &str| -> Vec<String> {
+            strings(statement.get(element).unwrap_or(&Value::Null))
+                .iter()
+                .map(|value| value.to_lowercase())
+                .collect()
+        };
+        (
+            statement.get("Effect") != Some(&Value::String("Allow".to_string())),
+            lowercase("Action"),
+            lowercase("NotAction"),
+            strings(statement.get("Resource").unwrap_or(&Value::Null)),
+            strings(statement.get("NotResource").unwrap_or(&Value::Null)),
+            Value::Object(statement.clone()).to_string(),
+        )
+    };
+    key(a).cmp(&key(b))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn test_normalize_policy() {
+        let policy = json!({
+            "Version": "2012-10-17",
+            "Id": "Orders",
+            "Statement": [
+                {
+                    "Sid": "Queue",
+                    "Effect": "Allow",
+                    "Action": "SQS:SendMessage",
+                    "Resource": "arn:aws:sqs:us-east-1:123456789012:jobs"
+                },
+                {
+                    "Effect": "Deny",
+                    "Action": "s3:DeleteObject",
+                    "Resource": "*"
+                },
+                {
+                    "Effect": "Allow",
+                    "Action": ["s3:GetObject", "s3:Get*", "s3:ListBucket", "s3:GetObject"],
+                    "Resource": ["arn:aws:s3:::reports/*", "arn:aws:s3:::reports/2024/*"],
+                    "Condition": {"StringEquals": {"aws:RequestedRegion": "us-east-1"}}
+                },
+                {
+                    "Effect": "Allow",
+                    "Action": ["s3:PutObject"],
+                    "Resource": "arn:aws:s3:::reports/*",
+                    "Condition": {"StringEquals": {"aws:RequestedRegion": ["us-east-1"]}}
+                }
+            ]
+        });
+
+        assert_eq!(
+            normalize_policy(&policy, false),
+            json!({
+                "Version": "2012-10-17",
+                "Statement": [
+                    {
+                        "Effect": "Allow",
+                        "Action": ["s3:Get*", "s3:ListBucket", "s3:PutObject"],
+                        "Resource": ["arn:aws:s3:::reports/*"],
+                        "Condition": {"StringEquals": {"aws:RequestedRegion": ["us-east-1"]}}
+                    },
+                    {
+                        "Effect": "Allow",
+                        "Action": ["sqs:SendMessage"],
+                        "Resource": ["arn:aws:sqs:us-east-1:123456789012:jobs"]
+                    },
+                    {
+                        "Effect": "Deny",
+                        "Action": ["s3:DeleteObject"],
+                        "Resource": ["*"]
+                    }
+                ]
+            })
+        );
+    }
+
+    #[test]
+    fn test_normalized_policies_are_equal() {
+        let written = json!({
+            "Statement": {
+                "Effect": "Allow",
+                "Action": ["kms:decrypt", "kms:Decrypt", "KMS:Encrypt", "kms:Encrypt"],
+                "Resource": ["*", "arn:aws:kms:us-east-1:123456789012:key/1"]
+            }
+        });
+        let generated = json!({
+            "Id": "IamPolicyAutopilot",
+            "Version": "2012-10-17",
+            "Statement": [
+                {"Effect": "Allow", "Action": ["kms:Encrypt"], "Resource": ["*"]},
+                {"Effect": "Allow", "Action": ["kms:Decrypt"], "Resource": ["*"]}
+            ]
+        });
+
+        let normalized = normalize_policy(&written, false);
+
+        assert_eq!(normalized, normalize_policy(&generated, false));
+        assert_eq!(normalize_policy(&normalized, false), normalized);
+    }
+
+    #[test]
+    fn test_ids_and_sids_are_kept_on_request() {
+        let policy = json!({
+            "Version": "2012-10-17",
+            "Id": "Orders",
+            "Statement": [
+                {"Sid": "Read", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"},
+                {"Sid": "Write", "Effect": "Allow", "Action": "s3:PutObject", "Resource": "*"}
+            ]
+        });
+
+        let normalized = normalize_policy(&policy, true);
+
+        assert_eq!(normalized["Id"], "Orders");
+        assert_eq!(normalized["Statement"][0]["Sid"], "Read");
+        assert_eq!(normalized["Statement"][1]["Sid"], "Write");
+    }
+}
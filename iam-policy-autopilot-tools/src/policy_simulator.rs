@@ -0,0 +1,269 @@
+//! IAM Policy Simulator
+//!
+//! This module simulates the requests of analyzed code against generated policies with
+//! `iam:SimulateCustomPolicy`, to catch resource scoping or conditions that deny the
+//! code's own operations. Each request is an action and an example resource the code
+//! calls it on.
+
+use std::collections::{BTreeMap, BTreeSet};
+
+use aws_config::BehaviorVersion;
+use aws_sdk_iam::operation::simulate_custom_policy::SimulateCustomPolicyError;
+use aws_sdk_iam::types::{ContextEntry, ContextKeyTypeEnum, PolicyEvaluationDecisionType};
+use aws_sdk_iam::Client as IamClient;
+use aws_smithy_runtime_api::client::result::SdkError;
+use iam_policy_autopilot_policy_generation::PolicyWithMetadata;
+use thiserror::Error;
+
+/// Name substituted for the wildcards of resource ARN patterns in example resources
+const EXAMPLE_NAME: &str = "example";
+
+/// Errors that can occur during policy simulation
+#[derive(Error, Debug)]
+pub enum SimulatorError {
+    /// AWS IAM simulate custom policy error
+    #[error("AWS IAM simulate custom policy error: {0}")]
+    SimulateCustomPolicy(
+        #[from] SdkError<SimulateCustomPolicyError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// JSON serialization error
+    #[error("JSON serialization error: {0}")]
+    JsonSerialization(#[from] serde_json::Error),
+}
+
+/// Result type for simulator operations
+pub type SimulatorResult<T> = Result<T, SimulatorError>;
+
+/// A request of the analyzed code: an action and an example resource it's called on
+#[derive(Debug, Clone, PartialEq, Eq, PartialOrd, Ord, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct SimulationRequest {
+    /// IAM action of the request, e.g. `s3:GetObject`
+    pub action: String,
+    /// Example resource of the request, or `*` for actions without resource types
+    pub resource: String,
+}
+
+/// Outcome of simulating a request against the policies
+#[derive(Debug, Clone, PartialEq, Eq, serde::Serialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct SimulationResult {
+    /// The simulated request
+    #[serde(flatten)]
+    pub request: SimulationRequest,
+    /// Evaluation decision: `allowed`, `explicitDeny` or `implicitDeny`
+    pub decision: String,
+    /// Condition keys of the policies that the simulation had no values for
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub missing_context_keys: Vec<String>,
+}
+
+impl SimulationResult {
+    /// Whether the policies allow the request
+    #[must_use]
+    pub fn is_allowed(&self) -> bool {
+        self.decision == PolicyEvaluationDecisionType::Allowed.as_str()
+    }
+
+    /// Whether the policies deny the request regardless of missing condition values
+    ///
+    /// Requests denied because the simulation lacks values of condition keys, e.g. the
+    /// source IP of a request, may be allowed when the code sends them.
+    #[must_use]
+    pub fn is_denied(&self) -> bool {
+        !self.is_allowed() && self.missing_context_keys.is_empty()
+    }
+}
+
+/// IAM Policy Simulator client
+pub struct PolicySimulator {
+    client: IamClient,
+}
+
+impl PolicySimulator {
+    /// Create a new PolicySimulator with default AWS configuration
+    pub async fn new() -> Self {
+        let config = aws_config::defaults(BehaviorVersion::latest()).load().await;
+
+        Self {
+            client: IamClient::new(&config),
+        }
+    }
+
+    /// Create a new PolicySimulator with custom AWS configuration
+    #[must_use]
+    pub fn with_client(client: IamClient) -> Self {
+        Self { client }
+    }
+
+    /// Simulate `requests` against the identity policies `policies`
+    ///
+    /// # Arguments
+    ///
+    /// * `policies` - JSON documents of the policies to simulate against
+    /// * `requests` - Requests to simulate
+    /// * `context` - Condition key values of every request, e.g. `aws:RequestedRegion`
+    ///
+    /// # Returns
+    ///
+    /// The outcome of each request, ordered by resource and action
+    pub async fn simulate(
+        &self,
+        policies: &[String],
+        requests: &[SimulationRequest],
+        context: &[(String, String)],
+    ) -> SimulatorResult<Vec<SimulationResult>> {
+        let context_entries = context
+            .iter()
+            .map(|(key, value)| {
+                ContextEntry::builder()
+                    .context_key_name(key)
+                    .context_key_values(value)
+                    .context_key_type(ContextKeyTypeEnum::String)
+                    .build()
+            })
+            .collect::<Vec<_>>();
+
+        // Each call simulates all of its actions on all of its resources
+        let mut actions_by_resource: BTreeMap<&str, Vec<String>> = BTreeMap::new();
+        for request in requests {
+            actions_by_resource
+                .entry(request.resource.as_str())
+                .or_default()
+                .push(request.action.clone());
+        }
+
+        let mut results = Vec::with_capacity(requests.len());
+        for (resource, actions) in actions_by_resource {
+            let mut marker = None;
+            loop {
+                let mut call = self
+                    .client
+                    .simulate_custom_policy()
+                    .set_policy_input_list(Some(policies.to_vec()))
+                    .set_action_names(Some(actions.clone()))
+                    .set_context_entries(Some(context_entries.clone()))
+                    .set_marker(marker);
+                // Resources default to `*`
+                if resource != "*" {
+                    call = call.resource_arns(resource);
+                }
+                let response = call.send().await?;
+
+                results.extend(response.evaluation_results().iter().map(|evaluation| {
+                    SimulationResult {
+                        request: SimulationRequest {
+                            action: evaluation.eval_action_name().to_string(),
+                            resource: resource.to_string(),
+                        },
+                        decision: evaluation.eval_decision().as_str().to_string(),
+                        missing_context_keys: evaluation.missing_context_values().to_vec(),
+                    }
+                }));
+
+                marker = response
+                    .is_truncated()
+                    .then(|| response.marker().map(ToString::to_string))
+                    .flatten();
+                if marker.is_none() {
+                    break;
+                }
+            }
+        }
+
+        log::debug!(
+            "Simulated {} requests: {} allowed",
+            results.len(),
+            results.iter().filter(|result| result.is_allowed()).count()
+        );
+        Ok(results)
+    }
+}
+
+/// Requests of the individual policies of the analyzed calls
+///
+/// Each action of an Allow statement is requested on each of its resources, with the
+/// wildcards of resource ARN patterns replaced by an example name, so a policy scoped
+/// to the pattern allows it.
+pub fn sample_requests(
+    individual_policies: &[PolicyWithMetadata],
+) -> SimulatorResult<Vec<SimulationRequest>> {
+    let mut requests = BTreeSet::new();
+    for policy in individual_policies {
+        let document = serde_json::to_value(&policy.policy)?;
+        for statement in document["Statement"].as_array().into_iter().flatten() {
+            if statement["Effect"] != "Allow" {
+                continue;
+            }
+            let strings = |key: &str| -> Vec<String> {
+                statement[key]
+                    .as_array()
+                    .into_iter()
+                    .flatten()
+                    .filter_map(|value| value.as_str().map(ToString::to_string))
+                    .collect()
+            };
+            let resources = strings("Resource");
+            for action in strings("Action") {
+                for resource in &resources {
+                    requests.insert(SimulationRequest {
+                        action: action.clone(),
+                        resource: example_resource(resource),
+                    });
+                }
+            }
+        }
+    }
+    Ok(requests.into_iter().collect())
+}
+
+/// `resource` with its wildcards replaced by an example name, or `*` for all resources
+fn example_resource(resource: &str) -> String {
+    if resource == "*" {
+        resource.to_string()
+    } else {
+        resource.replace('*', EXAMPLE_NAME)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use iam_policy_autopilot_policy_generation::{IamPolicy, PolicyType, Statement};
+
+    #[test]
+    fn test_sample_requests() {
+        let mut policy = IamPolicy::new();
+        policy.add_statement(Statement::allow(
+            vec!["s3:GetObject".to_string()],
+            vec!["arn:aws:s3:::*/*".to_string()],
+        ));
+        policy.add_statement(Statement::allow(
+            vec!["sts:GetCallerIdentity".to_string()],
+            vec!["*".to_string()],
+        ));
+        let policies = [PolicyWithMetadata {
+            policy,
+            policy_type: PolicyType::Identity,
+            assumed_role: None,
+            credential_profile: None,
+            entry_point: None,
+            named_principal: None,
+        }];
+
+        assert_eq!(
+            sample_requests(&policies).unwrap(),
+            vec![
+                SimulationRequest {
+                    action: "s3:GetObject".to_string(),
+                    resource: "arn:aws:s3:::example/example".to_string(),
+                },
+                SimulationRequest {
+                    action: "sts:GetCallerIdentity".to_string(),
+                    resource: "*".to_string(),
+                },
+            ]
+        );
+    }
+}
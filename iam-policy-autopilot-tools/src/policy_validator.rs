@@ -0,0 +1,375 @@
+//! IAM Policy Validator
+//!
+//! This module validates generated policies with IAM Access Analyzer `ValidatePolicy`,
+//! which checks them against the IAM policy grammar and AWS best practices. Findings are
+//! errors and security warnings, which should keep a policy from being deployed, and
+//! general warnings and suggestions to improve it.
+//!
+//! The custom policy checks of Access Analyzer guard against unwanted changes instead:
+//! `CheckNoNewAccess` fails for policies granting access a reference policy doesn't, and
+//! `CheckAccessNotGranted` for policies granting forbidden actions.
+
+use aws_config::BehaviorVersion;
+use aws_sdk_accessanalyzer::operation::check_access_not_granted::CheckAccessNotGrantedError;
+use aws_sdk_accessanalyzer::operation::check_no_new_access::CheckNoNewAccessError;
+use aws_sdk_accessanalyzer::operation::validate_policy::ValidatePolicyError;
+use aws_sdk_accessanalyzer::types::{
+    Access, AccessCheckPolicyType, CheckAccessNotGrantedResult, CheckNoNewAccessResult,
+    PathElement, PolicyType as AccessAnalyzerPolicyType, ReasonSummary, ValidatePolicyFinding,
+    ValidatePolicyFindingType,
+};
+use aws_sdk_accessanalyzer::Client as AccessAnalyzerClient;
+use aws_smithy_runtime_api::client::result::SdkError;
+use iam_policy_autopilot_policy_generation::PolicyWithMetadata;
+use thiserror::Error;
+
+/// Errors that can occur during policy validation
+#[derive(Error, Debug)]
+pub enum ValidatorError {
+    /// IAM Access Analyzer validate policy error
+    #[error("IAM Access Analyzer validate policy error: {0}")]
+    ValidatePolicy(#[from] SdkError<ValidatePolicyError, aws_smithy_runtime_api::http::Response>),
+
+    /// IAM Access Analyzer check no new access error
+    #[error("IAM Access Analyzer check no new access error: {0}")]
+    CheckNoNewAccess(
+        #[from] SdkError<CheckNoNewAccessError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// IAM Access Analyzer check access not granted error
+    #[error("IAM Access Analyzer check access not granted error: {0}")]
+    CheckAccessNotGranted(
+        #[from] SdkError<CheckAccessNotGrantedError, aws_smithy_runtime_api::http::Response>,
+    ),
+
+    /// JSON serialization error
+    #[error("JSON serialization error: {0}")]
+    JsonSerialization(#[from] serde_json::Error),
+}
+
+/// Result type for validator operations
+pub type ValidatorResult<T> = Result<T, ValidatorError>;
+
+/// Severity of a validation finding
+#[derive(Debug, Clone, Copy, PartialEq, Eq, serde::Serialize, serde::Deserialize)]
+#[serde(rename_all = "SCREAMING_SNAKE_CASE")]
+pub enum FindingType {
+    /// The policy is invalid or doesn't work as intended
+    Error,
+    /// The policy grants overly permissive access
+    SecurityWarning,
+    /// The policy doesn't follow best practices
+    Warning,
+    /// The policy can be simplified
+    Suggestion,
+}
+
+impl FindingType {
+    /// Whether findings of this type should keep the policy from being deployed
+    #[must_use]
+    pub const fn is_blocking(self) -> bool {
+        matches!(self, Self::Error | Self::SecurityWarning)
+    }
+}
+
+/// A finding of IAM Access Analyzer for one of the validated policies
+#[derive(Debug, Clone, PartialEq, Eq, serde::Serialize, serde::Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct ValidationFinding {
+    /// Index of the policy the finding is for
+    pub policy_index: usize,
+    /// Severity of the finding
+    pub finding_type: FindingType,
+    /// Code of the issue, e.g. `PASS_ROLE_WITH_STAR_IN_RESOURCE`
+    pub issue_code: String,
+    /// Description of the issue
+    pub finding_details: String,
+    /// Documentation of the issue
+    pub learn_more_link: String,
+    /// Paths of the policy elements the finding is about, e.g. `Statement[0].Resource`
+    pub locations: Vec<String>,
+}
+
+/// Custom policy check of IAM Access Analyzer
+#[derive(Debug, Clone, Copy, PartialEq, Eq, serde::Serialize, serde::Deserialize)]
+pub enum CustomCheck {
+    /// `CheckNoNewAccess`: the policy grants no access the reference policy doesn't
+    NoNewAccess,
+    /// `CheckAccessNotGranted`: the policy grants none of the forbidden actions
+    AccessNotGranted,
+}
+
+/// Result of a custom policy check for one of the checked policies
+#[derive(Debug, Clone, PartialEq, Eq, serde::Serialize, serde::Deserialize)]
+#[serde(rename_all = "PascalCase")]
+pub struct CustomCheckResult {
+    /// Index of the policy the check is for
+    pub policy_index: usize,
+    /// The check
+    pub check: CustomCheck,
+    /// Whether the policy passed the check
+    pub passed: bool,
+    /// Message of Access Analyzer about the result
+    pub message: String,
+    /// Why the policy failed, e.g. `Statement[1]: The policy grants s3:DeleteObject`
+    pub reasons: Vec<String>,
+}
+
+/// Maximum number of actions of a `CheckAccessNotGranted` request
+const MAX_CHECKED_ACTIONS: usize = 100;
+
+/// IAM Policy Validator client
+pub struct PolicyValidator {
+    client: AccessAnalyzerClient,
+}
+
+impl PolicyValidator {
+    /// Create a new PolicyValidator with default AWS configuration
+    pub async fn new() -> Self {
+        let config = aws_config::defaults(BehaviorVersion::latest()).load().await;
+
+        Self {
+            client: AccessAnalyzerClient::new(&config),
+        }
+    }
+
+    /// Create a new PolicyValidator with custom AWS configuration
+    #[must_use]
+    pub fn with_client(client: AccessAnalyzerClient) -> Self {
+        Self { client }
+    }
+
+    /// Validate identity policies with IAM Access Analyzer
+    ///
+    /// # Arguments
+    ///
+    /// * `policies` - Slice of IAM policies to validate
+    ///
+    /// # Returns
+    ///
+    /// The findings of all policies, in policy order
+    pub async fn validate_policies(
+        &self,
+        policies: &[PolicyWithMetadata],
+    ) -> ValidatorResult<Vec<ValidationFinding>> {
+        let mut findings = Vec::new();
+        for (policy_index, policy) in policies.iter().enumerate() {
+            let policy_document = serde_json::to_string(&policy.policy)?;
+            let mut next_token = None;
+            loop {
+                let response = self
+                    .client
+                    .validate_policy()
+                    .policy_document(&policy_document)
+                    .policy_type(AccessAnalyzerPolicyType::IdentityPolicy)
+                    .set_next_token(next_token)
+                    .send()
+                    .await?;
+
+                findings.extend(
+                    response
+                        .findings()
+                        .iter()
+                        .filter_map(|finding| validation_finding(policy_index, finding)),
+                );
+
+                next_token = response.next_token().map(ToString::to_string);
+                if next_token.is_none() {
+                    break;
+                }
+            }
+        }
+
+        log::debug!(
+            "Validated {} policies: {} findings",
+            policies.len(),
+            findings.len()
+        );
+        Ok(findings)
+    }
+
+    /// Check that identity policies grant no access the `reference` policy document
+    /// doesn't, e.g. the policy deployed today, with IAM Access Analyzer
+    ///
+    /// # Returns
+    ///
+    /// The result of each policy, in policy order
+    pub async fn check_no_new_access(
+        &self,
+        policies: &[PolicyWithMetadata],
+        reference: &str,
+    ) -> ValidatorResult<Vec<CustomCheckResult>> {
+        let mut results = Vec::new();
+        for (policy_index, policy) in policies.iter().enumerate() {
+            let response = self
+                .client
+                .check_no_new_access()
+                .new_policy_document(serde_json::to_string(&policy.policy)?)
+                .existing_policy_document(reference)
+                .policy_type(AccessCheckPolicyType::IdentityPolicy)
+                .send()
+                .await?;
+            results.push(CustomCheckResult {
+                policy_index,
+                check: CustomCheck::NoNewAccess,
+                passed: response.result() == Some(&CheckNoNewAccessResult::Pass),
+                message: response.message().unwrap_or_default().to_string(),
+                reasons: response.reasons().iter().map(reason).collect(),
+            });
+        }
+
+        log::debug!("Checked {} policies for new access", policies.len());
+        Ok(results)
+    }
+
+    /// Check that identity policies grant none of the `actions`, e.g. `iam:PassRole`,
+    /// with IAM Access Analyzer
+    ///
+    /// # Returns
+    ///
+    /// The result of each policy, in policy order
+    pub async fn check_access_not_granted(
+        &self,
+        policies: &[PolicyWithMetadata],
+        actions: &[String],
+    ) -> ValidatorResult<Vec<CustomCheckResult>> {
+        let mut results = Vec::new();
+        for (policy_index, policy) in policies.iter().enumerate() {
+            let policy_document = serde_json::to_string(&policy.policy)?;
+            let mut result = CustomCheckResult {
+                policy_index,
+                check: CustomCheck::AccessNotGranted,
+                passed: true,
+                message: String::new(),
+                reasons: Vec::new(),
+            };
+            for chunk in actions.chunks(MAX_CHECKED_ACTIONS) {
+                let response = self
+                    .client
+                    .check_access_not_granted()
+                    .policy_document(&policy_document)
+                    .access(Access::builder().set_actions(Some(chunk.to_vec())).build())
+                    .policy_type(AccessCheckPolicyType::IdentityPolicy)
+                    .send()
+                    .await?;
+                let passed = response.result() == Some(&CheckAccessNotGrantedResult::Pass);
+                if result.passed || !passed {
+                    result.message = response.message().unwrap_or_default().to_string();
+                }
+                result.passed &= passed;
+                result.reasons.extend(response.reasons().iter().map(reason));
+            }
+            results.push(result);
+        }
+
+        log::debug!(
+            "Checked {} policies for {} forbidden actions",
+            policies.len(),
+            actions.len()
+        );
+        Ok(results)
+    }
+}
+
+/// Description of why a policy failed a custom check, prefixed with its statement
+fn reason(reason: &ReasonSummary) -> String {
+    let description = reason.description().unwrap_or_default();
+    match (reason.statement_id(), reason.statement_index()) {
+        (Some(sid), _) => format!("Statement {sid}: {description}"),
+        (None, Some(index)) => format!("Statement[{index}]: {description}"),
+        (None, None) => description.to_string(),
+    }
+}
+
+/// Convert a finding of the IAM Access Analyzer API, skipping types this version doesn't know
+fn validation_finding(
+    policy_index: usize,
+    finding: &ValidatePolicyFinding,
+) -> Option<ValidationFinding> {
+    let finding_type = match finding.finding_type() {
+        ValidatePolicyFindingType::Error => FindingType::Error,
+        ValidatePolicyFindingType::SecurityWarning => FindingType::SecurityWarning,
+        ValidatePolicyFindingType::Warning => FindingType::Warning,
+        ValidatePolicyFindingType::Suggestion => FindingType::Suggestion,
+        other => {
+            log::debug!("Skipping finding of unknown type {other:?}");
+            return None;
+        }
+    };
+    Some(ValidationFinding {
+        policy_index,
+        finding_type,
+        issue_code: finding.issue_code().to_string(),
+        finding_details: finding.finding_details().to_string(),
+        learn_more_link: finding.learn_more_link().to_string(),
+        locations: finding
+            .locations()
+            .iter()
+            .map(|location| location_path(location.path()))
+            .collect(),
+    })
+}
+
+/// Path of a policy element, e.g. `Statement[0].Action[2]`
+fn location_path(path: &[PathElement]) -> String {
+    let mut formatted = String::new();
+    for element in path {
+        match element {
+            PathElement::Index(index) => formatted.push_str(&format!("[{index}]")),
+            PathElement::Key(key) | PathElement::Value(key) => {
+                if !formatted.is_empty() {
+                    formatted.push('.');
+                }
+                formatted.push_str(key);
+            }
+            _ => {}
+        }
+    }
+    formatted
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn test_location_path() {
+        let path = [
+            PathElement::Key("Statement".to_string()),
+            PathElement::Index(0),
+            PathElement::Key("Action".to_string()),
+            PathElement::Index(2),
+        ];
+        assert_eq!(location_path(&path), "Statement[0].Action[2]");
+    }
+
+    #[test]
+    fn test_reason() {
+        let by_sid = ReasonSummary::builder()
+            .description("New access in the statement")
+            .statement_id("Storage")
+            .statement_index(1)
+            .build();
+        assert_eq!(
+            reason(&by_sid),
+            "Statement Storage: New access in the statement"
+        );
+
+        let by_index = ReasonSummary::builder()
+            .description("New access in the statement")
+            .statement_index(0)
+            .build();
+        assert_eq!(
+            reason(&by_index),
+            "Statement[0]: New access in the statement"
+        );
+    }
+
+    #[test]
+    fn test_blocking_finding_types() {
+        assert!(FindingType::Error.is_blocking());
+        assert!(FindingType::SecurityWarning.is_blocking());
+        assert!(!FindingType::Warning.is_blocking());
+        assert!(!FindingType::Suggestion.is_blocking());
+    }
+}